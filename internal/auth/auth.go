@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -47,6 +48,13 @@ const (
 	MethodAWSIRSA Method = "aws-irsa"
 	// MethodAzureAAD uses Azure Active Directory Pod Identity.
 	MethodAzureAAD Method = "azure-aad"
+	// MethodGitHubApp uses short-lived GitHub App installation tokens.
+	MethodGitHubApp Method = "github-app"
+	// MethodClientCert uses a TLS client certificate.
+	MethodClientCert Method = "client-cert"
+	// MethodExec runs a kubeconfig exec credential plugin, such as
+	// aws eks get-token or kubelogin.
+	MethodExec Method = "exec"
 )
 
 // GitProvider represents a Git hosting provider.
@@ -132,6 +140,25 @@ type CredentialData struct {
 	AzureTenantID string `yaml:"azure_tenant_id,omitempty" json:"azure_tenant_id,omitempty"`
 	// AzureClientID for Azure AAD
 	AzureClientID string `yaml:"azure_client_id,omitempty" json:"azure_client_id,omitempty"`
+	// GitHubAppID is the GitHub App ID
+	GitHubAppID int64 `yaml:"github_app_id,omitempty" json:"github_app_id,omitempty"`
+	// GitHubAppInstallationID is the installation of the GitHub App to act as
+	GitHubAppInstallationID int64 `yaml:"github_app_installation_id,omitempty" json:"github_app_installation_id,omitempty"`
+	// GitHubAppPrivateKey is the PEM-encoded private key of the GitHub App
+	GitHubAppPrivateKey string `yaml:"github_app_private_key,omitempty" json:"github_app_private_key,omitempty"`
+	// GitHubAppEnterpriseBaseURL is the API URL of a GitHub Enterprise Server
+	GitHubAppEnterpriseBaseURL string `yaml:"github_app_enterprise_base_url,omitempty" json:"github_app_enterprise_base_url,omitempty"`
+	// Exec is the credential plugin of exec platform credentials
+	Exec *ExecConfig `yaml:"exec,omitempty" json:"exec,omitempty"`
+}
+
+// ExecConfig is a kubeconfig exec credential plugin, run by kubectl and
+// client-go to get a token for each request.
+type ExecConfig struct {
+	APIVersion string            `yaml:"api_version" json:"api_version"`
+	Command    string            `yaml:"command" json:"command"`
+	Args       []string          `yaml:"args,omitempty" json:"args,omitempty"`
+	Env        map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
 }
 
 // CredentialMetadata contains additional information about a credential.
@@ -150,12 +177,16 @@ type CredentialMetadata struct {
 	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 	// ExpiresAt is when the credential expires
 	ExpiresAt *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+	// KubeContext is the kubeconfig context a platform credential was
+	// imported from
+	KubeContext string `yaml:"kube_context,omitempty" json:"kube_context,omitempty"`
 }
 
 // Manager handles credential operations.
 type Manager struct {
 	store        Store
 	secretFormat SecretFormat
+	encrypter    SecretEncrypter
 }
 
 // Store interface for credential storage.
@@ -198,6 +229,7 @@ func (m *Manager) AddGitCredential(ctx context.Context, opts *GitCredentialOptio
 			URL:         opts.URL,
 			Namespace:   opts.Namespace,
 			SecretName:  opts.SecretName,
+			ExpiresAt:   opts.ExpiresAt,
 		},
 	}
 
@@ -217,6 +249,11 @@ func (m *Manager) AddGitCredential(ctx context.Context, opts *GitCredentialOptio
 		cred.Data.ClientID = opts.ClientID
 		cred.Data.ClientSecret = opts.ClientSecret
 		cred.Data.Token = opts.Token
+	case MethodGitHubApp:
+		cred.Data.GitHubAppID = opts.GitHubAppID
+		cred.Data.GitHubAppInstallationID = opts.GitHubAppInstallationID
+		cred.Data.GitHubAppPrivateKey = opts.GitHubAppPrivateKey
+		cred.Data.GitHubAppEnterpriseBaseURL = opts.GitHubAppEnterpriseBaseURL
 	}
 
 	if err := m.store.Save(ctx, cred); err != nil {
@@ -243,6 +280,13 @@ type GitCredentialOptions struct {
 	SSHKnownHosts string
 	ClientID      string
 	ClientSecret  string
+	// ExpiresAt is when the token or key expires
+	ExpiresAt *time.Time
+
+	GitHubAppID                int64
+	GitHubAppInstallationID    int64
+	GitHubAppPrivateKey        string
+	GitHubAppEnterpriseBaseURL string
 }
 
 // Validate validates the Git credential options.
@@ -274,6 +318,16 @@ func (o *GitCredentialOptions) Validate() error {
 		if o.Token == "" && (o.ClientID == "" || o.ClientSecret == "") {
 			return fmt.Errorf("token or client_id/client_secret required for OAuth")
 		}
+	case MethodGitHubApp:
+		if o.Provider != GitProviderGitHub {
+			return fmt.Errorf("github-app auth is only supported for the github provider")
+		}
+		if o.GitHubAppID <= 0 || o.GitHubAppInstallationID <= 0 {
+			return fmt.Errorf("github_app_id and github_app_installation_id are required for GitHub App auth")
+		}
+		if _, err := ParseGitHubAppPrivateKey(o.GitHubAppPrivateKey); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -297,6 +351,7 @@ func (m *Manager) AddPlatformCredential(ctx context.Context, opts *PlatformCrede
 			URL:         opts.URL,
 			Namespace:   opts.Namespace,
 			SecretName:  opts.SecretName,
+			ExpiresAt:   opts.ExpiresAt,
 		},
 	}
 
@@ -345,6 +400,8 @@ type PlatformCredentialOptions struct {
 	AWSRoleARN    string
 	AzureTenantID string
 	AzureClientID string
+	// ExpiresAt is when the token expires
+	ExpiresAt *time.Time
 }
 
 // Validate validates the platform credential options.
@@ -407,6 +464,7 @@ func (m *Manager) AddRegistryCredential(ctx context.Context, opts *RegistryCrede
 			URL:         opts.URL,
 			Namespace:   opts.Namespace,
 			SecretName:  opts.SecretName,
+			ExpiresAt:   opts.ExpiresAt,
 		},
 	}
 
@@ -427,6 +485,8 @@ type RegistryCredentialOptions struct {
 	Description string
 	Namespace   string
 	SecretName  string
+	// ExpiresAt is when the password expires
+	ExpiresAt *time.Time
 }
 
 // Validate validates the registry credential options.
@@ -489,12 +549,12 @@ func (m *Manager) TestCredential(ctx context.Context, name string) (*TestResult,
 
 // TestResult contains the result of testing a credential.
 type TestResult struct {
-	Name     string         `json:"name"`
-	Type     CredentialType `json:"type"`
-	Provider string         `json:"provider"`
-	Success  bool           `json:"success"`
-	Message  string         `json:"message"`
-	TestedAt time.Time      `json:"tested_at"`
+	Name     string         `json:"name" yaml:"name"`
+	Type     CredentialType `json:"type" yaml:"type"`
+	Provider string         `json:"provider" yaml:"provider"`
+	Success  bool           `json:"success" yaml:"success"`
+	Message  string         `json:"message" yaml:"message"`
+	TestedAt time.Time      `json:"tested_at" yaml:"tested_at"`
 }
 
 func (m *Manager) testGitCredential(_ context.Context, cred *Credential) (success bool, message string) {
@@ -518,6 +578,11 @@ func (m *Manager) testGitCredential(_ context.Context, cred *Credential) (succes
 			return false, "Username or password is empty"
 		}
 		return true, "Basic auth credentials are present"
+	case MethodGitHubApp:
+		if _, err := GitHubAppFromCredential(cred); err != nil {
+			return false, err.Error()
+		}
+		return true, "GitHub App credentials are present (token exchange requires network access)"
 	default:
 		return false, fmt.Sprintf("unsupported auth method: %s", cred.Method)
 	}
@@ -545,6 +610,21 @@ func (m *Manager) testPlatformCredential(_ context.Context, cred *Credential) (s
 			return false, "Azure tenant ID or client ID is empty"
 		}
 		return true, "Azure AAD configuration is present"
+	case MethodBasic:
+		if cred.Data.Username == "" || cred.Data.Password == "" {
+			return false, "Username or password is empty"
+		}
+		return true, "Basic auth credentials are present"
+	case MethodClientCert:
+		if cred.Data.TLSCert == "" || cred.Data.TLSKey == "" {
+			return false, "Client certificate or key is empty"
+		}
+		return true, "Client certificate is present"
+	case MethodExec:
+		if cred.Data.Exec == nil || cred.Data.Exec.Command == "" {
+			return false, "Exec command is empty"
+		}
+		return true, fmt.Sprintf("Exec plugin %s is configured (tokens are requested when used)", cred.Data.Exec.Command)
 	default:
 		return false, fmt.Sprintf("unsupported auth method: %s", cred.Method)
 	}
@@ -580,16 +660,22 @@ func (m *Manager) GenerateKubernetesSecret(ctx context.Context, name string) (st
 	}
 	labels["app.kubernetes.io/managed-by"] = "gitopsi"
 
+	var manifest string
 	switch cred.Type {
 	case CredentialTypeGit:
-		return m.generateGitSecret(cred, namespace, secretName, labels)
+		manifest, err = m.generateGitSecret(cred, namespace, secretName, labels)
 	case CredentialTypePlatform:
-		return m.generatePlatformSecret(cred, namespace, secretName, labels)
+		manifest, err = m.generatePlatformSecret(cred, namespace, secretName, labels)
 	case CredentialTypeRegistry:
-		return m.generateRegistrySecret(cred, namespace, secretName, labels)
+		manifest, err = m.generateRegistrySecret(cred, namespace, secretName, labels)
 	default:
 		return "", fmt.Errorf("unsupported credential type: %s", cred.Type)
 	}
+	if err != nil {
+		return "", err
+	}
+
+	return m.encodeSecret(ctx, manifest)
 }
 
 func (m *Manager) generateGitSecret(cred *Credential, namespace, secretName string, labels map[string]string) (string, error) {
@@ -620,6 +706,8 @@ func (m *Manager) generateGitSecret(cred *Credential, namespace, secretName stri
 		secret["type"] = "kubernetes.io/basic-auth"
 		stringData["username"] = cred.Data.Username
 		stringData["password"] = cred.Data.Password
+	case MethodGitHubApp:
+		addGitHubAppData(stringData, cred, "githubAppEnterpriseBaseUrl")
 	}
 
 	secret["stringData"] = stringData
@@ -655,6 +743,14 @@ func (m *Manager) generatePlatformSecret(cred *Credential, namespace, secretName
 	case MethodBasic:
 		stringData["username"] = cred.Data.Username
 		stringData["password"] = cred.Data.Password
+	case MethodClientCert:
+		stringData["tls.crt"] = cred.Data.TLSCert
+		stringData["tls.key"] = cred.Data.TLSKey
+		if cred.Data.CACert != "" {
+			stringData["ca.crt"] = cred.Data.CACert
+		}
+	case MethodExec:
+		return "", fmt.Errorf("exec credential %s has no secret to generate: its plugin requests tokens when used", cred.Name)
 	}
 
 	secret["stringData"] = stringData
@@ -704,6 +800,17 @@ func (m *Manager) generateRegistrySecret(cred *Credential, namespace, secretName
 
 // GenerateArgoCDRepoSecret generates an ArgoCD repository secret.
 func (m *Manager) GenerateArgoCDRepoSecret(ctx context.Context, name, argoCDNamespace string) (string, error) {
+	return m.generateArgoCDSecret(ctx, name, argoCDNamespace, "repository", "repo-%s")
+}
+
+// GenerateArgoCDRepoCredsSecret generates an ArgoCD repository credential
+// template (repo-creds) secret. ArgoCD uses it for every repository whose URL
+// starts with the credential URL, e.g. https://github.com/my-org.
+func (m *Manager) GenerateArgoCDRepoCredsSecret(ctx context.Context, name, argoCDNamespace string) (string, error) {
+	return m.generateArgoCDSecret(ctx, name, argoCDNamespace, "repo-creds", "creds-%s")
+}
+
+func (m *Manager) generateArgoCDSecret(ctx context.Context, name, argoCDNamespace, secretType, nameFormat string) (string, error) {
 	cred, err := m.store.Get(ctx, name)
 	if err != nil {
 		return "", fmt.Errorf("credential not found: %w", err)
@@ -715,11 +822,11 @@ func (m *Manager) GenerateArgoCDRepoSecret(ctx context.Context, name, argoCDName
 
 	secretName := cred.Metadata.SecretName
 	if secretName == "" {
-		secretName = fmt.Sprintf("repo-%s", cred.Name)
+		secretName = fmt.Sprintf(nameFormat, cred.Name)
 	}
 
 	labels := map[string]string{
-		"argocd.argoproj.io/secret-type": "repository",
+		"argocd.argoproj.io/secret-type": secretType,
 		"app.kubernetes.io/managed-by":   "gitopsi",
 	}
 
@@ -751,11 +858,17 @@ func (m *Manager) GenerateArgoCDRepoSecret(ctx context.Context, name, argoCDName
 	case MethodBasic:
 		stringData["username"] = cred.Data.Username
 		stringData["password"] = cred.Data.Password
+	case MethodGitHubApp:
+		addGitHubAppData(stringData, cred, "githubAppEnterpriseBaseUrl")
 	}
 
 	secret["stringData"] = stringData
 
-	return toYAML(secret)
+	manifest, err := toYAML(secret)
+	if err != nil {
+		return "", err
+	}
+	return m.encodeSecret(ctx, manifest)
 }
 
 // GenerateFluxGitRepositorySecret generates a Flux GitRepository secret.
@@ -807,11 +920,29 @@ func (m *Manager) GenerateFluxGitRepositorySecret(ctx context.Context, name, nam
 	case MethodBasic:
 		stringData["username"] = cred.Data.Username
 		stringData["password"] = cred.Data.Password
+	case MethodGitHubApp:
+		// Flux reads these keys when the GitRepository sets provider: github.
+		addGitHubAppData(stringData, cred, "githubAppBaseURL")
 	}
 
 	secret["stringData"] = stringData
 
-	return toYAML(secret)
+	manifest, err := toYAML(secret)
+	if err != nil {
+		return "", err
+	}
+	return m.encodeSecret(ctx, manifest)
+}
+
+// addGitHubAppData adds the GitHub App keys understood by ArgoCD and Flux.
+// The key holding the Enterprise API URL differs between the two.
+func addGitHubAppData(stringData map[string]string, cred *Credential, baseURLKey string) {
+	stringData["githubAppID"] = strconv.FormatInt(cred.Data.GitHubAppID, 10)
+	stringData["githubAppInstallationID"] = strconv.FormatInt(cred.Data.GitHubAppInstallationID, 10)
+	stringData["githubAppPrivateKey"] = cred.Data.GitHubAppPrivateKey
+	if cred.Data.GitHubAppEnterpriseBaseURL != "" {
+		stringData[baseURLKey] = cred.Data.GitHubAppEnterpriseBaseURL
+	}
 }
 
 // LoadSSHKeyFromFile loads an SSH private key from a file.
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHKeyPair is an SSH key pair generated for a deploy key.
+type SSHKeyPair struct {
+	// PrivateKey is the private key in OpenSSH PEM format.
+	PrivateKey string
+	// PublicKey is the public key in authorized_keys format.
+	PublicKey string
+	// Fingerprint is the SHA256 fingerprint of the public key.
+	Fingerprint string
+}
+
+// GenerateDeployKey generates an ed25519 key pair. comment is appended to the
+// public key, e.g. to identify the key in the provider's deploy key list.
+func GenerateDeployKey(comment string) (*SSHKeyPair, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ed25519 key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic)))
+	if comment != "" {
+		authorized += " " + comment
+	}
+	return &SSHKeyPair{
+		PrivateKey:  string(pem.EncodeToMemory(block)),
+		PublicKey:   authorized,
+		Fingerprint: ssh.FingerprintSHA256(sshPublic),
+	}, nil
+}
+
+// errHostKeyScanned stops the handshake once the host key is known.
+var errHostKeyScanned = errors.New("host key scanned")
+
+// ScanKnownHosts returns the known_hosts lines of an SSH server, like
+// ssh-keyscan. host may include a port (default 22).
+func ScanKnownHosts(ctx context.Context, host string) (string, error) {
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "22")
+	}
+
+	var lines []string
+	for _, algorithm := range []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoRSASHA256} {
+		var key ssh.PublicKey
+		config := &ssh.ClientConfig{
+			User:              "git",
+			HostKeyAlgorithms: []string{algorithm},
+			HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+				key = k
+				return errHostKeyScanned
+			},
+			Timeout: 10 * time.Second,
+		}
+		conn, err := (&net.Dialer{Timeout: config.Timeout}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return "", fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		if client, _, _, err := ssh.NewClientConn(conn, addr, config); err == nil {
+			client.Close()
+		}
+		conn.Close()
+		// key is unset when the server does not offer the algorithm
+		if key != nil {
+			lines = append(lines, knownhosts.Line([]string{knownhosts.Normalize(addr)}, key))
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no host keys found for %s", addr)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
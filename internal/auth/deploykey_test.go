@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateDeployKey(t *testing.T) {
+	pair, err := GenerateDeployKey("gitopsi-platform")
+	if err != nil {
+		t.Fatalf("GenerateDeployKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(pair.PublicKey, "ssh-ed25519 ") || !strings.HasSuffix(pair.PublicKey, " gitopsi-platform") {
+		t.Errorf("unexpected public key %q", pair.PublicKey)
+	}
+	if !strings.HasPrefix(pair.Fingerprint, "SHA256:") {
+		t.Errorf("unexpected fingerprint %q", pair.Fingerprint)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(pair.PrivateKey))
+	if err != nil {
+		t.Fatalf("private key does not parse: %v", err)
+	}
+	if got := ssh.FingerprintSHA256(signer.PublicKey()); got != pair.Fingerprint {
+		t.Errorf("private key fingerprint %s, want %s", got, pair.Fingerprint)
+	}
+
+	other, _ := GenerateDeployKey("")
+	if other.Fingerprint == pair.Fingerprint {
+		t.Error("generated keys should differ")
+	}
+	if strings.Count(other.PublicKey, " ") != 1 {
+		t.Errorf("public key without comment = %q", other.PublicKey)
+	}
+}
+
+func TestDeployKeyCredentialSecrets(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "credentials.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(store, SecretFormatPlain)
+	ctx := context.Background()
+
+	pair, _ := GenerateDeployKey("gitopsi-deploy")
+	_, err = manager.AddGitCredential(ctx, &GitCredentialOptions{
+		Name:          "deploy",
+		Provider:      GitProviderGitHub,
+		Method:        MethodSSH,
+		URL:           "git@github.com:org/platform.git",
+		SSHPrivateKey: pair.PrivateKey,
+		SSHPublicKey:  pair.PublicKey,
+	})
+	if err != nil {
+		t.Fatalf("AddGitCredential() error = %v", err)
+	}
+
+	argocd, err := manager.GenerateArgoCDRepoSecret(ctx, "deploy", "argocd")
+	if err != nil {
+		t.Fatalf("GenerateArgoCDRepoSecret() error = %v", err)
+	}
+	if !strings.Contains(argocd, "sshPrivateKey") || !strings.Contains(argocd, "OPENSSH PRIVATE KEY") {
+		t.Errorf("ArgoCD secret should carry the private key:\n%s", argocd)
+	}
+
+	flux, err := manager.GenerateFluxGitRepositorySecret(ctx, "deploy", "flux-system")
+	if err != nil {
+		t.Fatalf("GenerateFluxGitRepositorySecret() error = %v", err)
+	}
+	if !strings.Contains(flux, "identity") {
+		t.Errorf("Flux secret should carry the identity:\n%s", flux)
+	}
+}
+
+func TestScanKnownHosts(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _, _, _ = ssh.NewServerConn(conn, config)
+				conn.Close()
+			}()
+		}
+	}()
+
+	lines, err := ScanKnownHosts(context.Background(), listener.Addr().String())
+	if err != nil {
+		t.Fatalf("ScanKnownHosts() error = %v", err)
+	}
+	want := "[127.0.0.1]:" + strings.Split(listener.Addr().String(), ":")[1] + " ssh-ed25519 "
+	if !strings.HasPrefix(lines, want) {
+		t.Errorf("ScanKnownHosts() = %q, want prefix %q", lines, want)
+	}
+	if strings.Count(lines, "\n") != 1 {
+		t.Errorf("ScanKnownHosts() = %q, want only the offered ed25519 key", lines)
+	}
+}
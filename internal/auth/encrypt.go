@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// PassphraseEnvVar is the environment variable holding the credential store passphrase.
+const PassphraseEnvVar = "GITOPSI_STORE_PASSPHRASE"
+
+const (
+	envelopeVersion = 1
+	envelopeCipher  = "aes-256-gcm"
+	envelopeKDF     = "scrypt"
+
+	saltSize = 16
+	keySize  = 32
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	// ErrStoreEncrypted is returned when an encrypted store is opened without a passphrase.
+	ErrStoreEncrypted = errors.New("credential store is encrypted; set " + PassphraseEnvVar + " to unlock it")
+	// ErrInvalidPassphrase is returned when the passphrase does not decrypt the store.
+	ErrInvalidPassphrase = errors.New("invalid passphrase or corrupted credential store")
+)
+
+// encryptedEnvelope is the on-disk format of an encrypted store.
+type encryptedEnvelope struct {
+	Version int    `yaml:"version"`
+	Cipher  string `yaml:"cipher"`
+	KDF     string `yaml:"kdf"`
+	Salt    string `yaml:"salt"`
+	Nonce   string `yaml:"nonce"`
+	Data    string `yaml:"data"`
+}
+
+// NewEncryptedFileStore creates a file store whose contents are encrypted with
+// AES-256-GCM using a key derived from passphrase with scrypt. An existing
+// plaintext file is loaded and encrypted on the next write.
+func NewEncryptedFileStore(path, passphrase string) (*FileStore, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is required for an encrypted store")
+	}
+
+	store := &FileStore{
+		path:        path,
+		passphrase:  passphrase,
+		credentials: make(map[string]*Credential),
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if err := store.load(); err != nil {
+			return nil, fmt.Errorf("failed to load credentials: %w", err)
+		}
+	}
+
+	return store, nil
+}
+
+// OpenFileStore opens the store at path, decrypting it if it is encrypted.
+// A new store is created encrypted when a passphrase is given; an existing
+// plaintext store stays plaintext until it is migrated.
+func OpenFileStore(path, passphrase string) (*FileStore, error) {
+	encrypted, err := IsEncryptedStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted {
+		if passphrase == "" {
+			return nil, ErrStoreEncrypted
+		}
+		return NewEncryptedFileStore(path, passphrase)
+	}
+
+	if _, statErr := os.Stat(path); os.IsNotExist(statErr) && passphrase != "" {
+		return NewEncryptedFileStore(path, passphrase)
+	}
+
+	return NewFileStore(path)
+}
+
+// IsEncryptedStore reports whether the store file at path is encrypted.
+// A missing file is reported as not encrypted.
+func IsEncryptedStore(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read credential store: %w", err)
+	}
+	return isEncrypted(data), nil
+}
+
+// MigrateFileStore rewrites the store at path with a new passphrase. An empty
+// newPassphrase writes the store as plaintext. It returns the number of
+// credentials migrated.
+func MigrateFileStore(path, oldPassphrase, newPassphrase string) (int, error) {
+	store, err := OpenFileStore(path, oldPassphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.passphrase = newPassphrase
+	if err := store.persist(); err != nil {
+		return 0, fmt.Errorf("failed to write credential store: %w", err)
+	}
+
+	return len(store.credentials), nil
+}
+
+func isEncrypted(data []byte) bool {
+	var env encryptedEnvelope
+	if err := yaml.Unmarshal(data, &env); err != nil {
+		return false
+	}
+	return env.Cipher != "" && env.Data != ""
+}
+
+func encryptData(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	env := encryptedEnvelope{
+		Version: envelopeVersion,
+		Cipher:  envelopeCipher,
+		KDF:     envelopeKDF,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}
+
+	return yaml.Marshal(env)
+}
+
+func decryptData(data []byte, passphrase string) ([]byte, error) {
+	var env encryptedEnvelope
+	if err := yaml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted store: %w", err)
+	}
+	if env.Version != envelopeVersion || env.Cipher != envelopeCipher || env.KDF != envelopeKDF {
+		return nil, fmt.Errorf("unsupported encrypted store format: version %d, cipher %s, kdf %s", env.Version, env.Cipher, env.KDF)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(env.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrInvalidPassphrase
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+	return plaintext, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
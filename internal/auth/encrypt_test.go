@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCredential(name string) *Credential {
+	return &Credential{
+		Name:     name,
+		Type:     CredentialTypeGit,
+		Provider: "github",
+		Method:   MethodToken,
+		Data:     CredentialData{Token: "ghp_secret_token"},
+	}
+}
+
+func TestEncryptedFileStore_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	ctx := context.Background()
+
+	store, err := NewEncryptedFileStore(path, "correct horse")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	if !store.Encrypted() {
+		t.Error("Encrypted() = false, want true")
+	}
+	if err := store.Save(ctx, testCredential("gh")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ghp_secret_token") || strings.Contains(string(data), "github") {
+		t.Error("store file contains plaintext credential data")
+	}
+
+	reopened, err := OpenFileStore(path, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	manager := NewManager(reopened, SecretFormatPlain)
+	cred, err := manager.GetCredential(ctx, "gh")
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	if cred.Data.Token != "ghp_secret_token" {
+		t.Errorf("Token = %q, want decrypted token", cred.Data.Token)
+	}
+}
+
+func TestEncryptedFileStore_WrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+
+	store, err := NewEncryptedFileStore(path, "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(context.Background(), testCredential("gh")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenFileStore(path, "wrong"); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Errorf("OpenFileStore() with wrong passphrase error = %v, want ErrInvalidPassphrase", err)
+	}
+	if _, err := OpenFileStore(path, ""); !errors.Is(err, ErrStoreEncrypted) {
+		t.Errorf("OpenFileStore() without passphrase error = %v, want ErrStoreEncrypted", err)
+	}
+	if _, err := NewFileStore(path); !errors.Is(err, ErrStoreEncrypted) {
+		t.Errorf("NewFileStore() on encrypted store error = %v, want ErrStoreEncrypted", err)
+	}
+}
+
+func TestNewEncryptedFileStore_RequiresPassphrase(t *testing.T) {
+	if _, err := NewEncryptedFileStore(filepath.Join(t.TempDir(), "c.yaml"), ""); err == nil {
+		t.Error("expected error for empty passphrase")
+	}
+}
+
+func TestOpenFileStore_KeepsPlaintextStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+
+	plain, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Save(context.Background(), testCredential("gh")); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenFileStore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	if store.Encrypted() {
+		t.Error("existing plaintext store should stay plaintext until migrated")
+	}
+}
+
+func TestOpenFileStore_NewStoreEncrypted(t *testing.T) {
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "credentials.yaml"), "passphrase")
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	if !store.Encrypted() {
+		t.Error("new store with passphrase should be encrypted")
+	}
+}
+
+func TestMigrateFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	ctx := context.Background()
+
+	plain, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"gh", "gl"} {
+		if err := plain.Save(ctx, testCredential(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := MigrateFileStore(path, "", "secret")
+	if err != nil {
+		t.Fatalf("MigrateFileStore() to encrypted error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if encrypted, _ := IsEncryptedStore(path); !encrypted {
+		t.Fatal("store should be encrypted after migration")
+	}
+
+	if _, err := MigrateFileStore(path, "", ""); !errors.Is(err, ErrStoreEncrypted) {
+		t.Errorf("MigrateFileStore() without passphrase error = %v, want ErrStoreEncrypted", err)
+	}
+
+	if _, err := MigrateFileStore(path, "secret", ""); err != nil {
+		t.Fatalf("MigrateFileStore() to plain error = %v", err)
+	}
+	if encrypted, _ := IsEncryptedStore(path); encrypted {
+		t.Fatal("store should be plaintext after migration")
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := reopened.List(ctx, "")
+	if len(creds) != 2 {
+		t.Errorf("List() returned %d credentials after round trip, want 2", len(creds))
+	}
+}
+
+func TestIsEncryptedStore_Missing(t *testing.T) {
+	encrypted, err := IsEncryptedStore(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || encrypted {
+		t.Errorf("IsEncryptedStore() = %v, %v; want false, nil", encrypted, err)
+	}
+}
+
+func TestEncryptDecryptData_UniqueCiphertext(t *testing.T) {
+	a, err := encryptData([]byte("payload"), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := encryptData([]byte("payload"), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) == string(b) {
+		t.Error("encrypting twice should use a fresh salt and nonce")
+	}
+
+	plaintext, err := decryptData(a, "pass")
+	if err != nil {
+		t.Fatalf("decryptData() error = %v", err)
+	}
+	if string(plaintext) != "payload" {
+		t.Errorf("decryptData() = %q, want payload", plaintext)
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpiryState is the expiry of a credential at a point in time.
+type ExpiryState string
+
+const (
+	// ExpiryNone is the state of credentials without an expiry date.
+	ExpiryNone ExpiryState = "none"
+	// ExpiryValid is the state of credentials that do not expire soon.
+	ExpiryValid ExpiryState = "valid"
+	// ExpirySoon is the state of credentials expiring within the warning period.
+	ExpirySoon ExpiryState = "expiring"
+	// ExpiryExpired is the state of expired credentials.
+	ExpiryExpired ExpiryState = "expired"
+)
+
+// Expiry returns the expiry state of the credential at now and the time left,
+// negative once it expired. Credentials expiring within warning are
+// ExpirySoon.
+func (c *Credential) Expiry(now time.Time, warning time.Duration) (ExpiryState, time.Duration) {
+	if c.Metadata.ExpiresAt == nil {
+		return ExpiryNone, 0
+	}
+	left := c.Metadata.ExpiresAt.Sub(now)
+	switch {
+	case left <= 0:
+		return ExpiryExpired, left
+	case left <= warning:
+		return ExpirySoon, left
+	default:
+		return ExpiryValid, left
+	}
+}
+
+// ParseExpiry parses an expiry given as a date (2006-01-02), an RFC 3339
+// time, or a duration from now in days (90d) or hours (720h).
+func ParseExpiry(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q: use a date (2006-01-02), an RFC 3339 time or a duration such as 90d", value)
+}
+
+// Rotation is the new secret material of a rotated credential.
+type Rotation struct {
+	// Token replaces the token of token, OAuth and service account credentials
+	Token string
+	// Password replaces the password of basic auth credentials
+	Password string
+	// SSHPrivateKey and SSHPublicKey replace the key pair of SSH credentials
+	SSHPrivateKey string
+	SSHPublicKey  string
+	// Description replaces the description when set
+	Description string
+	// ExpiresAt is the expiry of the new material (nil: no expiry)
+	ExpiresAt *time.Time
+}
+
+// RotateCredential replaces the secret material of a credential, keeping its
+// name, type, provider and metadata.
+func (m *Manager) RotateCredential(ctx context.Context, name string, rotation *Rotation) (*Credential, error) {
+	cred, err := m.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cred.Method {
+	case MethodToken, MethodOAuth, MethodServiceAccount:
+		if rotation.Token == "" {
+			return nil, fmt.Errorf("a new token is required to rotate %s", name)
+		}
+		cred.Data.Token = rotation.Token
+	case MethodBasic:
+		if rotation.Password == "" {
+			return nil, fmt.Errorf("a new password is required to rotate %s", name)
+		}
+		cred.Data.Password = rotation.Password
+	case MethodSSH:
+		if rotation.SSHPrivateKey == "" {
+			return nil, fmt.Errorf("a new SSH private key is required to rotate %s", name)
+		}
+		cred.Data.SSHPrivateKey = rotation.SSHPrivateKey
+		cred.Data.SSHPublicKey = rotation.SSHPublicKey
+	default:
+		return nil, fmt.Errorf("%s credentials have no secret to rotate", cred.Method)
+	}
+	if rotation.Description != "" {
+		cred.Metadata.Description = rotation.Description
+	}
+	cred.Metadata.ExpiresAt = rotation.ExpiresAt
+	cred.UpdatedAt = time.Now()
+
+	if err := m.store.Save(ctx, cred); err != nil {
+		return nil, fmt.Errorf("failed to save credential: %w", err)
+	}
+	return cred, nil
+}
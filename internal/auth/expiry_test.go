@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCredential_Expiry(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *Credential {
+		expires := now.Add(d)
+		return &Credential{Metadata: CredentialMetadata{ExpiresAt: &expires}}
+	}
+	tests := []struct {
+		name string
+		cred *Credential
+		want ExpiryState
+	}{
+		{"no expiry", &Credential{}, ExpiryNone},
+		{"valid", at(30 * 24 * time.Hour), ExpiryValid},
+		{"expiring", at(3 * 24 * time.Hour), ExpirySoon},
+		{"expired", at(-time.Hour), ExpiryExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := tt.cred.Expiry(now, 7*24*time.Hour); got != tt.want {
+				t.Errorf("Expiry() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if _, left := at(-time.Hour).Expiry(now, 0); left != -time.Hour {
+		t.Errorf("Expiry() left = %s", left)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2026-12-31":           time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		"2026-12-31T08:00:00Z": time.Date(2026, 12, 31, 8, 0, 0, 0, time.UTC),
+		"90d":                  time.Date(2026, 12, 30, 12, 0, 0, 0, time.UTC),
+		"48h":                  time.Date(2026, 10, 3, 12, 0, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := ParseExpiry(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseExpiry(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "soon", "-5d", "0d"} {
+		if _, err := ParseExpiry(value, now); err == nil {
+			t.Errorf("ParseExpiry(%q) should fail", value)
+		}
+	}
+}
+
+func TestManager_RotateCredential(t *testing.T) {
+	manager := NewManager(NewMemoryStore(), SecretFormatPlain)
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+
+	_, err := manager.AddGitCredential(ctx, &GitCredentialOptions{
+		Name:      "github-main",
+		Provider:  GitProviderGitHub,
+		Method:    MethodToken,
+		Token:     "old-token",
+		URL:       "https://github.com/org/platform.git",
+		ExpiresAt: &old,
+	})
+	if err != nil {
+		t.Fatalf("AddGitCredential failed: %v", err)
+	}
+
+	if _, err := manager.RotateCredential(ctx, "github-main", &Rotation{}); err == nil {
+		t.Error("RotateCredential should require a new token")
+	}
+
+	expires := time.Now().AddDate(0, 0, 90)
+	cred, err := manager.RotateCredential(ctx, "github-main", &Rotation{Token: "new-token", ExpiresAt: &expires})
+	if err != nil {
+		t.Fatalf("RotateCredential failed: %v", err)
+	}
+	stored, _ := manager.GetCredential(ctx, "github-main")
+	if stored.Data.Token != "new-token" || stored.Metadata.URL != "https://github.com/org/platform.git" || !stored.Metadata.ExpiresAt.Equal(expires) {
+		t.Errorf("unexpected rotated credential %+v", stored)
+	}
+	if cred.UpdatedAt.Before(cred.CreatedAt) {
+		t.Errorf("UpdatedAt %v is before CreatedAt %v", cred.UpdatedAt, cred.CreatedAt)
+	}
+
+	if _, err := manager.AddRegistryCredential(ctx, &RegistryCredentialOptions{Name: "quay", URL: "quay.io", Username: "u", Password: "old"}); err != nil {
+		t.Fatalf("AddRegistryCredential failed: %v", err)
+	}
+	if cred, err := manager.RotateCredential(ctx, "quay", &Rotation{Password: "new"}); err != nil || cred.Data.Password != "new" || cred.Metadata.ExpiresAt != nil {
+		t.Errorf("RotateCredential(quay) = %+v, %v", cred, err)
+	}
+
+	if _, err := manager.AddPlatformCredential(ctx, &PlatformCredentialOptions{Name: "aws", Platform: PlatformAWS, Method: MethodAWSIRSA, AWSRoleARN: "arn:aws:iam::1:role/r"}); err != nil {
+		t.Fatalf("AddPlatformCredential failed: %v", err)
+	}
+	if _, err := manager.RotateCredential(ctx, "aws", &Rotation{Token: "x"}); err == nil {
+		t.Error("RotateCredential should fail for IRSA credentials")
+	}
+}
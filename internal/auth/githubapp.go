@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGitHubAPIURL is the API URL of github.com.
+	DefaultGitHubAPIURL = "https://api.github.com"
+
+	// githubAppJWTLifetime is the lifetime of the app JWT; GitHub allows at
+	// most ten minutes.
+	githubAppJWTLifetime = 9 * time.Minute
+	// githubAppClockSkew backdates the JWT to tolerate clock drift.
+	githubAppClockSkew = time.Minute
+	// tokenRefreshWindow is how long before expiry a cached installation
+	// token is replaced.
+	tokenRefreshWindow = 5 * time.Minute
+)
+
+// InstallationToken is a short-lived GitHub App installation access token.
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GitHubApp exchanges GitHub App credentials for installation tokens. Tokens
+// are cached and refreshed shortly before they expire, so a GitHubApp can be
+// shared by long-running operations.
+type GitHubApp struct {
+	AppID          int64
+	InstallationID int64
+	// BaseURL is the GitHub API URL (default: https://api.github.com).
+	BaseURL string
+
+	key    *rsa.PrivateKey
+	client *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	token *InstallationToken
+}
+
+// NewGitHubApp creates a GitHubApp from the app ID, installation ID and
+// PEM-encoded private key. baseURL is the API URL of a GitHub Enterprise
+// Server, e.g. https://github.example.com/api/v3; empty means github.com.
+func NewGitHubApp(appID, installationID int64, privateKey, baseURL string) (*GitHubApp, error) {
+	if appID <= 0 || installationID <= 0 {
+		return nil, fmt.Errorf("GitHub App ID and installation ID are required")
+	}
+	key, err := ParseGitHubAppPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		baseURL = DefaultGitHubAPIURL
+	}
+	return &GitHubApp{
+		AppID:          appID,
+		InstallationID: installationID,
+		BaseURL:        strings.TrimSuffix(baseURL, "/"),
+		key:            key,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		now:            time.Now,
+	}, nil
+}
+
+// GitHubAppFromCredential creates a GitHubApp from a github-app credential.
+func GitHubAppFromCredential(cred *Credential) (*GitHubApp, error) {
+	if cred.Method != MethodGitHubApp {
+		return nil, fmt.Errorf("credential %s does not use github-app auth", cred.Name)
+	}
+	return NewGitHubApp(cred.Data.GitHubAppID, cred.Data.GitHubAppInstallationID,
+		cred.Data.GitHubAppPrivateKey, cred.Data.GitHubAppEnterpriseBaseURL)
+}
+
+// ParseGitHubAppPrivateKey parses a PEM-encoded PKCS#1 or PKCS#8 RSA key as
+// downloaded from the GitHub App settings.
+func ParseGitHubAppPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("GitHub App private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key must be an RSA key")
+	}
+	return key, nil
+}
+
+// JWT returns a signed RS256 JSON Web Token authenticating as the app.
+func (a *GitHubApp) JWT() (string, error) {
+	now := a.now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-githubAppClockSkew).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Token returns a valid installation token, requesting a new one when none is
+// cached or the cached one expires within five minutes.
+func (a *GitHubApp) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != nil && a.now().Add(tokenRefreshWindow).Before(a.token.ExpiresAt) {
+		return a.token.Token, nil
+	}
+	token, err := a.InstallationToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	return token.Token, nil
+}
+
+// InstallationToken requests a new installation access token from GitHub.
+func (a *GitHubApp) InstallationToken(ctx context.Context) (*InstallationToken, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.BaseURL, a.InstallationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to request installation token: GitHub returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token InstallationToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse installation token: %w", err)
+	}
+	if token.Token == "" {
+		return nil, fmt.Errorf("GitHub returned an empty installation token")
+	}
+	return &token, nil
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testGitHubAppKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, string(pemKey)
+}
+
+func TestParseGitHubAppPrivateKey(t *testing.T) {
+	key, pkcs1 := testGitHubAppKey(t)
+	if _, err := ParseGitHubAppPrivateKey(pkcs1); err != nil {
+		t.Errorf("PKCS#1 key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if _, err := ParseGitHubAppPrivateKey(string(pkcs8)); err != nil {
+		t.Errorf("PKCS#8 key: %v", err)
+	}
+
+	if _, err := ParseGitHubAppPrivateKey("not a key"); err == nil {
+		t.Error("expected error for a non-PEM key")
+	}
+}
+
+func TestGitHubApp_JWT(t *testing.T) {
+	key, pemKey := testGitHubAppKey(t)
+	app, err := NewGitHubApp(42, 7, pemKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	app.now = func() time.Time { return now }
+
+	jwt, err := app.JWT()
+	if err != nil {
+		t.Fatalf("JWT() error = %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts", len(parts))
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "42" || claims.Iat != now.Unix()-60 || claims.Exp != now.Unix()+540 {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("invalid signature: %v", err)
+	}
+}
+
+func TestGitHubApp_Token(t *testing.T) {
+	_, pemKey := testGitHubAppKey(t)
+	var requests int32
+	expiresAt := time.Now().Add(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/7/access_tokens" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("missing JWT bearer token")
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(InstallationToken{Token: fmt.Sprintf("ghs_%d", n), ExpiresAt: expiresAt})
+	}))
+	defer server.Close()
+
+	app, err := NewGitHubApp(42, 7, pemKey, server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	token, err := app.Token(ctx)
+	if err != nil || token != "ghs_1" {
+		t.Fatalf("Token() = %q, %v", token, err)
+	}
+	if token, _ := app.Token(ctx); token != "ghs_1" {
+		t.Errorf("expected the cached token, got %q", token)
+	}
+
+	// Within the refresh window the token is replaced.
+	app.now = func() time.Time { return expiresAt.Add(-time.Minute) }
+	if token, _ := app.Token(ctx); token != "ghs_2" {
+		t.Errorf("expected a refreshed token, got %q", token)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
+
+func TestGitHubApp_TokenError(t *testing.T) {
+	_, pemKey := testGitHubAppKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	app, _ := NewGitHubApp(42, 7, pemKey, server.URL)
+	_, err := app.Token(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a 401 error, got %v", err)
+	}
+}
+
+func TestGitHubAppCredential(t *testing.T) {
+	_, pemKey := testGitHubAppKey(t)
+	manager := NewManager(NewMemoryStore(), SecretFormatPlain)
+	ctx := context.Background()
+
+	opts := &GitCredentialOptions{
+		Name:                       "github-app",
+		Provider:                   GitProviderGitHub,
+		Method:                     MethodGitHubApp,
+		URL:                        "https://github.com/my-org",
+		GitHubAppID:                42,
+		GitHubAppInstallationID:    7,
+		GitHubAppPrivateKey:        pemKey,
+		GitHubAppEnterpriseBaseURL: "https://ghe.example.com/api/v3",
+	}
+	if _, err := manager.AddGitCredential(ctx, opts); err != nil {
+		t.Fatalf("AddGitCredential failed: %v", err)
+	}
+
+	result, err := manager.TestCredential(ctx, "github-app")
+	if err != nil || !result.Success {
+		t.Errorf("TestCredential() = %+v, %v", result, err)
+	}
+
+	output, err := manager.GenerateArgoCDRepoCredsSecret(ctx, "github-app", "argocd")
+	if err != nil {
+		t.Fatalf("GenerateArgoCDRepoCredsSecret failed: %v", err)
+	}
+	for _, field := range []string{
+		"name: creds-github-app",
+		"argocd.argoproj.io/secret-type: repo-creds",
+		"url: https://github.com/my-org",
+		`githubAppID: "42"`,
+		`githubAppInstallationID: "7"`,
+		"githubAppPrivateKey: |",
+		"githubAppEnterpriseBaseUrl: https://ghe.example.com/api/v3",
+	} {
+		if !strings.Contains(output, field) {
+			t.Errorf("repo-creds secret missing %q:\n%s", field, output)
+		}
+	}
+	if strings.Contains(output, "password") {
+		t.Error("repo-creds secret should not contain a password")
+	}
+
+	output, err = manager.GenerateFluxGitRepositorySecret(ctx, "github-app", "flux-system")
+	if err != nil {
+		t.Fatalf("GenerateFluxGitRepositorySecret failed: %v", err)
+	}
+	if !strings.Contains(output, "githubAppBaseURL: https://ghe.example.com/api/v3") {
+		t.Errorf("flux secret missing githubAppBaseURL:\n%s", output)
+	}
+
+	invalid := *opts
+	invalid.Provider = GitProviderGitLab
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for a non-GitHub provider")
+	}
+	invalid = *opts
+	invalid.GitHubAppInstallationID = 0
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error without an installation ID")
+	}
+	invalid = *opts
+	invalid.GitHubAppPrivateKey = "bad"
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for an invalid private key")
+	}
+}
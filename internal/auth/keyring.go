@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// DefaultKeyringService is the keyring service name credentials are stored under.
+const DefaultKeyringService = "gitopsi"
+
+// keyringIndexKey holds the list of stored credential names, since OS
+// keyrings cannot enumerate the items of a service.
+const keyringIndexKey = "__gitopsi_index__"
+
+// KeyringStore implements Store using the OS keychain (macOS Keychain,
+// Windows Credential Manager, or the Linux Secret Service), so credential
+// data is never written to disk by gitopsi.
+type KeyringStore struct {
+	service string
+	mu      sync.Mutex
+}
+
+// NewKeyringStore creates a keyring-backed credential store. An empty service
+// uses DefaultKeyringService.
+func NewKeyringStore(service string) *KeyringStore {
+	if service == "" {
+		service = DefaultKeyringService
+	}
+	return &KeyringStore{service: service}
+}
+
+// Save stores a credential.
+func (s *KeyringStore) Save(ctx context.Context, cred *Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("failed to encode credential: %w", err)
+	}
+	if err := keyring.Set(s.service, cred.Name, string(data)); err != nil {
+		return fmt.Errorf("failed to save credential %q to keyring: %w", cred.Name, err)
+	}
+
+	names, err := s.index()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == cred.Name {
+			return nil
+		}
+	}
+	return s.writeIndex(append(names, cred.Name))
+}
+
+// Get retrieves a credential by name.
+func (s *KeyringStore) Get(ctx context.Context, name string) (*Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(name)
+}
+
+// List returns all credentials of a given type.
+func (s *KeyringStore) List(ctx context.Context, credType CredentialType) ([]*Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Credential
+	for _, name := range names {
+		cred, err := s.get(name)
+		if err != nil {
+			return nil, err
+		}
+		if credType == "" || cred.Type == credType {
+			result = append(result, cred)
+		}
+	}
+	return result, nil
+}
+
+// Delete removes a credential.
+func (s *KeyringStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := keyring.Delete(s.service, name); err != nil {
+		if errors.Is(err, keyring.ErrNotFound) {
+			return fmt.Errorf("credential %q not found", name)
+		}
+		return fmt.Errorf("failed to delete credential %q from keyring: %w", name, err)
+	}
+
+	names, err := s.index()
+	if err != nil {
+		return err
+	}
+	remaining := names[:0]
+	for _, n := range names {
+		if n != name {
+			remaining = append(remaining, n)
+		}
+	}
+	return s.writeIndex(remaining)
+}
+
+// Exists checks if a credential exists.
+func (s *KeyringStore) Exists(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := keyring.Get(s.service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	return true, nil
+}
+
+func (s *KeyringStore) get(name string) (*Credential, error) {
+	data, err := keyring.Get(s.service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, fmt.Errorf("credential %q not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential %q from keyring: %w", name, err)
+	}
+
+	var cred Credential
+	if err := json.Unmarshal([]byte(data), &cred); err != nil {
+		return nil, fmt.Errorf("failed to decode credential %q: %w", name, err)
+	}
+	return &cred, nil
+}
+
+func (s *KeyringStore) index() ([]string, error) {
+	data, err := keyring.Get(s.service, keyringIndexKey)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring index: %w", err)
+	}
+
+	var names []string
+	if err := json.Unmarshal([]byte(data), &names); err != nil {
+		return nil, fmt.Errorf("failed to decode keyring index: %w", err)
+	}
+	return names, nil
+}
+
+func (s *KeyringStore) writeIndex(names []string) error {
+	sort.Strings(names)
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	if err := keyring.Set(s.service, keyringIndexKey, string(data)); err != nil {
+		return fmt.Errorf("failed to write keyring index: %w", err)
+	}
+	return nil
+}
+
+// CopyCredentials copies every credential from one store to another and
+// returns the number copied.
+func CopyCredentials(ctx context.Context, from, to Store) (int, error) {
+	creds, err := from.List(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	for _, cred := range creds {
+		if err := to.Save(ctx, cred); err != nil {
+			return 0, err
+		}
+	}
+	return len(creds), nil
+}
@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigImport is the result of reading the contexts of a kubeconfig.
+type KubeconfigImport struct {
+	// Credentials are the platform credentials of the contexts
+	Credentials []*Credential
+	// Skipped explains the contexts that could not be converted
+	Skipped []string
+}
+
+// CredentialsFromKubeconfig converts the contexts of a kubeconfig into
+// platform credentials named after the contexts: bearer tokens, client
+// certificates, basic auth, and exec plugins, which are kept as is and run
+// when the credential is used. The kubeconfig is path, or the files of
+// KUBECONFIG or ~/.kube/config when empty. kubeContext selects one context.
+func CredentialsFromKubeconfig(path, kubeContext string) (*KubeconfigImport, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		rules.ExplicitPath = path
+	}
+	cfg, err := rules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	names := make([]string, 0, len(cfg.Contexts))
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if kubeContext != "" {
+		if _, ok := cfg.Contexts[kubeContext]; !ok {
+			return nil, fmt.Errorf("context %s not found in kubeconfig", kubeContext)
+		}
+		names = []string{kubeContext}
+	}
+
+	result := &KubeconfigImport{}
+	for _, name := range names {
+		cred, err := kubeconfigCredential(cfg, name)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		result.Credentials = append(result.Credentials, cred)
+	}
+	return result, nil
+}
+
+func kubeconfigCredential(cfg *clientcmdapi.Config, name string) (*Credential, error) {
+	kubeContext := cfg.Contexts[name]
+	cluster, ok := cfg.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", kubeContext.Cluster)
+	}
+	user, ok := cfg.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("user %s not found", kubeContext.AuthInfo)
+	}
+	if cluster.InsecureSkipTLSVerify {
+		return nil, fmt.Errorf("insecure-skip-tls-verify clusters are not imported: set certificate-authority")
+	}
+
+	now := time.Now()
+	cred := &Credential{
+		Name:      name,
+		Type:      CredentialTypePlatform,
+		Provider:  string(detectPlatform(cluster.Server, user)),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Metadata: CredentialMetadata{
+			Description: fmt.Sprintf("Imported from kubeconfig context %s", name),
+			URL:         cluster.Server,
+			KubeContext: name,
+		},
+	}
+
+	ca, err := fileOrData(cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate authority: %w", err)
+	}
+	cred.Data.CACert = ca
+
+	switch {
+	case user.Exec != nil:
+		cred.Method = MethodExec
+		exec := &ExecConfig{APIVersion: user.Exec.APIVersion, Command: user.Exec.Command, Args: user.Exec.Args}
+		for _, env := range user.Exec.Env {
+			if exec.Env == nil {
+				exec.Env = map[string]string{}
+			}
+			exec.Env[env.Name] = env.Value
+		}
+		cred.Data.Exec = exec
+	case user.Token != "" || user.TokenFile != "":
+		token, err := fileOrData([]byte(user.Token), user.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		cred.Method = MethodToken
+		cred.Data.Token = strings.TrimSpace(token)
+	case len(user.ClientCertificateData) > 0 || user.ClientCertificate != "":
+		cert, err := fileOrData(user.ClientCertificateData, user.ClientCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		key, err := fileOrData(user.ClientKeyData, user.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+		cred.Method = MethodClientCert
+		cred.Data.TLSCert = cert
+		cred.Data.TLSKey = key
+	case user.Username != "" && user.Password != "":
+		cred.Method = MethodBasic
+		cred.Data.Username = user.Username
+		cred.Data.Password = user.Password
+	case user.AuthProvider != nil:
+		return nil, fmt.Errorf("auth provider %s is not supported: use an exec plugin such as kubelogin", user.AuthProvider.Name)
+	default:
+		return nil, fmt.Errorf("user %s has no credentials", kubeContext.AuthInfo)
+	}
+	return cred, nil
+}
+
+// detectPlatform guesses the platform of a cluster from its API server and
+// exec plugin.
+func detectPlatform(server string, user *clientcmdapi.AuthInfo) PlatformType {
+	command := ""
+	if user.Exec != nil {
+		command = user.Exec.Command
+	}
+	switch {
+	case strings.Contains(server, ".eks.amazonaws.com") || strings.Contains(command, "aws"):
+		return PlatformAWS
+	case strings.Contains(server, ".azmk8s.io") || strings.Contains(command, "kubelogin"):
+		return PlatformAzure
+	case strings.Contains(command, "gke-gcloud-auth-plugin"):
+		return PlatformGCP
+	case strings.Contains(server, "openshift") || strings.HasPrefix(strings.TrimPrefix(server, "https://"), "api.") && strings.HasSuffix(server, ":6443"):
+		return PlatformOpenShift
+	default:
+		return PlatformKubernetes
+	}
+}
+
+func fileOrData(data []byte, path string) (string, error) {
+	if len(data) > 0 || path == "" {
+		return string(data), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// KubeconfigImportOptions holds options for importing kubeconfig contexts.
+type KubeconfigImportOptions struct {
+	// Path is the kubeconfig (default: KUBECONFIG or ~/.kube/config)
+	Path string
+	// Context selects one context
+	Context string
+	// Name renames the credential of Context
+	Name string
+	// Overwrite replaces existing credentials
+	Overwrite bool
+}
+
+// ImportKubeconfig stores the platform credentials of the contexts of a
+// kubeconfig (see CredentialsFromKubeconfig). Existing credentials are
+// skipped unless opts.Overwrite is set.
+func (m *Manager) ImportKubeconfig(ctx context.Context, opts *KubeconfigImportOptions) (*KubeconfigImport, error) {
+	if opts.Name != "" && opts.Context == "" {
+		return nil, fmt.Errorf("a context is required to name the imported credential")
+	}
+	result, err := CredentialsFromKubeconfig(opts.Path, opts.Context)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Name != "" {
+		for _, cred := range result.Credentials {
+			cred.Name = opts.Name
+		}
+	}
+	imported := result.Credentials[:0]
+	for _, cred := range result.Credentials {
+		exists, err := m.store.Exists(ctx, cred.Name)
+		if err != nil {
+			return nil, err
+		}
+		if exists && !opts.Overwrite {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: credential already exists", cred.Name))
+			continue
+		}
+		if err := m.store.Save(ctx, cred); err != nil {
+			return nil, fmt.Errorf("failed to save credential: %w", err)
+		}
+		imported = append(imported, cred)
+	}
+	result.Credentials = imported
+	return result, nil
+}
+
+// Kubeconfig renders a kubeconfig reaching the cluster of a platform
+// credential. Its only context is named after the context the credential was
+// imported from, or the credential.
+func (c *Credential) Kubeconfig() ([]byte, error) {
+	if c.Type != CredentialTypePlatform {
+		return nil, fmt.Errorf("credential %s is not a platform credential", c.Name)
+	}
+	if c.Metadata.URL == "" {
+		return nil, fmt.Errorf("credential %s has no cluster URL", c.Name)
+	}
+
+	user := clientcmdapi.NewAuthInfo()
+	switch c.Method {
+	case MethodToken, MethodServiceAccount:
+		user.Token = c.Data.Token
+	case MethodClientCert:
+		user.ClientCertificateData = []byte(c.Data.TLSCert)
+		user.ClientKeyData = []byte(c.Data.TLSKey)
+	case MethodBasic:
+		user.Username = c.Data.Username
+		user.Password = c.Data.Password
+	case MethodExec:
+		if c.Data.Exec == nil {
+			return nil, fmt.Errorf("credential %s has no exec plugin", c.Name)
+		}
+		user.Exec = &clientcmdapi.ExecConfig{
+			APIVersion:      c.Data.Exec.APIVersion,
+			Command:         c.Data.Exec.Command,
+			Args:            c.Data.Exec.Args,
+			InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+		}
+		envs := make([]string, 0, len(c.Data.Exec.Env))
+		for name := range c.Data.Exec.Env {
+			envs = append(envs, name)
+		}
+		sort.Strings(envs)
+		for _, name := range envs {
+			user.Exec.Env = append(user.Exec.Env, clientcmdapi.ExecEnvVar{Name: name, Value: c.Data.Exec.Env[name]})
+		}
+	default:
+		return nil, fmt.Errorf("%s credentials cannot be used to reach a cluster", c.Method)
+	}
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = c.Metadata.URL
+	cluster.CertificateAuthorityData = []byte(c.Data.CACert)
+
+	name := c.KubeContext()
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[name] = cluster
+	cfg.AuthInfos[name] = user
+	cfg.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	cfg.CurrentContext = name
+	data, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render kubeconfig: %w", err)
+	}
+	return data, nil
+}
+
+// KubeContext returns the context of the kubeconfig of a platform credential.
+func (c *Credential) KubeContext() string {
+	if c.Metadata.KubeContext != "" {
+		return c.Metadata.KubeContext
+	}
+	return c.Name
+}
+
+// WriteKubeconfig writes the kubeconfig of a platform credential to a
+// private temporary file. The returned function removes it.
+func WriteKubeconfig(cred *Credential) (string, func(), error) {
+	data, err := cred.Kubeconfig()
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := os.CreateTemp("", "gitopsi-kubeconfig-*.yaml")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create kubeconfig: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
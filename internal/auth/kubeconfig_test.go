@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	kubeconfig := `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+    certificate-authority-data: ` + b64("dev-ca") + `
+- name: eks
+  cluster:
+    server: https://ABC.gr7.eu-west-1.eks.amazonaws.com
+    certificate-authority-data: ` + b64("eks-ca") + `
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+    insecure-skip-tls-verify: true
+users:
+- name: dev-admin
+  user:
+    token: dev-token
+- name: cert-user
+  user:
+    client-certificate-data: ` + b64("client-cert") + `
+    client-key-data: ` + b64("client-key") + `
+- name: eks-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: [eks, get-token, --cluster-name, prod]
+      env:
+      - name: AWS_PROFILE
+        value: prod
+- name: gcp-user
+  user:
+    auth-provider:
+      name: gcp
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev-admin}
+- name: dev-cert
+  context: {cluster: dev, user: cert-user}
+- name: prod
+  context: {cluster: eks, user: eks-user}
+- name: legacy
+  context: {cluster: dev, user: gcp-user}
+- name: kind
+  context: {cluster: kind, user: dev-admin}
+`
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCredentialsFromKubeconfig(t *testing.T) {
+	path := writeTestKubeconfig(t)
+
+	result, err := CredentialsFromKubeconfig(path, "")
+	if err != nil {
+		t.Fatalf("CredentialsFromKubeconfig failed: %v", err)
+	}
+	creds := map[string]*Credential{}
+	for _, cred := range result.Credentials {
+		creds[cred.Name] = cred
+	}
+	if len(creds) != 3 {
+		t.Fatalf("expected 3 credentials, got %d (skipped %v)", len(creds), result.Skipped)
+	}
+	if len(result.Skipped) != 2 || !strings.HasPrefix(result.Skipped[0], "kind:") || !strings.HasPrefix(result.Skipped[1], "legacy:") {
+		t.Errorf("unexpected skipped contexts %v", result.Skipped)
+	}
+
+	if dev := creds["dev"]; dev.Method != MethodToken || dev.Data.Token != "dev-token" || dev.Data.CACert != "dev-ca" || dev.Metadata.URL != "https://dev.example.com:6443" {
+		t.Errorf("unexpected dev credential %+v", dev)
+	}
+	if cert := creds["dev-cert"]; cert.Method != MethodClientCert || cert.Data.TLSCert != "client-cert" || cert.Data.TLSKey != "client-key" {
+		t.Errorf("unexpected dev-cert credential %+v", cert)
+	}
+	prod := creds["prod"]
+	if prod.Method != MethodExec || prod.Provider != string(PlatformAWS) || prod.Data.Exec.Command != "aws" || prod.Data.Exec.Env["AWS_PROFILE"] != "prod" {
+		t.Errorf("unexpected prod credential %+v", prod)
+	}
+
+	if _, err := CredentialsFromKubeconfig(path, "missing"); err == nil {
+		t.Error("expected an error for a missing context")
+	}
+	result, err = CredentialsFromKubeconfig(path, "prod")
+	if err != nil || len(result.Credentials) != 1 || result.Credentials[0].Name != "prod" {
+		t.Errorf("CredentialsFromKubeconfig(prod) = %+v, %v", result, err)
+	}
+}
+
+func TestCredential_Kubeconfig(t *testing.T) {
+	result, err := CredentialsFromKubeconfig(writeTestKubeconfig(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cred := range result.Credentials {
+		cred.Name = "renamed-" + cred.Name
+		data, err := cred.Kubeconfig()
+		if err != nil {
+			t.Fatalf("Kubeconfig(%s) failed: %v", cred.Name, err)
+		}
+		cfg, err := clientcmd.Load(data)
+		if err != nil {
+			t.Fatalf("rendered kubeconfig of %s does not load: %v", cred.Name, err)
+		}
+		kubeContext := cred.KubeContext()
+		if cfg.CurrentContext != kubeContext || cfg.Clusters[kubeContext].Server != cred.Metadata.URL {
+			t.Errorf("unexpected kubeconfig for %s: %+v", cred.Name, cfg)
+		}
+		user := cfg.AuthInfos[kubeContext]
+		switch cred.Method {
+		case MethodToken:
+			if user.Token != "dev-token" {
+				t.Errorf("token = %q", user.Token)
+			}
+		case MethodClientCert:
+			if string(user.ClientKeyData) != "client-key" {
+				t.Errorf("client key = %q", user.ClientKeyData)
+			}
+		case MethodExec:
+			if user.Exec == nil || user.Exec.Command != "aws" || len(user.Exec.Args) != 4 {
+				t.Errorf("exec = %+v", user.Exec)
+			}
+		}
+	}
+
+	if _, err := (&Credential{Name: "git", Type: CredentialTypeGit}).Kubeconfig(); err == nil {
+		t.Error("Kubeconfig should fail for git credentials")
+	}
+	irsa := &Credential{Name: "aws", Type: CredentialTypePlatform, Method: MethodAWSIRSA, Metadata: CredentialMetadata{URL: "https://x"}}
+	if _, err := irsa.Kubeconfig(); err == nil {
+		t.Error("Kubeconfig should fail for IRSA credentials")
+	}
+}
+
+func TestManager_ImportKubeconfig(t *testing.T) {
+	path := writeTestKubeconfig(t)
+	manager := NewManager(NewMemoryStore(), SecretFormatPlain)
+	ctx := context.Background()
+
+	result, err := manager.ImportKubeconfig(ctx, &KubeconfigImportOptions{Path: path, Context: "dev", Name: "dev-cluster"})
+	if err != nil || len(result.Credentials) != 1 {
+		t.Fatalf("ImportKubeconfig = %+v, %v", result, err)
+	}
+	cred, err := manager.GetCredential(ctx, "dev-cluster")
+	if err != nil || cred.KubeContext() != "dev" {
+		t.Errorf("GetCredential(dev-cluster) = %+v, %v", cred, err)
+	}
+
+	result, err = manager.ImportKubeconfig(ctx, &KubeconfigImportOptions{Path: path, Context: "dev", Name: "dev-cluster"})
+	if err != nil || len(result.Credentials) != 0 || len(result.Skipped) != 1 {
+		t.Errorf("re-import should skip the existing credential: %+v, %v", result, err)
+	}
+	result, err = manager.ImportKubeconfig(ctx, &KubeconfigImportOptions{Path: path, Context: "dev", Name: "dev-cluster", Overwrite: true})
+	if err != nil || len(result.Credentials) != 1 {
+		t.Errorf("ImportKubeconfig with Overwrite = %+v, %v", result, err)
+	}
+
+	if _, err := manager.ImportKubeconfig(ctx, &KubeconfigImportOptions{Path: path, Name: "x"}); err == nil {
+		t.Error("Name without Context should fail")
+	}
+}
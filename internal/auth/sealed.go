@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// Sealed Secrets scopes, see https://github.com/bitnami-labs/sealed-secrets#scopes.
+const (
+	SealScopeStrict        = "strict"
+	SealScopeNamespaceWide = "namespace-wide"
+	SealScopeClusterWide   = "cluster-wide"
+)
+
+// SealOptions configures how secrets are sealed with kubeseal.
+type SealOptions struct {
+	// Context is the kubeconfig context of the target cluster.
+	Context string
+	// Kubeconfig is an optional kubeconfig path.
+	Kubeconfig string
+	// Cert is a path or URL to the controller's public certificate. When set,
+	// sealing works offline without contacting the cluster.
+	Cert string
+	// ControllerNamespace is where the sealed-secrets controller runs (default: kube-system).
+	ControllerNamespace string
+	// ControllerName is the controller service name (default: sealed-secrets-controller).
+	ControllerName string
+	// Scope is strict, namespace-wide, or cluster-wide (default: strict).
+	Scope string
+}
+
+// SealedSecretsEncrypter converts Secrets into SealedSecret custom resources
+// with the kubeseal CLI, so they can only be decrypted by the target cluster.
+type SealedSecretsEncrypter struct {
+	opts *SealOptions
+	run  commandRunner
+}
+
+// NewSealedSecretsEncrypter creates a sealer for the target cluster.
+func NewSealedSecretsEncrypter(opts *SealOptions) (*SealedSecretsEncrypter, error) {
+	if opts == nil {
+		opts = &SealOptions{}
+	}
+
+	switch opts.Scope {
+	case "", SealScopeStrict, SealScopeNamespaceWide, SealScopeClusterWide:
+	default:
+		return nil, fmt.Errorf("invalid seal scope: %s (must be %s, %s, or %s)", opts.Scope, SealScopeStrict, SealScopeNamespaceWide, SealScopeClusterWide)
+	}
+
+	return &SealedSecretsEncrypter{opts: opts, run: runWithStdin}, nil
+}
+
+// Args returns the kubeseal command line.
+func (e *SealedSecretsEncrypter) Args() []string {
+	args := []string{"--format", "yaml"}
+
+	if e.opts.Cert != "" {
+		args = append(args, "--cert", e.opts.Cert)
+	} else {
+		if e.opts.Kubeconfig != "" {
+			args = append(args, "--kubeconfig", e.opts.Kubeconfig)
+		}
+		if e.opts.Context != "" {
+			args = append(args, "--context", e.opts.Context)
+		}
+	}
+
+	if e.opts.ControllerNamespace != "" {
+		args = append(args, "--controller-namespace", e.opts.ControllerNamespace)
+	}
+	if e.opts.ControllerName != "" {
+		args = append(args, "--controller-name", e.opts.ControllerName)
+	}
+	if e.opts.Scope != "" {
+		args = append(args, "--scope", e.opts.Scope)
+	}
+
+	return args
+}
+
+// Encrypt pipes the manifest through kubeseal and returns the SealedSecret.
+func (e *SealedSecretsEncrypter) Encrypt(ctx context.Context, manifest []byte) ([]byte, error) {
+	out, err := e.run(ctx, manifest, "kubeseal", e.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("kubeseal failed: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewSealedSecretsEncrypter_InvalidScope(t *testing.T) {
+	if _, err := NewSealedSecretsEncrypter(&SealOptions{Scope: "global"}); err == nil {
+		t.Error("expected error for invalid scope")
+	}
+}
+
+func TestSealedSecretsEncrypter_Args(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *SealOptions
+		want string
+	}{
+		{
+			name: "cluster context",
+			opts: &SealOptions{Context: "prod", Kubeconfig: "/kc", Scope: SealScopeNamespaceWide},
+			want: "--format yaml --kubeconfig /kc --context prod --scope namespace-wide",
+		},
+		{
+			name: "offline cert ignores context",
+			opts: &SealOptions{Context: "prod", Cert: "pub.pem", ControllerNamespace: "sealed", ControllerName: "ctrl"},
+			want: "--format yaml --cert pub.pem --controller-namespace sealed --controller-name ctrl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := NewSealedSecretsEncrypter(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(enc.Args(), " "); got != tt.want {
+				t.Errorf("Args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_GenerateSealedSecret(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if err := store.Save(ctx, testCredential("gh")); err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := NewSealedSecretsEncrypter(&SealOptions{Context: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdinSeen string
+	enc.run = func(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+		if name != "kubeseal" {
+			t.Errorf("ran %s, want kubeseal", name)
+		}
+		stdinSeen = string(stdin)
+		return []byte("apiVersion: bitnami.com/v1alpha1\nkind: SealedSecret\n"), nil
+	}
+
+	manager := NewManager(store, SecretFormatSealed)
+	manager.SetEncrypter(enc)
+
+	out, err := manager.GenerateArgoCDRepoSecret(ctx, "gh", "argocd")
+	if err != nil {
+		t.Fatalf("GenerateArgoCDRepoSecret() error = %v", err)
+	}
+	if !strings.Contains(out, "kind: SealedSecret") {
+		t.Errorf("output = %q, want SealedSecret", out)
+	}
+	if !strings.Contains(stdinSeen, "argocd.argoproj.io/secret-type: repository") {
+		t.Error("kubeseal should receive the ArgoCD repository secret on stdin")
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultSopsEncryptedRegex limits SOPS encryption to Secret payload fields so
+// metadata stays readable in Git.
+const DefaultSopsEncryptedRegex = "^(data|stringData)$"
+
+// SecretEncrypter turns a plaintext Secret manifest into a form that is safe to commit.
+type SecretEncrypter interface {
+	Encrypt(ctx context.Context, manifest []byte) ([]byte, error)
+}
+
+// commandRunner runs an external command with stdin and returns its stdout.
+type commandRunner func(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error)
+
+// SopsOptions lists the recipients used to encrypt secrets with SOPS.
+type SopsOptions struct {
+	Age           []string
+	PGP           []string
+	KMS           []string
+	GCPKMS        []string
+	AzureKeyVault []string
+	// EncryptedRegex selects the keys to encrypt (default: DefaultSopsEncryptedRegex)
+	EncryptedRegex string
+}
+
+// HasKeys reports whether at least one recipient is configured.
+func (o *SopsOptions) HasKeys() bool {
+	return len(o.Age)+len(o.PGP)+len(o.KMS)+len(o.GCPKMS)+len(o.AzureKeyVault) > 0
+}
+
+// SopsEncrypter encrypts manifests with the sops CLI.
+type SopsEncrypter struct {
+	opts *SopsOptions
+	run  commandRunner
+}
+
+// NewSopsEncrypter creates an encrypter for the given recipients.
+func NewSopsEncrypter(opts *SopsOptions) (*SopsEncrypter, error) {
+	if opts == nil || !opts.HasKeys() {
+		return nil, fmt.Errorf("sops requires at least one age, pgp, kms, gcp-kms, or azure-kv key")
+	}
+	return &SopsEncrypter{opts: opts, run: runWithStdin}, nil
+}
+
+// Args returns the sops command line used to encrypt the file at path.
+func (e *SopsEncrypter) Args(path string) []string {
+	regex := e.opts.EncryptedRegex
+	if regex == "" {
+		regex = DefaultSopsEncryptedRegex
+	}
+
+	args := []string{"--encrypt", "--input-type", "yaml", "--output-type", "yaml", "--encrypted-regex", regex}
+	recipients := []struct {
+		flag string
+		keys []string
+	}{
+		{"--age", e.opts.Age},
+		{"--pgp", e.opts.PGP},
+		{"--kms", e.opts.KMS},
+		{"--gcp-kms", e.opts.GCPKMS},
+		{"--azure-kv", e.opts.AzureKeyVault},
+	}
+	for _, r := range recipients {
+		if len(r.keys) > 0 {
+			args = append(args, r.flag, strings.Join(r.keys, ","))
+		}
+	}
+	return append(args, path)
+}
+
+// Encrypt writes the manifest to a private temporary file, encrypts it with
+// sops and returns the encrypted document.
+func (e *SopsEncrypter) Encrypt(ctx context.Context, manifest []byte) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "gitopsi-secret-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(manifest); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	out, err := e.run(ctx, nil, "sops", e.Args(tmp.Name())...)
+	if err != nil {
+		return nil, fmt.Errorf("sops encryption failed: %w", err)
+	}
+	return out, nil
+}
+
+// SetEncrypter configures how generated secrets are encrypted for the
+// manager's secret format.
+func (m *Manager) SetEncrypter(enc SecretEncrypter) {
+	m.encrypter = enc
+}
+
+// encodeSecret applies the manager's secret format to a plaintext manifest.
+func (m *Manager) encodeSecret(ctx context.Context, manifest string) (string, error) {
+	switch m.secretFormat {
+	case "", SecretFormatPlain:
+		return manifest, nil
+	case SecretFormatSops, SecretFormatSealed:
+		if m.encrypter == nil {
+			return "", fmt.Errorf("secret format %s requires an encrypter", m.secretFormat)
+		}
+		out, err := m.encrypter.Encrypt(ctx, []byte(manifest))
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	default:
+		return "", fmt.Errorf("unsupported secret format: %s", m.secretFormat)
+	}
+}
+
+func runWithStdin(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
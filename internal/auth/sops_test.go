@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewSopsEncrypter_RequiresKeys(t *testing.T) {
+	if _, err := NewSopsEncrypter(&SopsOptions{}); err == nil {
+		t.Error("expected error without recipients")
+	}
+	if _, err := NewSopsEncrypter(nil); err == nil {
+		t.Error("expected error for nil options")
+	}
+}
+
+func TestSopsEncrypter_Args(t *testing.T) {
+	enc, err := NewSopsEncrypter(&SopsOptions{
+		Age: []string{"age1abc", "age1def"},
+		KMS: []string{"arn:aws:kms:eu-west-1:111:key/x"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(enc.Args("/tmp/secret.yaml"), " ")
+	want := "--encrypt --input-type yaml --output-type yaml --encrypted-regex ^(data|stringData)$ " +
+		"--age age1abc,age1def --kms arn:aws:kms:eu-west-1:111:key/x /tmp/secret.yaml"
+	if got != want {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+}
+
+func TestSopsEncrypter_Encrypt(t *testing.T) {
+	enc, err := NewSopsEncrypter(&SopsOptions{Age: []string{"age1abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tmpPath, tmpContent string
+	enc.run = func(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+		if name != "sops" {
+			t.Errorf("ran %s, want sops", name)
+		}
+		tmpPath = args[len(args)-1]
+		data, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return nil, err
+		}
+		tmpContent = string(data)
+		return []byte("stringData:\n    password: ENC[AES256_GCM,data:xyz]\nsops:\n    age: []\n"), nil
+	}
+
+	out, err := enc.Encrypt(context.Background(), []byte("stringData:\n  password: hunter2\n"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.Contains(string(out), "ENC[AES256_GCM") {
+		t.Errorf("Encrypt() = %s, want sops output", out)
+	}
+	if !strings.Contains(tmpContent, "hunter2") {
+		t.Error("sops should receive the plaintext manifest")
+	}
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Error("temporary plaintext file should be removed")
+	}
+}
+
+type fakeEncrypter struct {
+	input []byte
+	err   error
+}
+
+func (f *fakeEncrypter) Encrypt(ctx context.Context, manifest []byte) ([]byte, error) {
+	f.input = manifest
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte("encrypted: true\n"), nil
+}
+
+func TestManager_GenerateSecretsWithSops(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	cred := testCredential("gh")
+	cred.Metadata.URL = "https://github.com/org/repo.git"
+	if err := store.Save(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(store, SecretFormatSops)
+	if _, err := manager.GenerateKubernetesSecret(ctx, "gh"); err == nil {
+		t.Error("expected error when sops format has no encrypter")
+	}
+
+	enc := &fakeEncrypter{}
+	manager.SetEncrypter(enc)
+
+	generators := map[string]func() (string, error){
+		"kubernetes": func() (string, error) { return manager.GenerateKubernetesSecret(ctx, "gh") },
+		"argocd":     func() (string, error) { return manager.GenerateArgoCDRepoSecret(ctx, "gh", "argocd") },
+		"flux":       func() (string, error) { return manager.GenerateFluxGitRepositorySecret(ctx, "gh", "flux-system") },
+	}
+	for name, generate := range generators {
+		out, err := generate()
+		if err != nil {
+			t.Fatalf("%s: error = %v", name, err)
+		}
+		if out != "encrypted: true\n" {
+			t.Errorf("%s: output = %q, want encrypted output", name, out)
+		}
+		if !strings.Contains(string(enc.input), "ghp_secret_token") {
+			t.Errorf("%s: encrypter did not receive the plaintext secret", name)
+		}
+	}
+
+	enc.err = errors.New("no key")
+	if _, err := manager.GenerateKubernetesSecret(ctx, "gh"); err == nil {
+		t.Error("expected encrypter error to propagate")
+	}
+}
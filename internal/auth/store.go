@@ -10,9 +10,11 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// FileStore implements Store using file-based storage.
+// FileStore implements Store using file-based storage. When created with
+// NewEncryptedFileStore the file is encrypted at rest.
 type FileStore struct {
 	path        string
+	passphrase  string
 	mu          sync.RWMutex
 	credentials map[string]*Credential
 }
@@ -34,6 +36,16 @@ func NewFileStore(path string) (*FileStore, error) {
 	return store, nil
 }
 
+// Path returns the location of the store file.
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Encrypted reports whether the store is encrypted at rest.
+func (s *FileStore) Encrypted() bool {
+	return s.passphrase != ""
+}
+
 // Save stores a credential.
 func (s *FileStore) Save(ctx context.Context, cred *Credential) error {
 	s.mu.Lock()
@@ -97,6 +109,15 @@ func (s *FileStore) load() error {
 		return err
 	}
 
+	if isEncrypted(data) {
+		if s.passphrase == "" {
+			return ErrStoreEncrypted
+		}
+		if data, err = decryptData(data, s.passphrase); err != nil {
+			return err
+		}
+	}
+
 	var fileData struct {
 		Credentials []*Credential `yaml:"credentials"`
 	}
@@ -135,6 +156,12 @@ func (s *FileStore) persist() error {
 		return err
 	}
 
+	if s.passphrase != "" {
+		if data, err = encryptData(data, s.passphrase); err != nil {
+			return fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+	}
+
 	// Write with restricted permissions (owner only)
 	return os.WriteFile(s.path, data, 0600)
 }
@@ -217,3 +244,22 @@ func GetDefaultStorePath() string {
 	}
 	return filepath.Join(home, ".gitopsi", "credentials.yaml")
 }
+
+// Credential store backends, selected by the auth.store user setting.
+const (
+	StoreFile    = "file"
+	StoreKeyring = "keyring"
+)
+
+// OpenStore opens the credential store of a backend: the OS keyring, or the
+// file store at path (default: GetDefaultStorePath), unlocked with the
+// passphrase of PassphraseEnvVar when encrypted.
+func OpenStore(backend, path string) (Store, error) {
+	if backend == StoreKeyring {
+		return NewKeyringStore(""), nil
+	}
+	if path == "" {
+		path = GetDefaultStorePath()
+	}
+	return OpenFileStore(path, os.Getenv(PassphraseEnvVar))
+}
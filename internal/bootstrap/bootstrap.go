@@ -3,13 +3,18 @@ package bootstrap
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+	"github.com/ihsanmokhlisse/gitopsi/internal/telemetry"
 )
 
 // Tool represents the GitOps tool to bootstrap.
@@ -32,12 +37,13 @@ const (
 
 // HelmConfig holds Helm-specific configuration.
 type HelmConfig struct {
-	Repo      string            `yaml:"repo"`
-	Chart     string            `yaml:"chart"`
-	Version   string            `yaml:"version"`
-	Namespace string            `yaml:"namespace"`
-	Values    map[string]any    `yaml:"values"`
-	SetValues map[string]string `yaml:"set_values"`
+	Repo        string            `yaml:"repo"`
+	Chart       string            `yaml:"chart"`
+	Version     string            `yaml:"version"`
+	Namespace   string            `yaml:"namespace"`
+	Values      map[string]any    `yaml:"values"`
+	ValuesFiles []string          `yaml:"values_files"`
+	SetValues   map[string]string `yaml:"set_values"`
 }
 
 // OLMConfig holds OLM-specific configuration.
@@ -78,6 +84,22 @@ type Options struct {
 	SyncInitial     bool
 	ProjectName     string
 
+	// RepoCredentials are added to the repository configuration so private
+	// repositories sync right away.
+	RepoCredentials *RepoCredentials
+	// Repositories are added to ArgoCD with the repository, such as the
+	// application repositories of a hub-and-spoke layout.
+	Repositories []Repository
+
+	// HA installs ArgoCD in high availability: redis-ha and replicated
+	// components, from the HA chart values, manifests or kustomization.
+	HA bool
+
+	// Offline installs from Manifests and never reaches the network. Every
+	// mode but OLM applies the vendored install manifest.
+	Offline   bool
+	Manifests ManifestSource
+
 	// Mode-specific configurations
 	Helm      *HelmConfig      `yaml:"helm,omitempty"`
 	OLM       *OLMConfig       `yaml:"olm,omitempty"`
@@ -94,12 +116,15 @@ type Result struct {
 	Namespace string
 	Ready     bool
 	Message   string
+	Release   *ReleaseStatus
 }
 
 // Bootstrapper handles GitOps tool installation.
 type Bootstrapper struct {
 	cluster *cluster.Cluster
 	options *Options
+	helm    HelmInstaller
+	release *ReleaseStatus
 }
 
 // New creates a new Bootstrapper instance.
@@ -117,16 +142,49 @@ func New(c *cluster.Cluster, opts *Options) *Bootstrapper {
 	return &Bootstrapper{
 		cluster: c,
 		options: opts,
+		helm:    NewHelmInstaller(c),
 	}
 }
 
+// SetHelmInstaller overrides the Helm installer used for helm mode installs.
+func (b *Bootstrapper) SetHelmInstaller(h HelmInstaller) {
+	b.helm = h
+}
+
 // Bootstrap installs and configures the GitOps tool.
 func (b *Bootstrapper) Bootstrap(ctx context.Context) (*Result, error) {
+	ctx, end := telemetry.Start(ctx, "bootstrap",
+		attribute.String("tool", string(b.options.Tool)),
+		attribute.String("mode", string(b.options.Mode)),
+		attribute.Bool("ha", b.options.HA),
+	)
+	result, err := b.bootstrap(ctx)
+	end(err)
+	return result, err
+}
+
+func (b *Bootstrapper) bootstrap(ctx context.Context) (*Result, error) {
 	result := &Result{
 		Tool:      b.options.Tool,
 		Namespace: b.options.Namespace,
 	}
 
+	if err := b.validateHA(); err != nil {
+		return nil, err
+	}
+	// Read the Helm values files before touching the cluster
+	if b.options.Mode == ModeHelm && !b.options.Offline {
+		helmCfg := b.helmConfig()
+		if _, err := MergeHelmValues(helmCfg.ValuesFiles, helmCfg.Values, helmCfg.SetValues); err != nil {
+			return nil, err
+		}
+	}
+	if b.options.HA {
+		if _, err := CheckHACapacity(ctx, b.cluster); err != nil {
+			return nil, fmt.Errorf("cluster cannot run an HA installation: %w", err)
+		}
+	}
+
 	// Create namespace
 	if err := b.cluster.CreateNamespace(ctx, b.options.Namespace); err != nil {
 		return nil, fmt.Errorf("failed to create namespace: %w", err)
@@ -135,11 +193,11 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) (*Result, error) {
 	// Install GitOps tool
 	switch b.options.Tool {
 	case ToolArgoCD:
-		if err := b.installArgoCD(ctx); err != nil {
+		if err := telemetry.Run(ctx, "bootstrap.install", b.installArgoCD); err != nil {
 			return nil, fmt.Errorf("failed to install ArgoCD: %w", err)
 		}
 	case ToolFlux:
-		if err := b.installFlux(ctx); err != nil {
+		if err := telemetry.Run(ctx, "bootstrap.install", b.installFlux); err != nil {
 			return nil, fmt.Errorf("failed to install Flux: %w", err)
 		}
 	default:
@@ -148,16 +206,17 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) (*Result, error) {
 
 	// Wait for GitOps tool to be ready
 	if b.options.Wait {
-		if err := b.waitForReady(ctx); err != nil {
+		if err := telemetry.Run(ctx, "bootstrap.wait", b.waitForReady); err != nil {
 			return nil, fmt.Errorf("GitOps tool not ready: %w", err)
 		}
 	}
 
 	result.Ready = true
+	result.Release = b.release
 
 	// Configure repository
 	if b.options.ConfigureRepo && b.options.RepoURL != "" {
-		if err := b.configureRepository(ctx); err != nil {
+		if err := telemetry.Run(ctx, "bootstrap.repository", b.configureRepository); err != nil {
 			return nil, fmt.Errorf("failed to configure repository: %w", err)
 		}
 	}
@@ -171,7 +230,7 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) (*Result, error) {
 
 	// Create App-of-Apps
 	if b.options.CreateAppOfApps {
-		if err := b.createAppOfApps(ctx); err != nil {
+		if err := telemetry.Run(ctx, "bootstrap.app_of_apps", b.createAppOfApps); err != nil {
 			return nil, fmt.Errorf("failed to create App-of-Apps: %w", err)
 		}
 	}
@@ -192,6 +251,9 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) (*Result, error) {
 
 // installArgoCD installs ArgoCD using the specified mode.
 func (b *Bootstrapper) installArgoCD(ctx context.Context) error {
+	if b.options.Offline && b.options.Mode != ModeOLM {
+		return b.installOffline(ctx)
+	}
 	switch b.options.Mode {
 	case ModeHelm:
 		return b.installArgoCDHelm(ctx)
@@ -208,51 +270,32 @@ func (b *Bootstrapper) installArgoCD(ctx context.Context) error {
 
 // installArgoCDHelm installs ArgoCD using Helm.
 func (b *Bootstrapper) installArgoCDHelm(ctx context.Context) error {
-	helmCfg := b.getArgoCDHelmConfig()
-
-	// Add ArgoCD Helm repo
-	cmd := exec.CommandContext(ctx, "helm", "repo", "add", "argo", helmCfg.Repo)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		if !strings.Contains(string(output), "already exists") {
-			return fmt.Errorf("failed to add Helm repo: %w: %s", err, string(output))
-		}
-	}
-
-	// Update Helm repos
-	cmd = exec.CommandContext(ctx, "helm", "repo", "update")
-	if _, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to update Helm repos: %w", err)
-	}
-
-	// Install ArgoCD
-	args := []string{
-		"upgrade", "--install", "argocd", fmt.Sprintf("argo/%s", helmCfg.Chart),
-		"--namespace", b.options.Namespace,
-		"--create-namespace",
-		"--wait",
-	}
+	return b.installHelmRelease(ctx, "argocd", b.getArgoCDHelmConfig())
+}
 
-	if helmCfg.Version != "" {
-		args = append(args, "--version", helmCfg.Version)
-	} else if b.options.Version != "" {
-		args = append(args, "--version", b.options.Version)
-	}
+// installArgoCDManifest installs ArgoCD using manifests.
+func (b *Bootstrapper) installArgoCDManifest(ctx context.Context) error {
+	manifests := b.argoCDManifests()
 
-	// Add set values
-	for k, v := range helmCfg.SetValues {
-		args = append(args, "--set", fmt.Sprintf("%s=%s", k, v))
+	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-n", b.options.Namespace, "-f", manifests[0])
+	if output, err := logging.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("failed to apply ArgoCD manifests: %w: %s", err, string(output))
 	}
 
-	cmd = exec.CommandContext(ctx, "helm", args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to install ArgoCD: %w: %s", err, string(output))
+	// Apply additional manifests if specified
+	for _, path := range manifests[1:] {
+		cmd = exec.CommandContext(ctx, "kubectl", "apply", "-n", b.options.Namespace, "-f", path)
+		if output, err := logging.CombinedOutput(cmd); err != nil {
+			return fmt.Errorf("failed to apply manifest %s: %w: %s", path, err, string(output))
+		}
 	}
 
 	return nil
 }
 
-// installArgoCDManifest installs ArgoCD using manifests.
-func (b *Bootstrapper) installArgoCDManifest(ctx context.Context) error {
+// argoCDManifests returns the ArgoCD install manifest URL followed by the
+// additional manifests of manifest mode.
+func (b *Bootstrapper) argoCDManifests() []string {
 	manifestCfg := b.getArgoCDManifestConfig()
 
 	manifestURL := manifestCfg.URL
@@ -261,37 +304,33 @@ func (b *Bootstrapper) installArgoCDManifest(ctx context.Context) error {
 		if version == "" {
 			version = "stable"
 		}
-		manifestURL = fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/manifests/install.yaml", version)
-	}
-
-	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-n", b.options.Namespace, "-f", manifestURL)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to apply ArgoCD manifests: %w: %s", err, string(output))
+		manifestURL = fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/%s/install.yaml", version, b.argoCDManifestDir())
 	}
-
-	// Apply additional manifests if specified
-	for _, path := range manifestCfg.Paths {
-		cmd = exec.CommandContext(ctx, "kubectl", "apply", "-n", b.options.Namespace, "-f", path)
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("failed to apply manifest %s: %w: %s", path, err, string(output))
-		}
-	}
-
-	return nil
+	return append([]string{manifestURL}, manifestCfg.Paths...)
 }
 
 // installArgoCDOLM installs ArgoCD using OLM.
 func (b *Bootstrapper) installArgoCDOLM(ctx context.Context) error {
-	olmCfg := b.getArgoCDOLMConfig()
-
 	// Check if OLM is installed
 	cmd := exec.CommandContext(ctx, "kubectl", "get", "crd", "subscriptions.operators.coreos.com")
-	if _, err := cmd.CombinedOutput(); err != nil {
+	if _, err := logging.CombinedOutput(cmd); err != nil {
 		return fmt.Errorf("OLM not installed on cluster. OLM is required for this installation mode")
 	}
 
-	// Create OperatorGroup
-	operatorGroup := fmt.Sprintf(`apiVersion: operators.coreos.com/v1
+	operatorGroup, subscription := b.argoCDOLMManifests()
+	if err := b.cluster.Apply(ctx, operatorGroup); err != nil {
+		return fmt.Errorf("failed to create OperatorGroup: %w", err)
+	}
+
+	return b.cluster.Apply(ctx, subscription)
+}
+
+// argoCDOLMManifests returns the OperatorGroup and Subscription installing
+// the ArgoCD operator.
+func (b *Bootstrapper) argoCDOLMManifests() (operatorGroup, subscription string) {
+	olmCfg := b.getArgoCDOLMConfig()
+
+	operatorGroup = fmt.Sprintf(`apiVersion: operators.coreos.com/v1
 kind: OperatorGroup
 metadata:
   name: argocd-operator
@@ -300,12 +339,7 @@ spec:
   targetNamespaces:
     - %s`, b.options.Namespace, b.options.Namespace)
 
-	if err := b.cluster.Apply(ctx, operatorGroup); err != nil {
-		return fmt.Errorf("failed to create OperatorGroup: %w", err)
-	}
-
-	// Create ArgoCD subscription
-	subscription := fmt.Sprintf(`apiVersion: operators.coreos.com/v1alpha1
+	subscription = fmt.Sprintf(`apiVersion: operators.coreos.com/v1alpha1
 kind: Subscription
 metadata:
   name: argocd-operator
@@ -317,11 +351,14 @@ spec:
   sourceNamespace: %s
   installPlanApproval: %s`, b.options.Namespace, olmCfg.Channel, olmCfg.Source, olmCfg.SourceNamespace, olmCfg.Approval)
 
-	return b.cluster.Apply(ctx, subscription)
+	return operatorGroup, subscription
 }
 
 // installFlux installs Flux using the specified mode.
 func (b *Bootstrapper) installFlux(ctx context.Context) error {
+	if b.options.Offline {
+		return b.installOffline(ctx)
+	}
 	switch b.options.Mode {
 	case ModeManifest:
 		return b.installFluxManifest(ctx)
@@ -337,7 +374,7 @@ func (b *Bootstrapper) installFlux(ctx context.Context) error {
 // installFluxManifest installs Flux using manifests.
 func (b *Bootstrapper) installFluxManifest(ctx context.Context) error {
 	cmd := exec.CommandContext(ctx, "flux", "install", "--namespace", b.options.Namespace)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := logging.CombinedOutput(cmd); err != nil {
 		return fmt.Errorf("failed to install Flux: %w: %s", err, string(output))
 	}
 	return nil
@@ -345,63 +382,41 @@ func (b *Bootstrapper) installFluxManifest(ctx context.Context) error {
 
 // installFluxHelm installs Flux using Helm.
 func (b *Bootstrapper) installFluxHelm(ctx context.Context) error {
-	helmCfg := b.getFluxHelmConfig()
-
-	// Add Flux Helm repo
-	cmd := exec.CommandContext(ctx, "helm", "repo", "add", "fluxcd", helmCfg.Repo)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		if !strings.Contains(string(output), "already exists") {
-			return fmt.Errorf("failed to add Flux Helm repo: %w: %s", err, string(output))
-		}
-	}
-
-	// Update Helm repos
-	cmd = exec.CommandContext(ctx, "helm", "repo", "update")
-	if _, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to update Helm repos: %w", err)
-	}
-
-	// Install Flux
-	args := []string{
-		"upgrade", "--install", "flux2", fmt.Sprintf("fluxcd/%s", helmCfg.Chart),
-		"--namespace", b.options.Namespace,
-		"--create-namespace",
-		"--wait",
-	}
-
-	if helmCfg.Version != "" {
-		args = append(args, "--version", helmCfg.Version)
-	} else if b.options.Version != "" {
-		args = append(args, "--version", b.options.Version)
-	}
-
-	// Add set values
-	for k, v := range helmCfg.SetValues {
-		args = append(args, "--set", fmt.Sprintf("%s=%s", k, v))
-	}
+	return b.installHelmRelease(ctx, "flux2", b.getFluxHelmConfig())
+}
 
-	cmd = exec.CommandContext(ctx, "helm", args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to install Flux: %w: %s", err, string(output))
+// installHelmRelease installs or upgrades a chart release through the Helm SDK.
+func (b *Bootstrapper) installHelmRelease(ctx context.Context, name string, helmCfg *HelmConfig) error {
+	version := helmCfg.Version
+	if version == "" {
+		version = b.options.Version
+	}
+
+	status, err := b.helm.InstallOrUpgrade(ctx, &HelmRelease{
+		Name:          name,
+		RepoURL:       helmCfg.Repo,
+		Chart:         helmCfg.Chart,
+		Version:       version,
+		Namespace:     b.options.Namespace,
+		Values:        helmCfg.Values,
+		ValuesFiles:   helmCfg.ValuesFiles,
+		SetValues:     helmCfg.SetValues,
+		ProfileValues: b.profileValues(),
+		Wait:          true,
+		Timeout:       time.Duration(b.options.Timeout) * time.Second,
+	})
+	if err != nil {
+		return err
 	}
 
+	b.release = status
 	return nil
 }
 
 // installArgoCDKustomize installs ArgoCD using Kustomize.
 func (b *Bootstrapper) installArgoCDKustomize(ctx context.Context) error {
-	kustomizeCfg := b.getArgoCDKustomizeConfig()
-
-	kustomizeURL := kustomizeCfg.URL
-	if kustomizeURL == "" {
-		kustomizeURL = "https://github.com/argoproj/argo-cd/manifests/cluster-install"
-		if kustomizeCfg.Path != "" {
-			kustomizeURL = fmt.Sprintf("https://github.com/argoproj/argo-cd/manifests/%s", kustomizeCfg.Path)
-		}
-	}
-
-	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-k", kustomizeURL, "-n", b.options.Namespace)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-k", b.argoCDKustomizeURL(), "-n", b.options.Namespace)
+	if output, err := logging.CombinedOutput(cmd); err != nil {
 		return fmt.Errorf("failed to apply ArgoCD Kustomize: %w: %s", err, string(output))
 	}
 
@@ -410,22 +425,44 @@ func (b *Bootstrapper) installArgoCDKustomize(ctx context.Context) error {
 
 // installFluxKustomize installs Flux using Kustomize.
 func (b *Bootstrapper) installFluxKustomize(ctx context.Context) error {
-	kustomizeCfg := b.getFluxKustomizeConfig()
+	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-k", b.fluxKustomizeURL(), "-n", b.options.Namespace)
+	if output, err := logging.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("failed to apply Flux Kustomize: %w: %s", err, string(output))
+	}
 
-	kustomizeURL := kustomizeCfg.URL
-	if kustomizeURL == "" {
-		kustomizeURL = "https://github.com/fluxcd/flux2/manifests/install"
-		if kustomizeCfg.Path != "" {
-			kustomizeURL = fmt.Sprintf("https://github.com/fluxcd/flux2/manifests/%s", kustomizeCfg.Path)
-		}
+	return nil
+}
+
+// argoCDKustomizeURL returns the kustomization applied in kustomize mode.
+func (b *Bootstrapper) argoCDKustomizeURL() string {
+	kustomizeCfg := b.getArgoCDKustomizeConfig()
+	if kustomizeCfg.URL != "" {
+		return kustomizeCfg.URL
 	}
+	if kustomizeCfg.Path != "" {
+		return fmt.Sprintf("https://github.com/argoproj/argo-cd/manifests/%s", kustomizeCfg.Path)
+	}
+	return fmt.Sprintf("https://github.com/argoproj/argo-cd/%s/cluster-install", b.argoCDManifestDir())
+}
 
-	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-k", kustomizeURL, "-n", b.options.Namespace)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to apply Flux Kustomize: %w: %s", err, string(output))
+// fluxKustomizeURL returns the kustomization applied in kustomize mode.
+func (b *Bootstrapper) fluxKustomizeURL() string {
+	kustomizeCfg := b.getFluxKustomizeConfig()
+	if kustomizeCfg.URL != "" {
+		return kustomizeCfg.URL
+	}
+	if kustomizeCfg.Path != "" {
+		return fmt.Sprintf("https://github.com/fluxcd/flux2/manifests/%s", kustomizeCfg.Path)
 	}
+	return "https://github.com/fluxcd/flux2/manifests/install"
+}
 
-	return nil
+// helmConfig returns the Helm configuration of the tool with defaults.
+func (b *Bootstrapper) helmConfig() *HelmConfig {
+	if b.options.Tool == ToolFlux {
+		return b.getFluxHelmConfig()
+	}
+	return b.getArgoCDHelmConfig()
 }
 
 // getArgoCDHelmConfig returns the ArgoCD Helm configuration with defaults.
@@ -502,7 +539,7 @@ func (b *Bootstrapper) getArgoCDManifestConfig() *ManifestConfig {
 		version = "stable"
 	}
 	return &ManifestConfig{
-		URL: fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/manifests/install.yaml", version),
+		URL: fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/%s/install.yaml", version, b.argoCDManifestDir()),
 	}
 }
 
@@ -512,7 +549,7 @@ func (b *Bootstrapper) getArgoCDKustomizeConfig() *KustomizeConfig {
 		return b.options.Kustomize
 	}
 	return &KustomizeConfig{
-		URL:  "https://github.com/argoproj/argo-cd/manifests/cluster-install",
+		URL:  fmt.Sprintf("https://github.com/argoproj/argo-cd/%s/cluster-install", b.argoCDManifestDir()),
 		Path: "cluster-install",
 	}
 }
@@ -561,36 +598,74 @@ func (b *Bootstrapper) waitForReady(ctx context.Context) error {
 
 // configureRepository adds the repository to the GitOps tool.
 func (b *Bootstrapper) configureRepository(ctx context.Context) error {
+	manifests := append(b.repositoryManifests(b.options.RepoCredentials), b.additionalRepoManifests(false)...)
+	for _, manifest := range manifests {
+		if err := b.cluster.Apply(ctx, manifest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repositoryManifests returns the resources adding the repository to the
+// GitOps tool, with creds when the repository is private.
+func (b *Bootstrapper) repositoryManifests(creds *RepoCredentials) []string {
 	if b.options.Tool == ToolArgoCD {
-		return b.configureArgoCDRepo(ctx)
+		return []string{b.argoCDRepoManifest(creds)}
 	}
-	return b.configureFluxRepo(ctx)
+	return b.fluxRepoManifests(creds)
 }
 
-// configureArgoCDRepo adds a repository to ArgoCD.
-func (b *Bootstrapper) configureArgoCDRepo(ctx context.Context) error {
-	repoSecret := fmt.Sprintf(`apiVersion: v1
-kind: Secret
-metadata:
-  name: repo-%s
-  namespace: %s
-  labels:
-    argocd.argoproj.io/secret-type: repository
-stringData:
-  type: git
-  url: %s`, b.options.ProjectName, b.options.Namespace, b.options.RepoURL)
+// argoCDRepoManifest returns the ArgoCD repository secret.
+func (b *Bootstrapper) argoCDRepoManifest(creds *RepoCredentials) string {
+	return argoCDRepoSecret("repo-"+b.options.ProjectName, b.options.Namespace, b.options.RepoURL, creds)
+}
 
-	return b.cluster.Apply(ctx, repoSecret)
+// additionalRepoManifests returns the ArgoCD repository secrets of
+// Repositories, with masked credentials for plans.
+func (b *Bootstrapper) additionalRepoManifests(mask bool) []string {
+	if b.options.Tool != ToolArgoCD {
+		return nil
+	}
+	manifests := make([]string, 0, len(b.options.Repositories))
+	for _, repo := range b.options.Repositories {
+		creds := repo.Credentials
+		if mask {
+			creds = creds.masked()
+		}
+		manifests = append(manifests, argoCDRepoSecret("repo-"+repo.Name, b.options.Namespace, repo.URL, creds))
+	}
+	return manifests
 }
 
-// configureFluxRepo adds a repository to Flux.
-func (b *Bootstrapper) configureFluxRepo(ctx context.Context) error {
+// argoCDRepoSecret returns an ArgoCD repository secret for url.
+func argoCDRepoSecret(name, namespace, url string, creds *RepoCredentials) string {
+	data := map[string]string{
+		"type": "git",
+		"url":  url,
+	}
+	if creds != nil {
+		for k, v := range creds.argoCDData() {
+			data[k] = v
+		}
+	}
+	labels := map[string]string{"argocd.argoproj.io/secret-type": "repository"}
+	return secretManifest(name, namespace, labels, data)
+}
+
+// fluxRepoManifests returns the Flux GitRepository of the repository,
+// preceded by the secret it pulls with.
+func (b *Bootstrapper) fluxRepoManifests(creds *RepoCredentials) []string {
 	branch := b.options.RepoBranch
 	if branch == "" {
 		branch = "main"
 	}
 
-	gitRepo := fmt.Sprintf(`apiVersion: source.toolkit.fluxcd.io/v1
+	url := b.options.RepoURL
+	if creds != nil && creds.SSH() {
+		url = FluxSSHURL(url)
+	}
+	repo := fmt.Sprintf(`apiVersion: source.toolkit.fluxcd.io/v1
 kind: GitRepository
 metadata:
   name: %s
@@ -599,14 +674,38 @@ spec:
   interval: 1m
   url: %s
   ref:
-    branch: %s`, b.options.ProjectName, b.options.Namespace, b.options.RepoURL, branch)
+    branch: %s`, b.options.ProjectName, b.options.Namespace, url, branch)
+	if creds == nil {
+		return []string{repo}
+	}
 
-	return b.cluster.Apply(ctx, gitRepo)
+	secretName := b.options.ProjectName + "-git-credentials"
+	repo += "\n  secretRef:\n    name: " + secretName
+	if creds.GitHubApp() {
+		repo += "\n  provider: github"
+	}
+	return []string{secretManifest(secretName, b.options.Namespace, nil, creds.fluxData()), repo}
 }
 
 // createArgoCDProjects creates the required AppProjects for infrastructure and applications.
 // These must exist before child applications can reference them.
 func (b *Bootstrapper) createArgoCDProjects(ctx context.Context) error {
+	for _, proj := range b.argoCDProjectManifests() {
+		if err := b.cluster.Apply(ctx, proj.manifest); err != nil {
+			return fmt.Errorf("failed to create project %s: %w", proj.name, err)
+		}
+	}
+
+	return nil
+}
+
+type namedManifest struct {
+	name     string
+	manifest string
+}
+
+// argoCDProjectManifests returns the infrastructure and applications AppProjects.
+func (b *Bootstrapper) argoCDProjectManifests() []namedManifest {
 	projects := []struct {
 		name        string
 		description string
@@ -615,8 +714,9 @@ func (b *Bootstrapper) createArgoCDProjects(ctx context.Context) error {
 		{"applications", "Application workloads managed by GitOps"},
 	}
 
+	manifests := make([]namedManifest, 0, len(projects))
 	for _, proj := range projects {
-		projectYAML := fmt.Sprintf(`apiVersion: argoproj.io/v1alpha1
+		manifests = append(manifests, namedManifest{name: proj.name, manifest: fmt.Sprintf(`apiVersion: argoproj.io/v1alpha1
 kind: AppProject
 metadata:
   name: %s
@@ -633,26 +733,27 @@ spec:
       kind: '*'
   namespaceResourceWhitelist:
     - group: '*'
-      kind: '*'`, proj.name, b.options.Namespace, proj.description)
-
-		if err := b.cluster.Apply(ctx, projectYAML); err != nil {
-			return fmt.Errorf("failed to create project %s: %w", proj.name, err)
-		}
+      kind: '*'`, proj.name, b.options.Namespace, proj.description)})
 	}
 
-	return nil
+	return manifests
 }
 
 // createAppOfApps creates the root application.
 func (b *Bootstrapper) createAppOfApps(ctx context.Context) error {
+	return b.cluster.Apply(ctx, b.appOfAppsManifest())
+}
+
+// appOfAppsManifest returns the root application syncing the repository.
+func (b *Bootstrapper) appOfAppsManifest() string {
 	if b.options.Tool == ToolArgoCD {
-		return b.createArgoCDAppOfApps(ctx)
+		return b.argoCDAppOfAppsManifest()
 	}
-	return b.createFluxKustomization(ctx)
+	return b.fluxKustomizationManifest()
 }
 
-// createArgoCDAppOfApps creates an ArgoCD Application.
-func (b *Bootstrapper) createArgoCDAppOfApps(ctx context.Context) error {
+// argoCDAppOfAppsManifest returns the root ArgoCD Application.
+func (b *Bootstrapper) argoCDAppOfAppsManifest() string {
 	path := b.options.RepoPath
 	if path == "" {
 		path = "argocd/applicationsets"
@@ -663,7 +764,7 @@ func (b *Bootstrapper) createArgoCDAppOfApps(ctx context.Context) error {
 		branch = "main"
 	}
 
-	app := fmt.Sprintf(`apiVersion: argoproj.io/v1alpha1
+	return fmt.Sprintf(`apiVersion: argoproj.io/v1alpha1
 kind: Application
 metadata:
   name: %s-root
@@ -681,18 +782,16 @@ spec:
     automated:
       prune: true
       selfHeal: true`, b.options.ProjectName, b.options.Namespace, b.options.RepoURL, branch, path, b.options.Namespace)
-
-	return b.cluster.Apply(ctx, app)
 }
 
-// createFluxKustomization creates a Flux Kustomization.
-func (b *Bootstrapper) createFluxKustomization(ctx context.Context) error {
+// fluxKustomizationManifest returns the root Flux Kustomization.
+func (b *Bootstrapper) fluxKustomizationManifest() string {
 	path := b.options.RepoPath
 	if path == "" {
 		path = "./"
 	}
 
-	kustomization := fmt.Sprintf(`apiVersion: kustomize.toolkit.fluxcd.io/v1
+	return fmt.Sprintf(`apiVersion: kustomize.toolkit.fluxcd.io/v1
 kind: Kustomization
 metadata:
   name: %s
@@ -704,8 +803,6 @@ spec:
     name: %s
   path: %s
   prune: true`, b.options.ProjectName, b.options.Namespace, b.options.ProjectName, path)
-
-	return b.cluster.Apply(ctx, kustomization)
 }
 
 // getArgoCDAccess gets the ArgoCD UI URL and initial admin password.
@@ -718,9 +815,7 @@ func (b *Bootstrapper) getArgoCDAccess(ctx context.Context) (accessURL, accessPa
 		return "", "", err
 	}
 
-	// Decode base64 password
-	cmd := exec.CommandContext(ctx, "bash", "-c", fmt.Sprintf("echo %s | base64 -d", strings.TrimSpace(output)))
-	passwordBytes, err := cmd.CombinedOutput()
+	password, err := DecodeSecretValue(output)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to decode password: %w", err)
 	}
@@ -736,18 +831,34 @@ func (b *Bootstrapper) getArgoCDAccess(ctx context.Context) (accessURL, accessPa
 		accessURL = fmt.Sprintf("https://%s", strings.TrimSpace(extOutput))
 	}
 
-	accessPassword = strings.TrimSpace(string(passwordBytes))
-	return accessURL, accessPassword, nil
+	return accessURL, password, nil
+}
+
+// DecodeSecretValue decodes a base64 value of the data of a secret, as
+// printed by kubectl jsonpath.
+func DecodeSecretValue(value string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(decoded)), nil
 }
 
 // Uninstall removes the GitOps tool from the cluster.
 func (b *Bootstrapper) Uninstall(ctx context.Context) error {
+	if b.options.Offline && b.options.Mode != ModeOLM {
+		if err := b.uninstallOffline(ctx); err != nil {
+			return err
+		}
+		_, _ = b.cluster.RunCommand(ctx, "delete", "namespace", b.options.Namespace)
+		return nil
+	}
+
 	switch b.options.Tool {
 	case ToolArgoCD:
 		switch b.options.Mode {
 		case ModeHelm:
-			cmd := exec.CommandContext(ctx, "helm", "uninstall", "argocd", "-n", b.options.Namespace)
-			if _, err := cmd.CombinedOutput(); err != nil {
+			if err := b.helm.Uninstall(ctx, "argocd", b.options.Namespace); err != nil {
 				return fmt.Errorf("failed to uninstall ArgoCD: %w", err)
 			}
 		case ModeManifest:
@@ -757,14 +868,14 @@ func (b *Bootstrapper) Uninstall(ctx context.Context) error {
 			}
 			manifestURL := fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/manifests/install.yaml", version)
 			cmd := exec.CommandContext(ctx, "kubectl", "delete", "-n", b.options.Namespace, "-f", manifestURL)
-			if _, err := cmd.CombinedOutput(); err != nil {
+			if _, err := logging.CombinedOutput(cmd); err != nil {
 				return fmt.Errorf("failed to delete ArgoCD manifests: %w", err)
 			}
 		}
 
 	case ToolFlux:
 		cmd := exec.CommandContext(ctx, "flux", "uninstall", "--namespace", b.options.Namespace, "--silent")
-		if _, err := cmd.CombinedOutput(); err != nil {
+		if _, err := logging.CombinedOutput(cmd); err != nil {
 			return fmt.Errorf("failed to uninstall Flux: %w", err)
 		}
 	}
@@ -1,6 +1,8 @@
 package bootstrap
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -596,3 +598,54 @@ func TestManifestConfig_Fields(t *testing.T) {
 		t.Errorf("Namespace = %v, want custom-ns", cfg.Namespace)
 	}
 }
+
+type fakeManifests map[string][]byte
+
+func (f fakeManifests) Manifest(tool, version string) ([]byte, error) {
+	data, ok := f[tool+"@"+version]
+	if !ok {
+		return nil, fmt.Errorf("no %s %s manifest", tool, version)
+	}
+	return data, nil
+}
+
+func TestOfflineManifest(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolArgoCD, Offline: true})
+	if _, err := b.offlineManifest(); err == nil || !strings.Contains(err.Error(), "requires an offline bundle") {
+		t.Errorf("expected a missing bundle error, got %v", err)
+	}
+
+	b = New(nil, &Options{Tool: ToolFlux, Version: "v2.4.0", Offline: true, Manifests: fakeManifests{"flux@v2.4.0": []byte("kind: List")}})
+	data, err := b.offlineManifest()
+	if err != nil || string(data) != "kind: List" {
+		t.Errorf("offlineManifest() = %q, %v", data, err)
+	}
+
+	b.options.Version = "v9.9.9"
+	if _, err := b.offlineManifest(); err == nil || !strings.Contains(err.Error(), "offline bundle") {
+		t.Errorf("expected a bundle error, got %v", err)
+	}
+}
+
+func TestOfflinePaths(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolArgoCD, Offline: true, Manifest: &ManifestConfig{Paths: []string{"./extra.yaml", "file:///opt/argocd-cm.yaml"}}})
+	paths, err := b.offlinePaths()
+	if err != nil || len(paths) != 2 {
+		t.Errorf("offlinePaths() = %v, %v", paths, err)
+	}
+
+	b.options.Manifest.Paths = append(b.options.Manifest.Paths, "https://example.com/extra.yaml")
+	if _, err := b.offlinePaths(); err == nil || !strings.Contains(err.Error(), "needs network access") {
+		t.Errorf("expected a network error, got %v", err)
+	}
+}
+
+func TestDecodeSecretValue(t *testing.T) {
+	got, err := DecodeSecretValue("czNjcjN0LXA0c3M=\n")
+	if err != nil || got != "s3cr3t-p4ss" {
+		t.Errorf("DecodeSecretValue() = %q, %v, want s3cr3t-p4ss", got, err)
+	}
+	if _, err := DecodeSecretValue("not base64; rm -rf /"); err == nil {
+		t.Error("DecodeSecretValue() should reject invalid base64")
+	}
+}
@@ -0,0 +1,248 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// FluxSystemDir is the directory of the Flux components and sync
+	// manifests, below the path Flux syncs.
+	FluxSystemDir = "flux-system"
+	// FluxSecretName is the Secret holding the deploy key Flux pulls with.
+	FluxSecretName = "flux-system"
+
+	fluxComponentsFile = "gotk-components.yaml"
+	fluxSyncFile       = "gotk-sync.yaml"
+)
+
+// fluxInstallURL is the Flux release install manifest, by version.
+var fluxInstallURL = func(version string) string {
+	if version == "" || version == "latest" {
+		return "https://github.com/fluxcd/flux2/releases/latest/download/install.yaml"
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return fmt.Sprintf("https://github.com/fluxcd/flux2/releases/download/%s/install.yaml", version)
+}
+
+// FluxSync configures the GitRepository and Kustomization that make Flux
+// reconcile itself and the repository, like flux bootstrap.
+type FluxSync struct {
+	Namespace string // Default: flux-system
+	// URL is the ssh:// URL of the repository.
+	URL    string
+	Branch string // Default: main
+	// Path is the repository path Flux syncs, containing FluxSystemDir.
+	Path       string
+	SecretName string // Default: FluxSecretName
+	Interval   string // Default: 1m
+	// Lockdown patches the controllers with the multi-tenancy lockdown
+	// flags, and makes the flux-system Kustomization impersonate the
+	// kustomize-controller service account the lockdown leaves privileged.
+	Lockdown bool
+}
+
+// fluxLockdownPatches are the kustomize patches of the multi-tenancy
+// lockdown: no cross-namespace source references, no remote bases, and
+// reconciliation as the default service account of the object namespace
+// unless another one is set.
+const fluxLockdownPatches = `patches:
+  - patch: |
+      - op: add
+        path: /spec/template/spec/containers/0/args/-
+        value: --no-cross-namespace-refs=true
+    target:
+      kind: Deployment
+      name: "(kustomize-controller|helm-controller|notification-controller|image-reflector-controller|image-automation-controller)"
+  - patch: |
+      - op: add
+        path: /spec/template/spec/containers/0/args/-
+        value: --no-remote-bases=true
+    target:
+      kind: Deployment
+      name: "kustomize-controller"
+  - patch: |
+      - op: add
+        path: /spec/template/spec/containers/0/args/-
+        value: --default-service-account=default
+    target:
+      kind: Deployment
+      name: "(kustomize-controller|helm-controller)"
+`
+
+func (s *FluxSync) withDefaults() *FluxSync {
+	sync := *s
+	if sync.Namespace == "" {
+		sync.Namespace = "flux-system"
+	}
+	if sync.Branch == "" {
+		sync.Branch = "main"
+	}
+	if sync.Path == "" {
+		sync.Path = "./"
+	}
+	if sync.SecretName == "" {
+		sync.SecretName = FluxSecretName
+	}
+	if sync.Interval == "" {
+		sync.Interval = "1m"
+	}
+	return &sync
+}
+
+// Manifests returns the flux-system GitRepository and Kustomization.
+func (s *FluxSync) Manifests() string {
+	sync := s.withDefaults()
+	serviceAccount := ""
+	if sync.Lockdown {
+		serviceAccount = "\n  serviceAccountName: kustomize-controller"
+	}
+	return fmt.Sprintf(`apiVersion: source.toolkit.fluxcd.io/v1
+kind: GitRepository
+metadata:
+  name: flux-system
+  namespace: %[1]s
+spec:
+  interval: %[2]s
+  url: %[3]s
+  ref:
+    branch: %[4]s
+  secretRef:
+    name: %[5]s
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: flux-system
+  namespace: %[1]s
+spec:
+  interval: 10m%[7]s
+  path: %[6]s
+  prune: true
+  sourceRef:
+    kind: GitRepository
+    name: flux-system
+`, sync.Namespace, sync.Interval, sync.URL, sync.Branch, sync.SecretName, sync.Path, serviceAccount)
+}
+
+// FluxSSHURL returns the ssh:// form Flux requires of an SSH repository URL
+// such as git@github.com:org/repo.git.
+func FluxSSHURL(url string) string {
+	if strings.Contains(url, "://") {
+		return url
+	}
+	user, rest, ok := strings.Cut(url, "@")
+	if !ok {
+		return url
+	}
+	host, path, ok := strings.Cut(rest, ":")
+	if !ok {
+		return url
+	}
+	return fmt.Sprintf("ssh://%s@%s/%s", user, host, strings.TrimPrefix(path, "/"))
+}
+
+// FluxComponents returns the Flux install manifest of a version: from the
+// offline bundle when there is one, otherwise from the Flux release.
+func FluxComponents(ctx context.Context, version string, manifests ManifestSource) ([]byte, error) {
+	if manifests != nil {
+		data, err := manifests.Manifest(string(ToolFlux), version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load flux from the offline bundle: %w", err)
+		}
+		return data, nil
+	}
+
+	return downloadManifest(ctx, fluxInstallURL(version))
+}
+
+// downloadManifest fetches a release install manifest.
+func downloadManifest(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return data, nil
+}
+
+// WriteFluxSystem writes the Flux components, the sync manifests and their
+// kustomization into dir, and returns the written files.
+func WriteFluxSystem(dir string, components []byte, sync *FluxSync) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	kustomization := fmt.Sprintf(`apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - %s
+  - %s
+`, fluxComponentsFile, fluxSyncFile)
+	if sync.Lockdown {
+		kustomization += fluxLockdownPatches
+	}
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{fluxComponentsFile, components},
+		{fluxSyncFile, []byte(sync.Manifests())},
+		{"kustomization.yaml", []byte(kustomization)},
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, f.content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// BootstrapFluxRepo installs Flux from its components as committed to the
+// repository, then applies the deploy key secret and the sync manifests so
+// Flux takes over managing itself from the repository.
+func (b *Bootstrapper) BootstrapFluxRepo(ctx context.Context, components []byte, secret string, sync *FluxSync) (*Result, error) {
+	if err := b.cluster.Apply(ctx, string(components)); err != nil {
+		return nil, fmt.Errorf("failed to install Flux components: %w", err)
+	}
+	if b.options.Wait {
+		if err := b.waitForReady(ctx); err != nil {
+			return nil, fmt.Errorf("GitOps tool not ready: %w", err)
+		}
+	}
+	if err := b.cluster.Apply(ctx, secret); err != nil {
+		return nil, fmt.Errorf("failed to create the deploy key secret: %w", err)
+	}
+	if err := b.cluster.Apply(ctx, sync.Manifests()); err != nil {
+		return nil, fmt.Errorf("failed to create the sync manifests: %w", err)
+	}
+
+	return &Result{
+		Tool:      ToolFlux,
+		Namespace: b.options.Namespace,
+		Ready:     true,
+		Message:   fmt.Sprintf("flux installed in namespace %s and syncing %s", b.options.Namespace, sync.withDefaults().Path),
+	}, nil
+}
@@ -0,0 +1,139 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFluxSSHURL(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:acme/platform.git":         "ssh://git@github.com/acme/platform.git",
+		"git@ssh.dev.azure.com:v3/acme/p/platform": "ssh://git@ssh.dev.azure.com/v3/acme/p/platform",
+		"ssh://git@gitlab.com/acme/platform.git":   "ssh://git@gitlab.com/acme/platform.git",
+		"https://github.com/acme/platform.git":     "https://github.com/acme/platform.git",
+	}
+	for url, want := range tests {
+		if got := FluxSSHURL(url); got != want {
+			t.Errorf("FluxSSHURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestFluxSyncManifests(t *testing.T) {
+	sync := &FluxSync{URL: "ssh://git@github.com/acme/platform.git", Path: "./flux"}
+	manifests := sync.Manifests()
+
+	for _, want := range []string{
+		"kind: GitRepository",
+		"url: ssh://git@github.com/acme/platform.git",
+		"branch: main",
+		"secretRef:\n    name: flux-system",
+		"kind: Kustomization",
+		"path: ./flux",
+		"namespace: flux-system",
+	} {
+		if !strings.Contains(manifests, want) {
+			t.Errorf("Manifests() missing %q:\n%s", want, manifests)
+		}
+	}
+}
+
+func TestWriteFluxSystem(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "flux", FluxSystemDir)
+
+	paths, err := WriteFluxSystem(dir, []byte("kind: Namespace\n"), &FluxSync{URL: "ssh://git@example.com/platform.git"})
+	if err != nil {
+		t.Fatalf("WriteFluxSystem() error = %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("WriteFluxSystem() wrote %v", paths)
+	}
+	kustomization, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(kustomization), "- gotk-components.yaml\n  - gotk-sync.yaml") {
+		t.Errorf("kustomization.yaml = %s", kustomization)
+	}
+	components, _ := os.ReadFile(filepath.Join(dir, "gotk-components.yaml"))
+	if string(components) != "kind: Namespace\n" {
+		t.Errorf("gotk-components.yaml = %q", components)
+	}
+}
+
+func TestFluxComponentsFromBundle(t *testing.T) {
+	data, err := FluxComponents(context.Background(), "v2.3.0", fakeManifests{"flux@v2.3.0": []byte("bundled")})
+	if err != nil {
+		t.Fatalf("FluxComponents() error = %v", err)
+	}
+	if string(data) != "bundled" {
+		t.Errorf("FluxComponents() = %q", data)
+	}
+}
+
+func TestFluxComponentsDownload(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("downloaded"))
+	}))
+	defer server.Close()
+
+	orig := fluxInstallURL
+	defer func() { fluxInstallURL = orig }()
+	fluxInstallURL = func(version string) string { return server.URL + "/" + version }
+
+	data, err := FluxComponents(context.Background(), "v2.3.0", nil)
+	if err != nil {
+		t.Fatalf("FluxComponents() error = %v", err)
+	}
+	if string(data) != "downloaded" || requested != "/v2.3.0" {
+		t.Errorf("FluxComponents() = %q from %s", data, requested)
+	}
+
+	if _, err := FluxComponents(context.Background(), "missing", nil); err == nil {
+		t.Error("FluxComponents() should fail on HTTP errors")
+	}
+}
+
+func TestFluxInstallURL(t *testing.T) {
+	if got := fluxInstallURL("2.3.0"); got != "https://github.com/fluxcd/flux2/releases/download/v2.3.0/install.yaml" {
+		t.Errorf("fluxInstallURL(2.3.0) = %s", got)
+	}
+	if got := fluxInstallURL(""); !strings.Contains(got, "/latest/") {
+		t.Errorf("fluxInstallURL() = %s", got)
+	}
+}
+
+func TestWriteFluxSystem_Lockdown(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "flux", FluxSystemDir)
+	sync := &FluxSync{URL: "ssh://git@example.com/platform.git", Lockdown: true}
+
+	if _, err := WriteFluxSystem(dir, []byte("kind: Namespace\n"), sync); err != nil {
+		t.Fatalf("WriteFluxSystem() error = %v", err)
+	}
+	kustomization, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--no-cross-namespace-refs=true", "--no-remote-bases=true", "--default-service-account=default"} {
+		if !strings.Contains(string(kustomization), want) {
+			t.Errorf("kustomization.yaml missing %s:\n%s", want, kustomization)
+		}
+	}
+	if !strings.Contains(sync.Manifests(), "interval: 10m\n  serviceAccountName: kustomize-controller") {
+		t.Errorf("the flux-system Kustomization should run as kustomize-controller:\n%s", sync.Manifests())
+	}
+	if strings.Contains((&FluxSync{}).Manifests(), "serviceAccountName") {
+		t.Error("serviceAccountName should only be set with Lockdown")
+	}
+}
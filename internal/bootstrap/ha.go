@@ -0,0 +1,167 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+)
+
+// HA installs need a Redis replica per node: redis-ha spreads its three
+// replicas with required pod anti-affinity.
+const (
+	HAMinNodes  = 3
+	HAMinCPU    = "3"
+	HAMinMemory = "6Gi"
+)
+
+// HAHelmValues returns the argo-cd chart values of the HA profile: redis-ha,
+// two replicas of every ArgoCD component and resource requests sized for
+// them. Values files, inline values and set values override them.
+func HAHelmValues() map[string]any {
+	return map[string]any{
+		"redis-ha": map[string]any{
+			"enabled": true,
+		},
+		"controller": map[string]any{
+			"replicas":  2,
+			"resources": haResources("500m", "1Gi", "2Gi"),
+		},
+		"server": map[string]any{
+			"replicas":  2,
+			"resources": haResources("100m", "128Mi", "512Mi"),
+		},
+		"repoServer": map[string]any{
+			"replicas":  2,
+			"resources": haResources("250m", "256Mi", "1Gi"),
+		},
+		"applicationSet": map[string]any{
+			"replicas":  2,
+			"resources": haResources("100m", "128Mi", "512Mi"),
+		},
+	}
+}
+
+func haResources(cpu, memory, memoryLimit string) map[string]any {
+	return map[string]any{
+		"requests": map[string]any{"cpu": cpu, "memory": memory},
+		"limits":   map[string]any{"memory": memoryLimit},
+	}
+}
+
+// NodeCapacity is the number of schedulable nodes of a cluster and their
+// allocatable resources.
+type NodeCapacity struct {
+	Nodes  int
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+// ParseNodeCapacity sums the nodes of `kubectl get nodes -o json` that are
+// Ready and schedulable.
+func ParseNodeCapacity(nodesJSON []byte) (*NodeCapacity, error) {
+	var list struct {
+		Items []struct {
+			Spec struct {
+				Unschedulable bool `json:"unschedulable"`
+			} `json:"spec"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+				Conditions  []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(nodesJSON, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	capacity := &NodeCapacity{}
+	for _, node := range list.Items {
+		ready := false
+		for _, c := range node.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				ready = true
+			}
+		}
+		if !ready || node.Spec.Unschedulable {
+			continue
+		}
+		capacity.Nodes++
+		for name, q := range map[string]*resource.Quantity{"cpu": &capacity.CPU, "memory": &capacity.Memory} {
+			value, err := resource.ParseQuantity(node.Status.Allocatable[name])
+			if err != nil {
+				continue
+			}
+			q.Add(value)
+		}
+	}
+	return capacity, nil
+}
+
+// CheckHA returns an error if the nodes cannot run an HA installation.
+func (n *NodeCapacity) CheckHA() error {
+	if n.Nodes < HAMinNodes {
+		return fmt.Errorf("HA installs need at least %d schedulable nodes, the cluster has %d", HAMinNodes, n.Nodes)
+	}
+	if minCPU := resource.MustParse(HAMinCPU); n.CPU.Cmp(minCPU) < 0 {
+		return fmt.Errorf("HA installs need %s allocatable CPU, the cluster has %s", HAMinCPU, n.CPU.String())
+	}
+	if minMemory := resource.MustParse(HAMinMemory); n.Memory.Cmp(minMemory) < 0 {
+		return fmt.Errorf("HA installs need %s allocatable memory, the cluster has %s", HAMinMemory, n.Memory.String())
+	}
+	return nil
+}
+
+// CheckHACapacity checks that the cluster has the nodes and resources an HA
+// installation needs.
+func CheckHACapacity(ctx context.Context, c *cluster.Cluster) (*NodeCapacity, error) {
+	output, err := c.RunCommand(ctx, "get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	capacity, err := ParseNodeCapacity([]byte(output))
+	if err != nil {
+		return nil, err
+	}
+	return capacity, capacity.CheckHA()
+}
+
+// validateHA returns an error if the HA profile does not apply to the tool
+// and mode.
+func (b *Bootstrapper) validateHA() error {
+	switch {
+	case !b.options.HA:
+		return nil
+	case b.options.Tool != ToolArgoCD:
+		return fmt.Errorf("the HA profile is only available for ArgoCD")
+	case b.options.Offline:
+		return fmt.Errorf("the HA profile is not available offline: the offline bundle only vendors the standard ArgoCD manifests")
+	case b.options.Mode == ModeOLM:
+		return fmt.Errorf("the HA profile is not available with olm: enable spec.ha on the ArgoCD resource instead")
+	}
+	return nil
+}
+
+// profileValues returns the chart values of the install profile, applied
+// under the configured values.
+func (b *Bootstrapper) profileValues() map[string]any {
+	if b.options.HA && b.options.Tool == ToolArgoCD {
+		return HAHelmValues()
+	}
+	return nil
+}
+
+// argoCDManifestDir returns the directory of the ArgoCD release manifests
+// the profile installs from.
+func (b *Bootstrapper) argoCDManifestDir() string {
+	if b.options.HA {
+		return "manifests/ha"
+	}
+	return "manifests"
+}
@@ -0,0 +1,113 @@
+package bootstrap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func nodesJSON(nodes ...string) []byte {
+	return []byte(`{"items": [` + strings.Join(nodes, ",") + `]}`)
+}
+
+func node(cpu, memory, ready string, unschedulable bool) string {
+	return fmt.Sprintf(`{"spec": {"unschedulable": %t}, "status": {"allocatable": {"cpu": %q, "memory": %q}, "conditions": [{"type": "Ready", "status": %q}]}}`,
+		unschedulable, cpu, memory, ready)
+}
+
+func TestParseNodeCapacity(t *testing.T) {
+	capacity, err := ParseNodeCapacity(nodesJSON(
+		node("2", "4Gi", "True", false),
+		node("1500m", "3Gi", "True", false),
+		node("4", "16Gi", "False", false),
+		node("4", "16Gi", "True", true),
+	))
+	if err != nil {
+		t.Fatalf("ParseNodeCapacity() error = %v", err)
+	}
+	if capacity.Nodes != 2 || capacity.CPU.String() != "3500m" || capacity.Memory.String() != "7Gi" {
+		t.Errorf("ParseNodeCapacity() = %d nodes, %s CPU, %s memory", capacity.Nodes, capacity.CPU.String(), capacity.Memory.String())
+	}
+
+	if _, err := ParseNodeCapacity([]byte("not json")); err == nil {
+		t.Error("ParseNodeCapacity() should fail on invalid JSON")
+	}
+}
+
+func TestNodeCapacity_CheckHA(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []string
+		wantErr string
+	}{
+		{name: "enough", nodes: []string{node("2", "4Gi", "True", false), node("2", "4Gi", "True", false), node("2", "4Gi", "True", false)}},
+		{name: "two nodes", nodes: []string{node("8", "32Gi", "True", false), node("8", "32Gi", "True", false)}, wantErr: "at least 3 schedulable nodes"},
+		{name: "low cpu", nodes: []string{node("500m", "4Gi", "True", false), node("500m", "4Gi", "True", false), node("500m", "4Gi", "True", false)}, wantErr: "allocatable CPU"},
+		{name: "low memory", nodes: []string{node("2", "1Gi", "True", false), node("2", "1Gi", "True", false), node("2", "1Gi", "True", false)}, wantErr: "allocatable memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capacity, err := ParseNodeCapacity(nodesJSON(tt.nodes...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = capacity.CheckHA()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("CheckHA() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("CheckHA() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlan_HA(t *testing.T) {
+	plan, err := New(nil, &Options{
+		Tool: ToolArgoCD,
+		Mode: ModeHelm,
+		HA:   true,
+		Helm: &HelmConfig{Values: map[string]any{"server": map[string]any{"replicas": 3}}},
+	}).Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	values := plan.Helm.Values
+	if !plan.HA || fmt.Sprint(values["redis-ha"]) != "map[enabled:true]" {
+		t.Errorf("Plan() HA = %v, values = %v", plan.HA, values)
+	}
+	if got := values["server"].(map[string]any)["replicas"]; got != 3 {
+		t.Errorf("server.replicas = %v, want the configured 3", got)
+	}
+	if got := values["repoServer"].(map[string]any)["replicas"]; got != 2 {
+		t.Errorf("repoServer.replicas = %v, want 2", got)
+	}
+
+	plan, err = New(nil, &Options{Tool: ToolArgoCD, Mode: ModeManifest, Version: "v2.12.0", HA: true}).Plan()
+	if err != nil {
+		t.Fatalf("Plan(manifest) error = %v", err)
+	}
+	if plan.Manifests[0] != "https://raw.githubusercontent.com/argoproj/argo-cd/v2.12.0/manifests/ha/install.yaml" {
+		t.Errorf("Manifests = %v", plan.Manifests)
+	}
+
+	plan, err = New(nil, &Options{Tool: ToolArgoCD, Mode: ModeKustomize, HA: true}).Plan()
+	if err != nil {
+		t.Fatalf("Plan(kustomize) error = %v", err)
+	}
+	if plan.Kustomizations[0] != "https://github.com/argoproj/argo-cd/manifests/ha/cluster-install" {
+		t.Errorf("Kustomizations = %v", plan.Kustomizations)
+	}
+}
+
+func TestPlan_HAUnsupported(t *testing.T) {
+	for name, opts := range map[string]*Options{
+		"flux":    {Tool: ToolFlux, Mode: ModeHelm, HA: true},
+		"olm":     {Tool: ToolArgoCD, Mode: ModeOLM, HA: true},
+		"offline": {Tool: ToolArgoCD, Mode: ModeManifest, Offline: true, HA: true},
+	} {
+		if _, err := New(nil, opts).Plan(); err == nil || !strings.Contains(err.Error(), "HA profile") {
+			t.Errorf("Plan(%s) error = %v, want an HA profile error", name, err)
+		}
+	}
+}
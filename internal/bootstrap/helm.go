@@ -0,0 +1,322 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+)
+
+// HelmRelease describes a chart release to install or upgrade.
+type HelmRelease struct {
+	Name        string
+	RepoURL     string
+	Chart       string
+	Version     string
+	Namespace   string
+	Values      map[string]any
+	ValuesFiles []string
+	SetValues   map[string]string
+	// ProfileValues are the values of the install profile, overridden by
+	// the values files, inline values and set values.
+	ProfileValues map[string]any
+	Wait          bool
+	Timeout       time.Duration
+}
+
+// ReleaseStatus holds the structured status of a Helm release.
+type ReleaseStatus struct {
+	Name         string `json:"name" yaml:"name"`
+	Namespace    string `json:"namespace" yaml:"namespace"`
+	Chart        string `json:"chart" yaml:"chart"`
+	ChartVersion string `json:"chartVersion" yaml:"chartVersion"`
+	AppVersion   string `json:"appVersion" yaml:"appVersion"`
+	Revision     int    `json:"revision" yaml:"revision"`
+	Status       string `json:"status" yaml:"status"`
+}
+
+// HelmInstaller installs and removes Helm releases.
+type HelmInstaller interface {
+	InstallOrUpgrade(ctx context.Context, rel *HelmRelease) (*ReleaseStatus, error)
+	Uninstall(ctx context.Context, name, namespace string) error
+}
+
+// HelmUpgrader inspects, previews and rolls back Helm releases for upgrades.
+type HelmUpgrader interface {
+	HelmInstaller
+	Get(ctx context.Context, name, namespace string) (*ReleaseStatus, map[string]any, error)
+	Preview(ctx context.Context, rel *HelmRelease) (string, error)
+	Rollback(ctx context.Context, name, namespace string, revision int) error
+}
+
+// SDKHelmInstaller installs releases with the Helm Go SDK, so the helm binary is not required.
+type SDKHelmInstaller struct {
+	settings *cli.EnvSettings
+}
+
+// NewHelmInstaller creates a Helm SDK installer using the cluster's authentication settings.
+func NewHelmInstaller(c *cluster.Cluster) *SDKHelmInstaller {
+	settings := cli.New()
+	if c != nil {
+		if auth := c.GetAuthOptions(); auth != nil {
+			switch auth.Method {
+			case cluster.AuthKubeconfig, cluster.AuthOIDC:
+				settings.KubeConfig = auth.Kubeconfig
+				settings.KubeContext = auth.Context
+			case cluster.AuthToken:
+				settings.KubeAPIServer = c.URL
+				settings.KubeToken = auth.Token
+				settings.KubeCaFile = auth.CACert
+				settings.KubeInsecureSkipTLSVerify = auth.SkipTLS
+			}
+		}
+	}
+	return &SDKHelmInstaller{settings: settings}
+}
+
+// InstallOrUpgrade installs the release, or upgrades it if it already exists.
+func (h *SDKHelmInstaller) InstallOrUpgrade(ctx context.Context, rel *HelmRelease) (*ReleaseStatus, error) {
+	cfg, err := h.actionConfig(rel.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := rel.MergedValues()
+	if err != nil {
+		return nil, err
+	}
+
+	chartOpts, chrt, err := h.loadChart(rel)
+	if err != nil {
+		return nil, err
+	}
+
+	history := action.NewHistory(cfg)
+	history.Max = 1
+	_, histErr := history.Run(rel.Name)
+
+	var result *release.Release
+	if errors.Is(histErr, driver.ErrReleaseNotFound) {
+		install := action.NewInstall(cfg)
+		install.ReleaseName = rel.Name
+		install.Namespace = rel.Namespace
+		install.CreateNamespace = true
+		install.Wait = rel.Wait
+		install.Timeout = rel.Timeout
+		install.ChartPathOptions = chartOpts
+		result, err = install.RunWithContext(ctx, chrt, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install release %s: %w", rel.Name, err)
+		}
+	} else if histErr != nil {
+		return nil, fmt.Errorf("failed to read release history for %s: %w", rel.Name, histErr)
+	} else {
+		upgrade := action.NewUpgrade(cfg)
+		upgrade.Namespace = rel.Namespace
+		upgrade.Wait = rel.Wait
+		upgrade.Timeout = rel.Timeout
+		upgrade.ChartPathOptions = chartOpts
+		result, err = upgrade.RunWithContext(ctx, rel.Name, chrt, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade release %s: %w", rel.Name, err)
+		}
+	}
+
+	return toReleaseStatus(result), nil
+}
+
+// Get returns the status of an installed release and the values it was
+// installed with.
+func (h *SDKHelmInstaller) Get(ctx context.Context, name, namespace string) (*ReleaseStatus, map[string]any, error) {
+	cfg, err := h.actionConfig(namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rel, err := action.NewGet(cfg).Run(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get release %s: %w", name, err)
+	}
+	return toReleaseStatus(rel), rel.Config, nil
+}
+
+// Preview renders the manifest an upgrade of the release would apply, without
+// changing the cluster.
+func (h *SDKHelmInstaller) Preview(ctx context.Context, rel *HelmRelease) (string, error) {
+	cfg, err := h.actionConfig(rel.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := rel.MergedValues()
+	if err != nil {
+		return "", err
+	}
+
+	chartOpts, chrt, err := h.loadChart(rel)
+	if err != nil {
+		return "", err
+	}
+
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = rel.Namespace
+	upgrade.DryRun = true
+	upgrade.ChartPathOptions = chartOpts
+	result, err := upgrade.RunWithContext(ctx, rel.Name, chrt, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render upgrade of release %s: %w", rel.Name, err)
+	}
+	return result.Manifest, nil
+}
+
+// Rollback rolls a release back to a revision.
+func (h *SDKHelmInstaller) Rollback(ctx context.Context, name, namespace string, revision int) error {
+	cfg, err := h.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	rollback := action.NewRollback(cfg)
+	rollback.Version = revision
+	rollback.Wait = true
+	if err := rollback.Run(name); err != nil {
+		return fmt.Errorf("failed to roll back release %s to revision %d: %w", name, revision, err)
+	}
+	return nil
+}
+
+// Uninstall removes a Helm release.
+func (h *SDKHelmInstaller) Uninstall(ctx context.Context, name, namespace string) error {
+	cfg, err := h.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	if _, err := action.NewUninstall(cfg).Run(name); err != nil {
+		return fmt.Errorf("failed to uninstall release %s: %w", name, err)
+	}
+	return nil
+}
+
+func (h *SDKHelmInstaller) loadChart(rel *HelmRelease) (action.ChartPathOptions, *chart.Chart, error) {
+	chartOpts := action.ChartPathOptions{
+		RepoURL:               rel.RepoURL,
+		Version:               rel.Version,
+		CaFile:                network.CABundle(),
+		InsecureSkipTLSverify: network.InsecureTLS(),
+	}
+	chartPath, err := chartOpts.LocateChart(rel.Chart, h.settings)
+	if err != nil {
+		return chartOpts, nil, fmt.Errorf("failed to locate chart %s: %w", rel.Chart, err)
+	}
+
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return chartOpts, nil, fmt.Errorf("failed to load chart %s: %w", rel.Chart, err)
+	}
+	return chartOpts, chrt, nil
+}
+
+func (h *SDKHelmInstaller) actionConfig(namespace string) (*action.Configuration, error) {
+	h.settings.SetNamespace(namespace)
+
+	cfg := new(action.Configuration)
+	driverName := os.Getenv("HELM_DRIVER")
+	if err := cfg.Init(h.settings.RESTClientGetter(), namespace, driverName, debugLog); err != nil {
+		return nil, fmt.Errorf("failed to initialize helm: %w", err)
+	}
+	return cfg, nil
+}
+
+// MergeHelmValues merges values files, inline values, and --set style overrides, in that order.
+func MergeHelmValues(valuesFiles []string, values map[string]any, setValues map[string]string) (map[string]any, error) {
+	merged := map[string]any{}
+
+	for _, path := range valuesFiles {
+		fileValues, err := chartutil.ReadValuesFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file %s: %w", path, err)
+		}
+		merged = mergeMaps(merged, fileValues.AsMap())
+	}
+
+	merged = mergeMaps(merged, values)
+
+	for k, v := range setValues {
+		if err := strvals.ParseInto(fmt.Sprintf("%s=%s", k, v), merged); err != nil {
+			return nil, fmt.Errorf("failed to parse set value %s: %w", k, err)
+		}
+	}
+
+	return merged, nil
+}
+
+// MergedValues returns the profile values overridden by the merged values
+// files, inline values and set values of the release.
+func (rel *HelmRelease) MergedValues() (map[string]any, error) {
+	values, err := MergeHelmValues(rel.ValuesFiles, rel.Values, rel.SetValues)
+	if err != nil {
+		return nil, err
+	}
+	if len(rel.ProfileValues) == 0 {
+		return values, nil
+	}
+	return mergeMaps(rel.ProfileValues, values), nil
+}
+
+func mergeMaps(a, b map[string]any) map[string]any {
+	out := make(map[string]any, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if vm, ok := v.(map[string]any); ok {
+			if existing, ok := out[k].(map[string]any); ok {
+				out[k] = mergeMaps(existing, vm)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func toReleaseStatus(rel *release.Release) *ReleaseStatus {
+	if rel == nil {
+		return nil
+	}
+	status := &ReleaseStatus{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+	}
+	if rel.Info != nil {
+		status.Status = rel.Info.Status.String()
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		status.Chart = rel.Chart.Metadata.Name
+		status.ChartVersion = rel.Chart.Metadata.Version
+		status.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	return status
+}
+
+func debugLog(format string, v ...any) {
+	if os.Getenv("HELM_DEBUG") != "" {
+		log.Printf(format, v...)
+	}
+}
@@ -0,0 +1,188 @@
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+)
+
+type fakeHelmInstaller struct {
+	installed   *HelmRelease
+	uninstalled string
+}
+
+func (f *fakeHelmInstaller) InstallOrUpgrade(ctx context.Context, rel *HelmRelease) (*ReleaseStatus, error) {
+	f.installed = rel
+	return &ReleaseStatus{Name: rel.Name, Namespace: rel.Namespace, Status: "deployed", Revision: 1}, nil
+}
+
+func (f *fakeHelmInstaller) Uninstall(ctx context.Context, name, namespace string) error {
+	f.uninstalled = name
+	return nil
+}
+
+func TestInstallArgoCDHelm_UsesInstaller(t *testing.T) {
+	b := New(nil, &Options{
+		Tool:    ToolArgoCD,
+		Mode:    ModeHelm,
+		Version: "7.3.0",
+		Timeout: 120,
+		Helm: &HelmConfig{
+			ValuesFiles: []string{"values.yaml"},
+			SetValues:   map[string]string{"server.replicas": "2"},
+		},
+	})
+	fake := &fakeHelmInstaller{}
+	b.SetHelmInstaller(fake)
+
+	if err := b.installArgoCDHelm(context.Background()); err != nil {
+		t.Fatalf("installArgoCDHelm() error = %v", err)
+	}
+
+	rel := fake.installed
+	if rel == nil {
+		t.Fatal("expected release to be installed")
+	}
+	if rel.Name != "argocd" || rel.Chart != "argo-cd" || rel.RepoURL != "https://argoproj.github.io/argo-helm" {
+		t.Errorf("unexpected release: %+v", rel)
+	}
+	if rel.Version != "7.3.0" {
+		t.Errorf("Version = %s, want 7.3.0", rel.Version)
+	}
+	if rel.Namespace != "argocd" {
+		t.Errorf("Namespace = %s, want argocd", rel.Namespace)
+	}
+	if rel.Timeout != 120*time.Second {
+		t.Errorf("Timeout = %v, want 2m", rel.Timeout)
+	}
+	if len(rel.ValuesFiles) != 1 || rel.SetValues["server.replicas"] != "2" {
+		t.Errorf("values not passed through: %+v", rel)
+	}
+	if b.release == nil || b.release.Status != "deployed" {
+		t.Errorf("release status not recorded: %+v", b.release)
+	}
+}
+
+func TestInstallFluxHelm_UsesInstaller(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolFlux, Mode: ModeHelm})
+	fake := &fakeHelmInstaller{}
+	b.SetHelmInstaller(fake)
+
+	if err := b.installFluxHelm(context.Background()); err != nil {
+		t.Fatalf("installFluxHelm() error = %v", err)
+	}
+	if fake.installed.Name != "flux2" || fake.installed.Namespace != "flux-system" {
+		t.Errorf("unexpected release: %+v", fake.installed)
+	}
+}
+
+func TestMergeHelmValues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "values.yaml")
+	content := "server:\n  replicas: 1\n  service:\n    type: ClusterIP\nglobal:\n  domain: example.com\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := MergeHelmValues(
+		[]string{path},
+		map[string]any{"server": map[string]any{"service": map[string]any{"type": "LoadBalancer"}}},
+		map[string]string{"server.replicas": "3"},
+	)
+	if err != nil {
+		t.Fatalf("MergeHelmValues() error = %v", err)
+	}
+
+	server := values["server"].(map[string]any)
+	if server["replicas"] != int64(3) {
+		t.Errorf("replicas = %v, want 3", server["replicas"])
+	}
+	if server["service"].(map[string]any)["type"] != "LoadBalancer" {
+		t.Errorf("service.type = %v, want LoadBalancer", server["service"])
+	}
+	if values["global"].(map[string]any)["domain"] != "example.com" {
+		t.Errorf("global.domain missing: %v", values["global"])
+	}
+}
+
+func TestMergeHelmValues_FilesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	ingress := filepath.Join(dir, "ingress.yaml")
+	if err := os.WriteFile(base, []byte("server:\n  replicas: 1\n  ingress:\n    enabled: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ingress, []byte("server:\n  ingress:\n    enabled: true\n    hostname: argocd.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := MergeHelmValues([]string{base, ingress}, nil, nil)
+	if err != nil {
+		t.Fatalf("MergeHelmValues() error = %v", err)
+	}
+	server := values["server"].(map[string]any)
+	ing := server["ingress"].(map[string]any)
+	if server["replicas"] != float64(1) {
+		t.Errorf("replicas = %v, want 1 from the first file", server["replicas"])
+	}
+	if ing["enabled"] != true || ing["hostname"] != "argocd.example.com" {
+		t.Errorf("ingress = %v, want the second file to win", ing)
+	}
+}
+
+func TestBootstrap_MissingValuesFile(t *testing.T) {
+	b := New(nil, &Options{
+		Tool: ToolArgoCD,
+		Mode: ModeHelm,
+		Helm: &HelmConfig{ValuesFiles: []string{"/nonexistent/values.yaml"}},
+	})
+	if _, err := b.Bootstrap(context.Background()); err == nil {
+		t.Error("Bootstrap() should fail on a missing values file before touching the cluster")
+	}
+}
+
+func TestMergeHelmValues_MissingFile(t *testing.T) {
+	if _, err := MergeHelmValues([]string{"/nonexistent/values.yaml"}, nil, nil); err == nil {
+		t.Error("expected error for missing values file")
+	}
+}
+
+func TestToReleaseStatus(t *testing.T) {
+	if toReleaseStatus(nil) != nil {
+		t.Error("expected nil status for nil release")
+	}
+
+	rel := &release.Release{
+		Name:      "argocd",
+		Namespace: "argocd",
+		Version:   2,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "argo-cd", Version: "7.3.0", AppVersion: "v2.11.0"}},
+	}
+	status := toReleaseStatus(rel)
+	if status.Status != "deployed" || status.Revision != 2 || status.ChartVersion != "7.3.0" || status.AppVersion != "v2.11.0" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestNewHelmInstaller_TokenAuth(t *testing.T) {
+	c := cluster.New("https://api.example.com:6443", "prod", cluster.PlatformKubernetes)
+	if err := c.Authenticate(&cluster.AuthOptions{Method: cluster.AuthToken, Token: "abc", SkipTLS: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHelmInstaller(c)
+	if h.settings.KubeAPIServer != "https://api.example.com:6443" || h.settings.KubeToken != "abc" {
+		t.Errorf("token settings not applied: %+v", h.settings)
+	}
+	if !h.settings.KubeInsecureSkipTLSVerify {
+		t.Error("expected insecure skip TLS verify")
+	}
+}
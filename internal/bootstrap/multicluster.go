@@ -0,0 +1,653 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/telemetry"
+)
+
+// Strategy selects how multiple clusters are bootstrapped.
+type Strategy string
+
+const (
+	// StrategyStandalone installs the GitOps tool on every cluster.
+	StrategyStandalone Strategy = "standalone"
+	// StrategyHub installs ArgoCD on one hub cluster and registers the
+	// remaining clusters into it.
+	StrategyHub Strategy = "hub"
+)
+
+// Cluster roles reported in ClusterResult.
+const (
+	RoleStandalone = "standalone"
+	RoleHub        = "hub"
+	RoleSpoke      = "spoke"
+)
+
+// remoteServiceAccount is the account created on spoke clusters for the hub
+// ArgoCD, matching the one created by `argocd cluster add`.
+const remoteServiceAccount = "argocd-manager"
+
+// remoteFluxServiceAccount is the account created on spoke clusters for the
+// hub Flux.
+const remoteFluxServiceAccount = "flux-manager"
+
+// ClusterTarget is one cluster taking part in a multi-cluster bootstrap.
+type ClusterTarget struct {
+	Name        string
+	Environment string
+	Cluster     *cluster.Cluster
+	Labels      map[string]string
+	// Remote, when set, are the credentials the hub reaches the cluster
+	// with, in place of a service account created on it.
+	Remote *RemoteCredentials
+}
+
+// MultiClusterOptions configures a multi-cluster bootstrap.
+type MultiClusterOptions struct {
+	Strategy Strategy
+	// Hub is the name of the target acting as hub (hub strategy only).
+	Hub string
+	// Concurrency limits how many clusters are bootstrapped at once (default: 4).
+	Concurrency int
+	// Options are the base bootstrap options applied to every cluster.
+	Options *Options
+	// OnProgress is called as each cluster starts and finishes. Calls are
+	// serialized.
+	OnProgress func(ProgressEvent)
+}
+
+// ProgressEvent reports a state change of one cluster.
+type ProgressEvent struct {
+	Cluster string
+	Role    string
+	Done    bool
+	Err     error
+}
+
+// ClusterResult is the outcome of bootstrapping one cluster.
+type ClusterResult struct {
+	Name        string
+	Environment string
+	Server      string
+	Role        string
+	Result      *Result
+	// ClusterSecret is the ArgoCD cluster secret registering a spoke in the hub.
+	ClusterSecret string
+	Err           error
+	Duration      time.Duration
+}
+
+// MultiClusterResult aggregates the per-cluster results in target order.
+type MultiClusterResult struct {
+	Strategy  Strategy
+	Clusters  []ClusterResult
+	Succeeded int
+	Failed    int
+}
+
+// HasFailures reports whether any cluster failed.
+func (r *MultiClusterResult) HasFailures() bool {
+	return r.Failed > 0
+}
+
+// RemoteCredentials are the credentials the hub uses to reach a spoke. The
+// certificate and key data are base64 encoded PEM, as in a kubeconfig.
+type RemoteCredentials struct {
+	BearerToken string
+	CAData      string
+	CertData    string
+	KeyData     string
+	Username    string
+	Password    string
+	// AWSClusterName and AWSRoleARN authenticate to EKS with the IAM role of
+	// ArgoCD instead of a token.
+	AWSClusterName string
+	AWSRoleARN     string
+	// Exec runs a credential plugin in ArgoCD to get a token.
+	Exec *ExecProvider
+	// Insecure disables TLS verification of a cluster without CAData. It
+	// must be set explicitly: without CAData the system roots are used.
+	Insecure bool
+}
+
+// ExecProvider is a client-go credential plugin run by ArgoCD.
+type ExecProvider struct {
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	APIVersion  string            `json:"apiVersion"`
+	InstallHint string            `json:"installHint,omitempty"`
+}
+
+// clusterConfig is the config of an ArgoCD cluster secret.
+type clusterConfig struct {
+	Username           string          `json:"username,omitempty"`
+	Password           string          `json:"password,omitempty"`
+	BearerToken        string          `json:"bearerToken,omitempty"`
+	AWSAuthConfig      *awsAuthConfig  `json:"awsAuthConfig,omitempty"`
+	ExecProviderConfig *ExecProvider   `json:"execProviderConfig,omitempty"`
+	TLSClientConfig    tlsClientConfig `json:"tlsClientConfig"`
+}
+
+type awsAuthConfig struct {
+	ClusterName string `json:"clusterName"`
+	RoleARN     string `json:"roleARN,omitempty"`
+}
+
+type tlsClientConfig struct {
+	Insecure bool   `json:"insecure"`
+	CAData   string `json:"caData,omitempty"`
+	CertData string `json:"certData,omitempty"`
+	KeyData  string `json:"keyData,omitempty"`
+}
+
+// MultiClusterBootstrapper bootstraps several clusters concurrently.
+type MultiClusterBootstrapper struct {
+	opts    *MultiClusterOptions
+	targets []ClusterTarget
+	mu      sync.Mutex
+
+	bootstrap     func(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error)
+	prepareRemote func(ctx context.Context, target *ClusterTarget) (*RemoteCredentials, error)
+	apply         func(ctx context.Context, c *cluster.Cluster, manifest string) error
+}
+
+// NewMultiCluster creates a MultiClusterBootstrapper for the given targets.
+func NewMultiCluster(targets []ClusterTarget, opts *MultiClusterOptions) (*MultiClusterBootstrapper, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no clusters to bootstrap")
+	}
+	if opts.Options == nil {
+		return nil, fmt.Errorf("bootstrap options are required")
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = StrategyStandalone
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		if seen[t.Name] {
+			return nil, fmt.Errorf("duplicate cluster name: %s", t.Name)
+		}
+		seen[t.Name] = true
+	}
+
+	switch opts.Strategy {
+	case StrategyStandalone:
+	case StrategyHub:
+		if opts.Options.Tool != ToolArgoCD && opts.Options.Tool != ToolFlux {
+			return nil, fmt.Errorf("hub strategy requires argocd or flux, got %s", opts.Options.Tool)
+		}
+		if opts.Hub == "" {
+			return nil, fmt.Errorf("hub strategy requires a hub cluster")
+		}
+		if !seen[opts.Hub] {
+			return nil, fmt.Errorf("hub cluster %s is not one of the bootstrap targets", opts.Hub)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported multi-cluster strategy: %s (must be %s or %s)", opts.Strategy, StrategyStandalone, StrategyHub)
+	}
+
+	account := remoteServiceAccount
+	if opts.Options.Tool == ToolFlux {
+		account = remoteFluxServiceAccount
+	}
+	return &MultiClusterBootstrapper{
+		opts:      opts,
+		targets:   targets,
+		bootstrap: bootstrapTarget,
+		prepareRemote: func(ctx context.Context, target *ClusterTarget) (*RemoteCredentials, error) {
+			return prepareRemoteAccess(ctx, target, account)
+		},
+		apply: func(ctx context.Context, c *cluster.Cluster, manifest string) error {
+			return c.Apply(ctx, manifest)
+		},
+	}, nil
+}
+
+// Bootstrap runs the bootstrap on every target. Errors are reported per
+// cluster in the result; a failing cluster does not stop the others. With the
+// hub strategy the hub is bootstrapped first and spokes are only registered
+// once it is ready.
+func (m *MultiClusterBootstrapper) Bootstrap(ctx context.Context) *MultiClusterResult {
+	ctx, end := telemetry.Start(ctx, "bootstrap.clusters",
+		attribute.String("strategy", string(m.opts.Strategy)),
+		attribute.Int("clusters", len(m.targets)),
+	)
+	result := &MultiClusterResult{
+		Strategy: m.opts.Strategy,
+		Clusters: make([]ClusterResult, len(m.targets)),
+	}
+
+	if m.opts.Strategy == StrategyHub {
+		hubIdx := m.targetIndex(m.opts.Hub)
+		hub := &m.targets[hubIdx]
+		result.Clusters[hubIdx] = m.runTarget(ctx, hub, RoleHub, func(ctx context.Context) (*Result, string, error) {
+			r, err := m.bootstrap(ctx, hub, m.clusterOptions(hub))
+			return r, "", err
+		})
+
+		hubErr := result.Clusters[hubIdx].Err
+		m.forEach(func(i int) {
+			if i == hubIdx {
+				return
+			}
+			spoke := &m.targets[i]
+			result.Clusters[i] = m.runTarget(ctx, spoke, RoleSpoke, func(ctx context.Context) (*Result, string, error) {
+				if hubErr != nil {
+					return nil, "", fmt.Errorf("hub %s failed to bootstrap", hub.Name)
+				}
+				return m.registerSpoke(ctx, hub, spoke)
+			})
+		})
+	} else {
+		m.forEach(func(i int) {
+			target := &m.targets[i]
+			result.Clusters[i] = m.runTarget(ctx, target, RoleStandalone, func(ctx context.Context) (*Result, string, error) {
+				r, err := m.bootstrap(ctx, target, m.clusterOptions(target))
+				return r, "", err
+			})
+		})
+	}
+
+	for _, c := range result.Clusters {
+		if c.Err != nil {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+	}
+	if result.Failed > 0 {
+		end(fmt.Errorf("%d of %d clusters failed to bootstrap", result.Failed, len(result.Clusters)))
+	} else {
+		end(nil)
+	}
+	return result
+}
+
+// ClusterPlan is what a multi-cluster bootstrap does on one cluster.
+type ClusterPlan struct {
+	Name        string `json:"name" yaml:"name"`
+	Environment string `json:"environment" yaml:"environment"`
+	Server      string `json:"server,omitempty" yaml:"server,omitempty"`
+	Role        string `json:"role" yaml:"role"`
+	// Plan is the install on standalone and hub clusters.
+	Plan *Plan `json:"plan,omitempty" yaml:"plan,omitempty"`
+	// Registration describes how a spoke is registered into the hub.
+	Registration string `json:"registration,omitempty" yaml:"registration,omitempty"`
+}
+
+// Plan returns what Bootstrap would do on every target, in target order,
+// without touching the clusters.
+func (m *MultiClusterBootstrapper) Plan() ([]ClusterPlan, error) {
+	plans := make([]ClusterPlan, 0, len(m.targets))
+	for i := range m.targets {
+		target := &m.targets[i]
+		cp := ClusterPlan{
+			Name:        target.Name,
+			Environment: target.Environment,
+			Server:      target.Cluster.GetURL(),
+			Role:        RoleStandalone,
+		}
+		if m.opts.Strategy == StrategyHub {
+			cp.Role = RoleHub
+			if target.Name != m.opts.Hub {
+				cp.Role = RoleSpoke
+				cp.Registration = m.registration(target)
+				plans = append(plans, cp)
+				continue
+			}
+		}
+		plan, err := New(target.Cluster, m.clusterOptions(target)).Plan()
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan %s: %w", target.Name, err)
+		}
+		cp.Plan = plan
+		plans = append(plans, cp)
+	}
+	return plans, nil
+}
+
+// forEach calls fn for every target index with bounded concurrency.
+func (m *MultiClusterBootstrapper) forEach(fn func(i int)) {
+	sem := make(chan struct{}, m.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for i := range m.targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (m *MultiClusterBootstrapper) runTarget(ctx context.Context, target *ClusterTarget, role string, fn func(context.Context) (*Result, string, error)) ClusterResult {
+	m.notify(ProgressEvent{Cluster: target.Name, Role: role})
+
+	start := time.Now()
+	ctx, end := telemetry.Start(ctx, "bootstrap.cluster",
+		attribute.String("cluster", target.Name),
+		attribute.String("environment", target.Environment),
+		attribute.String("role", role),
+	)
+	r, secret, err := fn(ctx)
+	end(err)
+	res := ClusterResult{
+		Name:          target.Name,
+		Environment:   target.Environment,
+		Server:        target.Cluster.GetURL(),
+		Role:          role,
+		Result:        r,
+		ClusterSecret: secret,
+		Err:           err,
+		Duration:      time.Since(start),
+	}
+
+	m.notify(ProgressEvent{Cluster: target.Name, Role: role, Done: true, Err: err})
+	return res
+}
+
+// notify serializes progress callbacks from concurrent clusters.
+func (m *MultiClusterBootstrapper) notify(event ProgressEvent) {
+	if m.opts.OnProgress == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts.OnProgress(event)
+}
+
+// registerSpoke creates the hub's service account on the spoke, unless the
+// spoke has remote credentials, and applies a cluster secret for it to the
+// hub.
+func (m *MultiClusterBootstrapper) registerSpoke(ctx context.Context, hub, spoke *ClusterTarget) (*Result, string, error) {
+	creds := spoke.Remote
+	if creds == nil {
+		var err error
+		if creds, err = m.prepareRemote(ctx, spoke); err != nil {
+			return nil, "", fmt.Errorf("failed to prepare remote access: %w", err)
+		}
+	}
+
+	secret, err := m.spokeSecret(spoke, creds)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := m.apply(ctx, hub.Cluster, secret); err != nil {
+		return nil, secret, fmt.Errorf("failed to register cluster in hub: %w", err)
+	}
+
+	return &Result{
+		Tool:      m.opts.Options.Tool,
+		Namespace: m.hubNamespace(),
+		Ready:     true,
+		Message:   fmt.Sprintf("registered in hub %s", hub.Name),
+	}, secret, nil
+}
+
+// spokeSecret renders the secret registering a spoke in the hub: an ArgoCD
+// cluster secret, or the kubeConfig Secret of the Flux Kustomizations of the
+// spoke.
+func (m *MultiClusterBootstrapper) spokeSecret(spoke *ClusterTarget, creds *RemoteCredentials) (string, error) {
+	if m.opts.Options.Tool == ToolFlux {
+		return KubeconfigSecret(spoke, m.hubNamespace(), creds)
+	}
+	return ClusterSecret(spoke, m.hubNamespace(), creds)
+}
+
+// registration describes how a spoke is registered in the hub.
+func (m *MultiClusterBootstrapper) registration(spoke *ClusterTarget) string {
+	secret := "cluster-" + spoke.Name
+	if m.opts.Options.Tool == ToolFlux {
+		secret = KubeconfigSecretName(spoke.Name)
+	}
+	if spoke.Remote != nil {
+		return fmt.Sprintf("apply secret %s with the stored credentials to namespace %s of hub %s", secret, m.hubNamespace(), m.opts.Hub)
+	}
+	account := remoteServiceAccount
+	if m.opts.Options.Tool == ToolFlux {
+		account = remoteFluxServiceAccount
+	}
+	return fmt.Sprintf("create service account kube-system/%s bound to cluster-admin, then apply secret %s to namespace %s of hub %s",
+		account, secret, m.hubNamespace(), m.opts.Hub)
+}
+
+// clusterOptions returns a per-cluster copy of the base options.
+func (m *MultiClusterBootstrapper) clusterOptions(target *ClusterTarget) *Options {
+	opts := *m.opts.Options
+	if target.Environment != "" && opts.ProjectName != "" && m.opts.Strategy == StrategyStandalone {
+		opts.ProjectName = fmt.Sprintf("%s-%s", opts.ProjectName, target.Environment)
+	}
+	return &opts
+}
+
+func (m *MultiClusterBootstrapper) hubNamespace() string {
+	if m.opts.Options.Namespace != "" {
+		return m.opts.Options.Namespace
+	}
+	if m.opts.Options.Tool == ToolFlux {
+		return "flux-system"
+	}
+	return "argocd"
+}
+
+func (m *MultiClusterBootstrapper) targetIndex(name string) int {
+	for i, t := range m.targets {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ClusterSecret renders the ArgoCD cluster secret that registers target.
+func ClusterSecret(target *ClusterTarget, namespace string, creds *RemoteCredentials) (string, error) {
+	if target.Cluster.GetURL() == "" {
+		return "", fmt.Errorf("cluster %s has no server URL", target.Name)
+	}
+
+	cfg := clusterConfig{
+		Username:           creds.Username,
+		Password:           creds.Password,
+		BearerToken:        creds.BearerToken,
+		ExecProviderConfig: creds.Exec,
+		TLSClientConfig: tlsClientConfig{
+			Insecure: creds.Insecure && creds.CAData == "",
+			CAData:   creds.CAData,
+			CertData: creds.CertData,
+			KeyData:  creds.KeyData,
+		},
+	}
+	if creds.AWSClusterName != "" {
+		cfg.AWSAuthConfig = &awsAuthConfig{ClusterName: creds.AWSClusterName, RoleARN: creds.AWSRoleARN}
+	}
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cluster config: %w", err)
+	}
+
+	var labels strings.Builder
+	labels.WriteString("    argocd.argoproj.io/secret-type: cluster\n")
+	if target.Environment != "" {
+		fmt.Fprintf(&labels, "    env: %s\n", target.Environment)
+	}
+	for _, k := range sortedKeys(target.Labels) {
+		fmt.Fprintf(&labels, "    %s: %q\n", k, target.Labels[k])
+	}
+
+	return fmt.Sprintf(`apiVersion: v1
+kind: Secret
+metadata:
+  name: cluster-%s
+  namespace: %s
+  labels:
+%stype: Opaque
+stringData:
+  name: %s
+  server: %s
+  config: '%s'
+`, target.Name, namespace, labels.String(), target.Name, target.Cluster.GetURL(), strings.ReplaceAll(string(cfgJSON), "'", "''")), nil
+}
+
+// KubeconfigSecretName returns the name of the kubeConfig Secret of a spoke,
+// referenced by the Flux Kustomizations gitopsi generates for it.
+func KubeconfigSecretName(clusterName string) string {
+	return clusterName + "-kubeconfig"
+}
+
+// KubeconfigSecret renders the Secret holding the kubeconfig the hub Flux
+// reconciles target with, under the value key of Kustomization.spec.kubeConfig.
+// Flux cannot run credential plugins, so exec and AWS credentials are refused.
+func KubeconfigSecret(target *ClusterTarget, namespace string, creds *RemoteCredentials) (string, error) {
+	if target.Cluster.GetURL() == "" {
+		return "", fmt.Errorf("cluster %s has no server URL", target.Name)
+	}
+	if creds.Exec != nil || creds.AWSClusterName != "" {
+		return "", fmt.Errorf("flux cannot run credential plugins to reach cluster %s: use a token, client certificate or basic auth credential", target.Name)
+	}
+
+	decode := func(field, value string) ([]byte, error) {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s of cluster %s: %w", field, target.Name, err)
+		}
+		return data, nil
+	}
+	remote := clientcmdapi.NewCluster()
+	remote.Server = target.Cluster.GetURL()
+	remote.InsecureSkipTLSVerify = creds.Insecure && creds.CAData == ""
+	user := clientcmdapi.NewAuthInfo()
+	user.Token = creds.BearerToken
+	user.Username = creds.Username
+	user.Password = creds.Password
+	var err error
+	if remote.CertificateAuthorityData, err = decode("CA data", creds.CAData); err != nil {
+		return "", err
+	}
+	if user.ClientCertificateData, err = decode("certificate data", creds.CertData); err != nil {
+		return "", err
+	}
+	if user.ClientKeyData, err = decode("key data", creds.KeyData); err != nil {
+		return "", err
+	}
+
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[target.Name] = remote
+	cfg.AuthInfos[target.Name] = user
+	cfg.Contexts[target.Name] = &clientcmdapi.Context{Cluster: target.Name, AuthInfo: target.Name}
+	cfg.CurrentContext = target.Name
+	kubeconfig, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to render kubeconfig of cluster %s: %w", target.Name, err)
+	}
+
+	labels := map[string]string{}
+	if target.Environment != "" {
+		labels["env"] = target.Environment
+	}
+	for k, v := range target.Labels {
+		labels[k] = v
+	}
+	return secretManifest(KubeconfigSecretName(target.Name), namespace, labels, map[string]string{"value": string(kubeconfig)}) + "\n", nil
+}
+
+// bootstrapTarget runs a single-cluster bootstrap.
+func bootstrapTarget(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error) {
+	return New(target.Cluster, opts).Bootstrap(ctx)
+}
+
+// prepareRemoteAccess creates a cluster-admin service account on the target
+// and returns a long-lived token for it.
+func prepareRemoteAccess(ctx context.Context, target *ClusterTarget, account string) (*RemoteCredentials, error) {
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: %[1]s
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %[1]s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cluster-admin
+subjects:
+  - kind: ServiceAccount
+    name: %[1]s
+    namespace: kube-system
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: %[1]s-token
+  namespace: kube-system
+  annotations:
+    kubernetes.io/service-account.name: %[1]s
+type: kubernetes.io/service-account-token`, account)
+
+	if err := target.Cluster.Apply(ctx, manifest); err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	// The token controller populates the secret asynchronously.
+	var token, ca string
+	for attempt := 0; attempt < 10; attempt++ {
+		out, err := target.Cluster.RunCommand(ctx, "get", "secret", account+"-token",
+			"-n", "kube-system", "-o", `jsonpath={.data.token}{" "}{.data.ca\.crt}`)
+		if err == nil {
+			fields := strings.Fields(out)
+			if len(fields) == 2 {
+				token, ca = fields[0], fields[1]
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+	if token == "" {
+		return nil, fmt.Errorf("timed out waiting for service account token")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode service account token: %w", err)
+	}
+
+	return &RemoteCredentials{BearerToken: string(decoded), CAData: ca}, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,390 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+)
+
+func testTargets(names ...string) []ClusterTarget {
+	targets := make([]ClusterTarget, 0, len(names))
+	for _, n := range names {
+		targets = append(targets, ClusterTarget{
+			Name:        n,
+			Environment: n,
+			Cluster:     cluster.New("https://"+n+".example.com:6443", n, cluster.PlatformKubernetes),
+		})
+	}
+	return targets
+}
+
+func TestNewMultiCluster_Validation(t *testing.T) {
+	argo := &Options{Tool: ToolArgoCD}
+
+	tests := []struct {
+		name    string
+		targets []ClusterTarget
+		opts    *MultiClusterOptions
+		wantErr string
+	}{
+		{"no targets", nil, &MultiClusterOptions{Options: argo}, "no clusters"},
+		{"no options", testTargets("dev"), &MultiClusterOptions{}, "options are required"},
+		{"duplicate", testTargets("dev", "dev"), &MultiClusterOptions{Options: argo}, "duplicate"},
+		{"unknown strategy", testTargets("dev"), &MultiClusterOptions{Options: argo, Strategy: "mesh"}, "unsupported"},
+		{"hub without hub", testTargets("dev"), &MultiClusterOptions{Options: argo, Strategy: StrategyHub}, "requires a hub"},
+		{"hub not a target", testTargets("dev"), &MultiClusterOptions{Options: argo, Strategy: StrategyHub, Hub: "prod"}, "not one of"},
+		{"hub with another tool", testTargets("dev"), &MultiClusterOptions{Options: &Options{Tool: "rancher"}, Strategy: StrategyHub, Hub: "dev"}, "requires argocd or flux"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMultiCluster(tt.targets, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewMultiCluster() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewMultiCluster_Defaults(t *testing.T) {
+	opts := &MultiClusterOptions{Options: &Options{Tool: ToolArgoCD}}
+	if _, err := NewMultiCluster(testTargets("dev"), opts); err != nil {
+		t.Fatalf("NewMultiCluster() error = %v", err)
+	}
+	if opts.Strategy != StrategyStandalone {
+		t.Errorf("Strategy = %s, want %s", opts.Strategy, StrategyStandalone)
+	}
+	if opts.Concurrency != 4 {
+		t.Errorf("Concurrency = %d, want 4", opts.Concurrency)
+	}
+}
+
+func TestMultiClusterBootstrap_Standalone(t *testing.T) {
+	var mu sync.Mutex
+	var events []ProgressEvent
+	projects := map[string]string{}
+
+	m, err := NewMultiCluster(testTargets("dev", "staging", "prod"), &MultiClusterOptions{
+		Options:     &Options{Tool: ToolArgoCD, ProjectName: "platform"},
+		Concurrency: 2,
+		OnProgress:  func(e ProgressEvent) { events = append(events, e) },
+	})
+	if err != nil {
+		t.Fatalf("NewMultiCluster() error = %v", err)
+	}
+	m.bootstrap = func(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error) {
+		mu.Lock()
+		projects[target.Name] = opts.ProjectName
+		mu.Unlock()
+		if target.Name == "staging" {
+			return nil, errors.New("install failed")
+		}
+		return &Result{Tool: opts.Tool, Ready: true}, nil
+	}
+
+	result := m.Bootstrap(context.Background())
+
+	if result.Succeeded != 2 || result.Failed != 1 {
+		t.Errorf("Succeeded/Failed = %d/%d, want 2/1", result.Succeeded, result.Failed)
+	}
+	if !result.HasFailures() {
+		t.Error("HasFailures() = false, want true")
+	}
+	for i, name := range []string{"dev", "staging", "prod"} {
+		if result.Clusters[i].Name != name {
+			t.Errorf("Clusters[%d].Name = %s, want %s", i, result.Clusters[i].Name, name)
+		}
+		if result.Clusters[i].Role != RoleStandalone {
+			t.Errorf("Clusters[%d].Role = %s, want %s", i, result.Clusters[i].Role, RoleStandalone)
+		}
+	}
+	if projects["prod"] != "platform-prod" {
+		t.Errorf("ProjectName = %s, want platform-prod", projects["prod"])
+	}
+	if len(events) != 6 {
+		t.Errorf("got %d progress events, want 6", len(events))
+	}
+}
+
+func TestMultiClusterBootstrap_Hub(t *testing.T) {
+	var mu sync.Mutex
+	applied := map[string][]string{}
+	var bootstrapped []string
+
+	m, err := NewMultiCluster(testTargets("hub", "dev", "prod"), &MultiClusterOptions{
+		Options:  &Options{Tool: ToolArgoCD, Namespace: "openshift-gitops"},
+		Strategy: StrategyHub,
+		Hub:      "hub",
+	})
+	if err != nil {
+		t.Fatalf("NewMultiCluster() error = %v", err)
+	}
+	m.bootstrap = func(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error) {
+		bootstrapped = append(bootstrapped, target.Name)
+		return &Result{Tool: opts.Tool, Ready: true, URL: "https://argocd.hub"}, nil
+	}
+	m.prepareRemote = func(ctx context.Context, target *ClusterTarget) (*RemoteCredentials, error) {
+		return &RemoteCredentials{BearerToken: "token-" + target.Name, CAData: "Y2E="}, nil
+	}
+	m.apply = func(ctx context.Context, c *cluster.Cluster, manifest string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		applied[c.GetName()] = append(applied[c.GetName()], manifest)
+		return nil
+	}
+
+	result := m.Bootstrap(context.Background())
+
+	if result.Failed != 0 {
+		t.Fatalf("Failed = %d, want 0: %+v", result.Failed, result.Clusters)
+	}
+	if len(bootstrapped) != 1 || bootstrapped[0] != "hub" {
+		t.Errorf("bootstrapped = %v, want [hub]", bootstrapped)
+	}
+	if len(applied["hub"]) != 2 {
+		t.Fatalf("got %d manifests applied to hub, want 2", len(applied["hub"]))
+	}
+	if result.Clusters[0].Role != RoleHub || result.Clusters[1].Role != RoleSpoke {
+		t.Errorf("roles = %s, %s", result.Clusters[0].Role, result.Clusters[1].Role)
+	}
+
+	secret := result.Clusters[2].ClusterSecret
+	for _, want := range []string{
+		"name: cluster-prod",
+		"namespace: openshift-gitops",
+		"argocd.argoproj.io/secret-type: cluster",
+		"server: https://prod.example.com:6443",
+		`"bearerToken":"token-prod"`,
+		`"caData":"Y2E="`,
+	} {
+		if !strings.Contains(secret, want) {
+			t.Errorf("cluster secret missing %q:\n%s", want, secret)
+		}
+	}
+}
+
+func TestMultiClusterBootstrap_HubFailureSkipsSpokes(t *testing.T) {
+	m, err := NewMultiCluster(testTargets("hub", "dev"), &MultiClusterOptions{
+		Options:  &Options{Tool: ToolArgoCD},
+		Strategy: StrategyHub,
+		Hub:      "hub",
+	})
+	if err != nil {
+		t.Fatalf("NewMultiCluster() error = %v", err)
+	}
+	m.bootstrap = func(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error) {
+		return nil, errors.New("no permissions")
+	}
+	m.prepareRemote = func(ctx context.Context, target *ClusterTarget) (*RemoteCredentials, error) {
+		t.Error("prepareRemote called after hub failure")
+		return nil, nil
+	}
+
+	result := m.Bootstrap(context.Background())
+	if result.Failed != 2 {
+		t.Errorf("Failed = %d, want 2", result.Failed)
+	}
+}
+
+func TestClusterSecret_Insecure(t *testing.T) {
+	target := testTargets("dev")[0]
+	target.Labels = map[string]string{"region": "eu-west-1"}
+
+	secret, err := ClusterSecret(&target, "argocd", &RemoteCredentials{BearerToken: "abc"})
+	if err != nil {
+		t.Fatalf("ClusterSecret() error = %v", err)
+	}
+	if !strings.Contains(secret, `"tlsClientConfig":{"insecure":false}`) {
+		t.Errorf("expected TLS verified with the system roots without CA data:\n%s", secret)
+	}
+
+	secret, err = ClusterSecret(&target, "argocd", &RemoteCredentials{BearerToken: "abc", Insecure: true})
+	if err != nil {
+		t.Fatalf("ClusterSecret() error = %v", err)
+	}
+	if !strings.Contains(secret, `"insecure":true`) {
+		t.Errorf("expected insecure TLS when opted in:\n%s", secret)
+	}
+	if !strings.Contains(secret, `region: "eu-west-1"`) {
+		t.Errorf("expected region label:\n%s", secret)
+	}
+
+	target.Cluster = cluster.New("", "dev", cluster.PlatformKubernetes)
+	if _, err := ClusterSecret(&target, "argocd", &RemoteCredentials{}); err == nil {
+		t.Error("expected error for cluster without URL")
+	}
+}
+
+func TestClusterSecret_RemoteCredentials(t *testing.T) {
+	target := testTargets("prod")[0]
+
+	secret, err := ClusterSecret(&target, "argocd", &RemoteCredentials{
+		CAData:   "Y2E=",
+		CertData: "Y2VydA==",
+		KeyData:  "a2V5",
+		Password: "it's",
+		Username: "admin",
+	})
+	if err != nil {
+		t.Fatalf("ClusterSecret() error = %v", err)
+	}
+	for _, want := range []string{
+		`"tlsClientConfig":{"insecure":false,"caData":"Y2E=","certData":"Y2VydA==","keyData":"a2V5"}`,
+		`"password":"it''s"`,
+	} {
+		if !strings.Contains(secret, want) {
+			t.Errorf("cluster secret missing %q:\n%s", want, secret)
+		}
+	}
+	if strings.Contains(secret, "bearerToken") {
+		t.Errorf("unexpected bearer token:\n%s", secret)
+	}
+
+	secret, err = ClusterSecret(&target, "argocd", &RemoteCredentials{
+		CAData:         "Y2E=",
+		AWSClusterName: "prod",
+		AWSRoleARN:     "arn:aws:iam::1:role/argocd",
+	})
+	if err != nil {
+		t.Fatalf("ClusterSecret() error = %v", err)
+	}
+	if !strings.Contains(secret, `"awsAuthConfig":{"clusterName":"prod","roleARN":"arn:aws:iam::1:role/argocd"}`) {
+		t.Errorf("expected awsAuthConfig:\n%s", secret)
+	}
+
+	secret, err = ClusterSecret(&target, "argocd", &RemoteCredentials{
+		CAData: "Y2E=",
+		Exec:   &ExecProvider{Command: "kubelogin", Args: []string{"get-token"}, APIVersion: "client.authentication.k8s.io/v1beta1"},
+	})
+	if err != nil {
+		t.Fatalf("ClusterSecret() error = %v", err)
+	}
+	if !strings.Contains(secret, `"execProviderConfig":{"command":"kubelogin","args":["get-token"],"apiVersion":"client.authentication.k8s.io/v1beta1"}`) {
+		t.Errorf("expected execProviderConfig:\n%s", secret)
+	}
+}
+
+func TestMultiClusterBootstrap_StoredRemoteCredentials(t *testing.T) {
+	targets := testTargets("hub", "prod")
+	targets[1].Remote = &RemoteCredentials{BearerToken: "stored", CAData: "Y2E="}
+	m, err := NewMultiCluster(targets, &MultiClusterOptions{
+		Options:  &Options{Tool: ToolArgoCD},
+		Strategy: StrategyHub,
+		Hub:      "hub",
+	})
+	if err != nil {
+		t.Fatalf("NewMultiCluster() error = %v", err)
+	}
+	m.bootstrap = func(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error) {
+		return &Result{Tool: opts.Tool, Ready: true}, nil
+	}
+	m.prepareRemote = func(ctx context.Context, target *ClusterTarget) (*RemoteCredentials, error) {
+		t.Errorf("prepareRemote called for %s with stored credentials", target.Name)
+		return nil, errors.New("unexpected")
+	}
+	m.apply = func(ctx context.Context, c *cluster.Cluster, manifest string) error { return nil }
+
+	result := m.Bootstrap(context.Background())
+	if result.Failed != 0 {
+		t.Fatalf("Failed = %d: %+v", result.Failed, result.Clusters)
+	}
+	if !strings.Contains(result.Clusters[1].ClusterSecret, `"bearerToken":"stored"`) {
+		t.Errorf("expected the stored token:\n%s", result.Clusters[1].ClusterSecret)
+	}
+}
+
+func TestKubeconfigSecret(t *testing.T) {
+	target := testTargets("prod")[0]
+	target.Labels = map[string]string{"region": "eu-west-1"}
+
+	secret, err := KubeconfigSecret(&target, "flux-system", &RemoteCredentials{BearerToken: "abc", CAData: "Y2E="})
+	if err != nil {
+		t.Fatalf("KubeconfigSecret() error = %v", err)
+	}
+	for _, want := range []string{
+		"name: prod-kubeconfig",
+		"namespace: flux-system",
+		"env: prod",
+		"region: eu-west-1",
+		"value: |",
+		"server: https://prod.example.com:6443",
+		"certificate-authority-data: Y2E=",
+		"token: abc",
+	} {
+		if !strings.Contains(secret, want) {
+			t.Errorf("kubeconfig secret missing %q:\n%s", want, secret)
+		}
+	}
+
+	secret, err = KubeconfigSecret(&target, "flux-system", &RemoteCredentials{CertData: "Y2VydA==", KeyData: "a2V5"})
+	if err != nil {
+		t.Fatalf("KubeconfigSecret() error = %v", err)
+	}
+	for _, want := range []string{"client-certificate-data: Y2VydA==", "client-key-data: a2V5"} {
+		if !strings.Contains(secret, want) {
+			t.Errorf("kubeconfig secret missing %q:\n%s", want, secret)
+		}
+	}
+	if strings.Contains(secret, "insecure-skip-tls-verify") {
+		t.Errorf("TLS verification must not be skipped without opting in:\n%s", secret)
+	}
+
+	secret, err = KubeconfigSecret(&target, "flux-system", &RemoteCredentials{BearerToken: "abc", Insecure: true})
+	if err != nil {
+		t.Fatalf("KubeconfigSecret() error = %v", err)
+	}
+	if !strings.Contains(secret, "insecure-skip-tls-verify: true") {
+		t.Errorf("kubeconfig secret should skip TLS verification when opted in:\n%s", secret)
+	}
+
+	if _, err := KubeconfigSecret(&target, "flux-system", &RemoteCredentials{Exec: &ExecProvider{Command: "aws"}}); err == nil {
+		t.Error("expected an error for exec credentials")
+	}
+	if _, err := KubeconfigSecret(&target, "flux-system", &RemoteCredentials{CAData: "not base64"}); err == nil {
+		t.Error("expected an error for invalid CA data")
+	}
+}
+
+func TestMultiClusterBootstrap_FluxHub(t *testing.T) {
+	targets := testTargets("hub", "prod")
+	targets[1].Remote = &RemoteCredentials{BearerToken: "stored", CAData: "Y2E="}
+	m, err := NewMultiCluster(targets, &MultiClusterOptions{
+		Options:  &Options{Tool: ToolFlux, Mode: SuggestMode(ToolFlux, "kubernetes")},
+		Strategy: StrategyHub,
+		Hub:      "hub",
+	})
+	if err != nil {
+		t.Fatalf("NewMultiCluster() error = %v", err)
+	}
+	m.bootstrap = func(ctx context.Context, target *ClusterTarget, opts *Options) (*Result, error) {
+		return &Result{Tool: opts.Tool, Ready: true}, nil
+	}
+	var applied []string
+	m.apply = func(ctx context.Context, c *cluster.Cluster, manifest string) error {
+		if c.GetName() != "hub" {
+			t.Errorf("secret applied to %s", c.GetName())
+		}
+		applied = append(applied, manifest)
+		return nil
+	}
+
+	result := m.Bootstrap(context.Background())
+	if result.Failed != 0 {
+		t.Fatalf("Failed = %d: %+v", result.Failed, result.Clusters)
+	}
+	if len(applied) != 1 || !strings.Contains(applied[0], "name: prod-kubeconfig") || !strings.Contains(applied[0], "namespace: flux-system") {
+		t.Errorf("unexpected manifests applied to the hub: %v", applied)
+	}
+
+	plans, err := m.Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if want := "apply secret prod-kubeconfig with the stored credentials to namespace flux-system of hub hub"; plans[1].Registration != want {
+		t.Errorf("Registration = %q, want %q", plans[1].Registration, want)
+	}
+}
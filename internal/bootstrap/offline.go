@@ -0,0 +1,86 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+// ManifestSource provides vendored install manifests for offline installs.
+type ManifestSource interface {
+	// Manifest returns the install manifest of tool ("argocd" or "flux") at
+	// version; an empty version selects the newest available.
+	Manifest(tool, version string) ([]byte, error)
+}
+
+// offlineManifest returns the vendored install manifest of the tool.
+func (b *Bootstrapper) offlineManifest() ([]byte, error) {
+	if b.options.Manifests == nil {
+		return nil, fmt.Errorf("offline install of %s requires an offline bundle", b.options.Tool)
+	}
+	data, err := b.options.Manifests.Manifest(string(b.options.Tool), b.options.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s from the offline bundle: %w", b.options.Tool, err)
+	}
+	return data, nil
+}
+
+// offlinePaths returns the additional manifests to apply, which must be
+// local files.
+func (b *Bootstrapper) offlinePaths() ([]string, error) {
+	if b.options.Manifest == nil {
+		return nil, nil
+	}
+	for _, path := range b.options.Manifest.Paths {
+		if strings.Contains(path, "://") && !strings.HasPrefix(path, "file://") {
+			return nil, fmt.Errorf("manifest %s needs network access: offline mode only applies local files", path)
+		}
+	}
+	return b.options.Manifest.Paths, nil
+}
+
+// installOffline applies the vendored install manifest and any additional
+// local manifests.
+func (b *Bootstrapper) installOffline(ctx context.Context) error {
+	data, err := b.offlineManifest()
+	if err != nil {
+		return err
+	}
+	paths, err := b.offlinePaths()
+	if err != nil {
+		return err
+	}
+
+	if output, err := kubectlWithInput(ctx, data, "apply", "-n", b.options.Namespace, "-f", "-"); err != nil {
+		return fmt.Errorf("failed to apply %s manifests: %w: %s", b.options.Tool, err, string(output))
+	}
+	for _, path := range paths {
+		cmd := exec.CommandContext(ctx, "kubectl", "apply", "-n", b.options.Namespace, "-f", strings.TrimPrefix(path, "file://"))
+		if output, err := logging.CombinedOutput(cmd); err != nil {
+			return fmt.Errorf("failed to apply manifest %s: %w: %s", path, err, string(output))
+		}
+	}
+	return nil
+}
+
+// uninstallOffline deletes the resources of the vendored install manifest.
+func (b *Bootstrapper) uninstallOffline(ctx context.Context) error {
+	data, err := b.offlineManifest()
+	if err != nil {
+		return err
+	}
+	if output, err := kubectlWithInput(ctx, data, "delete", "--ignore-not-found", "-n", b.options.Namespace, "-f", "-"); err != nil {
+		return fmt.Errorf("failed to delete %s manifests: %w: %s", b.options.Tool, err, string(output))
+	}
+	return nil
+}
+
+func kubectlWithInput(ctx context.Context, input []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	return logging.CombinedOutput(cmd)
+}
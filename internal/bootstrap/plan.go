@@ -0,0 +1,146 @@
+package bootstrap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan describes what Bootstrap installs and creates, without touching the
+// cluster.
+type Plan struct {
+	Tool      Tool   `json:"tool" yaml:"tool"`
+	Mode      Mode   `json:"mode" yaml:"mode"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Version   string `json:"version,omitempty" yaml:"version,omitempty"`
+	HA        bool   `json:"ha,omitempty" yaml:"ha,omitempty"`
+	// Helm is the chart release installed in helm mode.
+	Helm *HelmPlan `json:"helm,omitempty" yaml:"helm,omitempty"`
+	// Manifests are the install manifests applied, by URL or path.
+	Manifests []string `json:"manifests,omitempty" yaml:"manifests,omitempty"`
+	// Kustomizations are the kustomizations applied with kubectl apply -k.
+	Kustomizations []string `json:"kustomizations,omitempty" yaml:"kustomizations,omitempty"`
+	// Command is the external command running the install, if any.
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Resources are created in order once the tool is installed.
+	Resources []PlannedResource `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
+
+// HelmPlan is the release Bootstrap installs or upgrades in helm mode.
+type HelmPlan struct {
+	Release string `json:"release" yaml:"release"`
+	Repo    string `json:"repo" yaml:"repo"`
+	Chart   string `json:"chart" yaml:"chart"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Values are the profile values overridden by the merged values files,
+	// inline values and set values.
+	Values map[string]any `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// PlannedResource is a resource Bootstrap applies.
+type PlannedResource struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Manifest  string `json:"manifest" yaml:"manifest"`
+}
+
+// Plan returns what Bootstrap would do with the current options. It is built
+// from the same manifests Bootstrap applies.
+func (b *Bootstrapper) Plan() (*Plan, error) {
+	plan := &Plan{
+		Tool:      b.options.Tool,
+		Mode:      b.options.Mode,
+		Namespace: b.options.Namespace,
+		Version:   b.options.Version,
+		HA:        b.options.HA,
+	}
+	if err := b.validateHA(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b.options.Tool != ToolArgoCD && b.options.Tool != ToolFlux:
+		return nil, fmt.Errorf("unsupported GitOps tool: %s", b.options.Tool)
+	case b.options.Offline && (b.options.Tool == ToolFlux || b.options.Mode != ModeOLM):
+		version := b.options.Version
+		if version == "" {
+			version = "latest"
+		}
+		plan.Manifests = append(plan.Manifests, fmt.Sprintf("offline bundle: %s %s", b.options.Tool, version))
+		paths, err := b.offlinePaths()
+		if err != nil {
+			return nil, err
+		}
+		plan.Manifests = append(plan.Manifests, paths...)
+	case b.options.Mode == ModeHelm:
+		helmCfg, release := b.helmConfig(), b.helmReleaseName()
+		rel := &HelmRelease{
+			Values:        helmCfg.Values,
+			ValuesFiles:   helmCfg.ValuesFiles,
+			SetValues:     helmCfg.SetValues,
+			ProfileValues: b.profileValues(),
+		}
+		values, err := rel.MergedValues()
+		if err != nil {
+			return nil, err
+		}
+		version := helmCfg.Version
+		if version == "" {
+			version = b.options.Version
+		}
+		plan.Helm = &HelmPlan{Release: release, Repo: helmCfg.Repo, Chart: helmCfg.Chart, Version: version, Values: values}
+	case b.options.Mode == ModeManifest && b.options.Tool == ToolArgoCD:
+		plan.Manifests = b.argoCDManifests()
+	case b.options.Mode == ModeManifest:
+		plan.Command = "flux install --namespace " + b.options.Namespace
+	case b.options.Mode == ModeKustomize && b.options.Tool == ToolArgoCD:
+		plan.Kustomizations = []string{b.argoCDKustomizeURL()}
+	case b.options.Mode == ModeKustomize:
+		plan.Kustomizations = []string{b.fluxKustomizeURL()}
+	case b.options.Mode == ModeOLM && b.options.Tool == ToolArgoCD:
+		operatorGroup, subscription := b.argoCDOLMManifests()
+		plan.Resources = append(plan.Resources,
+			plannedResource(operatorGroup),
+			plannedResource(subscription))
+	default:
+		return nil, fmt.Errorf("unsupported installation mode for %s: %s", b.options.Tool, b.options.Mode)
+	}
+
+	if b.options.ConfigureRepo && b.options.RepoURL != "" {
+		manifests := append(b.repositoryManifests(b.options.RepoCredentials.masked()), b.additionalRepoManifests(true)...)
+		for _, manifest := range manifests {
+			plan.Resources = append(plan.Resources, plannedResource(manifest))
+		}
+	}
+	if b.options.Tool == ToolArgoCD {
+		for _, proj := range b.argoCDProjectManifests() {
+			plan.Resources = append(plan.Resources, plannedResource(proj.manifest))
+		}
+	}
+	if b.options.CreateAppOfApps {
+		plan.Resources = append(plan.Resources, plannedResource(b.appOfAppsManifest()))
+	}
+	return plan, nil
+}
+
+// plannedResource reads the kind, name and namespace of a manifest rendered
+// by Bootstrap.
+func plannedResource(manifest string) PlannedResource {
+	res := PlannedResource{Manifest: manifest}
+	inMetadata := false
+	for _, line := range strings.Split(manifest, "\n") {
+		switch {
+		case strings.HasPrefix(line, "kind: "):
+			res.Kind = strings.TrimPrefix(line, "kind: ")
+		case line == "metadata:":
+			inMetadata = true
+		case inMetadata && strings.HasPrefix(line, "  name: ") && res.Name == "":
+			res.Name = strings.TrimPrefix(line, "  name: ")
+		case inMetadata && strings.HasPrefix(line, "  namespace: "):
+			res.Namespace = strings.TrimPrefix(line, "  namespace: ")
+		case !strings.HasPrefix(line, " "):
+			inMetadata = false
+		}
+	}
+	return res
+}
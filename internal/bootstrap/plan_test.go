@@ -0,0 +1,152 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan_ArgoCDHelm(t *testing.T) {
+	values := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(values, []byte("server:\n  replicas: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(nil, &Options{
+		Tool:            ToolArgoCD,
+		Mode:            ModeHelm,
+		Version:         "7.3.0",
+		ConfigureRepo:   true,
+		RepoURL:         "https://github.com/acme/platform.git",
+		CreateAppOfApps: true,
+		ProjectName:     "platform",
+		Helm: &HelmConfig{
+			ValuesFiles: []string{values},
+			SetValues:   map[string]string{"dex.enabled": "false"},
+		},
+	})
+
+	plan, err := b.Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if plan.Namespace != "argocd" || plan.Helm == nil {
+		t.Fatalf("Plan() = %+v", plan)
+	}
+	if plan.Helm.Release != "argocd" || plan.Helm.Chart != "argo-cd" || plan.Helm.Version != "7.3.0" {
+		t.Errorf("Helm = %+v", plan.Helm)
+	}
+	if got := fmt.Sprint(plan.Helm.Values); got != "map[dex:map[enabled:false] server:map[replicas:2]]" {
+		t.Errorf("Helm.Values = %s", got)
+	}
+
+	var kinds []string
+	for _, r := range plan.Resources {
+		kinds = append(kinds, r.Kind+"/"+r.Name)
+		if r.Namespace != "argocd" {
+			t.Errorf("%s/%s namespace = %q", r.Kind, r.Name, r.Namespace)
+		}
+	}
+	wantKinds := []string{"Secret/repo-platform", "AppProject/infrastructure", "AppProject/applications", "Application/platform-root"}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("Resources = %v, want %v", kinds, wantKinds)
+	}
+	if !strings.Contains(plan.Resources[3].Manifest, "repoURL: https://github.com/acme/platform.git") {
+		t.Errorf("App-of-Apps manifest = %s", plan.Resources[3].Manifest)
+	}
+}
+
+func TestPlan_Modes(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  *Options
+		check func(t *testing.T, plan *Plan)
+	}{
+		{
+			name: "argocd manifest",
+			opts: &Options{Tool: ToolArgoCD, Mode: ModeManifest, Version: "v2.12.0", Manifest: &ManifestConfig{Paths: []string{"extra.yaml"}}},
+			check: func(t *testing.T, plan *Plan) {
+				want := []string{"https://raw.githubusercontent.com/argoproj/argo-cd/v2.12.0/manifests/install.yaml", "extra.yaml"}
+				if !reflect.DeepEqual(plan.Manifests, want) {
+					t.Errorf("Manifests = %v, want %v", plan.Manifests, want)
+				}
+			},
+		},
+		{
+			name: "argocd olm",
+			opts: &Options{Tool: ToolArgoCD, Mode: ModeOLM, Namespace: "openshift-gitops"},
+			check: func(t *testing.T, plan *Plan) {
+				if plan.Resources[0].Kind != "OperatorGroup" || plan.Resources[1].Kind != "Subscription" {
+					t.Errorf("Resources = %+v", plan.Resources)
+				}
+			},
+		},
+		{
+			name: "flux manifest",
+			opts: &Options{Tool: ToolFlux, Mode: ModeManifest},
+			check: func(t *testing.T, plan *Plan) {
+				if plan.Command != "flux install --namespace flux-system" || len(plan.Resources) != 0 {
+					t.Errorf("Plan() = %+v", plan)
+				}
+			},
+		},
+		{
+			name: "flux kustomize",
+			opts: &Options{Tool: ToolFlux, Mode: ModeKustomize},
+			check: func(t *testing.T, plan *Plan) {
+				if len(plan.Kustomizations) != 1 || plan.Kustomizations[0] != "https://github.com/fluxcd/flux2/manifests/install" {
+					t.Errorf("Kustomizations = %v", plan.Kustomizations)
+				}
+			},
+		},
+		{
+			name: "offline",
+			opts: &Options{Tool: ToolArgoCD, Mode: ModeHelm, Offline: true, Version: "v2.12.0"},
+			check: func(t *testing.T, plan *Plan) {
+				if plan.Helm != nil || len(plan.Manifests) != 1 || plan.Manifests[0] != "offline bundle: argocd v2.12.0" {
+					t.Errorf("Plan() = %+v", plan)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := New(nil, tt.opts).Plan()
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			tt.check(t, plan)
+		})
+	}
+}
+
+func TestPlan_UnsupportedMode(t *testing.T) {
+	if _, err := New(nil, &Options{Tool: ToolFlux, Mode: ModeOLM}).Plan(); err == nil {
+		t.Error("Plan() should fail for Flux with olm")
+	}
+}
+
+func TestMultiClusterPlan_Hub(t *testing.T) {
+	mcb, err := NewMultiCluster(testTargets("prod", "dev"), &MultiClusterOptions{
+		Strategy: StrategyHub,
+		Hub:      "prod",
+		Options:  &Options{Tool: ToolArgoCD, Mode: ModeHelm},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := mcb.Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(plans) != 2 || plans[0].Role != RoleHub || plans[0].Plan == nil {
+		t.Fatalf("Plan() = %+v", plans)
+	}
+	if plans[1].Role != RoleSpoke || plans[1].Plan != nil || !strings.Contains(plans[1].Registration, "cluster-dev") {
+		t.Errorf("spoke plan = %+v", plans[1])
+	}
+}
@@ -0,0 +1,123 @@
+package bootstrap
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoCredentials is the credential material the GitOps tool pulls the
+// repository with. Set either Username and Password (a token is the
+// password), SSHPrivateKey, or the GitHub App fields.
+type RepoCredentials struct {
+	Username      string
+	Password      string
+	SSHPrivateKey string
+	SSHKnownHosts string
+
+	GitHubAppID                int64
+	GitHubAppInstallationID    int64
+	GitHubAppPrivateKey        string
+	GitHubAppEnterpriseBaseURL string
+}
+
+// Repository is an additional repository ArgoCD pulls from. A private
+// repository needs Credentials.
+type Repository struct {
+	Name        string
+	URL         string
+	Credentials *RepoCredentials
+}
+
+// SSH reports whether the credentials authenticate with an SSH key.
+func (c *RepoCredentials) SSH() bool {
+	return c.SSHPrivateKey != ""
+}
+
+// GitHubApp reports whether the credentials are a GitHub App installation.
+func (c *RepoCredentials) GitHubApp() bool {
+	return c.GitHubAppID != 0
+}
+
+// masked returns a copy of the credentials with the secret values replaced,
+// for plans.
+func (c *RepoCredentials) masked() *RepoCredentials {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	for _, v := range []*string{&out.Password, &out.SSHPrivateKey, &out.GitHubAppPrivateKey} {
+		if *v != "" {
+			*v = "********"
+		}
+	}
+	return &out
+}
+
+// argoCDData returns the repository secret keys ArgoCD reads the credentials
+// from.
+func (c *RepoCredentials) argoCDData() map[string]string {
+	data := map[string]string{}
+	switch {
+	case c.SSH():
+		data["sshPrivateKey"] = c.SSHPrivateKey
+	case c.GitHubApp():
+		addGitHubAppKeys(data, c, "githubAppEnterpriseBaseUrl")
+	default:
+		data["username"] = c.Username
+		data["password"] = c.Password
+	}
+	return data
+}
+
+// fluxData returns the keys of the secret a Flux GitRepository references.
+func (c *RepoCredentials) fluxData() map[string]string {
+	data := map[string]string{}
+	switch {
+	case c.SSH():
+		data["identity"] = c.SSHPrivateKey
+		if c.SSHKnownHosts != "" {
+			data["known_hosts"] = c.SSHKnownHosts
+		}
+	case c.GitHubApp():
+		addGitHubAppKeys(data, c, "githubAppBaseURL")
+	default:
+		data["username"] = c.Username
+		data["password"] = c.Password
+	}
+	return data
+}
+
+// addGitHubAppKeys adds the GitHub App keys; the key of the Enterprise API
+// URL differs between ArgoCD and Flux.
+func addGitHubAppKeys(data map[string]string, c *RepoCredentials, baseURLKey string) {
+	data["githubAppID"] = strconv.FormatInt(c.GitHubAppID, 10)
+	data["githubAppInstallationID"] = strconv.FormatInt(c.GitHubAppInstallationID, 10)
+	data["githubAppPrivateKey"] = c.GitHubAppPrivateKey
+	if c.GitHubAppEnterpriseBaseURL != "" {
+		data[baseURLKey] = c.GitHubAppEnterpriseBaseURL
+	}
+}
+
+// secretManifest renders a Secret. Unlike the other manifests it is encoded
+// rather than formatted, as credential values span lines.
+func secretManifest(name, namespace string, labels, stringData map[string]string) string {
+	type metadata struct {
+		Name      string            `yaml:"name"`
+		Namespace string            `yaml:"namespace"`
+		Labels    map[string]string `yaml:"labels,omitempty"`
+	}
+	secret := struct {
+		APIVersion string            `yaml:"apiVersion"`
+		Kind       string            `yaml:"kind"`
+		Metadata   metadata          `yaml:"metadata"`
+		StringData map[string]string `yaml:"stringData"`
+	}{"v1", "Secret", metadata{name, namespace, labels}, stringData}
+
+	var out strings.Builder
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	_ = enc.Encode(secret)
+	return strings.TrimSpace(out.String())
+}
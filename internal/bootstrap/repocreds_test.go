@@ -0,0 +1,104 @@
+package bootstrap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArgoCDRepoManifest_Credentials(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolArgoCD, ProjectName: "platform", RepoURL: "git@github.com:acme/platform.git"})
+
+	manifest := b.argoCDRepoManifest(&RepoCredentials{SSHPrivateKey: "-----BEGIN KEY-----\nabc\n-----END KEY-----\n"})
+	for _, want := range []string{
+		"  name: repo-platform\n  namespace: argocd\n",
+		"argocd.argoproj.io/secret-type: repository",
+		"  sshPrivateKey: |\n    -----BEGIN KEY-----\n    abc\n",
+		"  url: git@github.com:acme/platform.git",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest is missing %q:\n%s", want, manifest)
+		}
+	}
+
+	manifest = b.argoCDRepoManifest(&RepoCredentials{Username: "git", Password: "tok"})
+	if !strings.Contains(manifest, "password: tok") || !strings.Contains(manifest, "username: git") {
+		t.Errorf("token manifest = %s", manifest)
+	}
+	if manifest := b.argoCDRepoManifest(nil); strings.Contains(manifest, "password") {
+		t.Errorf("public repository manifest = %s", manifest)
+	}
+}
+
+func TestFluxRepoManifests_Credentials(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolFlux, ProjectName: "platform", RepoURL: "git@github.com:acme/platform.git"})
+
+	manifests := b.fluxRepoManifests(&RepoCredentials{SSHPrivateKey: "KEY", SSHKnownHosts: "github.com ssh-ed25519 AAAA"})
+	if len(manifests) != 2 {
+		t.Fatalf("fluxRepoManifests() = %d manifests, want a secret and a GitRepository", len(manifests))
+	}
+	if res := plannedResource(manifests[0]); res.Kind != "Secret" || res.Name != "platform-git-credentials" || !strings.Contains(res.Manifest, "identity: KEY") {
+		t.Errorf("secret = %s", manifests[0])
+	}
+	if !strings.Contains(manifests[1], "url: ssh://git@github.com/acme/platform.git") || !strings.Contains(manifests[1], "secretRef:\n    name: platform-git-credentials") {
+		t.Errorf("GitRepository = %s", manifests[1])
+	}
+
+	manifests = b.fluxRepoManifests(&RepoCredentials{GitHubAppID: 1, GitHubAppInstallationID: 2, GitHubAppPrivateKey: "PEM"})
+	if !strings.Contains(manifests[1], "provider: github") || !strings.Contains(manifests[0], "githubAppInstallationID: \"2\"") {
+		t.Errorf("GitHub App manifests = %v", manifests)
+	}
+
+	if manifests := b.fluxRepoManifests(nil); len(manifests) != 1 || strings.Contains(manifests[0], "secretRef") {
+		t.Errorf("public repository manifests = %v", manifests)
+	}
+}
+
+func TestPlan_MasksRepoCredentials(t *testing.T) {
+	plan, err := New(nil, &Options{
+		Tool:            ToolArgoCD,
+		Mode:            ModeManifest,
+		ConfigureRepo:   true,
+		RepoURL:         "https://github.com/acme/platform.git",
+		ProjectName:     "platform",
+		RepoCredentials: &RepoCredentials{Username: "git", Password: "ghp_secret"},
+	}).Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	secret := plan.Resources[0].Manifest
+	if strings.Contains(secret, "ghp_secret") || !strings.Contains(secret, "password: '********'") {
+		t.Errorf("repository secret in plan = %s", secret)
+	}
+}
+
+func TestPlan_AdditionalRepositories(t *testing.T) {
+	plan, err := New(nil, &Options{
+		Tool:          ToolArgoCD,
+		Mode:          ModeManifest,
+		ConfigureRepo: true,
+		RepoURL:       "https://github.com/acme/platform.git",
+		ProjectName:   "platform",
+		Repositories: []Repository{
+			{Name: "platform-shop", URL: "https://github.com/acme/platform-shop.git", Credentials: &RepoCredentials{Username: "git", Password: "ghp_secret"}},
+			{Name: "platform-ops", URL: "https://github.com/acme/platform-ops.git"},
+		},
+	}).Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(plan.Resources) < 3 {
+		t.Fatalf("Plan() = %d resources", len(plan.Resources))
+	}
+	shop, ops := plan.Resources[1].Manifest, plan.Resources[2].Manifest
+	for _, want := range []string{"name: repo-platform-shop", "url: https://github.com/acme/platform-shop.git", "password: '********'"} {
+		if !strings.Contains(shop, want) {
+			t.Errorf("repository secret is missing %q:\n%s", want, shop)
+		}
+	}
+	if strings.Contains(shop, "ghp_secret") {
+		t.Errorf("repository secret in plan = %s", shop)
+	}
+	if !strings.Contains(ops, "name: repo-platform-ops") || strings.Contains(ops, "password") {
+		t.Errorf("public repository secret = %s", ops)
+	}
+}
@@ -0,0 +1,548 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// argoCDInstallURL is the ArgoCD release install manifest, by version and
+// manifest directory.
+var argoCDInstallURL = func(version, dir string) string {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/%s/install.yaml", version, dir)
+}
+
+// Installation is a GitOps tool installation found on the cluster.
+type Installation struct {
+	Tool      Tool          `json:"tool" yaml:"tool"`
+	Namespace string        `json:"namespace" yaml:"namespace"`
+	Method    InstallMethod `json:"method" yaml:"method"`
+	// Version is the installed version: the chart version for Helm
+	// installs, the application version otherwise.
+	Version string         `json:"version" yaml:"version"`
+	Release *ReleaseStatus `json:"release,omitempty" yaml:"release,omitempty"`
+	// Values are the values the Helm release was installed with.
+	Values map[string]any `json:"-" yaml:"-"`
+}
+
+// CRDChange is a CustomResourceDefinition an upgrade adds, changes or removes.
+type CRDChange struct {
+	Name   string `json:"name" yaml:"name"`
+	Change string `json:"change" yaml:"change"` // added, updated, removed
+	// From and To are the served versions before and after the upgrade.
+	From []string `json:"from,omitempty" yaml:"from,omitempty"`
+	To   []string `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// ValueChange is a Helm value an upgrade changes, by dotted key. A nil From
+// or To means the value is unset.
+type ValueChange struct {
+	Key  string `json:"key" yaml:"key"`
+	From any    `json:"from,omitempty" yaml:"from,omitempty"`
+	To   any    `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// UpgradePlan describes the upgrade of an installation to a version.
+type UpgradePlan struct {
+	Tool      Tool          `json:"tool" yaml:"tool"`
+	Namespace string        `json:"namespace" yaml:"namespace"`
+	Method    InstallMethod `json:"method" yaml:"method"`
+	From      string        `json:"from" yaml:"from"`
+	To        string        `json:"to" yaml:"to"`
+	// Path lists the minor releases the upgrade crosses, ending with To.
+	Path         []string      `json:"path" yaml:"path"`
+	CRDChanges   []CRDChange   `json:"crdChanges,omitempty" yaml:"crdChanges,omitempty"`
+	ValueChanges []ValueChange `json:"valueChanges,omitempty" yaml:"valueChanges,omitempty"`
+	Warnings     []string      `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	// Revision is the Helm release revision a failed upgrade rolls back to.
+	Revision int `json:"revision,omitempty" yaml:"revision,omitempty"`
+
+	manifest []byte
+}
+
+// UpToDate reports whether the installation already runs the target version.
+func (p *UpgradePlan) UpToDate() bool {
+	return len(p.Path) == 0
+}
+
+// UpgradePath returns the minor releases an upgrade from one version to
+// another crosses, ending with the target, and warnings about the jump.
+// Downgrades are refused.
+func UpgradePath(from, to string) ([]string, []string, error) {
+	if from == "" {
+		return nil, nil, fmt.Errorf("the installed version could not be detected")
+	}
+	current, err := semver.NewVersion(from)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid installed version %q: %w", from, err)
+	}
+	target, err := semver.NewVersion(to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid target version %q: %w", to, err)
+	}
+	if target.LessThan(current) {
+		return nil, nil, fmt.Errorf("cannot downgrade from %s to %s", from, to)
+	}
+	if target.Equal(current) {
+		return nil, nil, nil
+	}
+
+	if target.Major() != current.Major() {
+		return []string{to}, []string{fmt.Sprintf("major upgrade from %s to %s: read the release notes for breaking changes", from, to)}, nil
+	}
+	prefix := ""
+	if strings.HasPrefix(to, "v") {
+		prefix = "v"
+	}
+	var path []string
+	for minor := current.Minor() + 1; minor < target.Minor(); minor++ {
+		path = append(path, fmt.Sprintf("%s%d.%d", prefix, target.Major(), minor))
+	}
+	var warnings []string
+	if len(path) > 0 {
+		warnings = append(warnings, fmt.Sprintf("upgrade crosses %s: review the upgrade notes of each", strings.Join(path, ", ")))
+	}
+	return append(path, to), warnings, nil
+}
+
+// DiffCRDs compares the installed CRDs, by name with their served versions,
+// with the CRDs of a target manifest.
+func DiffCRDs(current map[string][]string, manifest []byte) ([]CRDChange, error) {
+	target, err := manifestCRDs(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []CRDChange
+	for name, versions := range target {
+		installed, ok := current[name]
+		switch {
+		case !ok:
+			changes = append(changes, CRDChange{Name: name, Change: "added", To: versions})
+		case !reflect.DeepEqual(installed, versions):
+			changes = append(changes, CRDChange{Name: name, Change: "updated", From: installed, To: versions})
+		}
+	}
+	for name, versions := range current {
+		if _, ok := target[name]; !ok {
+			changes = append(changes, CRDChange{Name: name, Change: "removed", From: versions})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// manifestCRDs returns the CRDs of a multi-document manifest with their
+// served versions.
+func manifestCRDs(manifest []byte) (map[string][]string, error) {
+	crds := map[string][]string{}
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var doc struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+			Spec struct {
+				Versions []struct {
+					Name   string `yaml:"name"`
+					Served bool   `yaml:"served"`
+				} `yaml:"versions"`
+			} `yaml:"spec"`
+		}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if doc.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		var versions []string
+		for _, v := range doc.Spec.Versions {
+			if v.Served {
+				versions = append(versions, v.Name)
+			}
+		}
+		sort.Strings(versions)
+		crds[doc.Metadata.Name] = versions
+	}
+	return crds, nil
+}
+
+// DiffValues compares the values a Helm release was installed with to the
+// values an upgrade installs it with.
+func DiffValues(current, desired map[string]any) []ValueChange {
+	from := map[string]any{}
+	flattenValues("", current, from)
+	to := map[string]any{}
+	flattenValues("", desired, to)
+
+	var changes []ValueChange
+	for key, value := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes = append(changes, ValueChange{Key: key, From: from[key], To: value})
+		}
+	}
+	for key, value := range from {
+		if _, ok := to[key]; !ok {
+			changes = append(changes, ValueChange{Key: key, From: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func flattenValues(prefix string, values map[string]any, out map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenValues(key, nested, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+// DetectInstallation finds how and at which version the GitOps tool is
+// installed in the bootstrap namespace.
+func (b *Bootstrapper) DetectInstallation(ctx context.Context) (*Installation, error) {
+	inst := &Installation{Tool: b.options.Tool, Namespace: b.options.Namespace}
+
+	if upgrader, ok := b.helm.(HelmUpgrader); ok {
+		if status, values, err := upgrader.Get(ctx, b.helmReleaseName(), b.options.Namespace); err == nil {
+			inst.Method = InstallMethodHelm
+			inst.Version = status.ChartVersion
+			inst.Release = status
+			inst.Values = values
+			return inst, nil
+		}
+	}
+	if b.cluster == nil {
+		return nil, fmt.Errorf("%s is not installed with Helm and no cluster is connected", b.options.Tool)
+	}
+
+	switch b.options.Tool {
+	case ToolArgoCD:
+		d := NewDetector(b.kubeContext(), 0)
+		if !d.namespaceExists(ctx, b.options.Namespace) {
+			return nil, fmt.Errorf("ArgoCD is not installed in namespace %s", b.options.Namespace)
+		}
+		inst.Method = d.detectInstallMethod(ctx, b.options.Namespace)
+		inst.Version = d.detectVersion(ctx, b.options.Namespace)
+		if inst.Method == InstallMethodHelm {
+			return nil, fmt.Errorf("ArgoCD in namespace %s was installed with Helm under a release other than %s", b.options.Namespace, b.helmReleaseName())
+		}
+	case ToolFlux:
+		version, err := b.fluxVersion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Flux is not installed in namespace %s: %w", b.options.Namespace, err)
+		}
+		inst.Method = InstallMethodManifest
+		inst.Version = version
+	default:
+		return nil, fmt.Errorf("unsupported GitOps tool: %s", b.options.Tool)
+	}
+	return inst, nil
+}
+
+// PlanUpgrade computes the upgrade of an installation to a version: the
+// upgrade path, the CRD changes and, for Helm installs, the values changes.
+// The cluster is not changed.
+func (b *Bootstrapper) PlanUpgrade(ctx context.Context, inst *Installation, version string) (*UpgradePlan, error) {
+	if version == "" {
+		return nil, fmt.Errorf("a target version is required")
+	}
+	plan := &UpgradePlan{
+		Tool:      inst.Tool,
+		Namespace: inst.Namespace,
+		Method:    inst.Method,
+		From:      inst.Version,
+		To:        version,
+	}
+	if inst.Release != nil {
+		plan.Revision = inst.Release.Revision
+	}
+
+	switch inst.Method {
+	case InstallMethodOLM, InstallMethodOperator:
+		return nil, fmt.Errorf("%s in namespace %s is managed by OLM: upgrade it by changing the subscription channel", inst.Tool, inst.Namespace)
+	}
+
+	path, warnings, err := UpgradePath(inst.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	plan.Path = path
+	plan.Warnings = warnings
+	if plan.UpToDate() {
+		return plan, nil
+	}
+
+	if inst.Method == InstallMethodHelm {
+		upgrader, ok := b.helm.(HelmUpgrader)
+		if !ok {
+			return nil, fmt.Errorf("the Helm installer cannot preview upgrades")
+		}
+		rel := b.upgradeRelease(version)
+		manifest, err := upgrader.Preview(ctx, rel)
+		if err != nil {
+			return nil, err
+		}
+		plan.manifest = []byte(manifest)
+		desired, err := rel.MergedValues()
+		if err != nil {
+			return nil, err
+		}
+		plan.ValueChanges = DiffValues(inst.Values, desired)
+	} else {
+		plan.manifest, err = b.releaseManifest(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := b.installedCRDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan.CRDChanges, err = DiffCRDs(current, plan.manifest)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range plan.CRDChanges {
+		if c.Change == "removed" {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("CRD %s is removed by %s: its custom resources are no longer reconciled", c.Name, version))
+		}
+	}
+	return plan, nil
+}
+
+// Upgrade applies an upgrade plan and verifies the installation is healthy
+// afterwards. When the upgrade or the verification fails, the installation
+// is rolled back: Helm installs to the previous revision, manifest installs
+// by re-applying the manifest of the previous version.
+func (b *Bootstrapper) Upgrade(ctx context.Context, plan *UpgradePlan) (*Result, error) {
+	result := &Result{Tool: plan.Tool, Namespace: plan.Namespace}
+	if plan.UpToDate() {
+		result.Ready = true
+		result.Message = fmt.Sprintf("%s is already at %s", plan.Tool, plan.To)
+		return result, nil
+	}
+
+	var err error
+	switch plan.Method {
+	case InstallMethodHelm:
+		upgrader, ok := b.helm.(HelmUpgrader)
+		if !ok {
+			return nil, fmt.Errorf("the Helm installer cannot roll back upgrades")
+		}
+		rel := b.upgradeRelease(plan.To)
+		if err = b.installHelmRelease(ctx, rel.Name, &HelmConfig{
+			Repo:        rel.RepoURL,
+			Chart:       rel.Chart,
+			Version:     rel.Version,
+			Values:      rel.Values,
+			ValuesFiles: rel.ValuesFiles,
+			SetValues:   rel.SetValues,
+		}); err == nil {
+			err = b.verifyUpgrade(ctx, "")
+		}
+		if err != nil {
+			if rbErr := upgrader.Rollback(ctx, rel.Name, plan.Namespace, plan.Revision); rbErr != nil {
+				return nil, fmt.Errorf("upgrade to %s failed: %w (rollback failed: %v)", plan.To, err, rbErr)
+			}
+			return nil, fmt.Errorf("upgrade to %s failed, rolled back to revision %d: %w", plan.To, plan.Revision, err)
+		}
+		result.Release = b.release
+
+	default:
+		// The previous manifest is fetched first: without it there is
+		// nothing to roll back to.
+		previous, prevErr := b.releaseManifest(ctx, plan.From)
+		if prevErr != nil {
+			return nil, fmt.Errorf("failed to load the manifest of %s to roll back to: %w", plan.From, prevErr)
+		}
+		manifest := plan.manifest
+		if manifest == nil {
+			if manifest, err = b.releaseManifest(ctx, plan.To); err != nil {
+				return nil, err
+			}
+		}
+		if err = b.applyManifest(ctx, manifest); err == nil {
+			err = b.verifyUpgrade(ctx, plan.To)
+		}
+		if err != nil {
+			if rbErr := b.applyManifest(ctx, previous); rbErr != nil {
+				return nil, fmt.Errorf("upgrade to %s failed: %w (rollback failed: %v)", plan.To, err, rbErr)
+			}
+			return nil, fmt.Errorf("upgrade to %s failed, rolled back to %s: %w", plan.To, plan.From, err)
+		}
+	}
+
+	result.Ready = true
+	result.Message = fmt.Sprintf("%s upgraded from %s to %s in namespace %s", plan.Tool, plan.From, plan.To, plan.Namespace)
+	return result, nil
+}
+
+// verifyUpgrade waits for every workload of the namespace to be available
+// and, when version is set, checks the tool reports it.
+func (b *Bootstrapper) verifyUpgrade(ctx context.Context, version string) error {
+	output, err := b.cluster.RunCommand(ctx, "get", "deployments", "-n", b.options.Namespace, "-o", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, name := range strings.Fields(output) {
+		if err := b.cluster.WaitForDeployment(ctx, b.options.Namespace, name, b.options.Timeout); err != nil {
+			return fmt.Errorf("deployment %s is not available: %w", name, err)
+		}
+	}
+	if b.options.Tool == ToolArgoCD {
+		if _, err := b.cluster.RunCommand(ctx, "rollout", "status", "statefulset/argocd-application-controller",
+			"-n", b.options.Namespace, fmt.Sprintf("--timeout=%ds", b.options.Timeout)); err != nil {
+			return fmt.Errorf("application controller is not ready: %w", err)
+		}
+	}
+
+	if version == "" {
+		return nil
+	}
+	var running string
+	if b.options.Tool == ToolArgoCD {
+		running = NewDetector(b.kubeContext(), 0).detectVersion(ctx, b.options.Namespace)
+	} else {
+		running, _ = b.fluxVersion(ctx)
+	}
+	if strings.TrimPrefix(running, "v") != strings.TrimPrefix(version, "v") {
+		return fmt.Errorf("%s reports version %s after upgrading to %s", b.options.Tool, running, version)
+	}
+	return nil
+}
+
+// releaseManifest returns the install manifest of a version, from the
+// offline bundle when there is one.
+func (b *Bootstrapper) releaseManifest(ctx context.Context, version string) ([]byte, error) {
+	if b.options.Manifests != nil {
+		data, err := b.options.Manifests.Manifest(string(b.options.Tool), version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s %s from the offline bundle: %w", b.options.Tool, version, err)
+		}
+		return data, nil
+	}
+	if b.options.Tool == ToolFlux {
+		return downloadManifest(ctx, fluxInstallURL(version))
+	}
+	return downloadManifest(ctx, argoCDInstallURL(version, b.argoCDManifestDir()))
+}
+
+// applyManifest applies an install manifest server-side: the ArgoCD CRDs
+// are too large for the last-applied annotation of client-side apply.
+func (b *Bootstrapper) applyManifest(ctx context.Context, manifest []byte) error {
+	f, err := os.CreateTemp("", "gitopsi-upgrade-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(manifest); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if _, err := b.cluster.RunCommand(ctx, "apply", "--server-side", "--force-conflicts", "-n", b.options.Namespace, "-f", f.Name()); err != nil {
+		return fmt.Errorf("failed to apply %s manifests: %w", b.options.Tool, err)
+	}
+	return nil
+}
+
+// installedCRDs returns the CRDs of the tool on the cluster with their
+// served versions.
+func (b *Bootstrapper) installedCRDs(ctx context.Context) (map[string][]string, error) {
+	output, err := b.cluster.RunCommand(ctx, "get", "crd", "-o",
+		`jsonpath={range .items[*]}{.metadata.name}{"="}{range .spec.versions[?(@.served==true)]}{.name}{" "}{end}{"\n"}{end}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CRDs: %w", err)
+	}
+
+	group := "." + crdGroup(b.options.Tool)
+	crds := map[string][]string{}
+	for _, line := range strings.Split(output, "\n") {
+		name, versions, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || !strings.HasSuffix(name, group) {
+			continue
+		}
+		served := strings.Fields(versions)
+		sort.Strings(served)
+		crds[name] = served
+	}
+	return crds, nil
+}
+
+// crdGroup returns the API group suffix of the CRDs a tool installs.
+func crdGroup(tool Tool) string {
+	if tool == ToolFlux {
+		return "toolkit.fluxcd.io"
+	}
+	return "argoproj.io"
+}
+
+// fluxVersion returns the Flux version the install manifest labels the
+// namespace with.
+func (b *Bootstrapper) fluxVersion(ctx context.Context) (string, error) {
+	output, err := b.cluster.RunCommand(ctx, "get", "namespace", b.options.Namespace, "-o", `jsonpath={.metadata.labels.app\.kubernetes\.io/version}`)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// upgradeRelease returns the Helm release of the tool at a chart version.
+func (b *Bootstrapper) upgradeRelease(version string) *HelmRelease {
+	helmCfg := b.helmConfig()
+	return &HelmRelease{
+		Name:          b.helmReleaseName(),
+		RepoURL:       helmCfg.Repo,
+		Chart:         helmCfg.Chart,
+		Version:       version,
+		Namespace:     b.options.Namespace,
+		Values:        helmCfg.Values,
+		ValuesFiles:   helmCfg.ValuesFiles,
+		SetValues:     helmCfg.SetValues,
+		ProfileValues: b.profileValues(),
+	}
+}
+
+// helmReleaseName returns the name of the Helm release bootstrap installs.
+func (b *Bootstrapper) helmReleaseName() string {
+	if b.options.Tool == ToolFlux {
+		return "flux2"
+	}
+	return "argocd"
+}
+
+func (b *Bootstrapper) kubeContext() string {
+	if b.cluster == nil {
+		return ""
+	}
+	if auth := b.cluster.GetAuthOptions(); auth != nil {
+		return auth.Context
+	}
+	return ""
+}
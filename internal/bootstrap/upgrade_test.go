@@ -0,0 +1,207 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeHelmUpgrader struct {
+	fakeHelmInstaller
+	current    *ReleaseStatus
+	values     map[string]any
+	installErr error
+	rolledBack int
+}
+
+func (f *fakeHelmUpgrader) InstallOrUpgrade(ctx context.Context, rel *HelmRelease) (*ReleaseStatus, error) {
+	if f.installErr != nil {
+		return nil, f.installErr
+	}
+	return f.fakeHelmInstaller.InstallOrUpgrade(ctx, rel)
+}
+
+func (f *fakeHelmUpgrader) Get(ctx context.Context, name, namespace string) (*ReleaseStatus, map[string]any, error) {
+	if f.current == nil {
+		return nil, nil, fmt.Errorf("release: not found")
+	}
+	return f.current, f.values, nil
+}
+
+func (f *fakeHelmUpgrader) Preview(ctx context.Context, rel *HelmRelease) (string, error) {
+	return "", nil
+}
+
+func (f *fakeHelmUpgrader) Rollback(ctx context.Context, name, namespace string, revision int) error {
+	f.rolledBack = revision
+	return nil
+}
+
+func TestUpgradePath(t *testing.T) {
+	tests := []struct {
+		from, to     string
+		wantPath     []string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{from: "v2.12.3", to: "v2.12.3"},
+		{from: "v2.12.3", to: "v2.12.4", wantPath: []string{"v2.12.4"}},
+		{from: "v2.9.1", to: "v2.12.0", wantPath: []string{"v2.10", "v2.11", "v2.12.0"}, wantWarnings: 1},
+		{from: "6.7.0", to: "7.3.4", wantPath: []string{"7.3.4"}, wantWarnings: 1},
+		{from: "v2.12.0", to: "v2.11.0", wantErr: true},
+		{from: "", to: "v2.12.0", wantErr: true},
+		{from: "v2.12.0", to: "stable", wantErr: true},
+	}
+	for _, tt := range tests {
+		path, warnings, err := UpgradePath(tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UpgradePath(%q, %q) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(path, tt.wantPath) {
+			t.Errorf("UpgradePath(%q, %q) = %v, want %v", tt.from, tt.to, path, tt.wantPath)
+		}
+		if len(warnings) != tt.wantWarnings {
+			t.Errorf("UpgradePath(%q, %q) warnings = %v", tt.from, tt.to, warnings)
+		}
+	}
+}
+
+func TestDiffCRDs(t *testing.T) {
+	manifest := []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: applications.argoproj.io
+spec:
+  versions:
+    - name: v1alpha1
+      served: true
+---
+# Source: argo-cd/templates/crds/crd-applicationset.yaml
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: applicationsets.argoproj.io
+spec:
+  versions:
+    - name: v1alpha1
+      served: true
+    - name: v1beta1
+      served: true
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: argocd-server
+`)
+	current := map[string][]string{
+		"applications.argoproj.io":     {"v1alpha1"},
+		"applicationsets.argoproj.io":  {"v1alpha1"},
+		"argocdextensions.argoproj.io": {"v1alpha1"},
+	}
+
+	changes, err := DiffCRDs(current, manifest)
+	if err != nil {
+		t.Fatalf("DiffCRDs() error = %v", err)
+	}
+	want := []CRDChange{
+		{Name: "applicationsets.argoproj.io", Change: "updated", From: []string{"v1alpha1"}, To: []string{"v1alpha1", "v1beta1"}},
+		{Name: "argocdextensions.argoproj.io", Change: "removed", From: []string{"v1alpha1"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("DiffCRDs() = %+v, want %+v", changes, want)
+	}
+
+	changes, _ = DiffCRDs(nil, manifest)
+	if len(changes) != 2 || changes[0].Change != "added" {
+		t.Errorf("DiffCRDs(nil) = %+v, want two added CRDs", changes)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	current := map[string]any{
+		"server": map[string]any{"replicas": 1, "ingress": map[string]any{"enabled": true}},
+		"dex":    map[string]any{"enabled": true},
+	}
+	desired := map[string]any{
+		"server": map[string]any{"replicas": 2, "ingress": map[string]any{"enabled": true}},
+		"redis":  map[string]any{"enabled": false},
+	}
+
+	want := []ValueChange{
+		{Key: "dex.enabled", From: true},
+		{Key: "redis.enabled", To: false},
+		{Key: "server.replicas", From: 1, To: 2},
+	}
+	if got := DiffValues(current, desired); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffValues() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDetectInstallation_Helm(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolArgoCD})
+	b.SetHelmInstaller(&fakeHelmUpgrader{
+		current: &ReleaseStatus{Name: "argocd", ChartVersion: "7.3.0", Revision: 4},
+		values:  map[string]any{"server": map[string]any{"replicas": 2}},
+	})
+
+	inst, err := b.DetectInstallation(context.Background())
+	if err != nil {
+		t.Fatalf("DetectInstallation() error = %v", err)
+	}
+	if inst.Method != InstallMethodHelm || inst.Version != "7.3.0" || inst.Namespace != "argocd" {
+		t.Errorf("DetectInstallation() = %+v", inst)
+	}
+}
+
+func TestPlanUpgrade_Refused(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolArgoCD})
+
+	if _, err := b.PlanUpgrade(context.Background(), &Installation{Tool: ToolArgoCD, Method: InstallMethodOLM, Version: "v1.12.0"}, "v1.13.0"); err == nil || !strings.Contains(err.Error(), "OLM") {
+		t.Errorf("PlanUpgrade(OLM) error = %v, want an OLM error", err)
+	}
+	if _, err := b.PlanUpgrade(context.Background(), &Installation{Tool: ToolArgoCD, Method: InstallMethodManifest, Version: "v2.12.0"}, ""); err == nil {
+		t.Error("PlanUpgrade() should require a target version")
+	}
+
+	plan, err := b.PlanUpgrade(context.Background(), &Installation{Tool: ToolArgoCD, Method: InstallMethodManifest, Version: "v2.12.0"}, "v2.12.0")
+	if err != nil || !plan.UpToDate() {
+		t.Errorf("PlanUpgrade(same version) = %+v, %v, want an up-to-date plan", plan, err)
+	}
+	result, err := b.Upgrade(context.Background(), plan)
+	if err != nil || !strings.Contains(result.Message, "already at v2.12.0") {
+		t.Errorf("Upgrade(up to date) = %+v, %v", result, err)
+	}
+}
+
+func TestUpgrade_HelmRollsBack(t *testing.T) {
+	b := New(nil, &Options{Tool: ToolArgoCD})
+	fake := &fakeHelmUpgrader{installErr: fmt.Errorf("timed out waiting for the condition")}
+	b.SetHelmInstaller(fake)
+
+	_, err := b.Upgrade(context.Background(), &UpgradePlan{
+		Tool:     ToolArgoCD,
+		Method:   InstallMethodHelm,
+		From:     "7.2.0",
+		To:       "7.3.0",
+		Path:     []string{"7.3.0"},
+		Revision: 4,
+	})
+	if err == nil || !strings.Contains(err.Error(), "rolled back to revision 4") {
+		t.Errorf("Upgrade() error = %v, want a rollback", err)
+	}
+	if fake.rolledBack != 4 {
+		t.Errorf("rolled back to revision %d, want 4", fake.rolledBack)
+	}
+}
+
+func TestArgoCDInstallURL(t *testing.T) {
+	if got := argoCDInstallURL("2.12.0", "manifests"); got != "https://raw.githubusercontent.com/argoproj/argo-cd/v2.12.0/manifests/install.yaml" {
+		t.Errorf("argoCDInstallURL(2.12.0) = %s", got)
+	}
+	if got := argoCDInstallURL("v2.12.0", "manifests/ha"); got != "https://raw.githubusercontent.com/argoproj/argo-cd/v2.12.0/manifests/ha/install.yaml" {
+		t.Errorf("argoCDInstallURL(v2.12.0, ha) = %s", got)
+	}
+}
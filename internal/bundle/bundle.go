@@ -0,0 +1,204 @@
+// Package bundle prepares and reads offline bundles for air-gapped
+// environments. A bundle holds the ArgoCD and Flux install manifests of
+// selected versions and a local pattern registry, so bootstrap and the
+// marketplace never reach the network.
+//
+// Bundles are directories created with Create on a connected machine. A
+// bundle can also be compiled into the binary by creating it in the embedded
+// directory of this package before building (see make build-offline).
+package bundle
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// IndexFile is the bundle index at the root of a bundle.
+	IndexFile = "bundle.yaml"
+	// RegistryDir is the local pattern registry inside a bundle.
+	RegistryDir = "registry"
+)
+
+// ErrNoBundle is returned by Open when no bundle directory is given and none
+// is embedded in the binary.
+var ErrNoBundle = errors.New("no offline bundle: pass --bundle-dir or build with an embedded bundle")
+
+//go:embed all:embedded
+var embedded embed.FS
+
+// Component is a vendored install manifest.
+type Component struct {
+	Tool    string `yaml:"tool" json:"tool"`
+	Version string `yaml:"version" json:"version"`
+	// Source is where the manifest was downloaded from.
+	Source string `yaml:"source" json:"source"`
+	// File is the manifest, relative to the bundle root.
+	File   string `yaml:"file" json:"file"`
+	SHA256 string `yaml:"sha256" json:"sha256"`
+}
+
+// Index describes the content of a bundle.
+type Index struct {
+	CreatedAt  time.Time   `yaml:"created_at" json:"created_at"`
+	Components []Component `yaml:"components" json:"components"`
+	// Patterns lists the bundled patterns as name@version.
+	Patterns []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+}
+
+// Bundle is an opened offline bundle.
+type Bundle struct {
+	fsys  fs.FS
+	dir   string
+	index *Index
+}
+
+// Open opens the bundle in dir, or the bundle embedded in the binary when dir
+// is empty.
+func Open(dir string) (*Bundle, error) {
+	if dir == "" {
+		sub, err := fs.Sub(embedded, "embedded")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded bundle: %w", err)
+		}
+		if _, err := fs.Stat(sub, IndexFile); err != nil {
+			return nil, ErrNoBundle
+		}
+		return load(sub, "")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, IndexFile)); err != nil {
+		return nil, fmt.Errorf("failed to open bundle %s: %w", dir, err)
+	}
+	return load(os.DirFS(dir), dir)
+}
+
+func load(fsys fs.FS, dir string) (*Bundle, error) {
+	data, err := fs.ReadFile(fsys, IndexFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle index: %w", err)
+	}
+	var index Index
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle index: %w", err)
+	}
+	return &Bundle{fsys: fsys, dir: dir, index: &index}, nil
+}
+
+// Index returns the bundle index.
+func (b *Bundle) Index() *Index {
+	return b.index
+}
+
+// Source describes where the bundle was opened from.
+func (b *Bundle) Source() string {
+	if b.dir == "" {
+		return "embedded"
+	}
+	return b.dir
+}
+
+// RegistryDir returns the local pattern registry of a directory bundle. Embedded
+// bundles carry install manifests only.
+func (b *Bundle) RegistryDir() (string, bool) {
+	if b.dir == "" {
+		return "", false
+	}
+	dir := filepath.Join(b.dir, RegistryDir)
+	if _, err := os.Stat(filepath.Join(dir, "index.yaml")); err != nil {
+		return "", false
+	}
+	return dir, true
+}
+
+// Manifest returns the install manifest of tool at version. An empty version,
+// "stable" or "latest" selects the newest bundled version. The content is
+// checked against the checksum recorded when the bundle was created.
+func (b *Bundle) Manifest(tool, version string) ([]byte, error) {
+	c, err := b.component(tool, version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(b.fsys, c.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s manifest: %w", tool, c.Version, err)
+	}
+	if sum := checksum(data); sum != c.SHA256 {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", c.File, c.SHA256, sum)
+	}
+	return data, nil
+}
+
+func (b *Bundle) component(tool, version string) (*Component, error) {
+	var candidates []Component
+	for _, c := range b.index.Components {
+		if c.Tool == tool {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("bundle %s has no %s manifests", b.Source(), tool)
+	}
+
+	if version == "" || version == "stable" || version == "latest" {
+		sortVersions(candidates)
+		return &candidates[len(candidates)-1], nil
+	}
+	for i := range candidates {
+		if sameVersion(candidates[i].Version, version) {
+			return &candidates[i], nil
+		}
+	}
+
+	available := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		available = append(available, c.Version)
+	}
+	return nil, fmt.Errorf("bundle %s has no %s %s manifest (available: %v)", b.Source(), tool, version, available)
+}
+
+// sortVersions orders components by semantic version, falling back to a
+// string comparison for versions that do not parse.
+func sortVersions(components []Component) {
+	sort.SliceStable(components, func(i, j int) bool {
+		vi, errI := semver.NewVersion(components[i].Version)
+		vj, errJ := semver.NewVersion(components[j].Version)
+		if errI != nil || errJ != nil {
+			return components[i].Version < components[j].Version
+		}
+		return vi.LessThan(vj)
+	})
+}
+
+// sameVersion compares versions ignoring a leading "v".
+func sameVersion(a, b string) bool {
+	trim := func(v string) string {
+		if len(v) > 0 && v[0] == 'v' {
+			return v[1:]
+		}
+		return v
+	}
+	return trim(a) == trim(b)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestPath returns where the manifest of a component is stored.
+func manifestPath(tool, version string) string {
+	return path.Join("manifests", tool, version, "install.yaml")
+}
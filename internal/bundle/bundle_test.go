@@ -0,0 +1,189 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+func manifestServer(t *testing.T) (*httptest.Server, map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "missing") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "# manifest %s\n", r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+	return server, map[string]string{
+		"argocd": server.URL + "/argocd/%s/install.yaml",
+		"flux":   server.URL + "/flux/%s/install.yaml",
+	}
+}
+
+// sourceRegistry writes a local registry with a pattern depending on another.
+func sourceRegistry(t *testing.T) *marketplace.RegistryManager {
+	t.Helper()
+	dir := t.TempDir()
+
+	monitoring := marketplace.NewPattern("monitoring", "1.1.0", "Monitoring stack")
+	monitoring.Spec.Dependencies = []marketplace.Dependency{{Name: "cert-manager"}, {Name: "unknown", Optional: true}}
+	certManager := marketplace.NewPattern("cert-manager", "1.0.0", "Certificates")
+	for _, p := range []*marketplace.Pattern{monitoring, certManager} {
+		if err := p.Save(filepath.Join(dir, "patterns", p.Metadata.Name, p.Metadata.Version, "pattern.yaml")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := marketplace.RegistryIndex{Patterns: []marketplace.PatternIndexEntry{
+		{Name: "monitoring", Category: "observability", Versions: []string{"1.0.0", "1.1.0"}, Latest: "1.1.0"},
+		{Name: "cert-manager", Category: "security", Versions: []string{"1.0.0"}, Latest: "1.0.0"},
+	}}
+	data, _ := yaml.Marshal(index)
+	if err := os.WriteFile(filepath.Join(dir, "index.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rm := marketplace.NewRegistryManager("")
+	_ = rm.RemoveRegistry("official")
+	if err := rm.AddRegistry(marketplace.Registry{Name: "source", Type: marketplace.RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	return rm
+}
+
+func TestCreateAndOpen(t *testing.T) {
+	_, sources := manifestServer(t)
+	dir := filepath.Join(t.TempDir(), "bundle")
+
+	index, err := Create(context.Background(), &CreateOptions{
+		Dir:            dir,
+		ArgoCDVersions: []string{"v2.12.0", "v2.13.1"},
+		FluxVersions:   []string{"v2.4.0"},
+		Patterns:       []string{"monitoring"},
+		Registry:       sourceRegistry(t),
+		Sources:        sources,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(index.Components) != 3 {
+		t.Fatalf("expected 3 components, got %d", len(index.Components))
+	}
+	if want := []string{"cert-manager@1.0.0", "monitoring@1.1.0"}; fmt.Sprint(index.Patterns) != fmt.Sprint(want) {
+		t.Errorf("expected patterns %v, got %v", want, index.Patterns)
+	}
+
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	data, err := b.Manifest("argocd", "")
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	if !strings.Contains(string(data), "/argocd/v2.13.1/") {
+		t.Errorf("expected the newest ArgoCD manifest, got %q", data)
+	}
+	if data, err = b.Manifest("argocd", "2.12.0"); err != nil || !strings.Contains(string(data), "v2.12.0") {
+		t.Errorf("Manifest(2.12.0) = %q, %v", data, err)
+	}
+	if _, err := b.Manifest("argocd", "v9.9.9"); err == nil || !strings.Contains(err.Error(), "available") {
+		t.Errorf("expected an error listing available versions, got %v", err)
+	}
+
+	registryDir, ok := b.RegistryDir()
+	if !ok {
+		t.Fatal("expected a bundle registry")
+	}
+	rm := marketplace.NewRegistryManager("")
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(marketplace.Registry{Name: "bundle", Type: marketplace.RegistryTypeLocal, URL: registryDir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	entry, registry, err := rm.FindPattern(context.Background(), "cert-manager")
+	if err != nil || registry != "bundle" {
+		t.Fatalf("FindPattern() = %v, %s, %v", entry, registry, err)
+	}
+	if _, err := rm.FetchPattern(context.Background(), "bundle", "monitoring", "1.1.0"); err != nil {
+		t.Errorf("FetchPattern() error = %v", err)
+	}
+}
+
+func TestCreateExtendsBundle(t *testing.T) {
+	_, sources := manifestServer(t)
+	dir := t.TempDir()
+
+	for _, v := range []string{"v2.4.0", "v2.5.0", "v2.4.0"} {
+		if _, err := Create(context.Background(), &CreateOptions{Dir: dir, FluxVersions: []string{v}, Sources: sources}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Index().Components) != 2 {
+		t.Errorf("expected 2 components, got %d", len(b.Index().Components))
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	_, sources := manifestServer(t)
+
+	if _, err := Create(context.Background(), &CreateOptions{Dir: t.TempDir()}); err == nil {
+		t.Error("expected an error with nothing to bundle")
+	}
+	if _, err := Create(context.Background(), &CreateOptions{Dir: t.TempDir(), ArgoCDVersions: []string{"missing"}, Sources: sources}); err == nil {
+		t.Error("expected a download error")
+	}
+	if _, err := Create(context.Background(), &CreateOptions{Dir: t.TempDir(), Patterns: []string{"monitoring"}}); err == nil {
+		t.Error("expected an error without a registry")
+	}
+}
+
+func TestManifestChecksum(t *testing.T) {
+	_, sources := manifestServer(t)
+	dir := t.TempDir()
+	if _, err := Create(context.Background(), &CreateOptions{Dir: dir, ArgoCDVersions: []string{"v2.13.1"}, Sources: sources}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifests", "argocd", "v2.13.1", "install.yaml"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Manifest("argocd", "v2.13.1"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+	if _, err := b.Manifest("flux", ""); err == nil {
+		t.Error("expected an error for a tool without manifests")
+	}
+}
+
+func TestOpenWithoutBundle(t *testing.T) {
+	if _, err := fs.Stat(embedded, "embedded/"+IndexFile); err == nil {
+		t.Skip("binary built with an embedded bundle")
+	}
+	if _, err := Open(""); !errors.Is(err, ErrNoBundle) {
+		t.Errorf("expected ErrNoBundle, got %v", err)
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without a bundle index")
+	}
+}
@@ -0,0 +1,243 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+// DefaultSources are the download URLs of the install manifests of each tool;
+// %s is replaced by the version.
+var DefaultSources = map[string]string{
+	"argocd": "https://raw.githubusercontent.com/argoproj/argo-cd/%s/manifests/install.yaml",
+	"flux":   "https://github.com/fluxcd/flux2/releases/download/%s/install.yaml",
+}
+
+// CreateOptions configures Create.
+type CreateOptions struct {
+	// Dir is the bundle directory. An existing bundle is extended.
+	Dir            string
+	ArgoCDVersions []string
+	FluxVersions   []string
+	// Patterns are name or name@version; their dependencies are included.
+	Patterns []string
+	// Registry resolves patterns; required when Patterns is set.
+	Registry *marketplace.RegistryManager
+	// Sources overrides DefaultSources.
+	Sources    map[string]string
+	HTTPClient *http.Client
+}
+
+// Create downloads the requested manifests and patterns into a bundle
+// directory and returns its index.
+func Create(ctx context.Context, opts *CreateOptions) (*Index, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("bundle directory is required")
+	}
+	if len(opts.ArgoCDVersions) == 0 && len(opts.FluxVersions) == 0 && len(opts.Patterns) == 0 {
+		return nil, fmt.Errorf("nothing to bundle: select ArgoCD or Flux versions or patterns")
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", opts.Dir, err)
+	}
+
+	index := &Index{}
+	if existing, err := Open(opts.Dir); err == nil {
+		index = existing.Index()
+	}
+
+	c := &creator{opts: opts, index: index, client: opts.HTTPClient}
+	if c.client == nil {
+		c.client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	for _, v := range opts.ArgoCDVersions {
+		if err := c.addManifest(ctx, "argocd", v); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range opts.FluxVersions {
+		if err := c.addManifest(ctx, "flux", v); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Patterns) > 0 {
+		if err := c.addPatterns(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	index.CreatedAt = time.Now().UTC()
+	sort.Slice(index.Components, func(i, j int) bool {
+		if index.Components[i].Tool != index.Components[j].Tool {
+			return index.Components[i].Tool < index.Components[j].Tool
+		}
+		return index.Components[i].Version < index.Components[j].Version
+	})
+	sort.Strings(index.Patterns)
+
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(opts.Dir, IndexFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write bundle index: %w", err)
+	}
+	return index, nil
+}
+
+type creator struct {
+	opts   *CreateOptions
+	index  *Index
+	client *http.Client
+}
+
+func (c *creator) addManifest(ctx context.Context, tool, version string) error {
+	source, ok := c.opts.Sources[tool]
+	if !ok {
+		source = DefaultSources[tool]
+	}
+	url := fmt.Sprintf(source, version)
+
+	data, err := c.download(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s %s: %w", tool, version, err)
+	}
+
+	file := manifestPath(tool, version)
+	full := filepath.Join(c.opts.Dir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(full), err)
+	}
+	if err := os.WriteFile(full, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", full, err)
+	}
+
+	component := Component{Tool: tool, Version: version, Source: url, File: file, SHA256: checksum(data)}
+	c.index.Components = slices.DeleteFunc(c.index.Components, func(e Component) bool {
+		return e.Tool == tool && e.Version == version
+	})
+	c.index.Components = append(c.index.Components, component)
+	return nil
+}
+
+func (c *creator) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// addPatterns copies the requested patterns and their dependencies into the
+// local registry of the bundle.
+func (c *creator) addPatterns(ctx context.Context) error {
+	if c.opts.Registry == nil {
+		return fmt.Errorf("a pattern registry is required to bundle patterns")
+	}
+
+	registryDir := filepath.Join(c.opts.Dir, RegistryDir)
+	registryIndex := &marketplace.RegistryIndex{Version: "1"}
+	if data, err := os.ReadFile(filepath.Join(registryDir, "index.yaml")); err == nil {
+		if err := yaml.Unmarshal(data, registryIndex); err != nil {
+			return fmt.Errorf("failed to parse bundle registry index: %w", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	var add func(name, version string, optional bool) error
+	add = func(name, version string, optional bool) error {
+		entry, registryName, err := c.opts.Registry.FindPattern(ctx, name)
+		if err != nil {
+			if optional {
+				return nil
+			}
+			return err
+		}
+		if version == "" || !slices.Contains(entry.Versions, version) {
+			version = entry.Latest
+		}
+		key := name + "@" + version
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+
+		// Verify signatures while connected: the bundle serves the patterns
+		// from a local registry without signing policy.
+		pattern, _, err := c.opts.Registry.FetchVerifiedPattern(ctx, registryName, name, version)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pattern %s: %w", key, err)
+		}
+		if err := pattern.Save(filepath.Join(registryDir, "patterns", name, version, "pattern.yaml")); err != nil {
+			return err
+		}
+		addIndexEntry(registryIndex, entry, version)
+		if !slices.Contains(c.index.Patterns, key) {
+			c.index.Patterns = append(c.index.Patterns, key)
+		}
+
+		for _, dep := range pattern.Spec.Dependencies {
+			if err := add(dep.Name, dep.Version, dep.Optional); err != nil {
+				return fmt.Errorf("failed to bundle dependency %s of %s: %w", dep.Name, name, err)
+			}
+		}
+		return nil
+	}
+
+	for _, spec := range c.opts.Patterns {
+		name, version, _ := strings.Cut(spec, "@")
+		if err := add(name, version, false); err != nil {
+			return err
+		}
+	}
+
+	registryIndex.Generated = time.Now().UTC()
+	data, err := yaml.Marshal(registryIndex)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle registry index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(registryDir, "index.yaml"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle registry index: %w", err)
+	}
+	return nil
+}
+
+// addIndexEntry records a bundled pattern version in the registry index.
+func addIndexEntry(index *marketplace.RegistryIndex, entry *marketplace.PatternIndexEntry, version string) {
+	for i := range index.Patterns {
+		if index.Patterns[i].Name == entry.Name {
+			if !slices.Contains(index.Patterns[i].Versions, version) {
+				index.Patterns[i].Versions = append(index.Patterns[i].Versions, version)
+			}
+			if version == entry.Latest {
+				index.Patterns[i].Latest = version
+			}
+			return
+		}
+	}
+	bundled := *entry
+	bundled.Versions = []string{version}
+	bundled.Latest = version
+	index.Patterns = append(index.Patterns, bundled)
+}
@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/audit"
+)
+
+// auditActions maps the commands recorded in the audit log to their action.
+var auditActions = map[string]string{
+	"gitopsi init":                   "generate",
+	"gitopsi fix":                    "fix",
+	"gitopsi bootstrap":              "bootstrap",
+	"gitopsi bootstrap flux":         "bootstrap",
+	"gitopsi bootstrap upgrade":      "bootstrap.upgrade",
+	"gitopsi install":                "install",
+	"gitopsi patterns update":        "install.update",
+	"gitopsi patterns remove":        "install.remove",
+	"gitopsi marketplace publish":    "marketplace.publish",
+	"gitopsi promote":                "promote",
+	"gitopsi rollback":               "rollback",
+	"gitopsi env create":             "env.create",
+	"gitopsi env clone":              "env.clone",
+	"gitopsi env delete":             "env.delete",
+	"gitopsi env add-cluster":        "env.add-cluster",
+	"gitopsi env remove-cluster":     "env.remove-cluster",
+	"gitopsi cluster add":            "cluster.add",
+	"gitopsi cluster create":         "cluster.create",
+	"gitopsi cluster delete":         "cluster.delete",
+	"gitopsi dns sync":               "dns.sync",
+	"gitopsi upgrade":                "upgrade",
+	"gitopsi update apply":           "update",
+	"gitopsi images pin":             "images.pin",
+	"gitopsi images update":          "images.update",
+	"gitopsi auth add git":           "credential.add",
+	"gitopsi auth add platform":      "credential.add",
+	"gitopsi auth add registry":      "credential.add",
+	"gitopsi auth generate":          "credential.add",
+	"gitopsi auth create-deploy-key": "credential.add",
+	"gitopsi auth import-kubeconfig": "credential.add",
+	"gitopsi auth rotate":            "credential.rotate",
+	"gitopsi auth delete":            "credential.delete",
+	"gitopsi auth migrate":           "credential.migrate",
+	"gitopsi config set":             "config.set",
+	"gitopsi operator add":           "operator.add",
+	"gitopsi operator add-preset":    "operator.add",
+	"gitopsi operator remove":        "operator.remove",
+}
+
+var (
+	// auditProject and auditRepository are set by commands that know the
+	// project they change better than its --project or --path flag.
+	auditProject    string
+	auditRepository bool
+
+	auditListAction  string
+	auditListLimit   int
+	auditProjectPath string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of gitopsi operations",
+	Long: `Every mutating operation (generate, bootstrap, install, promote, credential
+add and delete, ...) is appended to ~/.gitopsi/audit.jsonl with the actor,
+timestamp, command line flags and result. Secret flag values are redacted.
+
+Projects setting audit.repository also record their operations in
+.gitopsi/audit.jsonl of the repository; read it with --project.
+
+The actor is GITOPSI_ACTOR, the global git user.email or the OS user.
+
+Examples:
+  gitopsi audit list                       # Latest operations
+  gitopsi audit list --action promote -o json
+  gitopsi audit list --project ./shop      # Operations recorded in the repository
+  gitopsi audit show 3f2a9c`,
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List audited operations, latest first",
+	Args:  cobra.NoArgs,
+	RunE:  runAuditList,
+}
+
+var auditShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show an audited operation",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAuditShow,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditShowCmd)
+
+	auditCmd.PersistentFlags().StringVar(&auditProjectPath, "project", "", "Read the audit log of the repository of a gitopsi project")
+	auditListCmd.Flags().StringVar(&auditListAction, "action", "", "Only list operations with this action (or action prefix, e.g. credential)")
+	auditListCmd.Flags().IntVar(&auditListLimit, "limit", 20, "Maximum number of operations to list (0 for all)")
+}
+
+func auditLog() *audit.Log {
+	if auditProjectPath != "" {
+		return audit.NewLog(audit.RepositoryPath(auditProjectPath))
+	}
+	return audit.NewLog(audit.DefaultPath())
+}
+
+func runAuditList(cmd *cobra.Command, args []string) error {
+	entries, err := auditLog().Entries()
+	if err != nil {
+		return err
+	}
+
+	var listed []audit.Entry
+	for _, e := range slices.Backward(entries) {
+		if auditListLimit > 0 && len(listed) == auditListLimit {
+			break
+		}
+		if auditListAction != "" && e.Action != auditListAction && !strings.HasPrefix(e.Action, auditListAction+".") {
+			continue
+		}
+		listed = append(listed, e)
+	}
+
+	if p := newPrinter(); p.structured() {
+		if listed == nil {
+			listed = []audit.Entry{}
+		}
+		return p.print(listed)
+	}
+	if len(listed) == 0 {
+		pterm.Info.Println("No audited operations")
+		return nil
+	}
+
+	tableData := [][]string{{"ID", "TIME", "ACTOR", "ACTION", "COMMAND", "RESULT"}}
+	for _, e := range listed {
+		tableData = append(tableData, []string{
+			e.ID, e.Time.Local().Format(time.DateTime), e.Actor, e.Action, e.Command, string(e.Result),
+		})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return nil
+}
+
+func runAuditShow(cmd *cobra.Command, args []string) error {
+	e, err := auditLog().Find(args[0])
+	if err != nil {
+		return err
+	}
+	if p := newPrinter(); p.structured() {
+		return p.print(e)
+	}
+
+	fmt.Printf("ID:       %s\n", e.ID)
+	fmt.Printf("Time:     %s\n", e.Time.Local().Format(time.RFC3339))
+	fmt.Printf("Actor:    %s\n", e.Actor)
+	fmt.Printf("Action:   %s\n", e.Action)
+	fmt.Printf("Command:  %s\n", strings.Join(append([]string{e.Command}, e.Args...), " "))
+	if e.Project != "" {
+		fmt.Printf("Project:  %s\n", e.Project)
+	}
+	fmt.Printf("Result:   %s\n", e.Result)
+	if e.Error != "" {
+		fmt.Printf("Error:    %s\n", e.Error)
+	}
+	fmt.Printf("Duration: %s\n", e.Duration)
+	if len(e.Flags) > 0 {
+		fmt.Println("Flags:")
+		names := make([]string, 0, len(e.Flags))
+		for name := range e.Flags {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Printf("  --%s=%s\n", name, e.Flags[name])
+		}
+	}
+	return nil
+}
+
+// recordAudit appends a command that ran to the audit logs when it is a
+// mutating operation. Dry runs change nothing and are not recorded. Failing
+// to record is reported without failing the command.
+func recordAudit(cmd *cobra.Command, start time.Time, runErr error) {
+	if cmd == nil {
+		return
+	}
+	action, ok := auditActions[cmd.CommandPath()]
+	if !ok || dryRun {
+		return
+	}
+
+	entry := auditEntry(cmd, action, start, runErr)
+	logs := []*audit.Log{audit.NewLog(audit.DefaultPath())}
+	if entry.Project != "" && auditRepositoryEnabled(entry.Project) {
+		logs = append(logs, audit.NewLog(audit.RepositoryPath(entry.Project)))
+	}
+	for _, log := range logs {
+		if err := log.Append(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record audit entry in %s: %v\n", log.Path(), err)
+		}
+	}
+}
+
+func auditEntry(cmd *cobra.Command, action string, start time.Time, runErr error) audit.Entry {
+	entry := audit.Entry{
+		ID:       audit.NewID(),
+		Time:     start.UTC(),
+		Actor:    audit.Actor(),
+		Action:   action,
+		Command:  cmd.CommandPath(),
+		Args:     audit.RedactArgs(cmd.Flags().Args()),
+		Project:  auditProjectDir(cmd),
+		Result:   audit.ResultSuccess,
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+	}
+	if runErr != nil {
+		entry.Result = audit.ResultFailure
+		entry.Error = runErr.Error()
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if entry.Flags == nil {
+			entry.Flags = map[string]string{}
+		}
+		entry.Flags[f.Name] = audit.Redact(f.Name, f.Value.String())
+	})
+	return entry
+}
+
+// auditProjectDir returns the directory of the project a command changed,
+// or an empty string when it changes none.
+func auditProjectDir(cmd *cobra.Command) string {
+	dir := auditProject
+	if dir == "" {
+		for _, name := range []string{"project", "path"} {
+			if f := cmd.Flags().Lookup(name); f != nil && f.Value.String() != "" {
+				dir = f.Value.String()
+				break
+			}
+		}
+	}
+	if dir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// auditRepositoryEnabled reports whether the project in dir records its
+// operations in its repository.
+func auditRepositoryEnabled(dir string) bool {
+	if auditRepository {
+		return true
+	}
+	cfg, err := loadProjectConfig(dir)
+	return err == nil && cfg != nil && cfg.Audit.Repository
+}
@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
 	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+	"github.com/ihsanmokhlisse/gitopsi/internal/status"
 )
 
 var (
@@ -27,6 +33,34 @@ var (
 	authRoleARN    string
 	authTenantID   string
 	authClientID   string
+	authMigrateTo  string
+	authExpires    string
+
+	authExpiryWarning time.Duration
+
+	authAppID               int64
+	authInstallationID      int64
+	authAppPrivateKeyFile   string
+	authGitHubEnterpriseAPI string
+
+	authDeployKeyTitle  string
+	authDeployKeyWrite  bool
+	authDeployKeyFormat string
+	authDeployKeyAPIURL string
+
+	authSecretFormat string
+	authSopsAge      []string
+	authSopsPGP      []string
+	authSopsKMS      []string
+	authSopsGCPKMS   []string
+	authSopsAzureKV  []string
+
+	authSealCluster             string
+	authSealKubeconfig          string
+	authSealCert                string
+	authSealControllerNamespace string
+	authSealControllerName      string
+	authSealScope               string
 )
 
 var authCmd = &cobra.Command{
@@ -41,6 +75,12 @@ Examples:
   # Add SSH key for GitLab
   gitopsi auth add git --provider gitlab --method ssh --ssh-key ~/.ssh/id_rsa
 
+  # Add a GitHub App
+  gitopsi auth add git --provider github --method github-app --app-id 123 --installation-id 456 --app-private-key app.pem
+
+  # Generate a deploy key, register it on the repository and print the ArgoCD secret
+  gitopsi auth create-deploy-key platform-deploy --url https://github.com/org/platform.git
+
   # Add OpenShift credentials
   gitopsi auth add platform --platform openshift --method token --token $OCP_TOKEN
 
@@ -51,7 +91,10 @@ Examples:
   gitopsi auth list
 
   # Test credentials
-  gitopsi auth test my-github-cred`,
+  gitopsi auth test my-github-cred
+
+  # Rotate a token and update its ArgoCD secret on the cluster
+  gitopsi auth rotate my-github-cred --token $NEW_TOKEN --expires 90d --context prod`,
 }
 
 var authAddCmd = &cobra.Command{
@@ -67,7 +110,12 @@ var authAddGitCmd = &cobra.Command{
 
 Examples:
   gitopsi auth add git github-main --provider github --method token --token $GITHUB_TOKEN
-  gitopsi auth add git gitlab-ssh --provider gitlab --method ssh --ssh-key ~/.ssh/id_rsa`,
+  gitopsi auth add git gitlab-ssh --provider gitlab --method ssh --ssh-key ~/.ssh/id_rsa
+  gitopsi auth add git github-app --provider github --method github-app \
+    --app-id 123 --installation-id 456 --app-private-key app.pem --url https://github.com/my-org
+
+GitHub App credentials are exchanged for short-lived installation tokens when
+pushing, and render ArgoCD secrets with githubAppID/githubAppInstallationID.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runAuthAddGit,
 }
@@ -132,6 +180,41 @@ Examples:
 	RunE: runAuthDelete,
 }
 
+var authMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Encrypt or decrypt the credential store",
+	Long: `Convert the credential store between plaintext, encrypted, and keyring storage.
+
+Encrypted stores use AES-256-GCM with a key derived from a passphrase.
+The passphrase is read from ` + auth.PassphraseEnvVar + ` or prompted for.
+
+Migrating to the keyring moves every credential into the OS keychain,
+removes the credentials file, and sets auth.store to keyring.
+
+Examples:
+  gitopsi auth migrate --to encrypted
+  gitopsi auth migrate --to plain
+  gitopsi auth migrate --to keyring`,
+	Args: cobra.NoArgs,
+	RunE: runAuthMigrate,
+}
+
+var authSealCmd = &cobra.Command{
+	Use:   "seal [name]",
+	Short: "Generate a SealedSecret for a credential",
+	Long: `Generate a SealedSecret from a stored credential using kubeseal.
+
+The secret is encrypted with the public certificate of the sealed-secrets
+controller on the target cluster, so only that cluster can decrypt it.
+
+Examples:
+  gitopsi auth seal github-main --cluster prod
+  gitopsi auth seal github-main --cluster prod --format argocd > repo-sealed.yaml
+  gitopsi auth seal quay --cert pub-cert.pem --scope namespace-wide`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthSeal,
+}
+
 var authGenerateCmd = &cobra.Command{
 	Use:   "generate [name]",
 	Short: "Generate Kubernetes secret from credential",
@@ -140,11 +223,30 @@ var authGenerateCmd = &cobra.Command{
 Examples:
   gitopsi auth generate github-main
   gitopsi auth generate github-main --format argocd
-  gitopsi auth generate github-main --format flux`,
+  gitopsi auth generate github-main --format flux
+  gitopsi auth generate github-main --secret-format sops --sops-age age1... > repo.sops.yaml`,
 	Args: cobra.ExactArgs(1),
 	RunE: runAuthGenerate,
 }
 
+var authCreateDeployKeyCmd = &cobra.Command{
+	Use:   "create-deploy-key [name]",
+	Short: "Generate an SSH deploy key and register it with the Git provider",
+	Long: `Generate an ed25519 key pair, register the public key as a deploy key of
+the repository through the provider API, store the private key as an SSH git
+credential and print the matching ArgoCD or Flux secret.
+
+The key is read-only unless --read-write is set. The API token is taken from
+--token or the provider's token environment variable (e.g. GITHUB_TOKEN).
+
+Examples:
+  gitopsi auth create-deploy-key platform-deploy --url https://github.com/org/platform.git > repo-secret.yaml
+  gitopsi auth create-deploy-key flux-deploy --url https://gitlab.com/group/platform.git --format flux --read-write
+  gitopsi auth create-deploy-key gitea-deploy --url https://git.example.com/org/platform.git --provider gitea --format none`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthCreateDeployKey,
+}
+
 func init() {
 	rootCmd.AddCommand(authCmd)
 
@@ -154,6 +256,11 @@ func init() {
 	authCmd.AddCommand(authTestCmd)
 	authCmd.AddCommand(authDeleteCmd)
 	authCmd.AddCommand(authGenerateCmd)
+	authCmd.AddCommand(authMigrateCmd)
+	authCmd.AddCommand(authSealCmd)
+	authCmd.AddCommand(authCreateDeployKeyCmd)
+	authCmd.AddCommand(authRotateCmd)
+	authCmd.AddCommand(authImportKubeconfigCmd)
 
 	// Add type-specific add commands
 	authAddCmd.AddCommand(authAddGitCmd)
@@ -162,7 +269,7 @@ func init() {
 
 	// Git credentials flags
 	authAddGitCmd.Flags().StringVar(&authProvider, "provider", "", "Git provider: github, gitlab, bitbucket, azure-devops, gitea")
-	authAddGitCmd.Flags().StringVar(&authMethod, "method", "", "Auth method: ssh, token, basic, oauth")
+	authAddGitCmd.Flags().StringVar(&authMethod, "method", "", "Auth method: ssh, token, basic, oauth, github-app")
 	authAddGitCmd.Flags().StringVar(&authToken, "token", "", "Access token (or use env var)")
 	authAddGitCmd.Flags().StringVar(&authUsername, "username", "", "Username for basic auth")
 	authAddGitCmd.Flags().StringVar(&authPassword, "password", "", "Password for basic auth")
@@ -170,6 +277,11 @@ func init() {
 	authAddGitCmd.Flags().StringVar(&authURL, "url", "", "Git repository URL")
 	authAddGitCmd.Flags().StringVar(&authNamespace, "namespace", "", "Kubernetes namespace for generated secret")
 	authAddGitCmd.Flags().StringVar(&authSecretName, "secret-name", "", "Name for generated Kubernetes secret")
+	authAddGitCmd.Flags().Int64Var(&authAppID, "app-id", 0, "GitHub App ID")
+	authAddGitCmd.Flags().Int64Var(&authInstallationID, "installation-id", 0, "GitHub App installation ID")
+	authAddGitCmd.Flags().StringVar(&authAppPrivateKeyFile, "app-private-key", "", "Path to the GitHub App private key (PEM)")
+	authAddGitCmd.Flags().StringVar(&authExpires, "expires", "", "When the token or key expires: a date (2026-12-31) or a duration (90d)")
+	authAddGitCmd.Flags().StringVar(&authGitHubEnterpriseAPI, "github-api-url", "", "GitHub Enterprise Server API URL for GitHub Apps (e.g. https://github.example.com/api/v3)")
 
 	// Platform credentials flags
 	authAddPlatformCmd.Flags().StringVar(&authPlatform, "platform", "", "Platform: kubernetes, openshift, aws, azure, gcp")
@@ -181,6 +293,7 @@ func init() {
 	authAddPlatformCmd.Flags().StringVar(&authClientID, "client-id", "", "Client ID for OIDC/Azure")
 	authAddPlatformCmd.Flags().StringVar(&authNamespace, "namespace", "", "Kubernetes namespace")
 	authAddPlatformCmd.Flags().StringVar(&authSecretName, "secret-name", "", "Secret name")
+	authAddPlatformCmd.Flags().StringVar(&authExpires, "expires", "", "When the token expires: a date (2026-12-31) or a duration (90d)")
 
 	// Registry credentials flags
 	authAddRegistryCmd.Flags().StringVar(&authURL, "url", "", "Registry URL")
@@ -188,10 +301,45 @@ func init() {
 	authAddRegistryCmd.Flags().StringVar(&authPassword, "password", "", "Registry password")
 	authAddRegistryCmd.Flags().StringVar(&authNamespace, "namespace", "", "Kubernetes namespace")
 	authAddRegistryCmd.Flags().StringVar(&authSecretName, "secret-name", "", "Secret name")
+	authAddRegistryCmd.Flags().StringVar(&authExpires, "expires", "", "When the password expires: a date (2026-12-31) or a duration (90d)")
+
+	// List flags
+	authListCmd.Flags().DurationVar(&authExpiryWarning, "expiry-warning", status.DefaultExpiryWarning, "Warn about credentials expiring within this duration")
 
 	// Generate flags
-	authGenerateCmd.Flags().StringVar(&authFormat, "format", "k8s", "Output format: k8s, argocd, flux")
+	authGenerateCmd.Flags().StringVar(&authFormat, "format", "k8s", "Output format: k8s, argocd, argocd-creds, flux")
 	authGenerateCmd.Flags().StringVar(&authNamespace, "namespace", "", "Override namespace for generated secret")
+	authGenerateCmd.Flags().StringVar(&authSecretFormat, "secret-format", "plain", "Secret encoding: plain, sops")
+	authGenerateCmd.Flags().StringSliceVar(&authSopsAge, "sops-age", nil, "age recipients for SOPS encryption")
+	authGenerateCmd.Flags().StringSliceVar(&authSopsPGP, "sops-pgp", nil, "PGP fingerprints for SOPS encryption")
+	authGenerateCmd.Flags().StringSliceVar(&authSopsKMS, "sops-kms", nil, "AWS KMS key ARNs for SOPS encryption")
+	authGenerateCmd.Flags().StringSliceVar(&authSopsGCPKMS, "sops-gcp-kms", nil, "GCP KMS resource IDs for SOPS encryption")
+	authGenerateCmd.Flags().StringSliceVar(&authSopsAzureKV, "sops-azure-kv", nil, "Azure Key Vault key URLs for SOPS encryption")
+
+	// Seal flags
+	authSealCmd.Flags().StringVar(&authFormat, "format", "k8s", "Secret format: k8s, argocd, argocd-creds, flux")
+	authSealCmd.Flags().StringVar(&authNamespace, "namespace", "", "Override namespace for generated secret")
+	authSealCmd.Flags().StringVar(&authSealCluster, "cluster", "", "Kubeconfig context of the target cluster")
+	authSealCmd.Flags().StringVar(&authSealKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	authSealCmd.Flags().StringVar(&authSealCert, "cert", "", "Path or URL to the controller public certificate (offline sealing)")
+	authSealCmd.Flags().StringVar(&authSealControllerNamespace, "controller-namespace", "", "Namespace of the sealed-secrets controller (default: kube-system)")
+	authSealCmd.Flags().StringVar(&authSealControllerName, "controller-name", "", "Name of the sealed-secrets controller (default: sealed-secrets-controller)")
+	authSealCmd.Flags().StringVar(&authSealScope, "scope", "", "Sealing scope: strict, namespace-wide, cluster-wide")
+
+	// Migrate flags
+	// Create deploy key flags
+	authCreateDeployKeyCmd.Flags().StringVar(&authURL, "url", "", "Git repository URL")
+	authCreateDeployKeyCmd.Flags().StringVar(&authProvider, "provider", "", "Git provider, for self-hosted instances: github, gitlab, gitea, bitbucket")
+	authCreateDeployKeyCmd.Flags().StringVar(&authToken, "token", "", "API token (or use env var)")
+	authCreateDeployKeyCmd.Flags().StringVar(&authDeployKeyAPIURL, "api-url", "", "Provider API URL (default: derived from the repository host)")
+	authCreateDeployKeyCmd.Flags().StringVar(&authDeployKeyTitle, "title", "", "Deploy key title (default: gitopsi-<name>)")
+	authCreateDeployKeyCmd.Flags().BoolVar(&authDeployKeyWrite, "read-write", false, "Grant the key push access")
+	authCreateDeployKeyCmd.Flags().StringVar(&authDeployKeyFormat, "format", "argocd", "Secret to print: argocd, flux, none")
+	authCreateDeployKeyCmd.Flags().StringVar(&authNamespace, "namespace", "", "Override namespace for generated secret")
+	authCreateDeployKeyCmd.Flags().StringVar(&authSecretName, "secret-name", "", "Name for generated Kubernetes secret")
+	_ = authCreateDeployKeyCmd.MarkFlagRequired("url")
+
+	authMigrateCmd.Flags().StringVar(&authMigrateTo, "to", "encrypted", "Target store format: encrypted, plain, keyring")
 
 	// Mark required flags
 	_ = authAddGitCmd.MarkFlagRequired("provider")
@@ -204,14 +352,84 @@ func init() {
 }
 
 func getAuthManager() (*auth.Manager, error) {
-	storePath := auth.GetDefaultStorePath()
-	store, err := auth.NewFileStore(storePath)
+	store, err := getAuthStore()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize credential store: %w", err)
 	}
 	return auth.NewManager(store, auth.SecretFormatPlain), nil
 }
 
+// readCredential returns a credential of the auth store by name.
+func readCredential(ctx context.Context, name string) (*auth.Credential, error) {
+	manager, err := getAuthManager()
+	if err != nil {
+		return nil, err
+	}
+	return manager.GetCredential(ctx, name)
+}
+
+// parseExpiresFlag returns the expiry of --expires, or nil when it is unset.
+func parseExpiresFlag() (*time.Time, error) {
+	if authExpires == "" {
+		return nil, nil
+	}
+	expires, err := auth.ParseExpiry(authExpires, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &expires, nil
+}
+
+// getSecretManager returns an auth manager that encodes generated secrets
+// in the format selected with --secret-format.
+func getSecretManager() (*auth.Manager, error) {
+	store, err := getAuthStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize credential store: %w", err)
+	}
+
+	format := auth.SecretFormat(authSecretFormat)
+	manager := auth.NewManager(store, format)
+
+	switch format {
+	case auth.SecretFormatPlain:
+	case auth.SecretFormatSops:
+		enc, err := auth.NewSopsEncrypter(&auth.SopsOptions{
+			Age:           authSopsAge,
+			PGP:           authSopsPGP,
+			KMS:           authSopsKMS,
+			GCPKMS:        authSopsGCPKMS,
+			AzureKeyVault: authSopsAzureKV,
+		})
+		if err != nil {
+			return nil, err
+		}
+		manager.SetEncrypter(enc)
+	default:
+		return nil, fmt.Errorf("unsupported secret format: %s (must be plain or sops)", authSecretFormat)
+	}
+
+	return manager, nil
+}
+
+// getAuthStore returns the credential store selected by the auth.store setting.
+func getAuthStore() (auth.Store, error) {
+	backend, err := authStoreBackend()
+	if err != nil {
+		return nil, err
+	}
+	return auth.OpenStore(backend, "")
+}
+
+// authStoreBackend returns the auth.store user setting.
+func authStoreBackend() (string, error) {
+	settings, err := config.LoadUserSettings(config.DefaultUserSettingsPath())
+	if err != nil {
+		return "", err
+	}
+	return settings.AuthStore(), nil
+}
+
 func runAuthAddGit(cmd *cobra.Command, args []string) error {
 	name := args[0]
 	ctx := context.Background()
@@ -230,6 +448,9 @@ func runAuthAddGit(cmd *cobra.Command, args []string) error {
 		SecretName: authSecretName,
 		Username:   authUsername,
 	}
+	if opts.ExpiresAt, err = parseExpiresFlag(); err != nil {
+		return err
+	}
 
 	// Get token from flag or environment
 	switch opts.Method {
@@ -259,12 +480,27 @@ func runAuthAddGit(cmd *cobra.Command, args []string) error {
 	case auth.MethodOAuth:
 		opts.Token = getTokenValue(authToken, authProvider)
 		opts.ClientID = authClientID
+	case auth.MethodGitHubApp:
+		if authAppPrivateKeyFile == "" {
+			return fmt.Errorf("--app-private-key is required for GitHub App authentication")
+		}
+		data, readErr := os.ReadFile(authAppPrivateKeyFile)
+		if readErr != nil {
+			return fmt.Errorf("failed to read GitHub App private key: %w", readErr)
+		}
+		opts.GitHubAppID = authAppID
+		opts.GitHubAppInstallationID = authInstallationID
+		opts.GitHubAppPrivateKey = string(data)
+		opts.GitHubAppEnterpriseBaseURL = authGitHubEnterpriseAPI
 	}
 
 	cred, err := manager.AddGitCredential(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to add credential: %w", err)
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(summarizeCredential(cred))
+	}
 
 	pterm.Success.Printf("Git credential '%s' added successfully\n", cred.Name)
 	pterm.Info.Printf("Provider: %s, Method: %s\n", cred.Provider, cred.Method)
@@ -289,6 +525,9 @@ func runAuthAddPlatform(cmd *cobra.Command, args []string) error {
 		Namespace:  authNamespace,
 		SecretName: authSecretName,
 	}
+	if opts.ExpiresAt, err = parseExpiresFlag(); err != nil {
+		return err
+	}
 
 	switch opts.Method {
 	case auth.MethodToken, auth.MethodServiceAccount:
@@ -318,6 +557,9 @@ func runAuthAddPlatform(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to add credential: %w", err)
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(summarizeCredential(cred))
+	}
 
 	pterm.Success.Printf("Platform credential '%s' added successfully\n", cred.Name)
 	pterm.Info.Printf("Platform: %s, Method: %s\n", cred.Provider, cred.Method)
@@ -342,11 +584,17 @@ func runAuthAddRegistry(cmd *cobra.Command, args []string) error {
 		Namespace:  authNamespace,
 		SecretName: authSecretName,
 	}
+	if opts.ExpiresAt, err = parseExpiresFlag(); err != nil {
+		return err
+	}
 
 	cred, err := manager.AddRegistryCredential(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to add credential: %w", err)
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(summarizeCredential(cred))
+	}
 
 	pterm.Success.Printf("Registry credential '%s' added successfully\n", cred.Name)
 	pterm.Info.Printf("URL: %s\n", cred.Metadata.URL)
@@ -371,6 +619,13 @@ func runAuthList(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to list credentials: %w", err)
 	}
+	if p := newPrinter(); p.structured() {
+		summaries := make([]credentialSummary, 0, len(creds))
+		for _, cred := range creds {
+			summaries = append(summaries, summarizeCredential(cred))
+		}
+		return p.print(summaries)
+	}
 
 	if len(creds) == 0 {
 		pterm.Info.Println("No credentials found")
@@ -378,24 +633,41 @@ func runAuthList(cmd *cobra.Command, args []string) error {
 	}
 
 	tableData := [][]string{
-		{"NAME", "TYPE", "PROVIDER", "METHOD", "URL"},
+		{"NAME", "TYPE", "PROVIDER", "METHOD", "URL", "EXPIRES"},
 	}
 
+	now := time.Now()
+	var expiring []string
 	for _, cred := range creds {
 		url := cred.Metadata.URL
 		if len(url) > 40 {
 			url = url[:37] + "..."
 		}
+		expires := "-"
+		switch state, left := cred.Expiry(now, authExpiryWarning); state {
+		case auth.ExpiryExpired:
+			expires = pterm.Red(fmt.Sprintf("expired %s ago", status.FormatDays(-left)))
+			expiring = append(expiring, fmt.Sprintf("%s expired %s ago", cred.Name, status.FormatDays(-left)))
+		case auth.ExpirySoon:
+			expires = pterm.Yellow("in " + status.FormatDays(left))
+			expiring = append(expiring, fmt.Sprintf("%s expires in %s", cred.Name, status.FormatDays(left)))
+		case auth.ExpiryValid:
+			expires = "in " + status.FormatDays(left)
+		}
 		tableData = append(tableData, []string{
 			cred.Name,
 			string(cred.Type),
 			cred.Provider,
 			string(cred.Method),
 			url,
+			expires,
 		})
 	}
 
 	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	for _, warning := range expiring {
+		pterm.Warning.Printf("%s: rotate it with gitopsi auth rotate\n", warning)
+	}
 	return nil
 }
 
@@ -414,6 +686,11 @@ func runAuthTest(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to test credential: %w", err)
 	}
+	if p := newPrinter(); p.structured() {
+		if err := p.print(result); err != nil {
+			return err
+		}
+	}
 
 	if result.Success {
 		pterm.Success.Printf("Credential '%s' is valid\n", name)
@@ -441,6 +718,9 @@ func runAuthDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	pterm.Success.Printf("Credential '%s' deleted successfully\n", name)
+	if p := newPrinter(); p.structured() {
+		return p.print(map[string]any{"name": name, "deleted": true})
+	}
 	return nil
 }
 
@@ -448,14 +728,63 @@ func runAuthGenerate(cmd *cobra.Command, args []string) error {
 	name := args[0]
 	ctx := context.Background()
 
-	manager, err := getAuthManager()
+	manager, err := getSecretManager()
+	if err != nil {
+		return err
+	}
+
+	output, err := generateSecretManifest(ctx, manager, name, authFormat)
 	if err != nil {
 		return err
 	}
 
+	fmt.Println(output)
+	return nil
+}
+
+func runAuthSeal(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	if authSealCluster == "" && authSealCert == "" {
+		return fmt.Errorf("either --cluster or --cert is required to select the sealing key")
+	}
+
+	sealer, err := auth.NewSealedSecretsEncrypter(&auth.SealOptions{
+		Context:             authSealCluster,
+		Kubeconfig:          authSealKubeconfig,
+		Cert:                authSealCert,
+		ControllerNamespace: authSealControllerNamespace,
+		ControllerName:      authSealControllerName,
+		Scope:               authSealScope,
+	})
+	if err != nil {
+		return err
+	}
+
+	store, err := getAuthStore()
+	if err != nil {
+		return fmt.Errorf("failed to initialize credential store: %w", err)
+	}
+	manager := auth.NewManager(store, auth.SecretFormatSealed)
+	manager.SetEncrypter(sealer)
+
+	output, err := generateSecretManifest(ctx, manager, name, authFormat)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(output)
+	return nil
+}
+
+// generateSecretManifest renders the credential as a k8s, argocd,
+// argocd-creds or flux secret.
+func generateSecretManifest(ctx context.Context, manager *auth.Manager, name, format string) (string, error) {
 	var output string
+	var err error
 
-	switch authFormat {
+	switch format {
 	case "k8s", "kubernetes":
 		output, err = manager.GenerateKubernetesSecret(ctx, name)
 	case "argocd":
@@ -464,6 +793,12 @@ func runAuthGenerate(cmd *cobra.Command, args []string) error {
 			ns = "argocd"
 		}
 		output, err = manager.GenerateArgoCDRepoSecret(ctx, name, ns)
+	case "argocd-creds":
+		ns := authNamespace
+		if ns == "" {
+			ns = "argocd"
+		}
+		output, err = manager.GenerateArgoCDRepoCredsSecret(ctx, name, ns)
 	case "flux":
 		ns := authNamespace
 		if ns == "" {
@@ -471,17 +806,125 @@ func runAuthGenerate(cmd *cobra.Command, args []string) error {
 		}
 		output, err = manager.GenerateFluxGitRepositorySecret(ctx, name, ns)
 	default:
-		return fmt.Errorf("unsupported format: %s", authFormat)
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return output, nil
+}
+
+func runAuthCreateDeployKey(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	if authDeployKeyFormat != "argocd" && authDeployKeyFormat != "flux" && authDeployKeyFormat != "none" {
+		return fmt.Errorf("unsupported format: %s (must be argocd, flux or none)", authDeployKeyFormat)
+	}
+	repo, err := gitprovider.ParseRepo(authURL)
+	if err != nil {
+		return err
+	}
+	provider := authProvider
+	if provider == "" {
+		provider = string(repo.Provider)
+	}
+	token := getTokenValue(authToken, provider)
+	if token == "" {
+		return fmt.Errorf("--token is required or set %s_TOKEN environment variable", strings.ToUpper(provider))
+	}
+	client, err := gitprovider.New(ctx, authURL, gitprovider.Options{
+		Provider: git.ProviderType(authProvider),
+		Token:    token,
+		BaseURL:  authDeployKeyAPIURL,
+		Probe:    true,
+	})
+	if err != nil {
+		return err
 	}
 
+	manager, err := getSecretManager()
 	if err != nil {
-		return fmt.Errorf("failed to generate secret: %w", err)
+		return err
+	}
+	if _, err := manager.GetCredential(ctx, name); err == nil {
+		return fmt.Errorf("credential %s already exists", name)
 	}
 
+	title := authDeployKeyTitle
+	if title == "" {
+		title = "gitopsi-" + name
+	}
+	pair, err := auth.GenerateDeployKey(title)
+	if err != nil {
+		return err
+	}
+	key, err := client.AddDeployKey(ctx, gitprovider.DeployKey{Title: title, Key: pair.PublicKey, ReadOnly: !authDeployKeyWrite})
+	if err != nil {
+		return fmt.Errorf("failed to register deploy key on %s: %w", client.Repo().FullName(), err)
+	}
+
+	access := "read-only"
+	if authDeployKeyWrite {
+		access = "read-write"
+	}
+	knownHosts, _ := auth.LoadSSHKnownHosts("")
+	_, err = manager.AddGitCredential(ctx, &auth.GitCredentialOptions{
+		Name:          name,
+		Provider:      auth.GitProvider(client.Name()),
+		Method:        auth.MethodSSH,
+		URL:           client.Repo().SSHURL(),
+		Description:   fmt.Sprintf("%s deploy key %s (%s)", access, title, pair.Fingerprint),
+		Namespace:     authNamespace,
+		SecretName:    authSecretName,
+		SSHPrivateKey: pair.PrivateKey,
+		SSHPublicKey:  pair.PublicKey,
+		SSHKnownHosts: knownHosts,
+	})
+	if err != nil {
+		return fmt.Errorf("deploy key %s was registered but the credential could not be saved: %w", key.ID, err)
+	}
+
+	// Status goes to stderr so the manifest can be redirected to a file.
+	pterm.Success.WithWriter(os.Stderr).Printf("Registered %s deploy key '%s' on %s (%s)\n", access, title, client.Repo().FullName(), pair.Fingerprint)
+	pterm.Info.WithWriter(os.Stderr).Printf("Stored as SSH git credential '%s' for %s\n", name, client.Repo().SSHURL())
+
+	if authDeployKeyFormat == "none" {
+		return nil
+	}
+	output, err := generateSecretManifest(ctx, manager, name, authDeployKeyFormat)
+	if err != nil {
+		return err
+	}
 	fmt.Println(output)
 	return nil
 }
 
+// credentialSummary is the structured output of a credential. It leaves out
+// the credential data so secrets never reach stdout.
+type credentialSummary struct {
+	Name      string                  `json:"name" yaml:"name"`
+	Type      auth.CredentialType     `json:"type" yaml:"type"`
+	Provider  string                  `json:"provider" yaml:"provider"`
+	Method    auth.Method             `json:"method" yaml:"method"`
+	Metadata  auth.CredentialMetadata `json:"metadata" yaml:"metadata"`
+	CreatedAt time.Time               `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time               `json:"updated_at" yaml:"updated_at"`
+}
+
+func summarizeCredential(cred *auth.Credential) credentialSummary {
+	return credentialSummary{
+		Name:      cred.Name,
+		Type:      cred.Type,
+		Provider:  cred.Provider,
+		Method:    cred.Method,
+		Metadata:  cred.Metadata,
+		CreatedAt: cred.CreatedAt,
+		UpdatedAt: cred.UpdatedAt,
+	}
+}
+
 func getTokenValue(tokenFlag, provider string) string {
 	if tokenFlag != "" {
 		// Check if it's an environment variable reference
@@ -508,3 +951,118 @@ func getTokenValue(tokenFlag, provider string) string {
 
 	return ""
 }
+
+func runAuthMigrate(cmd *cobra.Command, args []string) error {
+	storePath := auth.GetDefaultStorePath()
+
+	encrypted, err := auth.IsEncryptedStore(storePath)
+	if err != nil {
+		return err
+	}
+
+	if authMigrateTo == "keyring" {
+		return migrateToKeyring(storePath, encrypted)
+	}
+
+	var oldPassphrase, newPassphrase string
+	switch authMigrateTo {
+	case "encrypted":
+		if encrypted {
+			pterm.Info.Printf("Credential store %s is already encrypted\n", storePath)
+			return nil
+		}
+		newPassphrase, err = readStorePassphrase(true)
+	case "plain":
+		if !encrypted {
+			pterm.Info.Printf("Credential store %s is already plaintext\n", storePath)
+			return nil
+		}
+		oldPassphrase, err = readStorePassphrase(false)
+	default:
+		return fmt.Errorf("invalid store format: %s (must be encrypted, plain, or keyring)", authMigrateTo)
+	}
+	if err != nil {
+		return err
+	}
+
+	count, err := auth.MigrateFileStore(storePath, oldPassphrase, newPassphrase)
+	if err != nil {
+		return fmt.Errorf("failed to migrate credential store: %w", err)
+	}
+
+	if p := newPrinter(); p.structured() {
+		return p.print(map[string]any{"store": storePath, "format": authMigrateTo, "migrated": count})
+	}
+	pterm.Success.Printf("Migrated %d credentials to %s store %s\n", count, authMigrateTo, storePath)
+	if authMigrateTo == "encrypted" && os.Getenv(auth.PassphraseEnvVar) == "" {
+		pterm.Info.Printf("Set %s to unlock the store in future commands\n", auth.PassphraseEnvVar)
+	}
+	return nil
+}
+
+// migrateToKeyring moves all file store credentials into the OS keyring.
+func migrateToKeyring(storePath string, encrypted bool) error {
+	ctx := context.Background()
+
+	var passphrase string
+	if encrypted {
+		var err error
+		if passphrase, err = readStorePassphrase(false); err != nil {
+			return err
+		}
+	}
+
+	fileStore, err := auth.OpenFileStore(storePath, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to open credential store: %w", err)
+	}
+
+	count, err := auth.CopyCredentials(ctx, fileStore, auth.NewKeyringStore(""))
+	if err != nil {
+		return fmt.Errorf("failed to migrate credentials to keyring: %w", err)
+	}
+
+	settingsPath := config.DefaultUserSettingsPath()
+	settings, err := config.LoadUserSettings(settingsPath)
+	if err != nil {
+		return err
+	}
+	if err := settings.Set("auth.store", auth.StoreKeyring); err != nil {
+		return err
+	}
+	if err := config.SaveUserSettings(settings, settingsPath); err != nil {
+		return err
+	}
+
+	if err := os.Remove(storePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("credentials copied but failed to remove %s: %w", storePath, err)
+	}
+
+	pterm.Success.Printf("Migrated %d credentials to the OS keyring\n", count)
+	return nil
+}
+
+// readStorePassphrase returns the store passphrase from the environment or
+// prompts for it, asking for confirmation when a new passphrase is chosen.
+func readStorePassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(auth.PassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+
+	var passphrase string
+	if err := survey.AskOne(&survey.Password{Message: "Credential store passphrase:"}, &passphrase, survey.WithValidator(survey.Required)); err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	if confirm {
+		var again string
+		if err := survey.AskOne(&survey.Password{Message: "Confirm passphrase:"}, &again); err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
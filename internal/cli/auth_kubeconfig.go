@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+var (
+	authImportKubeconfig string
+	authImportContext    string
+	authImportName       string
+	authImportForce      bool
+)
+
+var authImportKubeconfigCmd = &cobra.Command{
+	Use:   "import-kubeconfig",
+	Short: "Import kubeconfig contexts as platform credentials",
+	Long: `Convert the contexts of a kubeconfig into platform credentials named after
+the contexts. Bearer tokens, client certificates and basic auth are stored with
+the certificate authority of the cluster; exec plugins (aws, gke-gcloud-auth-plugin,
+kubelogin, ...) are stored as is and run whenever the credential is used.
+
+Cluster operations use a stored platform credential instead of the kubeconfig
+with --cluster-credential, or with cluster.credential and
+environments[].credential in gitopsi.yaml.
+
+Examples:
+  gitopsi auth import-kubeconfig
+  gitopsi auth import-kubeconfig --context prod-admin --name prod
+  gitopsi auth import-kubeconfig --kubeconfig ./ci.kubeconfig --force
+  gitopsi bootstrap --cluster-credential prod`,
+	Args: cobra.NoArgs,
+	RunE: runAuthImportKubeconfig,
+}
+
+func init() {
+	authImportKubeconfigCmd.Flags().StringVar(&authImportKubeconfig, "kubeconfig", "", "Path to kubeconfig file (default: $KUBECONFIG or ~/.kube/config)")
+	authImportKubeconfigCmd.Flags().StringVar(&authImportContext, "context", "", "Import only this context")
+	authImportKubeconfigCmd.Flags().StringVar(&authImportName, "name", "", "Credential name for the imported context (requires --context)")
+	authImportKubeconfigCmd.Flags().BoolVar(&authImportForce, "force", false, "Overwrite existing credentials")
+}
+
+func runAuthImportKubeconfig(cmd *cobra.Command, args []string) error {
+	opts := &auth.KubeconfigImportOptions{
+		Path:      authImportKubeconfig,
+		Context:   authImportContext,
+		Name:      authImportName,
+		Overwrite: authImportForce,
+	}
+	if opts.Name != "" && opts.Context == "" {
+		return fmt.Errorf("--name requires --context")
+	}
+
+	var result *auth.KubeconfigImport
+	if dryRun {
+		var err error
+		if result, err = auth.CredentialsFromKubeconfig(opts.Path, opts.Context); err != nil {
+			return err
+		}
+		for _, cred := range result.Credentials {
+			if opts.Name != "" {
+				cred.Name = opts.Name
+			}
+		}
+	} else {
+		manager, err := getAuthManager()
+		if err != nil {
+			return err
+		}
+		if result, err = manager.ImportKubeconfig(context.Background(), opts); err != nil {
+			return err
+		}
+	}
+
+	p := newPrinter()
+	if p.structured() {
+		summaries := make([]credentialSummary, 0, len(result.Credentials))
+		for _, cred := range result.Credentials {
+			summaries = append(summaries, summarizeCredential(cred))
+		}
+		return p.print(summaries)
+	}
+
+	for _, skipped := range result.Skipped {
+		pterm.Warning.WithWriter(os.Stderr).Printf("Skipped %s\n", skipped)
+	}
+	if len(result.Credentials) == 0 {
+		pterm.Info.Println("No credentials imported")
+		return nil
+	}
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	data := pterm.TableData{{"NAME", "PLATFORM", "METHOD", "CONTEXT", "URL"}}
+	for _, cred := range result.Credentials {
+		data = append(data, []string{cred.Name, cred.Provider, string(cred.Method), cred.KubeContext(), cred.Metadata.URL})
+	}
+	pterm.Success.Printf("%s %d platform credential(s)\n", verb, len(result.Credentials))
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+)
+
+var (
+	authRotateFormats    []string
+	authRotateContext    string
+	authRotateKubeconfig string
+	authRotateNoApply    bool
+)
+
+var authRotateCmd = &cobra.Command{
+	Use:   "rotate [name]",
+	Short: "Rotate a credential and update its secrets on the cluster",
+	Long: `Replace the secret of a stored credential, regenerate the ArgoCD, Flux or
+Kubernetes secrets that use it and apply them to the cluster of the current
+kubeconfig context (or --context).
+
+The new secret comes from the provider API where it offers one:
+  - GitLab tokens are rotated with the token rotation API, which revokes the
+    old token and returns the new one with its expiry
+  - SSH deploy keys are replaced by a new key registered on the repository
+Other credentials take the new value from --token, --password or --ssh-key.
+
+With --dry-run nothing is rotated or applied. With --no-apply the secrets are
+printed instead of applied.
+
+Examples:
+  gitopsi auth rotate gitlab-main --expires 90d --context prod
+  gitopsi auth rotate github-main --token $NEW_TOKEN --expires 2026-12-31
+  gitopsi auth rotate platform-deploy --format argocd --format flux
+  gitopsi auth rotate quay --password $NEW_PASSWORD --no-apply > quay-secret.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthRotate,
+}
+
+func init() {
+	authRotateCmd.Flags().StringVar(&authToken, "token", "", "New token, or the API token registering a new deploy key (or use $VAR)")
+	authRotateCmd.Flags().StringVar(&authPassword, "password", "", "New password of basic auth credentials")
+	authRotateCmd.Flags().StringVar(&authSSHKeyFile, "ssh-key", "", "Path to the new SSH private key (default: generate and register a deploy key)")
+	authRotateCmd.Flags().StringVar(&authExpires, "expires", "", "When the new secret expires: a date (2026-12-31) or a duration (90d)")
+	authRotateCmd.Flags().StringVar(&authDeployKeyAPIURL, "api-url", "", "Provider API URL (default: derived from the repository host)")
+	authRotateCmd.Flags().StringSliceVar(&authRotateFormats, "format", nil, "Secret to regenerate: k8s, argocd, argocd-creds, flux (repeatable, default: argocd for git credentials, k8s otherwise)")
+	authRotateCmd.Flags().StringVar(&authNamespace, "namespace", "", "Override namespace for generated secrets")
+	authRotateCmd.Flags().StringVar(&authRotateContext, "context", "", "Kubeconfig context of the cluster to apply the secrets to")
+	authRotateCmd.Flags().StringVar(&authRotateKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	authRotateCmd.Flags().BoolVar(&authRotateNoApply, "no-apply", false, "Print the regenerated secrets instead of applying them")
+}
+
+// rotateResult is the structured output of auth rotate.
+type rotateResult struct {
+	Name      string     `json:"name" yaml:"name"`
+	Source    string     `json:"source" yaml:"source"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Secrets   []string   `json:"secrets" yaml:"secrets"`
+	Applied   bool       `json:"applied" yaml:"applied"`
+	DryRun    bool       `json:"dry_run" yaml:"dry_run"`
+}
+
+func runAuthRotate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	manager, err := getAuthManager()
+	if err != nil {
+		return err
+	}
+	cred, err := manager.GetCredential(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	formats := authRotateFormats
+	if len(formats) == 0 {
+		formats = []string{"k8s"}
+		if cred.Type == auth.CredentialTypeGit {
+			formats = []string{"argocd"}
+		}
+	}
+	for _, format := range formats {
+		if !slices.Contains([]string{"k8s", "kubernetes", "argocd", "argocd-creds", "flux"}, format) {
+			return fmt.Errorf("unsupported format: %s (must be k8s, argocd, argocd-creds or flux)", format)
+		}
+	}
+
+	expires, err := parseExpiresFlag()
+	if err != nil {
+		return err
+	}
+	rotate, source, err := planRotation(cred)
+	if err != nil {
+		return err
+	}
+
+	result := rotateResult{Name: name, Source: source, Secrets: formats, DryRun: dryRun}
+	p := newPrinter()
+	if dryRun {
+		if p.structured() {
+			return p.print(result)
+		}
+		pterm.Info.Printf("Would rotate '%s' with %s and regenerate its %s secrets\n", name, source, strings.Join(formats, ", "))
+		return nil
+	}
+
+	rotation, err := rotate(ctx, expires)
+	if err != nil {
+		return err
+	}
+	cred, err = manager.RotateCredential(ctx, name, rotation)
+	if err != nil {
+		// The provider API has already replaced the old secret: it is only
+		// known from this rotation.
+		if !strings.HasPrefix(source, "--") {
+			printUnsavedRotation(cmd.ErrOrStderr(), name, rotation)
+		}
+		return fmt.Errorf("failed to rotate credential: %w", err)
+	}
+	result.ExpiresAt = cred.Metadata.ExpiresAt
+
+	manifests := make([]string, 0, len(formats))
+	for _, format := range formats {
+		manifest, err := generateSecretManifest(ctx, manager, name, format)
+		if err != nil {
+			return fmt.Errorf("credential %s was rotated but its secrets could not be generated: %w", name, err)
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	// Status goes to stderr so the secrets can be redirected to a file.
+	status := os.Stderr
+	pterm.Success.WithWriter(status).Printf("Rotated credential '%s' with %s\n", name, source)
+	if expires := cred.Metadata.ExpiresAt; expires != nil {
+		pterm.Info.WithWriter(status).Printf("Expires %s\n", expires.Format(time.DateOnly))
+	}
+
+	if authRotateNoApply {
+		if p.structured() {
+			return p.print(result)
+		}
+		fmt.Println(strings.Join(manifests, "\n---\n"))
+		return nil
+	}
+
+	c := cluster.New("", authRotateContext, cluster.PlatformKubernetes)
+	if err := c.Authenticate(&cluster.AuthOptions{Method: cluster.AuthKubeconfig, Kubeconfig: authRotateKubeconfig, Context: authRotateContext}); err != nil {
+		return fmt.Errorf("credential %s was rotated but its secrets could not be applied: %w", name, err)
+	}
+	for i, manifest := range manifests {
+		if err := c.Apply(ctx, manifest); err != nil {
+			return fmt.Errorf("credential %s was rotated but its %s secret could not be applied: %w", name, formats[i], err)
+		}
+		pterm.Success.WithWriter(status).Printf("Applied the %s secret\n", formats[i])
+	}
+	result.Applied = true
+	if p.structured() {
+		return p.print(result)
+	}
+	return nil
+}
+
+// printUnsavedRotation writes the new secret of a rotation that could not be
+// stored, so it can be saved by hand. It is printed even with -o json or
+// yaml, which silence pterm.
+func printUnsavedRotation(w io.Writer, name string, rotation *auth.Rotation) {
+	fmt.Fprintf(w, "Warning: the previous secret of '%s' is no longer valid and the new one could not be stored. Save it now:\n", name)
+	switch {
+	case rotation.Token != "":
+		fmt.Fprintf(w, "  token: %s\n", rotation.Token)
+	case rotation.SSHPrivateKey != "":
+		fmt.Fprintf(w, "%s\n", strings.TrimSpace(rotation.SSHPrivateKey))
+	}
+	if rotation.ExpiresAt != nil {
+		fmt.Fprintf(w, "  expires: %s\n", rotation.ExpiresAt.Format(time.DateOnly))
+	}
+}
+
+// rotator returns the new secret material of a credential.
+type rotator func(ctx context.Context, expires *time.Time) (*auth.Rotation, error)
+
+// planRotation selects how the secret of a credential is replaced: with the
+// value of a flag, or through the provider API.
+func planRotation(cred *auth.Credential) (rotator, string, error) {
+	// The token environment variables usually hold the token being replaced,
+	// so only an explicit --token is a new token.
+	token := ""
+	if authToken != "" {
+		token = getTokenValue(authToken, cred.Provider)
+	}
+
+	switch cred.Method {
+	case auth.MethodToken, auth.MethodOAuth, auth.MethodServiceAccount:
+		if token != "" {
+			return func(_ context.Context, expires *time.Time) (*auth.Rotation, error) {
+				return &auth.Rotation{Token: token, ExpiresAt: expires}, nil
+			}, "--token", nil
+		}
+		if cred.Type == auth.CredentialTypeGit && cred.Provider == string(auth.GitProviderGitLab) && cred.Metadata.URL != "" {
+			return rotateGitLabToken(cred), "the GitLab token rotation API", nil
+		}
+		return nil, "", fmt.Errorf("--token is required: the %s API cannot rotate tokens", cred.Provider)
+	case auth.MethodBasic:
+		if authPassword == "" {
+			return nil, "", fmt.Errorf("--password is required to rotate basic auth credentials")
+		}
+		return func(_ context.Context, expires *time.Time) (*auth.Rotation, error) {
+			return &auth.Rotation{Password: authPassword, ExpiresAt: expires}, nil
+		}, "--password", nil
+	case auth.MethodSSH:
+		if authSSHKeyFile != "" {
+			return func(_ context.Context, expires *time.Time) (*auth.Rotation, error) {
+				key, err := auth.LoadSSHKeyFromFile(authSSHKeyFile)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load SSH key: %w", err)
+				}
+				return &auth.Rotation{SSHPrivateKey: key, ExpiresAt: expires}, nil
+			}, "--ssh-key", nil
+		}
+		if cred.Type != auth.CredentialTypeGit || cred.Metadata.URL == "" {
+			return nil, "", fmt.Errorf("--ssh-key is required: %s has no repository to register a deploy key on", cred.Name)
+		}
+		if token = getTokenValue(authToken, cred.Provider); token == "" {
+			return nil, "", fmt.Errorf("--token is required or set %s_TOKEN environment variable to register a new deploy key", strings.ToUpper(cred.Provider))
+		}
+		return rotateDeployKey(cred, token), "a new deploy key", nil
+	case auth.MethodGitHubApp:
+		return nil, "", fmt.Errorf("GitHub App credentials use short-lived installation tokens: rotate the private key in the app settings and add the credential again")
+	default:
+		return nil, "", fmt.Errorf("%s credentials have no secret to rotate", cred.Method)
+	}
+}
+
+// rotateGitLabToken rotates the token of a GitLab credential with the token
+// it replaces.
+func rotateGitLabToken(cred *auth.Credential) rotator {
+	return func(ctx context.Context, expires *time.Time) (*auth.Rotation, error) {
+		client, err := gitprovider.New(ctx, cred.Metadata.URL, gitprovider.Options{
+			Provider: git.ProviderGitLab,
+			Token:    cred.Data.Token,
+			BaseURL:  authDeployKeyAPIURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rotator, ok := client.(gitprovider.TokenRotator)
+		if !ok {
+			return nil, fmt.Errorf("--token is required: the %s API cannot rotate tokens", client.Name())
+		}
+		var until time.Time
+		if expires != nil {
+			until = *expires
+		}
+		token, expiresAt, err := rotator.RotateToken(ctx, until)
+		if err != nil {
+			return nil, err
+		}
+		return &auth.Rotation{Token: token, ExpiresAt: &expiresAt}, nil
+	}
+}
+
+// rotateDeployKey generates a deploy key with the access of the key of the
+// credential and registers it on its repository.
+func rotateDeployKey(cred *auth.Credential, token string) rotator {
+	return func(ctx context.Context, expires *time.Time) (*auth.Rotation, error) {
+		client, err := gitprovider.New(ctx, cred.Metadata.URL, gitprovider.Options{
+			Provider: git.ProviderType(cred.Provider),
+			Token:    token,
+			BaseURL:  authDeployKeyAPIURL,
+			Probe:    true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		title := fmt.Sprintf("gitopsi-%s-%s", cred.Name, time.Now().Format("20060102"))
+		pair, err := auth.GenerateDeployKey(title)
+		if err != nil {
+			return nil, err
+		}
+		access := "read-only"
+		if strings.HasPrefix(cred.Metadata.Description, "read-write") {
+			access = "read-write"
+		}
+		if _, err := client.AddDeployKey(ctx, gitprovider.DeployKey{Title: title, Key: pair.PublicKey, ReadOnly: access == "read-only"}); err != nil {
+			return nil, fmt.Errorf("failed to register deploy key on %s: %w", client.Repo().FullName(), err)
+		}
+		pterm.Info.WithWriter(os.Stderr).Printf("Registered %s deploy key '%s' on %s: remove the previous key once the new secrets are applied\n", access, title, client.Repo().FullName())
+		return &auth.Rotation{
+			SSHPrivateKey: pair.PrivateKey,
+			SSHPublicKey:  pair.PublicKey,
+			Description:   fmt.Sprintf("%s deploy key %s (%s)", access, title, pair.Fingerprint),
+			ExpiresAt:     expires,
+		}, nil
+	}
+}
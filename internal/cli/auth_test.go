@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func TestRunAuthCreateDeployKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(auth.PassphraseEnvVar, "")
+
+	var posted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/org/platform/keys" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&posted)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	}))
+	defer server.Close()
+
+	authURL, authToken, authDeployKeyAPIURL, authDeployKeyFormat = "https://github.com/org/platform.git", "tok", server.URL, "none"
+	defer func() { authURL, authToken, authDeployKeyAPIURL, authDeployKeyFormat = "", "", "", "argocd" }()
+
+	if err := runAuthCreateDeployKey(authCreateDeployKeyCmd, []string{"platform-deploy"}); err != nil {
+		t.Fatalf("runAuthCreateDeployKey() error = %v", err)
+	}
+	if posted["read_only"] != true || posted["title"] != "gitopsi-platform-deploy" {
+		t.Errorf("unexpected deploy key request %v", posted)
+	}
+	if key, _ := posted["key"].(string); !strings.HasPrefix(key, "ssh-ed25519 ") {
+		t.Errorf("unexpected public key %q", key)
+	}
+
+	manager, err := getAuthManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := manager.GetCredential(context.Background(), "platform-deploy")
+	if err != nil {
+		t.Fatalf("credential not stored: %v", err)
+	}
+	if cred.Method != auth.MethodSSH || cred.Metadata.URL != "git@github.com:org/platform.git" || cred.Data.SSHPublicKey != posted["key"] {
+		t.Errorf("unexpected credential %+v", cred)
+	}
+
+	if err := runAuthCreateDeployKey(authCreateDeployKeyCmd, []string{"platform-deploy"}); err == nil {
+		t.Error("expected error for an existing credential")
+	}
+
+	authDeployKeyFormat = "k8s"
+	if err := runAuthCreateDeployKey(authCreateDeployKeyCmd, []string{"other"}); err == nil {
+		t.Error("expected error for an unsupported format")
+	}
+}
+
+func TestRunAuthRotate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(auth.PassphraseEnvVar, "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/personal_access_tokens/self/rotate" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "glpat-old" {
+			t.Errorf("PRIVATE-TOKEN = %q", got)
+		}
+		_, _ = w.Write([]byte(`{"token": "glpat-new", "expires_at": "2027-01-31"}`))
+	}))
+	defer server.Close()
+
+	manager, err := getAuthManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := manager.AddGitCredential(ctx, &auth.GitCredentialOptions{
+		Name: "gitlab-main", Provider: auth.GitProviderGitLab, Method: auth.MethodToken,
+		Token: "glpat-old", URL: "https://gitlab.com/group/platform.git",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	authDeployKeyAPIURL, authRotateNoApply = server.URL, true
+	defer func() { authDeployKeyAPIURL, authRotateNoApply = "", false }()
+
+	dryRun = true
+	if err := runAuthRotate(authRotateCmd, []string{"gitlab-main"}); err != nil {
+		t.Fatalf("runAuthRotate() dry run error = %v", err)
+	}
+	dryRun = false
+	if cred, _ := manager.GetCredential(ctx, "gitlab-main"); cred.Data.Token != "glpat-old" {
+		t.Errorf("dry run rotated the token: %q", cred.Data.Token)
+	}
+
+	if err := runAuthRotate(authRotateCmd, []string{"gitlab-main"}); err != nil {
+		t.Fatalf("runAuthRotate() error = %v", err)
+	}
+	manager, _ = getAuthManager()
+	cred, err := manager.GetCredential(ctx, "gitlab-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Data.Token != "glpat-new" || cred.Metadata.ExpiresAt == nil || cred.Metadata.ExpiresAt.Format("2006-01-02") != "2027-01-31" {
+		t.Errorf("unexpected rotated credential %+v", cred)
+	}
+
+	if _, err := manager.AddGitCredential(ctx, &auth.GitCredentialOptions{
+		Name: "github-main", Provider: auth.GitProviderGitHub, Method: auth.MethodToken, Token: "old",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAuthRotate(authRotateCmd, []string{"github-main"}); err == nil || !strings.Contains(err.Error(), "--token is required") {
+		t.Errorf("expected --token error for GitHub tokens, got %v", err)
+	}
+}
+
+func TestRunAuthRotate_SaveFailure(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(auth.PassphraseEnvVar, "")
+
+	manager, err := getAuthManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := manager.AddGitCredential(ctx, &auth.GitCredentialOptions{
+		Name: "gitlab-main", Provider: auth.GitProviderGitLab, Method: auth.MethodToken,
+		Token: "glpat-old", URL: "https://gitlab.com/group/platform.git",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The store cannot be written once GitLab has revoked the old token.
+	storePath := auth.GetDefaultStorePath()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := os.Remove(storePath); err != nil {
+			t.Error(err)
+		}
+		if err := os.Mkdir(storePath, 0700); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(`{"token": "glpat-new", "expires_at": "2027-01-31"}`))
+	}))
+	defer server.Close()
+
+	authDeployKeyAPIURL, authRotateNoApply = server.URL, true
+	var stderr strings.Builder
+	authRotateCmd.SetErr(&stderr)
+	defer func() {
+		authDeployKeyAPIURL, authRotateNoApply = "", false
+		authRotateCmd.SetErr(nil)
+	}()
+
+	err = runAuthRotate(authRotateCmd, []string{"gitlab-main"})
+	if err == nil || !strings.Contains(err.Error(), "failed to rotate credential") {
+		t.Fatalf("expected a save error, got %v", err)
+	}
+	for _, want := range []string{"could not be stored", "token: glpat-new", "expires: 2027-01-31"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr missing %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestRunAuthImportKubeconfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(auth.PassphraseEnvVar, "")
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster: {server: "https://prod.example.com:6443"}
+users:
+- name: admin
+  user: {token: prod-token}
+contexts:
+- name: prod-admin
+  context: {cluster: prod, user: admin}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	authImportKubeconfig, authImportContext, authImportName = kubeconfig, "prod-admin", "prod"
+	defer func() { authImportKubeconfig, authImportContext, authImportName = "", "", "" }()
+
+	dryRun = true
+	if err := runAuthImportKubeconfig(authImportKubeconfigCmd, nil); err != nil {
+		t.Fatalf("runAuthImportKubeconfig() dry run error = %v", err)
+	}
+	dryRun = false
+	if _, err := readCredential(context.Background(), "prod"); err == nil {
+		t.Fatal("dry run stored the credential")
+	}
+
+	if err := runAuthImportKubeconfig(authImportKubeconfigCmd, nil); err != nil {
+		t.Fatalf("runAuthImportKubeconfig() error = %v", err)
+	}
+	defer cleanupCredentials()
+	cfg := &config.Config{Cluster: config.ClusterConfig{Credential: "prod"}}
+	if err := useClusterCredential(context.Background(), cfg); err != nil {
+		t.Fatalf("useClusterCredential() error = %v", err)
+	}
+	if cfg.Cluster.URL != "https://prod.example.com:6443" || cfg.Cluster.Context != "prod-admin" {
+		t.Errorf("unexpected cluster %+v", cfg.Cluster)
+	}
+	data, err := os.ReadFile(cfg.Cluster.Kubeconfig)
+	if err != nil || !strings.Contains(string(data), "prod-token") {
+		t.Errorf("unexpected credential kubeconfig %q, %v", data, err)
+	}
+
+	cleanupCredentials()
+	if _, err := os.Stat(cfg.Cluster.Kubeconfig); !os.IsNotExist(err) {
+		t.Errorf("credential kubeconfig was not removed: %v", err)
+	}
+
+	authImportContext = ""
+	if err := runAuthImportKubeconfig(authImportKubeconfigCmd, nil); err == nil || !strings.Contains(err.Error(), "--name requires --context") {
+		t.Errorf("expected --name error, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/importer"
+	outputpkg "github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up cluster state to the repository for disaster recovery",
+	Long: `Back up cluster state that is not already in Git.
+
+Workload backups are taken by Velero: with backup.enabled, init installs the
+Velero pattern and generates a Schedule per environment namespace under
+infrastructure/backup/<env>.`,
+}
+
+var backupArgoCDCmd = &cobra.Command{
+	Use:   "argocd",
+	Short: "Export ArgoCD applications, projects and settings to the repository",
+	Long: `Export the AppProjects, Applications, ApplicationSets and settings
+ConfigMaps of an ArgoCD instance, without status and server-populated fields,
+to a repository directory (default: backup.argocd_export or backup/argocd):
+
+  projects.yaml
+  applications.yaml
+  applicationsets.yaml
+  settings.yaml
+
+Restore them with kubectl apply -f <dir>. Repository and cluster credential
+Secrets are listed but not exported: recreate them from the auth store.
+
+Examples:
+  gitopsi backup argocd
+  gitopsi backup argocd --namespace openshift-gitops --context prod
+  gitopsi backup argocd --dir dr/argocd --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runBackupArgoCD,
+}
+
+var (
+	backupArgoCDNamespace  string
+	backupArgoCDDir        string
+	backupArgoCDContext    string
+	backupArgoCDKubeconfig string
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupArgoCDCmd)
+
+	backupArgoCDCmd.Flags().StringVarP(&backupArgoCDNamespace, "namespace", "n", "", "Namespace ArgoCD is installed in (default: from the config, or argocd)")
+	backupArgoCDCmd.Flags().StringVar(&backupArgoCDDir, "dir", "", "Directory to write the export to (default: backup.argocd_export, or backup/argocd)")
+	backupArgoCDCmd.Flags().StringVar(&backupArgoCDContext, "context", "", "Kubernetes context to use")
+	backupArgoCDCmd.Flags().StringVar(&backupArgoCDKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+}
+
+type backupArgoCDResult struct {
+	Namespace       string             `json:"namespace" yaml:"namespace"`
+	Dir             string             `json:"dir" yaml:"dir"`
+	Projects        int                `json:"projects" yaml:"projects"`
+	Applications    int                `json:"applications" yaml:"applications"`
+	ApplicationSets int                `json:"application_sets" yaml:"application_sets"`
+	Settings        int                `json:"settings" yaml:"settings"`
+	Skipped         []importer.Skipped `json:"skipped" yaml:"skipped"`
+}
+
+func runBackupArgoCD(cmd *cobra.Command, args []string) error {
+	cfg, err := loadProjectConfig(".")
+	if err != nil {
+		return err
+	}
+	namespace, dir := backupArgoCDNamespace, backupArgoCDDir
+	if namespace == "" {
+		namespace = "argocd"
+		if cfg != nil {
+			namespace = promotionArgoCDNamespace(cfg)
+		}
+	}
+	if dir == "" {
+		dir = "backup/argocd"
+		if cfg != nil {
+			dir = cfg.Backup.ArgoCDExportDir()
+		}
+	}
+
+	p := newPrinter()
+	imp := importer.New(&importer.Options{Context: backupArgoCDContext, Kubeconfig: backupArgoCDKubeconfig})
+	var spinner *pterm.SpinnerPrinter
+	if !p.structured() {
+		spinner, _ = pterm.DefaultSpinner.Start("Reading ArgoCD resources...")
+	}
+	fail := func(err error) error {
+		if spinner != nil {
+			spinner.Fail("Failed to back up ArgoCD")
+		}
+		return err
+	}
+	result, err := imp.ScanArgoCD(cmd.Context(), namespace)
+	if err != nil {
+		return fail(err)
+	}
+	settings, err := imp.ScanArgoCDSettings(cmd.Context(), namespace)
+	if err != nil {
+		return fail(err)
+	}
+	if result.Count() == 0 && len(settings.ConfigMaps) == 0 {
+		return fail(fmt.Errorf("no ArgoCD resources found in namespace %s", namespace))
+	}
+
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return fail(fmt.Errorf("failed to resolve the repository path: %w", err))
+	}
+	if err := importer.WriteArgoCDBackup(outputpkg.New(root, dryRun, verbose), dir, result, settings); err != nil {
+		return fail(fmt.Errorf("failed to write the ArgoCD backup: %w", err))
+	}
+
+	summary := backupArgoCDResult{
+		Namespace:       namespace,
+		Dir:             dir,
+		Projects:        len(result.Projects),
+		Applications:    len(result.Applications),
+		ApplicationSets: len(result.ApplicationSets),
+		Settings:        len(settings.ConfigMaps),
+		Skipped:         append(result.Skipped, settings.Secrets...),
+	}
+	if p.structured() {
+		return p.print(summary)
+	}
+	spinner.Success(fmt.Sprintf("Exported %d AppProject(s), %d Application(s), %d ApplicationSet(s) and %d settings ConfigMap(s) to %s/",
+		summary.Projects, summary.Applications, summary.ApplicationSets, summary.Settings, dir))
+	if verbose {
+		printSkipped(summary.Skipped)
+	} else if len(settings.Secrets) > 0 {
+		pterm.Warning.Printf("%d credential Secret(s) were not exported (use --verbose to list them)\n", len(settings.Secrets))
+	}
+	if dryRun {
+		pterm.Info.Println("Dry run: no file was written")
+	}
+	return nil
+}
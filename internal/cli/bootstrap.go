@@ -0,0 +1,524 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+var bootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Bootstrap the GitOps tool on every environment cluster",
+	Long: `Installs ArgoCD or Flux on all clusters listed under environments in the
+config file, in parallel.
+
+Each environment (or environments[].clusters entry) is reached through its
+kubeconfig context, or a bearer token from token_env.
+
+With --offline the install manifests come from an offline bundle (see
+gitopsi bundle create) instead of the network; every mode but olm applies the
+bundled manifests.
+
+With --ha (or bootstrap.ha) ArgoCD is installed in high availability mode:
+redis-ha and two replicas of the controller, server, repo-server and
+ApplicationSet controller, from the HA chart values (which bootstrap.helm
+values override) or the HA manifests. Each cluster must have at least three
+Ready, schedulable nodes with enough allocatable CPU and memory.
+
+The repository secret (or, for Flux, the secret of the GitRepository)
+carries the credentials of a private repository: the git credential named by
+--credential or bootstrap.credential, or else a stored git credential (see
+gitopsi auth add git) covering git.url.
+
+--values adds Helm values files to helm mode installs, merged in order after
+bootstrap.helm.values_files; bootstrap.helm.values and set_values still apply
+on top of them.
+
+Strategies:
+  standalone  Install the GitOps tool on every cluster (default)
+  hub         Install ArgoCD on the hub cluster and register the other
+              clusters into it with ArgoCD cluster secrets
+
+--plan (or --dry-run) prints what would be installed on every cluster: the
+Helm chart and merged values, manifest URLs, namespaces, repository secrets,
+AppProjects and the App-of-Apps, without applying anything. --confirm shows
+the plan and asks before applying; clusters, contexts or environments named
+prod or production always ask unless --yes is set.
+
+Examples:
+  gitopsi bootstrap --config gitops.yaml
+  gitopsi bootstrap --config gitops.yaml --plan
+  gitopsi bootstrap --config gitops.yaml --plan -o yaml
+  gitopsi bootstrap --config gitops.yaml --strategy hub --hub prod
+  gitopsi bootstrap --config gitops.yaml --concurrency 2
+  gitopsi bootstrap --config gitops.yaml --ha
+  gitopsi bootstrap --config gitops.yaml --values ingress.yaml --values sso.yaml
+  gitopsi bootstrap --config gitops.yaml --credential github-org
+  gitopsi bootstrap --config gitops.yaml --offline --bundle-dir ./gitopsi-bundle
+  gitopsi bootstrap --config gitops.yaml --strategy hub --hub prod --cluster-secrets-dir ./secrets`,
+	RunE: runBootstrap,
+}
+
+var (
+	bootstrapStrategy    string
+	bootstrapHub         string
+	bootstrapConcurrency int
+	bootstrapSecretsDir  string
+	bootstrapPlan        bool
+	bootstrapConfirm     bool
+	bootstrapYes         bool
+	bootstrapHA          bool
+	helmValuesFiles      []string
+	repoCredential       string
+)
+
+func init() {
+	rootCmd.AddCommand(bootstrapCmd)
+
+	bootstrapCmd.Flags().StringVar(&bootstrapStrategy, "strategy", "", "Multi-cluster strategy: standalone, hub (overrides bootstrap.multi_cluster.strategy)")
+	bootstrapCmd.Flags().StringVar(&bootstrapHub, "hub", "", "Cluster acting as ArgoCD or Flux hub (overrides bootstrap.multi_cluster.hub)")
+	bootstrapCmd.Flags().IntVar(&bootstrapConcurrency, "concurrency", 0, "Clusters bootstrapped in parallel (default 4)")
+	bootstrapCmd.Flags().StringVar(&bootstrapSecretsDir, "cluster-secrets-dir", "", "Also write generated cluster secrets to this directory")
+	bootstrapCmd.Flags().BoolVar(&bootstrapPlan, "plan", false, "Print what would be installed without applying it (same as --dry-run)")
+	bootstrapCmd.Flags().BoolVar(&bootstrapConfirm, "confirm", false, "Show the plan and ask for confirmation before applying it")
+	bootstrapCmd.Flags().BoolVarP(&bootstrapYes, "yes", "y", false, "Skip the confirmation, also for production clusters")
+	bootstrapCmd.Flags().BoolVar(&bootstrapHA, "ha", false, "Install ArgoCD in high availability mode (overrides bootstrap.ha)")
+	addHelmValuesFlag(bootstrapCmd.Flags())
+	addRepoCredentialFlag(bootstrapCmd.Flags())
+	addOfflineFlags(bootstrapCmd.Flags())
+}
+
+// addHelmValuesFlag registers --values.
+func addHelmValuesFlag(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&helmValuesFiles, "values", nil, "Helm values file for helm mode, merged in order after bootstrap.helm.values_files (repeatable)")
+}
+
+// applyHelmValuesFlag appends the --values files to the Helm values files of
+// the bootstrap section of cfg.
+func applyHelmValuesFlag(cfg *config.Config) {
+	if len(helmValuesFiles) == 0 {
+		return
+	}
+	if cfg.Bootstrap.Helm == nil {
+		cfg.Bootstrap.Helm = &config.BootstrapHelmConfig{}
+	}
+	cfg.Bootstrap.Helm.ValuesFiles = append(cfg.Bootstrap.Helm.ValuesFiles, helmValuesFiles...)
+}
+
+// addRepoCredentialFlag registers --credential.
+func addRepoCredentialFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&repoCredential, "credential", "", "Stored git credential added to the repository secret for private repositories (overrides bootstrap.credential)")
+}
+
+func runBootstrap(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "gitops.yaml"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.GitOpsTool != "argocd" && cfg.GitOpsTool != "flux" {
+		return fmt.Errorf("multi-cluster bootstrap supports argocd or flux, got %s", cfg.GitOpsTool)
+	}
+
+	if bootstrapHA {
+		cfg.Bootstrap.HA = true
+	}
+	applyHelmValuesFlag(cfg)
+	applyOfflineFlags(cfg)
+	mcOpts := &bootstrap.MultiClusterOptions{Options: bootstrapOptions(cfg)}
+	if err := useOfflineBundle(mcOpts.Options, cfg); err != nil {
+		return err
+	}
+	if err := useRepoCredentials(cmd.Context(), mcOpts.Options, cfg); err != nil {
+		return err
+	}
+	if mc := cfg.Bootstrap.MultiCluster; mc != nil {
+		mcOpts.Strategy = bootstrap.Strategy(mc.Strategy)
+		mcOpts.Hub = mc.Hub
+		mcOpts.Concurrency = mc.Concurrency
+	}
+	if bootstrapStrategy != "" {
+		mcOpts.Strategy = bootstrap.Strategy(bootstrapStrategy)
+	}
+	if bootstrapHub != "" {
+		mcOpts.Hub = bootstrapHub
+	}
+	if bootstrapConcurrency > 0 {
+		mcOpts.Concurrency = bootstrapConcurrency
+	}
+	if mcOpts.Options.Mode == "" {
+		mcOpts.Options.Mode = bootstrap.SuggestMode(mcOpts.Options.Tool, cfg.Platform)
+	}
+
+	targets, err := multiClusterTargets(cfg)
+	if err != nil {
+		return err
+	}
+
+	mcOpts.OnProgress = func(e bootstrap.ProgressEvent) {
+		switch {
+		case !e.Done:
+			pterm.Info.Printf("%s (%s): bootstrapping...\n", e.Cluster, e.Role)
+		case e.Err != nil:
+			pterm.Error.Printf("%s (%s): %v\n", e.Cluster, e.Role, e.Err)
+		default:
+			pterm.Success.Printf("%s (%s): done\n", e.Cluster, e.Role)
+		}
+	}
+
+	mcb, err := bootstrap.NewMultiCluster(targets, mcOpts)
+	if err != nil {
+		return err
+	}
+
+	p := newPrinter()
+	if bootstrapPlan || dryRun {
+		plans, err := mcb.Plan()
+		if err != nil {
+			return err
+		}
+		if p.structured() {
+			return p.print(bootstrapPlanDocument{Strategy: mcOpts.Strategy, Clusters: plans})
+		}
+		printBootstrapPlans(plans)
+		fmt.Println("\n🔍 DRY RUN complete - nothing was applied")
+		return nil
+	}
+	if err := confirmBootstrap(p, targetNames(targets), func() error {
+		plans, err := mcb.Plan()
+		if err != nil {
+			return err
+		}
+		printBootstrapPlans(plans)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if !p.structured() {
+		pterm.DefaultHeader.WithFullWidth().Printf("🚀 Bootstrapping %d clusters (%s)", len(targets), mcOpts.Strategy)
+		fmt.Println()
+	}
+
+	result := mcb.Bootstrap(context.Background())
+
+	if bootstrapSecretsDir != "" {
+		if err := writeClusterSecrets(bootstrapSecretsDir, result); err != nil {
+			return err
+		}
+	}
+
+	if p.structured() {
+		if err := p.print(newBootstrapDocument(result)); err != nil {
+			return err
+		}
+	} else {
+		printMultiClusterResult(result)
+	}
+
+	if result.HasFailures() {
+		return fmt.Errorf("bootstrap failed on %d of %d clusters", result.Failed, len(result.Clusters))
+	}
+	return nil
+}
+
+// multiClusterTargets builds an authenticated cluster per environment, or per
+// entry of environments[].clusters when present.
+func multiClusterTargets(cfg *config.Config) ([]bootstrap.ClusterTarget, error) {
+	var targets []bootstrap.ClusterTarget
+
+	add := func(name, env, url, kubeContext, tokenEnv, kubeconfig, credential string, labels map[string]string) error {
+		var remote *bootstrap.RemoteCredentials
+		if credential != "" {
+			cred, path, err := credentialKubeconfig(context.Background(), credential)
+			if err != nil {
+				return fmt.Errorf("failed to use credential %s for cluster %s: %w", credential, name, err)
+			}
+			if remote, err = staticRemoteCredentials(cred); err != nil {
+				return err
+			}
+			kubeconfig, kubeContext, tokenEnv = path, cred.KubeContext(), ""
+			if url == "" {
+				url = cred.Metadata.URL
+			}
+		}
+		c := cluster.New(url, name, cluster.Platform(cfg.Platform))
+		authOpts := &cluster.AuthOptions{
+			Method:     cluster.AuthKubeconfig,
+			Kubeconfig: kubeconfig,
+			Context:    kubeContext,
+			CACert:     cfg.Cluster.Auth.CACert,
+			SkipTLS:    cfg.Cluster.Auth.SkipTLS,
+		}
+		if tokenEnv != "" {
+			authOpts.Method = cluster.AuthToken
+			authOpts.TokenEnv = tokenEnv
+		}
+		if err := c.Authenticate(authOpts); err != nil {
+			return fmt.Errorf("failed to authenticate to cluster %s: %w", name, err)
+		}
+		targets = append(targets, bootstrap.ClusterTarget{Name: name, Environment: env, Cluster: c, Labels: labels, Remote: remote})
+		return nil
+	}
+
+	for _, env := range cfg.Environments {
+		if len(env.Clusters) > 0 {
+			for _, ec := range env.Clusters {
+				var labels map[string]string
+				if ec.Region != "" {
+					labels = map[string]string{"region": ec.Region}
+				}
+				if err := add(ec.Name, env.Name, ec.URL, ec.Context, ec.TokenEnv, cfg.Cluster.Kubeconfig, ec.Credential, labels); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		kubeconfig := environmentKubeconfig(cfg, env)
+		if env.Cluster == "" && env.Context == "" && env.Credential == "" && kubeconfig == cfg.Cluster.Kubeconfig {
+			continue
+		}
+		if err := add(env.Name, env.Name, env.Cluster, env.Context, env.TokenEnv, kubeconfig, env.Credential, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no environment clusters configured: set environments[].cluster and context, or environments[].clusters")
+	}
+	return targets, nil
+}
+
+// productionName matches the names of production clusters, contexts and
+// environments, such as prod, prod-eu or production.
+var productionName = regexp.MustCompile(`(?i)(^|[^a-z])prod(uction)?([^a-z]|$)`)
+
+// confirmBootstrap asks before bootstrapping when --confirm is set or one of
+// names looks like production, unless --yes is set. show prints the plan
+// first.
+func confirmBootstrap(p *printer, names []string, show func() error) error {
+	var production []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name != "" && !seen[name] && productionName.MatchString(name) {
+			seen[name] = true
+			production = append(production, name)
+		}
+	}
+	if bootstrapYes || (!bootstrapConfirm && len(production) == 0) {
+		return nil
+	}
+
+	question, target := "Apply this bootstrap plan?", ""
+	if len(production) > 0 {
+		target = fmt.Sprintf(" production (%s)", strings.Join(production, ", "))
+		question = fmt.Sprintf("Apply this bootstrap plan to%s?", target)
+	}
+	if p.structured() {
+		return fmt.Errorf("bootstrapping%s needs confirmation: pass --yes", target)
+	}
+	if err := show(); err != nil {
+		return err
+	}
+	confirmed, _ := pterm.DefaultInteractiveConfirm.Show(question)
+	if !confirmed {
+		return fmt.Errorf("bootstrap cancelled: pass --yes to skip the confirmation")
+	}
+	return nil
+}
+
+// targetNames returns the names, environments and kubeconfig contexts of
+// the targets.
+func targetNames(targets []bootstrap.ClusterTarget) []string {
+	var names []string
+	for _, t := range targets {
+		names = append(names, t.Name, t.Environment)
+		if auth := t.Cluster.GetAuthOptions(); auth != nil {
+			names = append(names, auth.Context)
+		}
+	}
+	return names
+}
+
+// bootstrapPlanDocument is the structured output of bootstrap --plan.
+type bootstrapPlanDocument struct {
+	Strategy bootstrap.Strategy      `json:"strategy" yaml:"strategy"`
+	Clusters []bootstrap.ClusterPlan `json:"clusters" yaml:"clusters"`
+}
+
+func printBootstrapPlans(plans []bootstrap.ClusterPlan) {
+	for _, cp := range plans {
+		title := fmt.Sprintf("%s (%s)", cp.Name, cp.Role)
+		if cp.Server != "" {
+			title += " → " + cp.Server
+		}
+		if cp.Plan == nil {
+			pterm.DefaultSection.Println(title)
+			pterm.Info.Println(cp.Registration)
+			continue
+		}
+		printBootstrapPlan(title, cp.Plan)
+	}
+}
+
+// printBootstrapPlan prints a plan with every manifest it applies.
+func printBootstrapPlan(title string, plan *bootstrap.Plan) {
+	pterm.DefaultSection.Println(title)
+	version := plan.Version
+	if version == "" {
+		version = "default"
+	}
+	profile := ""
+	if plan.HA {
+		profile = ", high availability"
+	}
+	fmt.Printf("  Install:   %s via %s (version %s%s)\n", plan.Tool, plan.Mode, version, profile)
+	fmt.Printf("  Namespace: %s\n", plan.Namespace)
+	if h := plan.Helm; h != nil {
+		fmt.Printf("  Helm:      release %s, chart %s from %s", h.Release, h.Chart, h.Repo)
+		if h.Version != "" {
+			fmt.Printf(" at %s", h.Version)
+		}
+		fmt.Println()
+		if len(h.Values) > 0 {
+			var values strings.Builder
+			enc := yaml.NewEncoder(&values)
+			enc.SetIndent(2)
+			_ = enc.Encode(h.Values)
+			fmt.Println("  Values:")
+			fmt.Println(indent(strings.TrimSpace(values.String()), "    "))
+		}
+	}
+	for _, m := range plan.Manifests {
+		fmt.Printf("  Apply:     %s\n", m)
+	}
+	for _, k := range plan.Kustomizations {
+		fmt.Printf("  Apply -k:  %s\n", k)
+	}
+	if plan.Command != "" {
+		fmt.Printf("  Run:       %s\n", plan.Command)
+	}
+	for _, r := range plan.Resources {
+		name := r.Name
+		if r.Namespace != "" {
+			name = r.Namespace + "/" + name
+		}
+		fmt.Printf("\n  %s %s:\n", r.Kind, name)
+		fmt.Println(indent(strings.TrimSpace(r.Manifest), "    "))
+	}
+	fmt.Println()
+}
+
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func writeClusterSecrets(dir string, result *bootstrap.MultiClusterResult) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cluster secrets directory: %w", err)
+	}
+	for _, c := range result.Clusters {
+		if c.ClusterSecret == "" {
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("cluster-%s.yaml", c.Name))
+		// Cluster secrets carry bearer tokens.
+		if err := os.WriteFile(path, []byte(c.ClusterSecret), 0600); err != nil {
+			return fmt.Errorf("failed to write cluster secret: %w", err)
+		}
+	}
+	return nil
+}
+
+// bootstrapDocument is the structured output of bootstrap. Passwords and
+// cluster secrets are left out.
+type bootstrapDocument struct {
+	Strategy  bootstrap.Strategy      `json:"strategy" yaml:"strategy"`
+	Succeeded int                     `json:"succeeded" yaml:"succeeded"`
+	Failed    int                     `json:"failed" yaml:"failed"`
+	Clusters  []bootstrapClusterEntry `json:"clusters" yaml:"clusters"`
+}
+
+type bootstrapClusterEntry struct {
+	Name        string `json:"name" yaml:"name"`
+	Environment string `json:"environment" yaml:"environment"`
+	Role        string `json:"role" yaml:"role"`
+	Status      string `json:"status" yaml:"status"`
+	Duration    string `json:"duration" yaml:"duration"`
+	Namespace   string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	URL         string `json:"url,omitempty" yaml:"url,omitempty"`
+	Message     string `json:"message,omitempty" yaml:"message,omitempty"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func newBootstrapDocument(result *bootstrap.MultiClusterResult) bootstrapDocument {
+	doc := bootstrapDocument{
+		Strategy:  result.Strategy,
+		Succeeded: result.Succeeded,
+		Failed:    result.Failed,
+		Clusters:  make([]bootstrapClusterEntry, 0, len(result.Clusters)),
+	}
+	for _, c := range result.Clusters {
+		entry := bootstrapClusterEntry{
+			Name:        c.Name,
+			Environment: c.Environment,
+			Role:        c.Role,
+			Status:      "ready",
+			Duration:    c.Duration.Round(time.Second).String(),
+		}
+		if c.Err != nil {
+			entry.Status = "failed"
+			entry.Error = c.Err.Error()
+		} else if c.Result != nil {
+			entry.Namespace = c.Result.Namespace
+			entry.URL = c.Result.URL
+			entry.Message = c.Result.Message
+		}
+		doc.Clusters = append(doc.Clusters, entry)
+	}
+	return doc
+}
+
+func printMultiClusterResult(result *bootstrap.MultiClusterResult) {
+	fmt.Println()
+	tableData := [][]string{{"CLUSTER", "ENVIRONMENT", "ROLE", "STATUS", "DURATION", "DETAILS"}}
+	for _, c := range result.Clusters {
+		status := "✅ ready"
+		details := ""
+		if c.Err != nil {
+			status = "❌ failed"
+			details = c.Err.Error()
+		} else if c.Result != nil {
+			details = c.Result.URL
+			if details == "" {
+				details = c.Result.Message
+			}
+		}
+		tableData = append(tableData, []string{c.Name, c.Environment, c.Role, status, c.Duration.Round(time.Second).String(), details})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	fmt.Println()
+	pterm.DefaultBox.WithTitle("Summary").Println(
+		fmt.Sprintf("✅ Succeeded: %d  ❌ Failed: %d", result.Succeeded, result.Failed),
+	)
+}
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+var bootstrapFluxCmd = &cobra.Command{
+	Use:   "flux",
+	Short: "Bootstrap Flux from the repository, like flux bootstrap",
+	Long: `Bootstrap Flux the way flux bootstrap github/gitlab does, without the flux CLI:
+
+  1. Check the repository exists, or create it with --create-repo
+  2. Register a deploy key and store it as an SSH git credential
+  3. Commit the Flux components and the flux-system sync manifests to
+     flux/flux-system of the generated project and push them
+  4. Install the committed components, the deploy key secret and the sync
+     GitRepository and Kustomization on the cluster
+
+Flux then reconciles itself and the project from the repository over SSH: the
+project GitRepository is switched to the SSH URL and the deploy key secret.
+The API token is taken from --git-token, GITOPSI_GIT_TOKEN or a stored
+git credential.
+
+Examples:
+  gitopsi bootstrap flux --config gitops.yaml
+  gitopsi bootstrap flux --config gitops.yaml --create-repo --git-token $GITHUB_TOKEN
+  gitopsi bootstrap flux --config gitops.yaml --read-write-key --flux-version v2.3.0`,
+	RunE: runBootstrapFlux,
+}
+
+var (
+	fluxProjectDir   string
+	fluxKeyName      string
+	fluxReadWriteKey bool
+	fluxVersion      string
+)
+
+func init() {
+	bootstrapCmd.AddCommand(bootstrapFluxCmd)
+
+	bootstrapFluxCmd.Flags().StringVar(&fluxProjectDir, "path", "", "Generated project to commit to (default: <output-dir>/<project.name>)")
+	bootstrapFluxCmd.Flags().StringVar(&gitToken, "git-token", "", "Git provider API token (or use GITOPSI_GIT_TOKEN env)")
+	bootstrapFluxCmd.Flags().BoolVar(&createRepo, "create-repo", false, "Create the Git repository through the provider API if it does not exist")
+	bootstrapFluxCmd.Flags().StringVar(&fluxKeyName, "key-name", "", "Name of the deploy key credential (default: flux-<project>)")
+	bootstrapFluxCmd.Flags().BoolVar(&fluxReadWriteKey, "read-write-key", false, "Grant the deploy key push access (default with flux.image_automation)")
+	bootstrapFluxCmd.Flags().StringVar(&fluxVersion, "flux-version", "", "Flux version (default: bootstrap.version or the latest release)")
+	addOfflineFlags(bootstrapFluxCmd.Flags())
+}
+
+func runBootstrapFlux(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "gitops.yaml"
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.GitOpsTool != "flux" && cfg.GitOpsTool != "both" {
+		return fmt.Errorf("bootstrap flux requires gitops_tool flux, got %s", cfg.GitOpsTool)
+	}
+	if cfg.Git.URL == "" {
+		return fmt.Errorf("git.url is required to bootstrap Flux from the repository")
+	}
+	token := gitToken
+	if token == "" {
+		token = os.Getenv("GITOPSI_GIT_TOKEN")
+	}
+	if token != "" {
+		cfg.Git.Auth.Token = token
+		cfg.Git.Auth.Method = "token"
+	}
+	if createRepo {
+		cfg.Git.CreateIfMissing = true
+	}
+	applyOfflineFlags(cfg)
+
+	projectDir := fluxProjectDir
+	if projectDir == "" {
+		projectDir = localProjectDir(cfg)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "flux")); err != nil {
+		return fmt.Errorf("%s has no flux directory: run gitopsi init first", projectDir)
+	}
+	branch := cfg.Git.Branch
+	if branch == "" {
+		branch = "main"
+	}
+
+	creds, err := gitPushCredentials(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if providerToken(creds) == "" {
+		return fmt.Errorf("a provider API token is required to register the deploy key (use --git-token or GITOPSI_GIT_TOKEN)")
+	}
+
+	// 1. Repository
+	spinner, _ := pterm.DefaultSpinner.Start("Checking repository...")
+	provider, err := newGitProvider(ctx, cfg, creds)
+	if err != nil {
+		spinner.Fail(err.Error())
+		return err
+	}
+	created, err := ensureRepository(ctx, cfg, creds)
+	if err == nil && created == nil {
+		_, err = provider.GetRepo(ctx)
+		if errors.Is(err, gitprovider.ErrNotFound) {
+			err = fmt.Errorf("repository %s not found: create it or use --create-repo", provider.Repo().FullName())
+		}
+	}
+	if err != nil {
+		spinner.Fail(err.Error())
+		return err
+	}
+	if created != nil {
+		spinner.Success(fmt.Sprintf("Created repository %s", provider.Repo().FullName()))
+	} else {
+		spinner.Success(fmt.Sprintf("Repository %s exists", provider.Repo().FullName()))
+	}
+
+	// 2. Deploy key
+	manager, err := getAuthManager()
+	if err != nil {
+		return err
+	}
+	keyName := fluxKeyName
+	if keyName == "" {
+		keyName = "flux-" + cfg.Project.Name
+	}
+	readWrite := fluxReadWriteKey || cfg.Flux.ImageAutomation.Enabled
+	if err := ensureFluxDeployKey(ctx, manager, provider, keyName, readWrite); err != nil {
+		return err
+	}
+
+	// 3. Commit the Flux components and sync manifests
+	opts := bootstrapOptions(cfg)
+	opts.Tool = bootstrap.ToolFlux
+	if err := useOfflineBundle(opts, cfg); err != nil {
+		return err
+	}
+	version := fluxVersion
+	if version == "" {
+		version = cfg.Bootstrap.Version
+	}
+	components, err := bootstrap.FluxComponents(ctx, version, opts.Manifests)
+	if err != nil {
+		return err
+	}
+	sync := &bootstrap.FluxSync{
+		Namespace: opts.Namespace,
+		URL:       bootstrap.FluxSSHURL(provider.Repo().SSHURL()),
+		Branch:    branch,
+		Path:      bootstrapRepoPath("flux"),
+		Lockdown:  cfg.FluxLockdown(),
+	}
+	if _, err := bootstrap.WriteFluxSystem(filepath.Join(projectDir, "flux", bootstrap.FluxSystemDir), components, sync); err != nil {
+		return err
+	}
+	if err := writeFluxSource(projectDir, cfg, sync); err != nil {
+		return err
+	}
+
+	spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Committing and pushing to origin/%s...", branch))
+	pushResult, err := gitops.NewPusher(&gitops.PushOptions{
+		Dir:           projectDir,
+		RemoteURL:     cfg.Git.URL,
+		Branch:        branch,
+		CommitMessage: "chore: Bootstrap Flux for {{.Project}}",
+		Project:       cfg.Project.Name,
+		Credentials:   creds,
+	}).Push(ctx)
+	switch {
+	case errors.Is(err, gitops.ErrNoChanges):
+		spinner.Success(fmt.Sprintf("origin/%s already has the Flux manifests", branch))
+	case err != nil:
+		spinner.Fail(err.Error())
+		return fmt.Errorf("failed to push the Flux manifests: %w", err)
+	default:
+		spinner.Success(fmt.Sprintf("Pushed %s to origin/%s", pushResult.Commit[:7], branch))
+	}
+
+	// 4. Install from the committed manifests
+	if cfg.Cluster.URL == "" {
+		if err := autoDetectCluster(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	c, err := authenticateCluster(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("cluster authentication failed: %w", err)
+	}
+	secret, err := manager.GenerateFluxGitRepositorySecret(ctx, keyName, opts.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to generate the deploy key secret: %w", err)
+	}
+	opts.Wait = true
+	spinner, _ = pterm.DefaultSpinner.Start("Installing Flux...")
+	result, err := bootstrap.New(c, opts).BootstrapFluxRepo(ctx, components, secret, sync)
+	if err != nil {
+		spinner.Fail(err.Error())
+		return err
+	}
+	spinner.Success(result.Message)
+	return nil
+}
+
+// ensureFluxDeployKey registers a deploy key for Flux and stores it as the
+// SSH git credential name, unless the credential already exists.
+func ensureFluxDeployKey(ctx context.Context, manager *auth.Manager, provider gitprovider.Provider, name string, readWrite bool) error {
+	if _, err := manager.GetCredential(ctx, name); err == nil {
+		pterm.Info.Printfln("Using the deploy key of git credential %s", name)
+		return nil
+	}
+
+	title := "gitopsi-" + name
+	pair, err := auth.GenerateDeployKey(title)
+	if err != nil {
+		return err
+	}
+	host := provider.Repo().Host
+	knownHosts, err := auth.ScanKnownHosts(ctx, sshHost(provider.Repo().SSHURL(), host))
+	if err != nil {
+		return fmt.Errorf("failed to scan the SSH host keys of %s: %w", host, err)
+	}
+	if _, err := provider.AddDeployKey(ctx, gitprovider.DeployKey{Title: title, Key: pair.PublicKey, ReadOnly: !readWrite}); err != nil {
+		return fmt.Errorf("failed to register deploy key on %s: %w", provider.Repo().FullName(), err)
+	}
+
+	access := "read-only"
+	if readWrite {
+		access = "read-write"
+	}
+	if _, err := manager.AddGitCredential(ctx, &auth.GitCredentialOptions{
+		Name:          name,
+		Provider:      auth.GitProvider(provider.Name()),
+		Method:        auth.MethodSSH,
+		URL:           provider.Repo().SSHURL(),
+		Description:   fmt.Sprintf("%s Flux deploy key %s (%s)", access, title, pair.Fingerprint),
+		SecretName:    bootstrap.FluxSecretName,
+		SSHPrivateKey: pair.PrivateKey,
+		SSHPublicKey:  pair.PublicKey,
+		SSHKnownHosts: knownHosts,
+	}); err != nil {
+		return fmt.Errorf("deploy key was registered but the credential could not be saved: %w", err)
+	}
+	pterm.Success.Printfln("Registered %s deploy key '%s' on %s, stored as git credential %s", access, title, provider.Repo().FullName(), name)
+	return nil
+}
+
+// sshHost returns the host of an SSH repository URL, falling back to the
+// repository host.
+func sshHost(sshURL, fallback string) string {
+	if _, rest, ok := strings.Cut(sshURL, "@"); ok {
+		if host, _, ok := strings.Cut(rest, ":"); ok {
+			return host
+		}
+	}
+	return fallback
+}
+
+// writeFluxSource points the generated project GitRepository at the SSH URL
+// and the deploy key secret Flux was bootstrapped with.
+func writeFluxSource(projectDir string, cfg *config.Config, sync *bootstrap.FluxSync) error {
+	namespace := sync.Namespace
+	if namespace == "" {
+		namespace = "flux-system"
+	}
+	content, err := templates.Render("flux/gitrepository.yaml.tmpl", map[string]any{
+		"Name":      cfg.Project.Name,
+		"Namespace": namespace,
+		"Interval":  "1m",
+		"URL":       sync.URL,
+		"Branch":    sync.Branch,
+		"SecretRef": bootstrap.FluxSecretName,
+	})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(projectDir, "flux", "sources", "gitrepository.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
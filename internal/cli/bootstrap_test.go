@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func TestProductionName(t *testing.T) {
+	tests := map[string]bool{
+		"prod":                       true,
+		"prod-eu":                    true,
+		"PRODUCTION":                 true,
+		"eks-prod-1":                 true,
+		"https://api.prod.acme.com":  true,
+		"dev":                        false,
+		"product-api":                false,
+		"staging":                    false,
+		"https://api.products.acme.": false,
+	}
+	for name, want := range tests {
+		if got := productionName.MatchString(name); got != want {
+			t.Errorf("productionName.MatchString(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestConfirmBootstrap(t *testing.T) {
+	defer func() { bootstrapConfirm, bootstrapYes = false, false }()
+	p := &printer{format: outputJSON}
+	noPlan := func() error { return nil }
+
+	if err := confirmBootstrap(p, []string{"dev", "staging"}, noPlan); err != nil {
+		t.Errorf("confirmBootstrap(non-production) error = %v", err)
+	}
+
+	err := confirmBootstrap(p, []string{"dev", "prod", "prod"}, noPlan)
+	if err == nil || !strings.Contains(err.Error(), "production (prod)") {
+		t.Errorf("confirmBootstrap(production) error = %v, want a confirmation error", err)
+	}
+
+	bootstrapConfirm = true
+	if err := confirmBootstrap(p, []string{"dev"}, noPlan); err == nil {
+		t.Error("confirmBootstrap() with --confirm should ask for confirmation")
+	}
+
+	bootstrapYes = true
+	if err := confirmBootstrap(p, []string{"prod"}, noPlan); err != nil {
+		t.Errorf("confirmBootstrap() with --yes error = %v", err)
+	}
+}
+
+func TestApplyHelmValuesFlag(t *testing.T) {
+	defer func() { helmValuesFiles = nil }()
+
+	cfg := &config.Config{}
+	applyHelmValuesFlag(cfg)
+	if cfg.Bootstrap.Helm != nil {
+		t.Errorf("applyHelmValuesFlag() without --values set bootstrap.helm = %+v", cfg.Bootstrap.Helm)
+	}
+
+	helmValuesFiles = []string{"ingress.yaml", "sso.yaml"}
+	applyHelmValuesFlag(cfg)
+	if want := []string{"ingress.yaml", "sso.yaml"}; !reflect.DeepEqual(cfg.Bootstrap.Helm.ValuesFiles, want) {
+		t.Errorf("ValuesFiles = %v, want %v", cfg.Bootstrap.Helm.ValuesFiles, want)
+	}
+
+	cfg = &config.Config{Bootstrap: config.BootstrapConfig{Helm: &config.BootstrapHelmConfig{ValuesFiles: []string{"base.yaml"}}}}
+	applyHelmValuesFlag(cfg)
+	if want := []string{"base.yaml", "ingress.yaml", "sso.yaml"}; !reflect.DeepEqual(cfg.Bootstrap.Helm.ValuesFiles, want) {
+		t.Errorf("ValuesFiles = %v, want %v", cfg.Bootstrap.Helm.ValuesFiles, want)
+	}
+}
+
+func TestRemoteCredentials(t *testing.T) {
+	kubelogin := &auth.ExecConfig{Command: "kubelogin", Args: []string{"get-token"}, APIVersion: "client.authentication.k8s.io/v1beta1"}
+	tests := []struct {
+		name string
+		cred *auth.Credential
+		want *bootstrap.RemoteCredentials
+	}{
+		{
+			"token",
+			&auth.Credential{Method: auth.MethodToken, Data: auth.CredentialData{Token: "abc", CACert: "ca"}},
+			&bootstrap.RemoteCredentials{BearerToken: "abc", CAData: "Y2E="},
+		},
+		{
+			"client cert",
+			&auth.Credential{Method: auth.MethodClientCert, Data: auth.CredentialData{TLSCert: "cert", TLSKey: "key"}},
+			&bootstrap.RemoteCredentials{CertData: "Y2VydA==", KeyData: "a2V5"},
+		},
+		{
+			"eks",
+			&auth.Credential{Method: auth.MethodExec, Data: auth.CredentialData{Exec: &auth.ExecConfig{
+				Command: "aws", Args: []string{"eks", "get-token", "--cluster-name", "prod", "--role-arn=arn:aws:iam::1:role/r"},
+			}}},
+			&bootstrap.RemoteCredentials{AWSClusterName: "prod", AWSRoleARN: "arn:aws:iam::1:role/r"},
+		},
+		{
+			"exec",
+			&auth.Credential{Method: auth.MethodExec, Data: auth.CredentialData{Exec: kubelogin}},
+			&bootstrap.RemoteCredentials{Exec: &bootstrap.ExecProvider{
+				Command:     "kubelogin",
+				Args:        []string{"get-token"},
+				APIVersion:  "client.authentication.k8s.io/v1beta1",
+				InstallHint: "install kubelogin in the ArgoCD server and application controller images",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cred.Type = auth.CredentialTypePlatform
+			got, err := remoteCredentials(tt.cred)
+			if err != nil {
+				t.Fatalf("remoteCredentials() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remoteCredentials() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := remoteCredentials(&auth.Credential{Type: auth.CredentialTypeGit, Method: auth.MethodToken}); err == nil {
+		t.Error("expected an error for git credentials")
+	}
+	if _, err := remoteCredentials(&auth.Credential{Type: auth.CredentialTypePlatform, Method: auth.MethodAWSIRSA}); err == nil {
+		t.Error("expected an error for IRSA credentials")
+	}
+	if remote, err := staticRemoteCredentials(tests[3].cred); remote != nil || err != nil {
+		t.Errorf("staticRemoteCredentials(exec) = %+v, %v", remote, err)
+	}
+}
+
+func TestEnvironmentClusterSecrets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(auth.PassphraseEnvVar, "")
+
+	manager, err := getAuthManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := manager.AddPlatformCredential(ctx, &auth.PlatformCredentialOptions{
+		Name: "prod-admin", Platform: auth.PlatformKubernetes, Method: auth.MethodToken,
+		Token: "prod-token", URL: "https://prod.example.com:6443",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	env := &config.Environment{Name: "prod", Credential: "prod-admin"}
+	secrets, err := environmentClusterSecrets(ctx, bootstrap.ToolArgoCD, env, "argocd", "")
+	if err != nil {
+		t.Fatalf("environmentClusterSecrets() error = %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Server != "https://prod.example.com:6443" {
+		t.Fatalf("unexpected secrets %+v", secrets)
+	}
+	for _, want := range []string{"name: cluster-prod", "argocd.argoproj.io/secret-type: cluster", "env: prod", `"bearerToken":"prod-token"`, `"insecure":false`} {
+		if !strings.Contains(secrets[0].Secret, want) {
+			t.Errorf("cluster secret missing %q:\n%s", want, secrets[0].Secret)
+		}
+	}
+
+	clusterAddInsecure = true
+	t.Cleanup(func() { clusterAddInsecure = false })
+	secrets, err = environmentClusterSecrets(ctx, bootstrap.ToolArgoCD, env, "argocd", "")
+	if err != nil {
+		t.Fatalf("environmentClusterSecrets(insecure) error = %v", err)
+	}
+	if !strings.Contains(secrets[0].Secret, `"insecure":true`) {
+		t.Errorf("--insecure-skip-tls-verify should disable TLS verification:\n%s", secrets[0].Secret)
+	}
+	clusterAddInsecure = false
+
+	secrets, err = environmentClusterSecrets(ctx, bootstrap.ToolFlux, env, "flux-system", "")
+	if err != nil {
+		t.Fatalf("environmentClusterSecrets(flux) error = %v", err)
+	}
+	for _, want := range []string{"name: prod-kubeconfig", "namespace: flux-system", "token: prod-token"} {
+		if !strings.Contains(secrets[0].Secret, want) {
+			t.Errorf("kubeconfig secret missing %q:\n%s", want, secrets[0].Secret)
+		}
+	}
+
+	multi := &config.Environment{Name: "prod", Clusters: []config.EnvironmentCluster{
+		{Name: "prod-eu", Region: "eu-west-1", Credential: "prod-admin"},
+		{Name: "prod-us", URL: "https://prod-us.example.com"},
+	}}
+	if _, err := environmentClusterSecrets(ctx, bootstrap.ToolArgoCD, multi, "argocd", ""); err == nil || !strings.Contains(err.Error(), "prod-us has no credential") {
+		t.Errorf("expected a missing credential error, got %v", err)
+	}
+	if _, err := environmentClusterSecrets(ctx, bootstrap.ToolArgoCD, multi, "argocd", "prod-admin"); err == nil {
+		t.Error("--credential should be refused for several clusters")
+	}
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+var bootstrapUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the GitOps tool installed by gitopsi",
+	Long: `Upgrade the ArgoCD or Flux installation of the bootstrap cluster to the
+version in gitops.yaml (bootstrap.version, or bootstrap.helm.version for Helm
+installs), or to --version.
+
+The installed version and install method are detected first, then the plan is
+shown: the minor releases crossed, the CRDs added, changed or removed and, for
+Helm installs, the values that change. After confirmation the upgrade is
+applied and every controller must become available again; otherwise the
+installation is rolled back, to the previous Helm revision or by re-applying
+the manifests of the previous version.
+
+Installations managed by OLM are upgraded through their subscription instead.
+
+Examples:
+  gitopsi bootstrap upgrade --config gitops.yaml --dry-run
+  gitopsi bootstrap upgrade --config gitops.yaml --version v2.12.0
+  gitopsi bootstrap upgrade --config gitops.yaml --yes -o json`,
+	Args: cobra.NoArgs,
+	RunE: runBootstrapUpgrade,
+}
+
+var (
+	upgradeVersion string
+	upgradeTool    string
+	upgradeYes     bool
+)
+
+func init() {
+	bootstrapCmd.AddCommand(bootstrapUpgradeCmd)
+
+	bootstrapUpgradeCmd.Flags().StringVar(&upgradeVersion, "version", "", "Target version (default: bootstrap.version, or bootstrap.helm.version for Helm installs)")
+	bootstrapUpgradeCmd.Flags().StringVar(&upgradeTool, "tool", "", "Tool to upgrade when gitops_tool is both: argocd, flux")
+	bootstrapUpgradeCmd.Flags().BoolVarP(&upgradeYes, "yes", "y", false, "Apply the upgrade without asking for confirmation")
+	addHelmValuesFlag(bootstrapUpgradeCmd.Flags())
+	addOfflineFlags(bootstrapUpgradeCmd.Flags())
+}
+
+// upgradeDocument is the structured output of bootstrap upgrade.
+type upgradeDocument struct {
+	Plan    *bootstrap.UpgradePlan `json:"plan" yaml:"plan"`
+	Applied bool                   `json:"applied" yaml:"applied"`
+	Message string                 `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+func runBootstrapUpgrade(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "gitops.yaml"
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	tool := cfg.GitOpsTool
+	if upgradeTool != "" {
+		tool = upgradeTool
+	}
+	if tool != "argocd" && tool != "flux" {
+		return fmt.Errorf("bootstrap upgrade supports argocd or flux, got %s: pass --tool", tool)
+	}
+
+	applyHelmValuesFlag(cfg)
+	applyOfflineFlags(cfg)
+	opts := bootstrapOptions(cfg)
+	opts.Tool = bootstrap.Tool(tool)
+	if err := useOfflineBundle(opts, cfg); err != nil {
+		return err
+	}
+	if err := autoDetectCluster(ctx, cfg); err != nil {
+		return err
+	}
+	c, err := authenticateCluster(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("cluster authentication failed: %w", err)
+	}
+	b := bootstrap.New(c, opts)
+
+	p := newPrinter()
+	spinner := startSpinner(p, fmt.Sprintf("Detecting %s in namespace %s...", tool, opts.Namespace))
+	inst, err := b.DetectInstallation(ctx)
+	if err != nil {
+		stopSpinner(spinner, err)
+		return err
+	}
+	stopSpinner(spinner, nil)
+
+	target := upgradeVersion
+	if target == "" && inst.Method == bootstrap.InstallMethodHelm && cfg.Bootstrap.Helm != nil {
+		target = cfg.Bootstrap.Helm.Version
+	}
+	if target == "" {
+		target = cfg.Bootstrap.Version
+	}
+	if target == "" {
+		return fmt.Errorf("no target version: set bootstrap.version or pass --version")
+	}
+
+	plan, err := b.PlanUpgrade(ctx, inst, target)
+	if err != nil {
+		return err
+	}
+	doc := &upgradeDocument{Plan: plan}
+	if !p.structured() {
+		printUpgradePlan(plan)
+	}
+
+	if plan.UpToDate() || dryRun {
+		if p.structured() {
+			return p.print(doc)
+		}
+		if dryRun && !plan.UpToDate() {
+			fmt.Println("\n🔍 DRY RUN complete - nothing was upgraded")
+		}
+		return nil
+	}
+
+	if !upgradeYes {
+		if p.structured() {
+			return fmt.Errorf("upgrade not confirmed: pass --yes to apply it")
+		}
+		confirmed, _ := pterm.DefaultInteractiveConfirm.Show(fmt.Sprintf("Upgrade %s from %s to %s?", tool, plan.From, plan.To))
+		if !confirmed {
+			return fmt.Errorf("upgrade cancelled")
+		}
+	}
+
+	spinner = startSpinner(p, fmt.Sprintf("Upgrading %s to %s...", tool, plan.To))
+	result, err := b.Upgrade(ctx, plan)
+	if err != nil {
+		stopSpinner(spinner, err)
+		return err
+	}
+	stopSpinner(spinner, nil)
+
+	doc.Applied = true
+	doc.Message = result.Message
+	if p.structured() {
+		return p.print(doc)
+	}
+	pterm.Success.Println(result.Message)
+	return nil
+}
+
+// startSpinner starts a spinner unless the output is structured.
+func startSpinner(p *printer, text string) *pterm.SpinnerPrinter {
+	if p.structured() {
+		return nil
+	}
+	spinner, _ := pterm.DefaultSpinner.Start(text)
+	return spinner
+}
+
+func stopSpinner(spinner *pterm.SpinnerPrinter, err error) {
+	if spinner == nil {
+		return
+	}
+	if err != nil {
+		spinner.Fail(err.Error())
+		return
+	}
+	spinner.Success()
+}
+
+func printUpgradePlan(plan *bootstrap.UpgradePlan) {
+	fmt.Println()
+	pterm.DefaultSection.Printf("Upgrade plan: %s in %s (%s)", plan.Tool, plan.Namespace, plan.Method)
+	if plan.UpToDate() {
+		pterm.Success.Printfln("%s is already at %s", plan.Tool, plan.To)
+		return
+	}
+	pterm.Info.Printfln("%s → %s (via %s)", plan.From, plan.To, strings.Join(plan.Path, " → "))
+
+	if len(plan.CRDChanges) > 0 {
+		rows := [][]string{{"CRD", "CHANGE", "VERSIONS"}}
+		for _, c := range plan.CRDChanges {
+			versions := strings.Join(c.To, ", ")
+			if c.Change == "updated" {
+				versions = strings.Join(c.From, ", ") + " → " + versions
+			} else if c.Change == "removed" {
+				versions = strings.Join(c.From, ", ")
+			}
+			rows = append(rows, []string{c.Name, c.Change, versions})
+		}
+		fmt.Println()
+		_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+	} else {
+		pterm.Info.Println("No CRD changes")
+	}
+
+	if plan.Method == bootstrap.InstallMethodHelm {
+		if len(plan.ValueChanges) > 0 {
+			rows := [][]string{{"VALUE", "CURRENT", "NEW"}}
+			for _, v := range plan.ValueChanges {
+				rows = append(rows, []string{v.Key, formatValue(v.From), formatValue(v.To)})
+			}
+			fmt.Println()
+			_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		} else {
+			pterm.Info.Println("No values changes")
+		}
+	}
+
+	for _, w := range plan.Warnings {
+		pterm.Warning.Println(w)
+	}
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/bundle"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Prepare offline bundles for air-gapped environments",
+	Long: `Prepare offline bundles on a connected machine for use with --offline.
+
+A bundle holds the ArgoCD and Flux install manifests of selected versions and
+a local registry with marketplace patterns and their dependencies. Copy it to
+the air-gapped machine and pass it with --bundle-dir (or GITOPSI_BUNDLE_DIR).
+Container images and Helm charts referenced by patterns are not bundled;
+mirror them to your internal registries.`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Download install manifests and patterns into a bundle directory",
+	Long: `Download install manifests and patterns into a bundle directory. Running it
+again on the same directory adds to the bundle.
+
+Examples:
+  gitopsi bundle create --dir ./gitopsi-bundle --argocd v2.13.1
+  gitopsi bundle create --dir ./gitopsi-bundle --argocd v2.13.1 --flux v2.4.0
+  gitopsi bundle create --dir ./gitopsi-bundle --pattern monitoring --pattern cert-manager@1.0.0`,
+	Args: cobra.NoArgs,
+	RunE: runBundleCreate,
+}
+
+var (
+	bundleCreateDir string
+	bundleArgoCD    []string
+	bundleFlux      []string
+	bundlePatterns  []string
+
+	// offlineMode and bundleDir are shared by the commands supporting
+	// --offline.
+	offlineMode bool
+	bundleDir   string
+)
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd)
+
+	bundleCreateCmd.Flags().StringVar(&bundleCreateDir, "dir", "gitopsi-bundle", "Bundle directory")
+	bundleCreateCmd.Flags().StringSliceVar(&bundleArgoCD, "argocd", nil, "ArgoCD versions to bundle (e.g. v2.13.1)")
+	bundleCreateCmd.Flags().StringSliceVar(&bundleFlux, "flux", nil, "Flux versions to bundle (e.g. v2.4.0)")
+	bundleCreateCmd.Flags().StringArrayVar(&bundlePatterns, "pattern", nil, "Pattern to bundle with its dependencies, as name or name@version")
+}
+
+// addOfflineFlags registers --offline and --bundle-dir.
+func addOfflineFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&offlineMode, "offline", false, "Never reach the network: install from an offline bundle and use local pattern registries only (or set GITOPSI_OFFLINE)")
+	flags.StringVar(&bundleDir, "bundle-dir", "", "Offline bundle directory (or GITOPSI_BUNDLE_DIR; default: the bundle built into the binary)")
+}
+
+// offlineRequested reports whether --offline or GITOPSI_OFFLINE is set.
+func offlineRequested() bool {
+	if offlineMode {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("GITOPSI_OFFLINE"))
+	return enabled
+}
+
+// offlineBundleDir returns the bundle directory from --bundle-dir or
+// GITOPSI_BUNDLE_DIR.
+func offlineBundleDir() string {
+	if bundleDir != "" {
+		return bundleDir
+	}
+	return os.Getenv("GITOPSI_BUNDLE_DIR")
+}
+
+// applyOfflineFlags applies --offline and --bundle-dir to the bootstrap
+// section of cfg.
+func applyOfflineFlags(cfg *config.Config) {
+	if offlineRequested() {
+		cfg.Bootstrap.Offline = true
+	}
+	if dir := offlineBundleDir(); dir != "" {
+		cfg.Bootstrap.BundleDir = dir
+	}
+}
+
+// useOfflineBundle opens the bundle of an offline bootstrap.
+func useOfflineBundle(opts *bootstrap.Options, cfg *config.Config) error {
+	if !opts.Offline {
+		return nil
+	}
+	b, err := bundle.Open(cfg.Bootstrap.BundleDir)
+	if err != nil {
+		return err
+	}
+	opts.Manifests = b
+	return nil
+}
+
+// configureOfflineRegistry restricts a registry manager to local registries
+// in offline mode, adding the registry of the offline bundle.
+func configureOfflineRegistry(rm *marketplace.RegistryManager) {
+	if !offlineRequested() {
+		return
+	}
+	rm.SetOffline(true)
+
+	dir := offlineBundleDir()
+	if dir == "" {
+		return
+	}
+	b, err := bundle.Open(dir)
+	if err != nil {
+		pterm.Warning.Printfln("Offline bundle not used: %v", err)
+		return
+	}
+	if registryDir, ok := b.RegistryDir(); ok {
+		_ = rm.AddRegistry(marketplace.Registry{
+			Name:     "bundle",
+			Type:     marketplace.RegistryTypeLocal,
+			URL:      registryDir,
+			Priority: 200,
+			Enabled:  true,
+		})
+	}
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	if offlineRequested() {
+		return fmt.Errorf("bundle create downloads manifests and cannot run offline")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	opts := &bundle.CreateOptions{
+		Dir:            bundleCreateDir,
+		ArgoCDVersions: bundleArgoCD,
+		FluxVersions:   bundleFlux,
+		Patterns:       bundlePatterns,
+	}
+	if len(bundlePatterns) > 0 {
+		opts.Registry = marketplace.NewMarketplace(".").GetRegistry()
+	}
+
+	p := newPrinter()
+	var spinner *pterm.SpinnerPrinter
+	if !p.structured() {
+		spinner, _ = pterm.DefaultSpinner.Start("Creating offline bundle...")
+	}
+	index, err := bundle.Create(ctx, opts)
+	if err != nil {
+		if spinner != nil {
+			spinner.Fail("Bundle creation failed")
+		}
+		return err
+	}
+	if spinner != nil {
+		spinner.Success(fmt.Sprintf("Bundle written to %s", bundleCreateDir))
+	}
+
+	if p.structured() {
+		return p.print(index)
+	}
+
+	rows := [][]string{{"TOOL", "VERSION", "SOURCE"}}
+	for _, c := range index.Components {
+		rows = append(rows, []string{c.Tool, c.Version, c.Source})
+	}
+	if len(rows) > 1 {
+		_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+	}
+	for _, pattern := range index.Patterns {
+		pterm.Printf("   └─ pattern %s\n", pattern)
+	}
+	fmt.Println()
+	pterm.Info.Printfln("Use it offline with: gitopsi bootstrap --offline --bundle-dir %s", bundleCreateDir)
+	return nil
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -204,8 +205,13 @@ func TestFlagDefaultValues(t *testing.T) {
 	flags := rootCmd.PersistentFlags()
 
 	outputFlag := flags.Lookup("output")
-	if outputFlag.DefValue != "." {
-		t.Errorf("Default output should be '.', got %s", outputFlag.DefValue)
+	if outputFlag.DefValue != "table" {
+		t.Errorf("Default output should be 'table', got %s", outputFlag.DefValue)
+	}
+
+	outputDirFlag := flags.Lookup("output-dir")
+	if outputDirFlag.DefValue != "." {
+		t.Errorf("Default output-dir should be '.', got %s", outputDirFlag.DefValue)
 	}
 
 	dryRunFlag := flags.Lookup("dry-run")
@@ -316,3 +322,37 @@ func TestExecuteWithHelp(t *testing.T) {
 		t.Errorf("Execute() with help should not error: %v", err)
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(nil); got != ExitOK {
+		t.Errorf("ExitCode(nil) = %d, want %d", got, ExitOK)
+	}
+	if got := ExitCode(fmt.Errorf("boom")); got != ExitError {
+		t.Errorf("ExitCode(error) = %d, want %d", got, ExitError)
+	}
+	wrapped := fmt.Errorf("wrapped: %w", withExitCode(ExitDrift, fmt.Errorf("drift")))
+	if got := ExitCode(wrapped); got != ExitDrift {
+		t.Errorf("ExitCode(drift) = %d, want %d", got, ExitDrift)
+	}
+}
+
+func TestGraphOutputFormat(t *testing.T) {
+	tests := []struct {
+		format, file, want string
+	}{
+		{"", "", "mermaid"},
+		{"", "docs/graph.md", "md"},
+		{"", "graph.dot", "dot"},
+		{"", "graph.SVG", "svg"},
+		{"", "graph.mmd", "mermaid"},
+		{"DOT", "graph.md", "dot"},
+	}
+	for _, tt := range tests {
+		if got, err := graphOutputFormat(tt.format, tt.file); err != nil || got != tt.want {
+			t.Errorf("graphOutputFormat(%q, %q) = %q, %v, want %q", tt.format, tt.file, got, err, tt.want)
+		}
+	}
+	if _, err := graphOutputFormat("png", ""); err == nil {
+		t.Error("graphOutputFormat(png) should fail")
+	}
+}
@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Manage local sandbox clusters and register remote clusters",
+	Long: `Create and delete local Kubernetes clusters with kind, k3d or minikube to try a GitOps project end to end,
+and register the clusters of an environment in the ArgoCD or Flux hub.`,
+}
+
+var clusterCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a local cluster and bootstrap GitOps on it",
+	Long: `Create a local cluster with kind, k3d (k3s in Docker) or minikube, switch the
+kubeconfig to it, then bootstrap the GitOps tool of the config (--config or
+./gitops.yaml) on it. An existing cluster with the same name is reused.
+
+--git-server mounts the generated project (or --mount) in the nodes and serves
+it from an in-cluster Git server, so ArgoCD syncs the local repository without
+pushing it anywhere. Commit changes to the local repository to sync them.
+
+Examples:
+  gitopsi cluster create
+  gitopsi cluster create --provider k3d --workers 2 --kubernetes-version v1.30.2
+  gitopsi cluster create --config gitops.yaml --git-server
+  gitopsi cluster create --provider minikube --no-bootstrap`,
+	RunE: runClusterCreate,
+}
+
+var clusterDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a local cluster",
+	Long: `Delete a local cluster created with gitopsi cluster create.
+
+Examples:
+  gitopsi cluster delete
+  gitopsi cluster delete --provider k3d --name dev`,
+	RunE: runClusterDelete,
+}
+
+var (
+	localProvider    string
+	localName        string
+	localWorkers     int
+	localK8sVersion  string
+	localKubeconfig  string
+	localMount       string
+	localGitServer   bool
+	localNoBootstrap bool
+)
+
+func init() {
+	rootCmd.AddCommand(clusterCmd)
+	clusterCmd.AddCommand(clusterCreateCmd)
+	clusterCmd.AddCommand(clusterDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{clusterCreateCmd, clusterDeleteCmd} {
+		cmd.Flags().StringVar(&localProvider, "provider", "kind", "Local cluster provider (kind, k3d, minikube)")
+		cmd.Flags().StringVar(&localName, "name", cluster.DefaultLocalName, "Cluster name")
+		cmd.Flags().StringVar(&localKubeconfig, "kubeconfig", "", "Kubeconfig to write the context to (default: $KUBECONFIG or ~/.kube/config)")
+	}
+	clusterCreateCmd.Flags().IntVar(&localWorkers, "workers", 0, "Worker nodes besides the control plane")
+	clusterCreateCmd.Flags().StringVar(&localK8sVersion, "kubernetes-version", "", "Kubernetes version, e.g. v1.30.2 (default: the provider's)")
+	clusterCreateCmd.Flags().StringVar(&localMount, "mount", "", "Host directory mounted in the nodes at "+cluster.LocalMountPath+" (default with --git-server: the generated project)")
+	clusterCreateCmd.Flags().BoolVar(&localGitServer, "git-server", false, "Serve the mounted repository from an in-cluster Git server and sync from it")
+	clusterCreateCmd.Flags().BoolVar(&localNoBootstrap, "no-bootstrap", false, "Only create the cluster")
+}
+
+// localClusterResult is the structured output of cluster create.
+type localClusterResult struct {
+	Cluster      *cluster.LocalCluster `yaml:"cluster" json:"cluster"`
+	GitURL       string                `yaml:"gitURL,omitempty" json:"gitURL,omitempty"`
+	Bootstrapped bool                  `yaml:"bootstrapped" json:"bootstrapped"`
+	Tool         string                `yaml:"tool,omitempty" json:"tool,omitempty"`
+	ToolURL      string                `yaml:"toolURL,omitempty" json:"toolURL,omitempty"`
+}
+
+func runClusterCreate(cmd *cobra.Command, args []string) error {
+	provider, err := cluster.ParseLocalProvider(localProvider)
+	if err != nil {
+		return err
+	}
+	if localWorkers < 0 {
+		return fmt.Errorf("--workers must not be negative")
+	}
+
+	cfg, err := localClusterConfig()
+	if err != nil {
+		return err
+	}
+	if localGitServer {
+		if cfg == nil {
+			return fmt.Errorf("--git-server requires a config to bootstrap: use --config")
+		}
+		if cfg.GitOpsTool != "argocd" {
+			return fmt.Errorf("--git-server serves the git protocol, which only ArgoCD syncs from; push the repository and use git.url with %s", cfg.GitOpsTool)
+		}
+		if localMount == "" {
+			localMount = localProjectDir(cfg)
+		}
+		if _, err := os.Stat(filepath.Join(localMount, ".git")); err != nil {
+			return fmt.Errorf("%s is not a git repository: run git init and commit the project first", localMount)
+		}
+	}
+
+	p := newPrinter()
+	structured := p.structured()
+	ctx := cmd.Context()
+
+	var spinner *pterm.SpinnerPrinter
+	if !structured {
+		spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Creating %s cluster %s...", provider, localName))
+	}
+	local, err := cluster.CreateLocal(ctx, &cluster.LocalOptions{
+		Provider:          provider,
+		Name:              localName,
+		Workers:           localWorkers,
+		KubernetesVersion: localK8sVersion,
+		Kubeconfig:        localKubeconfig,
+		HostPath:          localMount,
+	})
+	if err != nil {
+		if spinner != nil {
+			spinner.Fail(err.Error())
+		}
+		return err
+	}
+	if spinner != nil {
+		if local.Existed {
+			spinner.Info(fmt.Sprintf("Reusing %s cluster %s (context %s)", provider, local.Name, local.Context))
+		} else {
+			spinner.Success(fmt.Sprintf("Created %s cluster %s (context %s)", provider, local.Name, local.Context))
+		}
+	}
+
+	result := &localClusterResult{Cluster: local}
+	if cfg == nil {
+		if !structured && !localNoBootstrap {
+			pterm.Info.Println("No gitops.yaml found: skipping bootstrap (use --config)")
+		}
+		return printLocalCluster(p, result)
+	}
+
+	cfg.Cluster.URL = local.URL
+	cfg.Cluster.Name = local.Name
+	cfg.Cluster.Context = local.Context
+	cfg.Cluster.Kubeconfig = localKubeconfig
+	cfg.Cluster.Platform = string(cluster.PlatformKubernetes)
+	cfg.Cluster.Auth = config.ClusterAuth{Method: string(cluster.AuthKubeconfig)}
+	c, err := authenticateCluster(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster %s: %w", local.Name, err)
+	}
+
+	if localGitServer {
+		if err := deployLocalGitServer(ctx, c, structured); err != nil {
+			return err
+		}
+		cfg.Git.URL = cluster.LocalGitURL
+		cfg.Bootstrap.ConfigureRepo = true
+		cfg.Bootstrap.CreateAppOfApps = true
+		result.GitURL = cluster.LocalGitURL
+	}
+
+	cfg.Platform = string(cluster.PlatformKubernetes)
+	cfg.Bootstrap.Wait = true
+	if cfg.Bootstrap.Mode == "" {
+		cfg.Bootstrap.Mode = string(bootstrap.SuggestMode(bootstrap.Tool(cfg.GitOpsTool), cfg.Platform))
+	}
+	if !structured {
+		spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Bootstrapping %s (%s)...", cfg.GitOpsTool, cfg.Bootstrap.Mode))
+	}
+	bootstrapResult, err := bootstrapCluster(ctx, cfg, c)
+	if err != nil {
+		if spinner != nil {
+			spinner.Fail(err.Error())
+		}
+		return fmt.Errorf("failed to bootstrap cluster %s: %w", local.Name, err)
+	}
+	if spinner != nil {
+		spinner.Success(bootstrapResult.Message)
+	}
+	result.Bootstrapped = true
+	result.Tool = cfg.GitOpsTool
+	result.ToolURL = bootstrapResult.URL
+
+	if !structured && bootstrapResult.Password != "" {
+		pterm.Info.Printfln("ArgoCD: %s (user %s, password %s)", bootstrapResult.URL, bootstrapResult.Username, bootstrapResult.Password)
+	}
+	return printLocalCluster(p, result)
+}
+
+// localClusterConfig loads the config to bootstrap, if any: --config, or
+// ./gitops.yaml when it exists.
+func localClusterConfig() (*config.Config, error) {
+	if localNoBootstrap {
+		return nil, nil
+	}
+	path := cfgFile
+	if path == "" {
+		path = "gitops.yaml"
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// localProjectDir returns the directory gitopsi init generates the project
+// of a config into.
+func localProjectDir(cfg *config.Config) string {
+	outputDir := GetOutput()
+	if outputDir == "" {
+		outputDir = "."
+	}
+	return filepath.Join(outputDir, cfg.Project.Name)
+}
+
+// deployLocalGitServer deploys the in-cluster Git server and waits for it.
+func deployLocalGitServer(ctx context.Context, c *cluster.Cluster, structured bool) error {
+	var spinner *pterm.SpinnerPrinter
+	if !structured {
+		spinner, _ = pterm.DefaultSpinner.Start("Deploying the local Git server...")
+	}
+	err := c.Apply(ctx, cluster.LocalGitServerManifest())
+	if err == nil {
+		timeout := (2 * time.Minute).String()
+		_, err = c.RunCommand(ctx, "rollout", "status", "deployment/gitopsi-git", "-n", cluster.LocalGitNamespace, "--timeout", timeout)
+	}
+	if err != nil {
+		if spinner != nil {
+			spinner.Fail(err.Error())
+		}
+		return fmt.Errorf("failed to deploy the local Git server: %w", err)
+	}
+	if spinner != nil {
+		spinner.Success("Local Git server serving " + cluster.LocalGitURL)
+	}
+	return nil
+}
+
+func printLocalCluster(p *printer, result *localClusterResult) error {
+	if p.structured() {
+		return p.print(result)
+	}
+	fmt.Println()
+	pterm.Success.Printfln("Cluster %s is ready: kubectl --context %s get nodes", result.Cluster.Name, result.Cluster.Context)
+	pterm.Info.Printfln("Delete it with: gitopsi cluster delete --provider %s --name %s", result.Cluster.Provider, result.Cluster.Name)
+	return nil
+}
+
+func runClusterDelete(cmd *cobra.Command, args []string) error {
+	provider, err := cluster.ParseLocalProvider(localProvider)
+	if err != nil {
+		return err
+	}
+	if err := cluster.DeleteLocal(cmd.Context(), &cluster.LocalOptions{
+		Provider:   provider,
+		Name:       localName,
+		Kubeconfig: localKubeconfig,
+	}); err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Deleted %s cluster %s", provider, localName)
+	return nil
+}
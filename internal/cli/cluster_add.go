@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+var clusterAddCmd = &cobra.Command{
+	Use:   "add <environment>",
+	Short: "Register the clusters of an environment in the ArgoCD or Flux hub",
+	Long: `Generate the secrets registering the clusters of an environment in the hub
+from their stored platform credentials (environments[].credential or
+environments[].clusters[].credential, see gitopsi auth import-kubeconfig) and
+apply them to the hub: the cluster of the config, or --context.
+
+With ArgoCD these are cluster secrets. Bearer tokens, client certificates,
+basic auth and the certificate authority are written to their tlsClientConfig.
+Without a certificate authority the cluster is verified with the system roots,
+unless --insecure-skip-tls-verify is set.
+EKS exec credentials become an awsAuthConfig using the IAM role of ArgoCD;
+other exec plugins are passed through and must be installed in the ArgoCD
+server and application controller.
+
+With Flux these are the <cluster>-kubeconfig Secrets referenced by the
+spec.kubeConfig of the Kustomizations generated for the spokes with the hub
+strategy of bootstrap.multi_cluster. Flux runs no credential plugins, so the
+credentials must be a token, client certificate or basic auth.
+
+With --dry-run the secrets are printed instead of applied.
+
+Examples:
+  gitopsi cluster add prod
+  gitopsi cluster add prod --config flux-hub.yaml
+  gitopsi cluster add staging --credential staging-admin --context hub
+  gitopsi cluster add prod --dry-run > prod-cluster-secrets.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runClusterAdd,
+}
+
+var (
+	clusterAddCredential string
+	clusterAddNamespace  string
+	clusterAddContext    string
+	clusterAddKubeconfig string
+	clusterAddInsecure   bool
+)
+
+func init() {
+	clusterCmd.AddCommand(clusterAddCmd)
+	clusterAddCmd.Flags().StringVar(&clusterAddCredential, "credential", "", "Stored platform credential of the cluster (overrides environments[].credential)")
+	clusterAddCmd.Flags().StringVar(&clusterAddNamespace, "namespace", "", "ArgoCD or Flux namespace on the hub (default: bootstrap.namespace, argocd or flux-system)")
+	clusterAddCmd.Flags().StringVar(&clusterAddContext, "context", "", "Kubeconfig context of the hub (default: the cluster of the config)")
+	clusterAddCmd.Flags().StringVar(&clusterAddKubeconfig, "kubeconfig", "", "Path to the kubeconfig of the hub")
+	clusterAddCmd.Flags().BoolVar(&clusterAddInsecure, "insecure-skip-tls-verify", false, "Skip TLS verification of clusters whose credential has no certificate authority (unsafe)")
+}
+
+// clusterAddResult is the structured output of cluster add.
+type clusterAddResult struct {
+	Name        string `json:"name" yaml:"name"`
+	Environment string `json:"environment" yaml:"environment"`
+	Server      string `json:"server" yaml:"server"`
+	Credential  string `json:"credential" yaml:"credential"`
+	Secret      string `json:"secret" yaml:"secret"`
+	Applied     bool   `json:"applied" yaml:"applied"`
+}
+
+func runClusterAdd(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "gitops.yaml"
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tool := bootstrap.Tool(cfg.GitOpsTool)
+	if tool != bootstrap.ToolArgoCD && tool != bootstrap.ToolFlux {
+		return fmt.Errorf("cluster add registers clusters in ArgoCD or Flux, got %s", cfg.GitOpsTool)
+	}
+	env := cfg.GetEnvironment(args[0])
+	if env == nil {
+		return fmt.Errorf("environment %s not found in %s", args[0], configPath)
+	}
+
+	namespace := clusterAddNamespace
+	if namespace == "" {
+		namespace = cfg.Bootstrap.Namespace
+	}
+	if namespace == "" && tool == bootstrap.ToolFlux {
+		namespace = "flux-system"
+	}
+	if namespace == "" {
+		namespace = "argocd"
+	}
+
+	ctx := cmd.Context()
+	secrets, err := environmentClusterSecrets(ctx, tool, env, namespace, clusterAddCredential)
+	if err != nil {
+		return err
+	}
+
+	p := newPrinter()
+	if dryRun {
+		if p.structured() {
+			return p.print(secrets)
+		}
+		manifests := make([]string, 0, len(secrets))
+		for _, s := range secrets {
+			manifests = append(manifests, s.Secret)
+		}
+		fmt.Print(strings.Join(manifests, "---\n"))
+		return nil
+	}
+
+	hub, err := clusterAddHub(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the hub: %w", err)
+	}
+	for i := range secrets {
+		s := &secrets[i]
+		if err := hub.Apply(ctx, s.Secret); err != nil {
+			return fmt.Errorf("failed to register cluster %s in the hub: %w", s.Name, err)
+		}
+		s.Applied = true
+		if !p.structured() {
+			pterm.Success.Printf("Registered cluster %s (%s) with credential %s\n", s.Name, s.Server, s.Credential)
+		}
+	}
+	if p.structured() {
+		return p.print(secrets)
+	}
+	return nil
+}
+
+// environmentClusterSecrets renders the ArgoCD cluster secrets or Flux
+// kubeConfig Secrets of the clusters of env from their stored platform
+// credentials. credential overrides the credential of an environment with a
+// single cluster.
+func environmentClusterSecrets(ctx context.Context, tool bootstrap.Tool, env *config.Environment, namespace, credential string) ([]clusterAddResult, error) {
+	type entry struct {
+		name, url, credential string
+		labels                map[string]string
+	}
+	var entries []entry
+	if len(env.Clusters) > 0 {
+		if credential != "" && len(env.Clusters) > 1 {
+			return nil, fmt.Errorf("--credential needs an environment with one cluster: %s has %d, set environments[].clusters[].credential", env.Name, len(env.Clusters))
+		}
+		for _, ec := range env.Clusters {
+			var labels map[string]string
+			if ec.Region != "" {
+				labels = map[string]string{"region": ec.Region}
+			}
+			entries = append(entries, entry{ec.Name, ec.URL, ec.Credential, labels})
+		}
+	} else {
+		entries = append(entries, entry{env.Name, env.Cluster, env.Credential, nil})
+	}
+
+	results := make([]clusterAddResult, 0, len(entries))
+	for _, e := range entries {
+		if credential != "" {
+			e.credential = credential
+		}
+		if e.credential == "" {
+			return nil, fmt.Errorf("cluster %s has no credential: set environments[].credential or use --credential", e.name)
+		}
+		cred, err := readCredential(ctx, e.credential)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credential %s: %w", e.credential, err)
+		}
+		remote, err := remoteCredentials(cred)
+		if err != nil {
+			return nil, err
+		}
+		remote.Insecure = clusterAddInsecure
+		if e.url == "" {
+			e.url = cred.Metadata.URL
+		}
+		target := &bootstrap.ClusterTarget{
+			Name:        e.name,
+			Environment: env.Name,
+			Cluster:     cluster.New(e.url, e.name, cluster.PlatformKubernetes),
+			Labels:      e.labels,
+		}
+		render := bootstrap.ClusterSecret
+		if tool == bootstrap.ToolFlux {
+			render = bootstrap.KubeconfigSecret
+		}
+		secret, err := render(target, namespace, remote)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, clusterAddResult{
+			Name:        e.name,
+			Environment: env.Name,
+			Server:      e.url,
+			Credential:  e.credential,
+			Secret:      secret,
+		})
+	}
+	return results, nil
+}
+
+// clusterAddHub connects to the hub of cluster add.
+func clusterAddHub(ctx context.Context, cfg *config.Config) (*cluster.Cluster, error) {
+	if clusterAddContext == "" && clusterAddKubeconfig == "" {
+		return authenticateCluster(ctx, cfg)
+	}
+	c := cluster.New("", clusterAddContext, cluster.Platform(cfg.Platform))
+	if err := c.Authenticate(&cluster.AuthOptions{Method: cluster.AuthKubeconfig, Kubeconfig: clusterAddKubeconfig, Context: clusterAddContext}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// remoteCredentials converts a stored platform credential into the
+// credentials of an ArgoCD cluster secret.
+func remoteCredentials(cred *auth.Credential) (*bootstrap.RemoteCredentials, error) {
+	if cred.Type != auth.CredentialTypePlatform {
+		return nil, fmt.Errorf("credential %s is a %s credential, not a platform credential", cred.Name, cred.Type)
+	}
+	encode := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	remote := &bootstrap.RemoteCredentials{CAData: encode(cred.Data.CACert)}
+	switch cred.Method {
+	case auth.MethodToken, auth.MethodServiceAccount:
+		remote.BearerToken = cred.Data.Token
+	case auth.MethodClientCert:
+		remote.CertData = encode(cred.Data.TLSCert)
+		remote.KeyData = encode(cred.Data.TLSKey)
+	case auth.MethodBasic:
+		remote.Username = cred.Data.Username
+		remote.Password = cred.Data.Password
+	case auth.MethodExec:
+		exec := cred.Data.Exec
+		if exec == nil {
+			return nil, fmt.Errorf("credential %s has no exec plugin", cred.Name)
+		}
+		if name := argValue(exec.Args, "--cluster-name"); exec.Command == "aws" && name != "" {
+			remote.AWSClusterName = name
+			remote.AWSRoleARN = argValue(exec.Args, "--role-arn")
+			break
+		}
+		remote.Exec = &bootstrap.ExecProvider{
+			Command:     exec.Command,
+			Args:        exec.Args,
+			Env:         exec.Env,
+			APIVersion:  exec.APIVersion,
+			InstallHint: fmt.Sprintf("install %s in the ArgoCD server and application controller images", exec.Command),
+		}
+	default:
+		return nil, fmt.Errorf("%s credentials cannot be used in an ArgoCD cluster secret: use a token, client certificate or exec credential", cred.Method)
+	}
+	return remote, nil
+}
+
+// argValue returns the value of flag in args, given as "flag value" or
+// "flag=value".
+func argValue(args []string, flag string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value
+		}
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// staticRemoteCredentials returns the ArgoCD credentials of a stored platform
+// credential for the hub strategy, or nil for exec credentials, whose plugin
+// ArgoCD may lack: those spokes get a service account instead.
+func staticRemoteCredentials(cred *auth.Credential) (*bootstrap.RemoteCredentials, error) {
+	if !slices.Contains([]auth.Method{auth.MethodToken, auth.MethodServiceAccount, auth.MethodClientCert, auth.MethodBasic}, cred.Method) {
+		return nil, nil
+	}
+	return remoteCredentials(cred)
+}
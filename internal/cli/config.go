@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/upgrade"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage gitopsi user settings and config files",
+	Long: `Read and update user settings stored in ~/.gitopsi/config.yaml, and
+migrate gitopsi config files to the current apiVersion.
+
+Examples:
+  gitopsi config set auth.store keyring   # Store credentials in the OS keychain
+  gitopsi config set telemetry.enabled true  # Export OpenTelemetry traces and metrics
+  gitopsi config set network.ca-bundle /etc/ssl/corp-ca.pem  # Trust a proxy CA
+  gitopsi config get auth.store
+  gitopsi config list
+  gitopsi config migrate --dry-run        # Show the migration of gitops.yaml`,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a user setting",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Show a user setting",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all user settings",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigList,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file]",
+	Short: "Migrate a config file to the current apiVersion",
+	Long: `Upgrade a gitopsi config file (default: --config or gitops.yaml) written
+for an older apiVersion to the current schema, renaming and restructuring
+fields while keeping comments. Older configs are migrated in memory when
+loaded; this command rewrites the file. With --dry-run the diff is printed
+and the file is left unchanged.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigMigrate,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configMigrateCmd)
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	path := config.DefaultUserSettingsPath()
+	settings, err := config.LoadUserSettings(path)
+	if err != nil {
+		return err
+	}
+
+	if err := settings.Set(args[0], args[1]); err != nil {
+		return err
+	}
+	if err := config.SaveUserSettings(settings, path); err != nil {
+		return err
+	}
+
+	pterm.Success.Printf("Set %s = %s\n", args[0], args[1])
+	return nil
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	settings, err := config.LoadUserSettings(config.DefaultUserSettingsPath())
+	if err != nil {
+		return err
+	}
+
+	value, err := settings.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	settings, err := config.LoadUserSettings(config.DefaultUserSettingsPath())
+	if err != nil {
+		return err
+	}
+
+	tableData := [][]string{{"KEY", "VALUE"}}
+	for _, key := range config.SettingKeys() {
+		value, _ := settings.Get(key)
+		tableData = append(tableData, []string{key, value})
+	}
+
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return nil
+}
+
+// configMigrateResult is the structured output of config migrate.
+type configMigrateResult struct {
+	File                   string `json:"file" yaml:"file"`
+	config.MigrationResult `yaml:",inline"`
+	DryRun                 bool `json:"dry_run" yaml:"dry_run"`
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	path := cfgFile
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path == "" {
+		path = "gitops.yaml"
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	result, err := config.Migrate(data)
+	if err != nil {
+		return err
+	}
+
+	if result.Migrated() && !dryRun {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat config file: %w", err)
+		}
+		if err := os.WriteFile(path, result.Content, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+	}
+
+	p := newPrinter()
+	if p.structured() {
+		return p.print(configMigrateResult{File: path, MigrationResult: *result, DryRun: dryRun})
+	}
+
+	if !result.Migrated() {
+		pterm.Info.Printf("%s is already at apiVersion %s\n", path, config.APIVersion)
+		return nil
+	}
+	from := result.From
+	if from == "" {
+		from = "(none)"
+	}
+	for _, change := range result.Changes {
+		fmt.Printf("  • %s\n", change)
+	}
+	if dryRun {
+		fmt.Print(upgrade.Diff(path, data, result.Content))
+		pterm.Info.Printf("Would migrate %s from apiVersion %s to %s\n", path, from, result.To)
+		return nil
+	}
+	pterm.Success.Printf("Migrated %s from apiVersion %s to %s\n", path, from, result.To)
+	return nil
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+var (
+	// platformCredential is the stored platform credential of --cluster-credential.
+	platformCredential string
+	// credentialKubeconfigs are the kubeconfigs written for credentials, by
+	// credential name.
+	credentialKubeconfigs = map[string]string{}
+	// kubeconfigCleanups remove the kubeconfigs written for credentials.
+	kubeconfigCleanups []func()
+)
+
+// credentialKubeconfig writes the kubeconfig of a stored platform credential
+// once per command and returns the credential and the kubeconfig path. The
+// file is removed when the command ends.
+func credentialKubeconfig(ctx context.Context, name string) (*auth.Credential, string, error) {
+	cred, err := readCredential(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if path, ok := credentialKubeconfigs[name]; ok {
+		return cred, path, nil
+	}
+	path, cleanup, err := auth.WriteKubeconfig(cred)
+	if err != nil {
+		return nil, "", err
+	}
+	credentialKubeconfigs[name] = path
+	kubeconfigCleanups = append(kubeconfigCleanups, cleanup)
+	return cred, path, nil
+}
+
+// setupCredential points KUBECONFIG at the kubeconfig of --cluster-credential, so
+// that kubectl, helm and the Kubernetes clients of every cluster operation
+// reach the cluster with the stored credential.
+func setupCredential() error {
+	if platformCredential == "" {
+		return nil
+	}
+	_, path, err := credentialKubeconfig(context.Background(), platformCredential)
+	if err != nil {
+		return fmt.Errorf("failed to use credential %s: %w", platformCredential, err)
+	}
+	return os.Setenv("KUBECONFIG", path)
+}
+
+// cleanupCredentials removes the kubeconfigs written for credentials.
+func cleanupCredentials() {
+	for _, cleanup := range kubeconfigCleanups {
+		cleanup()
+	}
+	kubeconfigCleanups = nil
+	credentialKubeconfigs = map[string]string{}
+}
+
+// useClusterCredential replaces the kubeconfig and context of cluster with
+// the kubeconfig of cluster.credential, when set, and defaults the cluster URL
+// to the URL of the credential.
+func useClusterCredential(ctx context.Context, cfg *config.Config) error {
+	if cfg.Cluster.Credential == "" {
+		return nil
+	}
+	cred, path, err := credentialKubeconfig(ctx, cfg.Cluster.Credential)
+	if err != nil {
+		return fmt.Errorf("failed to use credential %s: %w", cfg.Cluster.Credential, err)
+	}
+	cfg.Cluster.Kubeconfig, cfg.Cluster.Context = path, cred.KubeContext()
+	cfg.Cluster.Auth.Method = string(cluster.AuthKubeconfig)
+	if cfg.Cluster.URL == "" {
+		cfg.Cluster.URL = cred.Metadata.URL
+	}
+	return nil
+}
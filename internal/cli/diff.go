@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/diff"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare generated manifests with live cluster state",
+	Long: `Renders the GitOps repository (kustomize build or helm template) and
+compares it with the live cluster using a server-side dry-run.
+
+Exit codes:
+  0  No drift
+  1  Error rendering or diffing
+  2  Drift detected
+
+Examples:
+  gitopsi diff --path ./my-platform --context prod
+  gitopsi diff --path ./my-platform --env staging
+  gitopsi diff --path ./my-platform/applications/overlays/prod
+  gitopsi diff --path ./my-platform -o json          # Machine readable output for CI`,
+	RunE: runDiff,
+}
+
+var (
+	diffPath        string
+	diffEnv         string
+	diffContext     string
+	diffKubeconfig  string
+	diffShowDetails bool
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&diffPath, "path", ".", "Path to the GitOps repository or a kustomization/chart directory")
+	diffCmd.Flags().StringVar(&diffEnv, "env", "", "Only diff overlays for this environment")
+	diffCmd.Flags().StringVar(&diffContext, "context", "", "Kubernetes context to use")
+	diffCmd.Flags().StringVar(&diffKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	diffCmd.Flags().BoolVar(&diffShowDetails, "details", false, "Print the unified diff of each drifted resource")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	d := diff.New(&diff.Options{
+		Path:        diffPath,
+		Environment: diffEnv,
+		Context:     diffContext,
+		Kubeconfig:  diffKubeconfig,
+	})
+
+	report, err := d.Run(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if p := newPrinter(); p.structured() {
+		if err := p.print(report); err != nil {
+			return err
+		}
+	} else {
+		printDiffReport(report)
+	}
+
+	switch {
+	case report.HasErrors():
+		return fmt.Errorf("diff failed for %d targets", report.Errors)
+	case report.HasDrift():
+		return withExitCode(ExitDrift, fmt.Errorf("drift detected in %d resources", report.Drifted))
+	}
+	return nil
+}
+
+func printDiffReport(report *diff.Report) {
+	pterm.DefaultHeader.WithFullWidth().Println("🔍 gitopsi diff")
+	fmt.Println()
+
+	for _, target := range report.Targets {
+		pterm.DefaultSection.Printf("%s (%s)\n", target.Path, target.Renderer)
+
+		if target.Error != "" {
+			pterm.Error.Println(target.Error)
+			continue
+		}
+		if len(target.Resources) == 0 {
+			pterm.Success.Println("In sync")
+			continue
+		}
+
+		tableData := [][]string{{"KIND", "NAMESPACE", "NAME", "STATUS"}}
+		for _, r := range target.Resources {
+			tableData = append(tableData, []string{r.Kind, r.Namespace, r.Name, string(r.Status)})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+		if diffShowDetails {
+			for _, r := range target.Resources {
+				fmt.Println()
+				pterm.Info.Println(r.ID())
+				fmt.Println(strings.TrimRight(r.Diff, "\n"))
+			}
+		}
+	}
+
+	fmt.Println()
+	pterm.DefaultBox.WithTitle("Summary").Println(
+		fmt.Sprintf("Targets: %d  Drifted resources: %d  Errors: %d",
+			len(report.Targets), report.Drifted, report.Errors),
+	)
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/dns"
+)
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Check the DNS zone and records of application hostnames",
+	Long: `Work with the DNS zone of the dns section, which the application
+ingress hosts belong to.
+
+With dns.mode external-dns (default), init annotates the generated Ingresses
+and Routes for external-dns. With dns.mode direct, for clusters without
+external-dns, dns sync creates the records.`,
+}
+
+var dnsCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the DNS zone is reachable with the configured credential",
+	Long: `Check that the zone of the dns section exists at its provider and that the
+auth store credential of dns.credential, or the cloud CLI login, can read it.
+
+Examples:
+  gitopsi dns check --config gitops.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runDNSCheck,
+}
+
+var dnsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Create the DNS records of the application hostnames",
+	Long: `Create or update the records of the application ingress hosts of an
+environment, pointing them at the load balancer of its cluster: a CNAME for
+a hostname, an A record for an IP address.
+
+Examples:
+  gitopsi dns sync --env prod --config gitops.yaml
+  gitopsi dns sync --env dev --target 203.0.113.10 --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runDNSSync,
+}
+
+var (
+	dnsEnv    string
+	dnsTarget string
+)
+
+func init() {
+	rootCmd.AddCommand(dnsCmd)
+	dnsCmd.AddCommand(dnsCheckCmd)
+	dnsCmd.AddCommand(dnsSyncCmd)
+
+	dnsSyncCmd.Flags().StringVar(&dnsEnv, "env", "", "Environment whose hostnames are synced (required)")
+	dnsSyncCmd.Flags().StringVar(&dnsTarget, "target", "", "Load balancer hostname or IP address (default: dns.target)")
+	_ = dnsSyncCmd.MarkFlagRequired("env")
+}
+
+type dnsCheckResult struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Zone     string `json:"zone" yaml:"zone"`
+	ZoneID   string `json:"zone_id" yaml:"zone_id"`
+}
+
+// loadDNSConfig returns the project config, which must have a dns section.
+func loadDNSConfig() (*config.Config, error) {
+	cfg, err := loadProjectConfig(".")
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("no gitopsi.yaml in the current directory: pass --config")
+	}
+	if !cfg.DNS.Enabled() {
+		return nil, fmt.Errorf("the config has no dns.zone")
+	}
+	return cfg, nil
+}
+
+// newDNSClient returns the client of the dns section, authenticated with the
+// auth store credential of dns.credential.
+func newDNSClient(ctx context.Context, cfg *config.Config) (*dns.Client, error) {
+	d := cfg.DNS
+	var creds dns.Credentials
+	if d.Credential != "" {
+		cred, err := readCredential(ctx, d.Credential)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load DNS credential %s: %w", d.Credential, err)
+		}
+		creds = dns.Credentials{
+			AccessKeyID:     cred.Data.Username,
+			SecretAccessKey: cred.Data.Password,
+			Token:           cred.Data.Token,
+		}
+	}
+	zone := dns.Zone{
+		Provider:      d.Provider,
+		Name:          d.Zone,
+		ID:            d.ZoneID,
+		Project:       d.Project,
+		ResourceGroup: d.ResourceGroup,
+	}
+	return dns.New(zone, creds), nil
+}
+
+func runDNSCheck(cmd *cobra.Command, args []string) error {
+	cfg, err := loadDNSConfig()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	client, err := newDNSClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	id, err := client.Check(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := dnsCheckResult{Provider: cfg.DNS.Provider, Zone: cfg.DNS.Zone, ZoneID: id}
+	if p := newPrinter(); p.structured() {
+		return p.print(result)
+	}
+	pterm.Success.Printfln("Zone %s is reachable on %s (%s)", result.Zone, result.Provider, result.ZoneID)
+	return nil
+}
+
+// dnsRecords returns the records of the application hostnames of env.
+func dnsRecords(cfg *config.Config, env, target string) []dns.Record {
+	var records []dns.Record
+	seen := map[string]bool{}
+	for _, app := range cfg.Apps {
+		app = app.ForEnvironment(env)
+		if app.Ingress == nil || seen[app.Ingress.Host] {
+			continue
+		}
+		seen[app.Ingress.Host] = true
+		records = append(records, dns.NewRecord(app.Ingress.Host, target, cfg.DNS.TTL))
+	}
+	return records
+}
+
+func runDNSSync(cmd *cobra.Command, args []string) error {
+	cfg, err := loadDNSConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.GetEnvironment(dnsEnv) == nil {
+		return fmt.Errorf("environment %s not found in config", dnsEnv)
+	}
+	target := dnsTarget
+	if target == "" {
+		target = cfg.DNS.Target
+	}
+	if target == "" {
+		return fmt.Errorf("no record target: pass --target or set dns.target")
+	}
+
+	records := dnsRecords(cfg, dnsEnv, target)
+	p := newPrinter()
+	if len(records) == 0 {
+		if p.structured() {
+			return p.print(records)
+		}
+		pterm.Info.Printfln("No application of %s has an ingress host", dnsEnv)
+		return nil
+	}
+	if cfg.DNS.ExternalDNS() && !p.structured() {
+		pterm.Warning.Println("dns.mode is external-dns: records created here are also managed by external-dns")
+	}
+
+	if !dryRun {
+		ctx := cmd.Context()
+		client, err := newDNSClient(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if err := client.Upsert(ctx, records); err != nil {
+			return err
+		}
+	}
+
+	if p.structured() {
+		return p.print(records)
+	}
+	for _, r := range records {
+		pterm.Success.Printfln("%s %s -> %s", r.Name, r.Type, r.Target)
+	}
+	if dryRun {
+		pterm.Info.Println("Dry run: no record was written")
+	}
+	return nil
+}
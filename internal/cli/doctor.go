@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/doctor"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the local environment and target cluster",
+	Long: `Checks everything gitopsi needs before generating and bootstrapping.
+
+Checks include:
+- Local prerequisites (git, kubectl, helm, flux CLI)
+- Cluster connectivity
+- RBAC permissions required for bootstrap
+- ArgoCD/Flux controller health
+- Credential store integrity
+
+Examples:
+  gitopsi doctor
+  gitopsi doctor --gitops-tool flux --context staging
+  gitopsi doctor --skip-cluster
+  gitopsi doctor -o json             # Machine readable output for CI`,
+	RunE: runDoctor,
+}
+
+var (
+	doctorKubeconfig  string
+	doctorContext     string
+	doctorGitopsTool  string
+	doctorTimeout     int
+	doctorSkipCluster bool
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&doctorKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	doctorCmd.Flags().StringVar(&doctorContext, "context", "", "Kubernetes context to use")
+	doctorCmd.Flags().StringVar(&doctorGitopsTool, "gitops-tool", "argocd", "GitOps tool to check (argocd, flux, both)")
+	doctorCmd.Flags().IntVar(&doctorTimeout, "timeout", 30, "Timeout in seconds for each check")
+	doctorCmd.Flags().BoolVar(&doctorSkipCluster, "skip-cluster", false, "Skip checks that need cluster access")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	switch doctorGitopsTool {
+	case "argocd", "flux", "both":
+	default:
+		return fmt.Errorf("invalid gitops tool: %s (must be argocd, flux, or both)", doctorGitopsTool)
+	}
+
+	backend, err := authStoreBackend()
+	if err != nil {
+		return err
+	}
+
+	d := doctor.New(&doctor.Options{
+		Kubeconfig:   doctorKubeconfig,
+		Context:      doctorContext,
+		GitOpsTool:   doctorGitopsTool,
+		StoreBackend: backend,
+		Timeout:      time.Duration(doctorTimeout) * time.Second,
+		SkipCluster:  doctorSkipCluster,
+	})
+	d.Register(doctor.DefaultChecks(doctorGitopsTool)...)
+
+	p := newPrinter()
+	if !p.structured() {
+		pterm.DefaultHeader.WithFullWidth().Println("🩺 gitopsi doctor")
+		fmt.Println()
+	}
+
+	report := d.Run(context.Background())
+
+	if p.structured() {
+		if err := p.print(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	if report.HasFailures() {
+		return fmt.Errorf("doctor found %d failing checks", report.Summary.Fail)
+	}
+	return nil
+}
+
+func printDoctorReport(report *doctor.Report) {
+	for _, category := range doctor.Categories {
+		results := report.ByCategory(category)
+		if len(results) == 0 {
+			continue
+		}
+
+		pterm.DefaultSection.Println(doctorCategoryTitle(category))
+		for _, r := range results {
+			printDoctorResult(r)
+		}
+	}
+
+	fmt.Println()
+	pterm.DefaultBox.WithTitle("Summary").Println(
+		fmt.Sprintf("✅ Passed: %d  ⚠️  Warnings: %d  ❌ Failed: %d  ⏭️  Skipped: %d",
+			report.Summary.OK, report.Summary.Warn, report.Summary.Fail, report.Summary.Skipped),
+	)
+
+	switch {
+	case report.Summary.Fail > 0:
+		pterm.Error.Println("Some checks failed - fix the issues above before continuing")
+	case report.Summary.Warn > 0:
+		pterm.Warning.Println("All required checks passed with warnings")
+	default:
+		pterm.Success.Println("Everything looks good")
+	}
+}
+
+func printDoctorResult(result doctor.Result) {
+	var icon string
+	var color pterm.Color
+
+	switch result.Status {
+	case doctor.StatusOK:
+		icon = "✅"
+		color = pterm.FgGreen
+	case doctor.StatusWarn:
+		icon = "⚠️ "
+		color = pterm.FgYellow
+	case doctor.StatusFail:
+		icon = "❌"
+		color = pterm.FgRed
+	default:
+		icon = "⏭️ "
+		color = pterm.FgGray
+	}
+
+	pterm.Printf("%s %-25s %s\n", icon, result.Name, pterm.NewStyle(color).Sprint(result.Message))
+
+	if result.Details != "" && (result.Status == doctor.StatusWarn || result.Status == doctor.StatusFail) {
+		pterm.Printf("   └─ %s\n", pterm.FgGray.Sprint(result.Details))
+	}
+}
+
+func doctorCategoryTitle(category doctor.Category) string {
+	switch category {
+	case doctor.CategoryPrerequisites:
+		return "Prerequisites"
+	case doctor.CategoryCluster:
+		return "Cluster"
+	case doctor.CategoryRBAC:
+		return "RBAC"
+	case doctor.CategoryGitOps:
+		return "GitOps"
+	case doctor.CategoryCredentials:
+		return "Credentials"
+	}
+	return string(category)
+}
@@ -1,15 +1,20 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
 	"github.com/ihsanmokhlisse/gitopsi/internal/environment"
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
 )
 
 var (
@@ -23,6 +28,15 @@ var (
 	envFromEnv     string
 	envToEnv       string
 	envPromoteAll  bool
+	envApprove     bool
+	envRewrites    []string
+
+	envDeleteCascade   bool
+	envDeleteNamespace bool
+	envDeleteYes       bool
+	envDeleteForce     bool
+	envDeleteContext   string
+	envArgoCDNamespace string
 )
 
 var envCmd = &cobra.Command{
@@ -41,6 +55,8 @@ Examples:
   gitopsi env list                                       # List all environments
   gitopsi env show prod                                  # Show environment details
   gitopsi env add-cluster prod --url https://eu.k8s    # Add cluster to environment
+  gitopsi env diff dev prod                              # Compare two environments
+  gitopsi env clone staging --from prod                  # Copy an environment
   gitopsi promote myapp --from dev --to staging         # Promote application`,
 }
 
@@ -78,8 +94,26 @@ var envShowCmd = &cobra.Command{
 var envDeleteCmd = &cobra.Command{
 	Use:   "delete [environment]",
 	Short: "Delete an environment",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runEnvDelete,
+	Long: `Delete an environment from the environment config.
+
+With --cascade the environment is decommissioned: its live ArgoCD
+Applications and ApplicationSets are deleted with the resources they
+deployed, and its ArgoCD cluster registrations, overlays, HelmReleases and
+ArgoCD and Flux files are removed, as are its entries in the kustomizations
+of infrastructure/base and the multi-cluster ApplicationSets.
+--delete-namespace also deletes its namespace.
+
+The teardown is summarized and needs confirmation; production environments
+need their name typed. --dry-run only prints the summary, --yes skips the
+confirmation. Commit and push the removed files, or the App-of-Apps recreates
+the Applications.
+
+Examples:
+  gitopsi env delete qa
+  gitopsi env delete prod --cascade --dry-run
+  gitopsi env delete staging --cascade --delete-namespace --context hub`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEnvDelete,
 }
 
 var envAddClusterCmd = &cobra.Command{
@@ -101,14 +135,66 @@ var envRemoveClusterCmd = &cobra.Command{
 	RunE:  runEnvRemoveCluster,
 }
 
+var envDiffCmd = &cobra.Command{
+	Use:   "diff [environment] [environment]",
+	Short: "Show the differences between two environments",
+	Long: `Compare the overlays, HelmReleases and ArgoCD and Flux resources of two
+environments, file by file and value by value.
+
+Occurrences of the environment names are ignored, so the demo-dev and
+demo-prod namespaces of dev and prod are not reported. Added files and values
+exist only in the second environment, removed ones only in the first.
+
+Examples:
+  gitopsi env diff dev prod
+  gitopsi env diff staging prod --output json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runEnvDiff,
+}
+
+var envCloneCmd = &cobra.Command{
+	Use:   "clone [environment] --from [environment]",
+	Short: "Create an environment by copying another",
+	Long: `Create an environment from the overlays, HelmReleases and ArgoCD and Flux
+resources of an existing one.
+
+The source environment name is replaced by the new one in file names and
+content, so the demo-prod namespace becomes demo-staging. --namespace sets
+another namespace and --host rewrites ingress hosts or any other literal
+value. The new environment is added to the per-environment kustomizations of
+infrastructure/base and to the multi-cluster ApplicationSets.
+
+Examples:
+  gitopsi env clone staging --from prod
+  gitopsi env clone staging --from prod --namespace shop-staging
+  gitopsi env clone staging --from prod --host shop.example.com=staging.shop.example.com --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEnvClone,
+}
+
 var promoteCmd = &cobra.Command{
 	Use:   "promote [application]",
 	Short: "Promote application between environments",
 	Long: `Promote an application from one environment to another.
 
+The image overrides and patch files of the application's overlay in
+applications/overlays/<from>/ are copied to the target overlay, and the chart
+version and image values of its HelmRelease in applications/helmreleases/<to>/
+are updated. Each promotion is recorded in .gitopsi/promotions.yaml with the
+previous content of the files it changed.
+
+Promotions to environments marked protected in gitopsi.yaml must pass the
+gates of promotion.gates: a healthy source Application, manual approval
+(--approve), a minimum soak time in the source environment and validation.
+
+With images.pin_digests in gitopsi.yaml, the promoted images are pinned to
+the digest their tag points to at promotion time.
+
 Examples:
   gitopsi promote myapp --from dev --to staging
-  gitopsi promote --all --from staging --to prod`,
+  gitopsi promote --all --from staging --to prod
+  gitopsi promote myapp --from dev --to staging --dry-run
+  gitopsi promote myapp --from staging --to prod --approve --pr`,
 	RunE: runPromote,
 }
 
@@ -122,6 +208,8 @@ func init() {
 	envCmd.AddCommand(envDeleteCmd)
 	envCmd.AddCommand(envAddClusterCmd)
 	envCmd.AddCommand(envRemoveClusterCmd)
+	envCmd.AddCommand(envDiffCmd)
+	envCmd.AddCommand(envCloneCmd)
 
 	envCmd.PersistentFlags().StringVar(&envProjectPath, "project", ".", "Path to gitopsi project")
 	envCmd.PersistentFlags().StringVar(&envTopology, "topology", "", "Environment topology: namespace-based, cluster-per-env, multi-cluster")
@@ -143,10 +231,24 @@ func init() {
 	envRemoveClusterCmd.Flags().StringVar(&envClusterName, "name", "", "Cluster name to remove (required)")
 	_ = envRemoveClusterCmd.MarkFlagRequired("name")
 
+	envDeleteCmd.Flags().BoolVar(&envDeleteCascade, "cascade", false, "Decommission the environment: delete its live Applications and files")
+	envDeleteCmd.Flags().BoolVar(&envDeleteNamespace, "delete-namespace", false, "Also delete the environment's namespace (with --cascade)")
+	envDeleteCmd.Flags().BoolVarP(&envDeleteYes, "yes", "y", false, "Skip the confirmation, also for production environments")
+	envDeleteCmd.Flags().BoolVar(&envDeleteForce, "force", false, "Continue when deleting a live resource fails")
+	envDeleteCmd.Flags().StringVar(&envDeleteContext, "context", "", "Kubernetes context of the ArgoCD cluster")
+	envDeleteCmd.Flags().StringVar(&envArgoCDNamespace, "argocd-namespace", "", "Namespace of the ArgoCD Applications (default: from gitopsi.yaml or argocd)")
+
+	envCloneCmd.Flags().StringVar(&envFromEnv, "from", "", "Environment to copy (required)")
+	envCloneCmd.Flags().StringVar(&envNamespace, "namespace", "", "Namespace of the new environment")
+	envCloneCmd.Flags().StringArrayVar(&envRewrites, "host", nil, "Replace a host or other value: old=new (repeatable)")
+	_ = envCloneCmd.MarkFlagRequired("from")
+
 	promoteCmd.Flags().StringVar(&envFromEnv, "from", "", "Source environment (required)")
 	promoteCmd.Flags().StringVar(&envToEnv, "to", "", "Target environment (required)")
 	promoteCmd.Flags().BoolVar(&envPromoteAll, "all", false, "Promote all applications")
+	promoteCmd.Flags().BoolVar(&envApprove, "approve", false, "Approve a promotion to a protected environment")
 	promoteCmd.Flags().StringVar(&envProjectPath, "project", ".", "Path to gitopsi project")
+	addPullRequestFlags(promoteCmd)
 	_ = promoteCmd.MarkFlagRequired("from")
 	_ = promoteCmd.MarkFlagRequired("to")
 }
@@ -176,6 +278,7 @@ func runEnvCreate(cmd *cobra.Command, args []string) error {
 	}
 
 	envNames := strings.Split(args[0], ",")
+	results := []envActionResult{}
 
 	if envTopology != "" {
 		topology, parseErr := environment.ParseTopology(envTopology)
@@ -213,13 +316,18 @@ func runEnvCreate(cmd *cobra.Command, args []string) error {
 		}
 
 		if createErr := mgr.CreateEnvironment(name, opts); createErr != nil {
+			results = append(results, envActionResult{Action: "create", Environment: name, Error: createErr.Error()})
 			pterm.Error.Printf("Failed to create environment %s: %v\n", name, createErr)
 			continue
 		}
 
+		results = append(results, envActionResult{Action: "create", Environment: name})
 		pterm.Success.Printf("Created environment: %s\n", name)
 	}
 
+	if p := newPrinter(); p.structured() {
+		return p.print(results)
+	}
 	return nil
 }
 
@@ -230,6 +338,9 @@ func runEnvList(cmd *cobra.Command, args []string) error {
 	}
 
 	envs := mgr.ListEnvironments()
+	if p := newPrinter(); p.structured() {
+		return p.print(envListResult{Topology: mgr.Config().Topology, Environments: append([]*environment.Environment{}, envs...)})
+	}
 	if len(envs) == 0 {
 		pterm.Info.Println("No environments configured")
 		return nil
@@ -279,6 +390,9 @@ func runEnvShow(cmd *cobra.Command, args []string) error {
 	if env == nil {
 		return fmt.Errorf("environment %s not found", envName)
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(env)
+	}
 
 	pterm.DefaultSection.Printf("Environment: %s\n", env.Name)
 
@@ -318,14 +432,138 @@ func runEnvDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	envName := args[0]
+	if envDeleteNamespace && !envDeleteCascade {
+		return fmt.Errorf("--delete-namespace requires --cascade")
+	}
+	if envDeleteCascade {
+		return decommissionEnvironment(cmd.Context(), mgr, envName)
+	}
 	if deleteErr := mgr.DeleteEnvironment(envName); deleteErr != nil {
 		return deleteErr
 	}
 
 	pterm.Success.Printf("Deleted environment: %s\n", envName)
+	return printEnvAction(envActionResult{Action: "delete", Environment: envName})
+}
+
+// decommissionEnvironment shows the teardown of an environment and, once
+// confirmed, deletes its live ArgoCD resources and files.
+func decommissionEnvironment(ctx context.Context, mgr *environment.Manager, envName string) error {
+	cfg, err := loadProjectConfig(envProjectPath)
+	if err != nil {
+		return err
+	}
+	argoCDNamespace := envArgoCDNamespace
+	if argoCDNamespace == "" {
+		argoCDNamespace = "argocd"
+		if cfg != nil {
+			argoCDNamespace = promotionArgoCDNamespace(cfg)
+		}
+	}
+	namespaceContext := envDeleteContext
+	if cfg != nil {
+		if e := cfg.GetEnvironment(envName); e != nil && e.Context != "" {
+			namespaceContext = e.Context
+		}
+	}
+
+	opts := environment.DecommissionOptions{
+		Force: envDeleteForce,
+		DeleteApplication: func(ctx context.Context, kind, name string) error {
+			if kind == "ApplicationSet" {
+				return environment.DeleteArgoCDApplicationSet(ctx, envDeleteContext, argoCDNamespace, name)
+			}
+			return environment.DeleteArgoCDApplication(ctx, envDeleteContext, argoCDNamespace, name, true)
+		},
+		DeleteCluster: func(ctx context.Context, name string) error {
+			return environment.DeleteArgoCDCluster(ctx, envDeleteContext, argoCDNamespace, name)
+		},
+	}
+	if envDeleteNamespace {
+		opts.DeleteNamespace = func(ctx context.Context, name string) error {
+			return environment.DeleteNamespace(ctx, namespaceContext, name)
+		}
+	}
+
+	planOpts := opts
+	planOpts.DryRun = true
+	plan, err := mgr.DecommissionEnvironment(ctx, envName, planOpts)
+	if err != nil {
+		return err
+	}
+	p := newPrinter()
+	if dryRun {
+		if p.structured() {
+			return p.print(plan)
+		}
+		printDecommission(plan)
+		pterm.Warning.Println("DRY RUN - No changes made")
+		return nil
+	}
+	if !p.structured() {
+		printDecommission(plan)
+	}
+	if err := confirmDecommission(p, envName); err != nil {
+		return err
+	}
+
+	result, err := mgr.DecommissionEnvironment(ctx, envName, opts)
+	if err != nil {
+		return err
+	}
+	if p.structured() {
+		return p.print(result)
+	}
+	for _, warning := range result.Warnings {
+		pterm.Warning.Println(warning)
+	}
+	pterm.Success.Printf("Decommissioned environment: %s\n", envName)
+	pterm.Info.Println("Commit and push the removed files to complete the teardown")
+	return nil
+}
+
+// confirmDecommission asks before tearing an environment down unless --yes is
+// set. Production environments need their name typed.
+func confirmDecommission(p *printer, envName string) error {
+	if envDeleteYes {
+		return nil
+	}
+	if p.structured() {
+		return fmt.Errorf("decommissioning environment %s needs confirmation: pass --yes", envName)
+	}
+	if productionName.MatchString(envName) {
+		typed, _ := pterm.DefaultInteractiveTextInput.Show(fmt.Sprintf("Type %s to decommission this production environment", envName))
+		if strings.TrimSpace(typed) != envName {
+			return fmt.Errorf("decommission cancelled: the environment name did not match")
+		}
+		return nil
+	}
+	confirmed, _ := pterm.DefaultInteractiveConfirm.Show(fmt.Sprintf("Decommission environment %s?", envName))
+	if !confirmed {
+		return fmt.Errorf("decommission cancelled: pass --yes to skip the confirmation")
+	}
 	return nil
 }
 
+func printDecommission(plan *environment.DecommissionResult) {
+	pterm.DefaultSection.Printf("Decommissioning %s\n", plan.Environment)
+	for _, app := range plan.Applications {
+		pterm.Info.Printf("  - delete %s and its resources\n", app)
+	}
+	for _, namespace := range plan.Namespaces {
+		pterm.Info.Printf("  - delete namespace %s\n", namespace)
+	}
+	for _, cluster := range plan.Clusters {
+		pterm.Info.Printf("  - unregister cluster %s from ArgoCD\n", cluster)
+	}
+	for _, file := range plan.Removed {
+		pterm.Info.Printf("  - remove %s\n", file)
+	}
+	for _, file := range plan.Updated {
+		pterm.Info.Printf("  ~ update %s\n", file)
+	}
+}
+
 func runEnvAddCluster(cmd *cobra.Command, args []string) error {
 	mgr, err := getEnvManager()
 	if err != nil {
@@ -347,7 +585,7 @@ func runEnvAddCluster(cmd *cobra.Command, args []string) error {
 	}
 
 	pterm.Success.Printf("Added cluster %s to environment %s\n", envClusterName, envName)
-	return nil
+	return printEnvAction(envActionResult{Action: "add-cluster", Environment: envName, Cluster: envClusterName})
 }
 
 func runEnvRemoveCluster(cmd *cobra.Command, args []string) error {
@@ -363,9 +601,93 @@ func runEnvRemoveCluster(cmd *cobra.Command, args []string) error {
 	}
 
 	pterm.Success.Printf("Removed cluster %s from environment %s\n", envClusterName, envName)
+	return printEnvAction(envActionResult{Action: "remove-cluster", Environment: envName, Cluster: envClusterName})
+}
+
+func runEnvDiff(cmd *cobra.Command, args []string) error {
+	mgr, err := getEnvManager()
+	if err != nil {
+		return err
+	}
+
+	diff, err := mgr.Diff(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if p := newPrinter(); p.structured() {
+		return p.print(diff)
+	}
+	if len(diff.Files) == 0 {
+		pterm.Success.Printf("Environments %s and %s do not differ\n", diff.From, diff.To)
+		return nil
+	}
+
+	pterm.DefaultSection.Printf("Differences from %s to %s\n", diff.From, diff.To)
+	tableData := pterm.TableData{{"File", "Field", "Status", diff.From, diff.To}}
+	for _, file := range diff.Files {
+		if len(file.Values) == 0 {
+			tableData = append(tableData, []string{file.Path, "-", file.Status, "", ""})
+			continue
+		}
+		for _, value := range file.Values {
+			tableData = append(tableData, []string{file.Path, value.Field, value.Status, value.From, value.To})
+		}
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return nil
+}
+
+func runEnvClone(cmd *cobra.Command, args []string) error {
+	mgr, err := getEnvManager()
+	if err != nil {
+		return err
+	}
+
+	rewrites, err := parseRewrites(envRewrites)
+	if err != nil {
+		return err
+	}
+	result, err := mgr.CloneEnvironment(args[0], envFromEnv, environment.CloneOptions{
+		Namespace: envNamespace,
+		Rewrites:  rewrites,
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	if p := newPrinter(); p.structured() {
+		return p.print(result)
+	}
+
+	if dryRun {
+		pterm.Warning.Println("DRY RUN - No changes made")
+	}
+	pterm.Success.Printf("Cloned environment %s from %s\n", result.Environment, result.From)
+	if result.Namespace != "" {
+		pterm.Info.Printf("Namespace: %s\n", result.Namespace)
+	}
+	for _, file := range result.Files {
+		pterm.Info.Printf("  + %s\n", file)
+	}
+	for _, file := range result.Updated {
+		pterm.Info.Printf("  ~ %s\n", file)
+	}
 	return nil
 }
 
+// parseRewrites parses old=new values of --host.
+func parseRewrites(values []string) ([]environment.Rewrite, error) {
+	var rewrites []environment.Rewrite
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid rewrite %q: expected old=new", value)
+		}
+		rewrites = append(rewrites, environment.Rewrite{From: from, To: to})
+	}
+	return rewrites, nil
+}
+
 func runPromote(cmd *cobra.Command, args []string) error {
 	mgr, err := getEnvManager()
 	if err != nil {
@@ -381,16 +703,47 @@ func runPromote(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("specify an application name or use --all")
 	}
 
+	cfg, err := loadProjectConfig(envProjectPath)
+	if err != nil {
+		return err
+	}
+	gates, err := promotionGates(cfg, envFromEnv, envToEnv)
+	if err != nil {
+		return err
+	}
+
 	opts := environment.PromotionOptions{
 		Application: appName,
 		FromEnv:     envFromEnv,
 		ToEnv:       envToEnv,
 		All:         envPromoteAll,
 		DryRun:      dryRun,
+		Gates:       gates,
+	}
+	if cfg.Images.PinDigests {
+		opts.PinImages = imagePinner()
 	}
 
-	result, promoteErr := mgr.Promote(opts)
+	result, promoteErr := mgr.PromoteContext(cmd.Context(), opts)
+	if p := newPrinter(); p.structured() && result != nil {
+		// The rest of the command only prints with pterm, silenced in
+		// structured mode.
+		if err := p.print(result); err != nil {
+			return err
+		}
+	}
+	if result != nil && len(result.Gates) > 0 {
+		printGateResults(result.Gates)
+	}
 	if promoteErr != nil {
+		if errors.Is(promoteErr, environment.ErrPromotionBlocked) {
+			for _, g := range result.Gates {
+				if g.Gate == "approval" && !g.Passed {
+					pterm.Info.Println("Approve the promotion with: --approve")
+					break
+				}
+			}
+		}
 		return promoteErr
 	}
 
@@ -408,5 +761,156 @@ func runPromote(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	for _, record := range result.Records {
+		pterm.Info.Printf("Recorded promotion %s in %s\n", record.ID, environment.PromotionHistoryFile)
+	}
+
+	if openPR && !dryRun && len(result.Changes) > 0 {
+		pterm.Println()
+		subject := appName
+		if envPromoteAll {
+			subject = "all applications"
+		}
+		return deliverPullRequest(cmd.Context(), envProjectPath, fmt.Sprintf("chore: Promote %s from %s to %s", subject, envFromEnv, envToEnv))
+	}
+
 	return nil
 }
+
+// envListResult is the structured output of env list.
+type envListResult struct {
+	Topology     environment.Topology       `json:"topology" yaml:"topology"`
+	Environments []*environment.Environment `json:"environments" yaml:"environments"`
+}
+
+// envActionResult is the structured output of the commands changing
+// environments.
+type envActionResult struct {
+	Action      string `json:"action" yaml:"action"`
+	Environment string `json:"environment" yaml:"environment"`
+	Cluster     string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func printEnvAction(result envActionResult) error {
+	if p := newPrinter(); p.structured() {
+		return p.print(result)
+	}
+	return nil
+}
+
+// loadProjectConfig reads the gitopsi.yaml of a project, or --config. It
+// returns nil when the project has none.
+func loadProjectConfig(projectPath string) (*config.Config, error) {
+	path := cfgFile
+	if path == "" {
+		path = filepath.Join(projectPath, "gitopsi.yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// promotionGates returns the gates of a promotion to the environment to, or
+// nil when it is not protected.
+func promotionGates(cfg *config.Config, from, to string) ([]environment.Gate, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if env := cfg.GetEnvironment(to); env == nil || !env.Protected {
+		return nil, nil
+	}
+
+	settings := cfg.Promotion.Gates
+	if !settings.Enabled() {
+		settings.Approval = true
+	}
+
+	var gates []environment.Gate
+	if settings.SourceHealthy {
+		gate := &environment.HealthGate{
+			Application: argoCDApplicationPattern(cfg),
+			Namespace:   promotionArgoCDNamespace(cfg),
+		}
+		if source := cfg.GetEnvironment(from); source != nil {
+			gate.KubeContext = source.Context
+		}
+		gates = append(gates, gate)
+	}
+	if settings.SoakTime != "" {
+		duration, err := time.ParseDuration(settings.SoakTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid promotion.gates.soak_time: %w", err)
+		}
+		gates = append(gates, &environment.SoakTimeGate{Duration: duration})
+	}
+	if settings.Validation {
+		gates = append(gates, &environment.ValidationGate{Validate: func(ctx context.Context) error {
+			return validatePromotion(ctx, cfg)
+		}})
+	}
+	if settings.Approval {
+		gates = append(gates, &environment.ApprovalGate{Approved: envApprove})
+	}
+	return gates, nil
+}
+
+// argoCDApplicationPattern returns the name of the ArgoCD Application
+// deploying an environment, with {app} and {env} left to replace.
+func argoCDApplicationPattern(cfg *config.Config) string {
+	pattern := cfg.Promotion.Gates.ArgoCDApplication
+	if pattern == "" {
+		pattern = "{project}-apps-{env}"
+	}
+	return strings.ReplaceAll(pattern, "{project}", cfg.Project.Name)
+}
+
+// promotionArgoCDNamespace returns the namespace ArgoCD runs in.
+func promotionArgoCDNamespace(cfg *config.Config) string {
+	if cfg.Bootstrap.Namespace != "" {
+		return cfg.Bootstrap.Namespace
+	}
+	if cfg.Platform == "openshift" {
+		return "openshift-gitops"
+	}
+	return "argocd"
+}
+
+// validatePromotion runs the validation gate: the checks of `gitopsi validate`
+// on the project, failing on high severity issues.
+func validatePromotion(ctx context.Context, cfg *config.Config) error {
+	opts := validate.DefaultOptions()
+	opts.Path = envProjectPath
+	if cfg.Version.Kubernetes != "" {
+		opts.K8sVersion = cfg.Version.Kubernetes
+	}
+	validator := validate.New(opts)
+	result, err := validator.Validate(ctx)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	if validator.ShouldFail(result) {
+		return fmt.Errorf("validation failed with %d issues (run gitopsi validate %s)", result.Failed, envProjectPath)
+	}
+	return nil
+}
+
+func printGateResults(results []environment.GateResult) {
+	pterm.Info.Println("Promotion gates:")
+	for _, r := range results {
+		if r.Passed {
+			pterm.Success.Printf("  %s (%s)\n", r.Gate, r.Application)
+		} else {
+			pterm.Error.Printf("  %s (%s): %s\n", r.Gate, r.Application, r.Message)
+		}
+	}
+	pterm.Println()
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/environment"
+)
+
+func TestPromotionGates(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "shop"
+	cfg.Environments = []config.Environment{
+		{Name: "staging", Context: "staging-cluster"},
+		{Name: "prod", Protected: true},
+	}
+
+	gates, err := promotionGates(cfg, "prod", "staging")
+	require.NoError(t, err)
+	assert.Empty(t, gates, "unprotected environments have no gates")
+
+	gates, err = promotionGates(nil, "staging", "prod")
+	require.NoError(t, err)
+	assert.Empty(t, gates)
+
+	// Protected environments require approval by default.
+	gates, err = promotionGates(cfg, "staging", "prod")
+	require.NoError(t, err)
+	require.Len(t, gates, 1)
+	assert.Equal(t, "approval", gates[0].Name())
+
+	cfg.Promotion.Gates = config.PromotionGates{SourceHealthy: true, SoakTime: "24h", Validation: true}
+	gates, err = promotionGates(cfg, "staging", "prod")
+	require.NoError(t, err)
+	require.Len(t, gates, 3)
+	health, ok := gates[0].(*environment.HealthGate)
+	require.True(t, ok)
+	assert.Equal(t, "shop-apps-{env}", health.Application)
+	assert.Equal(t, "argocd", health.Namespace)
+	assert.Equal(t, "staging-cluster", health.KubeContext)
+	soak, ok := gates[1].(*environment.SoakTimeGate)
+	require.True(t, ok)
+	assert.Equal(t, 24*time.Hour, soak.Duration)
+	assert.Equal(t, "validation", gates[2].Name())
+}
+
+func TestRollbackApplication(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "shop"
+	cfg.Environments = []config.Environment{{Name: "prod", Context: "prod-cluster"}}
+
+	name, namespace, kubeContext, err := rollbackApplication(cfg, "api", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "shop-apps-prod", name)
+	assert.Equal(t, "argocd", namespace)
+	assert.Equal(t, "prod-cluster", kubeContext)
+
+	cfg.Promotion.Gates.ArgoCDApplication = "{project}-{app}-{env}"
+	name, _, _, err = rollbackApplication(cfg, "api", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "shop-api-prod", name)
+
+	_, _, _, err = rollbackApplication(nil, "api", "prod")
+	assert.Error(t, err)
+}
+
+func TestParseRewrites(t *testing.T) {
+	rewrites, err := parseRewrites([]string{"shop.example.com=staging.shop.example.com", "a=b=c"})
+	require.NoError(t, err)
+	assert.Equal(t, []environment.Rewrite{
+		{From: "shop.example.com", To: "staging.shop.example.com"},
+		{From: "a", To: "b=c"},
+	}, rewrites)
+
+	_, err = parseRewrites([]string{"shop.example.com"})
+	assert.Error(t, err)
+	_, err = parseRewrites([]string{"=new"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package cli
+
+import "errors"
+
+// Process exit codes for commands whose result is consumed by CI.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	// ExitDrift signals that a command completed but found differences.
+	ExitDrift = 2
+)
+
+// exitError carries a specific process exit code alongside an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so that the process exits with code.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return ExitError
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/export/terraform"
+	outputpkg "github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+var (
+	exportFlavor          string
+	exportResources       string
+	exportModuleDir       string
+	exportArgoCDNamespace string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export project configuration to other formats",
+	Long: `Export a gitopsi project definition into other tooling formats.
+
+Examples:
+  gitopsi export terraform --config gitops.yaml
+  gitopsi export terraform --config gitops.yaml --flavor opentofu
+  gitopsi export terraform --config gitops.yaml --resources namespaces,argocd`,
+}
+
+var exportTerraformCmd = &cobra.Command{
+	Use:   "terraform",
+	Short: "Export as a Terraform/OpenTofu module",
+	Long: `Convert a gitopsi config into an equivalent Terraform/OpenTofu module.
+
+Resource types:
+  namespaces  - kubernetes_namespace per environment
+  argocd      - argocd_project and argocd_application resources
+  helm        - helm_release installing the GitOps tool
+
+Examples:
+  gitopsi export terraform --config gitops.yaml
+  gitopsi export terraform --config gitops.yaml --module-dir infra/tf
+  gitopsi export terraform --config gitops.yaml --flavor opentofu --resources helm`,
+	RunE: runExportTerraform,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportTerraformCmd)
+
+	exportTerraformCmd.Flags().StringVar(&exportFlavor, "flavor", "terraform", "Target tool: terraform, opentofu")
+	exportTerraformCmd.Flags().StringVar(&exportResources, "resources", "all", "Resource types to export: namespaces, argocd, helm (comma-separated)")
+	exportTerraformCmd.Flags().StringVar(&exportModuleDir, "module-dir", "terraform", "Directory for the generated module, relative to --output-dir")
+	exportTerraformCmd.Flags().StringVar(&exportArgoCDNamespace, "argocd-namespace", "", "Override the ArgoCD namespace")
+}
+
+func runExportTerraform(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "gitops.yaml"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !terraform.IsValidFlavor(exportFlavor) {
+		return fmt.Errorf("invalid flavor: %s (valid: terraform, opentofu)", exportFlavor)
+	}
+
+	resources, err := terraform.ParseResources(exportResources)
+	if err != nil {
+		return err
+	}
+
+	exporter := terraform.New(cfg, terraform.Options{
+		Flavor:          terraform.Flavor(exportFlavor),
+		Resources:       resources,
+		ArgoCDNamespace: exportArgoCDNamespace,
+	})
+
+	files, err := exporter.Export()
+	if err != nil {
+		return fmt.Errorf("failed to export terraform: %w", err)
+	}
+
+	fmt.Printf("📦 Exporting %s module for %s...\n", exportFlavor, cfg.Project.Name)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writer := outputpkg.New(GetOutput(), dryRun, verbose)
+	for _, name := range names {
+		if err := writer.WriteFile(filepath.Join(exportModuleDir, name), files[name]); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		fmt.Println("✅ Dry run complete - no files written")
+		return nil
+	}
+
+	fmt.Printf("✅ Exported %d files to %s\n", len(names), filepath.Join(GetOutput(), exportModuleDir))
+	return nil
+}
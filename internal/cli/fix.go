@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/fix"
+	"github.com/ihsanmokhlisse/gitopsi/internal/upgrade"
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
+)
+
+var (
+	fixRules []string
+	fixDiff  bool
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [path]",
+	Short: "Apply the suggested fixes of validate findings",
+	Long: `Run the security and deprecation checks of gitopsi validate and rewrite
+the manifests with the findings that have a known fix, keeping comments:
+
+  DEP001  migrate deprecated apiVersions of workloads, RBAC and NetworkPolicies
+  SEC002  set runAsNonRoot: true
+  SEC003  add resource requests and limits (validation.default_resources)
+  SEC007  set the RuntimeDefault seccomp profile
+  SEC008  drop ALL capabilities
+
+Findings that need a decision, such as privileged containers, hostPath
+volumes, image tags or Ingress migrations, are left unchanged: gitopsi
+validate lists them. Resources a fix cannot change safely, such as a
+Deployment without a selector, are listed to fix by hand. With --dry-run the
+diff is printed and no file is written.
+
+Examples:
+  gitopsi fix ./my-platform/ --dry-run       # Show the diff of every fix
+  gitopsi fix ./my-platform/                 # Apply every fix
+  gitopsi fix ./my-platform/ --rule SEC003   # Only add resource limits`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFix,
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+
+	fixCmd.Flags().StringSliceVar(&fixRules, "rule", nil, "Rule to fix (repeatable, default: every rule with a fix)")
+	fixCmd.Flags().BoolVar(&fixDiff, "diff", false, "Print the diff of the fixed files")
+}
+
+// fixOutput is the structured output of fix.
+type fixOutput struct {
+	fix.Result `yaml:",inline"`
+	DryRun     bool `json:"dry_run" yaml:"dry_run"`
+}
+
+func runFix(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadProjectConfig(path)
+	if err != nil {
+		return err
+	}
+
+	opts := &validate.Options{
+		Path:        path,
+		Security:    true,
+		Deprecation: true,
+		CacheDir:    filepath.Join(path, validate.DefaultCacheDir),
+	}
+	if cfg != nil {
+		opts.DisabledChecks = cfg.Validation.DisabledChecks
+	}
+	result, err := validate.New(opts).Validate(ctx)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	fixes, err := planFixes(result.Issues, cfg)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		if err := fixes.Write(); err != nil {
+			return err
+		}
+	}
+
+	p := newPrinter()
+	if p.structured() {
+		return p.print(fixOutput{Result: *fixes, DryRun: dryRun})
+	}
+	printFixes(fixes, dryRun || fixDiff)
+	switch {
+	case len(fixes.Files) == 0:
+		pterm.Info.Println("No fixes to apply")
+	case dryRun:
+		pterm.Info.Printf("Would apply %d fixes to %d files\n", fixes.Changes(), len(fixes.Files))
+	default:
+		pterm.Success.Printf("Applied %d fixes to %d files\n", fixes.Changes(), len(fixes.Files))
+	}
+	return nil
+}
+
+// planFixes computes the fixes of the issues with the fix rules and the
+// default resources of the project.
+func planFixes(issues []validate.Issue, cfg *config.Config) (*fix.Result, error) {
+	opts := fix.Options{Rules: fixRules}
+	if cfg != nil {
+		opts.Resources = cfg.Validation.DefaultResources
+	}
+	return fix.Plan(issues, opts)
+}
+
+func printFixes(fixes *fix.Result, diff bool) {
+	for _, f := range fixes.Files {
+		pterm.DefaultSection.Println(f.Path)
+		for _, change := range f.Changes {
+			fmt.Printf("  • %s %s: %s\n", change.Rule, change.Resource, change.Description)
+		}
+		if diff {
+			fmt.Print(upgrade.Diff(f.Path, f.Original, f.Content))
+		}
+	}
+	if len(fixes.Skipped) == 0 {
+		return
+	}
+	pterm.DefaultSection.Println("Fix by hand")
+	for _, s := range fixes.Skipped {
+		pterm.Warning.Printf("%s %s (%s): %s\n", s.Rule, s.Resource, s.File, s.Reason)
+	}
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
-	"strings"
 	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
 	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
 )
 
@@ -80,13 +80,10 @@ func getArgoCDPassword(ctx context.Context, namespace string) (string, error) {
 		return "", fmt.Errorf("failed to get ArgoCD password: %w\nMake sure you have access to the cluster and ArgoCD is installed", err)
 	}
 
-	// Decode base64
-	decodeCmd := exec.CommandContext(ctx, "bash", "-c",
-		fmt.Sprintf("echo %s | base64 -d", strings.TrimSpace(string(output))))
-	decoded, err := decodeCmd.CombinedOutput()
+	password, err := bootstrap.DecodeSecretValue(string(output))
 	if err != nil {
 		return "", fmt.Errorf("failed to decode password: %w", err)
 	}
 
-	return strings.TrimSpace(string(decoded)), nil
+	return password, nil
 }
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/graph"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [path]",
+	Short: "Render the application and pattern dependency graph of a repository",
+	Long: `Build the dependency graph of a GitOps repository from its manifests and
+pattern state: AppProjects, the Applications, ApplicationSets and Flux
+Kustomizations they hold, the paths and Helm charts these deploy, the
+installed patterns owning the paths (.gitopsi/patterns.yaml) and the patterns
+these depend on (.gitopsi/patterns.lock.yaml).
+
+The graph is printed, or written to --file, as Graphviz DOT, a Mermaid
+flowchart, a Markdown document holding the Mermaid flowchart for
+architecture docs, or an SVG image. The format follows the extension of
+--file (.dot, .mmd, .md, .svg) unless --format is set.
+
+Examples:
+  gitopsi graph
+  gitopsi graph ./my-platform --format dot | dot -Tpng -o graph.png
+  gitopsi graph --file docs/architecture/graph.md
+  gitopsi graph --file graph.svg
+  gitopsi graph -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runGraph,
+}
+
+var (
+	graphFormat string
+	graphFile   string
+)
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringVar(&graphFormat, "format", "", "Graph format: dot, mermaid, md, svg (default: from the --file extension, or mermaid)")
+	graphCmd.Flags().StringVar(&graphFile, "file", "", "File to write the graph to (default: standard output)")
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	format, err := graphOutputFormat(graphFormat, graphFile)
+	if err != nil {
+		return err
+	}
+
+	g, err := graph.Build(path)
+	if err != nil {
+		return fmt.Errorf("failed to build graph: %w", err)
+	}
+	p := newPrinter()
+	if p.structured() {
+		return p.print(g)
+	}
+
+	out, err := g.Render(format, graphTitle(path))
+	if err != nil {
+		return err
+	}
+	if graphFile == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if dryRun {
+		pterm.Info.Printf("Would write the %s graph of %d nodes and %d edges to %s\n", format, len(g.Nodes), len(g.Edges), graphFile)
+		return nil
+	}
+	if dir := filepath.Dir(graphFile); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(graphFile, out, 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	pterm.Success.Printf("Wrote the %s graph of %d nodes and %d edges to %s\n", format, len(g.Nodes), len(g.Edges), graphFile)
+	return nil
+}
+
+// graphOutputFormat returns the format of --format, or of the extension of
+// the --file.
+func graphOutputFormat(format, file string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".dot", ".gv":
+			format = "dot"
+		case ".md", ".markdown":
+			format = "md"
+		case ".svg":
+			format = "svg"
+		default:
+			format = "mermaid"
+		}
+	}
+	format = strings.ToLower(format)
+	if !slices.Contains(graph.Formats, format) {
+		return "", fmt.Errorf("unsupported graph format: %s (use %s)", format, strings.Join(graph.Formats, ", "))
+	}
+	return format, nil
+}
+
+// graphTitle is the title of the Markdown graph: the project name of the
+// gitopsi.yaml of the repository, or the name of its directory.
+func graphTitle(path string) string {
+	name := ""
+	if cfg, err := loadProjectConfig(path); err == nil && cfg != nil {
+		name = cfg.Project.Name
+	}
+	if name == "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			name = filepath.Base(abs)
+		}
+	}
+	return name + " dependency graph"
+}
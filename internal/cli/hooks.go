@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/hooks"
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage Git hooks validating manifests before commit and push",
+	Long: `Install Git hooks that run gitopsi validate and a secret leak scan
+before changes leave your machine:
+
+  • pre-commit validates the staged manifests and scans the staged files
+  • pre-push validates and scans the whole repository
+
+Findings are allowlisted in .gitopsi/secrets-allowlist.yaml (see
+gitopsi scan secrets --help).
+
+Examples:
+  gitopsi hooks install                    # .git/hooks/pre-commit and pre-push
+  gitopsi hooks install --pre-commit       # .pre-commit-config.yaml instead
+  gitopsi hooks run pre-commit             # What the hook runs`,
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the pre-commit and pre-push hooks",
+	Args:  cobra.NoArgs,
+	RunE:  runHooksInstall,
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:   "run <pre-commit|pre-push> [files...]",
+	Short: "Run a hook on the staged manifests, the repository or the given files",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runHooksRun,
+}
+
+var (
+	hooksPath      string
+	hooksPreCommit bool
+	hooksForce     bool
+	hooksFailOn    string
+	hooksSchemaLoc []string
+)
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksRunCmd)
+
+	hooksCmd.PersistentFlags().StringVar(&hooksPath, "path", ".", "Path to the Git repository")
+	hooksInstallCmd.Flags().BoolVar(&hooksPreCommit, "pre-commit", false, "Write a .pre-commit-config.yaml for the pre-commit framework")
+	hooksInstallCmd.Flags().BoolVar(&hooksForce, "force", false, "Overwrite hooks not installed by gitopsi")
+	hooksRunCmd.Flags().StringVar(&hooksFailOn, "fail-on", "high", "Fail on severity: critical, high, medium, low")
+	hooksRunCmd.Flags().StringSliceVar(&hooksSchemaLoc, "schema-location", nil, "Schema registry URL or path template (repeatable)")
+}
+
+func runHooksInstall(cmd *cobra.Command, args []string) error {
+	mode := hooks.ModeGit
+	if hooksPreCommit {
+		mode = hooks.ModePreCommit
+	}
+	files, err := hooks.Install(&hooks.InstallOptions{Dir: hooksPath, Mode: mode, Force: hooksForce})
+	if err != nil {
+		return err
+	}
+
+	if p := newPrinter(); p.structured() {
+		return p.print(map[string]any{"mode": mode, "files": files})
+	}
+	for _, f := range files {
+		pterm.Success.Printfln("Installed %s", f)
+	}
+	if mode == hooks.ModePreCommit {
+		pterm.Info.Println("Enable it with: pre-commit install --hook-type pre-commit --hook-type pre-push")
+	}
+	return nil
+}
+
+func runHooksRun(cmd *cobra.Command, args []string) error {
+	failOn, err := parseSeverity(hooksFailOn)
+	if err != nil {
+		return err
+	}
+
+	result, err := hooks.Run(cmd.Context(), &hooks.RunOptions{
+		Dir:             hooksPath,
+		Hook:            args[0],
+		Files:           args[1:],
+		FailOn:          failOn,
+		SchemaLocations: hooksSchemaLoc,
+	})
+	if err != nil {
+		return err
+	}
+
+	if p := newPrinter(); p.structured() {
+		if err := p.print(result); err != nil {
+			return err
+		}
+	} else {
+		printHookResult(args[0], result)
+	}
+
+	if result.Failed() {
+		return fmt.Errorf("%s hook failed: fix the issues above or bypass with --no-verify", args[0])
+	}
+	return nil
+}
+
+func printHookResult(hook string, result *hooks.Result) {
+	if len(result.Files) == 0 && !result.Repository {
+		pterm.Info.Printfln("gitopsi %s: no files to check", hook)
+		return
+	}
+	for _, issue := range result.Issues {
+		pterm.Error.Printfln("%s: [%s] %s: %s", issue.File, strings.ToUpper(string(issue.Severity)), issue.Rule, issue.Message)
+	}
+	printLeaks(result.Leaks)
+	if result.Failed() {
+		return
+	}
+	if result.Repository {
+		pterm.Success.Printfln("gitopsi %s: repository checked", hook)
+	} else {
+		pterm.Success.Printfln("gitopsi %s: %d files checked", hook, len(result.Files))
+	}
+}
+
+// parseSeverity converts a --fail-on value to a validation severity.
+func parseSeverity(s string) (validate.Severity, error) {
+	switch strings.ToLower(s) {
+	case "critical":
+		return validate.SeverityCritical, nil
+	case "high":
+		return validate.SeverityHigh, nil
+	case "medium":
+		return validate.SeverityMedium, nil
+	case "low":
+		return validate.SeverityLow, nil
+	}
+	return "", fmt.Errorf("invalid severity: %s (valid: critical, high, medium, low)", s)
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Pin container images by digest",
+	Long: `Resolve image tags to digests with their container registry and pin the
+images of a GitOps repository, so every environment runs exactly the image
+that was reviewed.
+
+Container images, the images entries of kustomizations and the image values
+of HelmReleases are rewritten in place, keeping comments and formatting.
+Private registries (Docker Hub, GHCR, Quay, ECR, ACR, GCR) are accessed with
+the credentials stored with: gitopsi auth add registry`,
+}
+
+var imagesPinCmd = &cobra.Command{
+	Use:   "pin [path]",
+	Short: "Pin the images without a digest",
+	Long: `Pin the images of the repository that do not have a digest yet. Pinned
+images keep their tag for reference: nginx:1.27 becomes nginx:1.27@sha256:...
+
+Examples:
+  gitopsi images pin
+  gitopsi images pin ./my-platform/applications --dry-run`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImages(false),
+}
+
+var imagesUpdateCmd = &cobra.Command{
+	Use:   "update [path]",
+	Short: "Re-resolve the tags of pinned images",
+	Long: `Resolve the tag of every pinned image again and update its digest when the
+tag now points to a different image, e.g. after a rebuild of a mutable tag or
+a base image security patch.
+
+Examples:
+  gitopsi images update
+  gitopsi images update ./my-platform --dry-run -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImages(true),
+}
+
+func init() {
+	rootCmd.AddCommand(imagesCmd)
+	imagesCmd.AddCommand(imagesPinCmd)
+	imagesCmd.AddCommand(imagesUpdateCmd)
+}
+
+func runImages(update bool) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		report, err := images.Run(cmd.Context(), &images.Options{
+			Path:     path,
+			Update:   update,
+			DryRun:   dryRun,
+			Resolver: newImageResolver(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if p := newPrinter(); p.structured() {
+			return p.print(report)
+		}
+
+		if len(report.Changes) == 0 {
+			if update {
+				pterm.Success.Printfln("All pinned images are up to date (%d files scanned)", report.Scanned)
+			} else {
+				pterm.Success.Printfln("All images are pinned (%d files scanned)", report.Scanned)
+			}
+			return nil
+		}
+
+		rows := [][]string{{"File", "Line", "Image", "Digest"}}
+		for _, c := range report.Changes {
+			digest := c.To
+			if c.From != "" {
+				digest = c.From + " → " + c.To
+			}
+			rows = append(rows, []string{c.File, fmt.Sprint(c.Line), c.Image, digest})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+
+		if dryRun {
+			pterm.Warning.Printfln("DRY RUN - %d images would be updated", len(report.Changes))
+			return nil
+		}
+		pterm.Success.Printfln("Updated %d images", len(report.Changes))
+		return nil
+	}
+}
+
+// newImageResolver returns a resolver authenticating with the registry
+// credentials stored with gitopsi auth. Registries without credentials are
+// accessed anonymously.
+func newImageResolver() *images.Resolver {
+	return images.NewResolver(func(ctx context.Context, registry string) (string, string, bool) {
+		manager, err := getAuthManager()
+		if err != nil {
+			return "", "", false
+		}
+		creds, err := manager.ListCredentials(ctx, auth.CredentialTypeRegistry)
+		if err != nil {
+			return "", "", false
+		}
+		for _, cred := range creds {
+			if images.SameRegistry(cred.Metadata.URL, registry) && cred.Data.Username != "" {
+				return cred.Data.Username, cred.Data.Password, true
+			}
+		}
+		return "", "", false
+	})
+}
+
+// imagePinner pins the images of files promoted with images.pin_digests.
+func imagePinner() func(context.Context, string, []byte) ([]byte, error) {
+	resolver := newImageResolver()
+	return func(ctx context.Context, path string, content []byte) ([]byte, error) {
+		pinned, _, err := images.Rewrite(ctx, resolver, path, content, false)
+		return pinned, err
+	}
+}
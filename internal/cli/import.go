@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/importer"
+	outputpkg "github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+var (
+	importArgoCDFiles      []string
+	importArgoCDNamespace  string
+	importArgoCDName       string
+	importArgoCDConfigOut  string
+	importArgoCDContext    string
+	importArgoCDKubeconfig string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import an existing GitOps setup into gitopsi",
+	Long: `Adopt gitopsi for an existing GitOps setup by importing its resources.
+
+Examples:
+  gitopsi import argocd
+  gitopsi import argocd --from-file ./argocd --name platform`,
+}
+
+var importArgoCDCmd = &cobra.Command{
+	Use:   "argocd",
+	Short: "Import ArgoCD Applications, ApplicationSets and AppProjects",
+	Long: `Read AppProjects, Applications and ApplicationSets from a cluster or from
+YAML files and synthesize a gitopsi config plus a matching directory layout.
+
+The config records the Git repository and branch the Applications deploy from,
+the environments detected from overlay paths and name suffixes, and their
+destination namespaces and clusters. The ArgoCD resources are written, without
+status and server-populated fields, to:
+
+  <project>/argocd/projects/
+  <project>/argocd/applications/
+  <project>/argocd/applicationsets/
+
+Applications generated by an ApplicationSet and the built-in default project
+are skipped.
+
+Examples:
+  gitopsi import argocd
+  gitopsi import argocd --namespace openshift-gitops --context prod
+  gitopsi import argocd --from-file ./argocd --name platform --config-out platform.yaml`,
+	RunE: runImportArgoCD,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importArgoCDCmd)
+
+	importArgoCDCmd.Flags().StringSliceVar(&importArgoCDFiles, "from-file", nil, "Read ArgoCD resources from these files or directories instead of the cluster")
+	importArgoCDCmd.Flags().StringVarP(&importArgoCDNamespace, "namespace", "n", "argocd", "Namespace ArgoCD is installed in")
+	importArgoCDCmd.Flags().StringVar(&importArgoCDName, "name", "argocd-import", "Project name for the imported repository")
+	importArgoCDCmd.Flags().StringVar(&importArgoCDConfigOut, "config-out", "gitops.yaml", "Path to write the synthesized config to")
+	importArgoCDCmd.Flags().StringVar(&importArgoCDContext, "context", "", "Kubernetes context to use")
+	importArgoCDCmd.Flags().StringVar(&importArgoCDKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+}
+
+func runImportArgoCD(cmd *cobra.Command, args []string) error {
+	var result *importer.ArgoCDResult
+	var err error
+	if len(importArgoCDFiles) > 0 {
+		result, err = importer.LoadArgoCDFiles(importArgoCDFiles)
+		if err != nil {
+			return err
+		}
+	} else {
+		imp := importer.New(&importer.Options{Context: importArgoCDContext, Kubeconfig: importArgoCDKubeconfig})
+		spinner, _ := pterm.DefaultSpinner.Start("Reading ArgoCD resources...")
+		result, err = imp.ScanArgoCD(cmd.Context(), importArgoCDNamespace)
+		if err != nil {
+			spinner.Fail("Failed to read ArgoCD resources")
+			return err
+		}
+		spinner.Success(fmt.Sprintf("Read ArgoCD resources from namespace %s", result.Namespace))
+	}
+
+	if result.Count() == 0 {
+		printSkipped(result.Skipped)
+		return fmt.Errorf("no ArgoCD resources to import")
+	}
+	if result.Namespace == "" {
+		result.Namespace = importArgoCDNamespace
+	}
+
+	cfg := importer.SynthesizeConfig(result, importArgoCDName)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid synthesized config: %w", err)
+	}
+
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %w", err)
+	}
+	writer := outputpkg.New(absOutput, dryRun, verbose)
+	if err := importer.WriteArgoCD(writer, result, cfg); err != nil {
+		return fmt.Errorf("failed to write imported ArgoCD resources: %w", err)
+	}
+	if !dryRun {
+		if err := config.Save(cfg, importArgoCDConfigOut); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println()
+	tableData := [][]string{{"ENVIRONMENT", "NAMESPACE", "CLUSTER"}}
+	for _, env := range cfg.Environments {
+		cluster := env.Cluster
+		if cluster == "" {
+			cluster = "in-cluster"
+		}
+		tableData = append(tableData, []string{env.Name, env.Namespace, cluster})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if verbose {
+		printSkipped(result.Skipped)
+	}
+
+	fmt.Println()
+	pterm.Success.Printf("Imported %d AppProject(s), %d Application(s) and %d ApplicationSet(s) into %s/\n",
+		len(result.Projects), len(result.Applications), len(result.ApplicationSets), cfg.Project.Name)
+	if !dryRun {
+		pterm.Success.Printf("Config written to %s\n", importArgoCDConfigOut)
+	}
+	if len(result.Skipped) > 0 && !verbose {
+		pterm.Info.Printf("Skipped %d resources (use --verbose to list them)\n", len(result.Skipped))
+	}
+	if cfg.Git.URL == "" {
+		pterm.Warning.Println("No Git repository found in the imported Applications - set git.url in the config")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
+)
+
+var infraCmd = &cobra.Command{
+	Use:   "infra",
+	Short: "Inspect generated infrastructure",
+}
+
+var infraNetpolCmd = &cobra.Command{
+	Use:   "netpol",
+	Short: "Work with NetworkPolicy profiles",
+	Long: `Work with the NetworkPolicies generated for each environment namespace.
+
+Profiles (infrastructure.network_policy_profile, or network_policy_profile per
+environment):
+  basic              - Allow traffic within the environment and DNS (default)
+  default-deny       - Deny all traffic except DNS egress
+  namespace-isolated - default-deny, plus traffic between pods of the namespace
+  app-allowlist      - default-deny, plus app-to-app traffic on the application
+                       ports, derived from applications and their allow_from`,
+}
+
+var infraNetpolPreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Print the NetworkPolicies of each environment",
+	Long: `Print the NetworkPolicies that init generates for each environment namespace,
+without writing files.
+
+Examples:
+  gitopsi infra netpol preview --config gitops.yaml
+  gitopsi infra netpol preview --config gitops.yaml --env prod
+  gitopsi infra netpol preview --config gitops.yaml --profile app-allowlist`,
+	Args: cobra.NoArgs,
+	RunE: runInfraNetpolPreview,
+}
+
+var (
+	netpolEnv     string
+	netpolProfile string
+)
+
+func init() {
+	rootCmd.AddCommand(infraCmd)
+	infraCmd.AddCommand(infraNetpolCmd)
+	infraNetpolCmd.AddCommand(infraNetpolPreviewCmd)
+
+	infraNetpolPreviewCmd.Flags().StringVar(&netpolEnv, "env", "", "Only preview this environment")
+	infraNetpolPreviewCmd.Flags().StringVar(&netpolProfile, "profile", "", "Preview this profile instead of the configured ones")
+}
+
+type netpolPreview struct {
+	Environment string `json:"environment" yaml:"environment"`
+	Namespace   string `json:"namespace" yaml:"namespace"`
+	Profile     string `json:"profile" yaml:"profile"`
+	Manifests   string `json:"manifests" yaml:"manifests"`
+}
+
+func runInfraNetpolPreview(cmd *cobra.Command, args []string) error {
+	cfg, err := loadProjectConfig(".")
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return fmt.Errorf("no gitopsi.yaml in the current directory: pass --config")
+	}
+
+	if netpolProfile != "" {
+		if !slices.Contains(config.ValidNetworkPolicyProfiles(), netpolProfile) {
+			return fmt.Errorf("invalid profile: %s (valid: %v)", netpolProfile, config.ValidNetworkPolicyProfiles())
+		}
+		cfg.Infra.NetworkPolicyProfile = netpolProfile
+		for i := range cfg.Environments {
+			cfg.Environments[i].NetworkPolicyProfile = ""
+		}
+	}
+	if netpolEnv != "" && cfg.GetEnvironment(netpolEnv) == nil {
+		return fmt.Errorf("environment %s not found in config", netpolEnv)
+	}
+
+	var previews []netpolPreview
+	for _, env := range cfg.Environments {
+		if netpolEnv != "" && env.Name != netpolEnv {
+			continue
+		}
+		content, err := generator.RenderNetworkPolicies(cfg, env.Name)
+		if err != nil {
+			return err
+		}
+		previews = append(previews, netpolPreview{
+			Environment: env.Name,
+			Namespace:   cfg.GetEnvironmentNamespace(env.Name),
+			Profile:     cfg.GetNetworkPolicyProfile(env.Name),
+			Manifests:   string(content),
+		})
+	}
+
+	if p := newPrinter(); p.structured() {
+		return p.print(previews)
+	}
+
+	for _, preview := range previews {
+		pterm.DefaultSection.Printfln("%s (%s, profile %s)", preview.Environment, preview.Namespace, preview.Profile)
+		fmt.Print(preview.Manifests)
+		fmt.Println()
+	}
+	if !cfg.Infra.NetworkPolicies {
+		pterm.Warning.Println("infrastructure.network_policies is disabled: init does not generate these policies")
+	}
+	return nil
+}
@@ -2,24 +2,33 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
 	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
 	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
 	"github.com/ihsanmokhlisse/gitopsi/internal/config"
 	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
 	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
 	outputpkg "github.com/ihsanmokhlisse/gitopsi/internal/output"
 	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
 	"github.com/ihsanmokhlisse/gitopsi/internal/prompt"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+	"github.com/ihsanmokhlisse/gitopsi/internal/tui"
+	"github.com/ihsanmokhlisse/gitopsi/internal/upgrade"
 	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
 )
 
@@ -36,6 +45,19 @@ var (
 	validateAfterInit bool
 	validateFailOn    string
 	presetFlag        string
+	pushBranch        string
+	forceWithLease    bool
+	commitMessage     string
+	gitCredential     string
+	createRepo        bool
+	repoVisibility    string
+	repoDescription   string
+	noInteractive     bool
+	regenerateForce   bool
+	provisionFlag     bool
+	threeWayMerge     bool
+	generateWorkers   int
+	keepPartial       bool
 )
 
 var initCmd = &cobra.Command{
@@ -44,21 +66,29 @@ var initCmd = &cobra.Command{
 	Long: `Initialize a new GitOps repository structure with all necessary
 manifests, documentation, and scripts.
 
-Can run in interactive mode (default) or with a config file.
+Runs a full-screen wizard in a terminal (default) or uses a config file.
+--no-interactive generates from the defaults, --preset and flags.
 Optionally push to Git repository and bootstrap GitOps tool on cluster.
 
 Presets:
   minimal     - Basic namespace + deployment (single env)
   standard    - Full infrastructure + apps (default)
   enterprise  - All components + security + monitoring + policies
+  <file|URL>  - An organization preset file (kind: Preset)
 
 Examples:
-  gitopsi init                                    # Interactive mode
+  gitopsi init                                    # Interactive wizard
+  gitopsi init --no-interactive --git-url <url>   # Defaults without prompts
   gitopsi init --preset minimal                   # Minimal preset
   gitopsi init --preset enterprise                # Enterprise preset
+  gitopsi init --preset https://example.com/acme-enterprise.yaml  # Organization preset
   gitopsi init --config gitops.yaml               # Config file mode
   gitopsi init --dry-run                          # Preview without writing
+  gitopsi init --config gitops.yaml --three-way-merge  # Regenerate, merging your edits
+  gitopsi init --from-cluster --namespaces shop   # Import from a live cluster
   gitopsi init --git-url <url> --push             # Generate and push to Git
+  gitopsi init --git-url <url> --pr               # Generate and open a pull request
+  gitopsi init --git-url <url> --push --create-repo  # Create the repository if missing
   gitopsi init --git-url <url> --cluster <url> --bootstrap  # Full E2E setup`,
 	RunE: runInit,
 }
@@ -69,15 +99,35 @@ func init() {
 	initCmd.Flags().StringVar(&gitURL, "git-url", "", "Git repository URL")
 	initCmd.Flags().StringVar(&gitToken, "git-token", "", "Git authentication token (or use GITOPSI_GIT_TOKEN env)")
 	initCmd.Flags().BoolVar(&pushAfterInit, "push", false, "Push generated code to Git repository")
+	initCmd.Flags().StringVar(&pushBranch, "branch", "", "Branch to push to (default: git.branch)")
+	initCmd.Flags().BoolVar(&forceWithLease, "force-with-lease", false, "Overwrite a diverged remote branch if it has not changed since it was fetched")
+	initCmd.Flags().StringVar(&commitMessage, "commit-message", "", "Commit message template (default: git.commit_message)")
+	initCmd.Flags().StringVar(&gitCredential, "git-credential", "", "Name of a stored git credential to push with")
+	initCmd.Flags().BoolVar(&createRepo, "create-repo", false, "Create the Git repository through the provider API if it does not exist")
+	initCmd.Flags().StringVar(&repoVisibility, "repo-visibility", "", "Visibility of a created repository: private, internal, public (default: private)")
+	initCmd.Flags().StringVar(&repoDescription, "repo-description", "", "Description of a created repository (default: project.description)")
+	addPullRequestFlags(initCmd)
 	initCmd.Flags().StringVar(&clusterURL, "cluster", "", "Target cluster URL")
 	initCmd.Flags().StringVar(&clusterToken, "cluster-token", "", "Cluster authentication token (or use GITOPSI_CLUSTER_TOKEN env)")
 	initCmd.Flags().BoolVar(&bootstrapFlag, "bootstrap", false, "Bootstrap GitOps tool on cluster")
+	initCmd.Flags().BoolVar(&bootstrapConfirm, "confirm", false, "Show the bootstrap plan and ask for confirmation before bootstrapping")
+	initCmd.Flags().BoolVarP(&bootstrapYes, "yes", "y", false, "Bootstrap production clusters without asking for confirmation")
+	initCmd.Flags().BoolVar(&provisionFlag, "provision", false, "Create the clusters of the clusters section before bootstrapping")
 	initCmd.Flags().StringVar(&bootstrapMode, "bootstrap-mode", "helm", "Bootstrap mode: helm, olm, manifest")
+	initCmd.Flags().BoolVar(&bootstrapHA, "ha", false, "Install ArgoCD in high availability mode (needs 3 schedulable nodes)")
+	addHelmValuesFlag(initCmd.Flags())
+	addRepoCredentialFlag(initCmd.Flags())
 	initCmd.Flags().BoolVar(&quietMode, "quiet", false, "Minimal output")
 	initCmd.Flags().BoolVar(&jsonMode, "json", false, "Output as JSON")
 	initCmd.Flags().BoolVar(&validateAfterInit, "validate", false, "Validate generated manifests")
 	initCmd.Flags().StringVar(&validateFailOn, "fail-on", "high", "Fail on severity: critical, high, medium, low")
-	initCmd.Flags().StringVar(&presetFlag, "preset", "", "Configuration preset: minimal, standard, enterprise")
+	initCmd.Flags().StringVar(&presetFlag, "preset", "", "Configuration preset: minimal, standard, enterprise, or the path or URL of a preset file")
+	initCmd.Flags().BoolVar(&noInteractive, "no-interactive", false, "Skip the wizard and use the defaults, --preset and flags")
+	initCmd.Flags().BoolVar(&regenerateForce, "force", false, "Regenerate an existing project, overwriting files you modified")
+	initCmd.Flags().BoolVar(&threeWayMerge, "three-way-merge", false, "Regenerate an existing project, merging your modifications with the new files")
+	initCmd.Flags().IntVar(&generateWorkers, "parallel", 0, "Number of generation tasks run concurrently (default: number of CPUs)")
+	initCmd.Flags().BoolVar(&keepPartial, "keep-partial", false, "Keep the partially generated files of a failed generation for debugging")
+	addOfflineFlags(initCmd.Flags())
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -88,16 +138,44 @@ func runInit(cmd *cobra.Command, args []string) error {
 	var cfg *config.Config
 	var err error
 
+	// --json predates the global --output flag.
+	p := newPrinter()
+	if jsonMode {
+		p.format = outputJSON
+		pterm.DisableOutput()
+	}
+	structured := p.structured()
+	wizard := false
+	var presetFile *config.PresetFile
+
 	if cfgFile != "" {
-		if !quietMode && !jsonMode {
+		if !quietMode && !structured {
 			fmt.Printf("📄 Loading config from: %s\n", cfgFile)
 		}
 		cfg, err = config.Load(cfgFile)
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+	} else if fromCluster {
+		cfg = newFromClusterConfig()
+	} else if noInteractive {
+		cfg = config.NewDefaultConfig()
+		cfg.Project.Name = "my-platform"
+	} else if isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		// The wizard edits the defaults with the preset and flags applied
+		// and draws on stderr, keeping stdout for --output.
+		cfg = config.NewDefaultConfig()
+		if presetFile, err = applyPresetFile(ctx, cfg); err != nil {
+			return err
+		}
+		applyFlagOverrides(cfg)
+		cfg, err = tui.Run(cfg, os.Stdin, os.Stderr)
+		if err != nil {
+			return fmt.Errorf("wizard failed: %w", err)
+		}
+		wizard = true
 	} else {
-		if !quietMode && !jsonMode {
+		if !quietMode && !structured {
 			fmt.Println("🎯 gitopsi - GitOps Repository Generator")
 			fmt.Println()
 		}
@@ -107,7 +185,12 @@ func runInit(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	applyFlagOverrides(cfg)
+	if !wizard {
+		if presetFile, err = applyPresetFile(ctx, cfg); err != nil {
+			return err
+		}
+		applyFlagOverrides(cfg)
+	}
 
 	if err = cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
@@ -125,17 +208,23 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	projectPath := filepath.Join(absOutput, cfg.Project.Name)
+	auditProject, auditRepository = projectPath, cfg.Audit.Repository
 
-	if !dryRun {
-		if _, statErr := os.Stat(projectPath); statErr == nil {
-			return fmt.Errorf("directory already exists: %s", cfg.Project.Name)
+	var guard *upgrade.Guard
+	if _, statErr := os.Stat(projectPath); statErr == nil {
+		if guard, err = regenerationGuard(projectPath, cfg.Project.Name); err != nil {
+			return err
 		}
 	}
 
+	if fromCluster {
+		return runInitFromCluster(ctx, cfg, absOutput)
+	}
+
 	// Initialize progress display
 	prog := progress.New("gitopsi", cfg.Project.Name)
 	prog.SetQuiet(quietMode)
-	prog.SetJSON(jsonMode)
+	prog.SetJSON(structured)
 	prog.ShowHeader()
 
 	// Setup summary for saving later
@@ -159,29 +248,58 @@ func runInit(cmd *cobra.Command, args []string) error {
 		},
 	}
 
+	// Provision new clusters first: the preflight checks connect to them
+	if provisionFlag {
+		if len(cfg.Clusters) == 0 {
+			return fmt.Errorf("--provision requires a clusters section in the config")
+		}
+		if err := provisionClusters(ctx, cfg, prog); err != nil {
+			return err
+		}
+		summary.Cluster.Name = cfg.Cluster.Name
+		summary.Cluster.URL = cfg.Cluster.URL
+	}
+
 	// ============================================================
 	// PREFLIGHT CHECKS - Validate everything before starting
 	// ============================================================
-	var gitProvider git.Provider
+	var gitCreds *gitops.Credentials
 	preflightSection := prog.StartSection("Preflight Checks")
 	preflightPassed := true
 	var preflightErrors []string
 
-	// Check 1: Git Token (if push enabled)
+	// Check 1: Git access (if push enabled)
 	if shouldPush(cfg) {
 		gitCheckStep := prog.StartStep(preflightSection, "Checking Git credentials...")
-		gitProvider, err = authenticateGit(ctx, cfg)
+		gitCreds, err = gitPushCredentials(ctx, cfg)
+		var createdRepo *createdRepository
+		if err == nil && !dryRun {
+			createdRepo, err = ensureRepository(ctx, cfg, gitCreds)
+		}
+		if err == nil {
+			err = gitops.CheckAccess(ctx, cfg.Git.URL, gitCreds)
+		}
 		if err != nil {
 			prog.FailStep(preflightSection, gitCheckStep, err)
 			preflightPassed = false
 			preflightErrors = append(preflightErrors, fmt.Sprintf("Git: %v", err))
 		} else {
 			prog.SuccessStep(preflightSection, gitCheckStep)
-			gitCheckStep.AddSubStep(fmt.Sprintf("Provider: %s", gitProvider.Name()), progress.StatusSuccess)
-			gitCheckStep.AddSubStep("Token valid", progress.StatusSuccess)
+			if providerType := detectGitProvider(ctx, cfg); providerType != git.ProviderGeneric {
+				gitCheckStep.AddSubStep(fmt.Sprintf("Provider: %s", providerType), progress.StatusSuccess)
+				summary.Git.Provider = string(providerType)
+			}
+			if createdRepo != nil {
+				gitCheckStep.AddSubStep(fmt.Sprintf("Created %s repository %s", createdRepo.Repository.Visibility, createdRepo.Repository.FullName), progress.StatusSuccess)
+				if createdRepo.DeployKey != "" {
+					gitCheckStep.AddSubStep(fmt.Sprintf("Registered deploy key %q", createdRepo.DeployKey), progress.StatusSuccess)
+				}
+				if createdRepo.DeployKeyErr != nil {
+					gitCheckStep.AddSubStep(createdRepo.DeployKeyErr.Error(), progress.StatusWarning)
+				}
+			}
 			gitCheckStep.AddSubStep("Repository accessible", progress.StatusSuccess)
 			prog.ShowSubSteps(gitCheckStep)
-			summary.Git.Provider = string(gitProvider.Name())
 			summary.Git.Status = "connected"
 		}
 	} else {
@@ -193,6 +311,12 @@ func runInit(cmd *cobra.Command, args []string) error {
 	if shouldBootstrap(cfg) {
 		clusterCheckStep := prog.StartStep(preflightSection, "Checking cluster connectivity...")
 
+		if credErr := useClusterCredential(ctx, cfg); credErr != nil {
+			prog.FailStep(preflightSection, clusterCheckStep, credErr)
+			preflightPassed = false
+			preflightErrors = append(preflightErrors, fmt.Sprintf("Cluster credential: %v", credErr))
+		}
+
 		// Auto-detect cluster if not specified
 		if cfg.Cluster.URL == "" {
 			if detectErr := autoDetectCluster(ctx, cfg); detectErr != nil {
@@ -205,7 +329,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 		if cfg.Cluster.URL != "" {
 			// Test cluster connection
 			testCluster := cluster.New(cfg.Cluster.URL, cfg.Cluster.Name, cluster.Platform(cfg.Platform))
-			if authErr := testCluster.Authenticate(&cluster.AuthOptions{Method: cluster.AuthKubeconfig}); authErr != nil {
+			if authErr := testCluster.Authenticate(&cluster.AuthOptions{
+				Method:     cluster.AuthKubeconfig,
+				Kubeconfig: cfg.Cluster.Kubeconfig,
+				Context:    cfg.Cluster.Context,
+			}); authErr != nil {
 				prog.FailStep(preflightSection, clusterCheckStep, authErr)
 				preflightPassed = false
 				preflightErrors = append(preflightErrors, fmt.Sprintf("Cluster auth: %v", authErr))
@@ -229,6 +357,17 @@ func runInit(cmd *cobra.Command, args []string) error {
 					summary.Cluster.Version = version
 				}
 
+				// HA installs need enough nodes and resources
+				if cfg.Bootstrap.HA {
+					if capacity, haErr := bootstrap.CheckHACapacity(ctx, testCluster); haErr != nil {
+						clusterCheckStep.AddSubStep(fmt.Sprintf("HA: %v", haErr), progress.StatusFailed)
+						preflightPassed = false
+						preflightErrors = append(preflightErrors, fmt.Sprintf("HA capacity: %v", haErr))
+					} else {
+						clusterCheckStep.AddSubStep(fmt.Sprintf("HA: %d nodes, %s CPU, %s memory", capacity.Nodes, capacity.CPU.String(), capacity.Memory.String()), progress.StatusSuccess)
+					}
+				}
+
 				prog.ShowSubSteps(clusterCheckStep)
 				summary.Cluster.URL = cfg.Cluster.URL
 				summary.Cluster.Platform = cfg.Platform
@@ -262,17 +401,20 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// STOP if preflight checks failed
 	if !preflightPassed {
-		fmt.Println()
+		pterm.Println()
 		pterm.Error.Println("Preflight checks failed! Fix the following issues:")
-		fmt.Println()
+		pterm.Println()
 		for i, errMsg := range preflightErrors {
 			pterm.Printf("  %d. %s\n", i+1, errMsg)
 		}
-		fmt.Println()
+		pterm.Println()
 		pterm.Info.Println("💡 Suggestions:")
 		if shouldPush(cfg) {
 			pterm.Println("   • Generate a GitHub token: https://github.com/settings/tokens")
 			pterm.Println("   • Set token: export GITOPSI_GIT_TOKEN=<your-token>")
+			if !cfg.Git.CreateIfMissing {
+				pterm.Println("   • Create a missing repository with: --create-repo")
+			}
 		}
 		if shouldBootstrap(cfg) {
 			pterm.Println("   • Ensure kubectl is configured: kubectl cluster-info")
@@ -281,9 +423,9 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("preflight checks failed")
 	}
 
-	fmt.Println()
+	pterm.Println()
 	pterm.Success.Println("All preflight checks passed!")
-	fmt.Println()
+	pterm.Println()
 
 	// ============================================================
 	// MAIN EXECUTION - All checks passed, proceed with setup
@@ -293,7 +435,20 @@ func runInit(cmd *cobra.Command, args []string) error {
 	genSection := prog.StartSection("File Generation")
 
 	writer := outputpkg.New(absOutput, dryRun, verbose)
+	writer.KeepPartial = keepPartial
+	if guard != nil {
+		writer.Reconcile = guard.Hook(cfg.Project.Name)
+	}
 	gen := generator.New(cfg, writer, verbose)
+	gen.Images = newImageResolver()
+	gen.Credentials = readCredential
+	gen.Workers = generateWorkers
+	gen.Context = ctx
+	if structured {
+		// Keep stdout for the summary document.
+		writer.Log = os.Stderr
+		gen.Log = os.Stderr
+	}
 
 	if dryRun {
 		step := prog.StartStep(genSection, "DRY RUN - Previewing changes...")
@@ -301,19 +456,66 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	step := prog.StartStep(genSection, "Generating GitOps repository structure...")
+	gen.Progress = func(done, total int) {
+		prog.UpdateStep("Generating GitOps repository structure... " + progress.Bar(done, total))
+	}
+	if tmplErr := templates.ValidateOverrides(); tmplErr != nil {
+		err := fmt.Errorf("invalid template overrides: %w", tmplErr)
+		prog.FailStep(genSection, step, err)
+		return err
+	}
+	if txErr := writer.Begin(); txErr != nil {
+		prog.FailStep(genSection, step, txErr)
+		return txErr
+	}
 	if genErr := gen.Generate(); genErr != nil {
 		prog.FailStep(genSection, step, genErr)
+		if partial, rbErr := writer.Rollback(); rbErr != nil {
+			pterm.Warning.Printf("Failed to roll back the output: %v\n", rbErr)
+		} else if partial != "" {
+			pterm.Info.Printf("Partial output kept in %s\n", partial)
+		}
 		return genErr
 	}
+	if txErr := writer.Commit(); txErr != nil {
+		prog.FailStep(genSection, step, txErr)
+		return txErr
+	}
 	prog.SuccessStep(genSection, step)
 
 	// Add substeps for generated directories
 	step.AddSubStep("infrastructure/", progress.StatusSuccess)
 	step.AddSubStep("applications/", progress.StatusSuccess)
 	step.AddSubStep(cfg.GitOpsTool+"/", progress.StatusSuccess)
+	for _, repo := range cfg.AppRepositories() {
+		step.AddSubStep(fmt.Sprintf("../%s/ (%s)", repo.Name, repo.URL), progress.StatusSuccess)
+	}
 	step.AddSubStep("docs/", progress.StatusSuccess)
+	if guard != nil {
+		addRegenerationSubSteps(step, guard)
+	}
 	prog.ShowSubSteps(step)
 
+	if guard != nil && threeWayMerge {
+		if conflicts := guard.Count(upgrade.ActionConflict); conflicts > 0 {
+			return withExitCode(ExitDrift, fmt.Errorf("%d files have merge conflicts: resolve them before pushing", conflicts))
+		}
+	}
+
+	if err := installPresetPatterns(ctx, prog, genSection, cfg, projectPath, presetFile); err != nil {
+		return err
+	}
+	if cfg.Backup.Enabled {
+		if err := installPatterns(ctx, prog, genSection, cfg, projectPath, "Installing Velero...", []config.PresetPattern{cfg.Backup.VeleroPattern()}); err != nil {
+			return err
+		}
+	}
+	if cfg.Cost.Enabled {
+		if err := installPatterns(ctx, prog, genSection, cfg, projectPath, "Installing OpenCost...", []config.PresetPattern{cfg.Cost.OpenCostPattern()}); err != nil {
+			return err
+		}
+	}
+
 	if validateAfterInit {
 		if valErr := runPostInitValidation(ctx, prog, absOutput); valErr != nil {
 			return valErr
@@ -321,67 +523,87 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	if dryRun {
-		if !quietMode && !jsonMode {
+		if structured {
+			return p.print(summary)
+		}
+		if shouldBootstrap(cfg) && !quietMode {
+			if err := printInitBootstrapPlan(ctx, cfg); err != nil {
+				return err
+			}
+		}
+		if !quietMode {
 			fmt.Println("\n🔍 DRY RUN complete - no files were written")
 		}
 		return nil
 	}
 
 	// Step 3: Push to Git if requested
-	if shouldPush(cfg) && gitProvider != nil {
+	if shouldPush(cfg) {
 		gitSection := prog.StartSection("Git Push")
 
-		initStep := prog.StartStep(gitSection, "Initializing local Git repository...")
-		// Use configured branch or default to main
-		initBranch := cfg.Git.Branch
-		if initBranch == "" {
-			initBranch = "main"
-		}
-		if gitErr := runGitCommand(ctx, projectPath, "init", "-b", initBranch); gitErr != nil {
-			prog.FailStep(gitSection, initStep, gitErr)
-			return fmt.Errorf("failed to init git repo: %w", gitErr)
-		}
-		prog.SuccessStep(gitSection, initStep)
-
-		remoteStep := prog.StartStep(gitSection, "Adding remote origin...")
-		if gitErr := runGitCommand(ctx, projectPath, "remote", "add", "origin", cfg.Git.URL); gitErr != nil {
-			prog.FailStep(gitSection, remoteStep, gitErr)
-			return fmt.Errorf("failed to add remote: %w", gitErr)
-		}
-		prog.SuccessStep(gitSection, remoteStep)
-
-		commitStep := prog.StartStep(gitSection, "Committing initial structure...")
-		if gitErr := runGitCommand(ctx, projectPath, "add", "."); gitErr != nil {
-			prog.FailStep(gitSection, commitStep, gitErr)
-			return fmt.Errorf("failed to stage files: %w", gitErr)
-		}
-		if gitErr := runGitCommand(ctx, projectPath, "commit", "-m", "feat: Initial GitOps repository structure"); gitErr != nil {
-			prog.FailStep(gitSection, commitStep, gitErr)
-			return fmt.Errorf("failed to commit: %w", gitErr)
-		}
-		prog.SuccessStep(gitSection, commitStep)
-
-		pushStep := prog.StartStep(gitSection, fmt.Sprintf("Pushing to origin/%s...", cfg.Git.Branch))
 		branch := cfg.Git.Branch
 		if branch == "" {
 			branch = "main"
 		}
-		if pushErr := gitProvider.Push(ctx, git.PushOptions{
-			Path:        projectPath,
-			Remote:      "origin",
-			Branch:      branch,
-			SetUpstream: true,
-		}); pushErr != nil {
+		pushOpts := &gitops.PushOptions{
+			Dir:            projectPath,
+			RemoteURL:      cfg.Git.URL,
+			Branch:         branch,
+			CommitMessage:  cfg.Git.CommitMessage,
+			Project:        cfg.Project.Name,
+			ForceWithLease: forceWithLease,
+			Credentials:    gitCreds,
+		}
+		var pushStep *progress.Step
+		var pushResult *gitops.PushResult
+		var pullRequest *gitprovider.PullRequest
+		var pushErr error
+		if openPR {
+			pushStep = prog.StartStep(gitSection, fmt.Sprintf("Opening pull request against %s...", branch))
+			pushResult, pullRequest, pushErr = openPullRequest(ctx, pushOpts, cfg.Git.Provider.Name, branch)
+		} else {
+			pushStep = prog.StartStep(gitSection, fmt.Sprintf("Committing and pushing to origin/%s...", branch))
+			pushResult, pushErr = gitops.NewPusher(pushOpts).Push(ctx)
+		}
+		switch {
+		case errors.Is(pushErr, gitops.ErrNoChanges):
+			prog.SuccessStep(gitSection, pushStep)
+			pushStep.AddSubStep("No changes to propose", progress.StatusSuccess)
+		case pushErr != nil:
 			prog.FailStep(gitSection, pushStep, pushErr)
-			prog.ShowError(pushErr, []string{
+			suggestions := []string{
 				"Ensure the repository exists",
 				"Check you have push permissions",
-				"Try: gitopsi init --git-url <url> --create-repo",
-			})
+				"Store credentials with: gitopsi auth add git",
+			}
+			if errors.Is(pushErr, gitops.ErrRemoteDiverged) {
+				suggestions = []string{
+					"Push to a new branch with --branch <name>",
+					"Or overwrite the remote branch with --force-with-lease",
+				}
+				if openPR {
+					suggestions[0] = "Open the pull request from a new branch with --pr-branch <name>"
+				}
+			}
+			prog.ShowError(pushErr, suggestions)
 			return fmt.Errorf("failed to push: %w", pushErr)
+		default:
+			prog.SuccessStep(gitSection, pushStep)
+			pushStep.AddSubStep(fmt.Sprintf("Commit: %s", pushResult.Commit[:7]), progress.StatusSuccess)
+			if pushResult.Forced {
+				pushStep.AddSubStep("Remote branch overwritten (force-with-lease)", progress.StatusWarning)
+			}
+			if pullRequest != nil {
+				pushStep.AddSubStep(fmt.Sprintf("Pull request #%d: %s", pullRequest.Number, pullRequest.URL), progress.StatusSuccess)
+			}
 		}
-		prog.SuccessStep(gitSection, pushStep)
+		prog.ShowSubSteps(pushStep)
+		summary.Git.Branch = branch
 		summary.Git.Status = "synced"
+		if openPR {
+			summary.Git.Branch = pushOpts.Branch
+			summary.Git.Status = "pull request"
+		}
 	}
 
 	// Step 4: Authenticate to cluster if needed
@@ -403,7 +625,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("cluster detection failed: %w", err)
 			}
 			prog.SuccessStep(clusterSection, detectStep)
-			fmt.Printf("   Detected: %s\n", cfg.Cluster.URL)
+			pterm.Printf("   Detected: %s\n", cfg.Cluster.URL)
 		}
 
 		authStep := prog.StartStep(clusterSection, "Connecting to cluster...")
@@ -435,6 +657,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// Step 5: Bootstrap GitOps tool if requested
 	var bootstrapResult *bootstrap.Result
 	if shouldBootstrap(cfg) && clusterConn != nil {
+		if err := confirmBootstrap(p, []string{cfg.Cluster.Name, cfg.Cluster.Context, cfg.Cluster.URL}, func() error {
+			return printInitBootstrapPlan(ctx, cfg)
+		}); err != nil {
+			return err
+		}
 		bootstrapSection := prog.StartSection(fmt.Sprintf("%s Bootstrap", cfg.GitOpsTool))
 
 		installStep := prog.StartStep(bootstrapSection, fmt.Sprintf("Installing %s via %s...", cfg.GitOpsTool, cfg.Bootstrap.Mode))
@@ -470,10 +697,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 	for _, env := range cfg.Environments {
 		summary.Environments = append(summary.Environments, progress.EnvironmentInfo{
 			Name:      env.Name,
-			Namespace: cfg.Project.Name + "-" + env.Name,
+			Namespace: cfg.GetEnvironmentNamespace(env.Name),
 			Status:    "created",
 		})
-		summary.Cluster.Namespaces = append(summary.Cluster.Namespaces, cfg.Project.Name+"-"+env.Name)
+		summary.Cluster.Namespaces = append(summary.Cluster.Namespaces, cfg.GetEnvironmentNamespace(env.Name))
 	}
 
 	// Add applications
@@ -490,13 +717,16 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// Save summary to file
 	if !dryRun {
 		if saveErr := progress.SaveSummary(projectPath, summary); saveErr != nil {
-			if !quietMode && !jsonMode {
+			if !quietMode && !structured {
 				fmt.Printf("Warning: Could not save summary: %v\n", saveErr)
 			}
 		}
 	}
 
 	// Show final summary
+	if structured {
+		return p.print(summary)
+	}
 	prog.ShowSummary(summary)
 
 	return nil
@@ -536,9 +766,24 @@ func applyFlagOverrides(cfg *config.Config) {
 		cfg.Git.Branch = branch
 	}
 
-	if pushAfterInit {
+	if pushAfterInit || openPR {
 		cfg.Git.PushOnInit = true
 	}
+	if pushBranch != "" {
+		cfg.Git.Branch = pushBranch
+	}
+	if commitMessage != "" {
+		cfg.Git.CommitMessage = commitMessage
+	}
+	if createRepo {
+		cfg.Git.CreateIfMissing = true
+	}
+	if repoVisibility != "" {
+		cfg.Git.Repository.Visibility = repoVisibility
+	}
+	if repoDescription != "" {
+		cfg.Git.Repository.Description = repoDescription
+	}
 
 	// Cluster URL: CLI flag > env var > config file
 	cURL := clusterURL
@@ -572,6 +817,60 @@ func applyFlagOverrides(cfg *config.Config) {
 	if bootstrapMode != "" {
 		cfg.Bootstrap.Mode = bootstrapMode
 	}
+
+	if bootstrapHA {
+		cfg.Bootstrap.HA = true
+	}
+
+	applyHelmValuesFlag(cfg)
+	applyOfflineFlags(cfg)
+}
+
+// regenerationGuard protects the files of an existing project directory
+// from being overwritten by init. Only files gitopsi generated and nobody
+// modified are replaced, unless --force or --three-way-merge is set.
+// Directories without layout metadata were not generated by gitopsi and are
+// only written to with one of these flags.
+func regenerationGuard(projectPath, name string) (*upgrade.Guard, error) {
+	strategy := upgrade.StrategyKeep
+	switch {
+	case regenerateForce && threeWayMerge:
+		return nil, fmt.Errorf("--force cannot be combined with --three-way-merge")
+	case regenerateForce:
+		strategy = upgrade.StrategyForce
+	case threeWayMerge:
+		strategy = upgrade.StrategyMerge
+	}
+
+	meta, err := layout.Load(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil && strategy == upgrade.StrategyKeep && !dryRun {
+		return nil, fmt.Errorf("directory already exists: %s (not generated by gitopsi: pass --force to overwrite or --three-way-merge to merge into it)", name)
+	}
+	return upgrade.NewGuard(projectPath, strategy, dryRun)
+}
+
+// addRegenerationSubSteps reports how the files of an existing project were
+// reconciled.
+func addRegenerationSubSteps(step *progress.Step, guard *upgrade.Guard) {
+	step.AddSubStep(fmt.Sprintf("%d created, %d updated, %d unchanged",
+		guard.Count(upgrade.ActionCreated), guard.Count(upgrade.ActionUpdated), guard.Unchanged), progress.StatusSuccess)
+	for _, c := range guard.Changes {
+		switch c.Action {
+		case upgrade.ActionMerged:
+			step.AddSubStep("Merged your changes: "+c.Path, progress.StatusSuccess)
+		case upgrade.ActionOverwritten:
+			step.AddSubStep("Overwrote your changes: "+c.Path, progress.StatusWarning)
+		case upgrade.ActionConflict:
+			if threeWayMerge {
+				step.AddSubStep("Merge conflict: "+c.Path, progress.StatusWarning)
+			} else {
+				step.AddSubStep("Modified, not overwritten (use --three-way-merge or --force): "+c.Path, progress.StatusWarning)
+			}
+		}
+	}
 }
 
 func shouldPush(cfg *config.Config) bool {
@@ -604,35 +903,10 @@ func autoDetectCluster(ctx context.Context, cfg *config.Config) error {
 	return nil
 }
 
-func authenticateGit(ctx context.Context, cfg *config.Config) (git.Provider, error) {
-	providerType, instance, err := git.DetectProvider(cfg.Git.URL)
-	if err != nil {
-		return nil, err
-	}
-
-	provider, err := git.NewProvider(providerType, instance)
-	if err != nil {
-		return nil, err
-	}
-
-	authOpts := git.AuthOptions{
-		Method: git.AuthMethod(cfg.Git.Auth.Method),
-		Token:  cfg.Git.Auth.Token,
-		SSHKey: cfg.Git.Auth.SSHKey,
-	}
-
-	if err := provider.Authenticate(ctx, authOpts); err != nil {
-		return nil, err
-	}
-
-	if err := provider.ValidateAccess(ctx); err != nil {
+func authenticateCluster(ctx context.Context, cfg *config.Config) (*cluster.Cluster, error) {
+	if err := useClusterCredential(ctx, cfg); err != nil {
 		return nil, err
 	}
-
-	return provider, nil
-}
-
-func authenticateCluster(ctx context.Context, cfg *config.Config) (*cluster.Cluster, error) {
 	c := cluster.New(cfg.Cluster.URL, cfg.Cluster.Name, cluster.Platform(cfg.Cluster.Platform))
 
 	authOpts := &cluster.AuthOptions{
@@ -656,47 +930,264 @@ func authenticateCluster(ctx context.Context, cfg *config.Config) (*cluster.Clus
 	return c, nil
 }
 
+// printInitBootstrapPlan prints what bootstrapCluster would install.
+func printInitBootstrapPlan(ctx context.Context, cfg *config.Config) error {
+	opts := bootstrapOptions(cfg)
+	if err := useOfflineBundle(opts, cfg); err != nil {
+		return err
+	}
+	if err := useRepoCredentials(ctx, opts, cfg); err != nil {
+		return err
+	}
+	plan, err := bootstrap.New(nil, opts).Plan()
+	if err != nil {
+		return err
+	}
+	target := cfg.Cluster.URL
+	if target == "" {
+		target = "current kubeconfig context"
+	}
+	printBootstrapPlan("Bootstrap plan → "+target, plan)
+	return nil
+}
+
 func bootstrapCluster(ctx context.Context, cfg *config.Config, c *cluster.Cluster) (*bootstrap.Result, error) {
+	opts := bootstrapOptions(cfg)
+	if err := useOfflineBundle(opts, cfg); err != nil {
+		return nil, err
+	}
+	if err := useRepoCredentials(ctx, opts, cfg); err != nil {
+		return nil, err
+	}
+	b := bootstrap.New(c, opts)
+	return b.Bootstrap(ctx)
+}
+
+// bootstrapOptions maps the bootstrap section of a config to bootstrap options.
+func bootstrapOptions(cfg *config.Config) *bootstrap.Options {
 	opts := &bootstrap.Options{
 		Tool:            bootstrap.Tool(cfg.GitOpsTool),
 		Mode:            bootstrap.Mode(cfg.Bootstrap.Mode),
 		Namespace:       cfg.Bootstrap.Namespace,
+		Version:         cfg.Bootstrap.Version,
 		Wait:            cfg.Bootstrap.Wait,
 		Timeout:         cfg.Bootstrap.Timeout,
 		ConfigureRepo:   cfg.Bootstrap.ConfigureRepo,
 		RepoURL:         cfg.Git.URL,
 		RepoBranch:      cfg.Git.Branch,
-		RepoPath:        cfg.GitOpsTool + "/applicationsets",
+		RepoPath:        bootstrapRepoPath(cfg.GitOpsTool),
 		CreateAppOfApps: cfg.Bootstrap.CreateAppOfApps,
 		SyncInitial:     cfg.Bootstrap.SyncInitial,
 		ProjectName:     cfg.Project.Name,
+		HA:              cfg.Bootstrap.HA,
+		Offline:         cfg.Bootstrap.Offline,
+	}
+	for _, repo := range cfg.AppRepositories() {
+		opts.Repositories = append(opts.Repositories, bootstrap.Repository{Name: repo.Name, URL: repo.URL})
 	}
 
-	b := bootstrap.New(c, opts)
-	return b.Bootstrap(ctx)
+	if h := cfg.Bootstrap.Helm; h != nil {
+		opts.Helm = &bootstrap.HelmConfig{
+			Repo:        h.Repo,
+			Chart:       h.Chart,
+			Version:     h.Version,
+			Values:      h.Values,
+			ValuesFiles: h.ValuesFiles,
+			SetValues:   h.SetValues,
+		}
+	}
+
+	return opts
+}
+
+// bootstrapRepoPath returns the repository path the root GitOps resource syncs from.
+func bootstrapRepoPath(tool string) string {
+	if tool == "flux" {
+		return "./flux"
+	}
+	return tool + "/applicationsets"
+}
+
+// gitPushCredentials resolves the credentials used to push: the credential
+// named by --git-credential or git.credential, then a stored git credential
+// covering the repository URL, then the configured token or SSH key.
+func gitPushCredentials(ctx context.Context, cfg *config.Config) (*gitops.Credentials, error) {
+	name := gitCredential
+	if name == "" {
+		name = cfg.Git.Credential
+	}
+
+	if manager, err := getAuthManager(); err == nil {
+		if name != "" {
+			cred, err := manager.GetCredential(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load git credential %s: %w", name, err)
+			}
+			return gitops.CredentialsFromAuth(ctx, cred)
+		}
+		if creds, err := manager.ListCredentials(ctx, auth.CredentialTypeGit); err == nil {
+			for _, cred := range creds {
+				if credentialCovers(cred.Metadata.URL, cfg.Git.URL) {
+					return gitops.CredentialsFromAuth(ctx, cred)
+				}
+			}
+		}
+	} else if name != "" {
+		return nil, err
+	}
+
+	creds := &gitops.Credentials{Token: cfg.Git.Auth.Token}
+	if creds.Token == "" && cfg.Git.Auth.TokenEnv != "" {
+		creds.Token = os.Getenv(cfg.Git.Auth.TokenEnv)
+	}
+	if cfg.Git.Auth.SSHKey != "" {
+		key, err := auth.LoadSSHKeyFromFile(cfg.Git.Auth.SSHKey)
+		if err != nil {
+			return nil, err
+		}
+		creds.SSHKey = key
+	}
+	return creds, nil
 }
 
-func runGitCommand(ctx context.Context, dir string, args ...string) error {
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
+// useRepoCredentials adds the credentials of private repositories to the
+// repositories the GitOps tool is configured with: the git credential named
+// by --credential or bootstrap.credential, or else a stored git credential
+// covering the repository URL. Without one a repository is added as public.
+func useRepoCredentials(ctx context.Context, opts *bootstrap.Options, cfg *config.Config) error {
+	if !opts.ConfigureRepo || opts.RepoURL == "" {
+		return nil
+	}
+	name := repoCredential
+	if name == "" {
+		name = cfg.Bootstrap.Credential
+	}
+
+	manager, err := getAuthManager()
 	if err != nil {
-		return fmt.Errorf("git %s failed: %w: %s", args[0], err, string(output))
+		if name != "" {
+			return err
+		}
+		return nil
+	}
+
+	var named *auth.Credential
+	var stored []*auth.Credential
+	if name != "" {
+		named, err = manager.GetCredential(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to load credential %s: %w", name, err)
+		}
+	} else if creds, err := manager.ListCredentials(ctx, auth.CredentialTypeGit); err == nil {
+		stored = creds
+	}
+	credentialFor := func(repoURL string) *auth.Credential {
+		if named != nil {
+			return named
+		}
+		for _, c := range stored {
+			if credentialCovers(c.Metadata.URL, repoURL) {
+				return c
+			}
+		}
+		return nil
+	}
+
+	if cred := credentialFor(opts.RepoURL); cred != nil {
+		if opts.RepoCredentials, err = repoCredentials(cred, opts.RepoURL); err != nil {
+			return err
+		}
+	}
+	for i := range opts.Repositories {
+		repo := &opts.Repositories[i]
+		if cred := credentialFor(repo.URL); cred != nil {
+			if repo.Credentials, err = repoCredentials(cred, repo.URL); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// repoCredentials returns the repository credentials of a stored git
+// credential for the repository at repoURL.
+func repoCredentials(cred *auth.Credential, repoURL string) (*bootstrap.RepoCredentials, error) {
+	if cred.Type != auth.CredentialTypeGit {
+		return nil, fmt.Errorf("credential %s must be of type 'git'", cred.Name)
+	}
+
+	switch cred.Method {
+	case auth.MethodSSH:
+		if strings.HasPrefix(repoURL, "http://") || strings.HasPrefix(repoURL, "https://") {
+			return nil, fmt.Errorf("credential %s is an SSH key, but %s is not an SSH URL", cred.Name, repoURL)
+		}
+		return &bootstrap.RepoCredentials{
+			SSHPrivateKey: cred.Data.SSHPrivateKey,
+			SSHKnownHosts: cred.Data.SSHKnownHosts,
+		}, nil
+	case auth.MethodToken:
+		username := cred.Data.Username
+		if username == "" {
+			username = "git"
+		}
+		return &bootstrap.RepoCredentials{Username: username, Password: cred.Data.Token}, nil
+	case auth.MethodBasic:
+		return &bootstrap.RepoCredentials{Username: cred.Data.Username, Password: cred.Data.Password}, nil
+	case auth.MethodGitHubApp:
+		return &bootstrap.RepoCredentials{
+			GitHubAppID:                cred.Data.GitHubAppID,
+			GitHubAppInstallationID:    cred.Data.GitHubAppInstallationID,
+			GitHubAppPrivateKey:        cred.Data.GitHubAppPrivateKey,
+			GitHubAppEnterpriseBaseURL: cred.Data.GitHubAppEnterpriseBaseURL,
+		}, nil
+	default:
+		return nil, fmt.Errorf("credential %s uses %s, which repository secrets do not support", cred.Name, cred.Method)
+	}
+}
+
+// detectGitProvider returns the provider hosting cfg.Git.URL: the configured
+// git.provider.name, the provider named by the host, or the one answering on a
+// self-hosted instance.
+func detectGitProvider(ctx context.Context, cfg *config.Config) git.ProviderType {
+	if cfg.Git.Provider.Name != "" {
+		return git.ProviderType(cfg.Git.Provider.Name)
+	}
+	repo, err := gitprovider.ParseRepo(cfg.Git.URL)
+	if err != nil {
+		return git.ProviderGeneric
+	}
+	if repo.Provider == git.ProviderGeneric {
+		return gitprovider.Probe(ctx, &http.Client{Timeout: 5 * time.Second}, repo.Host)
+	}
+	return repo.Provider
+}
+
+// credentialCovers reports whether a credential for credURL applies to
+// repoURL: the same repository, or a repository under the credential URL as
+// with ArgoCD repo-creds (e.g. https://github.com/my-org).
+func credentialCovers(credURL, repoURL string) bool {
+	normalize := func(u string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(u), "/"), ".git")
+	}
+	if credURL == "" {
+		return false
+	}
+	c, r := normalize(credURL), normalize(repoURL)
+	return c == r || strings.HasPrefix(r, c+"/")
+}
+
 func runPostInitValidation(ctx context.Context, prog *progress.Progress, projectPath string) error {
 	valSection := prog.StartSection("Validation")
 
 	opts := &validate.Options{
-		Path:         projectPath,
-		K8sVersion:   "1.29",
-		Schema:       true,
-		Security:     true,
-		Deprecation:  true,
-		Kustomize:    true,
-		OutputFormat: "table",
+		Path:           projectPath,
+		K8sVersion:     "1.29",
+		Schema:         true,
+		Security:       true,
+		Deprecation:    true,
+		Kustomize:      true,
+		OutputFormat:   "table",
+		SchemaCacheDir: validate.DefaultSchemaCacheDir(),
 	}
 
 	switch strings.ToLower(validateFailOn) {
@@ -748,3 +1239,8 @@ func runPostInitValidation(ctx context.Context, prog *progress.Progress, project
 	prog.SuccessStep(valSection, step)
 	return nil
 }
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
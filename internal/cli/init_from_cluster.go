@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pterm/pterm"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/importer"
+	outputpkg "github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+var (
+	fromCluster       bool
+	importNamespaces  []string
+	importKinds       []string
+	importSelector    string
+	importExclude     []string
+	importIncludeHelm bool
+	importEnv         string
+	importContext     string
+	importKubeconfig  string
+)
+
+func init() {
+	initCmd.Flags().BoolVar(&fromCluster, "from-cluster", false, "Generate the repository from resources running in a cluster")
+	initCmd.Flags().StringSliceVar(&importNamespaces, "namespaces", nil, "Namespaces to import with --from-cluster (default: all non-system namespaces)")
+	initCmd.Flags().StringSliceVar(&importKinds, "kinds", nil, "Kinds to import with --from-cluster (default: Deployment,Service,ConfigMap,Ingress)")
+	initCmd.Flags().StringVar(&importSelector, "selector", "", "Label selector for resources to import")
+	initCmd.Flags().StringSliceVar(&importExclude, "exclude", nil, "Skip resources whose name matches these glob patterns")
+	initCmd.Flags().BoolVar(&importIncludeHelm, "include-helm", false, "Import resources managed by Helm releases")
+	initCmd.Flags().StringVar(&importEnv, "import-env", "", "Environment the imported resources belong to (default: first configured environment)")
+	initCmd.Flags().StringVar(&importContext, "kube-context", "", "Kubeconfig context to import from")
+	initCmd.Flags().StringVar(&importKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+}
+
+// newFromClusterConfig is used by --from-cluster when no config file is given.
+func newFromClusterConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "cluster-import"
+	cfg.Scope = "application"
+	return cfg
+}
+
+func runInitFromCluster(ctx context.Context, cfg *config.Config, outputDir string) error {
+	env := importEnv
+	if env == "" && len(cfg.Environments) > 0 {
+		env = cfg.Environments[0].Name
+	}
+	if env == "" {
+		return fmt.Errorf("no environment for imported resources - set --import-env")
+	}
+
+	imp := importer.New(&importer.Options{
+		Namespaces:  importNamespaces,
+		Kinds:       importKinds,
+		Selector:    importSelector,
+		Exclude:     importExclude,
+		IncludeHelm: importIncludeHelm,
+		Context:     importContext,
+		Kubeconfig:  importKubeconfig,
+	})
+
+	spinner, _ := pterm.DefaultSpinner.Start("Scanning cluster...")
+	result, err := imp.Scan(ctx)
+	if err != nil {
+		spinner.Fail("Cluster scan failed")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Scanned %d namespace(s)", len(result.Namespaces)))
+
+	if len(result.Apps) == 0 {
+		printSkipped(result.Skipped)
+		return fmt.Errorf("no resources to import")
+	}
+
+	opts := importer.WriteOptions{
+		Project:     cfg.Project.Name,
+		Environment: env,
+		Branch:      cfg.Git.Branch,
+	}
+	if cfg.GitOpsTool == "argocd" || cfg.GitOpsTool == "both" {
+		opts.RepoURL = cfg.Git.URL
+		opts.ArgoCDNamespace = cfg.Bootstrap.Namespace
+		if opts.ArgoCDNamespace == "" {
+			opts.ArgoCDNamespace = "argocd"
+			if cfg.Platform == "openshift" {
+				opts.ArgoCDNamespace = "openshift-gitops"
+			}
+		}
+	}
+
+	writer := outputpkg.New(outputDir, dryRun, verbose)
+	if newPrinter().structured() {
+		writer.Log = os.Stderr
+	}
+	if err := importer.Write(writer, result, opts); err != nil {
+		return fmt.Errorf("failed to write imported repository: %w", err)
+	}
+	if p := newPrinter(); p.structured() {
+		return p.print(newImportDocument(cfg.Project.Name, env, result))
+	}
+
+	fmt.Println()
+	tableData := [][]string{{"APPLICATION", "NAMESPACE", "RESOURCES"}}
+	for _, app := range result.Apps {
+		tableData = append(tableData, []string{app.Name, app.Namespace, fmt.Sprintf("%d", len(app.Resources))})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if verbose {
+		printSkipped(result.Skipped)
+	}
+
+	fmt.Println()
+	pterm.Success.Printf("Imported %d resources into %d application(s) at %s/\n", result.ResourceCount(), len(result.Apps), cfg.Project.Name)
+	if len(result.Skipped) > 0 && !verbose {
+		pterm.Info.Printf("Skipped %d resources (use --verbose to list them)\n", len(result.Skipped))
+	}
+	if opts.RepoURL == "" && (cfg.GitOpsTool == "argocd" || cfg.GitOpsTool == "both") {
+		pterm.Warning.Println("git.url is not set - ArgoCD Applications were not generated")
+	}
+	return nil
+}
+
+// importDocument is the structured output of init --from-cluster.
+type importDocument struct {
+	Project      string             `json:"project" yaml:"project"`
+	Environment  string             `json:"environment" yaml:"environment"`
+	Applications []importedAppEntry `json:"applications" yaml:"applications"`
+	Skipped      []importer.Skipped `json:"skipped" yaml:"skipped"`
+}
+
+type importedAppEntry struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Resources int    `json:"resources" yaml:"resources"`
+}
+
+func newImportDocument(project, env string, result *importer.Result) importDocument {
+	doc := importDocument{
+		Project:      project,
+		Environment:  env,
+		Applications: make([]importedAppEntry, 0, len(result.Apps)),
+		Skipped:      append([]importer.Skipped{}, result.Skipped...),
+	}
+	for _, app := range result.Apps {
+		doc.Applications = append(doc.Applications, importedAppEntry{Name: app.Name, Namespace: app.Namespace, Resources: len(app.Resources)})
+	}
+	return doc
+}
+
+func printSkipped(skipped []importer.Skipped) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Println()
+	pterm.DefaultSection.Println("Skipped Resources")
+	tableData := [][]string{{"KIND", "NAMESPACE", "NAME", "REASON"}}
+	for _, s := range skipped {
+		tableData = append(tableData, []string{s.Kind, s.Namespace, s.Name, s.Reason})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
@@ -1,10 +1,22 @@
 package cli
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
 	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
 	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
 )
 
@@ -66,6 +78,190 @@ func TestApplyFlagOverrides_PushFlag(t *testing.T) {
 	}
 }
 
+func TestApplyFlagOverrides_PRFlag(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+
+	openPR = true
+	defer func() { openPR = false }()
+
+	applyFlagOverrides(cfg)
+
+	if !cfg.Git.PushOnInit {
+		t.Error("--pr should imply Git.PushOnInit")
+	}
+}
+
+func TestApplyFlagOverrides_CreateRepo(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+
+	createRepo, repoVisibility, repoDescription = true, "internal", "Platform manifests"
+	defer func() { createRepo, repoVisibility, repoDescription = false, "", "" }()
+
+	applyFlagOverrides(cfg)
+
+	if !cfg.Git.CreateIfMissing {
+		t.Error("Git.CreateIfMissing should be true")
+	}
+	if cfg.Git.Repository.Visibility != "internal" || cfg.Git.Repository.Description != "Platform manifests" {
+		t.Errorf("unexpected repository settings %+v", cfg.Git.Repository)
+	}
+}
+
+// fakeProvider is a gitprovider.Provider recording created repositories and
+// deploy keys.
+type fakeProvider struct {
+	exists  bool
+	created *git.CreateRepoOptions
+	keys    []gitprovider.DeployKey
+}
+
+func (f *fakeProvider) Name() git.ProviderType { return git.ProviderGitHub }
+func (f *fakeProvider) Repo() *gitprovider.Repo {
+	return &gitprovider.Repo{Owner: "org", Name: "platform"}
+}
+func (f *fakeProvider) GetRepo(context.Context) (*git.Repository, error) {
+	if !f.exists {
+		return nil, gitprovider.ErrNotFound
+	}
+	return &git.Repository{FullName: "org/platform"}, nil
+}
+func (f *fakeProvider) CreateRepo(_ context.Context, opts git.CreateRepoOptions) (*git.Repository, error) {
+	f.created = &opts
+	return &git.Repository{FullName: "org/" + opts.Name, Visibility: opts.Visibility}, nil
+}
+func (f *fakeProvider) EnsureBranch(context.Context, string, string) error { return nil }
+func (f *fakeProvider) SetBranchProtection(context.Context, string, gitprovider.BranchProtection) error {
+	return nil
+}
+func (f *fakeProvider) AddDeployKey(_ context.Context, key gitprovider.DeployKey) (*gitprovider.DeployKey, error) {
+	f.keys = append(f.keys, key)
+	return &key, nil
+}
+func (f *fakeProvider) CreateWebhook(context.Context, git.WebhookOptions) (*git.Webhook, error) {
+	return nil, gitprovider.ErrNotSupported
+}
+func (f *fakeProvider) CreatePullRequest(context.Context, *gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error) {
+	return nil, gitprovider.ErrNotSupported
+}
+
+func TestEnsureRepository(t *testing.T) {
+	fake := &fakeProvider{}
+	original := newGitProvider
+	newGitProvider = func(context.Context, *config.Config, *gitops.Credentials) (gitprovider.Provider, error) {
+		return fake, nil
+	}
+	defer func() { newGitProvider = original }()
+
+	cfg := config.NewDefaultConfig()
+	cfg.Git.URL = "https://github.com/org/platform.git"
+	cfg.Project.Description = "Platform"
+	creds := &gitops.Credentials{Token: "tok"}
+
+	created, err := ensureRepository(context.Background(), cfg, creds)
+	if err != nil || created != nil || fake.created != nil {
+		t.Fatalf("nothing should be created without create_if_missing, got %+v, %v", created, err)
+	}
+
+	cfg.Git.CreateIfMissing = true
+	if _, err := ensureRepository(context.Background(), cfg, &gitops.Credentials{}); err == nil {
+		t.Error("expected error without a token")
+	}
+
+	created, err = ensureRepository(context.Background(), cfg, creds)
+	if err != nil {
+		t.Fatalf("ensureRepository() error = %v", err)
+	}
+	if created == nil || created.Repository.FullName != "org/platform" {
+		t.Fatalf("unexpected result %+v", created)
+	}
+	if fake.created.Visibility != git.VisibilityPrivate || fake.created.Description != "Platform" || fake.created.AutoInit {
+		t.Errorf("unexpected create options %+v", fake.created)
+	}
+	if len(fake.keys) != 0 {
+		t.Error("no deploy key should be registered for token auth")
+	}
+
+	fake.exists, fake.created = true, nil
+	if created, err := ensureRepository(context.Background(), cfg, creds); err != nil || created != nil || fake.created != nil {
+		t.Errorf("existing repository should be left alone, got %+v, %v", created, err)
+	}
+}
+
+func TestEnsureRepository_DeployKey(t *testing.T) {
+	fake := &fakeProvider{}
+	original := newGitProvider
+	newGitProvider = func(context.Context, *config.Config, *gitops.Credentials) (gitprovider.Provider, error) {
+		return fake, nil
+	}
+	defer func() { newGitProvider = original }()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.NewDefaultConfig()
+	cfg.Git.URL = "git@github.com:org/platform.git"
+	cfg.Git.CreateIfMissing = true
+	cfg.Git.Repository.Visibility = "public"
+	created, err := ensureRepository(context.Background(), cfg, &gitops.Credentials{Token: "tok", SSHKey: string(pem.EncodeToMemory(block))})
+	if err != nil {
+		t.Fatalf("ensureRepository() error = %v", err)
+	}
+	if created.DeployKey != "gitopsi" || created.DeployKeyErr != nil {
+		t.Errorf("unexpected result %+v", created)
+	}
+	if len(fake.keys) != 1 || fake.keys[0].ReadOnly || !strings.HasPrefix(fake.keys[0].Key, "ssh-ed25519 ") {
+		t.Errorf("unexpected deploy keys %+v", fake.keys)
+	}
+	if fake.created.Visibility != git.VisibilityPublic {
+		t.Errorf("Visibility = %s", fake.created.Visibility)
+	}
+
+	fake.keys = nil
+	created, err = ensureRepository(context.Background(), cfg, &gitops.Credentials{Token: "tok", SSHKey: "not a key"})
+	if err != nil || created.DeployKeyErr == nil || len(fake.keys) != 0 {
+		t.Errorf("an invalid key should be reported as a warning, got %+v, %v", created, err)
+	}
+}
+
+func TestPullRequestFlags(t *testing.T) {
+	for _, cmd := range []*cobra.Command{initCmd, installCmd, promoteCmd} {
+		for _, name := range []string{"pr", "pr-branch", "pr-title", "draft"} {
+			if cmd.Flags().Lookup(name) == nil {
+				t.Errorf("%s is missing the --%s flag", cmd.Name(), name)
+			}
+		}
+	}
+}
+
+func TestDeliverPullRequest_NotARepository(t *testing.T) {
+	if err := deliverPullRequest(context.Background(), t.TempDir(), "chore: test"); err == nil {
+		t.Error("expected error outside a Git repository")
+	}
+}
+
+func TestCredentialCovers(t *testing.T) {
+	tests := []struct {
+		cred, repo string
+		want       bool
+	}{
+		{"https://github.com/org/repo.git", "https://github.com/org/repo", true},
+		{"https://github.com/org/", "https://github.com/org/repo.git", true},
+		{"https://github.com/org", "https://github.com/organization/repo.git", false},
+		{"", "https://github.com/org/repo.git", false},
+	}
+	for _, tt := range tests {
+		if got := credentialCovers(tt.cred, tt.repo); got != tt.want {
+			t.Errorf("credentialCovers(%q, %q) = %v, want %v", tt.cred, tt.repo, got, tt.want)
+		}
+	}
+}
+
 func TestApplyFlagOverrides_ClusterURL(t *testing.T) {
 	cfg := config.NewDefaultConfig()
 
@@ -346,3 +542,43 @@ func TestApplyFlagOverrides_AllFlags(t *testing.T) {
 		t.Errorf("Bootstrap.Mode = %v, want manifest", cfg.Bootstrap.Mode)
 	}
 }
+
+func TestRegenerationGuard(t *testing.T) {
+	defer func() { regenerateForce, threeWayMerge = false, false }()
+	dir := t.TempDir()
+
+	if _, err := regenerationGuard(dir, "demo"); err == nil || !strings.Contains(err.Error(), "not generated by gitopsi") {
+		t.Errorf("expected an error for a directory without metadata, got %v", err)
+	}
+
+	threeWayMerge = true
+	if guard, err := regenerationGuard(dir, "demo"); err != nil || guard == nil {
+		t.Errorf("expected --three-way-merge to regenerate, got %v", err)
+	}
+
+	regenerateForce = true
+	if _, err := regenerationGuard(dir, "demo"); err == nil {
+		t.Error("expected an error combining --force and --three-way-merge")
+	}
+}
+
+func TestRepoCredentials(t *testing.T) {
+	token := &auth.Credential{Name: "gh", Type: auth.CredentialTypeGit, Method: auth.MethodToken, Data: auth.CredentialData{Token: "tok"}}
+	creds, err := repoCredentials(token, "https://github.com/org/repo.git")
+	if err != nil || creds.Username != "git" || creds.Password != "tok" {
+		t.Errorf("repoCredentials(token) = %+v, %v", creds, err)
+	}
+
+	key := &auth.Credential{Name: "key", Type: auth.CredentialTypeGit, Method: auth.MethodSSH, Data: auth.CredentialData{SSHPrivateKey: "PRIVATE"}}
+	if creds, err := repoCredentials(key, "git@github.com:org/repo.git"); err != nil || !creds.SSH() {
+		t.Errorf("repoCredentials(ssh) = %+v, %v", creds, err)
+	}
+	if _, err := repoCredentials(key, "https://github.com/org/repo.git"); err == nil {
+		t.Error("an SSH key should be refused for an HTTPS repository URL")
+	}
+
+	registry := &auth.Credential{Name: "quay", Type: auth.CredentialTypeRegistry, Method: auth.MethodBasic}
+	if _, err := repoCredentials(registry, "https://github.com/org/repo.git"); err == nil {
+		t.Error("a registry credential should be refused")
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+var (
+	logLevel  string
+	logFormat string
+	logFile   string
+
+	// closeLog closes the log file once the command ran.
+	closeLog = func() error { return nil }
+)
+
+// loggingOptions returns the log options of the command line. GITOPSI_LOG_*
+// variables apply to the flags not set, and --verbose logs at debug level
+// unless a level is given.
+func loggingOptions(cmd *cobra.Command) logging.Options {
+	opts := logging.Options{Level: logLevel, Format: logFormat, File: logFile}
+	flags := cmd.Flags()
+	if env := os.Getenv("GITOPSI_LOG_LEVEL"); env != "" && !flags.Changed("log-level") {
+		opts.Level = env
+	} else if verbose && !flags.Changed("log-level") {
+		opts.Level = "debug"
+	}
+	if env := os.Getenv("GITOPSI_LOG_FORMAT"); env != "" && !flags.Changed("log-format") {
+		opts.Format = env
+	}
+	if env := os.Getenv("GITOPSI_LOG_FILE"); env != "" && !flags.Changed("log-file") {
+		opts.File = env
+	}
+	return opts
+}
+
+// setupLogging installs the logger configured on the command line.
+func setupLogging(cmd *cobra.Command) error {
+	closeFile, err := logging.Setup(loggingOptions(cmd), os.Stderr)
+	if err != nil {
+		return err
+	}
+	closeLog = closeFile
+	return nil
+}
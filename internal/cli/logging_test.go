@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+func TestLoggingOptions(t *testing.T) {
+	t.Setenv("GITOPSI_LOG_LEVEL", "")
+	t.Setenv("GITOPSI_LOG_FORMAT", "")
+	t.Setenv("GITOPSI_LOG_FILE", "")
+	previousLevel, previousFormat, previousFile, previousVerbose := logLevel, logFormat, logFile, verbose
+	t.Cleanup(func() {
+		logLevel, logFormat, logFile, verbose = previousLevel, previousFormat, previousFile, previousVerbose
+	})
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().StringVar(&logLevel, "log-level", logging.DefaultLevel, "")
+		cmd.Flags().StringVar(&logFormat, "log-format", logging.FormatText, "")
+		cmd.Flags().StringVar(&logFile, "log-file", "", "")
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+
+	verbose = false
+	if opts := loggingOptions(newCmd()); opts.Level != logging.DefaultLevel || opts.Format != logging.FormatText {
+		t.Errorf("default options = %+v", opts)
+	}
+
+	verbose = true
+	if opts := loggingOptions(newCmd()); opts.Level != "debug" {
+		t.Errorf("--verbose should log at debug level, got %+v", opts)
+	}
+	if opts := loggingOptions(newCmd("--log-level", "error")); opts.Level != "error" {
+		t.Errorf("--log-level should take precedence over --verbose, got %+v", opts)
+	}
+	verbose = false
+
+	t.Setenv("GITOPSI_LOG_LEVEL", "info")
+	t.Setenv("GITOPSI_LOG_FORMAT", "json")
+	t.Setenv("GITOPSI_LOG_FILE", "ci.log")
+	opts := loggingOptions(newCmd("--log-file", "run.log"))
+	if opts.Level != "info" || opts.Format != "json" || opts.File != "run.log" {
+		t.Errorf("GITOPSI_LOG_* should apply to the flags not set, got %+v", opts)
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/conventions"
+	"github.com/ihsanmokhlisse/gitopsi/internal/environment"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
 	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
 )
 
@@ -99,6 +105,7 @@ func init() {
 	marketplaceCmd.PersistentFlags().StringVar(&marketplaceProjectPath, "project", ".", "Project path")
 	marketplaceCmd.PersistentFlags().StringVar(&marketplaceGitOpsTool, "gitops-tool", "argocd", "GitOps tool (argocd, flux)")
 	marketplaceCmd.PersistentFlags().StringVar(&marketplacePlatform, "platform", "kubernetes", "Target platform")
+	addOfflineFlags(marketplaceCmd.PersistentFlags())
 
 	// Search flags
 	marketplaceSearchCmd.Flags().StringVar(&searchCategory, "category", "", "Filter by category")
@@ -107,11 +114,46 @@ func init() {
 }
 
 func getMarketplace() *marketplace.Marketplace {
-	mp := marketplace.NewMarketplace(marketplaceProjectPath)
-	mp.Configure(marketplaceGitOpsTool, marketplacePlatform)
+	return newMarketplace(marketplaceProjectPath, marketplaceGitOpsTool, marketplacePlatform)
+}
+
+// newMarketplace returns the marketplace of a project, with the custom
+// registries and credentials of the user.
+func newMarketplace(projectPath, gitOpsTool, platform string) *marketplace.Marketplace {
+	mp := marketplace.NewMarketplace(projectPath)
+	if err := mp.RegistriesError(); err != nil {
+		pterm.Warning.Printfln("Custom registries not loaded: %v", err)
+	}
+	mp.GetRegistry().SetGitCredentials(registryCredentials)
+	mp.GetRegistry().SetOCIResolver(newImageResolver())
+	configureOfflineRegistry(mp.GetRegistry())
+	mp.Configure(gitOpsTool, platform)
 	return mp
 }
 
+// applyProjectConventions applies the conventions and cost center label of
+// the gitopsi.yaml of the project, if any, to the patterns installed by mp.
+func applyProjectConventions(mp *marketplace.Marketplace) error {
+	cfg, err := loadProjectConfig(marketplaceProjectPath)
+	if err != nil || cfg == nil {
+		return err
+	}
+	if transform := projectTransform(cfg); transform != nil {
+		mp.GetInstaller().SetTransform(transform)
+	}
+	return nil
+}
+
+// projectTransform returns the transform adding the cost center label of
+// the project and the labels and annotations of its conventions to the
+// files of installed patterns, or nil when there are none to add.
+func projectTransform(cfg *config.Config) conventions.Transform {
+	return conventions.Chain(
+		conventions.CostTransformer(cfg.Cost, func(string) string { return cfg.ProjectCostCenter() }),
+		conventions.Transformer(cfg.Conventions),
+	)
+}
+
 func runMarketplaceBrowser(cmd *cobra.Command, args []string) error {
 	pterm.DefaultHeader.WithFullWidth().Println("🏪 GitOps Pattern Marketplace")
 	fmt.Println()
@@ -183,6 +225,9 @@ func runMarketplaceSearch(cmd *cobra.Command, args []string) error {
 	} else {
 		spinner.Success("Search complete")
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(append([]marketplace.PatternSearchResult{}, results...))
+	}
 
 	if len(results) == 0 {
 		pterm.Warning.Printf("No patterns found matching '%s'\n", query)
@@ -246,6 +291,9 @@ func runMarketplaceInfo(cmd *cobra.Command, args []string) error {
 	} else {
 		spinner.Success("Info retrieved")
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(info)
+	}
 
 	fmt.Println()
 	displayPatternInfo(info)
@@ -359,7 +407,11 @@ func displayPatternInfo(info *marketplace.PatternInfo) {
 			if dep.Optional {
 				optional = " (optional)"
 			}
-			fmt.Printf("  • %s%s\n", dep.Name, optional)
+			constraint := ""
+			if dep.Version != "" {
+				constraint = " " + dep.Version
+			}
+			fmt.Printf("  • %s%s%s\n", dep.Name, constraint, optional)
 			if dep.Reason != "" {
 				fmt.Printf("    %s\n", pterm.FgGray.Sprint(dep.Reason))
 			}
@@ -395,10 +447,17 @@ func runMarketplaceVersions(cmd *cobra.Command, args []string) error {
 	defer cancel()
 
 	versions, err := mp.GetRegistry().GetPatternVersions(ctx, patternName)
+	p := newPrinter()
 	if err != nil {
+		if p.structured() {
+			return fmt.Errorf("failed to fetch versions for '%s': %w", patternName, err)
+		}
 		pterm.Warning.Printf("Could not fetch versions for '%s': %v\n", patternName, err)
 		return nil
 	}
+	if p.structured() {
+		return p.print(append([]marketplace.PatternVersion{}, versions...))
+	}
 
 	pterm.DefaultSection.Printf("📋 Versions of '%s'\n", patternName)
 	fmt.Println()
@@ -430,6 +489,9 @@ func runMarketplaceCategories(cmd *cobra.Command, args []string) error {
 		// Use built-in categories
 		categories = getBuiltInCategories()
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(append([]marketplace.CategoryIndexEntry{}, categories...))
+	}
 
 	pterm.DefaultSection.Println("📦 Pattern Categories")
 	fmt.Println()
@@ -463,6 +525,9 @@ func runMarketplaceList(cmd *cobra.Command, args []string) error {
 	} else {
 		spinner.Success("Patterns retrieved")
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(append([]marketplace.PatternSearchResult{}, results...))
+	}
 
 	fmt.Println()
 	pterm.DefaultSection.Printf("📦 Available Patterns (%d)\n", len(results))
@@ -494,6 +559,23 @@ func runMarketplaceList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// patternStatusResult is the structured output of patterns status.
+type patternStatusResult struct {
+	Name   string `yaml:"name" json:"name"`
+	Health string `yaml:"health" json:"health"`
+	// Update is the newer version available, if any.
+	Update string `yaml:"update,omitempty" json:"update,omitempty"`
+	// Checks holds the validation checks run with --live.
+	Checks []marketplace.CheckResult `yaml:"checks,omitempty" json:"checks,omitempty"`
+}
+
+// patternValidateResult is the structured output of marketplace validate.
+type patternValidateResult struct {
+	Path     string   `yaml:"path" json:"path"`
+	Valid    bool     `yaml:"valid" json:"valid"`
+	Warnings []string `yaml:"warnings" json:"warnings"`
+}
+
 // Helper functions
 
 func getBuiltInCategories() []marketplace.CategoryIndexEntry {
@@ -607,7 +689,9 @@ Examples:
   gitopsi install prometheus-stack --version 1.2.0
   gitopsi install prometheus-stack --config values.yaml
   gitopsi install prometheus-stack --env dev,staging
-  gitopsi install prometheus-stack --dry-run`,
+  gitopsi install prometheus-stack --env dev,prod --config-env prod=prod-values.yaml
+  gitopsi install prometheus-stack --dry-run
+  gitopsi install prometheus-stack --pr`,
 	Args: cobra.ExactArgs(1),
 	RunE: runInstall,
 }
@@ -634,14 +718,33 @@ var patternsUpdateCmd = &cobra.Command{
 var patternsRemoveCmd = &cobra.Command{
 	Use:   "remove [pattern]",
 	Short: "Remove an installed pattern",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runPatternsRemove,
+	Long: `Remove an installed pattern: its generated files, the directories left
+empty and the kustomization entries referencing them.
+
+--delete-app also deletes the live ArgoCD Application of every environment of
+the pattern, and --cascade the resources it deployed. Deleting live
+Applications asks for confirmation unless --yes is set.
+
+Examples:
+  gitopsi patterns remove monitoring
+  gitopsi patterns remove monitoring --keep-files
+  gitopsi patterns remove monitoring --delete-app --cascade --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPatternsRemove,
 }
 
 var patternsStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check status of installed patterns",
-	RunE:  runPatternsStatus,
+	Long: `Check the status of the installed patterns. With --live the validation checks
+the patterns declare, such as "deployment/grafana ready", are run against the
+cluster; --wait retries failing checks until their timeout.
+
+Examples:
+  gitopsi patterns status
+  gitopsi patterns status --live --context prod
+  gitopsi patterns status --live --wait`,
+	RunE: runPatternsStatus,
 }
 
 var patternCreateCmd = &cobra.Command{
@@ -652,13 +755,30 @@ var patternCreateCmd = &cobra.Command{
 }
 
 var (
-	installVersion  string
-	installConfig   string
-	installEnvs     []string
-	installDryRun   bool
-	installForce    bool
-	installSkipDeps bool
-	patternCategory string
+	installVersion   string
+	installConfig    string
+	installEnvs      []string
+	installConfigEnv []string
+	installDryRun    bool
+	installForce     bool
+	installSkipDeps  bool
+	installVendor    bool
+	patternCategory  string
+
+	installInsecureSkipVerify bool
+	installVerifyKey          string
+
+	removeKeepFiles       bool
+	removeDeleteApp       bool
+	removeCascade         bool
+	removeYes             bool
+	removeContext         string
+	removeArgoCDNamespace string
+
+	installValidate bool
+	statusLive      bool
+	statusWait      bool
+	checkContext    string
 )
 
 func init() {
@@ -675,35 +795,121 @@ func init() {
 	installCmd.Flags().StringVar(&installVersion, "version", "", "Pattern version to install")
 	installCmd.Flags().StringVar(&installConfig, "config", "", "Path to configuration file")
 	installCmd.Flags().StringSliceVar(&installEnvs, "env", nil, "Target environments")
+	installCmd.Flags().StringArrayVar(&installConfigEnv, "config-env", nil, "Config overrides of an environment as env=file (repeatable)")
 	installCmd.Flags().BoolVar(&installDryRun, "dry-run", false, "Preview changes without applying")
 	installCmd.Flags().BoolVar(&installForce, "force", false, "Force reinstall if already installed")
 	installCmd.Flags().BoolVar(&installSkipDeps, "skip-deps", false, "Skip dependency installation")
+	installCmd.Flags().BoolVar(&installVendor, "vendor-helm", false, "Commit the manifests rendered by helm template instead of HelmReleases or ArgoCD Helm sources")
+	addPullRequestFlags(installCmd)
+	addOfflineFlags(installCmd.Flags())
+	addOfflineFlags(patternsCmd.PersistentFlags())
+	for _, cmd := range []*cobra.Command{installCmd, patternsUpdateCmd} {
+		cmd.Flags().BoolVar(&installInsecureSkipVerify, "insecure-skip-verify", false, "Install patterns without verifying their signature")
+		cmd.Flags().StringVar(&installVerifyKey, "verify-key", "", "Verify pattern signatures with this cosign public key instead of the registry policy")
+	}
+
+	installCmd.Flags().BoolVar(&installValidate, "validate", false, "Run the validation checks of the pattern against the cluster after installing, until their timeout")
+	installCmd.Flags().StringVar(&checkContext, "context", "", "Kubernetes context of the validation checks")
+
+	// Status flags
+	patternsStatusCmd.Flags().BoolVar(&statusLive, "live", false, "Run the validation checks of the patterns against the cluster")
+	patternsStatusCmd.Flags().BoolVar(&statusWait, "wait", false, "Retry failing checks until their timeout (with --live)")
+	patternsStatusCmd.Flags().StringVar(&checkContext, "context", "", "Kubernetes context of the validation checks")
+
+	// Remove flags
+	patternsRemoveCmd.Flags().BoolVar(&installForce, "force", false, "Continue when a file or Application cannot be removed")
+	patternsRemoveCmd.Flags().BoolVar(&removeKeepFiles, "keep-files", false, "Keep the generated files")
+	patternsRemoveCmd.Flags().BoolVar(&removeDeleteApp, "delete-app", false, "Delete the live ArgoCD Applications of the pattern")
+	patternsRemoveCmd.Flags().BoolVar(&removeCascade, "cascade", false, "Delete the resources of the Applications with them (with --delete-app)")
+	patternsRemoveCmd.Flags().BoolVarP(&removeYes, "yes", "y", false, "Delete live Applications without asking for confirmation")
+	patternsRemoveCmd.Flags().StringVar(&removeContext, "context", "", "Kubernetes context of the ArgoCD cluster")
+	patternsRemoveCmd.Flags().StringVar(&removeArgoCDNamespace, "argocd-namespace", "argocd", "Namespace of the ArgoCD Applications")
 
 	// Pattern create flags
 	patternCreateCmd.Flags().StringVar(&patternCategory, "category", "infrastructure", "Pattern category")
 }
 
+// loadPatternConfig reads a YAML file of pattern config values.
+func loadPatternConfig(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	config := map[string]any{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// loadEnvConfig reads the config overrides of --config-env env=file flags.
+func loadEnvConfig(flags []string) (map[string]map[string]any, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	envConfig := make(map[string]map[string]any, len(flags))
+	for _, flag := range flags {
+		env, path, ok := strings.Cut(flag, "=")
+		if !ok || env == "" || path == "" {
+			return nil, fmt.Errorf("invalid --config-env %q: use env=file", flag)
+		}
+		if _, dup := envConfig[env]; dup {
+			return nil, fmt.Errorf("--config-env given twice for environment %s", env)
+		}
+		config, err := loadPatternConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		envConfig[env] = config
+	}
+	return envConfig, nil
+}
+
 func runInstall(cmd *cobra.Command, args []string) error {
 	patternName := args[0]
 
 	mp := getMarketplace()
+	if err := applyProjectConventions(mp); err != nil {
+		return err
+	}
 	ctx := context.Background()
 
 	// Load config if provided
 	var config map[string]any
 	if installConfig != "" {
-		// TODO: Load config from file
-		config = make(map[string]any)
+		var err error
+		if config, err = loadPatternConfig(installConfig); err != nil {
+			return err
+		}
+	}
+	envConfig, err := loadEnvConfig(installConfigEnv)
+	if err != nil {
+		return err
 	}
 
 	opts := marketplace.InstallOptions{
 		Version:      installVersion,
 		Config:       config,
+		EnvConfig:    envConfig,
 		Environments: installEnvs,
 		DryRun:       installDryRun,
 		Force:        installForce,
 		SkipDeps:     installSkipDeps,
+		VendorHelm:   installVendor,
+
+		InsecureSkipVerify: installInsecureSkipVerify,
 	}
+	if installValidate {
+		switch {
+		case installDryRun:
+			pterm.Warning.Println("--validate is skipped in dry run mode")
+		case openPR:
+			pterm.Warning.Println("--validate is skipped with --pr: run gitopsi patterns status --live once the pull request is merged and synced")
+		default:
+			opts.Checks = &marketplace.CheckOptions{Context: checkContext, Wait: true}
+		}
+	}
+	applyVerifyKey(mp)
 
 	if installDryRun {
 		pterm.Info.Println("Dry run mode - no changes will be made")
@@ -712,6 +918,16 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Installing %s...", patternName))
 
 	result, err := mp.Install(ctx, patternName, opts)
+	if err != nil && result != nil && len(result.Checks) > 0 {
+		// Installed, but the cluster does not pass the checks
+		spinner.Warning(result.Message)
+		if p := newPrinter(); p.structured() {
+			_ = p.print(result)
+		} else {
+			printChecks(result.Checks)
+		}
+		return err
+	}
 	if err != nil {
 		spinner.Fail("Installation failed")
 		return err
@@ -723,7 +939,20 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		spinner.Warning(result.Message)
 	}
 
+	if p := newPrinter(); p.structured() {
+		if err := p.print(result); err != nil {
+			return err
+		}
+		if result.Success && !installDryRun && openPR {
+			return deliverPullRequest(ctx, marketplaceProjectPath, fmt.Sprintf("feat: Install %s pattern", patternName))
+		}
+		return nil
+	}
+
 	// Show results
+	if result.Signer != "" {
+		pterm.Info.Printfln("Signature verified: %s", result.Signer)
+	}
 	if len(result.GeneratedPath) > 0 {
 		fmt.Println()
 		pterm.DefaultSection.Println("📁 Generated Files")
@@ -742,10 +971,22 @@ func runInstall(cmd *cobra.Command, args []string) error {
 			} else if dep.Status == "skipped" {
 				status = pterm.FgYellow.Sprint("○")
 			}
-			fmt.Printf("  %s %s - %s\n", status, dep.Name, dep.Message)
+			name := dep.Name
+			if dep.Version != "" {
+				name += " " + dep.Version
+			}
+			if dep.Constraint != "" {
+				name += pterm.FgGray.Sprintf(" (%s)", dep.Constraint)
+			}
+			fmt.Printf("  %s %s - %s\n", status, name, dep.Message)
 		}
 	}
 
+	if len(result.Checks) > 0 {
+		fmt.Println()
+		printChecks(result.Checks)
+	}
+
 	if len(result.Warnings) > 0 {
 		fmt.Println()
 		for _, warning := range result.Warnings {
@@ -763,6 +1004,9 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	if result.Success && !installDryRun {
 		fmt.Println()
 		pterm.Success.Println("Pattern installed successfully!")
+		if openPR {
+			return deliverPullRequest(ctx, marketplaceProjectPath, fmt.Sprintf("feat: Install %s pattern", patternName))
+		}
 		pterm.Info.Println("Commit and push your changes to apply the pattern")
 	}
 
@@ -776,6 +1020,9 @@ func runPatternsList(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(append([]marketplace.InstalledPattern{}, patterns...))
+	}
 
 	if len(patterns) == 0 {
 		pterm.Info.Println("No patterns installed")
@@ -807,12 +1054,17 @@ func runPatternsUpdate(cmd *cobra.Command, args []string) error {
 	patternName := args[0]
 
 	mp := getMarketplace()
+	if err := applyProjectConventions(mp); err != nil {
+		return err
+	}
 	ctx := context.Background()
 
 	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Updating %s...", patternName))
 
+	applyVerifyKey(mp)
 	result, err := mp.Update(ctx, patternName, marketplace.UpdateOptions{
-		Force: installForce,
+		Force:              installForce,
+		InsecureSkipVerify: installInsecureSkipVerify,
 	})
 	if err != nil {
 		spinner.Fail("Update failed")
@@ -825,38 +1077,133 @@ func runPatternsUpdate(cmd *cobra.Command, args []string) error {
 		spinner.Warning(result.Message)
 	}
 
+	if p := newPrinter(); p.structured() {
+		return p.print(result)
+	}
 	return nil
 }
 
 func runPatternsRemove(cmd *cobra.Command, args []string) error {
 	patternName := args[0]
 
+	if removeCascade && !removeDeleteApp {
+		return fmt.Errorf("--cascade requires --delete-app")
+	}
 	mp := getMarketplace()
 	ctx := context.Background()
 
+	opts := marketplace.UninstallOptions{
+		Force:     installForce,
+		KeepFiles: removeKeepFiles,
+	}
+	if removeDeleteApp {
+		if marketplaceGitOpsTool != "argocd" {
+			return fmt.Errorf("--delete-app only supports ArgoCD Applications")
+		}
+		if !removeYes {
+			what := "the live ArgoCD Applications of " + patternName
+			if removeCascade {
+				what += " and every resource they deployed"
+			}
+			confirmed, _ := pterm.DefaultInteractiveConfirm.Show(fmt.Sprintf("Delete %s?", what))
+			if !confirmed {
+				return fmt.Errorf("removal cancelled: pass --yes to delete live Applications")
+			}
+		}
+		opts.DeleteApplication = func(ctx context.Context, name string) error {
+			return environment.DeleteArgoCDApplication(ctx, removeContext, removeArgoCDNamespace, name, removeCascade)
+		}
+	}
+
 	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Removing %s...", patternName))
 
-	err := mp.Uninstall(ctx, patternName, marketplace.UninstallOptions{
-		Force: installForce,
-	})
+	result, err := mp.Uninstall(ctx, patternName, opts)
 	if err != nil {
 		spinner.Fail("Removal failed")
 		return err
 	}
 
 	spinner.Success(fmt.Sprintf("Pattern '%s' removed successfully", patternName))
+	if p := newPrinter(); p.structured() {
+		return p.print(result)
+	}
+	for _, app := range result.Applications {
+		fmt.Printf("  • Deleted Application %s\n", app)
+	}
+	for _, path := range result.Pruned {
+		fmt.Printf("  • Pruned %s\n", path)
+	}
+	for _, warning := range result.Warnings {
+		pterm.Warning.Println(warning)
+	}
 	return nil
 }
 
+// printChecks prints the results of validation checks.
+func printChecks(results []marketplace.CheckResult) {
+	pterm.DefaultSection.Println("🔍 Validation")
+	for _, r := range results {
+		icon := pterm.FgGreen.Sprint("✓")
+		if r.Status != marketplace.CheckPassed {
+			icon = pterm.FgRed.Sprint("✗")
+		}
+		fmt.Printf("  %s %s (%s) - %s\n", icon, r.Name, r.Check, r.Message)
+	}
+}
+
 func runPatternsStatus(cmd *cobra.Command, args []string) error {
 	mp := getMarketplace()
 	ctx := context.Background()
 
+	if statusWait && !statusLive {
+		return fmt.Errorf("--wait requires --live")
+	}
 	status, err := mp.GetStatus(ctx)
 	if err != nil {
 		return err
 	}
 
+	// Run the validation checks against the cluster
+	checks := map[string][]marketplace.CheckResult{}
+	failed := 0
+	if statusLive {
+		names := make([]string, 0, len(status))
+		for name := range status {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			results, err := mp.CheckInstalled(ctx, name, marketplace.CheckOptions{Context: checkContext, Wait: statusWait})
+			if err != nil {
+				return err
+			}
+			checks[name] = results
+			for _, r := range results {
+				if r.Status != marketplace.CheckPassed {
+					status[name] = "unhealthy"
+					failed++
+				}
+			}
+		}
+	}
+
+	if p := newPrinter(); p.structured() {
+		// Updates are best effort, as in the table output.
+		updates, _ := mp.CheckUpdates(ctx)
+		results := make([]patternStatusResult, 0, len(status))
+		for name, health := range status {
+			results = append(results, patternStatusResult{Name: name, Health: health, Update: updates[name], Checks: checks[name]})
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+		if err := p.print(results); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d validation checks failed", failed)
+		}
+		return nil
+	}
+
 	if len(status) == 0 {
 		pterm.Info.Println("No patterns installed")
 		return nil
@@ -871,6 +1218,13 @@ func runPatternsStatus(cmd *cobra.Command, args []string) error {
 			icon = pterm.FgRed.Sprint("✗")
 		}
 		fmt.Printf("  %s %s: %s\n", icon, name, state)
+		for _, r := range checks[name] {
+			checkIcon := pterm.FgGreen.Sprint("✓")
+			if r.Status != marketplace.CheckPassed {
+				checkIcon = pterm.FgRed.Sprint("✗")
+			}
+			fmt.Printf("      %s %s - %s\n", checkIcon, r.Name, r.Message)
+		}
 	}
 
 	// Check for updates
@@ -909,6 +1263,9 @@ func runPatternCreate(cmd *cobra.Command, args []string) error {
 	}
 
 	spinner.Success("Pattern created")
+	if p := newPrinter(); p.structured() {
+		return p.print(pattern)
+	}
 	fmt.Println()
 
 	pterm.DefaultSection.Printf("📦 Pattern '%s' created\n", name)
@@ -927,8 +1284,8 @@ func runPatternCreate(cmd *cobra.Command, args []string) error {
 	pterm.Info.Println("Next steps:")
 	fmt.Println("  1. Edit pattern.yaml to define components")
 	fmt.Println("  2. Add configuration options")
-	fmt.Println("  3. Test with 'gitopsi pattern validate'")
-	fmt.Println("  4. Share with 'gitopsi pattern publish'")
+	fmt.Println("  3. Test with 'gitopsi marketplace validate'")
+	fmt.Println("  4. Share with 'gitopsi marketplace publish'")
 
 	return nil
 }
@@ -941,9 +1298,155 @@ var patternValidateCmd = &cobra.Command{
 	RunE:  runPatternValidate,
 }
 
+// Pattern sign command
+var patternSignCmd = &cobra.Command{
+	Use:   "sign [path]",
+	Short: "Sign a pattern with a cosign key",
+	Long: `Sign the pattern.yaml of a pattern with a cosign private key and write the
+signature to pattern.yaml.sig, which registries serve next to the pattern.
+Keys encrypted by cosign generate-key-pair are decrypted with $COSIGN_PASSWORD.
+
+Keyless signatures are created with cosign itself, e.g. in a CI workflow:
+  cosign sign-blob pattern.yaml --output-signature pattern.yaml.sig --output-certificate pattern.yaml.pem
+
+Examples:
+  gitopsi marketplace sign ./my-pattern --key cosign.key`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPatternSign,
+}
+
+// Pattern publish command
+var patternPublishCmd = &cobra.Command{
+	Use:   "publish [path]",
+	Short: "Publish a pattern to a registry",
+	Long: `Validate a pattern and publish its version to a registry added with
+gitopsi marketplace registry add:
+
+  local    Copied to the registry directory, whose index is regenerated
+  git      Committed with the regenerated index and pushed to the registry
+           branch, or proposed as a pull request with --pr
+  oci      Pushed as the artifact <registry>/<name>:<version>, and added to
+           the index artifact <registry>:index
+  private  Uploaded to <url>/api/v1/patterns, which regenerates the index
+
+The version must be a semantic version that is not published yet. The release
+notes are taken from --changelog, or from the section of the version in the
+CHANGELOG.md of the pattern. Sign the pattern with gitopsi marketplace sign
+first to publish its signature with it.
+
+Examples:
+  gitopsi marketplace publish ./my-pattern --registry team
+  gitopsi marketplace publish ./my-pattern --registry team --pr --changelog "Add alerts"
+  gitopsi marketplace publish ./my-pattern --registry ghcr --dry-run`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPatternPublish,
+}
+
+var (
+	patternSignKey          string
+	patternPublishRegistry  string
+	patternPublishChangelog string
+)
+
 func init() {
 	// Register as subcommand of marketplace, not root (to avoid overriding main validate command)
 	marketplaceCmd.AddCommand(patternValidateCmd)
+	marketplaceCmd.AddCommand(patternSignCmd)
+	marketplaceCmd.AddCommand(patternPublishCmd)
+	patternSignCmd.Flags().StringVar(&patternSignKey, "key", "", "Path to the cosign private key")
+	_ = patternSignCmd.MarkFlagRequired("key")
+
+	patternPublishCmd.Flags().StringVar(&patternPublishRegistry, "registry", "", "Registry to publish to")
+	patternPublishCmd.Flags().StringVar(&patternPublishChangelog, "changelog", "", "Release notes of the version (default: from CHANGELOG.md)")
+	_ = patternPublishCmd.MarkFlagRequired("registry")
+	addPullRequestFlags(patternPublishCmd)
+}
+
+func runPatternPublish(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	ctx := cmd.Context()
+	mp := getMarketplace()
+	opts := marketplace.PublishOptions{
+		Changelog: patternPublishChangelog,
+		Branch:    prBranch,
+		Title:     prTitle,
+		Draft:     prDraft,
+		DryRun:    dryRun,
+	}
+	if openPR {
+		reg, err := mp.GetRegistry().GetRegistry(patternPublishRegistry)
+		if err != nil {
+			return err
+		}
+		if reg.Type != marketplace.RegistryTypeGit {
+			return fmt.Errorf("--pr is only supported by git registries")
+		}
+		creds, err := registryCredentials(ctx, reg)
+		if err != nil {
+			return err
+		}
+		token := providerToken(creds)
+		if token == "" && reg.Auth != nil {
+			token = reg.Auth.Token
+		}
+		if token == "" {
+			return fmt.Errorf("a token is required to open pull requests on %s", reg.URL)
+		}
+		if !dryRun {
+			if opts.PullRequests, err = gitprovider.New(ctx, reg.URL, gitprovider.Options{Token: token, Probe: true}); err != nil {
+				return err
+			}
+		}
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Publishing pattern to %s...", patternPublishRegistry))
+	result, err := mp.PublishPattern(ctx, path, patternPublishRegistry, opts)
+	if err != nil {
+		spinner.Fail("Failed to publish pattern")
+		return err
+	}
+	spinner.Stop()
+	if p := newPrinter(); p.structured() {
+		return p.print(result)
+	}
+
+	switch {
+	case result.DryRun:
+		pterm.Warning.Printfln("DRY RUN - %s %s would be published to %s", result.Name, result.Version, result.Registry)
+	case result.PullRequest != nil:
+		pterm.Success.Printfln("Proposed %s %s to %s: pull request #%d %s", result.Name, result.Version, result.Registry, result.PullRequest.Number, result.PullRequest.URL)
+	default:
+		pterm.Success.Printfln("Published %s %s to %s: %s", result.Name, result.Version, result.Registry, result.Location)
+	}
+	if result.Changelog != "" {
+		pterm.Info.Println("Changelog:\n" + result.Changelog)
+	} else {
+		pterm.Info.Printfln("No changelog found for %s in %s", result.Version, marketplace.ChangelogFile)
+	}
+	return nil
+}
+
+func runPatternSign(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	sigPath, err := marketplace.SignPattern(path, patternSignKey, []byte(os.Getenv("COSIGN_PASSWORD")))
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Signed pattern: %s", sigPath)
+	return nil
+}
+
+// applyVerifyKey makes every registry verify patterns with --verify-key.
+func applyVerifyKey(mp *marketplace.Marketplace) {
+	if installVerifyKey != "" {
+		mp.GetRegistry().SetSigningPolicy(&marketplace.SignaturePolicy{PublicKey: installVerifyKey})
+	}
 }
 
 func runPatternValidate(cmd *cobra.Command, args []string) error {
@@ -967,6 +1470,9 @@ func runPatternValidate(cmd *cobra.Command, args []string) error {
 		spinner.Fail("Validation failed")
 		return err
 	}
+	if p := newPrinter(); p.structured() {
+		return p.print(patternValidateResult{Path: path, Valid: len(errors) == 0, Warnings: append([]string{}, errors...)})
+	}
 
 	if len(errors) > 0 {
 		spinner.Warning("Validation completed with warnings")
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+)
+
+var insecureTLS bool
+
+// networkOptions returns the outbound connection options of the network
+// user settings and --insecure-tls.
+func networkOptions() network.Options {
+	settings, err := config.LoadUserSettings(config.DefaultUserSettingsPath())
+	if err != nil {
+		settings = &config.UserSettings{}
+	}
+	return network.Options{
+		Proxy:       settings.Network.Proxy,
+		NoProxy:     settings.Network.NoProxy,
+		SSHProxy:    settings.Network.SSHProxy,
+		CABundle:    settings.Network.CABundle,
+		InsecureTLS: insecureTLS,
+	}
+}
+
+// setupNetwork configures the proxy, CA bundle and TLS verification of
+// outbound connections. Disabling TLS verification is warned about on every
+// run, also when the output is JSON or YAML.
+func setupNetwork() error {
+	opts := networkOptions()
+	if err := network.Configure(opts); err != nil {
+		return fmt.Errorf("failed to configure network: %w", err)
+	}
+	if opts.InsecureTLS {
+		fmt.Fprintln(os.Stderr, "WARNING: --insecure-tls disables TLS certificate verification of every outbound connection.")
+		fmt.Fprintln(os.Stderr, "WARNING: Manifests, charts, patterns and credentials can be intercepted or tampered with. Use network.ca-bundle instead.")
+		slog.Info("TLS certificate verification disabled")
+	}
+	return nil
+}
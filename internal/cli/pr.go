@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	gitapi "github.com/ihsanmokhlisse/gitopsi/internal/git"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+)
+
+var (
+	openPR   bool
+	prBranch string
+	prTitle  string
+	prDraft  bool
+)
+
+// addPullRequestFlags registers the flags of commands that can deliver their
+// changes as a pull request.
+func addPullRequestFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&openPR, "pr", false, "Push changes to a new branch and open a pull (merge) request")
+	cmd.Flags().StringVar(&prBranch, "pr-branch", "", "Branch to open the pull request from (default: gitopsi/<timestamp>)")
+	cmd.Flags().StringVar(&prTitle, "pr-title", "", "Pull request title (default: the commit message)")
+	cmd.Flags().BoolVar(&prDraft, "draft", false, "Open the pull request as a draft")
+}
+
+// openPullRequest pushes the working tree in opts.Dir to the pull request
+// branch and opens a pull request against base.
+func openPullRequest(ctx context.Context, opts *gitops.PushOptions, provider, base string) (*gitops.PushResult, *gitprovider.PullRequest, error) {
+	token := providerToken(opts.Credentials)
+	if token == "" {
+		return nil, nil, fmt.Errorf("a token is required to open pull requests on %s", opts.RemoteURL)
+	}
+	creator, err := gitprovider.New(ctx, opts.RemoteURL, gitprovider.Options{
+		Provider: gitapi.ProviderType(provider),
+		Token:    token,
+		Probe:    true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return gitops.OpenPullRequest(ctx, opts, creator, &gitprovider.PullRequestOptions{
+		Title: prTitle,
+		Head:  prBranch,
+		Base:  base,
+		Draft: prDraft,
+	})
+}
+
+// deliverPullRequest commits the uncommitted changes of the Git repository
+// containing dir with message and opens a pull request against its current
+// branch, which is checked out again afterwards.
+func deliverPullRequest(ctx context.Context, dir, message string) error {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("failed to open Git repository for %s: %w", dir, err)
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("failed to find remote %s: %w", git.DefaultRemoteName, err)
+	}
+	if len(remote.Config().URLs) == 0 {
+		return fmt.Errorf("remote %s has no URL; set one with git remote set-url %s <url>", git.DefaultRemoteName, git.DefaultRemoteName)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("HEAD is detached; check out the branch to propose changes against")
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	cfg := config.NewDefaultConfig()
+	cfg.Git.URL = remote.Config().URLs[0]
+	cfg.Git.Auth.Token = os.Getenv("GITOPSI_GIT_TOKEN")
+	creds, err := gitPushCredentials(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Opening pull request...")
+	_, pr, err := openPullRequest(ctx, &gitops.PushOptions{
+		Dir:           wt.Filesystem.Root(),
+		RemoteURL:     cfg.Git.URL,
+		CommitMessage: message,
+		Credentials:   creds,
+	}, "", head.Name().Short())
+	if errors.Is(err, gitops.ErrNoChanges) {
+		spinner.Info("No changes to propose")
+		return nil
+	}
+	if err != nil {
+		spinner.Fail("Failed to open pull request")
+		return err
+	}
+	printPullRequest(spinner, pr)
+	return nil
+}
+
+func printPullRequest(spinner *pterm.SpinnerPrinter, pr *gitprovider.PullRequest) {
+	if pr.Existing {
+		spinner.Success(fmt.Sprintf("Updated pull request #%d: %s", pr.Number, pr.URL))
+		return
+	}
+	spinner.Success(fmt.Sprintf("Opened pull request #%d: %s", pr.Number, pr.URL))
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+func TestDeliverPullRequest_RemoteWithoutURL(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	remote := "[remote \"origin\"]\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n"
+	f, err := os.OpenFile(filepath.Join(dir, ".git", "config"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(remote); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	err = deliverPullRequest(context.Background(), dir, "chore: test")
+	if err == nil || !strings.Contains(err.Error(), "remote origin has no URL") {
+		t.Errorf("expected missing URL error, got %v", err)
+	}
+}
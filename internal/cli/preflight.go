@@ -9,6 +9,9 @@ import (
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
 )
 
 // PreflightResult represents the result of a preflight check
@@ -29,7 +32,8 @@ Checks include:
 - Required permissions
 - GitOps tool status (ArgoCD/Flux)
 - Required CRDs
-- Platform detection`,
+- Platform detection
+- Node capacity for an HA installation (--ha)`,
 	RunE: runPreflight,
 }
 
@@ -39,6 +43,7 @@ var (
 	preflightContext    string
 	preflightGitopsTool string
 	preflightTimeout    int
+	preflightHA         bool
 )
 
 func init() {
@@ -49,6 +54,7 @@ func init() {
 	preflightCmd.Flags().StringVar(&preflightContext, "context", "", "Kubernetes context to use")
 	preflightCmd.Flags().StringVar(&preflightGitopsTool, "gitops-tool", "argocd", "GitOps tool to check (argocd, flux)")
 	preflightCmd.Flags().IntVar(&preflightTimeout, "timeout", 30, "Timeout in seconds for each check")
+	preflightCmd.Flags().BoolVar(&preflightHA, "ha", false, "Check the nodes can run an HA ArgoCD installation")
 }
 
 func runPreflight(cmd *cobra.Command, args []string) error {
@@ -100,6 +106,13 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 	results = append(results, result)
 	printResult(result)
 
+	// 8. Check node capacity for HA
+	if preflightHA {
+		result = checkHACapacity(ctx)
+		results = append(results, result)
+		printResult(result)
+	}
+
 	fmt.Println()
 	printSummary(results)
 
@@ -122,12 +135,12 @@ func checkClusterConnectivity(ctx context.Context) PreflightResult {
 	}
 
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 
 	if err != nil {
 		// Try with oc for OpenShift
 		cmd = exec.CommandContext(ctx, "oc", args...)
-		output, err = cmd.CombinedOutput()
+		output, err = logging.CombinedOutput(cmd)
 	}
 
 	if err != nil {
@@ -151,11 +164,11 @@ func checkAPIServerVersion(ctx context.Context) PreflightResult {
 	}
 
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 
 	if err != nil {
 		cmd = exec.CommandContext(ctx, "oc", args...)
-		output, err = cmd.CombinedOutput()
+		output, err = logging.CombinedOutput(cmd)
 	}
 
 	if err != nil {
@@ -205,7 +218,7 @@ func checkPermissions(ctx context.Context) PreflightResult {
 		}
 
 		cmd := exec.CommandContext(ctx, "kubectl", args...)
-		output, _ := cmd.CombinedOutput()
+		output, _ := logging.CombinedOutput(cmd)
 
 		if !strings.Contains(strings.ToLower(string(output)), "yes") {
 			failed = append(failed, fmt.Sprintf("%s %s", check.verb, check.resource))
@@ -233,7 +246,7 @@ func checkPlatform(ctx context.Context) PreflightResult {
 	}
 
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
-	output, _ := cmd.CombinedOutput()
+	output, _ := logging.CombinedOutput(cmd)
 
 	if strings.Contains(string(output), "routes") {
 		result.Status = "ok"
@@ -248,7 +261,7 @@ func checkPlatform(ctx context.Context) PreflightResult {
 	}
 
 	cmd = exec.CommandContext(ctx, "kubectl", args...)
-	output, _ = cmd.CombinedOutput()
+	output, _ = logging.CombinedOutput(cmd)
 
 	if strings.Contains(string(output), "aws") {
 		result.Status = "ok"
@@ -292,7 +305,7 @@ func checkGitOpsTool(ctx context.Context, tool string) PreflightResult {
 		}
 
 		cmd := exec.CommandContext(ctx, "kubectl", args...)
-		if _, err := cmd.CombinedOutput(); err != nil {
+		if _, err := logging.CombinedOutput(cmd); err != nil {
 			// Try standard argocd namespace
 			namespace = "argocd"
 			deployments = []string{"argocd-server", "argocd-repo-server", "argocd-applicationset-controller"}
@@ -303,7 +316,7 @@ func checkGitOpsTool(ctx context.Context, tool string) PreflightResult {
 			}
 
 			cmd = exec.CommandContext(ctx, "kubectl", args...)
-			if _, err := cmd.CombinedOutput(); err != nil {
+			if _, err := logging.CombinedOutput(cmd); err != nil {
 				result.Status = "warn"
 				result.Message = "Not installed"
 				result.Details = "Neither openshift-gitops nor argocd namespace found"
@@ -321,7 +334,7 @@ func checkGitOpsTool(ctx context.Context, tool string) PreflightResult {
 		}
 
 		cmd := exec.CommandContext(ctx, "kubectl", args...)
-		if _, err := cmd.CombinedOutput(); err != nil {
+		if _, err := logging.CombinedOutput(cmd); err != nil {
 			result.Status = "warn"
 			result.Message = "Not installed"
 			result.Details = "flux-system namespace not found"
@@ -340,7 +353,7 @@ func checkGitOpsTool(ctx context.Context, tool string) PreflightResult {
 		}
 
 		cmd := exec.CommandContext(ctx, "kubectl", args...)
-		output, err := cmd.CombinedOutput()
+		output, err := logging.CombinedOutput(cmd)
 
 		if err == nil && strings.TrimSpace(string(output)) != "" && strings.TrimSpace(string(output)) != "0" {
 			running++
@@ -395,7 +408,7 @@ func checkRequiredCRDs(ctx context.Context, tool string) PreflightResult {
 		}
 
 		cmd := exec.CommandContext(ctx, "kubectl", args...)
-		if _, err := cmd.CombinedOutput(); err == nil {
+		if _, err := logging.CombinedOutput(cmd); err == nil {
 			found++
 		} else {
 			missing = append(missing, crd)
@@ -430,7 +443,7 @@ func checkStorageClasses(ctx context.Context) PreflightResult {
 	}
 
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 
 	if err != nil {
 		result.Status = "warn"
@@ -451,6 +464,45 @@ func checkStorageClasses(ctx context.Context) PreflightResult {
 	return result
 }
 
+func checkHACapacity(ctx context.Context) PreflightResult {
+	result := PreflightResult{Name: "HA Capacity"}
+
+	args := []string{"get", "nodes", "-o", "json"}
+	if preflightContext != "" {
+		args = append([]string{"--context", preflightContext}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		result.Status = "fail"
+		result.Message = "Cannot list nodes"
+		result.Details = err.Error()
+		return result
+	}
+
+	capacity, err := bootstrap.ParseNodeCapacity(output)
+	if err != nil {
+		result.Status = "fail"
+		result.Message = "Cannot read nodes"
+		result.Details = err.Error()
+		return result
+	}
+	return haCapacityResult(result, capacity)
+}
+
+func haCapacityResult(result PreflightResult, capacity *bootstrap.NodeCapacity) PreflightResult {
+	result.Details = fmt.Sprintf("%d schedulable nodes, %s CPU, %s memory", capacity.Nodes, capacity.CPU.String(), capacity.Memory.String())
+	if err := capacity.CheckHA(); err != nil {
+		result.Status = "fail"
+		result.Message = err.Error()
+		return result
+	}
+	result.Status = "ok"
+	result.Message = fmt.Sprintf("%d nodes", capacity.Nodes)
+	return result
+}
+
 func printResult(result PreflightResult) {
 	var icon string
 	var color pterm.Color
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
 )
 
 func TestPreflightResultStructure(t *testing.T) {
@@ -72,6 +74,7 @@ func TestPreflightFlags(t *testing.T) {
 		{"context", ""},
 		{"gitops-tool", "argocd"},
 		{"timeout", "30"},
+		{"ha", "false"},
 	}
 
 	for _, flag := range flags {
@@ -130,3 +133,22 @@ func TestPrintSummaryFormats(t *testing.T) {
 		})
 	}
 }
+
+func TestHACapacityResult(t *testing.T) {
+	nodes := []byte(`{"items": [
+		{"status": {"allocatable": {"cpu": "2", "memory": "4Gi"}, "conditions": [{"type": "Ready", "status": "True"}]}},
+		{"status": {"allocatable": {"cpu": "2", "memory": "4Gi"}, "conditions": [{"type": "Ready", "status": "True"}]}}
+	]}`)
+	capacity, err := bootstrap.ParseNodeCapacity(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := haCapacityResult(PreflightResult{Name: "HA Capacity"}, capacity)
+	if result.Status != "fail" {
+		t.Errorf("Expected Status 'fail' for 2 nodes, got '%s'", result.Status)
+	}
+	if result.Details != "2 schedulable nodes, 4 CPU, 8Gi memory" {
+		t.Errorf("Unexpected Details '%s'", result.Details)
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
+)
+
+// applyPresetFile loads the preset file named by --preset, or by the preset
+// of cfg, and applies it to cfg. It returns nil for built-in presets.
+func applyPresetFile(ctx context.Context, cfg *config.Config) (*config.PresetFile, error) {
+	ref := presetFlag
+	if ref == "" {
+		ref = string(cfg.Preset)
+	}
+	if !config.IsPresetFile(ref) {
+		return nil, nil
+	}
+
+	preset, err := config.LoadPresetFile(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyPresetFile(preset); err != nil {
+		return nil, err
+	}
+	cfg.Preset = config.Preset(ref)
+	return preset, nil
+}
+
+// installPresetPatterns installs the marketplace patterns of a preset file
+// into the generated project. Patterns that are already installed are kept.
+func installPresetPatterns(ctx context.Context, prog *progress.Progress, section *progress.Section, cfg *config.Config, projectPath string, preset *config.PresetFile) error {
+	if preset == nil || len(preset.Patterns) == 0 {
+		return nil
+	}
+	title := fmt.Sprintf("Installing the patterns of preset %s...", preset.Name)
+	return installPatterns(ctx, prog, section, cfg, projectPath, title, preset.Patterns)
+}
+
+// installPatterns installs marketplace patterns into the generated project
+// as one progress step.
+func installPatterns(ctx context.Context, prog *progress.Progress, section *progress.Section, cfg *config.Config, projectPath, title string, patterns []config.PresetPattern) error {
+	step := prog.StartStep(section, title)
+	mp := newMarketplace(projectPath, cfg.GitOpsTool, cfg.Platform)
+	if transform := projectTransform(cfg); transform != nil {
+		mp.GetInstaller().SetTransform(transform)
+	}
+	for _, pattern := range patterns {
+		result, err := mp.Install(ctx, pattern.Name, marketplace.InstallOptions{
+			Version: pattern.Version,
+			Config:  pattern.Config,
+			DryRun:  dryRun,
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to install pattern %s: %w", pattern.Name, err)
+			prog.FailStep(section, step, err)
+			return err
+		}
+		name := pattern.Name
+		if result.Version != "" {
+			name += " " + result.Version
+		}
+		step.AddSubStep(fmt.Sprintf("%s: %s", name, result.Message), progress.StatusSuccess)
+	}
+	prog.SuccessStep(section, step)
+	prog.ShowSubSteps(step)
+	return nil
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func TestApplyPresetFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acme.yaml")
+	content := `kind: Preset
+name: acme
+base: minimal
+config:
+  platform: openshift
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write preset: %v", err)
+	}
+
+	presetFlag = path
+	defer func() { presetFlag = "" }()
+
+	cfg := config.NewDefaultConfig()
+	preset, err := applyPresetFile(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("applyPresetFile() error = %v", err)
+	}
+	applyFlagOverrides(cfg)
+
+	if preset == nil || preset.Name != "acme" {
+		t.Fatalf("applyPresetFile() = %v, want preset acme", preset)
+	}
+	if cfg.Platform != "openshift" {
+		t.Errorf("Platform = %v, want openshift", cfg.Platform)
+	}
+	if cfg.Infra.RBAC {
+		t.Error("Infra.RBAC should be disabled by the minimal base preset")
+	}
+	if string(cfg.Preset) != path {
+		t.Errorf("Preset = %v, want %v", cfg.Preset, path)
+	}
+}
+
+func TestApplyPresetFile_BuiltIn(t *testing.T) {
+	presetFlag = "enterprise"
+	defer func() { presetFlag = "" }()
+
+	preset, err := applyPresetFile(context.Background(), config.NewDefaultConfig())
+	if err != nil || preset != nil {
+		t.Errorf("applyPresetFile() = %v, %v, want nil, nil for a built-in preset", preset, err)
+	}
+}
+
+func TestApplyPresetFile_Missing(t *testing.T) {
+	presetFlag = filepath.Join(t.TempDir(), "missing.yaml")
+	defer func() { presetFlag = "" }()
+
+	if _, err := applyPresetFile(context.Background(), config.NewDefaultConfig()); err == nil {
+		t.Error("applyPresetFile() expected error for a missing preset file")
+	}
+}
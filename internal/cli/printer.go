@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the global --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printer writes command results in the format selected with --output.
+// Commands print a document with print when structured and fall back to
+// their pterm display otherwise.
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+func newPrinter() *printer {
+	return &printer{format: outputFormat, out: os.Stdout}
+}
+
+// structured reports whether results are printed as JSON or YAML.
+func (p *printer) structured() bool {
+	return p.format == outputJSON || p.format == outputYAML
+}
+
+// print writes v as indented JSON or YAML.
+func (p *printer) print(v any) error {
+	switch p.format {
+	case outputJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to render JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(p.out, string(data))
+		return err
+	case outputYAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to render YAML: %w", err)
+		}
+		_, err = p.out.Write(data)
+		return err
+	default:
+		return fmt.Errorf("output format %s is not structured", p.format)
+	}
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+func TestPrinter(t *testing.T) {
+	doc := envActionResult{Action: "delete", Environment: "prod"}
+
+	var out bytes.Buffer
+	p := &printer{format: outputJSON, out: &out}
+	assert.True(t, p.structured())
+	require.NoError(t, p.print(doc))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, map[string]any{"action": "delete", "environment": "prod"}, decoded)
+
+	out.Reset()
+	p.format = outputYAML
+	require.NoError(t, p.print(doc))
+	decoded = nil
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, map[string]any{"action": "delete", "environment": "prod"}, decoded)
+
+	p.format = outputTable
+	assert.False(t, p.structured())
+	assert.Error(t, p.print(doc))
+}
+
+func TestResolveOutputFlags(t *testing.T) {
+	savedFormat, savedDir := outputFormat, output
+	t.Cleanup(func() {
+		outputFormat, output = savedFormat, savedDir
+		_ = rootCmd.PersistentFlags().Set("output-dir", ".")
+		rootCmd.PersistentFlags().Lookup("output-dir").Changed = false
+		pterm.EnableOutput()
+	})
+
+	outputFormat, output = outputJSON, "."
+	require.NoError(t, resolveOutputFlags(rootCmd))
+	assert.False(t, pterm.Output, "structured output silences pterm")
+
+	outputFormat = outputTable
+	require.NoError(t, resolveOutputFlags(rootCmd))
+	assert.True(t, pterm.Output)
+
+	// Before --output-dir, --output was the output directory.
+	outputFormat = "./platforms"
+	require.NoError(t, resolveOutputFlags(rootCmd))
+	assert.Equal(t, outputTable, outputFormat)
+	assert.Equal(t, "./platforms", output)
+
+	require.NoError(t, rootCmd.PersistentFlags().Set("output-dir", "./platforms"))
+	outputFormat = "xml"
+	assert.Error(t, resolveOutputFlags(rootCmd))
+}
+
+func TestSummarizeCredential(t *testing.T) {
+	data, err := json.Marshal(summarizeCredential(&auth.Credential{
+		Name:     "github",
+		Type:     auth.CredentialTypeGit,
+		Provider: "github",
+		Method:   auth.MethodToken,
+		Data:     auth.CredentialData{Token: "ghp_secret"},
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"name":"github"`)
+	assert.NotContains(t, string(data), "ghp_secret")
+}
+
+func TestStructuredOutputCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() {
+		outputFormat = outputTable
+		_ = rootCmd.PersistentFlags().Set("output", outputTable)
+		pterm.EnableOutput()
+	})
+	exportDir := t.TempDir()
+
+	tests := []struct {
+		args []string
+		key  string
+	}{
+		{[]string{"version"}, "version"},
+		{[]string{"templates", "list"}, "templates"},
+		{[]string{"templates", "export", "kubernetes/", "--dir", exportDir, "--force"}, "exported"},
+		{[]string{"templates", "validate"}, "valid"},
+	}
+	for _, tt := range tests {
+		for _, format := range []string{outputJSON, outputYAML} {
+			t.Run(strings.Join(tt.args[:min(2, len(tt.args))], " ")+" -o "+format, func(t *testing.T) {
+				out := captureStdout(t, func() {
+					rootCmd.SetArgs(append(append([]string{}, tt.args...), "-o", format))
+					_, err := rootCmd.ExecuteC()
+					require.NoError(t, err)
+				})
+
+				var decoded map[string]any
+				if format == outputJSON {
+					require.NoError(t, json.Unmarshal(out, &decoded), "stdout: %s", out)
+				} else {
+					require.NoError(t, yaml.Unmarshal(out, &decoded), "stdout: %s", out)
+				}
+				assert.Contains(t, decoded, tt.key)
+			})
+		}
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	require.NoError(t, w.Close())
+	return <-done
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
+	"github.com/ihsanmokhlisse/gitopsi/internal/provision"
+)
+
+// provisionSpec maps a cluster of the clusters section to a provisioning spec.
+func provisionSpec(cl *config.ClusterProvision) *provision.Spec {
+	spec := &provision.Spec{
+		Name:          cl.Name,
+		Provider:      provision.Provider(cl.Provider),
+		Region:        cl.Region,
+		Version:       cl.Version,
+		Nodes:         cl.Nodes,
+		NodeSize:      cl.NodeSize,
+		ResourceGroup: cl.ResourceGroup,
+		Project:       cl.Project,
+		Kubeconfig:    cl.Kubeconfig,
+		ExtraArgs:     cl.ExtraArgs,
+	}
+	if c := cl.CAPI; c != nil {
+		spec.CAPI = &provision.CAPISpec{
+			Infrastructure:    c.Infrastructure,
+			Flavor:            c.Flavor,
+			Namespace:         c.Namespace,
+			ManagementContext: c.ManagementContext,
+			ControlPlaneNodes: c.ControlPlaneNodes,
+		}
+	}
+	return spec
+}
+
+// provisionClusters creates the clusters of the clusters section and points
+// their environments, and the bootstrap cluster when none is set, at them.
+func provisionClusters(ctx context.Context, cfg *config.Config, prog *progress.Progress) error {
+	section := prog.StartSection("Cluster Provisioning")
+	provisioner := provision.New("")
+	for i := range cfg.Clusters {
+		cl := &cfg.Clusters[i]
+		step := prog.StartStep(section, fmt.Sprintf("Provisioning %s with %s...", cl.Name, cl.Provider))
+		result, err := provisioner.Provision(ctx, provisionSpec(cl))
+		if err != nil {
+			prog.FailStep(section, step, err)
+			return fmt.Errorf("cluster provisioning failed: %w", err)
+		}
+		prog.SuccessStep(section, step)
+		if result.Created {
+			step.AddSubStep("Created", progress.StatusSuccess)
+		} else {
+			step.AddSubStep("Already exists", progress.StatusSuccess)
+		}
+		step.AddSubStep(fmt.Sprintf("URL: %s", result.URL), progress.StatusSuccess)
+		step.AddSubStep(fmt.Sprintf("Kubeconfig: %s", result.Kubeconfig), progress.StatusSuccess)
+		prog.ShowSubSteps(step)
+
+		applyProvisionedCluster(cfg, cl, result)
+	}
+	return nil
+}
+
+// applyProvisionedCluster records how to reach a provisioned cluster in its
+// environment, and makes it the bootstrap cluster of init when there is none.
+func applyProvisionedCluster(cfg *config.Config, cl *config.ClusterProvision, result *provision.Result) {
+	for i := range cfg.Environments {
+		env := &cfg.Environments[i]
+		if env.Name != cl.EnvironmentName() {
+			continue
+		}
+		if env.Cluster == "" {
+			env.Cluster = result.URL
+		}
+		env.Kubeconfig = result.Kubeconfig
+		env.Context = result.Context
+	}
+
+	if cfg.Cluster.URL == "" {
+		cfg.Cluster.URL = result.URL
+		cfg.Cluster.Name = cl.Name
+		cfg.Cluster.Kubeconfig = result.Kubeconfig
+		cfg.Cluster.Context = result.Context
+		cfg.Cluster.Auth = config.ClusterAuth{Method: string(cluster.AuthKubeconfig)}
+	}
+}
+
+// environmentKubeconfig returns the kubeconfig of an environment cluster:
+// its own, the one written when it was provisioned, or cluster.kubeconfig.
+func environmentKubeconfig(cfg *config.Config, env config.Environment) string {
+	if env.Kubeconfig != "" {
+		return env.Kubeconfig
+	}
+	if cl := cfg.ProvisionedCluster(env.Name); cl != nil {
+		return provision.New("").KubeconfigPath(provisionSpec(cl))
+	}
+	return cfg.Cluster.Kubeconfig
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+var (
+	registryType       string
+	registryURL        string
+	registryRef        string
+	registryPath       string
+	registryPriority   int
+	registryCredential string
+	registryToken      string
+)
+
+var marketplaceRegistryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage pattern registries",
+	Long: `Add, list and remove the registries patterns are searched and installed from.
+Custom registries are saved to ~/.gitopsi/registries.yaml.
+
+Registry types:
+  git      A Git repository holding patterns/<name>/<version>/pattern.yaml,
+           cloned with the credentials of gitopsi auth
+  oci      An OCI registry holding patterns as artifacts, e.g.
+           oci://ghcr.io/acme/patterns
+  private  An HTTP server serving index.yaml and the patterns
+  local    A directory on disk`,
+}
+
+var marketplaceRegistryAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a pattern registry",
+	Long: `Add a pattern registry. Git registries can be pinned to a branch or tag with
+--ref and hold their patterns in a subdirectory given by --path. An index.yaml
+is generated when the repository has none.
+
+Examples:
+  gitopsi marketplace registry add team --type git --url https://github.com/acme/patterns.git
+  gitopsi marketplace registry add team --type git --url git@github.com:acme/patterns.git --ref v2.0.0 --credential acme-git
+  gitopsi marketplace registry add ghcr --type oci --url oci://ghcr.io/acme/patterns
+  gitopsi marketplace registry add mirror --type private --url https://patterns.acme.internal`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRegistryAdd,
+}
+
+var marketplaceRegistryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the pattern registries",
+	RunE:  runRegistryList,
+}
+
+var marketplaceRegistryRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a pattern registry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRegistryRemove,
+}
+
+var marketplaceRefreshCmd = &cobra.Command{
+	Use:   "refresh [registry]",
+	Short: "Refresh the index of the pattern registries",
+	Long: `Pull the latest commit of Git registries and download the index of the other
+registries, so search and install see newly published patterns. Without a
+registry name, every enabled registry is refreshed.
+
+Examples:
+  gitopsi marketplace refresh
+  gitopsi marketplace refresh team`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMarketplaceRefresh,
+}
+
+func init() {
+	marketplaceCmd.AddCommand(marketplaceRegistryCmd)
+	marketplaceCmd.AddCommand(marketplaceRefreshCmd)
+	marketplaceRegistryCmd.AddCommand(marketplaceRegistryAddCmd)
+	marketplaceRegistryCmd.AddCommand(marketplaceRegistryListCmd)
+	marketplaceRegistryCmd.AddCommand(marketplaceRegistryRemoveCmd)
+
+	marketplaceRegistryAddCmd.Flags().StringVar(&registryType, "type", "git", "Registry type (git, oci, private, local)")
+	marketplaceRegistryAddCmd.Flags().StringVar(&registryURL, "url", "", "Repository URL, server URL or directory of the registry")
+	marketplaceRegistryAddCmd.Flags().StringVar(&registryRef, "ref", "", "Branch or tag of a Git registry (default: the default branch)")
+	marketplaceRegistryAddCmd.Flags().StringVar(&registryPath, "path", "", "Directory of the registry in a Git repository")
+	marketplaceRegistryAddCmd.Flags().IntVar(&registryPriority, "priority", 50, "Registry priority (higher is searched first)")
+	marketplaceRegistryAddCmd.Flags().StringVar(&registryCredential, "credential", "", "gitopsi auth credential of a Git registry (default: matched by URL)")
+	marketplaceRegistryAddCmd.Flags().StringVar(&registryToken, "token", "", "Bearer token of a private registry, such as gitopsi marketplace serve")
+	_ = marketplaceRegistryAddCmd.MarkFlagRequired("url")
+}
+
+// registryCredentials resolves the credentials of Git registries from the
+// gitopsi auth store: the named credential, else a Git credential whose URL
+// covers the registry URL.
+func registryCredentials(ctx context.Context, reg *marketplace.Registry) (*gitops.Credentials, error) {
+	manager, err := getAuthManager()
+	if err != nil {
+		if reg.Credential != "" {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if reg.Credential != "" {
+		cred, err := manager.GetCredential(ctx, reg.Credential)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credential %s of registry '%s': %w", reg.Credential, reg.Name, err)
+		}
+		return gitops.CredentialsFromAuth(ctx, cred)
+	}
+	creds, err := manager.ListCredentials(ctx, auth.CredentialTypeGit)
+	if err != nil {
+		return nil, nil
+	}
+	for _, cred := range creds {
+		if credentialCovers(cred.Metadata.URL, reg.URL) {
+			return gitops.CredentialsFromAuth(ctx, cred)
+		}
+	}
+	return nil, nil
+}
+
+func runRegistryAdd(cmd *cobra.Command, args []string) error {
+	mp := getMarketplace()
+	reg := marketplace.Registry{
+		Name:       args[0],
+		Type:       marketplace.RegistryType(registryType),
+		URL:        registryURL,
+		Ref:        registryRef,
+		Path:       registryPath,
+		Priority:   registryPriority,
+		Credential: registryCredential,
+		Enabled:    true,
+	}
+	switch reg.Type {
+	case marketplace.RegistryTypeGit, marketplace.RegistryTypeOCI, marketplace.RegistryTypePrivate, marketplace.RegistryTypeLocal:
+	default:
+		return fmt.Errorf("unknown registry type %q: use git, oci, private or local", registryType)
+	}
+	if registryToken != "" {
+		if reg.Type != marketplace.RegistryTypePrivate {
+			return fmt.Errorf("--token is only supported by private registries")
+		}
+		reg.Auth = &marketplace.RegistryAuth{Type: "token", Token: registryToken}
+	}
+	if err := mp.GetRegistry().AddRegistry(reg); err != nil {
+		return err
+	}
+
+	// Local registries may be empty until a pattern is published to them.
+	if !mp.GetRegistry().Offline() && reg.Type != marketplace.RegistryTypeLocal {
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Fetching registry %s...", reg.Name))
+		index, err := mp.Refresh(cmd.Context(), reg.Name)
+		if err != nil {
+			spinner.Fail("Failed to fetch registry")
+			return err
+		}
+		spinner.Success(fmt.Sprintf("Registry %s has %d patterns", reg.Name, len(index.Patterns)))
+	}
+	if err := mp.SaveRegistries(); err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Added registry: %s", reg.Name)
+	return nil
+}
+
+type registryView struct {
+	Name       string `json:"name" yaml:"name"`
+	Type       string `json:"type" yaml:"type"`
+	URL        string `json:"url" yaml:"url"`
+	Ref        string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Path       string `json:"path,omitempty" yaml:"path,omitempty"`
+	Priority   int    `json:"priority" yaml:"priority"`
+	Credential string `json:"credential,omitempty" yaml:"credential,omitempty"`
+	Enabled    bool   `json:"enabled" yaml:"enabled"`
+}
+
+func runRegistryList(cmd *cobra.Command, args []string) error {
+	mp := getMarketplace()
+	var views []registryView
+	for _, reg := range mp.GetRegistry().ListRegistries() {
+		views = append(views, registryView{
+			Name:       reg.Name,
+			Type:       string(reg.Type),
+			URL:        reg.URL,
+			Ref:        reg.Ref,
+			Path:       reg.Path,
+			Priority:   reg.Priority,
+			Credential: reg.Credential,
+			Enabled:    reg.Enabled,
+		})
+	}
+	if p := newPrinter(); p.structured() {
+		return p.print(views)
+	}
+
+	rows := [][]string{{"Name", "Type", "URL", "Ref", "Priority", "Enabled"}}
+	for _, v := range views {
+		ref := v.Ref
+		if ref == "" {
+			ref = "-"
+		}
+		rows = append(rows, []string{v.Name, v.Type, v.URL, ref, strconv.Itoa(v.Priority), strconv.FormatBool(v.Enabled)})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+	return nil
+}
+
+func runRegistryRemove(cmd *cobra.Command, args []string) error {
+	if args[0] == "official" {
+		return fmt.Errorf("the official registry cannot be removed")
+	}
+	mp := getMarketplace()
+	if err := mp.GetRegistry().RemoveRegistry(args[0]); err != nil {
+		return err
+	}
+	if err := mp.SaveRegistries(); err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Removed registry: %s", args[0])
+	return nil
+}
+
+func runMarketplaceRefresh(cmd *cobra.Command, args []string) error {
+	mp := getMarketplace()
+	var names []string
+	if len(args) > 0 {
+		names = args
+	} else {
+		for _, reg := range mp.GetRegistry().ListRegistries() {
+			if reg.Enabled {
+				names = append(names, reg.Name)
+			}
+		}
+	}
+
+	failed := 0
+	for _, name := range names {
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Refreshing %s...", name))
+		index, err := mp.Refresh(cmd.Context(), name)
+		if err != nil {
+			spinner.Fail(fmt.Sprintf("%s: %v", name, err))
+			failed++
+			continue
+		}
+		spinner.Success(fmt.Sprintf("%s: %d patterns", name, len(index.Patterns)))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to refresh %d of %d registries", failed, len(names))
+	}
+	return nil
+}
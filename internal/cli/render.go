@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/render"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render the final manifests of each environment",
+	Long: `Runs kustomize build on every environment overlay of the GitOps repository
+and prints the fully rendered manifests, exactly as ArgoCD or Flux will apply
+them. Kustomize is built in: no kustomize or kubectl binary is needed.
+
+With --out-dir the manifests are written to <out-dir>/<env>/<section>.yaml
+instead, ready to publish as a CI artifact.
+
+Examples:
+  gitopsi render --path ./my-platform
+  gitopsi render --path ./my-platform --env prod
+  gitopsi render --path ./my-platform/applications/overlays/prod
+  gitopsi render --path ./my-platform --out-dir ./rendered`,
+	Args: cobra.NoArgs,
+	RunE: runRender,
+}
+
+var (
+	renderPath   string
+	renderEnv    string
+	renderOutDir string
+)
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVar(&renderPath, "path", ".", "Path to the GitOps repository or a kustomization directory")
+	renderCmd.Flags().StringVar(&renderEnv, "env", "", "Only render overlays for this environment")
+	renderCmd.Flags().StringVar(&renderOutDir, "out-dir", "", "Write the manifests to this directory instead of stdout")
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	overlays, err := render.Render(&render.Options{
+		Path:        renderPath,
+		Environment: renderEnv,
+	})
+	if err != nil {
+		return err
+	}
+
+	if renderOutDir != "" {
+		files, err := render.Write(renderOutDir, overlays)
+		if err != nil {
+			return err
+		}
+		if p := newPrinter(); p.structured() {
+			return p.print(overlays)
+		}
+		for i, file := range files {
+			pterm.Success.Printfln("%s (%d resources)", file, overlays[i].Resources)
+		}
+		return nil
+	}
+
+	if p := newPrinter(); p.structured() {
+		return p.print(overlays)
+	}
+	fmt.Fprint(cmd.OutOrStdout(), render.Join(overlays))
+	return nil
+}
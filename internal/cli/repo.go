@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+)
+
+// newGitProvider returns the provider API client for cfg.Git.URL, authenticated
+// with the token of creds.
+var newGitProvider = func(ctx context.Context, cfg *config.Config, creds *gitops.Credentials) (gitprovider.Provider, error) {
+	return gitprovider.New(ctx, cfg.Git.URL, gitprovider.Options{
+		Provider: git.ProviderType(cfg.Git.Provider.Name),
+		Token:    providerToken(creds),
+		Probe:    true,
+	})
+}
+
+// providerToken returns the API token of push credentials: the token, or the
+// password of basic auth credentials.
+func providerToken(creds *gitops.Credentials) string {
+	if creds == nil {
+		return ""
+	}
+	if creds.Token != "" {
+		return creds.Token
+	}
+	return creds.Password
+}
+
+// createdRepository describes a repository created by ensureRepository.
+type createdRepository struct {
+	Repository *git.Repository
+	// DeployKey is the title of the registered deploy key, if any.
+	DeployKey string
+	// DeployKeyErr is set when the deploy key could not be registered; the
+	// repository is usable with the API token regardless.
+	DeployKeyErr error
+}
+
+// ensureRepository creates cfg.Git.URL through the provider API when
+// git.create_if_missing is set and the repository does not exist. When pushing
+// over SSH with git.auth.ssh_key, the key is registered as a read-write deploy
+// key of the new repository. It returns nil when nothing was created.
+func ensureRepository(ctx context.Context, cfg *config.Config, creds *gitops.Credentials) (*createdRepository, error) {
+	if !cfg.Git.CreateIfMissing {
+		return nil, nil
+	}
+	if providerToken(creds) == "" {
+		return nil, fmt.Errorf("a token is required to create %s (use --git-token or GITOPSI_GIT_TOKEN)", cfg.Git.URL)
+	}
+	provider, err := newGitProvider(ctx, cfg, creds)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := provider.GetRepo(ctx); err == nil {
+		return nil, nil
+	} else if !errors.Is(err, gitprovider.ErrNotFound) {
+		return nil, fmt.Errorf("failed to look up %s: %w", provider.Repo().FullName(), err)
+	}
+
+	description := cfg.Git.Repository.Description
+	if description == "" {
+		description = cfg.Project.Description
+	}
+	visibility := git.Visibility(cfg.Git.Repository.Visibility)
+	if visibility == "" {
+		visibility = git.VisibilityPrivate
+	}
+	// The repository is left empty so the first push sets its default branch.
+	repo, err := provider.CreateRepo(ctx, git.CreateRepoOptions{
+		Name:        provider.Repo().Name,
+		Description: description,
+		Visibility:  visibility,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created := &createdRepository{Repository: repo}
+	if creds.SSHKey != "" {
+		key, err := authorizedKey(creds)
+		if err == nil {
+			_, err = provider.AddDeployKey(ctx, gitprovider.DeployKey{Title: "gitopsi", Key: key})
+		}
+		if err != nil {
+			created.DeployKeyErr = fmt.Errorf("failed to register deploy key: %w", err)
+		} else {
+			created.DeployKey = "gitopsi"
+		}
+	}
+	return created, nil
+}
+
+// authorizedKey returns the public half of the SSH key of creds in
+// authorized_keys format.
+func authorizedKey(creds *gitops.Credentials) (string, error) {
+	var signer ssh.Signer
+	var err error
+	if creds.SSHKeyPassphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(creds.SSHKey), []byte(creds.SSHKeyPassphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(creds.SSHKey))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse SSH key: %w", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))), nil
+}
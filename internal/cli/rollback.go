@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/environment"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+)
+
+var (
+	rollbackEnv      string
+	rollbackTo       string
+	rollbackNoCommit bool
+	rollbackPush     bool
+	rollbackSync     bool
+	rollbackWait     bool
+	rollbackTimeout  time.Duration
+	rollbackArgoApp  string
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <application>",
+	Short: "Roll an application back to its previous state in an environment",
+	Long: `Roll an application back to a previous state in an environment.
+
+Without --to, the latest promotion into the environment recorded in
+.gitopsi/promotions.yaml is undone. Without a promotion record, the
+application's overlay and HelmRelease are restored from the commit before the
+latest commit changing them. --to accepts a promotion ID or a Git revision.
+
+Only the application's image overrides, overlay patch files and HelmRelease
+image values are restored; other applications are left alone. The rollback is
+committed to the current branch (--push to push it, --pr to open a pull request
+instead) and can trigger an ArgoCD sync of the environment.
+
+Examples:
+  gitopsi rollback myapp --env prod --dry-run
+  gitopsi rollback myapp --env prod --push --sync --wait
+  gitopsi rollback myapp --env prod --to 20260110T120000Z-myapp-prod --pr
+  gitopsi rollback myapp --env prod --to v1.4.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRollback,
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().StringVar(&rollbackEnv, "env", "", "Environment to roll back (required)")
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Promotion ID to undo or Git revision to restore")
+	rollbackCmd.Flags().StringVar(&envProjectPath, "project", ".", "Path to gitopsi project")
+	rollbackCmd.Flags().BoolVar(&rollbackNoCommit, "no-commit", false, "Leave the rollback uncommitted")
+	rollbackCmd.Flags().BoolVar(&rollbackPush, "push", false, "Push the rollback commit to the current branch")
+	rollbackCmd.Flags().BoolVar(&rollbackSync, "sync", false, "Sync the environment's ArgoCD Application after pushing")
+	rollbackCmd.Flags().BoolVar(&rollbackWait, "wait", false, "Wait for the ArgoCD Application to be Synced and Healthy")
+	rollbackCmd.Flags().DurationVar(&rollbackTimeout, "timeout", 5*time.Minute, "How long --wait waits")
+	rollbackCmd.Flags().StringVar(&rollbackArgoApp, "argocd-app", "", "ArgoCD Application to sync (default: from promotion.gates.argocd_application)")
+	addPullRequestFlags(rollbackCmd)
+	_ = rollbackCmd.MarkFlagRequired("env")
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	app := args[0]
+	if openPR && (rollbackSync || rollbackWait) {
+		return fmt.Errorf("--sync and --wait apply once the pull request is merged; run them without --pr")
+	}
+	if openPR && (rollbackPush || rollbackNoCommit) {
+		return fmt.Errorf("--pr cannot be combined with --push or --no-commit")
+	}
+
+	mgr, err := getEnvManager()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadProjectConfig(envProjectPath)
+	if err != nil {
+		return err
+	}
+
+	result, err := mgr.Rollback(environment.RollbackOptions{
+		Application: app,
+		Env:         rollbackEnv,
+		To:          rollbackTo,
+		DryRun:      dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	if p := newPrinter(); p.structured() {
+		if err := p.print(result); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		pterm.Warning.Println("DRY RUN - No changes made")
+	}
+	pterm.Success.Println(result.Message)
+	if len(result.Changes) > 0 {
+		pterm.Println()
+		pterm.Info.Println("Changes:")
+		for _, change := range result.Changes {
+			pterm.Info.Printf("  • %s\n", change)
+		}
+	}
+	if result.Record != nil {
+		pterm.Info.Printf("Recorded rollback %s in %s\n", result.Record.ID, environment.PromotionHistoryFile)
+	}
+	if dryRun {
+		return nil
+	}
+
+	if result.Record != nil {
+		pterm.Println()
+		message := fmt.Sprintf("fix: Roll back %s in %s to %s", app, rollbackEnv, result.Target)
+		switch {
+		case openPR:
+			return deliverPullRequest(cmd.Context(), envProjectPath, message)
+		case !rollbackNoCommit:
+			if err := deliverCommit(cmd.Context(), envProjectPath, message, rollbackPush); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !rollbackSync && !rollbackWait {
+		return nil
+	}
+	if !rollbackPush {
+		pterm.Warning.Println("ArgoCD syncs from the remote: the rollback takes effect once it is pushed")
+	}
+	return syncRollback(cmd.Context(), cfg, app)
+}
+
+// deliverCommit commits the changes of the Git repository containing dir on
+// its current branch, and pushes the branch when push is set.
+func deliverCommit(ctx context.Context, dir, message string, push bool) error {
+	if !push {
+		result, err := gitops.Commit(dir, message)
+		if errors.Is(err, gitops.ErrNoChanges) {
+			pterm.Info.Println("No changes to commit")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pterm.Success.Printf("Committed %s on %s (push it to apply the rollback)\n", result.Commit[:7], result.Branch)
+		return nil
+	}
+
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("failed to open Git repository for %s: %w", dir, err)
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("failed to find remote %s: %w", git.DefaultRemoteName, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("HEAD is detached; check out the branch to push the rollback to")
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	cfg := config.NewDefaultConfig()
+	cfg.Git.URL = remote.Config().URLs[0]
+	cfg.Git.Auth.Token = os.Getenv("GITOPSI_GIT_TOKEN")
+	creds, err := gitPushCredentials(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Pushing rollback...")
+	result, err := gitops.NewPusher(&gitops.PushOptions{
+		Dir:           wt.Filesystem.Root(),
+		RemoteURL:     cfg.Git.URL,
+		Branch:        head.Name().Short(),
+		CommitMessage: message,
+		Credentials:   creds,
+	}).Push(ctx)
+	if err != nil {
+		spinner.Fail("Failed to push rollback")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Pushed %s to %s", result.Commit[:7], result.Branch))
+	return nil
+}
+
+// syncRollback syncs the ArgoCD Application of the rolled back environment and
+// waits for it with --wait.
+func syncRollback(ctx context.Context, cfg *config.Config, app string) error {
+	name, namespace, kubeContext, err := rollbackApplication(cfg, app, rollbackEnv)
+	if err != nil {
+		return err
+	}
+
+	if rollbackSync {
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Syncing Application %s...", name))
+		if err := environment.SyncArgoCDApplication(ctx, kubeContext, namespace, name); err != nil {
+			spinner.Fail("Failed to sync")
+			return err
+		}
+		spinner.Success(fmt.Sprintf("Sync of %s started", name))
+	}
+	if rollbackWait {
+		client, err := environment.NewArgoCDClient(kubeContext)
+		if err != nil {
+			return err
+		}
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Waiting for %s to be Synced and Healthy...", name))
+		progress := func(s environment.ApplicationState) {
+			spinner.UpdateText(fmt.Sprintf("Waiting for %s to be Synced and Healthy: %s/%s", name, s.Health, s.Sync))
+		}
+		if err := environment.WaitForApplications(ctx, client, namespace, []string{name}, rollbackTimeout, progress); err != nil {
+			spinner.Fail("Application is not healthy")
+			return err
+		}
+		spinner.Success(fmt.Sprintf("%s is Synced and Healthy", name))
+	}
+	return nil
+}
+
+// rollbackApplication returns the ArgoCD Application deploying app in env, its
+// namespace and the kubeconfig context of the environment.
+func rollbackApplication(cfg *config.Config, app, env string) (name, namespace, kubeContext string, err error) {
+	namespace = "argocd"
+	if cfg != nil {
+		namespace = promotionArgoCDNamespace(cfg)
+		if e := cfg.GetEnvironment(env); e != nil {
+			kubeContext = e.Context
+		}
+	}
+
+	name = rollbackArgoApp
+	if name == "" {
+		if cfg == nil {
+			return "", "", "", fmt.Errorf("no gitopsi.yaml found: name the Application to sync with --argocd-app")
+		}
+		name = strings.NewReplacer("{app}", app, "{env}", env).Replace(argoCDApplicationPattern(cfg))
+	}
+	return name, namespace, kubeContext, nil
+}
@@ -2,17 +2,25 @@ package cli
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
 )
 
 var (
-	cfgFile string
-	output  string
-	dryRun  bool
-	verbose bool
+	cfgFile      string
+	output       string
+	outputFormat string
+	dryRun       bool
+	verbose      bool
+	templatesDir string
 )
 
 var rootCmd = &cobra.Command{
@@ -27,23 +35,57 @@ and generates documentation, scripts, and CI/CD configurations.
 Examples:
   gitopsi init                     Interactive mode
   gitopsi init --config gitops.yaml   Config file mode
-  gitopsi init --dry-run           Preview without writing`,
+  gitopsi init --dry-run           Preview without writing
+  gitopsi env list -o json         Machine readable output`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLogging(cmd); err != nil {
+			return err
+		}
+		if file := viper.ConfigFileUsed(); file != "" {
+			slog.Debug("using config file", "path", file)
+		}
+		if err := setupNetwork(); err != nil {
+			return err
+		}
+		if err := setupCredential(); err != nil {
+			return err
+		}
+		return resolveOutputFlags(cmd)
+	},
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	start := time.Now()
+	flushTelemetry := setupTelemetry()
+	defer flushTelemetry()
+
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil && cmd != nil {
+		slog.Debug("command failed", "command", cmd.CommandPath(), "error", err)
+	}
+	recordAudit(cmd, start, err)
+	cleanupCredentials()
+	_ = closeLog()
+	return err
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: gitops.yaml)")
-	rootCmd.PersistentFlags().StringVar(&output, "output", ".", "output directory")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format: table, json, yaml")
+	rootCmd.PersistentFlags().StringVar(&output, "output-dir", ".", "output directory")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "preview without writing files")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logging.DefaultLevel, "log level: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "log format: text, json")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "append the log to a file, at debug level")
+	rootCmd.PersistentFlags().BoolVar(&insecureTLS, "insecure-tls", false, "skip TLS certificate verification of outbound connections (unsafe)")
+	rootCmd.PersistentFlags().StringVar(&platformCredential, "cluster-credential", "", "stored platform credential to reach clusters with, in place of the kubeconfig")
+	rootCmd.PersistentFlags().StringVar(&templatesDir, "templates-dir", "", "directory of template overrides (default: "+templates.ProjectOverrideDir+")")
 
 	_ = viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
-	_ = viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	_ = viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output-dir"))
 	_ = viper.BindPFlag("dry-run", rootCmd.PersistentFlags().Lookup("dry-run"))
 	_ = viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 
@@ -61,11 +103,33 @@ func initConfig() {
 
 	viper.AutomaticEnv()
 
-	if err := viper.ReadInConfig(); err == nil {
-		if verbose {
-			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	// --templates-dir takes precedence over the per-project override directory.
+	templates.SetOverrideDirs(templatesDir, templates.ProjectOverrideDir)
+
+	_ = viper.ReadInConfig()
+}
+
+// resolveOutputFlags validates --output and silences the pterm output of
+// commands printing JSON or YAML. A value that is not a format is taken as the
+// output directory, as --output was before --output-dir.
+func resolveOutputFlags(cmd *cobra.Command) error {
+	switch outputFormat {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		if cmd.Flags().Changed("output-dir") {
+			return fmt.Errorf("invalid output format: %s (must be table, json or yaml)", outputFormat)
 		}
+		fmt.Fprintf(os.Stderr, "Warning: --output %s is deprecated for the output directory, use --output-dir %s\n", outputFormat, outputFormat)
+		output = outputFormat
+		outputFormat = outputTable
+	}
+
+	if newPrinter().structured() {
+		pterm.DisableOutput()
+	} else {
+		pterm.EnableOutput()
 	}
+	return nil
 }
 
 func GetConfig() string {
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/scan"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Scan a repository for problems",
+}
+
+var scanSecretsCmd = &cobra.Command{
+	Use:   "secrets [path]",
+	Short: "Detect credentials committed to a repository",
+	Long: `Scan every file of a repository for accidentally committed credentials:
+private keys, cloud and Git provider tokens, service account tokens, kubeconfig
+client keys, unencrypted Kubernetes Secret data and high-entropy values of
+keys named like secrets. Matches are redacted in the output.
+
+Accepted findings are listed in .gitopsi/secrets-allowlist.yaml at the
+repository root:
+
+  paths:                  # Files not scanned (** matches directories)
+    - docs/examples/**
+  rules:                  # Rules not applied
+    - high-entropy
+  fingerprints:           # Single findings, as printed by this command
+    - tests/fixture.yaml:private-key:12
+
+A line containing "gitopsi:allow" is never reported. The same scan runs as
+part of gitopsi validate and the Git hooks of gitopsi hooks install.
+
+Examples:
+  gitopsi scan secrets
+  gitopsi scan secrets ./my-platform -o json
+  gitopsi scan secrets --allowlist ci/allowlist.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runScanSecrets,
+}
+
+var scanAllowlist string
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+	scanCmd.AddCommand(scanSecretsCmd)
+
+	scanSecretsCmd.Flags().StringVar(&scanAllowlist, "allowlist", "", "Allowlist file (default: .gitopsi/secrets-allowlist.yaml in the repository)")
+}
+
+func runScanSecrets(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	opts := &scan.Options{Path: path}
+	if scanAllowlist != "" {
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		file, err := filepath.Abs(scanAllowlist)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", scanAllowlist, err)
+		}
+		if opts.Allowlist, err = scan.LoadAllowlist(file, root); err != nil {
+			return err
+		}
+	}
+
+	report, err := scan.Scan(opts)
+	if err != nil {
+		return err
+	}
+
+	if p := newPrinter(); p.structured() {
+		if err := p.print(report); err != nil {
+			return err
+		}
+	} else {
+		printLeaks(report.Findings)
+		if len(report.Findings) == 0 {
+			pterm.Success.Printfln("No leaked credentials in %d files (%d allowlisted)", report.Scanned, report.Allowed)
+		}
+	}
+
+	if len(report.Findings) > 0 {
+		return fmt.Errorf("%d leaked credentials found: remove and rotate them, or allowlist their fingerprints in %s", len(report.Findings), scan.AllowlistFile)
+	}
+	return nil
+}
+
+func printLeaks(findings []scan.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+	rows := [][]string{{"SEVERITY", "RULE", "FINGERPRINT", "MATCH"}}
+	for _, f := range findings {
+		rows = append(rows, []string{f.Severity, f.Rule, f.Fingerprint(), f.Match})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+}
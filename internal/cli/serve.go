@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+var marketplaceServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Host a pattern registry over HTTP",
+	Long: `Serve a registry directory as an internal marketplace that gitopsi consumes
+as a private registry:
+
+  GET  /index.yaml                        registry index with download counters
+  GET  /patterns/<name>/<version>/<file>  pattern files
+  GET  /api/v1/search?q=&category=&tag=   search
+  GET  /api/v1/patterns/<name>            pattern index entry
+  GET  /api/v1/stats                      download counters
+  POST /api/v1/patterns                   gitopsi marketplace publish uploads
+
+The directory has the layout of a local registry and is created when missing.
+With a token (--token or GITOPSI_MARKETPLACE_TOKEN), every request except
+/healthz requires it as a bearer token. --read-only rejects uploads.
+
+Examples:
+  gitopsi marketplace serve --dir ./registry
+  gitopsi marketplace serve --dir /srv/patterns --addr :8443 --tls-cert tls.crt --tls-key tls.key --read-only
+  gitopsi marketplace registry add internal --type private --url https://patterns.example.com --token $TOKEN`,
+	RunE: runMarketplaceServe,
+}
+
+var (
+	serveDir      string
+	serveAddr     string
+	serveToken    string
+	serveReadOnly bool
+	serveURL      string
+	serveTLSCert  string
+	serveTLSKey   string
+)
+
+func init() {
+	marketplaceCmd.AddCommand(marketplaceServeCmd)
+	marketplaceServeCmd.Flags().StringVar(&serveDir, "dir", ".", "Registry directory to serve")
+	marketplaceServeCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	marketplaceServeCmd.Flags().StringVar(&serveToken, "token", "", "Bearer token required by requests (default: $GITOPSI_MARKETPLACE_TOKEN)")
+	marketplaceServeCmd.Flags().BoolVar(&serveReadOnly, "read-only", false, "Reject pattern uploads")
+	marketplaceServeCmd.Flags().StringVar(&serveURL, "url", "", "External URL of the marketplace, returned to uploads")
+	marketplaceServeCmd.Flags().StringVar(&serveTLSCert, "tls-cert", "", "TLS certificate file")
+	marketplaceServeCmd.Flags().StringVar(&serveTLSKey, "tls-key", "", "TLS private key file")
+}
+
+func runMarketplaceServe(cmd *cobra.Command, args []string) error {
+	if (serveTLSCert == "") != (serveTLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("GITOPSI_MARKETPLACE_TOKEN")
+	}
+	server, err := marketplace.NewServer(marketplace.ServerOptions{
+		Dir:      serveDir,
+		Token:    token,
+		ReadOnly: serveReadOnly,
+		URL:      serveURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              serveAddr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	mode := "read-write"
+	if serveReadOnly {
+		mode = "read-only"
+	}
+	pterm.Info.Printfln("Serving %s on %s (%s)", serveDir, serveAddr, mode)
+	if token == "" {
+		pterm.Warning.Println("No token configured: the marketplace is open to anyone who can reach it")
+	}
+
+	if serveTLSCert != "" {
+		err = httpServer.ListenAndServeTLS(serveTLSCert, serveTLSKey)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
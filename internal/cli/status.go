@@ -2,27 +2,66 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
 	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
+	"github.com/ihsanmokhlisse/gitopsi/internal/status"
 )
 
 var statusCmd = &cobra.Command{
-	Use:   "status",
+	Use:   "status [path]",
 	Short: "Show GitOps setup status",
-	Long: `Display the current status of the GitOps setup including
-Git repository, cluster connection, and application sync status.
+	Long: `Display a dashboard of the project and its clusters:
+
+- Repository: files differing from the Git HEAD and commits not pushed to (or
+  not pulled from) the tracked branch, as of the last fetch
+- Environments: ArgoCD/Flux controller health and the sync and health state of
+  the environment's Applications or Kustomizations, using the environment's
+  kubeconfig context
+- Patterns installed from the marketplace
+- Credentials expiring within --expiry-warning
+
+Environments come from gitopsi.yaml in the project (or --config), or else from
+the overlay directories.
 
 Examples:
-  gitopsi status                # Show full status
-  gitopsi status --json         # Output as JSON
-  gitopsi status --quiet        # Minimal output`,
+  gitopsi status                    # Show full status
+  gitopsi status ./my-platform --env prod
+  gitopsi status --offline          # Skip cluster queries
+  gitopsi status -o json            # Machine readable output
+  gitopsi status --quiet            # Only the overall status and warnings`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runStatus,
 }
 
+var (
+	statusEnv           string
+	statusKubeconfig    string
+	statusOffline       bool
+	statusExpiryWarning time.Duration
+	statusTimeout       time.Duration
+	jsonOutput          bool
+	quiet               bool
+)
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVar(&statusEnv, "env", "", "Only show this environment")
+	statusCmd.Flags().StringVar(&statusKubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	statusCmd.Flags().BoolVar(&statusOffline, "offline", false, "Skip cluster queries")
+	statusCmd.Flags().DurationVar(&statusExpiryWarning, "expiry-warning", status.DefaultExpiryWarning, "Warn about credentials expiring within this duration")
+	statusCmd.Flags().DurationVar(&statusTimeout, "timeout", 30*time.Second, "Timeout for the queries of each cluster")
+	statusCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON (same as -o json)")
+	statusCmd.Flags().BoolVar(&quiet, "quiet", false, "Minimal output")
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -30,25 +69,237 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		projectPath = args[0]
 	}
+	p := newPrinter()
+	if jsonOutput {
+		p.format = outputJSON
+	}
+	if _, err := os.Stat(projectPath); err != nil {
+		return fmt.Errorf("project path does not exist: %s", projectPath)
+	}
 
-	summary, err := progress.LoadSummary(projectPath)
+	opts, err := statusOptions(projectPath)
 	if err != nil {
-		return fmt.Errorf("no gitopsi setup found in current directory: %w", err)
+		return err
 	}
 
-	p := progress.New("Status", summary.Setup.Version)
-	p.SetQuiet(quiet)
-	p.SetJSON(jsonOutput)
+	var spinner *pterm.SpinnerPrinter
+	if !p.structured() && !quiet {
+		spinner, _ = pterm.DefaultSpinner.WithRemoveWhenDone().Start("Collecting status...")
+	}
+	report := status.New(opts).Run(cmd.Context())
+	if spinner != nil {
+		_ = spinner.Stop()
+	}
 
-	p.ShowSummary(summary)
+	if p.structured() {
+		if err := p.print(report); err != nil {
+			return err
+		}
+	} else {
+		printStatusReport(report)
+	}
 
+	if report.HasFailures() {
+		return fmt.Errorf("status found %d problems", len(report.Warnings))
+	}
 	return nil
 }
 
-var jsonOutput bool
-var quiet bool
+// statusOptions builds the aggregator options from the gitopsi.yaml of the
+// project, the setup summary written by init, or the project layout.
+func statusOptions(projectPath string) (*status.Options, error) {
+	cfg, err := loadProjectConfig(projectPath)
+	if err != nil {
+		return nil, err
+	}
 
-func init() {
-	statusCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON/YAML")
-	statusCmd.Flags().BoolVar(&quiet, "quiet", false, "Minimal output")
+	backend, err := authStoreBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &status.Options{
+		ProjectPath:   projectPath,
+		StoreBackend:  backend,
+		Kubeconfig:    statusKubeconfig,
+		ExpiryWarning: statusExpiryWarning,
+		Timeout:       statusTimeout,
+		SkipCluster:   statusOffline,
+	}
+	if cfg != nil {
+		opts.Project = cfg.Project.Name
+		opts.GitOpsTool = cfg.GitOpsTool
+		opts.Platform = cfg.Platform
+		opts.ArgoCDNamespace = promotionArgoCDNamespace(cfg)
+		opts.Environments = statusEnvironments(cfg)
+	} else {
+		abs, err := filepath.Abs(projectPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve project path: %w", err)
+		}
+		opts.Project = filepath.Base(abs)
+		if summary, err := progress.LoadSummary(projectPath); err == nil {
+			opts.GitOpsTool = summary.GitOpsTool.Name
+			opts.ArgoCDNamespace = summary.GitOpsTool.Namespace
+		}
+		for _, name := range overlayEnvironments(projectPath) {
+			opts.Environments = append(opts.Environments, status.Environment{Name: name})
+		}
+	}
+
+	if statusEnv != "" {
+		var selected []status.Environment
+		for _, env := range opts.Environments {
+			if env.Name == statusEnv {
+				selected = append(selected, env)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("environment %s not found", statusEnv)
+		}
+		opts.Environments = selected
+	}
+	return opts, nil
+}
+
+// overlayEnvironments returns the environments with an application or
+// infrastructure overlay.
+func overlayEnvironments(projectPath string) []string {
+	seen := map[string]bool{}
+	for _, dir := range []string{"applications/overlays", "infrastructure/overlays"} {
+		entries, err := os.ReadDir(filepath.Join(projectPath, dir))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				seen[e.Name()] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printStatusReport(report *status.Report) {
+	if quiet {
+		pterm.Printf("%s %s: %s\n", statusIcon(report.Status), report.Project, report.Status)
+		for _, w := range report.Warnings {
+			pterm.Printf("   └─ %s\n", w)
+		}
+		return
+	}
+
+	pterm.DefaultHeader.WithFullWidth().Println("📊 gitopsi status: " + report.Project)
+	fmt.Println()
+
+	repo := report.Repository
+	pterm.DefaultSection.Println("Repository")
+	if repo.Branch != "" || repo.Head != "" {
+		pterm.Printf("   Branch: %s @ %s\n", valueOrDash(repo.Branch), repo.Head)
+	}
+	pterm.Printf("%s %s\n", statusIcon(repo.Status), repo.Message)
+	files := append(append([]string{}, repo.Modified...), repo.Untracked...)
+	for i, file := range files {
+		if i == 10 {
+			pterm.Printf("   └─ %s\n", pterm.FgGray.Sprintf("... and %d more", len(files)-i))
+			break
+		}
+		pterm.Printf("   └─ %s\n", pterm.FgGray.Sprint(file))
+	}
+
+	if len(report.Environments) > 0 {
+		pterm.DefaultSection.Println("Environments")
+		rows := [][]string{{"ENVIRONMENT", "CONTEXT", "BOOTSTRAP", "APPLICATIONS", "STATUS"}}
+		for _, env := range report.Environments {
+			var bootstrap []string
+			for _, b := range env.Bootstrap {
+				bootstrap = append(bootstrap, fmt.Sprintf("%s: %s", b.Tool, b.Message))
+			}
+			rows = append(rows, []string{
+				env.Name,
+				valueOrDash(env.Context),
+				valueOrDash(strings.Join(bootstrap, ", ")),
+				fmt.Sprintf("%d", len(env.Applications)),
+				statusIcon(env.Status) + " " + env.Message,
+			})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+
+		rows = [][]string{{"ENVIRONMENT", "APPLICATION", "HEALTH", "SYNC"}}
+		for _, env := range report.Environments {
+			for _, app := range env.Applications {
+				rows = append(rows, []string{env.Name, app.Name, statusIcon(app.Status) + " " + app.Health, app.Sync})
+			}
+		}
+		if len(rows) > 1 {
+			fmt.Println()
+			_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		}
+	}
+
+	if len(report.Patterns) > 0 {
+		pterm.DefaultSection.Println("Patterns")
+		rows := [][]string{{"PATTERN", "VERSION", "HEALTH"}}
+		for _, p := range report.Patterns {
+			rows = append(rows, []string{p.Name, valueOrDash(p.Version), statusIcon(p.Status) + " " + p.Health})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+	}
+
+	if len(report.Credentials) > 0 {
+		pterm.DefaultSection.Println("Credentials")
+		rows := [][]string{{"CREDENTIAL", "TYPE", "PROVIDER", "EXPIRY"}}
+		for _, c := range report.Credentials {
+			rows = append(rows, []string{c.Name, c.Type, valueOrDash(c.Provider), statusIcon(c.Status) + " " + c.Message})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+	}
+
+	fmt.Println()
+	switch {
+	case report.Status == status.StatusFail:
+		pterm.Error.Printf("%d problems need attention\n", len(report.Warnings))
+	case len(report.Warnings) > 0:
+		pterm.Warning.Printf("%d warnings\n", len(report.Warnings))
+	default:
+		pterm.Success.Println("Everything looks good")
+	}
+	for _, w := range report.Warnings {
+		pterm.Printf("   └─ %s\n", w)
+	}
+}
+
+func statusIcon(s status.Status) string {
+	switch s {
+	case status.StatusOK:
+		return "✅"
+	case status.StatusWarn:
+		return "⚠️ "
+	case status.StatusFail:
+		return "❌"
+	default:
+		return "⏭️ "
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// statusEnvironments returns the environments of a config as status
+// environments.
+func statusEnvironments(cfg *config.Config) []status.Environment {
+	envs := make([]status.Environment, 0, len(cfg.Environments))
+	for _, env := range cfg.Environments {
+		envs = append(envs, status.Environment{Name: env.Name, Context: env.Context})
+	}
+	return envs
 }
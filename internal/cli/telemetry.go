@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/telemetry"
+)
+
+// telemetryShutdownTimeout bounds how long exiting waits for telemetry to
+// be exported.
+const telemetryShutdownTimeout = 5 * time.Second
+
+// telemetryOptions returns the telemetry export options and whether the user
+// opted in with telemetry.enabled or GITOPSI_TELEMETRY, which takes
+// precedence.
+func telemetryOptions() (telemetry.Options, bool) {
+	settings, err := config.LoadUserSettings(config.DefaultUserSettingsPath())
+	if err != nil {
+		settings = &config.UserSettings{}
+	}
+	enabled := settings.Telemetry.Enabled
+	if env := os.Getenv("GITOPSI_TELEMETRY"); env != "" {
+		enabled, _ = strconv.ParseBool(env)
+	}
+	return telemetry.Options{Endpoint: settings.Telemetry.Endpoint, Version: Version}, enabled
+}
+
+// setupTelemetry starts exporting telemetry when the user opted in and
+// returns the function flushing it before exit.
+func setupTelemetry() func() {
+	opts, enabled := telemetryOptions()
+	if !enabled {
+		return func() {}
+	}
+	shutdown, err := telemetry.Setup(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: telemetry disabled: %v\n", err)
+		return func() {}
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			slog.Debug("failed to export telemetry", "error", err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func TestTelemetryOptions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GITOPSI_TELEMETRY", "")
+
+	if _, enabled := telemetryOptions(); enabled {
+		t.Error("telemetry should be disabled by default")
+	}
+
+	settings := &config.UserSettings{Telemetry: config.TelemetrySettings{Enabled: true, Endpoint: "http://collector:4318"}}
+	if err := config.SaveUserSettings(settings, filepath.Join(home, ".gitopsi", "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	opts, enabled := telemetryOptions()
+	if !enabled || opts.Endpoint != "http://collector:4318" {
+		t.Errorf("telemetryOptions() = %+v, %v", opts, enabled)
+	}
+
+	t.Setenv("GITOPSI_TELEMETRY", "false")
+	if _, enabled := telemetryOptions(); enabled {
+		t.Error("GITOPSI_TELEMETRY=false should disable telemetry")
+	}
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List, export, and validate manifest templates",
+	Long: `Manage the templates used to generate manifests.
+
+Templates are loaded from, in order:
+  1. --templates-dir
+  2. .gitopsi/templates/ in the current directory
+  3. Built-in defaults
+
+Override a template by placing a file with the same relative name in one of
+these directories, e.g. .gitopsi/templates/kubernetes/deployment.yaml.tmpl.
+
+Examples:
+  gitopsi templates list
+  gitopsi templates export kubernetes/deployment.yaml.tmpl
+  gitopsi templates export argocd/ --dir ./my-templates
+  gitopsi templates validate --templates-dir ./my-templates`,
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List templates and where they are loaded from",
+	Args:  cobra.NoArgs,
+	RunE:  runTemplatesList,
+}
+
+var templatesExportCmd = &cobra.Command{
+	Use:   "export [name...]",
+	Short: "Copy built-in templates into a directory for customization",
+	Long: `Copies built-in templates so they can be edited. Without arguments every
+template is exported; a name ending in "/" exports a whole directory.`,
+	RunE: runTemplatesExport,
+}
+
+var templatesValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check template overrides for unknown names and syntax errors",
+	Args:  cobra.NoArgs,
+	RunE:  runTemplatesValidate,
+}
+
+var (
+	templatesOutputFormat string
+	templatesExportDir    string
+	templatesExportForce  bool
+)
+
+func init() {
+	rootCmd.AddCommand(templatesCmd)
+
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesExportCmd)
+	templatesCmd.AddCommand(templatesValidateCmd)
+
+	templatesListCmd.Flags().StringVarP(&templatesOutputFormat, "format", "f", "table", "Output format: table, json")
+	templatesExportCmd.Flags().StringVar(&templatesExportDir, "dir", templates.ProjectOverrideDir, "Directory to export templates into")
+	templatesExportCmd.Flags().BoolVar(&templatesExportForce, "force", false, "Overwrite existing files")
+}
+
+func runTemplatesList(cmd *cobra.Command, args []string) error {
+	infos, err := templates.All()
+	if err != nil {
+		return err
+	}
+
+	if p := newPrinter(); p.structured() {
+		result := templatesListResult{Templates: infos}
+		for _, info := range infos {
+			if info.Overridden() {
+				result.Overridden++
+			}
+		}
+		return p.print(result)
+	}
+
+	switch templatesOutputFormat {
+	case "json":
+		data, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal templates: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		overridden := 0
+		tableData := [][]string{{"TEMPLATE", "SOURCE"}}
+		for _, info := range infos {
+			source := info.Source
+			if info.Overridden() {
+				overridden++
+				source = pterm.FgCyan.Sprint(source)
+			}
+			tableData = append(tableData, []string{info.Name, source})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		fmt.Println()
+		pterm.Info.Printf("%d templates, %d overridden\n", len(infos), overridden)
+	default:
+		return fmt.Errorf("invalid format: %s (must be table or json)", templatesOutputFormat)
+	}
+	return nil
+}
+
+func runTemplatesExport(cmd *cobra.Command, args []string) error {
+	written, err := templates.Export(templatesExportDir, templatesExportForce, args...)
+	if p := newPrinter(); p.structured() {
+		if printErr := p.print(templatesExportResult{Directory: templatesExportDir, Exported: written}); printErr != nil {
+			return printErr
+		}
+		return err
+	}
+	for _, path := range written {
+		pterm.Success.Printf("Exported %s\n", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	pterm.Info.Printf("Edit the files in %s and run 'gitopsi templates validate'\n", templatesExportDir)
+	return nil
+}
+
+func runTemplatesValidate(cmd *cobra.Command, args []string) error {
+	err := templates.ValidateOverrides()
+	if p := newPrinter(); p.structured() {
+		result := templatesValidateResult{Valid: err == nil}
+		if err != nil {
+			// ValidateOverrides joins one error per invalid file.
+			result.Errors = strings.Split(err.Error(), "\n")
+		}
+		if printErr := p.print(result); printErr != nil {
+			return printErr
+		}
+	}
+	if err != nil {
+		pterm.Error.Println("Template overrides are invalid:")
+		pterm.Println(err)
+		return fmt.Errorf("template validation failed")
+	}
+
+	pterm.Success.Println("Template overrides are valid")
+	return nil
+}
+
+// templatesListResult is the structured output of templates list.
+type templatesListResult struct {
+	Templates  []templates.Info `json:"templates" yaml:"templates"`
+	Overridden int              `json:"overridden" yaml:"overridden"`
+}
+
+// templatesExportResult is the structured output of templates export.
+type templatesExportResult struct {
+	Directory string   `json:"directory" yaml:"directory"`
+	Exported  []string `json:"exported" yaml:"exported"`
+}
+
+// templatesValidateResult is the structured output of templates validate.
+type templatesValidateResult struct {
+	Valid  bool     `json:"valid" yaml:"valid"`
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/cluster"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/progress"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Open the ArgoCD UI through a port-forward",
+	Long: `Forward a local port to the argocd-server service and print the admin
+credentials, replacing kubectl port-forward after a bootstrap.
+
+The credentials are read from the setup summary gitopsi init writes, or from
+the argocd-initial-admin-secret. The cluster and namespace come from
+gitops.yaml when present, otherwise from the current kubeconfig context.
+The port-forward runs until interrupted.
+
+Examples:
+  gitopsi ui
+  gitopsi ui --open --copy
+  gitopsi ui --port 9443 --namespace openshift-gitops --service openshift-gitops-server`,
+	Args: cobra.NoArgs,
+	RunE: runUI,
+}
+
+var (
+	uiNamespace string
+	uiService   string
+	uiPort      int
+	uiOpen      bool
+	uiCopy      bool
+)
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+	uiCmd.Flags().StringVarP(&uiNamespace, "namespace", "n", "", "ArgoCD namespace (default: the bootstrap namespace)")
+	uiCmd.Flags().StringVar(&uiService, "service", "argocd-server", "ArgoCD server service")
+	uiCmd.Flags().IntVar(&uiPort, "port", 8080, "Local port to listen on")
+	uiCmd.Flags().BoolVar(&uiOpen, "open", false, "Open the UI in the browser")
+	uiCmd.Flags().BoolVar(&uiCopy, "copy", false, "Copy the admin password to the clipboard")
+}
+
+func runUI(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "gitops.yaml"
+	}
+	cfg := config.NewDefaultConfig()
+	if _, err := os.Stat(configPath); err == nil {
+		if cfg, err = config.Load(configPath); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	if cfg.GitOpsTool == "flux" {
+		return fmt.Errorf("flux does not have a web UI")
+	}
+
+	summary, _ := progress.LoadSummary(".")
+	namespace := uiNamespace
+	if namespace == "" && summary != nil {
+		namespace = summary.GitOpsTool.Namespace
+	}
+	if namespace == "" {
+		namespace = cfg.Bootstrap.Namespace
+	}
+	if namespace == "" {
+		namespace = "argocd"
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := autoDetectCluster(ctx, cfg); err != nil {
+		return err
+	}
+	c, err := authenticateCluster(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("cluster authentication failed: %w", err)
+	}
+
+	username, password := "admin", ""
+	if summary != nil && summary.GitOpsTool.Password != "" {
+		username, password = summary.GitOpsTool.Username, summary.GitOpsTool.Password
+	}
+	if password == "" {
+		if password, err = getArgoCDPassword(ctx, namespace); err != nil {
+			pterm.Warning.Printfln("Admin password not found: %v", err)
+		}
+	}
+
+	fwd := &cluster.ServiceForward{Namespace: namespace, Service: uiService, Port: 443, LocalPort: uiPort}
+	pterm.Info.Printfln("Forwarding to %s/%s...", namespace, uiService)
+	return c.ForwardService(ctx, fwd, func(localPort int) {
+		url := fmt.Sprintf("https://localhost:%d", localPort)
+		pterm.Success.Printfln("ArgoCD UI: %s", url)
+		pterm.Printfln("  Username: %s", username)
+		if password != "" {
+			pterm.Printfln("  Password: %s", password)
+		}
+		if uiCopy && password != "" {
+			if err := copyToClipboard(password); err != nil {
+				pterm.Warning.Printfln("Failed to copy the password: %v", err)
+			} else {
+				pterm.Info.Println("Password copied to the clipboard")
+			}
+		}
+		if uiOpen {
+			if err := openBrowser(url); err != nil {
+				pterm.Warning.Printfln("Failed to open the browser: %v", err)
+			}
+		}
+		pterm.Info.Println("Press Ctrl+C to stop the port-forward")
+	})
+}
+
+// copyToClipboard writes text to the system clipboard.
+func copyToClipboard(text string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("clip")
+	case "linux":
+		if _, err := exec.LookPath("wl-copy"); err == nil && os.Getenv("WAYLAND_DISPLAY") != "" {
+			cmd = exec.Command("wl-copy")
+		} else {
+			cmd = exec.Command("xclip", "-selection", "clipboard")
+		}
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
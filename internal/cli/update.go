@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+	"github.com/ihsanmokhlisse/gitopsi/internal/updates"
+)
+
+var (
+	updateKinds     []string
+	updateOnly      []string
+	updateSkipMajor bool
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Check and apply dependency updates",
+	Long: `Find the outdated dependencies of a GitOps repository and update them, the way
+Renovate or Dependabot would:
+
+  chart    Helm chart versions of Flux HelmReleases, Argo CD Applications
+           and kustomization helmCharts, checked against the index of their
+           chart repository (HTTP or OCI)
+  image    Container image tags, checked against the tags of their registry.
+           Only tags of the same shape are proposed: 1.27-alpine moves to
+           1.28-alpine, never to a release candidate
+  pattern  Marketplace patterns installed in the project
+
+Registries are accessed with the credentials stored with:
+gitopsi auth add registry`,
+}
+
+var updateCheckCmd = &cobra.Command{
+	Use:   "check [path]",
+	Short: "Show the available dependency updates",
+	Long: `Scan the repository for Helm chart versions, image tags and pattern versions
+and print an update plan with the newer releases of their upstream sources.
+
+Examples:
+  gitopsi update check
+  gitopsi update check ./my-platform --kind chart --skip-major
+  gitopsi update check -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUpdateCheck,
+}
+
+var updateApplyCmd = &cobra.Command{
+	Use:   "apply [path]",
+	Short: "Apply the available dependency updates",
+	Long: `Rewrite the manifests to the versions of the update plan, keeping their
+formatting and comments. Images pinned by digest are pinned to the digest of
+their new tag.
+
+With --pr, every dependency update is proposed in its own pull request from a
+gitopsi/update-<dependency> branch, unless --pr-branch groups them into one.
+
+Examples:
+  gitopsi update apply --dry-run
+  gitopsi update apply --kind image --only nginx
+  gitopsi update apply --skip-major --pr`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUpdateApply,
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	updateCmd.AddCommand(updateCheckCmd)
+	updateCmd.AddCommand(updateApplyCmd)
+
+	for _, cmd := range []*cobra.Command{updateCheckCmd, updateApplyCmd} {
+		cmd.Flags().StringSliceVar(&updateKinds, "kind", nil, "Dependencies to check: chart, image, pattern (default: all)")
+		cmd.Flags().StringSliceVar(&updateOnly, "only", nil, "Only update the dependencies with these names")
+		cmd.Flags().BoolVar(&updateSkipMajor, "skip-major", false, "Ignore releases of a new major version")
+	}
+	addPullRequestFlags(updateApplyCmd)
+}
+
+func updateOptions(path string) (*updates.Options, error) {
+	opts := &updates.Options{
+		Path:      path,
+		SkipMajor: updateSkipMajor,
+		Resolver:  newImageResolver(),
+	}
+	for _, kind := range updateKinds {
+		k := updates.Kind(strings.ToLower(strings.TrimSpace(kind)))
+		switch k {
+		case updates.KindChart, updates.KindImage, updates.KindPattern:
+			opts.Kinds = append(opts.Kinds, k)
+		default:
+			return nil, fmt.Errorf("unknown dependency kind %q: use chart, image or pattern", kind)
+		}
+	}
+
+	cfg, err := loadProjectConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		mp := marketplace.NewMarketplace(path)
+		configureOfflineRegistry(mp.GetRegistry())
+		mp.Configure(cfg.GitOpsTool, cfg.Platform)
+		opts.Patterns = mp
+	}
+	return opts, nil
+}
+
+// checkUpdates returns the update plan of path, filtered by --only.
+func checkUpdates(cmd *cobra.Command, path string) (*updates.Options, *updates.Plan, error) {
+	opts, err := updateOptions(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	spinner, _ := pterm.DefaultSpinner.Start("Checking for updates...")
+	plan, err := updates.Check(cmd.Context(), opts)
+	if err != nil {
+		spinner.Fail("Failed to check for updates")
+		return nil, nil, err
+	}
+	spinner.Stop()
+
+	if len(updateOnly) > 0 {
+		var selected []updates.Update
+		for _, u := range plan.Updates {
+			for _, name := range updateOnly {
+				if u.Name == name || strings.HasSuffix(u.Name, "/"+name) {
+					selected = append(selected, u)
+					break
+				}
+			}
+		}
+		plan.Updates = selected
+	}
+	return opts, plan, nil
+}
+
+func runUpdateCheck(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	_, plan, err := checkUpdates(cmd, path)
+	if err != nil {
+		return err
+	}
+	if p := newPrinter(); p.structured() {
+		return p.print(plan)
+	}
+	printUpdatePlan(plan)
+	if len(plan.Updates) > 0 {
+		pterm.Info.Printfln("%d updates available: apply them with gitopsi update apply", len(plan.Updates))
+	}
+	return nil
+}
+
+func runUpdateApply(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	opts, plan, err := checkUpdates(cmd, path)
+	if err != nil {
+		return err
+	}
+	if p := newPrinter(); p.structured() {
+		if dryRun || len(plan.Updates) == 0 {
+			return p.print(plan)
+		}
+		if err := applyUpdates(cmd, opts, plan.Updates); err != nil {
+			return err
+		}
+		return p.print(plan)
+	}
+
+	printUpdatePlan(plan)
+	if len(plan.Updates) == 0 {
+		return nil
+	}
+	if dryRun {
+		pterm.Warning.Printfln("DRY RUN - %d updates would be applied", len(plan.Updates))
+		return nil
+	}
+	if err := applyUpdates(cmd, opts, plan.Updates); err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Applied %d updates", len(plan.Updates))
+	return nil
+}
+
+// applyUpdates applies the updates to the working tree, or proposes each
+// dependency update in its own pull request with --pr.
+func applyUpdates(cmd *cobra.Command, opts *updates.Options, list []updates.Update) error {
+	ctx := cmd.Context()
+	if !openPR || prBranch != "" {
+		if err := updates.Apply(ctx, opts, list); err != nil {
+			return err
+		}
+		if openPR {
+			return deliverPullRequest(ctx, opts.Path, fmt.Sprintf("chore: Update %d dependencies", len(list)))
+		}
+		return nil
+	}
+
+	repo, err := git.PlainOpenWithOptions(opts.Path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("failed to open Git repository for %s: %w", opts.Path, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	var keys []string
+	groups := map[string][]updates.Update{}
+	for _, u := range list {
+		if _, ok := groups[u.Key()]; !ok {
+			keys = append(keys, u.Key())
+		}
+		groups[u.Key()] = append(groups[u.Key()], u)
+	}
+	defer func() { prBranch, prTitle = "", "" }()
+	for _, key := range keys {
+		group := groups[key]
+		u := group[0]
+		if err := updates.Apply(ctx, opts, group); err != nil {
+			return err
+		}
+		prBranch = "gitopsi/update-" + branchSlug(u.Name+"-"+u.Latest)
+		prTitle = fmt.Sprintf("chore: Update %s %s from %s to %s", u.Kind, u.Name, u.Current, u.Latest)
+		if err := deliverPullRequest(ctx, opts.Path, prTitle); err != nil {
+			return err
+		}
+		// Opening the pull request leaves the pull request branch checked out.
+		if err := checkoutBranch(repo, head.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkoutBranch(repo *git.Repository, branch plumbing.ReferenceName) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: branch, Force: true}); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", branch.Short(), err)
+	}
+	return nil
+}
+
+var branchUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// branchSlug turns a dependency name into a branch name component.
+func branchSlug(name string) string {
+	return strings.Trim(branchUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+}
+
+func printUpdatePlan(plan *updates.Plan) {
+	for _, e := range plan.Errors {
+		pterm.Warning.Println(e)
+	}
+	if len(plan.Updates) == 0 && len(plan.Errors) > 0 {
+		pterm.Info.Printfln("No updates found, %d sources could not be checked (%d files scanned)", len(plan.Errors), plan.Scanned)
+		return
+	}
+	if len(plan.Updates) == 0 {
+		pterm.Success.Printfln("All dependencies are up to date (%d files scanned)", plan.Scanned)
+		return
+	}
+	rows := [][]string{{"Kind", "Name", "Current", "Latest", "Location"}}
+	for _, u := range plan.Updates {
+		latest := u.Latest
+		if u.Major {
+			latest += " (major)"
+		}
+		location := "-"
+		if u.File != "" {
+			location = fmt.Sprintf("%s:%d", u.File, u.Line)
+		}
+		rows = append(rows, []string{string(u.Kind), u.Name, u.Current, latest, location})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
+	"github.com/ihsanmokhlisse/gitopsi/internal/upgrade"
+)
+
+var upgradeProjectPath string
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Regenerate a repository with the current gitopsi layout and templates",
+	Long: `Regenerate a repository created by an older gitopsi with the current layout
+and templates, preserving your edits.
+
+The layout version is read from .gitopsi/metadata.yaml (repositories without
+it are treated as version 0) and structural migrations are applied first. The
+repository is then regenerated from its gitopsi.yaml (or --config):
+
+  • files you did not edit are replaced with their new version
+  • files you edited are merged three-way with the version gitopsi generated
+    before, read from the Git history; overlapping changes are left between
+    conflict markers to resolve
+  • files no longer generated are removed unless you edited them
+
+Exits with code 2 when conflicts remain.
+
+Examples:
+  gitopsi upgrade --dry-run
+  gitopsi upgrade --project ./my-platform
+  gitopsi upgrade --pr`,
+	Args: cobra.NoArgs,
+	RunE: runUpgrade,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVar(&upgradeProjectPath, "project", ".", "Path to the generated repository")
+	addPullRequestFlags(upgradeCmd)
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	cfg, err := loadProjectConfig(upgradeProjectPath)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return fmt.Errorf("no gitopsi.yaml in %s: pass --config", upgradeProjectPath)
+	}
+
+	report, err := upgrade.Run(&upgrade.Options{
+		Dir:    upgradeProjectPath,
+		Config: cfg,
+		DryRun: dryRun,
+		Images: newImageResolver(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if p := newPrinter(); p.structured() {
+		if err := p.print(report); err != nil {
+			return err
+		}
+	} else {
+		printUpgradeReport(report)
+	}
+
+	conflicts := report.Count(upgrade.ActionConflict)
+	if conflicts > 0 {
+		return withExitCode(ExitDrift, fmt.Errorf("%d files have merge conflicts: resolve them and commit", conflicts))
+	}
+	if openPR && !dryRun && len(report.Changes) > 0 {
+		pterm.Println()
+		return deliverPullRequest(cmd.Context(), upgradeProjectPath, fmt.Sprintf("chore: Upgrade repository layout to v%d", layout.CurrentVersion))
+	}
+	return nil
+}
+
+func printUpgradeReport(report *upgrade.Report) {
+	if report.DryRun {
+		pterm.Warning.Println("DRY RUN - No changes made")
+	}
+	if report.FromVersion == report.ToVersion {
+		pterm.Info.Printfln("Layout version %d is current", report.ToVersion)
+	} else {
+		pterm.Info.Printfln("Upgrading layout version %d to %d", report.FromVersion, report.ToVersion)
+	}
+	for _, m := range report.Migrations {
+		pterm.Printf("   └─ %s\n", m)
+	}
+
+	if len(report.Changes) == 0 {
+		pterm.Success.Printfln("Repository is up to date (%d files unchanged)", report.Unchanged)
+		return
+	}
+
+	rows := [][]string{{"ACTION", "FILE"}}
+	for _, c := range report.Changes {
+		path := c.Path
+		if c.From != "" {
+			path = c.From + " → " + c.Path
+		}
+		rows = append(rows, []string{string(c.Action), path})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+	pterm.Println()
+	pterm.Info.Printfln("%d created, %d updated, %d merged, %d conflicts, %d removed, %d kept, %d unchanged",
+		report.Count(upgrade.ActionCreated), report.Count(upgrade.ActionUpdated), report.Count(upgrade.ActionMerged),
+		report.Count(upgrade.ActionConflict), report.Count(upgrade.ActionRemoved), report.Count(upgrade.ActionKept), report.Unchanged)
+}
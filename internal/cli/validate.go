@@ -3,6 +3,9 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,10 +22,23 @@ var (
 	validateSecurity      bool
 	validateDeprecation   bool
 	validateKustomize     bool
+	validateReferences    bool
+	validateSecrets       bool
 	validateAll           bool
 	validateCmdFailOn     string
-	validateOutputFormat  string
 	validateFix           bool
+	validateSchemaLocs    []string
+	validateSchemaCache   string
+	validateStrictSchema  bool
+	validateRequireLabels []string
+	validateLive          bool
+	validateKubeContext   string
+	validateKubeconfig    string
+	validateReport        string
+	validateReportFile    string
+	validateJobs          int
+	validateNoCache       bool
+	validateDisableChecks []string
 )
 
 var validateCmd = &cobra.Command{
@@ -35,9 +51,48 @@ Examples:
   gitopsi validate ./my-platform/                    # Validate all checks
   gitopsi validate ./my-platform/ --security         # Security scan only
   gitopsi validate ./my-platform/ --deprecation      # Deprecated API check only
+  gitopsi validate ./my-platform/ --secrets          # Secret leak scan only
+  gitopsi validate ./my-platform/ --references       # Cross-resource reference check only
   gitopsi validate ./my-platform/ --k8s-version 1.29 # Specific K8s version
   gitopsi validate ./my-platform/ --fail-on high     # Fail on high+ severity
-  gitopsi validate ./my-platform/ --output json      # JSON output`,
+  gitopsi validate ./my-platform/ --require-label team # Flag resources without a team label
+  gitopsi validate ./my-platform/ -o json            # JSON output
+  gitopsi validate ./my-platform/ --live --context prod # Check the APIs the cluster serves
+  gitopsi validate ./my-platform/ --report sarif --report-file gitopsi.sarif # Code scanning report
+
+Schema validation uses kubeconform. ArgoCD and Flux CRD schemas are built in;
+Kubernetes schemas are downloaded once and cached for offline use. Use
+--schema-location (repeatable, kubeconform syntax) for a mirror or local copy:
+  gitopsi validate ./my-platform/ --schema-location ./schemas/{{ .ResourceKind }}{{ .KindSuffix }}.json
+
+The security category runs built-in checks of the pod specs of workloads,
+mapped to the CIS Kubernetes Benchmark and the NSA/CISA hardening guide:
+privileged containers, root users, resource limits, read-only root
+filesystems, hostPath volumes, latest image tags, seccomp profiles and
+dropped capabilities. trivy and checkov add their findings when installed.
+Skip checks with validation.disabled_checks in gitopsi.yaml or
+--disable-check.
+
+Resources missing the labels of conventions.required_labels in gitopsi.yaml,
+or of --require-label, are reported as conventions issues.
+
+The reference check reports kustomizations listing missing files,
+Applications and Flux Kustomizations syncing a path missing from the
+repository, Services selecting no workload, and destination namespaces that
+differ from the namespace of the manifests deployed.
+
+--live also reads the discovery API of the cluster of the current kubeconfig
+context and reports every apiVersion and kind it does not serve, including
+CRDs such as Application, Rollout or SealedSecret, with the marketplace
+pattern or bootstrap to install first.
+
+--report writes the result for CI systems: sarif for GitHub code scanning,
+junit for GitLab and other test reports, or json. The report is printed in
+place of the table, or written to --report-file next to it.
+
+Files are checked concurrently (--jobs, default one worker per CPU), and the
+results of unchanged files are reused from .gitopsi/validate-cache in the
+validated directory. Use --no-cache to check every file again.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runValidate,
 }
@@ -51,10 +106,23 @@ func init() {
 	validateCmd.Flags().BoolVar(&validateSecurity, "security", false, "Run security scan only")
 	validateCmd.Flags().BoolVar(&validateDeprecation, "deprecation", false, "Run deprecation check only")
 	validateCmd.Flags().BoolVar(&validateKustomize, "kustomize", false, "Run kustomize validation only")
+	validateCmd.Flags().BoolVar(&validateReferences, "references", false, "Run cross-resource reference check only")
+	validateCmd.Flags().BoolVar(&validateSecrets, "secrets", false, "Run secret leak scan only")
 	validateCmd.Flags().BoolVar(&validateAll, "all", true, "Run all validations (default)")
 	validateCmd.Flags().StringVar(&validateCmdFailOn, "fail-on", "high", "Fail on severity: critical, high, medium, low")
-	validateCmd.Flags().StringVar(&validateOutputFormat, "output", "table", "Output format: table, json, yaml")
-	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Auto-fix fixable issues")
+	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Apply the fixes of fixable issues, as gitopsi fix does, and validate again")
+	validateCmd.Flags().StringSliceVar(&validateSchemaLocs, "schema-location", nil, "Schema registry URL or path template (repeatable, default: upstream Kubernetes schemas and CRD catalog)")
+	validateCmd.Flags().StringVar(&validateSchemaCache, "schema-cache", validate.DefaultSchemaCacheDir(), "Directory to cache downloaded schemas (empty to disable)")
+	validateCmd.Flags().BoolVar(&validateStrictSchema, "strict-schema", false, "Reject fields not defined in the schema")
+	validateCmd.Flags().StringSliceVar(&validateRequireLabels, "require-label", nil, "Label every resource must set (repeatable, added to conventions.required_labels)")
+	validateCmd.Flags().BoolVar(&validateLive, "live", false, "Also check that the cluster serves the API of every resource")
+	validateCmd.Flags().StringVar(&validateKubeContext, "context", "", "Kubernetes context of the --live check")
+	validateCmd.Flags().StringVar(&validateKubeconfig, "kubeconfig", "", "Path to the kubeconfig file of the --live check")
+	validateCmd.Flags().StringVar(&validateReport, "report", "", "Report format for CI: sarif, junit, json")
+	validateCmd.Flags().StringSliceVar(&validateDisableChecks, "disable-check", nil, "Built-in security check to skip, by ID or name (repeatable, added to validation.disabled_checks)")
+	validateCmd.Flags().IntVarP(&validateJobs, "jobs", "j", 0, "Number of files to check concurrently (default: number of CPUs)")
+	validateCmd.Flags().BoolVar(&validateNoCache, "no-cache", false, "Check every file again instead of reusing the results of unchanged files")
+	validateCmd.Flags().StringVar(&validateReportFile, "report-file", "", "File to write the --report to (default: standard output)")
 }
 
 func runValidate(cmd *cobra.Command, args []string) error {
@@ -67,23 +135,52 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	}
 
 	opts := &validate.Options{
-		Path:          path,
-		K8sVersion:    validateK8sVersion,
-		ArgoCDVersion: validateArgoCDVersion,
-		OutputFormat:  validateOutputFormat,
-		Fix:           validateFix,
+		Path:            path,
+		K8sVersion:      validateK8sVersion,
+		ArgoCDVersion:   validateArgoCDVersion,
+		OutputFormat:    outputFormat,
+		Fix:             validateFix,
+		SchemaLocations: validateSchemaLocs,
+		SchemaCacheDir:  validateSchemaCache,
+		StrictSchema:    validateStrictSchema,
+		Live:            validateLive,
+		Kubeconfig:      validateKubeconfig,
+		Context:         validateKubeContext,
+		Jobs:            validateJobs,
+	}
+	if !validateNoCache {
+		opts.CacheDir = filepath.Join(path, validate.DefaultCacheDir)
 	}
 
-	if validateSchema || validateSecurity || validateDeprecation || validateKustomize {
+	cfg, err := loadProjectConfig(path)
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		opts.RequiredLabels = append(opts.RequiredLabels, cfg.Conventions.RequiredLabels...)
+		if cfg.Cost.Enabled {
+			opts.RequiredLabels = append(opts.RequiredLabels, cfg.Cost.LabelKey())
+		}
+		opts.RepoURL = cfg.Git.URL
+		opts.DisabledChecks = append(opts.DisabledChecks, cfg.Validation.DisabledChecks...)
+	}
+	opts.DisabledChecks = append(opts.DisabledChecks, validateDisableChecks...)
+	opts.RequiredLabels = append(opts.RequiredLabels, validateRequireLabels...)
+
+	if validateSchema || validateSecurity || validateDeprecation || validateKustomize || validateReferences || validateSecrets {
 		opts.Schema = validateSchema
 		opts.Security = validateSecurity
 		opts.Deprecation = validateDeprecation
 		opts.Kustomize = validateKustomize
+		opts.References = validateReferences
+		opts.Secrets = validateSecrets
 	} else {
 		opts.Schema = true
 		opts.Security = true
 		opts.Deprecation = true
 		opts.Kustomize = true
+		opts.References = true
+		opts.Secrets = true
 	}
 
 	switch strings.ToLower(validateCmdFailOn) {
@@ -99,7 +196,17 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		opts.FailOn = validate.SeverityHigh
 	}
 
-	if validateOutputFormat != "json" && validateOutputFormat != "yaml" {
+	if validateReport != "" && !slices.Contains(validate.ReportFormats, strings.ToLower(validateReport)) {
+		return fmt.Errorf("unsupported report format: %s (use sarif, junit or json)", validateReport)
+	}
+	if validateReportFile != "" && validateReport == "" {
+		return fmt.Errorf("--report-file requires --report")
+	}
+	// A report on standard output replaces the table.
+	reportOnly := validateReport != "" && validateReportFile == ""
+
+	p := newPrinter()
+	if !p.structured() && !reportOnly {
 		pterm.DefaultHeader.WithBackgroundStyle(pterm.NewStyle(pterm.BgBlue)).
 			WithTextStyle(pterm.NewStyle(pterm.FgWhite)).
 			Println("gitopsi validate")
@@ -114,21 +221,47 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	switch validateOutputFormat {
-	case "json":
-		jsonOutput, jsonErr := result.ToJSON()
-		if jsonErr != nil {
-			return jsonErr
+	if validateFix && !dryRun {
+		fixes, err := planFixes(result.Issues, cfg)
+		if err != nil {
+			return err
+		}
+		if err := fixes.Write(); err != nil {
+			return err
+		}
+		if len(fixes.Files) > 0 {
+			if !p.structured() && !reportOnly {
+				pterm.Success.Printf("Applied %d fixes to %d files\n", fixes.Changes(), len(fixes.Files))
+			}
+			if result, err = validator.Validate(ctx); err != nil {
+				return fmt.Errorf("validation failed: %w", err)
+			}
+		}
+	}
+
+	if validateReport != "" {
+		report, err := result.Report(validateReport)
+		if err != nil {
+			return err
 		}
-		fmt.Println(jsonOutput)
-	case "yaml":
-		yamlOutput, yamlErr := result.ToYAML()
-		if yamlErr != nil {
-			return yamlErr
+		if reportOnly {
+			fmt.Println(report)
+		} else if err := os.WriteFile(validateReportFile, []byte(report), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	switch {
+	case reportOnly:
+	case p.structured():
+		if err := p.print(result); err != nil {
+			return err
 		}
-		fmt.Println(yamlOutput)
 	default:
 		printValidationResult(result)
+		if validateReportFile != "" {
+			pterm.Info.Printf("%s report written to %s\n", strings.ToUpper(validateReport), validateReportFile)
+		}
 	}
 
 	if validator.ShouldFail(result) {
@@ -183,6 +316,57 @@ func printValidationResult(result *validate.ValidationResult) {
 		pterm.Println()
 	}
 
+	if catResult, ok := result.Categories[validate.CategoryReferences]; ok {
+		pterm.DefaultSection.Println("🔗 References")
+		if len(catResult.Issues) == 0 {
+			pterm.Success.Printf("✅ All references resolve\n")
+		} else {
+			pterm.Warning.Printf("⚠️  %d broken references found\n", len(catResult.Issues))
+			printIssues(catResult.Issues)
+		}
+		pterm.Println()
+	}
+
+	if catResult, ok := result.Categories[validate.CategorySecrets]; ok {
+		pterm.DefaultSection.Println("🔑 Secret Scan")
+		if len(catResult.Issues) == 0 {
+			pterm.Success.Printf("✅ No leaked credentials found\n")
+		} else {
+			pterm.Warning.Printf("⚠️  %d leaked credentials found\n", len(catResult.Issues))
+			printIssues(catResult.Issues)
+		}
+		pterm.Println()
+	}
+
+	if catResult, ok := result.Categories[validate.CategoryConventions]; ok {
+		pterm.DefaultSection.Println("🏷️  Conventions")
+		if len(catResult.Issues) == 0 {
+			pterm.Success.Printf("✅ All resources set the required labels\n")
+		} else {
+			pterm.Warning.Printf("⚠️  %d resources missing required labels\n", len(catResult.Issues))
+			printIssues(catResult.Issues)
+		}
+		pterm.Println()
+	}
+
+	if catResult, ok := result.Categories[validate.CategoryLive]; ok {
+		pterm.DefaultSection.Println("🛰️  Live Cluster APIs")
+		if len(catResult.Issues) == 0 {
+			pterm.Success.Printf("✅ The cluster serves the API of every resource\n")
+		} else {
+			pterm.Warning.Printf("⚠️  %d resources use APIs the cluster does not serve\n", len(catResult.Issues))
+			printIssues(catResult.Issues)
+		}
+		if len(result.Prerequisites) > 0 {
+			pterm.Println()
+			pterm.Info.Println("Install first:")
+			for _, p := range result.Prerequisites {
+				pterm.Printf("  • %s (%s): %s\n", p.Name, strings.Join(p.Kinds, ", "), p.Install)
+			}
+		}
+		pterm.Println()
+	}
+
 	pterm.DefaultSection.Println("📊 Summary")
 
 	tableData := pterm.TableData{
@@ -192,6 +376,9 @@ func printValidationResult(result *validate.ValidationResult) {
 		{"Warnings", fmt.Sprintf("%d", result.Warnings)},
 		{"Failed", fmt.Sprintf("%d", result.Failed)},
 	}
+	if result.CachedFiles > 0 {
+		tableData = append(tableData, []string{"Cached File Checks", fmt.Sprintf("%d", result.CachedFiles)})
+	}
 	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
 
 	pterm.Println()
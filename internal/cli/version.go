@@ -16,6 +16,10 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
 	Run: func(cmd *cobra.Command, args []string) {
+		if p := newPrinter(); p.structured() {
+			cobra.CheckErr(p.print(versionResult{Version: Version, Commit: Commit, BuildDate: BuildDate}))
+			return
+		}
 		fmt.Printf("gitopsi %s\n", Version)
 		if verbose {
 			fmt.Printf("  commit: %s\n", Commit)
@@ -24,6 +28,13 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// versionResult is the structured output of version.
+type versionResult struct {
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit" yaml:"commit"`
+	BuildDate string `json:"build_date" yaml:"build_date"`
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
 )
 
 // AuthMethod represents the authentication method for a cluster.
@@ -66,12 +68,16 @@ func (c *Cluster) Authenticate(opts *AuthOptions) error {
 	switch opts.Method {
 	case AuthKubeconfig:
 		if opts.Kubeconfig == "" {
-			// Use default kubeconfig
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("failed to get home directory: %w", err)
+			// Use the kubeconfig of KUBECONFIG, or the default kubeconfig
+			if files := filepath.SplitList(os.Getenv("KUBECONFIG")); len(files) == 1 {
+				opts.Kubeconfig = files[0]
+			} else {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return fmt.Errorf("failed to get home directory: %w", err)
+				}
+				opts.Kubeconfig = filepath.Join(home, ".kube", "config")
 			}
-			opts.Kubeconfig = filepath.Join(home, ".kube", "config")
 			c.auth.Kubeconfig = opts.Kubeconfig
 		}
 		if _, err := os.Stat(opts.Kubeconfig); os.IsNotExist(err) {
@@ -119,7 +125,7 @@ func (c *Cluster) TestConnection(ctx context.Context) error {
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to connect to cluster: %w: %s", err, string(output))
 	}
@@ -137,7 +143,7 @@ func (c *Cluster) GetServerVersion(ctx context.Context) (string, error) {
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get server version: %w: %s", err, string(output))
 	}
@@ -163,7 +169,7 @@ func (c *Cluster) CreateNamespace(ctx context.Context, name string) error {
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		// Check if namespace already exists
 		if strings.Contains(string(output), "already exists") {
@@ -186,7 +192,7 @@ func (c *Cluster) Apply(ctx context.Context, manifest string) error {
 	cmd.Env = c.getKubeEnv()
 	cmd.Stdin = strings.NewReader(manifest)
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to apply manifest: %w: %s", err, string(output))
 	}
@@ -204,7 +210,7 @@ func (c *Cluster) ApplyFile(ctx context.Context, path string) error {
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to apply file: %w: %s", err, string(output))
 	}
@@ -227,7 +233,7 @@ func (c *Cluster) WaitForDeployment(ctx context.Context, namespace, name string,
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("deployment not ready: %w: %s", err, string(output))
 	}
@@ -245,7 +251,7 @@ func (c *Cluster) GetPodLogs(ctx context.Context, namespace, pod string, lines i
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	output, err := logging.CombinedOutput(cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs: %w: %s", err, string(output))
 	}
@@ -263,7 +269,11 @@ func (c *Cluster) RunCommand(ctx context.Context, kubectlArgs ...string) (string
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
 	cmd.Env = c.getKubeEnv()
 
-	output, err := cmd.CombinedOutput()
+	run := logging.CombinedOutput
+	if readsSecret(kubectlArgs) {
+		run = logging.SecretOutput
+	}
+	output, err := run(cmd)
 	if err != nil {
 		return "", fmt.Errorf("kubectl command failed: %w: %s", err, string(output))
 	}
@@ -271,6 +281,21 @@ func (c *Cluster) RunCommand(ctx context.Context, kubectlArgs ...string) (string
 	return string(output), nil
 }
 
+// readsSecret reports whether kubectl args read secrets, whose output must
+// not be logged.
+func readsSecret(args []string) bool {
+	if len(args) < 2 || args[0] != "get" {
+		return false
+	}
+	for _, kind := range strings.Split(args[1], ",") {
+		switch strings.ToLower(strings.SplitN(kind, "/", 2)[0]) {
+		case "secret", "secrets":
+			return true
+		}
+	}
+	return false
+}
+
 // buildKubectlArgs builds kubectl arguments with authentication options.
 func (c *Cluster) buildKubectlArgs(args ...string) []string {
 	result := make([]string, 0, len(args)+6)
@@ -347,6 +372,15 @@ func (c *Cluster) GetAuthMethod() AuthMethod {
 	return c.auth.Method
 }
 
+// GetAuthOptions returns a copy of the configured authentication options.
+func (c *Cluster) GetAuthOptions() *AuthOptions {
+	if c.auth == nil {
+		return nil
+	}
+	opts := *c.auth
+	return &opts
+}
+
 // ClusterInfo holds information detected from kubeconfig.
 type ClusterInfo struct {
 	URL      string
@@ -340,3 +340,22 @@ func TestGetKubeEnv(t *testing.T) {
 		t.Error("getKubeEnv() should include KUBECONFIG environment variable")
 	}
 }
+
+func TestReadsSecret(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"get", "secret", "argocd-initial-admin-secret", "-o", "jsonpath={.data.password}"}, true},
+		{[]string{"get", "secrets/token", "-n", "kube-system"}, true},
+		{[]string{"get", "configmap,Secret"}, true},
+		{[]string{"get", "pods", "-n", "argocd"}, false},
+		{[]string{"delete", "secret", "old"}, false},
+		{[]string{"get"}, false},
+	}
+	for _, tt := range tests {
+		if got := readsSecret(tt.args); got != tt.want {
+			t.Errorf("readsSecret(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+// LocalProvider is a tool that runs a Kubernetes cluster on the local machine.
+type LocalProvider string
+
+const (
+	ProviderKind     LocalProvider = "kind"
+	ProviderK3d      LocalProvider = "k3d"
+	ProviderMinikube LocalProvider = "minikube"
+)
+
+// LocalProviders lists the supported local cluster providers.
+var LocalProviders = []LocalProvider{ProviderKind, ProviderK3d, ProviderMinikube}
+
+const (
+	// DefaultLocalName is the name of local clusters created by gitopsi.
+	DefaultLocalName = "gitopsi"
+	// LocalMountPath is where the mounted host directory appears in the nodes.
+	LocalMountPath = "/gitops"
+	// LocalGitNamespace is the namespace of the in-cluster Git server.
+	LocalGitNamespace = "gitopsi-system"
+	// LocalGitURL is the URL of the repository served by the in-cluster Git server.
+	LocalGitURL = "git://gitopsi-git." + LocalGitNamespace + ".svc.cluster.local/repo"
+)
+
+// LocalOptions configures a local cluster.
+type LocalOptions struct {
+	Provider          LocalProvider
+	Name              string
+	Workers           int    // Worker nodes besides the control plane
+	KubernetesVersion string // e.g. v1.30.0 (default: the provider's)
+	Kubeconfig        string // Kubeconfig the context is written to (default: $KUBECONFIG or ~/.kube/config)
+	// HostPath is a host directory mounted in every node at LocalMountPath.
+	HostPath string
+}
+
+// LocalCluster describes a local cluster.
+type LocalCluster struct {
+	Provider   LocalProvider `json:"provider" yaml:"provider"`
+	Name       string        `json:"name" yaml:"name"`
+	Context    string        `json:"context" yaml:"context"`
+	URL        string        `json:"url" yaml:"url"`
+	Kubeconfig string        `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+	Existed    bool          `json:"existed" yaml:"existed"`
+}
+
+// runLocal runs a provider command with extra environment variables.
+var runLocal = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := logging.CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// lookPath finds a provider binary.
+var lookPath = exec.LookPath
+
+// ParseLocalProvider returns the local provider with the given name.
+func ParseLocalProvider(name string) (LocalProvider, error) {
+	for _, p := range LocalProviders {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported local cluster provider %q: use kind, k3d or minikube", name)
+}
+
+// LocalContext returns the kubeconfig context a provider creates for a cluster.
+func LocalContext(provider LocalProvider, name string) string {
+	switch provider {
+	case ProviderKind:
+		return "kind-" + name
+	case ProviderK3d:
+		return "k3d-" + name
+	default:
+		return name
+	}
+}
+
+// CreateLocal creates a local cluster, or reuses it when it already exists,
+// and returns its kubeconfig context and API server URL.
+func CreateLocal(ctx context.Context, opts *LocalOptions) (*LocalCluster, error) {
+	if opts.Name == "" {
+		opts.Name = DefaultLocalName
+	}
+	if _, err := lookPath(string(opts.Provider)); err != nil {
+		return nil, fmt.Errorf("%s not found in PATH: %w", opts.Provider, err)
+	}
+	if opts.HostPath != "" {
+		abs, err := filepath.Abs(opts.HostPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", opts.HostPath, err)
+		}
+		opts.HostPath = abs
+	}
+
+	env := localEnv(opts.Kubeconfig)
+	local := &LocalCluster{
+		Provider:   opts.Provider,
+		Name:       opts.Name,
+		Context:    LocalContext(opts.Provider, opts.Name),
+		Kubeconfig: opts.Kubeconfig,
+	}
+
+	exists, err := localExists(ctx, env, opts.Provider, opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	local.Existed = exists
+	if !exists {
+		args, cleanup, err := createArgs(opts)
+		if err != nil {
+			return nil, err
+		}
+		_, err = runLocal(ctx, env, string(opts.Provider), args...)
+		cleanup()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s cluster %s: %w", opts.Provider, opts.Name, err)
+		}
+	}
+
+	server, err := runLocal(ctx, env, "kubectl", "config", "view", "--minify", "--context", local.Context,
+		"-o", "jsonpath={.clusters[0].cluster.server}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the API server of context %s: %w", local.Context, err)
+	}
+	local.URL = strings.TrimSpace(server)
+	return local, nil
+}
+
+// DeleteLocal deletes a local cluster and its kubeconfig context.
+func DeleteLocal(ctx context.Context, opts *LocalOptions) error {
+	if opts.Name == "" {
+		opts.Name = DefaultLocalName
+	}
+	var args []string
+	switch opts.Provider {
+	case ProviderKind:
+		args = []string{"delete", "cluster", "--name", opts.Name}
+	case ProviderK3d:
+		args = []string{"cluster", "delete", opts.Name}
+	case ProviderMinikube:
+		args = []string{"delete", "--profile", opts.Name}
+	default:
+		return fmt.Errorf("unsupported local cluster provider %q", opts.Provider)
+	}
+	if _, err := runLocal(ctx, localEnv(opts.Kubeconfig), string(opts.Provider), args...); err != nil {
+		return fmt.Errorf("failed to delete %s cluster %s: %w", opts.Provider, opts.Name, err)
+	}
+	return nil
+}
+
+func localEnv(kubeconfig string) []string {
+	if kubeconfig == "" {
+		return nil
+	}
+	return []string{"KUBECONFIG=" + kubeconfig}
+}
+
+// localExists reports whether the provider already runs a cluster with the name.
+func localExists(ctx context.Context, env []string, provider LocalProvider, name string) (bool, error) {
+	switch provider {
+	case ProviderKind:
+		output, err := runLocal(ctx, env, "kind", "get", "clusters")
+		if err != nil {
+			return false, fmt.Errorf("failed to list kind clusters: %w", err)
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if strings.TrimSpace(line) == name {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case ProviderK3d:
+		output, err := runLocal(ctx, env, "k3d", "cluster", "list", "-o", "json")
+		if err != nil {
+			return false, fmt.Errorf("failed to list k3d clusters: %w", err)
+		}
+		var clusters []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal([]byte(output), &clusters); err != nil {
+			return false, fmt.Errorf("failed to parse k3d clusters: %w", err)
+		}
+		for _, c := range clusters {
+			if c.Name == name {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case ProviderMinikube:
+		// minikube exits non-zero when there are no profiles at all
+		output, err := runLocal(ctx, env, "minikube", "profile", "list", "-o", "json")
+		if err != nil {
+			return false, nil
+		}
+		var profiles struct {
+			Valid []struct {
+				Name string `json:"Name"`
+			} `json:"valid"`
+		}
+		if err := json.Unmarshal([]byte(output), &profiles); err != nil {
+			return false, fmt.Errorf("failed to parse minikube profiles: %w", err)
+		}
+		for _, p := range profiles.Valid {
+			if p.Name == name {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported local cluster provider %q", provider)
+}
+
+// createArgs returns the provider arguments that create a cluster, with a
+// cleanup function for the files they reference.
+func createArgs(opts *LocalOptions) ([]string, func(), error) {
+	cleanup := func() {}
+	switch opts.Provider {
+	case ProviderKind:
+		args := []string{"create", "cluster", "--name", opts.Name, "--wait", "120s"}
+		if opts.KubernetesVersion != "" {
+			args = append(args, "--image", "kindest/node:"+opts.KubernetesVersion)
+		}
+		if opts.Workers > 0 || opts.HostPath != "" {
+			path, err := writeKindConfig(opts)
+			if err != nil {
+				return nil, cleanup, err
+			}
+			cleanup = func() { os.Remove(path) }
+			args = append(args, "--config", path)
+		}
+		return args, cleanup, nil
+
+	case ProviderK3d:
+		args := []string{"cluster", "create", opts.Name, "--wait"}
+		if opts.Workers > 0 {
+			args = append(args, "--agents", fmt.Sprint(opts.Workers))
+		}
+		if opts.KubernetesVersion != "" {
+			image := opts.KubernetesVersion
+			if !strings.Contains(image, "k3s") {
+				image += "-k3s1"
+			}
+			args = append(args, "--image", "rancher/k3s:"+image)
+		}
+		if opts.HostPath != "" {
+			args = append(args, "--volume", opts.HostPath+":"+LocalMountPath+"@all")
+		}
+		return args, cleanup, nil
+
+	case ProviderMinikube:
+		args := []string{"start", "--profile", opts.Name, "--wait", "all"}
+		if opts.Workers > 0 {
+			args = append(args, "--nodes", fmt.Sprint(opts.Workers+1))
+		}
+		if opts.KubernetesVersion != "" {
+			args = append(args, "--kubernetes-version", opts.KubernetesVersion)
+		}
+		if opts.HostPath != "" {
+			args = append(args, "--mount", "--mount-string", opts.HostPath+":"+LocalMountPath)
+		}
+		return args, cleanup, nil
+	}
+	return nil, cleanup, fmt.Errorf("unsupported local cluster provider %q", opts.Provider)
+}
+
+// writeKindConfig writes a kind cluster config with the worker nodes and the
+// host mount of the options.
+func writeKindConfig(opts *LocalOptions) (string, error) {
+	node := func(role string) map[string]any {
+		n := map[string]any{"role": role}
+		if opts.HostPath != "" {
+			n["extraMounts"] = []any{map[string]any{
+				"hostPath":      opts.HostPath,
+				"containerPath": LocalMountPath,
+			}}
+		}
+		return n
+	}
+	nodes := []any{node("control-plane")}
+	for i := 0; i < opts.Workers; i++ {
+		nodes = append(nodes, node("worker"))
+	}
+	data, err := yaml.Marshal(map[string]any{
+		"kind":       "Cluster",
+		"apiVersion": "kind.x-k8s.io/v1alpha4",
+		"nodes":      nodes,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp("", "gitopsi-kind-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to write kind config: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write kind config: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// LocalGitServerManifest returns a Git server that serves the repository
+// mounted at LocalMountPath over the git protocol at LocalGitURL.
+func LocalGitServerManifest() string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: gitopsi-git
+  namespace: %[1]s
+  labels:
+    app.kubernetes.io/name: gitopsi-git
+    app.kubernetes.io/managed-by: gitopsi
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: gitopsi-git
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: gitopsi-git
+    spec:
+      containers:
+        - name: git
+          image: alpine/git:2.45.2
+          command: ["sh", "-c"]
+          args:
+            - git config --global --add safe.directory '*' && exec git daemon --reuseaddr --export-all --base-path=/srv --informative-errors /srv
+          ports:
+            - name: git
+              containerPort: 9418
+          readinessProbe:
+            tcpSocket:
+              port: git
+          volumeMounts:
+            - name: repo
+              mountPath: /srv/repo
+              readOnly: true
+      volumes:
+        - name: repo
+          hostPath:
+            path: %[2]s
+            type: Directory
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: gitopsi-git
+  namespace: %[1]s
+spec:
+  selector:
+    app.kubernetes.io/name: gitopsi-git
+  ports:
+    - name: git
+      port: 9418
+      targetPort: git
+`, LocalGitNamespace, LocalMountPath)
+}
@@ -0,0 +1,195 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeLocal replaces the provider commands with canned outputs and records
+// the commands that ran.
+func fakeLocal(t *testing.T, outputs map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	origRun, origLook := runLocal, lookPath
+	runLocal = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+		call := strings.Join(append([]string{name}, args...), " ")
+		if name == "kind" && len(args) > 0 && args[0] == "create" {
+			for i, arg := range args {
+				if arg == "--config" {
+					data, err := os.ReadFile(args[i+1])
+					if err != nil {
+						t.Fatalf("kind config not written: %v", err)
+					}
+					call += "\n" + string(data)
+				}
+			}
+		}
+		calls = append(calls, call)
+		for prefix, output := range outputs {
+			if strings.HasPrefix(call, prefix) {
+				if strings.HasPrefix(output, "error:") {
+					return "", fmt.Errorf("%s", output)
+				}
+				return output, nil
+			}
+		}
+		return "", nil
+	}
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	t.Cleanup(func() { runLocal, lookPath = origRun, origLook })
+	return &calls
+}
+
+func TestParseLocalProvider(t *testing.T) {
+	for _, name := range []string{"kind", "k3d", "minikube"} {
+		if _, err := ParseLocalProvider(name); err != nil {
+			t.Errorf("ParseLocalProvider(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseLocalProvider("docker-desktop"); err == nil {
+		t.Error("ParseLocalProvider() should reject unknown providers")
+	}
+}
+
+func TestLocalContext(t *testing.T) {
+	tests := map[LocalProvider]string{
+		ProviderKind:     "kind-dev",
+		ProviderK3d:      "k3d-dev",
+		ProviderMinikube: "dev",
+	}
+	for provider, want := range tests {
+		if got := LocalContext(provider, "dev"); got != want {
+			t.Errorf("LocalContext(%s) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestCreateLocalKind(t *testing.T) {
+	calls := fakeLocal(t, map[string]string{
+		"kind get clusters":   "other\n",
+		"kubectl config view": "https://127.0.0.1:6443",
+	})
+	dir := t.TempDir()
+
+	local, err := CreateLocal(context.Background(), &LocalOptions{
+		Provider:          ProviderKind,
+		Workers:           1,
+		KubernetesVersion: "v1.30.0",
+		HostPath:          dir,
+	})
+	if err != nil {
+		t.Fatalf("CreateLocal() error = %v", err)
+	}
+	if local.Name != DefaultLocalName || local.Context != "kind-gitopsi" || local.Existed {
+		t.Errorf("CreateLocal() = %+v", local)
+	}
+	if local.URL != "https://127.0.0.1:6443" {
+		t.Errorf("URL = %q", local.URL)
+	}
+
+	create := (*calls)[1]
+	for _, want := range []string{
+		"kind create cluster --name gitopsi",
+		"--image kindest/node:v1.30.0",
+		"role: worker",
+		"hostPath: " + dir,
+		"containerPath: /gitops",
+	} {
+		if !strings.Contains(create, want) {
+			t.Errorf("create command missing %q:\n%s", want, create)
+		}
+	}
+	if !strings.Contains((*calls)[2], "--context kind-gitopsi") {
+		t.Errorf("server lookup = %q", (*calls)[2])
+	}
+}
+
+func TestCreateLocalReusesExisting(t *testing.T) {
+	calls := fakeLocal(t, map[string]string{
+		"k3d cluster list": `[{"name":"dev"}]`,
+	})
+
+	local, err := CreateLocal(context.Background(), &LocalOptions{Provider: ProviderK3d, Name: "dev"})
+	if err != nil {
+		t.Fatalf("CreateLocal() error = %v", err)
+	}
+	if !local.Existed {
+		t.Error("Existed = false, want true")
+	}
+	for _, call := range *calls {
+		if strings.HasPrefix(call, "k3d cluster create") {
+			t.Errorf("existing cluster was created again: %s", call)
+		}
+	}
+}
+
+func TestCreateLocalArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts LocalOptions
+		want []string
+	}{
+		{
+			name: "k3d",
+			opts: LocalOptions{Provider: ProviderK3d, Name: "dev", Workers: 2, KubernetesVersion: "v1.30.2", HostPath: "/repo"},
+			want: []string{"k3d cluster create dev --wait", "--agents 2", "--image rancher/k3s:v1.30.2-k3s1", "--volume /repo:/gitops@all"},
+		},
+		{
+			name: "minikube",
+			opts: LocalOptions{Provider: ProviderMinikube, Name: "dev", Workers: 1, KubernetesVersion: "v1.30.2", HostPath: "/repo"},
+			want: []string{"minikube start --profile dev", "--nodes 2", "--kubernetes-version v1.30.2", "--mount --mount-string /repo:/gitops"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeLocal(t, map[string]string{
+				"k3d cluster list":      "[]",
+				"minikube profile list": "error: no profiles",
+			})
+			if _, err := CreateLocal(context.Background(), &tt.opts); err != nil {
+				t.Fatalf("CreateLocal() error = %v", err)
+			}
+			create := (*calls)[1]
+			for _, want := range tt.want {
+				if !strings.Contains(create, want) {
+					t.Errorf("create command %q missing %q", create, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateLocalMissingBinary(t *testing.T) {
+	fakeLocal(t, nil)
+	lookPath = func(file string) (string, error) { return "", fmt.Errorf("not found") }
+
+	if _, err := CreateLocal(context.Background(), &LocalOptions{Provider: ProviderKind}); err == nil {
+		t.Error("CreateLocal() should fail without the provider binary")
+	}
+}
+
+func TestDeleteLocal(t *testing.T) {
+	calls := fakeLocal(t, nil)
+
+	if err := DeleteLocal(context.Background(), &LocalOptions{Provider: ProviderMinikube, Name: "dev"}); err != nil {
+		t.Fatalf("DeleteLocal() error = %v", err)
+	}
+	if len(*calls) != 1 || (*calls)[0] != "minikube delete --profile dev" {
+		t.Errorf("calls = %v", *calls)
+	}
+}
+
+func TestLocalGitServerManifest(t *testing.T) {
+	manifest := LocalGitServerManifest()
+	for _, want := range []string{"namespace: gitopsi-system", "git daemon", "path: /gitops", "port: 9418"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest missing %q", want)
+		}
+	}
+	if !strings.HasPrefix(LocalGitURL, "git://gitopsi-git.gitopsi-system.svc") {
+		t.Errorf("LocalGitURL = %q", LocalGitURL)
+	}
+}
@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// ServiceForward describes a port-forward to a pod backing a service, like
+// kubectl port-forward svc/<name>.
+type ServiceForward struct {
+	Namespace string
+	Service   string
+	// Port is the service port; the pod's target port is forwarded.
+	Port int
+	// LocalPort is the local port to listen on; 0 picks a free port.
+	LocalPort int
+}
+
+// RESTConfig returns the client-go configuration of the cluster's
+// authentication.
+func (c *Cluster) RESTConfig() (*rest.Config, error) {
+	if c.auth == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	switch c.auth.Method {
+	case AuthToken:
+		return &rest.Config{
+			Host:        c.URL,
+			BearerToken: c.auth.Token,
+			TLSClientConfig: rest.TLSClientConfig{
+				CAFile:   c.auth.CACert,
+				Insecure: c.auth.SkipTLS,
+			},
+		}, nil
+	case AuthServiceAccount:
+		return rest.InClusterConfig()
+	default:
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		if c.auth.Kubeconfig != "" {
+			rules.ExplicitPath = c.auth.Kubeconfig
+		}
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: c.auth.Context}
+		cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		return cfg, nil
+	}
+}
+
+// ForwardService forwards a local port to a ready pod of the service until
+// ctx is done. ready is called with the local port once it listens.
+func (c *Cluster) ForwardService(ctx context.Context, fwd *ServiceForward, ready func(localPort int)) error {
+	cfg, err := c.RESTConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	svc, err := client.CoreV1().Services(fwd.Namespace).Get(ctx, fwd.Service, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service %s/%s: %w", fwd.Namespace, fwd.Service, err)
+	}
+	pod, err := servicePod(ctx, client, svc)
+	if err != nil {
+		return err
+	}
+	remotePort, err := targetPort(svc, pod, fwd.Port)
+	if err != nil {
+		return err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+	url := client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).
+		SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	pf, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", fwd.LocalPort, remotePort)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return fmt.Errorf("failed to create port-forward: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stopCh)
+		case <-done:
+		}
+	}()
+	go func() {
+		select {
+		case <-readyCh:
+		case <-done:
+			return
+		}
+		if ports, err := pf.GetPorts(); err == nil && len(ports) > 0 && ready != nil {
+			ready(int(ports[0].Local))
+		}
+	}()
+
+	if err := pf.ForwardPorts(); err != nil {
+		return fmt.Errorf("port-forward to %s/%s failed: %w", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
+
+// servicePod returns a running, ready pod selected by the service.
+func servicePod(ctx context.Context, client kubernetes.Interface, svc *corev1.Service) (*corev1.Pod, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %s/%s has no selector", svc.Namespace, svc.Name)
+	}
+	pods, err := client.CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of service %s/%s: %w", svc.Namespace, svc.Name, err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return pod, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("service %s/%s has no ready pod", svc.Namespace, svc.Name)
+}
+
+// targetPort returns the container port of pod the service port routes to.
+func targetPort(svc *corev1.Service, pod *corev1.Pod, port int) (int, error) {
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) != port {
+			continue
+		}
+		if p.TargetPort.IntValue() != 0 {
+			return p.TargetPort.IntValue(), nil
+		}
+		name := p.TargetPort.String()
+		if name == "" || name == "0" {
+			return port, nil
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, cp := range container.Ports {
+				if cp.Name == name {
+					return int(cp.ContainerPort), nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, name)
+	}
+	return 0, fmt.Errorf("service %s/%s has no port %d", svc.Namespace, svc.Name, port)
+}
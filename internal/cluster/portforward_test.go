@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testService(targetPort intstr.IntOrString) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "argocd-server", Namespace: "argocd"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app.kubernetes.io/name": "argocd-server"},
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: targetPort},
+				{Name: "https", Port: 443, TargetPort: targetPort},
+			},
+		},
+	}
+}
+
+func testPod(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "argocd",
+			Labels:    map[string]string{"app.kubernetes.io/name": "argocd-server"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "server",
+				Ports: []corev1.ContainerPort{{Name: "server", ContainerPort: 8080}},
+			}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
+
+func TestServicePod(t *testing.T) {
+	svc := testService(intstr.FromInt32(8080))
+	client := fake.NewClientset(
+		testPod("pending", corev1.PodPending, corev1.ConditionFalse),
+		testPod("starting", corev1.PodRunning, corev1.ConditionFalse),
+		testPod("ready", corev1.PodRunning, corev1.ConditionTrue),
+	)
+
+	pod, err := servicePod(context.Background(), client, svc)
+	if err != nil {
+		t.Fatalf("servicePod() error = %v", err)
+	}
+	if pod.Name != "ready" {
+		t.Errorf("servicePod() = %s, want ready", pod.Name)
+	}
+
+	if _, err := servicePod(context.Background(), fake.NewClientset(), svc); err == nil {
+		t.Error("servicePod() should fail without a ready pod")
+	}
+	svc.Spec.Selector = nil
+	if _, err := servicePod(context.Background(), client, svc); err == nil {
+		t.Error("servicePod() should fail for a service without a selector")
+	}
+}
+
+func TestTargetPort(t *testing.T) {
+	pod := testPod("ready", corev1.PodRunning, corev1.ConditionTrue)
+	tests := []struct {
+		name    string
+		target  intstr.IntOrString
+		port    int
+		want    int
+		wantErr bool
+	}{
+		{name: "number", target: intstr.FromInt32(8080), port: 443, want: 8080},
+		{name: "named", target: intstr.FromString("server"), port: 443, want: 8080},
+		{name: "unset", port: 443, want: 443},
+		{name: "unknown name", target: intstr.FromString("grpc"), port: 443, wantErr: true},
+		{name: "unknown port", target: intstr.FromInt32(8080), port: 8443, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := targetPort(testService(tt.target), pod, tt.port)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("targetPort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("targetPort() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRESTConfig_Token(t *testing.T) {
+	c := New("https://api.example.com:6443", "test", PlatformKubernetes)
+	if _, err := c.RESTConfig(); err == nil {
+		t.Error("RESTConfig() should fail before authentication")
+	}
+
+	c.auth = &AuthOptions{Method: AuthToken, Token: "secret", SkipTLS: true}
+	cfg, err := c.RESTConfig()
+	if err != nil {
+		t.Fatalf("RESTConfig() error = %v", err)
+	}
+	if cfg.Host != "https://api.example.com:6443" || cfg.BearerToken != "secret" || !cfg.Insecure {
+		t.Errorf("RESTConfig() = %+v", cfg)
+	}
+}
@@ -0,0 +1,279 @@
+// Package compatibility checks generated manifests against the API versions
+// served by the targeted Kubernetes, OpenShift, ArgoCD and Flux releases.
+package compatibility
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/version"
+)
+
+// Severity of a compatibility finding.
+type Severity string
+
+const (
+	// SeverityWarning marks a deprecated API that is still served.
+	SeverityWarning Severity = "warning"
+	// SeverityError marks an API the target does not serve.
+	SeverityError Severity = "error"
+)
+
+// Target is the set of platform versions manifests are generated for. Empty
+// fields are not checked.
+type Target struct {
+	Kubernetes string
+	OpenShift  string
+	ArgoCD     string
+	Flux       string
+}
+
+// Finding is a compatibility problem in a generated manifest.
+type Finding struct {
+	File        string   `json:"file"`
+	Kind        string   `json:"kind"`
+	Name        string   `json:"name,omitempty"`
+	APIVersion  string   `json:"api_version"`
+	Severity    Severity `json:"severity"`
+	Message     string   `json:"message"`
+	Replacement string   `json:"replacement,omitempty"`
+}
+
+func (f Finding) String() string {
+	msg := fmt.Sprintf("%s: %s", f.File, f.Message)
+	if f.Replacement != "" {
+		msg += fmt.Sprintf(" (use %s)", f.Replacement)
+	}
+	return msg
+}
+
+// ToolAPI records the first release of a GitOps tool serving an API.
+type ToolAPI struct {
+	Tool         string
+	APIVersion   string
+	Kind         string
+	IntroducedIn string
+}
+
+// ToolAPIs is the ArgoCD and Flux API matrix for the resources gitopsi generates.
+var ToolAPIs = []ToolAPI{
+	{"argocd", "argoproj.io/v1alpha1", "Application", "1.0"},
+	{"argocd", "argoproj.io/v1alpha1", "AppProject", "1.0"},
+	// ApplicationSet controller is bundled with ArgoCD since 2.3.
+	{"argocd", "argoproj.io/v1alpha1", "ApplicationSet", "2.3"},
+
+	{"flux", "source.toolkit.fluxcd.io/v1", "GitRepository", "2.0"},
+	{"flux", "source.toolkit.fluxcd.io/v1", "HelmRepository", "2.3"},
+	{"flux", "kustomize.toolkit.fluxcd.io/v1", "Kustomization", "2.0"},
+	{"flux", "helm.toolkit.fluxcd.io/v2", "HelmRelease", "2.3"},
+	{"flux", "notification.toolkit.fluxcd.io/v1beta3", "Alert", "2.3"},
+	{"flux", "notification.toolkit.fluxcd.io/v1beta3", "Provider", "2.3"},
+	{"flux", "image.toolkit.fluxcd.io/v1beta2", "ImageRepository", "2.0"},
+	{"flux", "image.toolkit.fluxcd.io/v1beta2", "ImagePolicy", "2.0"},
+	{"flux", "image.toolkit.fluxcd.io/v1beta2", "ImageUpdateAutomation", "2.0"},
+}
+
+// Checker evaluates manifests against a Target and accumulates findings.
+type Checker struct {
+	target   Target
+	k8s      *version.KubernetesVersion
+	mapper   *version.Mapper
+	tools    map[string]*version.KubernetesVersion
+	strict   bool
+	mu       sync.Mutex
+	findings []Finding
+}
+
+// New creates a Checker. OpenShift versions are translated to the Kubernetes
+// version they ship. With strict set, deprecated APIs are reported as errors.
+func New(target Target, strict bool) (*Checker, error) {
+	c := &Checker{target: target, strict: strict, tools: map[string]*version.KubernetesVersion{}}
+
+	k8sVersion := target.Kubernetes
+	if k8sVersion == "" && target.OpenShift != "" {
+		v, ok := version.GetKubernetesVersionForOpenShift(target.OpenShift)
+		if !ok {
+			return nil, fmt.Errorf("unknown OpenShift version: %s", target.OpenShift)
+		}
+		k8sVersion = v
+	}
+	if k8sVersion != "" {
+		v, err := version.ParseVersion(k8sVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes version: %w", err)
+		}
+		c.k8s = v
+	}
+
+	mapper, err := version.NewMapper(k8sVersion, "")
+	if err != nil {
+		return nil, err
+	}
+	c.mapper = mapper
+
+	for tool, raw := range map[string]string{"argocd": target.ArgoCD, "flux": target.Flux} {
+		if raw == "" {
+			continue
+		}
+		v, err := version.ParseVersion(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s version: %w", tool, err)
+		}
+		c.tools[tool] = v
+	}
+
+	return c, nil
+}
+
+// KubernetesVersion returns the effective Kubernetes target, or "" if unset.
+func (c *Checker) KubernetesVersion() string {
+	if c.k8s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", c.k8s.Major, c.k8s.Minor)
+}
+
+// Check evaluates every document in a YAML manifest. Files that are not YAML
+// or cannot be parsed (e.g. Helm chart templates) are skipped.
+func (c *Checker) Check(file string, content []byte) []Finding {
+	if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+		return nil
+	}
+
+	var findings []Finding
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Metadata   struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return findings
+		}
+		if doc.APIVersion == "" || doc.Kind == "" {
+			continue
+		}
+		if f := c.checkResource(doc.Kind, doc.APIVersion); f != nil {
+			f.File = file
+			f.Name = doc.Metadata.Name
+			findings = append(findings, *f)
+		}
+	}
+	return findings
+}
+
+func (c *Checker) checkResource(kind, apiVersion string) *Finding {
+	for _, api := range ToolAPIs {
+		if api.Kind != kind || api.APIVersion != apiVersion {
+			continue
+		}
+		target, ok := c.tools[api.Tool]
+		if !ok {
+			return nil
+		}
+		introduced, err := version.ParseVersion(api.IntroducedIn)
+		if err == nil && target.Compare(introduced) < 0 {
+			return &Finding{
+				Kind:       kind,
+				APIVersion: apiVersion,
+				Severity:   SeverityError,
+				Message:    fmt.Sprintf("%s %s requires %s %s or later (target %s)", kind, apiVersion, toolName(api.Tool), api.IntroducedIn, target.Raw),
+			}
+		}
+		return nil
+	}
+
+	if dep := c.mapper.CheckDeprecation(kind, apiVersion); dep != nil {
+		severity := SeverityWarning
+		if dep.Severity == "error" || c.strict {
+			severity = SeverityError
+		}
+		return &Finding{
+			Kind:        kind,
+			APIVersion:  apiVersion,
+			Severity:    severity,
+			Message:     dep.Message,
+			Replacement: dep.ReplacementAPI,
+		}
+	}
+
+	// The preferred API may be newer than the target cluster.
+	if c.k8s != nil {
+		if want := c.mapper.GetAPIVersion(kind); want != "" && want != apiVersion && apiVersion == version.DefaultAPIVersions[kind] {
+			return &Finding{
+				Kind:        kind,
+				APIVersion:  apiVersion,
+				Severity:    SeverityError,
+				Message:     fmt.Sprintf("%s %s is not served by Kubernetes %s", kind, apiVersion, c.KubernetesVersion()),
+				Replacement: want,
+			}
+		}
+	}
+	return nil
+}
+
+// BeforeWrite records findings for a file about to be written and returns an
+// error if any of them would not be served by the target, blocking the write.
+func (c *Checker) BeforeWrite(file string, content []byte) error {
+	findings := c.Check(file, content)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.findings = append(c.findings, findings...)
+	c.mu.Unlock()
+
+	var errs []string
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.String())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("incompatible API versions for target: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Findings returns everything recorded by BeforeWrite.
+func (c *Checker) Findings() []Finding {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Finding(nil), c.findings...)
+}
+
+// Warnings returns the recorded findings that did not block generation.
+func (c *Checker) Warnings() []Finding {
+	var warnings []Finding
+	for _, f := range c.Findings() {
+		if f.Severity == SeverityWarning {
+			warnings = append(warnings, f)
+		}
+	}
+	return warnings
+}
+
+func toolName(tool string) string {
+	switch tool {
+	case "argocd":
+		return "ArgoCD"
+	case "flux":
+		return "Flux"
+	}
+	return tool
+}
@@ -0,0 +1,144 @@
+package compatibility
+
+import (
+	"strings"
+	"testing"
+)
+
+const cronJobV1beta1 = `apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: backup
+`
+
+const hpaV2beta2 = `apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+`
+
+const hpaV2 = `apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+`
+
+const appSet = `apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: apps
+`
+
+const helmRelease = `apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: podinfo
+`
+
+func mustNew(t *testing.T, target Target, strict bool) *Checker {
+	t.Helper()
+	c, err := New(target, strict)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       Target
+		strict       bool
+		content      string
+		wantSeverity Severity
+		wantReplace  string
+	}{
+		{"removed API", Target{Kubernetes: "1.29"}, false, cronJobV1beta1, SeverityError, "batch/v1"},
+		{"deprecated API", Target{Kubernetes: "1.24"}, false, hpaV2beta2, SeverityWarning, "autoscaling/v2"},
+		{"deprecated API strict", Target{Kubernetes: "1.24"}, true, hpaV2beta2, SeverityError, "autoscaling/v2"},
+		{"API newer than target", Target{Kubernetes: "1.22"}, false, hpaV2, SeverityError, "autoscaling/v2beta2"},
+		{"served API", Target{Kubernetes: "1.28"}, false, hpaV2, "", ""},
+		{"removed API via OpenShift", Target{OpenShift: "4.14"}, false, cronJobV1beta1, SeverityError, "batch/v1"},
+		{"ApplicationSet on old ArgoCD", Target{ArgoCD: "2.2"}, false, appSet, SeverityError, ""},
+		{"ApplicationSet on current ArgoCD", Target{ArgoCD: "v2.9.3"}, false, appSet, "", ""},
+		{"ApplicationSet without ArgoCD target", Target{}, false, appSet, "", ""},
+		{"HelmRelease v2 on old Flux", Target{Flux: "2.1"}, false, helmRelease, SeverityError, ""},
+		{"HelmRelease v2 on current Flux", Target{Flux: "2.3.0"}, false, helmRelease, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mustNew(t, tt.target, tt.strict)
+			findings := c.Check("test.yaml", []byte(tt.content))
+
+			if tt.wantSeverity == "" {
+				if len(findings) != 0 {
+					t.Fatalf("Check() = %v, want no findings", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("Check() returned %d findings, want 1", len(findings))
+			}
+			f := findings[0]
+			if f.Severity != tt.wantSeverity {
+				t.Errorf("Severity = %s, want %s (%s)", f.Severity, tt.wantSeverity, f.Message)
+			}
+			if f.Replacement != tt.wantReplace {
+				t.Errorf("Replacement = %q, want %q", f.Replacement, tt.wantReplace)
+			}
+			if f.File != "test.yaml" || f.Name == "" {
+				t.Errorf("finding missing location: %+v", f)
+			}
+		})
+	}
+}
+
+func TestCheckSkipsUnparsableFiles(t *testing.T) {
+	c := mustNew(t, Target{Kubernetes: "1.29"}, false)
+
+	if got := c.Check("README.md", []byte(cronJobV1beta1)); len(got) != 0 {
+		t.Errorf("non-YAML file should be skipped, got %v", got)
+	}
+	if got := c.Check("chart/templates/x.yaml", []byte("{{- if .Values.x }}\n: [\n")); len(got) != 0 {
+		t.Errorf("unparsable file should be skipped, got %v", got)
+	}
+
+	multi := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n---\n" + cronJobV1beta1
+	if got := c.Check("multi.yaml", []byte(multi)); len(got) != 1 || got[0].Name != "backup" {
+		t.Errorf("Check() on multi-document file = %v", got)
+	}
+}
+
+func TestBeforeWrite(t *testing.T) {
+	c := mustNew(t, Target{Kubernetes: "1.24", ArgoCD: "2.2"}, false)
+
+	if err := c.BeforeWrite("hpa.yaml", []byte(hpaV2beta2)); err != nil {
+		t.Errorf("BeforeWrite() for deprecated API error = %v, want nil", err)
+	}
+	err := c.BeforeWrite("appset.yaml", []byte(appSet))
+	if err == nil || !strings.Contains(err.Error(), "requires ArgoCD 2.3") {
+		t.Errorf("BeforeWrite() error = %v, want ArgoCD version error", err)
+	}
+
+	if got := len(c.Findings()); got != 2 {
+		t.Errorf("Findings() = %d, want 2", got)
+	}
+	if got := c.Warnings(); len(got) != 1 || got[0].File != "hpa.yaml" {
+		t.Errorf("Warnings() = %v", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Target{OpenShift: "3.11"}, false); err == nil {
+		t.Error("New() should reject unknown OpenShift versions")
+	}
+	if _, err := New(Target{ArgoCD: "latest"}, false); err == nil {
+		t.Error("New() should reject invalid ArgoCD versions")
+	}
+
+	c := mustNew(t, Target{OpenShift: "4.14"}, false)
+	if got := c.KubernetesVersion(); got != "1.27" {
+		t.Errorf("KubernetesVersion() = %s, want 1.27", got)
+	}
+}
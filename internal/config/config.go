@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/ihsanmokhlisse/gitopsi/internal/operator"
+	"github.com/ihsanmokhlisse/gitopsi/internal/syncwave"
 )
 
 // Preset defines a configuration preset type
@@ -16,6 +20,9 @@ const (
 
 // Config represents the complete gitopsi configuration.
 type Config struct {
+	// APIVersion is the schema version the config was written for; Load
+	// migrates older configs
+	APIVersion   string              `yaml:"apiVersion,omitempty"`
 	Preset       Preset              `yaml:"preset,omitempty"`
 	Project      Project             `yaml:"project"`
 	Structure    StructureConfig     `yaml:"structure,omitempty"`
@@ -23,25 +30,731 @@ type Config struct {
 	Output       Output              `yaml:"output"`
 	Git          GitConfig           `yaml:"git"`
 	Cluster      ClusterConfig       `yaml:"cluster"`
+	Clusters     []ClusterProvision  `yaml:"clusters,omitempty"`
 	Bootstrap    BootstrapConfig     `yaml:"bootstrap"`
 	Platform     string              `yaml:"platform"`
 	Scope        string              `yaml:"scope"`
 	GitOpsTool   string              `yaml:"gitops_tool"`
 	Topology     EnvironmentTopology `yaml:"topology,omitempty"`
 	Environments []Environment       `yaml:"environments"`
+	Tenants      []Tenant            `yaml:"tenants,omitempty"`
 	Infra        Infrastructure      `yaml:"infrastructure"`
 	Apps         []Application       `yaml:"applications"`
 	Docs         Documentation       `yaml:"docs"`
 	Version      VersionConfig       `yaml:"version,omitempty"`
 	Operators    operator.Config     `yaml:"operators,omitempty"`
+	ArgoCD       ArgoCDConfig        `yaml:"argocd,omitempty"`
+	Flux         FluxConfig          `yaml:"flux,omitempty"`
+	OpenShift    OpenShiftConfig     `yaml:"openshift,omitempty"`
+	Addons       AddonsConfig        `yaml:"addons,omitempty"`
+	TLS          TLSConfig           `yaml:"tls,omitempty"`
+	DNS          DNSConfig           `yaml:"dns,omitempty"`
+	Backup       BackupConfig        `yaml:"backup,omitempty"`
+	Cost         CostConfig          `yaml:"cost,omitempty"`
+	SSO          SSOConfig           `yaml:"sso,omitempty"`
+	Secrets      SecretsConfig       `yaml:"secrets,omitempty"`
+	Promotion    PromotionConfig     `yaml:"promotion,omitempty"`
+	CI           CIConfig            `yaml:"ci,omitempty"`
+	Preview      PreviewConfig       `yaml:"preview,omitempty"`
+	Layout       LayoutConfig        `yaml:"layout,omitempty"`
+	Images       ImagesConfig        `yaml:"images,omitempty"`
+	Conventions  ConventionsConfig   `yaml:"conventions,omitempty"`
+	Validation   ValidationConfig    `yaml:"validation,omitempty"`
+	Kustomize    KustomizeConfig     `yaml:"kustomize,omitempty"`
+	Audit        AuditConfig         `yaml:"audit,omitempty"`
+}
+
+// AuditConfig controls the audit log of the operations on the project.
+type AuditConfig struct {
+	// Repository also records the operations on the project in
+	// .gitopsi/audit.jsonl of the repository, next to ~/.gitopsi/audit.jsonl
+	Repository bool `yaml:"repository,omitempty"`
+}
+
+// SecretsConfig controls how secret manifests are protected before they are committed.
+type SecretsConfig struct {
+	// Format is the secret format: plain or sops (default: plain)
+	Format string `yaml:"format,omitempty"`
+	// Sops configures the keys used for SOPS encryption
+	Sops SopsConfig `yaml:"sops,omitempty"`
+}
+
+// SopsConfig lists SOPS recipients and the files they apply to.
+type SopsConfig struct {
+	Age           []string `yaml:"age,omitempty"`
+	PGP           []string `yaml:"pgp,omitempty"`
+	KMS           []string `yaml:"kms,omitempty"`
+	GCPKMS        []string `yaml:"gcp_kms,omitempty"`
+	AzureKeyVault []string `yaml:"azure_keyvault,omitempty"`
+	// PathRegex selects files encrypted by the creation rule (default: \.sops\.ya?ml$)
+	PathRegex string `yaml:"path_regex,omitempty"`
+	// EncryptedRegex selects the keys that are encrypted (default: ^(data|stringData)$)
+	EncryptedRegex string `yaml:"encrypted_regex,omitempty"`
+}
+
+// HasKeys reports whether at least one SOPS recipient is configured.
+func (s SopsConfig) HasKeys() bool {
+	return len(s.Age)+len(s.PGP)+len(s.KMS)+len(s.GCPKMS)+len(s.AzureKeyVault) > 0
+}
+
+// ImagesConfig controls how application images are referenced.
+type ImagesConfig struct {
+	// PinDigests resolves image tags to digests with their registry when the
+	// repository is generated and applications are promoted, using the
+	// registry credentials stored with gitopsi auth
+	PinDigests bool `yaml:"pin_digests,omitempty"`
+}
+
+// ConventionsConfig holds the organization conventions every generated
+// resource follows.
+type ConventionsConfig struct {
+	// Names holds the name templates of generated resources
+	Names NameConventions `yaml:"names,omitempty"`
+	// Labels are added to every generated resource that does not set them
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Annotations are added to every generated resource that does not set them
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	// RequiredLabels are the labels gitopsi validate requires on every resource
+	RequiredLabels []string `yaml:"required_labels,omitempty"`
+}
+
+// ValidationConfig configures the checks of gitopsi validate.
+type ValidationConfig struct {
+	// DisabledChecks are the IDs or names of the built-in security checks
+	// gitopsi validate skips, such as SEC004 or read-only-root-filesystem
+	DisabledChecks []string `yaml:"disabled_checks,omitempty"`
+	// DefaultResources are the resources gitopsi fix adds to containers
+	// without limits (default: the default resources of applications)
+	DefaultResources *Resources `yaml:"default_resources,omitempty"`
+}
+
+// NameConventions are name templates with {project} and {env} placeholders.
+type NameConventions struct {
+	// Namespace names the namespace of each environment (default: {project}-{env})
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// NamespaceName returns the namespace of environment env of a project
+// under the namespace name template.
+func (n NameConventions) NamespaceName(project, env string) string {
+	if n.Namespace == "" {
+		return project + "-" + env
+	}
+	return strings.NewReplacer("{project}", project, "{env}", env).Replace(n.Namespace)
+}
+
+// CI providers a pull request pipeline can be generated for.
+const (
+	CIProviderGitHubActions = "github-actions"
+	CIProviderGitLabCI      = "gitlab-ci"
+	CIProviderTekton        = "tekton"
+)
+
+// DefaultCIImage is the gitopsi image generated pipelines run.
+const DefaultCIImage = "ghcr.io/ihsanmokhlisse/gitopsi:latest"
+
+// CIConfig configures the pipeline generated into the repository to validate
+// every pull request.
+type CIConfig struct {
+	// Provider is github-actions, gitlab-ci or tekton (default: no pipeline)
+	Provider string `yaml:"provider,omitempty"`
+	// Image is the gitopsi container image the pipeline runs (default: DefaultCIImage)
+	Image string `yaml:"image,omitempty"`
+	// FailOn is the lowest validation severity failing the pipeline (default: high)
+	FailOn string `yaml:"fail_on,omitempty"`
+	// Diff adds a job comparing each environment with its cluster, using the
+	// kubeconfig stored in the KUBECONFIG CI secret
+	Diff bool `yaml:"diff,omitempty"`
+}
+
+// Enabled reports whether a pipeline is generated.
+func (c CIConfig) Enabled() bool {
+	return c.Provider != ""
+}
+
+// ImageRef returns the gitopsi image, defaulting to DefaultCIImage.
+func (c CIConfig) ImageRef() string {
+	if c.Image == "" {
+		return DefaultCIImage
+	}
+	return c.Image
+}
+
+// Severity returns the fail_on severity, defaulting to high.
+func (c CIConfig) Severity() string {
+	if c.FailOn == "" {
+		return "high"
+	}
+	return c.FailOn
+}
+
+// Pull request providers of preview environments.
+const (
+	PreviewProviderGitHub = "github"
+	PreviewProviderGitLab = "gitlab"
+)
+
+// PreviewConfig configures preview environments: an ArgoCD ApplicationSet
+// with the pull request generator deploys every open pull request of an
+// application repository to its own namespace.
+type PreviewConfig struct {
+	// Provider is github or gitlab (default: no preview environments)
+	Provider string `yaml:"provider,omitempty"`
+	// Repository is owner/repo on GitHub or the project path on GitLab
+	Repository string `yaml:"repository,omitempty"`
+	// RepoURL is the clone URL of the repository (default: from provider and repository)
+	RepoURL string `yaml:"repo_url,omitempty"`
+	// API is the API URL of GitHub Enterprise or a self-hosted GitLab
+	API string `yaml:"api,omitempty"`
+	// TokenSecret is the Secret in the ArgoCD namespace whose token key holds
+	// the provider API token (default: <project>-preview-token)
+	TokenSecret string `yaml:"token_secret,omitempty"`
+	// Labels only deploys pull requests with all of these labels
+	Labels []string `yaml:"labels,omitempty"`
+	// Path is the directory of the manifests in the repository (default: deploy)
+	Path string `yaml:"path,omitempty"`
+	// Helm renders Path as a Helm chart instead of a kustomization
+	Helm bool `yaml:"helm,omitempty"`
+	// Namespace of each preview; {number}, {branch}, {branch_slug} and
+	// {head_short_sha} are replaced (default: <project>-pr-{number})
+	Namespace string `yaml:"namespace,omitempty"`
+	// Host is the ingress host of each preview, with the same placeholders,
+	// e.g. pr-{number}.preview.example.com
+	Host string `yaml:"host,omitempty"`
+	// HostParameter is the Helm value set to Host (default: ingress.host)
+	HostParameter string `yaml:"host_parameter,omitempty"`
+	// RequeueAfter is how often the provider is polled for pull requests (default: 5m)
+	RequeueAfter string `yaml:"requeue_after,omitempty"`
+	// TTL removes the labels from pull requests without activity for this
+	// long, e.g. 72h, which deletes their preview. Requires labels.
+	TTL string `yaml:"ttl,omitempty"`
+	// Cleanup configures the CronJob enforcing the TTL
+	Cleanup PreviewCleanup `yaml:"cleanup,omitempty"`
+}
+
+// PreviewCleanup configures the TTL cleanup CronJob of preview environments.
+type PreviewCleanup struct {
+	// Schedule is the cron schedule (default: every hour)
+	Schedule string `yaml:"schedule,omitempty"`
+	// Image provides sh, curl, jq and kubectl (default: alpine/k8s:1.31.4)
+	Image string `yaml:"image,omitempty"`
+}
+
+// Enabled reports whether preview environments are generated.
+func (p PreviewConfig) Enabled() bool {
+	return p.Provider != ""
+}
+
+// SecretName returns the Secret holding the provider API token.
+func (p PreviewConfig) SecretName(project string) string {
+	if p.TokenSecret != "" {
+		return p.TokenSecret
+	}
+	return project + "-preview-token"
+}
+
+// Repository layouts.
+const (
+	LayoutMonorepo    = "monorepo"
+	LayoutHubAndSpoke = "hub-and-spoke"
+	LayoutPerApp      = "per-app"
+)
+
+// LayoutConfig splits the generated content across repositories. The
+// project repository (git.url) always holds the infrastructure and the
+// ArgoCD configuration; hub-and-spoke moves the applications of each team to
+// a team repository, per-app gives every application its own repository.
+type LayoutConfig struct {
+	// Strategy is monorepo, hub-and-spoke or per-app (default: monorepo)
+	Strategy string `yaml:"strategy,omitempty"`
+	// RepoURL is the URL of the additional repositories, with {repo}
+	// replaced by their name, e.g. https://github.com/acme/{repo}.git
+	RepoURL string `yaml:"repo_url,omitempty"`
+	// Teams assigns the applications to team repositories (hub-and-spoke)
+	Teams []LayoutTeam `yaml:"teams,omitempty"`
+}
+
+// LayoutTeam is a team repository of the hub-and-spoke layout.
+type LayoutTeam struct {
+	Name string   `yaml:"name"`
+	Apps []string `yaml:"apps"`
+	// RepoURL is the URL of the team repository (default: layout.repo_url)
+	RepoURL string `yaml:"repo_url,omitempty"`
+}
+
+// AppRepository is an application repository generated next to the project
+// repository.
+type AppRepository struct {
+	// Name is the directory of the repository: <project>-<team> or
+	// <project>-<app>
+	Name string
+	URL  string
+	// Team is the team owning the repository (hub-and-spoke)
+	Team string
+	Apps []string
+}
+
+// Split reports whether applications are generated into their own
+// repositories.
+func (l LayoutConfig) Split() bool {
+	return l.Strategy == LayoutHubAndSpoke || l.Strategy == LayoutPerApp
+}
+
+// AppRepositories returns the application repositories of the layout, in
+// the order of the teams or applications.
+func (c *Config) AppRepositories() []AppRepository {
+	var repos []AppRepository
+	switch c.Layout.Strategy {
+	case LayoutHubAndSpoke:
+		for _, team := range c.Layout.Teams {
+			name := c.Project.Name + "-" + team.Name
+			url := team.RepoURL
+			if url == "" {
+				url = strings.ReplaceAll(c.Layout.RepoURL, "{repo}", name)
+			}
+			repos = append(repos, AppRepository{Name: name, URL: url, Team: team.Name, Apps: team.Apps})
+		}
+	case LayoutPerApp:
+		for _, app := range c.Apps {
+			name := c.Project.Name + "-" + app.Name
+			url := strings.ReplaceAll(c.Layout.RepoURL, "{repo}", name)
+			repos = append(repos, AppRepository{Name: name, URL: url, Apps: []string{app.Name}})
+		}
+	}
+	return repos
+}
+
+// PromotionConfig controls `gitopsi promote`.
+type PromotionConfig struct {
+	// Gates must pass before promoting to an environment marked protected.
+	// Approval is required when no gate is enabled.
+	Gates PromotionGates `yaml:"gates,omitempty"`
+}
+
+// PromotionGates lists the checks of a promotion to a protected environment.
+type PromotionGates struct {
+	// SourceHealthy requires the ArgoCD Application of the source environment to be Synced and Healthy
+	SourceHealthy bool `yaml:"source_healthy,omitempty"`
+	// ArgoCDApplication names that Application; {project}, {app} and {env} are replaced (default: {project}-apps-{env})
+	ArgoCDApplication string `yaml:"argocd_application,omitempty"`
+	// Approval requires the promotion to be approved with --approve
+	Approval bool `yaml:"approval,omitempty"`
+	// SoakTime is how long the source environment must have run the promoted state, e.g. 24h
+	SoakTime string `yaml:"soak_time,omitempty"`
+	// Validation requires `gitopsi validate` to pass on the project
+	Validation bool `yaml:"validation,omitempty"`
+}
+
+// Enabled reports whether at least one gate is enabled.
+func (g PromotionGates) Enabled() bool {
+	return g.SourceHealthy || g.Approval || g.SoakTime != "" || g.Validation
+}
+
+// ArgoCDConfig holds ArgoCD-specific generation options.
+type ArgoCDConfig struct {
+	ApplicationSet ArgoCDApplicationSetConfig `yaml:"applicationset,omitempty"`
+	RBAC           ArgoCDRBACConfig           `yaml:"rbac,omitempty"`
+}
+
+// ArgoCDRBACConfig configures the generated argocd-rbac-cm. Tenant groups are
+// mapped to their AppProject roles automatically.
+type ArgoCDRBACConfig struct {
+	// AdminGroups are the IdP groups granted role:admin (default: cluster-admins)
+	AdminGroups []string `yaml:"admin_groups,omitempty"`
+	// DefaultPolicy is the role of authenticated users without a mapping (default: role:readonly)
+	DefaultPolicy string `yaml:"default_policy,omitempty"`
+	// Scopes are the OIDC claims holding groups (default: [groups])
+	Scopes []string `yaml:"scopes,omitempty"`
+	// Policies are additional policy.csv lines
+	Policies []string `yaml:"policies,omitempty"`
+}
+
+// Enabled reports whether any RBAC setting is configured.
+func (r ArgoCDRBACConfig) Enabled() bool {
+	return len(r.AdminGroups) > 0 || r.DefaultPolicy != "" || len(r.Scopes) > 0 || len(r.Policies) > 0
+}
+
+// SSO providers. oidc configures ArgoCD's built-in OIDC client; the others
+// configure a Dex connector.
+const (
+	SSOProviderOIDC      = "oidc"
+	SSOProviderGitHub    = "github"
+	SSOProviderGitLab    = "gitlab"
+	SSOProviderMicrosoft = "microsoft"
+	SSOProviderKeycloak  = "keycloak"
+)
+
+// SSOConfig configures single sign-on for the generated ArgoCD.
+type SSOConfig struct {
+	// Provider is oidc, github, gitlab, microsoft (Azure AD) or keycloak
+	Provider string `yaml:"provider,omitempty"`
+	// URL is the external ArgoCD URL used for login redirects
+	URL string `yaml:"url,omitempty"`
+	// Issuer is the OIDC issuer: the realm URL for keycloak, the instance URL for gitlab
+	Issuer   string `yaml:"issuer,omitempty"`
+	ClientID string `yaml:"client_id,omitempty"`
+	// ClientSecret locates the OAuth client secret
+	ClientSecret SSOSecretRef `yaml:"client_secret,omitempty"`
+	// GroupsClaim is the token claim holding group memberships (default: groups)
+	GroupsClaim string `yaml:"groups_claim,omitempty"`
+	// Scopes are additional scopes requested from the provider
+	Scopes []string `yaml:"scopes,omitempty"`
+	// Orgs restricts login to GitHub organizations or GitLab groups
+	Orgs []string `yaml:"orgs,omitempty"`
+	// TenantID is the Azure AD tenant (default: common)
+	TenantID string `yaml:"tenant_id,omitempty"`
+}
+
+// SSOSecretRef references the Kubernetes Secret holding the client secret.
+type SSOSecretRef struct {
+	// Name of the Secret (default: argocd-sso)
+	Name string `yaml:"name,omitempty"`
+	// Key in the Secret (default: clientSecret)
+	Key string `yaml:"key,omitempty"`
+	// Env is the environment variable read for the value when the Secret is generated
+	Env string `yaml:"env,omitempty"`
+	// Existing skips generating the Secret, which is managed elsewhere
+	Existing bool `yaml:"existing,omitempty"`
+}
+
+// Enabled reports whether SSO is configured.
+func (s SSOConfig) Enabled() bool {
+	return s.Provider != ""
+}
+
+// UsesDex reports whether the provider is configured through Dex.
+func (s SSOConfig) UsesDex() bool {
+	return s.Enabled() && s.Provider != SSOProviderOIDC
+}
+
+// SecretName returns the name of the client secret Secret.
+func (s SSOConfig) SecretName() string {
+	if s.ClientSecret.Name != "" {
+		return s.ClientSecret.Name
+	}
+	return "argocd-sso"
+}
+
+// SecretKey returns the key of the client secret in its Secret.
+func (s SSOConfig) SecretKey() string {
+	if s.ClientSecret.Key != "" {
+		return s.ClientSecret.Key
+	}
+	return "clientSecret"
+}
+
+// Groups returns the groups claim.
+func (s SSOConfig) Groups() string {
+	if s.GroupsClaim != "" {
+		return s.GroupsClaim
+	}
+	return "groups"
+}
+
+// ArgoCDApplicationSetConfig controls how ApplicationSets fan out.
+type ArgoCDApplicationSetConfig struct {
+	// Generator selects the ApplicationSet generator: cluster, git, or matrix.
+	// When empty, ApplicationSets follow the configured topology.
+	Generator string `yaml:"generator,omitempty"`
+}
+
+// FluxConfig holds Flux-specific generation options.
+type FluxConfig struct {
+	// Interval is the reconciliation interval for generated Flux resources (default: 10m)
+	Interval string `yaml:"interval,omitempty"`
+	// HelmReleases deploys applications as HelmReleases instead of plain Kustomize manifests
+	HelmReleases bool `yaml:"helm_releases,omitempty"`
+	// ImageAutomation configures ImageRepository/ImagePolicy/ImageUpdateAutomation resources
+	ImageAutomation FluxImageAutomation `yaml:"image_automation,omitempty"`
+	// Lockdown applies the multi-tenancy lockdown when tenants are defined:
+	// controllers refuse cross-namespace references and remote bases and
+	// impersonate the default service account (default: true)
+	Lockdown *bool `yaml:"lockdown,omitempty"`
+}
+
+// FluxImageAutomation holds Flux image update automation options.
+type FluxImageAutomation struct {
+	Enabled     bool   `yaml:"enabled"`
+	SemverRange string `yaml:"semver_range,omitempty"` // Semver range for ImagePolicy (default: >=0.0.0)
+	Interval    string `yaml:"interval,omitempty"`     // Registry scan interval (default: 5m)
+	Branch      string `yaml:"branch,omitempty"`       // Branch to push updates to (default: git.branch)
+	AuthorName  string `yaml:"author_name,omitempty"`
+	AuthorEmail string `yaml:"author_email,omitempty"`
+}
+
+// FluxLockdown reports whether the Flux multi-tenancy lockdown applies.
+func (c *Config) FluxLockdown() bool {
+	return len(c.Tenants) > 0 && (c.Flux.Lockdown == nil || *c.Flux.Lockdown)
+}
+
+// OpenShiftConfig holds the options of platform openshift.
+type OpenShiftConfig struct {
+	// ProjectRequests creates the environment and tenant namespaces with
+	// ProjectRequests, which apply the project template of the cluster,
+	// instead of Namespaces
+	ProjectRequests bool `yaml:"project_requests,omitempty"`
+}
+
+// IsCloud reports whether the project targets a managed Kubernetes of a
+// cloud provider: eks, aks or gke.
+func (c *Config) IsCloud() bool {
+	return c.Platform == "eks" || c.Platform == "aks" || c.Platform == "gke"
+}
+
+// IsOpenShift reports whether the project targets OpenShift.
+func (c *Config) IsOpenShift() bool {
+	return c.Platform == "openshift"
+}
+
+// TLS solvers.
+const (
+	TLSSolverHTTP01 = "http01"
+	TLSSolverDNS01  = "dns01"
+)
+
+// TLSConfig generates the cert-manager ClusterIssuers and Certificates of the
+// project and wires the application ingresses to them. cert-manager itself
+// is installed with the cert-manager pattern.
+type TLSConfig struct {
+	// Email is the ACME account email of the Let's Encrypt issuers
+	Email string `yaml:"email,omitempty"`
+	// Solver proves domain ownership: http01 (default) or dns01
+	Solver string `yaml:"solver,omitempty"`
+	// Staging issues certificates from the Let's Encrypt staging environment
+	Staging bool `yaml:"staging,omitempty"`
+	// IngressClass is the IngressClass of the HTTP01 solver
+	IngressClass string      `yaml:"ingress_class,omitempty"`
+	DNS01        DNS01Config `yaml:"dns01,omitempty"`
+	// Wildcards are the wildcard domains a Certificate is issued for in
+	// every environment namespace; {env} is replaced by the environment
+	Wildcards []string `yaml:"wildcards,omitempty"`
+}
+
+// DNS01Config configures the DNS provider of the DNS01 solver.
+type DNS01Config struct {
+	// Provider is route53, cloudflare, clouddns or azuredns
+	Provider string `yaml:"provider,omitempty"`
+	// Credential names the auth store credential of the provider: an access
+	// key (username/password) or role ARN for route53, an API token for
+	// cloudflare, a service account key (token) for clouddns and a managed
+	// identity client ID for azuredns
+	Credential string `yaml:"credential,omitempty"`
+	// Region of Route 53 (default: us-east-1)
+	Region string `yaml:"region,omitempty"`
+	// HostedZoneID restricts Route 53 to a hosted zone
+	HostedZoneID string `yaml:"hosted_zone_id,omitempty"`
+	// Project is the Google Cloud project of Cloud DNS
+	Project string `yaml:"project,omitempty"`
+	// SubscriptionID, ResourceGroup and HostedZone locate the Azure DNS zone
+	SubscriptionID string `yaml:"subscription_id,omitempty"`
+	ResourceGroup  string `yaml:"resource_group,omitempty"`
+	HostedZone     string `yaml:"hosted_zone,omitempty"`
+}
+
+// Enabled reports whether TLS issuers are generated.
+func (t TLSConfig) Enabled() bool {
+	return t.Email != ""
+}
+
+// Issuer returns the ClusterIssuer the certificates are requested from.
+func (t TLSConfig) Issuer() string {
+	if t.Staging {
+		return "letsencrypt-staging"
+	}
+	return "letsencrypt-prod"
+}
+
+// DNS record modes.
+const (
+	DNSModeExternalDNS = "external-dns"
+	DNSModeDirect      = "direct"
+)
+
+// DNSConfig automates the DNS records of the application hostnames, with
+// external-dns annotations on the generated Ingresses and Routes or with
+// records created by gitopsi dns sync for clusters without external-dns.
+type DNSConfig struct {
+	// Provider hosts the zone: route53, cloudflare, clouddns or azuredns
+	Provider string `yaml:"provider,omitempty"`
+	// Zone is the DNS zone the application hostnames belong to
+	Zone string `yaml:"zone,omitempty"`
+	// Credential names the auth store credential of the provider, used by
+	// gitopsi dns check and sync: an access key (username/password) for
+	// route53, an API token for cloudflare and a service account key (token)
+	// for clouddns; without one the cloud CLI login is used
+	Credential string `yaml:"credential,omitempty"`
+	// Mode is external-dns (default) or direct
+	Mode string `yaml:"mode,omitempty"`
+	// ZoneID is the Route 53 hosted zone ID or the Cloudflare zone ID
+	// (default: looked up by zone name)
+	ZoneID string `yaml:"zone_id,omitempty"`
+	// Project is the Google Cloud project of Cloud DNS
+	Project string `yaml:"project,omitempty"`
+	// ResourceGroup is the Azure resource group of the Azure DNS zone
+	ResourceGroup string `yaml:"resource_group,omitempty"`
+	// TTL of the records in seconds (default: the provider default)
+	TTL int `yaml:"ttl,omitempty"`
+	// Target is the load balancer hostname or IP address the records of
+	// the direct mode point to
+	Target string `yaml:"target,omitempty"`
+}
+
+// Enabled reports whether the hostname records are automated.
+func (d DNSConfig) Enabled() bool {
+	return d.Zone != ""
+}
+
+// ExternalDNS reports whether the Ingresses and Routes are annotated for
+// external-dns.
+func (d DNSConfig) ExternalDNS() bool {
+	return d.Enabled() && d.Mode != DNSModeDirect
+}
+
+// InZone reports whether host is the zone or one of its subdomains.
+func (d DNSConfig) InZone(host string) bool {
+	zone := strings.TrimSuffix(d.Zone, ".")
+	host = strings.TrimSuffix(host, ".")
+	return host == zone || strings.HasSuffix(host, "."+zone)
+}
+
+// BackupConfig schedules Velero backups of the environment and tenant
+// namespaces.
+type BackupConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Velero is the marketplace pattern installing Velero, with its storage
+	// configuration (default: the velero pattern)
+	Velero PresetPattern `yaml:"velero,omitempty"`
+	// Namespace is the namespace Velero runs in (default: velero)
+	Namespace string `yaml:"namespace,omitempty"`
+	// Schedule is the cron schedule of the backups (default: 0 2 * * *)
+	Schedule string `yaml:"schedule,omitempty"`
+	// TTL is how long backups are kept (default: 720h)
+	TTL string `yaml:"ttl,omitempty"`
+	// StorageLocation is the BackupStorageLocation of the backups (default:
+	// the Velero default location)
+	StorageLocation string `yaml:"storage_location,omitempty"`
+	// SnapshotVolumes takes snapshots of the persistent volumes
+	SnapshotVolumes bool `yaml:"snapshot_volumes,omitempty"`
+	// ArgoCDExport is the directory gitopsi backup argocd writes to
+	// (default: backup/argocd)
+	ArgoCDExport string `yaml:"argocd_export,omitempty"`
+}
+
+// VeleroNamespace returns the namespace of Velero and its Schedules.
+func (b BackupConfig) VeleroNamespace() string {
+	if b.Namespace != "" {
+		return b.Namespace
+	}
+	return "velero"
+}
+
+// VeleroPattern returns the marketplace pattern installing Velero.
+func (b BackupConfig) VeleroPattern() PresetPattern {
+	pattern := b.Velero
+	if pattern.Name == "" {
+		pattern.Name = "velero"
+	}
+	return pattern
+}
+
+// ArgoCDExportDir returns the repository directory of the ArgoCD export.
+func (b BackupConfig) ArgoCDExportDir() string {
+	if b.ArgoCDExport != "" {
+		return b.ArgoCDExport
+	}
+	return "backup/argocd"
 }
 
-// VersionConfig defines target Kubernetes/OpenShift version for manifest compatibility.
+// CostConfig allocates the cost of the clusters with OpenCost: the generated
+// namespaces and workloads carry a cost center label OpenCost aggregates by.
+type CostConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// OpenCost is the marketplace pattern installing OpenCost (default: the
+	// opencost pattern)
+	OpenCost PresetPattern `yaml:"opencost,omitempty"`
+	// Label is the cost center label (default: cost-center)
+	Label string `yaml:"label,omitempty"`
+	// CostCenter is the cost center of the project resources; tenants set
+	// their own (default: the project name)
+	CostCenter string `yaml:"cost_center,omitempty"`
+}
+
+// LabelKey returns the cost center label.
+func (c CostConfig) LabelKey() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return "cost-center"
+}
+
+// OpenCostPattern returns the marketplace pattern installing OpenCost.
+func (c CostConfig) OpenCostPattern() PresetPattern {
+	pattern := c.OpenCost
+	if pattern.Name == "" {
+		pattern.Name = "opencost"
+	}
+	return pattern
+}
+
+// ProjectCostCenter returns the cost center of the project resources.
+func (c *Config) ProjectCostCenter() string {
+	if c.Cost.CostCenter != "" {
+		return c.Cost.CostCenter
+	}
+	return c.Project.Name
+}
+
+// TenantCostCenter returns the cost center of the resources of a tenant.
+func (c *Config) TenantCostCenter(tenant Tenant) string {
+	if tenant.CostCenter != "" {
+		return tenant.CostCenter
+	}
+	return tenant.Name
+}
+
+// AddonsConfig toggles the cloud add-ons of platforms eks, aks and gke.
+type AddonsConfig struct {
+	// ClusterName is the cloud name of the cluster the controllers manage
+	// (default: the cluster of the environment, then <project>-<env>)
+	ClusterName string `yaml:"cluster_name,omitempty"`
+	// Region is the cloud region of the cluster (default: the region of the
+	// environment cluster)
+	Region string `yaml:"region,omitempty"`
+	// LoadBalancerController installs the AWS Load Balancer Controller (eks)
+	LoadBalancerController *CloudAddon `yaml:"load_balancer_controller,omitempty"`
+	// ClusterAutoscaler installs the cluster-autoscaler (eks); AKS and GKE
+	// scale node pools themselves
+	ClusterAutoscaler *CloudAddon `yaml:"cluster_autoscaler,omitempty"`
+	// StorageClasses generates the CSI storage classes of the platform and
+	// makes the first one the default
+	StorageClasses bool `yaml:"storage_classes,omitempty"`
+}
+
+// CloudAddon is a controller installed from its Helm chart.
+type CloudAddon struct {
+	Enabled bool `yaml:"enabled"`
+	// Identity is the IAM role ARN the controller service account assumes
+	// with IRSA
+	Identity string `yaml:"identity,omitempty"`
+	// Version is the chart version (default: the version gitopsi was tested with)
+	Version string `yaml:"version,omitempty"`
+}
+
+// On reports whether the add-on is enabled.
+func (a *CloudAddon) On() bool {
+	return a != nil && a.Enabled
+}
+
+// VersionConfig defines target platform and GitOps tool versions for manifest compatibility.
 type VersionConfig struct {
 	// Kubernetes specifies the target Kubernetes version (e.g., "1.28", "1.27.5")
 	Kubernetes string `yaml:"kubernetes,omitempty"`
 	// OpenShift specifies the target OpenShift version (e.g., "4.14", "4.13.0")
 	OpenShift string `yaml:"openshift,omitempty"`
+	// ArgoCD specifies the target ArgoCD version (e.g., "2.9")
+	ArgoCD string `yaml:"argocd,omitempty"`
+	// Flux specifies the target Flux version (e.g., "2.3")
+	Flux string `yaml:"flux,omitempty"`
 	// AutoDetect enables automatic version detection from cluster
 	AutoDetect bool `yaml:"auto_detect,omitempty"`
 	// StrictMode fails on any deprecated APIs (default: warn only)
@@ -138,6 +851,20 @@ type GitConfig struct {
 	Auth            GitAuth     `yaml:"auth"`
 	PushOnInit      bool        `yaml:"push_on_init"`
 	CreateIfMissing bool        `yaml:"create_if_missing"`
+	// CommitMessage is a Go template for commits made on push (default: conventional initial/update message)
+	CommitMessage string `yaml:"commit_message,omitempty"`
+	// Credential names a git credential from `gitopsi auth` used to push
+	Credential string `yaml:"credential,omitempty"`
+	// Repository configures the repository created when CreateIfMissing is set
+	Repository GitRepository `yaml:"repository,omitempty"`
+}
+
+// GitRepository holds the settings of a repository created by gitopsi. The
+// owner (user, organization, group or workspace) is taken from the URL and
+// the default branch is the branch pushed first (git.branch).
+type GitRepository struct {
+	Visibility  string `yaml:"visibility,omitempty"` // private (default), internal, public
+	Description string `yaml:"description,omitempty"`
 }
 
 type GitProvider struct {
@@ -160,6 +887,9 @@ type ClusterConfig struct {
 	Platform   string      `yaml:"platform"`   // kubernetes, openshift, aks, eks, gke
 	Kubeconfig string      `yaml:"kubeconfig"` // Path to kubeconfig file
 	Context    string      `yaml:"context"`    // Kubeconfig context to use
+	// Credential is a stored platform credential (gitopsi auth) reaching the
+	// cluster in place of the kubeconfig
+	Credential string `yaml:"credential,omitempty"`
 }
 
 // ClusterAuth holds cluster authentication configuration.
@@ -171,6 +901,57 @@ type ClusterAuth struct {
 	SkipTLS  bool   `yaml:"skip_tls"`  // Skip TLS verification (not recommended)
 }
 
+// ClusterProvision describes a cluster that gitopsi init --provision creates
+// before bootstrapping it, with Cluster API or a managed-cloud CLI.
+type ClusterProvision struct {
+	Name string `yaml:"name"`
+	// Environment is the environment the cluster runs (default: name)
+	Environment string `yaml:"environment,omitempty"`
+	Provider    string `yaml:"provider"`          // capi, eks, aks, gke
+	Region      string `yaml:"region,omitempty"`  // Region or location (eks, aks, gke)
+	Version     string `yaml:"version,omitempty"` // Kubernetes version
+	Nodes       int    `yaml:"nodes,omitempty"`   // Worker nodes (default: 3)
+	NodeSize    string `yaml:"node_size,omitempty"`
+	// ResourceGroup is the Azure resource group of an AKS cluster
+	ResourceGroup string `yaml:"resource_group,omitempty"`
+	// Project is the Google Cloud project of a GKE cluster
+	Project string `yaml:"project,omitempty"`
+	// Kubeconfig is the file the cluster credentials are written to
+	// (default: ~/.gitopsi/clusters/<name>/kubeconfig)
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+	// ExtraArgs are appended to the create command
+	ExtraArgs []string    `yaml:"extra_args,omitempty"`
+	CAPI      *CAPIConfig `yaml:"capi,omitempty"`
+}
+
+// CAPIConfig configures a cluster created through a Cluster API management cluster.
+type CAPIConfig struct {
+	Infrastructure string `yaml:"infrastructure"` // clusterctl infrastructure provider: aws, azure, gcp, vsphere, docker...
+	Flavor         string `yaml:"flavor,omitempty"`
+	Namespace      string `yaml:"namespace,omitempty"` // Namespace of the Cluster resources (default: default)
+	// ManagementContext is the kubeconfig context of the management cluster (default: current)
+	ManagementContext string `yaml:"management_context,omitempty"`
+	ControlPlaneNodes int    `yaml:"control_plane_nodes,omitempty"` // Default: 1
+}
+
+// EnvironmentName returns the environment a provisioned cluster runs.
+func (p ClusterProvision) EnvironmentName() string {
+	if p.Environment != "" {
+		return p.Environment
+	}
+	return p.Name
+}
+
+// ProvisionedCluster returns the cluster provisioned for an environment, if any.
+func (c *Config) ProvisionedCluster(env string) *ClusterProvision {
+	for i := range c.Clusters {
+		if c.Clusters[i].EnvironmentName() == env {
+			return &c.Clusters[i]
+		}
+	}
+	return nil
+}
+
 // BootstrapConfig holds GitOps tool bootstrap configuration.
 type BootstrapConfig struct {
 	Enabled         bool   `yaml:"enabled"`
@@ -183,6 +964,17 @@ type BootstrapConfig struct {
 	CreateAppOfApps bool   `yaml:"create_app_of_apps"` // Create root application
 	SyncInitial     bool   `yaml:"sync_initial"`       // Trigger initial sync
 	Version         string `yaml:"version,omitempty"`  // Tool version
+	HA              bool   `yaml:"ha,omitempty"`       // Install ArgoCD in high availability mode
+	// Credential names a git credential from `gitopsi auth` added to the
+	// repository secret (default: a stored credential covering git.url)
+	Credential string `yaml:"credential,omitempty"`
+
+	// Offline installs from an offline bundle and never reaches the network.
+	Offline   bool   `yaml:"offline,omitempty"`
+	BundleDir string `yaml:"bundle_dir,omitempty"` // Offline bundle directory (default: the bundle built into the binary)
+
+	// MultiCluster bootstraps every cluster listed under environments.
+	MultiCluster *BootstrapMultiClusterConfig `yaml:"multi_cluster,omitempty"`
 
 	// Mode-specific configurations
 	Helm      *BootstrapHelmConfig      `yaml:"helm,omitempty"`
@@ -191,13 +983,21 @@ type BootstrapConfig struct {
 	Kustomize *BootstrapKustomizeConfig `yaml:"kustomize,omitempty"`
 }
 
+// BootstrapMultiClusterConfig holds multi-cluster bootstrap configuration.
+type BootstrapMultiClusterConfig struct {
+	Strategy    string `yaml:"strategy,omitempty"`    // standalone, hub
+	Hub         string `yaml:"hub,omitempty"`         // Cluster or environment name acting as ArgoCD or Flux hub
+	Concurrency int    `yaml:"concurrency,omitempty"` // Clusters bootstrapped in parallel
+}
+
 // BootstrapHelmConfig holds Helm-specific bootstrap configuration.
 type BootstrapHelmConfig struct {
-	Repo      string            `yaml:"repo,omitempty"`
-	Chart     string            `yaml:"chart,omitempty"`
-	Version   string            `yaml:"version,omitempty"`
-	Values    map[string]any    `yaml:"values,omitempty"`
-	SetValues map[string]string `yaml:"set_values,omitempty"`
+	Repo        string            `yaml:"repo,omitempty"`
+	Chart       string            `yaml:"chart,omitempty"`
+	Version     string            `yaml:"version,omitempty"`
+	Values      map[string]any    `yaml:"values,omitempty"`
+	ValuesFiles []string          `yaml:"values_files,omitempty"`
+	SetValues   map[string]string `yaml:"set_values,omitempty"`
 }
 
 // BootstrapOLMConfig holds OLM-specific bootstrap configuration.
@@ -222,18 +1022,188 @@ type BootstrapKustomizeConfig struct {
 }
 
 type Environment struct {
-	Name      string               `yaml:"name"`
-	Cluster   string               `yaml:"cluster,omitempty"`
-	Namespace string               `yaml:"namespace,omitempty"`
-	Clusters  []EnvironmentCluster `yaml:"clusters,omitempty"`
+	Name     string `yaml:"name"`
+	Cluster  string `yaml:"cluster,omitempty"`
+	Context  string `yaml:"context,omitempty"`   // Kubeconfig context for multi-cluster bootstrap
+	TokenEnv string `yaml:"token_env,omitempty"` // Env var holding a bearer token for the cluster
+	// Kubeconfig overrides cluster.kubeconfig for multi-cluster bootstrap
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+	// Credential is a stored platform credential reaching the cluster, in
+	// place of context and kubeconfig
+	Credential string               `yaml:"credential,omitempty"`
+	Namespace  string               `yaml:"namespace,omitempty"`
+	Clusters   []EnvironmentCluster `yaml:"clusters,omitempty"`
+	Protected  bool                 `yaml:"protected,omitempty"` // Promotions must pass promotion.gates
+	// NetworkPolicyProfile overrides infrastructure.network_policy_profile
+	NetworkPolicyProfile string `yaml:"network_policy_profile,omitempty"`
+	// Kustomize customizes the applications overlay of the environment
+	Kustomize *EnvKustomize `yaml:"kustomize,omitempty"`
+}
+
+// KustomizeConfig holds the kustomize components environments include.
+type KustomizeConfig struct {
+	// Components are generated under applications/components/<name>
+	Components []KustomizeComponent `yaml:"components,omitempty"`
+}
+
+// KustomizeComponent is a reusable set of overlay changes, included by the
+// environments listing it in kustomize.components.
+type KustomizeComponent struct {
+	Name             string `yaml:"name"`
+	KustomizeOverlay `yaml:",inline"`
+}
+
+// EnvKustomize customizes the kustomization of an environment overlay.
+type EnvKustomize struct {
+	// Namespace sets the namespace of every resource of the overlay
+	Namespace string `yaml:"namespace,omitempty"`
+	// Components are the names of kustomize.components included by the overlay
+	Components       []string `yaml:"components,omitempty"`
+	KustomizeOverlay `yaml:",inline"`
+}
+
+// KustomizeOverlay holds the kustomize transformers and generators of an
+// overlay or component.
+type KustomizeOverlay struct {
+	Patches            []KustomizePatch     `yaml:"patches,omitempty"`
+	Images             []KustomizeImage     `yaml:"images,omitempty"`
+	Replicas           []KustomizeReplicas  `yaml:"replicas,omitempty"`
+	ConfigMapGenerator []KustomizeConfigMap `yaml:"config_map_generator,omitempty"`
+	// SecretGenerator writes a <name>.env stub with the keys of each Secret,
+	// whose values are filled in outside of gitopsi
+	SecretGenerator []KustomizeSecret `yaml:"secret_generator,omitempty"`
+}
+
+// KustomizePatch is an inline strategic merge patch, or a JSON 6902 patch
+// when it is a list of operations, which then requires a target.
+type KustomizePatch struct {
+	Patch  string       `yaml:"patch"`
+	Target *PatchTarget `yaml:"target,omitempty"`
+}
+
+// PatchTarget selects the resources a patch applies to.
+type PatchTarget struct {
+	Group              string `yaml:"group,omitempty"`
+	Version            string `yaml:"version,omitempty"`
+	Kind               string `yaml:"kind,omitempty"`
+	Name               string `yaml:"name,omitempty"`
+	Namespace          string `yaml:"namespace,omitempty"`
+	LabelSelector      string `yaml:"label_selector,omitempty"`
+	AnnotationSelector string `yaml:"annotation_selector,omitempty"`
+}
+
+// KustomizeImage replaces the name, tag or digest of an image.
+type KustomizeImage struct {
+	Name    string `yaml:"name"`
+	NewName string `yaml:"new_name,omitempty"`
+	NewTag  string `yaml:"new_tag,omitempty"`
+	Digest  string `yaml:"digest,omitempty"`
+}
+
+// KustomizeReplicas sets the replica count of a workload.
+type KustomizeReplicas struct {
+	Name  string `yaml:"name"`
+	Count int    `yaml:"count"`
+}
+
+// KustomizeConfigMap generates a ConfigMap, or with behavior merge or
+// replace changes one generated by the base.
+type KustomizeConfigMap struct {
+	Name     string            `yaml:"name"`
+	Behavior string            `yaml:"behavior,omitempty"`
+	Literals map[string]string `yaml:"literals,omitempty"`
+}
+
+// KustomizeSecret generates a Secret from a <name>.env file of the overlay.
+type KustomizeSecret struct {
+	Name     string   `yaml:"name"`
+	Behavior string   `yaml:"behavior,omitempty"`
+	Type     string   `yaml:"type,omitempty"`
+	Keys     []string `yaml:"keys"`
+}
+
+// GetKustomizeComponent returns the kustomize component named name, or nil.
+func (c *Config) GetKustomizeComponent(name string) *KustomizeComponent {
+	for i := range c.Kustomize.Components {
+		if c.Kustomize.Components[i].Name == name {
+			return &c.Kustomize.Components[i]
+		}
+	}
+	return nil
+}
+
+// Tenant is a team sharing the platform. Each tenant gets an ArgoCD AppProject
+// restricted to its repositories and namespaces, or with Flux a GitRepository
+// and Kustomization reconciled as the tenant service account, namespaces with
+// quotas and limits in every environment, and RoleBindings for its IdP groups.
+type Tenant struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// SourceRepos the tenant may deploy from (default: git.url)
+	SourceRepos []string `yaml:"source_repos,omitempty"`
+	// Namespaces are created as <tenant>-<namespace>-<env> (default: <tenant>-<env>)
+	Namespaces []string     `yaml:"namespaces,omitempty"`
+	Quota      *TenantQuota `yaml:"quota,omitempty"`
+	// DefaultLimits are the LimitRange defaults of containers without resources
+	DefaultLimits *Resources   `yaml:"default_limits,omitempty"`
+	Groups        TenantGroups `yaml:"groups,omitempty"`
+	// CostCenter labels the tenant resources when cost is enabled (default:
+	// the tenant name)
+	CostCenter string `yaml:"cost_center,omitempty"`
+	// ApplicationSet generates an ApplicationSet deploying <path>/<env> to the
+	// first tenant namespace of each environment
+	ApplicationSet bool   `yaml:"applicationset,omitempty"`
+	Path           string `yaml:"path,omitempty"` // Default: tenants/<name>
+	// RepoURL is the repository Flux syncs the tenant path from (default: git.url)
+	RepoURL string `yaml:"repo_url,omitempty"`
+}
+
+// TenantQuota is the ResourceQuota of each tenant namespace.
+type TenantQuota struct {
+	Requests ResourceList `yaml:"requests,omitempty"`
+	Limits   ResourceList `yaml:"limits,omitempty"`
+	Pods     string       `yaml:"pods,omitempty"`
+}
+
+// TenantGroups are the IdP groups of a tenant, bound to the admin, edit and
+// view ClusterRoles in its namespaces and to the matching AppProject roles.
+type TenantGroups struct {
+	Admins     []string `yaml:"admins,omitempty"`
+	Developers []string `yaml:"developers,omitempty"`
+	Viewers    []string `yaml:"viewers,omitempty"`
+}
+
+// EnvNamespaces returns the namespaces of a tenant in an environment.
+func (t Tenant) EnvNamespaces(envName string) []string {
+	if len(t.Namespaces) == 0 {
+		return []string{t.Name + "-" + envName}
+	}
+	namespaces := make([]string, 0, len(t.Namespaces))
+	for _, ns := range t.Namespaces {
+		namespaces = append(namespaces, t.Name+"-"+ns+"-"+envName)
+	}
+	return namespaces
+}
+
+// RepoPath returns the repository path of the tenant's manifests.
+func (t Tenant) RepoPath() string {
+	if t.Path != "" {
+		return t.Path
+	}
+	return "tenants/" + t.Name
 }
 
 type EnvironmentCluster struct {
 	Name      string `yaml:"name"`
 	URL       string `yaml:"url"`
+	Context   string `yaml:"context,omitempty"`
+	TokenEnv  string `yaml:"token_env,omitempty"`
 	Namespace string `yaml:"namespace,omitempty"`
 	Region    string `yaml:"region,omitempty"`
 	Primary   bool   `yaml:"primary,omitempty"`
+	// Credential is a stored platform credential reaching the cluster, in
+	// place of context
+	Credential string `yaml:"credential,omitempty"`
 }
 
 type EnvironmentTopology string
@@ -249,13 +1219,221 @@ type Infrastructure struct {
 	RBAC            bool `yaml:"rbac"`
 	NetworkPolicies bool `yaml:"network_policies"`
 	ResourceQuotas  bool `yaml:"resource_quotas"`
+	// NetworkPolicyProfile selects the generated NetworkPolicies: basic (default),
+	// default-deny, namespace-isolated or app-allowlist
+	NetworkPolicyProfile string `yaml:"network_policy_profile,omitempty"`
 }
 
+// Network policy profiles.
+const (
+	NetworkPolicyBasic             = "basic"
+	NetworkPolicyDefaultDeny       = "default-deny"
+	NetworkPolicyNamespaceIsolated = "namespace-isolated"
+	NetworkPolicyAppAllowlist      = "app-allowlist"
+)
+
 type Application struct {
-	Name     string `yaml:"name"`
-	Image    string `yaml:"image"`
-	Port     int    `yaml:"port"`
-	Replicas int    `yaml:"replicas"`
+	Name      string            `yaml:"name"`
+	Image     string            `yaml:"image"`
+	Port      int               `yaml:"port"`
+	Replicas  int               `yaml:"replicas"`
+	Env       []EnvVar          `yaml:"env,omitempty"`
+	ConfigMap map[string]string `yaml:"config_map,omitempty"` // Data of the <name>-config ConfigMap, exposed as env vars
+	Secrets   []SecretRef       `yaml:"secrets,omitempty"`    // Existing Secrets exposed as env vars
+	Resources *Resources        `yaml:"resources,omitempty"`  // Defaults to 100m/64Mi requests and 200m/128Mi limits
+	Probes    *Probes           `yaml:"probes,omitempty"`
+	// AllowFrom lists the applications allowed to reach this one under the
+	// app-allowlist network policy profile (default: every declared application)
+	AllowFrom []string `yaml:"allow_from,omitempty"`
+	// DependsOn lists the applications that must be synced and healthy
+	// before this one; ArgoCD sync waves are computed from it
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	// Ingress exposes the application outside the cluster, with a Route on
+	// OpenShift and an Ingress elsewhere
+	Ingress *AppIngress `yaml:"ingress,omitempty"`
+	// SCC is the OpenShift SecurityContextConstraints the application runs
+	// under (e.g. anyuid, nonroot-v2), granted to its service account
+	SCC string `yaml:"scc,omitempty"`
+	// CloudIdentity is the cloud identity of the application service
+	// account: an IAM role ARN on eks, a Google service account email on gke
+	// and a managed identity client ID on aks
+	CloudIdentity string `yaml:"cloud_identity,omitempty"`
+	// Overrides customize the application per environment, keyed by environment name
+	Overrides map[string]AppOverride `yaml:"overrides,omitempty"`
+}
+
+// AppIngress exposes an application on a host name.
+type AppIngress struct {
+	Host string `yaml:"host"`
+	Path string `yaml:"path,omitempty"` // Default: /
+	// TLS terminates TLS at the router or ingress controller; Ingresses read
+	// the certificate from the <name>-tls Secret
+	TLS bool `yaml:"tls,omitempty"`
+	// ClassName is the IngressClass of the Ingress; unused by Routes
+	ClassName string `yaml:"class_name,omitempty"`
+}
+
+// EnvVar is a literal environment variable of an application container.
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// SecretRef exposes an existing Secret to an application container.
+type SecretRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key,omitempty"` // Expose only this key; all keys are exposed when empty
+	Env  string `yaml:"env,omitempty"` // Env var holding Key (default: Key)
+}
+
+// Resources are the compute resources of an application container.
+type Resources struct {
+	Requests ResourceList `yaml:"requests,omitempty"`
+	Limits   ResourceList `yaml:"limits,omitempty"`
+}
+
+type ResourceList struct {
+	CPU    string `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// Probes are the health checks of an application container.
+type Probes struct {
+	Liveness  *Probe `yaml:"liveness,omitempty"`
+	Readiness *Probe `yaml:"readiness,omitempty"`
+	Startup   *Probe `yaml:"startup,omitempty"`
+}
+
+// Probe is an HTTP GET check when Path is set, and a TCP check otherwise.
+type Probe struct {
+	Path                string `yaml:"path,omitempty"`
+	Port                int    `yaml:"port,omitempty"` // Default: the application port
+	InitialDelaySeconds int    `yaml:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int    `yaml:"period_seconds,omitempty"`
+	TimeoutSeconds      int    `yaml:"timeout_seconds,omitempty"`
+	FailureThreshold    int    `yaml:"failure_threshold,omitempty"`
+}
+
+// AppOverride customizes an application in one environment. Env and
+// ConfigMap entries and the Resources fields that are set are merged into the
+// base values.
+type AppOverride struct {
+	Image     string            `yaml:"image,omitempty"`
+	Replicas  int               `yaml:"replicas,omitempty"`
+	Env       []EnvVar          `yaml:"env,omitempty"`
+	ConfigMap map[string]string `yaml:"config_map,omitempty"`
+	Resources *Resources        `yaml:"resources,omitempty"`
+	Host      string            `yaml:"host,omitempty"` // Host name of the application ingress
+	// CloudIdentity overrides the cloud identity of the application
+	CloudIdentity string `yaml:"cloud_identity,omitempty"`
+}
+
+// DefaultResources are the container resources of applications that do not
+// set any.
+var DefaultResources = Resources{
+	Requests: ResourceList{CPU: "100m", Memory: "64Mi"},
+	Limits:   ResourceList{CPU: "200m", Memory: "128Mi"},
+}
+
+// ContainerResources returns the resources of the application container.
+func (a Application) ContainerResources() Resources {
+	if a.Resources == nil {
+		return DefaultResources
+	}
+	return *a.Resources
+}
+
+// ForEnvironment returns the application with the overrides of envName applied.
+func (a Application) ForEnvironment(envName string) Application {
+	o, ok := a.Overrides[envName]
+	if !ok {
+		return a
+	}
+
+	merged := a
+	merged.Overrides = nil
+	if o.Image != "" {
+		merged.Image = o.Image
+	}
+	if o.Replicas != 0 {
+		merged.Replicas = o.Replicas
+	}
+	if o.Resources != nil {
+		resources := a.ContainerResources()
+		resources.Requests = resources.Requests.merge(o.Resources.Requests)
+		resources.Limits = resources.Limits.merge(o.Resources.Limits)
+		merged.Resources = &resources
+	}
+	if len(o.Env) > 0 {
+		merged.Env = mergeEnv(a.Env, o.Env)
+	}
+	if o.Host != "" && a.Ingress != nil {
+		ingress := *a.Ingress
+		ingress.Host = o.Host
+		merged.Ingress = &ingress
+	}
+	if o.CloudIdentity != "" {
+		merged.CloudIdentity = o.CloudIdentity
+	}
+	if len(o.ConfigMap) > 0 {
+		merged.ConfigMap = make(map[string]string, len(a.ConfigMap)+len(o.ConfigMap))
+		for k, v := range a.ConfigMap {
+			merged.ConfigMap[k] = v
+		}
+		for k, v := range o.ConfigMap {
+			merged.ConfigMap[k] = v
+		}
+	}
+	return merged
+}
+
+// AppSyncWaves returns the sync wave of every application, computed from
+// depends_on. It returns nil when no application declares dependencies.
+func (c *Config) AppSyncWaves() (map[string]int, error) {
+	graph := make(map[string][]string, len(c.Apps))
+	ordered := false
+	for _, app := range c.Apps {
+		graph[app.Name] = app.DependsOn
+		ordered = ordered || len(app.DependsOn) > 0
+	}
+	if !ordered {
+		return nil, nil
+	}
+	waves, err := syncwave.Compute(graph)
+	if err != nil {
+		return nil, fmt.Errorf("invalid application depends_on: %w", err)
+	}
+	return waves, nil
+}
+
+func (r ResourceList) merge(override ResourceList) ResourceList {
+	if override.CPU != "" {
+		r.CPU = override.CPU
+	}
+	if override.Memory != "" {
+		r.Memory = override.Memory
+	}
+	return r
+}
+
+// mergeEnv returns base with the variables of override replacing or
+// following those of the same name.
+func mergeEnv(base, override []EnvVar) []EnvVar {
+	merged := append([]EnvVar(nil), base...)
+	for _, o := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == o.Name {
+				merged[i].Value = o.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
 }
 
 type Documentation struct {
@@ -266,6 +1444,7 @@ type Documentation struct {
 
 func NewDefaultConfig() *Config {
 	return &Config{
+		APIVersion: APIVersion,
 		Platform:   "kubernetes",
 		Scope:      "both",
 		GitOpsTool: "argocd",
@@ -332,10 +1511,31 @@ func (c *Config) GetEnvironmentNamespace(envName string) string {
 			if env.Namespace != "" {
 				return env.Namespace
 			}
-			return c.Project.Name + "-" + env.Name
+			break
+		}
+	}
+	return c.Conventions.Names.NamespaceName(c.Project.Name, envName)
+}
+
+// GetEnvironment returns the environment named envName, or nil.
+func (c *Config) GetEnvironment(envName string) *Environment {
+	for i := range c.Environments {
+		if c.Environments[i].Name == envName {
+			return &c.Environments[i]
 		}
 	}
-	return c.Project.Name + "-" + envName
+	return nil
+}
+
+// GetNetworkPolicyProfile returns the network policy profile of an environment.
+func (c *Config) GetNetworkPolicyProfile(envName string) string {
+	if env := c.GetEnvironment(envName); env != nil && env.NetworkPolicyProfile != "" {
+		return env.NetworkPolicyProfile
+	}
+	if c.Infra.NetworkPolicyProfile != "" {
+		return c.Infra.NetworkPolicyProfile
+	}
+	return NetworkPolicyBasic
 }
 
 func (c *Config) GetEnvironmentClusters(envName string) []EnvironmentCluster {
@@ -353,7 +1553,13 @@ func (c *Config) IsMultiCluster() bool {
 
 // ApplyPreset applies a preset configuration
 func (c *Config) ApplyPreset() {
-	switch c.Preset {
+	c.applyPreset(c.Preset)
+}
+
+// applyPreset applies a built-in preset; other presets are left to
+// ApplyPresetFile.
+func (c *Config) applyPreset(preset Preset) {
+	switch preset {
 	case PresetMinimal:
 		c.applyMinimalPreset()
 	case PresetStandard:
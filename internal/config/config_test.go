@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -125,8 +127,8 @@ func TestConfigValidate(t *testing.T) {
 
 func TestValidPlatforms(t *testing.T) {
 	platforms := ValidPlatforms()
-	if len(platforms) != 4 {
-		t.Errorf("expected 4 platforms, got %d", len(platforms))
+	if len(platforms) != 5 {
+		t.Errorf("expected 5 platforms, got %d", len(platforms))
 	}
 
 	expected := map[string]bool{
@@ -134,6 +136,7 @@ func TestValidPlatforms(t *testing.T) {
 		"openshift":  true,
 		"aks":        true,
 		"eks":        true,
+		"gke":        true,
 	}
 
 	for _, p := range platforms {
@@ -307,3 +310,798 @@ func TestDefaultConfigEnvironments(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigValidateSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets SecretsConfig
+		wantErr bool
+	}{
+		{"default", SecretsConfig{}, false},
+		{"plain", SecretsConfig{Format: "plain"}, false},
+		{"sops with age", SecretsConfig{Format: "sops", Sops: SopsConfig{Age: []string{"age1abc"}}}, false},
+		{"sops without keys", SecretsConfig{Format: "sops"}, true},
+		{"unknown format", SecretsConfig{Format: "vault"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Project.Name = "test"
+			cfg.Secrets = tt.secrets
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidateMultiCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		mc      *BootstrapMultiClusterConfig
+		wantErr bool
+	}{
+		{"unset", nil, false},
+		{"standalone", &BootstrapMultiClusterConfig{Strategy: "standalone"}, false},
+		{"hub", &BootstrapMultiClusterConfig{Strategy: "hub", Hub: "prod"}, false},
+		{"hub without hub cluster", &BootstrapMultiClusterConfig{Strategy: "hub"}, true},
+		{"unknown strategy", &BootstrapMultiClusterConfig{Strategy: "mesh"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Project.Name = "test"
+			cfg.Bootstrap.MultiCluster = tt.mc
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidateClusters(t *testing.T) {
+	tests := []struct {
+		name     string
+		clusters []ClusterProvision
+		wantErr  bool
+	}{
+		{"unset", nil, false},
+		{"eks", []ClusterProvision{{Name: "dev", Provider: "eks", Region: "eu-west-1"}}, false},
+		{"aks for another environment", []ClusterProvision{{Name: "aks-prod", Environment: "prod", Provider: "aks", ResourceGroup: "platform"}}, false},
+		{"capi", []ClusterProvision{{Name: "dev", Provider: "capi", Version: "v1.30.2", CAPI: &CAPIConfig{Infrastructure: "docker"}}}, false},
+		{"unknown provider", []ClusterProvision{{Name: "dev", Provider: "openstack"}}, true},
+		{"unknown environment", []ClusterProvision{{Name: "qa", Provider: "eks", Region: "eu-west-1"}}, true},
+		{"eks without region", []ClusterProvision{{Name: "dev", Provider: "eks"}}, true},
+		{"aks without resource group", []ClusterProvision{{Name: "dev", Provider: "aks"}}, true},
+		{"capi without infrastructure", []ClusterProvision{{Name: "dev", Provider: "capi", Version: "v1.30.2"}}, true},
+		{"capi without version", []ClusterProvision{{Name: "dev", Provider: "capi", CAPI: &CAPIConfig{Infrastructure: "docker"}}}, true},
+		{"two clusters for one environment", []ClusterProvision{
+			{Name: "dev", Provider: "eks", Region: "eu-west-1"},
+			{Name: "dev-2", Environment: "dev", Provider: "eks", Region: "eu-west-1"},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Project.Name = "test"
+			cfg.Clusters = tt.clusters
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidateGitRepositoryVisibility(t *testing.T) {
+	for _, tt := range []struct {
+		visibility string
+		wantErr    bool
+	}{{"", false}, {"private", false}, {"internal", false}, {"public", false}, {"secret", true}} {
+		cfg := NewDefaultConfig()
+		cfg.Project.Name = "test"
+		cfg.Git.Repository.Visibility = tt.visibility
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with visibility %q error = %v, wantErr %v", tt.visibility, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigValidatePromotionSoakTime(t *testing.T) {
+	for _, tt := range []struct {
+		soak    string
+		wantErr bool
+	}{{"", false}, {"30m", false}, {"24h", false}, {"1d", true}, {"-1h", true}} {
+		cfg := NewDefaultConfig()
+		cfg.Project.Name = "test"
+		cfg.Promotion.Gates.SoakTime = tt.soak
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with soak_time %q error = %v, wantErr %v", tt.soak, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigGetEnvironment(t *testing.T) {
+	cfg := &Config{Environments: []Environment{{Name: "dev"}, {Name: "prod", Protected: true}}}
+	if env := cfg.GetEnvironment("prod"); env == nil || !env.Protected {
+		t.Errorf("GetEnvironment(prod) = %v, want protected environment", env)
+	}
+	if env := cfg.GetEnvironment("qa"); env != nil {
+		t.Errorf("GetEnvironment(qa) = %v, want nil", env)
+	}
+}
+
+func TestConfigValidateApplicationSetGenerator(t *testing.T) {
+	for _, gen := range []string{"", "cluster", "git", "matrix"} {
+		cfg := NewDefaultConfig()
+		cfg.Project.Name = "test"
+		cfg.ArgoCD.ApplicationSet.Generator = gen
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() with generator %q error = %v", gen, err)
+		}
+	}
+
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.ArgoCD.ApplicationSet.Generator = "scm"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for unknown generator")
+	}
+}
+
+func TestApplicationForEnvironment(t *testing.T) {
+	app := Application{
+		Name:      "api",
+		Image:     "api:1.0",
+		Replicas:  1,
+		Env:       []EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "MODE", Value: "a"}},
+		ConfigMap: map[string]string{"FEATURE": "on", "COLOR": "blue"},
+		Overrides: map[string]AppOverride{
+			"prod": {
+				Image:     "api:1.1",
+				Replicas:  3,
+				Env:       []EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "REGION", Value: "eu"}},
+				ConfigMap: map[string]string{"FEATURE": "off"},
+				Resources: &Resources{Limits: ResourceList{Memory: "1Gi"}},
+			},
+		},
+	}
+
+	if dev := app.ForEnvironment("dev"); dev.Image != "api:1.0" || dev.ContainerResources() != DefaultResources {
+		t.Errorf("ForEnvironment(dev) = %+v, want the base application", dev)
+	}
+
+	prod := app.ForEnvironment("prod")
+	if prod.Image != "api:1.1" || prod.Replicas != 3 {
+		t.Errorf("ForEnvironment(prod) image/replicas = %s/%d", prod.Image, prod.Replicas)
+	}
+	wantEnv := []EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "MODE", Value: "a"}, {Name: "REGION", Value: "eu"}}
+	if fmt.Sprint(prod.Env) != fmt.Sprint(wantEnv) {
+		t.Errorf("ForEnvironment(prod) env = %v, want %v", prod.Env, wantEnv)
+	}
+	if prod.ConfigMap["FEATURE"] != "off" || prod.ConfigMap["COLOR"] != "blue" {
+		t.Errorf("ForEnvironment(prod) config map = %v", prod.ConfigMap)
+	}
+	if app.ConfigMap["FEATURE"] != "on" || app.Env[0].Value != "debug" {
+		t.Error("ForEnvironment() must not modify the base application")
+	}
+	want := Resources{Requests: DefaultResources.Requests, Limits: ResourceList{CPU: "200m", Memory: "1Gi"}}
+	if got := prod.ContainerResources(); got != want {
+		t.Errorf("ForEnvironment(prod) resources = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigValidateApplications(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		app     Application
+		wantErr bool
+	}{
+		{"valid", Application{Name: "api", Env: []EnvVar{{Name: "A"}}, Secrets: []SecretRef{{Name: "s", Key: "k", Env: "K"}}, Overrides: map[string]AppOverride{"dev": {}}}, false},
+		{"missing name", Application{}, true},
+		{"env without name", Application{Name: "api", Env: []EnvVar{{Value: "x"}}}, true},
+		{"secret without name", Application{Name: "api", Secrets: []SecretRef{{Key: "k"}}}, true},
+		{"secret env without key", Application{Name: "api", Secrets: []SecretRef{{Name: "s", Env: "K"}}}, true},
+		{"unknown environment", Application{Name: "api", Overrides: map[string]AppOverride{"qa": {}}}, true},
+	} {
+		cfg := NewDefaultConfig()
+		cfg.Project.Name = "test"
+		cfg.Apps = []Application{tt.app}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigNetworkPolicyProfile(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Environments = []Environment{{Name: "dev"}, {Name: "prod", NetworkPolicyProfile: NetworkPolicyDefaultDeny}}
+
+	if got := cfg.GetNetworkPolicyProfile("dev"); got != NetworkPolicyBasic {
+		t.Errorf("GetNetworkPolicyProfile(dev) = %s, want %s", got, NetworkPolicyBasic)
+	}
+	cfg.Infra.NetworkPolicyProfile = NetworkPolicyNamespaceIsolated
+	if got := cfg.GetNetworkPolicyProfile("dev"); got != NetworkPolicyNamespaceIsolated {
+		t.Errorf("GetNetworkPolicyProfile(dev) = %s, want %s", got, NetworkPolicyNamespaceIsolated)
+	}
+	if got := cfg.GetNetworkPolicyProfile("prod"); got != NetworkPolicyDefaultDeny {
+		t.Errorf("GetNetworkPolicyProfile(prod) = %s, want %s", got, NetworkPolicyDefaultDeny)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Infra.NetworkPolicyProfile = "open"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for unknown profile")
+	}
+	cfg.Infra.NetworkPolicyProfile = ""
+	cfg.Environments[1].NetworkPolicyProfile = "open"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for unknown environment profile")
+	}
+
+	cfg.Environments[1].NetworkPolicyProfile = ""
+	cfg.Apps = []Application{{Name: "api", AllowFrom: []string{"web"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for unknown allow_from application")
+	}
+}
+
+func TestAppSyncWaves(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Apps = []Application{{Name: "db"}, {Name: "api"}, {Name: "web"}}
+	if waves, err := cfg.AppSyncWaves(); err != nil || waves != nil {
+		t.Errorf("AppSyncWaves() without depends_on = %v, %v; want nil", waves, err)
+	}
+
+	cfg.Apps[1].DependsOn = []string{"db"}
+	cfg.Apps[2].DependsOn = []string{"api"}
+	waves, err := cfg.AppSyncWaves()
+	if err != nil {
+		t.Fatalf("AppSyncWaves() error = %v", err)
+	}
+	if fmt.Sprint(waves) != "map[api:1 db:0 web:2]" {
+		t.Errorf("AppSyncWaves() = %v", waves)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Apps[0].DependsOn = []string{"web"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("Validate() error = %v, want a dependency cycle", err)
+	}
+	cfg.Apps[0].DependsOn = []string{"cache"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "depends_on references unknown application cache") {
+		t.Errorf("Validate() error = %v, want an unknown application", err)
+	}
+}
+
+func TestConfigValidateOpenShift(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Apps = []Application{{Name: "web", Ingress: &AppIngress{Host: "web.example.com"}, SCC: "anyuid"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "scc requires platform openshift") {
+		t.Errorf("Validate() error = %v, want scc to require openshift", err)
+	}
+	cfg.OpenShift.ProjectRequests = true
+	cfg.Apps[0].SCC = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "project_requests requires platform openshift") {
+		t.Errorf("Validate() error = %v, want project_requests to require openshift", err)
+	}
+
+	cfg.Platform = "openshift"
+	cfg.Apps[0].SCC = "anyuid"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	cfg.Apps[0].Ingress.Host = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ingress host is required") {
+		t.Errorf("Validate() error = %v, want a missing host", err)
+	}
+	cfg.Apps[0].Ingress = nil
+	cfg.Apps[0].Overrides = map[string]AppOverride{cfg.Environments[0].Name: {Host: "web.example.com"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "overrides host without ingress") {
+		t.Errorf("Validate() error = %v, want a host without ingress", err)
+	}
+}
+
+func TestConfigValidateAddons(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Platform = "aks"
+	cfg.Addons.LoadBalancerController = &CloudAddon{Enabled: true}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "load_balancer_controller requires platform eks") {
+		t.Errorf("Validate() error = %v, want the controller to require eks", err)
+	}
+	cfg.Addons.LoadBalancerController.Enabled = false
+	cfg.Addons.StorageClasses = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	cfg.Platform = "kubernetes"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "storage_classes requires platform eks, aks or gke") {
+		t.Errorf("Validate() error = %v, want storage classes to require a cloud platform", err)
+	}
+
+	cfg.Platform = "eks"
+	cfg.Scope = "application"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "addons require scope") {
+		t.Errorf("Validate() error = %v, want addons to require the infrastructure scope", err)
+	}
+	cfg.Scope = "both"
+	cfg.Addons = AddonsConfig{}
+	cfg.Platform = "kubernetes"
+	cfg.Apps = []Application{{Name: "api", CloudIdentity: "arn:aws:iam::123456789012:role/api"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cloud_identity requires platform") {
+		t.Errorf("Validate() error = %v, want cloud_identity to require a cloud platform", err)
+	}
+}
+
+func TestConfigValidateTLS(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.TLS.Wildcards = []string{"*.example.com"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tls.email is required") {
+		t.Errorf("Validate() error = %v, want tls to require an email", err)
+	}
+	cfg.TLS.Email = "ops@example.com"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "wildcards require the dns01 solver") {
+		t.Errorf("Validate() error = %v, want wildcards to require dns01", err)
+	}
+	cfg.TLS.Solver = TLSSolverDNS01
+	cfg.TLS.DNS01.Provider = "godaddy"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid tls.dns01.provider") {
+		t.Errorf("Validate() error = %v, want the provider to be rejected", err)
+	}
+	cfg.TLS.DNS01.Provider = "clouddns"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tls.dns01.project is required") {
+		t.Errorf("Validate() error = %v, want clouddns to require a project", err)
+	}
+	cfg.TLS.DNS01.Provider = "azuredns"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "required for azuredns") {
+		t.Errorf("Validate() error = %v, want azuredns to require its zone", err)
+	}
+	cfg.TLS.DNS01.Provider = "cloudflare"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if got := cfg.TLS.Issuer(); got != "letsencrypt-prod" {
+		t.Errorf("Issuer() = %q, want letsencrypt-prod", got)
+	}
+	cfg.Scope = "application"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tls requires scope") {
+		t.Errorf("Validate() error = %v, want tls to require the infrastructure scope", err)
+	}
+}
+
+func TestConfigValidateDNS(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.DNS.Provider = "route53"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dns.zone is required") {
+		t.Errorf("Validate() error = %v, want dns to require a zone", err)
+	}
+	cfg.DNS.Zone = "example.com"
+	cfg.DNS.Mode = "manual"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid dns.mode") {
+		t.Errorf("Validate() error = %v, want the mode to be rejected", err)
+	}
+	cfg.DNS.Mode = DNSModeDirect
+	cfg.DNS.Provider = "azuredns"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dns.resource_group is required") {
+		t.Errorf("Validate() error = %v, want azuredns to require a resource group", err)
+	}
+	cfg.DNS.Provider = "cloudflare"
+	cfg.Apps = []Application{{
+		Name:      "api",
+		Ingress:   &AppIngress{Host: "api.example.com"},
+		Overrides: map[string]AppOverride{"prod": {Host: "api.example.org"}},
+	}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "prod host api.example.org is not in dns.zone example.com") {
+		t.Errorf("Validate() error = %v, want the override host to be outside the zone", err)
+	}
+	cfg.Apps[0].Overrides = nil
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if cfg.DNS.ExternalDNS() {
+		t.Error("ExternalDNS() = true in direct mode")
+	}
+	if !cfg.DNS.InZone("example.com.") || cfg.DNS.InZone("notexample.com") {
+		t.Error("InZone() should match the zone and its subdomains only")
+	}
+}
+
+func TestConfigValidateBackup(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Backup = BackupConfig{Enabled: true, Schedule: "@daily"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid backup.schedule") {
+		t.Errorf("Validate() error = %v, want the schedule to be rejected", err)
+	}
+	cfg.Backup.Schedule = "0 2 * * *"
+	cfg.Backup.TTL = "30d"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid backup.ttl") {
+		t.Errorf("Validate() error = %v, want the ttl to be rejected", err)
+	}
+	cfg.Backup.TTL = "168h"
+	cfg.Backup.ArgoCDExport = "../dr"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be a directory of the repository") {
+		t.Errorf("Validate() error = %v, want the export directory to be rejected", err)
+	}
+	cfg.Backup.ArgoCDExport = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if cfg.Backup.VeleroNamespace() != "velero" || cfg.Backup.VeleroPattern().Name != "velero" || cfg.Backup.ArgoCDExportDir() != "backup/argocd" {
+		t.Errorf("backup defaults = %s, %s, %s", cfg.Backup.VeleroNamespace(), cfg.Backup.VeleroPattern().Name, cfg.Backup.ArgoCDExportDir())
+	}
+	cfg.Scope = "application"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "backup requires scope") {
+		t.Errorf("Validate() error = %v, want application scope to be rejected", err)
+	}
+}
+
+func TestConfigValidateCost(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Cost = CostConfig{Enabled: true, Label: "Cost Center"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid cost.label") {
+		t.Errorf("Validate() error = %v, want the label to be rejected", err)
+	}
+	cfg.Cost.Label = "example.com/cost-center"
+	cfg.Tenants = []Tenant{{Name: "payments", CostCenter: "cc 1234"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tenant payments: invalid cost_center") {
+		t.Errorf("Validate() error = %v, want the tenant cost center to be rejected", err)
+	}
+	cfg.Tenants[0].CostCenter = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if cfg.ProjectCostCenter() != "test" || cfg.TenantCostCenter(cfg.Tenants[0]) != "payments" {
+		t.Errorf("cost centers = %s, %s, want the project and tenant names", cfg.ProjectCostCenter(), cfg.TenantCostCenter(cfg.Tenants[0]))
+	}
+	if (CostConfig{}).LabelKey() != "cost-center" || (CostConfig{}).OpenCostPattern().Name != "opencost" {
+		t.Error("cost defaults should be the cost-center label and the opencost pattern")
+	}
+}
+
+func TestTenantNamespaces(t *testing.T) {
+	if got := (Tenant{Name: "team"}).EnvNamespaces("dev"); fmt.Sprint(got) != "[team-dev]" {
+		t.Errorf("EnvNamespaces() = %v, want [team-dev]", got)
+	}
+	tenant := Tenant{Name: "team", Namespaces: []string{"api", "jobs"}}
+	if got := tenant.EnvNamespaces("prod"); fmt.Sprint(got) != "[team-api-prod team-jobs-prod]" {
+		t.Errorf("EnvNamespaces() = %v", got)
+	}
+	if got := tenant.RepoPath(); got != "tenants/team" {
+		t.Errorf("RepoPath() = %s, want tenants/team", got)
+	}
+}
+
+func TestConfigValidateTenants(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Tenants = []Tenant{{Name: "a"}, {Name: "b"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Tenants = append(cfg.Tenants, Tenant{Name: "a"})
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for duplicate tenant")
+	}
+	cfg.Tenants = []Tenant{{}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for tenant without name")
+	}
+}
+
+func TestConfigValidateSSO(t *testing.T) {
+	tests := []struct {
+		name    string
+		sso     SSOConfig
+		wantErr bool
+	}{
+		{"disabled", SSOConfig{}, false},
+		{"github", SSOConfig{Provider: "github", URL: "https://argocd.example.com", ClientID: "id"}, false},
+		{"oidc", SSOConfig{Provider: "oidc", URL: "https://argocd.example.com", ClientID: "id", Issuer: "https://idp.example.com"}, false},
+		{"unknown provider", SSOConfig{Provider: "ldap", URL: "https://argocd.example.com", ClientID: "id"}, true},
+		{"missing url", SSOConfig{Provider: "github", ClientID: "id"}, true},
+		{"missing client id", SSOConfig{Provider: "github", URL: "https://argocd.example.com"}, true},
+		{"keycloak without issuer", SSOConfig{Provider: "keycloak", URL: "https://argocd.example.com", ClientID: "id"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Project.Name = "test"
+			cfg.SSO = tt.sso
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSSOConfigDefaults(t *testing.T) {
+	sso := SSOConfig{Provider: "github"}
+	if sso.SecretName() != "argocd-sso" || sso.SecretKey() != "clientSecret" || sso.Groups() != "groups" {
+		t.Errorf("unexpected defaults: %s %s %s", sso.SecretName(), sso.SecretKey(), sso.Groups())
+	}
+	if !sso.UsesDex() {
+		t.Error("expected github to use Dex")
+	}
+	if (SSOConfig{Provider: "oidc"}).UsesDex() {
+		t.Error("expected oidc not to use Dex")
+	}
+}
+
+func TestConfigValidateCI(t *testing.T) {
+	tests := []struct {
+		name    string
+		ci      CIConfig
+		wantErr bool
+	}{
+		{"disabled", CIConfig{}, false},
+		{"github actions", CIConfig{Provider: "github-actions"}, false},
+		{"tekton with diff", CIConfig{Provider: "tekton", Diff: true, FailOn: "medium"}, false},
+		{"unknown provider", CIConfig{Provider: "jenkins"}, true},
+		{"unknown severity", CIConfig{Provider: "gitlab-ci", FailOn: "info"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Project.Name = "test"
+			cfg.CI = tt.ci
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidatePinDigests(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	cfg.Images.PinDigests = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Flux.ImageAutomation.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject pin_digests with flux image automation")
+	}
+}
+
+func TestCIConfigDefaults(t *testing.T) {
+	ci := CIConfig{Provider: "gitlab-ci"}
+	if !ci.Enabled() || ci.ImageRef() != DefaultCIImage || ci.Severity() != "high" {
+		t.Errorf("unexpected defaults: %v %s %s", ci.Enabled(), ci.ImageRef(), ci.Severity())
+	}
+}
+
+func TestValidatePreview(t *testing.T) {
+	tests := []struct {
+		name    string
+		preview PreviewConfig
+		tool    string
+		wantErr string
+	}{
+		{name: "disabled", preview: PreviewConfig{}},
+		{name: "github", preview: PreviewConfig{Provider: "github", Repository: "acme/api", Labels: []string{"preview"}, TTL: "72h"}},
+		{name: "gitlab", preview: PreviewConfig{Provider: "gitlab", Repository: "acme/shop/api"}},
+		{name: "provider", preview: PreviewConfig{Provider: "bitbucket", Repository: "acme/api"}, wantErr: "invalid preview.provider"},
+		{name: "flux", preview: PreviewConfig{Provider: "github", Repository: "acme/api"}, tool: "flux", wantErr: "require ArgoCD"},
+		{name: "repository", preview: PreviewConfig{Provider: "github"}, wantErr: "preview.repository is required"},
+		{name: "github repository", preview: PreviewConfig{Provider: "github", Repository: "api"}, wantErr: "use owner/repo"},
+		{name: "ttl", preview: PreviewConfig{Provider: "github", Repository: "acme/api", Labels: []string{"preview"}, TTL: "3d"}, wantErr: "invalid preview.ttl"},
+		{name: "ttl labels", preview: PreviewConfig{Provider: "github", Repository: "acme/api", TTL: "72h"}, wantErr: "preview.ttl requires preview.labels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := tt.tool
+			if tool == "" {
+				tool = "argocd"
+			}
+			err := tt.preview.validate(tool)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+	apps := []Application{{Name: "web"}, {Name: "api"}}
+	tests := []struct {
+		name    string
+		layout  LayoutConfig
+		tool    string
+		wantErr string
+	}{
+		{name: "monorepo", layout: LayoutConfig{}},
+		{name: "hub-and-spoke", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, RepoURL: "https://github.com/acme/{repo}.git", Teams: []LayoutTeam{{Name: "shop", Apps: []string{"web"}}}}},
+		{name: "team url", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, Teams: []LayoutTeam{{Name: "shop", Apps: []string{"web"}, RepoURL: "https://github.com/acme/shop.git"}}}},
+		{name: "per-app", layout: LayoutConfig{Strategy: LayoutPerApp, RepoURL: "git@github.com:acme/{repo}.git"}},
+		{name: "strategy", layout: LayoutConfig{Strategy: "polyrepo"}, wantErr: "invalid layout.strategy"},
+		{name: "teams without hub-and-spoke", layout: LayoutConfig{Teams: []LayoutTeam{{Name: "shop"}}}, wantErr: "layout.teams requires"},
+		{name: "flux", layout: LayoutConfig{Strategy: LayoutPerApp, RepoURL: "https://github.com/acme/{repo}.git"}, tool: "flux", wantErr: "requires gitops_tool argocd"},
+		{name: "repo_url placeholder", layout: LayoutConfig{Strategy: LayoutPerApp, RepoURL: "https://github.com/acme/apps.git"}, wantErr: "use {repo}"},
+		{name: "per-app repo_url", layout: LayoutConfig{Strategy: LayoutPerApp}, wantErr: "requires layout.repo_url"},
+		{name: "no teams", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, RepoURL: "https://github.com/acme/{repo}.git"}, wantErr: "requires layout.teams"},
+		{name: "team url missing", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, Teams: []LayoutTeam{{Name: "shop", Apps: []string{"web"}}}}, wantErr: "repo_url or layout.repo_url is required"},
+		{name: "unknown app", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, RepoURL: "https://github.com/acme/{repo}.git", Teams: []LayoutTeam{{Name: "shop", Apps: []string{"cart"}}}}, wantErr: "unknown application cart"},
+		{name: "shared app", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, RepoURL: "https://github.com/acme/{repo}.git", Teams: []LayoutTeam{{Name: "shop", Apps: []string{"web"}}, {Name: "ops", Apps: []string{"web"}}}}, wantErr: "belongs to layout teams shop and ops"},
+		{name: "duplicate team", layout: LayoutConfig{Strategy: LayoutHubAndSpoke, RepoURL: "https://github.com/acme/{repo}.git", Teams: []LayoutTeam{{Name: "shop", Apps: []string{"web"}}, {Name: "shop", Apps: []string{"api"}}}}, wantErr: "duplicate layout team"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Scope: "both", GitOpsTool: "argocd", Apps: apps, Layout: tt.layout}
+			if tt.tool != "" {
+				cfg.GitOpsTool = tt.tool
+			}
+			err := cfg.validateLayout()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateLayout() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateLayout() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAppRepositories(t *testing.T) {
+	cfg := &Config{
+		Project: Project{Name: "shop"},
+		Apps:    []Application{{Name: "web"}, {Name: "api"}},
+		Layout: LayoutConfig{
+			Strategy: LayoutHubAndSpoke,
+			RepoURL:  "https://github.com/acme/{repo}.git",
+			Teams: []LayoutTeam{
+				{Name: "frontend", Apps: []string{"web"}},
+				{Name: "backend", Apps: []string{"api"}, RepoURL: "https://github.com/acme/api.git"},
+			},
+		},
+	}
+	repos := cfg.AppRepositories()
+	if len(repos) != 2 || repos[0].Name != "shop-frontend" || repos[0].URL != "https://github.com/acme/shop-frontend.git" || repos[0].Team != "frontend" {
+		t.Fatalf("AppRepositories() = %+v", repos)
+	}
+	if repos[1].URL != "https://github.com/acme/api.git" {
+		t.Errorf("team repo_url not used: %+v", repos[1])
+	}
+
+	cfg.Layout = LayoutConfig{Strategy: LayoutPerApp, RepoURL: "https://github.com/acme/{repo}.git"}
+	repos = cfg.AppRepositories()
+	if len(repos) != 2 || repos[1].Name != "shop-api" || repos[1].Apps[0] != "api" || repos[1].URL != "https://github.com/acme/shop-api.git" {
+		t.Fatalf("per-app AppRepositories() = %+v", repos)
+	}
+
+	cfg.Layout = LayoutConfig{}
+	if repos := cfg.AppRepositories(); len(repos) != 0 {
+		t.Errorf("monorepo AppRepositories() = %+v", repos)
+	}
+}
+
+func TestValidateConventions(t *testing.T) {
+	tests := []struct {
+		name        string
+		conventions ConventionsConfig
+		wantErr     string
+	}{
+		{name: "empty"},
+		{name: "valid", conventions: ConventionsConfig{
+			Names:          NameConventions{Namespace: "{env}-{project}"},
+			Labels:         map[string]string{"team": "platform"},
+			Annotations:    map[string]string{"owner": "platform@example.com"},
+			RequiredLabels: []string{"team", "cost-center"},
+		}},
+		{name: "namespace without env", conventions: ConventionsConfig{Names: NameConventions{Namespace: "{project}"}}, wantErr: "must contain {env}"},
+		{name: "empty label key", conventions: ConventionsConfig{Labels: map[string]string{"": "x"}}, wantErr: "empty label key"},
+		{name: "empty annotation key", conventions: ConventionsConfig{Annotations: map[string]string{"": "x"}}, wantErr: "empty annotation key"},
+		{name: "empty required label", conventions: ConventionsConfig{RequiredLabels: []string{""}}, wantErr: "empty label"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conventions.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetEnvironmentNamespaceConvention(t *testing.T) {
+	cfg := &Config{
+		Project:      Project{Name: "shop"},
+		Environments: []Environment{{Name: "dev"}, {Name: "prod", Namespace: "shop"}},
+	}
+	if ns := cfg.GetEnvironmentNamespace("dev"); ns != "shop-dev" {
+		t.Errorf("default namespace = %s, want shop-dev", ns)
+	}
+
+	cfg.Conventions.Names.Namespace = "{env}-{project}"
+	if ns := cfg.GetEnvironmentNamespace("dev"); ns != "dev-shop" {
+		t.Errorf("convention namespace = %s, want dev-shop", ns)
+	}
+	if ns := cfg.GetEnvironmentNamespace("prod"); ns != "shop" {
+		t.Errorf("environment namespace = %s, want shop", ns)
+	}
+}
+
+func TestValidateKustomize(t *testing.T) {
+	jsonPatch := KustomizePatch{Patch: "- op: remove\n  path: /spec/replicas"}
+	tests := []struct {
+		name       string
+		components []KustomizeComponent
+		env        *EnvKustomize
+		wantErr    string
+	}{
+		{name: "none"},
+		{name: "valid", components: []KustomizeComponent{{Name: "ha"}}, env: &EnvKustomize{
+			Namespace:  "shop",
+			Components: []string{"ha"},
+			KustomizeOverlay: KustomizeOverlay{
+				Patches:            []KustomizePatch{{Patch: "kind: Deployment\nmetadata:\n  name: api\n"}, {Patch: jsonPatch.Patch, Target: &PatchTarget{Kind: "Deployment"}}},
+				Images:             []KustomizeImage{{Name: "nginx", NewTag: "1.26"}},
+				Replicas:           []KustomizeReplicas{{Name: "api", Count: 3}},
+				ConfigMapGenerator: []KustomizeConfigMap{{Name: "flags", Behavior: "merge"}},
+				SecretGenerator:    []KustomizeSecret{{Name: "db", Keys: []string{"PASSWORD"}}},
+			},
+		}},
+		{name: "component name", components: []KustomizeComponent{{}}, wantErr: "name is required"},
+		{name: "duplicate component", components: []KustomizeComponent{{Name: "ha"}, {Name: "ha"}}, wantErr: "duplicate kustomize component"},
+		{name: "unknown component", env: &EnvKustomize{Components: []string{"ha"}}, wantErr: "unknown kustomize component ha"},
+		{name: "component patch", components: []KustomizeComponent{{Name: "ha", KustomizeOverlay: KustomizeOverlay{Patches: []KustomizePatch{jsonPatch}}}}, wantErr: "kustomize component ha: patches[0]: a JSON 6902 patch requires a target"},
+		{name: "scalar patch", env: &EnvKustomize{KustomizeOverlay: KustomizeOverlay{Patches: []KustomizePatch{{Patch: "replicas"}}}}, wantErr: "must be a resource or a list of operations"},
+		{name: "image", env: &EnvKustomize{KustomizeOverlay: KustomizeOverlay{Images: []KustomizeImage{{Name: "nginx"}}}}, wantErr: "one of new_name, new_tag or digest"},
+		{name: "replicas", env: &EnvKustomize{KustomizeOverlay: KustomizeOverlay{Replicas: []KustomizeReplicas{{Name: "api", Count: -1}}}}, wantErr: "must not be negative"},
+		{name: "behavior", env: &EnvKustomize{KustomizeOverlay: KustomizeOverlay{ConfigMapGenerator: []KustomizeConfigMap{{Name: "flags", Behavior: "append"}}}}, wantErr: "invalid behavior: append"},
+		{name: "secret keys", env: &EnvKustomize{KustomizeOverlay: KustomizeOverlay{SecretGenerator: []KustomizeSecret{{Name: "db"}}}}, wantErr: "keys are required"},
+		{name: "secret key", env: &EnvKustomize{KustomizeOverlay: KustomizeOverlay{SecretGenerator: []KustomizeSecret{{Name: "db", Keys: []string{"A=B"}}}}}, wantErr: "invalid key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Kustomize:    KustomizeConfig{Components: tt.components},
+				Environments: []Environment{{Name: "prod", Kustomize: tt.env}},
+			}
+			err := cfg.validateKustomize()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateKustomize() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateKustomize() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,8 +13,15 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Older configs are migrated in memory; gitopsi config migrate
+	// rewrites the file.
+	migration, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := NewDefaultConfig()
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	if err := yaml.Unmarshal(migration.Content, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
@@ -192,3 +192,31 @@ platform: kubernetes
 		t.Error("Default gitops tool not applied")
 	}
 }
+
+func TestLoadMigratesLegacyConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "legacy.yaml")
+
+	content := `
+project:
+  name: legacy-project
+infra:
+  namespaces: true
+  rbac: false
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.APIVersion != APIVersion {
+		t.Errorf("expected apiVersion %s, got %s", APIVersion, cfg.APIVersion)
+	}
+	if cfg.Infra.RBAC {
+		t.Error("infra was not migrated to infrastructure")
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion is the config schema of this gitopsi. Bump it and register a
+// migration from the previous version when fields are renamed or
+// restructured.
+const APIVersion = "gitopsi.io/v1"
+
+// Migration upgrades a config document from apiVersion From to To. Configs
+// written before apiVersion was introduced have the empty From.
+type Migration struct {
+	From        string
+	To          string
+	Description string
+	// Apply rewrites the root mapping of the document and returns the
+	// changes it made.
+	Apply func(root *yaml.Node) []string
+}
+
+// migrations are applied in order to configs of older apiVersions.
+var migrations = []Migration{
+	{From: "", To: APIVersion, Description: "Add apiVersion, rename infra and drop application types", Apply: migrateUnversioned},
+}
+
+// MigrationResult describes the migration of a config file.
+type MigrationResult struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	// Changes are the renamed, moved and removed fields.
+	Changes []string `json:"changes,omitempty" yaml:"changes,omitempty"`
+	// Content is the migrated document, or the original one when it is
+	// current.
+	Content []byte `json:"-" yaml:"-"`
+}
+
+// Migrated reports whether the config was of an older apiVersion.
+func (r *MigrationResult) Migrated() bool {
+	return r.From != r.To
+}
+
+// Migrate upgrades a config document to APIVersion, keeping its comments
+// and key order. It fails on apiVersions newer than APIVersion.
+func Migrate(data []byte) (*MigrationResult, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &MigrationResult{From: APIVersion, To: APIVersion, Content: data}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file must be a mapping")
+	}
+
+	from := ""
+	if _, value := mappingEntry(root, "apiVersion"); value != nil {
+		from = value.Value
+	}
+	result := &MigrationResult{From: from, To: from, Content: data}
+	if from == APIVersion {
+		return result, nil
+	}
+
+	start := -1
+	for i, m := range migrations {
+		if m.From == from {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("unsupported config apiVersion %s (this gitopsi supports %s): upgrade gitopsi", from, APIVersion)
+	}
+	lines := strings.Split(string(data), "\n")
+	spaced := map[*yaml.Node]bool{}
+	for i := 0; i < len(root.Content); i += 2 {
+		key := root.Content[i]
+		above := key.Line - 2 - strings.Count(key.HeadComment, "\n") - 1
+		if key.HeadComment == "" {
+			above = key.Line - 2
+		}
+		spaced[key] = above >= 0 && above < len(lines) && strings.TrimSpace(lines[above]) == ""
+	}
+
+	first := root.Content[0]
+	for _, m := range migrations[start:] {
+		result.Changes = append(result.Changes, m.Apply(root)...)
+		setAPIVersion(root, m.To)
+		result.To = m.To
+	}
+	// A new apiVersion takes the place of the first field.
+	if key := root.Content[0]; key != first {
+		spaced[key], spaced[first] = spaced[first], false
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+	result.Content = restoreBlankLines(out.Bytes(), root, spaced)
+	return result, nil
+}
+
+// restoreBlankLines puts back the blank lines above the top-level fields of
+// a config, which encoding the document drops.
+func restoreBlankLines(content []byte, root *yaml.Node, spaced map[*yaml.Node]bool) []byte {
+	var out []string
+	k := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		topLevel := line != "" && line[0] != ' ' && line[0] != '#' && line[0] != '-'
+		if topLevel && k < len(root.Content) {
+			// The blank line goes above the head comment of the field.
+			at := len(out)
+			for at > 0 && strings.HasPrefix(out[at-1], "#") {
+				at--
+			}
+			if spaced[root.Content[k]] && at > 0 && out[at-1] != "" {
+				out = append(out[:at], append([]string{""}, out[at:]...)...)
+			}
+			k += 2
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// migrateUnversioned migrates configs written before apiVersion: infra is
+// renamed to infrastructure, which it was silently ignored for, and the
+// unused application type is dropped.
+func migrateUnversioned(root *yaml.Node) []string {
+	var changes []string
+	if key, _ := mappingEntry(root, "infra"); key != nil {
+		if _, existing := mappingEntry(root, "infrastructure"); existing != nil {
+			deleteMappingEntry(root, "infra")
+			changes = append(changes, "removed infra: infrastructure is already set")
+		} else {
+			key.Value = "infrastructure"
+			changes = append(changes, "renamed infra to infrastructure")
+		}
+	}
+
+	if _, apps := mappingEntry(root, "applications"); apps != nil && apps.Kind == yaml.SequenceNode {
+		for _, app := range apps.Content {
+			if key, _ := mappingEntry(app, "type"); key != nil {
+				deleteMappingEntry(app, "type")
+				name := "?"
+				if _, n := mappingEntry(app, "name"); n != nil {
+					name = n.Value
+				}
+				changes = append(changes, fmt.Sprintf("removed applications[%s].type: applications are Deployments", name))
+			}
+		}
+	}
+	return changes
+}
+
+// setAPIVersion sets the apiVersion of a config, as its first field.
+func setAPIVersion(root *yaml.Node, version string) {
+	if _, value := mappingEntry(root, "apiVersion"); value != nil {
+		value.Value = version
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: "apiVersion"}
+	if len(root.Content) > 0 {
+		// Keep the comment heading the config above apiVersion.
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{key, {Kind: yaml.ScalarNode, Value: version}}, root.Content...)
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping.
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func deleteMappingEntry(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	legacy := `# Demo config
+project:
+  name: demo
+
+infra:
+  namespaces: true
+
+applications:
+  - name: web
+    type: deployment
+    image: nginx
+`
+	result, err := Migrate([]byte(legacy))
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if !result.Migrated() || result.From != "" || result.To != APIVersion {
+		t.Errorf("Migrate() from %q to %q, want from \"\" to %s", result.From, result.To, APIVersion)
+	}
+	want := "# Demo config\napiVersion: " + APIVersion + `
+project:
+  name: demo
+
+infrastructure:
+  namespaces: true
+
+applications:
+  - name: web
+    image: nginx
+`
+	if string(result.Content) != want {
+		t.Errorf("Migrate() content =\n%s\nwant\n%s", result.Content, want)
+	}
+	if len(result.Changes) != 2 {
+		t.Errorf("Migrate() changes = %v, want 2", result.Changes)
+	}
+
+	again, err := Migrate(result.Content)
+	if err != nil {
+		t.Fatalf("Migrate() of migrated config error = %v", err)
+	}
+	if again.Migrated() || string(again.Content) != string(result.Content) {
+		t.Error("Migrate() changed a current config")
+	}
+}
+
+func TestMigrateKeepsInfrastructure(t *testing.T) {
+	result, err := Migrate([]byte("infrastructure:\n  rbac: true\ninfra:\n  rbac: false\n"))
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if strings.Contains(string(result.Content), "infra:") || !strings.Contains(string(result.Content), "rbac: true") {
+		t.Errorf("Migrate() content = %s, want infrastructure kept and infra removed", result.Content)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"newer apiVersion", "apiVersion: gitopsi.io/v9\nproject:\n  name: demo\n"},
+		{"not a mapping", "- project\n"},
+		{"invalid yaml", "{invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Migrate([]byte(tt.content)); err == nil {
+				t.Error("Migrate() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PresetKind is the kind of preset files.
+const PresetKind = "Preset"
+
+// presetFetchTimeout bounds the download of a preset file.
+const presetFetchTimeout = 30 * time.Second
+
+// PresetFile is an organization-defined preset, shared as a YAML file and
+// loaded by path or URL with gitopsi init --preset.
+type PresetFile struct {
+	APIVersion  string `yaml:"apiVersion,omitempty"`
+	Kind        string `yaml:"kind"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Base is a built-in preset applied before the preset file
+	Base Preset `yaml:"base,omitempty"`
+	// Config holds the gitopsi.yaml fields the preset pre-fills, such as
+	// platform, infrastructure, environments and structure
+	Config yaml.Node `yaml:"config,omitempty"`
+	// Patterns are the marketplace patterns installed into generated projects
+	Patterns []PresetPattern `yaml:"patterns,omitempty"`
+}
+
+// PresetPattern is a marketplace pattern installed by a preset.
+type PresetPattern struct {
+	Name string `yaml:"name"`
+	// Version is a version or constraint (default: the latest version)
+	Version string         `yaml:"version,omitempty"`
+	Config  map[string]any `yaml:"config,omitempty"`
+}
+
+// IsPresetFile reports whether a --preset value names a preset file rather
+// than a built-in preset.
+func IsPresetFile(ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://") ||
+		strings.Contains(ref, "/") || strings.HasSuffix(ref, ".yaml") || strings.HasSuffix(ref, ".yml")
+}
+
+// LoadPresetFile reads a preset file from a path or an http(s) URL.
+func LoadPresetFile(ctx context.Context, ref string) (*PresetFile, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://") {
+		data, err = fetchPresetFile(ctx, ref)
+	} else {
+		data, err = os.ReadFile(ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preset %s: %w", ref, err)
+	}
+
+	preset := &PresetFile{}
+	if err := yaml.Unmarshal(data, preset); err != nil {
+		return nil, fmt.Errorf("failed to parse preset %s: %w", ref, err)
+	}
+	if err := preset.validate(); err != nil {
+		return nil, fmt.Errorf("invalid preset %s: %w", ref, err)
+	}
+	return preset, nil
+}
+
+func fetchPresetFile(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, presetFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (p *PresetFile) validate() error {
+	if p.Kind != PresetKind {
+		return fmt.Errorf("kind must be %s, got %q", PresetKind, p.Kind)
+	}
+	if p.APIVersion != "" && p.APIVersion != APIVersion {
+		return fmt.Errorf("unsupported apiVersion: %s (this gitopsi supports %s)", p.APIVersion, APIVersion)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch p.Base {
+	case "", PresetMinimal, PresetStandard, PresetEnterprise:
+	default:
+		return fmt.Errorf("invalid base: %s (must be one of: minimal, standard, enterprise)", p.Base)
+	}
+	if p.Config.Kind != 0 && p.Config.Kind != yaml.MappingNode {
+		return fmt.Errorf("config must be a mapping")
+	}
+	for i, pattern := range p.Patterns {
+		if pattern.Name == "" {
+			return fmt.Errorf("patterns[%d]: name is required", i)
+		}
+	}
+	return nil
+}
+
+// ApplyPresetFile applies the base preset of a preset file and then the
+// fields it pre-fills.
+func (c *Config) ApplyPresetFile(p *PresetFile) error {
+	c.applyPreset(p.Base)
+	if p.Config.Kind == 0 {
+		return nil
+	}
+	if err := p.Config.Decode(c); err != nil {
+		return fmt.Errorf("failed to apply preset %s: %w", p.Name, err)
+	}
+	return nil
+}
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPresetConstants(t *testing.T) {
@@ -227,3 +233,100 @@ func TestCustomDirs(t *testing.T) {
 	assert.Equal(t, "monitoring/dashboards", cfg.Structure.CustomDirs[0].Path)
 	assert.Equal(t, "Grafana dashboards", cfg.Structure.CustomDirs[0].Description)
 }
+
+func TestIsPresetFile(t *testing.T) {
+	assert.False(t, IsPresetFile("enterprise"))
+	assert.False(t, IsPresetFile(""))
+	assert.True(t, IsPresetFile("acme.yaml"))
+	assert.True(t, IsPresetFile("./presets/acme"))
+	assert.True(t, IsPresetFile("https://example.com/acme-enterprise.yaml"))
+}
+
+func TestLoadPresetFile(t *testing.T) {
+	content := `apiVersion: gitopsi.io/v1
+kind: Preset
+name: acme
+base: enterprise
+config:
+  platform: openshift
+  infrastructure:
+    resource_quotas: false
+  environments:
+    - name: dev
+    - name: prod
+patterns:
+  - name: cert-manager
+    version: ^1.0.0
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acme.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "acme.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	for _, ref := range []string{path, server.URL + "/acme.yaml"} {
+		preset, err := LoadPresetFile(context.Background(), ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, "acme", preset.Name)
+		assert.Equal(t, PresetEnterprise, preset.Base)
+		require.Len(t, preset.Patterns, 1)
+		assert.Equal(t, "cert-manager", preset.Patterns[0].Name)
+	}
+
+	_, err := LoadPresetFile(context.Background(), server.URL+"/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoadPresetFileInvalid(t *testing.T) {
+	tests := map[string]string{
+		"kind":    "kind: Config\nname: acme\n",
+		"name":    "kind: Preset\n",
+		"base":    "kind: Preset\nname: acme\nbase: huge\n",
+		"config":  "kind: Preset\nname: acme\nconfig: [platform]\n",
+		"pattern": "kind: Preset\nname: acme\npatterns:\n  - version: 1.0.0\n",
+		"version": "apiVersion: gitopsi.io/v9\nkind: Preset\nname: acme\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "preset.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			_, err := LoadPresetFile(context.Background(), path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyPresetFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acme.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`kind: Preset
+name: acme
+base: enterprise
+config:
+  platform: openshift
+  infrastructure:
+    resource_quotas: false
+  environments:
+    - name: dev
+    - name: prod
+`), 0644))
+	preset, err := LoadPresetFile(context.Background(), path)
+	require.NoError(t, err)
+
+	cfg := NewDefaultConfig()
+	cfg.Project.Name = "test"
+	require.NoError(t, cfg.ApplyPresetFile(preset))
+
+	assert.Equal(t, "openshift", cfg.Platform)
+	assert.True(t, cfg.Infra.NetworkPolicies, "base preset not applied")
+	assert.False(t, cfg.Infra.ResourceQuotas, "preset config not applied over the base")
+	assert.Len(t, cfg.Structure.CustomDirs, 3)
+	require.Len(t, cfg.Environments, 2)
+	assert.Equal(t, "prod", cfg.Environments[1].Name)
+	assert.Equal(t, "test", cfg.Project.Name)
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+// UserSettings holds per-user CLI preferences stored in ~/.gitopsi/config.yaml.
+// They are independent of project configuration files.
+type UserSettings struct {
+	Auth      AuthSettings      `yaml:"auth,omitempty"`
+	Telemetry TelemetrySettings `yaml:"telemetry,omitempty"`
+	Network   NetworkSettings   `yaml:"network,omitempty"`
+}
+
+// AuthSettings configures credential storage.
+type AuthSettings struct {
+	// Store is the credential store backend: file or keyring.
+	Store string `yaml:"store,omitempty"`
+}
+
+// TelemetrySettings configures the opt-in export of OpenTelemetry traces
+// and metrics.
+type TelemetrySettings struct {
+	// Enabled exports traces and metrics; GITOPSI_TELEMETRY overrides it.
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Endpoint is the OTLP/HTTP endpoint URL (default: the
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable or https://localhost:4318).
+	Endpoint string `yaml:"endpoint,omitempty"`
+}
+
+// NetworkSettings configures the outbound connections of the CLI.
+type NetworkSettings struct {
+	// Proxy is the HTTP(S) proxy URL; it takes precedence over the
+	// HTTP_PROXY and HTTPS_PROXY environment variables.
+	Proxy string `yaml:"proxy,omitempty"`
+	// NoProxy lists the hosts reached without the proxy, like NO_PROXY.
+	NoProxy string `yaml:"no-proxy,omitempty"`
+	// SSHProxy is the SOCKS5 proxy URL of Git over SSH (default: ALL_PROXY).
+	SSHProxy string `yaml:"ssh-proxy,omitempty"`
+	// CABundle is a PEM file of certificate authorities trusted next to the
+	// system roots, such as the one of a TLS-intercepting proxy.
+	CABundle string `yaml:"ca-bundle,omitempty"`
+}
+
+// settingKeys maps each dotted key to its accessor and allowed values.
+var settingKeys = map[string]struct {
+	get     func(s *UserSettings) string
+	set     func(s *UserSettings, v string)
+	allowed []string
+}{
+	"auth.store": {
+		get:     func(s *UserSettings) string { return s.AuthStore() },
+		set:     func(s *UserSettings, v string) { s.Auth.Store = v },
+		allowed: []string{auth.StoreFile, auth.StoreKeyring},
+	},
+	"telemetry.enabled": {
+		get:     func(s *UserSettings) string { return strconv.FormatBool(s.Telemetry.Enabled) },
+		set:     func(s *UserSettings, v string) { s.Telemetry.Enabled = v == "true" },
+		allowed: []string{"true", "false"},
+	},
+	"telemetry.endpoint": {
+		get: func(s *UserSettings) string { return s.Telemetry.Endpoint },
+		set: func(s *UserSettings, v string) { s.Telemetry.Endpoint = v },
+	},
+	"network.proxy": {
+		get: func(s *UserSettings) string { return s.Network.Proxy },
+		set: func(s *UserSettings, v string) { s.Network.Proxy = v },
+	},
+	"network.no-proxy": {
+		get: func(s *UserSettings) string { return s.Network.NoProxy },
+		set: func(s *UserSettings, v string) { s.Network.NoProxy = v },
+	},
+	"network.ssh-proxy": {
+		get: func(s *UserSettings) string { return s.Network.SSHProxy },
+		set: func(s *UserSettings, v string) { s.Network.SSHProxy = v },
+	},
+	"network.ca-bundle": {
+		get: func(s *UserSettings) string { return s.Network.CABundle },
+		set: func(s *UserSettings, v string) { s.Network.CABundle = v },
+	},
+}
+
+// DefaultUserSettingsPath returns the location of the user settings file.
+func DefaultUserSettingsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".gitopsi", "config.yaml")
+	}
+	return filepath.Join(home, ".gitopsi", "config.yaml")
+}
+
+// LoadUserSettings reads user settings from path. A missing file yields defaults.
+func LoadUserSettings(path string) (*UserSettings, error) {
+	settings := &UserSettings{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveUserSettings writes user settings to path.
+func SaveUserSettings(settings *UserSettings, path string) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+	return nil
+}
+
+// SettingKeys returns the supported setting keys in sorted order.
+func SettingKeys() []string {
+	keys := make([]string, 0, len(settingKeys))
+	for k := range settingKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of a dotted setting key.
+func (s *UserSettings) Get(key string) (string, error) {
+	setting, ok := settingKeys[key]
+	if !ok {
+		return "", fmt.Errorf("unknown setting: %s (valid: %s)", key, strings.Join(SettingKeys(), ", "))
+	}
+	return setting.get(s), nil
+}
+
+// Set updates a dotted setting key after validating the value.
+func (s *UserSettings) Set(key, value string) error {
+	setting, ok := settingKeys[key]
+	if !ok {
+		return fmt.Errorf("unknown setting: %s (valid: %s)", key, strings.Join(SettingKeys(), ", "))
+	}
+
+	if len(setting.allowed) > 0 && !slices.Contains(setting.allowed, value) {
+		return fmt.Errorf("invalid value for %s: %s (must be one of: %s)", key, value, strings.Join(setting.allowed, ", "))
+	}
+
+	setting.set(s, value)
+	return nil
+}
+
+// AuthStore returns the configured credential store backend, defaulting to file.
+func (s *UserSettings) AuthStore() string {
+	if s.Auth.Store == "" {
+		return auth.StoreFile
+	}
+	return s.Auth.Store
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+func TestUserSettings_SetGet(t *testing.T) {
+	settings := &UserSettings{}
+
+	if got, _ := settings.Get("auth.store"); got != auth.StoreFile {
+		t.Errorf("default auth.store = %s, want %s", got, auth.StoreFile)
+	}
+
+	if err := settings.Set("auth.store", "keyring"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := settings.Get("auth.store"); got != auth.StoreKeyring {
+		t.Errorf("auth.store = %s, want %s", got, auth.StoreKeyring)
+	}
+
+	if err := settings.Set("auth.store", "vault"); err == nil {
+		t.Error("Set() should reject invalid values")
+	}
+	if err := settings.Set("unknown.key", "x"); err == nil {
+		t.Error("Set() should reject unknown keys")
+	}
+	if _, err := settings.Get("unknown.key"); err == nil {
+		t.Error("Get() should reject unknown keys")
+	}
+}
+
+func TestUserSettings_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+
+	missing, err := LoadUserSettings(path)
+	if err != nil {
+		t.Fatalf("LoadUserSettings() on missing file error = %v", err)
+	}
+	if missing.AuthStore() != auth.StoreFile {
+		t.Errorf("AuthStore() = %s, want %s", missing.AuthStore(), auth.StoreFile)
+	}
+
+	settings := &UserSettings{Auth: AuthSettings{Store: auth.StoreKeyring}}
+	if err := SaveUserSettings(settings, path); err != nil {
+		t.Fatalf("SaveUserSettings() error = %v", err)
+	}
+
+	loaded, err := LoadUserSettings(path)
+	if err != nil {
+		t.Fatalf("LoadUserSettings() error = %v", err)
+	}
+	if loaded.AuthStore() != auth.StoreKeyring {
+		t.Errorf("AuthStore() = %s, want %s", loaded.AuthStore(), auth.StoreKeyring)
+	}
+}
+
+func TestLoadUserSettings_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("auth: ["), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUserSettings(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestSettingKeys(t *testing.T) {
+	found := false
+	for _, key := range SettingKeys() {
+		if key == "auth.store" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("SettingKeys() = %v, missing auth.store", SettingKeys())
+	}
+}
+
+func TestUserSettings_Telemetry(t *testing.T) {
+	settings := &UserSettings{}
+
+	if got, _ := settings.Get("telemetry.enabled"); got != "false" {
+		t.Errorf("default telemetry.enabled = %s, want false", got)
+	}
+	if err := settings.Set("telemetry.enabled", "true"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := settings.Set("telemetry.endpoint", "https://otel.example.com:4318"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !settings.Telemetry.Enabled || settings.Telemetry.Endpoint != "https://otel.example.com:4318" {
+		t.Errorf("Telemetry = %+v", settings.Telemetry)
+	}
+	if err := settings.Set("telemetry.enabled", "yes"); err == nil {
+		t.Error("Set() should reject non-boolean telemetry.enabled")
+	}
+}
+
+func TestUserSettings_Network(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	settings := &UserSettings{}
+	values := map[string]string{
+		"network.proxy":     "http://proxy.corp.example.com:3128",
+		"network.no-proxy":  ".corp.example.com,10.0.0.0/8",
+		"network.ssh-proxy": "socks5://proxy.corp.example.com:1080",
+		"network.ca-bundle": "/etc/ssl/corp-ca.pem",
+	}
+	for key, value := range values {
+		if err := settings.Set(key, value); err != nil {
+			t.Fatalf("Set(%s) error = %v", key, err)
+		}
+	}
+	if err := SaveUserSettings(settings, path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadUserSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range values {
+		if got, _ := loaded.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
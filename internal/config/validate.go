@@ -2,17 +2,40 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	validPlatforms   = []string{"kubernetes", "openshift", "aks", "eks"}
-	validScopes      = []string{"infrastructure", "application", "both"}
-	validGitOpsTools = []string{"argocd", "flux", "both"}
-	validOutputTypes = []string{"local", "git"}
+	validPlatforms          = []string{"kubernetes", "openshift", "aks", "eks", "gke"}
+	validDNS01Providers     = []string{"route53", "cloudflare", "clouddns", "azuredns"}
+	validScopes             = []string{"infrastructure", "application", "both"}
+	validGitOpsTools        = []string{"argocd", "flux", "both"}
+	validOutputTypes        = []string{"local", "git"}
+	validSecretFormats      = []string{"plain", "sops"}
+	validMultiCluster       = []string{"standalone", "hub"}
+	validAppSetGens         = []string{"cluster", "git", "matrix"}
+	validVisibilities       = []string{"private", "internal", "public"}
+	validNetPolicies        = []string{NetworkPolicyBasic, NetworkPolicyDefaultDeny, NetworkPolicyNamespaceIsolated, NetworkPolicyAppAllowlist}
+	validPreviewProviders   = []string{PreviewProviderGitHub, PreviewProviderGitLab}
+	validLayouts            = []string{LayoutMonorepo, LayoutHubAndSpoke, LayoutPerApp}
+	validSSOProviders       = []string{SSOProviderOIDC, SSOProviderGitHub, SSOProviderGitLab, SSOProviderMicrosoft, SSOProviderKeycloak}
+	validCIProviders        = []string{CIProviderGitHubActions, CIProviderGitLabCI, CIProviderTekton}
+	validSeverities         = []string{"critical", "high", "medium", "low"}
+	validProvisioners       = []string{"capi", "eks", "aks", "gke"}
+	validGeneratorBehaviors = []string{"", "create", "merge", "replace"}
 )
 
 func (c *Config) Validate() error {
+	if c.APIVersion != "" && c.APIVersion != APIVersion {
+		return fmt.Errorf("unsupported apiVersion: %s (this gitopsi supports %s)", c.APIVersion, APIVersion)
+	}
+
 	if c.Project.Name == "" {
 		return fmt.Errorf("project name is required")
 	}
@@ -20,6 +43,24 @@ func (c *Config) Validate() error {
 	if !slices.Contains(validPlatforms, c.Platform) {
 		return fmt.Errorf("invalid platform: %s (valid: %v)", c.Platform, validPlatforms)
 	}
+	if c.OpenShift.ProjectRequests && !c.IsOpenShift() {
+		return fmt.Errorf("openshift.project_requests requires platform openshift")
+	}
+	if err := c.validateAddons(); err != nil {
+		return err
+	}
+	if err := c.validateTLS(); err != nil {
+		return err
+	}
+	if err := c.validateDNS(); err != nil {
+		return err
+	}
+	if err := c.validateBackup(); err != nil {
+		return err
+	}
+	if err := c.validateCost(); err != nil {
+		return err
+	}
 
 	if !slices.Contains(validScopes, c.Scope) {
 		return fmt.Errorf("invalid scope: %s (valid: %v)", c.Scope, validScopes)
@@ -45,11 +86,472 @@ func (c *Config) Validate() error {
 		if env.Name == "" {
 			return fmt.Errorf("environment %d: name is required", i)
 		}
+		if p := env.NetworkPolicyProfile; p != "" && !slices.Contains(validNetPolicies, p) {
+			return fmt.Errorf("environment %s: invalid network_policy_profile: %s (valid: %v)", env.Name, p, validNetPolicies)
+		}
+	}
+
+	if err := c.validateClusters(); err != nil {
+		return err
+	}
+
+	if p := c.Infra.NetworkPolicyProfile; p != "" && !slices.Contains(validNetPolicies, p) {
+		return fmt.Errorf("invalid infrastructure.network_policy_profile: %s (valid: %v)", p, validNetPolicies)
+	}
+
+	tenants := map[string]bool{}
+	for i, tenant := range c.Tenants {
+		if tenant.Name == "" {
+			return fmt.Errorf("tenant %d: name is required", i)
+		}
+		if tenants[tenant.Name] {
+			return fmt.Errorf("duplicate tenant: %s", tenant.Name)
+		}
+		tenants[tenant.Name] = true
+	}
+
+	for _, app := range c.Apps {
+		if err := c.validateApplication(app); err != nil {
+			return err
+		}
+	}
+	if _, err := c.AppSyncWaves(); err != nil {
+		return err
+	}
+
+	if v := c.Git.Repository.Visibility; v != "" && !slices.Contains(validVisibilities, v) {
+		return fmt.Errorf("invalid git.repository.visibility: %s (valid: %v)", v, validVisibilities)
+	}
+
+	if c.Secrets.Format != "" && !slices.Contains(validSecretFormats, c.Secrets.Format) {
+		return fmt.Errorf("invalid secrets format: %s (valid: %v)", c.Secrets.Format, validSecretFormats)
+	}
+
+	if c.Secrets.Format == "sops" && !c.Secrets.Sops.HasKeys() {
+		return fmt.Errorf("secrets.sops requires at least one age, pgp, kms, gcp_kms, or azure_keyvault key")
+	}
+
+	if gen := c.ArgoCD.ApplicationSet.Generator; gen != "" && !slices.Contains(validAppSetGens, gen) {
+		return fmt.Errorf("invalid argocd.applicationset.generator: %s (valid: %v)", gen, validAppSetGens)
+	}
+
+	if err := c.SSO.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Preview.validate(c.GitOpsTool); err != nil {
+		return err
+	}
+
+	if err := c.validateLayout(); err != nil {
+		return err
+	}
+
+	if err := c.Conventions.validate(); err != nil {
+		return err
+	}
+
+	if err := c.validateKustomize(); err != nil {
+		return err
+	}
+
+	if p := c.CI.Provider; p != "" && !slices.Contains(validCIProviders, p) {
+		return fmt.Errorf("invalid ci.provider: %s (valid: %v)", p, validCIProviders)
+	}
+
+	if f := c.CI.FailOn; f != "" && !slices.Contains(validSeverities, f) {
+		return fmt.Errorf("invalid ci.fail_on: %s (valid: %v)", f, validSeverities)
+	}
+
+	if c.Images.PinDigests && c.Flux.ImageAutomation.Enabled {
+		return fmt.Errorf("images.pin_digests cannot be combined with flux.image_automation, which rewrites tags without digests")
+	}
+
+	if soak := c.Promotion.Gates.SoakTime; soak != "" {
+		if d, err := time.ParseDuration(soak); err != nil || d < 0 {
+			return fmt.Errorf("invalid promotion.gates.soak_time: %s (use a duration such as 24h)", soak)
+		}
+	}
+
+	if mc := c.Bootstrap.MultiCluster; mc != nil {
+		if mc.Strategy != "" && !slices.Contains(validMultiCluster, mc.Strategy) {
+			return fmt.Errorf("invalid bootstrap.multi_cluster.strategy: %s (valid: %v)", mc.Strategy, validMultiCluster)
+		}
+		if mc.Strategy == "hub" && mc.Hub == "" {
+			return fmt.Errorf("bootstrap.multi_cluster.hub is required for the hub strategy")
+		}
 	}
 
 	return nil
 }
 
+// validateClusters checks the clusters to provision and the provider
+// settings they require.
+func (c *Config) validateClusters() error {
+	names := map[string]bool{}
+	envs := map[string]bool{}
+	for i, cl := range c.Clusters {
+		if cl.Name == "" {
+			return fmt.Errorf("cluster %d: name is required", i)
+		}
+		if names[cl.Name] {
+			return fmt.Errorf("duplicate cluster: %s", cl.Name)
+		}
+		names[cl.Name] = true
+
+		env := cl.EnvironmentName()
+		if !slices.ContainsFunc(c.Environments, func(e Environment) bool { return e.Name == env }) {
+			return fmt.Errorf("cluster %s: unknown environment %s", cl.Name, env)
+		}
+		if envs[env] {
+			return fmt.Errorf("cluster %s: environment %s already has a provisioned cluster", cl.Name, env)
+		}
+		envs[env] = true
+
+		if !slices.Contains(validProvisioners, cl.Provider) {
+			return fmt.Errorf("cluster %s: invalid provider: %s (valid: %v)", cl.Name, cl.Provider, validProvisioners)
+		}
+		if cl.Nodes < 0 {
+			return fmt.Errorf("cluster %s: nodes must not be negative", cl.Name)
+		}
+		switch cl.Provider {
+		case "capi":
+			if cl.CAPI == nil || cl.CAPI.Infrastructure == "" {
+				return fmt.Errorf("cluster %s: capi.infrastructure is required", cl.Name)
+			}
+			if cl.Version == "" {
+				return fmt.Errorf("cluster %s: version is required with Cluster API", cl.Name)
+			}
+		case "eks", "gke":
+			if cl.Region == "" {
+				return fmt.Errorf("cluster %s: region is required with %s", cl.Name, cl.Provider)
+			}
+		case "aks":
+			if cl.ResourceGroup == "" {
+				return fmt.Errorf("cluster %s: resource_group is required with aks", cl.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (s SSOConfig) validate() error {
+	if !s.Enabled() {
+		return nil
+	}
+	if !slices.Contains(validSSOProviders, s.Provider) {
+		return fmt.Errorf("invalid sso.provider: %s (valid: %v)", s.Provider, validSSOProviders)
+	}
+	if s.URL == "" {
+		return fmt.Errorf("sso.url is required")
+	}
+	if s.ClientID == "" {
+		return fmt.Errorf("sso.client_id is required")
+	}
+	if (s.Provider == SSOProviderOIDC || s.Provider == SSOProviderKeycloak) && s.Issuer == "" {
+		return fmt.Errorf("sso.issuer is required for the %s provider", s.Provider)
+	}
+	return nil
+}
+
+func (p PreviewConfig) validate(gitOpsTool string) error {
+	if !p.Enabled() {
+		return nil
+	}
+	if !slices.Contains(validPreviewProviders, p.Provider) {
+		return fmt.Errorf("invalid preview.provider: %s (valid: %v)", p.Provider, validPreviewProviders)
+	}
+	if gitOpsTool == "flux" {
+		return fmt.Errorf("preview environments require ArgoCD")
+	}
+	if p.Repository == "" {
+		return fmt.Errorf("preview.repository is required")
+	}
+	if p.Provider == PreviewProviderGitHub && strings.Count(p.Repository, "/") != 1 {
+		return fmt.Errorf("invalid preview.repository: %s (use owner/repo)", p.Repository)
+	}
+	for _, field := range [][2]string{{"requeue_after", p.RequeueAfter}, {"ttl", p.TTL}} {
+		if field[1] == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(field[1]); err != nil || d <= 0 {
+			return fmt.Errorf("invalid preview.%s: %s (use a duration such as 72h)", field[0], field[1])
+		}
+	}
+	if p.TTL != "" && len(p.Labels) == 0 {
+		return fmt.Errorf("preview.ttl requires preview.labels: the cleanup removes them from inactive pull requests")
+	}
+	return nil
+}
+
+func (c *Config) validateLayout() error {
+	l := c.Layout
+	if l.Strategy != "" && !slices.Contains(validLayouts, l.Strategy) {
+		return fmt.Errorf("invalid layout.strategy: %s (valid: %v)", l.Strategy, validLayouts)
+	}
+	if !l.Split() {
+		if len(l.Teams) > 0 {
+			return fmt.Errorf("layout.teams requires layout.strategy %s", LayoutHubAndSpoke)
+		}
+		return nil
+	}
+	if c.GitOpsTool != "argocd" {
+		return fmt.Errorf("layout %s requires gitops_tool argocd", l.Strategy)
+	}
+	if c.Scope == "infrastructure" {
+		return fmt.Errorf("layout %s requires the application scope", l.Strategy)
+	}
+	if len(c.Apps) == 0 {
+		return fmt.Errorf("layout %s requires applications", l.Strategy)
+	}
+	if l.RepoURL != "" && !strings.Contains(l.RepoURL, "{repo}") {
+		return fmt.Errorf("invalid layout.repo_url: %s (use {repo} for the repository name)", l.RepoURL)
+	}
+
+	if l.Strategy == LayoutPerApp {
+		if len(l.Teams) > 0 {
+			return fmt.Errorf("layout.teams requires layout.strategy %s", LayoutHubAndSpoke)
+		}
+		if l.RepoURL == "" {
+			return fmt.Errorf("layout %s requires layout.repo_url", l.Strategy)
+		}
+		return nil
+	}
+
+	if len(l.Teams) == 0 {
+		return fmt.Errorf("layout %s requires layout.teams", l.Strategy)
+	}
+	owners := map[string]string{}
+	teams := map[string]bool{}
+	for _, team := range l.Teams {
+		if team.Name == "" {
+			return fmt.Errorf("layout team name is required")
+		}
+		if teams[team.Name] {
+			return fmt.Errorf("duplicate layout team: %s", team.Name)
+		}
+		teams[team.Name] = true
+		if team.RepoURL == "" && l.RepoURL == "" {
+			return fmt.Errorf("layout team %s: repo_url or layout.repo_url is required", team.Name)
+		}
+		if len(team.Apps) == 0 {
+			return fmt.Errorf("layout team %s has no applications", team.Name)
+		}
+		for _, app := range team.Apps {
+			if !slices.ContainsFunc(c.Apps, func(a Application) bool { return a.Name == app }) {
+				return fmt.Errorf("layout team %s: unknown application %s", team.Name, app)
+			}
+			if owner, ok := owners[app]; ok {
+				return fmt.Errorf("application %s belongs to layout teams %s and %s", app, owner, team.Name)
+			}
+			owners[app] = team.Name
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateApplication(app Application) error {
+	if app.Name == "" {
+		return fmt.Errorf("application name is required")
+	}
+	for _, env := range app.Env {
+		if env.Name == "" {
+			return fmt.Errorf("application %s: env var name is required", app.Name)
+		}
+	}
+	for _, secret := range app.Secrets {
+		if secret.Name == "" {
+			return fmt.Errorf("application %s: secret name is required", app.Name)
+		}
+		if secret.Env != "" && secret.Key == "" {
+			return fmt.Errorf("application %s: secret %s sets env without key", app.Name, secret.Name)
+		}
+	}
+	for _, from := range app.AllowFrom {
+		if !slices.ContainsFunc(c.Apps, func(a Application) bool { return a.Name == from }) {
+			return fmt.Errorf("application %s: allow_from references unknown application %s", app.Name, from)
+		}
+	}
+	for _, dep := range app.DependsOn {
+		if !slices.ContainsFunc(c.Apps, func(a Application) bool { return a.Name == dep }) {
+			return fmt.Errorf("application %s: depends_on references unknown application %s", app.Name, dep)
+		}
+	}
+	if app.Ingress != nil && app.Ingress.Host == "" {
+		return fmt.Errorf("application %s: ingress host is required", app.Name)
+	}
+	if app.SCC != "" && !c.IsOpenShift() {
+		return fmt.Errorf("application %s: scc requires platform openshift", app.Name)
+	}
+	if app.CloudIdentity != "" && !c.IsCloud() {
+		return fmt.Errorf("application %s: cloud_identity requires platform eks, aks or gke", app.Name)
+	}
+	for envName, override := range app.Overrides {
+		if c.GetEnvironment(envName) == nil {
+			return fmt.Errorf("application %s: overrides unknown environment %s", app.Name, envName)
+		}
+		if override.Host != "" && app.Ingress == nil {
+			return fmt.Errorf("application %s: %s overrides host without ingress", app.Name, envName)
+		}
+		if override.CloudIdentity != "" && app.CloudIdentity == "" {
+			return fmt.Errorf("application %s: %s overrides cloud_identity without cloud_identity", app.Name, envName)
+		}
+		for _, env := range override.Env {
+			if env.Name == "" {
+				return fmt.Errorf("application %s: env var name is required in %s overrides", app.Name, envName)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTLS checks the solver and DNS provider of the tls block.
+func (c *Config) validateTLS() error {
+	t := c.TLS
+	if !t.Enabled() {
+		if t.Solver != "" || t.DNS01.Provider != "" || len(t.Wildcards) > 0 {
+			return fmt.Errorf("tls.email is required")
+		}
+		return nil
+	}
+	if c.Scope == "application" {
+		return fmt.Errorf("tls requires scope infrastructure or both")
+	}
+	switch t.Solver {
+	case "", TLSSolverHTTP01:
+		if len(t.Wildcards) > 0 {
+			return fmt.Errorf("tls.wildcards require the dns01 solver")
+		}
+	case TLSSolverDNS01:
+		if !slices.Contains(validDNS01Providers, t.DNS01.Provider) {
+			return fmt.Errorf("invalid tls.dns01.provider: %s (valid: %v)", t.DNS01.Provider, validDNS01Providers)
+		}
+		if t.DNS01.Provider == "clouddns" && t.DNS01.Project == "" {
+			return fmt.Errorf("tls.dns01.project is required for clouddns")
+		}
+		if t.DNS01.Provider == "azuredns" && (t.DNS01.SubscriptionID == "" || t.DNS01.ResourceGroup == "" || t.DNS01.HostedZone == "") {
+			return fmt.Errorf("tls.dns01.subscription_id, resource_group and hosted_zone are required for azuredns")
+		}
+	default:
+		return fmt.Errorf("invalid tls.solver: %s (valid: [%s %s])", t.Solver, TLSSolverHTTP01, TLSSolverDNS01)
+	}
+	return nil
+}
+
+// validateDNS checks the provider of the dns block and that the application
+// hostnames belong to its zone.
+func (c *Config) validateDNS() error {
+	d := c.DNS
+	if !d.Enabled() {
+		if d.Provider != "" || d.Mode != "" || d.Target != "" {
+			return fmt.Errorf("dns.zone is required")
+		}
+		return nil
+	}
+	if !slices.Contains(validDNS01Providers, d.Provider) {
+		return fmt.Errorf("invalid dns.provider: %s (valid: %v)", d.Provider, validDNS01Providers)
+	}
+	if d.Mode != "" && d.Mode != DNSModeExternalDNS && d.Mode != DNSModeDirect {
+		return fmt.Errorf("invalid dns.mode: %s (valid: [%s %s])", d.Mode, DNSModeExternalDNS, DNSModeDirect)
+	}
+	if d.Provider == "clouddns" && d.Project == "" {
+		return fmt.Errorf("dns.project is required for clouddns")
+	}
+	if d.Provider == "azuredns" && d.ResourceGroup == "" {
+		return fmt.Errorf("dns.resource_group is required for azuredns")
+	}
+	if d.TTL < 0 {
+		return fmt.Errorf("dns.ttl must not be negative")
+	}
+	for _, app := range c.Apps {
+		if app.Ingress == nil {
+			continue
+		}
+		if !d.InZone(app.Ingress.Host) {
+			return fmt.Errorf("application %s: host %s is not in dns.zone %s", app.Name, app.Ingress.Host, d.Zone)
+		}
+		for env, o := range app.Overrides {
+			if o.Host != "" && !d.InZone(o.Host) {
+				return fmt.Errorf("application %s: %s host %s is not in dns.zone %s", app.Name, env, o.Host, d.Zone)
+			}
+		}
+	}
+	return nil
+}
+
+// validateBackup checks the schedule and retention of the backup block.
+func (c *Config) validateBackup() error {
+	b := c.Backup
+	if !b.Enabled {
+		return nil
+	}
+	if c.Scope == "application" {
+		return fmt.Errorf("backup requires scope infrastructure or both")
+	}
+	if b.Schedule != "" && len(strings.Fields(b.Schedule)) != 5 {
+		return fmt.Errorf("invalid backup.schedule: %s (use a cron expression such as 0 2 * * *)", b.Schedule)
+	}
+	if b.TTL != "" {
+		if d, err := time.ParseDuration(b.TTL); err != nil || d <= 0 {
+			return fmt.Errorf("invalid backup.ttl: %s (use a duration such as 720h)", b.TTL)
+		}
+	}
+	if filepath.IsAbs(b.ArgoCDExport) || strings.HasPrefix(filepath.ToSlash(filepath.Clean(b.ArgoCDExport)), "../") {
+		return fmt.Errorf("backup.argocd_export must be a directory of the repository: %s", b.ArgoCDExport)
+	}
+	return nil
+}
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+)
+
+// validateCost checks that the cost center label and its values are valid
+// Kubernetes labels.
+func (c *Config) validateCost() error {
+	if !c.Cost.Enabled {
+		return nil
+	}
+	if label := c.Cost.LabelKey(); !labelKeyPattern.MatchString(label) {
+		return fmt.Errorf("invalid cost.label: %s", label)
+	}
+	if center := c.ProjectCostCenter(); !validLabelValue(center) {
+		return fmt.Errorf("invalid cost.cost_center: %s (must be a valid label value)", center)
+	}
+	for _, tenant := range c.Tenants {
+		if center := c.TenantCostCenter(tenant); tenant.Name != "" && !validLabelValue(center) {
+			return fmt.Errorf("tenant %s: invalid cost_center: %s (must be a valid label value)", tenant.Name, center)
+		}
+	}
+	return nil
+}
+
+func validLabelValue(value string) bool {
+	return len(value) <= 63 && labelValuePattern.MatchString(value)
+}
+
+// validateAddons checks that the add-ons enabled exist on the platform.
+func (c *Config) validateAddons() error {
+	a := c.Addons
+	if !a.LoadBalancerController.On() && !a.ClusterAutoscaler.On() && !a.StorageClasses {
+		return nil
+	}
+	if c.Scope == "application" {
+		return fmt.Errorf("addons require scope infrastructure or both")
+	}
+	if a.LoadBalancerController.On() && c.Platform != "eks" {
+		return fmt.Errorf("addons.load_balancer_controller requires platform eks")
+	}
+	if a.ClusterAutoscaler.On() && c.Platform != "eks" {
+		return fmt.Errorf("addons.cluster_autoscaler requires platform eks")
+	}
+	if a.StorageClasses && !c.IsCloud() {
+		return fmt.Errorf("addons.storage_classes requires platform eks, aks or gke")
+	}
+	return nil
+}
+
 func ValidPlatforms() []string {
 	return validPlatforms
 }
@@ -58,6 +560,123 @@ func ValidScopes() []string {
 	return validScopes
 }
 
+// ValidNetworkPolicyProfiles returns the network policy profiles.
+func ValidNetworkPolicyProfiles() []string {
+	return validNetPolicies
+}
+
 func ValidGitOpsTools() []string {
 	return validGitOpsTools
 }
+
+func (c ConventionsConfig) validate() error {
+	if ns := c.Names.Namespace; ns != "" && !strings.Contains(ns, "{env}") {
+		return fmt.Errorf("conventions.names.namespace must contain {env}: %s", ns)
+	}
+	for key := range c.Labels {
+		if key == "" {
+			return fmt.Errorf("conventions.labels: empty label key")
+		}
+	}
+	for key := range c.Annotations {
+		if key == "" {
+			return fmt.Errorf("conventions.annotations: empty annotation key")
+		}
+	}
+	for _, label := range c.RequiredLabels {
+		if label == "" {
+			return fmt.Errorf("conventions.required_labels: empty label")
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateKustomize() error {
+	components := map[string]bool{}
+	for i, component := range c.Kustomize.Components {
+		if component.Name == "" {
+			return fmt.Errorf("kustomize.components[%d]: name is required", i)
+		}
+		if components[component.Name] {
+			return fmt.Errorf("duplicate kustomize component: %s", component.Name)
+		}
+		components[component.Name] = true
+		if err := component.validate(); err != nil {
+			return fmt.Errorf("kustomize component %s: %w", component.Name, err)
+		}
+	}
+
+	for _, env := range c.Environments {
+		if env.Kustomize == nil {
+			continue
+		}
+		for _, name := range env.Kustomize.Components {
+			if !components[name] {
+				return fmt.Errorf("environment %s: unknown kustomize component %s", env.Name, name)
+			}
+		}
+		if err := env.Kustomize.validate(); err != nil {
+			return fmt.Errorf("environment %s: kustomize: %w", env.Name, err)
+		}
+	}
+	return nil
+}
+
+func (o KustomizeOverlay) validate() error {
+	for i, p := range o.Patches {
+		var patch any
+		if err := yaml.Unmarshal([]byte(p.Patch), &patch); err != nil {
+			return fmt.Errorf("patches[%d]: invalid patch: %w", i, err)
+		}
+		switch patch.(type) {
+		case map[string]any:
+		case []any:
+			if p.Target == nil {
+				return fmt.Errorf("patches[%d]: a JSON 6902 patch requires a target", i)
+			}
+		default:
+			return fmt.Errorf("patches[%d]: patch must be a resource or a list of operations", i)
+		}
+	}
+	for i, image := range o.Images {
+		if image.Name == "" {
+			return fmt.Errorf("images[%d]: name is required", i)
+		}
+		if image.NewName == "" && image.NewTag == "" && image.Digest == "" {
+			return fmt.Errorf("images[%d]: one of new_name, new_tag or digest is required", i)
+		}
+	}
+	for i, r := range o.Replicas {
+		if r.Name == "" {
+			return fmt.Errorf("replicas[%d]: name is required", i)
+		}
+		if r.Count < 0 {
+			return fmt.Errorf("replicas[%d]: count must not be negative", i)
+		}
+	}
+	for i, g := range o.ConfigMapGenerator {
+		if g.Name == "" {
+			return fmt.Errorf("config_map_generator[%d]: name is required", i)
+		}
+		if !slices.Contains(validGeneratorBehaviors, g.Behavior) {
+			return fmt.Errorf("config_map_generator[%d]: invalid behavior: %s (valid: create, merge, replace)", i, g.Behavior)
+		}
+	}
+	for i, g := range o.SecretGenerator {
+		if g.Name == "" {
+			return fmt.Errorf("secret_generator[%d]: name is required", i)
+		}
+		if !slices.Contains(validGeneratorBehaviors, g.Behavior) {
+			return fmt.Errorf("secret_generator[%d]: invalid behavior: %s (valid: create, merge, replace)", i, g.Behavior)
+		}
+		if len(g.Keys) == 0 {
+			return fmt.Errorf("secret_generator[%d]: keys are required", i)
+		}
+		for _, key := range g.Keys {
+			if key == "" || strings.ContainsAny(key, "= \t") {
+				return fmt.Errorf("secret_generator[%d]: invalid key: %q", i, key)
+			}
+		}
+	}
+	return nil
+}
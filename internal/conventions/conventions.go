@@ -0,0 +1,293 @@
+// Package conventions applies the organization conventions of a config to
+// generated manifests.
+package conventions
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+// Transform rewrites a generated file before it is written.
+type Transform func(path string, content []byte) ([]byte, error)
+
+// Transformer returns the Transform adding the labels and annotations of
+// the conventions to the resources of YAML files, or nil when the
+// conventions add none.
+func Transformer(c config.ConventionsConfig) Transform {
+	if len(c.Labels) == 0 && len(c.Annotations) == 0 {
+		return nil
+	}
+	return func(path string, content []byte) ([]byte, error) {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return content, nil
+		}
+		return Apply(content, c.Labels, c.Annotations)
+	}
+}
+
+// CostTransformer returns the Transform adding the cost center label of
+// cost to the resources and pod templates of YAML files, or nil when cost
+// allocation is disabled. costCenter returns the cost center of a file.
+func CostTransformer(cost config.CostConfig, costCenter func(path string) string) Transform {
+	if !cost.Enabled {
+		return nil
+	}
+	return func(path string, content []byte) ([]byte, error) {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return content, nil
+		}
+		return ApplyLabels(content, map[string]string{cost.LabelKey(): costCenter(path)})
+	}
+}
+
+// Chain returns the Transform applying transforms in order, skipping nil
+// ones, or nil when all are nil.
+func Chain(transforms ...Transform) Transform {
+	var chain []Transform
+	for _, t := range transforms {
+		if t != nil {
+			chain = append(chain, t)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return func(path string, content []byte) ([]byte, error) {
+		for _, t := range chain {
+			var err error
+			if content, err = t(path, content); err != nil {
+				return nil, err
+			}
+		}
+		return content, nil
+	}
+}
+
+// Apply adds labels and annotations to the metadata of every Kubernetes
+// resource of a YAML stream, keeping the values resources set themselves.
+// Kustomize configurations, SOPS-encrypted documents, whose MAC covers the
+// metadata, and content that is not YAML, such as Helm templates, are left
+// unchanged.
+func Apply(content []byte, labels, annotations map[string]string) ([]byte, error) {
+	return apply(content, labels, annotations, false)
+}
+
+// ApplyLabels adds labels to the metadata of every Kubernetes resource of a
+// YAML stream like Apply, and to the pod templates of workloads, so that
+// the labels are also on their pods.
+func ApplyLabels(content []byte, labels map[string]string) ([]byte, error) {
+	return apply(content, labels, nil, true)
+}
+
+func apply(content []byte, labels, annotations map[string]string, podTemplates bool) ([]byte, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return content, nil
+		}
+		docs = append(docs, &doc)
+	}
+
+	changed := false
+	for _, doc := range docs {
+		resource := resourceRoot(doc)
+		if resource == nil {
+			continue
+		}
+		metadata := mappingValue(resource, "metadata", true)
+		if addEntries(mappingValue(metadata, "labels", len(labels) > 0), labels) {
+			changed = true
+		}
+		if addEntries(mappingValue(metadata, "annotations", len(annotations) > 0), annotations) {
+			changed = true
+		}
+		if template := podTemplate(resource); podTemplates && template != nil {
+			metadata := mappingValue(template, "metadata", true)
+			if addEntries(mappingValue(metadata, "labels", true), labels) {
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return content, nil
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Violation is a resource missing required labels.
+type Violation struct {
+	Kind    string
+	Name    string
+	Line    int
+	Missing []string
+}
+
+// Check returns the resources of a YAML stream missing required labels.
+// Content that is not YAML has no resources.
+func Check(content []byte, required []string) []Violation {
+	if len(required) == 0 {
+		return nil
+	}
+	var violations []Violation
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			return violations
+		}
+		resource := resourceRoot(&doc)
+		if resource == nil {
+			continue
+		}
+		metadata := mappingValue(resource, "metadata", false)
+		labels := mappingValue(metadata, "labels", false)
+		var missing []string
+		for _, label := range required {
+			if mappingValue(labels, label, false) == nil {
+				missing = append(missing, label)
+			}
+		}
+		if len(missing) > 0 {
+			violations = append(violations, Violation{
+				Kind:    scalarValue(resource, "kind"),
+				Name:    scalarValue(metadata, "name"),
+				Line:    resource.Line,
+				Missing: missing,
+			})
+		}
+	}
+}
+
+// resourceRoot returns the root mapping of a YAML document holding a
+// Kubernetes resource, or nil for other documents.
+func resourceRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	apiVersion, kind := scalarValue(root, "apiVersion"), scalarValue(root, "kind")
+	if apiVersion == "" || kind == "" || mappingValue(root, "sops", false) != nil {
+		return nil
+	}
+	if strings.HasPrefix(apiVersion, "kustomize.config.k8s.io/") {
+		return nil
+	}
+	return root
+}
+
+// podTemplate returns the pod template of a workload, or nil for other
+// resources.
+func podTemplate(resource *yaml.Node) *yaml.Node {
+	spec := mappingValue(resource, "spec", false)
+	if scalarValue(resource, "kind") == "CronJob" {
+		spec = mappingValue(mappingValue(spec, "jobTemplate", false), "spec", false)
+	}
+	if template := mappingValue(spec, "template", false); template != nil && template.Kind == yaml.MappingNode {
+		return template
+	}
+	return nil
+}
+
+// addEntries adds the entries missing from a mapping, in key order, and
+// reports whether it added any.
+func addEntries(node *yaml.Node, entries map[string]string) bool {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return false
+	}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	added := false
+	for _, key := range keys {
+		if mappingValue(node, key, false) != nil {
+			continue
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: entries[key], Style: quoteStyle(entries[key])},
+		)
+		added = true
+	}
+	return added
+}
+
+// quoteStyle quotes values that would not be read back as strings, such as
+// numeric cost centers.
+func quoteStyle(value string) yaml.Style {
+	var decoded any
+	if err := yaml.Unmarshal([]byte(value), &decoded); err != nil {
+		return yaml.DoubleQuotedStyle
+	}
+	if s, ok := decoded.(string); ok && s == value {
+		return 0
+	}
+	return yaml.DoubleQuotedStyle
+}
+
+// mappingValue returns the value of key in a mapping, adding an empty
+// mapping when create is set and the key is missing.
+func mappingValue(node *yaml.Node, key string, create bool) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			if create && value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+				value.Kind, value.Tag, value.Value = yaml.MappingNode, "", ""
+			}
+			return value
+		}
+	}
+	if !create {
+		return nil
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+func scalarValue(node *yaml.Node, key string) string {
+	if value := mappingValue(node, key, false); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
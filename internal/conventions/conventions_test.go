@@ -0,0 +1,193 @@
+package conventions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func TestApply(t *testing.T) {
+	content := `# Namespace of the dev environment
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop-dev
+  labels:
+    team: payments
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+`
+	out, err := Apply([]byte(content), map[string]string{"team": "platform", "cost-center": "1234"}, map[string]string{"owner": "platform@example.com"})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{
+		"# Namespace of the dev environment\n",
+		"  labels:\n    team: payments\n    cost-center: \"1234\"\n  annotations:\n    owner: platform@example.com\n---\n",
+		"  name: web\n  labels:\n    cost-center: \"1234\"\n    team: platform\n  annotations:\n    owner: platform@example.com\nspec:\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Apply() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestApplyLabels(t *testing.T) {
+	content := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+        cost-center: web-team
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          restartPolicy: OnFailure
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+	out, err := ApplyLabels([]byte(content), map[string]string{"cost-center": "shop"})
+	if err != nil {
+		t.Fatalf("ApplyLabels() error = %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{
+		"  name: web\n  labels:\n    cost-center: shop\nspec:\n",
+		"      labels:\n        app: web\n        cost-center: web-team\n",
+		"      template:\n        spec:\n          restartPolicy: OnFailure\n        metadata:\n          labels:\n            cost-center: shop\n",
+		"  name: settings\n  labels:\n    cost-center: shop\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ApplyLabels() missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "template:") != 2 {
+		t.Errorf("ApplyLabels() should not add pod templates:\n%s", got)
+	}
+}
+
+func TestApplyUnchanged(t *testing.T) {
+	labels := map[string]string{"team": "platform"}
+	tests := map[string]string{
+		"kustomization": "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n  - dev.yaml\n",
+		"sops":          "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nsops:\n  mac: ENC[abc]\n",
+		"values":        "replicaCount: 1\nimage:\n  tag: latest\n",
+		"labelled":      "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n  labels:\n    team: shop\n",
+		"template":      "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n  {{- include \"labels\" . }}\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := Apply([]byte(content), labels, nil)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if string(out) != content {
+				t.Errorf("Apply() changed content:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestTransformer(t *testing.T) {
+	if Transformer(config.ConventionsConfig{RequiredLabels: []string{"team"}}) != nil {
+		t.Error("Transformer() without labels or annotations should be nil")
+	}
+
+	transform := Transformer(config.ConventionsConfig{Labels: map[string]string{"team": "platform"}})
+	content := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n")
+	out, err := transform("docs/README.md", content)
+	if err != nil || string(out) != string(content) {
+		t.Errorf("transform(README.md) = %q, %v", out, err)
+	}
+	out, err = transform("apps/cfg.yaml", content)
+	if err != nil || !strings.Contains(string(out), "labels:\n    team: platform") {
+		t.Errorf("transform(cfg.yaml) = %q, %v", out, err)
+	}
+}
+
+func TestCostTransformer(t *testing.T) {
+	if CostTransformer(config.CostConfig{}, nil) != nil {
+		t.Error("CostTransformer() should be nil when cost is disabled")
+	}
+	transform := Chain(
+		CostTransformer(config.CostConfig{Enabled: true}, func(path string) string {
+			if strings.HasPrefix(path, "tenants/") {
+				return "payments"
+			}
+			return "shop"
+		}),
+		nil,
+		Transformer(config.ConventionsConfig{Labels: map[string]string{"cost-center": "ignored", "team": "platform"}}),
+	)
+	manifest := []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: payments-dev\n")
+	out, err := transform("tenants/payments/namespace.yaml", manifest)
+	if err != nil {
+		t.Fatalf("transform() error = %v", err)
+	}
+	if got := string(out); !strings.Contains(got, "cost-center: payments\n    team: platform\n") {
+		t.Errorf("transform() = %s, want the tenant cost center and the conventions", got)
+	}
+	if out, _ := transform("README.md", []byte("# shop\n")); string(out) != "# shop\n" {
+		t.Errorf("transform() = %s, want other files unchanged", out)
+	}
+	if Chain(nil, nil) != nil {
+		t.Error("Chain() of nil transforms should be nil")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	content := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop-dev
+  labels:
+    team: payments
+    cost-center: "1234"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    team: payments
+---
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources: []
+`
+	violations := Check([]byte(content), []string{"team", "cost-center"})
+	if len(violations) != 1 {
+		t.Fatalf("Check() = %+v, want 1 violation", violations)
+	}
+	v := violations[0]
+	if v.Kind != "Deployment" || v.Name != "web" || v.Line != 9 || len(v.Missing) != 1 || v.Missing[0] != "cost-center" {
+		t.Errorf("Check() = %+v", v)
+	}
+
+	if violations := Check([]byte(content), nil); violations != nil {
+		t.Errorf("Check() without required labels = %+v", violations)
+	}
+}
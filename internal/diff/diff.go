@@ -0,0 +1,360 @@
+// Package diff compares the manifests a GitOps repository would apply with the
+// live state of a cluster using server-side dry-run.
+package diff
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Status is the drift state of a single resource.
+type Status string
+
+const (
+	// StatusModified means the live object differs from the rendered manifest.
+	StatusModified Status = "modified"
+	// StatusMissing means the object does not exist in the cluster yet.
+	StatusMissing Status = "missing"
+)
+
+// ResourceDiff describes the drift of one object.
+type ResourceDiff struct {
+	Group     string `json:"group,omitempty" yaml:"group,omitempty"`
+	Version   string `json:"version" yaml:"version"`
+	Kind      string `json:"kind" yaml:"kind"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name" yaml:"name"`
+	Status    Status `json:"status" yaml:"status"`
+	Diff      string `json:"diff" yaml:"diff"`
+}
+
+// ID returns a kind/namespace/name identifier for display.
+func (r ResourceDiff) ID() string {
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+	}
+	return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+}
+
+// Target is a directory rendered and diffed as a unit.
+type Target struct {
+	// Path is the kustomization or chart directory.
+	Path string `json:"path" yaml:"path"`
+	// Renderer is kustomize or helm.
+	Renderer string `json:"renderer" yaml:"renderer"`
+}
+
+// TargetResult is the outcome of diffing one target.
+type TargetResult struct {
+	Target    `yaml:",inline"`
+	Resources []ResourceDiff `json:"resources" yaml:"resources"`
+	Error     string         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Report is the outcome of a diff run.
+type Report struct {
+	Targets []TargetResult `json:"targets" yaml:"targets"`
+	Drifted int            `json:"drifted" yaml:"drifted"`
+	Errors  int            `json:"errors" yaml:"errors"`
+}
+
+// HasDrift reports whether any resource differs from the cluster.
+func (r *Report) HasDrift() bool {
+	return r.Drifted > 0
+}
+
+// HasErrors reports whether any target could not be rendered or diffed.
+func (r *Report) HasErrors() bool {
+	return r.Errors > 0
+}
+
+// ToJSON renders the report as indented JSON.
+func (r *Report) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Options configures a diff run.
+type Options struct {
+	// Path is the repository root or a single kustomization/chart directory.
+	Path string
+	// Environment limits the diff to the overlays of one environment.
+	Environment string
+	Context     string
+	Kubeconfig  string
+}
+
+// CommandRunner executes an external command and returns stdout, stderr and
+// the exit code. A non-nil error means the command could not be started.
+type CommandRunner func(ctx context.Context, name string, args ...string) (stdout, stderr string, exitCode int, err error)
+
+// Differ renders targets and diffs them against the cluster.
+type Differ struct {
+	opts *Options
+	run  CommandRunner
+}
+
+// New creates a Differ.
+func New(opts *Options) *Differ {
+	return &Differ{opts: opts, run: runCommand}
+}
+
+// SetCommandRunner replaces the command runner (used for testing).
+func (d *Differ) SetCommandRunner(run CommandRunner) {
+	d.run = run
+}
+
+// DiscoverTargets finds the directories to diff under the configured path.
+// A path that is itself a kustomization or chart is returned as is; a
+// repository root yields its infrastructure and application overlays.
+func (d *Differ) DiscoverTargets() ([]Target, error) {
+	info, err := os.Stat(d.opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read path: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path must be a directory: %s", d.opts.Path)
+	}
+
+	if renderer := rendererFor(d.opts.Path); renderer != "" {
+		return []Target{{Path: d.opts.Path, Renderer: renderer}}, nil
+	}
+
+	var targets []Target
+	for _, section := range []string{"infrastructure", "applications"} {
+		overlays := filepath.Join(d.opts.Path, section, "overlays")
+		entries, err := os.ReadDir(overlays)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || (d.opts.Environment != "" && e.Name() != d.opts.Environment) {
+				continue
+			}
+			dir := filepath.Join(overlays, e.Name())
+			if renderer := rendererFor(dir); renderer != "" {
+				targets = append(targets, Target{Path: dir, Renderer: renderer})
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no kustomization or chart found under %s", d.opts.Path)
+	}
+	return targets, nil
+}
+
+// Run diffs every discovered target and returns the aggregated report.
+func (d *Differ) Run(ctx context.Context) (*Report, error) {
+	targets, err := d.DiscoverTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	for _, target := range targets {
+		result := d.diffTarget(ctx, target)
+		if result.Error != "" {
+			report.Errors++
+		}
+		report.Drifted += len(result.Resources)
+		report.Targets = append(report.Targets, result)
+	}
+	return report, nil
+}
+
+func (d *Differ) diffTarget(ctx context.Context, target Target) TargetResult {
+	result := TargetResult{Target: target, Resources: []ResourceDiff{}}
+
+	rendered, err := d.render(ctx, target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	tmp, err := os.CreateTemp("", "gitopsi-diff-*.yaml")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create temp file: %v", err)
+		return result
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(rendered); err != nil {
+		tmp.Close()
+		result.Error = fmt.Sprintf("failed to write temp file: %v", err)
+		return result
+	}
+	tmp.Close()
+
+	args := d.kubectlArgs("diff", "--server-side=true", "--force-conflicts", "-f", tmp.Name())
+	stdout, stderr, code, err := d.run(ctx, "kubectl", args...)
+	switch {
+	case err != nil:
+		result.Error = fmt.Sprintf("failed to run kubectl diff: %v", err)
+	case code == 0:
+	case code == 1:
+		result.Resources = ParseDiff(stdout)
+	default:
+		result.Error = fmt.Sprintf("kubectl diff failed: %s", strings.TrimSpace(stderr))
+	}
+	return result
+}
+
+func (d *Differ) render(ctx context.Context, target Target) (string, error) {
+	var name string
+	var args []string
+
+	switch target.Renderer {
+	case "helm":
+		name = "helm"
+		args = []string{"template", filepath.Base(target.Path), target.Path}
+		if values := filepath.Join(target.Path, "values-"+d.opts.Environment+".yaml"); d.opts.Environment != "" && fileExists(values) {
+			args = append(args, "--values", values)
+		}
+	default:
+		name = "kubectl"
+		args = d.kubectlArgs("kustomize", target.Path)
+	}
+
+	stdout, stderr, code, err := d.run(ctx, name, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	if code != 0 {
+		return "", fmt.Errorf("failed to render %s: %s", target.Path, strings.TrimSpace(stderr))
+	}
+	return stdout, nil
+}
+
+func (d *Differ) kubectlArgs(args ...string) []string {
+	var prefix []string
+	if d.opts.Kubeconfig != "" {
+		prefix = append(prefix, "--kubeconfig", d.opts.Kubeconfig)
+	}
+	if d.opts.Context != "" {
+		prefix = append(prefix, "--context", d.opts.Context)
+	}
+	return append(prefix, args...)
+}
+
+// ParseDiff splits `kubectl diff` output into per-resource diffs. kubectl
+// names the compared files group.version.Kind.namespace.name.
+func ParseDiff(output string) []ResourceDiff {
+	var diffs []ResourceDiff
+	var current *ResourceDiff
+	var body strings.Builder
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Diff = body.String()
+		if strings.Contains(current.Diff, "@@ -0,0 ") {
+			current.Status = StatusMissing
+		}
+		diffs = append(diffs, *current)
+		body.Reset()
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "diff ") {
+			flush()
+			fields := strings.Fields(line)
+			res := parseResourceName(filepath.Base(fields[len(fields)-1]))
+			current = &res
+			continue
+		}
+		if current != nil {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+	flush()
+
+	sort.SliceStable(diffs, func(i, j int) bool { return diffs[i].ID() < diffs[j].ID() })
+	return diffs
+}
+
+// parseResourceName decodes kubectl's group.version.Kind.namespace.name file
+// name. The group may contain dots; core resources have no group.
+func parseResourceName(base string) ResourceDiff {
+	res := ResourceDiff{Status: StatusModified}
+	parts := strings.Split(base, ".")
+
+	// Find the Kind: the first capitalised segment.
+	kindIdx := -1
+	for i, p := range parts {
+		if p != "" && p[0] >= 'A' && p[0] <= 'Z' {
+			kindIdx = i
+			break
+		}
+	}
+	if kindIdx < 1 {
+		res.Name = base
+		return res
+	}
+
+	res.Kind = parts[kindIdx]
+	res.Version = parts[kindIdx-1]
+	res.Group = strings.Join(parts[:kindIdx-1], ".")
+
+	rest := parts[kindIdx+1:]
+	switch len(rest) {
+	case 0:
+	case 1:
+		res.Name = rest[0]
+	default:
+		// Object names may contain dots; namespaces may not.
+		res.Namespace = rest[0]
+		res.Name = strings.Join(rest[1:], ".")
+	}
+	return res
+}
+
+func rendererFor(dir string) string {
+	for _, f := range []string{"kustomization.yaml", "kustomization.yml", "Kustomization"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return "kustomize"
+		}
+	}
+	if fileExists(filepath.Join(dir, "Chart.yaml")) {
+		return "helm"
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) (stdout, stderr string, exitCode int, err error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var out, errOut strings.Builder
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		return out.String(), errOut.String(), exitErr.ExitCode(), nil
+	}
+	if runErr != nil {
+		return "", "", -1, runErr
+	}
+	return out.String(), errOut.String(), 0, nil
+}
@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleDiff = `diff -u -N /tmp/LIVE-1/apps.v1.Deployment.prod.web /tmp/MERGED-1/apps.v1.Deployment.prod.web
+--- /tmp/LIVE-1/apps.v1.Deployment.prod.web
++++ /tmp/MERGED-1/apps.v1.Deployment.prod.web
+@@ -10,7 +10,7 @@
+-  replicas: 2
++  replicas: 3
+diff -u -N /tmp/LIVE-1/v1.ConfigMap.prod.app.settings /tmp/MERGED-1/v1.ConfigMap.prod.app.settings
+--- /tmp/LIVE-1/v1.ConfigMap.prod.app.settings
++++ /tmp/MERGED-1/v1.ConfigMap.prod.app.settings
+@@ -0,0 +1,5 @@
++apiVersion: v1
+diff -u -N /tmp/LIVE-1/rbac.authorization.k8s.io.v1.ClusterRole.viewer /tmp/MERGED-1/rbac.authorization.k8s.io.v1.ClusterRole.viewer
+--- /tmp/LIVE-1/rbac.authorization.k8s.io.v1.ClusterRole.viewer
++++ /tmp/MERGED-1/rbac.authorization.k8s.io.v1.ClusterRole.viewer
+@@ -1,3 +1,3 @@
+-  - get
++  - list
+`
+
+type call struct {
+	name string
+	args []string
+}
+
+type fakeRunner struct {
+	calls    []call
+	diffCode int
+	diffOut  string
+	stderr   string
+}
+
+func (f *fakeRunner) run(ctx context.Context, name string, args ...string) (string, string, int, error) {
+	f.calls = append(f.calls, call{name: name, args: args})
+	if name == "kubectl" && contains(args, "diff") {
+		return f.diffOut, f.stderr, f.diffCode, nil
+	}
+	return "apiVersion: v1\nkind: ConfigMap\n", "", 0, nil
+}
+
+func contains(args []string, s string) bool {
+	for _, a := range args {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, env := range []string{"dev", "prod"} {
+		writeFile(t, filepath.Join(dir, "infrastructure", "overlays", env, "kustomization.yaml"), "resources: []\n")
+		writeFile(t, filepath.Join(dir, "applications", "overlays", env, "kustomization.yaml"), "resources: []\n")
+	}
+	return dir
+}
+
+func TestParseDiff(t *testing.T) {
+	diffs := ParseDiff(sampleDiff)
+	require.Len(t, diffs, 3)
+
+	assert.Equal(t, "ClusterRole", diffs[0].Kind)
+	assert.Equal(t, "rbac.authorization.k8s.io", diffs[0].Group)
+	assert.Empty(t, diffs[0].Namespace)
+	assert.Equal(t, "viewer", diffs[0].Name)
+
+	assert.Equal(t, "ConfigMap", diffs[1].Kind)
+	assert.Empty(t, diffs[1].Group)
+	assert.Equal(t, "prod", diffs[1].Namespace)
+	assert.Equal(t, "app.settings", diffs[1].Name)
+	assert.Equal(t, StatusMissing, diffs[1].Status)
+
+	assert.Equal(t, "Deployment", diffs[2].Kind)
+	assert.Equal(t, "apps", diffs[2].Group)
+	assert.Equal(t, "v1", diffs[2].Version)
+	assert.Equal(t, StatusModified, diffs[2].Status)
+	assert.Contains(t, diffs[2].Diff, "+  replicas: 3")
+	assert.Equal(t, "Deployment/prod/web", diffs[2].ID())
+}
+
+func TestParseDiffEmpty(t *testing.T) {
+	assert.Empty(t, ParseDiff(""))
+}
+
+func TestDiscoverTargets(t *testing.T) {
+	dir := newRepo(t)
+
+	targets, err := New(&Options{Path: dir}).DiscoverTargets()
+	require.NoError(t, err)
+	assert.Len(t, targets, 4)
+
+	targets, err = New(&Options{Path: dir, Environment: "prod"}).DiscoverTargets()
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	for _, target := range targets {
+		assert.Equal(t, "kustomize", target.Renderer)
+		assert.True(t, strings.HasSuffix(target.Path, filepath.Join("overlays", "prod")))
+	}
+}
+
+func TestDiscoverTargetsSingleDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Chart.yaml"), "name: app\n")
+
+	targets, err := New(&Options{Path: dir}).DiscoverTargets()
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, "helm", targets[0].Renderer)
+}
+
+func TestDiscoverTargetsNone(t *testing.T) {
+	_, err := New(&Options{Path: t.TempDir()}).DiscoverTargets()
+	assert.Error(t, err)
+
+	_, err = New(&Options{Path: filepath.Join(t.TempDir(), "missing")}).DiscoverTargets()
+	assert.Error(t, err)
+}
+
+func TestRunNoDrift(t *testing.T) {
+	runner := &fakeRunner{}
+	d := New(&Options{Path: newRepo(t), Environment: "dev", Context: "dev-cluster"})
+	d.SetCommandRunner(runner.run)
+
+	report, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.HasDrift())
+	assert.False(t, report.HasErrors())
+	assert.Len(t, report.Targets, 2)
+
+	require.Len(t, runner.calls, 4)
+	assert.Equal(t, []string{"--context", "dev-cluster", "kustomize"}, runner.calls[0].args[:3])
+	assert.Contains(t, runner.calls[1].args, "--server-side=true")
+}
+
+func TestRunDrift(t *testing.T) {
+	runner := &fakeRunner{diffCode: 1, diffOut: sampleDiff}
+	d := New(&Options{Path: filepath.Join(newRepo(t), "applications", "overlays", "prod")})
+	d.SetCommandRunner(runner.run)
+
+	report, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.HasDrift())
+	assert.Equal(t, 3, report.Drifted)
+
+	out, err := report.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, out, `"kind": "Deployment"`)
+}
+
+func TestRunDiffError(t *testing.T) {
+	runner := &fakeRunner{diffCode: 2, stderr: "connection refused"}
+	d := New(&Options{Path: filepath.Join(newRepo(t), "applications", "overlays", "prod")})
+	d.SetCommandRunner(runner.run)
+
+	report, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.HasErrors())
+	assert.Contains(t, report.Targets[0].Error, "connection refused")
+}
+
+func TestRenderHelmWithEnvironmentValues(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Chart.yaml"), "name: app\n")
+	writeFile(t, filepath.Join(dir, "values-prod.yaml"), "replicas: 3\n")
+
+	runner := &fakeRunner{}
+	d := New(&Options{Path: dir, Environment: "prod"})
+	d.SetCommandRunner(runner.run)
+
+	_, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "helm", runner.calls[0].name)
+	assert.Contains(t, runner.calls[0].args, filepath.Join(dir, "values-prod.yaml"))
+}
@@ -0,0 +1,154 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// awsEnv returns the environment of the aws CLI: the access key of the
+// credentials, when set.
+func (c *Client) awsEnv() []string {
+	if c.creds.AccessKeyID == "" {
+		return nil
+	}
+	return []string{"AWS_ACCESS_KEY_ID=" + c.creds.AccessKeyID, "AWS_SECRET_ACCESS_KEY=" + c.creds.SecretAccessKey}
+}
+
+func (c *Client) route53Zone(ctx context.Context) (string, error) {
+	if c.zone.ID != "" {
+		out, err := runCommand(ctx, c.awsEnv(), "aws", "route53", "get-hosted-zone", "--id", c.zone.ID, "--output", "json")
+		if err != nil {
+			return "", err
+		}
+		var zone struct {
+			HostedZone struct{ Name string }
+		}
+		if err := json.Unmarshal([]byte(out), &zone); err != nil {
+			return "", fmt.Errorf("failed to parse hosted zone: %w", err)
+		}
+		if strings.TrimSuffix(zone.HostedZone.Name, ".") != c.zone.Name {
+			return "", fmt.Errorf("hosted zone %s is %s", c.zone.ID, zone.HostedZone.Name)
+		}
+		return c.zone.ID, nil
+	}
+
+	out, err := runCommand(ctx, c.awsEnv(), "aws", "route53", "list-hosted-zones-by-name",
+		"--dns-name", c.zone.Name, "--max-items", "1", "--output", "json")
+	if err != nil {
+		return "", err
+	}
+	var zones struct {
+		HostedZones []struct{ ID, Name string }
+	}
+	if err := json.Unmarshal([]byte(out), &zones); err != nil {
+		return "", fmt.Errorf("failed to parse hosted zones: %w", err)
+	}
+	if len(zones.HostedZones) == 0 || strings.TrimSuffix(zones.HostedZones[0].Name, ".") != c.zone.Name {
+		return "", fmt.Errorf("hosted zone not found")
+	}
+	return strings.TrimPrefix(zones.HostedZones[0].ID, "/hostedzone/"), nil
+}
+
+func (c *Client) route53Upsert(ctx context.Context, zoneID string, r Record) error {
+	batch, err := json.Marshal(map[string]any{
+		"Changes": []any{map[string]any{
+			"Action": "UPSERT",
+			"ResourceRecordSet": map[string]any{
+				"Name":            r.Name,
+				"Type":            r.Type,
+				"TTL":             ttlOrDefault(r.TTL),
+				"ResourceRecords": []any{map[string]string{"Value": r.Target}},
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = runCommand(ctx, c.awsEnv(), "aws", "route53", "change-resource-record-sets",
+		"--hosted-zone-id", zoneID, "--change-batch", string(batch))
+	return err
+}
+
+// gcloud runs a gcloud command of the project, authenticated with the
+// service account key of the credentials when set.
+func (c *Client) gcloud(ctx context.Context, args ...string) (string, error) {
+	args = append(args, "--project", c.zone.Project)
+	if c.creds.Token == "" {
+		return runCommand(ctx, nil, "gcloud", args...)
+	}
+	key, err := os.CreateTemp("", "gitopsi-clouddns-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to write service account key: %w", err)
+	}
+	defer os.Remove(key.Name())
+	if _, err := key.WriteString(c.creds.Token); err != nil {
+		key.Close()
+		return "", fmt.Errorf("failed to write service account key: %w", err)
+	}
+	if err := key.Close(); err != nil {
+		return "", fmt.Errorf("failed to write service account key: %w", err)
+	}
+	return runCommand(ctx, []string{"CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE=" + key.Name()}, "gcloud", args...)
+}
+
+func (c *Client) cloudDNSZone(ctx context.Context) (string, error) {
+	out, err := c.gcloud(ctx, "dns", "managed-zones", "list",
+		"--filter", "dnsName="+c.zone.Name+".", "--format", "value(name)")
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(strings.SplitN(out, "\n", 2)[0])
+	if name == "" {
+		return "", fmt.Errorf("managed zone not found")
+	}
+	return name, nil
+}
+
+func (c *Client) cloudDNSUpsert(ctx context.Context, zone string, r Record) error {
+	name := r.Name + "."
+	out, err := c.gcloud(ctx, "dns", "record-sets", "list", "--zone", zone,
+		"--name", name, "--type", r.Type, "--format", "value(name)")
+	if err != nil {
+		return err
+	}
+	action := "create"
+	if strings.TrimSpace(out) != "" {
+		action = "update"
+	}
+	target := r.Target
+	if r.Type == "CNAME" {
+		target += "."
+	}
+	_, err = c.gcloud(ctx, "dns", "record-sets", action, name, "--zone", zone,
+		"--type", r.Type, "--rrdatas", target, "--ttl", fmt.Sprint(ttlOrDefault(r.TTL)))
+	return err
+}
+
+func (c *Client) azureDNSZone(ctx context.Context) (string, error) {
+	out, err := runCommand(ctx, nil, "az", "network", "dns", "zone", "show",
+		"--resource-group", c.zone.ResourceGroup, "--name", c.zone.Name, "--query", "id", "--output", "tsv")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (c *Client) azureDNSUpsert(ctx context.Context, r Record) error {
+	args := []string{"network", "dns", "record-set", "cname", "set-record",
+		"--resource-group", c.zone.ResourceGroup, "--zone-name", c.zone.Name,
+		"--record-set-name", c.relativeName(r.Name), "--cname", r.Target}
+	if r.Type == "A" {
+		args = []string{"network", "dns", "record-set", "a", "add-record",
+			"--resource-group", c.zone.ResourceGroup, "--zone-name", c.zone.Name,
+			"--record-set-name", c.relativeName(r.Name), "--ipv4-address", r.Target}
+	}
+	if r.TTL != 0 {
+		args = append(args, "--ttl", fmt.Sprint(r.TTL))
+	}
+	args = append(args, "--output", "none")
+	_, err := runCommand(ctx, nil, "az", args...)
+	return err
+}
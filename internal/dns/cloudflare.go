@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// cloudflareResponse is the envelope of the Cloudflare API responses.
+type cloudflareResponse struct {
+	Success bool
+	Errors  []struct{ Message string }
+	Result  json.RawMessage
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+// cloudflare calls the Cloudflare API and decodes the result into out.
+func (c *Client) cloudflare(ctx context.Context, method, path string, body, out any) error {
+	if c.creds.Token == "" {
+		return fmt.Errorf("cloudflare requires an API token credential")
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.cloudflareURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.creds.Token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the Cloudflare API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to parse Cloudflare response (%s): %w", resp.Status, err)
+	}
+	if !envelope.Success {
+		if len(envelope.Errors) > 0 {
+			return fmt.Errorf("cloudflare: %s", envelope.Errors[0].Message)
+		}
+		return fmt.Errorf("cloudflare: %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, out)
+}
+
+func (c *Client) cloudflareZone(ctx context.Context) (string, error) {
+	if c.zone.ID != "" {
+		var zone struct{ ID, Name string }
+		if err := c.cloudflare(ctx, http.MethodGet, "/zones/"+c.zone.ID, nil, &zone); err != nil {
+			return "", err
+		}
+		if zone.Name != c.zone.Name {
+			return "", fmt.Errorf("zone %s is %s", c.zone.ID, zone.Name)
+		}
+		return zone.ID, nil
+	}
+	var zones []struct{ ID string }
+	if err := c.cloudflare(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(c.zone.Name), nil, &zones); err != nil {
+		return "", err
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone not found")
+	}
+	return zones[0].ID, nil
+}
+
+func (c *Client) cloudflareUpsert(ctx context.Context, zoneID string, r Record) error {
+	var existing []cloudflareRecord
+	query := fmt.Sprintf("/zones/%s/dns_records?type=%s&name=%s", zoneID, r.Type, url.QueryEscape(r.Name))
+	if err := c.cloudflare(ctx, http.MethodGet, query, nil, &existing); err != nil {
+		return err
+	}
+	ttl := r.TTL
+	if ttl == 0 {
+		ttl = 1 // automatic
+	}
+	record := cloudflareRecord{Type: r.Type, Name: r.Name, Content: r.Target, TTL: ttl}
+	if len(existing) > 0 {
+		return c.cloudflare(ctx, http.MethodPut, fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, existing[0].ID), record, nil)
+	}
+	return c.cloudflare(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", zoneID), record, nil)
+}
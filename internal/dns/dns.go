@@ -0,0 +1,172 @@
+// Package dns checks the DNS zone of the application hostnames and creates
+// their records for clusters without external-dns, with the aws, gcloud and
+// az CLIs and the Cloudflare API.
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Providers.
+const (
+	ProviderRoute53    = "route53"
+	ProviderCloudflare = "cloudflare"
+	ProviderCloudDNS   = "clouddns"
+	ProviderAzureDNS   = "azuredns"
+)
+
+// defaultTTL is the TTL of records without one on the providers requiring it.
+const defaultTTL = 300
+
+// Zone is the DNS zone of a provider.
+type Zone struct {
+	Provider string
+	// Name is the domain of the zone, such as example.com
+	Name string
+	// ID is the Route 53 hosted zone ID or the Cloudflare zone ID; empty
+	// looks the zone up by name
+	ID            string
+	Project       string
+	ResourceGroup string
+}
+
+// Credentials authenticate to the provider. Empty credentials use the login
+// of the cloud CLI.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// Token is the Cloudflare API token or the Google Cloud service account
+	// key JSON
+	Token string
+}
+
+// Record is the DNS record of a hostname.
+type Record struct {
+	Name   string `json:"name" yaml:"name"`
+	Type   string `json:"type" yaml:"type"`
+	Target string `json:"target" yaml:"target"`
+	TTL    int    `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// NewRecord returns the record pointing name at target: an A record for IP
+// addresses, a CNAME otherwise.
+func NewRecord(name, target string, ttl int) Record {
+	typ := "CNAME"
+	if net.ParseIP(target) != nil {
+		typ = "A"
+	}
+	return Record{Name: strings.TrimSuffix(name, "."), Type: typ, Target: strings.TrimSuffix(target, "."), TTL: ttl}
+}
+
+// runCommand runs a cloud CLI command with extra environment variables.
+var runCommand = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// Client manages the records of a zone.
+type Client struct {
+	zone  Zone
+	creds Credentials
+	// cloudflareURL is the Cloudflare API endpoint
+	cloudflareURL string
+	httpClient    *http.Client
+}
+
+// New creates a Client of zone.
+func New(zone Zone, creds Credentials) *Client {
+	zone.Name = strings.TrimSuffix(zone.Name, ".")
+	return &Client{
+		zone:          zone,
+		creds:         creds,
+		cloudflareURL: "https://api.cloudflare.com/client/v4",
+		httpClient:    http.DefaultClient,
+	}
+}
+
+// Check verifies that the zone exists and the credentials can read it, and
+// returns the zone ID of the provider.
+func (c *Client) Check(ctx context.Context) (string, error) {
+	var (
+		id  string
+		err error
+	)
+	switch c.zone.Provider {
+	case ProviderRoute53:
+		id, err = c.route53Zone(ctx)
+	case ProviderCloudflare:
+		id, err = c.cloudflareZone(ctx)
+	case ProviderCloudDNS:
+		id, err = c.cloudDNSZone(ctx)
+	case ProviderAzureDNS:
+		id, err = c.azureDNSZone(ctx)
+	default:
+		return "", fmt.Errorf("unsupported DNS provider: %s", c.zone.Provider)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read zone %s from %s: %w", c.zone.Name, c.zone.Provider, err)
+	}
+	return id, nil
+}
+
+// Upsert creates the records, or updates them when they exist.
+func (c *Client) Upsert(ctx context.Context, records []Record) error {
+	for _, r := range records {
+		if !c.inZone(r.Name) {
+			return fmt.Errorf("record %s is not in zone %s", r.Name, c.zone.Name)
+		}
+	}
+	id, err := c.Check(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		switch c.zone.Provider {
+		case ProviderRoute53:
+			err = c.route53Upsert(ctx, id, r)
+		case ProviderCloudflare:
+			err = c.cloudflareUpsert(ctx, id, r)
+		case ProviderCloudDNS:
+			err = c.cloudDNSUpsert(ctx, id, r)
+		case ProviderAzureDNS:
+			err = c.azureDNSUpsert(ctx, r)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write record %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) inZone(name string) bool {
+	return name == c.zone.Name || strings.HasSuffix(name, "."+c.zone.Name)
+}
+
+// relativeName returns the name of a record relative to the zone, @ for the
+// apex.
+func (c *Client) relativeName(name string) string {
+	if name == c.zone.Name {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+c.zone.Name)
+}
+
+func ttlOrDefault(ttl int) int {
+	if ttl == 0 {
+		return defaultTTL
+	}
+	return ttl
+}
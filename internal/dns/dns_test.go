@@ -0,0 +1,189 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeCommands replaces the cloud CLI commands: commands starting with a key
+// of outputs return its output, or fail when it starts with "error:".
+func fakeCommands(t *testing.T, outputs map[string]string) (*[]string, *[][]string) {
+	t.Helper()
+	var calls []string
+	var envs [][]string
+	orig := runCommand
+	runCommand = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+		call := strings.Join(append([]string{name}, args...), " ")
+		calls = append(calls, call)
+		envs = append(envs, env)
+		for prefix, output := range outputs {
+			if strings.HasPrefix(call, prefix) {
+				if strings.HasPrefix(output, "error:") {
+					return "", fmt.Errorf("%s", output)
+				}
+				return output, nil
+			}
+		}
+		return "", nil
+	}
+	t.Cleanup(func() { runCommand = orig })
+	return &calls, &envs
+}
+
+func TestNewRecord(t *testing.T) {
+	if r := NewRecord("api.example.com.", "lb.elb.amazonaws.com", 60); r.Type != "CNAME" || r.Name != "api.example.com" || r.TTL != 60 {
+		t.Errorf("NewRecord() = %+v, want a CNAME", r)
+	}
+	if r := NewRecord("api.example.com", "203.0.113.10", 0); r.Type != "A" {
+		t.Errorf("NewRecord() = %+v, want an A record", r)
+	}
+}
+
+func TestRoute53(t *testing.T) {
+	calls, envs := fakeCommands(t, map[string]string{
+		"aws route53 list-hosted-zones-by-name": `{"HostedZones": [{"Id": "/hostedzone/Z123", "Name": "example.com."}]}`,
+	})
+	client := New(Zone{Provider: ProviderRoute53, Name: "example.com"}, Credentials{AccessKeyID: "AKIA", SecretAccessKey: "secret"})
+
+	id, err := client.Check(context.Background())
+	if err != nil || id != "Z123" {
+		t.Fatalf("Check() = %q, %v", id, err)
+	}
+	if got := (*envs)[0]; len(got) != 2 || got[0] != "AWS_ACCESS_KEY_ID=AKIA" {
+		t.Errorf("aws env = %v, want the access key", got)
+	}
+
+	if err := client.Upsert(context.Background(), []Record{NewRecord("api.example.com", "lb.example.net", 0)}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	last := (*calls)[len(*calls)-1]
+	for _, want := range []string{"change-resource-record-sets", "--hosted-zone-id Z123", `"Action":"UPSERT"`, `"TTL":300`, `"Type":"CNAME"`, `"Value":"lb.example.net"`} {
+		if !strings.Contains(last, want) {
+			t.Errorf("upsert call %q does not contain %q", last, want)
+		}
+	}
+
+	fakeCommands(t, map[string]string{"aws route53 list-hosted-zones-by-name": `{"HostedZones": [{"Id": "/hostedzone/Z9", "Name": "other.com."}]}`})
+	if _, err := client.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "hosted zone not found") {
+		t.Errorf("Check() error = %v, want the zone to be missing", err)
+	}
+}
+
+func TestRoute53ZoneID(t *testing.T) {
+	fakeCommands(t, map[string]string{"aws route53 get-hosted-zone --id Z123": `{"HostedZone": {"Name": "example.org."}}`})
+	client := New(Zone{Provider: ProviderRoute53, Name: "example.com", ID: "Z123"}, Credentials{})
+	if _, err := client.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "hosted zone Z123 is example.org.") {
+		t.Errorf("Check() error = %v, want the zone ID to be of another domain", err)
+	}
+}
+
+func TestCloudDNS(t *testing.T) {
+	calls, envs := fakeCommands(t, map[string]string{
+		"gcloud dns managed-zones list":                             "example-com\n",
+		"gcloud dns record-sets list --zone example-com --name www": "www.example.com.\n",
+	})
+	client := New(Zone{Provider: ProviderCloudDNS, Name: "example.com", Project: "shop"}, Credentials{Token: `{"type": "service_account"}`})
+
+	records := []Record{NewRecord("api.example.com", "lb.example.net", 0), NewRecord("www.example.com", "203.0.113.10", 60)}
+	if err := client.Upsert(context.Background(), records); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	want := []string{
+		"gcloud dns record-sets create api.example.com. --zone example-com --type CNAME --rrdatas lb.example.net. --ttl 300 --project shop",
+		"gcloud dns record-sets update www.example.com. --zone example-com --type A --rrdatas 203.0.113.10 --ttl 60 --project shop",
+	}
+	for _, w := range want {
+		found := false
+		for _, call := range *calls {
+			found = found || call == w
+		}
+		if !found {
+			t.Errorf("missing call %q in %v", w, *calls)
+		}
+	}
+	if env := (*envs)[0]; len(env) != 1 || !strings.HasPrefix(env[0], "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE=") {
+		t.Errorf("gcloud env = %v, want the service account key", env)
+	}
+}
+
+func TestAzureDNS(t *testing.T) {
+	calls, _ := fakeCommands(t, map[string]string{"az network dns zone show": "/subscriptions/sub/zones/example.com\n"})
+	client := New(Zone{Provider: ProviderAzureDNS, Name: "example.com", ResourceGroup: "dns"}, Credentials{})
+
+	records := []Record{NewRecord("api.dev.example.com", "lb.example.net", 0), NewRecord("example.com", "203.0.113.10", 0)}
+	if err := client.Upsert(context.Background(), records); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if got := (*calls)[1]; !strings.Contains(got, "cname set-record --resource-group dns --zone-name example.com --record-set-name api.dev --cname lb.example.net") {
+		t.Errorf("cname call = %q", got)
+	}
+	if got := (*calls)[2]; !strings.Contains(got, "a add-record --resource-group dns --zone-name example.com --record-set-name @ --ipv4-address 203.0.113.10") {
+		t.Errorf("a call = %q", got)
+	}
+}
+
+func TestUpsertOutsideZone(t *testing.T) {
+	calls, _ := fakeCommands(t, nil)
+	client := New(Zone{Provider: ProviderAzureDNS, Name: "example.com", ResourceGroup: "dns"}, Credentials{})
+	err := client.Upsert(context.Background(), []Record{NewRecord("api.notexample.com", "lb.example.net", 0)})
+	if err == nil || !strings.Contains(err.Error(), "not in zone example.com") {
+		t.Errorf("Upsert() error = %v, want the record to be rejected", err)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("no command should run, got %v", *calls)
+	}
+}
+
+func TestCloudflare(t *testing.T) {
+	var writes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			_, _ = io.WriteString(w, `{"success": false, "errors": [{"message": "Invalid API Token"}]}`)
+			return
+		}
+		switch {
+		case r.URL.Path == "/zones":
+			_, _ = io.WriteString(w, `{"success": true, "result": [{"id": "zone1"}]}`)
+		case r.Method == http.MethodGet && r.URL.Query().Get("name") == "www.example.com":
+			_, _ = io.WriteString(w, `{"success": true, "result": [{"id": "rec1"}]}`)
+		case r.Method == http.MethodGet:
+			_, _ = io.WriteString(w, `{"success": true, "result": []}`)
+		default:
+			var record cloudflareRecord
+			_ = json.NewDecoder(r.Body).Decode(&record)
+			writes = append(writes, fmt.Sprintf("%s %s %s %s %d", r.Method, r.URL.Path, record.Type, record.Content, record.TTL))
+			_, _ = io.WriteString(w, `{"success": true, "result": {}}`)
+		}
+	}))
+	defer server.Close()
+
+	client := New(Zone{Provider: ProviderCloudflare, Name: "example.com"}, Credentials{Token: "token"})
+	client.cloudflareURL = server.URL
+	records := []Record{NewRecord("api.example.com", "lb.example.net", 0), NewRecord("www.example.com", "203.0.113.10", 120)}
+	if err := client.Upsert(context.Background(), records); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	want := []string{
+		"POST /zones/zone1/dns_records CNAME lb.example.net 1",
+		"PUT /zones/zone1/dns_records/rec1 A 203.0.113.10 120",
+	}
+	if fmt.Sprint(writes) != fmt.Sprint(want) {
+		t.Errorf("writes = %v, want %v", writes, want)
+	}
+
+	client = New(Zone{Provider: ProviderCloudflare, Name: "example.com"}, Credentials{Token: "wrong"})
+	client.cloudflareURL = server.URL
+	if _, err := client.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "Invalid API Token") {
+		t.Errorf("Check() error = %v, want the token to be rejected", err)
+	}
+	client = New(Zone{Provider: ProviderCloudflare, Name: "example.com"}, Credentials{})
+	if _, err := client.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "requires an API token") {
+		t.Errorf("Check() error = %v, want a token to be required", err)
+	}
+}
@@ -0,0 +1,333 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+// DefaultChecks returns the built-in checks for the given GitOps tool.
+func DefaultChecks(gitopsTool string) []Check {
+	usesFlux := gitopsTool == "flux" || gitopsTool == "both"
+
+	checks := []Check{
+		&BinaryCheck{Binary: "git", VersionArgs: []string{"--version"}, Required: true},
+		&BinaryCheck{Binary: "kubectl", VersionArgs: []string{"version", "--client"}, Required: true},
+		&BinaryCheck{Binary: "helm", VersionArgs: []string{"version", "--short"}},
+	}
+	if usesFlux {
+		checks = append(checks, &BinaryCheck{Binary: "flux", VersionArgs: []string{"version", "--client"}, Required: true})
+	}
+
+	checks = append(checks,
+		&ConnectivityCheck{},
+		&RBACCheck{Permissions: BootstrapPermissions},
+	)
+
+	if gitopsTool == "argocd" || gitopsTool == "both" {
+		checks = append(checks, &GitOpsHealthCheck{Tool: "argocd"})
+	}
+	if usesFlux {
+		checks = append(checks, &GitOpsHealthCheck{Tool: "flux"})
+	}
+
+	return append(checks, &CredentialStoreCheck{})
+}
+
+// BinaryCheck verifies that a CLI tool is installed.
+type BinaryCheck struct {
+	Binary      string
+	VersionArgs []string
+	// Required makes a missing binary a failure instead of a warning.
+	Required bool
+}
+
+func (c *BinaryCheck) Name() string       { return c.Binary + " CLI" }
+func (c *BinaryCheck) Category() Category { return CategoryPrerequisites }
+
+func (c *BinaryCheck) Run(ctx context.Context, env *Env) Result {
+	path, err := env.LookPath(c.Binary)
+	if err != nil {
+		status := StatusWarn
+		if c.Required {
+			status = StatusFail
+		}
+		return Result{Status: status, Message: "Not found in PATH"}
+	}
+
+	version := "installed"
+	if len(c.VersionArgs) > 0 {
+		if out, err := env.Run(ctx, c.Binary, c.VersionArgs...); err == nil {
+			if line := firstLine(string(out)); line != "" {
+				version = line
+			}
+		}
+	}
+
+	return Result{Status: StatusOK, Message: version, Details: path}
+}
+
+// ConnectivityCheck verifies that the cluster API is reachable. When it
+// fails, checks implementing ClusterCheck are skipped.
+type ConnectivityCheck struct{}
+
+func (c *ConnectivityCheck) Name() string       { return "Cluster connectivity" }
+func (c *ConnectivityCheck) Category() Category { return CategoryCluster }
+func (c *ConnectivityCheck) NeedsCluster() bool { return true }
+
+func (c *ConnectivityCheck) Run(ctx context.Context, env *Env) Result {
+	out, err := env.Kubectl(ctx, "cluster-info")
+	if err != nil {
+		return Result{Status: StatusFail, Message: "Cannot connect to cluster", Details: strings.TrimSpace(string(out))}
+	}
+
+	return Result{Status: StatusOK, Message: "Connected", Details: firstLine(string(out))}
+}
+
+// Permission is a verb/resource pair checked with `kubectl auth can-i`.
+type Permission struct {
+	Verb     string
+	Resource string
+}
+
+// BootstrapPermissions are the permissions needed to bootstrap a GitOps tool.
+var BootstrapPermissions = []Permission{
+	{"create", "namespaces"},
+	{"create", "customresourcedefinitions"},
+	{"create", "clusterroles"},
+	{"create", "clusterrolebindings"},
+	{"create", "deployments"},
+	{"create", "services"},
+	{"create", "secrets"},
+	{"create", "configmaps"},
+}
+
+// RBACCheck verifies that the current identity holds the given permissions.
+type RBACCheck struct {
+	Permissions []Permission
+}
+
+func (c *RBACCheck) Name() string       { return "Bootstrap permissions" }
+func (c *RBACCheck) Category() Category { return CategoryRBAC }
+func (c *RBACCheck) NeedsCluster() bool { return true }
+
+func (c *RBACCheck) Run(ctx context.Context, env *Env) Result {
+	var missing []string
+	for _, p := range c.Permissions {
+		out, _ := env.Kubectl(ctx, "auth", "can-i", p.Verb, p.Resource)
+		if strings.TrimSpace(strings.ToLower(string(out))) != "yes" {
+			missing = append(missing, p.Verb+" "+p.Resource)
+		}
+	}
+
+	if len(missing) > 0 {
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("Missing %d of %d permissions", len(missing), len(c.Permissions)),
+			Details: strings.Join(missing, ", "),
+		}
+	}
+
+	return Result{Status: StatusOK, Message: fmt.Sprintf("All %d permissions granted", len(c.Permissions))}
+}
+
+// GitOpsHealthCheck verifies that the GitOps tool controllers are running.
+type GitOpsHealthCheck struct {
+	Tool string
+}
+
+func (c *GitOpsHealthCheck) Name() string {
+	if c.Tool == "flux" {
+		return "Flux health"
+	}
+	return "ArgoCD health"
+}
+
+func (c *GitOpsHealthCheck) Category() Category { return CategoryGitOps }
+func (c *GitOpsHealthCheck) NeedsCluster() bool { return true }
+
+func (c *GitOpsHealthCheck) Run(ctx context.Context, env *Env) Result {
+	namespace, deployments := c.locate(ctx, env)
+	if namespace == "" {
+		return Result{Status: StatusWarn, Message: "Not installed", Details: "Run gitopsi init --bootstrap to install it"}
+	}
+
+	var notReady []string
+	for _, deploy := range deployments {
+		out, err := env.Kubectl(ctx, "get", "deployment", deploy, "-n", namespace, "-o", "jsonpath={.status.availableReplicas}")
+		replicas := strings.TrimSpace(string(out))
+		if err != nil || replicas == "" || replicas == "0" {
+			notReady = append(notReady, deploy)
+		}
+	}
+
+	running := len(deployments) - len(notReady)
+	switch {
+	case running == 0:
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("Not running (0/%d components) in %s", len(deployments), namespace),
+			Details: strings.Join(notReady, ", "),
+		}
+	case len(notReady) > 0:
+		return Result{
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("Degraded (%d/%d components) in %s", running, len(deployments), namespace),
+			Details: "Not ready: " + strings.Join(notReady, ", "),
+		}
+	}
+
+	return Result{Status: StatusOK, Message: fmt.Sprintf("Healthy (%d/%d components) in %s", running, len(deployments), namespace)}
+}
+
+// gitopsInstall describes where a GitOps tool's controllers are deployed.
+type gitopsInstall struct {
+	namespace   string
+	deployments []string
+}
+
+var gitopsInstalls = map[string][]gitopsInstall{
+	"argocd": {
+		{"openshift-gitops", []string{"openshift-gitops-server", "openshift-gitops-repo-server", "openshift-gitops-applicationset-controller"}},
+		{"argocd", []string{"argocd-server", "argocd-repo-server", "argocd-applicationset-controller"}},
+	},
+	"flux": {
+		{"flux-system", []string{"source-controller", "kustomize-controller", "helm-controller"}},
+	},
+}
+
+// locate returns the namespace and controller deployments of the installed
+// tool, or an empty namespace if it is not installed.
+func (c *GitOpsHealthCheck) locate(ctx context.Context, env *Env) (namespace string, deployments []string) {
+	for _, install := range gitopsInstalls[c.Tool] {
+		if _, err := env.Kubectl(ctx, "get", "namespace", install.namespace); err == nil {
+			return install.namespace, install.deployments
+		}
+	}
+	return "", nil
+}
+
+// CredentialStoreCheck verifies that the credential store of the auth.store
+// backend is readable, that a file store has owner-only permissions and that
+// every stored credential is usable.
+type CredentialStoreCheck struct{}
+
+func (c *CredentialStoreCheck) Name() string       { return "Credential store" }
+func (c *CredentialStoreCheck) Category() Category { return CategoryCredentials }
+
+func (c *CredentialStoreCheck) Run(ctx context.Context, env *Env) Result {
+	keyring := env.StoreBackend == auth.StoreKeyring
+	path := "keyring"
+	var info os.FileInfo
+	if !keyring {
+		path = env.StorePath
+		if path == "" {
+			path = auth.GetDefaultStorePath()
+		}
+
+		var err error
+		info, err = os.Stat(path)
+		if os.IsNotExist(err) {
+			return Result{Status: StatusOK, Message: "No credentials stored", Details: path}
+		}
+		if err != nil {
+			return Result{Status: StatusFail, Message: "Cannot access store", Details: err.Error()}
+		}
+	}
+
+	store, err := auth.OpenStore(env.StoreBackend, path)
+	if errors.Is(err, auth.ErrStoreEncrypted) {
+		return Result{Status: StatusWarn, Message: "Encrypted store is locked", Details: err.Error()}
+	}
+	if err != nil {
+		return Result{Status: StatusFail, Message: "Store is corrupt", Details: err.Error()}
+	}
+
+	creds, err := store.List(ctx, "")
+	if err != nil {
+		return Result{Status: StatusFail, Message: "Cannot list credentials", Details: err.Error()}
+	}
+
+	var problems, warnings []string
+	for _, cred := range creds {
+		if reason := credentialProblem(cred); reason != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", cred.Name, reason))
+		}
+		if cred.Metadata.ExpiresAt != nil && cred.Metadata.ExpiresAt.Before(time.Now()) {
+			warnings = append(warnings, cred.Name+": expired")
+		}
+	}
+
+	if info != nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		warnings = append(warnings, fmt.Sprintf("%s has permissions %o, expected 600", path, info.Mode().Perm()))
+	}
+
+	switch {
+	case len(problems) > 0:
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("%d of %d credentials invalid", len(problems), len(creds)),
+			Details: strings.Join(append(problems, warnings...), "; "),
+		}
+	case len(warnings) > 0:
+		return Result{
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("%d credentials, %d warnings", len(creds), len(warnings)),
+			Details: strings.Join(warnings, "; "),
+		}
+	}
+
+	message := fmt.Sprintf("%d credentials valid", len(creds))
+	if fs, ok := store.(*auth.FileStore); ok && fs.Encrypted() {
+		message += " (encrypted)"
+	}
+	return Result{Status: StatusOK, Message: message, Details: path}
+}
+
+// credentialProblem returns why a credential cannot be used, or "" if it is
+// complete for its authentication method.
+func credentialProblem(cred *auth.Credential) string {
+	if cred.Type == "" {
+		return "missing type"
+	}
+
+	switch cred.Method {
+	case auth.MethodToken, auth.MethodOAuth:
+		if cred.Data.Token == "" && cred.Data.ClientSecret == "" {
+			return "missing token"
+		}
+	case auth.MethodSSH:
+		if cred.Data.SSHPrivateKey == "" {
+			return "missing SSH private key"
+		}
+	case auth.MethodBasic:
+		if cred.Data.Username == "" || cred.Data.Password == "" {
+			return "missing username or password"
+		}
+	case auth.MethodAWSIRSA:
+		if cred.Data.AWSRoleARN == "" {
+			return "missing role ARN"
+		}
+	case auth.MethodAzureAAD:
+		if cred.Data.AzureTenantID == "" || cred.Data.AzureClientID == "" {
+			return "missing tenant or client ID"
+		}
+	case "":
+		return "missing method"
+	}
+	return ""
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
@@ -0,0 +1,260 @@
+// Package doctor runs diagnostic checks against the local environment and
+// the target cluster and reports what needs attention before using gitopsi.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Category groups related checks in the report.
+type Category string
+
+const (
+	CategoryPrerequisites Category = "prerequisites"
+	CategoryCluster       Category = "cluster"
+	CategoryRBAC          Category = "rbac"
+	CategoryGitOps        Category = "gitops"
+	CategoryCredentials   Category = "credentials"
+)
+
+// Categories lists categories in report order.
+var Categories = []Category{
+	CategoryPrerequisites,
+	CategoryCluster,
+	CategoryRBAC,
+	CategoryGitOps,
+	CategoryCredentials,
+}
+
+// Result is the outcome of running a check.
+type Result struct {
+	Name     string   `json:"name" yaml:"name"`
+	Category Category `json:"category" yaml:"category"`
+	Status   Status   `json:"status" yaml:"status"`
+	Message  string   `json:"message" yaml:"message"`
+	Details  string   `json:"details,omitempty" yaml:"details,omitempty"`
+	Duration string   `json:"duration,omitempty" yaml:"duration,omitempty"`
+}
+
+// Check is a single diagnostic. Implementations must be safe to run with a
+// context that may be cancelled by the per-check timeout.
+type Check interface {
+	// Name returns a short human readable name.
+	Name() string
+	// Category returns the report section the check belongs to.
+	Category() Category
+	// Run executes the check.
+	Run(ctx context.Context, env *Env) Result
+}
+
+// ClusterCheck is implemented by checks that need a reachable cluster.
+// They are skipped when the connectivity check fails.
+type ClusterCheck interface {
+	Check
+	NeedsCluster() bool
+}
+
+// CommandRunner executes an external command and returns its combined output.
+type CommandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// Env carries the shared settings and system hooks used by checks.
+type Env struct {
+	// Kubeconfig is an optional path to a kubeconfig file.
+	Kubeconfig string
+	// Context is an optional kubeconfig context.
+	Context string
+	// GitOpsTool is the tool whose health is checked (argocd, flux, both).
+	GitOpsTool string
+	// StoreBackend is the credential store backend, auth.StoreFile (the
+	// default) or auth.StoreKeyring.
+	StoreBackend string
+	// StorePath is the file store location.
+	StorePath string
+	// Run executes external commands.
+	Run CommandRunner
+	// LookPath resolves binaries on PATH.
+	LookPath func(file string) (string, error)
+}
+
+// KubectlArgs prepends the kubeconfig and context flags to args.
+func (e *Env) KubectlArgs(args ...string) []string {
+	var prefix []string
+	if e.Kubeconfig != "" {
+		prefix = append(prefix, "--kubeconfig", e.Kubeconfig)
+	}
+	if e.Context != "" {
+		prefix = append(prefix, "--context", e.Context)
+	}
+	return append(prefix, args...)
+}
+
+// Kubectl runs kubectl with the configured kubeconfig and context.
+func (e *Env) Kubectl(ctx context.Context, args ...string) ([]byte, error) {
+	return e.Run(ctx, "kubectl", e.KubectlArgs(args...)...)
+}
+
+// Summary counts results by status.
+type Summary struct {
+	OK      int `json:"ok" yaml:"ok"`
+	Warn    int `json:"warn" yaml:"warn"`
+	Fail    int `json:"fail" yaml:"fail"`
+	Skipped int `json:"skipped" yaml:"skipped"`
+}
+
+// Report is the outcome of a doctor run.
+type Report struct {
+	Results []Result `json:"results" yaml:"results"`
+	Summary Summary  `json:"summary" yaml:"summary"`
+}
+
+// HasFailures reports whether any check failed.
+func (r *Report) HasFailures() bool {
+	return r.Summary.Fail > 0
+}
+
+// ByCategory returns the results belonging to a category.
+func (r *Report) ByCategory(category Category) []Result {
+	var results []Result
+	for _, res := range r.Results {
+		if res.Category == category {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// ToJSON renders the report as indented JSON.
+func (r *Report) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Options configures a Doctor.
+type Options struct {
+	Kubeconfig   string
+	Context      string
+	GitOpsTool   string
+	StoreBackend string
+	StorePath    string
+	Timeout      time.Duration
+	SkipCluster  bool
+}
+
+// Doctor runs a set of registered checks.
+type Doctor struct {
+	opts   *Options
+	env    *Env
+	checks []Check
+}
+
+// New creates a Doctor with no checks registered.
+func New(opts *Options) *Doctor {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.GitOpsTool == "" {
+		opts.GitOpsTool = "argocd"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	return &Doctor{
+		opts: opts,
+		env: &Env{
+			Kubeconfig:   opts.Kubeconfig,
+			Context:      opts.Context,
+			GitOpsTool:   opts.GitOpsTool,
+			StoreBackend: opts.StoreBackend,
+			StorePath:    opts.StorePath,
+			Run:          runCommand,
+			LookPath:     exec.LookPath,
+		},
+	}
+}
+
+// SetCommandRunner replaces the command runner (used for testing).
+func (d *Doctor) SetCommandRunner(run CommandRunner) {
+	d.env.Run = run
+}
+
+// SetLookPath replaces the binary lookup function (used for testing).
+func (d *Doctor) SetLookPath(lookPath func(string) (string, error)) {
+	d.env.LookPath = lookPath
+}
+
+// Register adds checks to the doctor. Checks run in registration order.
+func (d *Doctor) Register(checks ...Check) {
+	d.checks = append(d.checks, checks...)
+}
+
+// Checks returns the registered checks.
+func (d *Doctor) Checks() []Check {
+	return d.checks
+}
+
+// Run executes every registered check and returns the report.
+func (d *Doctor) Run(ctx context.Context) *Report {
+	report := &Report{Results: make([]Result, 0, len(d.checks))}
+	clusterReachable := !d.opts.SkipCluster
+
+	for _, check := range d.checks {
+		var result Result
+		if cc, ok := check.(ClusterCheck); ok && cc.NeedsCluster() && !clusterReachable {
+			reason := "Cluster not reachable"
+			if d.opts.SkipCluster {
+				reason = "Cluster checks disabled"
+			}
+			result = Result{Status: StatusSkip, Message: reason}
+		} else {
+			start := time.Now()
+			checkCtx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+			result = check.Run(checkCtx, d.env)
+			cancel()
+			result.Duration = time.Since(start).Round(time.Millisecond).String()
+		}
+
+		result.Name = check.Name()
+		result.Category = check.Category()
+		report.Results = append(report.Results, result)
+
+		if _, ok := check.(*ConnectivityCheck); ok && result.Status == StatusFail {
+			clusterReachable = false
+		}
+
+		switch result.Status {
+		case StatusOK:
+			report.Summary.OK++
+		case StatusWarn:
+			report.Summary.Warn++
+		case StatusFail:
+			report.Summary.Fail++
+		case StatusSkip:
+			report.Summary.Skipped++
+		}
+	}
+
+	return report
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return logging.CombinedOutput(exec.CommandContext(ctx, name, args...))
+}
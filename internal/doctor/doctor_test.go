@@ -0,0 +1,305 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+// fakeCluster answers kubectl invocations from a table of argument strings.
+type fakeCluster struct {
+	responses map[string]string
+	calls     []string
+}
+
+func (f *fakeCluster) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	key := name + " " + strings.Join(args, " ")
+	f.calls = append(f.calls, key)
+	if out, ok := f.responses[key]; ok {
+		return []byte(out), nil
+	}
+	return []byte("error: not found"), errors.New("exit status 1")
+}
+
+func lookPathAll(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+func newTestDoctor(opts *Options, f *fakeCluster) *Doctor {
+	d := New(opts)
+	d.SetCommandRunner(f.run)
+	d.SetLookPath(lookPathAll)
+	return d
+}
+
+func healthyArgoCD() map[string]string {
+	responses := map[string]string{
+		"kubectl cluster-info":         "Kubernetes control plane is running at https://127.0.0.1:6443\n",
+		"kubectl get namespace argocd": "argocd Active",
+		"git --version":                "git version 2.45.0\n",
+		"kubectl version --client":     "Client Version: v1.30.0\nKustomize Version: v5.0.4\n",
+		"helm version --short":         "v3.17.3+g123\n",
+	}
+	for _, p := range BootstrapPermissions {
+		responses["kubectl auth can-i "+p.Verb+" "+p.Resource] = "yes\n"
+	}
+	for _, deploy := range []string{"argocd-server", "argocd-repo-server", "argocd-applicationset-controller"} {
+		responses["kubectl get deployment "+deploy+" -n argocd -o jsonpath={.status.availableReplicas}"] = "1"
+	}
+	return responses
+}
+
+func TestDoctor_AllHealthy(t *testing.T) {
+	f := &fakeCluster{responses: healthyArgoCD()}
+	d := newTestDoctor(&Options{StorePath: filepath.Join(t.TempDir(), "credentials.yaml")}, f)
+	d.Register(DefaultChecks("argocd")...)
+
+	report := d.Run(context.Background())
+
+	assert.False(t, report.HasFailures())
+	assert.Equal(t, len(d.Checks()), report.Summary.OK)
+	for _, r := range report.Results {
+		assert.NotEmpty(t, r.Name)
+		assert.NotEmpty(t, r.Category)
+	}
+
+	prereqs := report.ByCategory(CategoryPrerequisites)
+	require.Len(t, prereqs, 3)
+	assert.Equal(t, "Client Version: v1.30.0", prereqs[1].Message)
+}
+
+func TestDoctor_ConnectivityFailureSkipsClusterChecks(t *testing.T) {
+	f := &fakeCluster{responses: map[string]string{}}
+	d := newTestDoctor(&Options{StorePath: filepath.Join(t.TempDir(), "credentials.yaml")}, f)
+	d.Register(DefaultChecks("argocd")...)
+
+	report := d.Run(context.Background())
+
+	assert.True(t, report.HasFailures())
+	assert.Equal(t, 1, report.Summary.Fail)
+	assert.Equal(t, 2, report.Summary.Skipped)
+	for _, r := range report.ByCategory(CategoryRBAC) {
+		assert.Equal(t, StatusSkip, r.Status)
+	}
+	for _, call := range f.calls {
+		assert.NotContains(t, call, "auth can-i")
+	}
+}
+
+func TestDoctor_SkipCluster(t *testing.T) {
+	f := &fakeCluster{responses: map[string]string{}}
+	d := newTestDoctor(&Options{SkipCluster: true, StorePath: filepath.Join(t.TempDir(), "credentials.yaml")}, f)
+	d.Register(DefaultChecks("both")...)
+
+	report := d.Run(context.Background())
+
+	assert.False(t, report.HasFailures())
+	assert.Equal(t, 4, report.Summary.Skipped)
+	assert.Equal(t, "Cluster checks disabled", report.ByCategory(CategoryCluster)[0].Message)
+}
+
+func TestDefaultChecks(t *testing.T) {
+	names := func(checks []Check) []string {
+		var out []string
+		for _, c := range checks {
+			out = append(out, c.Name())
+		}
+		return out
+	}
+
+	argocd := names(DefaultChecks("argocd"))
+	assert.Contains(t, argocd, "ArgoCD health")
+	assert.NotContains(t, argocd, "flux CLI")
+	assert.NotContains(t, argocd, "Flux health")
+
+	flux := names(DefaultChecks("flux"))
+	assert.Contains(t, flux, "flux CLI")
+	assert.Contains(t, flux, "Flux health")
+	assert.NotContains(t, flux, "ArgoCD health")
+
+	both := names(DefaultChecks("both"))
+	assert.Contains(t, both, "ArgoCD health")
+	assert.Contains(t, both, "Flux health")
+}
+
+func TestBinaryCheck_Missing(t *testing.T) {
+	env := &Env{LookPath: func(string) (string, error) { return "", errors.New("not found") }}
+
+	assert.Equal(t, StatusFail, (&BinaryCheck{Binary: "kubectl", Required: true}).Run(context.Background(), env).Status)
+	assert.Equal(t, StatusWarn, (&BinaryCheck{Binary: "helm"}).Run(context.Background(), env).Status)
+}
+
+func TestRBACCheck_MissingPermissions(t *testing.T) {
+	f := &fakeCluster{responses: map[string]string{
+		"kubectl --context prod auth can-i create namespaces": "yes",
+		"kubectl --context prod auth can-i create secrets":    "no",
+	}}
+	env := &Env{Context: "prod", Run: f.run}
+	check := &RBACCheck{Permissions: []Permission{{"create", "namespaces"}, {"create", "secrets"}}}
+
+	result := check.Run(context.Background(), env)
+
+	assert.Equal(t, StatusFail, result.Status)
+	assert.Equal(t, "create secrets", result.Details)
+}
+
+func TestGitOpsHealthCheck(t *testing.T) {
+	deployKey := func(deploy string) string {
+		return "kubectl get deployment " + deploy + " -n flux-system -o jsonpath={.status.availableReplicas}"
+	}
+
+	tests := []struct {
+		name      string
+		responses map[string]string
+		want      Status
+	}{
+		{
+			name:      "not installed",
+			responses: map[string]string{},
+			want:      StatusWarn,
+		},
+		{
+			name: "degraded",
+			responses: map[string]string{
+				"kubectl get namespace flux-system": "",
+				deployKey("source-controller"):      "1",
+				deployKey("kustomize-controller"):   "0",
+			},
+			want: StatusWarn,
+		},
+		{
+			name: "down",
+			responses: map[string]string{
+				"kubectl get namespace flux-system": "",
+			},
+			want: StatusFail,
+		},
+		{
+			name: "healthy",
+			responses: map[string]string{
+				"kubectl get namespace flux-system": "",
+				deployKey("source-controller"):      "1",
+				deployKey("kustomize-controller"):   "1",
+				deployKey("helm-controller"):        "2",
+			},
+			want: StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeCluster{responses: tt.responses}
+			result := (&GitOpsHealthCheck{Tool: "flux"}).Run(context.Background(), &Env{Run: f.run})
+			assert.Equal(t, tt.want, result.Status)
+		})
+	}
+}
+
+func TestCredentialStoreCheck(t *testing.T) {
+	write := func(t *testing.T, content string, perm os.FileMode) string {
+		path := filepath.Join(t.TempDir(), "credentials.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), perm))
+		return path
+	}
+
+	t.Run("missing store", func(t *testing.T) {
+		env := &Env{StorePath: filepath.Join(t.TempDir(), "none.yaml")}
+		assert.Equal(t, StatusOK, (&CredentialStoreCheck{}).Run(context.Background(), env).Status)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		path := write(t, "credentials:\n  - name: gh\n    type: git\n    method: token\n    data:\n      token: abc\n", 0600)
+		result := (&CredentialStoreCheck{}).Run(context.Background(), &Env{StorePath: path})
+		assert.Equal(t, StatusOK, result.Status)
+		assert.Equal(t, "1 credentials valid", result.Message)
+	})
+
+	t.Run("encrypted store", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "credentials.yaml")
+		store, err := auth.NewEncryptedFileStore(path, "secret")
+		require.NoError(t, err)
+		require.NoError(t, store.Save(context.Background(), &auth.Credential{
+			Name: "gh", Type: auth.CredentialTypeGit, Method: auth.MethodToken, Data: auth.CredentialData{Token: "abc"},
+		}))
+
+		t.Setenv(auth.PassphraseEnvVar, "")
+		assert.Equal(t, StatusWarn, (&CredentialStoreCheck{}).Run(context.Background(), &Env{StorePath: path}).Status)
+
+		t.Setenv(auth.PassphraseEnvVar, "secret")
+		result := (&CredentialStoreCheck{}).Run(context.Background(), &Env{StorePath: path})
+		assert.Equal(t, StatusOK, result.Status)
+		assert.Equal(t, "1 credentials valid (encrypted)", result.Message)
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		path := write(t, "credentials: [", 0600)
+		assert.Equal(t, StatusFail, (&CredentialStoreCheck{}).Run(context.Background(), &Env{StorePath: path}).Status)
+	})
+
+	t.Run("incomplete credential", func(t *testing.T) {
+		path := write(t, "credentials:\n  - name: gl\n    type: git\n    method: ssh\n", 0600)
+		result := (&CredentialStoreCheck{}).Run(context.Background(), &Env{StorePath: path})
+		assert.Equal(t, StatusFail, result.Status)
+		assert.Contains(t, result.Details, "gl: missing SSH private key")
+	})
+
+	t.Run("loose permissions", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes are not enforced on Windows")
+		}
+		path := write(t, "credentials:\n  - name: gh\n    type: git\n    method: token\n    data:\n      token: abc\n", 0644)
+		require.NoError(t, os.Chmod(path, 0644))
+		result := (&CredentialStoreCheck{}).Run(context.Background(), &Env{StorePath: path})
+		assert.Equal(t, StatusWarn, result.Status)
+		assert.Contains(t, result.Details, "expected 600")
+	})
+
+	t.Run("keyring", func(t *testing.T) {
+		keyring.MockInit()
+		store := auth.NewKeyringStore("")
+		require.NoError(t, store.Save(context.Background(), &auth.Credential{
+			Name: "gh", Type: auth.CredentialTypeGit, Method: auth.MethodToken, Data: auth.CredentialData{Token: "abc"},
+		}))
+		require.NoError(t, store.Save(context.Background(), &auth.Credential{
+			Name: "gl", Type: auth.CredentialTypeGit, Method: auth.MethodSSH,
+		}))
+
+		env := &Env{StoreBackend: auth.StoreKeyring, StorePath: filepath.Join(t.TempDir(), "none.yaml")}
+		result := (&CredentialStoreCheck{}).Run(context.Background(), env)
+		assert.Equal(t, StatusFail, result.Status)
+		assert.Equal(t, "1 of 2 credentials invalid", result.Message)
+		assert.Equal(t, "gl: missing SSH private key", result.Details)
+
+		require.NoError(t, store.Delete(context.Background(), "gl"))
+		result = (&CredentialStoreCheck{}).Run(context.Background(), env)
+		assert.Equal(t, StatusOK, result.Status)
+		assert.Equal(t, "1 credentials valid", result.Message)
+		assert.Equal(t, "keyring", result.Details)
+	})
+}
+
+func TestReport_ToJSON(t *testing.T) {
+	report := &Report{
+		Results: []Result{{Name: "git CLI", Category: CategoryPrerequisites, Status: StatusOK, Message: "git version 2.45.0"}},
+		Summary: Summary{OK: 1},
+	}
+
+	out, err := report.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, out, `"status": "ok"`)
+	assert.Contains(t, out, `"category": "prerequisites"`)
+	assert.Contains(t, out, `"summary"`)
+}
+
+func TestEnv_KubectlArgs(t *testing.T) {
+	env := &Env{Kubeconfig: "/tmp/kc", Context: "dev"}
+	assert.Equal(t, []string{"--kubeconfig", "/tmp/kc", "--context", "dev", "get", "ns"}, env.KubectlArgs("get", "ns"))
+}
@@ -0,0 +1,247 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ApplicationResource is the ArgoCD Application custom resource.
+var ApplicationResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+
+// Application health states reported by WaitForApplications. Missing is
+// also reported for Applications that do not exist.
+const (
+	HealthHealthy     = "Healthy"
+	HealthProgressing = "Progressing"
+	HealthDegraded    = "Degraded"
+	HealthSuspended   = "Suspended"
+	HealthMissing     = "Missing"
+)
+
+// ApplicationState is the status of an ArgoCD Application.
+type ApplicationState struct {
+	Name   string
+	Health string
+	Sync   string
+	// Message explains an unhealthy or failed state: the failed sync
+	// operation, Application conditions and degraded resources.
+	Message string
+}
+
+// Ready reports whether the Application is Synced and Healthy.
+func (s ApplicationState) Ready() bool {
+	return s.Health == HealthHealthy && s.Sync == "Synced"
+}
+
+// NewArgoCDClient returns a client for ArgoCD Applications in the cluster of
+// the kubeconfig context; an empty context uses the current one.
+func NewArgoCDClient(kubeContext string) (dynamic.Interface, error) {
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// WaitForApplications waits until the named Applications are Synced and
+// Healthy, watching them all at once. progress, when set, is called with
+// every status change. After timeout the error is an *ApplicationsNotReadyError
+// describing the Applications that are not ready.
+func WaitForApplications(ctx context.Context, client dynamic.Interface, namespace string, names []string, timeout time.Duration, progress func(ApplicationState)) error {
+	if len(names) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	states := make(map[string]ApplicationState, len(names))
+	for _, name := range names {
+		states[name] = ApplicationState{Name: name, Health: HealthMissing, Message: "the Application does not exist"}
+	}
+	done := make(chan struct{})
+	var closeDone sync.Once
+
+	update := func(state ApplicationState) {
+		mu.Lock()
+		defer mu.Unlock()
+		previous, wanted := states[state.Name]
+		if !wanted || previous == state {
+			return
+		}
+		states[state.Name] = state
+		if progress != nil {
+			progress(state)
+		}
+		for _, s := range states {
+			if !s.Ready() {
+				return
+			}
+		}
+		closeDone.Do(func() { close(done) })
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, namespace, nil)
+	informer := factory.ForResource(ApplicationResource).Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if app, ok := obj.(*unstructured.Unstructured); ok {
+				update(applicationState(app))
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if app, ok := obj.(*unstructured.Unstructured); ok {
+				update(applicationState(app))
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if app, ok := obj.(*unstructured.Unstructured); ok {
+				update(ApplicationState{Name: app.GetName(), Health: HealthMissing, Message: "the Application was deleted"})
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch Applications: %w", err)
+	}
+	stop := make(chan struct{})
+	factory.Start(stop)
+	defer func() {
+		close(stop)
+		factory.Shutdown()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	notReady := &ApplicationsNotReadyError{Namespace: namespace, Timeout: timeout}
+	for _, name := range names {
+		if s := states[name]; !s.Ready() {
+			notReady.Applications = append(notReady.Applications, s)
+		}
+	}
+	if len(notReady.Applications) == 0 {
+		return nil
+	}
+	return notReady
+}
+
+// ApplicationsNotReadyError lists the Applications that were not Synced and
+// Healthy when WaitForApplications timed out.
+type ApplicationsNotReadyError struct {
+	Namespace    string
+	Timeout      time.Duration
+	Applications []ApplicationState
+}
+
+func (e *ApplicationsNotReadyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "timed out after %s waiting for %d Application(s):", e.Timeout, len(e.Applications))
+	for _, s := range e.Applications {
+		status := s.Health
+		if s.Sync != "" {
+			status += "/" + s.Sync
+		}
+		fmt.Fprintf(&b, "\n  %s: %s", s.Name, status)
+		if s.Message != "" {
+			fmt.Fprintf(&b, " - %s", s.Message)
+		}
+		fmt.Fprintf(&b, "\n    %s", e.hint(s))
+	}
+	return b.String()
+}
+
+// hint suggests what to do about an Application that is not ready.
+func (e *ApplicationsNotReadyError) hint(s ApplicationState) string {
+	switch {
+	case s.Health == HealthMissing && s.Sync == "":
+		return fmt.Sprintf("check that the App-of-Apps created it: kubectl get applications.argoproj.io -n %s", e.Namespace)
+	case s.Health == HealthDegraded || s.Health == HealthMissing:
+		return fmt.Sprintf("inspect the failing resources: kubectl describe applications.argoproj.io %s -n %s", s.Name, e.Namespace)
+	case s.Health == HealthProgressing:
+		return "the resources are still rolling out: wait longer or raise the timeout"
+	case s.Sync == "OutOfSync":
+		return fmt.Sprintf("the Application has not synced: enable automated sync or run argocd app sync %s", s.Name)
+	default:
+		return fmt.Sprintf("inspect it: kubectl describe applications.argoproj.io %s -n %s", s.Name, e.Namespace)
+	}
+}
+
+// applicationState reads the status of an Application resource.
+func applicationState(app *unstructured.Unstructured) ApplicationState {
+	state := ApplicationState{Name: app.GetName()}
+	state.Health, _, _ = unstructured.NestedString(app.Object, "status", "health", "status")
+	state.Sync, _, _ = unstructured.NestedString(app.Object, "status", "sync", "status")
+	if state.Health == "" {
+		state.Health = "Unknown"
+	}
+	if state.Sync == "" {
+		state.Sync = "Unknown"
+	}
+
+	var messages []string
+	phase, _, _ := unstructured.NestedString(app.Object, "status", "operationState", "phase")
+	if phase == "Failed" || phase == "Error" {
+		message, _, _ := unstructured.NestedString(app.Object, "status", "operationState", "message")
+		messages = append(messages, fmt.Sprintf("sync %s: %s", strings.ToLower(phase), message))
+	}
+	conditions, _, _ := unstructured.NestedSlice(app.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, _ := c.(map[string]any)
+		if message, _ := condition["message"].(string); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	if state.Health != HealthHealthy {
+		messages = append(messages, unhealthyResources(app)...)
+	}
+	state.Message = strings.Join(messages, "; ")
+	return state
+}
+
+// unhealthyResources describes the Degraded and Missing resources of an
+// Application.
+func unhealthyResources(app *unstructured.Unstructured) []string {
+	resources, _, _ := unstructured.NestedSlice(app.Object, "status", "resources")
+	var out []string
+	for _, r := range resources {
+		resource, _ := r.(map[string]any)
+		health, _ := resource["health"].(map[string]any)
+		status, _ := health["status"].(string)
+		if status != HealthDegraded && status != HealthMissing {
+			continue
+		}
+		description := fmt.Sprintf("%s/%s %s", resource["kind"], resource["name"], status)
+		if message, _ := health["message"].(string); message != "" {
+			description += ": " + message
+		}
+		out = append(out, description)
+	}
+	sort.Strings(out)
+	return out
+}
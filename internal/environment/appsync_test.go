@@ -0,0 +1,110 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func testApplication(name, health, sync string, resources ...any) *unstructured.Unstructured {
+	status := map[string]any{
+		"health": map[string]any{"status": health},
+		"sync":   map[string]any{"status": sync},
+	}
+	if len(resources) > 0 {
+		status["resources"] = resources
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]any{"name": name, "namespace": "argocd"},
+		"status":     status,
+	}}
+}
+
+func newFakeArgoCD(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{ApplicationResource: "ApplicationList"}, objects...)
+}
+
+func TestWaitForApplications(t *testing.T) {
+	client := newFakeArgoCD(
+		testApplication("shop-infra-prod", HealthHealthy, "Synced"),
+		testApplication("shop-apps-prod", HealthProgressing, "OutOfSync"),
+	)
+
+	var mu sync.Mutex
+	var events []string
+	progress := func(s ApplicationState) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, s.Name+"="+s.Health+"/"+s.Sync)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = client.Resource(ApplicationResource).Namespace("argocd").Update(context.Background(),
+			testApplication("shop-apps-prod", HealthHealthy, "Synced"), metav1.UpdateOptions{})
+	}()
+
+	err := WaitForApplications(context.Background(), client, "argocd", []string{"shop-infra-prod", "shop-apps-prod"}, 5*time.Second, progress)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, events, "shop-apps-prod=Progressing/OutOfSync")
+	assert.Contains(t, events, "shop-apps-prod=Healthy/Synced")
+	assert.Contains(t, events, "shop-infra-prod=Healthy/Synced")
+}
+
+func TestWaitForApplications_NotReady(t *testing.T) {
+	client := newFakeArgoCD(
+		testApplication("api-prod", HealthDegraded, "Synced",
+			map[string]any{"kind": "Deployment", "name": "api", "health": map[string]any{"status": "Degraded", "message": "Deployment exceeded its progress deadline"}},
+			map[string]any{"kind": "Service", "name": "api", "health": map[string]any{"status": "Healthy"}},
+		),
+		testApplication("web-prod", HealthProgressing, "Synced"),
+		testApplication("db-prod", HealthHealthy, "Synced"),
+	)
+
+	err := WaitForApplications(context.Background(), client, "argocd", []string{"api-prod", "web-prod", "db-prod", "cache-prod"}, 200*time.Millisecond, nil)
+	var notReady *ApplicationsNotReadyError
+	require.True(t, errors.As(err, &notReady), "error = %v", err)
+	require.Len(t, notReady.Applications, 3)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "waiting for 3 Application(s)")
+	assert.Contains(t, msg, "api-prod: Degraded/Synced - Deployment/api Degraded: Deployment exceeded its progress deadline")
+	assert.Contains(t, msg, "kubectl describe applications.argoproj.io api-prod -n argocd")
+	assert.Contains(t, msg, "web-prod: Progressing/Synced")
+	assert.Contains(t, msg, "raise the timeout")
+	assert.Contains(t, msg, "cache-prod: Missing - the Application does not exist")
+	assert.Contains(t, msg, "check that the App-of-Apps created it")
+	assert.NotContains(t, msg, "db-prod")
+	assert.NotContains(t, msg, "Service/api")
+}
+
+func TestApplicationState_SyncFailed(t *testing.T) {
+	app := testApplication("api-prod", HealthMissing, "OutOfSync")
+	app.Object["status"].(map[string]any)["operationState"] = map[string]any{
+		"phase":   "Failed",
+		"message": "one or more objects failed to apply",
+	}
+	app.Object["status"].(map[string]any)["conditions"] = []any{
+		map[string]any{"type": "ComparisonError", "message": "Unable to load data"},
+	}
+
+	state := applicationState(app)
+	assert.False(t, state.Ready())
+	assert.Equal(t, "sync failed: one or more objects failed to apply; Unable to load data", state.Message)
+}
@@ -0,0 +1,103 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+// ArgoCDApplicationStatus reads the status of an ArgoCD Application with kubectl.
+func ArgoCDApplicationStatus(ctx context.Context, kubeContext, namespace, name string) (string, string, error) {
+	output, err := kubectl(ctx, kubeContext, "get", "applications.argoproj.io", name, "-n", namespace,
+		"-o", "jsonpath={.status.health.status}/{.status.sync.status}")
+	if err != nil {
+		return "", "", err
+	}
+	health, sync, _ := strings.Cut(output, "/")
+	return health, sync, nil
+}
+
+// SyncArgoCDApplication refreshes an ArgoCD Application from Git and starts a
+// sync operation.
+func SyncArgoCDApplication(ctx context.Context, kubeContext, namespace, name string) error {
+	if _, err := kubectl(ctx, kubeContext, "annotate", "applications.argoproj.io", name, "-n", namespace,
+		"argocd.argoproj.io/refresh=hard", "--overwrite"); err != nil {
+		return fmt.Errorf("failed to refresh Application %s: %w", name, err)
+	}
+	patch := `{"operation":{"initiatedBy":{"username":"gitopsi"},"sync":{"syncStrategy":{"hook":{}}}}}`
+	if _, err := kubectl(ctx, kubeContext, "patch", "applications.argoproj.io", name, "-n", namespace,
+		"--type", "merge", "-p", patch); err != nil {
+		return fmt.Errorf("failed to sync Application %s: %w", name, err)
+	}
+	return nil
+}
+
+// resourcesFinalizer makes ArgoCD delete the resources of an Application
+// before the Application itself.
+const resourcesFinalizer = "resources-finalizer.argocd.argoproj.io"
+
+// DeleteArgoCDApplication deletes an ArgoCD Application. With cascade the
+// resources it deployed are deleted with it, otherwise they are left running.
+// Missing Applications are ignored.
+func DeleteArgoCDApplication(ctx context.Context, kubeContext, namespace, name string, cascade bool) error {
+	output, err := kubectl(ctx, kubeContext, "get", "applications.argoproj.io", name, "-n", namespace, "--ignore-not-found", "-o", "name")
+	if err != nil {
+		return fmt.Errorf("failed to get Application %s: %w", name, err)
+	}
+	if output == "" {
+		return nil
+	}
+	patch := `{"metadata":{"finalizers":null}}`
+	if cascade {
+		patch = fmt.Sprintf(`{"metadata":{"finalizers":[%q]}}`, resourcesFinalizer)
+	}
+	if _, err := kubectl(ctx, kubeContext, "patch", "applications.argoproj.io", name, "-n", namespace,
+		"--type", "merge", "-p", patch); err != nil {
+		return fmt.Errorf("failed to set the finalizers of Application %s: %w", name, err)
+	}
+	if _, err := kubectl(ctx, kubeContext, "delete", "applications.argoproj.io", name, "-n", namespace, "--ignore-not-found"); err != nil {
+		return fmt.Errorf("failed to delete Application %s: %w", name, err)
+	}
+	return nil
+}
+
+func kubectl(ctx context.Context, kubeContext string, args ...string) (string, error) {
+	if kubeContext != "" {
+		args = append(args, "--context", kubeContext)
+	}
+	output, err := logging.CombinedOutput(exec.CommandContext(ctx, "kubectl", args...))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DeleteArgoCDApplicationSet deletes an ArgoCD ApplicationSet; ArgoCD deletes
+// the Applications it generated. Missing ApplicationSets are ignored.
+func DeleteArgoCDApplicationSet(ctx context.Context, kubeContext, namespace, name string) error {
+	if _, err := kubectl(ctx, kubeContext, "delete", "applicationsets.argoproj.io", name, "-n", namespace, "--ignore-not-found"); err != nil {
+		return fmt.Errorf("failed to delete ApplicationSet %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteArgoCDCluster deletes the secret registering a cluster in ArgoCD.
+// Missing secrets are ignored.
+func DeleteArgoCDCluster(ctx context.Context, kubeContext, namespace, name string) error {
+	if _, err := kubectl(ctx, kubeContext, "delete", "secret", name, "-n", namespace, "--ignore-not-found"); err != nil {
+		return fmt.Errorf("failed to delete cluster secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteNamespace deletes a namespace and everything in it. Missing
+// namespaces are ignored.
+func DeleteNamespace(ctx context.Context, kubeContext, name string) error {
+	if _, err := kubectl(ctx, kubeContext, "delete", "namespace", name, "--ignore-not-found", "--wait=false"); err != nil {
+		return fmt.Errorf("failed to delete namespace %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubectl puts a kubectl on PATH that logs its arguments and prints
+// getOutput for get.
+func fakeKubectl(t *testing.T, getOutput string) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" >> %s\nif [ \"$1\" = get ]; then printf '%s'; fi\n", log, getOutput)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestDeleteArgoCDApplication(t *testing.T) {
+	log := fakeKubectl(t, "application.argoproj.io/monitoring-dev")
+	require.NoError(t, DeleteArgoCDApplication(context.Background(), "prod", "argocd", "monitoring-dev", true))
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[1], `patch applications.argoproj.io monitoring-dev -n argocd --type merge -p {"metadata":{"finalizers":["resources-finalizer.argocd.argoproj.io"]}} --context prod`)
+	assert.Contains(t, lines[2], "delete applications.argoproj.io monitoring-dev -n argocd --ignore-not-found --context prod")
+
+	log = fakeKubectl(t, "")
+	require.NoError(t, DeleteArgoCDApplication(context.Background(), "", "argocd", "monitoring-dev", false))
+	calls, err = os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(calls), "\n"), "missing Applications are not deleted")
+}
+
+func TestDeleteArgoCDTeardown(t *testing.T) {
+	log := fakeKubectl(t, "")
+	ctx := context.Background()
+	require.NoError(t, DeleteArgoCDApplicationSet(ctx, "hub", "argocd", "demo-apps-prod"))
+	require.NoError(t, DeleteArgoCDCluster(ctx, "hub", "argocd", "prod-eu"))
+	require.NoError(t, DeleteNamespace(ctx, "prod", "demo-prod"))
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, `delete applicationsets.argoproj.io demo-apps-prod -n argocd --ignore-not-found --context hub
+delete secret prod-eu -n argocd --ignore-not-found --context hub
+delete namespace demo-prod --ignore-not-found --wait=false --context prod
+`, string(calls))
+}
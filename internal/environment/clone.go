@@ -0,0 +1,257 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CloneOptions configures CloneEnvironment.
+type CloneOptions struct {
+	// Namespace replaces the namespace of the source environment. By
+	// default the source namespace is renamed like the environment, e.g.
+	// demo-prod becomes demo-staging.
+	Namespace string
+	// Rewrites are literal replacements, such as ingress hosts, applied to
+	// the copied files before the namespace and environment name.
+	Rewrites []Rewrite
+	DryRun   bool
+}
+
+// Rewrite replaces From with To.
+type Rewrite struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// CloneResult lists the files written by CloneEnvironment.
+type CloneResult struct {
+	Environment string `json:"environment" yaml:"environment"`
+	From        string `json:"from" yaml:"from"`
+	Namespace   string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Files are the files created for the new environment.
+	Files []string `json:"files" yaml:"files"`
+	// Updated are the existing files the environment was added to:
+	// kustomizations listing per-environment resources and multi-cluster
+	// ApplicationSets.
+	Updated []string `json:"updated,omitempty" yaml:"updated,omitempty"`
+	DryRun  bool     `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// CloneEnvironment creates the environment name by copying the overlays,
+// HelmReleases and ArgoCD and Flux resources of from, with the environment
+// name, namespace and opts.Rewrites replaced. The environment is added to the
+// multi-cluster ApplicationSets listing from, and registered in the
+// environment config when from is.
+func (m *Manager) CloneEnvironment(name, from string, opts CloneOptions) (*CloneResult, error) {
+	if name == from {
+		return nil, fmt.Errorf("cannot clone environment %s into itself", from)
+	}
+	srcFiles, err := m.envFiles(from)
+	if err != nil {
+		return nil, err
+	}
+	if len(srcFiles) == 0 {
+		return nil, fmt.Errorf("environment %s has no files in the project", from)
+	}
+	existing, err := m.envFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 || m.config.HasEnvironment(name) {
+		return nil, fmt.Errorf("environment %s already exists", name)
+	}
+
+	srcNamespace := m.envNamespace(from)
+	if opts.Namespace != "" && srcNamespace == "" {
+		return nil, fmt.Errorf("cannot determine the namespace of environment %s", from)
+	}
+	rewrite := func(s string) string {
+		for _, r := range opts.Rewrites {
+			s = strings.ReplaceAll(s, r.From, r.To)
+		}
+		if opts.Namespace != "" {
+			s = replaceToken(s, srcNamespace, opts.Namespace)
+		}
+		return replaceToken(s, from, name)
+	}
+
+	result := &CloneResult{Environment: name, From: from, Files: []string{}, DryRun: opts.DryRun}
+	if srcNamespace != "" {
+		result.Namespace = rewrite(srcNamespace)
+	}
+	writes := map[string][]byte{}
+
+	for key, src := range srcFiles {
+		data, err := os.ReadFile(filepath.Join(m.projectPath, src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		dst := strings.ReplaceAll(key, envPlaceholder, name)
+		writes[dst] = []byte(rewrite(string(data)))
+		result.Files = append(result.Files, dst)
+
+		if dir := path.Dir(src); path.Dir(dir) == infrastructureBaseDir {
+			updated, content, err := m.addKustomizationResource(dir, path.Base(src), path.Base(dst))
+			if err != nil {
+				return nil, err
+			}
+			if updated {
+				writes[path.Join(dir, "kustomization.yaml")] = content
+				result.Updated = append(result.Updated, path.Join(dir, "kustomization.yaml"))
+			}
+		}
+	}
+
+	appSets, err := m.addMultiClusterElements(name, from, rewrite)
+	if err != nil {
+		return nil, err
+	}
+	for rel, content := range appSets {
+		writes[rel] = content
+		result.Updated = append(result.Updated, rel)
+	}
+	sort.Strings(result.Files)
+	sort.Strings(result.Updated)
+
+	if opts.DryRun {
+		return result, nil
+	}
+	for rel, content := range writes {
+		file := filepath.Join(m.projectPath, rel)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", rel, err)
+		}
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+	}
+
+	src := m.config.GetEnvironment(from)
+	if src == nil {
+		return result, nil
+	}
+	env := &Environment{Name: name, Namespace: rewrite(src.Namespace)}
+	for _, c := range src.Clusters {
+		c.Name, c.URL, c.Namespace = rewrite(c.Name), rewrite(c.URL), rewrite(c.Namespace)
+		env.Clusters = append(env.Clusters, c)
+	}
+	if err := m.config.AddEnvironment(env); err != nil {
+		return nil, err
+	}
+	if err := m.Save(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// envNamespace returns the namespace of an environment: the one of the
+// environment config, or of its generated Namespace resource.
+func (m *Manager) envNamespace(env string) string {
+	if e := m.config.GetEnvironment(env); e != nil && e.Namespace != "" {
+		return e.Namespace
+	}
+	doc, err := readYAMLFile(filepath.Join(m.projectPath, infrastructureBaseDir, "namespaces", env+".yaml"))
+	if err != nil {
+		return ""
+	}
+	return mappingScalar(lookup(doc, "metadata"), "name")
+}
+
+// addKustomizationResource adds resource next to existing in the resources of
+// the kustomization of dir. It reports false when the kustomization does not
+// list existing.
+func (m *Manager) addKustomizationResource(dir, existing, resource string) (bool, []byte, error) {
+	file := filepath.Join(m.projectPath, dir, "kustomization.yaml")
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return false, nil, nil
+	}
+	doc, err := readYAMLFile(file)
+	if err != nil {
+		return false, nil, err
+	}
+	if !hasSequenceEntry(doc, "resources", &yaml.Node{Kind: yaml.ScalarNode, Value: existing}) {
+		return false, nil, nil
+	}
+	if !addSequenceEntry(doc, "resources", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: resource}) {
+		return false, nil, nil
+	}
+	content, err := encodeYAML(doc)
+	return err == nil, content, err
+}
+
+// addMultiClusterElements adds the environment name to the list generators of
+// the multi-cluster ApplicationSets that have an element for from, copying
+// that element. It returns the updated files.
+func (m *Manager) addMultiClusterElements(name, from string, rewrite func(string) string) (map[string][]byte, error) {
+	matches, err := filepath.Glob(filepath.Join(m.projectPath, "argocd", "applicationsets", "*-multi-cluster.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ApplicationSets: %w", err)
+	}
+	updated := map[string][]byte{}
+	for _, match := range matches {
+		doc, err := readYAMLFile(match)
+		if err != nil {
+			return nil, err
+		}
+		changed := false
+		for _, elements := range listElements(doc) {
+			var source *yaml.Node
+			listed := false
+			for _, element := range elements.Content {
+				switch mappingScalar(element, "env") {
+				case from:
+					source = element
+				case name:
+					listed = true
+				}
+			}
+			if source == nil || listed {
+				continue
+			}
+			element := copyNode(source)
+			rewriteScalars(element, rewrite)
+			elements.Content = append(elements.Content, element)
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		content, err := encodeYAML(doc)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(m.projectPath, match)
+		if err != nil {
+			return nil, err
+		}
+		updated[filepath.ToSlash(rel)] = content
+	}
+	return updated, nil
+}
+
+// listElements returns the elements of the list generators below node.
+func listElements(node *yaml.Node) []*yaml.Node {
+	var out []*yaml.Node
+	if elements := lookup(node, "list", "elements"); elements != nil && elements.Kind == yaml.SequenceNode {
+		out = append(out, elements)
+	}
+	for _, child := range node.Content {
+		out = append(out, listElements(child)...)
+	}
+	return out
+}
+
+func rewriteScalars(node *yaml.Node, rewrite func(string) string) {
+	if node.Kind == yaml.ScalarNode {
+		node.Value = rewrite(node.Value)
+	}
+	for _, child := range node.Content {
+		rewriteScalars(child, rewrite)
+	}
+}
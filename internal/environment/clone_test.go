@@ -0,0 +1,108 @@
+package environment
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMultiClusterAppSet = `apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: demo-apps-multi-cluster
+spec:
+  generators:
+    - matrix:
+        generators:
+          - list:
+              elements:
+                - env: dev
+                  namespace: demo-dev
+                - env: prod
+                  namespace: demo-prod
+          - clusters: {}
+`
+
+func TestCloneEnvironment(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "dev")
+	writeTestEnvironment(t, root, "prod")
+	writeTestFile(t, root, "applications/overlays/prod/ingress.yaml", "host: shop.example.com\n")
+	writeTestFile(t, root, "infrastructure/base/namespaces/kustomization.yaml", `resources:
+  - dev.yaml
+  - prod.yaml
+`)
+	writeTestFile(t, root, "argocd/applicationsets/apps-multi-cluster.yaml", testMultiClusterAppSet)
+
+	m := NewManager(root)
+	require.NoError(t, m.CreateEnvironment("prod", CreateEnvOptions{
+		Namespace: "demo-prod",
+		Clusters:  []ClusterInfo{{Name: "prod-cluster", URL: "https://prod.example.com"}},
+	}))
+
+	result, err := m.CloneEnvironment("staging", "prod", CloneOptions{
+		Rewrites: []Rewrite{{From: "shop.example.com", To: "staging.shop.example.com"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "demo-staging", result.Namespace)
+	assert.Equal(t, []string{
+		"applications/overlays/staging/ingress.yaml",
+		"applications/overlays/staging/kustomization.yaml",
+		"argocd/applicationsets/apps-staging.yaml",
+		"infrastructure/base/namespaces/staging.yaml",
+		"infrastructure/overlays/staging/kustomization.yaml",
+	}, result.Files)
+	assert.Equal(t, []string{
+		"argocd/applicationsets/apps-multi-cluster.yaml",
+		"infrastructure/base/namespaces/kustomization.yaml",
+	}, result.Updated)
+
+	assert.Equal(t, "host: staging.shop.example.com\n", readTestFile(t, root, "applications/overlays/staging/ingress.yaml"))
+	appSet := readTestFile(t, root, "argocd/applicationsets/apps-staging.yaml")
+	assert.Contains(t, appSet, "name: demo-apps-staging")
+	assert.Contains(t, appSet, "path: applications/overlays/staging")
+	assert.Contains(t, appSet, "namespace: demo-staging")
+	assert.Contains(t, readTestFile(t, root, "infrastructure/base/namespaces/kustomization.yaml"), "- staging.yaml")
+	assert.Contains(t, readTestFile(t, root, "argocd/applicationsets/apps-multi-cluster.yaml"), `- env: staging
+                  namespace: demo-staging`)
+
+	reloaded := NewManager(root)
+	require.NoError(t, reloaded.Load())
+	staging := reloaded.GetEnvironment("staging")
+	require.NotNil(t, staging)
+	assert.Equal(t, "demo-staging", staging.Namespace)
+	assert.Equal(t, []ClusterInfo{{Name: "staging-cluster", URL: "https://staging.example.com"}}, staging.Clusters)
+
+	_, err = m.CloneEnvironment("staging", "prod", CloneOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment staging already exists")
+}
+
+func TestCloneEnvironment_Namespace(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "prod")
+
+	result, err := NewManager(root).CloneEnvironment("qa", "prod", CloneOptions{Namespace: "shop-qa"})
+	require.NoError(t, err)
+	assert.Equal(t, "shop-qa", result.Namespace)
+	assert.Contains(t, readTestFile(t, root, "infrastructure/base/namespaces/qa.yaml"), "name: shop-qa")
+	assert.Contains(t, readTestFile(t, root, "argocd/applicationsets/apps-qa.yaml"), "namespace: shop-qa")
+}
+
+func TestCloneEnvironment_DryRun(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "prod")
+
+	result, err := NewManager(root).CloneEnvironment("staging", "prod", CloneOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.NotEmpty(t, result.Files)
+	assert.NoFileExists(t, filepath.Join(root, "applications", "overlays", "staging", "kustomization.yaml"))
+
+	_, err = NewManager(root).CloneEnvironment("staging", "dev", CloneOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment dev has no files")
+}
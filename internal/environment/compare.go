@@ -0,0 +1,300 @@
+package environment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	infrastructureBaseDir    = "infrastructure/base"
+	infrastructureOverlayDir = "infrastructure/overlays"
+
+	// envPlaceholder stands for the environment name in the paths of
+	// EnvDiff.
+	envPlaceholder = "{env}"
+)
+
+// Statuses of FileDiff and ValueDiff.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// envDirs are the directories holding one subdirectory per environment.
+var envDirs = []string{infrastructureOverlayDir, applicationsOverlayDir, helmReleasesDir}
+
+// envFilePatterns are the ArgoCD and Flux files generated per environment.
+var envFilePatterns = []string{
+	"argocd/applicationsets/infra-{env}.yaml",
+	"argocd/applicationsets/apps-{env}.yaml",
+	"argocd/applicationsets/infra-{env}-cluster.yaml",
+	"argocd/applicationsets/apps-{env}-cluster.yaml",
+	"flux/kustomizations/infra-{env}.yaml",
+	"flux/kustomizations/apps-{env}.yaml",
+}
+
+// EnvDiff lists the differences between the files of two environments.
+type EnvDiff struct {
+	From  string     `json:"from" yaml:"from"`
+	To    string     `json:"to" yaml:"to"`
+	Files []FileDiff `json:"files" yaml:"files"`
+}
+
+// FileDiff is a file that differs between two environments. Path has the
+// environment name replaced by {env}. Added files exist only in the second
+// environment, removed files only in the first.
+type FileDiff struct {
+	Path   string      `json:"path" yaml:"path"`
+	Status string      `json:"status" yaml:"status"`
+	Values []ValueDiff `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// ValueDiff is a YAML field that differs between two environments. Field is
+// the path to the value, prefixed with kind/name in files with several
+// documents.
+type ValueDiff struct {
+	Field  string `json:"field" yaml:"field"`
+	Status string `json:"status" yaml:"status"`
+	From   string `json:"from,omitempty" yaml:"from,omitempty"`
+	To     string `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// Diff compares the overlays, HelmReleases and ArgoCD and Flux resources of
+// two environments. Occurrences of the environment names are ignored, so that
+// demo-dev and demo-prod namespaces are not reported as different.
+func (m *Manager) Diff(from, to string) (*EnvDiff, error) {
+	if from == to {
+		return nil, fmt.Errorf("cannot compare environment %s with itself", from)
+	}
+	fromFiles, err := m.envFiles(from)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := m.envFiles(to)
+	if err != nil {
+		return nil, err
+	}
+	for env, files := range map[string]map[string]string{from: fromFiles, to: toFiles} {
+		if len(files) == 0 {
+			return nil, fmt.Errorf("environment %s has no files in the project", env)
+		}
+	}
+
+	diff := &EnvDiff{From: from, To: to, Files: []FileDiff{}}
+	for _, key := range sortedKeys(fromFiles, toFiles) {
+		fromRel, inFrom := fromFiles[key]
+		toRel, inTo := toFiles[key]
+		switch {
+		case !inTo:
+			diff.Files = append(diff.Files, FileDiff{Path: key, Status: DiffRemoved})
+		case !inFrom:
+			diff.Files = append(diff.Files, FileDiff{Path: key, Status: DiffAdded})
+		default:
+			fileDiff, err := m.diffFile(fromRel, toRel, from, to)
+			if err != nil {
+				return nil, err
+			}
+			if fileDiff != nil {
+				fileDiff.Path = key
+				diff.Files = append(diff.Files, *fileDiff)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// envFiles returns the files of an environment by their path with the
+// environment name replaced by {env}.
+func (m *Manager) envFiles(env string) (map[string]string, error) {
+	files := map[string]string{}
+	for _, dir := range envDirs {
+		rels, err := listFiles(filepath.Join(m.projectPath, dir, env))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s/%s: %w", dir, env, err)
+		}
+		for _, rel := range rels {
+			files[path.Join(dir, envPlaceholder, rel)] = path.Join(dir, env, rel)
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(m.projectPath, infrastructureBaseDir, "*", env+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", infrastructureBaseDir, err)
+	}
+	for _, match := range matches {
+		dir := path.Join(infrastructureBaseDir, filepath.Base(filepath.Dir(match)))
+		files[path.Join(dir, envPlaceholder+".yaml")] = path.Join(dir, env+".yaml")
+	}
+
+	for _, pattern := range envFilePatterns {
+		rel := strings.ReplaceAll(pattern, envPlaceholder, env)
+		if _, err := os.Stat(filepath.Join(m.projectPath, rel)); err == nil {
+			files[pattern] = rel
+		}
+	}
+	return files, nil
+}
+
+// diffFile compares a file of two environments. It returns nil when they only
+// differ by the environment name.
+func (m *Manager) diffFile(fromRel, toRel, from, to string) (*FileDiff, error) {
+	fromData, err := os.ReadFile(filepath.Join(m.projectPath, fromRel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fromRel, err)
+	}
+	toData, err := os.ReadFile(filepath.Join(m.projectPath, toRel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", toRel, err)
+	}
+	if replaceToken(string(fromData), from, to) == string(toData) {
+		return nil, nil
+	}
+
+	fromValues, fromErr := flattenYAML(fromData)
+	toValues, toErr := flattenYAML(toData)
+	if fromErr != nil || toErr != nil {
+		// Not YAML: only report that the content differs.
+		return &FileDiff{Status: DiffChanged}, nil
+	}
+
+	normalized := make(map[string]string, len(fromValues))
+	for field := range fromValues {
+		normalized[replaceToken(field, from, to)] = field
+	}
+	fileDiff := &FileDiff{Status: DiffChanged}
+	for _, field := range sortedKeys(normalized, toValues) {
+		fromField, inFrom := normalized[field]
+		toValue, inTo := toValues[field]
+		switch {
+		case !inTo:
+			fileDiff.Values = append(fileDiff.Values, ValueDiff{Field: field, Status: DiffRemoved, From: fromValues[fromField]})
+		case !inFrom:
+			fileDiff.Values = append(fileDiff.Values, ValueDiff{Field: field, Status: DiffAdded, To: toValue})
+		case replaceToken(fromValues[fromField], from, to) != toValue:
+			fileDiff.Values = append(fileDiff.Values, ValueDiff{Field: field, Status: DiffChanged, From: fromValues[fromField], To: toValue})
+		}
+	}
+	if len(fileDiff.Values) == 0 {
+		// Only formatting or comments differ.
+		return nil, nil
+	}
+	return fileDiff, nil
+}
+
+// flattenYAML maps the path of every value of a YAML stream to the value.
+// Documents are prefixed with kind/name when there are several; list items
+// with a name field are addressed by name.
+func flattenYAML(data []byte) (map[string]string, error) {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(doc.Content) > 0 {
+			docs = append(docs, doc.Content[0])
+		}
+	}
+
+	values := map[string]string{}
+	for _, doc := range docs {
+		prefix := ""
+		if len(docs) > 1 {
+			prefix = mappingScalar(doc, "kind") + "/" + mappingScalar(lookup(doc, "metadata"), "name") + ":"
+		}
+		flattenNode(prefix, doc, values)
+	}
+	return values, nil
+}
+
+func flattenNode(prefix string, node *yaml.Node, values map[string]string) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			values[prefix] = "{}"
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" && !strings.HasSuffix(prefix, ":") {
+				key = prefix + "." + key
+			} else {
+				key = prefix + key
+			}
+			flattenNode(key, node.Content[i+1], values)
+		}
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			values[prefix] = "[]"
+		}
+		for i, item := range node.Content {
+			index := strconv.Itoa(i)
+			if name := mappingScalar(item, "name"); name != "" {
+				index = "name=" + name
+			}
+			flattenNode(prefix+"["+index+"]", item, values)
+		}
+	default:
+		values[prefix] = node.Value
+	}
+}
+
+// replaceToken replaces the occurrences of old in s that are not part of a
+// longer word, so that prod is replaced in demo-prod but not in production.
+func replaceToken(s, old, replacement string) string {
+	if old == "" || old == replacement {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(old)
+		if (i == 0 || !isWordByte(s[i-1])) && (end == len(s) || !isWordByte(s[end])) {
+			b.WriteString(s[:i])
+			b.WriteString(replacement)
+		} else {
+			b.WriteString(s[:end])
+		}
+		s = s[end:]
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// sortedKeys returns the keys of both maps, sorted.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
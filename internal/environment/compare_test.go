@@ -0,0 +1,138 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestEnvironment writes the files gitopsi generates for an environment
+// with the demo-<env> namespace.
+func writeTestEnvironment(t *testing.T, root, env string) {
+	t.Helper()
+	writeTestFile(t, root, "applications/overlays/"+env+"/kustomization.yaml", testOverlay)
+	writeTestFile(t, root, "infrastructure/overlays/"+env+"/kustomization.yaml", testOverlay)
+	writeTestFile(t, root, "infrastructure/base/namespaces/"+env+".yaml", `apiVersion: v1
+kind: Namespace
+metadata:
+  name: demo-`+env+`
+  labels:
+    env: `+env+`
+`)
+	writeTestFile(t, root, "argocd/applicationsets/apps-"+env+".yaml", `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: demo-apps-`+env+`
+spec:
+  source:
+    path: applications/overlays/`+env+`
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: demo-`+env+`
+`)
+}
+
+func TestDiff(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "dev")
+	writeTestEnvironment(t, root, "prod")
+	writeTestFile(t, root, "applications/overlays/prod/kustomization.yaml", testOverlay+`patches:
+  - path: replicas.yaml
+`)
+	writeTestFile(t, root, "applications/overlays/prod/replicas.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: myapp
+spec:
+  replicas: 3
+`)
+	writeTestFile(t, root, "infrastructure/overlays/dev/debug.yaml", "debug: true\n")
+	writeTestFile(t, root, "argocd/applicationsets/apps-prod.yaml", `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: demo-apps-prod
+spec:
+  source:
+    path: applications/overlays/prod
+  destination:
+    server: https://prod.example.com
+    namespace: demo-prod
+`)
+
+	diff, err := NewManager(root).Diff("dev", "prod")
+	require.NoError(t, err)
+
+	assert.Equal(t, "dev", diff.From)
+	assert.Equal(t, "prod", diff.To)
+	require.Len(t, diff.Files, 4, "files differing only by the environment name are not reported")
+	assert.Equal(t, FileDiff{
+		Path:   "applications/overlays/{env}/kustomization.yaml",
+		Status: DiffChanged,
+		Values: []ValueDiff{{Field: "patches[0].path", Status: DiffAdded, To: "replicas.yaml"}},
+	}, diff.Files[0])
+	assert.Equal(t, FileDiff{Path: "applications/overlays/{env}/replicas.yaml", Status: DiffAdded}, diff.Files[1])
+	assert.Equal(t, FileDiff{
+		Path:   "argocd/applicationsets/apps-{env}.yaml",
+		Status: DiffChanged,
+		Values: []ValueDiff{{Field: "spec.destination.server", Status: DiffChanged, From: "https://kubernetes.default.svc", To: "https://prod.example.com"}},
+	}, diff.Files[2])
+	assert.Equal(t, FileDiff{Path: "infrastructure/overlays/{env}/debug.yaml", Status: DiffRemoved}, diff.Files[3])
+}
+
+func TestDiff_Errors(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "dev")
+	m := NewManager(root)
+
+	_, err := m.Diff("dev", "dev")
+	assert.Error(t, err)
+
+	_, err = m.Diff("dev", "prod")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment prod has no files")
+}
+
+func TestFlattenYAML(t *testing.T) {
+	values, err := flattenYAML([]byte(`kind: Role
+metadata:
+  name: reader
+rules: []
+---
+kind: RoleBinding
+metadata:
+  name: reader
+subjects:
+  - kind: ServiceAccount
+    name: default
+roleRef:
+  name: reader
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Role/reader:kind":                               "Role",
+		"Role/reader:metadata.name":                      "reader",
+		"Role/reader:rules":                              "[]",
+		"RoleBinding/reader:kind":                        "RoleBinding",
+		"RoleBinding/reader:metadata.name":               "reader",
+		"RoleBinding/reader:subjects[name=default].kind": "ServiceAccount",
+		"RoleBinding/reader:subjects[name=default].name": "default",
+		"RoleBinding/reader:roleRef.name":                "reader",
+	}, values)
+}
+
+func TestReplaceToken(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"demo-prod", "demo-staging"},
+		{"prod", "staging"},
+		{"overlays/prod/kustomization.yaml", "overlays/staging/kustomization.yaml"},
+		{"production", "production"},
+		{"preprod", "preprod"},
+		{"prod.example.com and prod", "staging.example.com and staging"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, replaceToken(tt.in, "prod", "staging"), tt.in)
+	}
+}
@@ -0,0 +1,276 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// argoCDClustersDir holds the ArgoCD cluster secrets of the environments.
+const argoCDClustersDir = "argocd/clusters"
+
+// DecommissionOptions configures DecommissionEnvironment. The live resources
+// are only deleted when the matching function is set.
+type DecommissionOptions struct {
+	// DeleteApplication deletes a live ArgoCD Application or ApplicationSet;
+	// kind is Application or ApplicationSet.
+	DeleteApplication func(ctx context.Context, kind, name string) error
+	// DeleteNamespace deletes the namespace of the environment.
+	DeleteNamespace func(ctx context.Context, name string) error
+	// DeleteCluster deletes the ArgoCD registration of a cluster.
+	DeleteCluster func(ctx context.Context, name string) error
+	// Force continues after failures, reporting them as warnings.
+	Force  bool
+	DryRun bool
+}
+
+// DecommissionResult describes the teardown of an environment.
+type DecommissionResult struct {
+	Environment string `json:"environment" yaml:"environment"`
+	// Removed are the files of the environment.
+	Removed []string `json:"removed" yaml:"removed"`
+	// Updated are the kustomizations and multi-cluster ApplicationSets the
+	// environment was removed from.
+	Updated []string `json:"updated,omitempty" yaml:"updated,omitempty"`
+	// Applications are the ArgoCD Applications and ApplicationSets of the
+	// environment, as kind/name.
+	Applications []string `json:"applications,omitempty" yaml:"applications,omitempty"`
+	Namespaces   []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	// Clusters are the ArgoCD cluster secrets of the environment.
+	Clusters []string `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	DryRun   bool     `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// DecommissionEnvironment removes an environment: its live ArgoCD
+// Applications, namespace and cluster registrations when requested, its
+// overlays, HelmReleases and ArgoCD and Flux files, its entries in the
+// kustomizations of infrastructure/base and the multi-cluster
+// ApplicationSets, and its environment config. With DryRun it only reports
+// what would be removed.
+func (m *Manager) DecommissionEnvironment(ctx context.Context, name string, opts DecommissionOptions) (*DecommissionResult, error) {
+	files, err := m.envFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	clusterFiles, clusters, err := m.clusterSecrets(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && len(clusterFiles) == 0 && !m.config.HasEnvironment(name) {
+		return nil, fmt.Errorf("environment %s not found", name)
+	}
+
+	result := &DecommissionResult{Environment: name, Removed: []string{}, DryRun: opts.DryRun}
+	writes := map[string][]byte{}
+	for _, rel := range files {
+		result.Removed = append(result.Removed, rel)
+	}
+	sort.Strings(result.Removed)
+	for _, rel := range result.Removed {
+		if dir := path.Dir(rel); path.Dir(dir) == infrastructureBaseDir {
+			content, err := m.removeKustomizationResource(dir, path.Base(rel))
+			if err != nil {
+				return nil, err
+			}
+			if content != nil {
+				writes[path.Join(dir, "kustomization.yaml")] = content
+			}
+		}
+		if strings.HasPrefix(rel, "argocd/") {
+			apps, err := argoCDApplications(filepath.Join(m.projectPath, rel))
+			if err != nil {
+				return nil, err
+			}
+			result.Applications = append(result.Applications, apps...)
+		}
+	}
+	result.Removed = append(result.Removed, clusterFiles...)
+	result.Clusters = clusters
+	if opts.DeleteNamespace != nil {
+		if namespace := m.envNamespace(name); namespace != "" {
+			result.Namespaces = []string{namespace}
+		}
+	}
+
+	appSets, err := m.removeMultiClusterElements(name)
+	if err != nil {
+		return nil, err
+	}
+	for rel, content := range appSets {
+		writes[rel] = content
+	}
+	for rel := range writes {
+		result.Updated = append(result.Updated, rel)
+	}
+	sort.Strings(result.Updated)
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	// Delete the live resources while their manifests still exist.
+	fail := func(format string, args ...any) error {
+		err := fmt.Errorf(format, args...)
+		if !opts.Force {
+			return err
+		}
+		result.Warnings = append(result.Warnings, err.Error())
+		return nil
+	}
+	if opts.DeleteApplication != nil {
+		for _, app := range result.Applications {
+			kind, appName, _ := strings.Cut(app, "/")
+			if err := opts.DeleteApplication(ctx, kind, appName); err != nil {
+				if err := fail("failed to delete %s %s: %w", kind, appName, err); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+	if opts.DeleteNamespace != nil {
+		for _, namespace := range result.Namespaces {
+			if err := opts.DeleteNamespace(ctx, namespace); err != nil {
+				if err := fail("failed to delete namespace %s: %w", namespace, err); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+	if opts.DeleteCluster != nil {
+		for _, cluster := range result.Clusters {
+			if err := opts.DeleteCluster(ctx, cluster); err != nil {
+				if err := fail("failed to delete cluster %s: %w", cluster, err); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+
+	for _, rel := range result.Removed {
+		if err := os.Remove(filepath.Join(m.projectPath, rel)); err != nil && !os.IsNotExist(err) {
+			return result, fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+	}
+	for _, dir := range envDirs {
+		if err := os.RemoveAll(filepath.Join(m.projectPath, dir, name)); err != nil {
+			return result, fmt.Errorf("failed to remove %s/%s: %w", dir, name, err)
+		}
+	}
+	for rel, content := range writes {
+		if err := os.WriteFile(filepath.Join(m.projectPath, rel), content, 0644); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+	}
+
+	if !m.config.HasEnvironment(name) {
+		return result, nil
+	}
+	if err := m.config.RemoveEnvironment(name); err != nil {
+		return result, err
+	}
+	return result, m.Save()
+}
+
+// clusterSecrets returns the ArgoCD cluster secret files labelled with the
+// environment, and the names of the secrets.
+func (m *Manager) clusterSecrets(env string) ([]string, []string, error) {
+	matches, err := filepath.Glob(filepath.Join(m.projectPath, argoCDClustersDir, "*.yaml"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s: %w", argoCDClustersDir, err)
+	}
+	var files, names []string
+	for _, match := range matches {
+		doc, err := readYAMLFile(match)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mappingScalar(lookup(doc, "metadata", "labels"), "env") != env {
+			continue
+		}
+		files = append(files, path.Join(argoCDClustersDir, filepath.Base(match)))
+		names = append(names, mappingScalar(lookup(doc, "metadata"), "name"))
+	}
+	return files, names, nil
+}
+
+// argoCDApplications returns the Applications and ApplicationSets of a file
+// as kind/name.
+func argoCDApplications(file string) ([]string, error) {
+	doc, err := readYAMLFile(file)
+	if err != nil {
+		return nil, err
+	}
+	kind := mappingScalar(doc, "kind")
+	if kind != "Application" && kind != "ApplicationSet" {
+		return nil, nil
+	}
+	return []string{kind + "/" + mappingScalar(lookup(doc, "metadata"), "name")}, nil
+}
+
+// removeKustomizationResource removes resource from the resources of the
+// kustomization of dir. It returns nil when the kustomization does not list
+// it.
+func (m *Manager) removeKustomizationResource(dir, resource string) ([]byte, error) {
+	file := filepath.Join(m.projectPath, dir, "kustomization.yaml")
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, nil
+	}
+	doc, err := readYAMLFile(file)
+	if err != nil {
+		return nil, err
+	}
+	entry := &yaml.Node{Kind: yaml.ScalarNode, Value: resource}
+	if !hasSequenceEntry(doc, "resources", entry) {
+		return nil, nil
+	}
+	removeSequenceEntry(doc, "resources", entry)
+	return encodeYAML(doc)
+}
+
+// removeMultiClusterElements removes the environment from the list generators
+// of the multi-cluster ApplicationSets. It returns the updated files.
+func (m *Manager) removeMultiClusterElements(name string) (map[string][]byte, error) {
+	matches, err := filepath.Glob(filepath.Join(m.projectPath, "argocd", "applicationsets", "*-multi-cluster.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ApplicationSets: %w", err)
+	}
+	updated := map[string][]byte{}
+	for _, match := range matches {
+		doc, err := readYAMLFile(match)
+		if err != nil {
+			return nil, err
+		}
+		changed := false
+		for _, elements := range listElements(doc) {
+			kept := elements.Content[:0]
+			for _, element := range elements.Content {
+				if mappingScalar(element, "env") == name {
+					changed = true
+					continue
+				}
+				kept = append(kept, element)
+			}
+			elements.Content = kept
+		}
+		if !changed {
+			continue
+		}
+		content, err := encodeYAML(doc)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(m.projectPath, match)
+		if err != nil {
+			return nil, err
+		}
+		updated[filepath.ToSlash(rel)] = content
+	}
+	return updated, nil
+}
@@ -0,0 +1,99 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecommissionEnvironment(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "dev")
+	writeTestEnvironment(t, root, "prod")
+	writeTestFile(t, root, "infrastructure/base/namespaces/kustomization.yaml", `resources:
+  - dev.yaml
+  - prod.yaml
+`)
+	writeTestFile(t, root, "argocd/applicationsets/apps-multi-cluster.yaml", testMultiClusterAppSet)
+	writeTestFile(t, root, "argocd/clusters/prod-eu.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: prod-eu
+  labels:
+    argocd.argoproj.io/secret-type: cluster
+    env: prod
+`)
+	m := NewManager(root)
+	require.NoError(t, m.CreateEnvironment("prod", CreateEnvOptions{Namespace: "demo-prod"}))
+
+	var deleted []string
+	record := func(what string) func(context.Context, string) error {
+		return func(_ context.Context, name string) error {
+			deleted = append(deleted, what+" "+name)
+			return nil
+		}
+	}
+	opts := DecommissionOptions{
+		DeleteApplication: func(_ context.Context, kind, name string) error {
+			deleted = append(deleted, kind+" "+name)
+			return nil
+		},
+		DeleteNamespace: record("namespace"),
+		DeleteCluster:   record("cluster"),
+		DryRun:          true,
+	}
+
+	plan, err := m.DecommissionEnvironment(context.Background(), "prod", opts)
+	require.NoError(t, err)
+	assert.Empty(t, deleted, "a dry run deletes nothing")
+	assert.Equal(t, []string{
+		"applications/overlays/prod/kustomization.yaml",
+		"argocd/applicationsets/apps-prod.yaml",
+		"infrastructure/base/namespaces/prod.yaml",
+		"infrastructure/overlays/prod/kustomization.yaml",
+		"argocd/clusters/prod-eu.yaml",
+	}, plan.Removed)
+	assert.Equal(t, []string{
+		"argocd/applicationsets/apps-multi-cluster.yaml",
+		"infrastructure/base/namespaces/kustomization.yaml",
+	}, plan.Updated)
+	assert.Equal(t, []string{"Application/demo-apps-prod"}, plan.Applications)
+	assert.Equal(t, []string{"demo-prod"}, plan.Namespaces)
+	assert.Equal(t, []string{"prod-eu"}, plan.Clusters)
+	assert.FileExists(t, filepath.Join(root, "argocd", "applicationsets", "apps-prod.yaml"))
+
+	opts.DryRun = false
+	_, err = m.DecommissionEnvironment(context.Background(), "prod", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Application demo-apps-prod", "namespace demo-prod", "cluster prod-eu"}, deleted)
+	assert.NoDirExists(t, filepath.Join(root, "applications", "overlays", "prod"))
+	assert.NoFileExists(t, filepath.Join(root, "argocd", "clusters", "prod-eu.yaml"))
+	assert.DirExists(t, filepath.Join(root, "applications", "overlays", "dev"))
+	assert.NotContains(t, readTestFile(t, root, "infrastructure/base/namespaces/kustomization.yaml"), "prod.yaml")
+	assert.NotContains(t, readTestFile(t, root, "argocd/applicationsets/apps-multi-cluster.yaml"), "env: prod")
+	assert.Nil(t, m.GetEnvironment("prod"))
+
+	_, err = m.DecommissionEnvironment(context.Background(), "prod", DecommissionOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment prod not found")
+}
+
+func TestDecommissionEnvironment_DeleteFails(t *testing.T) {
+	root := t.TempDir()
+	writeTestEnvironment(t, root, "prod")
+	failing := func(context.Context, string, string) error { return errors.New("forbidden") }
+
+	_, err := NewManager(root).DecommissionEnvironment(context.Background(), "prod", DecommissionOptions{DeleteApplication: failing})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to delete Application demo-apps-prod: forbidden")
+	assert.FileExists(t, filepath.Join(root, "argocd", "applicationsets", "apps-prod.yaml"), "files are kept when a live deletion fails")
+
+	result, err := NewManager(root).DecommissionEnvironment(context.Background(), "prod", DecommissionOptions{DeleteApplication: failing, Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"failed to delete Application demo-apps-prod: forbidden"}, result.Warnings)
+	assert.NoFileExists(t, filepath.Join(root, "argocd", "applicationsets", "apps-prod.yaml"))
+}
@@ -0,0 +1,264 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrPromotionBlocked is returned when a promotion gate does not pass.
+var ErrPromotionBlocked = errors.New("promotion blocked by gates")
+
+// GateRequest is the promotion a gate decides on.
+type GateRequest struct {
+	Application string
+	FromEnv     string
+	ToEnv       string
+}
+
+// Gate is a check a promotion has to pass. Check returns an error explaining
+// why the promotion may not proceed.
+type Gate interface {
+	Name() string
+	Check(ctx context.Context, m *Manager, req GateRequest) error
+}
+
+// GateResult is the outcome of a gate for one application.
+type GateResult struct {
+	Gate        string `yaml:"gate" json:"gate"`
+	Application string `yaml:"application,omitempty" json:"application,omitempty"`
+	Passed      bool   `yaml:"passed" json:"passed"`
+	Message     string `yaml:"message" json:"message"`
+}
+
+// ApprovalGate requires a promotion to be approved by the operator.
+type ApprovalGate struct {
+	Approved bool
+}
+
+func (g *ApprovalGate) Name() string { return "approval" }
+
+func (g *ApprovalGate) Check(_ context.Context, _ *Manager, req GateRequest) error {
+	if !g.Approved {
+		return fmt.Errorf("promotion to %s requires manual approval", req.ToEnv)
+	}
+	return nil
+}
+
+// SoakTimeGate requires the source environment to have run its current state
+// of the application for at least Duration.
+type SoakTimeGate struct {
+	Duration time.Duration
+	// Now returns the current time; defaults to time.Now.
+	Now func() time.Time
+}
+
+func (g *SoakTimeGate) Name() string { return "soak-time" }
+
+func (g *SoakTimeGate) Check(_ context.Context, m *Manager, req GateRequest) error {
+	since, err := m.LastChanged(req.Application, req.FromEnv)
+	if err != nil {
+		return err
+	}
+	if since.IsZero() {
+		return fmt.Errorf("no record of when %s last changed in %s", req.Application, req.FromEnv)
+	}
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	if soaked := now().Sub(since); soaked < g.Duration {
+		return fmt.Errorf("%s has run in %s for %s, %s required", req.Application, req.FromEnv,
+			soaked.Truncate(time.Minute), g.Duration)
+	}
+	return nil
+}
+
+// ApplicationStatusFunc returns the health and sync status of an ArgoCD
+// Application.
+type ApplicationStatusFunc func(ctx context.Context, kubeContext, namespace, name string) (health, sync string, err error)
+
+// HealthGate requires the ArgoCD Application deploying the source environment
+// to be Synced and Healthy.
+type HealthGate struct {
+	// Application is the Application name; {app} and {env} are replaced.
+	Application string
+	Namespace   string
+	// KubeContext selects the kubeconfig context of the source cluster.
+	KubeContext string
+	// Status defaults to ArgoCDApplicationStatus.
+	Status ApplicationStatusFunc
+}
+
+func (g *HealthGate) Name() string { return "source-healthy" }
+
+func (g *HealthGate) Check(ctx context.Context, _ *Manager, req GateRequest) error {
+	name := strings.NewReplacer("{app}", req.Application, "{env}", req.FromEnv).Replace(g.Application)
+	status := g.Status
+	if status == nil {
+		status = ArgoCDApplicationStatus
+	}
+	health, sync, err := status(ctx, g.KubeContext, g.Namespace, name)
+	if err != nil {
+		return fmt.Errorf("failed to get status of Application %s: %w", name, err)
+	}
+	if health != "Healthy" || sync != "Synced" {
+		return fmt.Errorf("application %s in %s is %s/%s, want Healthy/Synced", name, req.FromEnv, health, sync)
+	}
+	return nil
+}
+
+// ValidationGate requires the project to pass validation. The result is
+// reused for every application of a promotion.
+type ValidationGate struct {
+	Validate func(ctx context.Context) error
+
+	done bool
+	err  error
+}
+
+func (g *ValidationGate) Name() string { return "validation" }
+
+func (g *ValidationGate) Check(ctx context.Context, _ *Manager, _ GateRequest) error {
+	if !g.done {
+		g.err, g.done = g.Validate(ctx), true
+	}
+	return g.err
+}
+
+// LastChanged returns when the state of an application in an environment last
+// changed: the latest promotion into the environment or the latest commit
+// touching its overlay or HelmRelease. It returns the zero time when neither
+// is known.
+func (m *Manager) LastChanged(app, env string) (time.Time, error) {
+	var latest time.Time
+
+	history, err := m.LoadPromotionHistory()
+	if err != nil {
+		return latest, err
+	}
+	for _, record := range history.Promotions {
+		if record.Application == app && record.ToEnv == env && record.Timestamp.After(latest) {
+			latest = record.Timestamp
+		}
+	}
+
+	committed := m.lastCommitted(
+		path.Join(applicationsOverlayDir, env, "kustomization.yaml"),
+		path.Join(applicationsOverlayDir, env, app)+"/",
+		path.Join(helmReleasesDir, env, app+".yaml"),
+	)
+	if committed.After(latest) {
+		latest = committed
+	}
+	return latest, nil
+}
+
+// lastCommitted returns the commit time of the latest commit changing one of
+// the project paths; a path ending in a slash matches the files below it. It
+// returns the zero time outside a Git repository.
+func (m *Manager) lastCommitted(paths ...string) time.Time {
+	commit := m.lastCommit(paths...)
+	if commit == nil {
+		return time.Time{}
+	}
+	return commit.Committer.When
+}
+
+// lastCommit returns the latest commit changing one of the project paths, or
+// nil.
+func (m *Manager) lastCommit(paths ...string) *object.Commit {
+	repo, prefix, ok := m.projectRepo()
+	if !ok {
+		return nil
+	}
+	for i, p := range paths {
+		paths[i] = repoPath(prefix, p)
+	}
+
+	iter, err := repo.Log(&git.LogOptions{
+		PathFilter: func(file string) bool {
+			for _, p := range paths {
+				if file == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(file, p)) {
+					return true
+				}
+			}
+			return false
+		},
+	})
+	if err != nil {
+		// An empty repository has no history yet.
+		return nil
+	}
+	defer iter.Close()
+
+	commit, err := iter.Next()
+	if err != nil {
+		return nil
+	}
+	return commit
+}
+
+// projectRepo opens the Git repository containing the project and returns the
+// path of the project within it.
+func (m *Manager) projectRepo() (*git.Repository, string, bool) {
+	repo, err := git.PlainOpenWithOptions(m.projectPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", false
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", false
+	}
+	prefix, err := filepath.Rel(wt.Filesystem.Root(), m.projectPath)
+	if err != nil || strings.HasPrefix(prefix, "..") {
+		return nil, "", false
+	}
+	return repo, filepath.ToSlash(prefix), true
+}
+
+// repoPath converts a project path to a repository path, keeping a trailing
+// slash.
+func repoPath(prefix, p string) string {
+	joined := path.Join(prefix, p)
+	if strings.HasSuffix(p, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// checkGates runs the gates for each application and reports whether all of
+// them passed.
+func (m *Manager) checkGates(ctx context.Context, gates []Gate, apps []string, from, to string) ([]GateResult, bool) {
+	var results []GateResult
+	passed := true
+	for _, app := range apps {
+		for _, gate := range gates {
+			result := GateResult{Gate: gate.Name(), Application: app, Passed: true}
+			if err := gate.Check(ctx, m, GateRequest{Application: app, FromEnv: from, ToEnv: to}); err != nil {
+				result.Passed, result.Message = false, err.Error()
+				passed = false
+			}
+			results = append(results, result)
+		}
+	}
+	return results, passed
+}
+
+// failedGates summarizes the gates that did not pass.
+func failedGates(results []GateResult) string {
+	var failed []string
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, fmt.Sprintf("%s (%s): %s", r.Gate, r.Application, r.Message))
+		}
+	}
+	return strings.Join(failed, "; ")
+}
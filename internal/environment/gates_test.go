@@ -0,0 +1,143 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testGateRequest = GateRequest{Application: "myapp", FromEnv: "dev", ToEnv: "prod"}
+
+func TestApprovalGate(t *testing.T) {
+	gate := &ApprovalGate{}
+	err := gate.Check(context.Background(), nil, testGateRequest)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires manual approval")
+
+	gate.Approved = true
+	assert.NoError(t, gate.Check(context.Background(), nil, testGateRequest))
+}
+
+func TestHealthGate(t *testing.T) {
+	var gotContext, gotNamespace, gotName string
+	status := "Healthy/Synced"
+	gate := &HealthGate{
+		Application: "shop-apps-{env}",
+		Namespace:   "argocd",
+		KubeContext: "dev-cluster",
+		Status: func(_ context.Context, kubeContext, namespace, name string) (string, string, error) {
+			gotContext, gotNamespace, gotName = kubeContext, namespace, name
+			health, sync, _ := strings.Cut(status, "/")
+			return health, sync, nil
+		},
+	}
+
+	require.NoError(t, gate.Check(context.Background(), nil, testGateRequest))
+	assert.Equal(t, "dev-cluster", gotContext)
+	assert.Equal(t, "argocd", gotNamespace)
+	assert.Equal(t, "shop-apps-dev", gotName)
+
+	status = "Degraded/Synced"
+	err := gate.Check(context.Background(), nil, testGateRequest)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is Degraded/Synced")
+
+	gate.Status = func(context.Context, string, string, string) (string, string, error) {
+		return "", "", errors.New("not found")
+	}
+	assert.Error(t, gate.Check(context.Background(), nil, testGateRequest))
+}
+
+func TestValidationGate(t *testing.T) {
+	calls := 0
+	gate := &ValidationGate{Validate: func(context.Context) error {
+		calls++
+		return errors.New("validation failed with 2 issues")
+	}}
+	assert.Error(t, gate.Check(context.Background(), nil, testGateRequest))
+	assert.Error(t, gate.Check(context.Background(), nil, testGateRequest))
+	assert.Equal(t, 1, calls, "validation runs once per promotion")
+}
+
+func TestSoakTimeGate_History(t *testing.T) {
+	mgr := newPromotionProject(t)
+	promoted := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, mgr.recordPromotion(&PromotionRecord{ID: "1", Application: "myapp", FromEnv: "qa", ToEnv: "dev", Timestamp: promoted}))
+	require.NoError(t, mgr.recordPromotion(&PromotionRecord{ID: "2", Application: "other", FromEnv: "qa", ToEnv: "dev", Timestamp: promoted.Add(time.Hour)}))
+
+	gate := &SoakTimeGate{Duration: 24 * time.Hour, Now: func() time.Time { return promoted.Add(2 * time.Hour) }}
+	err := gate.Check(context.Background(), mgr, testGateRequest)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has run in dev for 2h0m0s, 24h0m0s required")
+
+	gate.Now = func() time.Time { return promoted.Add(25 * time.Hour) }
+	assert.NoError(t, gate.Check(context.Background(), mgr, testGateRequest))
+}
+
+func TestSoakTimeGate_Git(t *testing.T) {
+	mgr := newPromotionProject(t)
+	gate := &SoakTimeGate{Duration: time.Hour}
+
+	err := gate.Check(context.Background(), mgr, testGateRequest)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no record of when myapp last changed in dev")
+
+	repo, err := git.PlainInit(mgr.projectPath, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add("applications")
+	require.NoError(t, err)
+	committed := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: committed}
+	_, err = wt.Commit("Add applications", &git.CommitOptions{Author: sig, Committer: sig})
+	require.NoError(t, err)
+
+	since, err := mgr.LastChanged("myapp", "dev")
+	require.NoError(t, err)
+	assert.True(t, since.Equal(committed), "LastChanged = %s, want %s", since, committed)
+
+	gate.Now = func() time.Time { return committed.Add(30 * time.Minute) }
+	assert.Error(t, gate.Check(context.Background(), mgr, testGateRequest))
+	gate.Now = func() time.Time { return committed.Add(2 * time.Hour) }
+	assert.NoError(t, gate.Check(context.Background(), mgr, testGateRequest))
+}
+
+func TestManager_PromoteGates(t *testing.T) {
+	mgr := newPromotionProject(t)
+	approval := &ApprovalGate{}
+	opts := PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "prod", Gates: []Gate{approval}}
+
+	result, err := mgr.Promote(opts)
+	require.ErrorIs(t, err, ErrPromotionBlocked)
+	assert.False(t, result.Success)
+	require.Len(t, result.Gates, 1)
+	assert.Equal(t, GateResult{Gate: "approval", Application: "myapp", Message: "promotion to prod requires manual approval"}, result.Gates[0])
+	assert.Equal(t, testOverlay, readTestFile(t, mgr.projectPath, "applications/overlays/prod/kustomization.yaml"), "a blocked promotion writes nothing")
+
+	// Dry runs report the gates without failing.
+	opts.DryRun = true
+	result, err = mgr.Promote(opts)
+	require.NoError(t, err)
+	assert.False(t, result.Gates[0].Passed)
+
+	opts.DryRun = false
+	approval.Approved = true
+	result, err = mgr.Promote(opts)
+	require.NoError(t, err)
+	assert.True(t, result.Gates[0].Passed)
+	assert.Len(t, result.Records, 1)
+
+	// Nothing left to promote: the gates are not consulted.
+	approval.Approved = false
+	result, err = mgr.Promote(opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Gates)
+}
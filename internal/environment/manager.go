@@ -1,6 +1,7 @@
 package environment
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -165,18 +166,38 @@ type PromotionOptions struct {
 	ToEnv       string
 	All         bool
 	DryRun      bool
+	// Gates must pass before anything is written. Dry runs report their
+	// results without failing.
+	Gates []Gate
+	// PinImages, when set, rewrites the content of each promoted file, e.g.
+	// to pin its images by digest at promotion time.
+	PinImages func(ctx context.Context, path string, content []byte) ([]byte, error)
 }
 
 type PromotionResult struct {
-	Application string
-	FromEnv     string
-	ToEnv       string
-	Success     bool
-	Message     string
-	Changes     []string
+	Application string   `yaml:"application" json:"application"`
+	FromEnv     string   `yaml:"from" json:"from"`
+	ToEnv       string   `yaml:"to" json:"to"`
+	Success     bool     `yaml:"success" json:"success"`
+	Message     string   `yaml:"message" json:"message"`
+	Changes     []string `yaml:"changes" json:"changes"`
+	// Records are the promotions written to the history; empty on dry runs.
+	Records []PromotionRecord `yaml:"records,omitempty" json:"records,omitempty"`
+	Gates   []GateResult      `yaml:"gates,omitempty" json:"gates,omitempty"`
 }
 
+// Promote copies the release state of an application from one environment to
+// another: image overrides and patch files of the kustomize overlay, and the
+// chart version and image values of its HelmRelease. Each promotion is recorded
+// in PromotionHistoryFile with the previous content of the rewritten files.
 func (m *Manager) Promote(opts PromotionOptions) (*PromotionResult, error) {
+	return m.PromoteContext(context.Background(), opts)
+}
+
+// PromoteContext is Promote with a context for the promotion gates. When a
+// gate fails, the result lists the gate results and the error wraps
+// ErrPromotionBlocked.
+func (m *Manager) PromoteContext(ctx context.Context, opts PromotionOptions) (*PromotionResult, error) {
 	fromEnv := m.config.GetEnvironment(opts.FromEnv)
 	if fromEnv == nil {
 		return nil, fmt.Errorf("source environment %s not found", opts.FromEnv)
@@ -187,6 +208,25 @@ func (m *Manager) Promote(opts PromotionOptions) (*PromotionResult, error) {
 		return nil, fmt.Errorf("target environment %s not found", opts.ToEnv)
 	}
 
+	if opts.FromEnv == opts.ToEnv {
+		return nil, fmt.Errorf("source and target environment are both %s", opts.FromEnv)
+	}
+
+	apps := []string{opts.Application}
+	subject := opts.Application
+	if opts.All {
+		var err error
+		if apps, err = m.applications(opts.FromEnv); err != nil {
+			return nil, err
+		}
+		if len(apps) == 0 {
+			return nil, fmt.Errorf("no applications found in environment %s", opts.FromEnv)
+		}
+		subject = "all applications"
+	} else if opts.Application == "" {
+		return nil, fmt.Errorf("application name is required")
+	}
+
 	result := &PromotionResult{
 		Application: opts.Application,
 		FromEnv:     opts.FromEnv,
@@ -195,22 +235,60 @@ func (m *Manager) Promote(opts PromotionOptions) (*PromotionResult, error) {
 		Changes:     []string{},
 	}
 
-	if opts.DryRun {
-		result.Message = fmt.Sprintf("Would promote %s from %s to %s", opts.Application, opts.FromEnv, opts.ToEnv)
-		result.Changes = append(result.Changes,
-			fmt.Sprintf("Copy overlay from %s to %s", opts.FromEnv, opts.ToEnv),
-			"Update image tags",
-			"Update configuration values",
-		)
-		return result, nil
+	var planned []*promotion
+	for _, app := range apps {
+		p, err := m.planPromotion(app, opts.FromEnv, opts.ToEnv)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.writes) == 0 {
+			continue
+		}
+		if opts.PinImages != nil {
+			if err := p.pinImages(ctx, opts.PinImages); err != nil {
+				return nil, err
+			}
+		}
+		result.Changes = append(result.Changes, p.changes...)
+		planned = append(planned, p)
+	}
+
+	if len(opts.Gates) > 0 && len(planned) > 0 {
+		names := make([]string, len(planned))
+		for i, p := range planned {
+			names[i] = p.application
+		}
+		gates, passed := m.checkGates(ctx, opts.Gates, names, opts.FromEnv, opts.ToEnv)
+		result.Gates = gates
+		if !passed && !opts.DryRun {
+			result.Success = false
+			result.Message = fmt.Sprintf("Promotion of %s from %s to %s is blocked", subject, opts.FromEnv, opts.ToEnv)
+			return result, fmt.Errorf("%w: %s", ErrPromotionBlocked, failedGates(gates))
+		}
+	}
+
+	for _, p := range planned {
+		if opts.DryRun {
+			break
+		}
+		record, err := p.apply()
+		if err != nil {
+			return nil, fmt.Errorf("failed to promote %s: %w", p.application, err)
+		}
+		if err := m.recordPromotion(record); err != nil {
+			return nil, err
+		}
+		result.Records = append(result.Records, *record)
 	}
 
-	result.Message = fmt.Sprintf("Promoted %s from %s to %s", opts.Application, opts.FromEnv, opts.ToEnv)
-	result.Changes = append(result.Changes,
-		fmt.Sprintf("Copied overlay from %s to %s", opts.FromEnv, opts.ToEnv),
-		"Updated image tags",
-		"Updated configuration values",
-	)
+	switch {
+	case len(result.Changes) == 0:
+		result.Message = fmt.Sprintf("Nothing to promote: %s in %s already matches %s", subject, opts.ToEnv, opts.FromEnv)
+	case opts.DryRun:
+		result.Message = fmt.Sprintf("Would promote %s from %s to %s", subject, opts.FromEnv, opts.ToEnv)
+	default:
+		result.Message = fmt.Sprintf("Promoted %s from %s to %s", subject, opts.FromEnv, opts.ToEnv)
+	}
 
 	return result, nil
 }
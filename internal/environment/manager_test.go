@@ -213,18 +213,7 @@ func TestManager_ToYAML(t *testing.T) {
 }
 
 func TestManager_Promote(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "gitopsi-test-*")
-	require.NoError(t, err)
-	defer os.RemoveAll(tmpDir)
-
-	mgr := NewManager(tmpDir)
-	mgr.config = &Config{
-		Environments: []*Environment{
-			{Name: "dev"},
-			{Name: "staging"},
-			{Name: "prod"},
-		},
-	}
+	mgr := newPromotionProject(t)
 
 	result, err := mgr.Promote(PromotionOptions{
 		Application: "myapp",
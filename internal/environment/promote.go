@@ -0,0 +1,806 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PromotionHistoryFile = ".gitopsi/promotions.yaml"
+
+	applicationsBaseDir    = "applications/base"
+	applicationsOverlayDir = "applications/overlays"
+	helmReleasesDir        = "applications/helmreleases"
+)
+
+// promotedPatchFields are the kustomization fields whose entries referencing
+// files of the promoted application are carried over to the target overlay.
+var promotedPatchFields = []string{"patches", "patchesStrategicMerge", "patchesJson6902"}
+
+// ImageChange is an image rewritten in the target environment.
+type ImageChange struct {
+	Name string `yaml:"name" json:"name"`
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+}
+
+// FileSnapshot is the content of a file before a promotion rewrote it.
+type FileSnapshot struct {
+	Path    string `yaml:"path" json:"path"`
+	Content string `yaml:"content,omitempty" json:"content,omitempty"`
+	// Created is set when the promotion created the file.
+	Created bool `yaml:"created,omitempty" json:"created,omitempty"`
+}
+
+// PromotionRecord describes a promotion written to the project. Previous holds
+// the files as they were before, so that the promotion can be rolled back.
+type PromotionRecord struct {
+	ID          string         `yaml:"id" json:"id"`
+	Application string         `yaml:"application" json:"application"`
+	FromEnv     string         `yaml:"from" json:"from"`
+	ToEnv       string         `yaml:"to" json:"to"`
+	Timestamp   time.Time      `yaml:"timestamp" json:"timestamp"`
+	Images      []ImageChange  `yaml:"images,omitempty" json:"images,omitempty"`
+	Files       []string       `yaml:"files" json:"files"`
+	Previous    []FileSnapshot `yaml:"previous" json:"previous"`
+	// RollbackOf is set on rollbacks: the ID of the promotion undone or the
+	// Git revision restored.
+	RollbackOf string `yaml:"rollback_of,omitempty" json:"rollback_of,omitempty"`
+}
+
+// PromotionHistory is the content of PromotionHistoryFile, oldest first.
+type PromotionHistory struct {
+	Promotions []PromotionRecord `yaml:"promotions" json:"promotions"`
+}
+
+// LoadPromotionHistory reads the promotion history of the project. A project
+// without promotions has an empty history.
+func (m *Manager) LoadPromotionHistory() (*PromotionHistory, error) {
+	history := &PromotionHistory{}
+	data, err := os.ReadFile(filepath.Join(m.projectPath, PromotionHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, fmt.Errorf("failed to read promotion history: %w", err)
+	}
+	if err := yaml.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("failed to parse promotion history: %w", err)
+	}
+	return history, nil
+}
+
+func (m *Manager) recordPromotion(record *PromotionRecord) error {
+	history, err := m.LoadPromotionHistory()
+	if err != nil {
+		return err
+	}
+	// IDs have a resolution of one second; number repeated ones.
+	id := record.ID
+	for n := 2; history.find(record.ID) != nil; n++ {
+		record.ID = fmt.Sprintf("%s-%d", id, n)
+	}
+	history.Promotions = append(history.Promotions, *record)
+
+	data, err := yaml.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal promotion history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(m.projectPath, ".gitopsi"), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.projectPath, PromotionHistoryFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write promotion history: %w", err)
+	}
+	return nil
+}
+
+// applications returns the applications deployed to an environment: the
+// applications with a base and the HelmReleases of the environment.
+func (m *Manager) applications(env string) ([]string, error) {
+	seen := map[string]bool{}
+	entries, err := os.ReadDir(filepath.Join(m.projectPath, applicationsBaseDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list applications: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			seen[e.Name()] = true
+		}
+	}
+	entries, err = os.ReadDir(filepath.Join(m.projectPath, helmReleasesDir, env))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list HelmReleases: %w", err)
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".yaml")
+		if !e.IsDir() && name != e.Name() && name != "kustomization" {
+			seen[name] = true
+		}
+	}
+
+	apps := make([]string, 0, len(seen))
+	for app := range seen {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	return apps, nil
+}
+
+// promotion collects the changes promoting one application before they are
+// written.
+type promotion struct {
+	root string
+	// srcRoot holds the source environment; the project unless rolling back.
+	srcRoot     string
+	application string
+	from        string
+	to          string
+	// exact also removes the application's patch files and entries that are
+	// missing from the source, so the target matches it exactly.
+	exact   bool
+	writes  []fileWrite
+	images  []ImageChange
+	changes []string
+}
+
+// fileWrite is a file to write; a nil content removes it.
+type fileWrite struct {
+	path    string
+	content []byte
+}
+
+func (p *promotion) write(rel string, content []byte) {
+	for i := range p.writes {
+		if p.writes[i].path == rel {
+			p.writes[i].content = content
+			return
+		}
+	}
+	p.writes = append(p.writes, fileWrite{path: rel, content: content})
+}
+
+// pinImages passes the promoted files through pin.
+func (p *promotion) pinImages(ctx context.Context, pin func(context.Context, string, []byte) ([]byte, error)) error {
+	for i, w := range p.writes {
+		if w.content == nil {
+			continue
+		}
+		pinned, err := pin(ctx, w.path, w.content)
+		if err != nil {
+			return fmt.Errorf("failed to pin images of %s: %w", w.path, err)
+		}
+		if !bytes.Equal(pinned, w.content) {
+			p.writes[i].content = pinned
+			p.changes = append(p.changes, fmt.Sprintf("Pin images of %s by digest", w.path))
+		}
+	}
+	return nil
+}
+
+func (p *promotion) abs(rel string) string {
+	return filepath.Join(p.root, filepath.FromSlash(rel))
+}
+
+func (p *promotion) exists(rel string) bool {
+	_, err := os.Stat(p.abs(rel))
+	return err == nil
+}
+
+func (p *promotion) srcAbs(rel string) string {
+	return filepath.Join(p.srcRoot, filepath.FromSlash(rel))
+}
+
+func (p *promotion) srcExists(rel string) bool {
+	_, err := os.Stat(p.srcAbs(rel))
+	return err == nil
+}
+
+// planPromotion computes the changes promoting app from one environment to
+// another without writing them.
+func (m *Manager) planPromotion(app, from, to string) (*promotion, error) {
+	p := &promotion{root: m.projectPath, srcRoot: m.projectPath, application: app, from: from, to: to}
+	if err := p.plan(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *promotion) plan() error {
+	found := false
+	if p.exists(path.Join(applicationsBaseDir, p.application)) {
+		found = true
+		if err := p.planOverlay(); err != nil {
+			return err
+		}
+	}
+	if p.srcExists(path.Join(helmReleasesDir, p.from, p.application+".yaml")) {
+		found = true
+		if err := p.planHelmRelease(); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("application %s not found in environment %s", p.application, p.from)
+	}
+	return nil
+}
+
+// planOverlay promotes the kustomize overlay of the application: its image
+// overrides, its patch files and the patch entries referencing them.
+func (p *promotion) planOverlay() error {
+	srcRel := path.Join(applicationsOverlayDir, p.from, "kustomization.yaml")
+	dstRel := path.Join(applicationsOverlayDir, p.to, "kustomization.yaml")
+	if !p.srcExists(srcRel) {
+		return nil
+	}
+	if !p.exists(dstRel) {
+		return fmt.Errorf("overlay for environment %s not found: %s", p.to, dstRel)
+	}
+
+	images, err := baseImages(p.abs(path.Join(applicationsBaseDir, p.application)))
+	if err != nil {
+		return err
+	}
+	src, err := readYAMLFile(p.srcAbs(srcRel))
+	if err != nil {
+		return err
+	}
+	dst, err := readYAMLFile(p.abs(dstRel))
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, image := range images {
+		name, _, _ := parseImage(image)
+		srcEntry := imageEntry(src, name)
+		dstEntry := imageEntry(dst, name)
+		before, after := overrideImage(image, dstEntry), overrideImage(image, srcEntry)
+		if before == after {
+			continue
+		}
+		setImageEntry(dst, name, srcEntry)
+		changed = true
+		p.images = append(p.images, ImageChange{Name: name, From: before, To: after})
+		p.changes = append(p.changes, fmt.Sprintf("Set image %s to %s in %s (was %s)", name, after, p.to, before))
+	}
+
+	if err := p.planOverlayFiles(); err != nil {
+		return err
+	}
+
+	for _, field := range promotedPatchFields {
+		for _, entry := range patchEntries(src, field, p.application+"/") {
+			if addSequenceEntry(dst, field, entry) {
+				changed = true
+				p.changes = append(p.changes, fmt.Sprintf("Add %s entry %s to %s", field, patchPath(entry), dstRel))
+			}
+		}
+		if !p.exact {
+			continue
+		}
+		for _, entry := range patchEntries(dst, field, p.application+"/") {
+			if !hasSequenceEntry(src, field, entry) {
+				removeSequenceEntry(dst, field, entry)
+				changed = true
+				p.changes = append(p.changes, fmt.Sprintf("Remove %s entry %s from %s", field, patchPath(entry), dstRel))
+			}
+		}
+	}
+
+	if changed {
+		content, err := encodeYAML(dst)
+		if err != nil {
+			return err
+		}
+		p.write(dstRel, content)
+	}
+	return nil
+}
+
+// planOverlayFiles copies the files of the application's overlay directory.
+func (p *promotion) planOverlayFiles() error {
+	srcDir := path.Join(applicationsOverlayDir, p.from, p.application)
+	dstDir := path.Join(applicationsOverlayDir, p.to, p.application)
+	sources, err := listFiles(p.srcAbs(srcDir))
+	if err != nil {
+		return fmt.Errorf("failed to copy overlay files: %w", err)
+	}
+	for _, rel := range sources {
+		content, err := os.ReadFile(p.srcAbs(path.Join(srcDir, rel)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path.Join(srcDir, rel), err)
+		}
+		target := path.Join(dstDir, rel)
+		if existing, err := os.ReadFile(p.abs(target)); err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+		p.write(target, content)
+		p.changes = append(p.changes, fmt.Sprintf("Copy %s to %s", path.Join(srcDir, rel), target))
+	}
+	if !p.exact {
+		return nil
+	}
+
+	targets, err := listFiles(p.abs(dstDir))
+	if err != nil {
+		return fmt.Errorf("failed to list overlay files: %w", err)
+	}
+	for _, rel := range targets {
+		if !slices.Contains(sources, rel) {
+			p.write(path.Join(dstDir, rel), nil)
+			p.changes = append(p.changes, fmt.Sprintf("Remove %s", path.Join(dstDir, rel)))
+		}
+	}
+	return nil
+}
+
+// listFiles returns the files below dir as slash-separated relative paths. A
+// missing directory has no files.
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && file == dir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// planHelmRelease promotes the chart version and image values of the
+// application's HelmRelease. Comments of the target, such as Flux image policy
+// markers, are kept.
+func (p *promotion) planHelmRelease() error {
+	srcRel := path.Join(helmReleasesDir, p.from, p.application+".yaml")
+	dstRel := path.Join(helmReleasesDir, p.to, p.application+".yaml")
+	if !p.exists(dstRel) {
+		return fmt.Errorf("HelmRelease for %s not found in environment %s: %s", p.application, p.to, dstRel)
+	}
+	src, err := readYAMLFile(p.srcAbs(srcRel))
+	if err != nil {
+		return err
+	}
+	dst, err := readYAMLFile(p.abs(dstRel))
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if version := lookup(src, "spec", "chart", "spec", "version"); version != nil {
+		if chartSpec := lookup(dst, "spec", "chart", "spec"); chartSpec != nil && chartSpec.Kind == yaml.MappingNode {
+			if before, ok := setScalar(chartSpec, "version", version); ok {
+				changed = true
+				p.changes = append(p.changes, fmt.Sprintf("Set chart version to %s in %s (was %s)", version.Value, dstRel, before))
+			}
+		}
+	}
+
+	srcImage := lookup(src, "spec", "values", "image")
+	if srcImage != nil && srcImage.Kind == yaml.MappingNode {
+		values := lookup(dst, "spec", "values")
+		if values == nil || values.Kind != yaml.MappingNode {
+			return fmt.Errorf("HelmRelease %s has no values to update", dstRel)
+		}
+		dstImage := mappingValue(values, "image")
+		if dstImage == nil || dstImage.Kind != yaml.MappingNode {
+			dstImage = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(values, "image", dstImage)
+		}
+
+		before := valuesImage(dstImage)
+		for i := 0; i+1 < len(srcImage.Content); i += 2 {
+			key, value := srcImage.Content[i].Value, srcImage.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				continue
+			}
+			if old, ok := setScalar(dstImage, key, value); ok {
+				changed = true
+				p.changes = append(p.changes, fmt.Sprintf("Set image.%s to %s in %s (was %s)", key, value.Value, dstRel, old))
+			}
+		}
+		// A digest pins the previous tag of the target: drop it unless the
+		// source is pinned too.
+		if old := mappingScalar(dstImage, "digest"); old != "" && mappingValue(srcImage, "digest") == nil {
+			deleteMappingValue(dstImage, "digest")
+			changed = true
+			p.changes = append(p.changes, fmt.Sprintf("Remove image.digest from %s (was %s)", dstRel, old))
+		}
+		if after := valuesImage(dstImage); after != before {
+			p.images = append(p.images, ImageChange{Name: mappingScalar(srcImage, "repository"), From: before, To: after})
+		}
+	}
+
+	if changed {
+		content, err := encodeYAML(dst)
+		if err != nil {
+			return err
+		}
+		p.write(dstRel, content)
+	}
+	return nil
+}
+
+// apply writes the planned changes and returns the record describing them.
+func (p *promotion) apply() (*PromotionRecord, error) {
+	now := time.Now().UTC()
+	record := &PromotionRecord{
+		ID:          fmt.Sprintf("%s-%s-%s", now.Format("20060102T150405Z"), p.application, p.to),
+		Application: p.application,
+		FromEnv:     p.from,
+		ToEnv:       p.to,
+		Timestamp:   now,
+		Images:      p.images,
+	}
+
+	for _, w := range p.writes {
+		snapshot := FileSnapshot{Path: w.path}
+		existing, err := os.ReadFile(p.abs(w.path))
+		switch {
+		case err == nil:
+			snapshot.Content = string(existing)
+		case os.IsNotExist(err):
+			snapshot.Created = true
+		default:
+			return nil, fmt.Errorf("failed to read %s: %w", w.path, err)
+		}
+		record.Previous = append(record.Previous, snapshot)
+		record.Files = append(record.Files, w.path)
+
+		if w.content == nil {
+			if err := os.Remove(p.abs(w.path)); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", w.path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p.abs(w.path)), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", w.path, err)
+		}
+		if err := os.WriteFile(p.abs(w.path), w.content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", w.path, err)
+		}
+	}
+	return record, nil
+}
+
+// baseImages returns the container images referenced by the manifests of an
+// application base, in order of appearance.
+func baseImages(dir string) ([]string, error) {
+	var images []string
+	seen := map[string]bool{}
+	var collect func(n *yaml.Node)
+	collect = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key, value := n.Content[i], n.Content[i+1]
+				if key.Value == "image" && value.Kind == yaml.ScalarNode && value.Value != "" {
+					if !seen[value.Value] {
+						seen[value.Value] = true
+						images = append(images, value.Value)
+					}
+					continue
+				}
+				collect(value)
+			}
+			return
+		}
+		for _, c := range n.Content {
+			collect(c)
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(file); (ext != ".yaml" && ext != ".yml") || d.Name() == "kustomization.yaml" {
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc yaml.Node
+			if err := decoder.Decode(&doc); err != nil {
+				break
+			}
+			collect(&doc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read application base: %w", err)
+	}
+	return images, nil
+}
+
+// parseImage splits an image reference into its name, tag and digest.
+func parseImage(ref string) (name, tag, digest string) {
+	name = ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		name, tag = name[:colon], name[colon+1:]
+	}
+	return name, tag, digest
+}
+
+// overrideImage applies a kustomize images entry to an image reference.
+func overrideImage(ref string, entry *yaml.Node) string {
+	if entry == nil {
+		return ref
+	}
+	name, tag, digest := parseImage(ref)
+	if v := mappingScalar(entry, "newName"); v != "" {
+		name = v
+	}
+	if v := mappingScalar(entry, "newTag"); v != "" {
+		tag = v
+	}
+	if v := mappingScalar(entry, "digest"); v != "" {
+		return name + "@" + v
+	}
+	if digest != "" {
+		return name + "@" + digest
+	}
+	if tag != "" {
+		return name + ":" + tag
+	}
+	return name
+}
+
+// valuesImage returns the image configured by Helm image values.
+func valuesImage(image *yaml.Node) string {
+	ref := mappingScalar(image, "repository")
+	if tag := mappingScalar(image, "tag"); tag != "" {
+		ref += ":" + tag
+	}
+	if digest := mappingScalar(image, "digest"); digest != "" {
+		ref += "@" + digest
+	}
+	return ref
+}
+
+func imageEntry(doc *yaml.Node, name string) *yaml.Node {
+	images := mappingValue(doc, "images")
+	if images == nil || images.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range images.Content {
+		if mappingScalar(entry, "name") == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// setImageEntry replaces the images entry for name with a copy of entry, or
+// removes it when entry is nil.
+func setImageEntry(doc *yaml.Node, name string, entry *yaml.Node) {
+	images := mappingValue(doc, "images")
+	if images == nil || images.Kind != yaml.SequenceNode {
+		if entry == nil {
+			return
+		}
+		images = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(doc, "images", images)
+	}
+
+	for i, existing := range images.Content {
+		if mappingScalar(existing, "name") != name {
+			continue
+		}
+		if entry == nil {
+			images.Content = append(images.Content[:i], images.Content[i+1:]...)
+			if len(images.Content) == 0 {
+				deleteMappingValue(doc, "images")
+			}
+		} else {
+			images.Content[i] = copyNode(entry)
+		}
+		return
+	}
+	if entry != nil {
+		images.Content = append(images.Content, copyNode(entry))
+	}
+}
+
+// patchEntries returns the entries of a kustomization field referencing files
+// under prefix.
+func patchEntries(doc *yaml.Node, field, prefix string) []*yaml.Node {
+	seq := mappingValue(doc, field)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var entries []*yaml.Node
+	for _, entry := range seq.Content {
+		if strings.HasPrefix(patchPath(entry), prefix) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// patchPath returns the file a patch entry refers to.
+func patchPath(entry *yaml.Node) string {
+	if entry.Kind == yaml.ScalarNode {
+		return entry.Value
+	}
+	return mappingScalar(entry, "path")
+}
+
+func hasSequenceEntry(doc *yaml.Node, field string, entry *yaml.Node) bool {
+	seq := mappingValue(doc, field)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, existing := range seq.Content {
+		if patchPath(existing) == patchPath(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeSequenceEntry removes the entries for the file of entry from the
+// sequence field of doc, and the field once it is empty.
+func removeSequenceEntry(doc *yaml.Node, field string, entry *yaml.Node) {
+	seq := mappingValue(doc, field)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return
+	}
+	kept := seq.Content[:0]
+	for _, existing := range seq.Content {
+		if patchPath(existing) != patchPath(entry) {
+			kept = append(kept, existing)
+		}
+	}
+	seq.Content = kept
+	if len(seq.Content) == 0 {
+		deleteMappingValue(doc, field)
+	}
+}
+
+// addSequenceEntry appends a copy of entry to the sequence field of doc unless
+// an entry for the same file is already present.
+func addSequenceEntry(doc *yaml.Node, field string, entry *yaml.Node) bool {
+	seq := mappingValue(doc, field)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(doc, field, seq)
+	}
+	for _, existing := range seq.Content {
+		if patchPath(existing) == patchPath(entry) {
+			return false
+		}
+	}
+	seq.Content = append(seq.Content, copyNode(entry))
+	return true
+}
+
+// setScalar sets key of mapping to the value of src, keeping the comments of
+// the existing value. It reports the previous value and whether it changed.
+func setScalar(mapping *yaml.Node, key string, src *yaml.Node) (string, bool) {
+	existing := mappingValue(mapping, key)
+	if existing == nil {
+		setMappingValue(mapping, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: src.Tag, Style: src.Style, Value: src.Value})
+		return "unset", true
+	}
+	if existing.Kind == yaml.ScalarNode && existing.Value == src.Value {
+		return "", false
+	}
+	before := existing.Value
+	existing.Kind, existing.Tag, existing.Style, existing.Value = yaml.ScalarNode, src.Tag, src.Style, src.Value
+	existing.Content = nil
+	return before, true
+}
+
+func readYAMLFile(file string) (*yaml.Node, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse %s: expected a YAML mapping", file)
+	}
+	return doc.Content[0], nil
+}
+
+func encodeYAML(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func lookup(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node = mappingValue(node, key); node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func mappingScalar(mapping *yaml.Node, key string) string {
+	if v := mappingValue(mapping, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func deleteMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
@@ -0,0 +1,267 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOverlay = `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+  - ../../base
+`
+
+const testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: myapp
+spec:
+  template:
+    spec:
+      containers:
+        - name: myapp
+          image: registry.example.com/myapp:1.0.0
+`
+
+func testHelmRelease(version, tag string) string {
+	return `apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: myapp
+spec:
+  chart:
+    spec:
+      chart: ./charts/app
+      version: ` + version + `
+  values:
+    replicaCount: 1
+    image:
+      repository: registry.example.com/myapp
+      tag: ` + tag + ` # {"$imagepolicy": "flux-system:myapp:tag"}
+`
+}
+
+func writeTestFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func readTestFile(t *testing.T, root, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// newPromotionProject creates a project with dev, staging and prod overlays
+// for myapp, where dev runs 1.2.0.
+func newPromotionProject(t *testing.T) *Manager {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFile(t, root, "applications/base/myapp/deployment.yaml", testDeployment)
+	writeTestFile(t, root, "applications/base/myapp/kustomization.yaml", "resources:\n  - deployment.yaml\n")
+	writeTestFile(t, root, "applications/overlays/dev/kustomization.yaml", testOverlay+`images:
+  - name: registry.example.com/myapp
+    newTag: 1.2.0
+patches:
+  - path: myapp/resources.yaml
+  - path: shared.yaml
+`)
+	writeTestFile(t, root, "applications/overlays/dev/myapp/resources.yaml", "kind: Deployment\n")
+	writeTestFile(t, root, "applications/overlays/staging/kustomization.yaml", testOverlay)
+	writeTestFile(t, root, "applications/overlays/prod/kustomization.yaml", testOverlay)
+
+	mgr := NewManager(root)
+	mgr.config = &Config{
+		Environments: []*Environment{{Name: "dev"}, {Name: "staging"}, {Name: "prod"}},
+	}
+	return mgr
+}
+
+func TestManager_PromoteOverlay(t *testing.T) {
+	mgr := newPromotionProject(t)
+
+	result, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, "Promoted myapp from dev to staging", result.Message)
+	assert.Contains(t, result.Changes, "Set image registry.example.com/myapp to registry.example.com/myapp:1.2.0 in staging (was registry.example.com/myapp:1.0.0)")
+
+	overlay := readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml")
+	assert.Contains(t, overlay, "newTag: 1.2.0")
+	assert.Contains(t, overlay, "path: myapp/resources.yaml")
+	assert.NotContains(t, overlay, "shared.yaml", "patches of other applications are not promoted")
+	assert.Equal(t, "kind: Deployment\n", readTestFile(t, mgr.projectPath, "applications/overlays/staging/myapp/resources.yaml"))
+
+	require.Len(t, result.Records, 1)
+	record := result.Records[0]
+	assert.Equal(t, []ImageChange{{
+		Name: "registry.example.com/myapp",
+		From: "registry.example.com/myapp:1.0.0",
+		To:   "registry.example.com/myapp:1.2.0",
+	}}, record.Images)
+	assert.ElementsMatch(t, []string{
+		"applications/overlays/staging/kustomization.yaml",
+		"applications/overlays/staging/myapp/resources.yaml",
+	}, record.Files)
+	for _, snapshot := range record.Previous {
+		if snapshot.Path == "applications/overlays/staging/kustomization.yaml" {
+			assert.Equal(t, testOverlay, snapshot.Content)
+		} else {
+			assert.True(t, snapshot.Created)
+		}
+	}
+
+	history, err := mgr.LoadPromotionHistory()
+	require.NoError(t, err)
+	require.Len(t, history.Promotions, 1)
+	assert.Equal(t, record.ID, history.Promotions[0].ID)
+
+	// A second promotion finds nothing to change and records nothing.
+	result, err = mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+	assert.Contains(t, result.Message, "Nothing to promote")
+	history, err = mgr.LoadPromotionHistory()
+	require.NoError(t, err)
+	assert.Len(t, history.Promotions, 1)
+}
+
+func TestManager_PromoteRemovesOverride(t *testing.T) {
+	mgr := newPromotionProject(t)
+	writeTestFile(t, mgr.projectPath, "applications/overlays/prod/kustomization.yaml", testOverlay+`images:
+  - name: registry.example.com/myapp
+    newTag: 0.9.0
+`)
+
+	// staging runs the base image, so promoting it to prod drops the override.
+	result, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "staging", ToEnv: "prod"})
+	require.NoError(t, err)
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, "registry.example.com/myapp:1.0.0", result.Records[0].Images[0].To)
+	assert.NotContains(t, readTestFile(t, mgr.projectPath, "applications/overlays/prod/kustomization.yaml"), "images")
+}
+
+func TestManager_PromoteDryRun(t *testing.T) {
+	mgr := newPromotionProject(t)
+
+	result, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Would promote myapp from dev to staging", result.Message)
+	assert.NotEmpty(t, result.Changes)
+	assert.Empty(t, result.Records)
+	assert.Equal(t, testOverlay, readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml"))
+	_, err = os.Stat(filepath.Join(mgr.projectPath, PromotionHistoryFile))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestManager_PromoteHelmRelease(t *testing.T) {
+	mgr := newPromotionProject(t)
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/dev/myapp.yaml", testHelmRelease("0.2.0", "1.2.0"))
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/staging/myapp.yaml", testHelmRelease("0.1.0", "1.0.0"))
+
+	result, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Changes, "Set chart version to 0.2.0 in applications/helmreleases/staging/myapp.yaml (was 0.1.0)")
+	assert.Contains(t, result.Changes, "Set image.tag to 1.2.0 in applications/helmreleases/staging/myapp.yaml (was 1.0.0)")
+
+	release := readTestFile(t, mgr.projectPath, "applications/helmreleases/staging/myapp.yaml")
+	assert.Contains(t, release, "version: 0.2.0")
+	assert.Contains(t, release, `tag: 1.2.0 # {"$imagepolicy": "flux-system:myapp:tag"}`)
+	assert.Contains(t, release, "replicaCount: 1")
+}
+
+func TestManager_PromoteHelmReleaseDropsStaleDigest(t *testing.T) {
+	mgr := newPromotionProject(t)
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/dev/myapp.yaml", testHelmRelease("0.1.0", "1.2.0"))
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/staging/myapp.yaml", testHelmRelease("0.1.0", "1.0.0")+"      digest: sha256:1111\n")
+
+	result, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Changes, "Remove image.digest from applications/helmreleases/staging/myapp.yaml (was sha256:1111)")
+	assert.NotContains(t, readTestFile(t, mgr.projectPath, "applications/helmreleases/staging/myapp.yaml"), "digest")
+}
+
+func TestManager_PromotePinImages(t *testing.T) {
+	mgr := newPromotionProject(t)
+	var pinned []string
+	pin := func(_ context.Context, path string, content []byte) ([]byte, error) {
+		pinned = append(pinned, path)
+		return bytes.ReplaceAll(content, []byte("newTag: 1.2.0"), []byte("newTag: 1.2.0\n    digest: sha256:2222")), nil
+	}
+
+	result, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging", PinImages: pin})
+	require.NoError(t, err)
+	assert.Contains(t, pinned, "applications/overlays/staging/kustomization.yaml")
+	assert.Contains(t, result.Changes, "Pin images of applications/overlays/staging/kustomization.yaml by digest")
+	assert.Contains(t, readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml"), "digest: sha256:2222")
+
+	_, err = newPromotionProject(t).Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging",
+		PinImages: func(context.Context, string, []byte) ([]byte, error) { return nil, errors.New("registry unreachable") }})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "registry unreachable")
+}
+
+func TestManager_PromoteAll(t *testing.T) {
+	mgr := newPromotionProject(t)
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/dev/other.yaml", testHelmRelease("0.2.0", "2.0.0"))
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/dev/kustomization.yaml", "resources:\n  - other.yaml\n")
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/staging/other.yaml", testHelmRelease("0.2.0", "1.0.0"))
+
+	apps, err := mgr.applications("dev")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"myapp", "other"}, apps)
+
+	result, err := mgr.Promote(PromotionOptions{All: true, FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, "Promoted all applications from dev to staging", result.Message)
+	require.Len(t, result.Records, 2)
+	assert.Equal(t, "myapp", result.Records[0].Application)
+	assert.Equal(t, "other", result.Records[1].Application)
+}
+
+func TestManager_PromoteErrors(t *testing.T) {
+	mgr := newPromotionProject(t)
+
+	_, err := mgr.Promote(PromotionOptions{Application: "missing", FromEnv: "dev", ToEnv: "staging"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "application missing not found")
+
+	_, err = mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "dev"})
+	require.Error(t, err)
+
+	_, err = mgr.Promote(PromotionOptions{FromEnv: "dev", ToEnv: "staging"})
+	require.Error(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(mgr.projectPath, "applications/overlays/staging/kustomization.yaml")))
+	_, err = mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overlay for environment staging not found")
+}
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		ref, name, tag, digest string
+	}{
+		{"nginx", "nginx", "", ""},
+		{"nginx:1.25", "nginx", "1.25", ""},
+		{"localhost:5000/app", "localhost:5000/app", "", ""},
+		{"localhost:5000/app:v1", "localhost:5000/app", "v1", ""},
+		{"ghcr.io/org/app@sha256:abc", "ghcr.io/org/app", "", "sha256:abc"},
+	}
+	for _, tt := range tests {
+		name, tag, digest := parseImage(tt.ref)
+		assert.Equal(t, tt.name, name, tt.ref)
+		assert.Equal(t, tt.tag, tag, tt.ref)
+		assert.Equal(t, tt.digest, digest, tt.ref)
+	}
+}
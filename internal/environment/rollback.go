@@ -0,0 +1,319 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RollbackOptions selects the state an application is rolled back to.
+type RollbackOptions struct {
+	Application string
+	Env         string
+	// To is a promotion ID to undo or a Git revision to restore. When empty,
+	// the latest promotion into Env that was not rolled back is undone, or
+	// else the latest commit changing the application in Env.
+	To     string
+	DryRun bool
+}
+
+type RollbackResult struct {
+	Application string `yaml:"application" json:"application"`
+	Env         string `yaml:"env" json:"env"`
+	// Target describes the restored state.
+	Target  string   `yaml:"target" json:"target"`
+	Message string   `yaml:"message" json:"message"`
+	Changes []string `yaml:"changes" json:"changes"`
+	// Record is the rollback written to the history; nil on dry runs.
+	Record *PromotionRecord `yaml:"record,omitempty" json:"record,omitempty"`
+}
+
+// Rollback restores the kustomize overlay and HelmRelease state of an
+// application in an environment from the promotion history or from Git. Only
+// the application's image overrides, patch files and release values are
+// restored; other applications sharing the overlay are left alone. The
+// rollback is recorded in PromotionHistoryFile like a promotion.
+func (m *Manager) Rollback(opts RollbackOptions) (*RollbackResult, error) {
+	if opts.Application == "" {
+		return nil, fmt.Errorf("application name is required")
+	}
+	if m.config.GetEnvironment(opts.Env) == nil {
+		return nil, fmt.Errorf("environment %s not found", opts.Env)
+	}
+
+	state, err := os.MkdirTemp("", "gitopsi-rollback-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(state)
+
+	target, rollbackOf, err := m.restoreState(state, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &promotion{
+		root:        m.projectPath,
+		srcRoot:     state,
+		application: opts.Application,
+		from:        opts.Env,
+		to:          opts.Env,
+		exact:       true,
+	}
+	if err := p.plan(); err != nil {
+		return nil, err
+	}
+
+	result := &RollbackResult{
+		Application: opts.Application,
+		Env:         opts.Env,
+		Target:      target,
+		Changes:     p.changes,
+	}
+	switch {
+	case len(p.writes) == 0:
+		result.Message = fmt.Sprintf("Nothing to roll back: %s in %s already matches %s", opts.Application, opts.Env, target)
+		return result, nil
+	case opts.DryRun:
+		result.Message = fmt.Sprintf("Would roll back %s in %s to %s", opts.Application, opts.Env, target)
+		return result, nil
+	}
+
+	record, err := p.apply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back %s: %w", opts.Application, err)
+	}
+	record.ID += "-rollback"
+	record.RollbackOf = rollbackOf
+	if err := m.recordPromotion(record); err != nil {
+		return nil, err
+	}
+	result.Record = record
+	result.Message = fmt.Sprintf("Rolled back %s in %s to %s", opts.Application, opts.Env, target)
+	return result, nil
+}
+
+// restoreState writes the state to roll back to into dir. It returns a
+// description of the state and the promotion ID or revision it came from.
+func (m *Manager) restoreState(dir string, opts RollbackOptions) (string, string, error) {
+	history, err := m.LoadPromotionHistory()
+	if err != nil {
+		return "", "", err
+	}
+
+	var record *PromotionRecord
+	if opts.To == "" {
+		record = history.latestPromotion(opts.Application, opts.Env)
+	} else if record = history.find(opts.To); record != nil {
+		if record.Application != opts.Application || record.ToEnv != opts.Env {
+			return "", "", fmt.Errorf("promotion %s promoted %s to %s, not %s to %s",
+				record.ID, record.Application, record.ToEnv, opts.Application, opts.Env)
+		}
+		if record.RollbackOf != "" {
+			return "", "", fmt.Errorf("%s is a rollback; roll back to a promotion or Git revision instead", record.ID)
+		}
+	}
+
+	if record != nil {
+		if err := m.restoreSnapshots(dir, opts.Application, opts.Env, record.Previous); err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("the state before promotion %s", record.ID), record.ID, nil
+	}
+
+	commit, err := m.rollbackCommit(opts)
+	if err != nil {
+		return "", "", err
+	}
+	if err := m.restoreCommit(dir, opts.Application, opts.Env, commit); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("revision %s", commit.Hash.String()[:7]), commit.Hash.String(), nil
+}
+
+// latestPromotion returns the latest promotion of app into env that was not
+// rolled back, or nil.
+func (h *PromotionHistory) latestPromotion(app, env string) *PromotionRecord {
+	reverted := map[string]bool{}
+	for i := len(h.Promotions) - 1; i >= 0; i-- {
+		record := &h.Promotions[i]
+		if record.Application != app || record.ToEnv != env {
+			continue
+		}
+		if record.RollbackOf != "" {
+			reverted[record.RollbackOf] = true
+			continue
+		}
+		if !reverted[record.ID] {
+			return record
+		}
+	}
+	return nil
+}
+
+func (h *PromotionHistory) find(id string) *PromotionRecord {
+	for i := range h.Promotions {
+		if h.Promotions[i].ID == id {
+			return &h.Promotions[i]
+		}
+	}
+	return nil
+}
+
+// appPaths returns the project paths holding the state of app in env; a path
+// ending in a slash is a directory.
+func appPaths(app, env string) []string {
+	return []string{
+		path.Join(applicationsOverlayDir, env, "kustomization.yaml"),
+		path.Join(applicationsOverlayDir, env, app) + "/",
+		path.Join(helmReleasesDir, env, app+".yaml"),
+	}
+}
+
+// restoreSnapshots writes the current state of app in env to dir and replaces
+// the files of a promotion with their previous content.
+func (m *Manager) restoreSnapshots(dir, app, env string, snapshots []FileSnapshot) error {
+	for _, p := range appPaths(app, env) {
+		files := []string{p}
+		if strings.HasSuffix(p, "/") {
+			rels, err := listFiles(filepath.Join(m.projectPath, filepath.FromSlash(p)))
+			if err != nil {
+				return fmt.Errorf("failed to list %s: %w", p, err)
+			}
+			files = files[:0]
+			for _, rel := range rels {
+				files = append(files, p+rel)
+			}
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(filepath.Join(m.projectPath, filepath.FromSlash(file)))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			if err := writeStateFile(dir, file, content); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Created {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(snapshot.Path))); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to restore %s: %w", snapshot.Path, err)
+			}
+			continue
+		}
+		if err := writeStateFile(dir, snapshot.Path, []byte(snapshot.Content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackCommit resolves the commit to restore: opts.To, or the parent of the
+// latest commit changing the application in the environment.
+func (m *Manager) rollbackCommit(opts RollbackOptions) (*object.Commit, error) {
+	repo, _, ok := m.projectRepo()
+	if !ok {
+		if opts.To != "" {
+			return nil, fmt.Errorf("%s is not a promotion and %s is not in a Git repository", opts.To, m.projectPath)
+		}
+		return nil, fmt.Errorf("no promotion of %s to %s to roll back and %s is not in a Git repository",
+			opts.Application, opts.Env, m.projectPath)
+	}
+
+	if opts.To != "" {
+		hash, err := repo.ResolveRevision(plumbing.Revision(opts.To))
+		if err != nil {
+			return nil, fmt.Errorf("%s is neither a promotion nor a Git revision: %w", opts.To, err)
+		}
+		return repo.CommitObject(*hash)
+	}
+
+	latest := m.lastCommit(appPaths(opts.Application, opts.Env)...)
+	if latest == nil {
+		return nil, fmt.Errorf("no promotion or commit of %s in %s to roll back", opts.Application, opts.Env)
+	}
+	parent, err := latest.Parent(0)
+	if errors.Is(err, object.ErrParentNotFound) {
+		return nil, fmt.Errorf("commit %s introduced %s in %s; there is no earlier state", latest.Hash.String()[:7], opts.Application, opts.Env)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parent of %s: %w", latest.Hash, err)
+	}
+	return parent, nil
+}
+
+// restoreCommit writes the state of app in env at commit to dir.
+func (m *Manager) restoreCommit(dir, app, env string, commit *object.Commit) error {
+	_, prefix, _ := m.projectRepo()
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %w", commit.Hash, err)
+	}
+
+	for _, p := range appPaths(app, env) {
+		if !strings.HasSuffix(p, "/") {
+			file, err := tree.File(repoPath(prefix, p))
+			if errors.Is(err, object.ErrFileNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s at %s: %w", p, commit.Hash, err)
+			}
+			if err := writeTreeFile(dir, p, file); err != nil {
+				return err
+			}
+			continue
+		}
+
+		sub, err := tree.Tree(strings.TrimSuffix(repoPath(prefix, p), "/"))
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s at %s: %w", p, commit.Hash, err)
+		}
+		err = sub.Files().ForEach(func(file *object.File) error {
+			return writeTreeFile(dir, p+file.Name, file)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTreeFile(dir, rel string, file *object.File) error {
+	reader, err := file.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	return writeStateFile(dir, rel, content)
+}
+
+func writeStateFile(dir, rel string, content []byte) error {
+	file := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", rel, err)
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	return nil
+}
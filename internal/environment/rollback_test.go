@@ -0,0 +1,174 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func commitTestProject(t *testing.T, root, message string, when time.Time) string {
+	t.Helper()
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		repo, err = git.PlainInit(root, false)
+		require.NoError(t, err)
+	}
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.AddGlob("."))
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: when}
+	hash, err := wt.Commit(message, &git.CommitOptions{Author: sig, Committer: sig})
+	require.NoError(t, err)
+	return hash.String()
+}
+
+func TestManager_RollbackPromotion(t *testing.T) {
+	mgr := newPromotionProject(t)
+	writeTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml", testOverlay+`patches:
+  - path: shared.yaml
+`)
+	promoted, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	require.Len(t, promoted.Records, 1)
+
+	result, err := mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Would roll back myapp in staging to the state before promotion "+promoted.Records[0].ID, result.Message)
+	assert.Nil(t, result.Record)
+	assert.FileExists(t, filepath.Join(mgr.projectPath, "applications/overlays/staging/myapp/resources.yaml"))
+
+	result, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Changes, "Remove applications/overlays/staging/myapp/resources.yaml")
+	require.NotNil(t, result.Record)
+	assert.Equal(t, promoted.Records[0].ID, result.Record.RollbackOf)
+
+	overlay := readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml")
+	assert.NotContains(t, overlay, "newTag")
+	assert.NotContains(t, overlay, "myapp/resources.yaml")
+	assert.Contains(t, overlay, "shared.yaml", "patches of other applications are kept")
+	assert.NoFileExists(t, filepath.Join(mgr.projectPath, "applications/overlays/staging/myapp/resources.yaml"))
+
+	history, err := mgr.LoadPromotionHistory()
+	require.NoError(t, err)
+	require.Len(t, history.Promotions, 2)
+	assert.Equal(t, "staging", history.Promotions[1].ToEnv)
+
+	// The promotion was rolled back: there is nothing left to undo.
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no promotion of myapp to staging to roll back")
+}
+
+func TestManager_RollbackToPromotion(t *testing.T) {
+	mgr := newPromotionProject(t)
+	first, err := mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	writeTestFile(t, mgr.projectPath, "applications/overlays/dev/kustomization.yaml", testOverlay+`images:
+  - name: registry.example.com/myapp
+    newTag: 1.3.0
+`)
+	_, err = mgr.Promote(PromotionOptions{Application: "myapp", FromEnv: "dev", ToEnv: "staging"})
+	require.NoError(t, err)
+	assert.Contains(t, readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml"), "newTag: 1.3.0")
+
+	// Rolling back the latest promotion restores 1.2.0.
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging"})
+	require.NoError(t, err)
+	assert.Contains(t, readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml"), "newTag: 1.2.0")
+
+	// The next rollback undoes the first promotion.
+	result, err := mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, first.Records[0].ID, result.Record.RollbackOf)
+	overlay := readTestFile(t, mgr.projectPath, "applications/overlays/staging/kustomization.yaml")
+	assert.NotContains(t, overlay, "newTag")
+	assert.NotContains(t, overlay, "myapp/resources.yaml")
+
+	// A promotion ID selects the state before that promotion.
+	result, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging", To: first.Records[0].ID})
+	require.NoError(t, err)
+	assert.Contains(t, result.Message, "Nothing to roll back")
+
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "prod", To: first.Records[0].ID})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "promoted myapp to staging, not myapp to prod")
+
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging", To: result.Target})
+	require.Error(t, err)
+}
+
+func TestManager_RollbackGit(t *testing.T) {
+	mgr := newPromotionProject(t)
+	root := mgr.projectPath
+	writeTestFile(t, root, "applications/overlays/prod/kustomization.yaml", testOverlay+`images:
+  - name: registry.example.com/myapp
+    newTag: 1.1.0
+`)
+	good := commitTestProject(t, root, "Add applications", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+
+	writeTestFile(t, root, "applications/overlays/prod/kustomization.yaml", testOverlay+`images:
+  - name: registry.example.com/myapp
+    newTag: 1.2.0
+`)
+	commitTestProject(t, root, "Release 1.2.0", time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC))
+	writeTestFile(t, root, "applications/overlays/dev/kustomization.yaml", testOverlay)
+	commitTestProject(t, root, "Change dev", time.Date(2026, 1, 12, 12, 0, 0, 0, time.UTC))
+
+	result, err := mgr.Rollback(RollbackOptions{Application: "myapp", Env: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, "Rolled back myapp in prod to revision "+good[:7], result.Message)
+	assert.Equal(t, good, result.Record.RollbackOf)
+	assert.Contains(t, readTestFile(t, root, "applications/overlays/prod/kustomization.yaml"), "newTag: 1.1.0")
+
+	// An explicit revision restores that commit.
+	result, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "prod", To: "HEAD"})
+	require.NoError(t, err)
+	assert.Contains(t, readTestFile(t, root, "applications/overlays/prod/kustomization.yaml"), "newTag: 1.2.0")
+	assert.Equal(t, "Rolled back myapp in prod to revision "+result.Record.RollbackOf[:7], result.Message)
+
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "prod", To: "no-such-revision"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "neither a promotion nor a Git revision")
+
+	// The first commit of the application has no earlier state.
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "staging"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "there is no earlier state")
+}
+
+func TestManager_RollbackHelmRelease(t *testing.T) {
+	mgr := newPromotionProject(t)
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/dev/api.yaml", testHelmRelease("1.3.0", "2.0.0"))
+	writeTestFile(t, mgr.projectPath, "applications/helmreleases/prod/api.yaml", testHelmRelease("1.2.0", "1.0.0"))
+	_, err := mgr.Promote(PromotionOptions{Application: "api", FromEnv: "dev", ToEnv: "prod"})
+	require.NoError(t, err)
+
+	_, err = mgr.Rollback(RollbackOptions{Application: "api", Env: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, testHelmRelease("1.2.0", "1.0.0"), readTestFile(t, mgr.projectPath, "applications/helmreleases/prod/api.yaml"))
+}
+
+func TestManager_RollbackErrors(t *testing.T) {
+	mgr := newPromotionProject(t)
+
+	_, err := mgr.Rollback(RollbackOptions{Env: "prod"})
+	assert.Error(t, err)
+
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "qa"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment qa not found")
+
+	_, err = mgr.Rollback(RollbackOptions{Application: "myapp", Env: "prod"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not in a Git repository")
+
+	_, err = os.Stat(filepath.Join(mgr.projectPath, PromotionHistoryFile))
+	assert.True(t, os.IsNotExist(err), "failed rollbacks record nothing")
+}
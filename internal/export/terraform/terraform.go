@@ -0,0 +1,506 @@
+// Package terraform exports a gitopsi project definition as Terraform/OpenTofu HCL.
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/bootstrap"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+// Flavor represents the IaC tool the exported module targets.
+type Flavor string
+
+const (
+	FlavorTerraform Flavor = "terraform"
+	FlavorOpenTofu  Flavor = "opentofu"
+)
+
+// Resource represents a group of resources that can be exported.
+type Resource string
+
+const (
+	ResourceNamespaces Resource = "namespaces"
+	ResourceArgoCD     Resource = "argocd"
+	ResourceHelm       Resource = "helm"
+)
+
+// Options configures the Terraform export.
+type Options struct {
+	Flavor          Flavor
+	Resources       []Resource
+	ArgoCDNamespace string
+}
+
+// DefaultOptions returns options exporting every resource group for Terraform.
+func DefaultOptions() Options {
+	return Options{
+		Flavor:    FlavorTerraform,
+		Resources: AllResources(),
+	}
+}
+
+// Exporter converts a gitopsi config into Terraform/OpenTofu files.
+type Exporter struct {
+	config  *config.Config
+	options Options
+}
+
+// New creates a new Terraform exporter.
+func New(cfg *config.Config, opts Options) *Exporter {
+	if opts.Flavor == "" {
+		opts.Flavor = FlavorTerraform
+	}
+	if len(opts.Resources) == 0 {
+		opts.Resources = AllResources()
+	}
+	return &Exporter{
+		config:  cfg,
+		options: opts,
+	}
+}
+
+// AllResources returns every exportable resource group.
+func AllResources() []Resource {
+	return []Resource{ResourceNamespaces, ResourceArgoCD, ResourceHelm}
+}
+
+// ValidFlavors returns the supported export flavors.
+func ValidFlavors() []string {
+	return []string{string(FlavorTerraform), string(FlavorOpenTofu)}
+}
+
+// IsValidFlavor checks if a flavor name is supported.
+func IsValidFlavor(flavor string) bool {
+	for _, f := range ValidFlavors() {
+		if f == flavor {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseResources parses a comma-separated list of resource groups.
+func ParseResources(value string) ([]Resource, error) {
+	if strings.TrimSpace(value) == "" || value == "all" {
+		return AllResources(), nil
+	}
+
+	var resources []Resource
+	seen := make(map[Resource]bool)
+	for _, part := range strings.Split(value, ",") {
+		r := Resource(strings.TrimSpace(strings.ToLower(part)))
+		switch r {
+		case ResourceNamespaces, ResourceArgoCD, ResourceHelm:
+		default:
+			return nil, fmt.Errorf("unknown resource type: %s (valid: namespaces, argocd, helm)", part)
+		}
+		if !seen[r] {
+			seen[r] = true
+			resources = append(resources, r)
+		}
+	}
+	return resources, nil
+}
+
+// Export renders the Terraform files keyed by file name.
+func (e *Exporter) Export() (map[string][]byte, error) {
+	if e.config.Project.Name == "" {
+		return nil, fmt.Errorf("project.name is required for export")
+	}
+	if !IsValidFlavor(string(e.options.Flavor)) {
+		return nil, fmt.Errorf("unsupported flavor: %s", e.options.Flavor)
+	}
+
+	files := map[string][]byte{
+		"versions.tf":  []byte(e.renderVersions()),
+		"providers.tf": []byte(e.renderProviders()),
+		"variables.tf": []byte(e.renderVariables()),
+	}
+
+	if e.has(ResourceNamespaces) {
+		files["namespaces.tf"] = []byte(e.renderNamespaces())
+	}
+
+	if e.has(ResourceArgoCD) && e.usesArgoCD() {
+		content, err := e.renderArgoCD()
+		if err != nil {
+			return nil, err
+		}
+		files["argocd.tf"] = []byte(content)
+	}
+
+	if e.has(ResourceHelm) {
+		content, err := e.renderHelm()
+		if err != nil {
+			return nil, err
+		}
+		files["helm.tf"] = []byte(content)
+	}
+
+	return files, nil
+}
+
+func (e *Exporter) has(r Resource) bool {
+	for _, res := range e.options.Resources {
+		if res == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Exporter) usesArgoCD() bool {
+	return e.config.GitOpsTool == "argocd" || e.config.GitOpsTool == "both" || e.config.GitOpsTool == ""
+}
+
+func (e *Exporter) includesInfra() bool {
+	return e.config.Scope == "infrastructure" || e.config.Scope == "both"
+}
+
+func (e *Exporter) includesApps() bool {
+	return e.config.Scope == "application" || e.config.Scope == "both"
+}
+
+func (e *Exporter) argoCDNamespace() string {
+	if e.options.ArgoCDNamespace != "" {
+		return e.options.ArgoCDNamespace
+	}
+	if e.config.Bootstrap.Namespace != "" {
+		return e.config.Bootstrap.Namespace
+	}
+	if e.config.Platform == "openshift" {
+		return "openshift-gitops"
+	}
+	return "argocd"
+}
+
+func (e *Exporter) header() string {
+	tool := "Terraform"
+	if e.options.Flavor == FlavorOpenTofu {
+		tool = "OpenTofu"
+	}
+	return fmt.Sprintf("# Generated by gitopsi export for %s\n# Project: %s\n\n", tool, e.config.Project.Name)
+}
+
+func (e *Exporter) renderVersions() string {
+	var b strings.Builder
+	b.WriteString(e.header())
+
+	requiredVersion := ">= 1.5.0"
+	if e.options.Flavor == FlavorOpenTofu {
+		requiredVersion = ">= 1.6.0"
+	}
+
+	b.WriteString("terraform {\n")
+	fmt.Fprintf(&b, "  required_version = %q\n\n", requiredVersion)
+	b.WriteString("  required_providers {\n")
+	b.WriteString("    kubernetes = {\n      source  = \"hashicorp/kubernetes\"\n      version = \"~> 2.31\"\n    }\n")
+	if e.has(ResourceHelm) {
+		b.WriteString("    helm = {\n      source  = \"hashicorp/helm\"\n      version = \"~> 2.14\"\n    }\n")
+	}
+	if e.has(ResourceArgoCD) && e.usesArgoCD() {
+		b.WriteString("    argocd = {\n      source  = \"argoproj-labs/argocd\"\n      version = \"~> 7.0\"\n    }\n")
+	}
+	b.WriteString("  }\n}\n")
+
+	return b.String()
+}
+
+func (e *Exporter) renderProviders() string {
+	var b strings.Builder
+	b.WriteString(e.header())
+
+	b.WriteString("provider \"kubernetes\" {\n")
+	b.WriteString("  config_path    = var.kube_config_path\n")
+	b.WriteString("  config_context = var.kube_context\n")
+	b.WriteString("}\n")
+
+	if e.has(ResourceHelm) {
+		b.WriteString("\nprovider \"helm\" {\n")
+		b.WriteString("  kubernetes {\n")
+		b.WriteString("    config_path    = var.kube_config_path\n")
+		b.WriteString("    config_context = var.kube_context\n")
+		b.WriteString("  }\n")
+		b.WriteString("}\n")
+	}
+
+	if e.has(ResourceArgoCD) && e.usesArgoCD() {
+		b.WriteString("\nprovider \"argocd\" {\n")
+		b.WriteString("  server_addr = var.argocd_server_addr\n")
+		b.WriteString("  auth_token  = var.argocd_auth_token\n")
+		b.WriteString("  insecure    = var.argocd_insecure\n")
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+func (e *Exporter) renderVariables() string {
+	var b strings.Builder
+	b.WriteString(e.header())
+
+	writeVariable(&b, "kube_config_path", "string", "Path to the kubeconfig file", quote("~/.kube/config"), false)
+	writeVariable(&b, "kube_context", "string", "Kubeconfig context to use", quote(e.config.Cluster.Context), false)
+
+	if e.has(ResourceArgoCD) && e.usesArgoCD() {
+		writeVariable(&b, "argocd_server_addr", "string", "ArgoCD API server address (host:port)", "", false)
+		writeVariable(&b, "argocd_auth_token", "string", "ArgoCD API authentication token", "", true)
+		writeVariable(&b, "argocd_insecure", "bool", "Skip TLS verification for the ArgoCD API", "false", false)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeVariable(b *strings.Builder, name, typ, description, defaultValue string, sensitive bool) {
+	fmt.Fprintf(b, "variable %q {\n", name)
+	fmt.Fprintf(b, "  description = %s\n", quote(description))
+	fmt.Fprintf(b, "  type        = %s\n", typ)
+	if defaultValue != "" {
+		fmt.Fprintf(b, "  default     = %s\n", defaultValue)
+	}
+	if sensitive {
+		b.WriteString("  sensitive   = true\n")
+	}
+	b.WriteString("}\n\n")
+}
+
+func (e *Exporter) renderNamespaces() string {
+	var b strings.Builder
+	b.WriteString(e.header())
+
+	for i, env := range e.config.Environments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "resource \"kubernetes_namespace\" %q {\n", Identifier(env.Name))
+		b.WriteString("  metadata {\n")
+		fmt.Fprintf(&b, "    name = %s\n", quote(e.config.GetEnvironmentNamespace(env.Name)))
+		b.WriteString("    labels = {\n")
+		fmt.Fprintf(&b, "      \"environment\"                  = %s\n", quote(env.Name))
+		fmt.Fprintf(&b, "      \"app.kubernetes.io/part-of\"    = %s\n", quote(e.config.Project.Name))
+		b.WriteString("      \"app.kubernetes.io/managed-by\" = \"terraform\"\n")
+		b.WriteString("    }\n")
+		b.WriteString("  }\n")
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+func (e *Exporter) renderArgoCD() (string, error) {
+	repoURL := e.config.Git.URL
+	if repoURL == "" {
+		repoURL = e.config.Output.URL
+	}
+	if repoURL == "" {
+		return "", fmt.Errorf("git.url is required to export ArgoCD applications")
+	}
+
+	var b strings.Builder
+	b.WriteString(e.header())
+
+	argoNS := e.argoCDNamespace()
+	var projects []string
+	if e.includesInfra() {
+		projects = append(projects, "infrastructure")
+		e.writeArgoCDProject(&b, "infrastructure", "Infrastructure resources", argoNS, repoURL)
+	}
+	if e.includesApps() {
+		projects = append(projects, "applications")
+		e.writeArgoCDProject(&b, "applications", "Application deployments", argoNS, repoURL)
+	}
+
+	for _, env := range e.config.Environments {
+		for _, project := range projects {
+			kind, dir := "infra", "infrastructure"
+			if project == "applications" {
+				kind, dir = "apps", "applications"
+			}
+			e.writeArgoCDApplication(&b, argoCDApp{
+				Name:      fmt.Sprintf("%s-%s-%s", e.config.Project.Name, kind, env.Name),
+				Project:   project,
+				RepoURL:   repoURL,
+				Revision:  "HEAD",
+				Path:      fmt.Sprintf("%s/overlays/%s", dir, env.Name),
+				Server:    env.Cluster,
+				Namespace: e.config.GetEnvironmentNamespace(env.Name),
+				ArgoNS:    argoNS,
+			})
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func (e *Exporter) writeArgoCDProject(b *strings.Builder, name, description, argoNS, repoURL string) {
+	fmt.Fprintf(b, "resource \"argocd_project\" %q {\n", Identifier(name))
+	b.WriteString("  metadata {\n")
+	fmt.Fprintf(b, "    name      = %s\n", quote(name))
+	fmt.Fprintf(b, "    namespace = %s\n", quote(argoNS))
+	b.WriteString("  }\n\n")
+	b.WriteString("  spec {\n")
+	fmt.Fprintf(b, "    description  = %s\n", quote(description))
+	fmt.Fprintf(b, "    source_repos = [%s]\n\n", quote(repoURL))
+	b.WriteString("    destination {\n")
+	b.WriteString("      server    = \"*\"\n")
+	b.WriteString("      namespace = \"*\"\n")
+	b.WriteString("    }\n\n")
+	b.WriteString("    cluster_resource_whitelist {\n")
+	b.WriteString("      group = \"*\"\n")
+	b.WriteString("      kind  = \"*\"\n")
+	b.WriteString("    }\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n\n")
+}
+
+type argoCDApp struct {
+	Name      string
+	Project   string
+	RepoURL   string
+	Revision  string
+	Path      string
+	Server    string
+	Namespace string
+	ArgoNS    string
+}
+
+func (e *Exporter) writeArgoCDApplication(b *strings.Builder, app argoCDApp) {
+	server := app.Server
+	if server == "" {
+		server = "https://kubernetes.default.svc"
+	}
+
+	fmt.Fprintf(b, "resource \"argocd_application\" %q {\n", Identifier(app.Name))
+	b.WriteString("  metadata {\n")
+	fmt.Fprintf(b, "    name      = %s\n", quote(app.Name))
+	fmt.Fprintf(b, "    namespace = %s\n", quote(app.ArgoNS))
+	b.WriteString("  }\n\n")
+	b.WriteString("  spec {\n")
+	fmt.Fprintf(b, "    project = argocd_project.%s.metadata[0].name\n\n", Identifier(app.Project))
+	b.WriteString("    source {\n")
+	fmt.Fprintf(b, "      repo_url        = %s\n", quote(app.RepoURL))
+	fmt.Fprintf(b, "      target_revision = %s\n", quote(app.Revision))
+	fmt.Fprintf(b, "      path            = %s\n", quote(app.Path))
+	b.WriteString("    }\n\n")
+	b.WriteString("    destination {\n")
+	fmt.Fprintf(b, "      server    = %s\n", quote(server))
+	fmt.Fprintf(b, "      namespace = %s\n", quote(app.Namespace))
+	b.WriteString("    }\n\n")
+	b.WriteString("    sync_policy {\n")
+	b.WriteString("      automated {\n")
+	b.WriteString("        prune     = true\n")
+	b.WriteString("        self_heal = true\n")
+	b.WriteString("      }\n")
+	b.WriteString("      sync_options = [\"CreateNamespace=true\"]\n")
+	b.WriteString("    }\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n\n")
+}
+
+func (e *Exporter) renderHelm() (string, error) {
+	tool := bootstrap.ToolArgoCD
+	releaseName := "argocd"
+	if e.config.GitOpsTool == "flux" {
+		tool = bootstrap.ToolFlux
+		releaseName = "flux2"
+	}
+
+	helmCfg := bootstrap.DefaultHelmConfig(tool)
+	namespace := e.argoCDNamespace()
+	if tool == bootstrap.ToolFlux {
+		namespace = "flux-system"
+		if e.config.Bootstrap.Namespace != "" {
+			namespace = e.config.Bootstrap.Namespace
+		}
+	}
+
+	version := e.config.Bootstrap.Version
+	var values map[string]any
+	var setValues map[string]string
+	if h := e.config.Bootstrap.Helm; h != nil {
+		if h.Repo != "" {
+			helmCfg.Repo = h.Repo
+		}
+		if h.Chart != "" {
+			helmCfg.Chart = h.Chart
+		}
+		if h.Version != "" {
+			version = h.Version
+		}
+		values = h.Values
+		setValues = h.SetValues
+	}
+
+	var b strings.Builder
+	b.WriteString(e.header())
+
+	fmt.Fprintf(&b, "resource \"helm_release\" %q {\n", Identifier(releaseName))
+	fmt.Fprintf(&b, "  name             = %s\n", quote(releaseName))
+	fmt.Fprintf(&b, "  repository       = %s\n", quote(helmCfg.Repo))
+	fmt.Fprintf(&b, "  chart            = %s\n", quote(helmCfg.Chart))
+	if version != "" {
+		fmt.Fprintf(&b, "  version          = %s\n", quote(version))
+	}
+	fmt.Fprintf(&b, "  namespace        = %s\n", quote(namespace))
+	b.WriteString("  create_namespace = true\n")
+	b.WriteString("  wait             = true\n")
+	if e.config.Bootstrap.Timeout > 0 {
+		fmt.Fprintf(&b, "  timeout          = %d\n", e.config.Bootstrap.Timeout)
+	}
+
+	if len(values) > 0 {
+		data, err := yaml.Marshal(values)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal helm values: %w", err)
+		}
+		b.WriteString("\n  values = [\n    <<-EOT\n")
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+		b.WriteString("    EOT\n  ]\n")
+	}
+
+	keys := make([]string, 0, len(setValues))
+	for k := range setValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString("\n  set {\n")
+		fmt.Fprintf(&b, "    name  = %s\n", quote(k))
+		fmt.Fprintf(&b, "    value = %s\n", quote(setValues[k]))
+		b.WriteString("  }\n")
+	}
+
+	b.WriteString("}\n")
+
+	return b.String(), nil
+}
+
+var invalidIdentifierChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Identifier converts a name into a valid HCL resource identifier.
+func Identifier(name string) string {
+	id := invalidIdentifierChars.ReplaceAllString(name, "_")
+	if id == "" {
+		return "_"
+	}
+	if c := id[0]; (c >= '0' && c <= '9') || c == '-' {
+		id = "_" + id
+	}
+	return id
+}
+
+// quote renders a string as an HCL string literal, escaping interpolation sequences.
+func quote(s string) string {
+	q := fmt.Sprintf("%q", s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	q = strings.ReplaceAll(q, "%{", "%%{")
+	return q
+}
@@ -0,0 +1,122 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func newTestConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "demo"
+	cfg.Git.URL = "https://github.com/org/demo.git"
+	return cfg
+}
+
+func TestExport_AllResources(t *testing.T) {
+	files, err := New(newTestConfig(), DefaultOptions()).Export()
+	require.NoError(t, err)
+
+	for _, name := range []string{"versions.tf", "providers.tf", "variables.tf", "namespaces.tf", "argocd.tf", "helm.tf"} {
+		assert.Contains(t, files, name)
+	}
+
+	ns := string(files["namespaces.tf"])
+	assert.Contains(t, ns, `resource "kubernetes_namespace" "dev"`)
+	assert.Contains(t, ns, `name = "demo-prod"`)
+
+	argo := string(files["argocd.tf"])
+	assert.Contains(t, argo, `resource "argocd_project" "infrastructure"`)
+	assert.Contains(t, argo, `resource "argocd_application" "demo-apps-staging"`)
+	assert.Contains(t, argo, `path            = "infrastructure/overlays/dev"`)
+	assert.Contains(t, argo, `server    = "https://kubernetes.default.svc"`)
+
+	helm := string(files["helm.tf"])
+	assert.Contains(t, helm, `chart            = "argo-cd"`)
+	assert.Contains(t, helm, `namespace        = "argocd"`)
+}
+
+func TestExport_SelectedResources(t *testing.T) {
+	files, err := New(newTestConfig(), Options{Resources: []Resource{ResourceNamespaces}}).Export()
+	require.NoError(t, err)
+
+	assert.Contains(t, files, "namespaces.tf")
+	assert.NotContains(t, files, "argocd.tf")
+	assert.NotContains(t, files, "helm.tf")
+	assert.NotContains(t, string(files["versions.tf"]), "argoproj-labs/argocd")
+	assert.NotContains(t, string(files["providers.tf"]), `provider "helm"`)
+}
+
+func TestExport_OpenTofuFlavor(t *testing.T) {
+	files, err := New(newTestConfig(), Options{Flavor: FlavorOpenTofu}).Export()
+	require.NoError(t, err)
+
+	versions := string(files["versions.tf"])
+	assert.Contains(t, versions, "OpenTofu")
+	assert.Contains(t, versions, `required_version = ">= 1.6.0"`)
+}
+
+func TestExport_RequiresGitURLForArgoCD(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Git.URL = ""
+
+	_, err := New(cfg, Options{Resources: []Resource{ResourceArgoCD}}).Export()
+	assert.Error(t, err)
+}
+
+func TestExport_ScopeAndFlux(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Scope = "infrastructure"
+	files, err := New(cfg, DefaultOptions()).Export()
+	require.NoError(t, err)
+	assert.NotContains(t, string(files["argocd.tf"]), `"applications"`)
+
+	cfg.GitOpsTool = "flux"
+	files, err = New(cfg, DefaultOptions()).Export()
+	require.NoError(t, err)
+	assert.NotContains(t, files, "argocd.tf")
+	assert.Contains(t, string(files["helm.tf"]), `chart            = "flux2"`)
+	assert.Contains(t, string(files["helm.tf"]), `namespace        = "flux-system"`)
+}
+
+func TestExport_HelmValues(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Bootstrap.Helm = &config.BootstrapHelmConfig{
+		Version:   "7.3.0",
+		Values:    map[string]any{"server": map[string]any{"replicas": 2}},
+		SetValues: map[string]string{"b.key": "2", "a.key": "1"},
+	}
+
+	files, err := New(cfg, Options{Resources: []Resource{ResourceHelm}}).Export()
+	require.NoError(t, err)
+
+	helm := string(files["helm.tf"])
+	assert.Contains(t, helm, `version          = "7.3.0"`)
+	assert.Contains(t, helm, "<<-EOT")
+	assert.Contains(t, helm, "replicas: 2")
+	assert.Less(t, strings.Index(helm, `"a.key"`), strings.Index(helm, `"b.key"`))
+}
+
+func TestParseResources(t *testing.T) {
+	res, err := ParseResources("")
+	require.NoError(t, err)
+	assert.Equal(t, AllResources(), res)
+
+	res, err = ParseResources("helm, namespaces,helm")
+	require.NoError(t, err)
+	assert.Equal(t, []Resource{ResourceHelm, ResourceNamespaces}, res)
+
+	_, err = ParseResources("ingress")
+	assert.Error(t, err)
+}
+
+func TestIdentifierAndQuote(t *testing.T) {
+	assert.Equal(t, "my-app", Identifier("my-app"))
+	assert.Equal(t, "_1env", Identifier("1env"))
+	assert.Equal(t, "a_b", Identifier("a.b"))
+	assert.Equal(t, `"$${HOME}"`, quote("${HOME}"))
+}
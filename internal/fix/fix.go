@@ -0,0 +1,428 @@
+// Package fix rewrites manifests to apply the remediations of the findings
+// of gitopsi validate, editing YAML nodes so that comments are kept.
+package fix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
+)
+
+// Rules are the validate rules with a known fix.
+var Rules = map[string]string{
+	"DEP001": "migrate deprecated apiVersions of workloads, RBAC and NetworkPolicies",
+	"SEC002": "set runAsNonRoot: true",
+	"SEC003": "add resource requests and limits from the default profile",
+	"SEC007": "set the RuntimeDefault seccomp profile",
+	"SEC008": "drop ALL capabilities",
+}
+
+// Options select the fixes to apply.
+type Options struct {
+	// Rules restricts the fixes to these rules (default: every rule of Rules)
+	Rules []string
+	// Resources are added to containers without limits (default:
+	// config.DefaultResources)
+	Resources *config.Resources
+}
+
+// Change is a fix applied to a resource.
+type Change struct {
+	Resource    string `json:"resource" yaml:"resource"`
+	Rule        string `json:"rule" yaml:"rule"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// Skipped is a finding left for a person to fix.
+type Skipped struct {
+	File     string `json:"file" yaml:"file"`
+	Resource string `json:"resource" yaml:"resource"`
+	Rule     string `json:"rule" yaml:"rule"`
+	Reason   string `json:"reason" yaml:"reason"`
+}
+
+// File is a manifest the fixes changed.
+type File struct {
+	Path     string   `json:"path" yaml:"path"`
+	Changes  []Change `json:"changes" yaml:"changes"`
+	Original []byte   `json:"-" yaml:"-"`
+	Content  []byte   `json:"-" yaml:"-"`
+}
+
+// Result holds the files the fixes changed and the findings they skipped.
+type Result struct {
+	Files   []File    `json:"files" yaml:"files"`
+	Skipped []Skipped `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// Changes counts the changes of every file.
+func (r *Result) Changes() int {
+	n := 0
+	for _, f := range r.Files {
+		n += len(f.Changes)
+	}
+	return n
+}
+
+// Plan computes the fixes of the issues with a known fix, file by file,
+// without writing them. Every resource of a file with an issue of a rule is
+// fixed for that rule.
+func Plan(issues []validate.Issue, opts Options) (*Result, error) {
+	selected := map[string]bool{}
+	for rule := range Rules {
+		selected[rule] = len(opts.Rules) == 0
+	}
+	for _, rule := range opts.Rules {
+		rule = strings.ToUpper(rule)
+		if _, ok := Rules[rule]; !ok {
+			return nil, fmt.Errorf("no fix for rule %s", rule)
+		}
+		selected[rule] = true
+	}
+	resources := config.DefaultResources
+	if opts.Resources != nil {
+		resources = *opts.Resources
+	}
+
+	byFile := map[string]map[string]bool{}
+	for _, issue := range issues {
+		if !selected[issue.Rule] {
+			continue
+		}
+		if byFile[issue.File] == nil {
+			byFile[issue.File] = map[string]bool{}
+		}
+		byFile[issue.File][issue.Rule] = true
+	}
+	files := make([]string, 0, len(byFile))
+	for file := range byFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	result := &Result{}
+	for _, path := range files {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		f := &fixer{rules: byFile[path], resources: resources, file: path}
+		content, err := f.apply(original)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fix %s: %w", path, err)
+		}
+		result.Skipped = append(result.Skipped, f.skipped...)
+		if len(f.changes) > 0 {
+			result.Files = append(result.Files, File{Path: path, Changes: f.changes, Original: original, Content: content})
+		}
+	}
+	return result, nil
+}
+
+// Write writes the fixed files.
+func (r *Result) Write() error {
+	for _, f := range r.Files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", f.Path, err)
+		}
+		if err := os.WriteFile(f.Path, f.Content, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// fixer applies the fixes of rules to the resources of a file.
+type fixer struct {
+	rules     map[string]bool
+	resources config.Resources
+	file      string
+	resource  string
+	changes   []Change
+	skipped   []Skipped
+}
+
+func (f *fixer) change(rule, description string) {
+	f.changes = append(f.changes, Change{Resource: f.resource, Rule: rule, Description: description})
+}
+
+func (f *fixer) skip(rule, reason string) {
+	f.skipped = append(f.skipped, Skipped{File: f.file, Resource: f.resource, Rule: rule, Reason: reason})
+}
+
+// apply returns the content with the fixes applied, or the content itself
+// when nothing changed. Content that is not YAML is not changed.
+func (f *fixer) apply(content []byte) ([]byte, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return content, nil
+		}
+		docs = append(docs, &doc)
+	}
+
+	for _, doc := range docs {
+		if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		resource := doc.Content[0]
+		kind := scalarValue(resource, "kind")
+		if kind == "" || mappingValue(resource, "sops", false) != nil {
+			continue
+		}
+		f.resource = kind + " " + scalarValue(mappingValue(resource, "metadata", false), "name")
+		if f.rules["DEP001"] {
+			f.migrateAPIVersion(resource)
+		}
+		if spec := podSpec(resource); spec != nil {
+			f.fixPodSpec(spec)
+		}
+	}
+	if len(f.changes) == 0 {
+		return content, nil
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// apiMigrations are the replacements of deprecated apiVersions, by kind,
+// whose schemas are compatible.
+var apiMigrations = map[string]map[string]string{
+	"Deployment":         {"extensions/v1beta1": "apps/v1", "apps/v1beta1": "apps/v1", "apps/v1beta2": "apps/v1"},
+	"DaemonSet":          {"extensions/v1beta1": "apps/v1", "apps/v1beta2": "apps/v1"},
+	"ReplicaSet":         {"extensions/v1beta1": "apps/v1", "apps/v1beta2": "apps/v1"},
+	"StatefulSet":        {"apps/v1beta1": "apps/v1", "apps/v1beta2": "apps/v1"},
+	"NetworkPolicy":      {"extensions/v1beta1": "networking.k8s.io/v1"},
+	"Role":               {"rbac.authorization.k8s.io/v1beta1": "rbac.authorization.k8s.io/v1"},
+	"ClusterRole":        {"rbac.authorization.k8s.io/v1beta1": "rbac.authorization.k8s.io/v1"},
+	"RoleBinding":        {"rbac.authorization.k8s.io/v1beta1": "rbac.authorization.k8s.io/v1"},
+	"ClusterRoleBinding": {"rbac.authorization.k8s.io/v1beta1": "rbac.authorization.k8s.io/v1"},
+}
+
+// deprecatedAPIs are the apiVersions the validate deprecation check reports.
+var deprecatedAPIs = map[string]bool{
+	"extensions/v1beta1":                   true,
+	"apps/v1beta1":                         true,
+	"apps/v1beta2":                         true,
+	"networking.k8s.io/v1beta1":            true,
+	"rbac.authorization.k8s.io/v1beta1":    true,
+	"admissionregistration.k8s.io/v1beta1": true,
+}
+
+func (f *fixer) migrateAPIVersion(resource *yaml.Node) {
+	apiVersion := mappingValue(resource, "apiVersion", false)
+	if apiVersion == nil || !deprecatedAPIs[apiVersion.Value] {
+		return
+	}
+	kind := scalarValue(resource, "kind")
+	replacement, ok := apiMigrations[kind][apiVersion.Value]
+	if !ok {
+		f.skip("DEP001", fmt.Sprintf("the %s schema of %s changed: migrate it by hand", apiVersion.Value, kind))
+		return
+	}
+	// apps/v1 requires the selector the beta versions defaulted.
+	if replacement == "apps/v1" && mappingValue(mappingValue(resource, "spec", false), "selector", false) == nil {
+		f.skip("DEP001", "apps/v1 requires spec.selector: add it before migrating")
+		return
+	}
+	f.change("DEP001", fmt.Sprintf("apiVersion %s → %s", apiVersion.Value, replacement))
+	apiVersion.Value = replacement
+}
+
+// podSpec returns the pod spec of a workload, or nil.
+func podSpec(resource *yaml.Node) *yaml.Node {
+	spec := mappingValue(resource, "spec", false)
+	switch scalarValue(resource, "kind") {
+	case "Pod":
+		return spec
+	case "CronJob":
+		spec = mappingValue(mappingValue(spec, "jobTemplate", false), "spec", false)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "Rollout", "DeploymentConfig":
+	default:
+		return nil
+	}
+	return mappingValue(mappingValue(spec, "template", false), "spec", false)
+}
+
+func (f *fixer) fixPodSpec(spec *yaml.Node) {
+	var containers []*yaml.Node
+	for _, key := range []string{"initContainers", "containers"} {
+		if list := mappingValue(spec, key, false); list != nil && list.Kind == yaml.SequenceNode {
+			containers = append(containers, list.Content...)
+		}
+	}
+	pod := mappingValue(spec, "securityContext", false)
+
+	if f.rules["SEC007"] {
+		f.fixSeccomp(spec, pod, containers)
+	}
+	for _, container := range containers {
+		if container.Kind != yaml.MappingNode {
+			continue
+		}
+		name := scalarValue(container, "name")
+		if f.rules["SEC002"] {
+			f.fixNonRoot(name, pod, container)
+		}
+		if f.rules["SEC003"] {
+			f.fixResources(name, container)
+		}
+		if f.rules["SEC008"] {
+			f.fixCapabilities(name, container)
+		}
+	}
+}
+
+func (f *fixer) fixNonRoot(name string, pod, container *yaml.Node) {
+	sc := mappingValue(container, "securityContext", false)
+	setting := func(key string) string {
+		if value := scalarValue(sc, key); value != "" {
+			return value
+		}
+		return scalarValue(pod, key)
+	}
+	switch {
+	case setting("runAsUser") == "0":
+		f.skip("SEC002", fmt.Sprintf("container %s runs as user 0", name))
+	case setting("runAsUser") != "" || setting("runAsNonRoot") == "true":
+	case setting("runAsNonRoot") == "false":
+		f.skip("SEC002", fmt.Sprintf("container %s sets runAsNonRoot: false", name))
+	default:
+		setScalar(mappingValue(container, "securityContext", true), "runAsNonRoot", "true", "!!bool")
+		f.change("SEC002", fmt.Sprintf("container %s: runAsNonRoot: true", name))
+	}
+}
+
+func (f *fixer) fixResources(name string, container *yaml.Node) {
+	resources := mappingValue(container, "resources", false)
+	if mappingValue(resources, "limits", false) != nil {
+		return
+	}
+	resources = mappingValue(container, "resources", true)
+	if mappingValue(resources, "requests", false) == nil && f.resources.Requests != (config.ResourceList{}) {
+		setResourceList(mappingValue(resources, "requests", true), f.resources.Requests)
+	}
+	limits := mappingValue(resources, "limits", true)
+	setResourceList(limits, f.resources.Limits)
+	f.change("SEC003", fmt.Sprintf("container %s: limits cpu %s, memory %s", name, f.resources.Limits.CPU, f.resources.Limits.Memory))
+}
+
+func setResourceList(node *yaml.Node, list config.ResourceList) {
+	if list.CPU != "" {
+		setScalar(node, "cpu", list.CPU, "!!str")
+	}
+	if list.Memory != "" {
+		setScalar(node, "memory", list.Memory, "!!str")
+	}
+}
+
+func (f *fixer) fixSeccomp(spec, pod *yaml.Node, containers []*yaml.Node) {
+	for _, container := range containers {
+		profile := scalarValue(mappingValue(mappingValue(container, "securityContext", false), "seccompProfile", false), "type")
+		if profile == "Unconfined" {
+			f.skip("SEC007", fmt.Sprintf("container %s sets the Unconfined seccomp profile", scalarValue(container, "name")))
+			return
+		}
+	}
+	if scalarValue(mappingValue(pod, "seccompProfile", false), "type") != "" {
+		return
+	}
+	pod = mappingValue(spec, "securityContext", true)
+	setScalar(mappingValue(pod, "seccompProfile", true), "type", "RuntimeDefault", "!!str")
+	f.change("SEC007", "seccompProfile: RuntimeDefault")
+}
+
+func (f *fixer) fixCapabilities(name string, container *yaml.Node) {
+	drop := mappingValue(mappingValue(mappingValue(container, "securityContext", false), "capabilities", false), "drop", false)
+	if drop != nil && drop.Kind == yaml.SequenceNode {
+		for _, c := range drop.Content {
+			if strings.EqualFold(c.Value, "ALL") {
+				return
+			}
+		}
+	}
+	capabilities := mappingValue(mappingValue(container, "securityContext", true), "capabilities", true)
+	drop = mappingValue(capabilities, "drop", false)
+	if drop == nil || drop.Kind != yaml.SequenceNode {
+		drop = &yaml.Node{Kind: yaml.SequenceNode}
+		setNode(capabilities, "drop", drop)
+	}
+	drop.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Value: "ALL"}}
+	f.change("SEC008", fmt.Sprintf("container %s: capabilities.drop: [ALL]", name))
+}
+
+// mappingValue returns the value of key in a mapping, adding an empty
+// mapping when create is set and the key is missing.
+func mappingValue(node *yaml.Node, key string, create bool) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			if create && value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+				value.Kind, value.Tag, value.Value = yaml.MappingNode, "", ""
+			}
+			return value
+		}
+	}
+	if !create {
+		return nil
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+// setNode sets key of a mapping to value.
+func setNode(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+func setScalar(node *yaml.Node, key, value, tag string) {
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value, Tag: tag}
+	if tag == "!!str" && value != "" && strings.Trim(value, "0123456789.") == "" {
+		scalar.Style = yaml.DoubleQuotedStyle
+	}
+	setNode(node, key, scalar)
+}
+
+func scalarValue(node *yaml.Node, key string) string {
+	if value := mappingValue(node, key, false); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
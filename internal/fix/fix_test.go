@@ -0,0 +1,138 @@
+package fix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
+)
+
+const manifests = `# Web frontend
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: web # the public site
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      containers:
+        - name: app
+          image: nginx:1.25 # pinned
+          securityContext:
+            capabilities:
+              drop: [NET_RAW]
+---
+apiVersion: apps/v1beta1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsUser: 0
+      containers:
+        - name: db
+          image: postgres:16
+          resources:
+            limits:
+              memory: 1Gi
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: web
+`
+
+func validateIssues(t *testing.T, dir string) []validate.Issue {
+	t.Helper()
+	result, err := validate.New(&validate.Options{Path: dir, Security: true, Deprecation: true}).Validate(context.Background())
+	require.NoError(t, err)
+	return result.Issues
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(manifests), 0644))
+
+	result, err := Plan(validateIssues(t, dir), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	file := result.Files[0]
+	assert.Equal(t, path, file.Path)
+	assert.Equal(t, manifests, string(file.Original))
+
+	var rules []string
+	for _, change := range file.Changes {
+		rules = append(rules, change.Resource+" "+change.Rule)
+	}
+	assert.Equal(t, []string{
+		"Deployment web DEP001",
+		"Deployment web SEC007",
+		"Deployment web SEC002",
+		"Deployment web SEC003",
+		"Deployment web SEC008",
+		"StatefulSet db SEC007",
+		"StatefulSet db SEC008",
+	}, rules)
+
+	content := string(file.Content)
+	assert.Contains(t, content, "# Web frontend\napiVersion: apps/v1\n", "comments are kept")
+	assert.Contains(t, content, "name: web # the public site")
+	assert.Contains(t, content, "image: nginx:1.25 # pinned")
+	assert.Contains(t, content, "drop:\n                - ALL\n")
+	assert.Contains(t, content, "limits:\n              cpu: 200m\n              memory: 128Mi\n")
+	assert.Contains(t, content, "apiVersion: apps/v1beta1\nkind: StatefulSet", "apps/v1 requires a selector")
+
+	var skipped []string
+	for _, s := range result.Skipped {
+		skipped = append(skipped, s.Resource+" "+s.Rule)
+	}
+	assert.Equal(t, []string{"StatefulSet db DEP001", "StatefulSet db SEC002", "Ingress web DEP001"}, skipped)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, manifests, string(data), "the plan is not written")
+}
+
+func TestPlanIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(manifests), 0644))
+
+	result, err := Plan(validateIssues(t, dir), Options{})
+	require.NoError(t, err)
+	require.NoError(t, result.Write())
+
+	again, err := Plan(validateIssues(t, dir), Options{})
+	require.NoError(t, err)
+	assert.Empty(t, again.Files, "fixed resources are not found again")
+}
+
+func TestPlanRules(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(manifests), 0644))
+	issues := validateIssues(t, dir)
+
+	resources := &config.Resources{Limits: config.ResourceList{CPU: "1", Memory: "512Mi"}}
+	result, err := Plan(issues, Options{Rules: []string{"sec003"}, Resources: resources})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	require.Len(t, result.Files[0].Changes, 1)
+	assert.Equal(t, "container app: limits cpu 1, memory 512Mi", result.Files[0].Changes[0].Description)
+	assert.Contains(t, string(result.Files[0].Content), "limits:\n              cpu: \"1\"\n              memory: 512Mi\n")
+	assert.NotContains(t, string(result.Files[0].Content), "requests:")
+
+	_, err = Plan(issues, Options{Rules: []string{"SEC001"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no fix for rule SEC001")
+}
@@ -0,0 +1,314 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// addonsNamespace is the namespace of the cloud controllers.
+const addonsNamespace = "kube-system"
+
+// cloudAddon is a controller chart installed on the clusters of a cloud
+// platform.
+type cloudAddon struct {
+	Name           string
+	RepoURL        string
+	Chart          string
+	Version        string
+	ServiceAccount string
+	// values returns the chart values of a cluster, without the service
+	// account ones
+	values func(cluster addonCluster) map[string]any
+}
+
+var (
+	awsLoadBalancerController = cloudAddon{
+		Name:           "aws-load-balancer-controller",
+		RepoURL:        "https://aws.github.io/eks-charts",
+		Chart:          "aws-load-balancer-controller",
+		Version:        "1.8.1",
+		ServiceAccount: "aws-load-balancer-controller",
+		values: func(cluster addonCluster) map[string]any {
+			values := map[string]any{"clusterName": cluster.Name}
+			if cluster.Region != "" {
+				values["region"] = cluster.Region
+			}
+			return values
+		},
+	}
+	clusterAutoscaler = cloudAddon{
+		Name:           "cluster-autoscaler",
+		RepoURL:        "https://kubernetes.github.io/autoscaler",
+		Chart:          "cluster-autoscaler",
+		Version:        "9.37.0",
+		ServiceAccount: "cluster-autoscaler",
+		values: func(cluster addonCluster) map[string]any {
+			values := map[string]any{
+				"cloudProvider": "aws",
+				"autoDiscovery": map[string]any{"clusterName": cluster.Name},
+			}
+			if cluster.Region != "" {
+				values["awsRegion"] = cluster.Region
+			}
+			return values
+		},
+	}
+)
+
+// addonCluster is a cluster the cloud controllers are installed on.
+type addonCluster struct {
+	Env    string
+	Name   string // cloud name of the cluster
+	Region string
+	Server string
+}
+
+// addonClusters returns the clusters of the environments, once each.
+func (g *Generator) addonClusters() []addonCluster {
+	var clusters []addonCluster
+	seen := map[string]bool{}
+	add := func(c addonCluster) {
+		if c.Server == "" {
+			c.Server = inClusterServer
+		}
+		if seen[c.Server] {
+			return
+		}
+		seen[c.Server] = true
+		clusters = append(clusters, c)
+	}
+
+	for _, env := range g.Config.Environments {
+		if len(env.Clusters) > 0 {
+			for _, c := range env.Clusters {
+				region := c.Region
+				if region == "" {
+					region = g.Config.Addons.Region
+				}
+				add(addonCluster{Env: env.Name, Name: c.Name, Region: region, Server: c.URL})
+			}
+			continue
+		}
+
+		cluster := addonCluster{
+			Env:    env.Name,
+			Name:   g.Config.Addons.ClusterName,
+			Region: g.Config.Addons.Region,
+			Server: env.Cluster,
+		}
+		for _, p := range g.Config.Clusters {
+			if p.Name == env.Name || p.Environment == env.Name {
+				cluster.Name = p.Name
+				if p.Region != "" {
+					cluster.Region = p.Region
+				}
+				break
+			}
+		}
+		if cluster.Name == "" {
+			cluster.Name = g.Config.Project.Name + "-" + env.Name
+		}
+		add(cluster)
+	}
+	return clusters
+}
+
+// generatesCloudAddons reports whether a cloud controller is enabled.
+func (g *Generator) generatesCloudAddons() bool {
+	return g.Config.Addons.LoadBalancerController.On() || g.Config.Addons.ClusterAutoscaler.On()
+}
+
+// generateCloudAddons writes the cloud controllers of the platform: ArgoCD
+// Applications installing their charts on every cluster, or Flux
+// HelmReleases in flux/addons. Flux reconciles the cluster it runs in only,
+// so its releases are configured for the first cluster.
+func (g *Generator) generateCloudAddons() error {
+	g.printf("☁️  Generating %s add-ons...\n", g.Config.Platform)
+
+	type enabledAddon struct {
+		cloudAddon
+		config.CloudAddon
+	}
+	var addons []enabledAddon
+	for _, a := range []struct {
+		addon  cloudAddon
+		config *config.CloudAddon
+	}{
+		{awsLoadBalancerController, g.Config.Addons.LoadBalancerController},
+		{clusterAutoscaler, g.Config.Addons.ClusterAutoscaler},
+	} {
+		if a.config.On() {
+			addons = append(addons, enabledAddon{a.addon, *a.config})
+		}
+	}
+
+	for _, addon := range addons {
+		version := addon.CloudAddon.Version
+		if version == "" {
+			version = addon.cloudAddon.Version
+		}
+		clusters := g.addonClusters()
+		for i, cluster := range clusters {
+			values, err := addonValues(addon.cloudAddon, addon.Identity, cluster)
+			if err != nil {
+				return err
+			}
+			if g.Config.GitOpsTool == "argocd" || g.Config.GitOpsTool == "both" {
+				if err := g.writeArgoCDAddon(addon.cloudAddon, version, values, cluster); err != nil {
+					return err
+				}
+			}
+			if g.usesFlux() && i == 0 {
+				if err := g.writeFluxAddon(addon.cloudAddon, version, values); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addonValues returns the chart values of an add-on on a cluster, with the
+// service account bound to the IAM role identity.
+func addonValues(addon cloudAddon, identity string, cluster addonCluster) (string, error) {
+	values := addon.values(cluster)
+	serviceAccount := map[string]any{"create": true, "name": addon.ServiceAccount}
+	if identity != "" {
+		serviceAccount["annotations"] = map[string]any{"eks.amazonaws.com/role-arn": identity}
+	}
+	if addon.Name == clusterAutoscaler.Name {
+		values["rbac"] = map[string]any{"serviceAccount": serviceAccount}
+	} else {
+		values["serviceAccount"] = serviceAccount
+	}
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(values); err != nil {
+		return "", fmt.Errorf("failed to marshal %s values: %w", addon.Name, err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+func (g *Generator) writeArgoCDAddon(addon cloudAddon, version, values string, cluster addonCluster) error {
+	content, err := templates.Render("argocd/application-helm.yaml.tmpl", map[string]any{
+		"Name":            fmt.Sprintf("%s-%s-%s", g.Config.Project.Name, addon.Name, cluster.Env),
+		"ArgoCDNamespace": g.getArgoCDNamespace(),
+		"SyncWave":        infrastructureSyncWave,
+		"Project":         "infrastructure",
+		"RepoURL":         addon.RepoURL,
+		"Chart":           addon.Chart,
+		"Version":         version,
+		"ReleaseName":     addon.Name,
+		"Values":          values,
+		"Server":          cluster.Server,
+		"Namespace":       addonsNamespace,
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/%s/applicationsets/addon-%s-%s.yaml",
+		g.Config.Project.Name, g.Config.GitOpsTool, addon.Name, cluster.Env)
+	return g.Writer.WriteFile(path, content)
+}
+
+func (g *Generator) writeFluxAddon(addon cloudAddon, version, values string) error {
+	fluxNamespace := g.getFluxNamespace()
+	repository, err := templates.Render("flux/helmrepository.yaml.tmpl", map[string]any{
+		"Name":      addon.Name,
+		"Namespace": fluxNamespace,
+		"Interval":  "1h",
+		"URL":       addon.RepoURL,
+	})
+	if err != nil {
+		return err
+	}
+	release, err := templates.Render("flux/helmrelease.yaml.tmpl", map[string]any{
+		"Name":               addon.Name,
+		"ReleaseName":        addon.Name,
+		"Namespace":          fluxNamespace,
+		"Interval":           g.getFluxInterval(),
+		"Chart":              addon.Chart,
+		"Version":            version,
+		"RepoName":           addon.Name,
+		"RepoNamespace":      fluxNamespace,
+		"TargetNamespace":    addonsNamespace,
+		"Values":             values,
+		"ServiceAccountName": g.fluxServiceAccount("helm-controller"),
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/flux/addons/%s.yaml", g.Config.Project.Name, addon.Name)
+	return g.Writer.WriteFile(path, joinDocs([][]byte{bytes.TrimSpace(repository), bytes.TrimSpace(release)}))
+}
+
+// storageClass is a CSI storage class of a cloud platform.
+type storageClass struct {
+	Name        string
+	Provisioner string
+	Parameters  []metadataEntry
+}
+
+// cloudStorageClasses are the storage classes generated per platform; the
+// first one is made the default.
+var cloudStorageClasses = map[string][]storageClass{
+	"eks": {
+		{Name: "gp3", Provisioner: "ebs.csi.aws.com", Parameters: []metadataEntry{{"type", "gp3"}, {"encrypted", "true"}}},
+		{Name: "io2", Provisioner: "ebs.csi.aws.com", Parameters: []metadataEntry{{"type", "io2"}, {"iopsPerGB", "50"}, {"encrypted", "true"}}},
+	},
+	"aks": {
+		{Name: "premium-ssd", Provisioner: "disk.csi.azure.com", Parameters: []metadataEntry{{"skuName", "Premium_LRS"}}},
+		{Name: "standard-ssd", Provisioner: "disk.csi.azure.com", Parameters: []metadataEntry{{"skuName", "StandardSSD_LRS"}}},
+	},
+	"gke": {
+		{Name: "balanced", Provisioner: "pd.csi.storage.gke.io", Parameters: []metadataEntry{{"type", "pd-balanced"}}},
+		{Name: "ssd", Provisioner: "pd.csi.storage.gke.io", Parameters: []metadataEntry{{"type", "pd-ssd"}}},
+	},
+}
+
+// generateStorageClasses writes the storage classes of the platform into
+// infrastructure/base/storage-classes.
+func (g *Generator) generateStorageClasses() error {
+	var files []string
+	for i, class := range cloudStorageClasses[g.Config.Platform] {
+		content, err := templates.Render("infrastructure/storageclass.yaml.tmpl", map[string]any{
+			"Name":        class.Name,
+			"Provisioner": class.Provisioner,
+			"Parameters":  class.Parameters,
+			"Default":     i == 0,
+		})
+		if err != nil {
+			return err
+		}
+		file := class.Name + ".yaml"
+		path := fmt.Sprintf("%s/infrastructure/base/storage-classes/%s", g.Config.Project.Name, file)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	return g.generateSubdirKustomization("storage-classes", files)
+}
+
+// workloadIdentity returns the service account annotations and pod labels
+// binding an application to its cloud identity: IRSA on eks, Workload
+// Identity on gke and Microsoft Entra Workload ID on aks.
+func (g *Generator) workloadIdentity(identity string) (annotations, podLabels []metadataEntry) {
+	switch g.Config.Platform {
+	case "eks":
+		return []metadataEntry{{"eks.amazonaws.com/role-arn", identity}}, nil
+	case "gke":
+		return []metadataEntry{{"iam.gke.io/gcp-service-account", identity}}, nil
+	case "aks":
+		return []metadataEntry{{"azure.workload.identity/client-id", identity}},
+			[]metadataEntry{{"azure.workload.identity/use", "true"}}
+	}
+	return nil, nil
+}
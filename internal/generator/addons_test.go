@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newAddonsTestConfig() *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "shop"},
+		Platform:   "eks",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/org/shop.git"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod", Cluster: "https://prod.eks.example.com"},
+		},
+		Clusters: []config.ClusterProvision{{Name: "shop-prod-eks", Environment: "prod", Provider: "eks", Region: "eu-west-1"}},
+		Addons: config.AddonsConfig{
+			Region:                 "us-east-1",
+			LoadBalancerController: &config.CloudAddon{Enabled: true, Identity: "arn:aws:iam::123456789012:role/lbc"},
+			ClusterAutoscaler:      &config.CloudAddon{Enabled: true, Version: "9.40.0"},
+			StorageClasses:         true,
+		},
+		Apps: []config.Application{
+			{
+				Name:          "api",
+				Image:         "api:1.0",
+				Port:          8080,
+				CloudIdentity: "arn:aws:iam::123456789012:role/api-dev",
+				Overrides:     map[string]config.AppOverride{"prod": {CloudIdentity: "arn:aws:iam::210987654321:role/api"}},
+			},
+		},
+	}
+}
+
+func TestGenerator_CloudAddonsArgoCD(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newAddonsTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	dev := readGenerated(t, tmpDir, "shop/argocd/applicationsets/addon-aws-load-balancer-controller-dev.yaml")
+	assert.Contains(t, dev, "repoURL: https://aws.github.io/eks-charts\n    chart: aws-load-balancer-controller")
+	assert.Contains(t, dev, `targetRevision: "1.8.1"`)
+	assert.Contains(t, dev, "clusterName: shop-dev")
+	assert.Contains(t, dev, "region: us-east-1")
+	assert.Contains(t, dev, "eks.amazonaws.com/role-arn: arn:aws:iam::123456789012:role/lbc")
+	assert.Contains(t, dev, "server: https://kubernetes.default.svc\n    namespace: kube-system")
+	assert.Contains(t, dev, "project: infrastructure")
+
+	prod := readGenerated(t, tmpDir, "shop/argocd/applicationsets/addon-aws-load-balancer-controller-prod.yaml")
+	assert.Contains(t, prod, "clusterName: shop-prod-eks", "the provisioned cluster name is used")
+	assert.Contains(t, prod, "region: eu-west-1")
+	assert.Contains(t, prod, "server: https://prod.eks.example.com")
+
+	autoscaler := readGenerated(t, tmpDir, "shop/argocd/applicationsets/addon-cluster-autoscaler-prod.yaml")
+	assert.Contains(t, autoscaler, `targetRevision: "9.40.0"`)
+	assert.Contains(t, autoscaler, "autoDiscovery:\n          clusterName: shop-prod-eks")
+	assert.Contains(t, autoscaler, "rbac:\n          serviceAccount:")
+	assert.NotContains(t, autoscaler, "role-arn")
+}
+
+func TestGenerator_CloudAddonsFlux(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newAddonsTestConfig()
+	cfg.GitOpsTool = "flux"
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	addon := readGenerated(t, tmpDir, "shop/flux/addons/aws-load-balancer-controller.yaml")
+	assert.Contains(t, addon, "kind: HelmRepository")
+	assert.Contains(t, addon, "url: https://aws.github.io/eks-charts")
+	assert.Contains(t, addon, "kind: HelmRelease")
+	assert.Contains(t, addon, "targetNamespace: kube-system")
+	assert.Contains(t, addon, "clusterName: shop-dev", "Flux installs the add-ons for its own cluster")
+	assert.FileExists(t, tmpDir+"/shop/flux/addons/cluster-autoscaler.yaml")
+}
+
+func TestGenerator_StorageClasses(t *testing.T) {
+	for platform, want := range map[string][2]string{
+		"eks": {"gp3", "ebs.csi.aws.com"},
+		"aks": {"premium-ssd", "disk.csi.azure.com"},
+		"gke": {"balanced", "pd.csi.storage.gke.io"},
+	} {
+		t.Run(platform, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := newAddonsTestConfig()
+			cfg.Platform = platform
+			cfg.Addons.LoadBalancerController, cfg.Addons.ClusterAutoscaler = nil, nil
+			gen := New(cfg, output.New(tmpDir, false, false), false)
+
+			require.NoError(t, gen.Generate())
+
+			class := readGenerated(t, tmpDir, "shop/infrastructure/base/storage-classes/"+want[0]+".yaml")
+			assert.Contains(t, class, "provisioner: "+want[1])
+			assert.Contains(t, class, `storageclass.kubernetes.io/is-default-class: "true"`)
+			assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/base/kustomization.yaml"), "- storage-classes/")
+			assert.NoDirExists(t, tmpDir+"/shop/flux/addons")
+		})
+	}
+}
+
+func TestGenerator_WorkloadIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newAddonsTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	serviceAccount := readGenerated(t, tmpDir, "shop/applications/base/api/serviceaccount.yaml")
+	assert.Contains(t, serviceAccount, `eks.amazonaws.com/role-arn: "arn:aws:iam::123456789012:role/api-dev"`)
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/base/api/deployment.yaml"), "serviceAccountName: api")
+
+	patch := readGenerated(t, tmpDir, "shop/applications/overlays/prod/api-serviceaccount-patch.yaml")
+	assert.Contains(t, patch, `eks.amazonaws.com/role-arn: "arn:aws:iam::210987654321:role/api"`)
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/overlays/prod/kustomization.yaml"), "- path: api-serviceaccount-patch.yaml")
+}
+
+func TestGenerator_WorkloadIdentityAKS(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newAddonsTestConfig()
+	cfg.Platform = "aks"
+	cfg.Addons = config.AddonsConfig{}
+	cfg.Apps[0].CloudIdentity = "00000000-0000-0000-0000-000000000001"
+	cfg.Apps[0].Overrides = nil
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/base/api/serviceaccount.yaml"),
+		`azure.workload.identity/client-id: "00000000-0000-0000-0000-000000000001"`)
+	deployment := readGenerated(t, tmpDir, "shop/applications/base/api/deployment.yaml")
+	assert.Contains(t, deployment, "app: api\n        azure.workload.identity/use: \"true\"")
+	assert.Contains(t, deployment, "matchLabels:\n      app: api\n  template:")
+}
@@ -2,84 +2,386 @@ package generator
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/syncwave"
 	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
 )
 
-func (g *Generator) generateApplications() error {
-	fmt.Println("📦 Generating applications...")
-
+// defaultApplications adds a sample application to configs without any.
+func (g *Generator) defaultApplications() {
 	if len(g.Config.Apps) == 0 {
 		g.Config.Apps = []config.Application{
 			{Name: "sample-app", Image: "nginx:latest", Port: 80, Replicas: 1},
 		}
 	}
+}
+
+func (g *Generator) generateApplications() error {
+	g.printf("📦 Generating applications...\n")
 
-	appDirs := make([]string, 0, len(g.Config.Apps))
+	waves, err := g.appSyncWaves()
+	if err != nil {
+		return err
+	}
 
-	for _, app := range g.Config.Apps {
-		appDir := g.Config.Project.Name + "/applications/base/" + app.Name
-		if err := g.Writer.CreateDir(appDir); err != nil {
+	if apps := g.hubApps(); len(apps) > 0 {
+		if err := g.writeApplications(g.Config.Project.Name, apps, waves); err != nil {
 			return err
 		}
+	}
+	return g.generateAppRepositories(waves)
+}
+
+// writeApplications writes the base and overlays of apps into the
+// applications directory of the repository at root. Applications and
+// environments are written in parallel.
+func (g *Generator) writeApplications(root string, apps []config.Application, waves map[string]int) error {
+	appDirs := make([]string, len(apps))
+	err := g.parallel(len(apps), func(i int, view *Generator) error {
+		appDirs[i] = apps[i].Name + "/"
+		return view.writeAppBase(root, apps[i], waves)
+	})
+	if err != nil {
+		return err
+	}
+
+	baseKustomize := map[string]interface{}{
+		"Resources": appDirs,
+	}
+	content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", baseKustomize)
+	if err != nil {
+		return err
+	}
+	if err := g.Writer.WriteFile(root+"/applications/base/kustomization.yaml", content); err != nil {
+		return err
+	}
+
+	if err := g.writeKustomizeComponents(root); err != nil {
+		return err
+	}
+
+	envs := g.Config.Environments
+	return g.parallel(len(envs), func(i int, view *Generator) error {
+		return view.writeAppOverlay(root, envs[i], apps)
+	})
+}
 
-		appDirs = append(appDirs, app.Name+"/")
+// writeAppBase writes the base of an application.
+func (g *Generator) writeAppBase(root string, app config.Application, waves map[string]int) error {
+	appDir := root + "/applications/base/" + app.Name
+	if err := g.Writer.CreateDir(appDir); err != nil {
+		return err
+	}
+
+	container := g.newAppContainer(app)
+	if wave, ok := waves[app.Name]; ok {
+		container.SyncWave = syncwave.Value(wave)
+	}
+	deployContent, err := templates.Render("kubernetes/deployment.yaml.tmpl", container)
+	if err != nil {
+		return err
+	}
+	if err = g.Writer.WriteFile(appDir+"/deployment.yaml", deployContent); err != nil {
+		return err
+	}
+
+	svcContent, err := templates.Render("kubernetes/service.yaml.tmpl", container)
+	if err != nil {
+		return err
+	}
+	if err = g.Writer.WriteFile(appDir+"/service.yaml", svcContent); err != nil {
+		return err
+	}
+	resources := []string{"deployment.yaml", "service.yaml"}
 
-		deployContent, err := templates.Render("kubernetes/deployment.yaml.tmpl", app)
+	if app.Ingress != nil {
+		kind, content, err := g.renderAppIngress(app, "", container.SyncWave)
 		if err != nil {
 			return err
 		}
-		if err = g.Writer.WriteFile(appDir+"/deployment.yaml", deployContent); err != nil {
+		if err = g.Writer.WriteFile(appDir+"/"+kind+".yaml", content); err != nil {
 			return err
 		}
+		resources = append(resources, kind+".yaml")
+	}
+	if container.ServiceAccount != "" {
+		content, err := templates.Render("kubernetes/serviceaccount.yaml.tmpl", container)
+		if err != nil {
+			return err
+		}
+		if err = g.Writer.WriteFile(appDir+"/serviceaccount.yaml", content); err != nil {
+			return err
+		}
+		resources = append(resources, "serviceaccount.yaml")
+	}
 
-		svcContent, err := templates.Render("kubernetes/service.yaml.tmpl", app)
+	appKustomize := map[string]interface{}{
+		"Resources": resources,
+	}
+	if usesConfigMap(app) {
+		appKustomize["ConfigMapGenerator"] = []configMapGenerator{
+			{Name: app.Name + "-config", Literals: literals(app.ConfigMap)},
+		}
+	}
+	kContent, err := templates.Render("kubernetes/kustomization.yaml.tmpl", appKustomize)
+	if err != nil {
+		return err
+	}
+	if err = g.Writer.WriteFile(appDir+"/kustomization.yaml", kContent); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeAppOverlay writes the overlay of an environment.
+func (g *Generator) writeAppOverlay(root string, env config.Environment, apps []config.Application) error {
+	overlayDir := fmt.Sprintf("%s/applications/overlays/%s", root, env.Name)
+	resources := []string{"../../base"}
+	var patches []string
+	var generators []configMapGenerator
+
+	// The SCC RoleBindings name the service account namespace, known per
+	// environment only.
+	namespace := g.Config.GetEnvironmentNamespace(env.Name)
+	if env.Kustomize != nil && env.Kustomize.Namespace != "" {
+		namespace = env.Kustomize.Namespace
+	}
+	for _, app := range apps {
+		if app.SCC == "" {
+			continue
+		}
+		content, err := renderSCCRoleBinding(app, namespace)
 		if err != nil {
 			return err
 		}
-		if err = g.Writer.WriteFile(appDir+"/service.yaml", svcContent); err != nil {
+		file := app.Name + "-scc.yaml"
+		if err := g.Writer.WriteFile(overlayDir+"/"+file, content); err != nil {
 			return err
 		}
+		resources = append(resources, file)
+	}
 
-		appKustomize := map[string]interface{}{
-			"Resources": []string{"deployment.yaml", "service.yaml"},
+	for _, app := range apps {
+		override, ok := app.Overrides[env.Name]
+		if !ok {
+			continue
 		}
-		kContent, err := templates.Render("kubernetes/kustomization.yaml.tmpl", appKustomize)
+		if len(override.ConfigMap) > 0 {
+			generators = append(generators, configMapGenerator{
+				Name:     app.Name + "-config",
+				Behavior: "merge",
+				Literals: literals(override.ConfigMap),
+			})
+		}
+		if override.Host != "" && app.Ingress != nil {
+			kind, content, err := g.renderAppIngress(app, override.Host, "")
+			if err != nil {
+				return err
+			}
+			patch := app.Name + "-" + kind + "-patch.yaml"
+			if err := g.Writer.WriteFile(overlayDir+"/"+patch, content); err != nil {
+				return err
+			}
+			patches = append(patches, patch)
+		}
+		if override.CloudIdentity != "" && app.CloudIdentity != "" {
+			identity := app
+			identity.CloudIdentity = override.CloudIdentity
+			content, err := templates.Render("kubernetes/serviceaccount.yaml.tmpl", g.newAppContainer(identity))
+			if err != nil {
+				return err
+			}
+			patch := app.Name + "-serviceaccount-patch.yaml"
+			if err := g.Writer.WriteFile(overlayDir+"/"+patch, content); err != nil {
+				return err
+			}
+			patches = append(patches, patch)
+		}
+		if override.Image == "" && override.Replicas == 0 && len(override.Env) == 0 && override.Resources == nil {
+			continue
+		}
+
+		patchData := struct {
+			Name string
+			config.AppOverride
+		}{app.Name, override}
+		patchData.Image = g.image(override.Image)
+		content, err := templates.Render("kubernetes/deployment-patch.yaml.tmpl", patchData)
 		if err != nil {
 			return err
 		}
-		if err = g.Writer.WriteFile(appDir+"/kustomization.yaml", kContent); err != nil {
+		patch := app.Name + "-patch.yaml"
+		if err := g.Writer.WriteFile(overlayDir+"/"+patch, content); err != nil {
 			return err
 		}
+		patches = append(patches, patch)
 	}
 
-	baseKustomize := map[string]interface{}{
-		"Resources": appDirs,
+	overlayData := map[string]interface{}{
+		"Resources":          resources,
+		"ConfigMapGenerator": generators,
+		"Patches":            patches,
 	}
-	content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", baseKustomize)
+	if k := env.Kustomize; k != nil {
+		overlayData["Namespace"] = k.Namespace
+		components := make([]string, 0, len(k.Components))
+		for _, name := range k.Components {
+			components = append(components, "../../components/"+name)
+		}
+		overlayData["Components"] = components
+		if err := g.addKustomizeOverlay(overlayData, overlayDir, k.KustomizeOverlay); err != nil {
+			return err
+		}
+	}
+	content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", overlayData)
 	if err != nil {
 		return err
 	}
-	if err := g.Writer.WriteFile(g.Config.Project.Name+"/applications/base/kustomization.yaml", content); err != nil {
+
+	if err := g.Writer.WriteFile(overlayDir+"/kustomization.yaml", content); err != nil {
 		return err
 	}
+	return nil
+}
+
+// appContainer is the template data of an application Deployment.
+type appContainer struct {
+	config.Application
+	ImagePolicy string
+	EnvFrom     []envSource
+	SecretEnv   []secretEnv
+	Resources   config.Resources
+	Probes      []appProbe
+	// SyncWave is the ArgoCD sync wave of the application resources, set
+	// when applications declare depends_on
+	SyncWave string
+	// ServiceAccount is the service account of the pods, set when the
+	// application runs under an SCC or a cloud identity
+	ServiceAccount string
+	// IdentityAnnotations bind the service account to the cloud identity
+	IdentityAnnotations []metadataEntry
+	// PodLabels are the pod labels besides app
+	PodLabels []metadataEntry
+}
+
+// metadataEntry is a label or annotation.
+type metadataEntry struct {
+	Name  string
+	Value string
+}
+
+type envSource struct {
+	Kind string // configMapRef or secretRef
+	Name string
+}
 
-	for _, env := range g.Config.Environments {
-		overlayData := map[string]interface{}{
-			"Resources": []string{"../../base"},
+type secretEnv struct {
+	Name   string
+	Secret string
+	Key    string
+}
+
+type appProbe struct {
+	Kind string // livenessProbe, readinessProbe or startupProbe
+	config.Probe
+}
+
+type configMapGenerator struct {
+	Name     string
+	Behavior string
+	Literals []string
+}
+
+// appSyncWaves returns the sync waves of the applications for ArgoCD, which
+// orders the resources of a sync by them. Flux orders HelmReleases with
+// dependsOn instead.
+func (g *Generator) appSyncWaves() (map[string]int, error) {
+	if g.Config.GitOpsTool == "flux" {
+		return nil, nil
+	}
+	return g.Config.AppSyncWaves()
+}
+
+func (g *Generator) newAppContainer(app config.Application) appContainer {
+	c := appContainer{
+		Application: app,
+		ImagePolicy: g.imagePolicyRef(app.Name),
+		Resources:   app.ContainerResources(),
+		Probes:      appProbes(app),
+	}
+	c.Image = g.image(app.Image)
+	if app.SCC != "" || app.CloudIdentity != "" {
+		c.ServiceAccount = app.Name
+	}
+	if app.CloudIdentity != "" {
+		c.IdentityAnnotations, c.PodLabels = g.workloadIdentity(app.CloudIdentity)
+	}
+	if usesConfigMap(app) {
+		c.EnvFrom = append(c.EnvFrom, envSource{Kind: "configMapRef", Name: app.Name + "-config"})
+	}
+	for _, secret := range app.Secrets {
+		if secret.Key == "" {
+			c.EnvFrom = append(c.EnvFrom, envSource{Kind: "secretRef", Name: secret.Name})
+			continue
 		}
-		content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", overlayData)
-		if err != nil {
-			return err
+		name := secret.Env
+		if name == "" {
+			name = secret.Key
 		}
+		c.SecretEnv = append(c.SecretEnv, secretEnv{Name: name, Secret: secret.Name, Key: secret.Key})
+	}
+	return c
+}
 
-		path := fmt.Sprintf("%s/applications/overlays/%s/kustomization.yaml",
-			g.Config.Project.Name, env.Name)
-		if err := g.Writer.WriteFile(path, content); err != nil {
-			return err
+// appProbes returns the probes of an application, defaulting their port to
+// the application port.
+func appProbes(app config.Application) []appProbe {
+	if app.Probes == nil {
+		return nil
+	}
+	var probes []appProbe
+	for _, p := range []struct {
+		kind  string
+		probe *config.Probe
+	}{
+		{"livenessProbe", app.Probes.Liveness},
+		{"readinessProbe", app.Probes.Readiness},
+		{"startupProbe", app.Probes.Startup},
+	} {
+		if p.probe == nil {
+			continue
+		}
+		probe := *p.probe
+		if probe.Port == 0 {
+			probe.Port = app.Port
+		}
+		probes = append(probes, appProbe{Kind: p.kind, Probe: probe})
+	}
+	return probes
+}
+
+// usesConfigMap reports whether the application gets a <name>-config
+// ConfigMap, either from its own data or from an environment override.
+func usesConfigMap(app config.Application) bool {
+	if len(app.ConfigMap) > 0 {
+		return true
+	}
+	for _, o := range app.Overrides {
+		if len(o.ConfigMap) > 0 {
+			return true
 		}
 	}
+	return false
+}
 
-	return nil
+// literals returns ConfigMap data as sorted KEY=value literals.
+func literals(data map[string]string) []string {
+	out := make([]string, 0, len(data))
+	for k, v := range data {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
 }
@@ -0,0 +1,326 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/graph"
+)
+
+// architecture is the data of docs/ARCHITECTURE.md: the config, with the
+// Mermaid diagrams and the runbooks of the environments.
+type architecture struct {
+	*config.Config
+	// Tool names the GitOps tools, such as ArgoCD
+	Tool string
+	// ToolNamespace is the namespace of the GitOps tool
+	ToolNamespace    string
+	ClustersDiagram  string
+	PromotionDiagram string
+	TopologyDiagram  string
+	SyncDiagram      string
+	Runbooks         []runbook
+}
+
+// runbook is the runbook of an environment.
+type runbook struct {
+	Environment string
+	Namespace   string
+	Clusters    []string
+	Context     string
+	// Resources are the ArgoCD Applications or Flux Kustomizations of the
+	// environment
+	Resources []string
+	// From is the environment promoted from, empty for the first one
+	From  string
+	Gates []string
+	// App is the application of the example commands
+	App    string
+	ArgoCD bool
+	Flux   bool
+}
+
+// architecture builds the data of docs/ARCHITECTURE.md. The topology is
+// read from the manifests generated so far: docs are generated after the
+// GitOps config.
+func (g *Generator) architecture() *architecture {
+	a := &architecture{Config: g.Config, Tool: g.toolName(), ToolNamespace: g.architectureNamespace()}
+
+	topology := graph.FromFiles(g.projectFiles())
+	if len(topology.Nodes) > 0 {
+		a.TopologyDiagram = topology.Mermaid()
+	}
+	a.ClustersDiagram = g.clustersDiagram()
+	if len(g.Config.Environments) > 1 {
+		a.PromotionDiagram = g.promotionDiagram()
+	}
+	a.SyncDiagram = g.syncDiagram()
+
+	app := "<application>"
+	if len(g.Config.Apps) > 0 {
+		app = g.Config.Apps[0].Name
+	}
+	for i, env := range g.Config.Environments {
+		rb := runbook{
+			Environment: env.Name,
+			Namespace:   g.Config.GetEnvironmentNamespace(env.Name),
+			Clusters:    environmentClusters(env),
+			Context:     env.Context,
+			Resources:   deploying(topology, env.Name),
+			App:         app,
+			ArgoCD:      g.Config.GitOpsTool != "flux",
+			Flux:        g.usesFlux(),
+		}
+		if i > 0 {
+			rb.From = g.Config.Environments[i-1].Name
+		}
+		if env.Protected {
+			rb.Gates = g.promotionGates()
+		}
+		a.Runbooks = append(a.Runbooks, rb)
+	}
+	return a
+}
+
+// toolName names the GitOps tools of the project.
+func (g *Generator) toolName() string {
+	switch g.Config.GitOpsTool {
+	case "flux":
+		return "Flux"
+	case "both":
+		return "ArgoCD and Flux"
+	default:
+		return "ArgoCD"
+	}
+}
+
+// projectFiles returns the files generated under the project, by path
+// relative to it.
+func (g *Generator) projectFiles() map[string][]byte {
+	prefix := g.Config.Project.Name + "/"
+	files := map[string][]byte{}
+	for path, content := range g.written {
+		if rel, ok := strings.CutPrefix(path, prefix); ok {
+			files[rel] = content
+		}
+	}
+	return files
+}
+
+// environmentClusters describes the clusters of an environment.
+func environmentClusters(env config.Environment) []string {
+	if len(env.Clusters) == 0 {
+		if env.Cluster == "" {
+			return []string{"in-cluster"}
+		}
+		return []string{env.Cluster}
+	}
+	clusters := make([]string, 0, len(env.Clusters))
+	for _, c := range env.Clusters {
+		name := c.Name
+		if c.Region != "" {
+			name += " (" + c.Region + ")"
+		}
+		if c.URL != "" {
+			name += " " + c.URL
+		}
+		clusters = append(clusters, name)
+	}
+	return clusters
+}
+
+// deploying returns the Applications and Kustomizations deploying the
+// overlays of an environment.
+func deploying(topology *graph.Graph, env string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, e := range topology.Edges {
+		to, _ := topology.Node(e.To)
+		from, _ := topology.Node(e.From)
+		if to.Kind != graph.KindPath || !strings.HasSuffix(to.Label, "overlays/"+env) {
+			continue
+		}
+		if from.Kind != graph.KindApplication && from.Kind != graph.KindKustomization {
+			continue
+		}
+		_, name, _ := strings.Cut(from.ID, "/")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// promotionGates describes the gates of promotions to protected
+// environments.
+func (g *Generator) promotionGates() []string {
+	gates := g.Config.Promotion.Gates
+	if !gates.Enabled() {
+		return []string{"approval (`--approve`)"}
+	}
+	var described []string
+	if gates.SourceHealthy {
+		described = append(described, "a Synced and Healthy source Application")
+	}
+	if gates.Approval {
+		described = append(described, "approval (`--approve`)")
+	}
+	if gates.SoakTime != "" {
+		described = append(described, "a soak time of "+gates.SoakTime+" in the source environment")
+	}
+	if gates.Validation {
+		described = append(described, "`gitopsi validate`")
+	}
+	return described
+}
+
+// clustersDiagram draws the GitOps tool reconciling the repository and the
+// clusters and namespaces of the environments: a hub managing the clusters
+// of every environment, one cluster with a namespace per environment, or a
+// GitOps tool in the cluster of every environment.
+func (g *Generator) clustersDiagram() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	repo := g.Config.Git.URL
+	if repo == "" {
+		repo = g.Config.Project.Name
+	}
+	if g.Config.Git.Branch != "" {
+		repo += " (" + g.Config.Git.Branch + ")"
+	}
+	fmt.Fprintf(&b, "  git[(%s)]\n", graph.Quote("Git: "+repo))
+
+	tool := g.toolName() + "<br/>namespace " + g.architectureNamespace()
+	mc := g.Config.Bootstrap.MultiCluster
+	switch {
+	case mc != nil && mc.Strategy == "hub":
+		hub := g.toolName() + " hub"
+		if mc.Hub != "" {
+			hub += " on " + mc.Hub
+		}
+		fmt.Fprintf(&b, "  hub[%s]\n", graph.Quote(hub+"<br/>namespace "+g.architectureNamespace()))
+		b.WriteString("  git --> hub\n")
+		for i, env := range g.Config.Environments {
+			fmt.Fprintf(&b, "  subgraph env%d[%s]\n", i, graph.Quote(env.Name))
+			for j, c := range environmentClusters(env) {
+				fmt.Fprintf(&b, "    env%dc%d[%s]\n", i, j, graph.Quote(c))
+			}
+			b.WriteString("  end\n")
+			for j := range environmentClusters(env) {
+				fmt.Fprintf(&b, "  hub --> env%dc%d\n", i, j)
+			}
+		}
+	case !g.Config.IsMultiCluster() && !g.hasEnvironmentClusters():
+		fmt.Fprintf(&b, "  subgraph cluster[%s]\n", graph.Quote(strings.TrimSpace("Cluster "+g.Config.Cluster.URL)))
+		fmt.Fprintf(&b, "    tool[%s]\n", graph.Quote(tool))
+		for i, env := range g.Config.Environments {
+			fmt.Fprintf(&b, "    env%d[%s]\n", i, graph.Quote(env.Name+"<br/>namespace "+g.Config.GetEnvironmentNamespace(env.Name)))
+		}
+		b.WriteString("  end\n")
+		b.WriteString("  git --> tool\n")
+		for i := range g.Config.Environments {
+			fmt.Fprintf(&b, "  tool --> env%d\n", i)
+		}
+	default:
+		for i, env := range g.Config.Environments {
+			fmt.Fprintf(&b, "  subgraph env%d[%s]\n", i, graph.Quote(env.Name))
+			fmt.Fprintf(&b, "    env%dtool[%s]\n", i, graph.Quote(tool))
+			for j, c := range environmentClusters(env) {
+				fmt.Fprintf(&b, "    env%dc%d[%s]\n", i, j, graph.Quote(c))
+			}
+			b.WriteString("  end\n")
+			fmt.Fprintf(&b, "  git --> env%dtool\n", i)
+			for j := range environmentClusters(env) {
+				fmt.Fprintf(&b, "  env%dtool --> env%dc%d\n", i, i, j)
+			}
+		}
+	}
+	return b.String()
+}
+
+// hasEnvironmentClusters reports whether an environment lists its clusters.
+func (g *Generator) hasEnvironmentClusters() bool {
+	for _, env := range g.Config.Environments {
+		if len(env.Clusters) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// architectureNamespace is the namespace of the GitOps tool, Flux when only
+// Flux is used.
+func (g *Generator) architectureNamespace() string {
+	if g.Config.GitOpsTool == "flux" {
+		return g.getFluxNamespace()
+	}
+	return g.getArgoCDNamespace()
+}
+
+// promotionDiagram draws the promotions between environments, in their
+// order, with the gates of the protected ones.
+func (g *Generator) promotionDiagram() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, env := range g.Config.Environments {
+		label := env.Name
+		if env.Protected {
+			label += " (protected)"
+		}
+		fmt.Fprintf(&b, "  env%d[%s]\n", i, graph.Quote(label))
+	}
+	for i := 1; i < len(g.Config.Environments); i++ {
+		via := "gitopsi promote"
+		if g.Config.Environments[i].Protected {
+			via += ": " + strings.ReplaceAll(strings.Join(g.promotionGates(), ", "), "`", "")
+		}
+		fmt.Fprintf(&b, "  env%d -->|%s| env%d\n", i-1, graph.Quote(via), i)
+	}
+	return b.String()
+}
+
+// syncDiagram draws how a change reaches the clusters: the pull request and
+// its pipeline, the GitOps tool fetching the branch and applying the
+// infrastructure before the applications, and promotions.
+func (g *Generator) syncDiagram() string {
+	branch := g.Config.Git.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+	b.WriteString("  actor Dev as Developer\n")
+	fmt.Fprintf(&b, "  participant Git as Git (%s)\n", branch)
+	if g.Config.CI.Enabled() {
+		fmt.Fprintf(&b, "  participant CI as CI (%s)\n", g.Config.CI.Provider)
+	}
+	fmt.Fprintf(&b, "  participant Tool as %s\n", g.toolName())
+	b.WriteString("  participant Cluster as Clusters\n")
+	b.WriteString("  Dev->>Git: Open a pull request\n")
+	if g.Config.CI.Enabled() {
+		b.WriteString("  Git->>CI: Run gitopsi validate\n")
+		b.WriteString("  CI-->>Git: Report findings\n")
+	}
+	fmt.Fprintf(&b, "  Dev->>Git: Merge to %s\n", branch)
+	fmt.Fprintf(&b, "  Tool->>Git: Fetch %s\n", branch)
+
+	order := "sync waves"
+	if g.Config.GitOpsTool == "flux" {
+		order = "dependsOn"
+	}
+	switch g.Config.Scope {
+	case "infrastructure":
+		b.WriteString("  Tool->>Cluster: Apply the infrastructure overlay\n")
+	case "application":
+		b.WriteString("  Tool->>Cluster: Apply the applications overlay\n")
+	default:
+		fmt.Fprintf(&b, "  Tool->>Cluster: Apply the infrastructure, then the applications (%s)\n", order)
+	}
+	b.WriteString("  Cluster-->>Tool: Sync and health status\n")
+	if envs := g.Config.Environments; len(envs) > 1 {
+		fmt.Fprintf(&b, "  Dev->>Git: gitopsi promote --from %s --to %s\n", envs[0].Name, envs[1].Name)
+	}
+	return b.String()
+}
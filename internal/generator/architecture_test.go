@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func TestGenerator_ArchitectureDocs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Project:    config.Project{Name: "arch"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/org/arch.git", Branch: "main"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod", Protected: true},
+		},
+		Apps:      []config.Application{{Name: "api", Image: "nginx:1.27", Port: 80}},
+		Promotion: config.PromotionConfig{Gates: config.PromotionGates{Approval: true, SoakTime: "24h"}},
+		CI:        config.CIConfig{Provider: config.CIProviderGitHubActions},
+		Docs:      config.Documentation{Architecture: true},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	doc := readGenerated(t, tmpDir, "arch/docs/ARCHITECTURE.md")
+	for _, want := range []string{
+		"Infrastructure Layer",
+		`git[("Git: https://github.com/org/arch.git (main)")]`,
+		`env1["prod<br/>namespace arch-prod"]`,
+		`env0 -->|"gitopsi promote: approval (--approve), a soak time of 24h in the source environment"| env1`,
+		"## Application Topology",
+		`["arch-apps-prod (wave 1)"]`,
+		"participant CI as CI (github-actions)",
+		"Tool->>Cluster: Apply the infrastructure, then the applications (sync waves)",
+		"| ArgoCD resources | `arch-apps-dev`, `arch-infra-dev` |",
+		"dev is the first environment: changes reach it once merged to `main`.",
+		"gitopsi promote api --from dev --to prod --approve --pr",
+		"prod is protected: promotions must pass approval (`--approve`), a soak time of 24h in the source environment.",
+		"gitopsi rollback api --env prod --push --sync --wait",
+	} {
+		assert.Contains(t, doc, want)
+	}
+	assert.NotContains(t, doc, "\n\n\n")
+}
+
+func TestGenerator_ArchitectureDocs_FluxHub(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Environments = []config.Environment{
+		{Name: "dev", Context: "kind-dev"},
+		{Name: "prod", Clusters: []config.EnvironmentCluster{
+			{Name: "prod-eu", URL: "https://prod-eu.example.com", Region: "eu-west-1"},
+		}},
+	}
+	cfg.Bootstrap.MultiCluster = &config.BootstrapMultiClusterConfig{Strategy: "hub", Hub: "prod-eu"}
+	cfg.Docs = config.Documentation{Architecture: true}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	doc := readGenerated(t, tmpDir, "flux-app/docs/ARCHITECTURE.md")
+	for _, want := range []string{
+		`hub["Flux hub on prod-eu<br/>namespace flux-system"]`,
+		`env1c0["prod-eu (eu-west-1) https://prod-eu.example.com"]`,
+		"hub --> env1c0",
+		"Apply the infrastructure, then the applications (dependsOn)",
+		"| Kubeconfig context | `kind-dev` |",
+		"kubectl config use-context kind-dev",
+		"flux get kustomizations --namespace flux-system",
+		"`flux-app-apps-prod-prod-eu`",
+	} {
+		assert.Contains(t, doc, want)
+	}
+	assert.NotContains(t, doc, "gitopsi ui")
+	assert.NotContains(t, doc, "--sync --wait")
+}
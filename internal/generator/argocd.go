@@ -7,6 +7,13 @@ import (
 	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
 )
 
+// The App-of-Apps syncs the infrastructure Applications, and waits for them
+// to become healthy, before the application ones.
+const (
+	infrastructureSyncWave = "0"
+	applicationsSyncWave   = "1"
+)
+
 func (g *Generator) generateGitOps() error {
 	if g.Config.GitOpsTool == "argocd" || g.Config.GitOpsTool == "both" {
 		if err := g.generateArgoCD(); err != nil {
@@ -14,13 +21,11 @@ func (g *Generator) generateGitOps() error {
 		}
 	}
 
-	// TODO: Flux support disabled - focus on ArgoCD first
-	// Uncomment when Flux support is ready for production
-	// if g.Config.GitOpsTool == "flux" || g.Config.GitOpsTool == "both" {
-	// 	if err := g.generateFlux(); err != nil {
-	// 		return err
-	// 	}
-	// }
+	if g.usesFlux() {
+		if err := g.generateFlux(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -36,7 +41,7 @@ func (g *Generator) getArgoCDNamespace() string {
 }
 
 func (g *Generator) generateArgoCD() error {
-	fmt.Println("🔄 Generating ArgoCD configuration...")
+	g.printf("🔄 Generating ArgoCD configuration...\n")
 
 	argoCDNamespace := g.getArgoCDNamespace()
 
@@ -44,6 +49,30 @@ func (g *Generator) generateArgoCD() error {
 		return err
 	}
 
+	if err := g.generateTenantProjects(argoCDNamespace); err != nil {
+		return err
+	}
+
+	if err := g.generateArgoCDRBAC(argoCDNamespace); err != nil {
+		return err
+	}
+
+	if err := g.generateArgoCDSSO(argoCDNamespace); err != nil {
+		return err
+	}
+
+	if err := g.generatePreview(argoCDNamespace); err != nil {
+		return err
+	}
+
+	if err := g.generateRepositoryApplicationSets(argoCDNamespace); err != nil {
+		return err
+	}
+
+	if g.Config.ArgoCD.ApplicationSet.Generator != "" {
+		return g.generateGeneratorApplicationSets(argoCDNamespace)
+	}
+
 	if g.Config.IsMultiCluster() {
 		return g.generateMultiClusterArgoCD(argoCDNamespace)
 	}
@@ -69,11 +98,14 @@ func (g *Generator) generateArgoCDProjects(argoCDNamespace string) error {
 	}
 
 	if g.Config.Scope == "application" || g.Config.Scope == "both" {
-		projectData := map[string]string{
+		projectData := map[string]any{
 			"Name":            "applications",
 			"Description":     "Application deployments",
 			"ArgoCDNamespace": argoCDNamespace,
 		}
+		if g.Config.IsOpenShift() {
+			projectData["DeniedNamespaces"] = openShiftSystemNamespaces
+		}
 		content, err := templates.Render("argocd/project.yaml.tmpl", projectData)
 		if err != nil {
 			return err
@@ -104,9 +136,10 @@ func (g *Generator) generateSingleClusterArgoCD(argoCDNamespace string) error {
 				"RepoURL":         repoURL,
 				"Path":            fmt.Sprintf("infrastructure/overlays/%s", env.Name),
 				"Server":          env.Cluster,
-				"Namespace":       g.Config.Project.Name + "-" + env.Name,
+				"Namespace":       g.Config.GetEnvironmentNamespace(env.Name),
 				"TargetRevision":  "HEAD",
 				"ArgoCDNamespace": argoCDNamespace,
+				"SyncWave":        infrastructureSyncWave,
 			}
 			content, err := templates.Render("argocd/application.yaml.tmpl", appData)
 			if err != nil {
@@ -119,16 +152,17 @@ func (g *Generator) generateSingleClusterArgoCD(argoCDNamespace string) error {
 			}
 		}
 
-		if g.Config.Scope == "application" || g.Config.Scope == "both" {
+		if g.generatesHubApplications() {
 			appData := map[string]string{
 				"Name":            fmt.Sprintf("%s-apps-%s", g.Config.Project.Name, env.Name),
 				"Project":         "applications",
 				"RepoURL":         repoURL,
 				"Path":            fmt.Sprintf("applications/overlays/%s", env.Name),
 				"Server":          env.Cluster,
-				"Namespace":       g.Config.Project.Name + "-" + env.Name,
+				"Namespace":       g.Config.GetEnvironmentNamespace(env.Name),
 				"TargetRevision":  "HEAD",
 				"ArgoCDNamespace": argoCDNamespace,
+				"SyncWave":        applicationsSyncWave,
 			}
 			content, err := templates.Render("argocd/application.yaml.tmpl", appData)
 			if err != nil {
@@ -225,7 +259,7 @@ func (g *Generator) generateClusterPerEnvApplicationSets(argoCDNamespace, repoUR
 			}
 		}
 
-		if g.Config.Scope == "application" || g.Config.Scope == "both" {
+		if g.generatesHubApplications() {
 			appSetData := map[string]any{
 				"Name":            g.Config.Project.Name + "-apps",
 				"Environment":     env.Name,
@@ -286,7 +320,7 @@ func (g *Generator) generateMultiClusterApplicationSets(argoCDNamespace, repoURL
 		}
 	}
 
-	if g.Config.Scope == "application" || g.Config.Scope == "both" {
+	if g.generatesHubApplications() {
 		appSetData := map[string]any{
 			"Name":            g.Config.Project.Name + "-apps",
 			"Environments":    envList,
@@ -309,3 +343,79 @@ func (g *Generator) generateMultiClusterApplicationSets(argoCDNamespace, repoURL
 
 	return nil
 }
+
+// generateGeneratorApplicationSets emits ApplicationSets driven by the
+// generator selected in argocd.applicationset.generator, regardless of topology.
+func (g *Generator) generateGeneratorApplicationSets(argoCDNamespace string) error {
+	repoURL := g.Config.Git.URL
+	if repoURL == "" {
+		repoURL = g.Config.Output.URL
+	}
+	if repoURL == "" {
+		return fmt.Errorf("git.url is required to generate ArgoCD applications - ArgoCD needs to sync from a Git repository")
+	}
+
+	branch := g.Config.Output.Branch
+	if branch == "" {
+		branch = "main"
+	}
+
+	if g.Config.IsMultiCluster() {
+		if err := g.generateClusterSecrets(argoCDNamespace); err != nil {
+			return err
+		}
+	}
+
+	switch gen := g.Config.ArgoCD.ApplicationSet.Generator; gen {
+	case "cluster":
+		return g.generateClusterPerEnvApplicationSets(argoCDNamespace, repoURL, branch)
+	case "matrix":
+		return g.generateMultiClusterApplicationSets(argoCDNamespace, repoURL, branch)
+	case "git":
+		return g.generateGitApplicationSets(argoCDNamespace, repoURL, branch)
+	default:
+		return fmt.Errorf("unsupported ApplicationSet generator: %s", gen)
+	}
+}
+
+// generateGitApplicationSets emits one ApplicationSet per scope that creates an
+// Application for every overlay directory found in Git.
+func (g *Generator) generateGitApplicationSets(argoCDNamespace, repoURL, branch string) error {
+	scopes := []struct {
+		enabled bool
+		name    string
+		project string
+		path    string
+		file    string
+	}{
+		{g.Config.Scope == "infrastructure" || g.Config.Scope == "both", g.Config.Project.Name + "-infra", "infrastructure", "infrastructure", "infra-git.yaml"},
+		{g.generatesHubApplications(), g.Config.Project.Name + "-apps", "applications", "applications", "apps-git.yaml"},
+	}
+
+	for _, scope := range scopes {
+		if !scope.enabled {
+			continue
+		}
+
+		appSetData := map[string]any{
+			"Name":            scope.name,
+			"Project":         scope.project,
+			"RepoURL":         repoURL,
+			"Branch":          branch,
+			"Path":            scope.path,
+			"Namespace":       g.Config.Conventions.Names.NamespaceName(g.Config.Project.Name, "{{path.basename}}"),
+			"ArgoCDNamespace": argoCDNamespace,
+		}
+		content, err := templates.Render("argocd/applicationset-git.yaml.tmpl", appSetData)
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/%s/applicationsets/%s",
+			g.Config.Project.Name, g.Config.GitOpsTool, scope.file)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newArgoCDTestConfig(generator string) *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "appset"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/test/repo.git"},
+		ArgoCD: config.ArgoCDConfig{
+			ApplicationSet: config.ArgoCDApplicationSetConfig{Generator: generator},
+		},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod", Namespace: "production"},
+		},
+	}
+}
+
+func TestGenerator_ArgoCD_ClusterGenerator(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newArgoCDTestConfig("cluster"), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	apps := readGenerated(t, tmpDir, "appset/argocd/applicationsets/apps-prod-cluster.yaml")
+	assert.Contains(t, apps, "kind: ApplicationSet")
+	assert.Contains(t, apps, "- clusters:")
+	assert.Contains(t, apps, "env: prod")
+	assert.Contains(t, apps, "path: applications/overlays/prod")
+	assert.Contains(t, apps, "namespace: production")
+	assert.Contains(t, apps, "server: '{{server}}'")
+
+	readGenerated(t, tmpDir, "appset/argocd/applicationsets/infra-dev-cluster.yaml")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "appset/argocd/applicationsets/apps-dev.yaml"))
+}
+
+func TestGenerator_ArgoCD_MatrixGenerator(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newArgoCDTestConfig("matrix"), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	infra := readGenerated(t, tmpDir, "appset/argocd/applicationsets/infra-multi-cluster.yaml")
+	assert.Contains(t, infra, "- matrix:")
+	assert.Contains(t, infra, "- env: dev")
+	assert.Contains(t, infra, "namespace: production")
+	assert.Contains(t, infra, "path: infrastructure/overlays/{{env}}")
+}
+
+func TestGenerator_ArgoCD_GitGenerator(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newArgoCDTestConfig("git")
+	cfg.Scope = "application"
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	apps := readGenerated(t, tmpDir, "appset/argocd/applicationsets/apps-git.yaml")
+	assert.Contains(t, apps, "- git:")
+	assert.Contains(t, apps, "repoURL: https://github.com/test/repo.git")
+	assert.Contains(t, apps, "- path: applications/overlays/*")
+	assert.Contains(t, apps, "name: 'appset-apps-{{path.basename}}'")
+	assert.Contains(t, apps, "namespace: 'appset-{{path.basename}}'")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "appset/argocd/applicationsets/infra-git.yaml"))
+}
+
+func TestGenerator_ArgoCD_GeneratorWithClusterSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newArgoCDTestConfig("cluster")
+	cfg.Topology = config.TopologyMultiCluster
+	cfg.Environments[1].Clusters = []config.EnvironmentCluster{
+		{Name: "prod-eu", URL: "https://prod-eu.example.com:6443", Region: "eu-west-1"},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	secret := readGenerated(t, tmpDir, "appset/argocd/clusters/prod-eu.yaml")
+	assert.Contains(t, secret, "argocd.argoproj.io/secret-type: cluster")
+	readGenerated(t, tmpDir, "appset/argocd/applicationsets/apps-prod-cluster.yaml")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "appset/argocd/applicationsets/apps-multi-cluster.yaml"))
+}
+
+func TestGenerator_ArgoCD_GeneratorRequiresRepo(t *testing.T) {
+	cfg := newArgoCDTestConfig("git")
+	cfg.Git.URL = ""
+	gen := New(cfg, output.New(t.TempDir(), false, false), false)
+
+	assert.Error(t, gen.Generate())
+}
+
+func TestGenerator_ArgoCD_ApplicationSetRequiresTargetVersion(t *testing.T) {
+	cfg := newArgoCDTestConfig("cluster")
+	cfg.Version.ArgoCD = "2.2"
+	gen := New(cfg, output.New(t.TempDir(), false, false), false)
+
+	err := gen.Generate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ApplicationSet argoproj.io/v1alpha1 requires ArgoCD 2.3")
+
+	cfg.Version.ArgoCD = "2.9"
+	gen = New(cfg, output.New(t.TempDir(), false, false), false)
+	require.NoError(t, gen.Generate())
+	assert.Empty(t, gen.Compatibility.Findings())
+}
+
+func TestGenerator_ArgoCD_SyncWaves(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newArgoCDTestConfig("")
+	cfg.Apps = []config.Application{
+		{Name: "db", Image: "postgres:16", Port: 5432},
+		{Name: "api", Image: "ghcr.io/org/api:1.0.0", Port: 8080, DependsOn: []string{"db"}},
+		{Name: "web", Image: "ghcr.io/org/web:1.0.0", Port: 80, DependsOn: []string{"api"}},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	infra := readGenerated(t, tmpDir, "appset/argocd/applicationsets/infra-dev.yaml")
+	assert.Contains(t, infra, `argocd.argoproj.io/sync-wave: "0"`)
+	apps := readGenerated(t, tmpDir, "appset/argocd/applicationsets/apps-dev.yaml")
+	assert.Contains(t, apps, `argocd.argoproj.io/sync-wave: "1"`)
+
+	for app, wave := range map[string]string{"db": "0", "api": "1", "web": "2"} {
+		for _, file := range []string{"deployment.yaml", "service.yaml"} {
+			content := readGenerated(t, tmpDir, "appset/applications/base/"+app+"/"+file)
+			assert.Contains(t, content, `argocd.argoproj.io/sync-wave: "`+wave+`"`, "%s/%s", app, file)
+		}
+	}
+}
+
+func TestGenerator_ArgoCD_NoAppSyncWavesWithoutDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newArgoCDTestConfig("")
+	cfg.Apps = []config.Application{{Name: "web", Image: "nginx:1.27", Port: 80}}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	deployment := readGenerated(t, tmpDir, "appset/applications/base/web/deployment.yaml")
+	assert.NotContains(t, deployment, "sync-wave")
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// Defaults of the Velero Schedules.
+const (
+	defaultBackupSchedule = "0 2 * * *"
+	defaultBackupTTL      = "720h"
+)
+
+// backupDir returns the directory of the Velero Schedules of an environment,
+// relative to the project root.
+func backupDir(env string) string {
+	return "infrastructure/backup/" + env
+}
+
+// backupNamespaces returns the namespaces backed up in an environment: its
+// own and those of the tenants.
+func (g *Generator) backupNamespaces(env string) []string {
+	namespaces := []string{g.Config.GetEnvironmentNamespace(env)}
+	for _, tenant := range g.Config.Tenants {
+		namespaces = append(namespaces, tenant.EnvNamespaces(env)...)
+	}
+	return namespaces
+}
+
+// generateBackupSchedules writes a Velero Schedule per namespace of every
+// environment into infrastructure/backup/<env>. The Schedules live in the
+// Velero namespace, so they are kept out of the infrastructure overlays
+// Flux retargets to the environment namespace.
+func (g *Generator) generateBackupSchedules() error {
+	backup := g.Config.Backup
+	schedule, ttl := backup.Schedule, backup.TTL
+	if schedule == "" {
+		schedule = defaultBackupSchedule
+	}
+	if ttl == "" {
+		ttl = defaultBackupTTL
+	}
+
+	for _, env := range g.Config.Environments {
+		var docs [][]byte
+		for _, namespace := range g.backupNamespaces(env.Name) {
+			content, err := templates.Render("infrastructure/velero-schedule.yaml.tmpl", map[string]any{
+				"Name":            namespace,
+				"VeleroNamespace": backup.VeleroNamespace(),
+				"Env":             env.Name,
+				"Namespace":       namespace,
+				"Schedule":        schedule,
+				"TTL":             ttl,
+				"StorageLocation": backup.StorageLocation,
+				"SnapshotVolumes": backup.SnapshotVolumes,
+			})
+			if err != nil {
+				return err
+			}
+			docs = append(docs, bytes.TrimSpace(content))
+		}
+
+		dir := g.Config.Project.Name + "/" + backupDir(env.Name)
+		if err := g.Writer.WriteFile(dir+"/schedules.yaml", joinDocs(docs)); err != nil {
+			return err
+		}
+		content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", map[string]any{
+			"Resources": []string{"schedules.yaml"},
+		})
+		if err != nil {
+			return err
+		}
+		if err := g.Writer.WriteFile(dir+"/kustomization.yaml", content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupOverlayResource returns the resource of the backup directory an
+// infrastructure overlay includes, or an empty string. With Flux the
+// Schedules are reconciled by their own Kustomization instead.
+func (g *Generator) backupOverlayResource(env config.Environment) string {
+	if !g.Config.Backup.Enabled || g.usesFlux() {
+		return ""
+	}
+	return "../../backup/" + env.Name
+}
+
+// writeFluxBackupKustomization writes the Flux Kustomization of the Velero
+// Schedules of an environment, without a target namespace.
+func (g *Generator) writeFluxBackupKustomization(env config.Environment, fluxNamespace string, target fluxTarget) error {
+	content, err := templates.Render("flux/kustomization.yaml.tmpl", map[string]any{
+		"Name":               fmt.Sprintf("%s-backup-%s%s", g.Config.Project.Name, env.Name, target.Suffix),
+		"Namespace":          fluxNamespace,
+		"Interval":           g.getFluxInterval(),
+		"SourceName":         g.Config.Project.Name,
+		"Path":               "./" + backupDir(env.Name),
+		"Prune":              true,
+		"HealthChecks":       []any{},
+		"DependsOn":          []string{fmt.Sprintf("%s-infra-%s%s", g.Config.Project.Name, env.Name, target.Suffix)},
+		"ServiceAccountName": target.serviceAccount(g, "kustomize-controller"),
+		"KubeConfigSecret":   target.KubeConfigSecret,
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/flux/kustomizations/backup-%s%s.yaml", g.Config.Project.Name, env.Name, target.Suffix)
+	return g.Writer.WriteFile(path, content)
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newBackupTestConfig(tool string) *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "shop"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: tool,
+		Git:        config.GitConfig{URL: "https://github.com/org/shop.git"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod"},
+		},
+		Tenants: []config.Tenant{{Name: "payments"}},
+		Backup: config.BackupConfig{
+			Enabled:         true,
+			Schedule:        "0 3 * * *",
+			StorageLocation: "aws-s3",
+			SnapshotVolumes: true,
+		},
+	}
+}
+
+func TestGenerator_BackupSchedules(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newBackupTestConfig("argocd"), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	schedules := readGenerated(t, tmpDir, "shop/infrastructure/backup/prod/schedules.yaml")
+	assert.Contains(t, schedules, "kind: Schedule\nmetadata:\n  name: shop-prod\n  namespace: velero")
+	assert.Contains(t, schedules, "name: payments-prod")
+	assert.Contains(t, schedules, `schedule: "0 3 * * *"`)
+	assert.Contains(t, schedules, "includedNamespaces:\n      - shop-prod")
+	assert.Contains(t, schedules, "storageLocation: aws-s3")
+	assert.Contains(t, schedules, "snapshotVolumes: true")
+	assert.Contains(t, schedules, "ttl: 720h")
+	assert.NotContains(t, schedules, "shop-dev")
+
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/backup/dev/kustomization.yaml"), "- schedules.yaml")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/overlays/dev/kustomization.yaml"), "- ../../backup/dev")
+}
+
+func TestGenerator_BackupSchedulesFlux(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newBackupTestConfig("flux"), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/infrastructure/overlays/dev/kustomization.yaml"), "backup")
+	backup := readGenerated(t, tmpDir, "shop/flux/kustomizations/backup-dev.yaml")
+	assert.Contains(t, backup, "name: shop-backup-dev")
+	assert.Contains(t, backup, "path: ./infrastructure/backup/dev")
+	assert.Contains(t, backup, "- name: shop-infra-dev")
+	assert.NotContains(t, backup, "targetNamespace", "the Schedules stay in the Velero namespace")
+}
+
+func TestGenerator_BackupDisabled(t *testing.T) {
+	cfg := newBackupTestConfig("argocd")
+	cfg.Backup = config.BackupConfig{}
+	tmpDir := t.TempDir()
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NoDirExists(t, tmpDir+"/shop/infrastructure/backup")
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/infrastructure/overlays/dev/kustomization.yaml"), "backup")
+}
@@ -0,0 +1,36 @@
+package generator
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// BenchmarkGenerate generates a project of 100 applications deployed to
+// three environments, sequentially and on the default worker pool:
+//
+//	go test -run '^$' -bench Generate ./internal/generator/
+func BenchmarkGenerate(b *testing.B) {
+	cfg := newLargeConfig(100)
+	for _, bm := range []struct {
+		name    string
+		workers int
+	}{
+		{"sequential", 1},
+		{"parallel", 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for range b.N {
+				writer := output.New(b.TempDir(), false, false)
+				writer.Log = io.Discard
+				gen := New(cfg, writer, false)
+				gen.Log = io.Discard
+				gen.Workers = bm.workers
+				if err := gen.Generate(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// ciRenderDir is where pipelines write the rendered manifests they publish.
+const ciRenderDir = "rendered"
+
+// ciPipelines maps CI providers to their template and the path of the
+// pipeline, relative to the project root.
+var ciPipelines = map[string][2]string{
+	config.CIProviderGitHubActions: {"ci/github-actions.yaml.tmpl", ".github/workflows/gitopsi.yaml"},
+	config.CIProviderGitLabCI:      {"ci/gitlab-ci.yaml.tmpl", ".gitlab-ci.yml"},
+	config.CIProviderTekton:        {"ci/tekton.yaml.tmpl", ".tekton/pull-request.yaml"},
+}
+
+// ciDiff is the diff command of one environment.
+type ciDiff struct {
+	Environment string
+	Command     string
+}
+
+// ciDiffCommands returns a gitopsi diff per environment. Drift is expected
+// in a pull request, so only errors fail the job.
+func (g *Generator) ciDiffCommands() []ciDiff {
+	if !g.Config.CI.Diff {
+		return nil
+	}
+	diffs := make([]ciDiff, 0, len(g.Config.Environments))
+	for _, env := range g.Config.Environments {
+		cmd := "gitopsi diff --path . --env " + env.Name
+		if env.Context != "" {
+			cmd += " --context " + env.Context
+		}
+		diffs = append(diffs, ciDiff{Environment: env.Name, Command: cmd + " --details || [ $? -eq 2 ]"})
+	}
+	return diffs
+}
+
+// generateCI writes the pull request pipeline of the ci block: validation and
+// kustomize build of every overlay, policy checks and, optionally, a diff
+// against each environment's cluster.
+func (g *Generator) generateCI() error {
+	ci := g.Config.CI
+	if !ci.Enabled() {
+		return nil
+	}
+	pipeline, ok := ciPipelines[ci.Provider]
+	if !ok {
+		return fmt.Errorf("unsupported ci provider: %s", ci.Provider)
+	}
+
+	g.printf("🧪 Generating %s pipeline...\n", ci.Provider)
+
+	branch := g.Config.Git.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	content, err := templates.Render(pipeline[0], map[string]any{
+		"Project":         g.Config.Project.Name,
+		"Branch":          branch,
+		"Image":           ci.ImageRef(),
+		"ValidateCommand": "gitopsi validate . --schema --deprecation --kustomize --fail-on " + ci.Severity(),
+		"PolicyCommand":   "gitopsi validate . --security --secrets --fail-on " + ci.Severity(),
+		"RenderCommand":   "gitopsi render --path . --out-dir " + ciRenderDir,
+		"RenderDir":       ciRenderDir,
+		"DiffCommands":    g.ciDiffCommands(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return g.Writer.WriteFile(g.Config.Project.Name+"/"+pipeline[1], content)
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newCITestConfig(ci config.CIConfig) *config.Config {
+	cfg := newTenantTestConfig()
+	cfg.Tenants = nil
+	cfg.Environments[1].Context = "prod-admin"
+	cfg.CI = ci
+	return cfg
+}
+
+func TestGenerator_CIPipelines(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+	}{
+		{"github-actions", "plat/.github/workflows/gitopsi.yaml"},
+		{"gitlab-ci", "plat/.gitlab-ci.yml"},
+		{"tekton", "plat/.tekton/pull-request.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := newCITestConfig(config.CIConfig{Provider: tt.provider, FailOn: "medium", Diff: true})
+			gen := New(cfg, output.New(tmpDir, false, false), false)
+
+			require.NoError(t, gen.Generate())
+
+			pipeline := readGenerated(t, tmpDir, tt.path)
+			var doc map[string]any
+			require.NoError(t, yaml.Unmarshal([]byte(pipeline), &doc), "pipeline must be valid YAML")
+			assert.Contains(t, pipeline, config.DefaultCIImage)
+			assert.Contains(t, pipeline, "gitopsi validate . --schema --deprecation --kustomize --fail-on medium")
+			assert.Contains(t, pipeline, "gitopsi validate . --security --secrets --fail-on medium")
+			assert.Contains(t, pipeline, "gitopsi render --path . --out-dir rendered")
+			assert.Contains(t, pipeline, "gitopsi diff --path . --env dev --details || [ $? -eq 2 ]")
+			assert.Contains(t, pipeline, "gitopsi diff --path . --env prod --context prod-admin --details || [ $? -eq 2 ]")
+		})
+	}
+}
+
+func TestGenerator_CIPipelineWithoutDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newCITestConfig(config.CIConfig{Provider: "github-actions", Image: "registry.example.com/gitopsi:v1"})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	pipeline := readGenerated(t, tmpDir, "plat/.github/workflows/gitopsi.yaml")
+	assert.Contains(t, pipeline, "container: registry.example.com/gitopsi:v1")
+	assert.Contains(t, pipeline, "--fail-on high")
+	assert.NotContains(t, pipeline, "gitopsi diff")
+	assert.NotContains(t, pipeline, "secrets.KUBECONFIG")
+}
+
+func TestGenerator_NoCIPipeline(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newCITestConfig(config.CIConfig{}), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	for _, path := range []string{".github", ".gitlab-ci.yml", ".tekton"} {
+		assert.NoFileExists(t, filepath.Join(tmpDir, "plat", path))
+		assert.NoDirExists(t, filepath.Join(tmpDir, "plat", path))
+	}
+}
@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"github.com/ihsanmokhlisse/gitopsi/internal/compatibility"
+)
+
+// enableCompatibilityChecks checks every written manifest against the target
+// versions in the config. Writes using APIs the target does not serve fail.
+// A BeforeWrite hook already set on the writer runs after the check.
+func (g *Generator) enableCompatibilityChecks() error {
+	v := g.Config.Version
+	checker, err := compatibility.New(compatibility.Target{
+		Kubernetes: v.Kubernetes,
+		OpenShift:  v.OpenShift,
+		ArgoCD:     v.ArgoCD,
+		Flux:       v.Flux,
+	}, v.StrictMode)
+	if err != nil {
+		return err
+	}
+
+	g.Compatibility = checker
+	next := g.Writer.BeforeWrite
+	g.Writer.BeforeWrite = func(file string, content []byte) error {
+		if err := checker.BeforeWrite(file, content); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(file, content)
+		}
+		return nil
+	}
+	return nil
+}
+
+// reportCompatibility prints deprecated APIs found in the generated output.
+func (g *Generator) reportCompatibility() {
+	if g.Compatibility == nil {
+		return
+	}
+	warnings := g.Compatibility.Warnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	g.printf("\n⚠️  %d deprecated API(s) in generated manifests:\n", len(warnings))
+	for _, w := range warnings {
+		g.printf("  ⚠️  %s\n", w)
+	}
+}
@@ -0,0 +1,25 @@
+package generator
+
+import "github.com/ihsanmokhlisse/gitopsi/internal/conventions"
+
+// applyConventions adds the cost center label and the labels and
+// annotations of the conventions to every resource the generator writes.
+func (g *Generator) applyConventions() {
+	transform := conventions.Chain(
+		conventions.CostTransformer(g.Config.Cost, g.costCenter),
+		conventions.Transformer(g.Config.Conventions),
+	)
+	if transform == nil {
+		return
+	}
+	next := g.Writer.Transform
+	g.Writer.Transform = func(file string, content []byte) ([]byte, error) {
+		if next != nil {
+			var err error
+			if content, err = next(file, content); err != nil {
+				return nil, err
+			}
+		}
+		return transform(file, content)
+	}
+}
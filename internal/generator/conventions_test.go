@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func TestGenerator_Conventions(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "shop"
+	cfg.Git.URL = "https://github.com/org/shop.git"
+	cfg.Environments = []config.Environment{{Name: "dev"}}
+	cfg.Infra = config.Infrastructure{Namespaces: true}
+	cfg.Conventions = config.ConventionsConfig{
+		Names:       config.NameConventions{Namespace: "{env}-{project}"},
+		Labels:      map[string]string{"team": "platform", "cost-center": "1234"},
+		Annotations: map[string]string{"owner": "platform@example.com"},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	namespace := readGenerated(t, tmpDir, "shop/infrastructure/base/namespaces/dev.yaml")
+	assert.Contains(t, namespace, "name: dev-shop")
+	assert.Contains(t, namespace, "team: platform")
+	assert.Contains(t, namespace, `cost-center: "1234"`)
+	assert.Contains(t, namespace, "owner: platform@example.com")
+
+	kustomization := readGenerated(t, tmpDir, "shop/infrastructure/base/namespaces/kustomization.yaml")
+	assert.NotContains(t, kustomization, "team: platform")
+
+	app := readGenerated(t, tmpDir, "shop/argocd/applicationsets/infra-dev.yaml")
+	assert.Contains(t, app, "team: platform")
+	assert.Contains(t, app, "namespace: dev-shop")
+}
@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// costAllocation is the cost center of a set of namespaces.
+type costAllocation struct {
+	CostCenter string
+	Tenant     string
+	Namespaces []string
+}
+
+// costCenter returns the cost center of a generated file: the one of the
+// tenant whose resources it holds, or the project's.
+func (g *Generator) costCenter(file string) string {
+	project := g.Config.Project.Name + "/"
+	for _, tenant := range g.Config.Tenants {
+		for _, dir := range []string{"infrastructure/base/tenants/" + tenant.Name, "flux/tenants/" + tenant.Name, tenant.RepoPath()} {
+			if strings.HasPrefix(file, project+dir+"/") {
+				return g.Config.TenantCostCenter(tenant)
+			}
+		}
+		if path.Base(file) == "tenant-"+tenant.Name+".yaml" {
+			return g.Config.TenantCostCenter(tenant)
+		}
+	}
+	return g.Config.ProjectCostCenter()
+}
+
+// generateCostAllocation writes cost/allocation-values.yaml: the cost center
+// of the environment namespaces and of the namespaces of every tenant, to
+// break down the OpenCost allocations by cost center.
+func (g *Generator) generateCostAllocation() error {
+	project := costAllocation{CostCenter: g.Config.ProjectCostCenter()}
+	for _, env := range g.Config.Environments {
+		project.Namespaces = append(project.Namespaces, g.Config.GetEnvironmentNamespace(env.Name))
+	}
+	allocations := []costAllocation{project}
+	for _, tenant := range g.Config.Tenants {
+		allocation := costAllocation{CostCenter: g.Config.TenantCostCenter(tenant), Tenant: tenant.Name}
+		for _, env := range g.Config.Environments {
+			allocation.Namespaces = append(allocation.Namespaces, tenant.EnvNamespaces(env.Name)...)
+		}
+		allocations = append(allocations, allocation)
+	}
+
+	content, err := templates.Render("infrastructure/cost-allocation-values.yaml.tmpl", map[string]any{
+		"Project":     g.Config.Project.Name,
+		"Label":       g.Config.Cost.LabelKey(),
+		"Allocations": allocations,
+	})
+	if err != nil {
+		return err
+	}
+	return g.Writer.WriteFile(fmt.Sprintf("%s/cost/allocation-values.yaml", g.Config.Project.Name), content)
+}
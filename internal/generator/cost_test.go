@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newCostTestConfig(tool string) *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "shop"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: tool,
+		Git:        config.GitConfig{URL: "https://github.com/org/shop.git"},
+		Infra:      config.Infrastructure{Namespaces: true},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod"},
+		},
+		Tenants: []config.Tenant{
+			{Name: "payments", CostCenter: "cc-1234"},
+			{Name: "search"},
+		},
+		Apps: []config.Application{{Name: "api", Image: "api:1.0", Port: 8080}},
+		Cost: config.CostConfig{Enabled: true, CostCenter: "platform"},
+	}
+}
+
+func TestGenerator_CostLabels(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newCostTestConfig("argocd"), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	namespace := readGenerated(t, tmpDir, "shop/infrastructure/base/namespaces/dev.yaml")
+	assert.Contains(t, namespace, "cost-center: platform")
+
+	deployment := readGenerated(t, tmpDir, "shop/applications/base/api/deployment.yaml")
+	assert.Contains(t, deployment, "  labels:\n    app: api")
+	assert.Contains(t, deployment, "cost-center: platform")
+	assert.GreaterOrEqual(t, strings.Count(deployment, "cost-center: platform"), 2, "the pod template is labelled too")
+
+	payments := readGenerated(t, tmpDir, "shop/infrastructure/base/tenants/payments/dev.yaml")
+	assert.Contains(t, payments, "cost-center: cc-1234")
+	assert.NotContains(t, payments, "cost-center: platform")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/argocd/projects/tenant-search.yaml"), "cost-center: search")
+
+	allocation := readGenerated(t, tmpDir, "shop/cost/allocation-values.yaml")
+	assert.Contains(t, allocation, "label: cost-center")
+	assert.Contains(t, allocation, "  - cost_center: \"platform\"\n    namespaces:\n      - shop-dev\n      - shop-prod\n")
+	assert.Contains(t, allocation, "  - cost_center: \"cc-1234\"\n    tenant: payments\n    namespaces:\n      - payments-dev\n      - payments-prod\n")
+	assert.Contains(t, allocation, "  - cost_center: \"search\"\n    tenant: search\n")
+}
+
+func TestGenerator_CostLabelsFlux(t *testing.T) {
+	cfg := newCostTestConfig("flux")
+	cfg.Cost.Label = "example.com/cost-center"
+	tmpDir := t.TempDir()
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/flux/tenants/payments/dev.yaml"), "example.com/cost-center: cc-1234")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/tenants/search/dev/kustomization.yaml"), "namespace: search-dev")
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/tenants/search/dev/kustomization.yaml"), "cost-center", "kustomizations are not labelled")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/cost/allocation-values.yaml"), "label: example.com/cost-center")
+}
+
+func TestGenerator_CostDisabled(t *testing.T) {
+	cfg := newCostTestConfig("argocd")
+	cfg.Cost = config.CostConfig{}
+	tmpDir := t.TempDir()
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NoFileExists(t, tmpDir+"/shop/cost/allocation-values.yaml")
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/applications/base/api/deployment.yaml"), "cost-center")
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func (g *Generator) generateDocs() error {
-	fmt.Println("📚 Generating documentation...")
+	g.printf("📚 Generating documentation...\n")
 
 	if g.Config.Docs.Readme {
 		content, err := templates.Render("docs/README.md.tmpl", g.Config)
@@ -22,7 +22,7 @@ func (g *Generator) generateDocs() error {
 	}
 
 	if g.Config.Docs.Architecture {
-		content, err := templates.Render("docs/ARCHITECTURE.md.tmpl", g.Config)
+		content, err := templates.Render("docs/ARCHITECTURE.md.tmpl", g.architecture())
 		if err != nil {
 			return err
 		}
@@ -43,21 +43,27 @@ func (g *Generator) generateDocs() error {
 		if err := g.Writer.WriteFile(path, content); err != nil {
 			return err
 		}
+		if err := g.generateGuides(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (g *Generator) generateBootstrap() error {
-	fmt.Println("🔧 Generating bootstrap...")
+	g.printf("🔧 Generating bootstrap...\n")
 
-	argoCDNamespace := g.getArgoCDNamespace()
+	toolNamespace := g.getArgoCDNamespace()
+	if g.Config.GitOpsTool == "flux" {
+		toolNamespace = g.getFluxNamespace()
+	}
 
 	bootstrapContent := fmt.Sprintf(`apiVersion: v1
 kind: Namespace
 metadata:
   name: %s
-`, argoCDNamespace)
+`, toolNamespace)
 
 	path := fmt.Sprintf("%s/bootstrap/%s/namespace.yaml",
 		g.Config.Project.Name, g.Config.GitOpsTool)
@@ -69,7 +75,7 @@ metadata:
 }
 
 func (g *Generator) generateScripts() error {
-	fmt.Println("📜 Generating scripts...")
+	g.printf("📜 Generating scripts...\n")
 
 	bootstrapScript := fmt.Sprintf(`#!/bin/bash
 set -e
@@ -81,9 +87,9 @@ kubectl apply -f bootstrap/%s/namespace.yaml
 
 # Apply GitOps tool
 echo "Apply your %s installation manifests here"
-
+%s
 echo "Bootstrap complete!"
-`, g.Config.Project.Name, g.Config.GitOpsTool, g.Config.GitOpsTool)
+`, g.Config.Project.Name, g.Config.GitOpsTool, g.Config.GitOpsTool, g.bootstrapSSOStep()+g.bootstrapRBACStep()+g.bootstrapRepositoriesStep()+g.bootstrapTLSStep())
 
 	path := g.Config.Project.Name + "/scripts/bootstrap.sh"
 	if err := g.Writer.WriteFile(path, []byte(bootstrapScript)); err != nil {
@@ -119,3 +125,34 @@ echo "Validation complete!"
 
 	return nil
 }
+
+// bootstrapRBACStep returns the bootstrap script step applying argocd-rbac-cm.
+func (g *Generator) bootstrapRBACStep() string {
+	if !g.generatesArgoCDRBAC() {
+		return ""
+	}
+	return fmt.Sprintf(`
+# Apply ArgoCD RBAC policy
+kubectl apply -f bootstrap/%s/argocd-rbac-cm.yaml
+`, g.Config.GitOpsTool)
+}
+
+// bootstrapSSOStep returns the bootstrap script step applying the SSO
+// configuration and its client secret.
+func (g *Generator) bootstrapSSOStep() string {
+	if !g.generatesArgoCDSSO() {
+		return ""
+	}
+	secret := ""
+	switch {
+	case g.Config.SSO.ClientSecret.Existing:
+	case g.Config.Secrets.Format == "sops":
+		secret = fmt.Sprintf("sops --decrypt %s | kubectl apply -f -\n", g.ssoSecretPath())
+	default:
+		secret = fmt.Sprintf("kubectl apply -f %s\n", g.ssoSecretPath())
+	}
+	return fmt.Sprintf(`
+# Apply ArgoCD SSO configuration
+%skubectl apply -f bootstrap/%s/argocd-cm.yaml
+`, secret, g.Config.GitOpsTool)
+}
@@ -2,11 +2,14 @@ package generator
 
 import (
 	"fmt"
+	"strings"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
 	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
 )
 
-//nolint:unused // Flux support disabled for v0.2.0, will be enabled in v0.3.0
 func (g *Generator) getFluxNamespace() string {
 	if g.Config.Bootstrap.Namespace != "" {
 		return g.Config.Bootstrap.Namespace
@@ -14,9 +17,29 @@ func (g *Generator) getFluxNamespace() string {
 	return "flux-system"
 }
 
-//nolint:unused // Flux support disabled for v0.2.0, will be enabled in v0.3.0
+func (g *Generator) getFluxInterval() string {
+	if g.Config.Flux.Interval != "" {
+		return g.Config.Flux.Interval
+	}
+	return "10m"
+}
+
+func (g *Generator) usesFlux() bool {
+	return g.Config.GitOpsTool == "flux" || g.Config.GitOpsTool == "both"
+}
+
+// fluxServiceAccount returns the service account a platform Kustomization or
+// HelmRelease impersonates: under the multi-tenancy lockdown the default one
+// of the Flux namespace has no permissions, so they run as the controller.
+func (g *Generator) fluxServiceAccount(controller string) string {
+	if g.Config.FluxLockdown() {
+		return controller
+	}
+	return ""
+}
+
 func (g *Generator) generateFlux() error {
-	fmt.Println("🔄 Generating Flux configuration...")
+	g.printf("🔄 Generating Flux configuration...\n")
 
 	fluxNamespace := g.getFluxNamespace()
 
@@ -28,10 +51,27 @@ func (g *Generator) generateFlux() error {
 		return err
 	}
 
+	if g.Config.Flux.HelmReleases && (g.Config.Scope == "application" || g.Config.Scope == "both") {
+		if err := g.generateFluxHelmReleases(fluxNamespace); err != nil {
+			return err
+		}
+	}
+
+	if g.Config.Flux.ImageAutomation.Enabled {
+		if err := g.generateFluxImageAutomation(fluxNamespace); err != nil {
+			return err
+		}
+	}
+
+	if len(g.Config.Tenants) > 0 {
+		if err := g.generateFluxTenants(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-//nolint:unused // Flux support disabled for v0.2.0, will be enabled in v0.3.0
 func (g *Generator) generateFluxGitRepository(fluxNamespace string) error {
 	repoURL := g.Config.Git.URL
 	if repoURL == "" {
@@ -60,7 +100,7 @@ func (g *Generator) generateFluxGitRepository(fluxNamespace string) error {
 		return err
 	}
 
-	path := fmt.Sprintf("%s/%s/sources/gitrepository.yaml", g.Config.Project.Name, g.Config.GitOpsTool)
+	path := fmt.Sprintf("%s/flux/sources/gitrepository.yaml", g.Config.Project.Name)
 	if err := g.Writer.WriteFile(path, content); err != nil {
 		return err
 	}
@@ -68,63 +108,135 @@ func (g *Generator) generateFluxGitRepository(fluxNamespace string) error {
 	return nil
 }
 
-//nolint:unused // Flux support disabled for v0.2.0, will be enabled in v0.3.0
+// fluxTarget is a cluster the Kustomizations of an environment reconcile
+// into.
+type fluxTarget struct {
+	// Suffix distinguishes the Kustomizations of the clusters of an
+	// environment with several clusters.
+	Suffix string
+	// KubeConfigSecret is the kubeConfig Secret of a spoke cluster reconciled
+	// from the hub, empty for the cluster Flux runs on.
+	KubeConfigSecret string
+}
+
+// fluxTargets returns the clusters the Kustomizations of env reconcile into.
+// With the hub strategy of bootstrap.multi_cluster the hub Flux reconciles the
+// spoke clusters of the environments through their kubeConfig Secrets, named
+// <cluster>-kubeconfig as written by gitopsi bootstrap and gitopsi cluster add.
+func (g *Generator) fluxTargets(env config.Environment) []fluxTarget {
+	mc := g.Config.Bootstrap.MultiCluster
+	if mc == nil || mc.Strategy != "hub" || mc.Hub == "" {
+		return []fluxTarget{{}}
+	}
+	if len(env.Clusters) > 0 {
+		targets := make([]fluxTarget, 0, len(env.Clusters))
+		for _, ec := range env.Clusters {
+			target := fluxTarget{Suffix: "-" + ec.Name}
+			if ec.Name != mc.Hub {
+				target.KubeConfigSecret = fluxKubeConfigSecret(ec.Name)
+			}
+			targets = append(targets, target)
+		}
+		return targets
+	}
+	if env.Name == mc.Hub || (env.Cluster == "" && env.Context == "" && env.Credential == "" && env.Kubeconfig == "") {
+		return []fluxTarget{{}}
+	}
+	return []fluxTarget{{KubeConfigSecret: fluxKubeConfigSecret(env.Name)}}
+}
+
+// fluxKubeConfigSecret returns the name of the kubeConfig Secret of a spoke
+// cluster in the Flux namespace of the hub.
+func fluxKubeConfigSecret(clusterName string) string {
+	return clusterName + "-kubeconfig"
+}
+
+// serviceAccount returns the service account a Kustomization of the target
+// impersonates: on spoke clusters Flux would impersonate it there, where the
+// controller accounts of the hub do not exist.
+func (t fluxTarget) serviceAccount(g *Generator, controller string) string {
+	if t.KubeConfigSecret != "" {
+		return ""
+	}
+	return g.fluxServiceAccount(controller)
+}
+
 func (g *Generator) generateFluxKustomizations(fluxNamespace string) error {
 	for _, env := range g.Config.Environments {
-		namespace := g.Config.Project.Name + "-" + env.Name
+		namespace := g.Config.GetEnvironmentNamespace(env.Name)
 
-		if g.Config.Scope == "infrastructure" || g.Config.Scope == "both" {
-			kustomizationData := map[string]any{
-				"Name":            fmt.Sprintf("%s-infra-%s", g.Config.Project.Name, env.Name),
-				"Namespace":       fluxNamespace,
-				"Interval":        "10m",
-				"SourceName":      g.Config.Project.Name,
-				"Path":            fmt.Sprintf("./infrastructure/overlays/%s", env.Name),
-				"Prune":           true,
-				"TargetNamespace": namespace,
-				"HealthChecks":    []any{},
-				"DependsOn":       []string{},
-			}
+		for _, target := range g.fluxTargets(env) {
+			if g.Config.Scope == "infrastructure" || g.Config.Scope == "both" {
+				kustomizationData := map[string]any{
+					"Name":               fmt.Sprintf("%s-infra-%s%s", g.Config.Project.Name, env.Name, target.Suffix),
+					"Namespace":          fluxNamespace,
+					"Interval":           g.getFluxInterval(),
+					"SourceName":         g.Config.Project.Name,
+					"Path":               fmt.Sprintf("./infrastructure/overlays/%s", env.Name),
+					"Prune":              true,
+					"TargetNamespace":    namespace,
+					"HealthChecks":       []any{},
+					"DependsOn":          []string{},
+					"ServiceAccountName": target.serviceAccount(g, "kustomize-controller"),
+					"KubeConfigSecret":   target.KubeConfigSecret,
+				}
 
-			content, err := templates.Render("flux/kustomization.yaml.tmpl", kustomizationData)
-			if err != nil {
-				return err
-			}
+				content, err := templates.Render("flux/kustomization.yaml.tmpl", kustomizationData)
+				if err != nil {
+					return err
+				}
 
-			path := fmt.Sprintf("%s/%s/kustomizations/infra-%s.yaml",
-				g.Config.Project.Name, g.Config.GitOpsTool, env.Name)
-			if err := g.Writer.WriteFile(path, content); err != nil {
-				return err
-			}
-		}
+				path := fmt.Sprintf("%s/flux/kustomizations/infra-%s%s.yaml",
+					g.Config.Project.Name, env.Name, target.Suffix)
+				if err := g.Writer.WriteFile(path, content); err != nil {
+					return err
+				}
 
-		if g.Config.Scope == "application" || g.Config.Scope == "both" {
-			dependsOn := []string{}
-			if g.Config.Scope == "both" {
-				dependsOn = append(dependsOn, fmt.Sprintf("%s-infra-%s", g.Config.Project.Name, env.Name))
+				if g.Config.Backup.Enabled {
+					if err := g.writeFluxBackupKustomization(env, fluxNamespace, target); err != nil {
+						return err
+					}
+				}
 			}
 
-			kustomizationData := map[string]any{
-				"Name":            fmt.Sprintf("%s-apps-%s", g.Config.Project.Name, env.Name),
-				"Namespace":       fluxNamespace,
-				"Interval":        "10m",
-				"SourceName":      g.Config.Project.Name,
-				"Path":            fmt.Sprintf("./applications/overlays/%s", env.Name),
-				"Prune":           true,
-				"TargetNamespace": namespace,
-				"HealthChecks":    []any{},
-				"DependsOn":       dependsOn,
-			}
+			if g.Config.Scope == "application" || g.Config.Scope == "both" {
+				dependsOn := []string{}
+				if g.Config.Scope == "both" {
+					dependsOn = append(dependsOn, fmt.Sprintf("%s-infra-%s%s", g.Config.Project.Name, env.Name, target.Suffix))
+				}
 
-			content, err := templates.Render("flux/kustomization.yaml.tmpl", kustomizationData)
-			if err != nil {
-				return err
-			}
+				appsPath := fmt.Sprintf("./applications/overlays/%s", env.Name)
+				targetNamespace := namespace
+				if g.Config.Flux.HelmReleases {
+					// HelmReleases live in the Flux namespace and set their own target namespace
+					appsPath = fmt.Sprintf("./applications/helmreleases/%s", env.Name)
+					targetNamespace = ""
+				}
 
-			path := fmt.Sprintf("%s/%s/kustomizations/apps-%s.yaml",
-				g.Config.Project.Name, g.Config.GitOpsTool, env.Name)
-			if err := g.Writer.WriteFile(path, content); err != nil {
-				return err
+				kustomizationData := map[string]any{
+					"Name":               fmt.Sprintf("%s-apps-%s%s", g.Config.Project.Name, env.Name, target.Suffix),
+					"Namespace":          fluxNamespace,
+					"Interval":           g.getFluxInterval(),
+					"SourceName":         g.Config.Project.Name,
+					"Path":               appsPath,
+					"Prune":              true,
+					"TargetNamespace":    targetNamespace,
+					"HealthChecks":       []any{},
+					"DependsOn":          dependsOn,
+					"ServiceAccountName": target.serviceAccount(g, "kustomize-controller"),
+					"KubeConfigSecret":   target.KubeConfigSecret,
+				}
+
+				content, err := templates.Render("flux/kustomization.yaml.tmpl", kustomizationData)
+				if err != nil {
+					return err
+				}
+
+				path := fmt.Sprintf("%s/flux/kustomizations/apps-%s%s.yaml",
+					g.Config.Project.Name, env.Name, target.Suffix)
+				if err := g.Writer.WriteFile(path, content); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -132,7 +244,7 @@ func (g *Generator) generateFluxKustomizations(fluxNamespace string) error {
 	return nil
 }
 
-//nolint:unused // Flux support disabled for v0.2.0, will be enabled in v0.3.0
+//nolint:unused // Notifications need a provider address and are not generated yet
 func (g *Generator) generateFluxNotifications(fluxNamespace string) error {
 	// Provider for notifications (e.g., Slack, Discord, etc.)
 	providerData := map[string]any{
@@ -149,7 +261,7 @@ func (g *Generator) generateFluxNotifications(fluxNamespace string) error {
 		return err
 	}
 
-	path := fmt.Sprintf("%s/%s/notifications/provider.yaml", g.Config.Project.Name, g.Config.GitOpsTool)
+	path := fmt.Sprintf("%s/flux/notifications/provider.yaml", g.Config.Project.Name)
 	if writeErr := g.Writer.WriteFile(path, content); writeErr != nil {
 		return writeErr
 	}
@@ -186,6 +298,297 @@ func (g *Generator) generateFluxNotifications(fluxNamespace string) error {
 		return err
 	}
 
-	path = fmt.Sprintf("%s/%s/notifications/alert.yaml", g.Config.Project.Name, g.Config.GitOpsTool)
+	path = fmt.Sprintf("%s/flux/notifications/alert.yaml", g.Config.Project.Name)
+	return g.Writer.WriteFile(path, content)
+}
+
+// fluxAppChartFiles are the files of the generic application chart used by HelmReleases.
+var fluxAppChartFiles = []string{
+	"Chart.yaml",
+	"values.yaml",
+	"templates/configmap.yaml",
+	"templates/deployment.yaml",
+	"templates/service.yaml",
+}
+
+func (g *Generator) generateFluxHelmReleases(fluxNamespace string) error {
+	if err := g.pinImages(); err != nil {
+		return err
+	}
+
+	for _, file := range fluxAppChartFiles {
+		content, err := templates.Raw("charts/app/" + file)
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/charts/app/%s", g.Config.Project.Name, file)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	for _, env := range g.Config.Environments {
+		resources := make([]string, 0, len(g.Config.Apps))
+
+		for _, app := range g.Config.Apps {
+			releaseData := map[string]any{
+				"Name":               fmt.Sprintf("%s-%s", app.Name, env.Name),
+				"ReleaseName":        app.Name,
+				"Namespace":          fluxNamespace,
+				"Interval":           g.getFluxInterval(),
+				"Chart":              "./charts/app",
+				"SourceKind":         "GitRepository",
+				"RepoName":           g.Config.Project.Name,
+				"RepoNamespace":      fluxNamespace,
+				"TargetNamespace":    g.Config.GetEnvironmentNamespace(env.Name),
+				"Values":             g.fluxAppValues(app.ForEnvironment(env.Name)),
+				"DependsOn":          fluxReleaseDependencies(app, env.Name, fluxNamespace),
+				"ServiceAccountName": g.fluxServiceAccount("helm-controller"),
+			}
+
+			content, err := templates.Render("flux/helmrelease.yaml.tmpl", releaseData)
+			if err != nil {
+				return err
+			}
+
+			path := fmt.Sprintf("%s/applications/helmreleases/%s/%s.yaml", g.Config.Project.Name, env.Name, app.Name)
+			if err := g.Writer.WriteFile(path, content); err != nil {
+				return err
+			}
+			resources = append(resources, app.Name+".yaml")
+		}
+
+		content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", map[string]any{"Resources": resources})
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/applications/helmreleases/%s/kustomization.yaml", g.Config.Project.Name, env.Name)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fluxReleaseDependencies returns the HelmReleases of the applications app
+// depends on in the environment.
+func fluxReleaseDependencies(app config.Application, envName, fluxNamespace string) []map[string]string {
+	deps := make([]map[string]string, 0, len(app.DependsOn))
+	for _, dep := range app.DependsOn {
+		deps = append(deps, map[string]string{
+			"Name":      fmt.Sprintf("%s-%s", dep, envName),
+			"Namespace": fluxNamespace,
+		})
+	}
+	return deps
+}
+
+// fluxAppValues renders HelmRelease values for an application, adding image policy markers when enabled.
+func (g *Generator) fluxAppValues(app config.Application) string {
+	image := g.image(app.Image)
+	repository, tag := splitImage(image)
+	replicas, port := app.Replicas, app.Port
+	if replicas == 0 {
+		replicas = 1
+	}
+	if port == 0 {
+		port = 80
+	}
+	app.Port = port
+
+	repoMarker, tagMarker := "", ""
+	if policy := g.imagePolicyRef(app.Name); policy != "" {
+		repoMarker = fmt.Sprintf(` # {"$imagepolicy": "%s:name"}`, policy)
+		tagMarker = fmt.Sprintf(` # {"$imagepolicy": "%s:tag"}`, policy)
+	}
+
+	digest := ""
+	if _, d, ok := strings.Cut(image, "@"); ok {
+		digest = "\n  digest: " + d
+	}
+
+	values := fmt.Sprintf(`replicaCount: %d
+image:
+  repository: %s%s
+  tag: %s%s%s
+service:
+  port: %d`, replicas, repository, repoMarker, tag, tagMarker, digest, port)
+
+	if extra := fluxContainerValues(g.newAppContainer(app)); extra != "" {
+		values += "\n" + extra
+	}
+	return values
+}
+
+// fluxContainerValues renders the env, ConfigMap, resources and probe values
+// of the application chart, or an empty string when the application sets none.
+func fluxContainerValues(c appContainer) string {
+	type envVar struct {
+		Name      string `yaml:"name"`
+		Value     string `yaml:"value,omitempty"`
+		ValueFrom any    `yaml:"valueFrom,omitempty"`
+	}
+	values := struct {
+		Env            []envVar          `yaml:"env,omitempty"`
+		EnvFrom        []map[string]any  `yaml:"envFrom,omitempty"`
+		ConfigMap      map[string]string `yaml:"configMap,omitempty"`
+		Resources      map[string]any    `yaml:"resources,omitempty"`
+		LivenessProbe  map[string]any    `yaml:"livenessProbe,omitempty"`
+		ReadinessProbe map[string]any    `yaml:"readinessProbe,omitempty"`
+		StartupProbe   map[string]any    `yaml:"startupProbe,omitempty"`
+	}{ConfigMap: c.ConfigMap}
+
+	for _, e := range c.Env {
+		values.Env = append(values.Env, envVar{Name: e.Name, Value: e.Value})
+	}
+	for _, e := range c.SecretEnv {
+		values.Env = append(values.Env, envVar{Name: e.Name, ValueFrom: map[string]any{
+			"secretKeyRef": map[string]string{"name": e.Secret, "key": e.Key},
+		}})
+	}
+	for _, from := range c.EnvFrom {
+		if from.Kind == "secretRef" {
+			values.EnvFrom = append(values.EnvFrom, map[string]any{"secretRef": map[string]string{"name": from.Name}})
+		}
+	}
+	if c.Application.Resources != nil {
+		values.Resources = map[string]any{}
+		for key, list := range map[string]config.ResourceList{"requests": c.Resources.Requests, "limits": c.Resources.Limits} {
+			if list.CPU != "" || list.Memory != "" {
+				values.Resources[key] = list
+			}
+		}
+	}
+	for _, p := range c.Probes {
+		spec := map[string]any{}
+		if p.Path != "" {
+			spec["httpGet"] = map[string]any{"path": p.Path, "port": p.Port}
+		} else {
+			spec["tcpSocket"] = map[string]any{"port": p.Port}
+		}
+		for key, v := range map[string]int{
+			"initialDelaySeconds": p.InitialDelaySeconds,
+			"periodSeconds":       p.PeriodSeconds,
+			"timeoutSeconds":      p.TimeoutSeconds,
+			"failureThreshold":    p.FailureThreshold,
+		} {
+			if v != 0 {
+				spec[key] = v
+			}
+		}
+		switch p.Kind {
+		case "livenessProbe":
+			values.LivenessProbe = spec
+		case "readinessProbe":
+			values.ReadinessProbe = spec
+		case "startupProbe":
+			values.StartupProbe = spec
+		}
+	}
+
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(values); err != nil || strings.TrimSpace(buf.String()) == "{}" {
+		return ""
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// imagePolicyRef returns the Flux image policy setter reference for an application, or an empty string.
+func (g *Generator) imagePolicyRef(appName string) string {
+	if !g.usesFlux() || !g.Config.Flux.ImageAutomation.Enabled {
+		return ""
+	}
+	return g.getFluxNamespace() + ":" + appName
+}
+
+func (g *Generator) generateFluxImageAutomation(fluxNamespace string) error {
+	automation := g.Config.Flux.ImageAutomation
+
+	semverRange := automation.SemverRange
+	if semverRange == "" {
+		semverRange = ">=0.0.0"
+	}
+	scanInterval := automation.Interval
+	if scanInterval == "" {
+		scanInterval = "5m"
+	}
+
+	for _, app := range g.Config.Apps {
+		repository, _ := splitImage(app.Image)
+
+		repoContent, err := templates.Render("flux/imagerepository.yaml.tmpl", map[string]any{
+			"Name":      app.Name,
+			"Namespace": fluxNamespace,
+			"Image":     repository,
+			"Interval":  scanInterval,
+		})
+		if err != nil {
+			return err
+		}
+
+		policyContent, err := templates.Render("flux/imagepolicy.yaml.tmpl", map[string]any{
+			"Name":        app.Name,
+			"Namespace":   fluxNamespace,
+			"SemverRange": semverRange,
+		})
+		if err != nil {
+			return err
+		}
+
+		content := append(repoContent, []byte("---\n")...)
+		content = append(content, policyContent...)
+
+		path := fmt.Sprintf("%s/flux/image-automation/%s.yaml", g.Config.Project.Name, app.Name)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	branch := automation.Branch
+	if branch == "" {
+		branch = g.Config.Git.Branch
+	}
+	if branch == "" {
+		branch = "main"
+	}
+	authorName := automation.AuthorName
+	if authorName == "" {
+		authorName = "fluxcdbot"
+	}
+	authorEmail := automation.AuthorEmail
+	if authorEmail == "" {
+		authorEmail = "fluxcdbot@users.noreply.github.com"
+	}
+
+	content, err := templates.Render("flux/imageupdateautomation.yaml.tmpl", map[string]any{
+		"Name":        g.Config.Project.Name,
+		"Namespace":   fluxNamespace,
+		"Interval":    "30m",
+		"SourceName":  g.Config.Project.Name,
+		"Branch":      branch,
+		"AuthorName":  authorName,
+		"AuthorEmail": authorEmail,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s/flux/image-automation/image-update-automation.yaml", g.Config.Project.Name)
 	return g.Writer.WriteFile(path, content)
 }
+
+// splitImage splits an image reference into repository and tag, defaulting the tag to latest.
+func splitImage(image string) (repository, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	slash := strings.LastIndex(image, "/")
+	colon := strings.LastIndex(image, ":")
+	if colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
@@ -0,0 +1,238 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newFluxTestConfig() *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "flux-app"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: "flux",
+		Git:        config.GitConfig{URL: "https://github.com/test/repo.git", Branch: "main"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod"},
+		},
+		Apps: []config.Application{
+			{Name: "web", Image: "ghcr.io/org/web:1.2.3", Port: 8080, Replicas: 2},
+		},
+	}
+}
+
+func readGenerated(t *testing.T, dir, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, rel))
+	require.NoError(t, err, "expected %s to be generated", rel)
+	return string(data)
+}
+
+func TestGenerator_Flux_SourcesAndKustomizations(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newFluxTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	gitRepo := readGenerated(t, tmpDir, "flux-app/flux/sources/gitrepository.yaml")
+	assert.Contains(t, gitRepo, "kind: GitRepository")
+	assert.Contains(t, gitRepo, "url: https://github.com/test/repo.git")
+
+	for _, env := range []string{"dev", "prod"} {
+		infra := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/infra-"+env+".yaml")
+		assert.Contains(t, infra, "path: ./infrastructure/overlays/"+env)
+
+		apps := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/apps-"+env+".yaml")
+		assert.Contains(t, apps, "path: ./applications/overlays/"+env)
+		assert.Contains(t, apps, "targetNamespace: flux-app-"+env)
+		assert.Contains(t, apps, "- name: flux-app-infra-"+env)
+	}
+
+	bootstrapNS := readGenerated(t, tmpDir, "flux-app/bootstrap/flux/namespace.yaml")
+	assert.Contains(t, bootstrapNS, "name: flux-system")
+	assert.NoDirExists(t, filepath.Join(tmpDir, "flux-app/argocd"))
+}
+
+func TestGenerator_Flux_HubKubeConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Environments = []config.Environment{
+		{Name: "dev"},
+		{Name: "staging", Credential: "staging-admin"},
+		{Name: "prod", Clusters: []config.EnvironmentCluster{
+			{Name: "prod-eu", URL: "https://prod-eu.example.com"},
+			{Name: "prod-us", URL: "https://prod-us.example.com"},
+		}},
+	}
+	cfg.Tenants = []config.Tenant{{Name: "team-a"}}
+	cfg.Bootstrap.MultiCluster = &config.BootstrapMultiClusterConfig{Strategy: "hub", Hub: "prod-eu"}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	dev := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/infra-dev.yaml")
+	assert.NotContains(t, dev, "kubeConfig")
+	assert.Contains(t, dev, "serviceAccountName: kustomize-controller")
+
+	staging := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/apps-staging.yaml")
+	assert.Contains(t, staging, "kubeConfig:\n    secretRef:\n      name: staging-kubeconfig\n      key: value")
+	assert.NotContains(t, staging, "serviceAccountName")
+
+	hub := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/infra-prod-prod-eu.yaml")
+	assert.Contains(t, hub, "name: flux-app-infra-prod-prod-eu")
+	assert.NotContains(t, hub, "kubeConfig")
+
+	spoke := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/apps-prod-prod-us.yaml")
+	assert.Contains(t, spoke, "name: prod-us-kubeconfig")
+	assert.Contains(t, spoke, "- name: flux-app-infra-prod-prod-us")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "flux-app/flux/kustomizations/infra-prod.yaml"))
+}
+
+func TestGenerator_Flux_HelmReleases(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Flux.HelmReleases = true
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.FileExists(t, filepath.Join(tmpDir, "flux-app/charts/app/Chart.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "flux-app/charts/app/templates/deployment.yaml"))
+
+	release := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/prod/web.yaml")
+	assert.Contains(t, release, "kind: HelmRelease")
+	assert.Contains(t, release, "name: web-prod")
+	assert.Contains(t, release, "releaseName: web")
+	assert.Contains(t, release, "chart: ./charts/app")
+	assert.Contains(t, release, "kind: GitRepository")
+	assert.Contains(t, release, "targetNamespace: flux-app-prod")
+	assert.Contains(t, release, "    replicaCount: 2")
+	assert.Contains(t, release, "      repository: ghcr.io/org/web")
+	assert.Contains(t, release, "      tag: 1.2.3")
+	assert.NotContains(t, release, "$imagepolicy")
+
+	kustomization := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/prod/kustomization.yaml")
+	assert.Contains(t, kustomization, "web.yaml")
+
+	apps := readGenerated(t, tmpDir, "flux-app/flux/kustomizations/apps-prod.yaml")
+	assert.Contains(t, apps, "path: ./applications/helmreleases/prod")
+	assert.NotContains(t, apps, "targetNamespace")
+}
+
+func TestGenerator_Flux_HelmReleaseDependsOn(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Flux.HelmReleases = true
+	cfg.Apps = append(cfg.Apps, config.Application{Name: "api", Image: "ghcr.io/org/api:1.0.0", Port: 9000})
+	cfg.Apps[0].DependsOn = []string{"api"}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	web := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/prod/web.yaml")
+	assert.Contains(t, web, "  dependsOn:\n    - name: api-prod\n      namespace: flux-system")
+	assert.NotContains(t, web, "sync-wave")
+
+	api := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/prod/api.yaml")
+	assert.NotContains(t, api, "dependsOn")
+}
+
+func TestGenerator_Flux_HelmReleaseContainerValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Flux.HelmReleases = true
+	cfg.Apps[0].Env = []config.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}}
+	cfg.Apps[0].ConfigMap = map[string]string{"FEATURE": "on"}
+	cfg.Apps[0].Secrets = []config.SecretRef{{Name: "web-keys"}}
+	cfg.Apps[0].Probes = &config.Probes{Readiness: &config.Probe{Path: "/ready"}}
+	cfg.Apps[0].Overrides = map[string]config.AppOverride{
+		"prod": {Env: []config.EnvVar{{Name: "LOG_LEVEL", Value: "info"}}, Resources: &config.Resources{Limits: config.ResourceList{Memory: "1Gi"}}},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.FileExists(t, filepath.Join(tmpDir, "flux-app/charts/app/templates/configmap.yaml"))
+
+	prod := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/prod/web.yaml")
+	assert.Contains(t, prod, "      - name: LOG_LEVEL\n        value: info")
+	assert.Contains(t, prod, "    configMap:\n      FEATURE: \"on\"")
+	assert.Contains(t, prod, "      - secretRef:\n          name: web-keys")
+	assert.Contains(t, prod, "        memory: 1Gi")
+	assert.Contains(t, prod, "    readinessProbe:\n      httpGet:\n        path: /ready")
+
+	dev := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/dev/web.yaml")
+	assert.Contains(t, dev, "value: debug")
+	assert.NotContains(t, dev, "resources:")
+}
+
+func TestGenerator_Flux_ImageAutomation(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Flux.HelmReleases = true
+	cfg.Flux.ImageAutomation = config.FluxImageAutomation{Enabled: true, SemverRange: "^1.0.0"}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	policy := readGenerated(t, tmpDir, "flux-app/flux/image-automation/web.yaml")
+	assert.Contains(t, policy, "kind: ImageRepository")
+	assert.Contains(t, policy, "image: ghcr.io/org/web")
+	assert.Contains(t, policy, "kind: ImagePolicy")
+	assert.Contains(t, policy, `range: "^1.0.0"`)
+
+	automation := readGenerated(t, tmpDir, "flux-app/flux/image-automation/image-update-automation.yaml")
+	assert.Contains(t, automation, "kind: ImageUpdateAutomation")
+	assert.Contains(t, automation, "branch: main")
+	assert.Contains(t, automation, "strategy: Setters")
+
+	release := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/dev/web.yaml")
+	assert.Contains(t, release, `tag: 1.2.3 # {"$imagepolicy": "flux-system:web:tag"}`)
+
+	deployment := readGenerated(t, tmpDir, "flux-app/applications/base/web/deployment.yaml")
+	assert.Contains(t, deployment, `image: ghcr.io/org/web:1.2.3 # {"$imagepolicy": "flux-system:web"}`)
+}
+
+func TestGenerator_ArgoCD_NoImagePolicyMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.GitOpsTool = "argocd"
+	cfg.Flux.ImageAutomation.Enabled = true
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	deployment := readGenerated(t, tmpDir, "flux-app/applications/base/web/deployment.yaml")
+	assert.NotContains(t, deployment, "$imagepolicy")
+	assert.NoDirExists(t, filepath.Join(tmpDir, "flux-app/flux"))
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		wantRepo string
+		wantTag  string
+	}{
+		{"nginx", "nginx", "latest"},
+		{"nginx:1.25", "nginx", "1.25"},
+		{"registry:5000/team/app", "registry:5000/team/app", "latest"},
+		{"registry:5000/team/app:v2", "registry:5000/team/app", "v2"},
+		{"ghcr.io/org/app:1.0@sha256:abc", "ghcr.io/org/app", "1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repo, tag := splitImage(tt.image)
+			assert.Equal(t, tt.wantRepo, repo)
+			assert.Equal(t, tt.wantTag, tag)
+		})
+	}
+}
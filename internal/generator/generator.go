@@ -1,10 +1,20 @@
 package generator
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/compatibility"
 	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
 	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+	"github.com/ihsanmokhlisse/gitopsi/internal/telemetry"
 	"github.com/ihsanmokhlisse/gitopsi/internal/version"
 )
 
@@ -15,6 +25,28 @@ type Generator struct {
 	Verbose       bool
 	VersionMapper *version.Mapper
 	Deprecations  []version.DeprecationResult
+	Compatibility *compatibility.Checker
+	metadata      *layout.Metadata
+	// Images resolves image digests for images.pin_digests; nil pulls
+	// anonymously.
+	Images *images.Resolver
+	// Credentials reads the credentials of the auth store, such as the DNS01
+	// one of tls; nil leaves the generated Secrets to be filled in.
+	Credentials func(ctx context.Context, name string) (*auth.Credential, error)
+	// pinned maps the application images to their pinned references.
+	pinned map[string]string
+	// Log receives progress messages; nil means stdout.
+	Log io.Writer
+	// Workers is the number of generation tasks run concurrently; 0 means
+	// one per CPU.
+	Workers int
+	// Progress, if set, is called as generation tasks complete.
+	Progress func(done, total int)
+	// Context carries the telemetry span generation is traced under; nil
+	// means context.Background().
+	Context context.Context
+	// written holds the files of the last Generate, by path.
+	written map[string][]byte
 }
 
 // New creates a new Generator with the given configuration.
@@ -51,6 +83,15 @@ func New(cfg *config.Config, writer *output.Writer, verbose bool) *Generator {
 	return g
 }
 
+// printf writes a progress message to Log.
+func (g *Generator) printf(format string, args ...any) {
+	w := g.Log
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
 // GetAPIVersion returns the appropriate API version for a resource kind.
 func (g *Generator) GetAPIVersion(kind string) string {
 	if g.VersionMapper != nil {
@@ -76,9 +117,9 @@ func (g *Generator) CheckDeprecation(kind, apiVersion, name, filePath string) {
 		// Log warning if verbose
 		if g.Verbose || g.Config.Version.WarnOnDeprecated {
 			if result.Severity == "error" {
-				fmt.Printf("  ❌ %s: %s\n", filePath, result.Message)
+				g.printf("  ❌ %s: %s\n", filePath, result.Message)
 			} else {
-				fmt.Printf("  ⚠️  %s: %s\n", filePath, result.Message)
+				g.printf("  ⚠️  %s: %s\n", filePath, result.Message)
 			}
 		}
 	}
@@ -100,52 +141,63 @@ func (g *Generator) HasCriticalDeprecations() bool {
 }
 
 func (g *Generator) Generate() error {
-	fmt.Printf("\n🚀 Generating GitOps repository: %s\n\n", g.Config.Project.Name)
-
-	if err := g.generateStructure(); err != nil {
-		return fmt.Errorf("failed to generate structure: %w", err)
+	ctx := g.Context
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	ctx, end := telemetry.Start(ctx, "generate",
+		attribute.String("gitops_tool", g.Config.GitOpsTool),
+		attribute.String("scope", g.Config.Scope),
+		attribute.Int("applications", len(g.Config.Apps)),
+		attribute.Int("environments", len(g.Config.Environments)),
+	)
+	err := g.generate(ctx)
+	end(err)
+	return err
+}
 
-	if g.Config.Scope == "infrastructure" || g.Config.Scope == "both" {
-		if err := g.generateInfrastructure(); err != nil {
-			return fmt.Errorf("failed to generate infrastructure: %w", err)
-		}
+func (g *Generator) generate(ctx context.Context) error {
+	g.printf("\n🚀 Generating GitOps repository: %s\n\n", g.Config.Project.Name)
+
+	if err := g.enableCompatibilityChecks(); err != nil {
+		return fmt.Errorf("failed to check API compatibility: %w", err)
 	}
+	g.applyConventions()
+	g.trackGeneratedFiles()
+	g.trackKustomizations()
 
-	if g.Config.Scope == "application" || g.Config.Scope == "both" {
-		if err := g.generateApplications(); err != nil {
+	if g.generatesApplications() {
+		g.defaultApplications()
+		if err := g.pinImages(); err != nil {
 			return fmt.Errorf("failed to generate applications: %w", err)
 		}
 	}
 
-	if err := g.generateGitOps(); err != nil {
-		return fmt.Errorf("failed to generate gitops config: %w", err)
+	if err := g.runTasks(ctx, g.tasks()); err != nil {
+		return err
 	}
 
-	if g.Config.Docs.Readme {
-		if err := g.generateDocs(); err != nil {
-			return fmt.Errorf("failed to generate docs: %w", err)
-		}
+	if err := g.generateMetadata(); err != nil {
+		return fmt.Errorf("failed to generate layout metadata: %w", err)
 	}
 
-	if err := g.generateBootstrap(); err != nil {
-		return fmt.Errorf("failed to generate bootstrap: %w", err)
+	if err := telemetry.Run(ctx, "generate.verify", func(context.Context) error { return g.verifyKustomizations() }); err != nil {
+		return fmt.Errorf("failed to verify kustomizations: %w", err)
 	}
 
-	if err := g.generateScripts(); err != nil {
-		return fmt.Errorf("failed to generate scripts: %w", err)
-	}
+	g.reportCompatibility()
 
-	if err := g.generateOperators(); err != nil {
-		return fmt.Errorf("failed to generate operators: %w", err)
-	}
-
-	fmt.Printf("\n✅ Generated: %s/\n", g.Config.Project.Name)
+	g.printf("\n✅ Generated: %s/\n", g.Config.Project.Name)
 	return nil
 }
 
+// generatesApplications reports whether the scope includes applications.
+func (g *Generator) generatesApplications() bool {
+	return g.Config.Scope == "application" || g.Config.Scope == "both"
+}
+
 func (g *Generator) generateStructure() error {
-	fmt.Println("📁 Creating directory structure...")
+	g.printf("📁 Creating directory structure...\n")
 
 	dirs := []string{
 		g.Config.Project.Name,
@@ -173,7 +225,7 @@ func (g *Generator) generateStructure() error {
 		}
 	}
 
-	if g.Config.Scope == "application" || g.Config.Scope == "both" {
+	if g.generatesHubApplications() {
 		dirs = append(dirs, g.Config.Project.Name+"/applications/base")
 		for _, env := range g.Config.Environments {
 			dirs = append(dirs, g.Config.Project.Name+"/applications/overlays/"+env.Name)
@@ -191,15 +243,21 @@ func (g *Generator) generateStructure() error {
 		}
 	}
 
-	// TODO: Flux support disabled - focus on ArgoCD first
-	// Uncomment when Flux support is ready for production
-	// if g.Config.GitOpsTool == "flux" || g.Config.GitOpsTool == "both" {
-	// 	dirs = append(dirs,
-	// 		g.Config.Project.Name+"/flux/sources",
-	// 		g.Config.Project.Name+"/flux/kustomizations",
-	// 		g.Config.Project.Name+"/flux/notifications",
-	// 	)
-	// }
+	if g.usesFlux() {
+		dirs = append(dirs,
+			g.Config.Project.Name+"/flux/sources",
+			g.Config.Project.Name+"/flux/kustomizations",
+		)
+		if g.Config.Flux.ImageAutomation.Enabled {
+			dirs = append(dirs, g.Config.Project.Name+"/flux/image-automation")
+		}
+		if g.Config.Flux.HelmReleases && (g.Config.Scope == "application" || g.Config.Scope == "both") {
+			dirs = append(dirs, g.Config.Project.Name+"/charts/app")
+			for _, env := range g.Config.Environments {
+				dirs = append(dirs, g.Config.Project.Name+"/applications/helmreleases/"+env.Name)
+			}
+		}
+	}
 
 	for _, dir := range dirs {
 		if err := g.Writer.CreateDir(dir); err != nil {
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
 	"github.com/ihsanmokhlisse/gitopsi/internal/output"
 )
 
@@ -309,6 +310,82 @@ func TestGenerateApplications(t *testing.T) {
 	}
 }
 
+func TestGenerateApplicationsContainerConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Project:      config.Project{Name: "test-apps"},
+		Platform:     "kubernetes",
+		Scope:        "application",
+		GitOpsTool:   "argocd",
+		Environments: []config.Environment{{Name: "dev"}, {Name: "prod"}},
+		Apps: []config.Application{{
+			Name:      "api",
+			Image:     "api:1.0",
+			Port:      8080,
+			Env:       []config.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+			ConfigMap: map[string]string{"FEATURE": "on"},
+			Secrets:   []config.SecretRef{{Name: "api-db", Key: "password", Env: "DB_PASSWORD"}, {Name: "api-keys"}},
+			Resources: &config.Resources{Requests: config.ResourceList{CPU: "50m"}},
+			Probes:    &config.Probes{Readiness: &config.Probe{Path: "/healthz"}, Liveness: &config.Probe{Port: 9090}},
+			Overrides: map[string]config.AppOverride{
+				"prod": {Replicas: 3, Env: []config.EnvVar{{Name: "LOG_LEVEL", Value: "info"}}, ConfigMap: map[string]string{"FEATURE": "off"}},
+			},
+		}},
+	}
+
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+	if err := gen.generateApplications(); err != nil {
+		t.Fatalf("generateApplications() error = %v", err)
+	}
+
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(tmpDir, "test-apps/applications", path))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return string(data)
+	}
+
+	deployment := read("base/api/deployment.yaml")
+	for _, check := range []string{
+		"configMapRef:\n                name: api-config",
+		"secretRef:\n                name: api-keys",
+		"- name: LOG_LEVEL\n              value: \"debug\"",
+		"- name: DB_PASSWORD\n              valueFrom:\n                secretKeyRef:\n                  name: api-db\n                  key: password",
+		"requests:\n              cpu: \"50m\"",
+		"readinessProbe:\n            httpGet:\n              path: /healthz\n              port: 8080",
+		"livenessProbe:\n            tcpSocket:\n              port: 9090",
+	} {
+		if !strings.Contains(deployment, check) {
+			t.Errorf("Deployment missing: %s", check)
+		}
+	}
+	if strings.Contains(deployment, "limits:") {
+		t.Error("Deployment should only set the configured resources")
+	}
+
+	if k := read("base/api/kustomization.yaml"); !strings.Contains(k, "- name: api-config") || !strings.Contains(k, `- "FEATURE=on"`) {
+		t.Errorf("Base kustomization missing ConfigMap generator:\n%s", k)
+	}
+
+	prod := read("overlays/prod/kustomization.yaml")
+	for _, check := range []string{"behavior: merge", `- "FEATURE=off"`, "- path: api-patch.yaml"} {
+		if !strings.Contains(prod, check) {
+			t.Errorf("Prod overlay missing: %s", check)
+		}
+	}
+	patch := read("overlays/prod/api-patch.yaml")
+	if !strings.Contains(patch, "replicas: 3") || !strings.Contains(patch, `value: "info"`) {
+		t.Errorf("Prod patch missing overrides:\n%s", patch)
+	}
+
+	if dev := read("overlays/dev/kustomization.yaml"); strings.Contains(dev, "patches:") || strings.Contains(dev, "configMapGenerator:") {
+		t.Errorf("Dev overlay should not have overrides:\n%s", dev)
+	}
+}
+
 func TestGenerateArgoCD(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -1085,8 +1162,6 @@ func TestGenerateAllScopes(t *testing.T) {
 }
 
 func TestGenerateFluxTool(t *testing.T) {
-	// TODO: Flux support is disabled - focus on ArgoCD first
-	t.Skip("Flux support is disabled - focusing on ArgoCD first")
 	tmpDir := t.TempDir()
 
 	cfg := &config.Config{
@@ -1268,10 +1343,6 @@ func TestGenerateGitOpsForAllTools(t *testing.T) {
 
 	for _, tool := range tools {
 		t.Run(tool, func(t *testing.T) {
-			// TODO: Flux support is disabled - focus on ArgoCD first
-			if tool == "flux" {
-				t.Skip("Flux support is disabled - focusing on ArgoCD first")
-			}
 			tmpDir := t.TempDir()
 
 			cfg := &config.Config{
@@ -1776,3 +1847,41 @@ func TestGenerateCompleteProject(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateLayoutMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Project:      config.Project{Name: "test-project"},
+		Platform:     "kubernetes",
+		Scope:        "application",
+		GitOpsTool:   "argocd",
+		Git:          config.GitConfig{URL: testGitURL},
+		Environments: []config.Environment{{Name: "dev"}},
+		Apps:         []config.Application{{Name: "web", Image: "nginx:latest", Port: 80, Replicas: 1}},
+	}
+
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	meta, err := layout.Load(filepath.Join(tmpDir, "test-project"))
+	if err != nil || meta == nil {
+		t.Fatalf("Load() = %v, %v", meta, err)
+	}
+	if meta.LayoutVersion != layout.CurrentVersion {
+		t.Errorf("expected layout version %d, got %d", layout.CurrentVersion, meta.LayoutVersion)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test-project", "applications/base/web/deployment.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Modified("applications/base/web/deployment.yaml", content) {
+		t.Error("expected the deployment to be recorded with its generated content")
+	}
+	if _, ok := meta.Files[layout.MetadataFile]; ok {
+		t.Error("metadata should not record itself")
+	}
+}
@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+)
+
+// pinImages resolves the application images to digests once when
+// images.pin_digests is set, so Deployments, patches and HelmRelease values
+// reference what the tags point to at generation time.
+func (g *Generator) pinImages() error {
+	if !g.Config.Images.PinDigests || g.pinned != nil {
+		return nil
+	}
+	if g.Images == nil {
+		g.Images = images.NewResolver(nil)
+	}
+
+	pinned := map[string]string{}
+	for _, app := range g.Config.Apps {
+		refs := []string{app.Image}
+		for _, override := range app.Overrides {
+			refs = append(refs, override.Image)
+		}
+		for _, image := range refs {
+			if image == "" || pinned[image] != "" {
+				continue
+			}
+			ref, err := images.Parse(image)
+			if err != nil {
+				return err
+			}
+			if ref.Pinned() {
+				continue
+			}
+			if pinned[image], err = g.Images.Pin(context.Background(), image); err != nil {
+				return err
+			}
+		}
+	}
+	g.pinned = pinned
+	g.printf("  📌 Pinned %d images by digest\n", len(pinned))
+	return nil
+}
+
+// image returns image pinned by digest when images.pin_digests is set.
+func (g *Generator) image(image string) string {
+	if p, ok := g.pinned[image]; ok {
+		return p
+	}
+	return image
+}
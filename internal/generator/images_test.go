@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+const (
+	webDigest     = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	webNextDigest = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// newPinningTestConfig returns a config pinning the images of web, served
+// by an anonymous fake registry.
+func newPinningTestConfig(t *testing.T, tool string) (*config.Config, *images.Resolver, string) {
+	t.Helper()
+	digests := map[string]string{"1.2.3": webDigest, "1.3.0": webNextDigest}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, ok := digests[strings.TrimPrefix(r.URL.Path, "/v2/org/web/manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", d)
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	cfg := newFluxTestConfig()
+	cfg.GitOpsTool = tool
+	cfg.Flux.HelmReleases = tool == "flux"
+	cfg.Images.PinDigests = true
+	cfg.Apps[0].Image = host + "/org/web:1.2.3"
+	cfg.Apps[0].Overrides = map[string]config.AppOverride{"prod": {Image: host + "/org/web:1.3.0"}}
+
+	resolver := images.NewResolver(nil)
+	resolver.Client = server.Client()
+	return cfg, resolver, host
+}
+
+func TestGenerator_PinDigests(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, resolver, host := newPinningTestConfig(t, "argocd")
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+	gen.Images = resolver
+
+	require.NoError(t, gen.Generate())
+
+	deployment := readGenerated(t, tmpDir, "flux-app/applications/base/web/deployment.yaml")
+	assert.Contains(t, deployment, "image: "+host+"/org/web:1.2.3@"+webDigest)
+	patch := readGenerated(t, tmpDir, "flux-app/applications/overlays/prod/web-patch.yaml")
+	assert.Contains(t, patch, "image: "+host+"/org/web:1.3.0@"+webNextDigest)
+}
+
+func TestGenerator_PinDigestsHelmReleases(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, resolver, host := newPinningTestConfig(t, "flux")
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+	gen.Images = resolver
+
+	require.NoError(t, gen.Generate())
+
+	dev := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/dev/web.yaml")
+	assert.Contains(t, dev, "      repository: "+host+"/org/web\n      tag: 1.2.3\n      digest: "+webDigest+"\n")
+	prod := readGenerated(t, tmpDir, "flux-app/applications/helmreleases/prod/web.yaml")
+	assert.Contains(t, prod, "      tag: 1.3.0\n      digest: "+webNextDigest+"\n")
+	chart := readGenerated(t, tmpDir, "flux-app/charts/app/templates/deployment.yaml")
+	assert.Contains(t, chart, "{{ with .Values.image.digest }}@{{ . }}{{ end }}")
+}
+
+func TestGenerator_PinDigestsUnresolvable(t *testing.T) {
+	cfg, resolver, host := newPinningTestConfig(t, "argocd")
+	cfg.Apps[0].Image = host + "/org/web:missing"
+	gen := New(cfg, output.New(t.TempDir(), false, false), false)
+	gen.Images = resolver
+
+	err := gen.Generate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tag missing not found")
+}
@@ -7,17 +7,22 @@ import (
 )
 
 func (g *Generator) generateInfrastructure() error {
-	fmt.Println("🏗️  Generating infrastructure...")
+	g.printf("🏗️  Generating infrastructure...\n")
 
 	// Generate namespace files and collect filenames for kustomization
 	var namespaceFiles []string
 	for _, env := range g.Config.Environments {
 		nsData := map[string]string{
-			"Name": g.Config.Project.Name + "-" + env.Name,
-			"Env":  env.Name,
+			"Name":      g.Config.GetEnvironmentNamespace(env.Name),
+			"Env":       env.Name,
+			"ManagedBy": g.namespaceManagedBy(),
 		}
 
-		content, err := templates.Render("infrastructure/namespace.yaml.tmpl", nsData)
+		tmpl := "infrastructure/namespace.yaml.tmpl"
+		if g.projectRequests() {
+			tmpl = "infrastructure/projectrequest.yaml.tmpl"
+		}
+		content, err := templates.Render(tmpl, nsData)
 		if err != nil {
 			return err
 		}
@@ -54,6 +59,30 @@ func (g *Generator) generateInfrastructure() error {
 		}
 	}
 
+	if len(g.Config.Tenants) > 0 {
+		if err := g.generateTenants(); err != nil {
+			return err
+		}
+	}
+
+	if g.Config.Addons.StorageClasses {
+		if err := g.generateStorageClasses(); err != nil {
+			return err
+		}
+	}
+
+	if g.Config.TLS.Enabled() {
+		if err := g.generateTLS(); err != nil {
+			return err
+		}
+	}
+
+	if g.Config.Backup.Enabled {
+		if err := g.generateBackupSchedules(); err != nil {
+			return err
+		}
+	}
+
 	resources := []string{"namespaces/"}
 	if g.Config.Infra.RBAC {
 		resources = append(resources, "rbac/")
@@ -64,6 +93,15 @@ func (g *Generator) generateInfrastructure() error {
 	if g.Config.Infra.ResourceQuotas {
 		resources = append(resources, "resource-quotas/")
 	}
+	if len(g.Config.Tenants) > 0 {
+		resources = append(resources, "tenants/")
+	}
+	if g.Config.Addons.StorageClasses {
+		resources = append(resources, "storage-classes/")
+	}
+	if g.Config.TLS.Enabled() {
+		resources = append(resources, "tls/")
+	}
 
 	kustomizeData := map[string]interface{}{
 		"Resources": resources,
@@ -80,8 +118,19 @@ func (g *Generator) generateInfrastructure() error {
 	}
 
 	for _, env := range g.Config.Environments {
+		overlayResources := []string{"../../base"}
+		certificates, err := g.writeWildcardCertificates(env)
+		if err != nil {
+			return err
+		}
+		if certificates != "" {
+			overlayResources = append(overlayResources, certificates)
+		}
+		if backup := g.backupOverlayResource(env); backup != "" {
+			overlayResources = append(overlayResources, backup)
+		}
 		overlayData := map[string]interface{}{
-			"Resources": []string{"../../base"},
+			"Resources": overlayResources,
 		}
 
 		content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", overlayData)
@@ -120,7 +169,7 @@ func (g *Generator) generateRBAC() error {
 	for _, env := range g.Config.Environments {
 		rbacData := map[string]string{
 			"Name":      g.Config.Project.Name,
-			"Namespace": g.Config.Project.Name + "-" + env.Name,
+			"Namespace": g.Config.GetEnvironmentNamespace(env.Name),
 			"Env":       env.Name,
 		}
 
@@ -145,13 +194,7 @@ func (g *Generator) generateRBAC() error {
 func (g *Generator) generateNetworkPolicies() error {
 	var npFiles []string
 	for _, env := range g.Config.Environments {
-		npData := map[string]string{
-			"Name":      g.Config.Project.Name,
-			"Namespace": g.Config.Project.Name + "-" + env.Name,
-			"Env":       env.Name,
-		}
-
-		content, err := templates.Render("infrastructure/networkpolicy.yaml.tmpl", npData)
+		content, err := RenderNetworkPolicies(g.Config, env.Name)
 		if err != nil {
 			return err
 		}
@@ -190,7 +233,7 @@ func (g *Generator) generateResourceQuotas() error {
 
 		rqData := map[string]string{
 			"Name":           g.Config.Project.Name,
-			"Namespace":      g.Config.Project.Name + "-" + env.Name,
+			"Namespace":      g.Config.GetEnvironmentNamespace(env.Name),
 			"Env":            env.Name,
 			"RequestsCPU":    quota["RequestsCPU"],
 			"RequestsMemory": quota["RequestsMemory"],
@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+type secretGenerator struct {
+	Name     string
+	Behavior string
+	Type     string
+	Envs     []string
+}
+
+// writeKustomizeComponents writes the kustomize components of the config
+// into the applications directory of the repository at root.
+func (g *Generator) writeKustomizeComponents(root string) error {
+	for _, component := range g.Config.Kustomize.Components {
+		dir := fmt.Sprintf("%s/applications/components/%s", root, component.Name)
+		data := map[string]interface{}{"Component": true}
+		if err := g.addKustomizeOverlay(data, dir, component.KustomizeOverlay); err != nil {
+			return err
+		}
+		content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", data)
+		if err != nil {
+			return err
+		}
+		if err := g.Writer.WriteFile(dir+"/kustomization.yaml", content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addKustomizeOverlay adds the patches, transformers and generators of o to
+// the kustomization template data of dir, writing the env file stubs of its
+// Secret generators into dir.
+func (g *Generator) addKustomizeOverlay(data map[string]interface{}, dir string, o config.KustomizeOverlay) error {
+	generators, _ := data["ConfigMapGenerator"].([]configMapGenerator)
+	for _, cm := range o.ConfigMapGenerator {
+		generators = append(generators, configMapGenerator{
+			Name:     cm.Name,
+			Behavior: cm.Behavior,
+			Literals: literals(cm.Literals),
+		})
+	}
+	data["ConfigMapGenerator"] = generators
+
+	var secrets []secretGenerator
+	for _, secret := range o.SecretGenerator {
+		env := secret.Name + ".env"
+		if err := g.Writer.WriteFile(dir+"/"+env, secretEnvStub(secret)); err != nil {
+			return err
+		}
+		secrets = append(secrets, secretGenerator{
+			Name:     secret.Name,
+			Behavior: secret.Behavior,
+			Type:     secret.Type,
+			Envs:     []string{env},
+		})
+	}
+	data["SecretGenerator"] = secrets
+
+	data["InlinePatches"] = o.Patches
+	data["Images"] = o.Images
+	data["Replicas"] = o.Replicas
+	return nil
+}
+
+// secretEnvStub returns the env file of a Secret generator with an empty
+// value for each of its keys.
+func secretEnvStub(secret config.KustomizeSecret) []byte {
+	keys := append([]string(nil), secret.Keys...)
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Values of the %s Secret. Fill them in without committing them,\n", secret.Name)
+	b.WriteString("# or encrypt this file with SOPS.\n")
+	for _, key := range keys {
+		b.WriteString(key + "=\n")
+	}
+	return []byte(b.String())
+}
+
+// trackKustomizations records the files written by the generator, whose
+// kustomizations verifyKustomizations builds once generation is done.
+func (g *Generator) trackKustomizations() {
+	g.written = map[string][]byte{}
+	next := g.Writer.BeforeWrite
+	var mu sync.Mutex
+	g.Writer.BeforeWrite = func(file string, content []byte) error {
+		if next != nil {
+			if err := next(file, content); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		g.written[filepath.ToSlash(file)] = content
+		mu.Unlock()
+		return nil
+	}
+}
+
+// verifyKustomizations builds every generated kustomization with the
+// kustomize API, as ArgoCD and Flux render them, and returns the error of the
+// first one that does not build. Dry runs build the generated files in
+// memory.
+func (g *Generator) verifyKustomizations() error {
+	g.printf("🔍 Building kustomizations...\n")
+
+	// Kustomizations are built from the repository as it will be once the
+	// output is committed, or from the generated files on dry-run.
+	fs := filesys.MakeFsInMemory()
+	files := g.written
+	if !g.Writer.DryRun {
+		files = map[string][]byte{}
+		for _, dir := range topDirs(g.written) {
+			tree, err := g.Writer.ReadTree(dir)
+			if err != nil {
+				return err
+			}
+			maps.Copy(files, tree)
+		}
+	}
+	for file, content := range files {
+		if err := fs.WriteFile(path.Join("/", file), content); err != nil {
+			return err
+		}
+	}
+
+	var dirs []string
+	for file, content := range g.written {
+		if path.Base(file) == "kustomization.yaml" && isKustomization(content) {
+			dirs = append(dirs, path.Dir(file))
+		}
+	}
+	sort.Strings(dirs)
+
+	return g.parallel(len(dirs), func(i int, _ *Generator) error {
+		k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+		if _, err := k.Run(fs, path.Join("/", dirs[i])); err != nil {
+			return fmt.Errorf("kustomization %s does not build: %w", dirs[i], err)
+		}
+		return nil
+	})
+}
+
+// topDirs returns the top-level directories of files, sorted.
+func topDirs(files map[string][]byte) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for file := range files {
+		dir, _, _ := strings.Cut(filepath.ToSlash(file), "/")
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// isKustomization reports whether content is a kustomization that builds on
+// its own: components only build as part of the overlays including them.
+func isKustomization(content []byte) bool {
+	var kustomization struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(content)).Decode(&kustomization); err != nil {
+		return false
+	}
+	return kustomization.Kind == "" || kustomization.Kind == "Kustomization"
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+	"github.com/ihsanmokhlisse/gitopsi/internal/render"
+)
+
+func newKustomizeTestConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "shop"
+	cfg.Scope = "application"
+	cfg.Git.URL = "https://github.com/org/shop.git"
+	cfg.Apps = []config.Application{{Name: "api", Image: "registry.example.com/api:1.0.0", Port: 8080, Replicas: 1}}
+	cfg.Kustomize.Components = []config.KustomizeComponent{{
+		Name: "ha",
+		KustomizeOverlay: config.KustomizeOverlay{
+			Patches: []config.KustomizePatch{{
+				Patch:  "- op: add\n  path: /spec/template/spec/priorityClassName\n  value: high",
+				Target: &config.PatchTarget{Kind: "Deployment", LabelSelector: "app=api"},
+			}},
+		},
+	}}
+	cfg.Environments = []config.Environment{
+		{Name: "dev"},
+		{Name: "prod", Kustomize: &config.EnvKustomize{
+			Namespace:  "shop-production",
+			Components: []string{"ha"},
+			KustomizeOverlay: config.KustomizeOverlay{
+				Patches: []config.KustomizePatch{{
+					Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\nspec:\n  minReadySeconds: 10\n",
+				}},
+				Images:             []config.KustomizeImage{{Name: "registry.example.com/api", NewTag: "1.1.0"}},
+				Replicas:           []config.KustomizeReplicas{{Name: "api", Count: 4}},
+				ConfigMapGenerator: []config.KustomizeConfigMap{{Name: "api-flags", Literals: map[string]string{"CHECKOUT": "true"}}},
+				SecretGenerator:    []config.KustomizeSecret{{Name: "api-db", Keys: []string{"PASSWORD", "USER"}}},
+			},
+		}},
+	}
+	return cfg
+}
+
+func TestGenerator_KustomizeOverlay(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newKustomizeTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	prod := readGenerated(t, tmpDir, "shop/applications/overlays/prod/kustomization.yaml")
+	assert.Contains(t, prod, "namespace: shop-production\n")
+	assert.Contains(t, prod, "components:\n  - ../../components/ha\n")
+	assert.Contains(t, prod, "secretGenerator:\n  - name: api-db\n    envs:\n      - api-db.env\n")
+	assert.Contains(t, prod, "images:\n  - name: registry.example.com/api\n    newTag: \"1.1.0\"\n")
+	assert.Contains(t, prod, "replicas:\n  - name: api\n    count: 4\n")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/overlays/prod/api-db.env"), "PASSWORD=\nUSER=\n")
+
+	component := readGenerated(t, tmpDir, "shop/applications/components/ha/kustomization.yaml")
+	assert.Contains(t, component, "kind: Component\n")
+	assert.Contains(t, component, "    target:\n      kind: Deployment\n      labelSelector: \"app=api\"\n")
+	assert.NotContains(t, component, "resources:")
+
+	dev := readGenerated(t, tmpDir, "shop/applications/overlays/dev/kustomization.yaml")
+	assert.NotContains(t, dev, "components:")
+
+	overlays, err := render.Render(&render.Options{Path: filepath.Join(tmpDir, "shop"), Environment: "prod"})
+	require.NoError(t, err)
+	require.Len(t, overlays, 1)
+	manifests := overlays[0].Manifests
+	assert.Contains(t, manifests, "namespace: shop-production")
+	assert.Contains(t, manifests, "replicas: 4")
+	assert.Contains(t, manifests, "image: registry.example.com/api:1.1.0")
+	assert.Contains(t, manifests, "minReadySeconds: 10")
+	assert.Contains(t, manifests, "priorityClassName: high")
+	assert.Contains(t, manifests, "CHECKOUT: \"true\"")
+	assert.Contains(t, manifests, "kind: Secret")
+}
+
+func TestGenerator_VerifyKustomizations(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		cfg := newKustomizeTestConfig()
+		gen := New(cfg, output.New(t.TempDir(), dryRun, false), false)
+		require.NoError(t, gen.Generate(), "dry run %v", dryRun)
+
+		cfg = newKustomizeTestConfig()
+		cfg.Environments[1].Kustomize.Patches = append(cfg.Environments[1].Kustomize.Patches, config.KustomizePatch{
+			Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: missing\nspec:\n  replicas: 2\n",
+		})
+		gen = New(cfg, output.New(t.TempDir(), dryRun, false), false)
+		err := gen.Generate()
+		require.Error(t, err, "dry run %v", dryRun)
+		assert.Contains(t, err.Error(), "kustomization shop/applications/overlays/prod does not build")
+		assert.Contains(t, err.Error(), "missing")
+	}
+}
+
+func TestGenerator_VerifyKustomizationsStaged(t *testing.T) {
+	dir := t.TempDir()
+	writer := output.New(dir, false, false)
+	require.NoError(t, writer.Begin())
+
+	gen := New(newKustomizeTestConfig(), writer, false)
+	require.NoError(t, gen.Generate())
+	assert.NoFileExists(t, filepath.Join(dir, "shop", "applications", "base", "kustomization.yaml"))
+
+	require.NoError(t, writer.Commit())
+	assert.FileExists(t, filepath.Join(dir, "shop", "applications", "base", "kustomization.yaml"))
+}
@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
+)
+
+// trackGeneratedFiles records the hash of every file written under the
+// project for the layout metadata. A BeforeWrite hook already set on the
+// writer runs first.
+func (g *Generator) trackGeneratedFiles() {
+	g.metadata = layout.New()
+	prefix := g.Config.Project.Name + "/"
+	next := g.Writer.BeforeWrite
+	var mu sync.Mutex
+	g.Writer.BeforeWrite = func(file string, content []byte) error {
+		if next != nil {
+			if err := next(file, content); err != nil {
+				return err
+			}
+		}
+		if rel, ok := strings.CutPrefix(file, prefix); ok && rel != layout.MetadataFile {
+			hash := layout.Hash(content)
+			mu.Lock()
+			g.metadata.Files[rel] = hash
+			mu.Unlock()
+		}
+		return nil
+	}
+}
+
+// generateMetadata writes .gitopsi/metadata.yaml with the layout version and
+// the files generated so far.
+func (g *Generator) generateMetadata() error {
+	content, err := g.metadata.Marshal()
+	if err != nil {
+		return err
+	}
+	return g.Writer.WriteFile(g.Config.Project.Name+"/"+layout.MetadataFile, content)
+}
+
+// Metadata returns the layout metadata of the last Generate.
+func (g *Generator) Metadata() *layout.Metadata {
+	return g.metadata
+}
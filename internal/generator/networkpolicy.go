@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// networkPolicyTemplates are the templates rendered for each network policy
+// profile, in order.
+var networkPolicyTemplates = map[string][]string{
+	config.NetworkPolicyBasic:             {"infrastructure/networkpolicy.yaml.tmpl"},
+	config.NetworkPolicyDefaultDeny:       {"infrastructure/networkpolicy-default-deny.yaml.tmpl"},
+	config.NetworkPolicyNamespaceIsolated: {"infrastructure/networkpolicy-default-deny.yaml.tmpl", "infrastructure/networkpolicy-namespace-isolated.yaml.tmpl"},
+	config.NetworkPolicyAppAllowlist:      {"infrastructure/networkpolicy-default-deny.yaml.tmpl", "infrastructure/networkpolicy-app-allowlist.yaml.tmpl"},
+}
+
+type netpolApp struct {
+	Name string
+	Port int
+	From []string
+	To   []netpolApp
+}
+
+// RenderNetworkPolicies renders the NetworkPolicies of an environment
+// namespace for its network policy profile.
+func RenderNetworkPolicies(cfg *config.Config, envName string) ([]byte, error) {
+	profile := cfg.GetNetworkPolicyProfile(envName)
+	names, ok := networkPolicyTemplates[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown network policy profile: %s", profile)
+	}
+
+	data := map[string]any{
+		"Name":      cfg.Project.Name,
+		"Namespace": cfg.GetEnvironmentNamespace(envName),
+		"Env":       envName,
+		"OpenShift": cfg.Platform == "openshift",
+		"Apps":      netpolApps(cfg.Apps),
+	}
+
+	var docs [][]byte
+	for _, name := range names {
+		content, err := templates.Render(name, data)
+		if err != nil {
+			return nil, err
+		}
+		if content = bytes.TrimSpace(content); len(content) > 0 {
+			docs = append(docs, content)
+		}
+	}
+	return append(bytes.Join(docs, []byte("\n---\n")), '\n'), nil
+}
+
+// netpolApps derives the app-to-app allowlist from the declared applications:
+// each application accepts traffic on its port from its allow_from
+// applications, or from every other application when allow_from is empty.
+func netpolApps(apps []config.Application) []netpolApp {
+	result := make([]netpolApp, len(apps))
+	for i, app := range apps {
+		result[i] = netpolApp{Name: app.Name, Port: app.Port}
+		if result[i].Port == 0 {
+			result[i].Port = 80
+		}
+		if len(app.AllowFrom) > 0 {
+			result[i].From = app.AllowFrom
+			continue
+		}
+		for _, other := range apps {
+			if other.Name != app.Name {
+				result[i].From = append(result[i].From, other.Name)
+			}
+		}
+	}
+
+	for i := range result {
+		for _, target := range result {
+			if slices.Contains(target.From, result[i].Name) {
+				result[i].To = append(result[i].To, netpolApp{Name: target.Name, Port: target.Port})
+			}
+		}
+	}
+
+	return slices.DeleteFunc(result, func(a netpolApp) bool {
+		return len(a.From) == 0 && len(a.To) == 0
+	})
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newNetpolTestConfig(profile string) *config.Config {
+	return &config.Config{
+		Project:  config.Project{Name: "shop"},
+		Platform: "kubernetes",
+		Infra:    config.Infrastructure{NetworkPolicies: true, NetworkPolicyProfile: profile},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod", NetworkPolicyProfile: config.NetworkPolicyNamespaceIsolated},
+		},
+		Apps: []config.Application{
+			{Name: "web", Port: 8080},
+			{Name: "api", Port: 9000, AllowFrom: []string{"web"}},
+			{Name: "db", Port: 5432, AllowFrom: []string{"api"}},
+		},
+	}
+}
+
+// policyNames parses a multi-document manifest and returns the policy names.
+func policyNames(t *testing.T, content []byte) []string {
+	t.Helper()
+	var names []string
+	for _, doc := range strings.Split(string(content), "\n---\n") {
+		var policy struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		require.NoError(t, yaml.Unmarshal([]byte(doc), &policy))
+		require.Equal(t, "NetworkPolicy", policy.Kind)
+		names = append(names, policy.Metadata.Name)
+	}
+	return names
+}
+
+func TestRenderNetworkPolicies_Profiles(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"", []string{"shop-network-policy"}},
+		{config.NetworkPolicyDefaultDeny, []string{"default-deny-all", "allow-dns"}},
+		{config.NetworkPolicyAppAllowlist, []string{"default-deny-all", "allow-dns", "allow-web", "allow-api", "allow-db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			content, err := RenderNetworkPolicies(newNetpolTestConfig(tt.profile), "dev")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, policyNames(t, content))
+			assert.Contains(t, string(content), "namespace: shop-dev")
+		})
+	}
+
+	content, err := RenderNetworkPolicies(newNetpolTestConfig(config.NetworkPolicyAppAllowlist), "prod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default-deny-all", "allow-dns", "allow-same-namespace"}, policyNames(t, content))
+}
+
+func TestRenderNetworkPolicies_AppAllowlist(t *testing.T) {
+	content, err := RenderNetworkPolicies(newNetpolTestConfig(config.NetworkPolicyAppAllowlist), "dev")
+	require.NoError(t, err)
+	docs := strings.Split(string(content), "\n---\n")
+	require.Len(t, docs, 5)
+
+	web, api, db := docs[2], docs[3], docs[4]
+	assert.Contains(t, web, "app: api\n        - podSelector:\n            matchLabels:\n              app: db\n      ports:\n        - protocol: TCP\n          port: 8080")
+	assert.Contains(t, web, "egress:\n    - to:\n        - podSelector:\n            matchLabels:\n              app: api\n      ports:\n        - protocol: TCP\n          port: 9000")
+	assert.Contains(t, api, "ingress:\n    - from:\n        - podSelector:\n            matchLabels:\n              app: web\n      ports:")
+	assert.NotContains(t, api, "app: db\n      ports:\n        - protocol: TCP\n          port: 9000")
+	assert.Contains(t, db, "app: api\n      ports:\n        - protocol: TCP\n          port: 5432")
+	assert.Contains(t, db, "egress:\n    - to:\n        - podSelector:\n            matchLabels:\n              app: web\n")
+	assert.NotContains(t, db, "app: api\n      ports:\n        - protocol: TCP\n          port: 9000")
+}
+
+func TestRenderNetworkPolicies_OpenShiftDNS(t *testing.T) {
+	cfg := newNetpolTestConfig(config.NetworkPolicyDefaultDeny)
+	cfg.Platform = "openshift"
+
+	content, err := RenderNetworkPolicies(cfg, "dev")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "kubernetes.io/metadata.name: openshift-dns")
+	assert.Contains(t, string(content), "port: 5353")
+}
+
+func TestGenerator_NetworkPolicyFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newNetpolTestConfig(config.NetworkPolicyDefaultDeny)
+	cfg.Scope = "infrastructure"
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.generateStructure())
+	require.NoError(t, gen.generateInfrastructure())
+
+	dev := readGenerated(t, tmpDir, "shop/infrastructure/base/network-policies/dev.yaml")
+	assert.Contains(t, dev, "name: default-deny-all")
+	prod := readGenerated(t, tmpDir, "shop/infrastructure/base/network-policies/prod.yaml")
+	assert.Contains(t, prod, "name: allow-same-namespace")
+}
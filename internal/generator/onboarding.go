@@ -0,0 +1,280 @@
+package generator
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/graph"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// guide is the data of the onboarding guide of a tenant,
+// docs/onboarding/<tenant>.md, or the quickstart of an application,
+// docs/quickstart/<app>.md.
+type guide struct {
+	*config.Config
+	Name        string
+	Description string
+	// Repository is the repository the team pushes its manifests to
+	Repository string
+	Branch     string
+	// Directory is the directory of the team: the tenant path, or the base
+	// of the application
+	Directory string
+	// AppProject is the ArgoCD AppProject deploying the team's manifests
+	AppProject string
+	// ProjectURL is the ArgoCD UI filtered on the project, empty without
+	// sso.url
+	ProjectURL string
+	// Deployed reports whether gitopsi deploys the team directory: a tenant
+	// ApplicationSet or Flux Kustomization
+	Deployed bool
+	// SourceRepos are the repositories the AppProject deploys from
+	SourceRepos   []string
+	Groups        []guideGroup
+	Environments  []guideEnvironment
+	ToolNamespace string
+	ArgoCD        bool
+	Flux          bool
+	// Siblings are the other applications of the quickstart's repository
+	Siblings []string
+}
+
+// guideGroup is an IdP group role of a tenant.
+type guideGroup struct {
+	Role        string
+	Groups      []string
+	ClusterRole string
+	ProjectRole string
+}
+
+// guideEnvironment is an environment of a guide.
+type guideEnvironment struct {
+	Name       string
+	Namespaces []string
+	// Directory is the directory of the environment: the tenant path or the
+	// application overlay
+	Directory string
+	Context   string
+	// Server is the API server of the first cluster of the environment
+	Server string
+	// Resources are the ArgoCD Applications or Flux Kustomizations deploying
+	// the directory
+	Resources []string
+	// ResourceNamespace is the namespace of the Flux Kustomizations
+	ResourceNamespace string
+	From              string
+	Protected         bool
+	Gates             []string
+}
+
+// generateGuides writes the onboarding guide of every tenant and the
+// quickstart of every application.
+func (g *Generator) generateGuides() error {
+	topology := graph.FromFiles(g.projectFiles())
+	for _, tenant := range g.Config.Tenants {
+		content, err := templates.Render("docs/TENANT.md.tmpl", g.tenantGuide(tenant))
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/docs/onboarding/%s.md", g.Config.Project.Name, tenant.Name)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+	for _, app := range g.Config.Apps {
+		content, err := templates.Render("docs/QUICKSTART.md.tmpl", g.appGuide(app, topology))
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/docs/quickstart/%s.md", g.Config.Project.Name, app.Name)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newGuide returns a guide with the repository, tools and environments of
+// the project.
+func (g *Generator) newGuide(name string) *guide {
+	gd := &guide{
+		Config:        g.Config,
+		Name:          name,
+		Repository:    g.Config.Git.URL,
+		Branch:        g.Config.Git.Branch,
+		ToolNamespace: g.architectureNamespace(),
+		ArgoCD:        g.Config.GitOpsTool != "flux",
+		Flux:          g.usesFlux(),
+	}
+	if gd.Repository == "" {
+		gd.Repository = g.Config.Output.URL
+	}
+	if gd.Branch == "" {
+		gd.Branch = "main"
+	}
+	for i, env := range g.Config.Environments {
+		ge := guideEnvironment{
+			Name:      env.Name,
+			Context:   env.Context,
+			Server:    envServers(env)[0],
+			Protected: env.Protected,
+		}
+		if i > 0 {
+			ge.From = g.Config.Environments[i-1].Name
+		}
+		if env.Protected {
+			ge.Gates = g.promotionGates()
+		}
+		gd.Environments = append(gd.Environments, ge)
+	}
+	return gd
+}
+
+// tenantGuide builds the onboarding guide of a tenant: the tenant path per
+// environment and what deploys it, its namespaces and groups, and its
+// AppProject.
+func (g *Generator) tenantGuide(tenant config.Tenant) *guide {
+	gd := g.newGuide(tenant.Name)
+	gd.Description = tenant.Description
+	gd.Directory = tenant.RepoPath()
+	if gd.Flux && tenant.RepoURL != "" {
+		gd.Repository = tenant.RepoURL
+	}
+	if gd.ArgoCD {
+		gd.AppProject = tenant.Name
+		gd.ProjectURL = g.argoCDProjectURL(tenant.Name)
+		gd.SourceRepos = tenant.SourceRepos
+		if len(gd.SourceRepos) == 0 && gd.Repository != "" {
+			gd.SourceRepos = []string{gd.Repository}
+		}
+	}
+	gd.Deployed = gd.Flux || tenant.ApplicationSet
+
+	for _, r := range []struct {
+		role, clusterRole, projectRole string
+		groups                         []string
+	}{
+		{"Admins", "admin", "admin", tenant.Groups.Admins},
+		{"Developers", "edit", "developer", tenant.Groups.Developers},
+		{"Viewers", "view", "readonly", tenant.Groups.Viewers},
+	} {
+		if len(r.groups) == 0 {
+			continue
+		}
+		group := guideGroup{Role: r.role, Groups: r.groups, ClusterRole: r.clusterRole}
+		if gd.ArgoCD {
+			group.ProjectRole = r.projectRole
+		}
+		gd.Groups = append(gd.Groups, group)
+	}
+
+	for i := range gd.Environments {
+		env := &gd.Environments[i]
+		env.Namespaces = tenant.EnvNamespaces(env.Name)
+		env.Directory = tenant.RepoPath() + "/" + env.Name
+		switch {
+		case gd.Flux:
+			env.Resources = []string{tenant.Name}
+			env.ResourceNamespace = env.Namespaces[0]
+		case tenant.ApplicationSet:
+			env.Resources = []string{tenant.Name + "-" + env.Name}
+		}
+	}
+	return gd
+}
+
+// appGuide builds the quickstart of an application: its base and overlays,
+// in the project repository or its application repository, and the
+// Applications or Kustomizations deploying them.
+func (g *Generator) appGuide(app config.Application, topology *graph.Graph) *guide {
+	gd := g.newGuide(app.Name)
+	gd.Directory = "applications/base/" + app.Name
+	if gd.ArgoCD {
+		gd.AppProject = "applications"
+		gd.ProjectURL = g.argoCDProjectURL("applications")
+	}
+
+	var repo *config.AppRepository
+	for _, r := range g.Config.AppRepositories() {
+		if slices.Contains(r.Apps, app.Name) {
+			repo = &r
+			break
+		}
+	}
+	siblings := make([]string, 0, len(g.Config.Apps))
+	if repo != nil {
+		gd.Repository = repo.URL
+		siblings = repo.Apps
+	} else {
+		for _, a := range g.hubApps() {
+			siblings = append(siblings, a.Name)
+		}
+	}
+	for _, name := range siblings {
+		if name != app.Name {
+			gd.Siblings = append(gd.Siblings, name)
+		}
+	}
+
+	for i := range gd.Environments {
+		env := &gd.Environments[i]
+		env.Namespaces = []string{g.Config.GetEnvironmentNamespace(env.Name)}
+		env.Directory = "applications/overlays/" + env.Name
+		if repo != nil {
+			env.Resources = repositoryResources(*repo, g.Config.Environments[i])
+		} else {
+			env.Resources = deployingPath(topology, env.Directory)
+		}
+		if gd.Flux {
+			env.ResourceNamespace = g.getFluxNamespace()
+		}
+	}
+	return gd
+}
+
+// argoCDProjectURL returns the ArgoCD UI listing the applications of a
+// project, on sso.url.
+func (g *Generator) argoCDProjectURL(project string) string {
+	if g.Config.SSO.URL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(g.Config.SSO.URL, "/") + "/applications?proj=" + url.QueryEscape(project)
+}
+
+// repositoryResources returns the Applications the ApplicationSet of an
+// application repository generates in an environment.
+func repositoryResources(repo config.AppRepository, env config.Environment) []string {
+	if len(env.Clusters) < 2 {
+		return []string{repo.Name + "-" + env.Name}
+	}
+	names := make([]string, 0, len(env.Clusters))
+	for _, cluster := range env.Clusters {
+		names = append(names, repo.Name+"-"+env.Name+"-"+cluster.Name)
+	}
+	return names
+}
+
+// deployingPath returns the Applications and Kustomizations deploying a
+// path.
+func deployingPath(topology *graph.Graph, path string) []string {
+	var names []string
+	for _, e := range topology.Edges {
+		to, _ := topology.Node(e.To)
+		from, _ := topology.Node(e.From)
+		if to.Kind != graph.KindPath || to.Label != path {
+			continue
+		}
+		if from.Kind != graph.KindApplication && from.Kind != graph.KindKustomization {
+			continue
+		}
+		_, name, _ := strings.Cut(from.ID, "/")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func TestGenerator_TenantOnboardingDocs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newTenantTestConfig()
+	cfg.Tenants[0].Description = "Payments team"
+	cfg.Environments[1].Protected = true
+	cfg.SSO = config.SSOConfig{URL: "https://argocd.example.com/"}
+	cfg.Docs = config.Documentation{Onboarding: true}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	onboarding := readGenerated(t, tmpDir, "plat/docs/ONBOARDING.md")
+	assert.Contains(t, onboarding, "- [payments](onboarding/payments.md): Payments team")
+	assert.Contains(t, onboarding, "- [search](onboarding/search.md)")
+
+	payments := readGenerated(t, tmpDir, "plat/docs/onboarding/payments.md")
+	for _, want := range []string{
+		"# payments - Onboarding Guide\n\nPayments team",
+		"The manifests of the team live in `tenants/payments/` of\nhttps://github.com/org/plat.git, branch `main`.",
+		"| prod (protected) | `payments-prod` | `tenants/payments/prod/` | `payments` |",
+		"| Admins | `payments-leads` | `admin` | `payments/admin` |",
+		"| Viewers | `auditors` | `view` | `payments/readonly` |",
+		"[payments in ArgoCD](https://argocd.example.com/applications?proj=payments)",
+		"deploys from `https://github.com/org/payments-*`",
+		"mkdir -p tenants/payments/dev/<service>",
+		"They are\n   deployed to `payments-dev`.",
+		"Once merged, `payments-dev`\n   deploys it.",
+		"cp -r tenants/payments/dev/<service>/. tenants/payments/prod/<service>/",
+		"prod is protected: `gitopsi promote` into it requires approval (`--approve`)",
+		"argocd app get payments-prod\nkubectl get pods --namespace payments-prod",
+	} {
+		assert.Contains(t, payments, want)
+	}
+	assert.NotContains(t, payments, "Developers")
+	assert.NotContains(t, payments, "\n\n\n")
+
+	search := readGenerated(t, tmpDir, "plat/docs/onboarding/search.md")
+	for _, want := range []string{
+		"| dev | `search-api-dev`, `search-workers-dev` | `tenants/search/dev/` | `search` |",
+		"[search in ArgoCD](https://argocd.example.com/applications?proj=search)",
+		"argocd app create <service>-dev \\\n  --project search \\\n  --repo https://github.com/org/plat.git",
+		"--dest-server https://kubernetes.default.svc \\\n  --dest-namespace search-api-dev",
+		"argocd app set <service>-prod --revision <revision>",
+	} {
+		assert.Contains(t, search, want)
+	}
+	assert.NotContains(t, search, "## Access")
+	assert.NotContains(t, search, "\n\n\n")
+}
+
+func TestGenerator_TenantOnboardingDocs_Flux(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.Environments[0].Context = "kind-dev"
+	cfg.Tenants = []config.Tenant{{
+		Name:       "search",
+		Namespaces: []string{"api", "workers"},
+		RepoURL:    "https://github.com/org/search-config.git",
+		Groups:     config.TenantGroups{Developers: []string{"search-devs"}},
+	}}
+	cfg.Docs = config.Documentation{Onboarding: true}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	doc := readGenerated(t, tmpDir, "flux-app/docs/onboarding/search.md")
+	for _, want := range []string{
+		"`tenants/search/` of\nhttps://github.com/org/search-config.git",
+		"| dev | `search-api-dev`, `search-workers-dev` | `tenants/search/dev/` |\n",
+		"| Developers | `search-devs` | `edit` |\n",
+		"Set\n   `metadata.namespace` to one of `search-api-dev`, `search-workers-dev`.",
+		"kubectl config use-context kind-dev\nflux get kustomizations search --namespace search-api-dev",
+	} {
+		assert.Contains(t, doc, want)
+	}
+	assert.NotContains(t, doc, "ArgoCD")
+}
+
+func TestGenerator_AppQuickstartDocs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Project:    config.Project{Name: "shop"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/org/shop.git", Branch: "main"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod", Protected: true},
+		},
+		Apps: []config.Application{
+			{Name: "api", Image: "nginx:1.27", Port: 80},
+			{Name: "web", Image: "nginx:1.27", Port: 80},
+			{Name: "billing", Image: "nginx:1.27", Port: 80},
+		},
+		Layout: config.LayoutConfig{
+			Strategy: config.LayoutHubAndSpoke,
+			RepoURL:  "https://github.com/org/{repo}.git",
+			Teams:    []config.LayoutTeam{{Name: "payments", Apps: []string{"billing"}}},
+		},
+		Promotion: config.PromotionConfig{Gates: config.PromotionGates{Validation: true}},
+		SSO:       config.SSOConfig{URL: "https://argocd.example.com"},
+		Docs:      config.Documentation{Onboarding: true},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	onboarding := readGenerated(t, tmpDir, "shop/docs/ONBOARDING.md")
+	assert.Contains(t, onboarding, "- [api](quickstart/api.md)\n- [web](quickstart/web.md)\n- [billing](quickstart/billing.md)")
+	assert.NotContains(t, onboarding, "Onboarding guides of the tenants")
+
+	api := readGenerated(t, tmpDir, "shop/docs/quickstart/api.md")
+	for _, want := range []string{
+		"| Repository | https://github.com/org/shop.git (`main`) |",
+		"| Base manifests | `applications/base/api/` |",
+		"| ArgoCD project | [applications](https://argocd.example.com/applications?proj=applications) |",
+		"| prod (protected) | `shop-prod` | `applications/overlays/prod/` | `shop-apps-prod` |",
+		"next to `web/`.",
+		"gitopsi promote api --from dev --to prod --approve --pr",
+		"prod is protected: promotions must pass `gitopsi validate`.",
+		"kubectl get pods --namespace shop-dev -l app=api",
+	} {
+		assert.Contains(t, api, want)
+	}
+	assert.NotContains(t, api, "gitopsi ui")
+	assert.NotContains(t, api, "\n\n\n")
+
+	billing := readGenerated(t, tmpDir, "shop/docs/quickstart/billing.md")
+	assert.Contains(t, billing, "| Repository | https://github.com/org/shop-payments.git (`main`) |")
+	assert.Contains(t, billing, "| dev | `shop-dev` | `applications/overlays/dev/` | `shop-payments-dev` |")
+	assert.Contains(t, billing, "next to `billing/`.")
+}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// openShiftSystemNamespaces are the namespaces the applications AppProject
+// cannot deploy to on OpenShift: the cluster operators and openshift-gitops
+// itself live there.
+var openShiftSystemNamespaces = []string{"openshift-*", "kube-*"}
+
+// projectRequests reports whether namespaces are created with OpenShift
+// ProjectRequests.
+func (g *Generator) projectRequests() bool {
+	return g.Config.IsOpenShift() && g.Config.OpenShift.ProjectRequests
+}
+
+// namespaceManagedBy returns the value of the argocd.argoproj.io/managed-by
+// label of the generated namespaces, which lets the namespace-scoped
+// openshift-gitops instance manage them, or an empty string.
+func (g *Generator) namespaceManagedBy() string {
+	if !g.Config.IsOpenShift() || g.Config.GitOpsTool == "flux" {
+		return ""
+	}
+	return g.getArgoCDNamespace()
+}
+
+// appIngress is the template data of an application Route or Ingress.
+type appIngress struct {
+	config.AppIngress
+	Name     string
+	Port     int
+	SyncWave string
+	// Issuer is the cert-manager ClusterIssuer of the certificate
+	Issuer string
+	// ExternalDNS annotates the host for external-dns, with DNSTTL when set
+	ExternalDNS bool
+	DNSTTL      int
+}
+
+// renderAppIngress renders the Route of an application on OpenShift and its
+// Ingress elsewhere, with host replacing the configured host when set.
+func (g *Generator) renderAppIngress(app config.Application, host, syncWave string) (file string, content []byte, err error) {
+	data := appIngress{AppIngress: *app.Ingress, Name: app.Name, Port: app.Port, SyncWave: syncWave}
+	if host != "" {
+		data.Host = host
+	}
+	if data.Path == "" {
+		data.Path = "/"
+	}
+	if data.TLS && g.Config.TLS.Enabled() {
+		data.Issuer = g.Config.TLS.Issuer()
+	}
+	if g.Config.DNS.ExternalDNS() {
+		data.ExternalDNS, data.DNSTTL = true, g.Config.DNS.TTL
+	}
+	kind := "ingress"
+	if g.Config.IsOpenShift() {
+		kind = "route"
+	}
+	content, err = templates.Render("kubernetes/"+kind+".yaml.tmpl", data)
+	return kind, content, err
+}
+
+// renderSCCRoleBinding renders the RoleBinding granting the SCC of an
+// application to its service account in namespace.
+func renderSCCRoleBinding(app config.Application, namespace string) ([]byte, error) {
+	return templates.Render("kubernetes/scc-rolebinding.yaml.tmpl", map[string]string{
+		"Name":      app.Name,
+		"SCC":       app.SCC,
+		"Namespace": namespace,
+	})
+}
@@ -0,0 +1,162 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newOpenShiftTestConfig() *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "shop"},
+		Platform:   "openshift",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/org/shop.git"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod"},
+		},
+		Apps: []config.Application{
+			{
+				Name:      "web",
+				Image:     "nginx:1.25",
+				Port:      8080,
+				Ingress:   &config.AppIngress{Host: "web.apps.dev.example.com", TLS: true},
+				SCC:       "nonroot-v2",
+				Overrides: map[string]config.AppOverride{"prod": {Host: "shop.example.com"}},
+			},
+			{Name: "worker", Image: "worker:1.0", Port: 9000},
+		},
+	}
+}
+
+func TestGenerator_OpenShiftRoutes(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newOpenShiftTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	route := readGenerated(t, tmpDir, "shop/applications/base/web/route.yaml")
+	assert.Contains(t, route, "kind: Route")
+	assert.Contains(t, route, "host: web.apps.dev.example.com")
+	assert.Contains(t, route, "to:\n    kind: Service\n    name: web")
+	assert.Contains(t, route, "targetPort: 8080")
+	assert.Contains(t, route, "termination: edge")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/base/web/kustomization.yaml"), "- route.yaml")
+	assert.NoFileExists(t, tmpDir+"/shop/applications/base/worker/route.yaml")
+
+	patch := readGenerated(t, tmpDir, "shop/applications/overlays/prod/web-route-patch.yaml")
+	assert.Contains(t, patch, "host: shop.example.com")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/overlays/prod/kustomization.yaml"), "- path: web-route-patch.yaml")
+	assert.NoFileExists(t, tmpDir+"/shop/applications/overlays/dev/web-route-patch.yaml")
+}
+
+func TestGenerator_Ingress(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newOpenShiftTestConfig()
+	cfg.Platform = "kubernetes"
+	cfg.Apps[0].SCC = ""
+	cfg.Apps[0].Ingress.ClassName = "nginx"
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	ingress := readGenerated(t, tmpDir, "shop/applications/base/web/ingress.yaml")
+	assert.Contains(t, ingress, "kind: Ingress")
+	assert.Contains(t, ingress, "ingressClassName: nginx")
+	assert.Contains(t, ingress, "secretName: web-tls")
+	assert.Contains(t, ingress, "- host: web.apps.dev.example.com")
+	assert.Contains(t, ingress, "number: 8080")
+	assert.NoFileExists(t, tmpDir+"/shop/applications/base/web/route.yaml")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/overlays/prod/web-ingress-patch.yaml"), "- host: shop.example.com")
+}
+
+func TestGenerator_ExternalDNSAnnotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newOpenShiftTestConfig()
+	cfg.DNS = config.DNSConfig{Provider: "route53", Zone: "example.com", TTL: 60}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	route := readGenerated(t, tmpDir, "shop/applications/base/web/route.yaml")
+	assert.Contains(t, route, "external-dns.alpha.kubernetes.io/hostname: web.apps.dev.example.com")
+	assert.Contains(t, route, `external-dns.alpha.kubernetes.io/ttl: "60"`)
+	patch := readGenerated(t, tmpDir, "shop/applications/overlays/prod/web-route-patch.yaml")
+	assert.Contains(t, patch, "external-dns.alpha.kubernetes.io/hostname: shop.example.com", "the hostname follows the host override")
+
+	tmpDir = t.TempDir()
+	cfg.DNS.Mode = config.DNSModeDirect
+	require.NoError(t, New(cfg, output.New(tmpDir, false, false), false).Generate())
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/applications/base/web/route.yaml"), "external-dns")
+}
+
+func TestGenerator_OpenShiftSCC(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newOpenShiftTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/base/web/serviceaccount.yaml"), "kind: ServiceAccount\nmetadata:\n  name: web")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/base/web/deployment.yaml"), "serviceAccountName: web")
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/applications/base/worker/deployment.yaml"), "serviceAccountName")
+
+	binding := readGenerated(t, tmpDir, "shop/applications/overlays/prod/web-scc.yaml")
+	assert.Contains(t, binding, "name: system:openshift:scc:nonroot-v2")
+	assert.Contains(t, binding, "name: web\n    namespace: shop-prod")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/overlays/prod/kustomization.yaml"), "- web-scc.yaml")
+}
+
+func TestGenerator_OpenShiftNamespaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newOpenShiftTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	namespace := readGenerated(t, tmpDir, "shop/infrastructure/base/namespaces/dev.yaml")
+	assert.Contains(t, namespace, "kind: Namespace")
+	assert.Contains(t, namespace, "argocd.argoproj.io/managed-by: openshift-gitops")
+
+	project := readGenerated(t, tmpDir, "shop/argocd/projects/applications.yaml")
+	assert.Contains(t, project, "namespace: openshift-gitops")
+	assert.Contains(t, project, "- namespace: '!openshift-*'\n      server: '*'")
+	assert.Contains(t, project, "- namespace: '!kube-*'")
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/argocd/projects/infrastructure.yaml"), "'!openshift-*'")
+}
+
+func TestGenerator_OpenShiftProjectRequests(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newOpenShiftTestConfig()
+	cfg.OpenShift.ProjectRequests = true
+	cfg.Tenants = []config.Tenant{{Name: "payments"}}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	namespace := readGenerated(t, tmpDir, "shop/infrastructure/base/namespaces/prod.yaml")
+	assert.Contains(t, namespace, "apiVersion: project.openshift.io/v1\nkind: ProjectRequest\nmetadata:\n  name: shop-prod")
+	assert.Contains(t, namespace, "displayName: shop-prod")
+	assert.NotContains(t, namespace, "kind: Namespace")
+
+	tenant := readGenerated(t, tmpDir, "shop/infrastructure/base/tenants/payments/dev.yaml")
+	assert.Contains(t, tenant, "kind: ProjectRequest\nmetadata:\n  name: payments-dev")
+	assert.NotContains(t, tenant, "kind: Namespace")
+}
+
+func TestGenerator_KubernetesNamespaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newOpenShiftTestConfig()
+	cfg.Platform = "kubernetes"
+	cfg.Apps[0].SCC = ""
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/infrastructure/base/namespaces/dev.yaml"), "managed-by: openshift-gitops")
+	assert.NotContains(t, readGenerated(t, tmpDir, "shop/argocd/projects/applications.yaml"), "'!openshift-*'")
+}
@@ -18,7 +18,7 @@ func (g *Generator) generateOperators() error {
 		return nil
 	}
 
-	fmt.Println("🔧 Generating operator manifests...")
+	g.printf("🔧 Generating operator manifests...\n")
 
 	operatorsDir := filepath.Join(g.Config.Project.Name, "infrastructure", "base", "operators")
 	if err := g.Writer.CreateDir(operatorsDir); err != nil {
@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+const (
+	defaultPreviewPath         = "deploy"
+	defaultPreviewRequeue      = 5 * time.Minute
+	defaultPreviewHostParam    = "ingress.host"
+	defaultPreviewSchedule     = "0 * * * *"
+	defaultPreviewCleanupImage = "alpine/k8s:1.31.4"
+)
+
+// previewPlaceholders maps the placeholders of preview.namespace and
+// preview.host to the parameters of the ArgoCD pull request generator.
+var previewPlaceholders = strings.NewReplacer(
+	"{number}", "{{number}}",
+	"{branch}", "{{branch}}",
+	"{branch_slug}", "{{branch_slug}}",
+	"{head_short_sha}", "{{head_short_sha}}",
+)
+
+// generatePreview writes the ApplicationSet deploying every open pull request
+// of the preview repository to its own namespace and, with a TTL, the
+// CronJob cleaning up inactive previews.
+func (g *Generator) generatePreview(argoCDNamespace string) error {
+	preview := g.Config.Preview
+	if !preview.Enabled() {
+		return nil
+	}
+	project := g.Config.Project.Name
+
+	requeue := defaultPreviewRequeue
+	if preview.RequeueAfter != "" {
+		d, err := time.ParseDuration(preview.RequeueAfter)
+		if err != nil {
+			return fmt.Errorf("invalid preview.requeue_after: %w", err)
+		}
+		requeue = d
+	}
+	namespace := preview.Namespace
+	if namespace == "" {
+		namespace = project + "-pr-{number}"
+	}
+	appProject := "default"
+	if g.Config.Scope == "application" || g.Config.Scope == "both" {
+		appProject = "applications"
+	}
+	owner, repo, _ := strings.Cut(preview.Repository, "/")
+
+	data := map[string]any{
+		"Name":                project,
+		"ArgoCDNamespace":     argoCDNamespace,
+		"Provider":            preview.Provider,
+		"Repository":          preview.Repository,
+		"Owner":               owner,
+		"Repo":                repo,
+		"API":                 preview.API,
+		"TokenSecret":         preview.SecretName(project),
+		"Labels":              preview.Labels,
+		"RequeueAfterSeconds": int(requeue.Seconds()),
+		"Project":             appProject,
+		"RepoURL":             previewRepoURL(preview),
+		"Path":                valueOr(preview.Path, defaultPreviewPath),
+		"Helm":                preview.Helm,
+		"Host":                previewPlaceholders.Replace(preview.Host),
+		"HostParameter":       valueOr(preview.HostParameter, defaultPreviewHostParam),
+		"Namespace":           previewPlaceholders.Replace(namespace),
+	}
+	content, err := templates.Render("argocd/applicationset-preview.yaml.tmpl", data)
+	if err != nil {
+		return err
+	}
+	if err := g.Writer.WriteFile(project+"/argocd/applicationsets/preview.yaml", content); err != nil {
+		return err
+	}
+
+	if preview.TTL == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(preview.TTL)
+	if err != nil {
+		return fmt.Errorf("invalid preview.ttl: %w", err)
+	}
+	content, err = templates.Render("argocd/preview-cleanup.yaml.tmpl", map[string]any{
+		"Name":            project,
+		"ArgoCDNamespace": argoCDNamespace,
+		"TokenSecret":     preview.SecretName(project),
+		"Schedule":        valueOr(preview.Cleanup.Schedule, defaultPreviewSchedule),
+		"Image":           valueOr(preview.Cleanup.Image, defaultPreviewCleanupImage),
+		"Script":          previewCleanupScript(preview, project, argoCDNamespace, ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return g.Writer.WriteFile(project+"/argocd/applicationsets/preview-cleanup.yaml", content)
+}
+
+// previewRepoURL returns the clone URL of the preview repository.
+func previewRepoURL(preview config.PreviewConfig) string {
+	if preview.RepoURL != "" {
+		return preview.RepoURL
+	}
+	if preview.Provider == config.PreviewProviderGitLab {
+		base := "https://gitlab.com"
+		if preview.API != "" {
+			base = strings.TrimSuffix(preview.API, "/")
+		}
+		return fmt.Sprintf("%s/%s.git", base, preview.Repository)
+	}
+	return fmt.Sprintf("https://github.com/%s.git", preview.Repository)
+}
+
+// previewCleanupScript returns the shell script of the cleanup CronJob. It
+// removes the preview labels from pull requests without activity for ttl, so
+// that the pull request generator deletes their Applications, and deletes
+// the namespaces of previews whose Application is gone.
+func previewCleanupScript(preview config.PreviewConfig, project, argoCDNamespace string, ttl time.Duration) string {
+	var b strings.Builder
+	b.WriteString("set -eu\n")
+	fmt.Fprintf(&b, "cutoff=$(( $(date +%%s) - %d ))\n", int(ttl.Seconds()))
+
+	if preview.Provider == config.PreviewProviderGitLab {
+		api := "https://gitlab.com"
+		if preview.API != "" {
+			api = strings.TrimSuffix(preview.API, "/")
+		}
+		mrs := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", api, url.PathEscape(preview.Repository))
+		fmt.Fprintf(&b, "curl -fsS -H \"PRIVATE-TOKEN: $TOKEN\" '%s?state=opened&per_page=100' |\n", mrs)
+		b.WriteString("  jq -r --argjson cutoff \"$cutoff\" '.[] | select((.updated_at | sub(\"\\\\.[0-9]+\"; \"\") | fromdateiso8601) < $cutoff) | .iid' |\n")
+		b.WriteString("  while read -r iid; do\n")
+		fmt.Fprintf(&b, "    curl -fsS -X PUT -H \"PRIVATE-TOKEN: $TOKEN\" --data-urlencode %s \"%s/$iid\" >/dev/null || true\n",
+			shellQuote("remove_labels="+strings.Join(preview.Labels, ",")), mrs)
+		b.WriteString("  done\n")
+	} else {
+		api := "https://api.github.com"
+		if preview.API != "" {
+			api = strings.TrimSuffix(preview.API, "/")
+		}
+		repo := fmt.Sprintf("%s/repos/%s", api, preview.Repository)
+		fmt.Fprintf(&b, "curl -fsS -H \"Authorization: Bearer $TOKEN\" '%s/pulls?state=open&per_page=100' |\n", repo)
+		b.WriteString("  jq -r --argjson cutoff \"$cutoff\" '.[] | select((.updated_at | fromdateiso8601) < $cutoff) | .number' |\n")
+		b.WriteString("  while read -r number; do\n")
+		for _, label := range preview.Labels {
+			fmt.Fprintf(&b, "    curl -fsS -X DELETE -H \"Authorization: Bearer $TOKEN\" \"%s/issues/$number/labels/%s\" >/dev/null || true\n",
+				repo, url.PathEscape(label))
+		}
+		b.WriteString("  done\n")
+	}
+
+	fmt.Fprintf(&b, "for ns in $(kubectl get namespaces -l gitopsi.io/preview=%s -o jsonpath='{.items[*].metadata.name}'); do\n", project)
+	b.WriteString("  app=$(kubectl get namespace \"$ns\" -o jsonpath='{.metadata.labels.gitopsi\\.io/preview-app}')\n")
+	fmt.Fprintf(&b, "  kubectl get applications.argoproj.io \"$app\" -n %s >/dev/null 2>&1 || kubectl delete namespace \"$ns\" --wait=false\n", argoCDNamespace)
+	b.WriteString("done\n")
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newPreviewTestConfig(preview config.PreviewConfig) *config.Config {
+	cfg := newTenantTestConfig()
+	cfg.Tenants = nil
+	cfg.Preview = preview
+	return cfg
+}
+
+func TestGenerator_PreviewGitHub(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newPreviewTestConfig(config.PreviewConfig{
+		Provider:   "github",
+		Repository: "acme/shop-api",
+		Labels:     []string{"preview", "needs review"},
+		Host:       "pr-{number}.preview.example.com",
+		TTL:        "72h",
+	})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	appSet := readGenerated(t, tmpDir, "plat/argocd/applicationsets/preview.yaml")
+	assert.Contains(t, appSet, "name: plat-preview")
+	assert.Contains(t, appSet, "requeueAfterSeconds: 300")
+	assert.Contains(t, appSet, "github:\n          owner: acme\n          repo: shop-api\n")
+	assert.Contains(t, appSet, "secretName: plat-preview-token")
+	assert.Contains(t, appSet, "- \"preview\"\n            - \"needs review\"")
+	assert.Contains(t, appSet, "name: 'plat-pr-{{number}}'")
+	assert.Contains(t, appSet, "repoURL: https://github.com/acme/shop-api.git")
+	assert.Contains(t, appSet, "targetRevision: '{{head_sha}}'")
+	assert.Contains(t, appSet, "path: deploy")
+	assert.Contains(t, appSet, "path: /spec/rules/0/host\n                  value: pr-{{number}}.preview.example.com")
+	assert.Contains(t, appSet, "namespace: 'plat-pr-{{number}}'")
+	assert.Contains(t, appSet, "gitopsi.io/preview-app: 'plat-pr-{{number}}'")
+
+	cleanup := readGenerated(t, tmpDir, "plat/argocd/applicationsets/preview-cleanup.yaml")
+	assert.Contains(t, cleanup, "kind: CronJob")
+	assert.Contains(t, cleanup, `schedule: "0 * * * *"`)
+	assert.Contains(t, cleanup, "cutoff=$(( $(date +%s) - 259200 ))")
+	assert.Contains(t, cleanup, "https://api.github.com/repos/acme/shop-api/issues/$number/labels/preview\"")
+	assert.Contains(t, cleanup, "https://api.github.com/repos/acme/shop-api/issues/$number/labels/needs%20review\"")
+	assert.Contains(t, cleanup, "kubectl get namespaces -l gitopsi.io/preview=plat")
+}
+
+func TestGenerator_PreviewGitLabHelm(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newPreviewTestConfig(config.PreviewConfig{
+		Provider:     "gitlab",
+		Repository:   "acme/shop/api",
+		API:          "https://gitlab.example.com",
+		Path:         "charts/api",
+		Helm:         true,
+		Host:         "{branch_slug}.preview.example.com",
+		Namespace:    "preview-{number}",
+		RequeueAfter: "1m",
+	})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	appSet := readGenerated(t, tmpDir, "plat/argocd/applicationsets/preview.yaml")
+	assert.Contains(t, appSet, "requeueAfterSeconds: 60")
+	assert.Contains(t, appSet, "gitlab:\n          project: \"acme/shop/api\"\n          api: https://gitlab.example.com\n          pullRequestState: opened")
+	assert.Contains(t, appSet, "repoURL: https://gitlab.example.com/acme/shop/api.git")
+	assert.Contains(t, appSet, "helm:\n          parameters:\n            - name: ingress.host\n              value: '{{branch_slug}}.preview.example.com'")
+	assert.Contains(t, appSet, "namespace: 'preview-{{number}}'")
+	assert.NotContains(t, appSet, "kustomize:")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat", "argocd", "applicationsets", "preview-cleanup.yaml"))
+}
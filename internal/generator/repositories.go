@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+type repositoryDestination struct {
+	Name      string
+	Env       string
+	Namespace string
+	Server    string
+}
+
+// hubApps returns the applications generated into the project repository:
+// all of them with the monorepo layout, and those no team repository holds
+// with hub-and-spoke.
+func (g *Generator) hubApps() []config.Application {
+	var owned []string
+	for _, repo := range g.Config.AppRepositories() {
+		owned = append(owned, repo.Apps...)
+	}
+	var apps []config.Application
+	for _, app := range g.Config.Apps {
+		if !slices.Contains(owned, app.Name) {
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+// generatesHubApplications reports whether the project repository holds
+// applications, and so the applications directory and the ArgoCD resources
+// deploying it.
+func (g *Generator) generatesHubApplications() bool {
+	if g.Config.Scope != "application" && g.Config.Scope != "both" {
+		return false
+	}
+	return !g.Config.Layout.Split() || len(g.hubApps()) > 0
+}
+
+// generateAppRepositories writes the application repositories of the layout
+// next to the project repository.
+func (g *Generator) generateAppRepositories(waves map[string]int) error {
+	for _, repo := range g.Config.AppRepositories() {
+		g.printf("📦 Generating application repository %s...\n", repo.Name)
+
+		var apps []config.Application
+		for _, app := range g.Config.Apps {
+			if slices.Contains(repo.Apps, app.Name) {
+				apps = append(apps, app)
+			}
+		}
+		if err := g.writeApplications(repo.Name, apps, waves); err != nil {
+			return err
+		}
+
+		owner := ""
+		if repo.Team != "" {
+			owner = " of team " + repo.Team
+		}
+		var overlays []string
+		for _, env := range g.Config.Environments {
+			overlays = append(overlays, "- `applications/overlays/"+env.Name+"`")
+		}
+		readme := fmt.Sprintf(`# %s
+
+Applications %s%s. The %s ApplicationSet of the %s repository syncs
+the overlay of each environment to its cluster:
+
+%s
+`, repo.Name, strings.Join(repo.Apps, ", "), owner, repo.Name, g.Config.Project.Name, strings.Join(overlays, "\n"))
+		if err := g.Writer.WriteFile(repo.Name+"/README.md", []byte(readme)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateRepositoryApplicationSets writes an ApplicationSet per application
+// repository, deploying its overlays to every environment, and the ArgoCD
+// repository secrets of the application repositories.
+func (g *Generator) generateRepositoryApplicationSets(argoCDNamespace string) error {
+	repos := g.Config.AppRepositories()
+	if len(repos) == 0 {
+		return nil
+	}
+
+	branch := g.Config.Output.Branch
+	if branch == "" {
+		branch = "main"
+	}
+
+	for _, repo := range repos {
+		var destinations []repositoryDestination
+		for _, env := range g.Config.Environments {
+			namespace := g.Config.GetEnvironmentNamespace(env.Name)
+			if len(env.Clusters) < 2 {
+				destinations = append(destinations, repositoryDestination{
+					Name:      repo.Name + "-" + env.Name,
+					Env:       env.Name,
+					Namespace: namespace,
+					Server:    envServers(env)[0],
+				})
+				continue
+			}
+			for _, cluster := range env.Clusters {
+				destinations = append(destinations, repositoryDestination{
+					Name:      repo.Name + "-" + env.Name + "-" + cluster.Name,
+					Env:       env.Name,
+					Namespace: namespace,
+					Server:    cluster.URL,
+				})
+			}
+		}
+
+		content, err := templates.Render("argocd/applicationset-repository.yaml.tmpl", map[string]any{
+			"Name":            repo.Name,
+			"Team":            repo.Team,
+			"ArgoCDNamespace": argoCDNamespace,
+			"Project":         "applications",
+			"RepoURL":         repo.URL,
+			"Branch":          branch,
+			"Destinations":    destinations,
+		})
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/%s/applicationsets/%s.yaml", g.Config.Project.Name, g.Config.GitOpsTool, repo.Name)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	content, err := templates.Render("argocd/repository-secret.yaml.tmpl", map[string]any{
+		"ArgoCDNamespace": argoCDNamespace,
+		"Repositories":    repos,
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/bootstrap/%s/repositories.yaml", g.Config.Project.Name, g.Config.GitOpsTool)
+	return g.Writer.WriteFile(path, content)
+}
+
+// bootstrapRepositoriesStep returns the bootstrap script step applying the
+// repository secrets of the application repositories.
+func (g *Generator) bootstrapRepositoriesStep() string {
+	if !g.Config.Layout.Split() {
+		return ""
+	}
+	return fmt.Sprintf(`
+# Register the application repositories; private repositories need a
+# repo-creds credential template covering their URL
+kubectl apply -f bootstrap/%s/repositories.yaml
+`, g.Config.GitOpsTool)
+}
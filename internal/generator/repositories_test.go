@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newLayoutTestConfig(layout config.LayoutConfig) *config.Config {
+	cfg := newTenantTestConfig()
+	cfg.Tenants = nil
+	cfg.Apps = []config.Application{
+		{Name: "web", Image: "nginx:1.25", Port: 80, Replicas: 1},
+		{Name: "api", Image: "nginx:1.25", Port: 8080, Replicas: 1},
+		{Name: "admin", Image: "nginx:1.25", Port: 80, Replicas: 1},
+	}
+	cfg.Layout = layout
+	return cfg
+}
+
+func TestGenerator_HubAndSpokeLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newLayoutTestConfig(config.LayoutConfig{
+		Strategy: config.LayoutHubAndSpoke,
+		RepoURL:  "https://github.com/org/{repo}.git",
+		Teams: []config.LayoutTeam{
+			{Name: "shop", Apps: []string{"web", "api"}},
+			{Name: "ops", Apps: []string{"admin"}, RepoURL: "https://gitlab.example.com/ops/admin.git"},
+		},
+	})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	readGenerated(t, tmpDir, "plat-shop/applications/base/web/deployment.yaml")
+	readGenerated(t, tmpDir, "plat-shop/applications/base/api/deployment.yaml")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat-shop/applications/base/admin/deployment.yaml"))
+	base := readGenerated(t, tmpDir, "plat-shop/applications/base/kustomization.yaml")
+	assert.Contains(t, base, "- web/")
+	assert.NotContains(t, base, "- admin/")
+	readGenerated(t, tmpDir, "plat-shop/applications/overlays/prod/kustomization.yaml")
+	assert.Contains(t, readGenerated(t, tmpDir, "plat-shop/README.md"), "Applications web, api of team shop.")
+	readGenerated(t, tmpDir, "plat-ops/applications/base/admin/deployment.yaml")
+
+	// The hub keeps the infrastructure only.
+	assert.NoDirExists(t, filepath.Join(tmpDir, "plat/applications"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat/argocd/applicationsets/apps-dev.yaml"))
+	readGenerated(t, tmpDir, "plat/argocd/applicationsets/infra-dev.yaml")
+
+	appSet := readGenerated(t, tmpDir, "plat/argocd/applicationsets/plat-shop.yaml")
+	assert.Contains(t, appSet, "kind: ApplicationSet")
+	assert.Contains(t, appSet, "team: shop")
+	assert.Contains(t, appSet, "- name: plat-shop-dev\n            env: dev\n            namespace: plat-dev\n            server: https://kubernetes.default.svc")
+	assert.Contains(t, appSet, "- name: plat-shop-prod\n            env: prod\n            namespace: plat-prod\n            server: https://prod.example.com")
+	assert.Contains(t, appSet, "repoURL: https://github.com/org/plat-shop.git")
+	assert.Contains(t, appSet, "path: 'applications/overlays/{{env}}'")
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/argocd/applicationsets/plat-ops.yaml"), "repoURL: https://gitlab.example.com/ops/admin.git")
+
+	secrets := readGenerated(t, tmpDir, "plat/bootstrap/argocd/repositories.yaml")
+	assert.Contains(t, secrets, "name: repo-plat-shop\n")
+	assert.Contains(t, secrets, "url: https://github.com/org/plat-shop.git\n---\n")
+	assert.Contains(t, secrets, "name: repo-plat-ops\n")
+	assert.Contains(t, secrets, "argocd.argoproj.io/secret-type: repository")
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/scripts/bootstrap.sh"), "kubectl apply -f bootstrap/argocd/repositories.yaml")
+
+	_, tracked := gen.Metadata().Files["argocd/applicationsets/plat-shop.yaml"]
+	assert.True(t, tracked)
+}
+
+func TestGenerator_HubAndSpokeKeepsUnassignedApps(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newLayoutTestConfig(config.LayoutConfig{
+		Strategy: config.LayoutHubAndSpoke,
+		RepoURL:  "https://github.com/org/{repo}.git",
+		Teams:    []config.LayoutTeam{{Name: "shop", Apps: []string{"web"}}},
+	})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	base := readGenerated(t, tmpDir, "plat/applications/base/kustomization.yaml")
+	assert.Contains(t, base, "- api/")
+	assert.Contains(t, base, "- admin/")
+	assert.NotContains(t, base, "- web/")
+	readGenerated(t, tmpDir, "plat/argocd/applicationsets/apps-dev.yaml")
+	readGenerated(t, tmpDir, "plat-shop/applications/base/web/deployment.yaml")
+}
+
+func TestGenerator_PerAppLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newLayoutTestConfig(config.LayoutConfig{
+		Strategy: config.LayoutPerApp,
+		RepoURL:  "git@github.com:org/{repo}.git",
+	})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	for _, app := range []string{"web", "api", "admin"} {
+		readGenerated(t, tmpDir, "plat-"+app+"/applications/base/"+app+"/deployment.yaml")
+		appSet := readGenerated(t, tmpDir, "plat/argocd/applicationsets/plat-"+app+".yaml")
+		assert.Contains(t, appSet, "repoURL: git@github.com:org/plat-"+app+".git")
+		assert.NotContains(t, appSet, "team:")
+	}
+	assert.NoDirExists(t, filepath.Join(tmpDir, "plat/applications"))
+}
+
+func TestGenerator_LayoutMultiClusterDestinations(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newLayoutTestConfig(config.LayoutConfig{Strategy: config.LayoutPerApp, RepoURL: "https://github.com/org/{repo}.git"})
+	cfg.Apps = cfg.Apps[:1]
+	cfg.Environments = []config.Environment{{
+		Name: "prod",
+		Clusters: []config.EnvironmentCluster{
+			{Name: "eu", URL: "https://eu.example.com"},
+			{Name: "us", URL: "https://us.example.com"},
+		},
+	}}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	appSet := readGenerated(t, tmpDir, "plat/argocd/applicationsets/plat-web.yaml")
+	assert.Contains(t, appSet, "- name: plat-web-prod-eu\n            env: prod")
+	assert.Contains(t, appSet, "server: https://us.example.com")
+}
@@ -0,0 +1,44 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+const (
+	defaultSopsPathRegex      = `\.sops\.ya?ml$`
+	defaultSopsEncryptedRegex = `^(data|stringData)$`
+)
+
+func (g *Generator) generateSecretsConfig() error {
+	if g.Config.Secrets.Format != "sops" {
+		return nil
+	}
+
+	g.printf("🔐 Generating SOPS configuration...\n")
+
+	sops := g.Config.Secrets.Sops
+	data := map[string]string{
+		"PathRegex":      sops.PathRegex,
+		"EncryptedRegex": sops.EncryptedRegex,
+		"Age":            strings.Join(sops.Age, ","),
+		"PGP":            strings.Join(sops.PGP, ","),
+		"KMS":            strings.Join(sops.KMS, ","),
+		"GCPKMS":         strings.Join(sops.GCPKMS, ","),
+		"AzureKeyVault":  strings.Join(sops.AzureKeyVault, ","),
+	}
+	if data["PathRegex"] == "" {
+		data["PathRegex"] = defaultSopsPathRegex
+	}
+	if data["EncryptedRegex"] == "" {
+		data["EncryptedRegex"] = defaultSopsEncryptedRegex
+	}
+
+	content, err := templates.Render("secrets/sops.yaml.tmpl", data)
+	if err != nil {
+		return err
+	}
+
+	return g.Writer.WriteFile(g.Config.Project.Name+"/.sops.yaml", content)
+}
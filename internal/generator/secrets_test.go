@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func TestGenerator_SopsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	cfg.GitOpsTool = "argocd"
+	cfg.Secrets = config.SecretsConfig{
+		Format: "sops",
+		Sops: config.SopsConfig{
+			Age: []string{"age1abc", "age1def"},
+			PGP: []string{"FBC7B9E2A4F9289AC0C1D4843D16CEE4A27381B4"},
+		},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	sops := readGenerated(t, tmpDir, "flux-app/.sops.yaml")
+	assert.Contains(t, sops, `path_regex: '\.sops\.ya?ml$'`)
+	assert.Contains(t, sops, `encrypted_regex: '^(data|stringData)$'`)
+	assert.Contains(t, sops, "age: age1abc,age1def")
+	assert.Contains(t, sops, "pgp: FBC7B9E2A4F9289AC0C1D4843D16CEE4A27381B4")
+	assert.NotContains(t, sops, "kms:")
+}
+
+func TestGenerator_NoSopsConfigForPlainSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newFluxTestConfig()
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "flux-app/.sops.yaml"))
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// dexConnectors maps SSO providers to their Dex connector type and display
+// name.
+var dexConnectors = map[string][2]string{
+	config.SSOProviderGitHub:    {"github", "GitHub"},
+	config.SSOProviderGitLab:    {"gitlab", "GitLab"},
+	config.SSOProviderMicrosoft: {"microsoft", "Azure AD"},
+	config.SSOProviderKeycloak:  {"oidc", "Keycloak"},
+}
+
+// generatesArgoCDSSO reports whether argocd-cm SSO configuration is generated.
+func (g *Generator) generatesArgoCDSSO() bool {
+	usesArgoCD := g.Config.GitOpsTool == "argocd" || g.Config.GitOpsTool == "both"
+	return usesArgoCD && g.Config.SSO.Enabled()
+}
+
+// ssoSecretPath returns the path of the client secret manifest, relative to
+// the project root.
+func (g *Generator) ssoSecretPath() string {
+	name := "argocd-sso-secret.yaml"
+	if g.Config.Secrets.Format == "sops" {
+		name = "argocd-sso-secret.sops.yaml"
+	}
+	return fmt.Sprintf("bootstrap/%s/%s", g.Config.GitOpsTool, name)
+}
+
+// ssoScopes returns the scopes requested from the provider: the standard
+// OIDC scopes, the groups claim and any configured extras.
+func ssoScopes(sso config.SSOConfig) []string {
+	scopes := []string{"openid", "profile", "email", sso.Groups()}
+	for _, scope := range sso.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// generateArgoCDSSO writes argocd-cm with the OIDC or Dex configuration of the
+// sso block and the Secret holding the client secret.
+func (g *Generator) generateArgoCDSSO(argoCDNamespace string) error {
+	if !g.generatesArgoCDSSO() {
+		return nil
+	}
+	sso := g.Config.SSO
+
+	issuer := sso.Issuer
+	if issuer == "" && sso.Provider == config.SSOProviderGitLab {
+		issuer = "https://gitlab.com"
+	}
+	tenantID := sso.TenantID
+	if tenantID == "" {
+		tenantID = "common"
+	}
+	connector := dexConnectors[sso.Provider]
+
+	content, err := templates.Render("argocd/argocd-cm.yaml.tmpl", map[string]any{
+		"ArgoCDNamespace": argoCDNamespace,
+		"URL":             sso.URL,
+		"Provider":        sso.Provider,
+		"Issuer":          issuer,
+		"ClientID":        sso.ClientID,
+		"ClientSecret":    fmt.Sprintf("$%s:%s", sso.SecretName(), sso.SecretKey()),
+		"GroupsClaim":     sso.Groups(),
+		"Scopes":          ssoScopes(sso),
+		"Orgs":            sso.Orgs,
+		"TenantID":        tenantID,
+		"ConnectorType":   connector[0],
+		"ConnectorName":   connector[1],
+		"RedirectURI":     strings.TrimSuffix(sso.URL, "/") + "/api/dex/callback",
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/bootstrap/%s/argocd-cm.yaml", g.Config.Project.Name, g.Config.GitOpsTool)
+	if err := g.Writer.WriteFile(path, content); err != nil {
+		return err
+	}
+
+	if sso.ClientSecret.Existing {
+		return nil
+	}
+
+	var value string
+	if sso.ClientSecret.Env != "" {
+		value = os.Getenv(sso.ClientSecret.Env)
+	}
+	secret, err := templates.Render("argocd/sso-secret.yaml.tmpl", map[string]any{
+		"Name":            sso.SecretName(),
+		"Key":             sso.SecretKey(),
+		"Value":           value,
+		"Provider":        sso.Provider,
+		"ArgoCDNamespace": argoCDNamespace,
+		"Sops":            g.Config.Secrets.Format == "sops",
+		"File":            g.ssoSecretPath(),
+	})
+	if err != nil {
+		return err
+	}
+	return g.Writer.WriteFile(g.Config.Project.Name+"/"+g.ssoSecretPath(), secret)
+}
@@ -0,0 +1,135 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newSSOTestConfig(sso config.SSOConfig) *config.Config {
+	cfg := newTenantTestConfig()
+	cfg.Tenants = nil
+	cfg.SSO = sso
+	return cfg
+}
+
+func TestGenerator_ArgoCDOIDC(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TEST_SSO_SECRET", "s3cret")
+	cfg := newSSOTestConfig(config.SSOConfig{
+		Provider:     "oidc",
+		URL:          "https://argocd.example.com",
+		Issuer:       "https://idp.example.com",
+		ClientID:     "argocd",
+		ClientSecret: config.SSOSecretRef{Env: "TEST_SSO_SECRET"},
+		GroupsClaim:  "roles",
+		Scopes:       []string{"email", "offline_access"},
+	})
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	cm := readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-cm.yaml")
+	assert.Contains(t, cm, `url: "https://argocd.example.com"`)
+	assert.Contains(t, cm, "oidc.config: |")
+	assert.Contains(t, cm, `issuer: "https://idp.example.com"`)
+	assert.Contains(t, cm, `clientSecret: "$argocd-sso:clientSecret"`)
+	assert.Contains(t, cm, "- \"openid\"\n      - \"profile\"\n      - \"email\"\n      - \"roles\"\n      - \"offline_access\"\n")
+	assert.Contains(t, cm, "requestedIDTokenClaims:\n      roles:\n        essential: true")
+	assert.NotContains(t, cm, "dex.config")
+
+	secret := readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-sso-secret.yaml")
+	assert.Contains(t, secret, "name: argocd-sso")
+	assert.Contains(t, secret, "app.kubernetes.io/part-of: argocd")
+	assert.Contains(t, secret, `clientSecret: "s3cret"`)
+	assert.NotContains(t, secret, "# Set clientSecret")
+
+	script := readGenerated(t, tmpDir, "plat/scripts/bootstrap.sh")
+	assert.Contains(t, script, "kubectl apply -f bootstrap/argocd/argocd-sso-secret.yaml\nkubectl apply -f bootstrap/argocd/argocd-cm.yaml")
+}
+
+func TestGenerator_ArgoCDDexConnectors(t *testing.T) {
+	base := config.SSOConfig{URL: "https://argocd.example.com/", ClientID: "argocd"}
+	tests := []struct {
+		name     string
+		provider string
+		issuer   string
+		orgs     []string
+		contains []string
+	}{
+		{"github", "github", "", []string{"acme"}, []string{"type: github", "name: GitHub", "orgs:\n            - name: \"acme\"", "teamNameField: slug"}},
+		{"gitlab", "gitlab", "", []string{"platform"}, []string{"type: gitlab", `baseURL: "https://gitlab.com"`, "groups:\n            - \"platform\""}},
+		{"microsoft", "microsoft", "", nil, []string{"type: microsoft", "name: Azure AD", `tenant: "common"`}},
+		{"keycloak", "keycloak", "https://sso.example.com/realms/platform", nil, []string{"type: oidc", "id: keycloak", `issuer: "https://sso.example.com/realms/platform"`, "insecureEnableGroups: true", `groups: "groups"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			sso := base
+			sso.Provider = tt.provider
+			sso.Issuer = tt.issuer
+			sso.Orgs = tt.orgs
+			gen := New(newSSOTestConfig(sso), output.New(tmpDir, false, false), false)
+
+			require.NoError(t, gen.Generate())
+
+			cm := readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-cm.yaml")
+			assert.Contains(t, cm, "dex.config: |")
+			assert.Contains(t, cm, `redirectURI: "https://argocd.example.com/api/dex/callback"`)
+			assert.Contains(t, cm, `clientSecret: "$argocd-sso:clientSecret"`)
+			for _, want := range tt.contains {
+				assert.Contains(t, cm, want)
+			}
+			assert.Contains(t, readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-sso-secret.yaml"), "# Set clientSecret to the OAuth client secret")
+		})
+	}
+}
+
+func TestGenerator_ArgoCDSSOSecret(t *testing.T) {
+	t.Run("sops", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := newSSOTestConfig(config.SSOConfig{Provider: "github", URL: "https://argocd.example.com", ClientID: "argocd"})
+		cfg.Secrets = config.SecretsConfig{Format: "sops", Sops: config.SopsConfig{Age: []string{"age1test"}}}
+		gen := New(cfg, output.New(tmpDir, false, false), false)
+
+		require.NoError(t, gen.Generate())
+
+		secret := readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-sso-secret.sops.yaml")
+		assert.Contains(t, secret, "# Encrypt before committing: sops --encrypt --in-place bootstrap/argocd/argocd-sso-secret.sops.yaml")
+		assert.NoFileExists(t, filepath.Join(tmpDir, "plat/bootstrap/argocd/argocd-sso-secret.yaml"))
+		assert.Contains(t, readGenerated(t, tmpDir, "plat/scripts/bootstrap.sh"), "sops --decrypt bootstrap/argocd/argocd-sso-secret.sops.yaml | kubectl apply -f -")
+	})
+
+	t.Run("existing", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := newSSOTestConfig(config.SSOConfig{
+			Provider:     "github",
+			URL:          "https://argocd.example.com",
+			ClientID:     "argocd",
+			ClientSecret: config.SSOSecretRef{Name: "github-oauth", Key: "secret", Existing: true},
+		})
+		gen := New(cfg, output.New(tmpDir, false, false), false)
+
+		require.NoError(t, gen.Generate())
+
+		assert.Contains(t, readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-cm.yaml"), `clientSecret: "$github-oauth:secret"`)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "plat/bootstrap/argocd/argocd-sso-secret.yaml"))
+		assert.NotContains(t, readGenerated(t, tmpDir, "plat/scripts/bootstrap.sh"), "argocd-sso-secret")
+	})
+}
+
+func TestGenerator_ArgoCDSSODisabledForFlux(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newSSOTestConfig(config.SSOConfig{Provider: "github", URL: "https://argocd.example.com", ClientID: "argocd"})
+	cfg.GitOpsTool = "flux"
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat/bootstrap/flux/argocd-cm.yaml"))
+}
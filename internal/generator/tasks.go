@@ -0,0 +1,238 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/telemetry"
+)
+
+// task is a unit of generation work, run once the tasks it comes after are
+// done and concurrently with the other tasks ready at the same time.
+type task struct {
+	// name names the task in errors: "failed to generate <name>"
+	name  string
+	after []string
+	run   func(g *Generator) error
+}
+
+// tasks returns the generation task graph of the config.
+func (g *Generator) tasks() []task {
+	infra := g.Config.Scope == "infrastructure" || g.Config.Scope == "both"
+	apps := g.generatesApplications()
+
+	tasks := []task{{name: "structure", run: (*Generator).generateStructure}}
+	after := []string{"structure"}
+	if infra {
+		tasks = append(tasks, task{name: "infrastructure", after: after, run: (*Generator).generateInfrastructure})
+		if g.generatesCloudAddons() {
+			tasks = append(tasks, task{name: "cloud addons", after: after, run: (*Generator).generateCloudAddons})
+		}
+	}
+	if apps {
+		tasks = append(tasks, task{name: "applications", after: after, run: (*Generator).generateApplications})
+		// The GitOps resources and docs list the applications and their
+		// sync waves.
+		after = []string{"applications"}
+	}
+	tasks = append(tasks, task{name: "gitops config", after: after, run: (*Generator).generateGitOps})
+	if docs := g.Config.Docs; docs.Readme || docs.Architecture || docs.Onboarding {
+		// The architecture diagrams draw the generated GitOps resources.
+		tasks = append(tasks, task{name: "docs", after: []string{"gitops config"}, run: (*Generator).generateDocs})
+	}
+	if g.Config.Cost.Enabled {
+		tasks = append(tasks, task{name: "cost allocation", after: []string{"structure"}, run: (*Generator).generateCostAllocation})
+	}
+	return append(tasks,
+		task{name: "bootstrap", after: []string{"structure"}, run: (*Generator).generateBootstrap},
+		task{name: "scripts", after: []string{"structure"}, run: (*Generator).generateScripts},
+		task{name: "operators", after: []string{"structure"}, run: (*Generator).generateOperators},
+		task{name: "secrets config", after: []string{"structure"}, run: (*Generator).generateSecretsConfig},
+		task{name: "ci pipeline", after: []string{"structure"}, run: (*Generator).generateCI},
+	)
+}
+
+// workers returns the number of tasks run concurrently.
+func (g *Generator) workers() int {
+	if g.Workers > 0 {
+		return g.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// runTasks runs a task graph level by level: each level holds the tasks
+// whose dependencies are done, run concurrently by parallel.
+func (g *Generator) runTasks(ctx context.Context, tasks []task) error {
+	var mu sync.Mutex
+	completed := 0
+	done := map[string]bool{}
+	for len(done) < len(tasks) {
+		var level []task
+		for _, t := range tasks {
+			if !done[t.name] && allDone(t.after, done) {
+				level = append(level, t)
+			}
+		}
+		if len(level) == 0 {
+			return fmt.Errorf("generation tasks have unknown or circular dependencies")
+		}
+
+		err := g.parallel(len(level), func(i int, view *Generator) error {
+			run := func(context.Context) error { return level[i].run(view) }
+			if err := telemetry.Run(ctx, "generate."+strings.ReplaceAll(level[i].name, " ", "_"), run); err != nil {
+				return fmt.Errorf("failed to generate %s: %w", level[i].name, err)
+			}
+			if g.Progress != nil {
+				mu.Lock()
+				completed++
+				g.Progress(completed, len(tasks))
+				mu.Unlock()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, t := range level {
+			done[t.name] = true
+		}
+	}
+	return nil
+}
+
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// parallel runs n jobs on the worker pool. Each job gets its own view of the
+// generator buffering its progress messages and file listing, written in job
+// order once all jobs are done, so the output does not depend on scheduling.
+// Once a job fails no more jobs start, and the error of the first failing
+// job in job order is returned.
+func (g *Generator) parallel(n int, job func(i int, view *Generator) error) error {
+	logs := make([]taskLog, n)
+	views := make([]*Generator, n)
+	errs := make([]error, n)
+
+	if n == 1 || g.workers() == 1 {
+		for i := range n {
+			views[i] = g.view(&logs[i])
+			if errs[i] = job(i, views[i]); errs[i] != nil {
+				break
+			}
+		}
+	} else {
+		var failed atomic.Bool
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, g.workers())
+		for i := range n {
+			sem <- struct{}{}
+			if failed.Load() {
+				<-sem
+				break
+			}
+			views[i] = g.view(&logs[i])
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if errs[i] = job(i, views[i]); errs[i] != nil {
+					failed.Store(true)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	for i, view := range views {
+		if view == nil {
+			break
+		}
+		if err := logs[i].flush(g.logOutput(), writerLog(g)); err != nil {
+			return err
+		}
+		g.Deprecations = append(g.Deprecations, view.Deprecations...)
+		if errs[i] != nil {
+			return errs[i]
+		}
+	}
+	return nil
+}
+
+// view returns a copy of the generator and its writer sharing the config,
+// hooks and recorded state, logging into log.
+func (g *Generator) view(log *taskLog) *Generator {
+	v := *g
+	w := *g.Writer
+	w.Log = log.to(true)
+	v.Writer = &w
+	v.Log = log.to(false)
+	v.Deprecations = nil
+	return &v
+}
+
+func (g *Generator) logOutput() io.Writer {
+	if g.Log == nil {
+		return os.Stdout
+	}
+	return g.Log
+}
+
+func writerLog(g *Generator) io.Writer {
+	if g.Writer.Log == nil {
+		return os.Stdout
+	}
+	return g.Writer.Log
+}
+
+// taskLog buffers the output of a task, in order, until it is written to the
+// logs of the generator and of its writer.
+type taskLog struct {
+	chunks []logChunk
+}
+
+type logChunk struct {
+	writer bool
+	data   []byte
+}
+
+// to returns an io.Writer appending to the log of the writer or of the
+// generator.
+func (l *taskLog) to(writer bool) io.Writer {
+	return logDestination{log: l, writer: writer}
+}
+
+func (l *taskLog) flush(generatorLog, writerLog io.Writer) error {
+	for _, c := range l.chunks {
+		w := generatorLog
+		if c.writer {
+			w = writerLog
+		}
+		if _, err := w.Write(c.data); err != nil {
+			return err
+		}
+	}
+	l.chunks = nil
+	return nil
+}
+
+type logDestination struct {
+	log    *taskLog
+	writer bool
+}
+
+func (d logDestination) Write(p []byte) (int, error) {
+	d.log.chunks = append(d.log.chunks, logChunk{writer: d.writer, data: append([]byte(nil), p...)})
+	return len(p), nil
+}
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// newLargeConfig returns a config with apps applications deployed to three
+// environments.
+func newLargeConfig(apps int) *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "platform"
+	cfg.Scope = "both"
+	cfg.Git.URL = "https://github.com/org/platform.git"
+	cfg.Docs.Readme = true
+	cfg.Environments = []config.Environment{{Name: "dev"}, {Name: "staging"}, {Name: "prod"}}
+	cfg.Apps = nil
+	for i := range apps {
+		name := fmt.Sprintf("app-%03d", i)
+		cfg.Apps = append(cfg.Apps, config.Application{
+			Name:      name,
+			Image:     "registry.example.com/" + name + ":1.0.0",
+			Port:      8080,
+			Replicas:  2,
+			ConfigMap: map[string]string{"LOG_LEVEL": "info"},
+			Overrides: map[string]config.AppOverride{
+				"prod": {Replicas: 4, ConfigMap: map[string]string{"LOG_LEVEL": "warn"}},
+			},
+		})
+	}
+	return cfg
+}
+
+// generateWith generates cfg into a new directory with workers, returning
+// the directory and the progress and file listing output.
+func generateWith(t *testing.T, cfg *config.Config, workers int) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	var log bytes.Buffer
+	writer := output.New(dir, false, true)
+	writer.Log = &log
+	gen := New(cfg, writer, true)
+	gen.Log = &log
+	gen.Workers = workers
+	require.NoError(t, gen.Generate())
+	return dir, log.String()
+}
+
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestGenerator_ParallelDeterministic(t *testing.T) {
+	cfg := newLargeConfig(20)
+
+	seqDir, seqLog := generateWith(t, cfg, 1)
+	parDir, parLog := generateWith(t, cfg, 8)
+
+	assert.Equal(t, readTree(t, seqDir), readTree(t, parDir))
+	assert.Equal(t, seqLog, parLog)
+	assert.Contains(t, seqLog, "app-019")
+}
+
+func TestGenerator_Progress(t *testing.T) {
+	cfg := newLargeConfig(2)
+	gen := New(cfg, output.New(t.TempDir(), false, false), false)
+	gen.Log = &bytes.Buffer{}
+	gen.Writer.Log = gen.Log
+
+	var calls []int
+	total := 0
+	gen.Progress = func(done, n int) {
+		calls = append(calls, done)
+		total = n
+	}
+	require.NoError(t, gen.Generate())
+
+	assert.Equal(t, len(gen.tasks()), total)
+	require.Len(t, calls, total)
+	for i, done := range calls {
+		assert.Equal(t, i+1, done)
+	}
+}
+
+func TestGenerator_Parallel(t *testing.T) {
+	gen := New(newLargeConfig(0), output.New(t.TempDir(), true, false), false)
+	var log bytes.Buffer
+	gen.Log = &log
+	gen.Workers = 4
+
+	err := gen.parallel(8, func(i int, view *Generator) error {
+		view.printf("job %d\n", i)
+		if i == 2 || i == 5 {
+			return fmt.Errorf("job %d failed", i)
+		}
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, "job 2 failed", err.Error())
+	assert.Equal(t, "job 0\njob 1\njob 2\n", log.String())
+}
+
+func TestGenerator_RunTasksCycle(t *testing.T) {
+	gen := New(newLargeConfig(0), output.New(t.TempDir(), true, false), false)
+	run := func(*Generator) error { return errors.New("should not run") }
+
+	err := gen.runTasks(context.Background(), []task{
+		{name: "a", after: []string{"b"}, run: run},
+		{name: "b", after: []string{"a"}, run: run},
+	})
+	assert.ErrorContains(t, err, "circular")
+}
@@ -0,0 +1,383 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+const inClusterServer = "https://kubernetes.default.svc"
+
+type tenantBinding struct {
+	Role   string // admin, edit or view
+	Groups []string
+}
+
+type tenantRole struct {
+	Name        string
+	Description string
+	Policies    []string
+	Groups      []string
+}
+
+type tenantDestination struct {
+	Namespace string
+	Server    string
+}
+
+// envServers returns the cluster API servers of an environment.
+func envServers(env config.Environment) []string {
+	if len(env.Clusters) > 0 {
+		servers := make([]string, 0, len(env.Clusters))
+		for _, cluster := range env.Clusters {
+			servers = append(servers, cluster.URL)
+		}
+		return servers
+	}
+	if env.Cluster != "" {
+		return []string{env.Cluster}
+	}
+	return []string{inClusterServer}
+}
+
+// generateTenants writes the namespaces, quotas, limits and RoleBindings of
+// every tenant into infrastructure/base/tenants.
+func (g *Generator) generateTenants() error {
+	var files []string
+	for _, tenant := range g.Config.Tenants {
+		for _, env := range g.Config.Environments {
+			docs, err := g.tenantNamespaceDocs(tenant, env.Name)
+			if err != nil {
+				return err
+			}
+
+			file := fmt.Sprintf("%s/%s.yaml", tenant.Name, env.Name)
+			path := fmt.Sprintf("%s/infrastructure/base/tenants/%s", g.Config.Project.Name, file)
+			if err := g.Writer.WriteFile(path, joinDocs(docs)); err != nil {
+				return err
+			}
+			files = append(files, file)
+		}
+	}
+
+	return g.generateSubdirKustomization("tenants", files)
+}
+
+// tenantNamespaceDocs renders the namespaces of a tenant in an environment,
+// with their quotas, limits and RoleBindings.
+func (g *Generator) tenantNamespaceDocs(tenant config.Tenant, envName string) ([][]byte, error) {
+	var bindings []tenantBinding
+	for _, b := range []tenantBinding{
+		{"admin", tenant.Groups.Admins},
+		{"edit", tenant.Groups.Developers},
+		{"view", tenant.Groups.Viewers},
+	} {
+		if len(b.Groups) > 0 {
+			bindings = append(bindings, b)
+		}
+	}
+
+	var docs [][]byte
+	for _, namespace := range tenant.EnvNamespaces(envName) {
+		content, err := templates.Render("infrastructure/tenant.yaml.tmpl", map[string]any{
+			"Tenant":         tenant.Name,
+			"Namespace":      namespace,
+			"Env":            envName,
+			"Quota":          tenant.Quota,
+			"DefaultLimits":  tenant.DefaultLimits,
+			"Bindings":       bindings,
+			"ProjectRequest": g.projectRequests(),
+			"ManagedBy":      g.namespaceManagedBy(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, bytes.TrimSpace(content))
+	}
+	return docs, nil
+}
+
+// joinDocs joins YAML documents into a multi-document file.
+func joinDocs(docs [][]byte) []byte {
+	return append(bytes.Join(docs, []byte("\n---\n")), '\n')
+}
+
+// generateFluxTenants writes the Flux multi-tenancy layout into flux/tenants:
+// for every tenant and environment, the service account the tenant is
+// reconciled as, its RoleBindings in the tenant namespaces, and the
+// GitRepository and Kustomization syncing the tenant path. The tenant
+// namespaces are written too when the infrastructure scope does not create
+// them.
+func (g *Generator) generateFluxTenants() error {
+	platformRepo := g.Config.Git.URL
+	if platformRepo == "" {
+		platformRepo = g.Config.Output.URL
+	}
+	branch := g.Config.Git.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	withNamespaces := g.Config.Scope != "infrastructure" && g.Config.Scope != "both"
+
+	for _, tenant := range g.Config.Tenants {
+		repoURL := tenant.RepoURL
+		if repoURL == "" {
+			repoURL = platformRepo
+		}
+		if repoURL == "" {
+			return fmt.Errorf("tenant %s: repo_url or git.url is required to generate its Flux GitRepository", tenant.Name)
+		}
+
+		for _, env := range g.Config.Environments {
+			namespaces := tenant.EnvNamespaces(env.Name)
+			var docs [][]byte
+			if withNamespaces {
+				namespaceDocs, err := g.tenantNamespaceDocs(tenant, env.Name)
+				if err != nil {
+					return err
+				}
+				docs = append(docs, namespaceDocs...)
+			}
+
+			// A tenant with several namespaces sets them in its manifests.
+			targetNamespace := ""
+			if len(namespaces) == 1 {
+				targetNamespace = namespaces[0]
+			}
+			content, err := templates.Render("flux/tenant.yaml.tmpl", map[string]any{
+				"Tenant":          tenant.Name,
+				"Namespace":       namespaces[0],
+				"Namespaces":      namespaces,
+				"Env":             env.Name,
+				"URL":             repoURL,
+				"Branch":          branch,
+				"Interval":        g.getFluxInterval(),
+				"Path":            fmt.Sprintf("./%s/%s", tenant.RepoPath(), env.Name),
+				"TargetNamespace": targetNamespace,
+			})
+			if err != nil {
+				return err
+			}
+			docs = append(docs, bytes.TrimSpace(content))
+
+			path := fmt.Sprintf("%s/flux/tenants/%s/%s.yaml", g.Config.Project.Name, tenant.Name, env.Name)
+			if err := g.Writer.WriteFile(path, joinDocs(docs)); err != nil {
+				return err
+			}
+			if repoURL == platformRepo {
+				if err := g.writeTenantPlaceholder(tenant, env.Name, namespaces[0]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeTenantPlaceholder writes an empty kustomization for the team to fill
+// at the tenant path of an environment. The namespace keeps it buildable
+// until the tenant adds resources.
+func (g *Generator) writeTenantPlaceholder(tenant config.Tenant, envName, namespace string) error {
+	kustomization, err := templates.Render("kubernetes/kustomization.yaml.tmpl", map[string]any{
+		"Namespace": namespace,
+		"Resources": []string{},
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/%s/%s/kustomization.yaml", g.Config.Project.Name, tenant.RepoPath(), envName)
+	return g.Writer.WriteFile(path, kustomization)
+}
+
+// generateTenantProjects writes an AppProject per tenant, restricted to its
+// source repositories and namespaces, and the optional tenant ApplicationSets.
+func (g *Generator) generateTenantProjects(argoCDNamespace string) error {
+	repoURL := g.Config.Git.URL
+	if repoURL == "" {
+		repoURL = g.Config.Output.URL
+	}
+
+	for _, tenant := range g.Config.Tenants {
+		sourceRepos := tenant.SourceRepos
+		if len(sourceRepos) == 0 {
+			if repoURL == "" {
+				return fmt.Errorf("tenant %s: source_repos or git.url is required to restrict its AppProject", tenant.Name)
+			}
+			sourceRepos = []string{repoURL}
+		}
+		if tenant.ApplicationSet && repoURL != "" && !slices.Contains(sourceRepos, repoURL) {
+			// The tenant ApplicationSet deploys from the platform repository.
+			sourceRepos = append(slices.Clone(sourceRepos), repoURL)
+		}
+
+		var destinations []tenantDestination
+		for _, env := range g.Config.Environments {
+			for _, server := range envServers(env) {
+				for _, namespace := range tenant.EnvNamespaces(env.Name) {
+					destinations = append(destinations, tenantDestination{Namespace: namespace, Server: server})
+				}
+			}
+		}
+
+		scope := tenant.Name + "/*"
+		roles := []tenantRole{
+			{
+				Name:        "admin",
+				Description: "Manage the applications of tenant " + tenant.Name,
+				Policies: []string{
+					"admin, applications, *, " + scope + ", allow",
+					"admin, repositories, *, " + scope + ", allow",
+					"admin, exec, create, " + scope + ", allow",
+				},
+				Groups: tenant.Groups.Admins,
+			},
+			{
+				Name:        "developer",
+				Description: "Sync the applications of tenant " + tenant.Name,
+				Policies: []string{
+					"developer, applications, get, " + scope + ", allow",
+					"developer, applications, sync, " + scope + ", allow",
+					"developer, applications, action/*, " + scope + ", allow",
+				},
+				Groups: tenant.Groups.Developers,
+			},
+			{
+				Name:        "readonly",
+				Description: "Read-only access to the applications of tenant " + tenant.Name,
+				Policies:    []string{"readonly, applications, get, " + scope + ", allow"},
+				Groups:      tenant.Groups.Viewers,
+			},
+		}
+
+		content, err := templates.Render("argocd/tenant-project.yaml.tmpl", map[string]any{
+			"Name":            tenant.Name,
+			"Description":     tenant.Description,
+			"ArgoCDNamespace": argoCDNamespace,
+			"SourceRepos":     sourceRepos,
+			"Destinations":    destinations,
+			"Roles":           roles,
+		})
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s/%s/projects/tenant-%s.yaml", g.Config.Project.Name, g.Config.GitOpsTool, tenant.Name)
+		if err := g.Writer.WriteFile(path, content); err != nil {
+			return err
+		}
+
+		if tenant.ApplicationSet {
+			if err := g.generateTenantApplicationSet(tenant, argoCDNamespace, repoURL); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// generateTenantApplicationSet writes an ApplicationSet deploying the tenant
+// path of each environment, and its placeholder kustomizations.
+func (g *Generator) generateTenantApplicationSet(tenant config.Tenant, argoCDNamespace, repoURL string) error {
+	if repoURL == "" {
+		return fmt.Errorf("tenant %s: git.url is required to generate its ApplicationSet", tenant.Name)
+	}
+
+	branch := g.Config.Output.Branch
+	if branch == "" {
+		branch = "main"
+	}
+
+	var environments []map[string]string
+	for _, env := range g.Config.Environments {
+		environments = append(environments, map[string]string{
+			"Name":      env.Name,
+			"Namespace": tenant.EnvNamespaces(env.Name)[0],
+			"Server":    envServers(env)[0],
+		})
+		if err := g.writeTenantPlaceholder(tenant, env.Name, tenant.EnvNamespaces(env.Name)[0]); err != nil {
+			return err
+		}
+	}
+
+	content, err := templates.Render("argocd/applicationset-tenant.yaml.tmpl", map[string]any{
+		"Name":            tenant.Name,
+		"ArgoCDNamespace": argoCDNamespace,
+		"RepoURL":         repoURL,
+		"Branch":          branch,
+		"Path":            tenant.RepoPath(),
+		"Environments":    environments,
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/%s/applicationsets/tenant-%s.yaml", g.Config.Project.Name, g.Config.GitOpsTool, tenant.Name)
+	return g.Writer.WriteFile(path, content)
+}
+
+// generatesArgoCDRBAC reports whether argocd-rbac-cm is generated.
+func (g *Generator) generatesArgoCDRBAC() bool {
+	usesArgoCD := g.Config.GitOpsTool == "argocd" || g.Config.GitOpsTool == "both"
+	return usesArgoCD && (len(g.Config.Tenants) > 0 || g.Config.ArgoCD.RBAC.Enabled())
+}
+
+type rbacBinding struct {
+	Group   string
+	Project string
+	Role    string
+}
+
+// generateArgoCDRBAC writes argocd-rbac-cm, mapping the groups of every
+// tenant to its AppProject roles.
+func (g *Generator) generateArgoCDRBAC(argoCDNamespace string) error {
+	if !g.generatesArgoCDRBAC() {
+		return nil
+	}
+	rbac := g.Config.ArgoCD.RBAC
+
+	type tenantBindings struct {
+		Name     string
+		Bindings []rbacBinding
+	}
+	var tenants []tenantBindings
+	for _, tenant := range g.Config.Tenants {
+		t := tenantBindings{Name: tenant.Name}
+		for _, role := range []struct {
+			name   string
+			groups []string
+		}{
+			{"admin", tenant.Groups.Admins},
+			{"developer", tenant.Groups.Developers},
+			{"readonly", tenant.Groups.Viewers},
+		} {
+			for _, group := range role.groups {
+				t.Bindings = append(t.Bindings, rbacBinding{Group: group, Project: tenant.Name, Role: role.name})
+			}
+		}
+		if len(t.Bindings) > 0 {
+			tenants = append(tenants, t)
+		}
+	}
+
+	scopes := rbac.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{g.Config.SSO.Groups()}
+	}
+
+	content, err := templates.Render("argocd/rbac-cm.yaml.tmpl", map[string]any{
+		"ArgoCDNamespace": argoCDNamespace,
+		"AdminGroups":     rbac.AdminGroups,
+		"Tenants":         tenants,
+		"Policies":        rbac.Policies,
+		"DefaultPolicy":   rbac.DefaultPolicy,
+		"Scopes":          "[" + strings.Join(scopes, ", ") + "]",
+	})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/bootstrap/%s/argocd-rbac-cm.yaml", g.Config.Project.Name, g.Config.GitOpsTool)
+	return g.Writer.WriteFile(path, content)
+}
@@ -0,0 +1,217 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newTenantTestConfig() *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "plat"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/org/plat.git"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod", Cluster: "https://prod.example.com"},
+		},
+		Tenants: []config.Tenant{
+			{
+				Name:           "payments",
+				SourceRepos:    []string{"https://github.com/org/payments-*"},
+				Quota:          &config.TenantQuota{Requests: config.ResourceList{CPU: "4"}, Pods: "50"},
+				DefaultLimits:  &config.Resources{Limits: config.ResourceList{Memory: "256Mi"}},
+				Groups:         config.TenantGroups{Admins: []string{"payments-leads"}, Viewers: []string{"auditors"}},
+				ApplicationSet: true,
+			},
+			{Name: "search", Namespaces: []string{"api", "workers"}},
+		},
+	}
+}
+
+func TestGenerator_TenantInfrastructure(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newTenantTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	payments := readGenerated(t, tmpDir, "plat/infrastructure/base/tenants/payments/prod.yaml")
+	assert.Contains(t, payments, "kind: Namespace\nmetadata:\n  name: payments-prod")
+	assert.Contains(t, payments, `requests.cpu: "4"`)
+	assert.Contains(t, payments, `pods: "50"`)
+	assert.Contains(t, payments, "kind: LimitRange")
+	assert.Contains(t, payments, "default:\n        memory: \"256Mi\"")
+	assert.NotContains(t, payments, "defaultRequest:")
+	assert.Contains(t, payments, "name: payments-admin")
+	assert.Contains(t, payments, "kind: Group\n    name: payments-leads")
+	assert.Contains(t, payments, "kind: ClusterRole\n  name: view")
+	assert.NotContains(t, payments, "name: payments-edit")
+
+	search := readGenerated(t, tmpDir, "plat/infrastructure/base/tenants/search/dev.yaml")
+	assert.Contains(t, search, "name: search-api-dev")
+	assert.Contains(t, search, "name: search-workers-dev")
+	assert.NotContains(t, search, "ResourceQuota")
+
+	kustomization := readGenerated(t, tmpDir, "plat/infrastructure/base/tenants/kustomization.yaml")
+	assert.Contains(t, kustomization, "- payments/dev.yaml")
+	assert.Contains(t, kustomization, "- search/prod.yaml")
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/infrastructure/base/kustomization.yaml"), "- tenants/")
+}
+
+func TestGenerator_TenantAppProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newTenantTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	project := readGenerated(t, tmpDir, "plat/argocd/projects/tenant-payments.yaml")
+	assert.Contains(t, project, `- "https://github.com/org/payments-*"`)
+	assert.Contains(t, project, `- "https://github.com/org/plat.git"`, "the ApplicationSet source must be allowed")
+	assert.Contains(t, project, "- namespace: payments-dev\n      server: https://kubernetes.default.svc")
+	assert.Contains(t, project, "- namespace: payments-prod\n      server: https://prod.example.com")
+	assert.Contains(t, project, "clusterResourceWhitelist: []")
+	assert.Contains(t, project, "p, proj:payments:admin, applications, *, payments/*, allow")
+	assert.Contains(t, project, "groups:\n        - auditors")
+
+	search := readGenerated(t, tmpDir, "plat/argocd/projects/tenant-search.yaml")
+	assert.Contains(t, search, `- "https://github.com/org/plat.git"`)
+	assert.Contains(t, search, "- namespace: search-workers-prod")
+
+	appSet := readGenerated(t, tmpDir, "plat/argocd/applicationsets/tenant-payments.yaml")
+	assert.Contains(t, appSet, "- env: prod\n            namespace: payments-prod\n            server: https://prod.example.com")
+	assert.Contains(t, appSet, "project: payments")
+	assert.Contains(t, appSet, "path: 'tenants/payments/{{env}}'")
+	assert.FileExists(t, filepath.Join(tmpDir, "plat/tenants/payments/dev/kustomization.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat/argocd/applicationsets/tenant-search.yaml"))
+}
+
+func TestGenerator_TenantRequiresSourceRepos(t *testing.T) {
+	cfg := newTenantTestConfig()
+	cfg.Git.URL = ""
+	cfg.Scope = "infrastructure"
+	cfg.Tenants = []config.Tenant{{Name: "search"}}
+	gen := New(cfg, output.New(t.TempDir(), false, false), false)
+
+	require.NoError(t, gen.generateInfrastructure())
+	err := gen.generateTenantProjects("argocd")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "source_repos")
+}
+
+func TestGenerator_ArgoCDRBACFromTenants(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newTenantTestConfig()
+	cfg.Tenants[0].Groups.Developers = []string{"payments-devs"}
+	cfg.ArgoCD.RBAC = config.ArgoCDRBACConfig{
+		AdminGroups: []string{"platform-admins"},
+		Scopes:      []string{"groups", "email"},
+		Policies:    []string{"g, release-bot, role:admin"},
+	}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	rbac := readGenerated(t, tmpDir, "plat/bootstrap/argocd/argocd-rbac-cm.yaml")
+	assert.Contains(t, rbac, "name: argocd-rbac-cm")
+	assert.Contains(t, rbac, "g, platform-admins, role:admin")
+	assert.NotContains(t, rbac, "g, cluster-admins, role:admin")
+	assert.Contains(t, rbac, "# Tenant payments\n    g, payments-leads, proj:payments:admin\n    g, payments-devs, proj:payments:developer\n    g, auditors, proj:payments:readonly")
+	assert.NotContains(t, rbac, "# Tenant search")
+	assert.Contains(t, rbac, "    g, release-bot, role:admin\n  policy.default: role:readonly")
+	assert.Contains(t, rbac, "scopes: '[groups, email]'")
+
+	project := readGenerated(t, tmpDir, "plat/argocd/projects/tenant-payments.yaml")
+	assert.Contains(t, project, "- name: readonly")
+	assert.Contains(t, project, "p, proj:payments:readonly, applications, get, payments/*, allow")
+
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/scripts/bootstrap.sh"), "kubectl apply -f bootstrap/argocd/argocd-rbac-cm.yaml")
+}
+
+func TestGenerator_ArgoCDRBACDisabled(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newTenantTestConfig()
+	cfg.Tenants = nil
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat/bootstrap/argocd/argocd-rbac-cm.yaml"))
+	assert.NotContains(t, readGenerated(t, tmpDir, "plat/scripts/bootstrap.sh"), "argocd-rbac-cm")
+}
+
+func TestGenerator_FluxTenants(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newTenantTestConfig()
+	cfg.GitOpsTool = "flux"
+	cfg.Tenants[1].RepoURL = "https://github.com/org/search.git"
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	payments := readGenerated(t, tmpDir, "plat/flux/tenants/payments/prod.yaml")
+	assert.Contains(t, payments, "kind: ServiceAccount\nmetadata:\n  name: payments\n  namespace: payments-prod")
+	assert.Contains(t, payments, "name: gotk-reconciler\n  namespace: payments-prod")
+	assert.Contains(t, payments, "kind: ClusterRole\n  name: admin")
+	assert.Contains(t, payments, "url: https://github.com/org/plat.git")
+	assert.Contains(t, payments, "serviceAccountName: payments")
+	assert.Contains(t, payments, "path: ./tenants/payments/prod")
+	assert.Contains(t, payments, "targetNamespace: payments-prod")
+	assert.NotContains(t, payments, "kind: Namespace", "the infrastructure scope creates the tenant namespaces")
+	assert.FileExists(t, filepath.Join(tmpDir, "plat/tenants/payments/prod/kustomization.yaml"))
+
+	search := readGenerated(t, tmpDir, "plat/flux/tenants/search/dev.yaml")
+	assert.Contains(t, search, "name: gotk-reconciler\n  namespace: search-api-dev")
+	assert.Contains(t, search, "name: gotk-reconciler\n  namespace: search-workers-dev")
+	assert.Contains(t, search, "    name: search\n    namespace: search-api-dev")
+	assert.Contains(t, search, "url: https://github.com/org/search.git")
+	assert.NotContains(t, search, "targetNamespace:", "a tenant with several namespaces sets them itself")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat/tenants/search/dev/kustomization.yaml"))
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "plat/argocd/projects/tenant-payments.yaml"))
+}
+
+func TestGenerator_FluxTenantNamespaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newTenantTestConfig()
+	cfg.GitOpsTool = "flux"
+	cfg.Scope = "application"
+	cfg.Apps = []config.Application{{Name: "web", Image: "nginx:1.27"}}
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	payments := readGenerated(t, tmpDir, "plat/flux/tenants/payments/dev.yaml")
+	assert.Contains(t, payments, "kind: Namespace\nmetadata:\n  name: payments-dev")
+	assert.Contains(t, payments, "kind: ResourceQuota")
+	assert.Contains(t, payments, "name: payments-admin")
+	assert.Contains(t, payments, "kind: Kustomization")
+}
+
+func TestGenerator_FluxLockdown(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newTenantTestConfig()
+	cfg.GitOpsTool = "flux"
+	cfg.Apps = []config.Application{{Name: "web", Image: "nginx:1.27"}}
+	cfg.Flux.HelmReleases = true
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/flux/kustomizations/infra-dev.yaml"), "serviceAccountName: kustomize-controller")
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/flux/kustomizations/apps-dev.yaml"), "serviceAccountName: kustomize-controller")
+	assert.Contains(t, readGenerated(t, tmpDir, "plat/applications/helmreleases/dev/web.yaml"), "serviceAccountName: helm-controller")
+
+	disabled := false
+	cfg.Flux.Lockdown = &disabled
+	tmpDir = t.TempDir()
+	require.NoError(t, New(cfg, output.New(tmpDir, false, false), false).Generate())
+	assert.NotContains(t, readGenerated(t, tmpDir, "plat/flux/kustomizations/infra-dev.yaml"), "serviceAccountName")
+	assert.FileExists(t, filepath.Join(tmpDir, "plat/flux/tenants/payments/dev.yaml"))
+}
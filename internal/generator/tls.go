@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// letsEncryptServers are the ACME servers of the generated ClusterIssuers.
+var letsEncryptServers = []struct{ Issuer, Server string }{
+	{"letsencrypt-staging", "https://acme-staging-v02.api.letsencrypt.org/directory"},
+	{"letsencrypt-prod", "https://acme-v02.api.letsencrypt.org/directory"},
+}
+
+// dns01Secrets are the Secret name and key holding the credential of the
+// DNS providers that read one.
+var dns01Secrets = map[string][2]string{
+	"route53":    {"route53-credentials", "secret-access-key"},
+	"cloudflare": {"cloudflare-api-token", "api-token"},
+	"clouddns":   {"clouddns-credentials", "key.json"},
+}
+
+// dns01Solver is the DNS provider configuration of the ClusterIssuers and
+// the credential Secret it reads.
+type dns01Solver struct {
+	Route53    *route53Solver
+	Cloudflare bool
+	CloudDNS   *config.DNS01Config
+	AzureDNS   *azureDNSSolver
+	SecretName string
+	SecretKey  string
+	// SecretValue is the credential, empty when it is set after generation
+	SecretValue string
+}
+
+type route53Solver struct {
+	Region       string
+	HostedZoneID string
+	Role         string
+	AccessKeyID  string
+}
+
+type azureDNSSolver struct {
+	config.DNS01Config
+	ClientID string
+}
+
+// tlsSecretPath returns the path of the DNS01 credential Secret, relative to
+// the project root.
+func (g *Generator) tlsSecretPath() string {
+	name := "cert-manager-dns01-secret.yaml"
+	if g.Config.Secrets.Format == "sops" {
+		name = "cert-manager-dns01-secret.sops.yaml"
+	}
+	return fmt.Sprintf("bootstrap/%s/%s", g.Config.GitOpsTool, name)
+}
+
+// generateTLS writes the Let's Encrypt ClusterIssuers into
+// infrastructure/base/tls and the Secret of the DNS01 credential into the
+// bootstrap directory.
+func (g *Generator) generateTLS() error {
+	tls := g.Config.TLS
+	var solver dns01Solver
+	if tls.Solver == config.TLSSolverDNS01 {
+		var err error
+		if solver, err = g.dns01Solver(); err != nil {
+			return err
+		}
+	}
+
+	var docs [][]byte
+	for _, server := range letsEncryptServers {
+		content, err := templates.Render("infrastructure/cluster-issuer.yaml.tmpl", map[string]any{
+			"Name":         server.Issuer,
+			"Email":        tls.Email,
+			"Server":       server.Server,
+			"Solver":       tls.Solver,
+			"IngressClass": tls.IngressClass,
+			"Route53":      solver.Route53,
+			"Cloudflare":   solver.Cloudflare,
+			"CloudDNS":     solver.CloudDNS,
+			"AzureDNS":     solver.AzureDNS,
+			"SecretName":   solver.SecretName,
+			"SecretKey":    solver.SecretKey,
+		})
+		if err != nil {
+			return err
+		}
+		docs = append(docs, bytes.TrimSpace(content))
+	}
+	path := g.Config.Project.Name + "/infrastructure/base/tls/cluster-issuers.yaml"
+	if err := g.Writer.WriteFile(path, joinDocs(docs)); err != nil {
+		return err
+	}
+	if err := g.generateSubdirKustomization("tls", []string{"cluster-issuers.yaml"}); err != nil {
+		return err
+	}
+
+	if solver.SecretName == "" {
+		return nil
+	}
+	secret, err := templates.Render("secrets/dns01-secret.yaml.tmpl", map[string]any{
+		"Name":     solver.SecretName,
+		"Key":      solver.SecretKey,
+		"Value":    solver.SecretValue,
+		"Provider": tls.DNS01.Provider,
+		"Sops":     g.Config.Secrets.Format == "sops",
+		"File":     g.tlsSecretPath(),
+	})
+	if err != nil {
+		return err
+	}
+	return g.Writer.WriteFile(g.Config.Project.Name+"/"+g.tlsSecretPath(), secret)
+}
+
+// dns01Solver returns the DNS01 solver of the tls block, reading the provider
+// credential from the auth store. Without a credential, route53 and azuredns
+// use the identity of cert-manager and the Secret of the other providers is
+// written for the credential to be filled in.
+func (g *Generator) dns01Solver() (dns01Solver, error) {
+	dns := g.Config.TLS.DNS01
+	var cred *auth.Credential
+	if dns.Credential != "" && g.Credentials != nil {
+		ctx := g.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		var err error
+		if cred, err = g.Credentials(ctx, dns.Credential); err != nil {
+			return dns01Solver{}, fmt.Errorf("failed to load DNS01 credential %s: %w", dns.Credential, err)
+		}
+	}
+	if cred == nil {
+		cred = &auth.Credential{}
+	}
+
+	var solver dns01Solver
+	switch dns.Provider {
+	case "route53":
+		region := dns.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		solver.Route53 = &route53Solver{
+			Region:       region,
+			HostedZoneID: dns.HostedZoneID,
+			Role:         cred.Data.AWSRoleARN,
+			AccessKeyID:  cred.Data.Username,
+		}
+		if cred.Data.Username == "" {
+			return solver, nil
+		}
+		solver.SecretValue = cred.Data.Password
+	case "cloudflare":
+		solver.Cloudflare = true
+		solver.SecretValue = cred.Data.Token
+	case "clouddns":
+		solver.CloudDNS = &dns
+		solver.SecretValue = cred.Data.Token
+	case "azuredns":
+		solver.AzureDNS = &azureDNSSolver{DNS01Config: dns, ClientID: cred.Data.AzureClientID}
+		return solver, nil
+	}
+	secret := dns01Secrets[dns.Provider]
+	solver.SecretName, solver.SecretKey = secret[0], secret[1]
+	return solver, nil
+}
+
+// writeWildcardCertificates writes the wildcard Certificates of an
+// environment into its infrastructure overlay and returns the file name, or
+// an empty string without wildcards.
+func (g *Generator) writeWildcardCertificates(env config.Environment) (string, error) {
+	tls := g.Config.TLS
+	if !tls.Enabled() || len(tls.Wildcards) == 0 {
+		return "", nil
+	}
+	var docs [][]byte
+	for _, wildcard := range tls.Wildcards {
+		domain := strings.ReplaceAll(wildcard, "{env}", env.Name)
+		content, err := templates.Render("infrastructure/certificate.yaml.tmpl", map[string]string{
+			"Name":      wildcardCertificateName(domain),
+			"Namespace": g.Config.GetEnvironmentNamespace(env.Name),
+			"Env":       env.Name,
+			"Domain":    domain,
+			"Issuer":    tls.Issuer(),
+		})
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, bytes.TrimSpace(content))
+	}
+	file := "certificates.yaml"
+	path := fmt.Sprintf("%s/infrastructure/overlays/%s/%s", g.Config.Project.Name, env.Name, file)
+	return file, g.Writer.WriteFile(path, joinDocs(docs))
+}
+
+// wildcardCertificateName returns the Certificate name of a wildcard
+// domain: *.apps.example.com is wildcard-apps-example-com.
+func wildcardCertificateName(domain string) string {
+	return "wildcard-" + strings.ReplaceAll(strings.TrimPrefix(domain, "*."), ".", "-")
+}
+
+// bootstrapTLSStep returns the bootstrap script step applying the DNS01
+// credential Secret. Route 53 without an access key has no Secret, so the
+// step checks that the file exists.
+func (g *Generator) bootstrapTLSStep() string {
+	tls := g.Config.TLS
+	if !tls.Enabled() || tls.Solver != config.TLSSolverDNS01 {
+		return ""
+	}
+	if _, ok := dns01Secrets[tls.DNS01.Provider]; !ok {
+		return ""
+	}
+	path := g.tlsSecretPath()
+	apply := "kubectl apply -f " + path
+	if g.Config.Secrets.Format == "sops" {
+		apply = fmt.Sprintf("sops --decrypt %s | kubectl apply -f -", path)
+	}
+	return fmt.Sprintf(`
+# Apply the cert-manager DNS01 credential
+if [ -f %s ]; then
+  kubectl create namespace cert-manager --dry-run=client -o yaml | kubectl apply -f -
+  %s
+fi
+`, path, apply)
+}
@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func newTLSTestConfig() *config.Config {
+	return &config.Config{
+		Project:    config.Project{Name: "shop"},
+		Platform:   "kubernetes",
+		Scope:      "both",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/org/shop.git"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "prod"},
+		},
+		TLS: config.TLSConfig{Email: "ops@example.com", IngressClass: "nginx"},
+		Apps: []config.Application{
+			{
+				Name:    "api",
+				Image:   "api:1.0",
+				Port:    8080,
+				Ingress: &config.AppIngress{Host: "api.example.com", TLS: true},
+			},
+			{
+				Name:    "web",
+				Image:   "web:1.0",
+				Port:    80,
+				Ingress: &config.AppIngress{Host: "web.example.com"},
+			},
+		},
+	}
+}
+
+func TestGenerator_TLSHTTP01(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := New(newTLSTestConfig(), output.New(tmpDir, false, false), false)
+
+	require.NoError(t, gen.Generate())
+
+	issuers := readGenerated(t, tmpDir, "shop/infrastructure/base/tls/cluster-issuers.yaml")
+	assert.Contains(t, issuers, "name: letsencrypt-staging")
+	assert.Contains(t, issuers, "server: https://acme-staging-v02.api.letsencrypt.org/directory")
+	assert.Contains(t, issuers, "name: letsencrypt-prod")
+	assert.Contains(t, issuers, "server: https://acme-v02.api.letsencrypt.org/directory")
+	assert.Contains(t, issuers, "email: ops@example.com")
+	assert.Contains(t, issuers, "- http01:\n          ingress:\n            ingressClassName: nginx")
+
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/base/tls/kustomization.yaml"), "cluster-issuers.yaml")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/base/kustomization.yaml"), "tls/")
+	assert.NoFileExists(t, tmpDir+"/shop/infrastructure/overlays/dev/certificates.yaml")
+
+	api := readGenerated(t, tmpDir, "shop/applications/base/api/ingress.yaml")
+	assert.Contains(t, api, "cert-manager.io/cluster-issuer: letsencrypt-prod")
+	assert.Contains(t, api, "secretName: api-tls")
+	web := readGenerated(t, tmpDir, "shop/applications/base/web/ingress.yaml")
+	assert.NotContains(t, web, "cert-manager.io", "ingresses without tls are not annotated")
+}
+
+func TestGenerator_TLSDNS01(t *testing.T) {
+	cfg := newTLSTestConfig()
+	cfg.TLS.Solver = config.TLSSolverDNS01
+	cfg.TLS.Staging = true
+	cfg.TLS.DNS01 = config.DNS01Config{Provider: "route53", Credential: "route53", HostedZoneID: "Z123"}
+	cfg.TLS.Wildcards = []string{"*.{env}.example.com"}
+
+	tmpDir := t.TempDir()
+	gen := New(cfg, output.New(tmpDir, false, false), false)
+	gen.Credentials = func(_ context.Context, name string) (*auth.Credential, error) {
+		require.Equal(t, "route53", name)
+		return &auth.Credential{Data: auth.CredentialData{Username: "AKIAEXAMPLE", Password: "s3cret"}}, nil
+	}
+
+	require.NoError(t, gen.Generate())
+
+	issuers := readGenerated(t, tmpDir, "shop/infrastructure/base/tls/cluster-issuers.yaml")
+	assert.Contains(t, issuers, "route53:\n            region: us-east-1\n            hostedZoneID: Z123")
+	assert.Contains(t, issuers, "accessKeyID: AKIAEXAMPLE\n            secretAccessKeySecretRef:\n              name: route53-credentials\n              key: secret-access-key")
+
+	secret := readGenerated(t, tmpDir, "shop/bootstrap/argocd/cert-manager-dns01-secret.yaml")
+	assert.Contains(t, secret, "namespace: cert-manager")
+	assert.Contains(t, secret, `secret-access-key: "s3cret"`)
+	assert.NotContains(t, secret, "# Set")
+
+	dev := readGenerated(t, tmpDir, "shop/infrastructure/overlays/dev/certificates.yaml")
+	assert.Contains(t, dev, "name: wildcard-dev-example-com")
+	assert.Contains(t, dev, "namespace: shop-dev")
+	assert.Contains(t, dev, `- "*.dev.example.com"`)
+	assert.Contains(t, dev, "name: letsencrypt-staging")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/overlays/prod/certificates.yaml"), `- "*.prod.example.com"`)
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/infrastructure/overlays/prod/kustomization.yaml"), "certificates.yaml")
+
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/applications/base/api/ingress.yaml"), "cert-manager.io/cluster-issuer: letsencrypt-staging")
+	assert.Contains(t, readGenerated(t, tmpDir, "shop/scripts/bootstrap.sh"), "kubectl apply -f bootstrap/argocd/cert-manager-dns01-secret.yaml")
+}
+
+func TestGenerator_TLSDNS01WithoutCredential(t *testing.T) {
+	tests := []struct {
+		provider string
+		dns      config.DNS01Config
+		contains string
+		secret   bool
+		step     bool
+	}{
+		{"cloudflare", config.DNS01Config{}, "cloudflare:\n            apiTokenSecretRef:\n              name: cloudflare-api-token", true, true},
+		{"clouddns", config.DNS01Config{Project: "shop-dns"}, "cloudDNS:\n            project: shop-dns\n            serviceAccountSecretRef:", true, true},
+		{"route53", config.DNS01Config{}, "route53:\n            region: us-east-1\n", false, true},
+		{"azuredns", config.DNS01Config{SubscriptionID: "sub", ResourceGroup: "dns", HostedZone: "example.com"}, "azureDNS:\n            subscriptionID: sub", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			cfg := newTLSTestConfig()
+			cfg.Secrets.Format = "sops"
+			cfg.TLS.Solver = config.TLSSolverDNS01
+			cfg.TLS.DNS01 = tt.dns
+			cfg.TLS.DNS01.Provider = tt.provider
+
+			tmpDir := t.TempDir()
+			require.NoError(t, New(cfg, output.New(tmpDir, false, false), false).Generate())
+
+			issuers := readGenerated(t, tmpDir, "shop/infrastructure/base/tls/cluster-issuers.yaml")
+			assert.Contains(t, issuers, tt.contains)
+			assert.NotContains(t, issuers, "accessKeyID")
+
+			path := tmpDir + "/shop/bootstrap/argocd/cert-manager-dns01-secret.sops.yaml"
+			script := readGenerated(t, tmpDir, "shop/scripts/bootstrap.sh")
+			if tt.step {
+				assert.Contains(t, script, "sops --decrypt bootstrap/argocd/cert-manager-dns01-secret.sops.yaml | kubectl apply -f -")
+			} else {
+				assert.NotContains(t, script, "cert-manager-dns01-secret")
+			}
+			if !tt.secret {
+				assert.NoFileExists(t, path)
+				return
+			}
+			secret := readGenerated(t, tmpDir, "shop/bootstrap/argocd/cert-manager-dns01-secret.sops.yaml")
+			assert.Contains(t, secret, "# Encrypt before committing")
+			assert.Contains(t, secret, fmt.Sprintf("# Set %s to the %s credential", dns01Secrets[tt.provider][1], tt.provider))
+		})
+	}
+}
+
+func TestGenerator_TLSCredentialError(t *testing.T) {
+	cfg := newTLSTestConfig()
+	cfg.TLS.Solver = config.TLSSolverDNS01
+	cfg.TLS.DNS01 = config.DNS01Config{Provider: "cloudflare", Credential: "missing"}
+
+	gen := New(cfg, output.New(t.TempDir(), false, false), false)
+	gen.Credentials = func(context.Context, string) (*auth.Credential, error) {
+		return nil, fmt.Errorf("credential not found")
+	}
+
+	err := gen.Generate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load DNS01 credential missing")
+}
+
+func TestGenerator_TLSRouteAnnotations(t *testing.T) {
+	cfg := newTLSTestConfig()
+	cfg.Platform = "openshift"
+
+	tmpDir := t.TempDir()
+	require.NoError(t, New(cfg, output.New(tmpDir, false, false), false).Generate())
+
+	route := readGenerated(t, tmpDir, "shop/applications/base/api/route.yaml")
+	assert.Contains(t, route, "cert-manager.io/issuer-kind: ClusterIssuer")
+	assert.Contains(t, route, "cert-manager.io/issuer-name: letsencrypt-prod")
+}
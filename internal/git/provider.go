@@ -279,6 +279,12 @@ func ParseGitURL(gitURL string) (*ParsedURL, error) {
 	return nil, fmt.Errorf("unable to parse git URL: %s", gitURL)
 }
 
+// ProviderForHost detects the provider from a Git host name. Hosts that do
+// not name a provider are reported as ProviderGeneric.
+func ProviderForHost(host string) ProviderType {
+	return detectProviderFromHost(host)
+}
+
 func detectProviderFromHost(host string) ProviderType {
 	host = strings.ToLower(host)
 
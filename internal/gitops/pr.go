@@ -0,0 +1,130 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+)
+
+// ErrNoChanges is returned by OpenPullRequest when there is nothing to review.
+var ErrNoChanges = errors.New("no changes to deliver")
+
+// PullRequestCreator opens pull requests on a Git provider. It is satisfied
+// by gitprovider.Provider.
+type PullRequestCreator interface {
+	CreatePullRequest(ctx context.Context, opts *gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error)
+}
+
+// OpenPullRequest commits the working tree to a new branch started from
+// pr.Base, pushes it and opens a pull request. pr.Head defaults to
+// gitopsi/<timestamp>, and the title and body to the commit message and a
+// summary of the changes. Nothing is pushed to the base branch, so
+// protected-branch workflows are honored. When opts.Dir is a repository on a
+// branch, that branch is checked out again once the commit is pushed.
+func OpenPullRequest(ctx context.Context, opts *PushOptions, creator PullRequestCreator, pr *gitprovider.PullRequestOptions) (*PushResult, *gitprovider.PullRequest, error) {
+	if pr.Base == "" {
+		pr.Base = "main"
+	}
+	if pr.Head == "" {
+		pr.Head = "gitopsi/" + time.Now().UTC().Format("20060102-150405")
+	}
+	if pr.Head == pr.Base {
+		return nil, nil, fmt.Errorf("pull request branch must differ from the base branch %s", pr.Base)
+	}
+
+	opts.Branch = pr.Head
+	opts.BaseBranch = pr.Base
+	original := currentBranch(opts.Dir)
+	result, err := NewPusher(opts).Push(ctx)
+	if original != "" {
+		// Keep the working tree when the push failed so no change is lost.
+		if restoreErr := restoreBranch(opts.Dir, original, err != nil); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !result.Committed && result.UpToDate {
+		return result, nil, ErrNoChanges
+	}
+
+	if pr.Title == "" {
+		pr.Title = result.Message
+	}
+	if pr.Body == "" {
+		pr.Body = Summary(result.Changes)
+	}
+	created, err := creator.CreatePullRequest(ctx, pr)
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to open pull request: %w", err)
+	}
+	return result, created, nil
+}
+
+// currentBranch returns the branch checked out in dir, or "" when dir is not
+// a repository or HEAD is detached or has no commits.
+func currentBranch(dir string) plumbing.ReferenceName {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return ""
+	}
+	head, err := repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return ""
+	}
+	return head.Name()
+}
+
+// restoreBranch checks out branch in dir, keeping the working tree and index
+// when keep is set.
+func restoreBranch(dir string, branch plumbing.ReferenceName, keep bool) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err == nil && head.Name() == branch {
+		return nil
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: branch, Keep: keep}); err != nil {
+		return fmt.Errorf("failed to check out %s again: %w", branch.Short(), err)
+	}
+	return nil
+}
+
+// maxSummaryFiles caps the file list in generated pull request descriptions.
+const maxSummaryFiles = 50
+
+// Summary renders a Markdown description of the changed files.
+func Summary(changes []Change) string {
+	counts := make(map[ChangeType]int)
+	for _, c := range changes {
+		counts[c.Type]++
+	}
+
+	var b strings.Builder
+	b.WriteString("Generated by gitopsi.\n\n")
+	fmt.Fprintf(&b, "**%d added, %d modified, %d deleted**\n\n", counts[ChangeAdded], counts[ChangeModified], counts[ChangeDeleted])
+	b.WriteString("<details>\n<summary>Changed files</summary>\n\n")
+	for i, c := range changes {
+		if i == maxSummaryFiles {
+			fmt.Fprintf(&b, "- … and %d more\n", len(changes)-maxSummaryFiles)
+			break
+		}
+		fmt.Fprintf(&b, "- `%s` %s\n", c.Path, c.Type)
+	}
+	b.WriteString("\n</details>\n")
+	return b.String()
+}
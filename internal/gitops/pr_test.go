@@ -0,0 +1,166 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+)
+
+type fakeCreator struct {
+	opts *gitprovider.PullRequestOptions
+}
+
+func (f *fakeCreator) CreatePullRequest(_ context.Context, opts *gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error) {
+	f.opts = opts
+	return &gitprovider.PullRequest{Number: 1, URL: "https://example.com/pr/1"}, nil
+}
+
+func TestOpenPullRequest(t *testing.T) {
+	remote := newRemote(t)
+	seed := t.TempDir()
+	writeFile(t, seed, "README.md", "# platform\n")
+	writeFile(t, seed, "apps/web.yaml", "replicas: 1\n")
+	if _, err := NewPusher(&PushOptions{Dir: seed, RemoteURL: remote}).Push(context.Background()); err != nil {
+		t.Fatalf("seed Push() error = %v", err)
+	}
+	base := remoteBranch(t, remote, "main").Hash()
+
+	dir := t.TempDir()
+	writeFile(t, dir, "apps/web.yaml", "replicas: 3\n")
+	writeFile(t, dir, "apps/api.yaml", "replicas: 1\n")
+
+	creator := &fakeCreator{}
+	pr := &gitprovider.PullRequestOptions{Head: "gitopsi/update"}
+	result, created, err := OpenPullRequest(context.Background(), &PushOptions{Dir: dir, RemoteURL: remote, Project: "demo"}, creator, pr)
+	if err != nil {
+		t.Fatalf("OpenPullRequest() error = %v", err)
+	}
+	if created.Number != 1 {
+		t.Errorf("unexpected pull request %+v", created)
+	}
+	if remoteBranch(t, remote, "main").Hash() != base {
+		t.Error("base branch must not be pushed to")
+	}
+
+	head := remoteBranch(t, remote, "gitopsi/update").Hash()
+	if head.String() != result.Commit {
+		t.Errorf("head branch at %s, want %s", head, result.Commit)
+	}
+	repo, _ := git.PlainOpen(dir)
+	commit, _ := repo.CommitObject(head)
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != base {
+		t.Errorf("head commit should build on %s, parents %v", base, commit.ParentHashes)
+	}
+	tree, _ := commit.Tree()
+	if _, err := tree.File("README.md"); err != nil {
+		t.Error("files of the base branch should be kept")
+	}
+
+	want := []Change{{Path: "apps/api.yaml", Type: ChangeAdded}, {Path: "apps/web.yaml", Type: ChangeModified}}
+	if fmt.Sprint(result.Changes) != fmt.Sprint(want) {
+		t.Errorf("Changes = %v, want %v", result.Changes, want)
+	}
+	if creator.opts.Base != "main" || creator.opts.Title != "chore: Update demo GitOps manifests" {
+		t.Errorf("unexpected options %+v", creator.opts)
+	}
+	if !strings.Contains(creator.opts.Body, "1 added, 1 modified, 0 deleted") {
+		t.Errorf("body = %q", creator.opts.Body)
+	}
+
+	_, _, err = OpenPullRequest(context.Background(), &PushOptions{Dir: dir, RemoteURL: remote}, creator, &gitprovider.PullRequestOptions{Head: "gitopsi/update"})
+	if !errors.Is(err, ErrNoChanges) {
+		t.Errorf("expected ErrNoChanges, got %v", err)
+	}
+
+	_, _, err = OpenPullRequest(context.Background(), &PushOptions{Dir: t.TempDir(), RemoteURL: remote}, creator, &gitprovider.PullRequestOptions{Head: "main"})
+	if err == nil {
+		t.Error("expected error when head equals base")
+	}
+
+	other := t.TempDir()
+	writeFile(t, other, "a.yaml", "a: 1\n")
+	_, _, err = OpenPullRequest(context.Background(), &PushOptions{Dir: other, RemoteURL: remote}, creator, &gitprovider.PullRequestOptions{Base: "missing"})
+	if err == nil || !strings.Contains(err.Error(), "base branch missing does not exist") {
+		t.Errorf("expected missing base error, got %v", err)
+	}
+}
+
+func TestOpenPullRequest_ExistingRepository(t *testing.T) {
+	remote := newRemote(t)
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yaml", "a: 1\n")
+	if _, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote}).Push(context.Background()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	writeFile(t, dir, "b.yaml", "b: 1\n")
+	result, _, err := OpenPullRequest(context.Background(), &PushOptions{Dir: dir, RemoteURL: remote}, &fakeCreator{}, &gitprovider.PullRequestOptions{Head: "gitopsi/b"})
+	if err != nil {
+		t.Fatalf("OpenPullRequest() error = %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Path != "b.yaml" {
+		t.Errorf("unexpected changes %v", result.Changes)
+	}
+	repo, _ := git.PlainOpen(dir)
+	head, _ := repo.Head()
+	if head.Name() != plumbing.NewBranchReferenceName("main") {
+		t.Errorf("HEAD = %s, want main checked out again", head.Name())
+	}
+	branch, err := repo.Reference(plumbing.NewBranchReferenceName("gitopsi/b"), false)
+	if err != nil || branch.Hash().String() != result.Commit {
+		t.Errorf("gitopsi/b = %v, %v; want %s", branch, err, result.Commit)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.yaml")); !os.IsNotExist(err) {
+		t.Errorf("b.yaml should only exist on gitopsi/b, stat error = %v", err)
+	}
+}
+
+func TestOpenPullRequest_RestoresBranchOnFailure(t *testing.T) {
+	remote := newRemote(t)
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yaml", "a: 1\n")
+	if _, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote}).Push(context.Background()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	writeFile(t, dir, "a.yaml", "a: 2\n")
+	_, _, err := OpenPullRequest(context.Background(), &PushOptions{Dir: dir, RemoteURL: newRemote(t)}, &fakeCreator{}, &gitprovider.PullRequestOptions{Head: "gitopsi/a"})
+	if err == nil {
+		t.Fatal("expected error for a different remote")
+	}
+	repo, _ := git.PlainOpen(dir)
+	head, _ := repo.Head()
+	if head.Name() != plumbing.NewBranchReferenceName("main") {
+		t.Errorf("HEAD = %s, want main checked out again", head.Name())
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "a.yaml"))
+	if string(data) != "a: 2\n" {
+		t.Errorf("a.yaml = %q, uncommitted change should be kept", data)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	changes := make([]Change, 0, maxSummaryFiles+2)
+	for i := 0; i < maxSummaryFiles+2; i++ {
+		changes = append(changes, Change{Path: fmt.Sprintf("f%02d.yaml", i), Type: ChangeDeleted})
+	}
+	summary := Summary(changes)
+	if !strings.Contains(summary, "0 added, 0 modified, 52 deleted") {
+		t.Errorf("missing counts in %q", summary)
+	}
+	if !strings.Contains(summary, "- `f00.yaml` deleted") || strings.Contains(summary, "f51.yaml") {
+		t.Errorf("unexpected file list in %q", summary)
+	}
+	if !strings.Contains(summary, "and 2 more") {
+		t.Errorf("missing truncation note in %q", summary)
+	}
+}
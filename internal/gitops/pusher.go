@@ -0,0 +1,585 @@
+// Package gitops delivers generated repositories to Git remotes.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+)
+
+// DefaultCommitMessage is the commit message template used when none is set.
+const DefaultCommitMessage = `{{if .Initial}}feat: Initial GitOps repository structure{{else}}chore: Update {{.Project}} GitOps manifests{{end}}`
+
+// ErrRemoteDiverged is returned when the remote branch has commits that are
+// not in the local history and ForceWithLease is not set.
+var ErrRemoteDiverged = errors.New("remote branch has commits that are not in the local repository")
+
+// Credentials authenticate against the Git remote.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+	// SSHKey is the PEM-encoded private key.
+	SSHKey           string
+	SSHKeyPassphrase string
+}
+
+// CredentialsFromAuth converts a stored git credential. GitHub App
+// credentials are exchanged for an installation token.
+func CredentialsFromAuth(ctx context.Context, cred *auth.Credential) (*Credentials, error) {
+	if cred.Method == auth.MethodGitHubApp {
+		app, err := auth.GitHubAppFromCredential(cred)
+		if err != nil {
+			return nil, err
+		}
+		token, err := app.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Credentials{Username: "x-access-token", Token: token}, nil
+	}
+	return &Credentials{
+		Username: cred.Data.Username,
+		Password: cred.Data.Password,
+		Token:    cred.Data.Token,
+		SSHKey:   cred.Data.SSHPrivateKey,
+	}, nil
+}
+
+// PushOptions configures a Pusher.
+type PushOptions struct {
+	// Dir is the working tree to commit. It is initialized if it is not a
+	// repository yet.
+	Dir        string
+	RemoteURL  string
+	RemoteName string // default: origin
+	Branch     string // default: main
+	// BaseBranch is the remote branch a newly initialized repository builds
+	// on, so the pushed branch shares its history. Used for pull requests.
+	BaseBranch string
+	// CommitMessage is a text/template rendered with CommitData
+	// (default: DefaultCommitMessage).
+	CommitMessage string
+	Project       string
+	AuthorName    string
+	AuthorEmail   string
+	// ForceWithLease overwrites a diverged remote branch, but only if it
+	// still points at the commit observed before pushing.
+	ForceWithLease bool
+	Credentials    *Credentials
+}
+
+// CommitData is available to commit message templates.
+type CommitData struct {
+	Project string
+	Branch  string
+	// Initial is true for the first commit of the repository.
+	Initial bool
+	// Files is the number of added, modified and deleted files.
+	Files int
+	Date  string
+}
+
+// ChangeType classifies a committed file.
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeModified ChangeType = "modified"
+	ChangeDeleted  ChangeType = "deleted"
+)
+
+// Change is a file included in the commit.
+type Change struct {
+	Path string
+	Type ChangeType
+}
+
+// PushResult describes the outcome of Push.
+type PushResult struct {
+	Branch  string
+	Commit  string
+	Message string
+	// Changes lists the committed files, sorted by path.
+	Changes []Change
+	// Committed is false when the working tree had no changes.
+	Committed bool
+	// Forced is true when a diverged remote branch was overwritten.
+	Forced bool
+	// UpToDate is true when the remote already had the commit.
+	UpToDate bool
+}
+
+// Pusher commits a working tree and pushes it using go-git.
+type Pusher struct {
+	opts *PushOptions
+}
+
+// NewPusher creates a Pusher, applying defaults to opts.
+func NewPusher(opts *PushOptions) *Pusher {
+	if opts.RemoteName == "" {
+		opts.RemoteName = "origin"
+	}
+	if opts.Branch == "" {
+		opts.Branch = "main"
+	}
+	if opts.CommitMessage == "" {
+		opts.CommitMessage = DefaultCommitMessage
+	}
+	if opts.AuthorName == "" || opts.AuthorEmail == "" {
+		name, email := globalAuthor()
+		if opts.AuthorName == "" {
+			opts.AuthorName = name
+		}
+		if opts.AuthorEmail == "" {
+			opts.AuthorEmail = email
+		}
+	}
+	return &Pusher{opts: opts}
+}
+
+// Push initializes the repository if needed, commits all changes on the
+// configured branch and pushes it to the remote.
+func (p *Pusher) Push(ctx context.Context) (*PushResult, error) {
+	if p.opts.RemoteURL == "" {
+		return nil, fmt.Errorf("remote URL is required")
+	}
+	authMethod, err := AuthMethod(p.opts.RemoteURL, p.opts.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, created, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.ensureRemote(repo); err != nil {
+		return nil, err
+	}
+	if created && p.opts.BaseBranch != "" {
+		if err := p.startFromBase(ctx, repo, authMethod); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PushResult{Branch: p.opts.Branch}
+	head, err := p.commit(repo, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Commit = head.String()
+
+	remoteHash, err := p.fetch(ctx, repo, p.opts.Branch, authMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	branchRef := plumbing.NewBranchReferenceName(p.opts.Branch)
+	pushOpts := &git.PushOptions{
+		RemoteName:   p.opts.RemoteName,
+		RefSpecs:     []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("%s:%s", branchRef, branchRef))},
+		Auth:         authMethod,
+		ProxyOptions: network.GitProxy(p.opts.RemoteURL),
+	}
+	if !remoteHash.IsZero() && !isAncestor(repo, remoteHash, head) {
+		if !p.opts.ForceWithLease {
+			return nil, fmt.Errorf("%w: %s/%s is at %s", ErrRemoteDiverged, p.opts.RemoteName, p.opts.Branch, remoteHash.String()[:7])
+		}
+		pushOpts.Force = true
+		pushOpts.ForceWithLease = &git.ForceWithLease{RefName: branchRef, Hash: remoteHash}
+		result.Forced = true
+	}
+
+	if err := repo.PushContext(ctx, pushOpts); err != nil {
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			result.UpToDate = true
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to push %s: %w", p.opts.Branch, err)
+	}
+	return result, nil
+}
+
+// open opens the repository in Dir, initializing it when it does not exist.
+// The boolean reports whether the repository was created.
+func (p *Pusher) open() (*git.Repository, bool, error) {
+	repo, err := git.PlainOpen(p.opts.Dir)
+	if err == nil {
+		return repo, false, p.checkoutBranch(repo)
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, false, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	repo, err = git.PlainInitWithOptions(p.opts.Dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName(p.opts.Branch)},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to init repository: %w", err)
+	}
+	return repo, true, nil
+}
+
+// startFromBase fetches BaseBranch into a newly initialized repository and
+// points the push branch and index at it. Generated files in the working
+// tree take precedence over those of the base branch.
+func (p *Pusher) startFromBase(ctx context.Context, repo *git.Repository, authMethod transport.AuthMethod) error {
+	base, err := p.fetch(ctx, repo, p.opts.BaseBranch, authMethod)
+	if err != nil {
+		return err
+	}
+	if base.IsZero() {
+		return fmt.Errorf("base branch %s does not exist on %s", p.opts.BaseBranch, p.opts.RemoteURL)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(p.opts.Branch), base)); err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := wt.Reset(&git.ResetOptions{Commit: base, Mode: git.MixedReset}); err != nil {
+		return fmt.Errorf("failed to start from %s: %w", p.opts.BaseBranch, err)
+	}
+
+	// Check out the files of the base branch that were not generated so the
+	// commit does not delete them.
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	var missing []string
+	for path, st := range status {
+		if st.Worktree == git.Deleted {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := wt.Restore(&git.RestoreOptions{Staged: true, Worktree: true, Files: missing}); err != nil {
+		return fmt.Errorf("failed to check out files of %s: %w", p.opts.BaseBranch, err)
+	}
+	return nil
+}
+
+// checkoutBranch switches an existing repository to the push branch,
+// creating it from HEAD if needed. Uncommitted changes are kept.
+func (p *Pusher) checkoutBranch(repo *git.Repository) error {
+	branchRef := plumbing.NewBranchReferenceName(p.opts.Branch)
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	if head.Target() == branchRef {
+		return nil
+	}
+
+	resolved, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// No commits yet: point HEAD at the new branch.
+		return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchRef))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	_, err = repo.Reference(branchRef, false)
+	create := errors.Is(err, plumbing.ErrReferenceNotFound)
+	opts := &git.CheckoutOptions{Branch: branchRef, Create: create, Keep: true}
+	if create {
+		opts.Hash = resolved.Hash()
+	}
+	if err := wt.Checkout(opts); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", p.opts.Branch, err)
+	}
+	return nil
+}
+
+func (p *Pusher) ensureRemote(repo *git.Repository) error {
+	remote, err := repo.Remote(p.opts.RemoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: p.opts.RemoteName, URLs: []string{p.opts.RemoteURL}})
+		if err != nil {
+			return fmt.Errorf("failed to add remote %s: %w", p.opts.RemoteName, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read remote %s: %w", p.opts.RemoteName, err)
+	}
+	if urls := remote.Config().URLs; len(urls) == 0 || urls[0] != p.opts.RemoteURL {
+		return fmt.Errorf("remote %s points to %s, not %s", p.opts.RemoteName, strings.Join(urls, ", "), p.opts.RemoteURL)
+	}
+	return nil
+}
+
+// commit stages every change and commits it. When nothing changed the
+// current HEAD is returned.
+func (p *Pusher) commit(repo *git.Repository, result *PushResult) (plumbing.Hash, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	changes, err := stage(wt)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	head, headErr := repo.Head()
+	if len(changes) == 0 {
+		if headErr != nil {
+			return plumbing.ZeroHash, fmt.Errorf("nothing to commit in %s", p.opts.Dir)
+		}
+		return head.Hash(), nil
+	}
+
+	message, err := p.message(CommitData{
+		Project: p.opts.Project,
+		Branch:  p.opts.Branch,
+		Initial: headErr != nil,
+		Files:   len(changes),
+		Date:    time.Now().UTC().Format("2006-01-02"),
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	hash, err := wt.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: p.opts.AuthorName, Email: p.opts.AuthorEmail, When: time.Now()},
+	})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to commit: %w", err)
+	}
+	result.Committed = true
+	result.Message = message
+	result.Changes = changes
+	return hash, nil
+}
+
+// Commit stages every change of the repository containing dir and commits it
+// on the checked out branch without pushing. It returns ErrNoChanges when the
+// worktree is clean.
+func Commit(dir, message string) (*PushResult, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Git repository for %s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	changes, err := stage(wt)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, ErrNoChanges
+	}
+
+	name, email := globalAuthor()
+	hash, err := wt.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: name, Email: email, When: time.Now()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	result := &PushResult{Commit: hash.String(), Message: message, Changes: changes, Committed: true}
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		result.Branch = head.Name().Short()
+	}
+	return result, nil
+}
+
+// stage adds every new, modified and deleted file to the index and returns
+// the staged changes.
+func stage(wt *git.Worktree) ([]Change, error) {
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	for path, st := range status {
+		switch st.Worktree {
+		case git.Unmodified:
+		case git.Deleted:
+			if _, err := wt.Remove(path); err != nil {
+				return nil, fmt.Errorf("failed to stage %s: %w", path, err)
+			}
+		default:
+			if _, err := wt.Add(path); err != nil {
+				return nil, fmt.Errorf("failed to stage %s: %w", path, err)
+			}
+		}
+	}
+
+	status, err = wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	var changes []Change
+	for path, st := range status {
+		switch st.Staging {
+		case git.Added:
+			changes = append(changes, Change{Path: path, Type: ChangeAdded})
+		case git.Modified, git.Renamed, git.Copied:
+			changes = append(changes, Change{Path: path, Type: ChangeModified})
+		case git.Deleted:
+			changes = append(changes, Change{Path: path, Type: ChangeDeleted})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func (p *Pusher) message(data CommitData) (string, error) {
+	tmpl, err := template.New("commit").Option("missingkey=error").Parse(p.opts.CommitMessage)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render commit message: %w", err)
+	}
+	message := strings.TrimSpace(buf.String())
+	if message == "" {
+		return "", fmt.Errorf("commit message is empty")
+	}
+	return message, nil
+}
+
+// fetch fetches a remote branch into its remote-tracking ref and returns
+// the commit it points at, or the zero hash if the branch or the repository
+// is empty.
+func (p *Pusher) fetch(ctx context.Context, repo *git.Repository, branch string, authMethod transport.AuthMethod) (plumbing.Hash, error) {
+	branchRef := plumbing.NewBranchReferenceName(branch)
+	trackingRef := plumbing.NewRemoteReferenceName(p.opts.RemoteName, branch)
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:   p.opts.RemoteName,
+		RefSpecs:     []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("+%s:%s", branchRef, trackingRef))},
+		Auth:         authMethod,
+		ProxyOptions: network.GitProxy(p.opts.RemoteURL),
+	})
+	var noMatch git.NoMatchingRefSpecError
+	switch {
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+	case errors.Is(err, transport.ErrEmptyRemoteRepository), errors.As(err, &noMatch):
+		return plumbing.ZeroHash, nil
+	default:
+		return plumbing.ZeroHash, fmt.Errorf("failed to fetch %s/%s: %w", p.opts.RemoteName, branch, err)
+	}
+
+	ref, err := repo.Reference(trackingRef, true)
+	if err != nil {
+		return plumbing.ZeroHash, nil
+	}
+	return ref.Hash(), nil
+}
+
+// CheckAccess verifies that the remote can be read with creds.
+func CheckAccess(ctx context.Context, remoteURL string, creds *Credentials) error {
+	authMethod, err := AuthMethod(remoteURL, creds)
+	if err != nil {
+		return err
+	}
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteURL}})
+	if _, err := remote.ListContext(ctx, &git.ListOptions{Auth: authMethod, ProxyOptions: network.GitProxy(remoteURL)}); err != nil && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return fmt.Errorf("failed to access %s: %w", remoteURL, err)
+	}
+	return nil
+}
+
+// AuthMethod returns the go-git authentication of creds for remoteURL: an SSH
+// key for SSH remotes, a token or username and password for HTTP remotes.
+func AuthMethod(remoteURL string, c *Credentials) (transport.AuthMethod, error) {
+	if c == nil {
+		return nil, nil
+	}
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote URL: %w", err)
+	}
+
+	switch endpoint.Protocol {
+	case "ssh":
+		if c.SSHKey == "" {
+			return nil, nil
+		}
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		keys, err := ssh.NewPublicKeys(user, []byte(c.SSHKey), c.SSHKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key: %w", err)
+		}
+		return keys, nil
+	case "http", "https":
+		switch {
+		case c.Token != "":
+			user := c.Username
+			if user == "" {
+				// Providers accept any non-empty username with a token.
+				user = "git"
+			}
+			return &http.BasicAuth{Username: user, Password: c.Token}, nil
+		case c.Username != "":
+			return &http.BasicAuth{Username: c.Username, Password: c.Password}, nil
+		}
+	}
+	return nil, nil
+}
+
+// isAncestor reports whether ancestor is in the history of head.
+func isAncestor(repo *git.Repository, ancestor, head plumbing.Hash) bool {
+	if ancestor == head {
+		return true
+	}
+	a, err := repo.CommitObject(ancestor)
+	if err != nil {
+		return false
+	}
+	h, err := repo.CommitObject(head)
+	if err != nil {
+		return false
+	}
+	ok, err := a.IsAncestor(h)
+	return err == nil && ok
+}
+
+// globalAuthor returns user.name and user.email from the global git config,
+// falling back to a gitopsi identity.
+func globalAuthor() (string, string) {
+	name, email := "gitopsi", "gitopsi@localhost"
+	cfg, err := gitconfig.LoadConfig(gitconfig.GlobalScope)
+	if err != nil {
+		return name, email
+	}
+	if cfg.User.Name != "" {
+		name = cfg.User.Name
+	}
+	if cfg.User.Email != "" {
+		email = cfg.User.Email
+	}
+	return name, email
+}
@@ -0,0 +1,303 @@
+package gitops
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+)
+
+func newRemote(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatalf("failed to init remote: %v", err)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remoteBranch(t *testing.T, remote, branch string) *plumbing.Reference {
+	t.Helper()
+	repo, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("remote branch %s not found: %v", branch, err)
+	}
+	return ref
+}
+
+func TestPusher_InitialPush(t *testing.T) {
+	remote := newRemote(t)
+	dir := t.TempDir()
+	writeFile(t, dir, "argocd/projects/apps.yaml", "kind: AppProject\n")
+
+	p := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote, Branch: "gitops", Project: "demo", AuthorName: "Test", AuthorEmail: "test@example.com"})
+	result, err := p.Push(context.Background())
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if !result.Committed || result.Forced || result.Branch != "gitops" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	ref := remoteBranch(t, remote, "gitops")
+	if ref.Hash().String() != result.Commit {
+		t.Errorf("remote at %s, want %s", ref.Hash(), result.Commit)
+	}
+
+	repo, _ := git.PlainOpen(dir)
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "feat: Initial GitOps repository structure" {
+		t.Errorf("message = %q", commit.Message)
+	}
+	if commit.Author.Email != "test@example.com" {
+		t.Errorf("author = %s", commit.Author.Email)
+	}
+}
+
+func TestPusher_UpdateAndUpToDate(t *testing.T) {
+	remote := newRemote(t)
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yaml", "a: 1\n")
+
+	opts := &PushOptions{Dir: dir, RemoteURL: remote, Project: "demo", CommitMessage: "{{.Project}}: {{.Files}} file(s) on {{.Branch}}"}
+	if _, err := NewPusher(opts).Push(context.Background()); err != nil {
+		t.Fatalf("first Push() error = %v", err)
+	}
+
+	result, err := NewPusher(opts).Push(context.Background())
+	if err != nil {
+		t.Fatalf("second Push() error = %v", err)
+	}
+	if result.Committed || !result.UpToDate {
+		t.Errorf("expected an up-to-date no-op, got %+v", result)
+	}
+
+	writeFile(t, dir, "a.yaml", "a: 2\n")
+	writeFile(t, dir, "b.yaml", "b: 1\n")
+	result, err = NewPusher(opts).Push(context.Background())
+	if err != nil {
+		t.Fatalf("third Push() error = %v", err)
+	}
+	repo, _ := git.PlainOpen(dir)
+	commit, _ := repo.CommitObject(plumbing.NewHash(result.Commit))
+	if commit.Message != "demo: 2 file(s) on main" {
+		t.Errorf("message = %q", commit.Message)
+	}
+	if remoteBranch(t, remote, "main").Hash().String() != result.Commit {
+		t.Error("remote main was not updated")
+	}
+}
+
+func TestCommit(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "apps/overlays/prod/kustomization.yaml", "images: []\n")
+
+	result, err := Commit(filepath.Join(dir, "apps"), "chore: Roll back")
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if !result.Committed || result.Branch != "master" || len(result.Changes) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Changes[0] != (Change{Path: "apps/overlays/prod/kustomization.yaml", Type: ChangeAdded}) {
+		t.Errorf("changes = %+v", result.Changes)
+	}
+
+	if _, err := Commit(dir, "chore: Nothing"); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Commit() on a clean worktree error = %v, want ErrNoChanges", err)
+	}
+	if _, err := Commit(t.TempDir(), "chore: Nothing"); err == nil {
+		t.Error("Commit() outside a repository should fail")
+	}
+}
+
+func TestPusher_ForceWithLease(t *testing.T) {
+	remote := newRemote(t)
+
+	other := t.TempDir()
+	writeFile(t, other, "other.yaml", "x: 1\n")
+	if _, err := NewPusher(&PushOptions{Dir: other, RemoteURL: remote}).Push(context.Background()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, dir, "mine.yaml", "y: 1\n")
+	_, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote}).Push(context.Background())
+	if !errors.Is(err, ErrRemoteDiverged) {
+		t.Fatalf("expected ErrRemoteDiverged, got %v", err)
+	}
+
+	result, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote, ForceWithLease: true}).Push(context.Background())
+	if err != nil {
+		t.Fatalf("forced Push() error = %v", err)
+	}
+	if !result.Forced {
+		t.Error("expected Forced")
+	}
+	if remoteBranch(t, remote, "main").Hash().String() != result.Commit {
+		t.Error("remote main was not overwritten")
+	}
+}
+
+func TestPusher_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewPusher(&PushOptions{Dir: dir}).Push(context.Background()); err == nil {
+		t.Error("expected error without remote URL")
+	}
+
+	remote := newRemote(t)
+	if _, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote}).Push(context.Background()); err == nil {
+		t.Error("expected error for an empty working tree")
+	}
+
+	writeFile(t, dir, "a.yaml", "a: 1\n")
+	if _, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: remote, CommitMessage: "{{.Missing}}"}).Push(context.Background()); err == nil {
+		t.Error("expected error for an invalid commit message template")
+	}
+
+	if _, err := NewPusher(&PushOptions{Dir: dir, RemoteURL: "/elsewhere.git"}).Push(context.Background()); err == nil {
+		t.Error("expected error when origin points elsewhere")
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	remote := newRemote(t)
+	if err := CheckAccess(context.Background(), remote, nil); err != nil {
+		t.Errorf("CheckAccess() on an empty remote error = %v", err)
+	}
+	if err := CheckAccess(context.Background(), filepath.Join(t.TempDir(), "missing.git"), nil); err == nil {
+		t.Error("expected error for a missing remote")
+	}
+}
+
+func TestCheckAccess_SSHProxy(t *testing.T) {
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	dialed := make(chan struct{}, 1)
+	go func() {
+		conn, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		dialed <- struct{}{}
+		conn.Close()
+	}()
+
+	transport := nethttp.DefaultTransport.(*nethttp.Transport)
+	httpProxy, tlsConfig := transport.Proxy, transport.TLSClientConfig
+	t.Cleanup(func() {
+		_ = network.Configure(network.Options{})
+		transport.Proxy, transport.TLSClientConfig = httpProxy, tlsConfig
+	})
+	if err := network.Configure(network.Options{SSHProxy: "socks5://" + proxy.Addr().String()}); err != nil {
+		t.Fatal(err)
+	}
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	writeFile(t, filepath.Dir(knownHosts), "known_hosts", "")
+	t.Setenv("SSH_KNOWN_HOSTS", knownHosts)
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	creds := &Credentials{SSHKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := CheckAccess(ctx, "ssh://git@git.example.invalid/org/repo.git", creds); err == nil {
+		t.Error("expected error from the closed proxy connection")
+	}
+	select {
+	case <-dialed:
+	default:
+		t.Error("CheckAccess() did not connect through the SSH proxy")
+	}
+}
+
+func TestNewAuthMethod(t *testing.T) {
+	method, err := AuthMethod("https://github.com/org/repo.git", &Credentials{Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	basic, ok := method.(*http.BasicAuth)
+	if !ok || basic.Username != "git" || basic.Password != "tok" {
+		t.Errorf("unexpected auth method %#v", method)
+	}
+
+	if _, err := AuthMethod("git@github.com:org/repo.git", &Credentials{SSHKey: "not a key"}); err == nil {
+		t.Error("expected error for an invalid SSH key")
+	}
+
+	if method, _ := AuthMethod("https://github.com/org/repo.git", nil); method != nil {
+		t.Errorf("expected no auth method without credentials, got %#v", method)
+	}
+}
+
+func TestCredentialsFromAuth(t *testing.T) {
+	creds, err := CredentialsFromAuth(context.Background(), &auth.Credential{
+		Method: auth.MethodToken,
+		Data:   auth.CredentialData{Username: "bot", Token: "tok"},
+	})
+	if err != nil || creds.Username != "bot" || creds.Token != "tok" {
+		t.Errorf("CredentialsFromAuth() = %+v, %v", creds, err)
+	}
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(nethttp.StatusCreated)
+		_, _ = w.Write([]byte(`{"token":"ghs_installation","expires_at":"2099-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	creds, err = CredentialsFromAuth(context.Background(), &auth.Credential{
+		Method: auth.MethodGitHubApp,
+		Data: auth.CredentialData{
+			GitHubAppID:                1,
+			GitHubAppInstallationID:    2,
+			GitHubAppPrivateKey:        string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+			GitHubAppEnterpriseBaseURL: server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatalf("CredentialsFromAuth() error = %v", err)
+	}
+	if creds.Username != "x-access-token" || creds.Token != "ghs_installation" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+}
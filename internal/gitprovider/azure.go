@@ -0,0 +1,273 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+const (
+	azureAPIVersion = "7.1"
+	// azureMinReviewersPolicy and azureStatusPolicy are the built-in policy
+	// type IDs of Azure Repos.
+	azureMinReviewersPolicy = "fa4e907d-c16b-4a4c-9dfa-4906e5d171dd"
+	azureStatusPolicy       = "cbdc66da-9728-4af8-aada-9a5a32e4a226"
+	azureZeroObjectID       = "0000000000000000000000000000000000000000"
+)
+
+// azureDevOps implements Provider for Azure Repos. Owner is
+// "organization/project".
+type azureDevOps struct {
+	api          *apiClient
+	repo         *Repo
+	organization string
+	project      string
+}
+
+type azureRepo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	RemoteURL     string `json:"remoteUrl"`
+	SSHURL        string `json:"sshUrl"`
+	WebURL        string `json:"webUrl"`
+	DefaultBranch string `json:"defaultBranch"`
+	Project       struct {
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	} `json:"project"`
+}
+
+func (r *azureRepo) toRepository(organization string) *git.Repository {
+	return &git.Repository{
+		Name:          r.Name,
+		FullName:      organization + "/" + r.Project.Name + "/" + r.Name,
+		URL:           r.WebURL,
+		HTTPSURL:      r.RemoteURL,
+		SSHURL:        r.SSHURL,
+		CloneURL:      r.RemoteURL,
+		Visibility:    git.Visibility(strings.ToLower(r.Project.Visibility)),
+		DefaultBranch: strings.TrimPrefix(r.DefaultBranch, "refs/heads/"),
+		Owner:         organization + "/" + r.Project.Name,
+	}
+}
+
+type azureRef struct {
+	Name     string `json:"name"`
+	ObjectID string `json:"objectId"`
+}
+
+func (a *azureDevOps) Name() git.ProviderType { return git.ProviderAzureDevOps }
+func (a *azureDevOps) Repo() *Repo            { return a.repo }
+
+// path returns a project-scoped API path with the api-version query set.
+func (a *azureDevOps) path(format string, args ...interface{}) string {
+	p := fmt.Sprintf("/%s/%s/_apis", url.PathEscape(a.organization), url.PathEscape(a.project)) + fmt.Sprintf(format, args...)
+	sep := "?"
+	if strings.Contains(p, "?") {
+		sep = "&"
+	}
+	return p + sep + "api-version=" + azureAPIVersion
+}
+
+func (a *azureDevOps) repoPath(format string, args ...interface{}) string {
+	return a.path("/git/repositories/%s%s", url.PathEscape(a.repo.Name), fmt.Sprintf(format, args...))
+}
+
+func (a *azureDevOps) get(ctx context.Context) (*azureRepo, error) {
+	var repo azureRepo
+	if err := a.api.do(ctx, http.MethodGet, a.repoPath(""), nil, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+func (a *azureDevOps) GetRepo(ctx context.Context) (*git.Repository, error) {
+	repo, err := a.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.toRepository(a.organization), nil
+}
+
+func (a *azureDevOps) CreateRepo(ctx context.Context, opts git.CreateRepoOptions) (*git.Repository, error) {
+	var project struct {
+		ID string `json:"id"`
+	}
+	projectPath := fmt.Sprintf("/%s/_apis/projects/%s?api-version=%s", url.PathEscape(a.organization), url.PathEscape(a.project), azureAPIVersion)
+	if err := a.api.do(ctx, http.MethodGet, projectPath, nil, &project); err != nil {
+		return nil, fmt.Errorf("failed to find project %s: %w", a.project, err)
+	}
+	var repo azureRepo
+	err := a.api.do(ctx, http.MethodPost, a.path("/git/repositories"), map[string]interface{}{
+		"name":    a.repo.Name,
+		"project": map[string]string{"id": project.ID},
+	}, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository %s: %w", a.repo.Name, err)
+	}
+	return repo.toRepository(a.organization), nil
+}
+
+func (a *azureDevOps) ref(ctx context.Context, branch string) (*azureRef, error) {
+	var refs struct {
+		Value []azureRef `json:"value"`
+	}
+	if err := a.api.do(ctx, http.MethodGet, a.repoPath("/refs?filter=%s", url.QueryEscape("heads/"+branch)), nil, &refs); err != nil {
+		return nil, err
+	}
+	// The filter is a prefix match.
+	for i := range refs.Value {
+		if refs.Value[i].Name == "refs/heads/"+branch {
+			return &refs.Value[i], nil
+		}
+	}
+	return nil, fmt.Errorf("branch %s: %w", branch, ErrNotFound)
+}
+
+func (a *azureDevOps) EnsureBranch(ctx context.Context, branch, from string) error {
+	if _, err := a.ref(ctx, branch); err == nil {
+		return nil
+	}
+	source, err := a.ref(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to resolve branch %s: %w", from, err)
+	}
+	return a.api.do(ctx, http.MethodPost, a.repoPath("/refs"), []map[string]string{{
+		"name":        "refs/heads/" + branch,
+		"oldObjectId": azureZeroObjectID,
+		"newObjectId": source.ObjectID,
+	}}, nil)
+}
+
+// SetBranchProtection configures branch policies. Force pushes are governed
+// by repository permissions in Azure Repos, so AllowForcePush is ignored.
+func (a *azureDevOps) SetBranchProtection(ctx context.Context, branch string, rules BranchProtection) error {
+	repo, err := a.get(ctx)
+	if err != nil {
+		return err
+	}
+	scope := []map[string]string{{"repositoryId": repo.ID, "refName": "refs/heads/" + branch, "matchKind": "exact"}}
+	policy := func(typeID string, settings map[string]interface{}) error {
+		settings["scope"] = scope
+		return a.api.do(ctx, http.MethodPost, a.path("/policy/configurations"), map[string]interface{}{
+			"isEnabled":  true,
+			"isBlocking": true,
+			"type":       map[string]string{"id": typeID},
+			"settings":   settings,
+		}, nil)
+	}
+
+	if rules.RequiredApprovals > 0 {
+		err := policy(azureMinReviewersPolicy, map[string]interface{}{
+			"minimumApproverCount": rules.RequiredApprovals,
+			"creatorVoteCounts":    false,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to require reviewers: %w", err)
+		}
+	}
+	for _, check := range rules.RequiredChecks {
+		if err := policy(azureStatusPolicy, map[string]interface{}{"statusName": check}); err != nil {
+			return fmt.Errorf("failed to require status %s: %w", check, err)
+		}
+	}
+	return nil
+}
+
+// AddDeployKey is not supported: Azure DevOps has no per-repository SSH keys.
+func (a *azureDevOps) AddDeployKey(ctx context.Context, key DeployKey) (*DeployKey, error) {
+	return nil, fmt.Errorf("azure DevOps deploy keys: %w", ErrNotSupported)
+}
+
+// CreateWebhook creates one service hook subscription per event and returns
+// the ID of the first.
+func (a *azureDevOps) CreateWebhook(ctx context.Context, opts git.WebhookOptions) (*git.Webhook, error) {
+	repo, err := a.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events := webhookEvents(opts)
+	hook := &git.Webhook{URL: opts.URL, Events: events, Active: opts.Active}
+	var eventTypes []string
+	for _, e := range events {
+		// Tag pushes are delivered as git.push.
+		eventType := "git.push"
+		if e == EventPullRequest {
+			eventType = "git.pullrequest.created"
+		}
+		if !hasEvent(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	for _, eventType := range eventTypes {
+		consumer := map[string]string{"url": opts.URL}
+		if opts.Secret != "" {
+			consumer["httpHeaders"] = "X-Gitopsi-Secret:" + opts.Secret
+		}
+		var created struct {
+			ID string `json:"id"`
+		}
+		path := fmt.Sprintf("/%s/_apis/hooks/subscriptions?api-version=%s", url.PathEscape(a.organization), azureAPIVersion)
+		err := a.api.do(ctx, http.MethodPost, path, map[string]interface{}{
+			"publisherId":      "tfs",
+			"eventType":        eventType,
+			"resourceVersion":  "1.0",
+			"consumerId":       "webHooks",
+			"consumerActionId": "httpRequest",
+			"publisherInputs":  map[string]string{"projectId": repo.Project.ID, "repository": repo.ID},
+			"consumerInputs":   consumer,
+		}, &created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", eventType, err)
+		}
+		if hook.ID == "" {
+			hook.ID = created.ID
+		}
+	}
+	return hook, nil
+}
+
+type azurePull struct {
+	PullRequestID int `json:"pullRequestId"`
+	Repository    struct {
+		WebURL string `json:"webUrl"`
+	} `json:"repository"`
+}
+
+func (p *azurePull) toPullRequest(existing bool) *PullRequest {
+	return &PullRequest{
+		Number:   p.PullRequestID,
+		URL:      p.Repository.WebURL + "/pullrequest/" + strconv.Itoa(p.PullRequestID),
+		Existing: existing,
+	}
+}
+
+func (a *azureDevOps) CreatePullRequest(ctx context.Context, opts *PullRequestOptions) (*PullRequest, error) {
+	var pull azurePull
+	err := a.api.do(ctx, http.MethodPost, a.repoPath("/pullrequests"), map[string]interface{}{
+		"title":         opts.Title,
+		"description":   opts.Body,
+		"sourceRefName": "refs/heads/" + opts.Head,
+		"targetRefName": "refs/heads/" + opts.Base,
+		"isDraft":       opts.Draft,
+	}, &pull)
+	if statusIs(err, http.StatusConflict) {
+		var open struct {
+			Value []azurePull `json:"value"`
+		}
+		query := "?searchCriteria.status=active&searchCriteria.sourceRefName=" + url.QueryEscape("refs/heads/"+opts.Head)
+		if listErr := a.api.do(ctx, http.MethodGet, a.repoPath("/pullrequests%s", query), nil, &open); listErr == nil && len(open.Value) > 0 {
+			return open.Value[0].toPullRequest(true), nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pull.toPullRequest(false), nil
+}
@@ -0,0 +1,147 @@
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+const (
+	azureRepoURL  = "https://dev.azure.com/acme/infra/_git/platform"
+	azureRepoPath = "/acme/infra/_apis/git/repositories/platform"
+)
+
+var azureRepoResponse = response{Body: map[string]interface{}{
+	"id": "repo-id", "name": "platform", "remoteUrl": "https://dev.azure.com/acme/infra/_git/platform",
+	"webUrl": "https://dev.azure.com/acme/infra/_git/platform", "defaultBranch": "refs/heads/main",
+	"project": map[string]string{"id": "project-id", "name": "infra", "visibility": "private"},
+}}
+
+func TestAzureDevOps_GetAndCreateRepo(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET " + azureRepoPath:                    azureRepoResponse,
+		"GET /acme/_apis/projects/infra":          {Body: map[string]string{"id": "project-id"}},
+		"POST /acme/infra/_apis/git/repositories": azureRepoResponse,
+	})
+	p := newTestProvider(t, azureRepoURL, "", server)
+
+	repo, err := p.GetRepo(context.Background())
+	if err != nil {
+		t.Fatalf("GetRepo() error = %v", err)
+	}
+	if repo.DefaultBranch != "main" || repo.Owner != "acme/infra" || repo.Visibility != git.VisibilityPrivate {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+	if got := (*requests)[0].Query; got != "api-version="+azureAPIVersion {
+		t.Errorf("query = %q", got)
+	}
+	if _, pass, ok := (&http.Request{Header: (*requests)[0].Header}).BasicAuth(); !ok || pass != "tok" {
+		t.Error("expected the token as the basic auth password")
+	}
+
+	if _, err := p.CreateRepo(context.Background(), git.CreateRepoOptions{}); err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, "/acme/infra/_apis/git/repositories")
+	if project, _ := body["project"].(map[string]interface{}); body["name"] != "platform" || project["id"] != "project-id" {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestAzureDevOps_EnsureBranch(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET " + azureRepoPath + "/refs": {Body: map[string]interface{}{"value": []map[string]string{
+			{"name": "refs/heads/main", "objectId": "abc"},
+		}}},
+		"POST " + azureRepoPath + "/refs": {},
+	})
+	p := newTestProvider(t, azureRepoURL, "", server)
+
+	if err := p.EnsureBranch(context.Background(), "staging", "main"); err != nil {
+		t.Fatalf("EnsureBranch() error = %v", err)
+	}
+	last := (*requests)[len(*requests)-1]
+	updates, _ := last.Body.([]interface{})
+	if last.Method != http.MethodPost || len(updates) != 1 {
+		t.Fatalf("unexpected request %+v", last)
+	}
+	update, _ := updates[0].(map[string]interface{})
+	if update["name"] != "refs/heads/staging" || update["newObjectId"] != "abc" || update["oldObjectId"] != azureZeroObjectID {
+		t.Errorf("unexpected ref update %v", update)
+	}
+}
+
+func TestAzureDevOps_SetBranchProtection(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET " + azureRepoPath:                         azureRepoResponse,
+		"POST /acme/infra/_apis/policy/configurations": {},
+	})
+	p := newTestProvider(t, azureRepoURL, "", server)
+
+	err := p.SetBranchProtection(context.Background(), "main", BranchProtection{RequiredApprovals: 2, RequiredChecks: []string{"ci"}})
+	if err != nil {
+		t.Fatalf("SetBranchProtection() error = %v", err)
+	}
+	var types []interface{}
+	for _, r := range *requests {
+		if r.Method == http.MethodPost {
+			body, _ := r.Body.(map[string]interface{})
+			policyType, _ := body["type"].(map[string]interface{})
+			types = append(types, policyType["id"])
+		}
+	}
+	if len(types) != 2 || types[0] != azureMinReviewersPolicy || types[1] != azureStatusPolicy {
+		t.Errorf("unexpected policies %v", types)
+	}
+}
+
+func TestAzureDevOps_DeployKeyAndWebhook(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET " + azureRepoPath:                 azureRepoResponse,
+		"POST /acme/_apis/hooks/subscriptions": {Body: map[string]string{"id": "sub-1"}},
+	})
+	p := newTestProvider(t, azureRepoURL, "", server)
+
+	if _, err := p.AddDeployKey(context.Background(), DeployKey{Key: "ssh-ed25519 AAAA"}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+
+	hook, err := p.CreateWebhook(context.Background(), git.WebhookOptions{URL: "https://example.com/hook", Secret: "s", Events: []string{EventPush, EventTag, EventPullRequest}})
+	if err != nil || hook.ID != "sub-1" {
+		t.Fatalf("CreateWebhook() = %+v, %v", hook, err)
+	}
+	var eventTypes []string
+	for _, r := range *requests {
+		if r.Method == http.MethodPost {
+			body, _ := r.Body.(map[string]interface{})
+			eventTypes = append(eventTypes, body["eventType"].(string))
+		}
+	}
+	if strings.Join(eventTypes, ",") != "git.push,git.pullrequest.created" {
+		t.Errorf("unexpected subscriptions %v", eventTypes)
+	}
+}
+
+func TestAzureDevOps_CreatePullRequest(t *testing.T) {
+	created := response{Status: http.StatusCreated, Body: map[string]interface{}{
+		"pullRequestId": 11, "repository": map[string]string{"webUrl": "https://dev.azure.com/acme/infra/_git/platform"},
+	}}
+	server, requests := apiServer(t, map[string]response{"POST " + azureRepoPath + "/pullrequests": created})
+	p := newTestProvider(t, azureRepoURL, "", server)
+
+	pr, err := p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main", Draft: true})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 11 || pr.URL != "https://dev.azure.com/acme/infra/_git/platform/pullrequest/11" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, azureRepoPath+"/pullrequests")
+	if body["sourceRefName"] != "refs/heads/h" || body["targetRefName"] != "refs/heads/main" || body["isDraft"] != true {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
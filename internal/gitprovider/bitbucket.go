@@ -0,0 +1,241 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+// bitbucket implements Provider for Bitbucket Cloud. Owner is the workspace.
+type bitbucket struct {
+	api  *apiClient
+	repo *Repo
+}
+
+type bitbucketRepo struct {
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	IsPrivate   bool   `json:"is_private"`
+	MainBranch  struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Workspace struct {
+		Slug string `json:"slug"`
+	} `json:"workspace"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+		Clone []struct {
+			Name string `json:"name"`
+			Href string `json:"href"`
+		} `json:"clone"`
+	} `json:"links"`
+}
+
+func (r *bitbucketRepo) toRepository() *git.Repository {
+	visibility := git.VisibilityPublic
+	if r.IsPrivate {
+		visibility = git.VisibilityPrivate
+	}
+	repo := &git.Repository{
+		Name:          r.Name,
+		FullName:      r.FullName,
+		Description:   r.Description,
+		URL:           r.Links.HTML.Href,
+		Visibility:    visibility,
+		DefaultBranch: r.MainBranch.Name,
+		Owner:         r.Workspace.Slug,
+	}
+	for _, link := range r.Links.Clone {
+		switch link.Name {
+		case "https":
+			repo.HTTPSURL = link.Href
+			repo.CloneURL = link.Href
+		case "ssh":
+			repo.SSHURL = link.Href
+		}
+	}
+	return repo
+}
+
+func (b *bitbucket) Name() git.ProviderType { return git.ProviderBitbucket }
+func (b *bitbucket) Repo() *Repo            { return b.repo }
+
+func (b *bitbucket) path(format string, args ...interface{}) string {
+	return fmt.Sprintf("/repositories/%s/%s", b.repo.Owner, b.repo.Name) + fmt.Sprintf(format, args...)
+}
+
+func (b *bitbucket) GetRepo(ctx context.Context) (*git.Repository, error) {
+	var repo bitbucketRepo
+	if err := b.api.do(ctx, http.MethodGet, b.path(""), nil, &repo); err != nil {
+		return nil, err
+	}
+	return repo.toRepository(), nil
+}
+
+func (b *bitbucket) CreateRepo(ctx context.Context, opts git.CreateRepoOptions) (*git.Repository, error) {
+	var repo bitbucketRepo
+	err := b.api.do(ctx, http.MethodPost, b.path(""), map[string]interface{}{
+		"scm":         "git",
+		"description": opts.Description,
+		"is_private":  opts.Visibility != git.VisibilityPublic,
+	}, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository %s: %w", b.repo.FullName(), err)
+	}
+	return repo.toRepository(), nil
+}
+
+func (b *bitbucket) EnsureBranch(ctx context.Context, branch, from string) error {
+	err := b.api.do(ctx, http.MethodGet, b.path("/refs/branches/%s", url.PathEscape(branch)), nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !statusIs(err, http.StatusNotFound) {
+		return err
+	}
+	var source struct {
+		Target struct {
+			Hash string `json:"hash"`
+		} `json:"target"`
+	}
+	if err := b.api.do(ctx, http.MethodGet, b.path("/refs/branches/%s", url.PathEscape(from)), nil, &source); err != nil {
+		return fmt.Errorf("failed to resolve branch %s: %w", from, err)
+	}
+	return b.api.do(ctx, http.MethodPost, b.path("/refs/branches"), map[string]interface{}{
+		"name":   branch,
+		"target": map[string]string{"hash": source.Target.Hash},
+	}, nil)
+}
+
+func (b *bitbucket) SetBranchProtection(ctx context.Context, branch string, rules BranchProtection) error {
+	kinds := []string{"delete"}
+	if !rules.AllowForcePush {
+		kinds = append(kinds, "force")
+	}
+	for _, kind := range kinds {
+		if err := b.restrict(ctx, branch, kind, 0); err != nil {
+			return err
+		}
+	}
+	if rules.RequiredApprovals > 0 {
+		if err := b.restrict(ctx, branch, "require_approvals_to_merge", rules.RequiredApprovals); err != nil {
+			return err
+		}
+	}
+	if len(rules.RequiredChecks) > 0 {
+		if err := b.restrict(ctx, branch, "require_passing_builds_to_merge", 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bitbucket) restrict(ctx context.Context, branch, kind string, value int) error {
+	body := map[string]interface{}{
+		"kind":              kind,
+		"branch_match_kind": "glob",
+		"pattern":           branch,
+	}
+	if value > 0 {
+		body["value"] = value
+	}
+	if err := b.api.do(ctx, http.MethodPost, b.path("/branch-restrictions"), body, nil); err != nil {
+		// Bitbucket rejects duplicates of an existing restriction.
+		if statusIs(err, http.StatusConflict) {
+			return nil
+		}
+		return fmt.Errorf("failed to add %s restriction: %w", kind, err)
+	}
+	return nil
+}
+
+func (b *bitbucket) AddDeployKey(ctx context.Context, key DeployKey) (*DeployKey, error) {
+	if !key.ReadOnly {
+		return nil, fmt.Errorf("bitbucket deploy keys are read-only: %w", ErrNotSupported)
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := b.api.do(ctx, http.MethodPost, b.path("/deploy-keys"), map[string]string{
+		"key":   key.Key,
+		"label": key.Title,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = strconv.FormatInt(created.ID, 10)
+	return &key, nil
+}
+
+func (b *bitbucket) CreateWebhook(ctx context.Context, opts git.WebhookOptions) (*git.Webhook, error) {
+	events := webhookEvents(opts)
+	var bbEvents []string
+	for _, e := range events {
+		switch e {
+		case EventPullRequest:
+			bbEvents = append(bbEvents, "pullrequest:created", "pullrequest:updated")
+		case EventPush, EventTag:
+			// Tag pushes are delivered as repo:push.
+			if !hasEvent(bbEvents, "repo:push") {
+				bbEvents = append(bbEvents, "repo:push")
+			}
+		default:
+			bbEvents = append(bbEvents, e)
+		}
+	}
+	var created struct {
+		UUID string `json:"uuid"`
+	}
+	err := b.api.do(ctx, http.MethodPost, b.path("/hooks"), map[string]interface{}{
+		"description": "gitopsi",
+		"url":         opts.URL,
+		"active":      opts.Active,
+		"secret":      opts.Secret,
+		"events":      bbEvents,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &git.Webhook{ID: created.UUID, URL: opts.URL, Events: events, Active: opts.Active}, nil
+}
+
+type bitbucketPull struct {
+	ID    int `json:"id"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+func (b *bitbucket) CreatePullRequest(ctx context.Context, opts *PullRequestOptions) (*PullRequest, error) {
+	var pull bitbucketPull
+	err := b.api.do(ctx, http.MethodPost, b.path("/pullrequests"), map[string]interface{}{
+		"title":               opts.Title,
+		"description":         opts.Body,
+		"draft":               opts.Draft,
+		"source":              map[string]interface{}{"branch": map[string]string{"name": opts.Head}},
+		"destination":         map[string]interface{}{"branch": map[string]string{"name": opts.Base}},
+		"close_source_branch": true,
+	}, &pull)
+	if statusIs(err, http.StatusBadRequest, http.StatusConflict) {
+		var open struct {
+			Values []bitbucketPull `json:"values"`
+		}
+		query := "?state=OPEN&q=" + url.QueryEscape(fmt.Sprintf("source.branch.name=%q", opts.Head))
+		if listErr := b.api.do(ctx, http.MethodGet, b.path("/pullrequests")+query, nil, &open); listErr == nil && len(open.Values) > 0 {
+			return &PullRequest{Number: open.Values[0].ID, URL: open.Values[0].Links.HTML.Href, Existing: true}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: pull.ID, URL: pull.Links.HTML.Href}, nil
+}
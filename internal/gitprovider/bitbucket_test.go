@@ -0,0 +1,123 @@
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+const bitbucketRepoPath = "/repositories/ws/platform"
+
+func TestBitbucket_CreateRepo(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + bitbucketRepoPath: {Body: map[string]interface{}{
+			"name": "platform", "full_name": "ws/platform", "is_private": true,
+			"links": map[string]interface{}{"clone": []map[string]string{
+				{"name": "https", "href": "https://bitbucket.org/ws/platform.git"},
+				{"name": "ssh", "href": "git@bitbucket.org:ws/platform.git"},
+			}},
+		}},
+	})
+	p, err := New(context.Background(), "https://bitbucket.org/ws/platform.git", Options{Token: "app-pass", Username: "me", BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := p.CreateRepo(context.Background(), git.CreateRepoOptions{})
+	if err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	if repo.CloneURL != "https://bitbucket.org/ws/platform.git" || repo.SSHURL != "git@bitbucket.org:ws/platform.git" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+	if user, pass, ok := (&http.Request{Header: (*requests)[0].Header}).BasicAuth(); !ok || user != "me" || pass != "app-pass" {
+		t.Errorf("expected basic auth, got %q %q", user, pass)
+	}
+	if body := bodyOf(t, *requests, http.MethodPost, bitbucketRepoPath); body["scm"] != "git" || body["is_private"] != true {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestBitbucket_EnsureBranch(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET " + bitbucketRepoPath + "/refs/branches/main": {Body: map[string]interface{}{"target": map[string]string{"hash": "abc"}}},
+		"POST " + bitbucketRepoPath + "/refs/branches":     {Status: http.StatusCreated},
+	})
+	p := newTestProvider(t, "https://bitbucket.org/ws/platform.git", "", server)
+
+	if err := p.EnsureBranch(context.Background(), "staging", "main"); err != nil {
+		t.Fatalf("EnsureBranch() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, bitbucketRepoPath+"/refs/branches")
+	target, _ := body["target"].(map[string]interface{})
+	if body["name"] != "staging" || target["hash"] != "abc" {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestBitbucket_SetBranchProtection(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + bitbucketRepoPath + "/branch-restrictions": {Status: http.StatusCreated},
+	})
+	p := newTestProvider(t, "https://bitbucket.org/ws/platform.git", "", server)
+
+	if err := p.SetBranchProtection(context.Background(), "main", BranchProtection{RequiredApprovals: 2}); err != nil {
+		t.Fatalf("SetBranchProtection() error = %v", err)
+	}
+	var kinds []interface{}
+	for _, r := range *requests {
+		body, _ := r.Body.(map[string]interface{})
+		kinds = append(kinds, body["kind"])
+	}
+	if len(kinds) != 3 || kinds[0] != "delete" || kinds[1] != "force" || kinds[2] != "require_approvals_to_merge" {
+		t.Errorf("unexpected restrictions %v", kinds)
+	}
+}
+
+func TestBitbucket_DeployKeyAndWebhook(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + bitbucketRepoPath + "/deploy-keys": {Body: map[string]int{"id": 3}},
+		"POST " + bitbucketRepoPath + "/hooks":       {Status: http.StatusCreated, Body: map[string]string{"uuid": "{hook}"}},
+	})
+	p := newTestProvider(t, "https://bitbucket.org/ws/platform.git", "", server)
+
+	if _, err := p.AddDeployKey(context.Background(), DeployKey{Key: "ssh-ed25519 AAAA"}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for a write key, got %v", err)
+	}
+	key, err := p.AddDeployKey(context.Background(), DeployKey{Title: "argocd", Key: "ssh-ed25519 AAAA", ReadOnly: true})
+	if err != nil || key.ID != "3" {
+		t.Fatalf("AddDeployKey() = %+v, %v", key, err)
+	}
+
+	hook, err := p.CreateWebhook(context.Background(), git.WebhookOptions{URL: "https://example.com/hook", Events: []string{EventPush, EventTag}, Active: true})
+	if err != nil || hook.ID != "{hook}" {
+		t.Fatalf("CreateWebhook() = %+v, %v", hook, err)
+	}
+	events, _ := bodyOf(t, *requests, http.MethodPost, bitbucketRepoPath+"/hooks")["events"].([]interface{})
+	if len(events) != 1 || events[0] != "repo:push" {
+		t.Errorf("unexpected events %v", events)
+	}
+}
+
+func TestBitbucket_CreatePullRequest(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + bitbucketRepoPath + "/pullrequests": {Status: http.StatusBadRequest, Body: map[string]string{"type": "error"}},
+		"GET " + bitbucketRepoPath + "/pullrequests": {Body: map[string]interface{}{"values": []map[string]interface{}{
+			{"id": 8, "links": map[string]interface{}{"html": map[string]string{"href": "https://bitbucket.org/ws/platform/pull-requests/8"}}},
+		}}},
+	})
+	p := newTestProvider(t, "https://bitbucket.org/ws/platform.git", "", server)
+
+	pr, err := p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main"})
+	if err != nil || pr.Number != 8 || !pr.Existing {
+		t.Fatalf("expected the existing pull request, got %+v, %v", pr, err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, bitbucketRepoPath+"/pullrequests")
+	source, _ := body["source"].(map[string]interface{})
+	if branch, _ := source["branch"].(map[string]interface{}); branch["name"] != "h" {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
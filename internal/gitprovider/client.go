@@ -0,0 +1,103 @@
+package gitprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiClient is a minimal JSON REST client.
+type apiClient struct {
+	baseURL  string
+	token    string
+	username string
+	// header sets the authentication header (default: Bearer token, or basic
+	// auth when username is set).
+	header func(req *http.Request, token string)
+	http   *http.Client
+}
+
+// apiError is returned for non-2xx responses.
+type apiError struct {
+	Status int
+	Body   string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("API returned %d: %s", e.Status, strings.TrimSpace(e.Body))
+}
+
+// Is makes 404 responses match ErrNotFound.
+func (e *apiError) Is(target error) bool {
+	return target == ErrNotFound && e.Status == http.StatusNotFound
+}
+
+func (c *apiClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	target := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		target = c.baseURL + path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case c.token == "":
+	case c.header != nil:
+		c.header(req, c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &apiError{Status: resp.StatusCode, Body: string(data)}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return nil
+}
+
+func statusIs(err error, codes ...int) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, c := range codes {
+		if apiErr.Status == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+// gitea implements Provider for Gitea and Forgejo.
+type gitea struct {
+	api  *apiClient
+	repo *Repo
+}
+
+type giteaRepo struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func (r *giteaRepo) toRepository() *git.Repository {
+	visibility := git.VisibilityPublic
+	if r.Private {
+		visibility = git.VisibilityPrivate
+	}
+	return &git.Repository{
+		Name:          r.Name,
+		FullName:      r.FullName,
+		Description:   r.Description,
+		URL:           r.HTMLURL,
+		HTTPSURL:      r.CloneURL,
+		SSHURL:        r.SSHURL,
+		CloneURL:      r.CloneURL,
+		Visibility:    visibility,
+		DefaultBranch: r.DefaultBranch,
+		Owner:         r.Owner.Login,
+	}
+}
+
+func (g *gitea) Name() git.ProviderType { return git.ProviderGitea }
+func (g *gitea) Repo() *Repo            { return g.repo }
+
+func (g *gitea) path(format string, args ...interface{}) string {
+	return fmt.Sprintf("/repos/%s/%s", g.repo.Owner, g.repo.Name) + fmt.Sprintf(format, args...)
+}
+
+func (g *gitea) GetRepo(ctx context.Context) (*git.Repository, error) {
+	var repo giteaRepo
+	if err := g.api.do(ctx, http.MethodGet, g.path(""), nil, &repo); err != nil {
+		return nil, err
+	}
+	return repo.toRepository(), nil
+}
+
+func (g *gitea) CreateRepo(ctx context.Context, opts git.CreateRepoOptions) (*git.Repository, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, "/user", nil, &user); err != nil {
+		return nil, fmt.Errorf("failed to get authenticated user: %w", err)
+	}
+	path := "/orgs/" + g.repo.Owner + "/repos"
+	if user.Login == g.repo.Owner {
+		path = "/user/repos"
+	}
+	var repo giteaRepo
+	err := g.api.do(ctx, http.MethodPost, path, map[string]interface{}{
+		"name":        g.repo.Name,
+		"description": opts.Description,
+		"private":     opts.Visibility != git.VisibilityPublic,
+		"auto_init":   opts.AutoInit,
+	}, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository %s: %w", g.repo.FullName(), err)
+	}
+	return repo.toRepository(), nil
+}
+
+func (g *gitea) EnsureBranch(ctx context.Context, branch, from string) error {
+	err := g.api.do(ctx, http.MethodGet, g.path("/branches/%s", url.PathEscape(branch)), nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !statusIs(err, http.StatusNotFound) {
+		return err
+	}
+	return g.api.do(ctx, http.MethodPost, g.path("/branches"), map[string]string{
+		"new_branch_name": branch,
+		"old_branch_name": from,
+	}, nil)
+}
+
+func (g *gitea) SetBranchProtection(ctx context.Context, branch string, rules BranchProtection) error {
+	body := map[string]interface{}{
+		"branch_name":           branch,
+		"rule_name":             branch,
+		"enable_push":           rules.AllowForcePush,
+		"required_approvals":    rules.RequiredApprovals,
+		"enable_status_check":   len(rules.RequiredChecks) > 0,
+		"status_check_contexts": rules.RequiredChecks,
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/branch_protections"), body, nil)
+	if statusIs(err, http.StatusForbidden, http.StatusConflict, http.StatusUnprocessableEntity) {
+		// The rule already exists: update it.
+		return g.api.do(ctx, http.MethodPatch, g.path("/branch_protections/%s", url.PathEscape(branch)), body, nil)
+	}
+	return err
+}
+
+func (g *gitea) AddDeployKey(ctx context.Context, key DeployKey) (*DeployKey, error) {
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/keys"), map[string]interface{}{
+		"title":     key.Title,
+		"key":       key.Key,
+		"read_only": key.ReadOnly,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = strconv.FormatInt(created.ID, 10)
+	return &key, nil
+}
+
+func (g *gitea) CreateWebhook(ctx context.Context, opts git.WebhookOptions) (*git.Webhook, error) {
+	events := webhookEvents(opts)
+	var giteaEvents []string
+	for _, e := range events {
+		switch e {
+		case EventTag:
+			giteaEvents = append(giteaEvents, "create")
+		default:
+			giteaEvents = append(giteaEvents, e)
+		}
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/hooks"), map[string]interface{}{
+		"type":   "gitea",
+		"active": opts.Active,
+		"events": giteaEvents,
+		"config": map[string]string{"url": opts.URL, "content_type": "json", "secret": opts.Secret},
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &git.Webhook{ID: strconv.FormatInt(created.ID, 10), URL: opts.URL, Events: events, Active: opts.Active}, nil
+}
+
+type giteaPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+}
+
+func (g *gitea) CreatePullRequest(ctx context.Context, opts *PullRequestOptions) (*PullRequest, error) {
+	title := opts.Title
+	if opts.Draft {
+		title = "WIP: " + title
+	}
+	var pull giteaPull
+	err := g.api.do(ctx, http.MethodPost, g.path("/pulls"), map[string]interface{}{
+		"title": title,
+		"body":  opts.Body,
+		"head":  opts.Head,
+		"base":  opts.Base,
+	}, &pull)
+	if statusIs(err, http.StatusConflict, http.StatusUnprocessableEntity) {
+		var open []giteaPull
+		if listErr := g.api.do(ctx, http.MethodGet, g.path("/pulls?state=open"), nil, &open); listErr == nil {
+			for _, p := range open {
+				if p.Head.Ref == opts.Head {
+					return &PullRequest{Number: p.Number, URL: p.HTMLURL, Existing: true}, nil
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: pull.Number, URL: pull.HTMLURL}, nil
+}
@@ -0,0 +1,69 @@
+package gitprovider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+func TestGitea_CreateRepoAndBranch(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET /user":                         {Body: map[string]string{"login": "org"}},
+		"POST /user/repos":                  {Status: http.StatusCreated, Body: map[string]interface{}{"name": "platform", "private": true}},
+		"POST /repos/org/platform/branches": {Status: http.StatusCreated},
+	})
+	p := newTestProvider(t, "https://git.example.com/org/platform.git", git.ProviderGitea, server)
+
+	repo, err := p.CreateRepo(context.Background(), git.CreateRepoOptions{AutoInit: true})
+	if err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	if repo.Visibility != git.VisibilityPrivate {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+	if got := (*requests)[0].Header.Get("Authorization"); got != "token tok" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	if err := p.EnsureBranch(context.Background(), "staging", "main"); err != nil {
+		t.Fatalf("EnsureBranch() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, "/repos/org/platform/branches")
+	if body["new_branch_name"] != "staging" || body["old_branch_name"] != "main" {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestGitea_SetBranchProtectionUpdatesExisting(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST /repos/org/platform/branch_protections":       {Status: http.StatusForbidden, Body: map[string]string{"message": "exists"}},
+		"PATCH /repos/org/platform/branch_protections/main": {},
+	})
+	p := newTestProvider(t, "https://git.example.com/org/platform.git", git.ProviderGitea, server)
+
+	if err := p.SetBranchProtection(context.Background(), "main", BranchProtection{RequiredApprovals: 1}); err != nil {
+		t.Fatalf("SetBranchProtection() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPatch, "/repos/org/platform/branch_protections/main")
+	if body["required_approvals"] != float64(1) || body["enable_push"] != false {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestGitea_CreatePullRequest(t *testing.T) {
+	server, _ := apiServer(t, map[string]response{
+		"POST /repos/org/platform/pulls": {Status: http.StatusConflict, Body: map[string]string{"message": "exists"}},
+		"GET /repos/org/platform/pulls": {Body: []map[string]interface{}{
+			{"number": 1, "html_url": "https://example.com/1", "head": map[string]string{"ref": "other"}},
+			{"number": 5, "html_url": "https://example.com/5", "head": map[string]string{"ref": "h"}},
+		}},
+	})
+	p := newTestProvider(t, "https://git.example.com/org/platform.git", git.ProviderGitea, server)
+
+	pr, err := p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main"})
+	if err != nil || pr.Number != 5 || !pr.Existing {
+		t.Errorf("expected the existing pull request, got %+v, %v", pr, err)
+	}
+}
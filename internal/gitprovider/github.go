@@ -0,0 +1,205 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+// gitHub implements Provider for github.com and GitHub Enterprise Server.
+type gitHub struct {
+	api  *apiClient
+	repo *Repo
+}
+
+type githubRepo struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	Visibility    string `json:"visibility"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func (r *githubRepo) toRepository() *git.Repository {
+	return &git.Repository{
+		Name:          r.Name,
+		FullName:      r.FullName,
+		Description:   r.Description,
+		URL:           r.HTMLURL,
+		HTTPSURL:      r.CloneURL,
+		SSHURL:        r.SSHURL,
+		CloneURL:      r.CloneURL,
+		Visibility:    git.Visibility(r.Visibility),
+		DefaultBranch: r.DefaultBranch,
+		Owner:         r.Owner.Login,
+	}
+}
+
+func (g *gitHub) Name() git.ProviderType { return git.ProviderGitHub }
+func (g *gitHub) Repo() *Repo            { return g.repo }
+
+func (g *gitHub) path(format string, args ...interface{}) string {
+	return fmt.Sprintf("/repos/%s/%s", g.repo.Owner, g.repo.Name) + fmt.Sprintf(format, args...)
+}
+
+func (g *gitHub) GetRepo(ctx context.Context) (*git.Repository, error) {
+	var repo githubRepo
+	if err := g.api.do(ctx, http.MethodGet, g.path(""), nil, &repo); err != nil {
+		return nil, err
+	}
+	return repo.toRepository(), nil
+}
+
+func (g *gitHub) CreateRepo(ctx context.Context, opts git.CreateRepoOptions) (*git.Repository, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, "/user", nil, &user); err != nil {
+		return nil, fmt.Errorf("failed to get authenticated user: %w", err)
+	}
+	path := "/orgs/" + g.repo.Owner + "/repos"
+	if user.Login == g.repo.Owner {
+		path = "/user/repos"
+	}
+
+	body := map[string]interface{}{
+		"name":        g.repo.Name,
+		"description": opts.Description,
+		"private":     opts.Visibility != git.VisibilityPublic,
+		"auto_init":   opts.AutoInit,
+	}
+	if opts.Visibility == git.VisibilityInternal {
+		body["visibility"] = "internal"
+	}
+	var repo githubRepo
+	if err := g.api.do(ctx, http.MethodPost, path, body, &repo); err != nil {
+		return nil, fmt.Errorf("failed to create repository %s: %w", g.repo.FullName(), err)
+	}
+	return repo.toRepository(), nil
+}
+
+func (g *gitHub) EnsureBranch(ctx context.Context, branch, from string) error {
+	type ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	var existing ref
+	err := g.api.do(ctx, http.MethodGet, g.path("/git/ref/heads/%s", branch), nil, &existing)
+	if err == nil {
+		return nil
+	}
+	if !statusIs(err, http.StatusNotFound) {
+		return err
+	}
+
+	var source ref
+	if err := g.api.do(ctx, http.MethodGet, g.path("/git/ref/heads/%s", from), nil, &source); err != nil {
+		return fmt.Errorf("failed to resolve branch %s: %w", from, err)
+	}
+	return g.api.do(ctx, http.MethodPost, g.path("/git/refs"), map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": source.Object.SHA,
+	}, nil)
+}
+
+func (g *gitHub) SetBranchProtection(ctx context.Context, branch string, rules BranchProtection) error {
+	var checks interface{}
+	if len(rules.RequiredChecks) > 0 {
+		checks = map[string]interface{}{"strict": true, "contexts": rules.RequiredChecks}
+	}
+	var reviews interface{}
+	if rules.RequiredApprovals > 0 {
+		reviews = map[string]interface{}{"required_approving_review_count": rules.RequiredApprovals}
+	}
+	return g.api.do(ctx, http.MethodPut, g.path("/branches/%s/protection", url.PathEscape(branch)), map[string]interface{}{
+		"required_status_checks":        checks,
+		"enforce_admins":                true,
+		"required_pull_request_reviews": reviews,
+		"restrictions":                  nil,
+		"allow_force_pushes":            rules.AllowForcePush,
+		"allow_deletions":               false,
+	}, nil)
+}
+
+func (g *gitHub) AddDeployKey(ctx context.Context, key DeployKey) (*DeployKey, error) {
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/keys"), map[string]interface{}{
+		"title":     key.Title,
+		"key":       key.Key,
+		"read_only": key.ReadOnly,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = strconv.FormatInt(created.ID, 10)
+	return &key, nil
+}
+
+func (g *gitHub) CreateWebhook(ctx context.Context, opts git.WebhookOptions) (*git.Webhook, error) {
+	events := webhookEvents(opts)
+	var ghEvents []string
+	for _, e := range events {
+		switch e {
+		case EventPullRequest:
+			ghEvents = append(ghEvents, "pull_request")
+		case EventTag:
+			ghEvents = append(ghEvents, "create")
+		default:
+			ghEvents = append(ghEvents, e)
+		}
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/hooks"), map[string]interface{}{
+		"name":   "web",
+		"active": opts.Active,
+		"events": ghEvents,
+		"config": map[string]string{"url": opts.URL, "content_type": "json", "secret": opts.Secret},
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &git.Webhook{ID: strconv.FormatInt(created.ID, 10), URL: opts.URL, Events: events, Active: opts.Active}, nil
+}
+
+type githubPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (g *gitHub) CreatePullRequest(ctx context.Context, opts *PullRequestOptions) (*PullRequest, error) {
+	var pull githubPull
+	err := g.api.do(ctx, http.MethodPost, g.path("/pulls"), map[string]interface{}{
+		"title": opts.Title,
+		"body":  opts.Body,
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"draft": opts.Draft,
+	}, &pull)
+	if statusIs(err, http.StatusUnprocessableEntity) {
+		// GitHub rejects a second pull request for the same head branch.
+		var open []githubPull
+		query := "?state=open&head=" + url.QueryEscape(g.repo.Owner+":"+opts.Head)
+		if listErr := g.api.do(ctx, http.MethodGet, g.path("/pulls")+query, nil, &open); listErr == nil && len(open) > 0 {
+			return &PullRequest{Number: open[0].Number, URL: open[0].HTMLURL, Existing: true}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: pull.Number, URL: pull.HTMLURL}, nil
+}
@@ -0,0 +1,145 @@
+package gitprovider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+func TestGitHub_GetAndCreateRepo(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET /user": {Body: map[string]string{"login": "someone"}},
+		"POST /orgs/org/repos": {Status: http.StatusCreated, Body: map[string]interface{}{
+			"name": "platform", "full_name": "org/platform", "clone_url": "https://github.com/org/platform.git",
+			"visibility": "private", "default_branch": "main", "owner": map[string]string{"login": "org"},
+		}},
+	})
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+
+	repo, err := p.CreateRepo(context.Background(), git.CreateRepoOptions{Description: "GitOps", Visibility: git.VisibilityInternal})
+	if err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	if repo.FullName != "org/platform" || repo.Visibility != git.VisibilityPrivate || repo.Owner != "org" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, "/orgs/org/repos")
+	if body["name"] != "platform" || body["private"] != true || body["visibility"] != "internal" {
+		t.Errorf("unexpected request body %v", body)
+	}
+	if got := (*requests)[0].Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestGitHub_CreateRepoForUser(t *testing.T) {
+	server, _ := apiServer(t, map[string]response{
+		"GET /user":        {Body: map[string]string{"login": "org"}},
+		"POST /user/repos": {Status: http.StatusCreated, Body: map[string]string{"name": "platform"}},
+	})
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+	if _, err := p.CreateRepo(context.Background(), git.CreateRepoOptions{}); err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+}
+
+func TestGitHub_EnsureBranch(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET /repos/org/platform/git/ref/heads/main": {Body: map[string]interface{}{"object": map[string]string{"sha": "abc"}}},
+		"POST /repos/org/platform/git/refs":          {Status: http.StatusCreated},
+	})
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+
+	if err := p.EnsureBranch(context.Background(), "staging", "main"); err != nil {
+		t.Fatalf("EnsureBranch() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, "/repos/org/platform/git/refs")
+	if body["ref"] != "refs/heads/staging" || body["sha"] != "abc" {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	*requests = nil
+	if err := p.EnsureBranch(context.Background(), "main", "main"); err != nil {
+		t.Fatalf("EnsureBranch() error = %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("existing branch should not be created, got %d requests", len(*requests))
+	}
+}
+
+func TestGitHub_SetBranchProtection(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"PUT /repos/org/platform/branches/main/protection": {},
+	})
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+
+	err := p.SetBranchProtection(context.Background(), "main", BranchProtection{RequiredApprovals: 2, RequiredChecks: []string{"validate"}})
+	if err != nil {
+		t.Fatalf("SetBranchProtection() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPut, "/repos/org/platform/branches/main/protection")
+	reviews, _ := body["required_pull_request_reviews"].(map[string]interface{})
+	checks, _ := body["required_status_checks"].(map[string]interface{})
+	if reviews["required_approving_review_count"] != float64(2) || checks == nil || body["allow_force_pushes"] != false {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestGitHub_DeployKeyAndWebhook(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST /repos/org/platform/keys":  {Status: http.StatusCreated, Body: map[string]int{"id": 12}},
+		"POST /repos/org/platform/hooks": {Status: http.StatusCreated, Body: map[string]int{"id": 34}},
+	})
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+
+	key, err := p.AddDeployKey(context.Background(), DeployKey{Title: "argocd", Key: "ssh-ed25519 AAAA", ReadOnly: true})
+	if err != nil || key.ID != "12" {
+		t.Fatalf("AddDeployKey() = %+v, %v", key, err)
+	}
+	if body := bodyOf(t, *requests, http.MethodPost, "/repos/org/platform/keys"); body["read_only"] != true {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	hook, err := p.CreateWebhook(context.Background(), git.WebhookOptions{URL: "https://argocd.example.com/api/webhook", Secret: "s", Events: []string{EventPush, EventPullRequest}, Active: true})
+	if err != nil || hook.ID != "34" {
+		t.Fatalf("CreateWebhook() = %+v, %v", hook, err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, "/repos/org/platform/hooks")
+	events, _ := body["events"].([]interface{})
+	if len(events) != 2 || events[1] != "pull_request" {
+		t.Errorf("unexpected events %v", body["events"])
+	}
+}
+
+func TestGitHub_CreatePullRequest(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST /repos/org/platform/pulls": {Status: http.StatusCreated, Body: map[string]interface{}{"number": 7, "html_url": "https://example.com/7"}},
+	})
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+
+	pr, err := p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main", Draft: true})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 7 || pr.Existing {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	if body := bodyOf(t, *requests, http.MethodPost, "/repos/org/platform/pulls"); body["head"] != "h" || body["draft"] != true {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	server, requests = apiServer(t, map[string]response{
+		"POST /repos/org/platform/pulls": {Status: http.StatusUnprocessableEntity, Body: map[string]string{"message": "exists"}},
+		"GET /repos/org/platform/pulls":  {Body: []map[string]interface{}{{"number": 3, "html_url": "https://example.com/3"}}},
+	})
+	p = newTestProvider(t, "https://github.com/org/platform.git", "", server)
+	pr, err = p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main"})
+	if err != nil || pr.Number != 3 || !pr.Existing {
+		t.Errorf("expected the existing pull request, got %+v, %v", pr, err)
+	}
+	if got := (*requests)[1].Query; got != "state=open&head=org%3Ah" {
+		t.Errorf("list query = %q", got)
+	}
+}
@@ -0,0 +1,233 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+// GitLab access levels.
+const (
+	gitlabNoAccess   = 0
+	gitlabDeveloper  = 30
+	gitlabMaintainer = 40
+)
+
+// gitLab implements Provider for gitlab.com and self-managed GitLab.
+type gitLab struct {
+	api  *apiClient
+	repo *Repo
+}
+
+type gitlabProject struct {
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	WebURL            string `json:"web_url"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+	Visibility        string `json:"visibility"`
+	DefaultBranch     string `json:"default_branch"`
+	Namespace         struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+}
+
+func (p *gitlabProject) toRepository() *git.Repository {
+	return &git.Repository{
+		Name:          p.Name,
+		FullName:      p.PathWithNamespace,
+		Description:   p.Description,
+		URL:           p.WebURL,
+		HTTPSURL:      p.HTTPURLToRepo,
+		SSHURL:        p.SSHURLToRepo,
+		CloneURL:      p.HTTPURLToRepo,
+		Visibility:    git.Visibility(p.Visibility),
+		DefaultBranch: p.DefaultBranch,
+		Owner:         p.Namespace.FullPath,
+	}
+}
+
+func (g *gitLab) Name() git.ProviderType { return git.ProviderGitLab }
+func (g *gitLab) Repo() *Repo            { return g.repo }
+
+func (g *gitLab) path(format string, args ...interface{}) string {
+	return "/projects/" + url.PathEscape(g.repo.FullName()) + fmt.Sprintf(format, args...)
+}
+
+func (g *gitLab) GetRepo(ctx context.Context) (*git.Repository, error) {
+	var project gitlabProject
+	if err := g.api.do(ctx, http.MethodGet, g.path(""), nil, &project); err != nil {
+		return nil, err
+	}
+	return project.toRepository(), nil
+}
+
+func (g *gitLab) CreateRepo(ctx context.Context, opts git.CreateRepoOptions) (*git.Repository, error) {
+	var namespace struct {
+		ID int64 `json:"id"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, "/namespaces/"+url.PathEscape(g.repo.Owner), nil, &namespace); err != nil {
+		return nil, fmt.Errorf("failed to find namespace %s: %w", g.repo.Owner, err)
+	}
+	visibility := opts.Visibility
+	if visibility == "" {
+		visibility = git.VisibilityPrivate
+	}
+	var project gitlabProject
+	err := g.api.do(ctx, http.MethodPost, "/projects", map[string]interface{}{
+		"name":                   g.repo.Name,
+		"path":                   g.repo.Name,
+		"namespace_id":           namespace.ID,
+		"description":            opts.Description,
+		"visibility":             visibility,
+		"initialize_with_readme": opts.AutoInit,
+	}, &project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project %s: %w", g.repo.FullName(), err)
+	}
+	return project.toRepository(), nil
+}
+
+func (g *gitLab) EnsureBranch(ctx context.Context, branch, from string) error {
+	err := g.api.do(ctx, http.MethodGet, g.path("/repository/branches/%s", url.PathEscape(branch)), nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !statusIs(err, http.StatusNotFound) {
+		return err
+	}
+	query := url.Values{"branch": {branch}, "ref": {from}}
+	return g.api.do(ctx, http.MethodPost, g.path("/repository/branches?%s", query.Encode()), nil, nil)
+}
+
+func (g *gitLab) SetBranchProtection(ctx context.Context, branch string, rules BranchProtection) error {
+	// Protection settings cannot be updated in place: replace the rule.
+	err := g.api.do(ctx, http.MethodDelete, g.path("/protected_branches/%s", url.PathEscape(branch)), nil, nil)
+	if err != nil && !statusIs(err, http.StatusNotFound) {
+		return err
+	}
+	push := gitlabNoAccess
+	if rules.AllowForcePush {
+		push = gitlabMaintainer
+	}
+	err = g.api.do(ctx, http.MethodPost, g.path("/protected_branches"), map[string]interface{}{
+		"name":               branch,
+		"push_access_level":  push,
+		"merge_access_level": gitlabDeveloper,
+		"allow_force_push":   rules.AllowForcePush,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if rules.RequiredApprovals > 0 {
+		err = g.api.do(ctx, http.MethodPost, g.path("/approval_rules"), map[string]interface{}{
+			"name":               "gitopsi",
+			"approvals_required": rules.RequiredApprovals,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to require approvals (approval rules need GitLab Premium): %w", err)
+		}
+	}
+	if len(rules.RequiredChecks) > 0 {
+		err = g.api.do(ctx, http.MethodPut, g.path(""), map[string]interface{}{
+			"only_allow_merge_if_pipeline_succeeds": true,
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *gitLab) AddDeployKey(ctx context.Context, key DeployKey) (*DeployKey, error) {
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/deploy_keys"), map[string]interface{}{
+		"title":    key.Title,
+		"key":      key.Key,
+		"can_push": !key.ReadOnly,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = strconv.FormatInt(created.ID, 10)
+	return &key, nil
+}
+
+// RotateToken rotates the personal, project or group access token of the
+// client (GitLab 16.0 and later).
+func (g *gitLab) RotateToken(ctx context.Context, expiresAt time.Time) (string, time.Time, error) {
+	in := map[string]interface{}{}
+	if !expiresAt.IsZero() {
+		in["expires_at"] = expiresAt.Format(time.DateOnly)
+	}
+	var rotated struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := g.api.do(ctx, http.MethodPost, "/personal_access_tokens/self/rotate", in, &rotated); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to rotate token: %w", err)
+	}
+	expires, err := time.Parse(time.DateOnly, rotated.ExpiresAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse token expiry %q: %w", rotated.ExpiresAt, err)
+	}
+	return rotated.Token, expires, nil
+}
+
+func (g *gitLab) CreateWebhook(ctx context.Context, opts git.WebhookOptions) (*git.Webhook, error) {
+	events := webhookEvents(opts)
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err := g.api.do(ctx, http.MethodPost, g.path("/hooks"), map[string]interface{}{
+		"url":                   opts.URL,
+		"token":                 opts.Secret,
+		"push_events":           hasEvent(events, EventPush),
+		"merge_requests_events": hasEvent(events, EventPullRequest),
+		"tag_push_events":       hasEvent(events, EventTag),
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &git.Webhook{ID: strconv.FormatInt(created.ID, 10), URL: opts.URL, Events: events, Active: true}, nil
+}
+
+type gitlabMR struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+func (g *gitLab) CreatePullRequest(ctx context.Context, opts *PullRequestOptions) (*PullRequest, error) {
+	title := opts.Title
+	if opts.Draft {
+		title = "Draft: " + title
+	}
+	var mr gitlabMR
+	err := g.api.do(ctx, http.MethodPost, g.path("/merge_requests"), map[string]interface{}{
+		"title":                title,
+		"description":          opts.Body,
+		"source_branch":        opts.Head,
+		"target_branch":        opts.Base,
+		"remove_source_branch": true,
+	}, &mr)
+	if statusIs(err, http.StatusConflict) {
+		var open []gitlabMR
+		query := "?state=opened&source_branch=" + url.QueryEscape(opts.Head)
+		if listErr := g.api.do(ctx, http.MethodGet, g.path("/merge_requests")+query, nil, &open); listErr == nil && len(open) > 0 {
+			return &PullRequest{Number: open[0].IID, URL: open[0].WebURL, Existing: true}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: mr.IID, URL: mr.WebURL}, nil
+}
@@ -0,0 +1,154 @@
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+const gitlabProjectPath = "/projects/group%2Fsub%2Fplatform"
+
+func TestGitLab_CreateRepo(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"GET /namespaces/group%2Fsub": {Body: map[string]int{"id": 9}},
+		"POST /projects": {Status: http.StatusCreated, Body: map[string]interface{}{
+			"name": "platform", "path_with_namespace": "group/sub/platform", "visibility": "private",
+			"namespace": map[string]string{"full_path": "group/sub"},
+		}},
+	})
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+
+	repo, err := p.CreateRepo(context.Background(), git.CreateRepoOptions{})
+	if err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	if repo.FullName != "group/sub/platform" || repo.Owner != "group/sub" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, "/projects")
+	if body["namespace_id"] != float64(9) || body["visibility"] != "private" {
+		t.Errorf("unexpected request body %v", body)
+	}
+	if got := (*requests)[0].Header.Get("PRIVATE-TOKEN"); got != "tok" {
+		t.Errorf("PRIVATE-TOKEN = %q", got)
+	}
+}
+
+func TestGitLab_GetRepoNotFound(t *testing.T) {
+	server, _ := apiServer(t, nil)
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+	if _, err := p.GetRepo(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGitLab_EnsureBranch(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + gitlabProjectPath + "/repository/branches": {Status: http.StatusCreated},
+	})
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+
+	if err := p.EnsureBranch(context.Background(), "staging", "main"); err != nil {
+		t.Fatalf("EnsureBranch() error = %v", err)
+	}
+	if got := (*requests)[1].Query; got != "branch=staging&ref=main" {
+		t.Errorf("create query = %q", got)
+	}
+}
+
+func TestGitLab_SetBranchProtection(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + gitlabProjectPath + "/protected_branches": {Status: http.StatusCreated},
+		"POST " + gitlabProjectPath + "/approval_rules":     {Status: http.StatusCreated},
+		"PUT " + gitlabProjectPath:                          {},
+	})
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+
+	err := p.SetBranchProtection(context.Background(), "main", BranchProtection{RequiredApprovals: 1, RequiredChecks: []string{"pipeline"}})
+	if err != nil {
+		t.Fatalf("SetBranchProtection() error = %v", err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, gitlabProjectPath+"/protected_branches")
+	if body["push_access_level"] != float64(gitlabNoAccess) || body["merge_access_level"] != float64(gitlabDeveloper) {
+		t.Errorf("unexpected request body %v", body)
+	}
+	if body := bodyOf(t, *requests, http.MethodPost, gitlabProjectPath+"/approval_rules"); body["approvals_required"] != float64(1) {
+		t.Errorf("unexpected approval rule %v", body)
+	}
+	if body := bodyOf(t, *requests, http.MethodPut, gitlabProjectPath); body["only_allow_merge_if_pipeline_succeeds"] != true {
+		t.Errorf("unexpected project update %v", body)
+	}
+}
+
+func TestGitLab_DeployKeyAndWebhook(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + gitlabProjectPath + "/deploy_keys": {Status: http.StatusCreated, Body: map[string]int{"id": 5}},
+		"POST " + gitlabProjectPath + "/hooks":       {Status: http.StatusCreated, Body: map[string]int{"id": 6}},
+	})
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+
+	if _, err := p.AddDeployKey(context.Background(), DeployKey{Title: "flux", Key: "ssh-ed25519 AAAA"}); err != nil {
+		t.Fatalf("AddDeployKey() error = %v", err)
+	}
+	if body := bodyOf(t, *requests, http.MethodPost, gitlabProjectPath+"/deploy_keys"); body["can_push"] != true {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	hook, err := p.CreateWebhook(context.Background(), git.WebhookOptions{URL: "https://example.com/hook", Events: []string{EventTag}})
+	if err != nil || hook.ID != "6" {
+		t.Fatalf("CreateWebhook() = %+v, %v", hook, err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, gitlabProjectPath+"/hooks")
+	if body["tag_push_events"] != true || body["push_events"] != false {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestGitLab_RotateToken(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST /personal_access_tokens/self/rotate": {Body: map[string]string{"token": "glpat-new", "expires_at": "2026-12-31"}},
+	})
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+
+	rotator, ok := p.(TokenRotator)
+	if !ok {
+		t.Fatal("GitLab does not implement TokenRotator")
+	}
+	token, expires, err := rotator.RotateToken(context.Background(), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil || token != "glpat-new" || expires.Format(time.DateOnly) != "2026-12-31" {
+		t.Fatalf("RotateToken() = %q, %v, %v", token, expires, err)
+	}
+	if body := bodyOf(t, *requests, http.MethodPost, "/personal_access_tokens/self/rotate"); body["expires_at"] != "2026-12-31" {
+		t.Errorf("unexpected request body %v", body)
+	}
+}
+
+func TestGitLab_CreateMergeRequest(t *testing.T) {
+	server, requests := apiServer(t, map[string]response{
+		"POST " + gitlabProjectPath + "/merge_requests": {Status: http.StatusCreated, Body: map[string]interface{}{"iid": 7, "web_url": "https://example.com/7"}},
+	})
+	p := newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+
+	pr, err := p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main", Draft: true})
+	if err != nil || pr.Number != 7 {
+		t.Fatalf("CreatePullRequest() = %+v, %v", pr, err)
+	}
+	body := bodyOf(t, *requests, http.MethodPost, gitlabProjectPath+"/merge_requests")
+	if body["title"] != "Draft: t" || body["source_branch"] != "h" || body["target_branch"] != "main" {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	server, _ = apiServer(t, map[string]response{
+		"POST " + gitlabProjectPath + "/merge_requests": {Status: http.StatusConflict, Body: map[string]string{"message": "exists"}},
+		"GET " + gitlabProjectPath + "/merge_requests":  {Body: []map[string]interface{}{{"iid": 4, "web_url": "https://example.com/4"}}},
+	})
+	p = newTestProvider(t, "https://gitlab.com/group/sub/platform.git", "", server)
+	pr, err = p.CreatePullRequest(context.Background(), &PullRequestOptions{Title: "t", Head: "h", Base: "main"})
+	if err != nil || pr.Number != 4 || !pr.Existing {
+		t.Errorf("expected the existing merge request, got %+v, %v", pr, err)
+	}
+}
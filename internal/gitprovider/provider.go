@@ -0,0 +1,290 @@
+// Package gitprovider talks to the REST APIs of Git hosting providers to
+// manage the repositories gitopsi delivers to.
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+var (
+	// ErrNotFound is returned when a repository, branch or other object does
+	// not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrNotSupported is returned for operations a provider does not offer.
+	ErrNotSupported = errors.New("not supported by this provider")
+)
+
+// Provider is the API of a Git hosting provider for one repository.
+type Provider interface {
+	Name() git.ProviderType
+	Repo() *Repo
+
+	// GetRepo returns the repository, or ErrNotFound.
+	GetRepo(ctx context.Context) (*git.Repository, error)
+	// CreateRepo creates the repository under its owner (a user,
+	// organization, group, workspace or project).
+	CreateRepo(ctx context.Context, opts git.CreateRepoOptions) (*git.Repository, error)
+	// EnsureBranch creates branch from the head of from unless it exists.
+	EnsureBranch(ctx context.Context, branch, from string) error
+	SetBranchProtection(ctx context.Context, branch string, rules BranchProtection) error
+	AddDeployKey(ctx context.Context, key DeployKey) (*DeployKey, error)
+	CreateWebhook(ctx context.Context, opts git.WebhookOptions) (*git.Webhook, error)
+	CreatePullRequest(ctx context.Context, opts *PullRequestOptions) (*PullRequest, error)
+}
+
+// TokenRotator is implemented by providers whose API replaces the token
+// the client authenticates with.
+type TokenRotator interface {
+	// RotateToken revokes the token of the client and returns a new token
+	// valid until expiresAt (zero: the provider default), and its expiry.
+	RotateToken(ctx context.Context, expiresAt time.Time) (string, time.Time, error)
+}
+
+// BranchProtection describes the rules applied to a protected branch.
+type BranchProtection struct {
+	// RequiredApprovals is the number of approving reviews needed to merge.
+	RequiredApprovals int
+	// RequiredChecks are status checks that must pass before merging.
+	RequiredChecks []string
+	// AllowForcePush permits force pushes to the branch.
+	AllowForcePush bool
+}
+
+// DeployKey is an SSH key granting access to a single repository.
+type DeployKey struct {
+	ID    string
+	Title string
+	// Key is the public key in authorized_keys format.
+	Key      string
+	ReadOnly bool
+}
+
+// PullRequestOptions describes a pull (or merge) request.
+type PullRequestOptions struct {
+	Title string
+	// Body is the description.
+	Body string
+	// Head is the branch with the changes.
+	Head string
+	// Base is the branch the changes are proposed against.
+	Base  string
+	Draft bool
+}
+
+// PullRequest is an opened pull or merge request.
+type PullRequest struct {
+	Number int
+	URL    string
+	// Existing is true when an open request for the head branch already existed.
+	Existing bool
+}
+
+// Webhook events understood by every provider.
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventTag         = "tag"
+)
+
+// Repo identifies a repository on a provider.
+type Repo struct {
+	Provider git.ProviderType
+	Host     string
+	// Owner is the user, organization or namespace. GitLab subgroups are
+	// included ("group/subgroup"); Azure DevOps uses "organization/project".
+	Owner string
+	Name  string
+	URL   string
+}
+
+// FullName returns owner/name.
+func (r *Repo) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// SSHURL returns the scp-style SSH clone URL of the repository, the form
+// deploy keys are used with.
+func (r *Repo) SSHURL() string {
+	host := strings.Split(r.Host, ":")[0]
+	if r.Provider == git.ProviderAzureDevOps {
+		return fmt.Sprintf("git@ssh.%s:v3/%s/%s", host, r.Owner, r.Name)
+	}
+	return fmt.Sprintf("git@%s:%s.git", host, r.FullName())
+}
+
+// Options configures New.
+type Options struct {
+	// Provider overrides detection, e.g. for self-hosted instances.
+	Provider git.ProviderType
+	Token    string
+	// Username selects basic auth with Token as the password (Bitbucket app
+	// passwords).
+	Username string
+	// BaseURL overrides the API URL derived from the repository host.
+	BaseURL    string
+	HTTPClient *http.Client
+	// Probe queries the host to identify self-hosted instances whose name
+	// does not reveal the provider.
+	Probe bool
+}
+
+// ParseRepo parses an HTTPS, SSH or scp-style repository URL and detects the
+// provider from the host name.
+func ParseRepo(repoURL string) (*Repo, error) {
+	raw := strings.TrimSpace(repoURL)
+	if raw == "" {
+		return nil, fmt.Errorf("repository URL cannot be empty")
+	}
+
+	var host, path string
+	if colon := strings.Index(raw, ":"); colon > 0 && !strings.Contains(raw, "://") {
+		// scp-style: git@host:owner/repo.git
+		host = raw[strings.LastIndex(raw[:colon], "@")+1 : colon]
+		path = raw[colon+1:]
+	} else {
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("unable to parse repository URL: %s", repoURL)
+		}
+		host = parsed.Hostname()
+		if parsed.Scheme == "http" || parsed.Scheme == "https" {
+			// Keep the port of self-hosted instances; it also serves the API.
+			host = parsed.Host
+		}
+		path = parsed.Path
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/"), "/")
+	repo := &Repo{Host: strings.ToLower(host), URL: raw, Provider: git.ProviderForHost(strings.Split(host, ":")[0])}
+
+	switch {
+	case repo.Host == "ssh.dev.azure.com" && len(segments) == 4 && segments[0] == "v3":
+		repo.Host = "dev.azure.com"
+		repo.Owner, repo.Name = segments[1]+"/"+segments[2], segments[3]
+	case len(segments) == 4 && segments[2] == "_git":
+		repo.Provider = git.ProviderAzureDevOps
+		repo.Owner, repo.Name = segments[0]+"/"+segments[1], segments[3]
+	case len(segments) >= 2:
+		repo.Owner = strings.Join(segments[:len(segments)-1], "/")
+		repo.Name = segments[len(segments)-1]
+	default:
+		return nil, fmt.Errorf("repository URL must include an owner and a name: %s", repoURL)
+	}
+	return repo, nil
+}
+
+// New returns the provider API client for repoURL.
+func New(ctx context.Context, repoURL string, opts Options) (Provider, error) {
+	repo, err := ParseRepo(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	if opts.Provider != "" {
+		repo.Provider = opts.Provider
+	} else if repo.Provider == git.ProviderGeneric && opts.Probe {
+		repo.Provider = Probe(ctx, httpClient, repo.Host)
+	}
+
+	api := &apiClient{token: opts.Token, username: opts.Username, http: httpClient}
+	switch repo.Provider {
+	case git.ProviderGitHub:
+		api.baseURL = githubAPIURL(repo.Host, opts.BaseURL)
+		return &gitHub{api: api, repo: repo}, nil
+	case git.ProviderGitLab:
+		api.baseURL = apiURL(opts.BaseURL, "https://"+repo.Host+"/api/v4")
+		api.header = func(req *http.Request, token string) { req.Header.Set("PRIVATE-TOKEN", token) }
+		return &gitLab{api: api, repo: repo}, nil
+	case git.ProviderGitea:
+		api.baseURL = apiURL(opts.BaseURL, "https://"+repo.Host+"/api/v1")
+		api.header = func(req *http.Request, token string) { req.Header.Set("Authorization", "token "+token) }
+		return &gitea{api: api, repo: repo}, nil
+	case git.ProviderBitbucket:
+		api.baseURL = apiURL(opts.BaseURL, "https://api.bitbucket.org/2.0")
+		return &bitbucket{api: api, repo: repo}, nil
+	case git.ProviderAzureDevOps:
+		parts := strings.SplitN(repo.Owner, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("azure DevOps repository URL must include organization and project: %s", repoURL)
+		}
+		api.baseURL = apiURL(opts.BaseURL, "https://"+repo.Host)
+		// Personal access tokens are sent as the basic auth password.
+		api.header = func(req *http.Request, token string) { req.SetBasicAuth("", token) }
+		return &azureDevOps{api: api, repo: repo, organization: parts[0], project: parts[1]}, nil
+	default:
+		return nil, fmt.Errorf("unsupported git provider %q for %s (supported: github, gitlab, gitea, bitbucket, azure-devops)", repo.Provider, repo.Host)
+	}
+}
+
+// Probe identifies the provider serving host by querying the unauthenticated
+// version endpoints of GitLab, Gitea and GitHub Enterprise Server. It returns
+// ProviderGeneric when none answers.
+func Probe(ctx context.Context, httpClient *http.Client, host string) git.ProviderType {
+	probes := []struct {
+		path     string
+		provider git.ProviderType
+	}{
+		{"/api/v4/version", git.ProviderGitLab},
+		{"/api/v1/version", git.ProviderGitea},
+		{"/api/v3/meta", git.ProviderGitHub},
+	}
+	for _, p := range probes {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+p.path, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		// GitLab answers 401 to anonymous version requests.
+		if resp.StatusCode == http.StatusOK || (p.provider == git.ProviderGitLab && resp.StatusCode == http.StatusUnauthorized) {
+			return p.provider
+		}
+	}
+	return git.ProviderGeneric
+}
+
+func githubAPIURL(host, override string) string {
+	if host == "github.com" {
+		return apiURL(override, "https://api.github.com")
+	}
+	return apiURL(override, "https://"+host+"/api/v3")
+}
+
+func apiURL(override, derived string) string {
+	if override != "" {
+		return strings.TrimSuffix(override, "/")
+	}
+	return derived
+}
+
+// webhookEvents returns the events of opts, defaulting to push.
+func webhookEvents(opts git.WebhookOptions) []string {
+	if len(opts.Events) == 0 {
+		return []string{EventPush}
+	}
+	return opts.Events
+}
+
+func hasEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
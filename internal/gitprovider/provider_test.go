@@ -0,0 +1,211 @@
+package gitprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/git"
+)
+
+// request is a request recorded by apiServer.
+type request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   interface{}
+}
+
+// response is the canned answer of apiServer for "METHOD /escaped/path".
+type response struct {
+	Status int
+	Body   interface{}
+}
+
+// apiServer answers requests from routes and records them. Unknown routes
+// return 404.
+func apiServer(t *testing.T, routes map[string]response) (*httptest.Server, *[]request) {
+	t.Helper()
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := request{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery, Header: r.Header}
+		_ = json.NewDecoder(r.Body).Decode(&rec.Body)
+		requests = append(requests, rec)
+
+		resp, ok := routes[r.Method+" "+rec.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+		if resp.Status == 0 {
+			resp.Status = http.StatusOK
+		}
+		w.WriteHeader(resp.Status)
+		if resp.Body != nil {
+			_ = json.NewEncoder(w).Encode(resp.Body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// bodyOf returns the JSON object sent in the last request to method path.
+func bodyOf(t *testing.T, requests []request, method, path string) map[string]interface{} {
+	t.Helper()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Method == method && requests[i].Path == path {
+			body, _ := requests[i].Body.(map[string]interface{})
+			return body
+		}
+	}
+	t.Fatalf("no %s %s request in %v", method, path, requests)
+	return nil
+}
+
+func newTestProvider(t *testing.T, repoURL string, provider git.ProviderType, server *httptest.Server) Provider {
+	t.Helper()
+	p, err := New(context.Background(), repoURL, Options{Provider: provider, Token: "tok", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return p
+}
+
+func TestParseRepo(t *testing.T) {
+	tests := []struct {
+		url      string
+		provider git.ProviderType
+		host     string
+		owner    string
+		name     string
+	}{
+		{"https://github.com/org/platform.git", git.ProviderGitHub, "github.com", "org", "platform"},
+		{"git@github.com:org/platform.git", git.ProviderGitHub, "github.com", "org", "platform"},
+		{"ssh://git@gitlab.com/group/sub/platform.git", git.ProviderGitLab, "gitlab.com", "group/sub", "platform"},
+		{"https://gitlab.example.com:8443/group/platform", git.ProviderGitLab, "gitlab.example.com:8443", "group", "platform"},
+		{"https://bitbucket.org/workspace/platform.git", git.ProviderBitbucket, "bitbucket.org", "workspace", "platform"},
+		{"https://dev.azure.com/acme/infra/_git/platform", git.ProviderAzureDevOps, "dev.azure.com", "acme/infra", "platform"},
+		{"git@ssh.dev.azure.com:v3/acme/infra/platform", git.ProviderAzureDevOps, "dev.azure.com", "acme/infra", "platform"},
+		{"https://gitea.example.com/org/platform.git", git.ProviderGitea, "gitea.example.com", "org", "platform"},
+		{"https://git.example.com/org/platform.git", git.ProviderGeneric, "git.example.com", "org", "platform"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			repo, err := ParseRepo(tt.url)
+			if err != nil {
+				t.Fatalf("ParseRepo() error = %v", err)
+			}
+			if repo.Provider != tt.provider || repo.Host != tt.host || repo.Owner != tt.owner || repo.Name != tt.name {
+				t.Errorf("ParseRepo() = %+v", repo)
+			}
+		})
+	}
+
+	for url, want := range map[string]string{
+		"https://gitlab.example.com:8443/group/sub/platform": "git@gitlab.example.com:group/sub/platform.git",
+		"git@github.com:org/platform.git":                    "git@github.com:org/platform.git",
+		"https://dev.azure.com/acme/infra/_git/platform":     "git@ssh.dev.azure.com:v3/acme/infra/platform",
+	} {
+		repo, _ := ParseRepo(url)
+		if got := repo.SSHURL(); got != want {
+			t.Errorf("SSHURL(%s) = %s, want %s", url, got, want)
+		}
+	}
+
+	for _, bad := range []string{"", "platform", "https://github.com/platform"} {
+		if _, err := ParseRepo(bad); err == nil {
+			t.Errorf("ParseRepo(%q) expected error", bad)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		url      string
+		provider git.ProviderType
+		baseURL  string
+	}{
+		{"https://github.com/org/platform.git", "", "https://api.github.com"},
+		{"https://ghe.example.com/org/platform.git", git.ProviderGitHub, "https://ghe.example.com/api/v3"},
+		{"https://gitlab.com/group/platform.git", "", "https://gitlab.com/api/v4"},
+		{"https://git.example.com/org/platform.git", git.ProviderGitea, "https://git.example.com/api/v1"},
+		{"https://bitbucket.org/ws/platform.git", "", "https://api.bitbucket.org/2.0"},
+		{"https://dev.azure.com/acme/infra/_git/platform", "", "https://dev.azure.com"},
+	}
+	for _, tt := range tests {
+		p, err := New(ctx, tt.url, Options{Provider: tt.provider})
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", tt.url, err)
+		}
+		var api *apiClient
+		switch p := p.(type) {
+		case *gitHub:
+			api = p.api
+		case *gitLab:
+			api = p.api
+		case *gitea:
+			api = p.api
+		case *bitbucket:
+			api = p.api
+		case *azureDevOps:
+			api = p.api
+		}
+		if api.baseURL != tt.baseURL {
+			t.Errorf("New(%s) baseURL = %s, want %s", tt.url, api.baseURL, tt.baseURL)
+		}
+	}
+
+	if _, err := New(ctx, "https://git.example.com/org/platform.git", Options{}); err == nil || !strings.Contains(err.Error(), "unsupported git provider") {
+		t.Errorf("expected unsupported provider error, got %v", err)
+	}
+	if _, err := New(ctx, "https://git.example.com/org/platform.git", Options{Provider: git.ProviderAzureDevOps}); err == nil {
+		t.Error("expected error for an Azure DevOps URL without a project")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/version" {
+			_, _ = w.Write([]byte(`{"version":"1.21.0"}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	if got := Probe(context.Background(), server.Client(), host); got != git.ProviderGitea {
+		t.Errorf("Probe() = %s, want gitea", got)
+	}
+
+	p, err := New(context.Background(), server.URL+"/org/platform.git", Options{HTTPClient: server.Client(), Probe: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if p.Name() != git.ProviderGitea || p.Repo().Host != host {
+		t.Errorf("unexpected provider %s for %+v", p.Name(), p.Repo())
+	}
+
+	if got := Probe(context.Background(), server.Client(), "127.0.0.1:1"); got != git.ProviderGeneric {
+		t.Errorf("Probe() of an unreachable host = %s, want generic", got)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server, _ := apiServer(t, nil)
+	p := newTestProvider(t, "https://github.com/org/platform.git", "", server)
+	_, err := p.GetRepo(context.Background())
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "API returned 404") {
+		t.Errorf("unexpected message %q", err)
+	}
+}
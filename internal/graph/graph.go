@@ -0,0 +1,452 @@
+// Package graph builds the dependency graph of a GitOps repository: the
+// AppProjects, the Applications, ApplicationSets and Flux Kustomizations they
+// hold, the paths and charts these deploy, the installed patterns owning the
+// paths and the patterns these depend on.
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+	"github.com/ihsanmokhlisse/gitopsi/internal/syncwave"
+)
+
+// Kind is the kind of a node of the graph.
+type Kind string
+
+const (
+	KindProject        Kind = "project"
+	KindApplication    Kind = "application"
+	KindApplicationSet Kind = "applicationset"
+	KindKustomization  Kind = "kustomization"
+	KindPath           Kind = "path"
+	KindChart          Kind = "chart"
+	KindPattern        Kind = "pattern"
+)
+
+// rank orders the kinds from left to right.
+var rank = map[Kind]int{
+	KindProject:        0,
+	KindApplication:    1,
+	KindApplicationSet: 1,
+	KindKustomization:  1,
+	KindPath:           2,
+	KindChart:          2,
+	KindPattern:        3,
+}
+
+// Node is a node of the graph.
+type Node struct {
+	ID    string `json:"id" yaml:"id"`
+	Kind  Kind   `json:"kind" yaml:"kind"`
+	Label string `json:"label" yaml:"label"`
+	// File is the manifest declaring the node, relative to the repository.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+// Edge links two nodes of the graph.
+type Edge struct {
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+}
+
+// Graph is the dependency graph of a repository.
+type Graph struct {
+	Nodes []Node `json:"nodes" yaml:"nodes"`
+	Edges []Edge `json:"edges" yaml:"edges"`
+
+	index map[string]int
+	edges map[Edge]bool
+}
+
+// New creates an empty graph.
+func New() *Graph {
+	return &Graph{index: map[string]int{}, edges: map[Edge]bool{}}
+}
+
+// AddNode adds a node, keeping the first of nodes with the same ID.
+func (g *Graph) AddNode(id string, kind Kind, label, file string) string {
+	if _, ok := g.index[id]; !ok {
+		g.index[id] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, Node{ID: id, Kind: kind, Label: label, File: file})
+	}
+	return id
+}
+
+// AddEdge adds an edge between two nodes once.
+func (g *Graph) AddEdge(from, to, label string) {
+	e := Edge{From: from, To: to, Label: label}
+	if g.edges[e] {
+		return
+	}
+	g.edges[e] = true
+	g.Edges = append(g.Edges, e)
+}
+
+// Node returns the node with id.
+func (g *Graph) Node(id string) (Node, bool) {
+	i, ok := g.index[id]
+	if !ok {
+		return Node{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// sort orders the nodes by kind and ID, and the edges by their nodes, so
+// that renderings are stable.
+func (g *Graph) sort() {
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		a, b := g.Nodes[i], g.Nodes[j]
+		if rank[a.Kind] != rank[b.Kind] {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		return a.ID < b.ID
+	})
+	for i, n := range g.Nodes {
+		g.index[n.ID] = i
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return g.index[a.From] < g.index[b.From]
+		}
+		return g.index[a.To] < g.index[b.To]
+	})
+}
+
+// source is an ArgoCD Application source.
+type source struct {
+	RepoURL        string `yaml:"repoURL"`
+	Path           string `yaml:"path"`
+	Chart          string `yaml:"chart"`
+	TargetRevision string `yaml:"targetRevision"`
+}
+
+// appSpec is the part of an ArgoCD Application spec the graph reads.
+type appSpec struct {
+	Project string   `yaml:"project"`
+	Source  *source  `yaml:"source"`
+	Sources []source `yaml:"sources"`
+}
+
+// manifest is the part of a manifest the graph reads.
+type manifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name        string            `yaml:"name"`
+		Namespace   string            `yaml:"namespace"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec struct {
+		appSpec    `yaml:",inline"`
+		Generators []generator `yaml:"generators"`
+		Template   struct {
+			Spec appSpec `yaml:"spec"`
+		} `yaml:"template"`
+		// Path and DependsOn are the fields of Flux Kustomizations.
+		Path      string `yaml:"path"`
+		DependsOn []struct {
+			Name string `yaml:"name"`
+		} `yaml:"dependsOn"`
+	} `yaml:"spec"`
+}
+
+// generator is an ApplicationSet generator, of which the graph reads the
+// directories of Git generators, also nested in matrix and merge generators.
+type generator struct {
+	Git *struct {
+		Directories []struct {
+			Path    string `yaml:"path"`
+			Exclude bool   `yaml:"exclude"`
+		} `yaml:"directories"`
+	} `yaml:"git"`
+	Matrix *struct {
+		Generators []generator `yaml:"generators"`
+	} `yaml:"matrix"`
+	Merge *struct {
+		Generators []generator `yaml:"generators"`
+	} `yaml:"merge"`
+}
+
+func (g generator) directories() []string {
+	var dirs []string
+	if g.Git != nil {
+		for _, d := range g.Git.Directories {
+			if !d.Exclude {
+				dirs = append(dirs, d.Path)
+			}
+		}
+	}
+	var nested []generator
+	if g.Matrix != nil {
+		nested = append(nested, g.Matrix.Generators...)
+	}
+	if g.Merge != nil {
+		nested = append(nested, g.Merge.Generators...)
+	}
+	for _, n := range nested {
+		dirs = append(dirs, n.directories()...)
+	}
+	return dirs
+}
+
+// Build builds the graph of the repository at root from its manifests and
+// the pattern state of .gitopsi/patterns.yaml and the pattern lockfile.
+func Build(root string) (*Graph, error) {
+	g := New()
+	files, err := manifestFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		g.addManifests(file, data)
+	}
+	if err := g.addPatterns(root); err != nil {
+		return nil, err
+	}
+	g.sort()
+	return g, nil
+}
+
+// FromFiles builds the graph of manifests held in memory, by path relative to
+// the repository, without pattern state.
+func FromFiles(files map[string][]byte) *Graph {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	g := New()
+	for _, path := range paths {
+		g.addManifests(path, files[path])
+	}
+	g.sort()
+	return g
+}
+
+// manifestFiles lists the YAML files of the repository, relative to root,
+// skipping the Git and gitopsi directories.
+func manifestFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(file string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != root && (d.Name() == ".git" || d.Name() == ".gitopsi") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifests: %w", err)
+	}
+	return files, nil
+}
+
+// addManifests adds the AppProjects, Applications, ApplicationSets and Flux
+// Kustomizations of a file. Reading stops at the first document that does not
+// parse: gitopsi validate reports it.
+func (g *Graph) addManifests(file string, data []byte) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var m manifest
+		if err := dec.Decode(&m); err != nil {
+			return
+		}
+		if m.Metadata.Name == "" {
+			continue
+		}
+		switch {
+		case m.Kind == "AppProject" && isArgoCD(m.APIVersion):
+			g.AddNode("project/"+m.Metadata.Name, KindProject, m.Metadata.Name, file)
+		case m.Kind == "Application" && isArgoCD(m.APIVersion):
+			id := g.AddNode("application/"+m.Metadata.Name, KindApplication, appLabel(&m), file)
+			g.addApp(id, m.Spec.appSpec, nil)
+		case m.Kind == "ApplicationSet" && isArgoCD(m.APIVersion):
+			id := g.AddNode("applicationset/"+m.Metadata.Name, KindApplicationSet, m.Metadata.Name, file)
+			var dirs []string
+			for _, gen := range m.Spec.Generators {
+				dirs = append(dirs, gen.directories()...)
+			}
+			g.addApp(id, m.Spec.Template.Spec, dirs)
+		case m.Kind == "Kustomization" && strings.HasPrefix(m.APIVersion, "kustomize.toolkit.fluxcd.io/"):
+			id := g.AddNode("kustomization/"+m.Metadata.Name, KindKustomization, m.Metadata.Name, file)
+			if p := cleanPath(m.Spec.Path); p != "" {
+				g.AddEdge(id, g.AddNode("path/"+p, KindPath, p, ""), "")
+			}
+			for _, dep := range m.Spec.DependsOn {
+				to := g.AddNode("kustomization/"+dep.Name, KindKustomization, dep.Name, "")
+				g.AddEdge(id, to, "depends on")
+			}
+		}
+	}
+}
+
+func isArgoCD(apiVersion string) bool {
+	return strings.HasPrefix(apiVersion, "argoproj.io/")
+}
+
+// appLabel labels an Application with its sync wave.
+func appLabel(m *manifest) string {
+	if wave, ok := m.Metadata.Annotations[syncwave.Annotation]; ok && wave != "" && wave != "0" {
+		return fmt.Sprintf("%s (wave %s)", m.Metadata.Name, wave)
+	}
+	return m.Metadata.Name
+}
+
+// addApp links an Application or ApplicationSet to its project and sources.
+// The templated paths of ApplicationSets are replaced by the directories of
+// their Git generators.
+func (g *Graph) addApp(id string, spec appSpec, dirs []string) {
+	project := spec.Project
+	if project == "" {
+		project = "default"
+	}
+	g.AddEdge(g.AddNode("project/"+project, KindProject, project, ""), id, "")
+
+	sources := spec.Sources
+	if spec.Source != nil {
+		sources = append([]source{*spec.Source}, sources...)
+	}
+	for _, s := range sources {
+		switch {
+		case s.Chart != "":
+			label := s.Chart
+			if s.TargetRevision != "" {
+				label += " " + s.TargetRevision
+			}
+			g.AddEdge(id, g.AddNode("chart/"+s.RepoURL+"/"+s.Chart, KindChart, label, ""), "")
+		case strings.Contains(s.Path, "{{") && len(dirs) > 0:
+			for _, dir := range dirs {
+				p := cleanPath(dir)
+				g.AddEdge(id, g.AddNode("path/"+p, KindPath, p, ""), "")
+			}
+		case s.Path != "":
+			p := cleanPath(s.Path)
+			g.AddEdge(id, g.AddNode("path/"+p, KindPath, p, ""), "")
+		}
+	}
+}
+
+// cleanPath normalizes a repository path such as ./apps/ to apps.
+func cleanPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	p = path.Clean(strings.TrimPrefix(p, "./"))
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// addPatterns adds the installed patterns owning the paths of the graph,
+// and the dependencies of the installed and locked patterns.
+func (g *Graph) addPatterns(root string) error {
+	installed, err := marketplace.NewInstaller(nil, root, "", "").ListInstalled()
+	if err != nil {
+		return err
+	}
+	lock, err := marketplace.LoadLock(filepath.Join(root, marketplace.LockFile))
+	if err != nil {
+		return err
+	}
+
+	patternNode := func(name string) string {
+		label := name
+		if locked, ok := lock.Patterns[name]; ok && locked.Version != "" {
+			label += " " + locked.Version
+		}
+		return g.AddNode("pattern/"+name, KindPattern, label, "")
+	}
+
+	sort.Slice(installed, func(i, j int) bool {
+		return installed[i].Pattern.Metadata.Name < installed[j].Pattern.Metadata.Name
+	})
+	for _, p := range installed {
+		meta := p.Pattern.Metadata
+		if meta.Name == "" {
+			continue
+		}
+		id := g.AddNode("pattern/"+meta.Name, KindPattern, patternLabel(&p), "")
+		dir := path.Join("infrastructure", meta.Category, meta.Name)
+		for _, n := range g.Nodes {
+			if n.Kind == KindPath && (n.Label == dir || strings.HasPrefix(n.Label, dir+"/")) {
+				g.AddEdge(n.ID, id, "")
+			}
+		}
+	}
+	for _, p := range installed {
+		for _, dep := range p.Pattern.Spec.Dependencies {
+			label := "depends on"
+			if dep.Optional {
+				label = "optionally depends on"
+			}
+			g.AddEdge(patternNode(p.Pattern.Metadata.Name), patternNode(dep.Name), label)
+		}
+	}
+
+	names := make([]string, 0, len(lock.Patterns))
+	for name := range lock.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		deps := make([]string, 0, len(lock.Patterns[name].Dependencies))
+		for dep := range lock.Patterns[name].Dependencies {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if from, to := patternNode(name), patternNode(dep); !g.linked(from, to) {
+				g.AddEdge(from, to, "depends on")
+			}
+		}
+	}
+	return nil
+}
+
+// linked reports whether an edge goes from one node to another.
+func (g *Graph) linked(from, to string) bool {
+	for e := range g.edges {
+		if e.From == from && e.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+func patternLabel(p *marketplace.InstalledPattern) string {
+	if p.Pattern.Metadata.Version == "" {
+		return p.Pattern.Metadata.Name
+	}
+	return p.Pattern.Metadata.Name + " " + p.Pattern.Metadata.Version
+}
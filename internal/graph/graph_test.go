@@ -0,0 +1,252 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// repository writes a repository with a project, an Application of an
+// installed pattern depending on another, an ApplicationSet and a Flux
+// Kustomization.
+func repository(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "argocd/projects/infrastructure.yaml"), `apiVersion: argoproj.io/v1alpha1
+kind: AppProject
+metadata:
+  name: infrastructure
+`)
+	writeFile(t, filepath.Join(root, "argocd/applications/monitoring-prod.yaml"), `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: monitoring-prod
+  annotations:
+    argocd.argoproj.io/sync-wave: "1"
+spec:
+  project: infrastructure
+  sources:
+    - repoURL: https://github.com/org/platform.git
+      path: ./infrastructure/observability/monitoring/overlays/prod
+    - repoURL: https://prometheus-community.github.io/helm-charts
+      chart: kube-prometheus-stack
+      targetRevision: 58.0.0
+`)
+	writeFile(t, filepath.Join(root, "argocd/applicationsets/apps.yaml"), `apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: apps-git
+spec:
+  generators:
+    - git:
+        repoURL: https://github.com/org/platform.git
+        directories:
+          - path: applications/overlays/*
+  template:
+    spec:
+      source:
+        path: '{{path}}'
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+`)
+	writeFile(t, filepath.Join(root, "flux/kustomizations/apps-prod.yaml"), `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: apps-prod
+spec:
+  path: ./applications/overlays/prod
+  dependsOn:
+    - name: infra-prod
+`)
+	writeFile(t, filepath.Join(root, "infrastructure/observability/monitoring/base/kustomization.yaml"), `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources: []
+`)
+	writeFile(t, filepath.Join(root, ".gitopsi/patterns.yaml"), `patterns:
+  monitoring:
+    pattern:
+      metadata:
+        name: monitoring
+        version: 1.2.0
+        category: observability
+      spec:
+        dependencies:
+          - name: cert-manager
+    status: installed
+`)
+	writeFile(t, filepath.Join(root, ".gitopsi/patterns.lock.yaml"), `version: "1.0"
+patterns:
+  monitoring:
+    version: 1.2.0
+    registry: official
+    dependencies:
+      cert-manager: ^1.0.0
+  cert-manager:
+    version: 1.4.0
+    registry: official
+    dependencies:
+      crds: "*"
+`)
+	return root
+}
+
+func TestBuild(t *testing.T) {
+	g, err := Build(repository(t))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	nodes := map[string]string{
+		"project/infrastructure":                                     "infrastructure",
+		"project/default":                                            "default",
+		"application/monitoring-prod":                                "monitoring-prod (wave 1)",
+		"applicationset/apps-git":                                    "apps-git",
+		"kustomization/apps-prod":                                    "apps-prod",
+		"kustomization/infra-prod":                                   "infra-prod",
+		"path/infrastructure/observability/monitoring/overlays/prod": "infrastructure/observability/monitoring/overlays/prod",
+		"path/applications/overlays/*":                               "applications/overlays/*",
+		"path/applications/overlays/prod":                            "applications/overlays/prod",
+		"chart/https://prometheus-community.github.io/helm-charts/kube-prometheus-stack": "kube-prometheus-stack 58.0.0",
+		"pattern/monitoring":   "monitoring 1.2.0",
+		"pattern/cert-manager": "cert-manager 1.4.0",
+		"pattern/crds":         "crds",
+	}
+	if len(g.Nodes) != len(nodes) {
+		t.Errorf("Build() has %d nodes, want %d: %+v", len(g.Nodes), len(nodes), g.Nodes)
+	}
+	for id, label := range nodes {
+		if n, ok := g.Node(id); !ok || n.Label != label {
+			t.Errorf("node %s = %+v, want label %q", id, n, label)
+		}
+	}
+	if n, _ := g.Node("application/monitoring-prod"); n.File != "argocd/applications/monitoring-prod.yaml" {
+		t.Errorf("application file = %q", n.File)
+	}
+
+	edges := []Edge{
+		{From: "project/infrastructure", To: "application/monitoring-prod"},
+		{From: "project/default", To: "applicationset/apps-git"},
+		{From: "application/monitoring-prod", To: "path/infrastructure/observability/monitoring/overlays/prod"},
+		{From: "application/monitoring-prod", To: "chart/https://prometheus-community.github.io/helm-charts/kube-prometheus-stack"},
+		{From: "applicationset/apps-git", To: "path/applications/overlays/*"},
+		{From: "kustomization/apps-prod", To: "path/applications/overlays/prod"},
+		{From: "kustomization/apps-prod", To: "kustomization/infra-prod", Label: "depends on"},
+		{From: "path/infrastructure/observability/monitoring/overlays/prod", To: "pattern/monitoring"},
+		{From: "pattern/monitoring", To: "pattern/cert-manager", Label: "depends on"},
+		{From: "pattern/cert-manager", To: "pattern/crds", Label: "depends on"},
+	}
+	if len(g.Edges) != len(edges) {
+		t.Errorf("Build() has %d edges, want %d: %+v", len(g.Edges), len(edges), g.Edges)
+	}
+	for _, e := range edges {
+		if !g.edges[e] {
+			t.Errorf("missing edge %+v", e)
+		}
+	}
+	if g.Nodes[0].Kind != KindProject || g.Nodes[len(g.Nodes)-1].Kind != KindPattern {
+		t.Errorf("nodes are not ordered by kind: %+v", g.Nodes)
+	}
+}
+
+func TestBuild_Empty(t *testing.T) {
+	g, err := Build(t.TempDir())
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if len(g.Nodes) != 0 || len(g.Edges) != 0 {
+		t.Errorf("Build() = %+v, want an empty graph", g)
+	}
+	if _, err := g.Render("svg", ""); err != nil {
+		t.Errorf("Render(svg) error = %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	g := New()
+	project := g.AddNode("project/default", KindProject, "default", "")
+	app := g.AddNode("application/api", KindApplication, `api "v2"`, "")
+	pattern := g.AddNode("pattern/ingress", KindPattern, "ingress", "")
+	dep := g.AddNode("pattern/cert-manager", KindPattern, "cert-manager", "")
+	g.AddEdge(project, app, "")
+	g.AddEdge(app, pattern, "")
+	g.AddEdge(pattern, dep, "depends on")
+	g.AddEdge(pattern, dep, "depends on")
+	if len(g.Edges) != 3 {
+		t.Errorf("AddEdge() added %d edges, want 3", len(g.Edges))
+	}
+
+	tests := map[string][]string{
+		"dot": {
+			"digraph gitopsi {",
+			`"project/default" [label="default", shape=folder`,
+			`"application/api" [label="api \"v2\""`,
+			`"pattern/ingress" -> "pattern/cert-manager" [label="depends on", style=dashed];`,
+		},
+		"mermaid": {
+			"flowchart LR\n",
+			`n0[/"default"/]`,
+			`n1["api #quot;v2#quot;"]`,
+			`n2{{"ingress"}}`,
+			"n0 --> n1",
+			"n2 -.->|depends on| n3",
+			"class n2,n3 pattern",
+		},
+		"md": {"# Platform\n", "```mermaid\nflowchart LR\n", "n1 --> n2"},
+		"svg": {
+			`<svg xmlns="http://www.w3.org/2000/svg"`,
+			"<text x=\"32\" y=\"38\">default</text>",
+			"api &#34;v2&#34;",
+			`stroke-dasharray="4 3"><title>depends on</title>`,
+		},
+	}
+	for format, want := range tests {
+		t.Run(format, func(t *testing.T) {
+			out, err := g.Render(format, "Platform")
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, w := range want {
+				if !strings.Contains(string(out), w) {
+					t.Errorf("Render(%s) does not contain %q:\n%s", format, w, out)
+				}
+			}
+		})
+	}
+	if _, err := g.Render("png", ""); err == nil {
+		t.Error("Render(png) should fail")
+	}
+}
+
+func TestColumns(t *testing.T) {
+	g := New()
+	g.AddNode("path/apps", KindPath, "apps", "")
+	a := g.AddNode("pattern/a", KindPattern, "a", "")
+	b := g.AddNode("pattern/b", KindPattern, "b", "")
+	c := g.AddNode("pattern/c", KindPattern, "c", "")
+	g.AddEdge(a, b, "depends on")
+	g.AddEdge(b, c, "depends on")
+	columns := g.columns()
+	if columns["path/apps"] != 2 || columns[a] != 3 || columns[b] != 4 || columns[c] != 5 {
+		t.Errorf("columns() = %v", columns)
+	}
+
+	g.AddEdge(c, a, "depends on")
+	columns = g.columns()
+	if columns[a] != 3 || columns[c] != 3 {
+		t.Errorf("columns() with a cycle = %v", columns)
+	}
+}
@@ -0,0 +1,242 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/syncwave"
+)
+
+// Formats are the formats a graph is rendered in.
+var Formats = []string{"dot", "mermaid", "md", "svg"}
+
+// style is the rendering of the nodes of a kind.
+type style struct {
+	shape string // Graphviz shape
+	open  string // Mermaid shape delimiters
+	close string
+	fill  string
+}
+
+var styles = map[Kind]style{
+	KindProject:        {"folder", "[/", "/]", "#dbeafe"},
+	KindApplication:    {"box", "[", "]", "#dcfce7"},
+	KindApplicationSet: {"box3d", "[[", "]]", "#bbf7d0"},
+	KindKustomization:  {"box", "[", "]", "#dcfce7"},
+	KindPath:           {"note", ">", "]", "#f3f4f6"},
+	KindChart:          {"component", "[(", ")]", "#fef9c3"},
+	KindPattern:        {"hexagon", "{{", "}}", "#fde68a"},
+}
+
+// Render renders the graph in format: Graphviz DOT, Mermaid, a Markdown
+// document holding the Mermaid graph under title, or SVG.
+func (g *Graph) Render(format, title string) ([]byte, error) {
+	switch format {
+	case "dot":
+		return []byte(g.DOT()), nil
+	case "mermaid":
+		return []byte(g.Mermaid()), nil
+	case "md":
+		return []byte(g.Markdown(title)), nil
+	case "svg":
+		return []byte(g.SVG()), nil
+	default:
+		return nil, fmt.Errorf("unsupported graph format: %s (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph gitopsi {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=10, style=filled];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n")
+	for _, n := range g.Nodes {
+		s := styles[n.Kind]
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s, fillcolor=%q];\n", strconv.Quote(n.ID), strconv.Quote(n.Label), s.shape, s.fill)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if e.Label != "" {
+			fmt.Fprintf(&b, " [label=%s, style=dashed]", strconv.Quote(e.Label))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g *Graph) Mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	classes := map[Kind][]string{}
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		classes[n.Kind] = append(classes[n.Kind], id)
+		s := styles[n.Kind]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", id, s.open, Quote(n.Label), s.close)
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %s -.->|%s| %s\n", ids[e.From], e.Label, ids[e.To])
+			continue
+		}
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+	for _, kind := range []Kind{KindProject, KindApplication, KindApplicationSet, KindKustomization, KindPath, KindChart, KindPattern} {
+		if len(classes[kind]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#6b7280\n", kind, styles[kind].fill)
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[kind], ","), kind)
+	}
+	return b.String()
+}
+
+// Quote quotes a Mermaid label.
+func Quote(label string) string {
+	return `"` + strings.ReplaceAll(label, `"`, "#quot;") + `"`
+}
+
+// Markdown renders a Markdown document holding the Mermaid graph, which
+// GitHub, GitLab and most documentation sites draw.
+func (g *Graph) Markdown(title string) string {
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", title)
+	}
+	b.WriteString("Generated by `gitopsi graph`: AppProjects, the Applications, ApplicationSets\n")
+	b.WriteString("and Flux Kustomizations they hold, the paths and charts these deploy, and the\n")
+	b.WriteString("patterns owning the paths with their dependencies.\n\n")
+	b.WriteString("```mermaid\n")
+	b.WriteString(g.Mermaid())
+	b.WriteString("```\n")
+	return b.String()
+}
+
+// Layout of the SVG rendering, in pixels.
+const (
+	svgMargin    = 20
+	svgCharWidth = 7
+	svgPadding   = 12
+	svgHeight    = 28
+	svgRowGap    = 12
+	svgColumnGap = 80
+)
+
+// SVG renders the graph as an SVG image, laid out in columns from the
+// AppProjects on the left to the patterns on the right, the dependencies of
+// patterns right of the patterns depending on them.
+func (g *Graph) SVG() string {
+	columns := g.columns()
+	last := 0
+	for _, c := range columns {
+		last = max(last, c)
+	}
+
+	widths := make([]int, last+1)
+	rows := make([]int, last+1)
+	type box struct{ x, y, w int }
+	boxes := make(map[string]box, len(g.Nodes))
+	for _, n := range g.Nodes {
+		widths[columns[n.ID]] = max(widths[columns[n.ID]], len(n.Label)*svgCharWidth+2*svgPadding)
+	}
+	xs := make([]int, last+1)
+	x := svgMargin
+	for c := range widths {
+		xs[c] = x
+		if widths[c] > 0 {
+			x += widths[c] + svgColumnGap
+		}
+	}
+	height := 0
+	for _, n := range g.Nodes {
+		c := columns[n.ID]
+		y := svgMargin + rows[c]*(svgHeight+svgRowGap)
+		rows[c]++
+		boxes[n.ID] = box{xs[c], y, widths[c]}
+		height = max(height, y+svgHeight+svgMargin)
+	}
+	width := max(x-svgColumnGap+svgMargin, 2*svgMargin)
+	height = max(height, 2*svgMargin)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"12\">\n", width, height, width, height)
+	b.WriteString("  <defs><marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"6\" markerHeight=\"6\" orient=\"auto-start-reverse\"><path d=\"M 0 0 L 10 5 L 0 10 z\" fill=\"#6b7280\"/></marker></defs>\n")
+	for _, e := range g.Edges {
+		from, to := boxes[e.From], boxes[e.To]
+		x1, y1 := from.x+from.w, from.y+svgHeight/2
+		x2, y2 := to.x, to.y+svgHeight/2
+		bend := svgColumnGap / 2
+		if to.x <= from.x {
+			// Edges within a column loop around its right side.
+			x2 = to.x + to.w
+			bend = svgColumnGap/2 + abs(y2-y1)/4
+			fmt.Fprintf(&b, "  <path d=\"M %d %d C %d %d, %d %d, %d %d\"", x1, y1, x1+bend, y1, x2+bend, y2, x2, y2)
+		} else {
+			fmt.Fprintf(&b, "  <path d=\"M %d %d C %d %d, %d %d, %d %d\"", x1, y1, x1+bend, y1, x2-bend, y2, x2, y2)
+		}
+		b.WriteString(" fill=\"none\" stroke=\"#6b7280\" marker-end=\"url(#arrow)\"")
+		if e.Label != "" {
+			b.WriteString(" stroke-dasharray=\"4 3\"><title>" + html.EscapeString(e.Label) + "</title></path>\n")
+			continue
+		}
+		b.WriteString("/>\n")
+	}
+	for _, n := range g.Nodes {
+		bx := boxes[n.ID]
+		fmt.Fprintf(&b, "  <g><title>%s</title><rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"4\" fill=\"%s\" stroke=\"#6b7280\"/>", html.EscapeString(string(n.Kind)+": "+n.Label), bx.x, bx.y, bx.w, svgHeight, styles[n.Kind].fill)
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">%s</text></g>\n", bx.x+svgPadding, bx.y+svgHeight/2+4, html.EscapeString(n.Label))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// columns assigns the nodes to the columns of the SVG rendering. Patterns
+// are placed one column right of the latest pattern depending on them; a
+// dependency cycle keeps them in one column.
+func (g *Graph) columns() map[string]int {
+	dependsOn := map[string][]string{}
+	for _, n := range g.Nodes {
+		if n.Kind == KindPattern {
+			dependsOn[n.ID] = nil
+		}
+	}
+	for _, e := range g.Edges {
+		if _, ok := dependsOn[e.From]; ok {
+			if _, ok := dependsOn[e.To]; ok {
+				dependsOn[e.From] = append(dependsOn[e.From], e.To)
+			}
+		}
+	}
+	waves, err := syncwave.Compute(dependsOn)
+	if err != nil {
+		waves = nil
+	}
+	deepest := 0
+	for _, w := range waves {
+		deepest = max(deepest, w)
+	}
+
+	columns := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		columns[n.ID] = rank[n.Kind]
+		if n.Kind == KindPattern {
+			columns[n.ID] += deepest - waves[n.ID]
+		}
+	}
+	return columns
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,309 @@
+// Package hooks installs Git hooks that validate manifests and scan changes
+// for leaked secrets before they are committed or pushed.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/scan"
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
+)
+
+const (
+	// PreCommit runs on the staged manifests.
+	PreCommit = "pre-commit"
+	// PrePush runs on the whole repository.
+	PrePush = "pre-push"
+
+	// Marker identifies hooks installed by gitopsi, which are overwritten
+	// without --force.
+	Marker = "# Installed by gitopsi"
+
+	// PreCommitConfigFile is the configuration of the pre-commit framework.
+	PreCommitConfigFile = ".pre-commit-config.yaml"
+)
+
+// Names are the hooks gitopsi installs.
+var Names = []string{PreCommit, PrePush}
+
+// Mode selects how hooks are installed.
+type Mode string
+
+const (
+	// ModeGit writes scripts to the hooks directory of the repository.
+	ModeGit Mode = "git"
+	// ModePreCommit writes a .pre-commit-config.yaml for the pre-commit
+	// framework.
+	ModePreCommit Mode = "pre-commit"
+)
+
+// InstallOptions configures Install.
+type InstallOptions struct {
+	// Dir is a directory of the Git repository.
+	Dir  string
+	Mode Mode
+	// Force overwrites hooks and configuration not written by gitopsi.
+	Force bool
+}
+
+// Install writes the hooks and returns the written files.
+func Install(opts *InstallOptions) ([]string, error) {
+	repo, root, err := openRepository(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Mode {
+	case ModePreCommit:
+		path := filepath.Join(root, PreCommitConfigFile)
+		if err := writeOwned(path, []byte(preCommitConfig()), 0644, opts.Force); err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	case ModeGit, "":
+		dir, err := hooksDir(repo, root)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+		}
+		var files []string
+		for _, name := range Names {
+			path := filepath.Join(dir, name)
+			if err := writeOwned(path, []byte(script(name)), 0755, opts.Force); err != nil {
+				return nil, err
+			}
+			files = append(files, path)
+		}
+		return files, nil
+	default:
+		return nil, fmt.Errorf("unsupported hook mode: %s (valid: %s, %s)", opts.Mode, ModeGit, ModePreCommit)
+	}
+}
+
+// script returns the Git hook running `gitopsi hooks run`.
+func script(name string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s: validates manifests and scans them for secrets.
+# Reinstall with: gitopsi hooks install --force
+exec gitopsi hooks run %s
+`, Marker, name)
+}
+
+// preCommitConfig returns a pre-commit configuration running the hooks.
+func preCommitConfig() string {
+	return Marker + `: validates manifests and scans them for secrets.
+# Enable with: pre-commit install --hook-type pre-commit --hook-type pre-push
+repos:
+  - repo: local
+    hooks:
+      - id: gitopsi-pre-commit
+        name: gitopsi validate and secret scan
+        entry: gitopsi hooks run pre-commit
+        language: system
+        stages: [pre-commit]
+      - id: gitopsi-pre-push
+        name: gitopsi validate and secret scan
+        entry: gitopsi hooks run pre-push
+        language: system
+        pass_filenames: false
+        always_run: true
+        stages: [pre-push]
+`
+}
+
+// writeOwned writes content to path unless path exists and was not written
+// by gitopsi.
+func writeOwned(path string, content []byte, perm os.FileMode, force bool) error {
+	existing, err := os.ReadFile(path)
+	if err == nil && !force && !bytes.Contains(existing, []byte(Marker)) {
+		return fmt.Errorf("%s already exists and was not installed by gitopsi: use --force to overwrite it", path)
+	}
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Chmod(path, perm)
+}
+
+// openRepository opens the Git repository containing dir and returns its root.
+func openRepository(dir string) (*git.Repository, string, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open Git repository at %s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open worktree: %w", err)
+	}
+	return repo, wt.Filesystem.Root(), nil
+}
+
+// hooksDir returns the hooks directory, honouring core.hooksPath.
+func hooksDir(repo *git.Repository, root string) (string, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", fmt.Errorf("failed to read Git config: %w", err)
+	}
+	if path := cfg.Raw.Section("core").Option("hooksPath"); path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		return path, nil
+	}
+	gitDir := filepath.Join(root, git.GitDirName)
+	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory: worktrees and submodules need core.hooksPath", gitDir)
+	}
+	return filepath.Join(gitDir, "hooks"), nil
+}
+
+// RunOptions configures Run.
+type RunOptions struct {
+	// Dir is a directory of the Git repository.
+	Dir string
+	// Hook is PreCommit or PrePush.
+	Hook string
+	// Files limits the run to these files, relative to the repository root.
+	// Empty means the staged files for PreCommit and the repository for
+	// PrePush.
+	Files []string
+	// FailOn is the lowest severity failing validation.
+	FailOn validate.Severity
+	// SchemaLocations overrides the schema registries of the validation.
+	SchemaLocations []string
+}
+
+// Result is the outcome of a hook.
+type Result struct {
+	// Files are the files checked.
+	Files []string `json:"files,omitempty" yaml:"files,omitempty"`
+	// Repository reports whether the whole repository was checked.
+	Repository bool `json:"repository,omitempty" yaml:"repository,omitempty"`
+	// Issues are the validation issues at FailOn or above.
+	Issues []validate.Issue `json:"issues,omitempty" yaml:"issues,omitempty"`
+	// Leaks are the credentials found by the secret scan.
+	Leaks []scan.Finding `json:"leaks,omitempty" yaml:"leaks,omitempty"`
+}
+
+// Failed reports whether the hook must block the commit or push.
+func (r *Result) Failed() bool {
+	return len(r.Issues) > 0 || len(r.Leaks) > 0
+}
+
+// Run validates the manifests of the hook and scans its files for secrets.
+func Run(ctx context.Context, opts *RunOptions) (*Result, error) {
+	if opts.Hook != PreCommit && opts.Hook != PrePush {
+		return nil, fmt.Errorf("unsupported hook: %s (valid: %s)", opts.Hook, strings.Join(Names, ", "))
+	}
+	_, root, err := openRepository(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Files: opts.Files}
+	if len(opts.Files) == 0 {
+		switch opts.Hook {
+		case PreCommit:
+			if result.Files, err = stagedFiles(root); err != nil {
+				return nil, err
+			}
+		case PrePush:
+			result.Repository = true
+		}
+	}
+	if len(result.Files) == 0 && !result.Repository {
+		return result, nil
+	}
+
+	targets := manifests(result.Files)
+	if result.Repository {
+		targets = []string{"."}
+	}
+	for _, file := range targets {
+		issues, err := validateManifest(ctx, filepath.Join(root, file), opts)
+		if err != nil {
+			return nil, err
+		}
+		result.Issues = append(result.Issues, issues...)
+	}
+
+	report, err := scan.Scan(&scan.Options{Path: root, Files: result.Files})
+	if err != nil {
+		return nil, err
+	}
+	result.Leaks = report.Findings
+	return result, nil
+}
+
+// validateManifest returns the issues of path at opts.FailOn or above.
+func validateManifest(ctx context.Context, path string, opts *RunOptions) ([]validate.Issue, error) {
+	vopts := validate.DefaultOptions()
+	vopts.Path = path
+	vopts.Kustomize = false
+	vopts.Secrets = false
+	vopts.FailOn = opts.FailOn
+	vopts.SchemaLocations = opts.SchemaLocations
+	vopts.SchemaCacheDir = validate.DefaultSchemaCacheDir()
+
+	v := validate.New(vopts)
+	result, err := v.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate %s: %w", path, err)
+	}
+	var issues []validate.Issue
+	for _, issue := range result.Issues {
+		single := &validate.ValidationResult{Issues: []validate.Issue{issue}}
+		if v.ShouldFail(single) {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// stagedFiles returns the files added, modified, renamed or copied in the
+// index.
+func stagedFiles(root string) ([]string, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Git repository at %s: %w", root, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Git status: %w", err)
+	}
+
+	var files []string
+	for path, s := range status {
+		switch s.Staging {
+		case git.Added, git.Modified, git.Renamed, git.Copied:
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// manifests returns the YAML files among files.
+func manifests(files []string) []string {
+	var out []string
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".yaml", ".yml":
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/validate"
+)
+
+const privilegedPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+    - name: shell
+      image: busybox:1.36
+      securityContext:
+        privileged: true
+`
+
+func initRepo(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, repo
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallGitHooks(t *testing.T) {
+	dir, _ := initRepo(t)
+
+	files, err := Install(&InstallOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Install() wrote %v, want pre-commit and pre-push", files)
+	}
+	for _, name := range Names {
+		path := filepath.Join(dir, ".git", "hooks", name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("hook %s not installed: %v", name, err)
+		}
+		if info.Mode().Perm()&0100 == 0 {
+			t.Errorf("hook %s is not executable: %v", name, info.Mode())
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), "exec gitopsi hooks run "+name) {
+			t.Errorf("hook %s = %s", name, data)
+		}
+	}
+
+	// Reinstalling overwrites gitopsi hooks.
+	if _, err := Install(&InstallOptions{Dir: dir}); err != nil {
+		t.Errorf("Install() again error = %v", err)
+	}
+}
+
+func TestInstallKeepsForeignHooks(t *testing.T) {
+	dir, _ := initRepo(t)
+	hook := filepath.Join(dir, ".git", "hooks", PreCommit)
+	writeFile(t, hook, "#!/bin/sh\nmake lint\n")
+
+	if _, err := Install(&InstallOptions{Dir: dir}); err == nil {
+		t.Fatal("Install() expected error for an existing hook")
+	}
+	if data, _ := os.ReadFile(hook); string(data) != "#!/bin/sh\nmake lint\n" {
+		t.Errorf("existing hook overwritten: %s", data)
+	}
+
+	if _, err := Install(&InstallOptions{Dir: dir, Force: true}); err != nil {
+		t.Fatalf("Install(Force) error = %v", err)
+	}
+	if data, _ := os.ReadFile(hook); !strings.Contains(string(data), Marker) {
+		t.Errorf("hook not replaced with --force: %s", data)
+	}
+}
+
+func TestInstallHooksPath(t *testing.T) {
+	dir, repo := initRepo(t)
+	cfg, err := repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Raw.Section("core").SetOption("hooksPath", ".githooks")
+	if err := repo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Install(&InstallOptions{Dir: dir}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".githooks", PrePush)); err != nil {
+		t.Errorf("hook not installed in core.hooksPath: %v", err)
+	}
+}
+
+func TestInstallPreCommitConfig(t *testing.T) {
+	dir, _ := initRepo(t)
+
+	files, err := Install(&InstallOptions{Dir: dir, Mode: ModePreCommit})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"entry: gitopsi hooks run pre-commit", "entry: gitopsi hooks run pre-push", "stages: [pre-push]"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s missing %q", PreCommitConfigFile, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "hooks", PreCommit)); err == nil {
+		t.Error("pre-commit mode must not write Git hooks")
+	}
+}
+
+func TestRunPreCommit(t *testing.T) {
+	dir, repo := initRepo(t)
+	writeFile(t, filepath.Join(dir, "apps", "pod.yaml"), privilegedPod)
+	writeFile(t, filepath.Join(dir, "apps", "unstaged.yaml"), privilegedPod)
+	writeFile(t, filepath.Join(dir, "kubeconfig"), "users:\n- user:\n    client-key-data: "+strings.Repeat("QUJD", 8)+"\n")
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"apps/pod.yaml", "kubeconfig"} {
+		if _, err := wt.Add(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Run(context.Background(), &RunOptions{
+		Dir:             filepath.Join(dir, "apps"),
+		Hook:            PreCommit,
+		FailOn:          validate.SeverityHigh,
+		SchemaLocations: []string{filepath.Join(t.TempDir(), "{{ .ResourceKind }}.json")},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(result.Files) != 2 || result.Files[0] != "apps/pod.yaml" || result.Files[1] != "kubeconfig" {
+		t.Errorf("Files = %v, want the staged files", result.Files)
+	}
+	if len(result.Leaks) != 1 || result.Leaks[0].Rule != "kubeconfig-credentials" {
+		t.Errorf("Leaks = %+v, want the staged kubeconfig", result.Leaks)
+	}
+	if !result.Failed() || result.Issues[0].Rule != "SEC001" {
+		t.Errorf("Run() = %+v, want a privileged container failure", result)
+	}
+	for _, issue := range result.Issues {
+		if issue.Severity == validate.SeverityLow {
+			t.Errorf("issue below fail-on reported: %+v", issue)
+		}
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir, _ := initRepo(t)
+	writeFile(t, filepath.Join(dir, "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n")
+
+	result, err := Run(context.Background(), &RunOptions{
+		Dir:             dir,
+		Hook:            PrePush,
+		Files:           []string{"cm.yaml", "notes.txt"},
+		FailOn:          validate.SeverityHigh,
+		SchemaLocations: []string{filepath.Join(t.TempDir(), "{{ .ResourceKind }}.json")},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Repository || len(result.Files) != 2 || result.Failed() {
+		t.Errorf("Run() = %+v, want the files checked without failures", result)
+	}
+
+	if _, err := Run(context.Background(), &RunOptions{Dir: dir, Hook: "post-merge"}); err == nil {
+		t.Error("Run() expected error for an unsupported hook")
+	}
+}
@@ -0,0 +1,216 @@
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	manifestType = "application/vnd.oci.image.manifest.v1+json"
+	// emptyConfigType is the config of artifacts that are not images.
+	emptyConfigType = "application/vnd.oci.empty.v1+json"
+	// titleAnnotation names the file a layer holds, as set by oras.
+	titleAnnotation = "org.opencontainers.image.title"
+)
+
+// emptyConfig is the content of the empty config descriptor.
+var emptyConfig = []byte("{}")
+
+// Artifact is a set of files stored in a registry as an OCI artifact, one
+// layer per file, the way oras push stores them.
+type Artifact struct {
+	ArtifactType string
+	Annotations  map[string]string
+	Files        []File
+}
+
+// File is a file of an artifact.
+type File struct {
+	// Name is the slash-separated path of the file in the artifact.
+	Name      string
+	MediaType string
+	Data      []byte
+}
+
+// File returns the file of the artifact with the given name.
+func (a *Artifact) File(name string) (*File, bool) {
+	for i := range a.Files {
+		if a.Files[i].Name == name {
+			return &a.Files[i], true
+		}
+	}
+	return nil, false
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+func blobDigest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// Push uploads the files of artifact and tags its manifest with the tag of
+// ref. It returns the digest of the manifest.
+func (r *Resolver) Push(ctx context.Context, ref Reference, artifact *Artifact) (string, error) {
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestType,
+		ArtifactType:  artifact.ArtifactType,
+		Config:        descriptor{MediaType: emptyConfigType, Digest: blobDigest(emptyConfig), Size: int64(len(emptyConfig))},
+		Annotations:   artifact.Annotations,
+	}
+	if err := r.pushBlob(ctx, ref, emptyConfig); err != nil {
+		return "", err
+	}
+	for _, f := range artifact.Files {
+		if err := r.pushBlob(ctx, ref, f.Data); err != nil {
+			return "", fmt.Errorf("failed to push %s: %w", f.Name, err)
+		}
+		m.Layers = append(m.Layers, descriptor{
+			MediaType:   f.MediaType,
+			Digest:      blobDigest(f.Data),
+			Size:        int64(len(f.Data)),
+			Annotations: map[string]string{titleAnnotation: f.Name},
+		})
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registryHost(ref.Registry), ref.Repository, ref.TagOrDefault())
+	resp, err := r.send(ctx, ref, http.MethodPut, u, manifestType, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to push %s:%s: %w", ref.Name, ref.TagOrDefault(), err)
+	}
+	defer resp.Body.Close()
+	if err := pushStatus(resp, ref); err != nil {
+		return "", fmt.Errorf("failed to push %s:%s: %w", ref.Name, ref.TagOrDefault(), err)
+	}
+	return blobDigest(data), nil
+}
+
+// pushBlob uploads a blob in a single request unless the repository already
+// has it.
+func (r *Resolver) pushBlob(ctx context.Context, ref Reference, data []byte) error {
+	host := registryHost(ref.Registry)
+	d := blobDigest(data)
+	resp, err := r.request(ctx, ref, http.MethodHead, fmt.Sprintf("https://%s/v2/%s/blobs/%s", host, ref.Repository, d))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	start := fmt.Sprintf("https://%s/v2/%s/blobs/uploads/", host, ref.Repository)
+	resp, err = r.send(ctx, ref, http.MethodPost, start, "", []byte{})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if err := pushStatus(resp, ref); err != nil {
+		return err
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return fmt.Errorf("registry %s did not return an upload location", ref.Registry)
+	}
+	upload, _ := url.Parse(start)
+	upload = upload.ResolveReference(location)
+	q := upload.Query()
+	q.Set("digest", d)
+	upload.RawQuery = q.Encode()
+
+	resp, err = r.send(ctx, ref, http.MethodPut, upload.String(), "application/octet-stream", data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return pushStatus(resp, ref)
+}
+
+// pushStatus returns an error for a push response other than 201 Created or
+// 202 Accepted.
+func pushStatus(resp *http.Response, ref Reference) error {
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusAccepted:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("push denied by %s: store registry credentials with push access with gitopsi auth add registry", ref.Registry)
+	default:
+		return fmt.Errorf("registry %s returned %s", ref.Registry, resp.Status)
+	}
+}
+
+// Pull downloads the artifact tagged with the tag of ref. Missing tags return
+// an error wrapping ErrNotFound.
+func (r *Resolver) Pull(ctx context.Context, ref Reference) (*Artifact, error) {
+	host := registryHost(ref.Registry)
+	resp, err := r.request(ctx, ref, http.MethodGet, fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, ref.Repository, ref.TagOrDefault()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull %s:%s: %w", ref.Name, ref.TagOrDefault(), err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, ref, "tag "+ref.TagOrDefault()); err != nil {
+		return nil, fmt.Errorf("failed to pull %s:%s: %w", ref.Name, ref.TagOrDefault(), err)
+	}
+	var m manifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s:%s: %w", ref.Name, ref.TagOrDefault(), err)
+	}
+
+	artifact := &Artifact{ArtifactType: m.ArtifactType, Annotations: m.Annotations}
+	for _, layer := range m.Layers {
+		name := layer.Annotations[titleAnnotation]
+		if name == "" {
+			continue
+		}
+		data, err := r.fetchBlob(ctx, ref, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pull %s of %s:%s: %w", name, ref.Name, ref.TagOrDefault(), err)
+		}
+		artifact.Files = append(artifact.Files, File{Name: name, MediaType: layer.MediaType, Data: data})
+	}
+	return artifact, nil
+}
+
+// fetchBlob downloads a blob and checks its digest.
+func (r *Resolver) fetchBlob(ctx context.Context, ref Reference, layer descriptor) ([]byte, error) {
+	resp, err := r.request(ctx, ref, http.MethodGet, fmt.Sprintf("https://%s/v2/%s/blobs/%s", registryHost(ref.Registry), ref.Repository, layer.Digest))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, ref, "blob "+layer.Digest); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, layer.Size+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	if !strings.EqualFold(blobDigest(data), layer.Digest) {
+		return nil, fmt.Errorf("blob %s does not match its digest", layer.Digest)
+	}
+	return data, nil
+}
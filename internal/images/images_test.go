@@ -0,0 +1,511 @@
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	digestV1 = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	digestV2 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+// registry is a fake registry serving the tags of org/app behind the bearer
+// token flow, or basic authentication when basic is set.
+type registry struct {
+	server *httptest.Server
+	tags   map[string]string
+	basic  bool
+	// noDigestHeader omits Docker-Content-Digest, as some registries do on HEAD.
+	noDigestHeader bool
+	requests       int
+}
+
+func newRegistry(t *testing.T) *registry {
+	t.Helper()
+	r := &registry{tags: map[string]string{"v1": digestV1}}
+	r.server = httptest.NewTLSServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *registry) host() string {
+	return strings.TrimPrefix(r.server.URL, "https://")
+}
+
+func (r *registry) resolver(credentials Credentials) *Resolver {
+	res := NewResolver(credentials)
+	res.Client = r.server.Client()
+	return res
+}
+
+func (r *registry) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != "user" || pass != "pass" || req.URL.Query().Get("scope") != "repository:org/app:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token":"secret-token"}`)
+		return
+	}
+
+	r.requests++
+	authorized := req.Header.Get("Authorization") == "Bearer secret-token"
+	if r.basic {
+		user, pass, ok := req.BasicAuth()
+		authorized = ok && user == "user" && pass == "pass"
+	}
+	if !authorized {
+		if r.basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test"`, r.server.URL))
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if req.URL.Path == "/v2/org/app/tags/list" {
+		// Serve one tag per page to exercise pagination.
+		tags := []string{"v1", "v2", "v3"}
+		page := 0
+		fmt.Sscan(req.URL.Query().Get("last"), &page)
+		if page+1 < len(tags) {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/org/app/tags/list?last=%d>; rel="next"`, page+1))
+		}
+		fmt.Fprintf(w, `{"name":"org/app","tags":["%s"]}`, tags[page])
+		return
+	}
+
+	tag := strings.TrimPrefix(req.URL.Path, "/v2/org/app/manifests/")
+	d, ok := r.tags[tag]
+	if !ok || !strings.Contains(req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.noDigestHeader {
+		fmt.Fprint(w, "manifest-"+tag)
+		return
+	}
+	w.Header().Set("Docker-Content-Digest", d)
+}
+
+func credentials(_ context.Context, registry string) (string, string, bool) {
+	return "user", "pass", true
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		image      string
+		name       string
+		registry   string
+		repository string
+		tag        string
+		digest     string
+	}{
+		{"nginx", "nginx", "docker.io", "library/nginx", "", ""},
+		{"nginx:1.27", "nginx", "docker.io", "library/nginx", "1.27", ""},
+		{"ghcr.io/org/app:v1@" + digestV1, "ghcr.io/org/app", "ghcr.io", "org/app", "v1", digestV1},
+		{"localhost:5000/app", "localhost:5000/app", "localhost:5000", "app", "", ""},
+		{"quay.io/org/app@" + digestV1, "quay.io/org/app", "quay.io", "org/app", "", digestV1},
+	}
+	for _, tt := range tests {
+		ref, err := Parse(tt.image)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.image, err)
+		}
+		if ref.Name != tt.name || ref.Registry != tt.registry || ref.Repository != tt.repository || ref.Tag != tt.tag || ref.Digest != tt.digest {
+			t.Errorf("Parse(%s) = %+v", tt.image, ref)
+		}
+		if ref.String() != tt.image {
+			t.Errorf("Parse(%s).String() = %s", tt.image, ref.String())
+		}
+	}
+
+	if _, err := Parse("Invalid Image"); err == nil {
+		t.Error("Parse() should reject invalid references")
+	}
+	if got := registryHost("docker.io"); got != "registry-1.docker.io" {
+		t.Errorf("registryHost(docker.io) = %s", got)
+	}
+}
+
+func TestSameRegistry(t *testing.T) {
+	tests := []struct {
+		url      string
+		registry string
+		want     bool
+	}{
+		{"https://index.docker.io/v1/", "docker.io", true},
+		{"docker.io", "docker.io", true},
+		{"registry-1.docker.io", "docker.io", true},
+		{"https://ghcr.io/my-org", "ghcr.io", true},
+		{"Quay.io", "quay.io", true},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com", "123456789012.dkr.ecr.eu-west-1.amazonaws.com", true},
+		{"quay.io", "ghcr.io", false},
+	}
+	for _, tt := range tests {
+		if got := SameRegistry(tt.url, tt.registry); got != tt.want {
+			t.Errorf("SameRegistry(%s, %s) = %v, want %v", tt.url, tt.registry, got, tt.want)
+		}
+	}
+}
+
+func TestResolverBearer(t *testing.T) {
+	reg := newRegistry(t)
+	res := reg.resolver(credentials)
+
+	pinned, err := res.Pin(context.Background(), reg.host()+"/org/app:v1")
+	if err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if want := reg.host() + "/org/app:v1@" + digestV1; pinned != want {
+		t.Errorf("Pin() = %s, want %s", pinned, want)
+	}
+
+	// Resolved tags are cached.
+	requests := reg.requests
+	if _, err := res.Pin(context.Background(), reg.host()+"/org/app:v1"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if reg.requests != requests {
+		t.Errorf("Pin() sent %d requests for a cached tag", reg.requests-requests)
+	}
+
+	if _, err := res.Pin(context.Background(), reg.host()+"/org/app:missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Pin() of a missing tag error = %v", err)
+	}
+	if _, err := reg.resolver(nil).Pin(context.Background(), reg.host()+"/org/app:v1"); err == nil {
+		t.Error("Pin() without credentials should fail")
+	}
+}
+
+func TestResolverBasic(t *testing.T) {
+	reg := newRegistry(t)
+	reg.basic = true
+
+	d, err := reg.resolver(credentials).Resolve(context.Background(), Reference{Name: reg.host() + "/org/app", Registry: reg.host(), Repository: "org/app", Tag: "v1"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if d != digestV1 {
+		t.Errorf("Resolve() = %s, want %s", d, digestV1)
+	}
+
+	_, err = reg.resolver(nil).Resolve(context.Background(), Reference{Name: reg.host() + "/org/app", Registry: reg.host(), Repository: "org/app", Tag: "v1"})
+	if err == nil || !strings.Contains(err.Error(), "requires credentials") {
+		t.Errorf("Resolve() without credentials error = %v", err)
+	}
+}
+
+func TestResolverHashesManifest(t *testing.T) {
+	reg := newRegistry(t)
+	reg.noDigestHeader = true
+
+	d, err := reg.resolver(credentials).Resolve(context.Background(), Reference{Name: reg.host() + "/org/app", Registry: reg.host(), Repository: "org/app", Tag: "v1"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if want := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("manifest-v1"))); d != want {
+		t.Errorf("Resolve() = %s, want %s", d, want)
+	}
+}
+
+func TestResolverTags(t *testing.T) {
+	reg := newRegistry(t)
+	ref, err := Parse(reg.host() + "/org/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := reg.resolver(credentials).Tags(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Tags() error = %v", err)
+	}
+	if strings.Join(tags, ",") != "v1,v2,v3" {
+		t.Errorf("Tags() = %v, want all pages", tags)
+	}
+
+	if _, err := reg.resolver(nil).Tags(context.Background(), ref); err == nil {
+		t.Error("Tags() should fail without credentials")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/nginx:pull" {
+		t.Errorf("parseChallenge() = %s, %v", scheme, params)
+	}
+	if scheme, _ := parseChallenge(`Basic realm="ecr"`); scheme != "Basic" {
+		t.Errorf("parseChallenge() scheme = %s", scheme)
+	}
+}
+
+func manifests(host string) string {
+	return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "` + host + `/org/app:v1" # keep this comment
+        - name: sidecar
+          image: ` + host + `/org/app:v1@` + digestV1 + `
+        - name: templated
+          image: "{{ .Values.image }}"
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: app
+spec:
+  values:
+    image:
+      repository: ` + host + `/org/app
+      tag: v1
+`
+}
+
+func TestRewrite(t *testing.T) {
+	reg := newRegistry(t)
+	res := reg.resolver(credentials)
+	host := reg.host()
+
+	pinned, changes, err := Rewrite(context.Background(), res, "app.yaml", []byte(manifests(host)), false)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Rewrite() changes = %+v, want the container and the HelmRelease", changes)
+	}
+	if changes[0].Line != 10 || changes[0].To != digestV1 || changes[0].From != "" || changes[0].Image != host+"/org/app:v1" {
+		t.Errorf("Rewrite() change = %+v", changes[0])
+	}
+	for _, want := range []string{
+		`image: "` + host + `/org/app:v1@` + digestV1 + `" # keep this comment`,
+		"      tag: v1\n      digest: " + digestV1 + "\n",
+		`image: "{{ .Values.image }}"`,
+	} {
+		if !strings.Contains(string(pinned), want) {
+			t.Errorf("Rewrite() output missing %q:\n%s", want, pinned)
+		}
+	}
+
+	// Pinning again changes nothing; updating picks up a moved tag.
+	if _, changes, _ = Rewrite(context.Background(), res, "app.yaml", pinned, false); len(changes) != 0 {
+		t.Errorf("Rewrite() of pinned images changes = %+v", changes)
+	}
+	reg.tags["v1"] = digestV2
+	updated, changes, err := Rewrite(context.Background(), reg.resolver(credentials), "app.yaml", pinned, true)
+	if err != nil {
+		t.Fatalf("Rewrite() update error = %v", err)
+	}
+	if len(changes) != 3 || changes[0].From != digestV1 || changes[0].To != digestV2 {
+		t.Errorf("Rewrite() update changes = %+v", changes)
+	}
+	if strings.Contains(string(updated), digestV1) {
+		t.Errorf("Rewrite() update left the old digest:\n%s", updated)
+	}
+}
+
+func TestRewriteKustomization(t *testing.T) {
+	reg := newRegistry(t)
+	content := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - ../../base
+images:
+  - name: app
+    newName: ` + reg.host() + `/org/app
+    newTag: v1
+  - name: other
+    digest: ` + digestV1 + `
+`
+	out, changes, err := Rewrite(context.Background(), reg.resolver(credentials), "kustomization.yaml", []byte(content), false)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if len(changes) != 1 || !strings.Contains(string(out), "    newTag: v1\n    digest: "+digestV1+"\n") {
+		t.Errorf("Rewrite() = %+v:\n%s", changes, out)
+	}
+
+	if out, changes, err := Rewrite(context.Background(), nil, "chart.yaml", []byte("image: {{ .Values.image }}\n"), false); err != nil || len(changes) != 0 || string(out) != "image: {{ .Values.image }}\n" {
+		t.Errorf("Rewrite() of a template = %q, %v, %v", out, changes, err)
+	}
+}
+
+func TestRetag(t *testing.T) {
+	content := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "ghcr.io/org/app:v1@` + digestV1 + `"
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+spec:
+  values:
+    image:
+      repository: ghcr.io/org/api
+      tag: v1
+      digest: ` + digestV1 + `
+`
+	found := Find([]byte(content))
+	if len(found) != 2 || found[0].Line != 8 || found[1].Image != "ghcr.io/org/api:v1@"+digestV1 {
+		t.Fatalf("Find() = %+v", found)
+	}
+
+	out, err := Retag([]byte(content), 8, "v2", digestV2)
+	if err != nil {
+		t.Fatalf("Retag() error = %v", err)
+	}
+	if !strings.Contains(string(out), `image: "ghcr.io/org/app:v2@`+digestV2+`"`) {
+		t.Errorf("Retag() did not retag the container:\n%s", out)
+	}
+
+	out, err = Retag(out, found[1].Line, "v2", "")
+	if err != nil {
+		t.Fatalf("Retag() error = %v", err)
+	}
+	if !strings.Contains(string(out), "      tag: v2\n") || strings.Contains(string(out), "digest: "+digestV1) {
+		t.Errorf("Retag() should update the tag and drop the digest:\n%s", out)
+	}
+
+	if _, err := Retag(out, 2, "v2", ""); err == nil {
+		t.Error("Retag() should fail on a line without image")
+	}
+}
+
+func TestRun(t *testing.T) {
+	reg := newRegistry(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "apps", "app.yaml")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(manifests(reg.host())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("image: nginx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), &Options{Path: dir, DryRun: true, Resolver: reg.resolver(credentials)})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Scanned != 1 || len(report.Changes) != 2 || report.Changes[0].File != "apps/app.yaml" {
+		t.Errorf("Run() = %+v", report)
+	}
+	if data, _ := os.ReadFile(file); strings.Contains(string(data), digestV1+`"`) {
+		t.Error("Run() with DryRun wrote the file")
+	}
+
+	if _, err := Run(context.Background(), &Options{Path: dir, Resolver: reg.resolver(credentials)}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), "digest: "+digestV1) {
+		t.Errorf("Run() did not pin the images:\n%s", data)
+	}
+}
+
+// artifactRegistry is a fake registry storing pushed blobs and manifests.
+func artifactRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	blobs, manifests := map[string][]byte{}, map[string][]byte{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := strings.TrimPrefix(req.URL.Path, "/v2/patterns/")
+		body, _ := io.ReadAll(req.Body)
+		switch {
+		case req.Method == http.MethodPost && path == "blobs/uploads/":
+			w.Header().Set("Location", "/v2/patterns/blobs/uploads/1?session=1")
+			w.WriteHeader(http.StatusAccepted)
+		case req.Method == http.MethodPut && strings.HasPrefix(path, "blobs/uploads/"):
+			if req.URL.Query().Get("session") != "1" || req.URL.Query().Get("digest") != fmt.Sprintf("sha256:%x", sha256.Sum256(body)) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			blobs[req.URL.Query().Get("digest")] = body
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(path, "blobs/"):
+			data, ok := blobs[strings.TrimPrefix(path, "blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case req.Method == http.MethodPut && strings.HasPrefix(path, "manifests/"):
+			manifests[strings.TrimPrefix(path, "manifests/")] = body
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(path, "manifests/"):
+			data, ok := manifests[strings.TrimPrefix(path, "manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPushPull(t *testing.T) {
+	server := artifactRegistry(t)
+	r := NewResolver(nil)
+	r.Client = server.Client()
+	ref, err := Parse(strings.TrimPrefix(server.URL, "https://") + "/patterns:1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	artifact := &Artifact{
+		ArtifactType: "application/vnd.gitopsi.pattern.v1",
+		Annotations:  map[string]string{"org.opencontainers.image.version": "1.0.0"},
+		Files: []File{
+			{Name: "pattern.yaml", MediaType: "application/yaml", Data: []byte("kind: Pattern\n")},
+			{Name: "templates/app.yaml", MediaType: "application/yaml", Data: []byte("kind: Application\n")},
+		},
+	}
+	d, err := r.Push(ctx, ref, artifact)
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if !strings.HasPrefix(d, "sha256:") {
+		t.Errorf("Push() digest = %s", d)
+	}
+
+	pulled, err := r.Pull(ctx, ref)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if pulled.ArtifactType != artifact.ArtifactType || pulled.Annotations["org.opencontainers.image.version"] != "1.0.0" || len(pulled.Files) != 2 {
+		t.Fatalf("Pull() = %+v", pulled)
+	}
+	if f, ok := pulled.File("templates/app.yaml"); !ok || string(f.Data) != "kind: Application\n" {
+		t.Errorf("Pull() file = %v, %v", f, ok)
+	}
+
+	ref.Tag = "2.0.0"
+	if _, err := r.Pull(ctx, ref); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Pull() of a missing tag error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,401 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options configures Run.
+type Options struct {
+	// Path is the repository root, a directory or a single file.
+	Path string
+	// Update re-resolves the tags of pinned images instead of pinning the
+	// images without a digest.
+	Update bool
+	// DryRun reports the changes without writing them.
+	DryRun   bool
+	Resolver *Resolver
+}
+
+// Change is an image reference rewritten by Run.
+type Change struct {
+	File string `json:"file" yaml:"file"`
+	Line int    `json:"line" yaml:"line"`
+	// Image is the image name and tag.
+	Image string `json:"image" yaml:"image"`
+	// From is the previous digest, empty when the image was not pinned.
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Path    string   `json:"path" yaml:"path"`
+	Scanned int      `json:"scanned" yaml:"scanned"`
+	Changes []Change `json:"changes" yaml:"changes"`
+	DryRun  bool     `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// Run pins or updates the images of the YAML files under opts.Path: container
+// images, the images entries of kustomizations and the image values of
+// HelmReleases.
+func Run(ctx context.Context, opts *Options) (*Report, error) {
+	files, err := yamlFiles(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Path: opts.Path, Changes: []Change{}, DryRun: opts.DryRun}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		report.Scanned++
+
+		rel, err := filepath.Rel(opts.Path, file)
+		if err != nil || rel == "." {
+			rel = filepath.Base(file)
+		}
+		rewritten, changes, err := Rewrite(ctx, opts.Resolver, filepath.ToSlash(rel), content, opts.Update)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		report.Changes = append(report.Changes, changes...)
+		if opts.DryRun {
+			continue
+		}
+		if err := os.WriteFile(file, rewritten, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	return report, nil
+}
+
+// Rewrite pins the images of content, or re-resolves the pinned ones when
+// update is set, keeping its formatting and comments. Content that is not
+// valid YAML, such as a Helm template, is returned unchanged.
+func Rewrite(ctx context.Context, resolver *Resolver, file string, content []byte, update bool) ([]byte, []Change, error) {
+	targets := findImages(content)
+	if len(targets) == 0 {
+		return content, nil, nil
+	}
+	if resolver == nil {
+		resolver = NewResolver(nil)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	var changes []Change
+	// Edit from the bottom so inserted lines do not shift the next targets.
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].line() > targets[j].line() })
+	for _, t := range targets {
+		ref, err := Parse(t.image)
+		if err != nil {
+			continue
+		}
+		current := t.digest()
+		if current == "" {
+			current = ref.Digest
+		}
+		if update != (current != "") || (update && ref.Tag == "") {
+			continue
+		}
+		ref.Digest = ""
+		d, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if d == current {
+			continue
+		}
+		if err := t.apply(lines, ref, d); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		ref.Tag = ref.TagOrDefault()
+		changes = append(changes, Change{File: file, Line: t.line(), Image: ref.String(), From: current, To: d})
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Line < changes[j].Line })
+	return []byte(strings.Join(lines, "")), changes, nil
+}
+
+// Occurrence is an image reference found by Find.
+type Occurrence struct {
+	// Image is the reference as name[:tag][@digest].
+	Image string
+	Line  int
+}
+
+// Find returns the image references of content: container images, the images
+// entries of kustomizations and the image values of HelmReleases.
+func Find(content []byte) []Occurrence {
+	var found []Occurrence
+	for _, t := range findImages(content) {
+		image := t.image
+		if d := t.digest(); d != "" {
+			image += "@" + d
+		}
+		found = append(found, Occurrence{Image: image, Line: t.line()})
+	}
+	return found
+}
+
+// Retag sets the tag and digest of the image found on line of content,
+// keeping its formatting and comments. An empty digest unpins the image.
+func Retag(content []byte, line int, tag, digest string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	for _, t := range findImages(content) {
+		if t.line() != line {
+			continue
+		}
+		ref, err := Parse(t.image)
+		if err != nil {
+			return nil, err
+		}
+		ref.Tag, ref.Digest = tag, digest
+		if err := t.retag(lines, ref); err != nil {
+			return nil, err
+		}
+		return []byte(strings.Join(lines, "")), nil
+	}
+	return nil, fmt.Errorf("no image on line %d", line)
+}
+
+// target is an image reference found in a YAML document.
+type target struct {
+	image string
+	// value is the scalar holding the full reference of a container image.
+	value *yaml.Node
+	// tagKey and digestValue locate the tag and digest of an image split into
+	// fields: kustomization images entries and HelmRelease values.
+	tagKey      *yaml.Node
+	tagValue    *yaml.Node
+	digestValue *yaml.Node
+}
+
+func (t target) line() int {
+	if t.value != nil {
+		return t.value.Line
+	}
+	return t.tagKey.Line
+}
+
+func (t target) digest() string {
+	if t.digestValue != nil {
+		return t.digestValue.Value
+	}
+	return ""
+}
+
+// apply writes digest d of ref into lines.
+func (t target) apply(lines []string, ref Reference, d string) error {
+	if t.value != nil {
+		ref.Tag = ref.TagOrDefault()
+		ref.Digest = d
+		return replaceScalar(lines, t.value, ref.String())
+	}
+	if t.digestValue != nil {
+		return replaceScalar(lines, t.digestValue, d)
+	}
+	i := t.tagKey.Line - 1
+	if i >= len(lines) {
+		return fmt.Errorf("line %d out of range", t.tagKey.Line)
+	}
+	if !strings.HasSuffix(lines[i], "\n") {
+		lines[i] += "\n"
+	}
+	indent := strings.Repeat(" ", t.tagKey.Column-1)
+	lines[i] += indent + "digest: " + d + "\n"
+	return nil
+}
+
+// retag writes the tag and digest of ref into lines, removing the digest
+// field of a split image when ref has none.
+func (t target) retag(lines []string, ref Reference) error {
+	if t.value != nil {
+		return replaceScalar(lines, t.value, ref.String())
+	}
+	if err := replaceScalar(lines, t.tagValue, ref.Tag); err != nil {
+		return err
+	}
+	switch {
+	case t.digestValue != nil && ref.Digest == "":
+		// Blank the line rather than removing it so line numbers stay valid.
+		lines[t.digestValue.Line-1] = ""
+		return nil
+	case ref.Digest == "":
+		return nil
+	}
+	return t.apply(lines, ref, ref.Digest)
+}
+
+// replaceScalar replaces the value of a plain or quoted scalar on its line.
+func replaceScalar(lines []string, node *yaml.Node, value string) error {
+	token, replacement := node.Value, value
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		token, replacement = `"`+token+`"`, `"`+value+`"`
+	case yaml.SingleQuotedStyle:
+		token, replacement = "'"+token+"'", "'"+value+"'"
+	case 0, yaml.TaggedStyle:
+	default:
+		return fmt.Errorf("line %d: unsupported scalar style for image %s", node.Line, node.Value)
+	}
+	i, col := node.Line-1, node.Column-1
+	if i >= len(lines) || col > len(lines[i]) || !strings.HasPrefix(lines[i][col:], token) {
+		return fmt.Errorf("line %d: cannot locate image %s", node.Line, node.Value)
+	}
+	lines[i] = lines[i][:col] + replacement + lines[i][col+len(token):]
+	return nil
+}
+
+// findImages returns the images of every document of content.
+func findImages(content []byte) []target {
+	var targets []target
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			// Stop at the end and skip files that are not YAML.
+			if !errors.Is(err, io.EOF) {
+				return nil
+			}
+			break
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		root := doc.Content[0]
+		switch {
+		case scalar(root, "kind") == "HelmRelease":
+			if image := lookup(root, "spec", "values", "image"); image != nil {
+				targets = appendSplit(targets, image, "repository", "tag")
+			}
+		case scalar(root, "kind") == "Kustomization" || (scalar(root, "kind") == "" && mapping(root, "images") != nil):
+			if entries := mapping(root, "images"); entries != nil && entries.Kind == yaml.SequenceNode {
+				for _, entry := range entries.Content {
+					name := "newName"
+					if scalar(entry, name) == "" {
+						name = "name"
+					}
+					targets = appendSplit(targets, entry, name, "newTag")
+				}
+			}
+		}
+		targets = appendContainers(targets, root)
+	}
+	return targets
+}
+
+// appendContainers adds the images of the containers under node: mappings
+// with a name and an image.
+func appendContainers(targets []target, node *yaml.Node) []target {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if image := mapping(node, "image"); image != nil && image.Kind == yaml.ScalarNode && mapping(node, "name") != nil && image.Value != "" && !strings.Contains(image.Value, "{{") {
+			targets = append(targets, target{image: image.Value, value: image})
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			targets = appendContainers(targets, node.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			targets = appendContainers(targets, n)
+		}
+	}
+	return targets
+}
+
+// appendSplit adds an image whose name and tag are separate fields of m.
+func appendSplit(targets []target, m *yaml.Node, nameKey, tagKey string) []target {
+	if m.Kind != yaml.MappingNode {
+		return targets
+	}
+	name := scalar(m, nameKey)
+	var key *yaml.Node
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == tagKey && m.Content[i+1].Kind == yaml.ScalarNode {
+			key = m.Content[i]
+		}
+	}
+	if name == "" || key == nil || strings.Contains(name, "{{") {
+		return targets
+	}
+	value := mapping(m, tagKey)
+	t := target{image: name + ":" + value.Value, tagKey: key, tagValue: value}
+	if d := mapping(m, "digest"); d != nil && d.Kind == yaml.ScalarNode {
+		t.digestValue = d
+	}
+	return append(targets, t)
+}
+
+func mapping(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(m *yaml.Node, key string) string {
+	if v := mapping(m, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+func lookup(m *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if m = mapping(m, key); m == nil {
+			return nil
+		}
+	}
+	return m
+}
+
+// yamlFiles returns the YAML files under path, skipping the Git and gitopsi
+// metadata directories.
+func yamlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == ".gitopsi" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return files, nil
+}
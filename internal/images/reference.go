@@ -0,0 +1,111 @@
+// Package images pins container images by digest. It resolves image tags
+// against their registry with the OCI distribution API and rewrites the image
+// references of manifests, kustomizations and HelmRelease values.
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// DefaultTag is the tag of references that do not set one.
+const DefaultTag = "latest"
+
+// dockerHub is the domain of Docker Hub references and dockerHubRegistry the
+// host serving its registry API.
+const (
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Reference is a parsed image reference.
+type Reference struct {
+	// Name is the image name as written, e.g. nginx or ghcr.io/org/app.
+	Name string
+	// Registry is the domain of the registry, docker.io for Docker Hub.
+	Registry string
+	// Repository is the path of the image in the registry, e.g. library/nginx.
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Parse parses an image reference such as nginx, nginx:1.27 or
+// ghcr.io/org/app:v1@sha256:....
+func Parse(image string) (Reference, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return Reference{}, fmt.Errorf("invalid image reference %s: %w", image, err)
+	}
+	ref := Reference{
+		Name:       image,
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if i := strings.Index(ref.Name, "@"); i >= 0 {
+		ref.Name = ref.Name[:i]
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		ref.Tag = tagged.Tag()
+		ref.Name = strings.TrimSuffix(ref.Name, ":"+ref.Tag)
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		ref.Digest = digested.Digest().String()
+	}
+	return ref, nil
+}
+
+// Pinned reports whether the reference sets a digest.
+func (r Reference) Pinned() bool {
+	return r.Digest != ""
+}
+
+// TagOrDefault returns the tag, defaulting to DefaultTag.
+func (r Reference) TagOrDefault() string {
+	if r.Tag == "" {
+		return DefaultTag
+	}
+	return r.Tag
+}
+
+// String returns the reference as name[:tag][@digest].
+func (r Reference) String() string {
+	s := r.Name
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// SameRegistry reports whether a registry URL, as stored with registry
+// credentials (e.g. https://index.docker.io/v1/ or quay.io), designates the
+// registry domain of an image reference.
+func SameRegistry(url, registry string) bool {
+	return normalizeRegistry(url) == normalizeRegistry(registry)
+}
+
+func normalizeRegistry(url string) string {
+	host := strings.ToLower(strings.TrimSpace(url))
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", dockerHubRegistry:
+		return dockerHub
+	}
+	return host
+}
+
+// registryHost returns the host serving the registry API of domain.
+func registryHost(domain string) string {
+	if domain == dockerHub {
+		return dockerHubRegistry
+	}
+	return domain
+}
@@ -0,0 +1,353 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// ErrNotFound is wrapped by the errors of missing repositories, tags and
+// blobs.
+var ErrNotFound = errors.New("not found")
+
+// manifestTypes are the manifest media types accepted from registries, image
+// indexes first so multi-architecture images pin to their index.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Credentials returns the username and password of a registry, or false to
+// access it anonymously. registry is the domain of the image reference, e.g.
+// docker.io, ghcr.io or <account>.dkr.ecr.<region>.amazonaws.com.
+type Credentials func(ctx context.Context, registry string) (username, password string, ok bool)
+
+// Resolver resolves image tags to digests with the OCI distribution API. It
+// handles the bearer token flow of Docker Hub, GHCR, Quay, ACR and GCR and the
+// basic authentication of ECR.
+type Resolver struct {
+	Client      *http.Client
+	Credentials Credentials
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewResolver returns a Resolver authenticating with credentials, which may be
+// nil to pull anonymously.
+func NewResolver(credentials Credentials) *Resolver {
+	return &Resolver{
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		Credentials: credentials,
+		cache:       map[string]string{},
+	}
+}
+
+// Resolve returns the digest the tag of ref points to.
+func (r *Resolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	key := ref.Registry + "/" + ref.Repository + ":" + ref.TagOrDefault()
+	r.mu.Lock()
+	if d, ok := r.cache[key]; ok {
+		r.mu.Unlock()
+		return d, nil
+	}
+	r.mu.Unlock()
+
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registryHost(ref.Registry), ref.Repository, ref.TagOrDefault())
+	d, err := r.fetchDigest(ctx, ref, http.MethodHead, u)
+	if err == nil && d == "" {
+		// Some registries omit Docker-Content-Digest on HEAD: hash the manifest.
+		d, err = r.fetchDigest(ctx, ref, http.MethodGet, u)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s:%s: %w", ref.Name, ref.TagOrDefault(), err)
+	}
+
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = map[string]string{}
+	}
+	r.cache[key] = d
+	r.mu.Unlock()
+	return d, nil
+}
+
+// Pin returns image pinned to the digest of its tag, e.g. nginx:1.27@sha256:....
+func (r *Resolver) Pin(ctx context.Context, image string) (string, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest, err = r.Resolve(ctx, ref); err != nil {
+		return "", err
+	}
+	ref.Tag = ref.TagOrDefault()
+	return ref.String(), nil
+}
+
+// Tags returns the tags of the repository of ref, following the pagination
+// of the registry.
+func (r *Resolver) Tags(ctx context.Context, ref Reference) ([]string, error) {
+	host := registryHost(ref.Registry)
+	next := fmt.Sprintf("https://%s/v2/%s/tags/list?n=1000", host, ref.Repository)
+	var tags []string
+	for page := 0; next != "" && page < maxTagPages; page++ {
+		resp, err := r.request(ctx, ref, http.MethodGet, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", ref.Name, err)
+		}
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		err = checkStatus(resp, ref, "repository "+ref.Repository)
+		if err == nil {
+			if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				err = fmt.Errorf("failed to parse tag list: %w", err)
+			}
+		}
+		next = nextPage(resp, host)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", ref.Name, err)
+		}
+		tags = append(tags, body.Tags...)
+	}
+	return tags, nil
+}
+
+// maxTagPages bounds the tag list pages fetched from a registry.
+const maxTagPages = 50
+
+// nextPage returns the URL of the next page announced by the Link header.
+func nextPage(resp *http.Response, host string) string {
+	link := resp.Header.Get("Link")
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start || !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	next := link[start+1 : end]
+	if strings.HasPrefix(next, "/") {
+		next = "https://" + host + next
+	}
+	return next
+}
+
+// fetchDigest requests the manifest at u and returns its digest. A HEAD
+// response without a digest header returns an empty digest.
+func (r *Resolver) fetchDigest(ctx context.Context, ref Reference, method, u string) (string, error) {
+	resp, err := r.request(ctx, ref, method, u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, ref, "tag "+ref.TagOrDefault()); err != nil {
+		return "", err
+	}
+
+	if d := resp.Header.Get("Docker-Content-Digest"); d != "" {
+		if _, err := digest.Parse(d); err != nil {
+			return "", fmt.Errorf("registry %s returned an invalid digest %q: %w", ref.Registry, d, err)
+		}
+		return d, nil
+	}
+	if method == http.MethodHead {
+		return "", nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(body)), nil
+}
+
+// request sends a request to the registry of ref, authenticating when the
+// registry asks to.
+func (r *Resolver) request(ctx context.Context, ref Reference, method, u string) (*http.Response, error) {
+	return r.send(ctx, ref, method, u, "", nil)
+}
+
+// send is request with a body. Requests other than GET and HEAD ask for a
+// push token.
+func (r *Resolver) send(ctx context.Context, ref Reference, method, u, contentType string, body []byte) (*http.Response, error) {
+	resp, err := r.do(ctx, method, u, "", contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	push := method != http.MethodGet && method != http.MethodHead
+	auth, err := r.authorize(ctx, ref, challenge, push)
+	if err != nil {
+		return nil, err
+	}
+	return r.do(ctx, method, u, auth, contentType, body)
+}
+
+// checkStatus returns an error for a response other than 200 OK; what names
+// the resource requested.
+func checkStatus(resp *http.Response, ref Reference, what string) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("access denied by %s: store registry credentials with gitopsi auth add registry", ref.Registry)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s %w in %s", what, ErrNotFound, ref.Registry)
+	default:
+		return fmt.Errorf("registry %s returned %s", ref.Registry, resp.Status)
+	}
+}
+
+func (r *Resolver) do(ctx context.Context, method, u, auth, contentType string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry: %w", err)
+	}
+	return resp, nil
+}
+
+// authorize answers a WWW-Authenticate challenge and returns the value of the
+// Authorization header to retry with.
+func (r *Resolver) authorize(ctx context.Context, ref Reference, challenge string, push bool) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	username, password, hasCredentials := "", "", false
+	if r.Credentials != nil {
+		username, password, hasCredentials = r.Credentials(ctx, ref.Registry)
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCredentials {
+			return "", fmt.Errorf("registry %s requires credentials: store them with gitopsi auth add registry", ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		realm := params["realm"]
+		if realm == "" {
+			return "", fmt.Errorf("registry %s sent a bearer challenge without realm", ref.Registry)
+		}
+		scope := "repository:" + ref.Repository + ":pull"
+		if push {
+			scope += ",push"
+		}
+		token, err := r.token(ctx, realm, params["service"], scope, username, password, hasCredentials)
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate with %s: %w", ref.Registry, err)
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("registry %s sent an unsupported authentication challenge: %q", ref.Registry, challenge)
+	}
+}
+
+// token requests a pull or push token from the authorization server of a registry.
+func (r *Resolver) token(ctx context.Context, realm, service, scope, username, password string, hasCredentials bool) (string, error) {
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %s: %w", realm, err)
+	}
+	q := u.Query()
+	if service != "" {
+		q.Set("service", service)
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if hasCredentials {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("token response has no token")
+}
+
+func (r *Resolver) client() *http.Client {
+	if r.Client == nil {
+		return http.DefaultClient
+	}
+	return r.Client
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and
+// parameters, e.g. Bearer realm="https://auth.docker.io/token",service="registry.docker.io".
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var param string
+		rest = strings.TrimLeft(rest, " ,")
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				param, rest = value[1:], ""
+			} else {
+				param, rest = value[1:end+1], value[end+2:]
+			}
+		} else {
+			param, rest, _ = strings.Cut(value, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = param
+	}
+	return scheme, params
+}
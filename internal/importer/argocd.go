@@ -0,0 +1,386 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// argocdResources are the ArgoCD resources read by ScanArgoCD.
+const argocdResources = "appprojects.argoproj.io,applications.argoproj.io,applicationsets.argoproj.io"
+
+// inClusterServer is the destination ArgoCD uses for its own cluster.
+const inClusterServer = "https://kubernetes.default.svc"
+
+// envNames are path segments and name suffixes recognised as environments.
+var envNames = []string{
+	"dev", "development", "test", "qa", "uat", "stage", "staging", "preprod", "prod", "production",
+}
+
+// ArgoCDResult holds the ArgoCD resources found by ScanArgoCD or LoadArgoCDFiles.
+type ArgoCDResult struct {
+	// Namespace is the namespace the ArgoCD resources live in.
+	Namespace       string
+	Projects        []Object
+	Applications    []Object
+	ApplicationSets []Object
+	Skipped         []Skipped
+}
+
+// Count returns the number of imported ArgoCD resources.
+func (r *ArgoCDResult) Count() int {
+	return len(r.Projects) + len(r.Applications) + len(r.ApplicationSets)
+}
+
+// ScanArgoCD reads AppProjects, Applications and ApplicationSets from the
+// ArgoCD namespace of the cluster.
+func (i *Importer) ScanArgoCD(ctx context.Context, namespace string) (*ArgoCDResult, error) {
+	if namespace == "" {
+		namespace = "argocd"
+	}
+	items, err := i.list(ctx, "get", argocdResources, "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ArgoCD resources in namespace %s: %w", namespace, err)
+	}
+
+	result := &ArgoCDResult{Namespace: namespace}
+	for _, obj := range items {
+		result.add(obj)
+	}
+	result.sort()
+	return result, nil
+}
+
+// LoadArgoCDFiles reads ArgoCD resources from YAML files. Directories are
+// walked recursively; documents that are not ArgoCD resources are ignored.
+func LoadArgoCDFiles(paths []string) (*ArgoCDResult, error) {
+	result := &ArgoCDResult{}
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+				return nil
+			}
+			objects, err := decodeYAML(path)
+			if err != nil {
+				return err
+			}
+			for _, obj := range objects {
+				if strings.HasPrefix(apiVersion(obj), "argoproj.io/") {
+					result.add(obj)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+	}
+	result.sort()
+	return result, nil
+}
+
+func decodeYAML(path string) ([]Object, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var objects []Object
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if obj != nil {
+			objects = append(objects, Object(obj))
+		}
+	}
+	return objects, nil
+}
+
+func (r *ArgoCDResult) add(obj Object) {
+	if r.Namespace == "" {
+		r.Namespace = obj.namespace()
+	}
+
+	if owners, ok := obj.metadata()["ownerReferences"].([]interface{}); ok && len(owners) > 0 {
+		if owner, ok := owners[0].(map[string]interface{}); ok && owner["kind"] == "ApplicationSet" {
+			r.Skipped = append(r.Skipped, skipped(obj, fmt.Sprintf("generated by ApplicationSet %v", owner["name"])))
+			return
+		}
+	}
+
+	switch obj.kind() {
+	case "AppProject":
+		if obj.name() == "default" {
+			r.Skipped = append(r.Skipped, skipped(obj, "built-in default project"))
+			return
+		}
+		r.Projects = append(r.Projects, obj)
+	case "Application":
+		r.Applications = append(r.Applications, obj)
+	case "ApplicationSet":
+		r.ApplicationSets = append(r.ApplicationSets, obj)
+	}
+}
+
+func (r *ArgoCDResult) sort() {
+	for _, list := range [][]Object{r.Projects, r.Applications, r.ApplicationSets} {
+		sort.Slice(list, func(i, j int) bool { return list[i].name() < list[j].name() })
+	}
+}
+
+// CleanArgoCD returns a copy of an ArgoCD resource without status, pending
+// sync operations and server-populated metadata. Unlike Clean, the namespace
+// is kept: ArgoCD resources must live in the ArgoCD namespace.
+func CleanArgoCD(obj Object) Object {
+	out := Clean(obj)
+	delete(out, "operation")
+	if ns := obj.namespace(); ns != "" {
+		if meta, ok := out["metadata"].(map[string]interface{}); ok {
+			meta["namespace"] = ns
+		}
+	}
+	return out
+}
+
+// SynthesizeConfig derives a gitopsi configuration from imported ArgoCD
+// resources. The Git repository and branch are the ones most Applications
+// use; environments are detected from overlay paths and name suffixes, and
+// their namespaces and clusters from the Application destinations.
+func SynthesizeConfig(result *ArgoCDResult, name string) *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = name
+	cfg.Project.Description = "Imported from ArgoCD"
+	cfg.GitOpsTool = "argocd"
+	cfg.Bootstrap.Tool = "argocd"
+	cfg.Bootstrap.Namespace = result.Namespace
+
+	repos := make(map[string]int)
+	revisions := make(map[string]int)
+	type envInfo struct {
+		namespaces map[string]bool
+		servers    map[string]bool
+	}
+	envs := make(map[string]*envInfo)
+	var envOrder []string
+	infra, apps := false, false
+
+	for _, app := range result.Applications {
+		repo, path, revision := source(app)
+		if repo != "" {
+			repos[repo]++
+		}
+		if revision != "" && revision != "HEAD" {
+			revisions[revision]++
+		}
+		if strings.HasPrefix(path, "infrastructure") || lookup(app, "spec", "project") == "infrastructure" {
+			infra = true
+		} else {
+			apps = true
+		}
+
+		env := detectEnvironment(app.name(), path)
+		if env == "" {
+			continue
+		}
+		info, ok := envs[env]
+		if !ok {
+			info = &envInfo{namespaces: make(map[string]bool), servers: make(map[string]bool)}
+			envs[env] = info
+			envOrder = append(envOrder, env)
+		}
+		if ns, _ := lookup(app, "spec", "destination", "namespace").(string); ns != "" {
+			info.namespaces[ns] = true
+		}
+		info.servers[destination(app)] = true
+	}
+
+	if repo := mostCommon(repos); repo != "" {
+		cfg.Git.URL = repo
+	}
+	if revision := mostCommon(revisions); revision != "" {
+		cfg.Git.Branch = revision
+	}
+
+	switch {
+	case infra && !apps:
+		cfg.Scope = "infrastructure"
+	case apps && !infra:
+		cfg.Scope = "application"
+	}
+
+	if len(envOrder) == 0 {
+		cfg.Environments = []config.Environment{{Name: "default"}}
+		return cfg
+	}
+
+	sort.SliceStable(envOrder, func(i, j int) bool { return envRank(envOrder[i]) < envRank(envOrder[j]) })
+	cfg.Environments = make([]config.Environment, 0, len(envOrder))
+	remote := false
+	for _, name := range envOrder {
+		info := envs[name]
+		env := config.Environment{Name: name}
+		if len(info.namespaces) == 1 {
+			env.Namespace = firstKey(info.namespaces)
+		}
+		if len(info.servers) == 1 {
+			if server := firstKey(info.servers); server != inClusterServer && server != "in-cluster" {
+				env.Cluster = server
+				remote = true
+			}
+		}
+		cfg.Environments = append(cfg.Environments, env)
+	}
+	if remote {
+		cfg.Topology = config.TopologyClusterPerEnv
+	}
+	return cfg
+}
+
+// WriteArgoCD writes the cleaned ArgoCD resources in gitopsi's layout and
+// creates the source directories Applications point to in the repository:
+//
+//	argocd/projects/<name>.yaml
+//	argocd/applications/<name>.yaml
+//	argocd/applicationsets/<name>.yaml
+func WriteArgoCD(w *output.Writer, result *ArgoCDResult, cfg *config.Config) error {
+	dir := cfg.Project.Name + "/argocd"
+	groups := []struct {
+		subdir  string
+		objects []Object
+	}{
+		{"projects", result.Projects},
+		{"applications", result.Applications},
+		{"applicationsets", result.ApplicationSets},
+	}
+	for _, g := range groups {
+		for _, obj := range g.objects {
+			content, err := Marshal(CleanArgoCD(obj))
+			if err != nil {
+				return fmt.Errorf("failed to encode %s %s: %w", obj.kind(), obj.name(), err)
+			}
+			if err := w.WriteFile(fmt.Sprintf("%s/%s/%s.yaml", dir, g.subdir, obj.name()), content); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, app := range result.Applications {
+		repo, path, _ := source(app)
+		if path == "" || path == "." || (cfg.Git.URL != "" && repo != cfg.Git.URL) {
+			continue
+		}
+		clean := filepath.ToSlash(filepath.Clean(path))
+		if strings.HasPrefix(clean, "../") || filepath.IsAbs(clean) {
+			continue
+		}
+		if err := w.CreateDir(cfg.Project.Name + "/" + clean); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// source returns the repository, path and revision of an Application's
+// first source.
+func source(app Object) (repo, path, revision string) {
+	src := lookup(app, "spec", "source")
+	if src == nil {
+		if sources, ok := lookup(app, "spec", "sources").([]interface{}); ok && len(sources) > 0 {
+			src = sources[0]
+		}
+	}
+	repo, _ = lookup(src, "repoURL").(string)
+	path, _ = lookup(src, "path").(string)
+	revision, _ = lookup(src, "targetRevision").(string)
+	return repo, path, revision
+}
+
+func destination(app Object) string {
+	if server, ok := lookup(app, "spec", "destination", "server").(string); ok && server != "" {
+		return server
+	}
+	if name, ok := lookup(app, "spec", "destination", "name").(string); ok && name != "" {
+		return name
+	}
+	return inClusterServer
+}
+
+// detectEnvironment finds the environment of an Application from an
+// overlays/<env> style path segment or a -<env> name suffix.
+func detectEnvironment(name, path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, seg := range segments {
+		switch seg {
+		case "overlays", "envs", "environments", "clusters":
+			if i+1 < len(segments) && segments[i+1] != "" {
+				return segments[i+1]
+			}
+		}
+	}
+	for _, seg := range segments {
+		if envRank(seg) < len(envNames) {
+			return seg
+		}
+	}
+	if idx := strings.LastIndex(name, "-"); idx >= 0 {
+		if suffix := name[idx+1:]; envRank(suffix) < len(envNames) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// envRank orders environments from development to production. Unknown names
+// sort last.
+func envRank(name string) int {
+	for i, n := range envNames {
+		if n == name {
+			return i
+		}
+	}
+	return len(envNames)
+}
+
+func mostCommon(counts map[string]int) string {
+	best, bestCount := "", 0
+	for k, n := range counts {
+		if n > bestCount || (n == bestCount && k < best) {
+			best, bestCount = k, n
+		}
+	}
+	return best
+}
+
+func firstKey(m map[string]bool) string {
+	for k := range m {
+		return k
+	}
+	return ""
+}
+
+func apiVersion(obj Object) string {
+	v, _ := obj["apiVersion"].(string)
+	return v
+}
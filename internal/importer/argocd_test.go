@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func argoApp(name, path, server, namespace string) Object {
+	return Object{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata": map[string]interface{}{
+			"name": name, "namespace": "argocd", "resourceVersion": "7",
+		},
+		"spec": map[string]interface{}{
+			"project": "applications",
+			"source": map[string]interface{}{
+				"repoURL":        "https://github.com/org/platform.git",
+				"path":           path,
+				"targetRevision": "release",
+			},
+			"destination": map[string]interface{}{"server": server, "namespace": namespace},
+		},
+		"status":    map[string]interface{}{"sync": map[string]interface{}{"status": "Synced"}},
+		"operation": map[string]interface{}{"sync": map[string]interface{}{}},
+	}
+}
+
+func argoObjects() []Object {
+	generated := argoApp("web-qa", "apps/web", inClusterServer, "qa")
+	generated.metadata()["ownerReferences"] = []interface{}{
+		map[string]interface{}{"kind": "ApplicationSet", "name": "web"},
+	}
+	return []Object{
+		{"apiVersion": "argoproj.io/v1alpha1", "kind": "AppProject", "metadata": map[string]interface{}{"name": "default", "namespace": "argocd"}},
+		{"apiVersion": "argoproj.io/v1alpha1", "kind": "AppProject", "metadata": map[string]interface{}{"name": "applications", "namespace": "argocd"}},
+		argoApp("web-prod", "applications/overlays/prod", "https://prod.example.com", "shop"),
+		argoApp("web-dev", "applications/overlays/dev", inClusterServer, "shop-dev"),
+		argoApp("api-dev", "applications/overlays/dev", inClusterServer, "shop-dev"),
+		generated,
+		{"apiVersion": "argoproj.io/v1alpha1", "kind": "ApplicationSet", "metadata": map[string]interface{}{"name": "web", "namespace": "argocd"}},
+	}
+}
+
+func TestScanArgoCD(t *testing.T) {
+	fake := &fakeKubectl{items: map[string][]Object{"gitops": argoObjects()}}
+	imp := New(&Options{Kubeconfig: "/tmp/kc"})
+	imp.SetRunner(fake.run)
+
+	result, err := imp.ScanArgoCD(context.Background(), "gitops")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--kubeconfig", "/tmp/kc", "get", argocdResources, "-n", "gitops", "-o", "json"}, fake.calls[0])
+
+	assert.Equal(t, "gitops", result.Namespace)
+	require.Len(t, result.Projects, 1)
+	require.Len(t, result.Applications, 3)
+	assert.Equal(t, "api-dev", result.Applications[0].name())
+	assert.Len(t, result.ApplicationSets, 1)
+	assert.Equal(t, 5, result.Count())
+
+	reasons := make(map[string]string)
+	for _, s := range result.Skipped {
+		reasons[s.Name] = s.Reason
+	}
+	assert.Equal(t, "built-in default project", reasons["default"])
+	assert.Equal(t, "generated by ApplicationSet web", reasons["web-qa"])
+}
+
+func TestLoadArgoCDFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "apps"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apps", "web.yaml"), []byte(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: web-staging
+  namespace: argocd
+spec:
+  source:
+    repoURL: https://github.com/org/platform.git
+    path: applications/overlays/staging
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# not yaml"), 0644))
+
+	result, err := LoadArgoCDFiles([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, result.Applications, 1)
+	assert.Equal(t, "argocd", result.Namespace)
+	assert.Equal(t, "web-staging", result.Applications[0].name())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("kind: [\n"), 0644))
+	_, err = LoadArgoCDFiles([]string{dir})
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestSynthesizeConfig(t *testing.T) {
+	result := &ArgoCDResult{Namespace: "argocd"}
+	for _, obj := range argoObjects() {
+		result.add(obj)
+	}
+
+	cfg := SynthesizeConfig(result, "platform")
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, "platform", cfg.Project.Name)
+	assert.Equal(t, "argocd", cfg.GitOpsTool)
+	assert.Equal(t, "argocd", cfg.Bootstrap.Namespace)
+	assert.Equal(t, "https://github.com/org/platform.git", cfg.Git.URL)
+	assert.Equal(t, "release", cfg.Git.Branch)
+	assert.Equal(t, "application", cfg.Scope)
+	assert.Equal(t, config.TopologyClusterPerEnv, cfg.Topology)
+	assert.Equal(t, []config.Environment{
+		{Name: "dev", Namespace: "shop-dev"},
+		{Name: "prod", Namespace: "shop", Cluster: "https://prod.example.com"},
+	}, cfg.Environments)
+
+	empty := SynthesizeConfig(&ArgoCDResult{}, "empty")
+	assert.Equal(t, []config.Environment{{Name: "default"}}, empty.Environments)
+	assert.Equal(t, config.TopologyNamespaceBased, empty.Topology)
+}
+
+func TestDetectEnvironment(t *testing.T) {
+	assert.Equal(t, "prod", detectEnvironment("web", "apps/web/overlays/prod"))
+	assert.Equal(t, "eu-1", detectEnvironment("web", "clusters/eu-1/web"))
+	assert.Equal(t, "staging", detectEnvironment("web", "deploy/staging/web"))
+	assert.Equal(t, "qa", detectEnvironment("web-qa", "apps/web"))
+	assert.Empty(t, detectEnvironment("web", "apps/web"))
+}
+
+func TestWriteArgoCD(t *testing.T) {
+	result := &ArgoCDResult{Namespace: "argocd"}
+	for _, obj := range argoObjects() {
+		result.add(obj)
+	}
+	cfg := SynthesizeConfig(result, "platform")
+
+	dir := t.TempDir()
+	require.NoError(t, WriteArgoCD(output.New(dir, false, false), result, cfg))
+
+	read := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(dir, "platform", rel))
+		require.NoError(t, err, "expected %s", rel)
+		return string(data)
+	}
+
+	app := read("argocd/applications/web-prod.yaml")
+	assert.True(t, strings.HasPrefix(app, "apiVersion: argoproj.io/v1alpha1\nkind: Application\n"), app)
+	assert.Contains(t, app, "namespace: argocd")
+	assert.Contains(t, app, "path: applications/overlays/prod")
+	assert.NotContains(t, app, "resourceVersion")
+	assert.NotContains(t, app, "status:")
+	assert.NotContains(t, app, "operation:")
+
+	read("argocd/projects/applications.yaml")
+	read("argocd/applicationsets/web.yaml")
+	assert.NoFileExists(t, filepath.Join(dir, "platform", "argocd", "projects", "default.yaml"))
+	assert.NoFileExists(t, filepath.Join(dir, "platform", "argocd", "applications", "web-qa.yaml"))
+
+	assert.DirExists(t, filepath.Join(dir, "platform", "applications", "overlays", "prod"))
+	assert.DirExists(t, filepath.Join(dir, "platform", "applications", "overlays", "dev"))
+}
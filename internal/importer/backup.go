@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// argocdSettingsConfigMaps are the ConfigMaps holding the settings of an
+// ArgoCD instance.
+var argocdSettingsConfigMaps = map[string]bool{
+	"argocd-cm":                 true,
+	"argocd-rbac-cm":            true,
+	"argocd-cmd-params-cm":      true,
+	"argocd-ssh-known-hosts-cm": true,
+	"argocd-tls-certs-cm":       true,
+	"argocd-gpg-keys-cm":        true,
+}
+
+// argocdSecretTypeLabel labels the repository and cluster credential Secrets.
+const argocdSecretTypeLabel = "argocd.argoproj.io/secret-type"
+
+// ArgoCDSettings holds the settings of an ArgoCD instance read by
+// ScanArgoCDSettings.
+type ArgoCDSettings struct {
+	ConfigMaps []Object
+	// Secrets are the credential Secrets, which are not exported.
+	Secrets []Skipped
+}
+
+// ScanArgoCDSettings reads the settings ConfigMaps of the ArgoCD namespace
+// and lists its repository and cluster credential Secrets.
+func (i *Importer) ScanArgoCDSettings(ctx context.Context, namespace string) (*ArgoCDSettings, error) {
+	if namespace == "" {
+		namespace = "argocd"
+	}
+	configMaps, err := i.list(ctx, "get", "configmaps", "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ArgoCD settings in namespace %s: %w", namespace, err)
+	}
+	secrets, err := i.list(ctx, "get", "secrets", "-n", namespace, "-l", argocdSecretTypeLabel, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ArgoCD credentials in namespace %s: %w", namespace, err)
+	}
+
+	settings := &ArgoCDSettings{}
+	for _, obj := range configMaps {
+		if argocdSettingsConfigMaps[obj.name()] {
+			settings.ConfigMaps = append(settings.ConfigMaps, obj)
+		}
+	}
+	sort.Slice(settings.ConfigMaps, func(a, b int) bool {
+		return settings.ConfigMaps[a].name() < settings.ConfigMaps[b].name()
+	})
+	for _, obj := range secrets {
+		reason := fmt.Sprintf("%s credential", obj.labels()[argocdSecretTypeLabel])
+		settings.Secrets = append(settings.Secrets, skipped(obj, reason))
+	}
+	return settings, nil
+}
+
+// WriteArgoCDBackup writes the cleaned ArgoCD resources and settings to dir,
+// one file per kind, to restore with kubectl apply:
+//
+//	<dir>/projects.yaml
+//	<dir>/applications.yaml
+//	<dir>/applicationsets.yaml
+//	<dir>/settings.yaml
+//
+// Files of kinds without resources are not written.
+func WriteArgoCDBackup(w *output.Writer, dir string, result *ArgoCDResult, settings *ArgoCDSettings) error {
+	var configMaps []Object
+	if settings != nil {
+		configMaps = settings.ConfigMaps
+	}
+	files := []struct {
+		name    string
+		objects []Object
+	}{
+		{"projects.yaml", result.Projects},
+		{"applications.yaml", result.Applications},
+		{"applicationsets.yaml", result.ApplicationSets},
+		{"settings.yaml", configMaps},
+	}
+
+	for _, f := range files {
+		if len(f.objects) == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		for n, obj := range f.objects {
+			content, err := Marshal(CleanArgoCD(obj))
+			if err != nil {
+				return fmt.Errorf("failed to encode %s %s: %w", obj.kind(), obj.name(), err)
+			}
+			if n > 0 {
+				buf.WriteString("---\n")
+			}
+			buf.Write(content)
+		}
+		if err := w.WriteFile(dir+"/"+f.name, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
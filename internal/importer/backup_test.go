@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func TestArgoCDBackup(t *testing.T) {
+	items := map[string][]Object{
+		"configmaps": {
+			object("ConfigMap", "argocd", "argocd-rbac-cm", nil, map[string]interface{}{"data": map[string]interface{}{"policy.default": "role:readonly"}}),
+			object("ConfigMap", "argocd", "argocd-cm", map[string]interface{}{"resourceVersion": "3"}, map[string]interface{}{"data": map[string]interface{}{"url": "https://argocd.example.com"}}),
+			object("ConfigMap", "argocd", "kube-root-ca.crt", nil, nil),
+		},
+		"secrets": {
+			object("Secret", "argocd", "repo-platform", map[string]interface{}{"labels": map[string]interface{}{argocdSecretTypeLabel: "repository"}}, nil),
+		},
+		argocdResources: argoObjects(),
+	}
+	var calls [][]string
+	imp := New(&Options{})
+	imp.SetRunner(func(ctx context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return json.Marshal(map[string]interface{}{"items": items[args[1]]})
+	})
+
+	result, err := imp.ScanArgoCD(context.Background(), "argocd")
+	require.NoError(t, err)
+	settings, err := imp.ScanArgoCDSettings(context.Background(), "argocd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"get", "secrets", "-n", "argocd", "-l", argocdSecretTypeLabel, "-o", "json"}, calls[2])
+
+	require.Len(t, settings.ConfigMaps, 2)
+	assert.Equal(t, "argocd-cm", settings.ConfigMaps[0].name())
+	require.Len(t, settings.Secrets, 1)
+	assert.Equal(t, Skipped{Kind: "Secret", Namespace: "argocd", Name: "repo-platform", Reason: "repository credential"}, settings.Secrets[0])
+
+	dir := t.TempDir()
+	require.NoError(t, WriteArgoCDBackup(output.New(dir, false, false), "backup/argocd", result, settings))
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, "backup", "argocd", name))
+		require.NoError(t, err, "expected %s", name)
+		return string(data)
+	}
+
+	apps := read("applications.yaml")
+	assert.Equal(t, 2, strings.Count(apps, "---\n"))
+	assert.Contains(t, apps, "namespace: argocd")
+	assert.NotContains(t, apps, "status:")
+	assert.NotContains(t, apps, "operation:")
+	assert.Contains(t, read("projects.yaml"), "name: applications")
+	assert.Contains(t, read("applicationsets.yaml"), "name: web")
+
+	cm := read("settings.yaml")
+	assert.Contains(t, cm, "url: https://argocd.example.com")
+	assert.Contains(t, cm, "policy.default: role:readonly")
+	assert.NotContains(t, cm, "kube-root-ca.crt")
+	assert.NotContains(t, cm, "resourceVersion")
+
+	empty := t.TempDir()
+	require.NoError(t, WriteArgoCDBackup(output.New(empty, false, false), "backup", &ArgoCDResult{Projects: result.Projects}, nil))
+	assert.FileExists(t, filepath.Join(empty, "backup", "projects.yaml"))
+	assert.NoFileExists(t, filepath.Join(empty, "backup", "applications.yaml"))
+	assert.NoFileExists(t, filepath.Join(empty, "backup", "settings.yaml"))
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"encoding/json"
+)
+
+// serverMetadata are metadata fields set by the API server.
+var serverMetadata = []string{
+	"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields",
+	"selfLink", "deletionTimestamp", "deletionGracePeriodSeconds", "namespace",
+}
+
+// serverAnnotations are annotations added by kubectl and controllers.
+var serverAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/restartedAt",
+}
+
+// Clean returns a copy of obj without status, server-populated metadata and
+// cluster-assigned fields, suitable for committing to Git. The namespace is
+// removed as well; overlays set it.
+func Clean(obj Object) Object {
+	out := deepCopy(obj)
+	delete(out, "status")
+
+	if meta, ok := out["metadata"].(map[string]interface{}); ok {
+		cleanMetadata(meta)
+	}
+
+	switch out.kind() {
+	case "Deployment":
+		if meta, ok := lookup(out, "spec", "template", "metadata").(map[string]interface{}); ok {
+			cleanMetadata(meta)
+		}
+	case "Service":
+		spec, _ := out["spec"].(map[string]interface{})
+		if spec == nil {
+			break
+		}
+		for _, f := range []string{"clusterIP", "clusterIPs", "ipFamilies", "ipFamilyPolicy", "internalTrafficPolicy", "healthCheckNodePort"} {
+			delete(spec, f)
+		}
+		// nodePorts are allocated by the cluster unless the Service asks for them.
+		if spec["type"] != "NodePort" {
+			if ports, ok := spec["ports"].([]interface{}); ok {
+				for _, p := range ports {
+					if port, ok := p.(map[string]interface{}); ok {
+						delete(port, "nodePort")
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func cleanMetadata(meta map[string]interface{}) {
+	for _, f := range serverMetadata {
+		delete(meta, f)
+	}
+	if annotations, ok := meta["annotations"].(map[string]interface{}); ok {
+		for _, a := range serverAnnotations {
+			delete(annotations, a)
+		}
+		if len(annotations) == 0 {
+			delete(meta, "annotations")
+		}
+	}
+}
+
+func deepCopy(obj Object) Object {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	var out Object
+	if err := json.Unmarshal(data, &out); err != nil {
+		return obj
+	}
+	return out
+}
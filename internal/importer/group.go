@@ -0,0 +1,213 @@
+package importer
+
+import (
+	"sort"
+)
+
+// appLabels are checked in order to name the application a resource belongs to.
+var appLabels = []string{"app.kubernetes.io/name", "app", "k8s-app"}
+
+type appKey struct {
+	namespace string
+	name      string
+}
+
+// group assigns resources to applications. Deployments define applications;
+// Services join the Deployment their selector matches, Ingresses the
+// application of their backend Service, and ConfigMaps the Deployment that
+// mounts or references them. Services and Ingresses without a match form
+// their own application. ConfigMaps nothing refers to are returned as unowned.
+func group(objects []Object) ([]App, []Object) {
+	apps := make(map[appKey]*App)
+	var order []appKey
+	add := func(key appKey, obj Object) {
+		app, ok := apps[key]
+		if !ok {
+			app = &App{Name: key.name, Namespace: key.namespace}
+			apps[key] = app
+			order = append(order, key)
+		}
+		app.Resources = append(app.Resources, Resource{
+			Kind:      obj.kind(),
+			Name:      obj.name(),
+			Namespace: obj.namespace(),
+			Object:    Clean(obj),
+		})
+	}
+
+	byKind := make(map[string][]Object)
+	for _, obj := range objects {
+		byKind[obj.kind()] = append(byKind[obj.kind()], obj)
+	}
+
+	type workload struct {
+		key       appKey
+		podLabels map[string]string
+		configs   map[string]bool
+	}
+	var workloads []workload
+	for _, d := range byKind["Deployment"] {
+		key := appKey{d.namespace(), appName(d)}
+		workloads = append(workloads, workload{
+			key:       key,
+			podLabels: stringMap(lookup(d, "spec", "template", "metadata", "labels")),
+			configs:   configMapRefs(lookup(d, "spec", "template", "spec")),
+		})
+		add(key, d)
+	}
+
+	// labelled finds an existing application named by obj's labels.
+	labelled := func(obj Object) (appKey, bool) {
+		key := appKey{obj.namespace(), appName(obj)}
+		_, ok := apps[key]
+		return key, ok
+	}
+
+	serviceApps := make(map[appKey]appKey)
+	for _, svc := range byKind["Service"] {
+		key, found := appKey{}, false
+		if selector := stringMap(lookup(svc, "spec", "selector")); len(selector) > 0 {
+			for _, w := range workloads {
+				if w.key.namespace == svc.namespace() && matches(selector, w.podLabels) {
+					key, found = w.key, true
+					break
+				}
+			}
+		}
+		if !found {
+			key, found = labelled(svc)
+		}
+		if !found {
+			key = appKey{svc.namespace(), appName(svc)}
+		}
+		serviceApps[appKey{svc.namespace(), svc.name()}] = key
+		add(key, svc)
+	}
+
+	for _, ing := range byKind["Ingress"] {
+		key, found := appKey{}, false
+		for _, backend := range ingressBackends(ing) {
+			if k, ok := serviceApps[appKey{ing.namespace(), backend}]; ok {
+				key, found = k, true
+				break
+			}
+		}
+		if !found {
+			key, found = labelled(ing)
+		}
+		if !found {
+			key = appKey{ing.namespace(), appName(ing)}
+		}
+		add(key, ing)
+	}
+
+	var unowned []Object
+	for _, cm := range byKind["ConfigMap"] {
+		key, found := appKey{}, false
+		for _, w := range workloads {
+			if w.key.namespace == cm.namespace() && w.configs[cm.name()] {
+				key, found = w.key, true
+				break
+			}
+		}
+		if !found {
+			key, found = labelled(cm)
+		}
+		if !found {
+			unowned = append(unowned, cm)
+			continue
+		}
+		add(key, cm)
+	}
+
+	// Application names must be unique across namespaces.
+	counts := make(map[string]int)
+	for _, key := range order {
+		counts[key.name]++
+	}
+	result := make([]App, 0, len(order))
+	for _, key := range order {
+		app := *apps[key]
+		if counts[key.name] > 1 {
+			app.Name = key.name + "-" + key.namespace
+		}
+		result = append(result, app)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, unowned
+}
+
+func appName(obj Object) string {
+	labels := obj.labels()
+	for _, l := range appLabels {
+		if v := labels[l]; v != "" {
+			return v
+		}
+	}
+	return obj.name()
+}
+
+func matches(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// configMapRefs returns the ConfigMaps a pod spec mounts or references.
+func configMapRefs(podSpec interface{}) map[string]bool {
+	refs := make(map[string]bool)
+	addRef := func(v interface{}) {
+		if name, ok := v.(string); ok && name != "" {
+			refs[name] = true
+		}
+	}
+
+	if volumes, ok := lookup(podSpec, "volumes").([]interface{}); ok {
+		for _, vol := range volumes {
+			addRef(lookup(vol, "configMap", "name"))
+			if sources, ok := lookup(vol, "projected", "sources").([]interface{}); ok {
+				for _, src := range sources {
+					addRef(lookup(src, "configMap", "name"))
+				}
+			}
+		}
+	}
+
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, _ := lookup(podSpec, field).([]interface{})
+		for _, c := range containers {
+			if envFrom, ok := lookup(c, "envFrom").([]interface{}); ok {
+				for _, ef := range envFrom {
+					addRef(lookup(ef, "configMapRef", "name"))
+				}
+			}
+			if env, ok := lookup(c, "env").([]interface{}); ok {
+				for _, e := range env {
+					addRef(lookup(e, "valueFrom", "configMapKeyRef", "name"))
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// ingressBackends returns the Service names an Ingress routes to.
+func ingressBackends(ing Object) []string {
+	var names []string
+	if name, ok := lookup(ing, "spec", "defaultBackend", "service", "name").(string); ok {
+		names = append(names, name)
+	}
+	rules, _ := lookup(ing, "spec", "rules").([]interface{})
+	for _, rule := range rules {
+		paths, _ := lookup(rule, "http", "paths").([]interface{})
+		for _, p := range paths {
+			if name, ok := lookup(p, "backend", "service", "name").(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,332 @@
+// Package importer reverse-engineers a gitopsi repository from resources
+// running in a live cluster.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultKinds are the resource kinds imported when Options.Kinds is empty.
+var DefaultKinds = []string{"Deployment", "Service", "ConfigMap", "Ingress"}
+
+// kubectlResources maps importable kinds to their kubectl resource names.
+var kubectlResources = map[string]string{
+	"Deployment": "deployments.apps",
+	"Service":    "services",
+	"ConfigMap":  "configmaps",
+	"Ingress":    "ingresses.networking.k8s.io",
+}
+
+// systemNamespaces are never scanned unless selected explicitly.
+var systemNamespaces = []string{
+	"default", "kube-system", "kube-public", "kube-node-lease",
+	"argocd", "openshift-gitops", "flux-system", "local-path-storage",
+}
+
+// Options configures a cluster scan.
+type Options struct {
+	// Namespaces to scan. Empty scans every non-system namespace.
+	Namespaces []string
+	// Kinds to import. Empty means DefaultKinds.
+	Kinds []string
+	// Selector is a label selector passed to kubectl.
+	Selector string
+	// Exclude skips resources whose name matches any of these glob patterns.
+	Exclude []string
+	// IncludeHelm imports resources managed by Helm releases.
+	IncludeHelm bool
+	// Context and Kubeconfig select the cluster.
+	Context    string
+	Kubeconfig string
+}
+
+// Object is a Kubernetes resource as decoded from JSON.
+type Object map[string]interface{}
+
+// Resource is an imported, cleaned resource.
+type Resource struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Object    Object
+}
+
+// App is a group of resources that belong to the same application.
+type App struct {
+	Name      string
+	Namespace string
+	Resources []Resource
+}
+
+// Skipped records a resource that was not imported and why.
+type Skipped struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+// Result is the outcome of a scan.
+type Result struct {
+	Namespaces []string
+	Apps       []App
+	Skipped    []Skipped
+}
+
+// ResourceCount returns the number of imported resources.
+func (r *Result) ResourceCount() int {
+	n := 0
+	for _, app := range r.Apps {
+		n += len(app.Resources)
+	}
+	return n
+}
+
+// Runner executes kubectl with args and returns its stdout.
+type Runner func(ctx context.Context, args ...string) ([]byte, error)
+
+// Importer scans a cluster for resources to import.
+type Importer struct {
+	opts *Options
+	run  Runner
+}
+
+// New creates an Importer that shells out to kubectl.
+func New(opts *Options) *Importer {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &Importer{opts: opts, run: runKubectl}
+}
+
+// SetRunner replaces the kubectl runner (used for testing).
+func (i *Importer) SetRunner(run Runner) {
+	i.run = run
+}
+
+// Scan reads resources from the selected namespaces, cleans them and groups
+// them into applications.
+func (i *Importer) Scan(ctx context.Context) (*Result, error) {
+	kinds := i.opts.Kinds
+	if len(kinds) == 0 {
+		kinds = DefaultKinds
+	}
+	resources := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		res, ok := kubectlResources[kind]
+		if !ok {
+			return nil, fmt.Errorf("unsupported kind: %s (supported: %s)", kind, strings.Join(DefaultKinds, ", "))
+		}
+		resources = append(resources, res)
+	}
+
+	namespaces, err := i.namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Namespaces: namespaces}
+	var objects []Object
+	for _, ns := range namespaces {
+		args := []string{"get", strings.Join(resources, ","), "-n", ns, "-o", "json"}
+		if i.opts.Selector != "" {
+			args = append(args, "-l", i.opts.Selector)
+		}
+		items, err := i.list(ctx, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resources in namespace %s: %w", ns, err)
+		}
+		for _, obj := range items {
+			if reason := i.skipReason(obj); reason != "" {
+				result.Skipped = append(result.Skipped, skipped(obj, reason))
+				continue
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	apps, unowned := group(objects)
+	for _, obj := range unowned {
+		result.Skipped = append(result.Skipped, skipped(obj, "not referenced by any imported workload"))
+	}
+	result.Apps = apps
+	return result, nil
+}
+
+func (i *Importer) namespaces(ctx context.Context) ([]string, error) {
+	if len(i.opts.Namespaces) > 0 {
+		return i.opts.Namespaces, nil
+	}
+
+	items, err := i.list(ctx, "get", "namespaces", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var namespaces []string
+	for _, obj := range items {
+		name := obj.name()
+		if isSystemNamespace(name) {
+			continue
+		}
+		namespaces = append(namespaces, name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (i *Importer) list(ctx context.Context, args ...string) ([]Object, error) {
+	out, err := i.run(ctx, i.kubectlArgs(args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []Object `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return list.Items, nil
+}
+
+func (i *Importer) kubectlArgs(args ...string) []string {
+	var prefix []string
+	if i.opts.Kubeconfig != "" {
+		prefix = append(prefix, "--kubeconfig", i.opts.Kubeconfig)
+	}
+	if i.opts.Context != "" {
+		prefix = append(prefix, "--context", i.opts.Context)
+	}
+	return append(prefix, args...)
+}
+
+// skipReason applies the resource filters and ownership heuristics. It
+// returns why obj should not be imported, or "" to import it.
+func (i *Importer) skipReason(obj Object) string {
+	kind, name := obj.kind(), obj.name()
+	labels := obj.labels()
+	annotations := obj.annotations()
+
+	for _, pattern := range i.opts.Exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return "excluded by pattern " + pattern
+		}
+	}
+
+	if owners, ok := obj.metadata()["ownerReferences"].([]interface{}); ok && len(owners) > 0 {
+		if owner, ok := owners[0].(map[string]interface{}); ok {
+			return fmt.Sprintf("owned by %v/%v", owner["kind"], owner["name"])
+		}
+		return "owned by another resource"
+	}
+
+	if _, ok := annotations["argocd.argoproj.io/tracking-id"]; ok {
+		return "already managed by ArgoCD"
+	}
+	if _, ok := labels["kustomize.toolkit.fluxcd.io/name"]; ok {
+		return "already managed by Flux"
+	}
+	if _, ok := labels["helm.toolkit.fluxcd.io/name"]; ok {
+		return "already managed by Flux"
+	}
+	if labels["app.kubernetes.io/managed-by"] == "Helm" && !i.opts.IncludeHelm {
+		if release := annotations["meta.helm.sh/release-name"]; release != "" {
+			return "managed by Helm release " + release
+		}
+		return "managed by Helm"
+	}
+
+	switch kind {
+	case "ConfigMap":
+		if name == "kube-root-ca.crt" || name == "openshift-service-ca.crt" || strings.HasSuffix(name, "-ca-root-cert") {
+			return "cluster-managed ConfigMap"
+		}
+	case "Service":
+		if name == "kubernetes" && obj.namespace() == "default" {
+			return "cluster-managed Service"
+		}
+	}
+	return ""
+}
+
+func isSystemNamespace(name string) bool {
+	return slices.Contains(systemNamespaces, name) ||
+		strings.HasPrefix(name, "kube-") ||
+		strings.HasPrefix(name, "openshift")
+}
+
+func skipped(obj Object, reason string) Skipped {
+	return Skipped{Kind: obj.kind(), Namespace: obj.namespace(), Name: obj.name(), Reason: reason}
+}
+
+func runKubectl(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("kubectl %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to run kubectl: %w", err)
+	}
+	return out, nil
+}
+
+func (o Object) kind() string {
+	kind, _ := o["kind"].(string)
+	return kind
+}
+
+func (o Object) metadata() map[string]interface{} {
+	meta, _ := o["metadata"].(map[string]interface{})
+	return meta
+}
+
+func (o Object) name() string {
+	name, _ := o.metadata()["name"].(string)
+	return name
+}
+
+func (o Object) namespace() string {
+	ns, _ := o.metadata()["namespace"].(string)
+	return ns
+}
+
+func (o Object) labels() map[string]string {
+	return stringMap(o.metadata()["labels"])
+}
+
+func (o Object) annotations() map[string]string {
+	return stringMap(o.metadata()["annotations"])
+}
+
+func stringMap(v interface{}) map[string]string {
+	m, _ := v.(map[string]interface{})
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		if s, ok := val.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+// lookup walks nested maps by key and returns the value or nil.
+func lookup(v interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		switch m := v.(type) {
+		case Object:
+			v = m[k]
+		case map[string]interface{}:
+			v = m[k]
+		default:
+			return nil
+		}
+	}
+	return v
+}
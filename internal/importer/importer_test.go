@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func deployment(ns, name string, labels map[string]interface{}, podSpec map[string]interface{}) Object {
+	return Object{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name": name, "namespace": ns, "labels": labels,
+			"uid": "abc", "resourceVersion": "42", "generation": 3.0,
+			"creationTimestamp": "2024-01-01T00:00:00Z",
+			"managedFields":     []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			"annotations": map[string]interface{}{
+				"deployment.kubernetes.io/revision": "3",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 2.0,
+			"selector": map[string]interface{}{"matchLabels": labels},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels, "creationTimestamp": nil},
+				"spec":     podSpec,
+			},
+		},
+		"status": map[string]interface{}{"readyReplicas": 2.0},
+	}
+}
+
+func object(kind, ns, name string, meta map[string]interface{}, fields map[string]interface{}) Object {
+	m := map[string]interface{}{"name": name, "namespace": ns}
+	for k, v := range meta {
+		m[k] = v
+	}
+	obj := Object{"apiVersion": "v1", "kind": kind, "metadata": m}
+	for k, v := range fields {
+		obj[k] = v
+	}
+	return obj
+}
+
+func shopObjects() []Object {
+	web := map[string]interface{}{"app": "web"}
+	return []Object{
+		deployment("shop", "web", web, map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{
+				"name":    "web",
+				"image":   "nginx:1.25",
+				"envFrom": []interface{}{map[string]interface{}{"configMapRef": map[string]interface{}{"name": "web-env"}}},
+			}},
+			"volumes": []interface{}{map[string]interface{}{"name": "cfg", "configMap": map[string]interface{}{"name": "web-config"}}},
+		}),
+		object("Service", "shop", "web-svc", nil, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"type":       "ClusterIP",
+				"selector":   web,
+				"clusterIP":  "10.0.0.1",
+				"clusterIPs": []interface{}{"10.0.0.1"},
+				"ports":      []interface{}{map[string]interface{}{"port": 80.0, "nodePort": 30080.0}},
+			},
+			"status": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+		}),
+		object("Ingress", "shop", "web", nil, map[string]interface{}{
+			"apiVersion": "networking.k8s.io/v1",
+			"spec": map[string]interface{}{"rules": []interface{}{map[string]interface{}{
+				"http": map[string]interface{}{"paths": []interface{}{map[string]interface{}{
+					"path":    "/",
+					"backend": map[string]interface{}{"service": map[string]interface{}{"name": "web-svc"}},
+				}}},
+			}}},
+		}),
+		object("ConfigMap", "shop", "web-config", nil, map[string]interface{}{"data": map[string]interface{}{"a": "b"}}),
+		object("ConfigMap", "shop", "web-env", nil, map[string]interface{}{"data": map[string]interface{}{"LOG": "debug"}}),
+		object("ConfigMap", "shop", "kube-root-ca.crt", nil, nil),
+		object("ConfigMap", "shop", "orphan", nil, nil),
+		object("ConfigMap", "shop", "tmp-cache", map[string]interface{}{"labels": web}, nil),
+		object("Service", "shop", "redis", map[string]interface{}{
+			"labels":      map[string]interface{}{"app.kubernetes.io/managed-by": "Helm"},
+			"annotations": map[string]interface{}{"meta.helm.sh/release-name": "redis"},
+		}, nil),
+		object("Service", "shop", "tracked", map[string]interface{}{
+			"annotations": map[string]interface{}{"argocd.argoproj.io/tracking-id": "x"},
+		}, nil),
+		object("ConfigMap", "shop", "owned", map[string]interface{}{
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": "Certificate", "name": "tls"}},
+		}, nil),
+	}
+}
+
+type fakeKubectl struct {
+	calls [][]string
+	items map[string][]Object
+}
+
+func (f *fakeKubectl) run(ctx context.Context, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, args)
+	key := "namespaces"
+	for i, a := range args {
+		if a == "-n" {
+			key = args[i+1]
+		}
+	}
+	return json.Marshal(map[string]interface{}{"items": f.items[key]})
+}
+
+func TestScan(t *testing.T) {
+	fake := &fakeKubectl{items: map[string][]Object{
+		"namespaces": {
+			object("Namespace", "", "shop", nil, nil),
+			object("Namespace", "", "blog", nil, nil),
+			object("Namespace", "", "kube-system", nil, nil),
+			object("Namespace", "", "openshift-monitoring", nil, nil),
+			object("Namespace", "", "argocd", nil, nil),
+		},
+		"shop": shopObjects(),
+		"blog": {deployment("blog", "web", map[string]interface{}{"app": "web"}, nil)},
+	}}
+
+	imp := New(&Options{Context: "prod", Exclude: []string{"tmp-*"}})
+	imp.SetRunner(fake.run)
+
+	result, err := imp.Scan(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"blog", "shop"}, result.Namespaces)
+	assert.Equal(t, []string{"--context", "prod", "get", "namespaces", "-o", "json"}, fake.calls[0])
+	assert.Contains(t, strings.Join(fake.calls[1], " "), "get deployments.apps,services,configmaps,ingresses.networking.k8s.io -n blog")
+
+	require.Len(t, result.Apps, 2)
+	assert.Equal(t, "web-blog", result.Apps[0].Name)
+	shop := result.Apps[1]
+	assert.Equal(t, "web-shop", shop.Name)
+	assert.Equal(t, "shop", shop.Namespace)
+
+	var names []string
+	for _, r := range shop.Resources {
+		names = append(names, r.Kind+"/"+r.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"Deployment/web", "Service/web-svc", "Ingress/web", "ConfigMap/web-config", "ConfigMap/web-env",
+	}, names)
+	assert.Equal(t, 6, result.ResourceCount())
+
+	reasons := make(map[string]string)
+	for _, s := range result.Skipped {
+		reasons[s.Name] = s.Reason
+	}
+	assert.Equal(t, "cluster-managed ConfigMap", reasons["kube-root-ca.crt"])
+	assert.Equal(t, "not referenced by any imported workload", reasons["orphan"])
+	assert.Equal(t, "excluded by pattern tmp-*", reasons["tmp-cache"])
+	assert.Equal(t, "managed by Helm release redis", reasons["redis"])
+	assert.Equal(t, "already managed by ArgoCD", reasons["tracked"])
+	assert.Equal(t, "owned by Certificate/tls", reasons["owned"])
+}
+
+func TestScanOptions(t *testing.T) {
+	fake := &fakeKubectl{items: map[string][]Object{"shop": shopObjects()}}
+	imp := New(&Options{Namespaces: []string{"shop"}, Kinds: []string{"Deployment"}, Selector: "tier=web", IncludeHelm: true})
+	imp.SetRunner(fake.run)
+
+	_, err := imp.Scan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, []string{"get", "deployments.apps", "-n", "shop", "-o", "json", "-l", "tier=web"}, fake.calls[0])
+
+	_, err = New(&Options{Kinds: []string{"Secret"}}).Scan(context.Background())
+	assert.ErrorContains(t, err, "unsupported kind: Secret")
+}
+
+func TestClean(t *testing.T) {
+	objs := shopObjects()
+
+	d := Clean(objs[0])
+	assert.NotContains(t, d, "status")
+	meta := d.metadata()
+	for _, f := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "namespace", "annotations"} {
+		assert.NotContains(t, meta, f)
+	}
+	assert.NotContains(t, lookup(d, "spec", "template", "metadata"), "creationTimestamp")
+	assert.Contains(t, objs[0].metadata(), "uid", "Clean must not modify its input")
+
+	svc := Clean(objs[1])
+	spec := svc["spec"].(map[string]interface{})
+	assert.NotContains(t, spec, "clusterIP")
+	assert.NotContains(t, spec, "clusterIPs")
+	assert.NotContains(t, spec["ports"].([]interface{})[0], "nodePort")
+	assert.Equal(t, "ClusterIP", spec["type"])
+}
+
+func TestWrite(t *testing.T) {
+	fake := &fakeKubectl{items: map[string][]Object{"shop": shopObjects()}}
+	imp := New(&Options{Namespaces: []string{"shop"}})
+	imp.SetRunner(fake.run)
+	result, err := imp.Scan(context.Background())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	err = Write(output.New(dir, false, false), result, WriteOptions{
+		Project:         "imported",
+		Environment:     "prod",
+		RepoURL:         "https://github.com/org/repo.git",
+		Branch:          "main",
+		ArgoCDNamespace: "argocd",
+	})
+	require.NoError(t, err)
+
+	read := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(dir, "imported", rel))
+		require.NoError(t, err, "expected %s", rel)
+		return string(data)
+	}
+
+	deploy := read("applications/base/web/deployment.yaml")
+	assert.True(t, strings.HasPrefix(deploy, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n"), deploy)
+	assert.Contains(t, deploy, "replicas: 2\n")
+	assert.NotContains(t, deploy, "resourceVersion")
+	assert.NotContains(t, deploy, "status:")
+
+	read("applications/base/web/service-web-svc.yaml")
+	read("applications/base/web/configmap-web-config.yaml")
+	assert.Contains(t, read("applications/base/web/kustomization.yaml"), "- configmap-web-config.yaml")
+	assert.Contains(t, read("applications/base/kustomization.yaml"), "- web/")
+
+	overlay := read("applications/overlays/prod/web/kustomization.yaml")
+	assert.Contains(t, overlay, "namespace: shop")
+	assert.Contains(t, overlay, "- ../../../base/web")
+	assert.Contains(t, read("applications/overlays/prod/kustomization.yaml"), "- web/")
+
+	app := read("argocd/applications/web-prod.yaml")
+	assert.Contains(t, app, "path: applications/overlays/prod/web")
+	assert.Contains(t, app, "namespace: shop")
+	assert.Contains(t, app, "targetRevision: main")
+	assert.Contains(t, read("argocd/projects/applications.yaml"), "kind: AppProject")
+
+	noArgo := t.TempDir()
+	require.NoError(t, Write(output.New(noArgo, false, false), result, WriteOptions{Project: "imported", Environment: "prod"}))
+	assert.NoDirExists(t, filepath.Join(noArgo, "imported", "argocd"))
+}
@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+	"github.com/ihsanmokhlisse/gitopsi/internal/templates"
+)
+
+// WriteOptions controls the layout of the imported repository.
+type WriteOptions struct {
+	Project     string
+	Environment string
+	// RepoURL and Branch are used for ArgoCD Applications. Without a
+	// RepoURL no Applications are written.
+	RepoURL         string
+	Branch          string
+	ArgoCDNamespace string
+}
+
+// Write emits the imported applications in gitopsi's layout:
+//
+//	applications/base/<app>/            cleaned manifests
+//	applications/overlays/<env>/<app>/  namespace overlay
+//	argocd/applications/<app>-<env>.yaml
+func Write(w *output.Writer, result *Result, opts WriteOptions) error {
+	appsDir := opts.Project + "/applications"
+	baseDirs := make([]string, 0, len(result.Apps))
+	overlayDirs := make([]string, 0, len(result.Apps))
+
+	for _, app := range result.Apps {
+		files, err := writeBase(w, appsDir+"/base/"+app.Name, app)
+		if err != nil {
+			return fmt.Errorf("failed to write application %s: %w", app.Name, err)
+		}
+		if err := writeKustomization(w, appsDir+"/base/"+app.Name, "", files); err != nil {
+			return err
+		}
+
+		overlay := appsDir + "/overlays/" + opts.Environment + "/" + app.Name
+		if err := writeKustomization(w, overlay, app.Namespace, []string{"../../../base/" + app.Name}); err != nil {
+			return err
+		}
+
+		baseDirs = append(baseDirs, app.Name+"/")
+		overlayDirs = append(overlayDirs, app.Name+"/")
+	}
+
+	if err := writeKustomization(w, appsDir+"/base", "", baseDirs); err != nil {
+		return err
+	}
+	if err := writeKustomization(w, appsDir+"/overlays/"+opts.Environment, "", overlayDirs); err != nil {
+		return err
+	}
+
+	if opts.RepoURL == "" {
+		return nil
+	}
+	return writeArgoCD(w, result, opts)
+}
+
+func writeBase(w *output.Writer, dir string, app App) ([]string, error) {
+	files := make([]string, 0, len(app.Resources))
+	seen := make(map[string]bool)
+	for _, res := range app.Resources {
+		file := strings.ToLower(res.Kind)
+		if res.Name != app.Name {
+			file += "-" + res.Name
+		}
+		file += ".yaml"
+		if seen[file] {
+			return nil, fmt.Errorf("duplicate resource %s %s", res.Kind, res.Name)
+		}
+		seen[file] = true
+
+		content, err := Marshal(res.Object)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.WriteFile(dir+"/"+file, content); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func writeKustomization(w *output.Writer, dir, namespace string, resources []string) error {
+	content, err := templates.Render("kubernetes/kustomization.yaml.tmpl", map[string]interface{}{
+		"Resources": resources,
+	})
+	if err != nil {
+		return err
+	}
+	if namespace != "" {
+		content = bytes.Replace(content, []byte("kind: Kustomization\n"),
+			[]byte("kind: Kustomization\n\nnamespace: "+namespace+"\n"), 1)
+	}
+	return w.WriteFile(dir+"/kustomization.yaml", content)
+}
+
+func writeArgoCD(w *output.Writer, result *Result, opts WriteOptions) error {
+	dir := opts.Project + "/argocd"
+
+	project, err := templates.Render("argocd/project.yaml.tmpl", map[string]string{
+		"Name":            "applications",
+		"Description":     "Applications imported from the cluster",
+		"ArgoCDNamespace": opts.ArgoCDNamespace,
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.WriteFile(dir+"/projects/applications.yaml", project); err != nil {
+		return err
+	}
+
+	revision := opts.Branch
+	if revision == "" {
+		revision = "HEAD"
+	}
+	for _, app := range result.Apps {
+		content, err := templates.Render("argocd/application.yaml.tmpl", map[string]string{
+			"Name":            app.Name + "-" + opts.Environment,
+			"Project":         "applications",
+			"RepoURL":         opts.RepoURL,
+			"Path":            fmt.Sprintf("applications/overlays/%s/%s", opts.Environment, app.Name),
+			"Namespace":       app.Namespace,
+			"TargetRevision":  revision,
+			"ArgoCDNamespace": opts.ArgoCDNamespace,
+		})
+		if err != nil {
+			return err
+		}
+		if err := w.WriteFile(fmt.Sprintf("%s/applications/%s-%s.yaml", dir, app.Name, opts.Environment), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topLevelOrder is the conventional order of manifest fields.
+var topLevelOrder = []string{"apiVersion", "kind", "metadata", "spec", "data", "binaryData"}
+
+// Marshal encodes obj as YAML with apiVersion, kind and metadata first.
+// Remaining fields are sorted.
+func Marshal(obj Object) ([]byte, error) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	rank := func(k string) int {
+		for i, o := range topLevelOrder {
+			if k == o {
+				return i
+			}
+		}
+		return len(topLevelOrder)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := rank(keys[i]), rank(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range keys {
+		var value yaml.Node
+		if err := value.Encode(obj[k]); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", k, err)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k}, &value)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
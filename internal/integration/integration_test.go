@@ -331,9 +331,34 @@ func TestIntegration_EnvironmentFlow_CreateAndManage(t *testing.T) {
 func TestIntegration_EnvironmentFlow_Promotion(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	mgr := environment.NewManager(tmpDir)
+	cfg := &config.Config{
+		Project:    config.Project{Name: "promotion-test"},
+		Platform:   "kubernetes",
+		Scope:      "application",
+		GitOpsTool: "argocd",
+		Git:        config.GitConfig{URL: "https://github.com/test/promotion-test.git"},
+		Environments: []config.Environment{
+			{Name: "dev"},
+			{Name: "staging"},
+			{Name: "prod"},
+		},
+		Apps: []config.Application{
+			{Name: "my-app", Image: "ghcr.io/test/my-app:1.0.0", Port: 8080, Replicas: 1},
+		},
+	}
+	gen := generator.New(cfg, output.New(tmpDir, false, false), false)
+	require.NoError(t, gen.Generate(), "Generation should succeed")
 
-	err := mgr.CreateEnvironment("dev", environment.CreateEnvOptions{})
+	projectDir := filepath.Join(tmpDir, "promotion-test")
+	devOverlay := filepath.Join(projectDir, "applications/overlays/dev/kustomization.yaml")
+	content, err := os.ReadFile(devOverlay)
+	require.NoError(t, err)
+	content = append(content, []byte("images:\n  - name: ghcr.io/test/my-app\n    newTag: 1.1.0\n")...)
+	require.NoError(t, os.WriteFile(devOverlay, content, 0644))
+
+	mgr := environment.NewManager(projectDir)
+
+	err = mgr.CreateEnvironment("dev", environment.CreateEnvOptions{})
 	require.NoError(t, err)
 
 	err = mgr.CreateEnvironment("staging", environment.CreateEnvOptions{})
@@ -352,6 +377,12 @@ func TestIntegration_EnvironmentFlow_Promotion(t *testing.T) {
 	assert.Equal(t, "my-app", result.Application)
 	assert.Equal(t, "dev", result.FromEnv)
 	assert.Equal(t, "staging", result.ToEnv)
+
+	staging, err := os.ReadFile(filepath.Join(projectDir, "applications/overlays/staging/kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(staging), "newTag: 1.1.0", "Staging should run the dev image")
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, "ghcr.io/test/my-app:1.0.0", result.Records[0].Images[0].From)
 }
 
 func TestIntegration_FullWorkflow_ConfigToValidation(t *testing.T) {
@@ -504,10 +535,6 @@ func TestIntegration_GitOpsToolSelection(t *testing.T) {
 
 	for _, tool := range tools {
 		t.Run(tool, func(t *testing.T) {
-			// TODO: Flux support is disabled - focus on ArgoCD first
-			if tool == "flux" {
-				t.Skip("Flux support is disabled - focusing on ArgoCD first")
-			}
 			tmpDir := t.TempDir()
 
 			cfg := &config.Config{
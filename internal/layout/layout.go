@@ -0,0 +1,84 @@
+// Package layout records which files of a generated repository gitopsi owns
+// and the layout version they follow, in .gitopsi/metadata.yaml.
+//
+// Files are identified by the Git blob ID of their generated content, so the
+// content generated by an earlier run can be read back from the history of
+// the repository as the base of a three-way merge.
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MetadataFile is the metadata of a generated repository, relative to its
+	// root.
+	MetadataFile = ".gitopsi/metadata.yaml"
+
+	// CurrentVersion is the layout version generated by this gitopsi. Bump it
+	// and register a migration in internal/upgrade when the generated
+	// structure changes.
+	CurrentVersion = 1
+)
+
+const header = "# Generated by gitopsi. Do not edit: gitopsi upgrade relies on it.\n"
+
+// Metadata describes a generated repository.
+type Metadata struct {
+	LayoutVersion int `yaml:"layout_version" json:"layout_version"`
+	// Files maps each generated file, relative to the repository root, to
+	// the Git blob ID of its generated content.
+	Files map[string]string `yaml:"files" json:"files"`
+}
+
+// New returns metadata of the current layout without files.
+func New() *Metadata {
+	return &Metadata{LayoutVersion: CurrentVersion, Files: map[string]string{}}
+}
+
+// Hash returns the Git blob ID of content.
+func Hash(content []byte) string {
+	return plumbing.ComputeHash(plumbing.BlobObject, content).String()
+}
+
+// Load reads the metadata of the repository in dir. It returns nil when the
+// repository has none, as do repositories generated before layouts were
+// versioned.
+func Load(dir string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", MetadataFile, err)
+	}
+	m := New()
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", MetadataFile, err)
+	}
+	if m.Files == nil {
+		m.Files = map[string]string{}
+	}
+	return m, nil
+}
+
+// Marshal encodes the metadata for MetadataFile.
+func (m *Metadata) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal layout metadata: %w", err)
+	}
+	return append([]byte(header), data...), nil
+}
+
+// Modified reports whether content differs from what gitopsi generated for
+// path. Files gitopsi never generated are reported as modified.
+func (m *Metadata) Modified(path string, content []byte) bool {
+	hash, ok := m.Files[path]
+	return !ok || hash != Hash(content)
+}
@@ -0,0 +1,49 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	// git hash-object of "hello\n"
+	if got := Hash([]byte("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Hash() = %s", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	meta, err := Load(dir)
+	if err != nil || meta != nil {
+		t.Fatalf("Load() without metadata = %v, %v", meta, err)
+	}
+
+	m := New()
+	m.Files["README.md"] = Hash([]byte("readme"))
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".gitopsi"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, MetadataFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if meta.LayoutVersion != CurrentVersion {
+		t.Errorf("expected layout version %d, got %d", CurrentVersion, meta.LayoutVersion)
+	}
+	if meta.Modified("README.md", []byte("readme")) {
+		t.Error("expected README.md to be unmodified")
+	}
+	if !meta.Modified("README.md", []byte("edited")) || !meta.Modified("other.yaml", nil) {
+		t.Error("expected edited and unknown files to be modified")
+	}
+}
@@ -0,0 +1,198 @@
+// Package logging configures the structured log of gitopsi. Records are
+// written with log/slog to stderr and an optional log file, next to the
+// terminal output rendered with pterm.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/audit"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// DefaultLevel is the level logged to stderr unless configured otherwise.
+// Warnings and errors only, so the rendered terminal output stays readable.
+const DefaultLevel = "warn"
+
+// Options configures the log.
+type Options struct {
+	// Level is the minimum level logged to stderr: debug, info, warn or error.
+	Level string
+	// Format is the format of records: text or json.
+	Format string
+	// File is a file the log is appended to, at debug level whatever Level is.
+	File string
+}
+
+// ParseLevel returns the slog level named s.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level: %s (must be debug, info, warn or error)", s)
+	}
+}
+
+// New returns a logger writing to stderr, and to the log file of opts, and
+// the function closing the file.
+func New(opts Options, stderr io.Writer) (*slog.Logger, func() error, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	format := opts.Format
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatJSON {
+		return nil, nil, fmt.Errorf("invalid log format: %s (must be text or json)", format)
+	}
+
+	handlers := fanout{newHandler(stderr, format, level)}
+	closeFile := func() error { return nil }
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create log file directory: %w", err)
+		}
+		f, err := os.OpenFile(opts.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		handlers = append(handlers, newHandler(f, format, slog.LevelDebug))
+		closeFile = f.Close
+	}
+	return slog.New(handlers), closeFile, nil
+}
+
+// Setup installs the logger of opts as the default slog logger.
+func Setup(opts Options, stderr io.Writer) (func() error, error) {
+	logger, closeFile, err := New(opts, stderr)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return closeFile, nil
+}
+
+func newHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// fanout sends records to every handler enabled for their level.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// CombinedOutput runs cmd like (*exec.Cmd).CombinedOutput and logs the
+// command, its duration and its output at debug level, so the output of
+// failed external tools can be read in the log even where it is not part of
+// the returned error. Secret flag values are redacted.
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	return combinedOutput(cmd, true)
+}
+
+// SecretOutput runs cmd like CombinedOutput for commands printing secrets,
+// such as kubectl get secret: their output is not logged.
+func SecretOutput(cmd *exec.Cmd) ([]byte, error) {
+	return combinedOutput(cmd, false)
+}
+
+func combinedOutput(cmd *exec.Cmd, logOutput bool) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+
+	attrs := []any{
+		slog.String("command", strings.Join(RedactArgs(cmd.Args), " ")),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if logOutput {
+		attrs = append(attrs, slog.String("output", string(bytes.TrimSpace(output))))
+	}
+	if err != nil {
+		slog.Debug("command failed", append(attrs, slog.String("error", err.Error()))...)
+	} else {
+		slog.Debug("command succeeded", attrs...)
+	}
+	return output, err
+}
+
+// RedactArgs returns args with the values of secret flags, such as --token,
+// redacted.
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, inline := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if audit.Redact(name, value) == value {
+			continue
+		}
+		if inline {
+			redacted[i] = arg[:len(arg)-len(value)] + "***"
+		} else if i+1 < len(redacted) {
+			redacted[i+1] = "***"
+		}
+	}
+	return redacted
+}
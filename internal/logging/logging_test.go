@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for name, want := range tests {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel(trace) should fail")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var stderr bytes.Buffer
+	file := filepath.Join(t.TempDir(), "logs", "gitopsi.log")
+	logger, closeFile, err := New(Options{Level: "warn", Format: FormatJSON, File: file}, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("applying manifest", "path", "install.yaml")
+	logger.Warn("cluster unreachable", "context", "prod")
+	if err := closeFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("stderr should only have the warning, got %q", stderr.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("stderr is not JSON: %v", err)
+	}
+	if record["msg"] != "cluster unreachable" || record["context"] != "prod" {
+		t.Errorf("record = %v", record)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("log file should have both records, got %q", data)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, _, err := New(Options{Level: "loud"}, &bytes.Buffer{}); err == nil {
+		t.Error("New() should reject an invalid level")
+	}
+	if _, _, err := New(Options{Format: "xml"}, &bytes.Buffer{}); err == nil {
+		t.Error("New() should reject an invalid format")
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	var stderr bytes.Buffer
+	logger, _, err := New(Options{Level: "debug"}, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	output, err := CombinedOutput(exec.Command("sh", "-c", "echo forbidden >&2; exit 1", "--token", "abc"))
+	if err == nil || strings.TrimSpace(string(output)) != "forbidden" {
+		t.Fatalf("CombinedOutput() = %q, %v", output, err)
+	}
+	logged := stderr.String()
+	if !strings.Contains(logged, `msg="command failed"`) || !strings.Contains(logged, "output=forbidden") {
+		t.Errorf("failed command not logged with its output: %s", logged)
+	}
+	if strings.Contains(logged, "abc") {
+		t.Errorf("token logged: %s", logged)
+	}
+
+	stderr.Reset()
+	if _, err := SecretOutput(exec.Command("sh", "-c", "echo s3cr3t")); err != nil {
+		t.Fatal(err)
+	}
+	if logged := stderr.String(); !strings.Contains(logged, `msg="command succeeded"`) || strings.Contains(logged, "output=") {
+		t.Errorf("SecretOutput() log = %s", logged)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"kubectl", "--server", "https://api:6443", "--token", "abc", "--password=hunter2", "get", "pods"}
+	want := []string{"kubectl", "--server", "https://api:6443", "--token", "***", "--password=***", "get", "pods"}
+	if got := RedactArgs(args); !slices.Equal(got, want) {
+		t.Errorf("RedactArgs() = %v, want %v", got, want)
+	}
+	if args[4] != "abc" {
+		t.Error("RedactArgs() should not change its argument")
+	}
+}
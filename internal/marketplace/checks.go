@@ -0,0 +1,255 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+// DefaultCheckTimeout is the timeout of validation checks without one.
+const DefaultCheckTimeout = 5 * time.Minute
+
+// checkPollInterval is how often a failing check is evaluated again.
+var checkPollInterval = 5 * time.Second
+
+// Check statuses.
+const (
+	CheckPassed = "passed"
+	CheckFailed = "failed"
+)
+
+// Kubectl runs kubectl with args and returns its trimmed output.
+type Kubectl func(ctx context.Context, args ...string) (string, error)
+
+// CheckOptions configures the execution of validation checks.
+type CheckOptions struct {
+	// Kubectl runs kubectl; the default runs the kubectl binary with Context.
+	Kubectl Kubectl
+	// Context is the kubeconfig context of the cluster.
+	Context string
+	// Wait retries failing checks until their timeout; otherwise each check
+	// is evaluated once.
+	Wait bool
+}
+
+// CheckResult is the result of a validation check.
+type CheckResult struct {
+	Name     string        `yaml:"name" json:"name"`
+	Check    string        `yaml:"check" json:"check"`
+	Status   string        `yaml:"status" json:"status"`
+	Message  string        `yaml:"message,omitempty" json:"message,omitempty"`
+	Duration time.Duration `yaml:"duration" json:"duration"`
+}
+
+// check is a parsed validation check: "<kind>/<name> <condition>" with an
+// optional "-n <namespace>". Conditions are ready, exists, healthy (ArgoCD
+// Applications and Flux resources) and condition=<type>.
+type check struct {
+	kind, name, namespace, condition string
+}
+
+func parseCheck(text, namespace string) (check, error) {
+	fields := strings.Fields(text)
+	c := check{namespace: namespace}
+	var rest []string
+	for idx := 0; idx < len(fields); idx++ {
+		switch {
+		case (fields[idx] == "-n" || fields[idx] == "--namespace") && idx+1 < len(fields):
+			c.namespace = fields[idx+1]
+			idx++
+		case strings.HasPrefix(fields[idx], "--namespace="):
+			c.namespace = strings.TrimPrefix(fields[idx], "--namespace=")
+		default:
+			rest = append(rest, fields[idx])
+		}
+	}
+	if len(rest) != 2 {
+		return c, fmt.Errorf("invalid check %q: use <kind>/<name> ready|exists|healthy|condition=<type>", text)
+	}
+	kind, name, ok := strings.Cut(rest[0], "/")
+	if !ok || kind == "" || name == "" {
+		return c, fmt.Errorf("invalid check %q: %s is not <kind>/<name>", text, rest[0])
+	}
+	c.kind, c.name, c.condition = strings.ToLower(kind), name, rest[1]
+	switch {
+	case c.condition == "ready", c.condition == "exists", c.condition == "healthy":
+	case strings.HasPrefix(c.condition, "condition=") && len(c.condition) > len("condition="):
+	default:
+		return c, fmt.Errorf("invalid check %q: unknown condition %s", text, c.condition)
+	}
+	return c, nil
+}
+
+// RunChecks evaluates the validation checks of a pattern in namespace, the
+// default namespace of resources without -n.
+func RunChecks(ctx context.Context, pattern *Pattern, namespace string, opts CheckOptions) []CheckResult {
+	if opts.Kubectl == nil {
+		opts.Kubectl = kubectlBinary(opts.Context)
+	}
+	results := make([]CheckResult, 0, len(pattern.Spec.Validation))
+	for _, v := range pattern.Spec.Validation {
+		results = append(results, runCheck(ctx, v, namespace, opts))
+	}
+	return results
+}
+
+func runCheck(ctx context.Context, v ValidationCheck, namespace string, opts CheckOptions) CheckResult {
+	result := CheckResult{Name: v.Name, Check: v.Check, Status: CheckFailed}
+	c, err := parseCheck(v.Check, namespace)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	timeout := DefaultCheckTimeout
+	if v.Timeout != "" {
+		if timeout, err = time.ParseDuration(v.Timeout); err != nil {
+			result.Message = fmt.Sprintf("invalid timeout %q: %v", v.Timeout, err)
+			return result
+		}
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		ok, message, err := c.evaluate(ctx, opts.Kubectl)
+		result.Duration = time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			message = err.Error()
+		}
+		result.Message = message
+		if ok {
+			result.Status = CheckPassed
+			return result
+		}
+		if !opts.Wait {
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			result.Message = fmt.Sprintf("timed out after %s: %s", timeout, message)
+			return result
+		case <-time.After(checkPollInterval):
+		}
+	}
+}
+
+// evaluate evaluates a check once and describes the state of the resource.
+func (c check) evaluate(ctx context.Context, kubectl Kubectl) (bool, string, error) {
+	resource := c.kind + "/" + c.name
+	get := func(jsonpath string) (string, error) {
+		args := []string{"get", resource}
+		if c.namespace != "" {
+			args = append(args, "-n", c.namespace)
+		}
+		return kubectl(ctx, append(args, "-o", "jsonpath="+jsonpath)...)
+	}
+
+	switch {
+	case c.condition == "exists":
+		if _, err := get("{.metadata.name}"); err != nil {
+			return false, "", err
+		}
+		return true, resource + " exists", nil
+
+	case c.condition == "healthy" && isArgoCDApplication(c.kind):
+		out, err := get("{.status.health.status}/{.status.sync.status}")
+		if err != nil {
+			return false, "", err
+		}
+		health, sync, _ := strings.Cut(out, "/")
+		return health == "Healthy" && sync == "Synced", fmt.Sprintf("%s is %s and %s", resource, valueOrUnknown(health), valueOrUnknown(sync)), nil
+
+	case c.condition == "ready" && (c.kind == "deployment" || c.kind == "deploy" || c.kind == "statefulset" || c.kind == "sts"):
+		out, err := get("{.status.readyReplicas}/{.spec.replicas}")
+		if err != nil {
+			return false, "", err
+		}
+		return replicasReady(resource, out)
+
+	case c.condition == "ready" && (c.kind == "daemonset" || c.kind == "ds"):
+		out, err := get("{.status.numberReady}/{.status.desiredNumberScheduled}")
+		if err != nil {
+			return false, "", err
+		}
+		return replicasReady(resource, out)
+	}
+
+	// ready and healthy of other resources read their Ready condition
+	condition := "Ready"
+	if strings.HasPrefix(c.condition, "condition=") {
+		condition = strings.TrimPrefix(c.condition, "condition=")
+	}
+	out, err := get(fmt.Sprintf(`{.status.conditions[?(@.type=="%s")].status}`, condition))
+	if err != nil {
+		return false, "", err
+	}
+	return out == "True", fmt.Sprintf("%s condition %s is %s", resource, condition, valueOrUnknown(out)), nil
+}
+
+func replicasReady(resource, out string) (bool, string, error) {
+	readyText, desiredText, _ := strings.Cut(out, "/")
+	ready, _ := strconv.Atoi(readyText)
+	desired, err := strconv.Atoi(desiredText)
+	if err != nil {
+		desired = 1
+	}
+	return ready >= desired, fmt.Sprintf("%s has %d/%d ready replicas", resource, ready, desired), nil
+}
+
+func isArgoCDApplication(kind string) bool {
+	switch kind {
+	case "application", "applications", "app", "applications.argoproj.io":
+		return true
+	}
+	return false
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "Unknown"
+	}
+	return value
+}
+
+// kubectlBinary runs the kubectl binary with a kubeconfig context.
+func kubectlBinary(kubeContext string) Kubectl {
+	return func(ctx context.Context, args ...string) (string, error) {
+		if kubeContext != "" {
+			args = append(args, "--context", kubeContext)
+		}
+		output, err := logging.CombinedOutput(exec.CommandContext(ctx, "kubectl", args...))
+		if err != nil {
+			return "", fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+}
+
+// checksFailed returns the number of failed checks.
+func checksFailed(results []CheckResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Status != CheckPassed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// checkNamespace returns the default namespace of the checks of a pattern:
+// the namespace of its first component that has one, else the namespace its
+// Applications deploy to.
+func checkNamespace(pattern *Pattern) string {
+	for _, comp := range pattern.Spec.Components {
+		if comp.Namespace != "" {
+			return comp.Namespace
+		}
+	}
+	return pattern.Metadata.Name
+}
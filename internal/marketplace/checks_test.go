@@ -0,0 +1,169 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKubectl answers kubectl get with the outputs of resources.
+func fakeKubectl(outputs map[string][]string, calls *[]string) Kubectl {
+	return func(ctx context.Context, args ...string) (string, error) {
+		*calls = append(*calls, strings.Join(args, " "))
+		resource := args[1]
+		values, ok := outputs[resource]
+		if !ok {
+			return "", fmt.Errorf("%s not found", resource)
+		}
+		value := values[0]
+		if len(values) > 1 {
+			outputs[resource] = values[1:]
+		}
+		return value, nil
+	}
+}
+
+func TestParseCheck(t *testing.T) {
+	c, err := parseCheck("deployment/nginx ready -n ingress", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.kind != "deployment" || c.name != "nginx" || c.namespace != "ingress" || c.condition != "ready" {
+		t.Errorf("parseCheck() = %+v", c)
+	}
+	if c, err = parseCheck("certificate/wildcard condition=Issued", "default"); err != nil || c.namespace != "default" {
+		t.Errorf("parseCheck() = %+v, %v", c, err)
+	}
+
+	for _, text := range []string{"nginx ready", "deployment/nginx", "deployment/nginx running", "deployment/ ready", "pod/a condition="} {
+		if _, err := parseCheck(text, ""); err == nil {
+			t.Errorf("parseCheck(%q) should fail", text)
+		}
+	}
+}
+
+func TestRunChecks(t *testing.T) {
+	pattern := NewPattern("ingress", "1.0.0", "Ingress controller")
+	pattern.Spec.Validation = []ValidationCheck{
+		{Name: "controller", Check: "deployment/nginx ready"},
+		{Name: "agents", Check: "daemonset/agent ready -n kube-system"},
+		{Name: "config", Check: "configmap/nginx exists"},
+		{Name: "app", Check: "application/ingress-dev healthy -n argocd"},
+		{Name: "certificate", Check: "certificate/wildcard condition=Issued"},
+		{Name: "missing", Check: "secret/tls exists"},
+		{Name: "invalid", Check: "nginx"},
+	}
+	var calls []string
+	kubectl := fakeKubectl(map[string][]string{
+		"deployment/nginx":        {"2/2"},
+		"daemonset/agent":         {"1/3"},
+		"configmap/nginx":         {"nginx"},
+		"application/ingress-dev": {"Healthy/OutOfSync"},
+		"certificate/wildcard":    {"True"},
+	}, &calls)
+
+	results := RunChecks(context.Background(), pattern, "ingress", CheckOptions{Kubectl: kubectl})
+	want := map[string]string{
+		"controller":  CheckPassed,
+		"agents":      CheckFailed,
+		"config":      CheckPassed,
+		"app":         CheckFailed,
+		"certificate": CheckPassed,
+		"missing":     CheckFailed,
+		"invalid":     CheckFailed,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("RunChecks() = %d results, want %d", len(results), len(want))
+	}
+	for _, r := range results {
+		if r.Status != want[r.Name] {
+			t.Errorf("check %s = %s (%s), want %s", r.Name, r.Status, r.Message, want[r.Name])
+		}
+	}
+	if results[1].Message != "daemonset/agent has 1/3 ready replicas" {
+		t.Errorf("agents message = %q", results[1].Message)
+	}
+	if results[3].Message != "application/ingress-dev is Healthy and OutOfSync" {
+		t.Errorf("app message = %q", results[3].Message)
+	}
+	if !strings.Contains(calls[0], "-n ingress") || !strings.Contains(calls[1], "-n kube-system") {
+		t.Errorf("kubectl calls = %v", calls)
+	}
+	if !strings.Contains(calls[4], `conditions[?(@.type=="Issued")]`) {
+		t.Errorf("condition call = %s", calls[4])
+	}
+}
+
+func TestRunChecksWait(t *testing.T) {
+	interval := checkPollInterval
+	checkPollInterval = time.Millisecond
+	defer func() { checkPollInterval = interval }()
+
+	pattern := NewPattern("ingress", "1.0.0", "Ingress controller")
+	pattern.Spec.Validation = []ValidationCheck{
+		{Name: "controller", Check: "deployment/nginx ready", Timeout: "1s"},
+		{Name: "stuck", Check: "deployment/stuck ready", Timeout: "20ms"},
+	}
+	var calls []string
+	kubectl := fakeKubectl(map[string][]string{
+		"deployment/nginx": {"/2", "1/2", "2/2"},
+		"deployment/stuck": {"0/1"},
+	}, &calls)
+
+	results := RunChecks(context.Background(), pattern, "ingress", CheckOptions{Kubectl: kubectl, Wait: true})
+	if results[0].Status != CheckPassed {
+		t.Errorf("controller = %s (%s), want passed after retries", results[0].Status, results[0].Message)
+	}
+	if results[1].Status != CheckFailed || !strings.HasPrefix(results[1].Message, "timed out after 20ms") {
+		t.Errorf("stuck = %s (%s), want a timeout", results[1].Status, results[1].Message)
+	}
+}
+
+func TestInstallRunsChecks(t *testing.T) {
+	dir := t.TempDir()
+	pattern := templatedPattern()
+	pattern.Spec.Validation = []ValidationCheck{
+		{Name: "controller", Check: "deployment/nginx ready"},
+		{Name: "certificate", Check: "certificate/wildcard condition=Ready"},
+	}
+	addPattern(t, dir, pattern)
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	installer := NewInstaller(rm, t.TempDir(), "argocd", "kubernetes")
+	ctx := context.Background()
+
+	var calls []string
+	outputs := map[string][]string{"deployment/nginx": {"2/2"}, "certificate/wildcard": {"False"}}
+	opts := InstallOptions{Checks: &CheckOptions{Kubectl: fakeKubectl(outputs, &calls)}}
+	result, err := installer.Install(ctx, "ingress", opts)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 validation checks") {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if result == nil || result.Success || len(result.Checks) != 2 {
+		t.Fatalf("Install() result = %+v", result)
+	}
+	if !strings.Contains(calls[0], "-n ingress") {
+		t.Errorf("checks should default to the component namespace: %v", calls)
+	}
+	installed, err := installer.GetInstalled("ingress")
+	if err != nil || installed.Health != "unhealthy" {
+		t.Fatalf("installed health = %v, %v", installed, err)
+	}
+
+	outputs["certificate/wildcard"] = []string{"True"}
+	results, err := installer.CheckInstalled(ctx, "ingress", CheckOptions{Kubectl: fakeKubectl(outputs, &calls)})
+	if err != nil || checksFailed(results) != 0 {
+		t.Fatalf("CheckInstalled() = %+v, %v", results, err)
+	}
+	if installed, _ := installer.GetInstalled("ingress"); installed.Health != "healthy" {
+		t.Errorf("installed health = %s, want healthy", installed.Health)
+	}
+	if _, err := installer.CheckInstalled(ctx, "missing", CheckOptions{}); err == nil {
+		t.Error("CheckInstalled() of a pattern that is not installed should fail")
+	}
+}
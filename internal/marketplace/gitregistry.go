@@ -0,0 +1,160 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+)
+
+// GitCredentials returns the credentials of a Git registry, or nil to clone
+// it anonymously.
+type GitCredentials func(ctx context.Context, reg *Registry) (*gitops.Credentials, error)
+
+// SetGitCredentials sets how the credentials of Git registries are resolved.
+// Registries without resolved credentials use their Auth.
+func (rm *RegistryManager) SetGitCredentials(credentials GitCredentials) {
+	rm.gitCredentials = credentials
+}
+
+// gitDir returns the directory of the clone of a Git registry.
+func (rm *RegistryManager) gitDir(reg *Registry) string {
+	return filepath.Join(rm.cacheDir, "git", reg.Name)
+}
+
+// gitRoot returns the registry directory of a Git registry, cloning it when
+// it is not cached yet. Offline, only cached clones are used.
+func (rm *RegistryManager) gitRoot(ctx context.Context, reg *Registry) (string, error) {
+	dir := rm.gitDir(reg)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if rm.offline {
+			return "", fmt.Errorf("registry '%s' has not been cloned: run gitopsi marketplace refresh while connected", reg.Name)
+		}
+		if err := rm.cloneGit(ctx, reg); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, reg.Path), nil
+}
+
+// Refresh updates the clone of a Git registry to the latest commit of its
+// ref, regenerating its index, and returns the index of the registry.
+func (rm *RegistryManager) Refresh(ctx context.Context, registryName string) (*RegistryIndex, error) {
+	reg, err := rm.GetRegistry(registryName)
+	if err != nil {
+		return nil, err
+	}
+	if reg.Type == RegistryTypeGit {
+		if rm.offline {
+			return nil, fmt.Errorf("cannot refresh registry '%s' in offline mode", registryName)
+		}
+		if err := rm.cloneGit(ctx, reg); err != nil {
+			return nil, err
+		}
+	}
+	return rm.FetchIndex(ctx, registryName)
+}
+
+// cloneGit clones the ref of a Git registry into a fresh directory and
+// replaces the previous clone.
+func (rm *RegistryManager) cloneGit(ctx context.Context, reg *Registry) error {
+	dir := rm.gitDir(reg)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create registry cache: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), reg.Name+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create registry cache: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if _, err := rm.cloneInto(ctx, reg, tmp, 1); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(tmp, reg.Path, "index.yaml")); os.IsNotExist(err) {
+		if err := GenerateIndex(filepath.Join(tmp, reg.Path, "patterns"), filepath.Join(tmp, reg.Path, "index.yaml")); err != nil {
+			return fmt.Errorf("failed to index registry '%s': %w", reg.Name, err)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace registry cache: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to replace registry cache: %w", err)
+	}
+	return nil
+}
+
+// gitCredentialsFor returns the credentials of a Git registry: those of the
+// GitCredentials hook, else its Auth.
+func (rm *RegistryManager) gitCredentialsFor(ctx context.Context, reg *Registry) (*gitops.Credentials, error) {
+	if rm.gitCredentials != nil {
+		creds, err := rm.gitCredentials(ctx, reg)
+		if err != nil || creds != nil {
+			return creds, err
+		}
+	}
+	if reg.Auth == nil {
+		return nil, nil
+	}
+	creds := &gitops.Credentials{Username: reg.Auth.Username, Password: reg.Auth.Password, Token: reg.Auth.Token}
+	if reg.Auth.SSHKey != "" {
+		key, err := os.ReadFile(reg.Auth.SSHKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key of registry '%s': %w", reg.Name, err)
+		}
+		creds.SSHKey = string(key)
+	}
+	return creds, nil
+}
+
+// cloneInto clones the ref of a Git registry into the empty directory dir,
+// with the given depth (0 for the full history), and returns the credentials
+// it was cloned with.
+func (rm *RegistryManager) cloneInto(ctx context.Context, reg *Registry, dir string, depth int) (*gitops.Credentials, error) {
+	creds, err := rm.gitCredentialsFor(ctx, reg)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := gitops.AuthMethod(reg.URL, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	// A ref is a branch or a tag: try the branch first.
+	refs := []plumbing.ReferenceName{""}
+	if reg.Ref != "" {
+		refs = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(reg.Ref), plumbing.NewTagReferenceName(reg.Ref)}
+	}
+	for _, ref := range refs {
+		_, err = git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+			URL:           reg.URL,
+			Auth:          auth,
+			ReferenceName: ref,
+			SingleBranch:  true,
+			Depth:         depth,
+			ProxyOptions:  network.GitProxy(reg.URL),
+		})
+		if !errors.Is(err, git.NoMatchingRefSpecError{}) {
+			break
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		if reg.Ref != "" {
+			return nil, fmt.Errorf("failed to clone registry '%s' at %s: %w", reg.Name, reg.Ref, err)
+		}
+		return nil, fmt.Errorf("failed to clone registry '%s': %w", reg.Name, err)
+	}
+	return creds, nil
+}
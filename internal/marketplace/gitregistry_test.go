@@ -0,0 +1,129 @@
+package marketplace
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// patternRepo creates a Git repository of patterns: monitoring 1.0.0 tagged
+// v1, then monitoring 1.1.0 on the default branch.
+func patternRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, _ := repo.Worktree()
+	sig := &object.Signature{Name: "publisher", Email: "publisher@example.com", When: time.Now()}
+
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		pattern := NewPattern("monitoring", version, "Monitoring stack")
+		if err := pattern.Save(filepath.Join(dir, "registry", "patterns", "monitoring", version, "pattern.yaml")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("."); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := wt.Commit("Add monitoring "+version, &git.CommitOptions{Author: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version == "1.0.0" {
+			if _, err := repo.CreateTag("v1", hash, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestGitRegistry(t *testing.T) {
+	repoDir := patternRepo(t)
+	rm := NewRegistryManager(t.TempDir())
+	for _, reg := range []Registry{
+		{Name: "team", Type: RegistryTypeGit, URL: repoDir, Path: "registry", Enabled: true, Priority: 200},
+		{Name: "pinned", Type: RegistryTypeGit, URL: repoDir, Path: "registry", Ref: "v1", Enabled: true},
+	} {
+		if err := rm.AddRegistry(reg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	index, err := rm.FetchIndex(ctx, "team")
+	if err != nil {
+		t.Fatalf("FetchIndex() error = %v", err)
+	}
+	if len(index.Patterns) != 1 || index.Patterns[0].Latest != "1.1.0" || len(index.Patterns[0].Versions) != 2 {
+		t.Errorf("FetchIndex() = %+v, want one entry for both versions", index.Patterns)
+	}
+	index, err = rm.FetchIndex(ctx, "pinned")
+	if err != nil {
+		t.Fatalf("FetchIndex() of the tag error = %v", err)
+	}
+	if len(index.Patterns) != 1 || index.Patterns[0].Latest != "1.0.0" {
+		t.Errorf("FetchIndex() of the tag = %+v", index.Patterns)
+	}
+
+	pattern, err := rm.FetchPattern(ctx, "team", "monitoring", "1.1.0")
+	if err != nil || pattern.Metadata.Version != "1.1.0" {
+		t.Fatalf("FetchPattern() = %v, %v", pattern, err)
+	}
+
+	// Cached clones are used offline, but cannot be refreshed.
+	rm.SetOffline(true)
+	if _, err := rm.FetchPattern(ctx, "pinned", "monitoring", "1.0.0"); err != nil {
+		t.Errorf("FetchPattern() offline error = %v", err)
+	}
+	if _, err := rm.Refresh(ctx, "team"); err == nil {
+		t.Error("Refresh() should fail offline")
+	}
+	rm.SetOffline(false)
+	if _, err := rm.Refresh(ctx, "team"); err != nil {
+		t.Errorf("Refresh() error = %v", err)
+	}
+
+	if err := rm.AddRegistry(Registry{Name: "bad-ref", Type: RegistryTypeGit, URL: repoDir, Ref: "v9", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.Refresh(ctx, "bad-ref"); err == nil || !strings.Contains(err.Error(), "at v9") {
+		t.Errorf("Refresh() of a missing ref error = %v", err)
+	}
+}
+
+func TestSaveRegistries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registries.yaml")
+	rm := NewRegistryManager(t.TempDir())
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeGit, URL: "https://git.example.com/patterns.git", Ref: "main", Credential: "team-git", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.SaveRegistries(path); err != nil {
+		t.Fatalf("SaveRegistries() error = %v", err)
+	}
+
+	loaded := NewRegistryManager(t.TempDir())
+	if err := loaded.LoadRegistries(path); err != nil {
+		t.Fatalf("LoadRegistries() error = %v", err)
+	}
+	registries := loaded.ListRegistries()
+	if len(registries) != 2 || registries[1].Name != "team" || registries[1].Ref != "main" || registries[1].Credential != "team-git" {
+		t.Errorf("LoadRegistries() = %+v", registries)
+	}
+	if err := loaded.LoadRegistries(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Errorf("LoadRegistries() of a missing file error = %v", err)
+	}
+
+	if err := rm.AddRegistry(Registry{Name: "web", Type: RegistryTypePrivate, URL: "https://patterns.example.com", Ref: "main"}); err == nil {
+		t.Error("AddRegistry() should reject a ref on a non-git registry")
+	}
+	if err := rm.AddRegistry(Registry{Name: "../escape", Type: RegistryTypeGit, URL: "https://git.example.com/x.git"}); err == nil {
+		t.Error("AddRegistry() should reject names that are not a directory name")
+	}
+}
@@ -0,0 +1,121 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// helmRendering is how the Helm components of a pattern are deployed.
+type helmRendering string
+
+const (
+	// helmFlux generates Flux HelmRepository and HelmRelease resources.
+	helmFlux helmRendering = "flux"
+	// helmSource adds the charts as Helm sources of the ArgoCD Applications.
+	helmSource helmRendering = "source"
+	// helmVendor commits the manifests rendered by helm template.
+	helmVendor helmRendering = "vendor"
+)
+
+// helmRendering returns how the installer deploys Helm components: as Helm
+// sources of multi-source Applications with ArgoCD, as Flux resources
+// otherwise, and as rendered manifests when vendoring.
+func (i *Installer) helmRendering(vendor bool) helmRendering {
+	switch {
+	case vendor:
+		return helmVendor
+	case i.gitOpsTool == "argocd":
+		return helmSource
+	default:
+		return helmFlux
+	}
+}
+
+// helmTemplate renders a chart with helm template.
+var helmTemplate = func(ctx context.Context, comp *Component, values map[string]any) ([]byte, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	valuesFile, err := os.CreateTemp("", "gitopsi-values-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(valuesFile.Name())
+	if _, err := valuesFile.Write(data); err != nil {
+		valuesFile.Close()
+		return nil, err
+	}
+	if err := valuesFile.Close(); err != nil {
+		return nil, err
+	}
+
+	args := []string{"template", comp.Name}
+	if strings.HasPrefix(comp.Repository, "oci://") {
+		args = append(args, strings.TrimSuffix(comp.Repository, "/")+"/"+comp.Chart)
+	} else {
+		args = append(args, comp.Chart, "--repo", comp.Repository)
+	}
+	if comp.Version != "" {
+		args = append(args, "--version", comp.Version)
+	}
+	if comp.Namespace != "" {
+		args = append(args, "--namespace", comp.Namespace)
+	}
+	args = append(args, "--values", valuesFile.Name())
+
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, "helm", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("helm template %s: %w: %s", comp.Chart, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// vendorChart renders the chart of a Helm component into a manifest file.
+func vendorChart(ctx context.Context, comp *Component, values map[string]any) ([]byte, error) {
+	output, err := helmTemplate(ctx, comp, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart '%s': %w", comp.Chart, err)
+	}
+	header := fmt.Sprintf("# Rendered from chart %s %s of %s by gitopsi; do not edit.\n", comp.Chart, comp.Version, comp.Repository)
+	return append([]byte(header), output...), nil
+}
+
+// helmSources returns the ArgoCD Application sources of the Helm components.
+func helmSources(components []*Component, config map[string]any) []any {
+	var sources []any
+	for _, comp := range components {
+		if comp.Type != ComponentTypeHelm {
+			continue
+		}
+		targetRevision := comp.Version
+		if targetRevision == "" {
+			targetRevision = "*"
+		}
+		sources = append(sources, map[string]any{
+			// ArgoCD takes OCI registries without their scheme
+			"repoURL":        strings.TrimPrefix(comp.Repository, "oci://"),
+			"chart":          comp.Chart,
+			"targetRevision": targetRevision,
+			"helm": map[string]any{
+				"releaseName":  comp.Name,
+				"valuesObject": mergeValues(comp.Values, config),
+			},
+		})
+	}
+	return sources
+}
+
+// chartPath returns the path of the vendored manifests of a Helm component.
+func chartPath(dir string, comp *Component) string {
+	return filepath.Join(dir, comp.Name+"-chart.yaml")
+}
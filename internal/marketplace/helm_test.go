@@ -0,0 +1,146 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func helmInstaller(t *testing.T, gitOpsTool string) (*Installer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	addPattern(t, dir, templatedPattern())
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	return NewInstaller(rm, project, gitOpsTool, "kubernetes"), project
+}
+
+func TestInstallHelmArgoCDSources(t *testing.T) {
+	installer, project := helmInstaller(t, "argocd")
+	opts := InstallOptions{
+		Environments: []string{"dev", "prod"},
+		EnvConfig:    map[string]map[string]any{"prod": {"replicas": 5}},
+	}
+	result, err := installer.Install(context.Background(), "ingress", opts)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if !strings.Contains(strings.Join(result.Warnings, "\n"), "patches of Helm component 'nginx'") {
+		t.Errorf("Install() warnings = %v", result.Warnings)
+	}
+
+	base := filepath.Join(project, "infrastructure", "networking", "ingress", "base")
+	if _, err := os.Stat(filepath.Join(base, "nginx-release.yaml")); !os.IsNotExist(err) {
+		t.Errorf("no HelmRelease should be generated for ArgoCD: %v", err)
+	}
+	kustomization, _ := os.ReadFile(filepath.Join(base, "kustomization.yaml"))
+	if strings.Contains(string(kustomization), "nginx") {
+		t.Errorf("base kustomization = %s", kustomization)
+	}
+
+	var app struct {
+		Spec struct {
+			Source  map[string]any `yaml:"source"`
+			Sources []struct {
+				RepoURL        string `yaml:"repoURL"`
+				Chart          string `yaml:"chart"`
+				Path           string `yaml:"path"`
+				TargetRevision string `yaml:"targetRevision"`
+				Helm           struct {
+					ReleaseName  string         `yaml:"releaseName"`
+					ValuesObject map[string]any `yaml:"valuesObject"`
+				} `yaml:"helm"`
+			} `yaml:"sources"`
+		} `yaml:"spec"`
+	}
+	for env, replicas := range map[string]int{"dev": 2, "prod": 5} {
+		data, err := os.ReadFile(filepath.Join(project, "argocd", "applications", "ingress-"+env+".yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := yaml.Unmarshal(data, &app); err != nil {
+			t.Fatal(err)
+		}
+		if app.Spec.Source != nil || len(app.Spec.Sources) != 2 {
+			t.Fatalf("%s Application spec = %s", env, data)
+		}
+		if app.Spec.Sources[0].Path != "infrastructure/networking/ingress/overlays/"+env {
+			t.Errorf("%s overlay source = %+v", env, app.Spec.Sources[0])
+		}
+		chart := app.Spec.Sources[1]
+		if chart.RepoURL != "https://kubernetes.github.io/ingress-nginx" || chart.Chart != "ingress-nginx" || chart.TargetRevision != "*" || chart.Helm.ReleaseName != "nginx" {
+			t.Errorf("%s chart source = %+v", env, chart)
+		}
+		if chart.Helm.ValuesObject["replicaCount"] != replicas {
+			t.Errorf("%s chart values = %v, want replicaCount %d", env, chart.Helm.ValuesObject, replicas)
+		}
+		app.Spec.Source, app.Spec.Sources = nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(project, "infrastructure", "networking", "ingress", "overlays", "prod", "nginx-release-patch.yaml")); !os.IsNotExist(err) {
+		t.Errorf("no HelmRelease patch should be generated for ArgoCD: %v", err)
+	}
+}
+
+func TestInstallHelmVendor(t *testing.T) {
+	render := helmTemplate
+	defer func() { helmTemplate = render }()
+	helmTemplate = func(ctx context.Context, comp *Component, values map[string]any) ([]byte, error) {
+		return []byte(fmt.Sprintf("kind: Deployment\nmetadata:\n  name: %s\nspec:\n  replicas: %v\n", comp.Name, values["replicaCount"])), nil
+	}
+
+	installer, project := helmInstaller(t, "argocd")
+	ctx := context.Background()
+	opts := InstallOptions{
+		Environments: []string{"dev", "prod"},
+		EnvConfig:    map[string]map[string]any{"prod": {"replicas": 5}},
+		VendorHelm:   true,
+	}
+	if _, err := installer.Install(ctx, "ingress", opts); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	dir := filepath.Join(project, "infrastructure", "networking", "ingress")
+	chart, err := os.ReadFile(filepath.Join(dir, "base", "nginx-chart.yaml"))
+	if err != nil || !strings.Contains(string(chart), "replicas: 2") || !strings.HasPrefix(string(chart), "# Rendered from chart ingress-nginx") {
+		t.Errorf("vendored chart = %s, %v", chart, err)
+	}
+	kustomization, _ := os.ReadFile(filepath.Join(dir, "base", "kustomization.yaml"))
+	for _, want := range []string{"- nginx-chart.yaml", "value: example-com"} {
+		if !strings.Contains(string(kustomization), want) {
+			t.Errorf("base kustomization = %s, want %q", kustomization, want)
+		}
+	}
+	patch, err := os.ReadFile(filepath.Join(dir, "overlays", "prod", "nginx-chart-patch.yaml"))
+	if err != nil || !strings.Contains(string(patch), "replicas: 5") {
+		t.Errorf("prod chart patch = %s, %v", patch, err)
+	}
+	app, _ := os.ReadFile(filepath.Join(project, "argocd", "applications", "ingress-prod.yaml"))
+	if strings.Contains(string(app), "sources:") {
+		t.Errorf("vendored charts should not be Application sources: %s", app)
+	}
+
+	// Updates render the charts again.
+	if _, err := installer.Update(ctx, "ingress", UpdateOptions{Force: true}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if installed, _ := installer.GetInstalled("ingress"); !installed.VendorHelm {
+		t.Error("Update() should keep the charts vendored")
+	}
+
+	helmTemplate = func(ctx context.Context, comp *Component, values map[string]any) ([]byte, error) {
+		return nil, fmt.Errorf("chart not found")
+	}
+	opts.Force = true
+	if _, err := installer.Install(ctx, "ingress", opts); err == nil || !strings.Contains(err.Error(), "chart not found") {
+		t.Errorf("Install() with a failing helm template error = %v", err)
+	}
+}
@@ -9,7 +9,12 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/syncwave"
+	"github.com/ihsanmokhlisse/gitopsi/internal/telemetry"
 )
 
 // InstallOptions defines options for pattern installation.
@@ -17,32 +22,51 @@ type InstallOptions struct {
 	Version      string
 	Config       map[string]any
 	Environments []string
-	DryRun       bool
-	Force        bool
-	SkipDeps     bool
-	AutoApprove  bool
+	// EnvConfig overrides Config in the overlay of each environment.
+	EnvConfig   map[string]map[string]any
+	DryRun      bool
+	Force       bool
+	SkipDeps    bool
+	AutoApprove bool
+	// InsecureSkipVerify installs patterns without verifying their signature.
+	InsecureSkipVerify bool
+	// Checks runs the validation checks of the pattern against the cluster
+	// once it is installed, when set.
+	Checks *CheckOptions
+	// VendorHelm commits the manifests rendered by helm template instead of
+	// HelmReleases or ArgoCD Helm sources.
+	VendorHelm bool
+
+	// upgrade ignores the locked version of the pattern.
+	upgrade bool
 }
 
 // InstallResult represents the result of a pattern installation.
 type InstallResult struct {
-	Pattern       string
-	Version       string
-	Success       bool
-	Message       string
-	GeneratedPath []string
-	AccessInfo    map[string]string
-	Dependencies  []DependencyResult
-	Errors        []string
-	Warnings      []string
+	Pattern       string             `yaml:"pattern" json:"pattern"`
+	Version       string             `yaml:"version" json:"version"`
+	Success       bool               `yaml:"success" json:"success"`
+	Message       string             `yaml:"message" json:"message"`
+	GeneratedPath []string           `yaml:"generatedPaths,omitempty" json:"generatedPaths,omitempty"`
+	AccessInfo    map[string]string  `yaml:"accessInfo,omitempty" json:"accessInfo,omitempty"`
+	Dependencies  []DependencyResult `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	Errors        []string           `yaml:"errors,omitempty" json:"errors,omitempty"`
+	Warnings      []string           `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+	// Signer describes the verified signer of the pattern.
+	Signer string `yaml:"signer,omitempty" json:"signer,omitempty"`
+	// Checks holds the results of the validation checks of the pattern.
+	Checks []CheckResult `yaml:"checks,omitempty" json:"checks,omitempty"`
 }
 
 // DependencyResult represents the result of installing a dependency.
 type DependencyResult struct {
-	Name     string
-	Version  string
-	Status   string // installed, skipped, failed
-	Optional bool
-	Message  string
+	Name    string `yaml:"name" json:"name"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	// Constraint is the version constraint of the patterns requiring it.
+	Constraint string `yaml:"constraint,omitempty" json:"constraint,omitempty"`
+	Status     string `yaml:"status" json:"status"` // installed, skipped, failed
+	Optional   bool   `yaml:"optional,omitempty" json:"optional,omitempty"`
+	Message    string `yaml:"message,omitempty" json:"message,omitempty"`
 }
 
 // Installer handles pattern installation.
@@ -50,9 +74,13 @@ type Installer struct {
 	registry    *RegistryManager
 	projectPath string
 	stateFile   string
+	lockFile    string
 	gitOpsTool  string
 	platform    string
 	installed   map[string]*InstalledPattern
+	// transform rewrites the generated files, such as with the conventions
+	// of the project.
+	transform func(path string, content []byte) ([]byte, error)
 }
 
 // NewInstaller creates a new pattern installer.
@@ -61,12 +89,19 @@ func NewInstaller(registry *RegistryManager, projectPath, gitOpsTool, platform s
 		registry:    registry,
 		projectPath: projectPath,
 		stateFile:   filepath.Join(projectPath, ".gitopsi", "patterns.yaml"),
+		lockFile:    filepath.Join(projectPath, LockFile),
 		gitOpsTool:  gitOpsTool,
 		platform:    platform,
 		installed:   make(map[string]*InstalledPattern),
 	}
 }
 
+// SetTransform sets the function rewriting every file generated from a
+// pattern, applied once the pattern is generated.
+func (i *Installer) SetTransform(transform func(path string, content []byte) ([]byte, error)) {
+	i.transform = transform
+}
+
 // LoadState loads the installed patterns state.
 func (i *Installer) LoadState() error {
 	data, err := os.ReadFile(i.stateFile)
@@ -113,8 +148,20 @@ func (i *Installer) SaveState() error {
 	return os.WriteFile(i.stateFile, data, 0644)
 }
 
-// Install installs a pattern.
+// Install installs a pattern and the dependencies it needs. Versions are
+// resolved against the constraints of the pattern, its dependencies and the
+// installed patterns, and recorded in the lockfile.
 func (i *Installer) Install(ctx context.Context, patternName string, opts InstallOptions) (*InstallResult, error) {
+	ctx, end := telemetry.Start(ctx, "install",
+		attribute.String("pattern", patternName),
+		attribute.Bool("dry_run", opts.DryRun),
+	)
+	result, err := i.install(ctx, patternName, opts)
+	end(err)
+	return result, err
+}
+
+func (i *Installer) install(ctx context.Context, patternName string, opts InstallOptions) (*InstallResult, error) {
 	result := &InstallResult{
 		Pattern:      patternName,
 		AccessInfo:   make(map[string]string),
@@ -133,26 +180,57 @@ func (i *Installer) Install(ctx context.Context, patternName string, opts Instal
 		return result, nil
 	}
 
-	// Find and fetch the pattern
-	entry, registryName, err := i.registry.FindPattern(ctx, patternName)
+	// Resolve the versions of the pattern and its dependencies; signatures
+	// are verified before anything is generated from them
+	plan, err := i.Resolve(ctx, patternName, opts.Version, ResolveOptions{
+		SkipDeps:           opts.SkipDeps,
+		Upgrade:            opts.upgrade,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	})
 	if err != nil {
 		result.Success = false
 		result.Errors = append(result.Errors, err.Error())
 		return result, err
 	}
+	root := plan[len(plan)-1]
+	result.Version = root.Version
+	result.Signer = root.signer
+	if opts.InsecureSkipVerify {
+		result.Warnings = append(result.Warnings, "Signature verification skipped (--insecure-skip-verify)")
+	}
 
-	version := opts.Version
-	if version == "" {
-		version = entry.Latest
+	// Install dependencies first
+	for _, dep := range plan[:len(plan)-1] {
+		depResult := i.installDependency(ctx, &dep, opts)
+		result.Dependencies = append(result.Dependencies, depResult)
+		if depResult.Status == "failed" && !dep.Optional {
+			result.Success = false
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("required dependency '%s' failed to install", dep.Name))
+			return result, fmt.Errorf("dependency installation failed")
+		}
 	}
-	result.Version = version
 
-	pattern, err := i.registry.FetchPattern(ctx, registryName, patternName, version)
-	if err != nil {
-		result.Success = false
-		result.Errors = append(result.Errors, fmt.Sprintf("failed to fetch pattern: %v", err))
+	if err := i.installResolved(ctx, &root, opts, result); err != nil {
 		return result, err
 	}
+	return result, nil
+}
+
+// installResolved generates a resolved pattern and records it in the state
+// and the lockfile.
+func (i *Installer) installResolved(ctx context.Context, resolved *ResolvedPattern, opts InstallOptions, result *InstallResult) error {
+	ctx, end := telemetry.Start(ctx, "install.pattern",
+		attribute.String("pattern", resolved.Name),
+		attribute.String("version", resolved.Version),
+	)
+	err := i.generateResolved(ctx, resolved, opts, result)
+	end(err)
+	return err
+}
+
+func (i *Installer) generateResolved(ctx context.Context, resolved *ResolvedPattern, opts InstallOptions, result *InstallResult) error {
+	pattern := resolved.pattern
 
 	// Check compatibility
 	if !pattern.IsCompatibleWithPlatform(i.platform) {
@@ -164,20 +242,6 @@ func (i *Installer) Install(ctx context.Context, patternName string, opts Instal
 			fmt.Sprintf("Pattern may not be fully compatible with GitOps tool '%s'", i.gitOpsTool))
 	}
 
-	// Install dependencies first
-	if !opts.SkipDeps {
-		for _, dep := range pattern.Spec.Dependencies {
-			depResult := i.installDependency(ctx, dep, opts)
-			result.Dependencies = append(result.Dependencies, depResult)
-			if depResult.Status == "failed" && !dep.Optional {
-				result.Success = false
-				result.Errors = append(result.Errors,
-					fmt.Sprintf("required dependency '%s' failed to install", dep.Name))
-				return result, fmt.Errorf("dependency installation failed")
-			}
-		}
-	}
-
 	// Merge config with defaults
 	config := pattern.MergeConfigWithDefaults(opts.Config)
 
@@ -185,32 +249,72 @@ func (i *Installer) Install(ctx context.Context, patternName string, opts Instal
 	if configErr := pattern.ValidateConfig(config); configErr != nil {
 		result.Success = false
 		result.Errors = append(result.Errors, fmt.Sprintf("config validation failed: %v", configErr))
-		return result, configErr
+		return configErr
 	}
 
 	// Determine target environments
 	environments := opts.Environments
+	if len(environments) == 0 {
+		environments = sortedKeys(opts.EnvConfig)
+	}
 	if len(environments) == 0 {
 		environments = []string{"dev"} // Default
 	}
 
+	// Merge and validate the config of each environment
+	envConfigs, envErr := mergeEnvConfig(pattern, config, opts.EnvConfig, environments)
+	if envErr != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, fmt.Sprintf("config validation failed: %v", envErr))
+		return envErr
+	}
+
+	// Check the templates of the components render with the config
+	for _, c := range append([]map[string]any{config}, mapValues(envConfigs)...) {
+		if _, renderErr := renderComponents(pattern, c); renderErr != nil {
+			result.Success = false
+			result.Errors = append(result.Errors, renderErr.Error())
+			return renderErr
+		}
+	}
+
+	for _, comp := range pattern.Spec.Components {
+		if i.resolveOperator(&comp).Type == ComponentTypeOperator && !i.hasOLM() {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("operator component '%s' subscribes to OperatorHub, which requires OLM on platform '%s'", comp.Name, i.platform))
+		}
+	}
+
+	helm := i.helmRendering(opts.VendorHelm)
+	if helm == helmSource {
+		for _, comp := range pattern.Spec.Components {
+			if comp.Type == ComponentTypeHelm && len(comp.Patches) > 0 {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("patches of Helm component '%s' are not applied to ArgoCD Helm sources; use --vendor-helm to apply them", comp.Name))
+			}
+		}
+	}
+
 	// Dry run check
 	if opts.DryRun {
 		result.Message = "Dry run - no changes made"
-		paths, planErr := i.planGeneration(pattern, config, environments)
+		paths, planErr := i.planGeneration(pattern, config, environments, helm)
 		if planErr != nil {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("planning error: %v", planErr))
 		}
 		result.GeneratedPath = paths
-		return result, nil
+		return nil
 	}
 
 	// Generate pattern files
-	generatedPaths, err := i.generatePattern(pattern, config, environments)
+	generatedPaths, err := i.generatePattern(ctx, pattern, config, environments, envConfigs, helm)
+	if err == nil {
+		err = i.transformFiles(generatedPaths)
+	}
 	if err != nil {
 		result.Success = false
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to generate pattern: %v", err))
-		return result, err
+		return err
 	}
 	result.GeneratedPath = generatedPaths
 
@@ -219,29 +323,85 @@ func (i *Installer) Install(ctx context.Context, patternName string, opts Instal
 		Pattern:      *pattern,
 		InstalledAt:  time.Now(),
 		Config:       config,
+		EnvConfig:    opts.EnvConfig,
 		Environments: environments,
+		VendorHelm:   opts.VendorHelm,
 		Status:       "installed",
 		Paths:        generatedPaths,
 	}
-	i.installed[patternName] = installedPattern
+	i.installed[resolved.Name] = installedPattern
 
 	// Save state
 	if err := i.SaveState(); err != nil {
 		result.Warnings = append(result.Warnings, fmt.Sprintf("failed to save state: %v", err))
 	}
+	if err := i.lockPattern(resolved); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("failed to update lockfile: %v", err))
+	}
 
 	result.Success = true
-	result.Message = fmt.Sprintf("Pattern '%s' version %s installed successfully", patternName, version)
+	result.Message = fmt.Sprintf("Pattern '%s' version %s installed successfully", resolved.Name, resolved.Version)
+
+	// Validate the installation against the cluster
+	if opts.Checks != nil && len(pattern.Spec.Validation) > 0 {
+		result.Checks = RunChecks(ctx, pattern, checkNamespace(pattern), *opts.Checks)
+		installedPattern.Health = checksHealth(result.Checks)
+		if err := i.SaveState(); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("failed to save state: %v", err))
+		}
+		if failed := checksFailed(result.Checks); failed > 0 {
+			result.Success = false
+			result.Message = fmt.Sprintf("Pattern '%s' version %s installed, %d of %d validation checks failed",
+				resolved.Name, resolved.Version, failed, len(result.Checks))
+			result.Errors = append(result.Errors, result.Message)
+			return fmt.Errorf("%d of %d validation checks of '%s' failed", failed, len(result.Checks), resolved.Name)
+		}
+	}
 
-	return result, nil
+	return nil
+}
+
+// CheckInstalled runs the validation checks of an installed pattern against
+// the cluster and records its health.
+func (i *Installer) CheckInstalled(ctx context.Context, name string, opts CheckOptions) ([]CheckResult, error) {
+	if err := i.LoadState(); err != nil {
+		return nil, err
+	}
+	installed, ok := i.installed[name]
+	if !ok {
+		return nil, fmt.Errorf("pattern '%s' is not installed", name)
+	}
+	results := RunChecks(ctx, &installed.Pattern, checkNamespace(&installed.Pattern), opts)
+	if len(results) > 0 {
+		installed.Health = checksHealth(results)
+		if err := i.SaveState(); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
 }
 
-// installDependency installs a single dependency.
-func (i *Installer) installDependency(ctx context.Context, dep Dependency, opts InstallOptions) DependencyResult {
+// checksHealth returns the health of a pattern with the given check results.
+func checksHealth(results []CheckResult) string {
+	if checksFailed(results) > 0 {
+		return "unhealthy"
+	}
+	return "healthy"
+}
+
+// installDependency installs a single resolved dependency.
+func (i *Installer) installDependency(ctx context.Context, dep *ResolvedPattern, opts InstallOptions) DependencyResult {
 	result := DependencyResult{
-		Name:     dep.Name,
-		Version:  dep.Version,
-		Optional: dep.Optional,
+		Name:       dep.Name,
+		Version:    dep.Version,
+		Constraint: dep.Constraint(),
+		Optional:   dep.Optional,
+	}
+
+	if dep.Missing != nil {
+		result.Status = "failed"
+		result.Message = dep.Missing.Error()
+		return result
 	}
 
 	// Check if already installed
@@ -251,41 +411,68 @@ func (i *Installer) installDependency(ctx context.Context, dep Dependency, opts
 		return result
 	}
 
-	// Try to install
 	depOpts := InstallOptions{
-		Version:     dep.Version,
 		Config:      make(map[string]any),
 		DryRun:      opts.DryRun,
 		AutoApprove: opts.AutoApprove,
-		SkipDeps:    false, // Install transitive deps
 	}
-
-	installResult, err := i.Install(ctx, dep.Name, depOpts)
-	if err != nil {
+	if err := i.installResolved(ctx, dep, depOpts, &InstallResult{Pattern: dep.Name}); err != nil {
 		result.Status = "failed"
 		result.Message = err.Error()
 		return result
 	}
 
 	result.Status = "installed"
-	result.Version = installResult.Version
 	result.Message = "installed successfully"
 	return result
 }
 
+// lockPattern records an installed pattern version in the lockfile.
+func (i *Installer) lockPattern(resolved *ResolvedPattern) error {
+	lock, err := LoadLock(i.lockFile)
+	if err != nil {
+		return err
+	}
+	locked := LockedPattern{
+		Version:  resolved.Version,
+		Registry: resolved.Registry,
+		Digest:   resolved.digest,
+	}
+	for _, dep := range resolved.pattern.Spec.Dependencies {
+		if locked.Dependencies == nil {
+			locked.Dependencies = map[string]string{}
+		}
+		constraint := dep.Version
+		if unconstrained(constraint) {
+			constraint = "*"
+		}
+		locked.Dependencies[dep.Name] = constraint
+	}
+	lock.Patterns[resolved.Name] = locked
+	return lock.Save(i.lockFile)
+}
+
 // planGeneration returns the paths that would be generated.
-func (i *Installer) planGeneration(pattern *Pattern, config map[string]any, environments []string) ([]string, error) {
+func (i *Installer) planGeneration(pattern *Pattern, config map[string]any, environments []string, helm helmRendering) ([]string, error) {
 	var paths []string
 
 	basePath := filepath.Join(i.projectPath, "infrastructure", pattern.Metadata.Category, pattern.Metadata.Name)
 
-	for _, comp := range pattern.Spec.Components {
+	for _, c := range pattern.Spec.Components {
+		comp := i.resolveOperator(&c)
 		switch comp.Type {
 		case ComponentTypeHelm:
-			paths = append(paths,
-				filepath.Join(basePath, "base", "helm-release.yaml"),
-				filepath.Join(basePath, "base", "values.yaml"),
-			)
+			switch helm {
+			case helmFlux:
+				paths = append(paths,
+					filepath.Join(basePath, "base", comp.Name+"-repo.yaml"),
+					filepath.Join(basePath, "base", comp.Name+"-release.yaml"),
+				)
+			case helmVendor:
+				paths = append(paths, chartPath(filepath.Join(basePath, "base"), comp))
+			}
+		case ComponentTypeOperator:
+			paths = append(paths, operatorPath(filepath.Join(basePath, "base"), comp))
 		case ComponentTypeKustomize:
 			paths = append(paths, filepath.Join(basePath, "base", "kustomization.yaml"))
 		case ComponentTypeManifest:
@@ -306,7 +493,8 @@ func (i *Installer) planGeneration(pattern *Pattern, config map[string]any, envi
 }
 
 // generatePattern generates the pattern files.
-func (i *Installer) generatePattern(pattern *Pattern, config map[string]any, environments []string) ([]string, error) {
+// envConfigs holds the merged config of the environments with overrides.
+func (i *Installer) generatePattern(ctx context.Context, pattern *Pattern, config map[string]any, environments []string, envConfigs map[string]map[string]any, helm helmRendering) ([]string, error) {
 	var generatedPaths []string
 
 	basePath := filepath.Join(i.projectPath, "infrastructure", pattern.Metadata.Category, pattern.Metadata.Name)
@@ -317,18 +505,24 @@ func (i *Installer) generatePattern(pattern *Pattern, config map[string]any, env
 		return nil, fmt.Errorf("failed to create base directory: %w", err)
 	}
 
+	// Render the templates of the components with the config
+	components, err := i.resolveComponents(pattern, config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate component files
-	for idx := range pattern.Spec.Components {
-		paths, err := i.generateComponent(baseDir, pattern, &pattern.Spec.Components[idx], config)
+	for _, comp := range components {
+		paths, err := i.generateComponent(ctx, baseDir, pattern, comp, config, helm)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate component '%s': %w", pattern.Spec.Components[idx].Name, err)
+			return nil, fmt.Errorf("failed to generate component '%s': %w", comp.Name, err)
 		}
 		generatedPaths = append(generatedPaths, paths...)
 	}
 
 	// Generate base kustomization
 	kustomizePath := filepath.Join(baseDir, "kustomization.yaml")
-	if err := i.generateBaseKustomization(kustomizePath, pattern); err != nil {
+	if err := i.generateBaseKustomization(kustomizePath, components, helm); err != nil {
 		return nil, err
 	}
 	generatedPaths = append(generatedPaths, kustomizePath)
@@ -340,15 +534,26 @@ func (i *Installer) generatePattern(pattern *Pattern, config map[string]any, env
 			return nil, fmt.Errorf("failed to create overlay directory: %w", err)
 		}
 
+		// Patch the base with the environment's values
+		var patches []any
+		if envConfig, ok := envConfigs[env]; ok {
+			patchPaths, overlayPatches, err := i.generateOverlayPatches(ctx, overlayDir, pattern, components, config, envConfig, helm)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate overlay %s: %w", env, err)
+			}
+			generatedPaths = append(generatedPaths, patchPaths...)
+			patches = overlayPatches
+		}
+
 		overlayPath := filepath.Join(overlayDir, "kustomization.yaml")
-		if err := i.generateOverlayKustomization(overlayPath, env, config); err != nil {
+		if err := i.generateOverlayKustomization(overlayPath, env, patches); err != nil {
 			return nil, err
 		}
 		generatedPaths = append(generatedPaths, overlayPath)
 	}
 
 	// Generate ArgoCD application
-	argoCDPaths, err := i.generateArgoCDApplication(pattern, config, environments)
+	argoCDPaths, err := i.generateArgoCDApplication(pattern, components, config, environments, envConfigs, helm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate ArgoCD application: %w", err)
 	}
@@ -357,12 +562,47 @@ func (i *Installer) generatePattern(pattern *Pattern, config map[string]any, env
 	return generatedPaths, nil
 }
 
+// transformFiles applies the transform of the installer to generated files.
+func (i *Installer) transformFiles(paths []string) error {
+	if i.transform == nil {
+		return nil
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		transformed, err := i.transform(path, data)
+		if err != nil {
+			return fmt.Errorf("failed to transform %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, transformed, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // generateComponent generates files for a single component.
-func (i *Installer) generateComponent(baseDir string, pattern *Pattern, comp *Component, config map[string]any) ([]string, error) {
+func (i *Installer) generateComponent(ctx context.Context, baseDir string, pattern *Pattern, comp *Component, config map[string]any, helm helmRendering) ([]string, error) {
 	var paths []string
 
-	switch comp.Type {
-	case ComponentTypeHelm:
+	switch {
+	case comp.Type == ComponentTypeHelm && helm == helmSource:
+		// The chart is a source of the ArgoCD Applications
+
+	case comp.Type == ComponentTypeHelm && helm == helmVendor:
+		data, err := vendorChart(ctx, comp, mergeValues(comp.Values, config))
+		if err != nil {
+			return nil, err
+		}
+		path := chartPath(baseDir, comp)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+	case comp.Type == ComponentTypeHelm:
 		// Generate HelmRelease
 		helmRelease := map[string]any{
 			"apiVersion": "source.toolkit.fluxcd.io/v1beta2",
@@ -421,7 +661,7 @@ func (i *Installer) generateComponent(baseDir string, pattern *Pattern, comp *Co
 		}
 		paths = append(paths, releasePath)
 
-	case ComponentTypeKustomize:
+	case comp.Type == ComponentTypeKustomize:
 		// Generate kustomization reference
 		kustomization := map[string]any{
 			"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
@@ -435,6 +675,9 @@ func (i *Installer) generateComponent(baseDir string, pattern *Pattern, comp *Co
 				"prune":    true,
 			},
 		}
+		if len(comp.Patches) > 0 {
+			kustomization["spec"].(map[string]any)["patches"] = comp.Patches
+		}
 
 		data, err := yaml.Marshal(kustomization)
 		if err != nil {
@@ -447,11 +690,20 @@ func (i *Installer) generateComponent(baseDir string, pattern *Pattern, comp *Co
 		}
 		paths = append(paths, kustomizePath)
 
-	case ComponentTypeManifest:
+	case comp.Type == ComponentTypeOperator:
+		path, err := i.writeOperator(baseDir, comp)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+	case comp.Type == ComponentTypeManifest:
 		// Copy or generate manifest
 		manifestPath := filepath.Join(baseDir, comp.Name+".yaml")
-		// For now, generate a placeholder
-		manifest := fmt.Sprintf("# Manifest for %s\n# TODO: Add actual manifest content\n", comp.Name)
+		manifest := comp.Manifest
+		if manifest == "" {
+			manifest = fmt.Sprintf("# Manifest for %s\n# TODO: Add actual manifest content\n", comp.Name)
+		}
 		if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
 			return nil, err
 		}
@@ -462,16 +714,27 @@ func (i *Installer) generateComponent(baseDir string, pattern *Pattern, comp *Co
 }
 
 // generateBaseKustomization generates the base kustomization.yaml.
-func (i *Installer) generateBaseKustomization(path string, pattern *Pattern) error {
+func (i *Installer) generateBaseKustomization(path string, components []*Component, helm helmRendering) error {
 	var resources []string
-	for _, comp := range pattern.Spec.Components {
-		switch comp.Type {
-		case ComponentTypeHelm:
+	var patches []Patch
+	for _, comp := range components {
+		// Patches of kustomize components are applied by their Kustomization,
+		// and ArgoCD Helm sources are not rendered by kustomize
+		if comp.Type != ComponentTypeKustomize && (comp.Type != ComponentTypeHelm || helm != helmSource) {
+			patches = append(patches, comp.Patches...)
+		}
+		switch {
+		case comp.Type == ComponentTypeHelm && helm == helmSource:
+		case comp.Type == ComponentTypeHelm && helm == helmVendor:
+			resources = append(resources, comp.Name+"-chart.yaml")
+		case comp.Type == ComponentTypeHelm:
 			resources = append(resources, comp.Name+"-repo.yaml", comp.Name+"-release.yaml")
-		case ComponentTypeKustomize:
+		case comp.Type == ComponentTypeKustomize:
 			resources = append(resources, comp.Name+"-kustomization.yaml")
-		case ComponentTypeManifest:
+		case comp.Type == ComponentTypeManifest:
 			resources = append(resources, comp.Name+".yaml")
+		case comp.Type == ComponentTypeOperator:
+			resources = append(resources, comp.Name+"-operator.yaml")
 		}
 	}
 
@@ -480,6 +743,9 @@ func (i *Installer) generateBaseKustomization(path string, pattern *Pattern) err
 		"kind":       "Kustomization",
 		"resources":  resources,
 	}
+	if len(patches) > 0 {
+		kustomization["patches"] = patches
+	}
 
 	data, err := yaml.Marshal(kustomization)
 	if err != nil {
@@ -490,7 +756,7 @@ func (i *Installer) generateBaseKustomization(path string, pattern *Pattern) err
 }
 
 // generateOverlayKustomization generates an overlay kustomization.yaml.
-func (i *Installer) generateOverlayKustomization(path, env string, config map[string]any) error {
+func (i *Installer) generateOverlayKustomization(path, env string, patches []any) error {
 	kustomization := map[string]any{
 		"apiVersion": "kustomize.config.k8s.io/v1beta1",
 		"kind":       "Kustomization",
@@ -501,6 +767,9 @@ func (i *Installer) generateOverlayKustomization(path, env string, config map[st
 			"environment": env,
 		},
 	}
+	if len(patches) > 0 {
+		kustomization["patches"] = patches
+	}
 
 	data, err := yaml.Marshal(kustomization)
 	if err != nil {
@@ -510,8 +779,10 @@ func (i *Installer) generateOverlayKustomization(path, env string, config map[st
 	return os.WriteFile(path, data, 0644)
 }
 
-// generateArgoCDApplication generates ArgoCD Application resources.
-func (i *Installer) generateArgoCDApplication(pattern *Pattern, config map[string]any, environments []string) ([]string, error) {
+// generateArgoCDApplication generates ArgoCD Application resources. With
+// ArgoCD Helm sources, the Applications are multi-source Applications
+// deploying the overlay and the charts with the config of the environment.
+func (i *Installer) generateArgoCDApplication(pattern *Pattern, components []*Component, config map[string]any, environments []string, envConfigs map[string]map[string]any, helm helmRendering) ([]string, error) {
 	var paths []string
 
 	appDir := filepath.Join(i.projectPath, i.gitOpsTool, "applications")
@@ -519,32 +790,56 @@ func (i *Installer) generateArgoCDApplication(pattern *Pattern, config map[strin
 		return nil, err
 	}
 
+	wave, err := i.patternSyncWave(pattern)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, env := range environments {
 		appName := fmt.Sprintf("%s-%s", pattern.Metadata.Name, env)
+		source := map[string]any{
+			"repoURL":        "{{ .RepoURL }}",
+			"targetRevision": "HEAD",
+			"path":           fmt.Sprintf("infrastructure/%s/%s/overlays/%s", pattern.Metadata.Category, pattern.Metadata.Name, env),
+		}
+		spec := map[string]any{
+			"project": "default",
+			"source":  source,
+			"destination": map[string]any{
+				"server":    "https://kubernetes.default.svc",
+				"namespace": pattern.Metadata.Name,
+			},
+			"syncPolicy": map[string]any{
+				"automated": map[string]any{
+					"prune":    true,
+					"selfHeal": true,
+				},
+			},
+		}
+		if helm == helmSource {
+			envComponents, envConfig := components, config
+			if c, ok := envConfigs[env]; ok {
+				rendered, err := i.resolveComponents(pattern, c)
+				if err != nil {
+					return nil, err
+				}
+				envComponents, envConfig = rendered, c
+			}
+			if sources := helmSources(envComponents, envConfig); len(sources) > 0 {
+				delete(spec, "source")
+				spec["sources"] = append([]any{source}, sources...)
+			}
+		}
 		app := map[string]any{
 			"apiVersion": "argoproj.io/v1alpha1",
 			"kind":       "Application",
 			"metadata": map[string]any{
 				"name": appName,
-			},
-			"spec": map[string]any{
-				"project": "default",
-				"source": map[string]any{
-					"repoURL":        "{{ .RepoURL }}",
-					"targetRevision": "HEAD",
-					"path":           fmt.Sprintf("infrastructure/%s/%s/overlays/%s", pattern.Metadata.Category, pattern.Metadata.Name, env),
-				},
-				"destination": map[string]any{
-					"server":    "https://kubernetes.default.svc",
-					"namespace": pattern.Metadata.Name,
-				},
-				"syncPolicy": map[string]any{
-					"automated": map[string]any{
-						"prune":    true,
-						"selfHeal": true,
-					},
+				"annotations": map[string]string{
+					syncwave.Annotation: syncwave.Value(wave),
 				},
 			},
+			"spec": spec,
 		}
 
 		data, err := yaml.Marshal(app)
@@ -562,38 +857,33 @@ func (i *Installer) generateArgoCDApplication(pattern *Pattern, config map[strin
 	return paths, nil
 }
 
-// Uninstall removes an installed pattern.
-func (i *Installer) Uninstall(ctx context.Context, patternName string, opts UninstallOptions) error {
-	if err := i.LoadState(); err != nil {
-		return err
-	}
-
-	installed, ok := i.installed[patternName]
-	if !ok {
-		return fmt.Errorf("pattern '%s' is not installed", patternName)
-	}
-
-	// Remove generated files
-	if !opts.KeepFiles {
-		for _, path := range installed.Paths {
-			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-				if !opts.Force {
-					return fmt.Errorf("failed to remove %s: %w", path, err)
-				}
+// patternSyncWave returns the sync wave of the pattern's Applications: one
+// after the installed patterns it depends on, so that CRDs and operators
+// land before the resources using them.
+func (i *Installer) patternSyncWave(pattern *Pattern) (int, error) {
+	graph := map[string][]string{}
+	dependencies := func(p *Pattern) []string {
+		var deps []string
+		for _, dep := range p.Spec.Dependencies {
+			_, installed := i.installed[dep.Name]
+			if (installed || dep.Name == pattern.Metadata.Name) && dep.Name != p.Metadata.Name {
+				deps = append(deps, dep.Name)
 			}
 		}
+		return deps
 	}
+	for name, installed := range i.installed {
+		if name != pattern.Metadata.Name {
+			graph[name] = dependencies(&installed.Pattern)
+		}
+	}
+	graph[pattern.Metadata.Name] = dependencies(pattern)
 
-	// Remove from state
-	delete(i.installed, patternName)
-
-	return i.SaveState()
-}
-
-// UninstallOptions defines options for pattern uninstallation.
-type UninstallOptions struct {
-	Force     bool
-	KeepFiles bool
+	waves, err := syncwave.Compute(graph)
+	if err != nil {
+		return 0, fmt.Errorf("failed to order pattern %s: %w", pattern.Metadata.Name, err)
+	}
+	return waves[pattern.Metadata.Name], nil
 }
 
 // Update updates an installed pattern to a new version.
@@ -607,16 +897,18 @@ func (i *Installer) Update(ctx context.Context, patternName string, opts UpdateO
 		return nil, fmt.Errorf("pattern '%s' is not installed", patternName)
 	}
 
-	// Get target version
+	// Get target version: the newest version the patterns depending on it
+	// allow, ignoring the lockfile
 	targetVersion := opts.Version
-	if targetVersion == "" {
-		// Get latest version
-		entry, _, err := i.registry.FindPattern(ctx, patternName)
-		if err != nil {
-			return nil, err
-		}
-		targetVersion = entry.Latest
+	plan, err := i.Resolve(ctx, patternName, targetVersion, ResolveOptions{
+		SkipDeps:           true,
+		Upgrade:            true,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
 	}
+	targetVersion = plan[len(plan)-1].Version
 
 	// Check if update is needed
 	if installed.Pattern.Metadata.Version == targetVersion && !opts.Force {
@@ -632,8 +924,13 @@ func (i *Installer) Update(ctx context.Context, patternName string, opts UpdateO
 	installOpts := InstallOptions{
 		Version:      targetVersion,
 		Config:       installed.Config,
+		EnvConfig:    installed.EnvConfig,
 		Environments: installed.Environments,
+		VendorHelm:   installed.VendorHelm,
 		Force:        true,
+
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		upgrade:            true,
 	}
 
 	return i.Install(ctx, patternName, installOpts)
@@ -643,6 +940,8 @@ func (i *Installer) Update(ctx context.Context, patternName string, opts UpdateO
 type UpdateOptions struct {
 	Version string
 	Force   bool
+	// InsecureSkipVerify installs the new version without verifying its signature.
+	InsecureSkipVerify bool
 }
 
 // ListInstalled returns all installed patterns.
@@ -865,6 +1164,18 @@ func ValidatePattern(patternDir string) ([]string, error) {
 		if !validTypes[comp.Type] {
 			errors = append(errors, fmt.Sprintf("component '%s' has invalid type '%s'", comp.Name, comp.Type))
 		}
+		if comp.Type == ComponentTypeOperator && comp.Operator != nil {
+			if err := validateOperatorSpec(comp.Operator); err != nil {
+				errors = append(errors, fmt.Sprintf("component '%s': %v", comp.Name, err))
+			}
+		}
+	}
+
+	// Check the templates of the components parse
+	for idx := range pattern.Spec.Components {
+		if err := parseTemplates(&pattern.Spec.Components[idx]); err != nil {
+			errors = append(errors, fmt.Sprintf("component '%s': %v", pattern.Spec.Components[idx].Name, err))
+		}
 	}
 
 	return errors, nil
@@ -886,16 +1197,11 @@ func GenerateIndex(patternsDir, outputPath string) error {
 				return nil // Skip invalid patterns
 			}
 
-			entry := PatternIndexEntry{
-				Name:        pattern.Metadata.Name,
-				Description: pattern.Metadata.Description,
-				Category:    pattern.Metadata.Category,
-				Tags:        pattern.Metadata.Tags,
-				Versions:    []string{pattern.Metadata.Version},
-				Latest:      pattern.Metadata.Version,
-				Author:      pattern.Metadata.Author,
+			var changelog string
+			if data, err := os.ReadFile(filepath.Join(filepath.Dir(path), ChangelogFile)); err == nil {
+				changelog = changelogSection(string(data), pattern.Metadata.Version)
 			}
-			entries = append(entries, entry)
+			entries = indexPattern(entries, pattern, changelog)
 		}
 
 		return nil
@@ -910,7 +1216,18 @@ func GenerateIndex(patternsDir, outputPath string) error {
 		Patterns:  entries,
 	}
 
-	// Build categories from patterns
+	index.Categories = indexCategories(entries)
+
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// indexCategories builds the categories of an index from its patterns.
+func indexCategories(entries []PatternIndexEntry) []CategoryIndexEntry {
 	categoryCount := make(map[string]int)
 	for _, entry := range entries {
 		if entry.Category != "" {
@@ -918,20 +1235,57 @@ func GenerateIndex(patternsDir, outputPath string) error {
 		}
 	}
 
+	var categories []CategoryIndexEntry
 	for cat, count := range categoryCount {
-		index.Categories = append(index.Categories, CategoryIndexEntry{
+		categories = append(categories, CategoryIndexEntry{
 			Name:        cat,
 			Description: CategoryDescription(PatternCategory(cat)),
 			Count:       count,
 		})
 	}
+	return categories
+}
 
-	data, err := yaml.Marshal(index)
-	if err != nil {
-		return err
+// indexPattern adds a pattern version to the entries of an index. Versions
+// of a pattern share one entry describing the latest.
+func indexPattern(entries []PatternIndexEntry, pattern *Pattern, changelog string) []PatternIndexEntry {
+	version := pattern.Metadata.Version
+	i := 0
+	for i < len(entries) && entries[i].Name != pattern.Metadata.Name {
+		i++
+	}
+	if i == len(entries) {
+		entries = append(entries, PatternIndexEntry{
+			Name:        pattern.Metadata.Name,
+			Description: pattern.Metadata.Description,
+			Category:    pattern.Metadata.Category,
+			Tags:        pattern.Metadata.Tags,
+			Latest:      version,
+			Author:      pattern.Metadata.Author,
+		})
+	} else if newerVersion(version, entries[i].Latest) {
+		entries[i].Latest = version
+		entries[i].Description = pattern.Metadata.Description
+	}
+	entries[i].Versions = append(entries[i].Versions, version)
+	if changelog != "" {
+		if entries[i].Changelogs == nil {
+			entries[i].Changelogs = map[string]string{}
+		}
+		entries[i].Changelogs[version] = changelog
 	}
+	return entries
+}
 
-	return os.WriteFile(outputPath, data, 0644)
+// newerVersion reports whether version a is newer than b, comparing them as
+// semantic versions when both parse.
+func newerVersion(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return va.GreaterThan(vb)
 }
 
 // CheckUpdates checks for available updates for installed patterns.
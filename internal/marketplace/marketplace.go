@@ -17,6 +17,9 @@ type Marketplace struct {
 	installer   *Installer
 	projectPath string
 	cacheDir    string
+	// registriesFile holds the registries added with gitopsi marketplace registry add.
+	registriesFile string
+	registriesErr  error
 }
 
 // NewMarketplace creates a new marketplace instance.
@@ -28,14 +31,32 @@ func NewMarketplace(projectPath string) *Marketplace {
 	cacheDir := filepath.Join(homeDir, ".gitopsi", "cache")
 
 	registry := NewRegistryManager(cacheDir)
+	registriesFile := filepath.Join(homeDir, ".gitopsi", "registries.yaml")
 
 	return &Marketplace{
-		registry:    registry,
-		projectPath: projectPath,
-		cacheDir:    cacheDir,
+		registry:       registry,
+		projectPath:    projectPath,
+		cacheDir:       cacheDir,
+		registriesFile: registriesFile,
+		registriesErr:  registry.LoadRegistries(registriesFile),
 	}
 }
 
+// RegistriesError returns the error of loading the saved registries, if any.
+func (m *Marketplace) RegistriesError() error {
+	return m.registriesErr
+}
+
+// SaveRegistries saves the custom registries, so later commands use them.
+func (m *Marketplace) SaveRegistries() error {
+	return m.registry.SaveRegistries(m.registriesFile)
+}
+
+// Refresh updates the index of a registry, pulling Git registries.
+func (m *Marketplace) Refresh(ctx context.Context, registryName string) (*RegistryIndex, error) {
+	return m.registry.Refresh(ctx, registryName)
+}
+
 // Configure configures the marketplace with GitOps settings.
 func (m *Marketplace) Configure(gitOpsTool, platform string) {
 	m.installer = NewInstaller(m.registry, m.projectPath, gitOpsTool, platform)
@@ -133,13 +154,21 @@ func (m *Marketplace) Install(ctx context.Context, name string, opts InstallOpti
 }
 
 // Uninstall removes a pattern.
-func (m *Marketplace) Uninstall(ctx context.Context, name string, opts UninstallOptions) error {
+func (m *Marketplace) Uninstall(ctx context.Context, name string, opts UninstallOptions) (*UninstallResult, error) {
 	if m.installer == nil {
-		return fmt.Errorf("marketplace not configured, call Configure() first")
+		return nil, fmt.Errorf("marketplace not configured, call Configure() first")
 	}
 	return m.installer.Uninstall(ctx, name, opts)
 }
 
+// CheckInstalled runs the validation checks of an installed pattern.
+func (m *Marketplace) CheckInstalled(ctx context.Context, name string, opts CheckOptions) ([]CheckResult, error) {
+	if m.installer == nil {
+		return nil, fmt.Errorf("marketplace not configured, call Configure() first")
+	}
+	return m.installer.CheckInstalled(ctx, name, opts)
+}
+
 // Update updates a pattern to a newer version.
 func (m *Marketplace) Update(ctx context.Context, name string, opts UpdateOptions) (*InstallResult, error) {
 	if m.installer == nil {
@@ -201,69 +230,18 @@ func (m *Marketplace) ValidatePattern(patternDir string) ([]string, error) {
 	return ValidatePattern(patternDir)
 }
 
-// PublishPattern publishes a pattern to a registry.
-func (m *Marketplace) PublishPattern(ctx context.Context, patternDir, registryName string) error {
+// PublishPattern validates the pattern in patternDir and publishes it to a
+// registry.
+func (m *Marketplace) PublishPattern(ctx context.Context, patternDir, registryName string, opts PublishOptions) (*PublishResult, error) {
 	// Validate first
 	errors, err := ValidatePattern(patternDir)
 	if err != nil {
-		return fmt.Errorf("validation failed: %v", err)
+		return nil, fmt.Errorf("validation failed: %v", err)
 	}
 	if len(errors) > 0 {
-		return fmt.Errorf("validation warnings: %v", errors)
-	}
-
-	// For now, only local registries support publishing
-	reg, err := m.registry.GetRegistry(registryName)
-	if err != nil {
-		return err
-	}
-
-	if reg.Type != RegistryTypeLocal {
-		return fmt.Errorf("publishing to remote registries is not yet supported")
-	}
-
-	// Copy pattern to registry
-	pattern, err := LoadPattern(filepath.Join(patternDir, "pattern.yaml"))
-	if err != nil {
-		return err
-	}
-
-	destDir := filepath.Join(reg.URL, "patterns", pattern.Metadata.Name, pattern.Metadata.Version)
-	if mkdirErr := os.MkdirAll(destDir, 0755); mkdirErr != nil {
-		return fmt.Errorf("failed to create destination directory: %w", mkdirErr)
+		return nil, fmt.Errorf("validation warnings: %v", errors)
 	}
-
-	// Copy all files
-	err = filepath.Walk(patternDir, func(path string, info os.FileInfo, walkErr error) error {
-		if walkErr != nil {
-			return walkErr
-		}
-
-		relPath, relErr := filepath.Rel(patternDir, path)
-		if relErr != nil {
-			return relErr
-		}
-		destPath := filepath.Join(destDir, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(destPath, 0755)
-		}
-
-		data, readErr := os.ReadFile(path)
-		if readErr != nil {
-			return readErr
-		}
-
-		return os.WriteFile(destPath, data, 0644)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to copy pattern: %w", err)
-	}
-
-	// Regenerate index
-	patternsDir := filepath.Join(reg.URL, "patterns")
-	indexPath := filepath.Join(reg.URL, "index.yaml")
-	return GenerateIndex(patternsDir, indexPath)
+	return m.registry.Publish(ctx, patternDir, registryName, opts)
 }
 
 // GetDependencies returns the dependencies of a pattern.
@@ -625,6 +603,15 @@ func GetOfficialPatterns() []PatternIndexEntry {
 			Versions:    []string{"1.0.0"},
 			Verified:    true,
 		},
+		{
+			Name:        "velero",
+			Description: "Velero backups and restores of cluster resources and volumes",
+			Category:    string(CategorySecurity),
+			Tags:        []string{"backup", "disaster-recovery", "velero"},
+			Latest:      "1.0.0",
+			Versions:    []string{"1.0.0"},
+			Verified:    true,
+		},
 		{
 			Name:        "sealed-secrets",
 			Description: "Bitnami Sealed Secrets for GitOps-safe secrets",
@@ -697,6 +684,15 @@ func GetOfficialPatterns() []PatternIndexEntry {
 			Versions:    []string{"1.0.0"},
 			Verified:    true,
 		},
+		{
+			Name:        "opencost",
+			Description: "OpenCost cost allocation and showback by label",
+			Category:    string(CategoryEnterprise),
+			Tags:        []string{"cost", "finops", "showback", "opencost"},
+			Latest:      "1.0.0",
+			Versions:    []string{"1.0.0"},
+			Verified:    true,
+		},
 		{
 			Name:        "external-dns",
 			Description: "External DNS for automatic DNS management",
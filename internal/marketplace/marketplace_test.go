@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -899,3 +900,47 @@ func TestPatternToYAML(t *testing.T) {
 func containsString(s, substr string) bool {
 	return len(s) > 0 && len(substr) > 0 && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || containsString(s[1:], substr)))
 }
+
+func TestRegistryManagerOffline(t *testing.T) {
+	dir := t.TempDir()
+	pattern := NewPattern("monitoring", "1.0.0", "Monitoring stack")
+	if err := pattern.Save(filepath.Join(dir, "patterns", "monitoring", "1.0.0", "pattern.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	index := "patterns:\n  - name: monitoring\n    versions: [1.0.0]\n    latest: 1.0.0\n"
+	if err := os.WriteFile(filepath.Join(dir, "index.yaml"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if !rm.Offline() {
+		t.Error("expected offline mode")
+	}
+	if err := rm.AddRegistry(Registry{Name: "mirror", Type: RegistryTypePrivate, URL: "file://" + dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := rm.FetchIndex(ctx, "official"); err == nil {
+		t.Error("expected remote registry to be refused offline")
+	}
+	if _, err := rm.FetchPattern(ctx, "official", "monitoring", "1.0.0"); err == nil {
+		t.Error("expected remote pattern fetch to be refused offline")
+	}
+
+	_, registry, err := rm.FindPattern(ctx, "monitoring")
+	if err != nil || registry != "mirror" {
+		t.Fatalf("FindPattern() = %s, %v", registry, err)
+	}
+	if _, err := rm.FetchPattern(ctx, registry, "monitoring", "1.0.0"); err != nil {
+		t.Errorf("FetchPattern() error = %v", err)
+	}
+	results, err := rm.SearchPatterns(ctx, "", SearchOptions{})
+	if err != nil || len(results) != 1 {
+		t.Errorf("SearchPatterns() = %v, %v", results, err)
+	}
+	if _, _, err := rm.FindPattern(ctx, "unknown"); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("expected an offline not found error, got %v", err)
+	}
+}
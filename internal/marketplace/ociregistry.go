@@ -0,0 +1,118 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+)
+
+// OCI registries hold every pattern version as an artifact tagged
+// <registry>/<name>:<version>, one layer per file, and their index as the
+// artifact <registry>:index.
+const (
+	PatternArtifactType = "application/vnd.gitopsi.pattern.v1"
+	IndexArtifactType   = "application/vnd.gitopsi.index.v1"
+	indexTag            = "index"
+)
+
+// SetOCIResolver sets the client of OCI registries. Registries with an Auth
+// use their own credentials.
+func (rm *RegistryManager) SetOCIResolver(resolver *images.Resolver) {
+	rm.oci = resolver
+}
+
+// ociResolver returns the client of an OCI registry.
+func (rm *RegistryManager) ociResolver(reg *Registry) *images.Resolver {
+	if reg.Auth != nil {
+		username, password := reg.Auth.Username, reg.Auth.Password
+		if reg.Auth.Token != "" {
+			password = reg.Auth.Token
+		}
+		resolver := images.NewResolver(func(context.Context, string) (string, string, bool) {
+			return username, password, true
+		})
+		if rm.oci != nil && rm.oci.Client != nil {
+			resolver.Client = rm.oci.Client
+		}
+		return resolver
+	}
+	if rm.oci == nil {
+		rm.oci = images.NewResolver(nil)
+	}
+	return rm.oci
+}
+
+// ociRepository returns the repository of an OCI registry.
+func ociRepository(reg *Registry) (images.Reference, error) {
+	ref, err := images.Parse(strings.TrimPrefix(reg.URL, "oci://"))
+	if err != nil || ref.Tag != "" || ref.Digest != "" {
+		return images.Reference{}, fmt.Errorf("invalid OCI registry URL %s: use oci://<host>/<repository>", reg.URL)
+	}
+	return ref, nil
+}
+
+// ociReference returns the reference of tag in the repository of an OCI
+// registry, or of a pattern repository below it when pattern is set.
+func ociReference(reg *Registry, pattern, tag string) (images.Reference, error) {
+	ref, err := ociRepository(reg)
+	if err != nil {
+		return ref, err
+	}
+	if pattern != "" {
+		ref.Repository += "/" + pattern
+		ref.Name += "/" + pattern
+	}
+	ref.Tag = tag
+	return ref, nil
+}
+
+// fetchOCIIndex pulls the index of an OCI registry. A registry nothing was
+// published to yet has an empty index.
+func (rm *RegistryManager) fetchOCIIndex(ctx context.Context, reg *Registry) (*RegistryIndex, error) {
+	ref, err := ociReference(reg, "", indexTag)
+	if err != nil {
+		return nil, err
+	}
+	artifact, err := rm.ociResolver(reg).Pull(ctx, ref)
+	if errors.Is(err, images.ErrNotFound) {
+		return &RegistryIndex{Version: "1.0"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch index: %w", err)
+	}
+	file, ok := artifact.File("index.yaml")
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch index: %s has no index.yaml", ref.Name)
+	}
+	var index RegistryIndex
+	if err := yaml.Unmarshal(file.Data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	if err := rm.cacheIndex(reg.Name, &index); err != nil {
+		fmt.Printf("Warning: failed to cache index: %v\n", err)
+	}
+	return &index, nil
+}
+
+// fetchOCIFile pulls a file of a pattern version from an OCI registry.
+func (rm *RegistryManager) fetchOCIFile(ctx context.Context, reg *Registry, name, version, file string) ([]byte, error) {
+	ref, err := ociReference(reg, name, version)
+	if err != nil {
+		return nil, err
+	}
+	artifact, err := rm.ociResolver(reg).Pull(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pattern: %w", err)
+	}
+	f, ok := artifact.File(file)
+	if !ok {
+		return nil, fmt.Errorf("%s not found: %w", file, os.ErrNotExist)
+	}
+	return f.Data, nil
+}
@@ -0,0 +1,219 @@
+package marketplace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/operator"
+)
+
+// hasOLM reports whether the platform of the installer ships with the
+// Operator Lifecycle Manager.
+func (i *Installer) hasOLM() bool {
+	return i.platform == "openshift"
+}
+
+// resolveComponents renders the components of a pattern with the config and
+// resolves their operator components for the platform.
+func (i *Installer) resolveComponents(pattern *Pattern, config map[string]any) ([]*Component, error) {
+	components, err := renderComponents(pattern, config)
+	if err != nil {
+		return nil, err
+	}
+	for idx, comp := range components {
+		components[idx] = i.resolveOperator(comp)
+	}
+	return components, nil
+}
+
+// resolveOperator returns how a component is deployed. Without OLM, an
+// operator component with a chart is deployed as a Helm component and one
+// with a manifest as a manifest component; the others subscribe to
+// OperatorHub, which requires OLM to be installed.
+func (i *Installer) resolveOperator(comp *Component) *Component {
+	if comp.Type != ComponentTypeOperator || i.hasOLM() {
+		return comp
+	}
+	fallback := *comp
+	switch {
+	case comp.Chart != "" && comp.Repository != "":
+		fallback.Type = ComponentTypeHelm
+	case comp.Manifest != "":
+		fallback.Type = ComponentTypeManifest
+	default:
+		return comp
+	}
+	return &fallback
+}
+
+// olmOperator returns the OLM operator of an operator component.
+// Components without a namespace are installed in the global operators
+// namespace, which has an OperatorGroup for all namespaces.
+func (i *Installer) olmOperator(comp *Component) (*operator.Operator, bool, error) {
+	spec := OperatorSpec{}
+	if comp.Operator != nil {
+		spec = *comp.Operator
+	}
+	source, sourceNamespace, globalNamespace := string(operator.CatalogSourceCommunity), "openshift-marketplace", "openshift-operators"
+	if !i.hasOLM() {
+		source, sourceNamespace, globalNamespace = string(operator.CatalogSourceOperatorHub), "olm", "operators"
+	}
+	if spec.Source != "" {
+		source = spec.Source
+	}
+	if spec.SourceNamespace != "" {
+		sourceNamespace = spec.SourceNamespace
+	}
+
+	op := &operator.Operator{
+		Name:                comp.Name,
+		Namespace:           comp.Namespace,
+		Channel:             spec.Channel,
+		Source:              source,
+		SourceNamespace:     sourceNamespace,
+		Version:             spec.StartingCSV,
+		InstallPlanApproval: spec.InstallPlanApproval,
+		InstallMode:         spec.InstallMode,
+		TargetNamespaces:    spec.TargetNamespaces,
+	}
+	if spec.Package != "" {
+		op.Name = spec.Package
+	}
+	global := op.Namespace == "" || op.Namespace == globalNamespace
+	if global {
+		op.Namespace = globalNamespace
+		if op.InstallMode == "" {
+			op.InstallMode = string(operator.InstallModeAllNamespaces)
+		}
+	}
+
+	if err := validateOperator(op); err != nil {
+		return nil, false, err
+	}
+	return op, !global, nil
+}
+
+func validateOperator(op *operator.Operator) error {
+	if err := op.Validate(); err != nil {
+		return err
+	}
+	if approval := op.GetInstallPlanApproval(); approval != "Automatic" && approval != "Manual" {
+		return fmt.Errorf("invalid install plan approval %q: use Automatic or Manual", approval)
+	}
+	return nil
+}
+
+// validateOperatorSpec checks the install mode and plan approval of an
+// operator component, unless they are templates.
+func validateOperatorSpec(spec *OperatorSpec) error {
+	op := &operator.Operator{Name: "operator", Namespace: "operators"}
+	if !strings.Contains(spec.InstallMode, "{{") {
+		op.InstallMode = spec.InstallMode
+	}
+	if !strings.Contains(spec.InstallPlanApproval, "{{") {
+		op.InstallPlanApproval = spec.InstallPlanApproval
+	}
+	return validateOperator(op)
+}
+
+// subscriptionManifest returns the OLM Subscription of an operator.
+func subscriptionManifest(op *operator.Operator) map[string]any {
+	manifest := op.ToSubscriptionManifest("", "")
+	spec := map[string]any{
+		"channel":             manifest.Channel,
+		"name":                manifest.Name,
+		"source":              manifest.Source,
+		"sourceNamespace":     manifest.SourceNamespace,
+		"installPlanApproval": manifest.InstallPlanApproval,
+	}
+	if manifest.StartingCSV != "" {
+		spec["startingCSV"] = manifest.StartingCSV
+	}
+	return map[string]any{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "Subscription",
+		"metadata": map[string]any{
+			"name":      manifest.Name,
+			"namespace": manifest.Namespace,
+		},
+		"spec": spec,
+	}
+}
+
+// operatorManifests returns the OLM resources of an operator component:
+// its Namespace and OperatorGroup outside the global operators namespace,
+// and its Subscription.
+func (i *Installer) operatorManifests(comp *Component) ([]byte, error) {
+	op, ownNamespace, err := i.olmOperator(comp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator: %w", err)
+	}
+
+	var docs []any
+	if ownNamespace {
+		group := op.ToGroupManifest()
+		groupSpec := map[string]any{}
+		if len(group.TargetNamespaces) > 0 {
+			groupSpec["targetNamespaces"] = group.TargetNamespaces
+		}
+		docs = append(docs,
+			map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]any{"name": op.Namespace},
+			},
+			map[string]any{
+				"apiVersion": "operators.coreos.com/v1",
+				"kind":       "OperatorGroup",
+				"metadata": map[string]any{
+					"name":      group.Name,
+					"namespace": group.Namespace,
+				},
+				"spec": groupSpec,
+			},
+		)
+	}
+	docs = append(docs, subscriptionManifest(op))
+
+	parts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, string(data))
+	}
+	return []byte(strings.Join(parts, "---\n")), nil
+}
+
+// subscriptionPatch returns the Subscription of an operator component, which
+// patches the base one in overlays.
+func (i *Installer) subscriptionPatch(comp *Component) ([]byte, error) {
+	op, _, err := i.olmOperator(comp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator: %w", err)
+	}
+	return yaml.Marshal(subscriptionManifest(op))
+}
+
+// operatorPath returns the path of the OLM resources of a component.
+func operatorPath(dir string, comp *Component) string {
+	return filepath.Join(dir, comp.Name+"-operator.yaml")
+}
+
+// writeOperator writes the OLM resources of an operator component.
+func (i *Installer) writeOperator(baseDir string, comp *Component) (string, error) {
+	data, err := i.operatorManifests(comp)
+	if err != nil {
+		return "", err
+	}
+	path := operatorPath(baseDir, comp)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
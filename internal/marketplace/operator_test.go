@@ -0,0 +1,158 @@
+package marketplace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func operatorPattern() *Pattern {
+	pattern := NewPattern("cert-manager", "1.0.0", "Certificate management")
+	pattern.Metadata.Category = "security"
+	pattern.Spec.Config = map[string]ConfigItem{
+		"approval": {Type: ConfigTypeString, Default: "Automatic"},
+	}
+	pattern.Spec.Components = []Component{
+		{
+			Name:      "cert-manager",
+			Type:      ComponentTypeOperator,
+			Namespace: "cert-manager",
+			Operator: &OperatorSpec{
+				Package:             "cert-manager-operator",
+				Channel:             "stable-v1",
+				Source:              "redhat-operators",
+				InstallPlanApproval: "{{ .Config.approval }}",
+			},
+		},
+		{
+			Name:     "global",
+			Type:     ComponentTypeOperator,
+			Operator: &OperatorSpec{Channel: "alpha"},
+		},
+	}
+	return pattern
+}
+
+func operatorInstaller(t *testing.T, pattern *Pattern, platform string) (*Installer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	addPattern(t, dir, pattern)
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	return NewInstaller(rm, project, "argocd", platform), project
+}
+
+func TestInstallOperatorOLM(t *testing.T) {
+	installer, project := operatorInstaller(t, operatorPattern(), "openshift")
+	opts := InstallOptions{
+		Environments: []string{"dev", "prod"},
+		EnvConfig:    map[string]map[string]any{"prod": {"approval": "Manual"}},
+	}
+	result, err := installer.Install(context.Background(), "cert-manager", opts)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("Install() warnings = %v", result.Warnings)
+	}
+
+	dir := filepath.Join(project, "infrastructure", "security", "cert-manager")
+	data, err := os.ReadFile(filepath.Join(dir, "base", "cert-manager-operator.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := string(data)
+	for _, want := range []string{
+		"kind: Namespace", "kind: OperatorGroup", "name: cert-manager-operator-og", "- cert-manager",
+		"kind: Subscription", "name: cert-manager-operator", "channel: stable-v1",
+		"source: redhat-operators", "sourceNamespace: openshift-marketplace", "installPlanApproval: Automatic",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("operator manifest = %s, want %q", manifest, want)
+		}
+	}
+	if strings.Count(manifest, "---\n") != 2 {
+		t.Errorf("operator manifest should have 3 documents: %s", manifest)
+	}
+
+	global, err := os.ReadFile(filepath.Join(dir, "base", "global-operator.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(global), "OperatorGroup") || !strings.Contains(string(global), "namespace: openshift-operators") {
+		t.Errorf("global operator manifest = %s", global)
+	}
+	kustomization, _ := os.ReadFile(filepath.Join(dir, "base", "kustomization.yaml"))
+	if !strings.Contains(string(kustomization), "- cert-manager-operator.yaml") {
+		t.Errorf("base kustomization = %s", kustomization)
+	}
+
+	patch, err := os.ReadFile(filepath.Join(dir, "overlays", "prod", "cert-manager-subscription-patch.yaml"))
+	if err != nil || !strings.Contains(string(patch), "installPlanApproval: Manual") {
+		t.Errorf("prod subscription patch = %s, %v", patch, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "overlays", "dev", "cert-manager-subscription-patch.yaml")); !os.IsNotExist(err) {
+		t.Errorf("dev overlay should have no patch: %v", err)
+	}
+}
+
+func TestInstallOperatorFallback(t *testing.T) {
+	pattern := operatorPattern()
+	pattern.Spec.Components[0].Chart = "cert-manager"
+	pattern.Spec.Components[0].Repository = "https://charts.jetstack.io"
+	pattern.Spec.Components[1].Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: global\n"
+	installer, project := operatorInstaller(t, pattern, "kubernetes")
+	if _, err := installer.Install(context.Background(), "cert-manager", InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	dir := filepath.Join(project, "infrastructure", "security", "cert-manager")
+	if _, err := os.Stat(filepath.Join(dir, "base", "cert-manager-operator.yaml")); !os.IsNotExist(err) {
+		t.Errorf("no OLM resources should be generated without OLM: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "base", "global.yaml")); err != nil || !strings.Contains(string(data), "kind: ConfigMap") {
+		t.Errorf("manifest fallback = %s, %v", data, err)
+	}
+	app, _ := os.ReadFile(filepath.Join(project, "argocd", "applications", "cert-manager-dev.yaml"))
+	if !strings.Contains(string(app), "chart: cert-manager") {
+		t.Errorf("chart fallback should be a Helm source: %s", app)
+	}
+
+	// Without a fallback, the operators subscribe to OperatorHub.
+	installer, project = operatorInstaller(t, operatorPattern(), "kubernetes")
+	result, err := installer.Install(context.Background(), "cert-manager", InstallOptions{})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if !strings.Contains(strings.Join(result.Warnings, "\n"), "requires OLM") {
+		t.Errorf("Install() warnings = %v", result.Warnings)
+	}
+	data, _ := os.ReadFile(filepath.Join(project, "infrastructure", "security", "cert-manager", "base", "global-operator.yaml"))
+	for _, want := range []string{"source: operatorhubio-catalog", "sourceNamespace: olm", "namespace: operators"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("OperatorHub subscription = %s, want %q", data, want)
+		}
+	}
+}
+
+func TestValidatePatternOperator(t *testing.T) {
+	dir := t.TempDir()
+	pattern := operatorPattern()
+	pattern.Spec.Components[1].Operator.InstallPlanApproval = "Sometimes"
+	if err := pattern.Save(filepath.Join(dir, "pattern.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := ValidatePattern(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(problems, "\n"), "component 'global': invalid install plan approval") {
+		t.Errorf("ValidatePattern() = %v", problems)
+	}
+}
@@ -0,0 +1,165 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeEnvConfig returns the config of each environment with overrides: the
+// install config with the overrides of the environment applied, validated
+// against the pattern.
+func mergeEnvConfig(pattern *Pattern, config map[string]any, overrides map[string]map[string]any, environments []string) (map[string]map[string]any, error) {
+	merged := make(map[string]map[string]any, len(overrides))
+	for _, env := range sortedKeys(overrides) {
+		if !slices.Contains(environments, env) {
+			return nil, fmt.Errorf("config for environment '%s', which is not one of the target environments %v", env, environments)
+		}
+		envConfig := make(map[string]any, len(config)+len(overrides[env]))
+		for k, v := range config {
+			envConfig[k] = v
+		}
+		for k, v := range overrides[env] {
+			envConfig[k] = v
+		}
+		if err := pattern.ValidateConfig(envConfig); err != nil {
+			return nil, fmt.Errorf("environment %s: %w", env, err)
+		}
+		merged[env] = envConfig
+	}
+	return merged, nil
+}
+
+// generateOverlayPatches renders the components with the config of an
+// environment and writes a patch of every generated resource that differs
+// from the base. It returns the patch files and the patch entries of the
+// overlay kustomization. The values of ArgoCD Helm sources are set in the
+// Application of the environment instead.
+func (i *Installer) generateOverlayPatches(ctx context.Context, overlayDir string, pattern *Pattern, base []*Component, config, envConfig map[string]any, helm helmRendering) ([]string, []any, error) {
+	components, err := i.resolveComponents(pattern, envConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var paths []string
+	var patches []any
+	writePatch := func(name string, data []byte) error {
+		path := filepath.Join(overlayDir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		patches = append(patches, map[string]any{"path": name})
+		return nil
+	}
+
+	for idx, comp := range components {
+		baseComp := base[idx]
+		switch {
+		case comp.Type == ComponentTypeHelm && helm == helmSource:
+			continue
+
+		case comp.Type == ComponentTypeHelm && helm == helmVendor:
+			values := mergeValues(comp.Values, envConfig)
+			if reflect.DeepEqual(values, mergeValues(baseComp.Values, config)) {
+				break
+			}
+			// The chart rendered for the environment patches the base one
+			data, err := vendorChart(ctx, comp, values)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := writePatch(comp.Name+"-chart-patch.yaml", data); err != nil {
+				return nil, nil, err
+			}
+
+		case comp.Type == ComponentTypeHelm:
+			values := mergeValues(comp.Values, envConfig)
+			if reflect.DeepEqual(values, mergeValues(baseComp.Values, config)) {
+				break
+			}
+			data, err := yaml.Marshal(map[string]any{
+				"apiVersion": "helm.toolkit.fluxcd.io/v2beta1",
+				"kind":       "HelmRelease",
+				"metadata":   map[string]any{"name": comp.Name},
+				"spec":       map[string]any{"values": values},
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := writePatch(comp.Name+"-release-patch.yaml", data); err != nil {
+				return nil, nil, err
+			}
+
+		case comp.Type == ComponentTypeKustomize:
+			if reflect.DeepEqual(comp.Patches, baseComp.Patches) {
+				break
+			}
+			data, err := yaml.Marshal(map[string]any{
+				"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+				"kind":       "Kustomization",
+				"metadata":   map[string]any{"name": comp.Name},
+				"spec":       map[string]any{"patches": comp.Patches},
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := writePatch(comp.Name+"-kustomization-patch.yaml", data); err != nil {
+				return nil, nil, err
+			}
+			continue
+
+		case comp.Type == ComponentTypeOperator:
+			if reflect.DeepEqual(comp.Operator, baseComp.Operator) {
+				break
+			}
+			data, err := i.subscriptionPatch(comp)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := writePatch(comp.Name+"-subscription-patch.yaml", data); err != nil {
+				return nil, nil, err
+			}
+
+		case comp.Type == ComponentTypeManifest:
+			// The manifest rendered for the environment patches the base one
+			if comp.Manifest != baseComp.Manifest && comp.Manifest != "" {
+				if err := writePatch(comp.Name+"-patch.yaml", []byte(comp.Manifest)); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+
+		// Patches rendered for the environment are applied after the base ones
+		if !reflect.DeepEqual(comp.Patches, baseComp.Patches) {
+			for _, patch := range comp.Patches {
+				patches = append(patches, patch)
+			}
+		}
+	}
+	return paths, patches, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mapValues returns the values of m in the order of their keys.
+func mapValues[V any](m map[string]V) []V {
+	values := make([]V, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		values = append(values, m[k])
+	}
+	return values
+}
@@ -0,0 +1,107 @@
+package marketplace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallEnvConfig(t *testing.T) {
+	dir := t.TempDir()
+	addPattern(t, dir, templatedPattern())
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	installer := NewInstaller(rm, project, "flux", "kubernetes")
+	ctx := context.Background()
+
+	opts := InstallOptions{
+		Environments: []string{"dev", "prod"},
+		EnvConfig:    map[string]map[string]any{"prod": {"replicas": 5, "domain": "acme.io"}},
+	}
+	if _, err := installer.Install(ctx, "ingress", opts); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	overlays := filepath.Join(project, "infrastructure", "networking", "ingress", "overlays")
+	release, err := os.ReadFile(filepath.Join(overlays, "prod", "nginx-release-patch.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: HelmRelease", "replicaCount: 5", "host: ingress.acme.io"} {
+		if !strings.Contains(string(release), want) {
+			t.Errorf("prod release patch = %s, want %q", release, want)
+		}
+	}
+	certificate, err := os.ReadFile(filepath.Join(overlays, "prod", "certificate-patch.yaml"))
+	if err != nil || !strings.Contains(string(certificate), "*.acme.io") {
+		t.Errorf("prod certificate patch = %s, %v", certificate, err)
+	}
+	kustomization, _ := os.ReadFile(filepath.Join(overlays, "prod", "kustomization.yaml"))
+	for _, want := range []string{"path: nginx-release-patch.yaml", "path: certificate-patch.yaml", "value: acme-io"} {
+		if !strings.Contains(string(kustomization), want) {
+			t.Errorf("prod kustomization = %s, want %q", kustomization, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(overlays, "dev", "nginx-release-patch.yaml")); !os.IsNotExist(err) {
+		t.Errorf("dev overlay should have no patch: %v", err)
+	}
+
+	installed, err := installer.GetInstalled("ingress")
+	if err != nil || installed.EnvConfig["prod"]["replicas"] != 5 {
+		t.Fatalf("state EnvConfig = %v, %v", installed.EnvConfig, err)
+	}
+
+	// Reinstalls through Update keep the overrides.
+	if _, err := installer.Update(ctx, "ingress", UpdateOptions{Force: true}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(overlays, "prod", "nginx-release-patch.yaml")); err != nil {
+		t.Errorf("Update() dropped the prod overrides: %v", err)
+	}
+
+	opts = InstallOptions{Environments: []string{"dev"}, Force: true, EnvConfig: map[string]map[string]any{"prod": {"replicas": 3}}}
+	if _, err := installer.Install(ctx, "ingress", opts); err == nil || !strings.Contains(err.Error(), "'prod'") {
+		t.Errorf("Install() with config of another environment error = %v", err)
+	}
+	opts = InstallOptions{Force: true, EnvConfig: map[string]map[string]any{"prod": {"replicas": "many"}}}
+	if _, err := installer.Install(ctx, "ingress", opts); err == nil || !strings.Contains(err.Error(), "environment prod") {
+		t.Errorf("Install() with invalid prod config error = %v", err)
+	}
+}
+
+func TestInstallTransform(t *testing.T) {
+	dir := t.TempDir()
+	addPattern(t, dir, templatedPattern())
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	installer := NewInstaller(rm, project, "flux", "kubernetes")
+	var transformed []string
+	installer.SetTransform(func(path string, content []byte) ([]byte, error) {
+		transformed = append(transformed, path)
+		return append([]byte("# transformed\n"), content...), nil
+	})
+
+	result, err := installer.Install(context.Background(), "ingress", InstallOptions{Environments: []string{"dev"}})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if len(transformed) != len(result.GeneratedPath) {
+		t.Errorf("transformed %d files, generated %d", len(transformed), len(result.GeneratedPath))
+	}
+	for _, path := range result.GeneratedPath {
+		data, err := os.ReadFile(path)
+		if err != nil || !strings.HasPrefix(string(data), "# transformed\n") {
+			t.Errorf("%s was not transformed: %v", path, err)
+		}
+	}
+}
@@ -112,6 +112,46 @@ type Component struct {
 	Values     map[string]any    `yaml:"values,omitempty" json:"values,omitempty"`
 	Namespace  string            `yaml:"namespace,omitempty" json:"namespace,omitempty"`
 	Labels     map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	// Manifest is the content of a manifest component.
+	Manifest string `yaml:"manifest,omitempty" json:"manifest,omitempty"`
+	// Patches are kustomize patches applied to the component.
+	Patches []Patch `yaml:"patches,omitempty" json:"patches,omitempty"`
+	// Operator configures the OLM subscription of an operator component.
+	Operator *OperatorSpec `yaml:"operator,omitempty" json:"operator,omitempty"`
+}
+
+// OperatorSpec configures the OLM Subscription and OperatorGroup of an
+// operator component. On platforms without OLM, the chart or manifest of the
+// component is deployed instead when it has one.
+type OperatorSpec struct {
+	// Package is the OLM package, the component name by default.
+	Package string `yaml:"package,omitempty" json:"package,omitempty"`
+	Channel string `yaml:"channel,omitempty" json:"channel,omitempty"`
+	// Source is the CatalogSource of the package.
+	Source          string `yaml:"source,omitempty" json:"source,omitempty"`
+	SourceNamespace string `yaml:"sourceNamespace,omitempty" json:"sourceNamespace,omitempty"`
+	// InstallPlanApproval is Automatic or Manual.
+	InstallPlanApproval string   `yaml:"installPlanApproval,omitempty" json:"installPlanApproval,omitempty"`
+	StartingCSV         string   `yaml:"startingCSV,omitempty" json:"startingCSV,omitempty"`
+	InstallMode         string   `yaml:"installMode,omitempty" json:"installMode,omitempty"`
+	TargetNamespaces    []string `yaml:"targetNamespaces,omitempty" json:"targetNamespaces,omitempty"`
+}
+
+// Patch is a kustomize patch, a strategic merge or JSON 6902 patch applied
+// to the resources selected by its target.
+type Patch struct {
+	Patch  string       `yaml:"patch" json:"patch"`
+	Target *PatchTarget `yaml:"target,omitempty" json:"target,omitempty"`
+}
+
+// PatchTarget selects the resources a patch applies to.
+type PatchTarget struct {
+	Group         string `yaml:"group,omitempty" json:"group,omitempty"`
+	Version       string `yaml:"version,omitempty" json:"version,omitempty"`
+	Kind          string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Name          string `yaml:"name,omitempty" json:"name,omitempty"`
+	Namespace     string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	LabelSelector string `yaml:"labelSelector,omitempty" json:"labelSelector,omitempty"`
 }
 
 // ConfigItem defines a configuration option.
@@ -153,15 +193,19 @@ type PatternHooks struct {
 
 // InstalledPattern represents an installed pattern with its configuration.
 type InstalledPattern struct {
-	Pattern      Pattern           `yaml:"pattern" json:"pattern"`
-	InstalledAt  time.Time         `yaml:"installedAt" json:"installedAt"`
-	UpdatedAt    time.Time         `yaml:"updatedAt,omitempty" json:"updatedAt,omitempty"`
-	Config       map[string]any    `yaml:"config,omitempty" json:"config,omitempty"`
-	Environments []string          `yaml:"environments,omitempty" json:"environments,omitempty"`
-	Status       string            `yaml:"status" json:"status"`
-	Health       string            `yaml:"health,omitempty" json:"health,omitempty"`
-	Paths        []string          `yaml:"paths,omitempty" json:"paths,omitempty"`
-	Annotations  map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	Pattern     Pattern        `yaml:"pattern" json:"pattern"`
+	InstalledAt time.Time      `yaml:"installedAt" json:"installedAt"`
+	UpdatedAt   time.Time      `yaml:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+	Config      map[string]any `yaml:"config,omitempty" json:"config,omitempty"`
+	// EnvConfig holds the config overrides of each environment.
+	EnvConfig    map[string]map[string]any `yaml:"envConfig,omitempty" json:"envConfig,omitempty"`
+	Environments []string                  `yaml:"environments,omitempty" json:"environments,omitempty"`
+	// VendorHelm records that the charts were installed rendered.
+	VendorHelm  bool              `yaml:"vendorHelm,omitempty" json:"vendorHelm,omitempty"`
+	Status      string            `yaml:"status" json:"status"`
+	Health      string            `yaml:"health,omitempty" json:"health,omitempty"`
+	Paths       []string          `yaml:"paths,omitempty" json:"paths,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 }
 
 // PatternVersion represents a specific version of a pattern.
@@ -0,0 +1,443 @@
+package marketplace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	git "github.com/go-git/go-git/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+)
+
+// ChangelogFile holds the release notes of a pattern version.
+const ChangelogFile = "CHANGELOG.md"
+
+// PublishOptions configures the publication of a pattern.
+type PublishOptions struct {
+	// Changelog describes the version. It defaults to the section of the
+	// version in the CHANGELOG.md of the pattern.
+	Changelog string
+	// PullRequests proposes the pattern to a Git registry as a pull request.
+	// Without it, the pattern is pushed to the branch of the registry.
+	PullRequests gitops.PullRequestCreator
+	// Branch is the pull request branch (default: gitopsi/publish-<name>-<version>).
+	Branch string
+	// Title is the pull request title (default: the commit message).
+	Title string
+	Draft bool
+	// DryRun validates the pattern without publishing it.
+	DryRun bool
+}
+
+// PublishResult describes a published pattern.
+type PublishResult struct {
+	Name      string `json:"name" yaml:"name"`
+	Version   string `json:"version" yaml:"version"`
+	Registry  string `json:"registry" yaml:"registry"`
+	Changelog string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	// Location is the directory, commit, URL or OCI reference of the
+	// published pattern.
+	Location    string                   `json:"location,omitempty" yaml:"location,omitempty"`
+	PullRequest *gitprovider.PullRequest `json:"pullRequest,omitempty" yaml:"pullRequest,omitempty"`
+	DryRun      bool                     `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+}
+
+// patternFile is a file of a pattern, named by its slash-separated path.
+type patternFile struct {
+	name string
+	data []byte
+}
+
+// Publish publishes the pattern in dir to a registry: copied to local
+// registries, uploaded to HTTP registries, committed to Git registries and
+// pushed as an artifact to OCI registries. Published versions are immutable.
+func (rm *RegistryManager) Publish(ctx context.Context, dir, registryName string, opts PublishOptions) (*PublishResult, error) {
+	reg, err := rm.GetRegistry(registryName)
+	if err != nil {
+		return nil, err
+	}
+	if reg.Type == RegistryTypeOfficial {
+		return nil, fmt.Errorf("patterns are published to the official registry by pull request to github.com/ihsanmokhlisse/gitopsi-patterns")
+	}
+	pattern, err := LoadPattern(filepath.Join(dir, "pattern.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	version := pattern.Metadata.Version
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		return nil, fmt.Errorf("pattern version %q is not a semantic version (MAJOR.MINOR.PATCH): %w", version, err)
+	}
+
+	files, err := readPatternDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	changelog := strings.TrimSpace(opts.Changelog)
+	if changelog != "" {
+		files = setPatternFile(files, ChangelogFile, []byte(fmt.Sprintf("## %s\n\n%s\n", version, changelog)))
+	} else if data, err := os.ReadFile(filepath.Join(dir, ChangelogFile)); err == nil {
+		changelog = changelogSection(string(data), version)
+	}
+
+	result := &PublishResult{Name: pattern.Metadata.Name, Version: version, Registry: reg.Name, Changelog: changelog, DryRun: opts.DryRun}
+	if opts.DryRun {
+		return result, nil
+	}
+	if rm.offline && !isLocal(reg) {
+		return nil, fmt.Errorf("cannot publish to registry '%s' in offline mode", reg.Name)
+	}
+
+	switch {
+	case isLocal(reg):
+		err = publishDir(localPath(reg), pattern, files, result)
+	case reg.Type == RegistryTypeGit:
+		err = rm.publishGit(ctx, reg, pattern, files, opts, result)
+	case reg.Type == RegistryTypeOCI:
+		err = rm.publishOCI(ctx, reg, pattern, files, changelog, result)
+	default:
+		err = rm.publishHTTP(ctx, reg, pattern, files, changelog, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// publishDir writes a pattern version to a registry directory and
+// regenerates its index.
+func publishDir(root string, pattern *Pattern, files []patternFile, result *PublishResult) error {
+	dest := filepath.Join(root, "patterns", pattern.Metadata.Name, pattern.Metadata.Version)
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("pattern '%s' %s is already published to registry '%s'", pattern.Metadata.Name, pattern.Metadata.Version, result.Registry)
+	}
+	for _, f := range files {
+		target := filepath.Join(dest, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create destination directory: %w", err)
+		}
+		if err := os.WriteFile(target, f.data, 0644); err != nil {
+			return fmt.Errorf("failed to copy pattern: %w", err)
+		}
+	}
+	if err := GenerateIndex(filepath.Join(root, "patterns"), filepath.Join(root, "index.yaml")); err != nil {
+		return fmt.Errorf("failed to regenerate index: %w", err)
+	}
+	result.Location = dest
+	return nil
+}
+
+// publishGit commits a pattern version and the regenerated index to a Git
+// registry, pushing to its branch or opening a pull request.
+func (rm *RegistryManager) publishGit(ctx context.Context, reg *Registry, pattern *Pattern, files []patternFile, opts PublishOptions, result *PublishResult) error {
+	tmp, err := os.MkdirTemp("", "gitopsi-publish-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	creds, err := rm.cloneInto(ctx, reg, tmp, 0)
+	if err != nil {
+		return err
+	}
+	repo, err := git.PlainOpen(tmp)
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD of registry '%s': %w", reg.Name, err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("registry '%s' is pinned to tag %s: publishing needs a branch", reg.Name, reg.Ref)
+	}
+	if err := publishDir(filepath.Join(tmp, reg.Path), pattern, files, result); err != nil {
+		return err
+	}
+
+	name, version := pattern.Metadata.Name, pattern.Metadata.Version
+	message := fmt.Sprintf("Publish pattern %s %s", name, version)
+	pushOpts := &gitops.PushOptions{
+		Dir:           tmp,
+		RemoteURL:     reg.URL,
+		CommitMessage: message,
+		Credentials:   creds,
+	}
+	base := head.Name().Short()
+	if opts.PullRequests == nil {
+		pushOpts.Branch = base
+		pushed, err := gitops.NewPusher(pushOpts).Push(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to publish to registry '%s': %w", reg.Name, err)
+		}
+		result.Location = fmt.Sprintf("%s@%s", reg.URL, shortHash(pushed.Commit))
+		// Refresh the cached clone so the new version is installable.
+		if err := rm.cloneGit(ctx, reg); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = fmt.Sprintf("gitopsi/publish-%s-%s", name, version)
+	}
+	title := opts.Title
+	if title == "" {
+		title = message
+	}
+	body := result.Changelog
+	if body == "" {
+		body = fmt.Sprintf("Publishes version %s of the %s pattern.", version, name)
+	}
+	pushed, pr, err := gitops.OpenPullRequest(ctx, pushOpts, opts.PullRequests, &gitprovider.PullRequestOptions{
+		Title: title,
+		Body:  body,
+		Head:  branch,
+		Base:  base,
+		Draft: opts.Draft,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to registry '%s': %w", reg.Name, err)
+	}
+	result.Location = fmt.Sprintf("%s@%s", reg.URL, shortHash(pushed.Commit))
+	result.PullRequest = pr
+	return nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// publishHTTP uploads a pattern version to an HTTP registry, which
+// regenerates its index:
+//
+//	POST <url>/api/v1/patterns (multipart: name, version, changelog, archive)
+func (rm *RegistryManager) publishHTTP(ctx context.Context, reg *Registry, pattern *Pattern, files []patternFile, changelog string, result *PublishResult) error {
+	archive, err := archivePattern(files)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for key, value := range map[string]string{"name": pattern.Metadata.Name, "version": pattern.Metadata.Version, "changelog": changelog} {
+		if err := form.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("archive", fmt.Sprintf("%s-%s.tgz", pattern.Metadata.Name, pattern.Metadata.Version))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(archive); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(reg.URL, "/")+"/api/v1/patterns", &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	setAuth(req, reg)
+
+	resp, err := rm.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish to registry '%s': %w", reg.Name, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusConflict:
+		return fmt.Errorf("pattern '%s' %s is already published to registry '%s'", pattern.Metadata.Name, pattern.Metadata.Version, reg.Name)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("registry '%s' denied the upload: configure a token with publish access in its auth", reg.Name)
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to publish to registry '%s': HTTP %d: %s", reg.Name, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var uploaded struct {
+		URL string `json:"url"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&uploaded)
+	result.Location = uploaded.URL
+	if result.Location == "" {
+		result.Location = fmt.Sprintf("%s/patterns/%s/%s", strings.TrimSuffix(reg.URL, "/"), pattern.Metadata.Name, pattern.Metadata.Version)
+	}
+	return nil
+}
+
+// publishOCI pushes a pattern version as an artifact to an OCI registry and
+// pushes the updated index.
+func (rm *RegistryManager) publishOCI(ctx context.Context, reg *Registry, pattern *Pattern, files []patternFile, changelog string, result *PublishResult) error {
+	name, version := pattern.Metadata.Name, pattern.Metadata.Version
+	index, err := rm.fetchOCIIndex(ctx, reg)
+	if err != nil {
+		return err
+	}
+	for _, entry := range index.Patterns {
+		if entry.Name == name && slices.Contains(entry.Versions, version) {
+			return fmt.Errorf("pattern '%s' %s is already published to registry '%s'", name, version, reg.Name)
+		}
+	}
+
+	ref, err := ociReference(reg, name, version)
+	if err != nil {
+		return err
+	}
+	artifact := &images.Artifact{
+		ArtifactType: PatternArtifactType,
+		Annotations: map[string]string{
+			"org.opencontainers.image.title":       name,
+			"org.opencontainers.image.version":     version,
+			"org.opencontainers.image.description": pattern.Metadata.Description,
+			"org.opencontainers.image.created":     time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	for _, f := range files {
+		mediaType := "application/octet-stream"
+		if strings.HasSuffix(f.name, ".yaml") || strings.HasSuffix(f.name, ".yml") {
+			mediaType = "application/yaml"
+		}
+		artifact.Files = append(artifact.Files, images.File{Name: f.name, MediaType: mediaType, Data: f.data})
+	}
+	resolver := rm.ociResolver(reg)
+	digest, err := resolver.Push(ctx, ref, artifact)
+	if err != nil {
+		return fmt.Errorf("failed to publish to registry '%s': %w", reg.Name, err)
+	}
+
+	index.Patterns = indexPattern(index.Patterns, pattern, changelog)
+	index.Generated = time.Now()
+	index.Categories = indexCategories(index.Patterns)
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	indexRef, err := ociReference(reg, "", indexTag)
+	if err != nil {
+		return err
+	}
+	if _, err := resolver.Push(ctx, indexRef, &images.Artifact{
+		ArtifactType: IndexArtifactType,
+		Files:        []images.File{{Name: "index.yaml", MediaType: "application/yaml", Data: data}},
+	}); err != nil {
+		return fmt.Errorf("failed to update the index of registry '%s': %w", reg.Name, err)
+	}
+	result.Location = fmt.Sprintf("%s:%s@%s", ref.Name, version, digest)
+	return nil
+}
+
+// readPatternDir reads the files of a pattern, skipping its Git metadata.
+func readPatternDir(dir string) ([]patternFile, error) {
+	var files []patternFile
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, patternFile{name: filepath.ToSlash(rel), data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pattern: %w", err)
+	}
+	return files, nil
+}
+
+// setPatternFile adds or replaces a file of a pattern.
+func setPatternFile(files []patternFile, name string, data []byte) []patternFile {
+	for i := range files {
+		if files[i].name == name {
+			files[i].data = data
+			return files
+		}
+	}
+	return append(files, patternFile{name: name, data: data})
+}
+
+// archivePattern packs the files of a pattern into a gzipped tarball.
+func archivePattern(files []patternFile) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: path.Clean(f.name), Mode: 0644, Size: int64(len(f.data)), ModTime: time.Now()}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// changelogSection returns the release notes of version in a Markdown
+// changelog: the section under a heading naming the version, as in
+// "## [1.2.0] - 2024-05-01" or "## v1.2.0".
+func changelogSection(changelog, version string) string {
+	var section []string
+	level := 0
+	for _, line := range strings.Split(changelog, "\n") {
+		trimmed := strings.TrimSpace(line)
+		hashes := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if hashes > 0 && strings.HasPrefix(trimmed[hashes:], " ") {
+			if level > 0 && hashes <= level {
+				break
+			}
+			if level == 0 {
+				title := strings.TrimSpace(trimmed[hashes:])
+				title = strings.TrimPrefix(strings.TrimPrefix(title, "["), "v")
+				if title == version || strings.HasPrefix(title, version+"]") || strings.HasPrefix(title, version+" ") {
+					level = hashes
+				}
+				continue
+			}
+		}
+		if level > 0 {
+			section = append(section, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(section, "\n"))
+}
@@ -0,0 +1,295 @@
+package marketplace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitprovider"
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+)
+
+// patternSource writes a pattern with a changelog and a template to a
+// directory.
+func patternSource(t *testing.T, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := NewPattern("monitoring", version, "Monitoring stack").Save(filepath.Join(dir, "pattern.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	changelog := "# Changelog\n\n## [" + version + "] - 2026-10-01\n\n- Add alerts\n\n## [0.9.0]\n\n- Initial release\n"
+	if err := os.WriteFile(filepath.Join(dir, ChangelogFile), []byte(changelog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "app.yaml"), []byte("kind: Application\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPublishLocal(t *testing.T) {
+	registryDir := t.TempDir()
+	rm := NewRegistryManager(t.TempDir())
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: registryDir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	result, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "team", PublishOptions{})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if result.Changelog != "- Add alerts" {
+		t.Errorf("Publish() changelog = %q", result.Changelog)
+	}
+	if _, err := os.Stat(filepath.Join(registryDir, "patterns", "monitoring", "1.0.0", "templates", "app.yaml")); err != nil {
+		t.Errorf("Publish() did not copy the templates: %v", err)
+	}
+
+	if _, err := rm.Publish(ctx, patternSource(t, "1.1.0"), "team", PublishOptions{Changelog: "Fix dashboards"}); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := rm.GetPatternVersions(ctx, "monitoring")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("GetPatternVersions() = %v, %v", versions, err)
+	}
+	for _, v := range versions {
+		want := map[string]string{"1.0.0": "- Add alerts", "1.1.0": "Fix dashboards"}[v.Version]
+		if v.Changelog != want {
+			t.Errorf("changelog of %s = %q, want %q", v.Version, v.Changelog, want)
+		}
+	}
+
+	if _, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "team", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "already published") {
+		t.Errorf("Publish() of a published version error = %v", err)
+	}
+	if _, err := rm.Publish(ctx, patternSource(t, "v2"), "team", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "semantic version") {
+		t.Errorf("Publish() of a non-semver version error = %v", err)
+	}
+	if _, err := rm.Publish(ctx, patternSource(t, "2.0.0"), "official", PublishOptions{}); err == nil {
+		t.Error("Publish() to the official registry should fail")
+	}
+}
+
+type fakePullRequests struct {
+	opts *gitprovider.PullRequestOptions
+}
+
+func (f *fakePullRequests) CreatePullRequest(_ context.Context, opts *gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error) {
+	f.opts = opts
+	return &gitprovider.PullRequest{Number: 7, URL: "https://git.example.com/patterns/pull/7"}, nil
+}
+
+func TestPublishGit(t *testing.T) {
+	bare := filepath.Join(t.TempDir(), "patterns.git")
+	if _, err := git.PlainClone(bare, true, &git.CloneOptions{URL: patternRepo(t)}); err != nil {
+		t.Fatal(err)
+	}
+	rm := NewRegistryManager(t.TempDir())
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeGit, URL: bare, Path: "registry", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	result, err := rm.Publish(ctx, patternSource(t, "2.0.0"), "team", PublishOptions{})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if !strings.HasPrefix(result.Location, bare+"@") {
+		t.Errorf("Publish() location = %s", result.Location)
+	}
+	index, err := rm.FetchIndex(ctx, "team")
+	if err != nil || len(index.Patterns) != 1 || index.Patterns[0].Latest != "2.0.0" || index.Patterns[0].Changelogs["2.0.0"] != "- Add alerts" {
+		t.Fatalf("FetchIndex() after Publish() = %+v, %v", index, err)
+	}
+
+	prs := &fakePullRequests{}
+	result, err = rm.Publish(ctx, patternSource(t, "2.1.0"), "team", PublishOptions{PullRequests: prs})
+	if err != nil {
+		t.Fatalf("Publish() with a pull request error = %v", err)
+	}
+	if result.PullRequest == nil || prs.opts.Head != "gitopsi/publish-monitoring-2.1.0" || prs.opts.Base != "master" || prs.opts.Body != "- Add alerts" {
+		t.Errorf("Publish() pull request = %+v, options %+v", result.PullRequest, prs.opts)
+	}
+	repo, _ := git.PlainOpen(bare)
+	if _, err := repo.Reference("refs/heads/gitopsi/publish-monitoring-2.1.0", false); err != nil {
+		t.Errorf("Publish() did not push the pull request branch: %v", err)
+	}
+
+	if _, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "team", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "already published") {
+		t.Errorf("Publish() of a published version error = %v", err)
+	}
+}
+
+func TestPublishHTTP(t *testing.T) {
+	var files []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1/patterns" || req.Header.Get("Authorization") != "Bearer publish-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.FormValue("version") == "0.1.0" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if req.FormValue("name") != "monitoring" || req.FormValue("changelog") != "- Add alerts" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		archive, _, err := req.FormFile("archive")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gz, _ := gzip.NewReader(archive)
+		tr := tar.NewReader(gz)
+		for {
+			h, err := tr.Next()
+			if err != nil {
+				break
+			}
+			files = append(files, h.Name)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"url":"https://patterns.example.com/monitoring/1.0.0"}`)
+	}))
+	defer server.Close()
+
+	rm := NewRegistryManager(t.TempDir())
+	if err := rm.AddRegistry(Registry{Name: "web", Type: RegistryTypePrivate, URL: server.URL, Enabled: true, Auth: &RegistryAuth{Type: "token", Token: "publish-token"}}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := rm.Publish(context.Background(), patternSource(t, "1.0.0"), "web", PublishOptions{})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if result.Location != "https://patterns.example.com/monitoring/1.0.0" || len(files) != 3 {
+		t.Errorf("Publish() = %+v, uploaded %v", result, files)
+	}
+	if _, err := rm.Publish(context.Background(), patternSource(t, "0.1.0"), "web", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "already published") {
+		t.Errorf("Publish() conflict error = %v", err)
+	}
+}
+
+// ociRegistry is a fake OCI registry storing pushed blobs and manifests.
+func ociRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	blobs, manifests := map[string][]byte{}, map[string][]byte{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		repo, rest, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/v2/"), "/blobs/")
+		if rest == "" {
+			repo, rest, _ = strings.Cut(strings.TrimPrefix(req.URL.Path, "/v2/"), "/manifests/")
+			rest = "manifests/" + rest
+		} else {
+			rest = "blobs/" + rest
+		}
+		body, _ := io.ReadAll(req.Body)
+		switch {
+		case req.Method == http.MethodPost && rest == "blobs/uploads/":
+			w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/session")
+			w.WriteHeader(http.StatusAccepted)
+		case req.Method == http.MethodPut && strings.HasPrefix(rest, "blobs/uploads/"):
+			blobs[fmt.Sprintf("sha256:%x", sha256.Sum256(body))] = body
+			w.WriteHeader(http.StatusCreated)
+		case req.Method == http.MethodPut:
+			manifests[repo+"/"+rest] = body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			data, ok := blobs[strings.TrimPrefix(rest, "blobs/")]
+			if strings.HasPrefix(rest, "manifests/") {
+				data, ok = manifests[repo+"/"+rest]
+			}
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPublishOCI(t *testing.T) {
+	server := ociRegistry(t)
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOCIResolver(&images.Resolver{Client: server.Client()})
+	url := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/acme/patterns"
+	if err := rm.AddRegistry(Registry{Name: "oci", Type: RegistryTypeOCI, URL: url, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	index, err := rm.FetchIndex(ctx, "oci")
+	if err != nil || len(index.Patterns) != 0 {
+		t.Fatalf("FetchIndex() of an empty registry = %+v, %v", index, err)
+	}
+	result, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "oci", PublishOptions{})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if !strings.Contains(result.Location, "/acme/patterns/monitoring:1.0.0@sha256:") {
+		t.Errorf("Publish() location = %s", result.Location)
+	}
+
+	index, err = rm.FetchIndex(ctx, "oci")
+	if err != nil || len(index.Patterns) != 1 || index.Patterns[0].Changelogs["1.0.0"] != "- Add alerts" {
+		t.Fatalf("FetchIndex() = %+v, %v", index, err)
+	}
+	pattern, err := rm.FetchPattern(ctx, "oci", "monitoring", "1.0.0")
+	if err != nil || pattern.Metadata.Version != "1.0.0" {
+		t.Fatalf("FetchPattern() = %v, %v", pattern, err)
+	}
+	reg, _ := rm.GetRegistry("oci")
+	if _, err := rm.fetchPatternFile(ctx, reg, "monitoring", "1.0.0", SignatureFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("fetchPatternFile() of a missing file error = %v", err)
+	}
+	if _, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "oci", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "already published") {
+		t.Errorf("Publish() of a published version error = %v", err)
+	}
+
+	if err := rm.AddRegistry(Registry{Name: "tagged", Type: RegistryTypeOCI, URL: "oci://ghcr.io/acme/patterns:v1", Enabled: true}); err == nil {
+		t.Error("AddRegistry() should reject an OCI URL with a tag")
+	}
+}
+
+func TestChangelogSection(t *testing.T) {
+	changelog := `# Changelog
+
+## [Unreleased]
+
+## [1.2.0] - 2026-05-01
+
+### Added
+- Alerts
+
+## v1.1.0
+
+- Dashboards
+`
+	tests := map[string]string{
+		"1.2.0": "### Added\n- Alerts",
+		"1.1.0": "- Dashboards",
+		"1.0.0": "",
+	}
+	for version, want := range tests {
+		if got := changelogSection(changelog, version); got != want {
+			t.Errorf("changelogSection(%s) = %q, want %q", version, got, want)
+		}
+	}
+}
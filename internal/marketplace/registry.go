@@ -2,7 +2,9 @@ package marketplace
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +15,8 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
 )
 
 // RegistryType represents the type of pattern registry.
@@ -23,6 +27,12 @@ const (
 	RegistryTypeCommunity RegistryType = "community"
 	RegistryTypePrivate   RegistryType = "private"
 	RegistryTypeLocal     RegistryType = "local"
+	// RegistryTypeGit is a Git repository holding a registry, cloned with
+	// go-git into the cache.
+	RegistryTypeGit RegistryType = "git"
+	// RegistryTypeOCI is an OCI registry holding patterns as artifacts,
+	// e.g. oci://ghcr.io/acme/patterns.
+	RegistryTypeOCI RegistryType = "oci"
 )
 
 // Registry represents a pattern registry configuration.
@@ -33,6 +43,14 @@ type Registry struct {
 	Priority int           `yaml:"priority,omitempty" json:"priority,omitempty"`
 	Auth     *RegistryAuth `yaml:"auth,omitempty" json:"auth,omitempty"`
 	Enabled  bool          `yaml:"enabled" json:"enabled"`
+	// Signing requires the patterns of the registry to be signed.
+	Signing *SignaturePolicy `yaml:"signing,omitempty" json:"signing,omitempty"`
+	// Ref pins a Git registry to a branch or tag (default: the remote HEAD).
+	Ref string `yaml:"ref,omitempty" json:"ref,omitempty"`
+	// Path is the directory of a Git registry in its repository.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Credential names the gitopsi auth credential of a Git registry.
+	Credential string `yaml:"credential,omitempty" json:"credential,omitempty"`
 }
 
 // RegistryAuth contains authentication for private registries.
@@ -65,6 +83,8 @@ type PatternIndexEntry struct {
 	Downloads   int      `yaml:"downloads,omitempty" json:"downloads,omitempty"`
 	Verified    bool     `yaml:"verified,omitempty" json:"verified,omitempty"`
 	Deprecated  bool     `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	// Changelogs holds the release notes of the versions.
+	Changelogs map[string]string `yaml:"changelogs,omitempty" json:"changelogs,omitempty"`
 }
 
 // CategoryIndexEntry represents a category in the registry.
@@ -80,6 +100,10 @@ type RegistryManager struct {
 	registries []Registry
 	cacheDir   string
 	httpClient *http.Client
+	// offline restricts lookups to local registries.
+	offline        bool
+	gitCredentials GitCredentials
+	oci            *images.Resolver
 }
 
 // NewRegistryManager creates a new registry manager.
@@ -92,6 +116,10 @@ func NewRegistryManager(cacheDir string) *RegistryManager {
 				URL:      "https://raw.githubusercontent.com/ihsanmokhlisse/gitopsi-patterns/main",
 				Priority: 100,
 				Enabled:  true,
+				Signing: &SignaturePolicy{
+					Issuer:         "https://token.actions.githubusercontent.com",
+					IdentityRegexp: `^https://github\.com/ihsanmokhlisse/gitopsi-patterns/`,
+				},
 			},
 		},
 		cacheDir: cacheDir,
@@ -101,6 +129,27 @@ func NewRegistryManager(cacheDir string) *RegistryManager {
 	}
 }
 
+// SetOffline restricts the manager to local registries (and file:// URLs), so
+// it never reaches the network.
+func (rm *RegistryManager) SetOffline(offline bool) {
+	rm.offline = offline
+}
+
+// Offline reports whether only local registries are used.
+func (rm *RegistryManager) Offline() bool {
+	return rm.offline
+}
+
+// isLocal reports whether a registry is read from the filesystem.
+func isLocal(reg *Registry) bool {
+	return reg.Type == RegistryTypeLocal || strings.HasPrefix(reg.URL, "file://")
+}
+
+// localPath returns the directory of a local registry.
+func localPath(reg *Registry) string {
+	return strings.TrimPrefix(reg.URL, "file://")
+}
+
 // AddRegistry adds a custom registry.
 func (rm *RegistryManager) AddRegistry(reg Registry) error {
 	// Validate registry
@@ -110,6 +159,17 @@ func (rm *RegistryManager) AddRegistry(reg Registry) error {
 	if reg.URL == "" && reg.Type != RegistryTypeLocal {
 		return fmt.Errorf("registry URL is required")
 	}
+	if strings.ContainsAny(reg.Name, `/\`) || reg.Name == "." || reg.Name == ".." {
+		return fmt.Errorf("invalid registry name '%s'", reg.Name)
+	}
+	if reg.Type != RegistryTypeGit && (reg.Ref != "" || reg.Path != "") {
+		return fmt.Errorf("ref and path are only supported by git registries")
+	}
+	if reg.Type == RegistryTypeOCI {
+		if _, err := ociRepository(&reg); err != nil {
+			return err
+		}
+	}
 
 	// Check for duplicates
 	for _, r := range rm.registries {
@@ -128,6 +188,52 @@ func (rm *RegistryManager) AddRegistry(reg Registry) error {
 	return nil
 }
 
+// registriesFile is the format of the file holding the custom registries.
+type registriesFile struct {
+	Registries []Registry `yaml:"registries"`
+}
+
+// LoadRegistries adds the registries saved in path. A missing file adds none.
+func (rm *RegistryManager) LoadRegistries(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read registries: %w", err)
+	}
+	var file registriesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse registries %s: %w", path, err)
+	}
+	for _, reg := range file.Registries {
+		if err := rm.AddRegistry(reg); err != nil {
+			return fmt.Errorf("invalid registry in %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// SaveRegistries saves the registries added to the manager to path, leaving
+// out the official registry.
+func (rm *RegistryManager) SaveRegistries(path string) error {
+	file := registriesFile{Registries: []Registry{}}
+	for _, reg := range rm.registries {
+		if reg.Type != RegistryTypeOfficial {
+			file.Registries = append(file.Registries, reg)
+		}
+	}
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registries: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create registries directory: %w", err)
+	}
+	// Registries may hold tokens.
+	return os.WriteFile(path, data, 0600)
+}
+
 // RemoveRegistry removes a registry by name.
 func (rm *RegistryManager) RemoveRegistry(name string) error {
 	for i, r := range rm.registries {
@@ -139,6 +245,13 @@ func (rm *RegistryManager) RemoveRegistry(name string) error {
 	return fmt.Errorf("registry '%s' not found", name)
 }
 
+// SetSigningPolicy sets the signing policy of every registry.
+func (rm *RegistryManager) SetSigningPolicy(policy *SignaturePolicy) {
+	for i := range rm.registries {
+		rm.registries[i].Signing = policy
+	}
+}
+
 // GetRegistry returns a registry by name.
 func (rm *RegistryManager) GetRegistry(name string) (*Registry, error) {
 	for _, r := range rm.registries {
@@ -165,17 +278,27 @@ func (rm *RegistryManager) FetchIndex(ctx context.Context, registryName string)
 		return nil, fmt.Errorf("registry '%s' is disabled", registryName)
 	}
 
-	switch reg.Type {
-	case RegistryTypeLocal:
-		return rm.fetchLocalIndex(reg)
+	switch {
+	case isLocal(reg):
+		return readIndex(localPath(reg))
+	case reg.Type == RegistryTypeGit:
+		root, err := rm.gitRoot(ctx, reg)
+		if err != nil {
+			return nil, err
+		}
+		return readIndex(root)
+	case rm.offline:
+		return nil, fmt.Errorf("registry '%s' is remote and offline mode only uses local registries", registryName)
+	case reg.Type == RegistryTypeOCI:
+		return rm.fetchOCIIndex(ctx, reg)
 	default:
 		return rm.fetchRemoteIndex(ctx, reg)
 	}
 }
 
-// fetchLocalIndex fetches index from a local directory.
-func (rm *RegistryManager) fetchLocalIndex(reg *Registry) (*RegistryIndex, error) {
-	indexPath := filepath.Join(reg.URL, "index.yaml")
+// readIndex reads the index of a registry directory.
+func readIndex(dir string) (*RegistryIndex, error) {
+	indexPath := filepath.Join(dir, "index.yaml")
 	data, err := os.ReadFile(indexPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read local index: %w", err)
@@ -198,15 +321,7 @@ func (rm *RegistryManager) fetchRemoteIndex(ctx context.Context, reg *Registry)
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Add authentication if configured
-	if reg.Auth != nil {
-		switch reg.Auth.Type {
-		case "token":
-			req.Header.Set("Authorization", "Bearer "+reg.Auth.Token)
-		case "basic":
-			req.SetBasicAuth(reg.Auth.Username, reg.Auth.Password)
-		}
-	}
+	setAuth(req, reg)
 
 	resp, err := rm.httpClient.Do(req)
 	if err != nil {
@@ -237,6 +352,19 @@ func (rm *RegistryManager) fetchRemoteIndex(ctx context.Context, reg *Registry)
 	return &index, nil
 }
 
+// setAuth adds the authentication of a registry to a request.
+func setAuth(req *http.Request, reg *Registry) {
+	if reg.Auth == nil {
+		return
+	}
+	switch reg.Auth.Type {
+	case "token":
+		req.Header.Set("Authorization", "Bearer "+reg.Auth.Token)
+	case "basic":
+		req.SetBasicAuth(reg.Auth.Username, reg.Auth.Password)
+	}
+}
+
 // cacheIndex caches a registry index locally.
 func (rm *RegistryManager) cacheIndex(registryName string, index *RegistryIndex) error {
 	if rm.cacheDir == "" {
@@ -278,38 +406,85 @@ func (rm *RegistryManager) GetCachedIndex(registryName string) (*RegistryIndex,
 
 // FetchPattern fetches a specific pattern from a registry.
 func (rm *RegistryManager) FetchPattern(ctx context.Context, registryName, patternName, version string) (*Pattern, error) {
+	pattern, _, _, err := rm.fetchPattern(ctx, registryName, patternName, version, false)
+	return pattern, err
+}
+
+// FetchVerifiedPattern fetches a pattern and verifies its signature with the
+// signing policy of its registry. Patterns of registries without a policy are
+// returned unverified. The returned string describes the signer, empty for
+// unverified patterns.
+func (rm *RegistryManager) FetchVerifiedPattern(ctx context.Context, registryName, patternName, version string) (*Pattern, string, error) {
+	pattern, signer, _, err := rm.fetchPattern(ctx, registryName, patternName, version, true)
+	return pattern, signer, err
+}
+
+// fetchPattern fetches a pattern, verifying its signature when verify is
+// set, and returns it with its signer and the digest of its pattern.yaml.
+func (rm *RegistryManager) fetchPattern(ctx context.Context, registryName, patternName, version string, verify bool) (*Pattern, string, string, error) {
 	reg, err := rm.GetRegistry(registryName)
 	if err != nil {
-		return nil, err
+		return nil, "", "", err
+	}
+	data, err := rm.fetchPatternFile(ctx, reg, patternName, version, "pattern.yaml")
+	if err != nil {
+		return nil, "", "", err
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	if !verify || reg.Signing == nil {
+		pattern, err := parsePattern(data)
+		return pattern, "", digest, err
 	}
 
-	switch reg.Type {
-	case RegistryTypeLocal:
-		return rm.fetchLocalPattern(reg, patternName, version)
-	default:
-		return rm.fetchRemotePattern(ctx, reg, patternName, version)
+	signature, err := rm.fetchPatternFile(ctx, reg, patternName, version, SignatureFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", "", fmt.Errorf("pattern '%s' %s from registry '%s' is not signed (use --insecure-skip-verify to install it anyway)", patternName, version, registryName)
+	}
+	if err != nil {
+		return nil, "", "", err
 	}
+	var certificate []byte
+	if reg.Signing.Keyless() {
+		certificate, err = rm.fetchPatternFile(ctx, reg, patternName, version, CertificateFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, "", "", err
+		}
+	}
+	signer, err := reg.Signing.Verify(data, signature, certificate)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("signature verification failed for pattern '%s' %s: %w", patternName, version, err)
+	}
+	pattern, err := parsePattern(data)
+	return pattern, signer, digest, err
 }
 
-// fetchLocalPattern fetches a pattern from a local directory.
-func (rm *RegistryManager) fetchLocalPattern(reg *Registry, name, version string) (*Pattern, error) {
-	patternPath := filepath.Join(reg.URL, "patterns", name, version, "pattern.yaml")
-	return LoadPattern(patternPath)
-}
+// fetchPatternFile reads a file of a pattern version from a registry. Missing
+// files return an error wrapping os.ErrNotExist.
+func (rm *RegistryManager) fetchPatternFile(ctx context.Context, reg *Registry, name, version, file string) ([]byte, error) {
+	switch {
+	case isLocal(reg):
+		return readPatternFile(localPath(reg), name, version, file)
+	case reg.Type == RegistryTypeGit:
+		root, err := rm.gitRoot(ctx, reg)
+		if err != nil {
+			return nil, err
+		}
+		return readPatternFile(root, name, version, file)
+	case rm.offline:
+		return nil, fmt.Errorf("registry '%s' is remote and offline mode only uses local registries", reg.Name)
+	case reg.Type == RegistryTypeOCI:
+		return rm.fetchOCIFile(ctx, reg, name, version, file)
+	}
 
-// fetchRemotePattern fetches a pattern from a remote URL.
-func (rm *RegistryManager) fetchRemotePattern(ctx context.Context, reg *Registry, name, version string) (*Pattern, error) {
-	patternURL := fmt.Sprintf("%s/patterns/%s/%s/pattern.yaml",
-		strings.TrimSuffix(reg.URL, "/"), name, version)
+	fileURL := fmt.Sprintf("%s/patterns/%s/%s/%s",
+		strings.TrimSuffix(reg.URL, "/"), name, version, file)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, patternURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	if reg.Auth != nil && reg.Auth.Type == "token" {
-		req.Header.Set("Authorization", "Bearer "+reg.Auth.Token)
-	}
+	setAuth(req, reg)
 
 	resp, err := rm.httpClient.Do(req)
 	if err != nil {
@@ -317,7 +492,10 @@ func (rm *RegistryManager) fetchRemotePattern(ctx context.Context, reg *Registry
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch {
+	case resp.StatusCode == http.StatusNotFound && file != "pattern.yaml":
+		return nil, fmt.Errorf("%s not found: %w", file, os.ErrNotExist)
+	case resp.StatusCode != http.StatusOK:
 		return nil, fmt.Errorf("pattern not found: HTTP %d", resp.StatusCode)
 	}
 
@@ -325,7 +503,20 @@ func (rm *RegistryManager) fetchRemotePattern(ctx context.Context, reg *Registry
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	return data, nil
+}
 
+// readPatternFile reads a file of a pattern version in a registry directory.
+func readPatternFile(dir, name, version, file string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "patterns", name, version, file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return data, nil
+}
+
+// parsePattern parses and validates a pattern definition.
+func parsePattern(data []byte) (*Pattern, error) {
 	var pattern Pattern
 	if err := yaml.Unmarshal(data, &pattern); err != nil {
 		return nil, fmt.Errorf("failed to parse pattern: %w", err)
@@ -351,6 +542,10 @@ func (rm *RegistryManager) SearchPatterns(ctx context.Context, query string, opt
 		}
 
 		index, err := rm.FetchIndex(ctx, reg.Name)
+		if err != nil && rm.offline {
+			// Cached remote indexes list patterns that cannot be fetched.
+			continue
+		}
 		if err != nil {
 			// Try cached index
 			index, err = rm.GetCachedIndex(reg.Name)
@@ -487,7 +682,8 @@ func (rm *RegistryManager) GetPatternVersions(ctx context.Context, patternName s
 				var versions []PatternVersion
 				for _, v := range entry.Versions {
 					versions = append(versions, PatternVersion{
-						Version: v,
+						Version:   v,
+						Changelog: entry.Changelogs[v],
 					})
 				}
 				return versions, nil
@@ -517,6 +713,9 @@ func (rm *RegistryManager) FindPattern(ctx context.Context, name string) (*Patte
 		}
 	}
 
+	if rm.offline {
+		return nil, "", fmt.Errorf("pattern '%s' not found in any local registry (offline mode)", name)
+	}
 	return nil, "", fmt.Errorf("pattern '%s' not found in any registry", name)
 }
 
@@ -0,0 +1,198 @@
+package marketplace
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateData is the data component values, manifests and patches are
+// rendered with at install time.
+type TemplateData struct {
+	// Config is the install config merged with the defaults of the pattern.
+	Config    map[string]any
+	Pattern   PatternMetadata
+	Component string
+	Namespace string
+}
+
+// renderComponents renders the components of a pattern with the config.
+func renderComponents(pattern *Pattern, config map[string]any) ([]*Component, error) {
+	components := make([]*Component, 0, len(pattern.Spec.Components))
+	for idx := range pattern.Spec.Components {
+		comp, err := renderComponent(pattern, &pattern.Spec.Components[idx], config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render component '%s': %w", pattern.Spec.Components[idx].Name, err)
+		}
+		components = append(components, comp)
+	}
+	return components, nil
+}
+
+// renderComponent returns a copy of comp with the Go templates of its values,
+// manifest and patches rendered with the config. Templates can use the Sprig
+// functions; a reference to a missing config key is an error.
+func renderComponent(pattern *Pattern, comp *Component, config map[string]any) (*Component, error) {
+	data := TemplateData{
+		Config:    config,
+		Pattern:   pattern.Metadata,
+		Component: comp.Name,
+		Namespace: comp.Namespace,
+	}
+	rendered := *comp
+
+	values, err := renderValue(comp.Values, data, "values")
+	if err != nil {
+		return nil, err
+	}
+	if values != nil {
+		rendered.Values = values.(map[string]any)
+	}
+	if rendered.Manifest, err = renderTemplate("manifest", comp.Manifest, data); err != nil {
+		return nil, err
+	}
+	rendered.Patches = make([]Patch, len(comp.Patches))
+	for idx, patch := range comp.Patches {
+		rendered.Patches[idx] = patch
+		if rendered.Patches[idx].Patch, err = renderTemplate(fmt.Sprintf("patches[%d]", idx), patch.Patch, data); err != nil {
+			return nil, err
+		}
+	}
+	if len(comp.Patches) == 0 {
+		rendered.Patches = nil
+	}
+	if comp.Operator != nil {
+		spec := *comp.Operator
+		for name, field := range map[string]*string{
+			"operator.channel":             &spec.Channel,
+			"operator.installPlanApproval": &spec.InstallPlanApproval,
+			"operator.startingCSV":         &spec.StartingCSV,
+		} {
+			if *field, err = renderTemplate(name, *field, data); err != nil {
+				return nil, err
+			}
+		}
+		rendered.Operator = &spec
+	}
+	return &rendered, nil
+}
+
+// renderValue renders the templates in the strings of a values tree. A string
+// that is a single template action takes the YAML type of its output, so
+// "{{ .Config.replicas }}" renders to a number.
+func renderValue(value any, data TemplateData, path string) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if v == nil {
+			return nil, nil
+		}
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			rendered, err := renderValue(item, data, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = rendered
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for idx, item := range v {
+			rendered, err := renderValue(item, data, fmt.Sprintf("%s[%d]", path, idx))
+			if err != nil {
+				return nil, err
+			}
+			out[idx] = rendered
+		}
+		return out, nil
+	case string:
+		rendered, err := renderTemplate(path, v, data)
+		if err != nil || rendered == v {
+			return rendered, err
+		}
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+			var typed any
+			if yaml.Unmarshal([]byte(rendered), &typed) == nil && isScalar(typed) {
+				return typed, nil
+			}
+		}
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+func isScalar(value any) bool {
+	switch value.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return value != nil
+}
+
+// parseTemplates checks the syntax of the templates of a component.
+func parseTemplates(comp *Component) error {
+	texts := map[string]string{"manifest": comp.Manifest}
+	for idx, patch := range comp.Patches {
+		texts[fmt.Sprintf("patches[%d]", idx)] = patch.Patch
+	}
+	if comp.Operator != nil {
+		texts["operator.channel"] = comp.Operator.Channel
+		texts["operator.installPlanApproval"] = comp.Operator.InstallPlanApproval
+		texts["operator.startingCSV"] = comp.Operator.StartingCSV
+	}
+	var collect func(value any, path string)
+	collect = func(value any, path string) {
+		switch v := value.(type) {
+		case map[string]any:
+			for key, item := range v {
+				collect(item, path+"."+key)
+			}
+		case []any:
+			for idx, item := range v {
+				collect(item, fmt.Sprintf("%s[%d]", path, idx))
+			}
+		case string:
+			texts[path] = v
+		}
+	}
+	collect(comp.Values, "values")
+
+	names := make([]string, 0, len(texts))
+	for name := range texts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !strings.Contains(texts[name], "{{") {
+			continue
+		}
+		if _, err := template.New(name).Funcs(sprig.TxtFuncMap()).Parse(texts[name]); err != nil {
+			return fmt.Errorf("invalid template %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// renderTemplate renders a Go template. Text without actions is returned
+// unchanged.
+func renderTemplate(name, text string, data TemplateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
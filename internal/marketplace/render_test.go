@@ -0,0 +1,121 @@
+package marketplace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func templatedPattern() *Pattern {
+	pattern := NewPattern("ingress", "1.0.0", "Ingress controller")
+	pattern.Metadata.Category = "networking"
+	pattern.Spec.Config = map[string]ConfigItem{
+		"domain":   {Type: ConfigTypeString, Default: "example.com"},
+		"replicas": {Type: ConfigTypeInteger, Default: 2},
+	}
+	pattern.Spec.Components = []Component{
+		{
+			Name:       "nginx",
+			Type:       ComponentTypeHelm,
+			Repository: "https://kubernetes.github.io/ingress-nginx",
+			Chart:      "ingress-nginx",
+			Namespace:  "ingress",
+			Values: map[string]any{
+				"replicaCount": "{{ .Config.replicas }}",
+				"host":         "ingress.{{ .Config.domain }}",
+				"hosts":        []any{"{{ .Config.domain | upper }}", "static"},
+				"labels":       map[string]any{"app": "{{ .Pattern.Name }}-{{ .Component }}"},
+			},
+			Patches: []Patch{{
+				Patch:  "- op: replace\n  path: /spec/values/controller/ingressClass\n  value: {{ .Config.domain | replace \".\" \"-\" }}\n",
+				Target: &PatchTarget{Kind: "HelmRelease", Name: "nginx"},
+			}},
+		},
+		{
+			Name:     "certificate",
+			Type:     ComponentTypeManifest,
+			Manifest: "apiVersion: cert-manager.io/v1\nkind: Certificate\nmetadata:\n  name: wildcard\nspec:\n  dnsNames:\n    - \"*.{{ .Config.domain }}\"\n",
+		},
+	}
+	return pattern
+}
+
+func TestRenderComponent(t *testing.T) {
+	pattern := templatedPattern()
+	config := pattern.MergeConfigWithDefaults(map[string]any{"domain": "acme.io"})
+
+	comp, err := renderComponent(pattern, &pattern.Spec.Components[0], config)
+	if err != nil {
+		t.Fatalf("renderComponent() error = %v", err)
+	}
+	want := map[string]any{
+		"replicaCount": 2,
+		"host":         "ingress.acme.io",
+		"hosts":        []any{"ACME.IO", "static"},
+		"labels":       map[string]any{"app": "ingress-nginx"},
+	}
+	if !reflect.DeepEqual(comp.Values, want) {
+		t.Errorf("renderComponent() values = %#v, want %#v", comp.Values, want)
+	}
+	if !strings.Contains(comp.Patches[0].Patch, "value: acme-io") {
+		t.Errorf("renderComponent() patch = %q", comp.Patches[0].Patch)
+	}
+	// The pattern itself is left untemplated.
+	if pattern.Spec.Components[0].Values["host"] != "ingress.{{ .Config.domain }}" {
+		t.Errorf("renderComponent() modified the pattern: %v", pattern.Spec.Components[0].Values)
+	}
+
+	missing := Component{Name: "broken", Values: map[string]any{"host": "{{ .Config.hostname }}"}}
+	if _, err := renderComponent(pattern, &missing, config); err == nil || !strings.Contains(err.Error(), "hostname") {
+		t.Errorf("renderComponent() with a missing key error = %v", err)
+	}
+}
+
+func TestInstallRendersTemplates(t *testing.T) {
+	dir := t.TempDir()
+	addPattern(t, dir, templatedPattern())
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	installer := NewInstaller(rm, project, "flux", "kubernetes")
+	if _, err := installer.Install(context.Background(), "ingress", InstallOptions{Config: map[string]any{"domain": "acme.io"}}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	base := filepath.Join(project, "infrastructure", "networking", "ingress", "base")
+	for file, want := range map[string]string{
+		"nginx-release.yaml": "host: ingress.acme.io",
+		"certificate.yaml":   `- "*.acme.io"`,
+		"kustomization.yaml": "value: acme-io",
+	} {
+		data, err := os.ReadFile(filepath.Join(base, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s = %s, want %q", file, data, want)
+		}
+	}
+}
+
+func TestValidatePatternTemplates(t *testing.T) {
+	dir := t.TempDir()
+	pattern := templatedPattern()
+	pattern.Spec.Components[1].Manifest = "name: {{ .Config.domain"
+	if err := pattern.Save(filepath.Join(dir, "pattern.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := ValidatePattern(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(problems, "\n"), "component 'certificate': invalid template manifest") {
+		t.Errorf("ValidatePattern() = %v", problems)
+	}
+}
@@ -0,0 +1,441 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is the lockfile of the installed patterns, relative to the project.
+const LockFile = ".gitopsi/patterns.lock.yaml"
+
+// maxResolveSteps bounds the version changes of a resolution, which only
+// happen when a newly selected version drops or tightens constraints.
+const maxResolveSteps = 1000
+
+// Lock pins the versions of the installed patterns and their dependencies so
+// installs are reproducible.
+type Lock struct {
+	Version  string                   `yaml:"version" json:"version"`
+	Patterns map[string]LockedPattern `yaml:"patterns" json:"patterns"`
+}
+
+// LockedPattern is a locked pattern version.
+type LockedPattern struct {
+	Version  string `yaml:"version" json:"version"`
+	Registry string `yaml:"registry" json:"registry"`
+	// Digest is the sha256 digest of the pattern.yaml of the version.
+	Digest string `yaml:"digest,omitempty" json:"digest,omitempty"`
+	// Dependencies maps the dependencies of the pattern to their constraints.
+	Dependencies map[string]string `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+}
+
+// LoadLock reads a lockfile. A missing lockfile is empty.
+func LoadLock(path string) (*Lock, error) {
+	lock := &Lock{Version: "1.0", Patterns: map[string]LockedPattern{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.Patterns == nil {
+		lock.Patterns = map[string]LockedPattern{}
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile.
+func (l *Lock) Save(path string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create lockfile directory: %w", err)
+	}
+	header := []byte("# Generated by gitopsi. Do not edit; use gitopsi install and gitopsi patterns update.\n")
+	return os.WriteFile(path, append(header, data...), 0644)
+}
+
+// Requirement is a version constraint on a pattern.
+type Requirement struct {
+	// From is the requiring pattern as name@version, empty when the user
+	// requested the pattern.
+	From       string `yaml:"from,omitempty" json:"from,omitempty"`
+	Constraint string `yaml:"constraint,omitempty" json:"constraint,omitempty"`
+	Optional   bool   `yaml:"optional,omitempty" json:"optional,omitempty"`
+}
+
+func (r Requirement) String() string {
+	constraint := r.Constraint
+	if unconstrained(constraint) {
+		constraint = "any version"
+	}
+	if r.From == "" {
+		return constraint + " (requested)"
+	}
+	return fmt.Sprintf("%s (required by %s)", constraint, r.From)
+}
+
+// ConflictError reports requirements on a pattern that no version satisfies.
+type ConflictError struct {
+	Pattern      string
+	Requirements []Requirement
+	// Installed is the installed version of a dependency, which is kept
+	// rather than changed behind the back of the patterns using it.
+	Installed string
+}
+
+func (e *ConflictError) Error() string {
+	reqs := make([]string, len(e.Requirements))
+	for i, r := range e.Requirements {
+		reqs[i] = r.String()
+	}
+	if e.Installed != "" {
+		return fmt.Sprintf("installed version %s of pattern '%s' does not satisfy %s: update it first with gitopsi patterns update %s",
+			e.Installed, e.Pattern, strings.Join(reqs, ", "), e.Pattern)
+	}
+	return fmt.Sprintf("version conflict: no version of pattern '%s' satisfies %s", e.Pattern, strings.Join(reqs, ", "))
+}
+
+// ResolvedPattern is a pattern version selected by Resolve.
+type ResolvedPattern struct {
+	Name         string        `yaml:"name" json:"name"`
+	Version      string        `yaml:"version,omitempty" json:"version,omitempty"`
+	Registry     string        `yaml:"registry,omitempty" json:"registry,omitempty"`
+	Requirements []Requirement `yaml:"requirements,omitempty" json:"requirements,omitempty"`
+	// Installed is set when the version is already installed.
+	Installed bool `yaml:"installed,omitempty" json:"installed,omitempty"`
+	// Optional is set when every requirement on the pattern is optional.
+	Optional bool `yaml:"optional,omitempty" json:"optional,omitempty"`
+	// Missing is the error of an optional dependency that cannot be resolved.
+	Missing error `yaml:"-" json:"-"`
+
+	pattern *Pattern
+	signer  string
+	digest  string
+}
+
+// Constraint returns the constraints on the pattern.
+func (r *ResolvedPattern) Constraint() string {
+	var constraints []string
+	for _, req := range r.Requirements {
+		if !unconstrained(req.Constraint) && !slices.Contains(constraints, req.Constraint) {
+			constraints = append(constraints, req.Constraint)
+		}
+	}
+	return strings.Join(constraints, ", ")
+}
+
+// ResolveOptions configures Resolve.
+type ResolveOptions struct {
+	// SkipDeps resolves the pattern without its dependencies.
+	SkipDeps bool
+	// Upgrade ignores the installed and locked version of the pattern, so
+	// the newest version the installed patterns allow is selected.
+	Upgrade bool
+	// InsecureSkipVerify fetches patterns without verifying their signature.
+	InsecureSkipVerify bool
+}
+
+// Resolve selects a version of a pattern that satisfies constraint, and of
+// each of its dependencies a version that satisfies the constraints of every
+// pattern requiring it, including installed ones. Installed dependencies keep
+// their version and locked versions are preferred when they satisfy the
+// constraints. The patterns are returned in install order, dependencies
+// first and the pattern last.
+func (i *Installer) Resolve(ctx context.Context, patternName, constraint string, opts ResolveOptions) ([]ResolvedPattern, error) {
+	if err := i.LoadState(); err != nil {
+		return nil, err
+	}
+	lock, err := LoadLock(i.lockFile)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &resolver{installer: i, lock: lock, root: patternName, opts: opts, nodes: map[string]*resolveNode{}}
+	r.require(patternName, Requirement{Constraint: constraint})
+	for steps := 0; len(r.queue) > 0; steps++ {
+		if steps > maxResolveSteps {
+			return nil, fmt.Errorf("failed to resolve the dependencies of '%s': too many version changes", patternName)
+		}
+		name := r.queue[0]
+		r.queue = r.queue[1:]
+		if err := r.visit(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return r.order()
+}
+
+type resolveNode struct {
+	ResolvedPattern
+	entry   *PatternIndexEntry
+	findErr error
+}
+
+type resolver struct {
+	installer *Installer
+	lock      *Lock
+	root      string
+	opts      ResolveOptions
+	nodes     map[string]*resolveNode
+	queue     []string
+}
+
+// node returns the node of a pattern, seeding a new node with the
+// constraints of the installed patterns depending on it.
+func (r *resolver) node(name string) *resolveNode {
+	if n, ok := r.nodes[name]; ok {
+		return n
+	}
+	n := &resolveNode{ResolvedPattern: ResolvedPattern{Name: name}}
+	installed := make([]string, 0, len(r.installer.installed))
+	for other := range r.installer.installed {
+		installed = append(installed, other)
+	}
+	sort.Strings(installed)
+	for _, other := range installed {
+		// The installed version of the resolved pattern is being replaced.
+		if other == r.root || other == name {
+			continue
+		}
+		p := &r.installer.installed[other].Pattern
+		for _, dep := range p.Spec.Dependencies {
+			if dep.Name == name {
+				n.Requirements = append(n.Requirements, Requirement{
+					From:       other + "@" + p.Metadata.Version,
+					Constraint: dep.Version,
+					Optional:   dep.Optional,
+				})
+			}
+		}
+	}
+	r.nodes[name] = n
+	return n
+}
+
+// require adds a requirement on a pattern and queues it for resolution.
+func (r *resolver) require(name string, req Requirement) {
+	n := r.node(name)
+	n.Requirements = slices.DeleteFunc(n.Requirements, func(existing Requirement) bool {
+		return existing.From == req.From
+	})
+	n.Requirements = append(n.Requirements, req)
+	r.enqueue(name)
+}
+
+// drop removes the requirements of a pattern version that is no longer
+// selected.
+func (r *resolver) drop(name, from string) {
+	n, ok := r.nodes[name]
+	if !ok {
+		return
+	}
+	n.Requirements = slices.DeleteFunc(n.Requirements, func(existing Requirement) bool {
+		return existing.From == from
+	})
+	r.enqueue(name)
+}
+
+func (r *resolver) enqueue(name string) {
+	if !slices.Contains(r.queue, name) {
+		r.queue = append(r.queue, name)
+	}
+}
+
+// visit selects the version of a pattern and requires its dependencies.
+func (r *resolver) visit(ctx context.Context, name string) error {
+	i := r.installer
+	n := r.nodes[name]
+	if len(n.Requirements) == 0 {
+		// No selected pattern requires it anymore.
+		return nil
+	}
+	n.Optional = allOptional(n.Requirements)
+	n.Missing = nil
+
+	if n.entry == nil && n.findErr == nil {
+		n.entry, n.Registry, n.findErr = i.registry.FindPattern(ctx, name)
+	}
+	if n.findErr != nil {
+		return r.fail(n, n.findErr)
+	}
+	version, err := r.selectVersion(n)
+	if err != nil {
+		return r.fail(n, err)
+	}
+	if version == n.Version && n.pattern != nil {
+		return nil
+	}
+
+	if n.pattern != nil {
+		from := name + "@" + n.Version
+		for _, dep := range n.pattern.Spec.Dependencies {
+			r.drop(dep.Name, from)
+		}
+	}
+	n.Version = version
+	n.pattern, n.signer, n.digest, err = i.registry.fetchPattern(ctx, n.Registry, name, version, !r.opts.InsecureSkipVerify)
+	if err != nil {
+		n.pattern = nil
+		return r.fail(n, fmt.Errorf("failed to fetch pattern '%s' %s: %w", name, version, err))
+	}
+	if locked, ok := r.lock.Patterns[name]; ok && locked.Version == version && locked.Digest != "" && locked.Digest != n.digest &&
+		!(name == r.root && r.opts.Upgrade) {
+		return fmt.Errorf("pattern '%s' %s changed since it was locked in %s: review it and run gitopsi patterns update %s", name, version, LockFile, name)
+	}
+	if installed, ok := i.installed[name]; ok {
+		n.Installed = installed.Pattern.Metadata.Version == version
+	}
+
+	// Installed dependencies already have their own dependencies installed.
+	if (name == r.root && r.opts.SkipDeps) || (name != r.root && n.Installed) {
+		return nil
+	}
+	for _, dep := range n.pattern.Spec.Dependencies {
+		r.require(dep.Name, Requirement{From: name + "@" + version, Constraint: dep.Version, Optional: dep.Optional})
+	}
+	return nil
+}
+
+// fail records the error of an optional dependency, which is skipped, and
+// returns the error of any other pattern.
+func (r *resolver) fail(n *resolveNode, err error) error {
+	if n.Name != r.root && n.Optional {
+		n.Missing = err
+		return nil
+	}
+	if n.Name != r.root {
+		if _, ok := err.(*ConflictError); !ok {
+			return fmt.Errorf("failed to resolve dependency '%s': %w", n.Name, err)
+		}
+	}
+	return err
+}
+
+// selectVersion returns the version of a pattern to install: the installed
+// version of dependencies, else the locked version, else the newest version
+// satisfying every requirement.
+func (r *resolver) selectVersion(n *resolveNode) (string, error) {
+	if installed, ok := r.installer.installed[n.Name]; ok && n.Name != r.root {
+		version := installed.Pattern.Metadata.Version
+		if !satisfiesAll(version, n.Requirements) {
+			return "", &ConflictError{Pattern: n.Name, Requirements: n.Requirements, Installed: version}
+		}
+		return version, nil
+	}
+
+	if locked, ok := r.lock.Patterns[n.Name]; ok && !(n.Name == r.root && r.opts.Upgrade) &&
+		slices.Contains(n.entry.Versions, locked.Version) && satisfiesAll(locked.Version, n.Requirements) {
+		return locked.Version, nil
+	}
+
+	constrained := slices.ContainsFunc(n.Requirements, func(req Requirement) bool {
+		return !unconstrained(req.Constraint)
+	})
+	if !constrained && n.entry.Latest != "" {
+		return n.entry.Latest, nil
+	}
+	versions := slices.Clone(n.entry.Versions)
+	sort.SliceStable(versions, func(a, b int) bool {
+		return newerVersion(versions[a], versions[b])
+	})
+	for _, version := range versions {
+		if satisfiesAll(version, n.Requirements) {
+			return version, nil
+		}
+	}
+	return "", &ConflictError{Pattern: n.Name, Requirements: n.Requirements}
+}
+
+// order returns the resolved patterns with every pattern after its
+// dependencies.
+func (r *resolver) order() ([]ResolvedPattern, error) {
+	var plan []ResolvedPattern
+	state := map[string]int{} // 1 visiting, 2 done
+	var walk func(name string, path []string) error
+	walk = func(name string, path []string) error {
+		n := r.nodes[name]
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		if n.pattern != nil && n.Missing == nil && !(name != r.root && n.Installed) {
+			for _, dep := range n.pattern.Spec.Dependencies {
+				if d, ok := r.nodes[dep.Name]; ok && len(d.Requirements) > 0 {
+					if err := walk(dep.Name, append(path, name)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		state[name] = 2
+		plan = append(plan, n.ResolvedPattern)
+		return nil
+	}
+	if err := walk(r.root, nil); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func allOptional(reqs []Requirement) bool {
+	for _, req := range reqs {
+		if !req.Optional {
+			return false
+		}
+	}
+	return len(reqs) > 0
+}
+
+func unconstrained(constraint string) bool {
+	switch strings.TrimSpace(constraint) {
+	case "", "*", "latest":
+		return true
+	}
+	return false
+}
+
+func satisfiesAll(version string, reqs []Requirement) bool {
+	for _, req := range reqs {
+		if !Satisfies(version, req.Constraint) {
+			return false
+		}
+	}
+	return true
+}
+
+// Satisfies reports whether a pattern version satisfies a semver constraint
+// such as "^1.2", "~1.2.0" or ">=1.2 <2.0". Constraints that are not valid
+// semver only match the same version string.
+func Satisfies(version, constraint string) bool {
+	constraint = strings.TrimSpace(constraint)
+	if unconstrained(constraint) || constraint == version {
+		return true
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
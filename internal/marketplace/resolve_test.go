@@ -0,0 +1,239 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// constrainedRegistry returns a local registry with several cert-manager
+// versions and patterns requiring different ranges of them.
+func constrainedRegistry(t *testing.T) (string, *RegistryManager) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, version := range []string{"1.0.0", "1.3.0", "2.0.0", "2.1.0"} {
+		addPattern(t, dir, NewPattern("cert-manager", version, "Certificates"))
+	}
+	monitoring := NewPattern("monitoring", "1.0.0", "Monitoring stack")
+	monitoring.Spec.Dependencies = []Dependency{{Name: "cert-manager", Version: ">=1.2 <2.0"}}
+	addPattern(t, dir, monitoring)
+	ingress := NewPattern("ingress", "1.0.0", "Ingress controller")
+	ingress.Spec.Dependencies = []Dependency{{Name: "cert-manager", Version: "^2.1"}}
+	addPattern(t, dir, ingress)
+	logging := NewPattern("logging", "1.0.0", "Logging stack")
+	logging.Spec.Dependencies = []Dependency{{Name: "cert-manager", Version: "~1.3"}, {Name: "tracing", Optional: true}}
+	addPattern(t, dir, logging)
+	stack := NewPattern("stack", "1.0.0", "Platform stack")
+	stack.Spec.Dependencies = []Dependency{{Name: "monitoring"}, {Name: "ingress"}}
+	addPattern(t, dir, stack)
+
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	return dir, rm
+}
+
+// addPattern saves a pattern version to a local registry and regenerates its
+// index.
+func addPattern(t *testing.T, dir string, pattern *Pattern) {
+	t.Helper()
+	path := filepath.Join(dir, "patterns", pattern.Metadata.Name, pattern.Metadata.Version, "pattern.yaml")
+	if err := pattern.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateIndex(filepath.Join(dir, "patterns"), filepath.Join(dir, "index.yaml")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func planVersions(plan []ResolvedPattern) string {
+	var versions []string
+	for _, p := range plan {
+		versions = append(versions, p.Name+"@"+p.Version)
+	}
+	return strings.Join(versions, " ")
+}
+
+func TestResolve(t *testing.T) {
+	_, rm := constrainedRegistry(t)
+	installer := NewInstaller(rm, t.TempDir(), "argocd", "kubernetes")
+	ctx := context.Background()
+
+	tests := []struct {
+		pattern, constraint, want string
+	}{
+		{"monitoring", "", "cert-manager@1.3.0 monitoring@1.0.0"},
+		{"ingress", "", "cert-manager@2.1.0 ingress@1.0.0"},
+		{"cert-manager", "^1.0", "cert-manager@1.3.0"},
+		{"cert-manager", "1.0.0", "cert-manager@1.0.0"},
+		{"logging", "", "cert-manager@1.3.0 tracing@ logging@1.0.0"},
+	}
+	for _, tt := range tests {
+		plan, err := installer.Resolve(ctx, tt.pattern, tt.constraint, ResolveOptions{})
+		if err != nil {
+			t.Errorf("Resolve(%s, %q) error = %v", tt.pattern, tt.constraint, err)
+			continue
+		}
+		if got := planVersions(plan); got != tt.want {
+			t.Errorf("Resolve(%s, %q) = %s, want %s", tt.pattern, tt.constraint, got, tt.want)
+		}
+	}
+
+	plan, _ := installer.Resolve(ctx, "logging", "", ResolveOptions{})
+	if tracing := plan[1]; !tracing.Optional || tracing.Missing == nil {
+		t.Errorf("Resolve() optional tracing = %+v, want missing", tracing)
+	}
+
+	_, err := installer.Resolve(ctx, "stack", "", ResolveOptions{})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Pattern != "cert-manager" {
+		t.Fatalf("Resolve(stack) error = %v, want a cert-manager conflict", err)
+	}
+	for _, want := range []string{">=1.2 <2.0 (required by monitoring@1.0.0)", "^2.1 (required by ingress@1.0.0)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Resolve(stack) error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestInstallResolvesConstraints(t *testing.T) {
+	dir, rm := constrainedRegistry(t)
+	project := t.TempDir()
+	installer := NewInstaller(rm, project, "argocd", "kubernetes")
+	ctx := context.Background()
+
+	result, err := installer.Install(ctx, "monitoring", InstallOptions{})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if len(result.Dependencies) != 1 || result.Dependencies[0].Version != "1.3.0" || result.Dependencies[0].Constraint != ">=1.2 <2.0" {
+		t.Errorf("Install() dependencies = %+v", result.Dependencies)
+	}
+
+	lock, err := LoadLock(filepath.Join(project, LockFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Patterns["cert-manager"].Version != "1.3.0" || lock.Patterns["monitoring"].Dependencies["cert-manager"] != ">=1.2 <2.0" {
+		t.Errorf("lockfile = %+v", lock.Patterns)
+	}
+	if !strings.HasPrefix(lock.Patterns["monitoring"].Digest, "sha256:") {
+		t.Errorf("lockfile digest = %q", lock.Patterns["monitoring"].Digest)
+	}
+
+	// The installed cert-manager cannot move to the range ingress needs.
+	_, err = installer.Install(ctx, "ingress", InstallOptions{DryRun: true})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Installed != "1.3.0" || !strings.Contains(err.Error(), "gitopsi patterns update cert-manager") {
+		t.Errorf("Install(ingress) error = %v, want a conflict with the installed version", err)
+	}
+
+	// Updates stay within the constraints of the installed patterns.
+	addPattern(t, dir, NewPattern("cert-manager", "1.4.0", "Certificates"))
+	result, err = installer.Update(ctx, "cert-manager", UpdateOptions{})
+	if err != nil || result.Version != "1.4.0" {
+		t.Fatalf("Update() = %+v, %v, want 1.4.0", result, err)
+	}
+	if _, err := installer.Update(ctx, "cert-manager", UpdateOptions{Version: "2.1.0"}); err == nil {
+		t.Error("Update() to 2.1.0 should conflict with monitoring")
+	}
+
+	if _, err := installer.Uninstall(ctx, "monitoring", UninstallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	lock, _ = LoadLock(filepath.Join(project, LockFile))
+	if _, ok := lock.Patterns["monitoring"]; ok {
+		t.Error("Uninstall() should remove the pattern from the lockfile")
+	}
+}
+
+func TestInstallUsesLock(t *testing.T) {
+	dir, rm := constrainedRegistry(t)
+	project := t.TempDir()
+	ctx := context.Background()
+	if _, err := NewInstaller(rm, project, "argocd", "kubernetes").Install(ctx, "monitoring", InstallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	addPattern(t, dir, NewPattern("cert-manager", "1.4.0", "Certificates"))
+
+	// A checkout with the lockfile but no installed patterns gets the locked
+	// versions rather than the newest ones.
+	checkout := t.TempDir()
+	data, err := os.ReadFile(filepath.Join(project, LockFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(checkout, ".gitopsi"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(checkout, LockFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	installer := NewInstaller(rm, checkout, "argocd", "kubernetes")
+	plan, err := installer.Resolve(ctx, "monitoring", "", ResolveOptions{})
+	if err != nil || planVersions(plan) != "cert-manager@1.3.0 monitoring@1.0.0" {
+		t.Fatalf("Resolve() with lockfile = %s, %v", planVersions(plan), err)
+	}
+	if err := os.Remove(filepath.Join(checkout, LockFile)); err != nil {
+		t.Fatal(err)
+	}
+	if plan, _ := installer.Resolve(ctx, "monitoring", "", ResolveOptions{}); planVersions(plan) != "cert-manager@1.4.0 monitoring@1.0.0" {
+		t.Errorf("Resolve() without lockfile = %s", planVersions(plan))
+	}
+
+	// Locked versions whose content changed are refused.
+	modified := NewPattern("cert-manager", "1.3.0", "Certificates, modified")
+	addPattern(t, dir, modified)
+	if _, err := NewInstaller(rm, project, "argocd", "kubernetes").Resolve(ctx, "logging", "", ResolveOptions{}); err == nil || !strings.Contains(err.Error(), "changed since it was locked") {
+		t.Errorf("Resolve() of a modified locked version error = %v", err)
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"1.3.0", "", true},
+		{"1.3.0", "latest", true},
+		{"1.3.0", ">=1.2 <2.0", true},
+		{"2.0.0", ">=1.2 <2.0", false},
+		{"1.3.0", ">=1.2, <2.0", true},
+		{"2.1.5", "^2.1", true},
+		{"1.3.9", "~1.3", true},
+		{"1.4.0", "~1.3", false},
+		{"1.3.0", "1.3.0", true},
+		{"1.3.1", "1.3.0", false},
+		{"nightly", "nightly", true},
+		{"nightly", "^1.0", false},
+	}
+	for _, tt := range tests {
+		if got := Satisfies(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestInstallSyncWaves(t *testing.T) {
+	_, rm := constrainedRegistry(t)
+	project := t.TempDir()
+	installer := NewInstaller(rm, project, "argocd", "kubernetes")
+	if _, err := installer.Install(context.Background(), "monitoring", InstallOptions{Environments: []string{"dev"}}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	for name, wave := range map[string]string{"cert-manager": "0", "monitoring": "1"} {
+		data, err := os.ReadFile(filepath.Join(project, "argocd", "applications", name+"-dev.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "argocd.argoproj.io/sync-wave: \"" + wave + "\""; !strings.Contains(string(data), want) {
+			t.Errorf("%s Application = %s, want %s", name, data, want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package marketplace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxUploadSize is the default size limit of uploaded pattern archives.
+const DefaultMaxUploadSize = 32 << 20
+
+// downloadsFile holds the download counters of a served registry directory.
+const downloadsFile = "downloads.yaml"
+
+// ServerOptions configures a marketplace server.
+type ServerOptions struct {
+	// Dir is the registry directory: index.yaml and
+	// patterns/<name>/<version>/.
+	Dir string
+	// Token is required as a bearer token by every request when set.
+	Token string
+	// ReadOnly rejects uploads.
+	ReadOnly bool
+	// URL is the external URL of the server, used in upload responses. The
+	// URL of the request is used when empty.
+	URL string
+	// MaxUploadSize limits uploaded archives; DefaultMaxUploadSize when 0.
+	MaxUploadSize int64
+}
+
+// Server serves a registry directory as an HTTP pattern registry that the
+// CLI consumes as a private registry:
+//
+//	GET  /index.yaml                        index with download counters
+//	GET  /patterns/<name>/<version>/<file>  pattern files; pattern.yaml counts a download
+//	GET  /api/v1/search                     search: q, category, tag and limit parameters
+//	GET  /api/v1/patterns/<name>            index entry of a pattern
+//	GET  /api/v1/stats                      download counters
+//	POST /api/v1/patterns                   publish (multipart: name, version, changelog, archive)
+//	GET  /healthz                           liveness, without authentication
+type Server struct {
+	opts      ServerOptions
+	mu        sync.Mutex
+	downloads map[string]int
+}
+
+// NewServer creates a marketplace server for a registry directory, creating
+// its index when missing.
+func NewServer(opts ServerOptions) (*Server, error) {
+	if opts.MaxUploadSize == 0 {
+		opts.MaxUploadSize = DefaultMaxUploadSize
+	}
+	patternsDir := filepath.Join(opts.Dir, "patterns")
+	if err := os.MkdirAll(patternsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Dir, "index.yaml")); os.IsNotExist(err) {
+		if err := GenerateIndex(patternsDir, filepath.Join(opts.Dir, "index.yaml")); err != nil {
+			return nil, fmt.Errorf("failed to generate index: %w", err)
+		}
+	}
+
+	s := &Server{opts: opts, downloads: map[string]int{}}
+	data, err := os.ReadFile(filepath.Join(opts.Dir, downloadsFile))
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &s.downloads); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", downloadsFile, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read %s: %w", downloadsFile, err)
+	}
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("GET /index.yaml", s.authorize(http.HandlerFunc(s.serveIndex)))
+	mux.Handle("GET /patterns/{name}/{version}/{file...}", s.authorize(http.HandlerFunc(s.serveFile)))
+	mux.Handle("GET /api/v1/search", s.authorize(http.HandlerFunc(s.serveSearch)))
+	mux.Handle("GET /api/v1/patterns/{name}", s.authorize(http.HandlerFunc(s.servePattern)))
+	mux.Handle("GET /api/v1/stats", s.authorize(http.HandlerFunc(s.serveStats)))
+	mux.Handle("POST /api/v1/patterns", s.authorize(http.HandlerFunc(s.upload)))
+	return mux
+}
+
+// authorize requires the bearer token of the server.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gitopsi"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// index returns the registry index with the download counters.
+func (s *Server) index() (*RegistryIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index, err := readIndex(s.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range index.Patterns {
+		index.Patterns[idx].Downloads += s.downloads[index.Patterns[idx].Name]
+	}
+	return index, nil
+}
+
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	index, err := s.index()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(data)
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	name, version, file := r.PathValue("name"), r.PathValue("version"), r.PathValue("file")
+	if !validPathSegment(name) || !validPathSegment(version) || file == "" || path.Clean(file) != file || strings.HasPrefix(file, "../") {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := readPatternFile(s.opts.Dir, name, version, filepath.FromSlash(file))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if file == "pattern.yaml" {
+		if err := s.countDownload(name); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if strings.HasSuffix(file, ".yaml") {
+		w.Header().Set("Content-Type", "application/yaml")
+	}
+	_, _ = w.Write(data)
+}
+
+// countDownload increments and saves the download counter of a pattern.
+func (s *Server) countDownload(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloads[name]++
+	data, err := yaml.Marshal(s.downloads)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.opts.Dir, downloadsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save download counters: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) {
+	index, err := s.index()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	opts := SearchOptions{Category: r.URL.Query().Get("category"), Tags: r.URL.Query()["tag"]}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if opts.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results := []PatternSearchResult{}
+	for _, entry := range index.Patterns {
+		if matchesSearch(entry, query, opts) {
+			results = append(results, PatternSearchResult{
+				Name:        entry.Name,
+				Version:     entry.Latest,
+				Description: entry.Description,
+				Category:    entry.Category,
+				Tags:        entry.Tags,
+				Rating:      entry.Rating,
+				Downloads:   entry.Downloads,
+			})
+		}
+	}
+	sortSearchResults(results, query)
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) servePattern(w http.ResponseWriter, r *http.Request) {
+	index, err := s.index()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, entry := range index.Patterns {
+		if entry.Name == r.PathValue("name") {
+			writeJSON(w, http.StatusOK, entry)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Server) serveStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	downloads := make(map[string]int, len(s.downloads))
+	total := 0
+	for name, count := range s.downloads {
+		downloads[name] = count
+		total += count
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"downloads": downloads, "total": total})
+}
+
+// upload publishes an uploaded pattern version to the registry directory.
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	if s.opts.ReadOnly {
+		http.Error(w, "the marketplace is read-only", http.StatusForbidden)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxUploadSize)
+	if err := r.ParseMultipartForm(s.opts.MaxUploadSize); err != nil {
+		http.Error(w, fmt.Sprintf("invalid upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	archive, _, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "missing archive", http.StatusBadRequest)
+		return
+	}
+	defer archive.Close()
+	files, err := extractPattern(archive)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var pattern *Pattern
+	for _, f := range files {
+		if f.name == "pattern.yaml" {
+			pattern, err = parsePattern(f.data)
+		}
+	}
+	switch {
+	case pattern == nil && err == nil:
+		http.Error(w, "the archive has no pattern.yaml", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("invalid pattern: %v", err), http.StatusBadRequest)
+		return
+	}
+	name, version := pattern.Metadata.Name, pattern.Metadata.Version
+	if r.FormValue("name") != name || r.FormValue("version") != version {
+		http.Error(w, fmt.Sprintf("the archive is pattern '%s' %s", name, version), http.StatusBadRequest)
+		return
+	}
+	if !validPathSegment(name) {
+		http.Error(w, fmt.Sprintf("invalid pattern name %q", name), http.StatusBadRequest)
+		return
+	}
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		http.Error(w, fmt.Sprintf("pattern version %q is not a semantic version", version), http.StatusBadRequest)
+		return
+	}
+	if changelog := strings.TrimSpace(r.FormValue("changelog")); changelog != "" && !hasPatternFile(files, ChangelogFile) {
+		files = setPatternFile(files, ChangelogFile, []byte(fmt.Sprintf("## %s\n\n%s\n", version, changelog)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(filepath.Join(s.opts.Dir, "patterns", name, version)); err == nil {
+		http.Error(w, fmt.Sprintf("pattern '%s' %s is already published", name, version), http.StatusConflict)
+		return
+	}
+	result := &PublishResult{Name: name, Version: version}
+	if err := publishDir(s.opts.Dir, pattern, files, result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	base := strings.TrimSuffix(s.opts.URL, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"url": fmt.Sprintf("%s/patterns/%s/%s", base, name, version)})
+}
+
+// extractPattern reads the files of a pattern archive.
+func extractPattern(archive io.Reader) ([]patternFile, error) {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var files []patternFile
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid archive: file %q is outside the pattern", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		files = append(files, patternFile{name: name, data: data})
+	}
+	return files, nil
+}
+
+func hasPatternFile(files []patternFile, name string) bool {
+	for _, f := range files {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validPathSegment reports whether a pattern name or version is a single
+// path segment.
+func validPathSegment(segment string) bool {
+	return segment != "" && segment != "." && segment != ".." && !strings.ContainsAny(segment, `/\`)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,132 @@
+package marketplace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func marketplaceServer(t *testing.T, opts ServerOptions) (*httptest.Server, *RegistryManager) {
+	t.Helper()
+	s, err := NewServer(opts)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+
+	rm := NewRegistryManager(t.TempDir())
+	reg := Registry{Name: "internal", Type: RegistryTypePrivate, URL: server.URL, Enabled: true}
+	if opts.Token != "" {
+		reg.Auth = &RegistryAuth{Type: "token", Token: opts.Token}
+	}
+	if err := rm.AddRegistry(reg); err != nil {
+		t.Fatal(err)
+	}
+	return server, rm
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	addPattern(t, dir, templatedPattern())
+	server, rm := marketplaceServer(t, ServerOptions{Dir: dir, Token: "secret"})
+	ctx := context.Background()
+
+	if _, err := rm.FetchPattern(ctx, "internal", "ingress", "1.0.0"); err != nil {
+		t.Fatalf("FetchPattern() error = %v", err)
+	}
+	index, err := rm.FetchIndex(ctx, "internal")
+	if err != nil || len(index.Patterns) != 1 || index.Patterns[0].Downloads != 1 {
+		t.Fatalf("FetchIndex() = %+v, %v", index, err)
+	}
+
+	results, err := rm.SearchPatterns(ctx, "ingress", SearchOptions{})
+	if err != nil || len(results) != 1 {
+		t.Errorf("SearchPatterns() = %v, %v", results, err)
+	}
+
+	get := func(path string, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	var search []PatternSearchResult
+	if err := json.NewDecoder(get("/api/v1/search?q=ingress&category=networking", "secret").Body).Decode(&search); err != nil || len(search) != 1 || search[0].Downloads != 1 {
+		t.Errorf("search = %+v, %v", search, err)
+	}
+	if err := json.NewDecoder(get("/api/v1/search?category=security", "secret").Body).Decode(&search); err != nil || len(search) != 0 {
+		t.Errorf("search of another category = %+v, %v", search, err)
+	}
+	var stats struct {
+		Downloads map[string]int `json:"downloads"`
+		Total     int            `json:"total"`
+	}
+	if err := json.NewDecoder(get("/api/v1/stats", "secret").Body).Decode(&stats); err != nil || stats.Downloads["ingress"] != 1 || stats.Total != 1 {
+		t.Errorf("stats = %+v, %v", stats, err)
+	}
+	if resp := get("/api/v1/patterns/ingress", "secret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("pattern entry status = %d", resp.StatusCode)
+	}
+	if resp := get("/api/v1/patterns/missing", "secret"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing pattern status = %d", resp.StatusCode)
+	}
+	for _, path := range []string{"/index.yaml", "/patterns/ingress/1.0.0/pattern.yaml"} {
+		if resp := get(path, "wrong"); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s with a wrong token status = %d", path, resp.StatusCode)
+		}
+	}
+	if resp := get("/healthz", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("healthz status = %d", resp.StatusCode)
+	}
+	if resp := get("/patterns/ingress/1.0.0/..%2f..%2findex.yaml", "secret"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("path traversal status = %d", resp.StatusCode)
+	}
+
+	// The counters survive restarts.
+	if s, err := NewServer(ServerOptions{Dir: dir}); err != nil || s.downloads["ingress"] != 1 {
+		t.Errorf("NewServer() downloads = %v, %v", s, err)
+	}
+}
+
+func TestServerPublish(t *testing.T) {
+	dir := t.TempDir()
+	_, rm := marketplaceServer(t, ServerOptions{Dir: dir, Token: "secret", URL: "https://patterns.example.com/"})
+	ctx := context.Background()
+
+	result, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "internal", PublishOptions{})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if result.Location != "https://patterns.example.com/patterns/monitoring/1.0.0" {
+		t.Errorf("Publish() location = %s", result.Location)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "patterns", "monitoring", "1.0.0", "templates", "app.yaml")); err != nil {
+		t.Errorf("Publish() did not store the pattern files: %v", err)
+	}
+	index, err := rm.FetchIndex(ctx, "internal")
+	if err != nil || len(index.Patterns) != 1 || index.Patterns[0].Changelogs["1.0.0"] != "- Add alerts" {
+		t.Fatalf("FetchIndex() = %+v, %v", index, err)
+	}
+	if _, err := rm.Publish(ctx, patternSource(t, "1.0.0"), "internal", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "already published") {
+		t.Errorf("Publish() of a published version error = %v", err)
+	}
+
+	_, readOnly := marketplaceServer(t, ServerOptions{Dir: dir, Token: "secret", ReadOnly: true})
+	if _, err := readOnly.Publish(ctx, patternSource(t, "1.1.0"), "internal", PublishOptions{}); err == nil || !strings.Contains(err.Error(), "denied the upload") {
+		t.Errorf("Publish() to a read-only server error = %v", err)
+	}
+}
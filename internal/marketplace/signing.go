@@ -0,0 +1,374 @@
+package marketplace
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Pattern signatures are cosign blob signatures of pattern.yaml, served next
+// to it by registries:
+//
+//	cosign sign-blob pattern.yaml --output-signature pattern.yaml.sig --output-certificate pattern.yaml.pem
+const (
+	SignatureFile   = "pattern.yaml.sig"
+	CertificateFile = "pattern.yaml.pem"
+)
+
+// Fulcio certificate extensions holding the OIDC issuer of keyless signatures.
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// SignaturePolicy configures how the patterns of a registry are verified:
+// with a cosign public key, or keyless with the identity of the Fulcio
+// certificate that signed them.
+type SignaturePolicy struct {
+	// PublicKey is a PEM public key, or the path of one.
+	PublicKey string `yaml:"publicKey,omitempty" json:"publicKey,omitempty"`
+	// Identity or IdentityRegexp match the subject of keyless certificates,
+	// e.g. https://github.com/org/patterns/.github/workflows/release.yaml@refs/heads/main.
+	Identity       string `yaml:"identity,omitempty" json:"identity,omitempty"`
+	IdentityRegexp string `yaml:"identityRegexp,omitempty" json:"identityRegexp,omitempty"`
+	// Issuer is the OIDC issuer of keyless certificates.
+	Issuer string `yaml:"issuer,omitempty" json:"issuer,omitempty"`
+	// Roots is a PEM file of the Fulcio root and intermediate certificates.
+	// It defaults to $SIGSTORE_ROOT_FILE, then to the Sigstore TUF targets
+	// cached by cosign initialize in ~/.sigstore/root/targets.
+	Roots string `yaml:"roots,omitempty" json:"roots,omitempty"`
+}
+
+// Keyless reports whether the policy verifies Fulcio certificates.
+func (p *SignaturePolicy) Keyless() bool {
+	return p.PublicKey == ""
+}
+
+// Verify verifies the base64 cosign signature of content and returns a
+// description of the signer. certificate is the Fulcio certificate of keyless
+// signatures. The Rekor transparency log is not consulted: keyless
+// certificates are checked against the Fulcio roots as of their issuance.
+func (p *SignaturePolicy) Verify(content, signature, certificate []byte) (string, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	if !p.Keyless() {
+		key, err := parsePublicKey(p.PublicKey)
+		if err != nil {
+			return "", err
+		}
+		if err := verifySignature(key, content, sig); err != nil {
+			return "", err
+		}
+		return "public key", nil
+	}
+
+	if len(certificate) == 0 {
+		return "", fmt.Errorf("keyless signature has no certificate (%s)", CertificateFile)
+	}
+	cert, err := parseCertificate(certificate)
+	if err != nil {
+		return "", err
+	}
+	roots, intermediates, err := fulcioRoots(p.Roots)
+	if err != nil {
+		return "", err
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   cert.NotBefore,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return "", fmt.Errorf("certificate is not issued by Fulcio: %w", err)
+	}
+
+	identity, issuer := certificateIdentity(cert), certificateIssuer(cert)
+	if p.Issuer != "" && issuer != p.Issuer {
+		return "", fmt.Errorf("certificate issuer %q does not match %q", issuer, p.Issuer)
+	}
+	if err := p.matchIdentity(identity); err != nil {
+		return "", err
+	}
+	if err := verifySignature(cert.PublicKey, content, sig); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s)", identity, issuer), nil
+}
+
+func (p *SignaturePolicy) matchIdentity(identity string) error {
+	switch {
+	case p.Identity != "":
+		if identity != p.Identity {
+			return fmt.Errorf("certificate identity %q does not match %q", identity, p.Identity)
+		}
+	case p.IdentityRegexp != "":
+		re, err := regexp.Compile(p.IdentityRegexp)
+		if err != nil {
+			return fmt.Errorf("invalid identity regexp: %w", err)
+		}
+		if !re.MatchString(identity) {
+			return fmt.Errorf("certificate identity %q does not match %s", identity, p.IdentityRegexp)
+		}
+	default:
+		return fmt.Errorf("keyless verification requires an identity or identityRegexp")
+	}
+	return nil
+}
+
+// verifySignature verifies sig over content the way cosign signs blobs:
+// ECDSA and RSA sign the SHA-256 digest, Ed25519 the content itself.
+func verifySignature(key crypto.PublicKey, content, sig []byte) error {
+	digest := sha256.Sum256(content)
+	var ok bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil || rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, content, sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// SignPattern signs the pattern.yaml of patternDir with a cosign private key
+// and writes the signature to pattern.yaml.sig. Keys encrypted by cosign
+// generate-key-pair are decrypted with password.
+func SignPattern(patternDir, keyPath string, password []byte) (string, error) {
+	content, err := os.ReadFile(filepath.Join(patternDir, "pattern.yaml"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read pattern: %w", err)
+	}
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signing key: %w", err)
+	}
+	signer, err := parsePrivateKey(keyData, password)
+	if err != nil {
+		return "", err
+	}
+
+	var sig []byte
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		sig, err = signer.Sign(rand.Reader, content, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(content)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign pattern: %w", err)
+	}
+
+	path := filepath.Join(patternDir, SignatureFile)
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(sig)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write signature: %w", err)
+	}
+	return path, nil
+}
+
+// parsePrivateKey parses a PKCS#8, EC or PKCS#1 PEM private key, or a cosign
+// key encrypted with scrypt and NaCl secretbox.
+func parsePrivateKey(data, password []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+	der := block.Bytes
+	switch block.Type {
+	case "ENCRYPTED SIGSTORE PRIVATE KEY", "ENCRYPTED COSIGN PRIVATE KEY":
+		var err error
+		if der, err = decryptCosignKey(block.Bytes, password); err != nil {
+			return nil, err
+		}
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(der)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(der)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	return signer, nil
+}
+
+// decryptCosignKey decrypts the JSON envelope of cosign private keys.
+func decryptCosignKey(data, password []byte) ([]byte, error) {
+	var envelope struct {
+		KDF struct {
+			Name   string `json:"name"`
+			Params struct {
+				N int `json:"N"`
+				R int `json:"r"`
+				P int `json:"p"`
+			} `json:"params"`
+			Salt []byte `json:"salt"`
+		} `json:"kdf"`
+		Cipher struct {
+			Name  string `json:"name"`
+			Nonce []byte `json:"nonce"`
+		} `json:"cipher"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted signing key: %w", err)
+	}
+	if envelope.KDF.Name != "scrypt" || envelope.Cipher.Name != "nacl/secretbox" || len(envelope.Cipher.Nonce) != 24 {
+		return nil, fmt.Errorf("unsupported signing key encryption %s/%s", envelope.KDF.Name, envelope.Cipher.Name)
+	}
+	key, err := scrypt.Key(password, envelope.KDF.Salt, envelope.KDF.Params.N, envelope.KDF.Params.R, envelope.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive signing key password: %w", err)
+	}
+	var k [32]byte
+	var nonce [24]byte
+	copy(k[:], key)
+	copy(nonce[:], envelope.Cipher.Nonce)
+	der, ok := secretbox.Open(nil, envelope.Ciphertext, &nonce, &k)
+	if !ok {
+		return nil, fmt.Errorf("failed to decrypt signing key: wrong password")
+	}
+	return der, nil
+}
+
+// parsePublicKey parses a PEM public key given inline or as a path.
+func parsePublicKey(key string) (crypto.PublicKey, error) {
+	data := []byte(key)
+	if !strings.Contains(key, "-----BEGIN") {
+		var err error
+		if data, err = os.ReadFile(key); err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key is not PEM encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return pub, nil
+}
+
+// parseCertificate parses a PEM certificate, which cosign may also write
+// base64 encoded.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("certificate is not PEM encoded")
+		}
+		data = decoded
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// fulcioRoots loads the Fulcio roots and intermediates of path or of the
+// default locations.
+func fulcioRoots(path string) (*x509.CertPool, *x509.CertPool, error) {
+	var files []string
+	switch {
+	case path != "":
+		files = []string{path}
+	case os.Getenv("SIGSTORE_ROOT_FILE") != "":
+		files = []string{os.Getenv("SIGSTORE_ROOT_FILE")}
+	default:
+		home, _ := os.UserHomeDir()
+		targets := filepath.Join(home, ".sigstore", "root", "targets")
+		files = []string{filepath.Join(targets, "fulcio_v1.crt.pem"), filepath.Join(targets, "fulcio_intermediate_v1.crt.pem")}
+	}
+
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	found := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) && path == "" {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read Fulcio roots: %w", err)
+		}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse Fulcio certificate in %s: %w", file, err)
+			}
+			if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+				roots.AddCert(cert)
+				found = true
+			} else {
+				intermediates.AddCert(cert)
+			}
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("no Fulcio root certificate found: run cosign initialize or set the roots of the signing policy")
+	}
+	return roots, intermediates, nil
+}
+
+// certificateIdentity returns the subject of a Fulcio certificate: the email
+// of a user or the URI of a workload such as a CI workflow.
+func certificateIdentity(cert *x509.Certificate) string {
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return ""
+}
+
+// certificateIssuer returns the OIDC issuer recorded by Fulcio.
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package marketplace
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// signedRegistry returns a local registry holding the monitoring pattern and
+// the PEM public key of the ECDSA key written to keyPath.
+func signedRegistry(t *testing.T) (dir, keyPath, publicKey string) {
+	t.Helper()
+	dir = t.TempDir()
+	pattern := NewPattern("monitoring", "1.0.0", "Monitoring stack")
+	if err := pattern.Save(filepath.Join(dir, "patterns", "monitoring", "1.0.0", "pattern.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	index := "patterns:\n  - name: monitoring\n    versions: [1.0.0]\n    latest: 1.0.0\n"
+	if err := os.WriteFile(filepath.Join(dir, "index.yaml"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyPath = filepath.Join(t.TempDir(), "cosign.key")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return dir, keyPath, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+}
+
+func TestFetchVerifiedPattern(t *testing.T) {
+	dir, keyPath, publicKey := signedRegistry(t)
+	rm := NewRegistryManager(t.TempDir())
+	if err := rm.AddRegistry(Registry{Name: "signed", Type: RegistryTypeLocal, URL: dir, Enabled: true, Signing: &SignaturePolicy{PublicKey: publicKey}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, _, err := rm.FetchVerifiedPattern(ctx, "signed", "monitoring", "1.0.0"); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Fatalf("FetchVerifiedPattern() of an unsigned pattern error = %v", err)
+	}
+
+	patternDir := filepath.Join(dir, "patterns", "monitoring", "1.0.0")
+	if _, err := SignPattern(patternDir, keyPath, nil); err != nil {
+		t.Fatalf("SignPattern() error = %v", err)
+	}
+	pattern, signer, err := rm.FetchVerifiedPattern(ctx, "signed", "monitoring", "1.0.0")
+	if err != nil || pattern.Metadata.Name != "monitoring" || signer != "public key" {
+		t.Fatalf("FetchVerifiedPattern() = %v, %q, %v", pattern, signer, err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(patternDir, "pattern.yaml"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("# tampered\n")
+	f.Close()
+	if _, _, err := rm.FetchVerifiedPattern(ctx, "signed", "monitoring", "1.0.0"); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("FetchVerifiedPattern() of a tampered pattern error = %v", err)
+	}
+
+	// Registries without a policy return patterns unverified.
+	rm.SetSigningPolicy(nil)
+	if _, signer, err := rm.FetchVerifiedPattern(ctx, "signed", "monitoring", "1.0.0"); err != nil || signer != "" {
+		t.Errorf("FetchVerifiedPattern() without policy = %q, %v", signer, err)
+	}
+}
+
+func TestInstallVerifiesSignature(t *testing.T) {
+	dir, _, publicKey := signedRegistry(t)
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "signed", Type: RegistryTypeLocal, URL: dir, Enabled: true, Signing: &SignaturePolicy{PublicKey: publicKey}}); err != nil {
+		t.Fatal(err)
+	}
+	installer := NewInstaller(rm, t.TempDir(), "argocd", "kubernetes")
+	ctx := context.Background()
+
+	if _, err := installer.Install(ctx, "monitoring", InstallOptions{DryRun: true}); err == nil {
+		t.Fatal("Install() should refuse an unsigned pattern")
+	}
+	result, err := installer.Install(ctx, "monitoring", InstallOptions{DryRun: true, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Install() with InsecureSkipVerify error = %v", err)
+	}
+	if len(result.Warnings) == 0 || !strings.Contains(result.Warnings[0], "skipped") {
+		t.Errorf("Install() warnings = %v", result.Warnings)
+	}
+}
+
+func TestParseEncryptedCosignKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	salt, nonce := make([]byte, 32), [24]byte{}
+	_, _ = rand.Read(salt)
+	_, _ = rand.Read(nonce[:])
+	derived, _ := scrypt.Key([]byte("secret"), salt, 1024, 8, 1, 32)
+	var k [32]byte
+	copy(k[:], derived)
+
+	envelope, _ := json.Marshal(map[string]any{
+		"kdf":        map[string]any{"name": "scrypt", "params": map[string]int{"N": 1024, "r": 8, "p": 1}, "salt": salt},
+		"cipher":     map[string]any{"name": "nacl/secretbox", "nonce": nonce[:]},
+		"ciphertext": secretbox.Seal(nil, der, &nonce, &k),
+	})
+	data := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: envelope})
+
+	signer, err := parsePrivateKey(data, []byte("secret"))
+	if err != nil {
+		t.Fatalf("parsePrivateKey() error = %v", err)
+	}
+	if !signer.Public().(*ecdsa.PublicKey).Equal(&key.PublicKey) {
+		t.Error("parsePrivateKey() returned another key")
+	}
+	if _, err := parsePrivateKey(data, []byte("wrong")); err == nil {
+		t.Error("parsePrivateKey() should fail with a wrong password")
+	}
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	now := time.Now()
+	rootKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, _ := x509.CreateCertificate(rand.Reader, root, root, &rootKey.PublicKey, rootKey)
+	root, _ = x509.ParseCertificate(rootDER)
+	rootsFile := filepath.Join(t.TempDir(), "fulcio.pem")
+	if err := os.WriteFile(rootsFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer, _ := asn1.Marshal("https://token.actions.githubusercontent.com")
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       now.Add(-time.Minute),
+		NotAfter:        now.Add(-time.Second), // Fulcio certificates expire after minutes
+		EmailAddresses:  []string{"publisher@example.com"},
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	leafDER, _ := x509.CreateCertificate(rand.Reader, leaf, root, &leafKey.PublicKey, rootKey)
+	certificate := []byte(base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})))
+
+	content := []byte("apiVersion: gitopsi.io/v1\n")
+	digest := sha256.Sum256(content)
+	sig, _ := ecdsa.SignASN1(rand.Reader, leafKey, digest[:])
+	signature := []byte(base64.StdEncoding.EncodeToString(sig))
+
+	policy := &SignaturePolicy{Identity: "publisher@example.com", Issuer: "https://token.actions.githubusercontent.com", Roots: rootsFile}
+	signer, err := policy.Verify(content, signature, certificate)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if signer != "publisher@example.com (https://token.actions.githubusercontent.com)" {
+		t.Errorf("Verify() signer = %s", signer)
+	}
+
+	tests := []struct {
+		name   string
+		policy SignaturePolicy
+	}{
+		{"identity", SignaturePolicy{Identity: "someone@example.com", Roots: rootsFile}},
+		{"identity regexp", SignaturePolicy{IdentityRegexp: `@gitopsi\.io$`, Roots: rootsFile}},
+		{"issuer", SignaturePolicy{Identity: "publisher@example.com", Issuer: "https://accounts.google.com", Roots: rootsFile}},
+		{"no identity", SignaturePolicy{Roots: rootsFile}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.policy.Verify(content, signature, certificate); err == nil {
+			t.Errorf("Verify() should reject a mismatching %s", tt.name)
+		}
+	}
+	if _, err := policy.Verify([]byte("tampered"), signature, certificate); err == nil {
+		t.Error("Verify() should reject tampered content")
+	}
+	if _, err := policy.Verify(content, signature, nil); err == nil {
+		t.Error("Verify() should require a certificate")
+	}
+}
@@ -0,0 +1,259 @@
+package marketplace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFiles are the file names kustomize reads in a directory.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// UninstallOptions defines options for pattern uninstallation.
+type UninstallOptions struct {
+	Force     bool
+	KeepFiles bool
+	// DeleteApplication deletes a live ArgoCD Application of the pattern when
+	// set. It is called for the Application of every installed environment
+	// before any file is removed.
+	DeleteApplication func(ctx context.Context, name string) error
+}
+
+// UninstallResult represents the result of a pattern uninstallation.
+type UninstallResult struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Removed lists the removed files and empty directories.
+	Removed []string `yaml:"removed,omitempty" json:"removed,omitempty"`
+	// Pruned lists the kustomizations whose references to removed files
+	// were dropped.
+	Pruned []string `yaml:"pruned,omitempty" json:"pruned,omitempty"`
+	// Applications lists the deleted live ArgoCD Applications.
+	Applications []string `yaml:"applications,omitempty" json:"applications,omitempty"`
+	Warnings     []string `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+}
+
+// Uninstall removes an installed pattern: its live Applications when
+// requested, its generated files, the directories left empty and the
+// kustomization entries referencing them.
+func (i *Installer) Uninstall(ctx context.Context, patternName string, opts UninstallOptions) (*UninstallResult, error) {
+	if err := i.LoadState(); err != nil {
+		return nil, err
+	}
+
+	installed, ok := i.installed[patternName]
+	if !ok {
+		return nil, fmt.Errorf("pattern '%s' is not installed", patternName)
+	}
+	result := &UninstallResult{Pattern: patternName}
+
+	// Delete the live Applications while their manifests still exist
+	if opts.DeleteApplication != nil {
+		for _, env := range installed.Environments {
+			name := fmt.Sprintf("%s-%s", patternName, env)
+			if err := opts.DeleteApplication(ctx, name); err != nil {
+				if !opts.Force {
+					return result, fmt.Errorf("failed to delete Application %s: %w", name, err)
+				}
+				result.Warnings = append(result.Warnings, fmt.Sprintf("failed to delete Application %s: %v", name, err))
+				continue
+			}
+			result.Applications = append(result.Applications, name)
+		}
+	}
+
+	// Remove generated files
+	if !opts.KeepFiles {
+		var removed []string
+		for _, path := range installed.Paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				if !opts.Force {
+					return result, fmt.Errorf("failed to remove %s: %w", path, err)
+				}
+				result.Warnings = append(result.Warnings, fmt.Sprintf("failed to remove %s: %v", path, err))
+				continue
+			}
+			removed = append(removed, path)
+		}
+		removed = append(removed, i.removeEmptyDirs(removed)...)
+		result.Removed = removed
+
+		pruned, err := i.pruneKustomizations(removed)
+		if err != nil {
+			if !opts.Force {
+				return result, err
+			}
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+		result.Pruned = pruned
+	}
+
+	// Remove from state
+	delete(i.installed, patternName)
+
+	if err := i.SaveState(); err != nil {
+		return result, err
+	}
+	lock, err := LoadLock(i.lockFile)
+	if err != nil {
+		return result, err
+	}
+	if _, ok := lock.Patterns[patternName]; !ok {
+		return result, nil
+	}
+	delete(lock.Patterns, patternName)
+	return result, lock.Save(i.lockFile)
+}
+
+// removeEmptyDirs removes the directories of removed files left empty, up to
+// the top-level directories of the project, and returns them.
+func (i *Installer) removeEmptyDirs(removed []string) []string {
+	root := filepath.Clean(i.projectPath)
+	var dirs []string
+	for _, path := range removed {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	// Deepest first, so parents are empty once their children are gone
+	sort.Slice(dirs, func(a, b int) bool {
+		return strings.Count(dirs[a], string(filepath.Separator)) > strings.Count(dirs[b], string(filepath.Separator))
+	})
+
+	var deleted []string
+	for len(dirs) > 0 {
+		dir := filepath.Clean(dirs[0])
+		dirs = dirs[1:]
+		if slices.Contains(deleted, dir) || filepath.Dir(dir) == root || !within(root, dir) {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			continue
+		}
+		if err := os.Remove(dir); err != nil {
+			continue
+		}
+		deleted = append(deleted, dir)
+		dirs = append(dirs, filepath.Dir(dir))
+	}
+	return deleted
+}
+
+// pruneKustomizations drops the entries referencing removed paths from the
+// kustomizations of the directories above them, and returns the changed
+// kustomizations.
+func (i *Installer) pruneKustomizations(removed []string) ([]string, error) {
+	root := filepath.Clean(i.projectPath)
+	var dirs []string
+	for _, path := range removed {
+		for dir := filepath.Dir(path); within(root, dir); dir = filepath.Dir(dir) {
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+			if dir == root {
+				break
+			}
+		}
+	}
+	sort.Strings(dirs)
+
+	var pruned []string
+	for _, dir := range dirs {
+		for _, name := range kustomizationFiles {
+			path := filepath.Join(dir, name)
+			changed, err := pruneKustomization(path, removed)
+			if err != nil {
+				return pruned, err
+			}
+			if changed {
+				pruned = append(pruned, path)
+			}
+		}
+	}
+	return pruned, nil
+}
+
+// pruneKustomization drops the resources, components and patches of a
+// kustomization that reference a removed path, keeping its comments and
+// layout.
+func pruneKustomization(path string, removed []string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false, nil
+	}
+
+	dir := filepath.Dir(path)
+	isRemoved := func(ref string) bool {
+		if ref == "" || strings.Contains(ref, "://") {
+			return false
+		}
+		target := filepath.Join(dir, filepath.FromSlash(ref))
+		for _, r := range removed {
+			if target == r || within(r, target) {
+				return true
+			}
+		}
+		return false
+	}
+
+	changed := false
+	root := doc.Content[0]
+	for k := 0; k+1 < len(root.Content); k += 2 {
+		key, list := root.Content[k].Value, root.Content[k+1]
+		if list.Kind != yaml.SequenceNode {
+			continue
+		}
+		var keep []*yaml.Node
+		for _, item := range list.Content {
+			var ref string
+			switch {
+			case item.Kind == yaml.ScalarNode && (key == "resources" || key == "components" || key == "bases" || key == "patchesStrategicMerge"):
+				ref = item.Value
+			case item.Kind == yaml.MappingNode && (key == "patches" || key == "patchesJson6902"):
+				for p := 0; p+1 < len(item.Content); p += 2 {
+					if item.Content[p].Value == "path" {
+						ref = item.Content[p+1].Value
+					}
+				}
+			}
+			if isRemoved(ref) {
+				changed = true
+				continue
+			}
+			keep = append(keep, item)
+		}
+		list.Content = keep
+	}
+	if !changed {
+		return false, nil
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
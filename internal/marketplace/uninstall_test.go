@@ -0,0 +1,118 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// installedProject installs a monitoring pattern into a project whose parent
+// kustomizations reference it.
+func installedProject(t *testing.T) (*Installer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	pattern := NewPattern("monitoring", "1.0.0", "Monitoring stack")
+	pattern.Metadata.Category = "observability"
+	pattern.Spec.Components = []Component{
+		{Name: "prometheus", Type: ComponentTypeHelm, Repository: "https://prometheus-community.github.io/helm-charts", Chart: "kube-prometheus-stack", Version: "55.0.0"},
+		{Name: "alerts", Type: ComponentTypeManifest},
+	}
+	addPattern(t, dir, pattern)
+	rm := NewRegistryManager(t.TempDir())
+	rm.SetOffline(true)
+	if err := rm.AddRegistry(Registry{Name: "team", Type: RegistryTypeLocal, URL: dir, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	installer := NewInstaller(rm, project, "argocd", "kubernetes")
+	if _, err := installer.Install(context.Background(), "monitoring", InstallOptions{Environments: []string{"dev", "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(project, "infrastructure", "kustomization.yaml"),
+		"# Infrastructure\nresources:\n  - observability/monitoring/overlays/dev\n  - networking # Ingress\n")
+	writeFile(t, filepath.Join(project, "argocd", "applications", "kustomization.yaml"),
+		"resources:\n  - monitoring-dev.yaml\n  - monitoring-prod.yaml\n  - shop.yaml\npatches:\n  - path: monitoring-dev.yaml\n  - path: https://example.com/patch.yaml\n")
+	return installer, project
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUninstallCleansUp(t *testing.T) {
+	installer, project := installedProject(t)
+	var deleted []string
+	result, err := installer.Uninstall(context.Background(), "monitoring", UninstallOptions{
+		DeleteApplication: func(_ context.Context, name string) error {
+			deleted = append(deleted, name)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Uninstall() error = %v", err)
+	}
+	if !slices.Equal(deleted, []string{"monitoring-dev", "monitoring-prod"}) || !slices.Equal(result.Applications, deleted) {
+		t.Errorf("Uninstall() deleted Applications %v, result %v", deleted, result.Applications)
+	}
+
+	if _, err := os.Stat(filepath.Join(project, "infrastructure", "observability")); !os.IsNotExist(err) {
+		t.Errorf("empty pattern tree was not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "infrastructure")); err != nil {
+		t.Errorf("top-level directory was removed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(project, "infrastructure", "kustomization.yaml"))
+	if got := string(data); strings.Contains(got, "monitoring") || !strings.Contains(got, "networking") || !strings.Contains(got, "# Ingress") {
+		t.Errorf("infrastructure kustomization = %s", got)
+	}
+	data, _ = os.ReadFile(filepath.Join(project, "argocd", "applications", "kustomization.yaml"))
+	if got := string(data); strings.Contains(got, "monitoring-") || !strings.Contains(got, "shop.yaml") || !strings.Contains(got, "https://example.com/patch.yaml") {
+		t.Errorf("applications kustomization = %s", got)
+	}
+	if len(result.Pruned) != 2 {
+		t.Errorf("Uninstall() pruned = %v", result.Pruned)
+	}
+	if _, err := installer.GetInstalled("monitoring"); err == nil {
+		t.Error("pattern is still installed")
+	}
+}
+
+func TestUninstallStopsOnApplicationError(t *testing.T) {
+	installer, project := installedProject(t)
+	failing := func(context.Context, string) error { return errors.New("connection refused") }
+
+	if _, err := installer.Uninstall(context.Background(), "monitoring", UninstallOptions{DeleteApplication: failing}); err == nil {
+		t.Fatal("Uninstall() should fail when an Application cannot be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(project, "argocd", "applications", "monitoring-dev.yaml")); err != nil {
+		t.Errorf("files were removed after the failure: %v", err)
+	}
+
+	result, err := installer.Uninstall(context.Background(), "monitoring", UninstallOptions{DeleteApplication: failing, Force: true})
+	if err != nil || len(result.Warnings) != 2 {
+		t.Fatalf("Uninstall() with Force = %+v, %v", result, err)
+	}
+}
+
+func TestUninstallKeepFiles(t *testing.T) {
+	installer, project := installedProject(t)
+	result, err := installer.Uninstall(context.Background(), "monitoring", UninstallOptions{KeepFiles: true})
+	if err != nil || len(result.Removed) != 0 || len(result.Pruned) != 0 {
+		t.Fatalf("Uninstall() with KeepFiles = %+v, %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "infrastructure", "observability", "monitoring", "base", "kustomization.yaml")); err != nil {
+		t.Errorf("files were removed: %v", err)
+	}
+}
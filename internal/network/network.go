@@ -0,0 +1,128 @@
+// Package network configures the outbound connections of gitopsi: the
+// proxy, the certificate authorities trusted next to the system roots and
+// TLS verification. Configure applies them to http.DefaultTransport, which
+// every HTTP client of gitopsi and the go-git HTTP transport use.
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Options configures outbound connections.
+type Options struct {
+	// Proxy is the HTTP(S) proxy URL. Empty uses HTTP_PROXY and HTTPS_PROXY.
+	Proxy string
+	// NoProxy lists the hosts reached without the proxy. Empty uses NO_PROXY.
+	NoProxy string
+	// SSHProxy is the SOCKS5 proxy URL of Git over SSH. Empty uses ALL_PROXY.
+	SSHProxy string
+	// CABundle is a PEM file of certificate authorities trusted next to the
+	// system roots.
+	CABundle string
+	// InsecureTLS disables TLS certificate verification.
+	InsecureTLS bool
+}
+
+// current is the configuration applied by Configure.
+var current Options
+
+// Configure applies opts to the outbound connections of the process. The
+// proxy is also exported as HTTP_PROXY, HTTPS_PROXY and NO_PROXY so that
+// the tools gitopsi runs, such as kubectl, helm and flux, use it too.
+func Configure(opts Options) error {
+	if opts.SSHProxy != "" {
+		if _, err := url.Parse(opts.SSHProxy); err != nil {
+			return fmt.Errorf("invalid SSH proxy URL: %w", err)
+		}
+	}
+	tlsConfig, err := TLSConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Proxy != "" {
+		if _, err := url.Parse(opts.Proxy); err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+			_ = os.Setenv(name, opts.Proxy)
+		}
+	}
+	if opts.NoProxy != "" {
+		for _, name := range []string{"NO_PROXY", "no_proxy"} {
+			_ = os.Setenv(name, opts.NoProxy)
+		}
+	}
+
+	// The default transport is changed in place: clients holding it, like
+	// the default go-git HTTP client, see the change.
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+	current = opts
+	return nil
+}
+
+// TLSConfig returns the TLS configuration of opts, or nil when it uses the
+// defaults.
+func TLSConfig(opts Options) (*tls.Config, error) {
+	if opts.CABundle == "" && !opts.InsecureTLS {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: opts.InsecureTLS} //nolint:gosec // Opt-in with --insecure-tls.
+	if opts.CABundle != "" {
+		pem, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CABundle)
+		}
+		config.RootCAs = roots
+	}
+	return config, nil
+}
+
+// CABundle returns the CA bundle file configured, or an empty string.
+func CABundle() string {
+	return current.CABundle
+}
+
+// InsecureTLS reports whether TLS certificate verification is disabled.
+func InsecureTLS() bool {
+	return current.InsecureTLS
+}
+
+// GitProxy returns the proxy options of a Git remote URL: the SSH proxy for
+// SSH remotes. HTTP remotes use the proxy of the default transport.
+func GitProxy(remoteURL string) transport.ProxyOptions {
+	if current.SSHProxy == "" || !isSSH(remoteURL) {
+		return transport.ProxyOptions{}
+	}
+	return transport.ProxyOptions{URL: current.SSHProxy}
+}
+
+func isSSH(remoteURL string) bool {
+	if strings.HasPrefix(remoteURL, "ssh://") {
+		return true
+	}
+	// scp-like syntax: user@host:path.
+	return !strings.Contains(remoteURL, "://") && strings.Contains(remoteURL, "@") && strings.Contains(remoteURL, ":")
+}
@@ -0,0 +1,128 @@
+package network
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// restoreDefaults undoes Configure once the test ran.
+func restoreDefaults(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+	transport := http.DefaultTransport.(*http.Transport)
+	proxy, tlsConfig := transport.Proxy, transport.TLSClientConfig
+	t.Cleanup(func() {
+		transport.Proxy, transport.TLSClientConfig = proxy, tlsConfig
+		transport.CloseIdleConnections()
+		current = Options{}
+	})
+}
+
+func TestConfigure_CABundle(t *testing.T) {
+	restoreDefaults(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	if _, err := http.Get(server.URL); err == nil {
+		t.Fatal("the test server certificate should not be trusted by default")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure(Options{CABundle: bundle}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("the CA bundle should be trusted: %v", err)
+	}
+	resp.Body.Close()
+	if CABundle() != bundle {
+		t.Errorf("CABundle() = %q", CABundle())
+	}
+}
+
+func TestConfigure_InsecureTLS(t *testing.T) {
+	restoreDefaults(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	if err := Configure(Options{InsecureTLS: true}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("InsecureTLS should skip verification: %v", err)
+	}
+	resp.Body.Close()
+	if !InsecureTLS() {
+		t.Error("InsecureTLS() = false")
+	}
+}
+
+func TestConfigure_Proxy(t *testing.T) {
+	restoreDefaults(t)
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	if err := Configure(Options{Proxy: proxy.URL, NoProxy: "internal.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://charts.example.com/index.yaml")
+	if err != nil {
+		t.Fatalf("request through the proxy failed: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://charts.example.com/index.yaml" {
+		t.Errorf("proxy received %q", proxied)
+	}
+	if os.Getenv("HTTPS_PROXY") != proxy.URL || os.Getenv("NO_PROXY") != "internal.example.com" {
+		t.Error("the proxy should be exported to the tools gitopsi runs")
+	}
+}
+
+func TestConfigure_InvalidCABundle(t *testing.T) {
+	restoreDefaults(t)
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure(Options{CABundle: bundle}); err == nil {
+		t.Error("Configure() should reject a bundle without certificates")
+	}
+	if err := Configure(Options{CABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("Configure() should reject a missing bundle")
+	}
+}
+
+func TestGitProxy(t *testing.T) {
+	restoreDefaults(t)
+	if err := Configure(Options{SSHProxy: "socks5://proxy.example.com:1080"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"git@github.com:org/repo.git":           "socks5://proxy.example.com:1080",
+		"ssh://git@gitlab.example.com/org/repo": "socks5://proxy.example.com:1080",
+		"https://github.com/org/repo.git":       "",
+	}
+	for remote, want := range tests {
+		if got := GitProxy(remote).URL; got != want {
+			t.Errorf("GitProxy(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
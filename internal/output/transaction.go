@@ -0,0 +1,186 @@
+package output
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// stagingPattern names the temporary tree files are staged in, inside the
+// base directory so staged files are moved into place by renames.
+const stagingPattern = ".gitopsi-staging-*"
+
+// Begin starts a transaction: until Commit or Rollback, files and
+// directories are written to a temporary tree instead of the base
+// directory, so a failing generation leaves the base directory untouched.
+func (w *Writer) Begin() error {
+	if w.DryRun || w.staging != "" {
+		return nil
+	}
+	if _, err := os.Stat(w.BaseDir); os.IsNotExist(err) {
+		w.createdBase = true
+	}
+	if err := os.MkdirAll(w.BaseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", w.BaseDir, err)
+	}
+	staging, err := os.MkdirTemp(w.BaseDir, stagingPattern)
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	w.staging = staging
+	return nil
+}
+
+// Commit moves the staged files into the base directory. Trees missing from
+// the base directory are moved at once; staged files are merged into
+// existing trees one rename at a time, so a commit into an existing
+// directory is only atomic per file. When a move fails, the staging
+// directory is removed, or kept with KeepPartial and named in the error.
+func (w *Writer) Commit() error {
+	if w.staging == "" {
+		return nil
+	}
+	staging := w.staging
+	w.staging = ""
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return fmt.Errorf("failed to read staging directory: %w", err)
+	}
+	for _, entry := range entries {
+		if err := move(filepath.Join(staging, entry.Name()), filepath.Join(w.BaseDir, entry.Name())); err != nil {
+			if w.KeepPartial {
+				return fmt.Errorf("failed to commit output, files not moved are kept in %s: %w", staging, err)
+			}
+			_ = os.RemoveAll(staging)
+			return fmt.Errorf("failed to commit output: %w", err)
+		}
+	}
+	if err := syncDir(w.BaseDir); err != nil {
+		return fmt.Errorf("failed to commit output: %w", err)
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("failed to remove staging directory: %w", err)
+	}
+	return nil
+}
+
+// Rollback discards the staged files. With KeepPartial the staged files are
+// kept for debugging and Rollback returns their directory.
+func (w *Writer) Rollback() (string, error) {
+	if w.staging == "" {
+		return "", nil
+	}
+	staging := w.staging
+	w.staging = ""
+
+	if w.KeepPartial {
+		return staging, nil
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		return "", fmt.Errorf("failed to remove staging directory: %w", err)
+	}
+	if w.createdBase {
+		// Only removes the base directory if nothing else was written to it.
+		_ = os.Remove(w.BaseDir)
+	}
+	return "", nil
+}
+
+// ReadTree returns the files under dir, relative to the base directory and
+// with slash separators, as they will be once staged files are committed.
+// Git directories are skipped.
+func (w *Writer) ReadTree(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, root := range []string{w.BaseDir, w.staging} {
+		if root == "" {
+			continue
+		}
+		if err := readTree(root, dir, files); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func readTree(root, dir string, files map[string][]byte) error {
+	err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return nil
+}
+
+// move moves src to dst, merging directories into existing ones.
+func move(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); os.IsNotExist(err) || !info.IsDir() {
+		return os.Rename(src, dst)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := move(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return syncDir(dst)
+}
+
+// writeFileSync writes a file and flushes it to disk.
+func writeFileSync(path string, content []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir flushes the entries of a directory to disk, so renames into it
+// survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,206 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriter_Commit(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "existing"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "existing", "old.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "existing", "keep.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := New(tmpDir, false, false)
+	if err := writer.Begin(); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	for path, content := range map[string]string{
+		"project/a/file.txt": "a",
+		"existing/old.txt":   "new",
+	} {
+		if err := writer.WriteFile(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := writer.CreateDir("project/empty"); err != nil {
+		t.Fatalf("CreateDir() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "project")); !os.IsNotExist(err) {
+		t.Error("files should be staged until Commit")
+	}
+	if !writer.Exists("project/a/file.txt") {
+		t.Error("Exists() should see staged files")
+	}
+
+	if err := writer.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	for path, want := range map[string]string{
+		"project/a/file.txt": "a",
+		"existing/old.txt":   "new",
+		"existing/keep.txt":  "keep",
+	} {
+		got, err := os.ReadFile(filepath.Join(tmpDir, path))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(tmpDir, "project", "empty")); err != nil || !info.IsDir() {
+		t.Error("Commit() should create staged directories")
+	}
+	assertNoStaging(t, tmpDir)
+}
+
+func TestWriter_CommitRenameFailure(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		tmpDir := t.TempDir()
+		// A staged file cannot be renamed over a non-empty directory.
+		if err := os.MkdirAll(filepath.Join(tmpDir, "existing", "conflict", "sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		writer := New(tmpDir, false, false)
+		writer.KeepPartial = keep
+		if err := writer.Begin(); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		if err := writer.WriteFile("existing/conflict", []byte("file")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+
+		err := writer.Commit()
+		if err == nil {
+			t.Fatal("Commit() should fail when a rename fails")
+		}
+		matches, _ := filepath.Glob(filepath.Join(tmpDir, stagingPattern))
+		if keep {
+			if len(matches) != 1 || !strings.Contains(err.Error(), matches[0]) {
+				t.Errorf("KeepPartial: staging %v should be kept and named in %q", matches, err)
+			}
+		} else {
+			assertNoStaging(t, tmpDir)
+		}
+	}
+}
+
+func TestWriter_Rollback(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "out")
+	writer := New(baseDir, false, false)
+	if err := writer.Begin(); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := writer.WriteFile("project/file.txt", []byte("partial")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	partial, err := writer.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if partial != "" {
+		t.Errorf("Rollback() kept %s", partial)
+	}
+	if _, err := os.Stat(baseDir); !os.IsNotExist(err) {
+		t.Error("Rollback() should remove the base directory it created")
+	}
+}
+
+func TestWriter_RollbackKeepPartial(t *testing.T) {
+	tmpDir := t.TempDir()
+	writer := New(tmpDir, false, false)
+	writer.KeepPartial = true
+	if err := writer.Begin(); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := writer.WriteFile("project/file.txt", []byte("partial")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	partial, err := writer.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(partial, "project", "file.txt"))
+	if err != nil || string(got) != "partial" {
+		t.Errorf("partial file = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "project")); !os.IsNotExist(err) {
+		t.Error("Rollback() should not write to the base directory")
+	}
+}
+
+func TestWriter_BeginDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	writer := New(tmpDir, true, false)
+	if err := writer.Begin(); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	assertNoStaging(t, tmpDir)
+	if err := writer.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+}
+
+func TestWriter_ReadTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	for path, content := range map[string]string{
+		"project/a.txt":        "old",
+		"project/b.txt":        "b",
+		"project/.git/HEAD":    "ref",
+		"other/ignored.txt":    "x",
+		"project/sub/deep.txt": "deep",
+	} {
+		full := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := New(tmpDir, false, false)
+	if err := writer.Begin(); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer writer.Rollback()
+	if err := writer.WriteFile("project/a.txt", []byte("new")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	files, err := writer.ReadTree("project")
+	if err != nil {
+		t.Fatalf("ReadTree() error = %v", err)
+	}
+	want := map[string]string{"project/a.txt": "new", "project/b.txt": "b", "project/sub/deep.txt": "deep"}
+	if len(files) != len(want) {
+		t.Errorf("ReadTree() = %v, want %v", files, want)
+	}
+	for path, content := range want {
+		if string(files[path]) != content {
+			t.Errorf("ReadTree()[%s] = %q, want %q", path, files[path], content)
+		}
+	}
+}
+
+func assertNoStaging(t *testing.T, dir string) {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, stagingPattern))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("staging directories left behind: %v", matches)
+	}
+}
@@ -2,6 +2,7 @@ package output
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -10,6 +11,22 @@ type Writer struct {
 	BaseDir string
 	DryRun  bool
 	Verbose bool
+	// Transform, if set, rewrites every file before BeforeWrite sees it.
+	Transform func(relativePath string, content []byte) ([]byte, error)
+	// BeforeWrite, if set, is called with every file before it is written
+	// (including in dry-run mode). A non-nil error aborts the write.
+	BeforeWrite func(relativePath string, content []byte) error
+	// Reconcile, if set, is called with every file after BeforeWrite and
+	// returns the content to write, or false to leave the file untouched.
+	Reconcile func(relativePath string, content []byte) ([]byte, bool, error)
+	// Log receives the dry-run and verbose file listing; nil means stdout.
+	Log io.Writer
+	// KeepPartial keeps the staged files of a rolled back transaction.
+	KeepPartial bool
+
+	// staging is the directory files are staged in during a transaction.
+	staging     string
+	createdBase bool
 }
 
 func New(baseDir string, dryRun, verbose bool) *Writer {
@@ -21,10 +38,35 @@ func New(baseDir string, dryRun, verbose bool) *Writer {
 }
 
 func (w *Writer) WriteFile(relativePath string, content []byte) error {
-	fullPath := filepath.Join(w.BaseDir, relativePath)
+	fullPath := filepath.Join(w.root(), relativePath)
+
+	if w.Transform != nil {
+		transformed, err := w.Transform(relativePath, content)
+		if err != nil {
+			return fmt.Errorf("failed to transform %s: %w", relativePath, err)
+		}
+		content = transformed
+	}
+
+	if w.BeforeWrite != nil {
+		if err := w.BeforeWrite(relativePath, content); err != nil {
+			return err
+		}
+	}
+
+	if w.Reconcile != nil {
+		reconciled, write, err := w.Reconcile(relativePath, content)
+		if err != nil {
+			return err
+		}
+		if !write {
+			return nil
+		}
+		content = reconciled
+	}
 
 	if w.Verbose || w.DryRun {
-		fmt.Printf("  → %s\n", relativePath)
+		fmt.Fprintf(w.log(), "  → %s\n", relativePath)
 	}
 
 	if w.DryRun {
@@ -36,7 +78,12 @@ func (w *Writer) WriteFile(relativePath string, content []byte) error {
 		return fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
-	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+	// Staged files are flushed to disk before they are moved into place.
+	write := os.WriteFile
+	if w.staging != "" {
+		write = writeFileSync
+	}
+	if err := write(fullPath, content, 0644); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", fullPath, err)
 	}
 
@@ -44,10 +91,10 @@ func (w *Writer) WriteFile(relativePath string, content []byte) error {
 }
 
 func (w *Writer) CreateDir(relativePath string) error {
-	fullPath := filepath.Join(w.BaseDir, relativePath)
+	fullPath := filepath.Join(w.root(), relativePath)
 
 	if w.Verbose || w.DryRun {
-		fmt.Printf("  📁 %s/\n", relativePath)
+		fmt.Fprintf(w.log(), "  📁 %s/\n", relativePath)
 	}
 
 	if w.DryRun {
@@ -61,7 +108,28 @@ func (w *Writer) CreateDir(relativePath string) error {
 	return nil
 }
 
+func (w *Writer) log() io.Writer {
+	if w.Log == nil {
+		return os.Stdout
+	}
+	return w.Log
+}
+
+// root returns the directory files are written to: the staging directory
+// during a transaction, the base directory otherwise.
+func (w *Writer) root() string {
+	if w.staging != "" {
+		return w.staging
+	}
+	return w.BaseDir
+}
+
 func (w *Writer) Exists(relativePath string) bool {
+	if w.staging != "" {
+		if _, err := os.Stat(filepath.Join(w.staging, relativePath)); err == nil {
+			return true
+		}
+	}
 	fullPath := filepath.Join(w.BaseDir, relativePath)
 	_, err := os.Stat(fullPath)
 	return err == nil
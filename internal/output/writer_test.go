@@ -1,6 +1,7 @@
 package output
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -230,3 +231,87 @@ func TestNewWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestWriter_WriteFile_BeforeWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	writer := New(tmpDir, false, false)
+
+	var seen []string
+	writer.BeforeWrite = func(relativePath string, content []byte) error {
+		seen = append(seen, relativePath)
+		if relativePath == "blocked.yaml" {
+			return errors.New("blocked")
+		}
+		return nil
+	}
+
+	if err := writer.WriteFile("ok.yaml", []byte("a")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := writer.WriteFile("blocked.yaml", []byte("b")); err == nil {
+		t.Error("WriteFile() should return the BeforeWrite error")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "blocked.yaml")); !os.IsNotExist(err) {
+		t.Error("blocked file should not be written")
+	}
+	if len(seen) != 2 {
+		t.Errorf("BeforeWrite called %d times, want 2", len(seen))
+	}
+}
+
+func TestWriter_Reconcile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writer := New(tmpDir, false, false)
+	writer.Reconcile = func(relativePath string, content []byte) ([]byte, bool, error) {
+		if relativePath == "skip.txt" {
+			return nil, false, nil
+		}
+		return append(content, '!'), true, nil
+	}
+
+	if err := writer.WriteFile("skip.txt", []byte("skipped")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "skip.txt")); !os.IsNotExist(err) {
+		t.Error("file should not be written when Reconcile skips it")
+	}
+
+	if err := writer.WriteFile("write.txt", []byte("content")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "write.txt"))
+	if err != nil || string(data) != "content!" {
+		t.Errorf("expected the reconciled content, got %q, %v", data, err)
+	}
+}
+
+func TestWriter_Transform(t *testing.T) {
+	tmpDir := t.TempDir()
+	writer := New(tmpDir, false, false)
+	writer.Transform = func(relativePath string, content []byte) ([]byte, error) {
+		if relativePath == "bad.txt" {
+			return nil, errors.New("boom")
+		}
+		return append(content, '!'), nil
+	}
+	var seen string
+	writer.BeforeWrite = func(relativePath string, content []byte) error {
+		seen = string(content)
+		return nil
+	}
+
+	if err := writer.WriteFile("write.txt", []byte("content")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if seen != "content!" {
+		t.Errorf("BeforeWrite saw %q, want the transformed content", seen)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "write.txt"))
+	if err != nil || string(data) != "content!" {
+		t.Errorf("expected the transformed content, got %q, %v", data, err)
+	}
+
+	if err := writer.WriteFile("bad.txt", []byte("content")); err == nil {
+		t.Error("expected the Transform error")
+	}
+}
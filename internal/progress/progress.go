@@ -3,6 +3,7 @@ package progress
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pterm/pterm"
@@ -248,6 +249,19 @@ func getStatusIcon(status StepStatus) string {
 	}
 }
 
+// barWidth is the number of cells of a progress bar.
+const barWidth = 20
+
+// Bar renders the progress of done out of total units of work, such as
+// "[██████░░░░░░░░░░░░░░] 3/10".
+func Bar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(max(done, 0), total) * barWidth / total
+	}
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), done, total)
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Second {
 		return fmt.Sprintf("%dms", d.Milliseconds())
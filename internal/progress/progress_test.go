@@ -331,3 +331,21 @@ func TestShowSummaryQuiet(t *testing.T) {
 
 	p.ShowSummary(summary)
 }
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		done, total int
+		want        string
+	}{
+		{0, 10, "[░░░░░░░░░░░░░░░░░░░░] 0/10"},
+		{3, 10, "[██████░░░░░░░░░░░░░░] 3/10"},
+		{10, 10, "[████████████████████] 10/10"},
+		{12, 10, "[████████████████████] 12/10"},
+		{0, 0, "[░░░░░░░░░░░░░░░░░░░░] 0/0"},
+	}
+	for _, tt := range tests {
+		if got := Bar(tt.done, tt.total); got != tt.want {
+			t.Errorf("Bar(%d, %d) = %q, want %q", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
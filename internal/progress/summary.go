@@ -12,66 +12,66 @@ import (
 
 // SetupSummary contains all setup information.
 type SetupSummary struct {
-	Setup        SetupInfo         `yaml:"setup"`
-	Git          GitInfo           `yaml:"git"`
-	Cluster      ClusterInfo       `yaml:"cluster"`
-	GitOpsTool   GitOpsToolInfo    `yaml:"gitops_tool"`
-	Environments []EnvironmentInfo `yaml:"environments"`
-	Applications []ApplicationInfo `yaml:"applications"`
+	Setup        SetupInfo         `yaml:"setup" json:"setup"`
+	Git          GitInfo           `yaml:"git" json:"git"`
+	Cluster      ClusterInfo       `yaml:"cluster" json:"cluster"`
+	GitOpsTool   GitOpsToolInfo    `yaml:"gitops_tool" json:"gitops_tool"`
+	Environments []EnvironmentInfo `yaml:"environments" json:"environments"`
+	Applications []ApplicationInfo `yaml:"applications" json:"applications"`
 }
 
 // SetupInfo contains setup metadata.
 type SetupInfo struct {
-	CompletedAt time.Time     `yaml:"completed_at"`
-	Duration    time.Duration `yaml:"duration"`
-	Version     string        `yaml:"version"`
+	CompletedAt time.Time     `yaml:"completed_at" json:"completed_at"`
+	Duration    time.Duration `yaml:"duration" json:"duration"`
+	Version     string        `yaml:"version" json:"version"`
 }
 
 // GitInfo contains Git repository information.
 type GitInfo struct {
-	URL      string `yaml:"url"`
-	Branch   string `yaml:"branch"`
-	WebURL   string `yaml:"web_url"`
-	Provider string `yaml:"provider"`
-	Status   string `yaml:"status"`
+	URL      string `yaml:"url" json:"url"`
+	Branch   string `yaml:"branch" json:"branch"`
+	WebURL   string `yaml:"web_url" json:"web_url"`
+	Provider string `yaml:"provider" json:"provider"`
+	Status   string `yaml:"status" json:"status"`
 }
 
 // ClusterInfo contains cluster information.
 type ClusterInfo struct {
-	Name       string   `yaml:"name"`
-	URL        string   `yaml:"url"`
-	Platform   string   `yaml:"platform"`
-	Version    string   `yaml:"version"`
-	Status     string   `yaml:"status"`
-	Namespaces []string `yaml:"namespaces"`
+	Name       string   `yaml:"name" json:"name"`
+	URL        string   `yaml:"url" json:"url"`
+	Platform   string   `yaml:"platform" json:"platform"`
+	Version    string   `yaml:"version" json:"version"`
+	Status     string   `yaml:"status" json:"status"`
+	Namespaces []string `yaml:"namespaces" json:"namespaces"`
 }
 
 // GitOpsToolInfo contains GitOps tool information.
 type GitOpsToolInfo struct {
-	Name           string `yaml:"name"`
-	URL            string `yaml:"url"`
-	Username       string `yaml:"username"`
-	Password       string `yaml:"password,omitempty"`
-	PasswordSecret string `yaml:"password_secret,omitempty"`
-	Namespace      string `yaml:"namespace"`
-	Version        string `yaml:"version"`
-	Status         string `yaml:"status"`
-	PodCount       string `yaml:"pod_count"`
+	Name           string `yaml:"name" json:"name"`
+	URL            string `yaml:"url" json:"url"`
+	Username       string `yaml:"username" json:"username"`
+	Password       string `yaml:"password,omitempty" json:"password,omitempty"`
+	PasswordSecret string `yaml:"password_secret,omitempty" json:"password_secret,omitempty"`
+	Namespace      string `yaml:"namespace" json:"namespace"`
+	Version        string `yaml:"version" json:"version"`
+	Status         string `yaml:"status" json:"status"`
+	PodCount       string `yaml:"pod_count" json:"pod_count"`
 }
 
 // EnvironmentInfo contains environment information.
 type EnvironmentInfo struct {
-	Name      string `yaml:"name"`
-	Namespace string `yaml:"namespace"`
-	Status    string `yaml:"status"`
+	Name      string `yaml:"name" json:"name"`
+	Namespace string `yaml:"namespace" json:"namespace"`
+	Status    string `yaml:"status" json:"status"`
 }
 
 // ApplicationInfo contains application information.
 type ApplicationInfo struct {
-	Name     string   `yaml:"name"`
-	Type     string   `yaml:"type"`
-	Status   string   `yaml:"status"`
-	Children []string `yaml:"children,omitempty"`
+	Name     string   `yaml:"name" json:"name"`
+	Type     string   `yaml:"type" json:"type"`
+	Status   string   `yaml:"status" json:"status"`
+	Children []string `yaml:"children,omitempty" json:"children,omitempty"`
 }
 
 // ShowSummary displays the complete setup summary.
@@ -126,6 +126,25 @@ func RunWith(p Prompter) (*config.Config, error) {
 			}
 			cfg.Git.Auth.TokenEnv = tokenEnv
 		}
+
+		createPrompt := &survey.Confirm{
+			Message: "Create the repository if it does not exist?",
+			Help:    "Uses the provider API with your token; the owner is taken from the URL",
+			Default: true,
+		}
+		if err := p.AskOne(createPrompt, &cfg.Git.CreateIfMissing); err != nil {
+			return nil, err
+		}
+		if cfg.Git.CreateIfMissing {
+			visibilityPrompt := &survey.Select{
+				Message: "Repository visibility:",
+				Options: []string{"private", "internal", "public"},
+				Default: "private",
+			}
+			if err := p.AskOne(visibilityPrompt, &cfg.Git.Repository.Visibility); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	envNames := []string{}
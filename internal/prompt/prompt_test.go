@@ -169,8 +169,12 @@ func TestRunWithMock_GitOutput(t *testing.T) {
 		t.Errorf("Git.Auth.TokenEnv = %s, want GITHUB_TOKEN", cfg.Git.Auth.TokenEnv)
 	}
 
-	if askOneCallCount != 5 {
-		t.Errorf("AskOne() called %d times, want 5 (git URL + auth method + token env + envs + docs)", askOneCallCount)
+	if !cfg.Git.CreateIfMissing {
+		t.Error("Git.CreateIfMissing should be set when confirmed")
+	}
+
+	if askOneCallCount != 7 {
+		t.Errorf("AskOne() called %d times, want 7 (git URL + auth method + token env + create repo + visibility + envs + docs)", askOneCallCount)
 	}
 }
 
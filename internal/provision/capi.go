@@ -0,0 +1,82 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// provisionCAPI generates the Cluster API manifest of a cluster with
+// clusterctl, applies it to the management cluster, waits for the cluster
+// and writes its kubeconfig.
+func (p *Provisioner) provisionCAPI(ctx context.Context, spec *Spec, result *Result) error {
+	capi := spec.CAPI
+	if capi == nil || capi.Infrastructure == "" {
+		return fmt.Errorf("capi.infrastructure is required")
+	}
+	namespace := capi.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	controlPlane := capi.ControlPlaneNodes
+	if controlPlane == 0 {
+		controlPlane = 1
+	}
+	var management, clusterctlManagement []string
+	if capi.ManagementContext != "" {
+		management = []string{"--context", capi.ManagementContext}
+		clusterctlManagement = []string{"--kubeconfig-context", capi.ManagementContext}
+	}
+
+	generate := []string{"generate", "cluster", spec.Name,
+		"--infrastructure", capi.Infrastructure,
+		"--kubernetes-version", spec.Version,
+		"--control-plane-machine-count", fmt.Sprint(controlPlane),
+		"--worker-machine-count", fmt.Sprint(nodes(spec)),
+		"--target-namespace", namespace,
+	}
+	if capi.Flavor != "" {
+		generate = append(generate, "--flavor", capi.Flavor)
+	}
+	generate = append(generate, clusterctlManagement...)
+	generate = append(generate, spec.ExtraArgs...)
+	manifest, err := runCommand(ctx, nil, "clusterctl", generate...)
+	if err != nil {
+		return fmt.Errorf("failed to generate the Cluster API manifest: %w", err)
+	}
+
+	manifestPath := filepath.Join(p.stateDir, spec.Name, "cluster.yaml")
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", manifestPath, err)
+	}
+	result.Manifest = manifestPath
+
+	exists := append(append([]string{}, management...), "get", "cluster", spec.Name, "-n", namespace)
+	if _, err := runCommand(ctx, nil, "kubectl", exists...); err != nil {
+		result.Created = true
+	}
+	apply := append(append([]string{}, management...), "apply", "-f", manifestPath)
+	if _, err := runCommand(ctx, nil, "kubectl", apply...); err != nil {
+		return fmt.Errorf("failed to apply the Cluster API manifest: %w", err)
+	}
+
+	wait := append(append([]string{}, management...), "wait", "cluster/"+spec.Name, "-n", namespace,
+		"--for=condition=Ready", "--timeout", p.timeout.String())
+	if _, err := runCommand(ctx, nil, "kubectl", wait...); err != nil {
+		return fmt.Errorf("cluster did not become ready: %w", err)
+	}
+
+	getKubeconfig := append([]string{"get", "kubeconfig", spec.Name, "-n", namespace}, clusterctlManagement...)
+	kubeconfig, err := runCommand(ctx, nil, "clusterctl", getKubeconfig...)
+	if err != nil {
+		return fmt.Errorf("failed to get the kubeconfig: %w", err)
+	}
+	if err := os.WriteFile(result.Kubeconfig, []byte(kubeconfig), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", result.Kubeconfig, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+)
+
+// cloudCommands are the CLI invocations that manage a managed-cloud cluster.
+type cloudCommands struct {
+	binary      string
+	exists      []string
+	create      []string
+	credentials []string
+	// credentialsEnv points the credentials command at the kubeconfig
+	// when it has no flag for it.
+	credentialsEnv []string
+}
+
+// provisionCloud creates an EKS, AKS or GKE cluster with its cloud CLI.
+func (p *Provisioner) provisionCloud(ctx context.Context, spec *Spec, result *Result) error {
+	commands := cloudCommandsFor(spec, result.Kubeconfig)
+
+	// The describe commands fail for missing clusters
+	if _, err := runCommand(ctx, nil, commands.binary, commands.exists...); err != nil {
+		ctx, cancel := context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+		if _, err := runCommand(ctx, nil, commands.binary, commands.create...); err != nil {
+			return err
+		}
+		result.Created = true
+	}
+
+	if _, err := runCommand(ctx, commands.credentialsEnv, commands.binary, commands.credentials...); err != nil {
+		return fmt.Errorf("failed to get credentials: %w", err)
+	}
+	return nil
+}
+
+func cloudCommandsFor(spec *Spec, kubeconfig string) *cloudCommands {
+	n := fmt.Sprint(nodes(spec))
+	switch spec.Provider {
+	case ProviderEKS:
+		c := &cloudCommands{
+			binary: "eksctl",
+			exists: []string{"get", "cluster", "--name", spec.Name, "--region", spec.Region},
+			create: []string{"create", "cluster", "--name", spec.Name, "--region", spec.Region, "--nodes", n,
+				"--kubeconfig", kubeconfig},
+			credentials: []string{"utils", "write-kubeconfig", "--cluster", spec.Name, "--region", spec.Region,
+				"--kubeconfig", kubeconfig},
+		}
+		if spec.Version != "" {
+			c.create = append(c.create, "--version", spec.Version)
+		}
+		if spec.NodeSize != "" {
+			c.create = append(c.create, "--node-type", spec.NodeSize)
+		}
+		c.create = append(c.create, spec.ExtraArgs...)
+		return c
+
+	case ProviderAKS:
+		c := &cloudCommands{
+			binary: "az",
+			exists: []string{"aks", "show", "--resource-group", spec.ResourceGroup, "--name", spec.Name, "--output", "none"},
+			create: []string{"aks", "create", "--resource-group", spec.ResourceGroup, "--name", spec.Name,
+				"--node-count", n, "--generate-ssh-keys", "--output", "none"},
+			credentials: []string{"aks", "get-credentials", "--resource-group", spec.ResourceGroup, "--name", spec.Name,
+				"--file", kubeconfig, "--overwrite-existing"},
+		}
+		if spec.Region != "" {
+			c.create = append(c.create, "--location", spec.Region)
+		}
+		if spec.Version != "" {
+			c.create = append(c.create, "--kubernetes-version", spec.Version)
+		}
+		if spec.NodeSize != "" {
+			c.create = append(c.create, "--node-vm-size", spec.NodeSize)
+		}
+		c.create = append(c.create, spec.ExtraArgs...)
+		return c
+
+	default:
+		location := []string{"--location", spec.Region}
+		if spec.Project != "" {
+			location = append(location, "--project", spec.Project)
+		}
+		c := &cloudCommands{
+			binary:         "gcloud",
+			exists:         append([]string{"container", "clusters", "describe", spec.Name, "--format", "value(name)"}, location...),
+			create:         append([]string{"container", "clusters", "create", spec.Name, "--num-nodes", n}, location...),
+			credentials:    append([]string{"container", "clusters", "get-credentials", spec.Name}, location...),
+			credentialsEnv: []string{"KUBECONFIG=" + kubeconfig},
+		}
+		if spec.Version != "" {
+			c.create = append(c.create, "--cluster-version", spec.Version)
+		}
+		if spec.NodeSize != "" {
+			c.create = append(c.create, "--machine-type", spec.NodeSize)
+		}
+		c.create = append(c.create, spec.ExtraArgs...)
+		return c
+	}
+}
@@ -0,0 +1,147 @@
+// Package provision creates the clusters of environments before they are
+// bootstrapped, with Cluster API or the eksctl, az and gcloud CLIs.
+package provision
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Provider creates clusters.
+type Provider string
+
+const (
+	ProviderCAPI Provider = "capi"
+	ProviderEKS  Provider = "eks"
+	ProviderAKS  Provider = "aks"
+	ProviderGKE  Provider = "gke"
+)
+
+// DefaultNodes is the number of worker nodes of a cluster without nodes.
+const DefaultNodes = 3
+
+// Spec describes a cluster to provision.
+type Spec struct {
+	Name          string
+	Provider      Provider
+	Region        string
+	Version       string
+	Nodes         int
+	NodeSize      string
+	ResourceGroup string
+	Project       string
+	Kubeconfig    string
+	ExtraArgs     []string
+	CAPI          *CAPISpec
+}
+
+// CAPISpec configures a cluster created through a Cluster API management cluster.
+type CAPISpec struct {
+	Infrastructure    string
+	Flavor            string
+	Namespace         string
+	ManagementContext string
+	ControlPlaneNodes int
+}
+
+// Result describes a provisioned cluster.
+type Result struct {
+	Name       string   `json:"name" yaml:"name"`
+	Provider   Provider `json:"provider" yaml:"provider"`
+	URL        string   `json:"url" yaml:"url"`
+	Kubeconfig string   `json:"kubeconfig" yaml:"kubeconfig"`
+	Context    string   `json:"context" yaml:"context"`
+	// Created is false when the cluster already existed.
+	Created bool `json:"created" yaml:"created"`
+	// Manifest is the Cluster API manifest applied to the management cluster.
+	Manifest string `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+}
+
+// runCommand runs a provisioning command with extra environment variables.
+var runCommand = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// Provisioner creates clusters and writes their kubeconfigs.
+type Provisioner struct {
+	stateDir string
+	timeout  time.Duration
+}
+
+// New creates a Provisioner keeping kubeconfigs and manifests in stateDir
+// (default: ~/.gitopsi/clusters).
+func New(stateDir string) *Provisioner {
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		stateDir = filepath.Join(home, ".gitopsi", "clusters")
+	}
+	return &Provisioner{stateDir: stateDir, timeout: 30 * time.Minute}
+}
+
+// KubeconfigPath returns the kubeconfig of a cluster.
+func (p *Provisioner) KubeconfigPath(spec *Spec) string {
+	if spec.Kubeconfig != "" {
+		return spec.Kubeconfig
+	}
+	return filepath.Join(p.stateDir, spec.Name, "kubeconfig")
+}
+
+// Provision creates a cluster unless it exists, writes its kubeconfig and
+// returns how to reach it.
+func (p *Provisioner) Provision(ctx context.Context, spec *Spec) (*Result, error) {
+	kubeconfig := p.KubeconfigPath(spec)
+	if err := os.MkdirAll(filepath.Dir(kubeconfig), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(kubeconfig), err)
+	}
+	result := &Result{Name: spec.Name, Provider: spec.Provider, Kubeconfig: kubeconfig}
+
+	var err error
+	switch spec.Provider {
+	case ProviderCAPI:
+		err = p.provisionCAPI(ctx, spec, result)
+	case ProviderEKS, ProviderAKS, ProviderGKE:
+		err = p.provisionCloud(ctx, spec, result)
+	default:
+		err = fmt.Errorf("unsupported provider %q", spec.Provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision cluster %s: %w", spec.Name, err)
+	}
+
+	if err := os.Chmod(kubeconfig, 0600); err != nil {
+		return nil, fmt.Errorf("failed to protect kubeconfig of cluster %s: %w", spec.Name, err)
+	}
+	server, err := runCommand(ctx, nil, "kubectl", "--kubeconfig", kubeconfig, "config", "view", "--minify",
+		"-o", "jsonpath={.clusters[0].cluster.server}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig of cluster %s: %w", spec.Name, err)
+	}
+	result.URL = strings.TrimSpace(server)
+	if current, err := runCommand(ctx, nil, "kubectl", "--kubeconfig", kubeconfig, "config", "current-context"); err == nil {
+		result.Context = strings.TrimSpace(current)
+	}
+	return result, nil
+}
+
+func nodes(spec *Spec) int {
+	if spec.Nodes > 0 {
+		return spec.Nodes
+	}
+	return DefaultNodes
+}
@@ -0,0 +1,211 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCommands replaces the provisioning commands: commands starting with a
+// key of outputs return its output, or fail when it starts with "error:".
+// Credential commands write a kubeconfig to the path they reference.
+func fakeCommands(t *testing.T, outputs map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	orig := runCommand
+	runCommand = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+		call := strings.Join(append([]string{name}, args...), " ")
+		calls = append(calls, call)
+		for i, arg := range args {
+			if (arg == "--kubeconfig" || arg == "--file") && name != "kubectl" && i+1 < len(args) {
+				if err := os.WriteFile(args[i+1], []byte("kubeconfig"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		for _, e := range env {
+			if path, ok := strings.CutPrefix(e, "KUBECONFIG="); ok {
+				if err := os.WriteFile(path, []byte("kubeconfig"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		for prefix, output := range outputs {
+			if strings.HasPrefix(call, prefix) {
+				if strings.HasPrefix(output, "error:") {
+					return "", fmt.Errorf("%s", output)
+				}
+				return output, nil
+			}
+		}
+		return "", nil
+	}
+	t.Cleanup(func() { runCommand = orig })
+	return &calls
+}
+
+func hasCall(calls []string, prefix string, parts ...string) bool {
+	for _, call := range calls {
+		if !strings.HasPrefix(call, prefix) {
+			continue
+		}
+		found := true
+		for _, part := range parts {
+			if !strings.Contains(call, part) {
+				found = false
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func TestProvisionEKS(t *testing.T) {
+	calls := fakeCommands(t, map[string]string{
+		"eksctl get cluster":   "error: not found",
+		"kubectl --kubeconfig": "https://eks.example.com",
+	})
+	dir := t.TempDir()
+	p := New(dir)
+
+	result, err := p.Provision(context.Background(), &Spec{
+		Name:     "dev",
+		Provider: ProviderEKS,
+		Region:   "eu-west-1",
+		Version:  "1.30",
+		NodeSize: "m5.large",
+	})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if !result.Created {
+		t.Error("Created = false, want true")
+	}
+	if result.URL != "https://eks.example.com" {
+		t.Errorf("URL = %q", result.URL)
+	}
+	if want := filepath.Join(dir, "dev", "kubeconfig"); result.Kubeconfig != want {
+		t.Errorf("Kubeconfig = %q, want %q", result.Kubeconfig, want)
+	}
+	if !hasCall(*calls, "eksctl create cluster --name dev --region eu-west-1", "--nodes 3", "--version 1.30", "--node-type m5.large") {
+		t.Errorf("eksctl create not called as expected: %v", *calls)
+	}
+	info, err := os.Stat(result.Kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("kubeconfig mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestProvisionExistingCluster(t *testing.T) {
+	calls := fakeCommands(t, nil)
+
+	result, err := New(t.TempDir()).Provision(context.Background(), &Spec{
+		Name:          "prod",
+		Provider:      ProviderAKS,
+		ResourceGroup: "platform",
+	})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if result.Created {
+		t.Error("Created = true for an existing cluster")
+	}
+	if hasCall(*calls, "az aks create") {
+		t.Errorf("existing cluster was created again: %v", *calls)
+	}
+	if !hasCall(*calls, "az aks get-credentials --resource-group platform --name prod", "--file") {
+		t.Errorf("credentials not written: %v", *calls)
+	}
+}
+
+func TestProvisionGKE(t *testing.T) {
+	calls := fakeCommands(t, map[string]string{
+		"gcloud container clusters describe": "error: not found",
+	})
+
+	if _, err := New(t.TempDir()).Provision(context.Background(), &Spec{
+		Name:     "staging",
+		Provider: ProviderGKE,
+		Region:   "europe-west1",
+		Project:  "acme",
+		Nodes:    2,
+	}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if !hasCall(*calls, "gcloud container clusters create staging --num-nodes 2", "--location europe-west1", "--project acme") {
+		t.Errorf("gcloud create not called as expected: %v", *calls)
+	}
+}
+
+func TestProvisionCAPI(t *testing.T) {
+	calls := fakeCommands(t, map[string]string{
+		"clusterctl generate cluster": "apiVersion: cluster.x-k8s.io/v1beta1\nkind: Cluster\n",
+		"clusterctl get kubeconfig":   "apiVersion: v1\nkind: Config\n",
+		"kubectl --context mgmt get":  "error: not found",
+	})
+	dir := t.TempDir()
+
+	result, err := New(dir).Provision(context.Background(), &Spec{
+		Name:     "edge",
+		Provider: ProviderCAPI,
+		Version:  "v1.30.2",
+		CAPI: &CAPISpec{
+			Infrastructure:    "docker",
+			Namespace:         "clusters",
+			ManagementContext: "mgmt",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if !result.Created {
+		t.Error("Created = false, want true")
+	}
+
+	manifest, err := os.ReadFile(result.Manifest)
+	if err != nil || !strings.Contains(string(manifest), "kind: Cluster") {
+		t.Errorf("manifest not written: %v %q", err, manifest)
+	}
+	kubeconfig, err := os.ReadFile(result.Kubeconfig)
+	if err != nil || !strings.Contains(string(kubeconfig), "kind: Config") {
+		t.Errorf("kubeconfig not written: %v %q", err, kubeconfig)
+	}
+	for _, want := range []string{
+		"clusterctl generate cluster edge --infrastructure docker --kubernetes-version v1.30.2",
+		"kubectl --context mgmt apply -f " + result.Manifest,
+		"kubectl --context mgmt wait cluster/edge -n clusters --for=condition=Ready",
+		"clusterctl get kubeconfig edge -n clusters --kubeconfig-context mgmt",
+	} {
+		if !hasCall(*calls, want) {
+			t.Errorf("missing call %q in %v", want, *calls)
+		}
+	}
+}
+
+func TestProvisionCAPIRequiresInfrastructure(t *testing.T) {
+	fakeCommands(t, nil)
+
+	if _, err := New(t.TempDir()).Provision(context.Background(), &Spec{Name: "edge", Provider: ProviderCAPI}); err == nil {
+		t.Error("Provision() should fail without capi.infrastructure")
+	}
+}
+
+func TestProvisionCreateFailure(t *testing.T) {
+	fakeCommands(t, map[string]string{
+		"eksctl get":    "error: not found",
+		"eksctl create": "error: quota exceeded",
+	})
+
+	_, err := New(t.TempDir()).Provision(context.Background(), &Spec{Name: "dev", Provider: ProviderEKS, Region: "us-east-1"})
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Provision() error = %v, want the create failure", err)
+	}
+}
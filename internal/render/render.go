@@ -0,0 +1,144 @@
+// Package render builds the final manifests of every environment overlay
+// with the kustomize API, as ArgoCD and Flux apply them.
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// Sections are the directories of a repository holding per-environment
+// overlays.
+var Sections = []string{"infrastructure", "applications"}
+
+// Options configures Render.
+type Options struct {
+	// Path is the repository root or a single kustomization directory.
+	Path string
+	// Environment limits rendering to the overlays of one environment.
+	Environment string
+}
+
+// Overlay is a rendered kustomization.
+type Overlay struct {
+	// Environment is empty for a kustomization rendered on its own.
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+	Section     string `json:"section,omitempty" yaml:"section,omitempty"`
+	Path        string `json:"path" yaml:"path"`
+	Resources   int    `json:"resources" yaml:"resources"`
+	Manifests   string `json:"manifests" yaml:"manifests"`
+}
+
+// Name returns a file name for the rendered overlay.
+func (o Overlay) Name() string {
+	switch {
+	case o.Environment != "" && o.Section != "":
+		return filepath.Join(o.Environment, o.Section+".yaml")
+	case o.Section != "":
+		return o.Section + ".yaml"
+	default:
+		return filepath.Base(o.Path) + ".yaml"
+	}
+}
+
+// Render builds the overlays under opts.Path. A path that is itself a
+// kustomization is rendered as is; a repository root yields its
+// infrastructure and application overlays.
+func Render(opts *Options) ([]Overlay, error) {
+	info, err := os.Stat(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read path: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path must be a directory: %s", opts.Path)
+	}
+
+	var overlays []Overlay
+	if isKustomization(opts.Path) {
+		overlays = append(overlays, Overlay{Path: opts.Path})
+	} else {
+		for _, section := range Sections {
+			dir := filepath.Join(opts.Path, section, "overlays")
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if !e.IsDir() || (opts.Environment != "" && e.Name() != opts.Environment) {
+					continue
+				}
+				path := filepath.Join(dir, e.Name())
+				if isKustomization(path) {
+					overlays = append(overlays, Overlay{Environment: e.Name(), Section: section, Path: path})
+				}
+			}
+		}
+	}
+	if len(overlays) == 0 {
+		if opts.Environment != "" {
+			return nil, fmt.Errorf("no overlays for environment %s under %s", opts.Environment, opts.Path)
+		}
+		return nil, fmt.Errorf("no kustomization found under %s", opts.Path)
+	}
+
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	fs := filesys.MakeFsOnDisk()
+	for i := range overlays {
+		resources, err := k.Run(fs, overlays[i].Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", overlays[i].Path, err)
+		}
+		data, err := resources.AsYaml()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", overlays[i].Path, err)
+		}
+		overlays[i].Resources = resources.Size()
+		overlays[i].Manifests = string(data)
+	}
+	return overlays, nil
+}
+
+// Write writes every overlay to dir as <env>/<section>.yaml and returns the
+// written files.
+func Write(dir string, overlays []Overlay) ([]string, error) {
+	var files []string
+	for _, o := range overlays {
+		path := filepath.Join(dir, o.Name())
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(o.Manifests), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// Join concatenates the overlays into one multi-document stream, each
+// preceded by a comment naming its source.
+func Join(overlays []Overlay) string {
+	var b strings.Builder
+	for i, o := range overlays {
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		fmt.Fprintf(&b, "# Source: %s\n", filepath.ToSlash(o.Path))
+		b.WriteString(o.Manifests)
+	}
+	return b.String()
+}
+
+func isKustomization(dir string) bool {
+	for _, name := range []string{"kustomization.yaml", "kustomization.yml", "Kustomization"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
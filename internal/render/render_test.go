@@ -0,0 +1,125 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "applications/base/api/kustomization.yaml"), "resources:\n  - configmap.yaml\n")
+	writeFile(t, filepath.Join(dir, "applications/base/api/configmap.yaml"),
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: api\ndata:\n  level: info\n")
+	for _, env := range []string{"dev", "prod"} {
+		writeFile(t, filepath.Join(dir, "applications/overlays", env, "kustomization.yaml"),
+			"namespace: demo-"+env+"\nresources:\n  - ../../base/api\n")
+	}
+	return dir
+}
+
+func TestRender(t *testing.T) {
+	dir := testRepo(t)
+
+	overlays, err := Render(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(overlays) != 2 {
+		t.Fatalf("Render() returned %d overlays, want 2", len(overlays))
+	}
+	for _, o := range overlays {
+		if o.Section != "applications" || o.Resources != 1 {
+			t.Errorf("overlay %s = %+v", o.Path, o)
+		}
+		if !strings.Contains(o.Manifests, "namespace: demo-"+o.Environment) {
+			t.Errorf("overlay %s manifests not namespaced:\n%s", o.Environment, o.Manifests)
+		}
+	}
+}
+
+func TestRenderEnvironment(t *testing.T) {
+	dir := testRepo(t)
+
+	overlays, err := Render(&Options{Path: dir, Environment: "prod"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(overlays) != 1 || overlays[0].Environment != "prod" {
+		t.Fatalf("Render() = %+v, want the prod overlay", overlays)
+	}
+
+	if _, err := Render(&Options{Path: dir, Environment: "qa"}); err == nil {
+		t.Error("Render() expected error for an unknown environment")
+	}
+}
+
+func TestRenderKustomization(t *testing.T) {
+	dir := testRepo(t)
+
+	overlays, err := Render(&Options{Path: filepath.Join(dir, "applications/base/api")})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(overlays) != 1 || overlays[0].Name() != "api.yaml" {
+		t.Fatalf("Render() = %+v, want the api kustomization", overlays)
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "kustomization.yaml"), "resources:\n  - missing.yaml\n")
+
+	if _, err := Render(&Options{Path: dir}); err == nil {
+		t.Error("Render() expected error for a broken kustomization")
+	}
+	if _, err := Render(&Options{Path: t.TempDir()}); err == nil {
+		t.Error("Render() expected error without kustomizations")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	overlays, err := Render(&Options{Path: testRepo(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	files, err := Write(out, overlays)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Write() wrote %d files, want 2", len(files))
+	}
+	data, err := os.ReadFile(filepath.Join(out, "prod", "applications.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "namespace: demo-prod") {
+		t.Errorf("prod/applications.yaml = %s", data)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	joined := Join([]Overlay{
+		{Path: "a", Manifests: "kind: A\n"},
+		{Path: "b", Manifests: "kind: B\n"},
+	})
+	want := "# Source: a\nkind: A\n---\n# Source: b\nkind: B\n"
+	if joined != want {
+		t.Errorf("Join() = %q, want %q", joined, want)
+	}
+}
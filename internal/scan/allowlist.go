@@ -0,0 +1,142 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AllowlistFile is the allowlist of a repository, relative to its root.
+const AllowlistFile = ".gitopsi/secrets-allowlist.yaml"
+
+// Allowlist suppresses known or accepted findings.
+type Allowlist struct {
+	// Paths are glob patterns of files not scanned, relative to the
+	// repository root; ** matches any number of directories.
+	Paths []string `yaml:"paths,omitempty"`
+	// Rules are rule IDs not applied.
+	Rules []string `yaml:"rules,omitempty"`
+	// Fingerprints are single findings, as <file>:<rule>:<line>.
+	Fingerprints []string `yaml:"fingerprints,omitempty"`
+
+	// root is the directory paths and fingerprints are relative to.
+	root string
+	// file is the allowlist file, which is not scanned.
+	file string
+}
+
+// LoadAllowlist reads the allowlist at file, whose paths are relative to
+// root. A missing file yields an empty allowlist.
+func LoadAllowlist(file, root string) (*Allowlist, error) {
+	a := &Allowlist{root: root, file: file}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowlist: %w", err)
+	}
+	if err := yaml.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("failed to parse allowlist %s: %w", file, err)
+	}
+	return a, nil
+}
+
+// FindAllowlist loads the AllowlistFile of the repository containing dir:
+// the closest parent with a .gitopsi or .git directory, or dir itself.
+func FindAllowlist(dir string) (*Allowlist, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	root := abs
+	for d := abs; ; d = filepath.Dir(d) {
+		if isDir(filepath.Join(d, ".gitopsi")) || isDir(filepath.Join(d, ".git")) {
+			root = d
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return LoadAllowlist(filepath.Join(root, filepath.FromSlash(AllowlistFile)), root)
+}
+
+// Root returns the directory paths and fingerprints are relative to.
+func (a *Allowlist) Root() string {
+	return a.root
+}
+
+// IsAllowlistFile reports whether file is the allowlist itself.
+func (a *Allowlist) IsAllowlistFile(file string) bool {
+	if a.file == "" {
+		return false
+	}
+	abs, err := filepath.Abs(file)
+	return err == nil && abs == a.file
+}
+
+// Allows reports whether finding f is suppressed.
+func (a *Allowlist) Allows(f Finding) bool {
+	if slices.Contains(a.Rules, f.Rule) || slices.Contains(a.Fingerprints, f.Fingerprint()) {
+		return true
+	}
+	for _, pattern := range a.Paths {
+		if matchPath(pattern, f.File) {
+			return true
+		}
+	}
+	return false
+}
+
+// relative returns file relative to the allowlist root, with slashes.
+func (a *Allowlist) relative(file string) string {
+	if abs, err := filepath.Abs(file); err == nil && a.root != "" {
+		if rel, err := filepath.Rel(a.root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+// matchPath matches a slash-separated path against a glob pattern in which
+// ** matches any number of directories. Patterns without a slash match the
+// file name.
+func matchPath(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,339 @@
+// Package scan detects credentials accidentally committed to a repository:
+// private keys, provider tokens, kubeconfig credentials, unencrypted Secret
+// data and high-entropy values of secret-looking keys.
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of findings.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+)
+
+// InlineAllow on a line suppresses its findings.
+const InlineAllow = "gitopsi:allow"
+
+// maxFileSize bounds the files scanned; larger files are not hand-written
+// configuration.
+const maxFileSize = 1 << 20
+
+// Rule detects one kind of credential on a line.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    string
+	Pattern     *regexp.Regexp
+}
+
+// Rules are the line rules applied to every file.
+var Rules = []Rule{
+	{"private-key", "Private key", SeverityCritical, regexp.MustCompile(`-----BEGIN ((RSA|EC|DSA|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{"aws-access-key", "AWS access key ID", SeverityCritical, regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", "GitHub token", SeverityCritical, regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{"gitlab-token", "GitLab personal access token", SeverityCritical, regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{"slack-token", "Slack token", SeverityCritical, regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"google-api-key", "Google API key", SeverityCritical, regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"age-secret-key", "age secret key", SeverityCritical, regexp.MustCompile(`\bAGE-SECRET-KEY-1[0-9A-Z]{58}\b`)},
+	{"jwt", "JSON Web Token, such as a service account token", SeverityHigh, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"kubeconfig-credentials", "Kubeconfig client key", SeverityCritical, regexp.MustCompile(`\bclient-key-data:\s*["']?[A-Za-z0-9+/=]{20,}`)},
+}
+
+// RuleHighEntropy and RuleSecretData are the rules that need context beyond
+// a pattern.
+const (
+	RuleHighEntropy = "high-entropy"
+	RuleSecretData  = "kubernetes-secret-data"
+)
+
+// secretAssignment matches a value assigned to a key that names a secret.
+var secretAssignment = regexp.MustCompile(`(?i)^\s*(?:-\s*)?["']?[\w.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)["']?\s*[:=]\s*["']?([^\s"'#]+)`)
+
+// tokenValue matches the characters of generated credentials, excluding
+// expressions such as opts.Password or make(...).
+var tokenValue = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// minEntropyLength and minEntropy bound what counts as a random value.
+const (
+	minEntropyLength = 16
+	minEntropy       = 3.5
+)
+
+// Finding is a detected credential.
+type Finding struct {
+	// File is relative to the root of the allowlist, the repository root
+	// unless given.
+	File        string `json:"file" yaml:"file"`
+	Line        int    `json:"line" yaml:"line"`
+	Rule        string `json:"rule" yaml:"rule"`
+	Description string `json:"description" yaml:"description"`
+	Severity    string `json:"severity" yaml:"severity"`
+	// Match is the redacted credential.
+	Match string `json:"match" yaml:"match"`
+}
+
+// Fingerprint identifies the finding in an allowlist.
+func (f Finding) Fingerprint() string {
+	return fmt.Sprintf("%s:%s:%d", f.File, f.Rule, f.Line)
+}
+
+// Options configures Scan.
+type Options struct {
+	// Path is the directory or file to scan.
+	Path string
+	// Files limits the scan to these files, relative to Path.
+	Files []string
+	// Allowlist suppresses findings. Nil loads the AllowlistFile of the
+	// repository containing Path.
+	Allowlist *Allowlist
+}
+
+// Report is the result of a scan.
+type Report struct {
+	Path     string    `json:"path" yaml:"path"`
+	Scanned  int       `json:"scanned" yaml:"scanned"`
+	Findings []Finding `json:"findings" yaml:"findings"`
+	// Allowed is the number of findings suppressed by the allowlist.
+	Allowed int `json:"allowed" yaml:"allowed"`
+}
+
+// Scan scans opts.Path for credentials.
+func Scan(opts *Options) (*Report, error) {
+	info, err := os.Stat(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read path: %w", err)
+	}
+
+	dir := opts.Path
+	if !info.IsDir() {
+		dir = filepath.Dir(opts.Path)
+	}
+	allowlist := opts.Allowlist
+	if allowlist == nil {
+		if allowlist, err = FindAllowlist(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := listFiles(opts, info.IsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Path: opts.Path, Findings: []Finding{}}
+	for _, file := range files {
+		if allowlist.IsAllowlistFile(file) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if len(data) > maxFileSize || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+		report.Scanned++
+
+		for _, f := range scanContent(allowlist.relative(file), data) {
+			if allowlist.Allows(f) {
+				report.Allowed++
+				continue
+			}
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	return report, nil
+}
+
+// listFiles returns the files to scan, skipping the Git directory.
+func listFiles(opts *Options, isDir bool) ([]string, error) {
+	if !isDir {
+		return []string{opts.Path}, nil
+	}
+	if len(opts.Files) > 0 {
+		files := make([]string, 0, len(opts.Files))
+		for _, f := range opts.Files {
+			path := filepath.Join(opts.Path, f)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				files = append(files, path)
+			}
+		}
+		return files, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(opts.Path, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return files, nil
+}
+
+// scanContent applies the rules to the content of file.
+func scanContent(file string, data []byte) []Finding {
+	var findings []Finding
+	lines := map[int]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		lines[n] = line
+		if strings.Contains(line, InlineAllow) {
+			continue
+		}
+		found := false
+		for _, rule := range Rules {
+			if m := rule.Pattern.FindString(line); m != "" {
+				findings = append(findings, newFinding(file, n, rule.ID, rule.Description, rule.Severity, m))
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+		if m := secretAssignment.FindStringSubmatch(line); m != nil && isRandom(m[1]) {
+			findings = append(findings, newFinding(file, n, RuleHighEntropy, "High-entropy value of a secret key", SeverityHigh, m[1]))
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		for _, f := range secretData(file, data) {
+			if !strings.Contains(lines[f.Line], InlineAllow) && !hasFinding(findings, f.Line) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// secretData reports the values of unencrypted Kubernetes Secrets.
+func secretData(file string, data []byte) []Finding {
+	var findings []Finding
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		m := doc.Content[0]
+		if value(m, "kind") == nil || value(m, "kind").Value != "Secret" || value(m, "sops") != nil {
+			continue
+		}
+		for _, key := range []string{"data", "stringData"} {
+			entries := value(m, key)
+			if entries == nil || entries.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(entries.Content); i += 2 {
+				v := entries.Content[i+1]
+				if v.Kind != yaml.ScalarNode || isPlaceholder(v.Value) {
+					continue
+				}
+				findings = append(findings, newFinding(file, v.Line, RuleSecretData,
+					fmt.Sprintf("Unencrypted value of Secret key %s", entries.Content[i].Value), SeverityHigh, v.Value))
+			}
+		}
+	}
+	return findings
+}
+
+func value(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func hasFinding(findings []Finding, line int) bool {
+	for _, f := range findings {
+		if f.Line == line {
+			return true
+		}
+	}
+	return false
+}
+
+func newFinding(file string, line int, rule, description, severity, match string) Finding {
+	return Finding{File: file, Line: line, Rule: rule, Description: description, Severity: severity, Match: redact(match)}
+}
+
+// redact keeps the first characters of a credential.
+func redact(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
+
+// isPlaceholder reports whether a value is empty, a reference or encrypted.
+func isPlaceholder(v string) bool {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return true
+	}
+	for _, prefix := range []string{"${", "$(", "{{", "<", "ENC[", "vault:", "ref+", "REPLACE", "CHANGE"} {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRandom reports whether v looks like a generated credential rather than a
+// word, a reference or a placeholder.
+func isRandom(v string) bool {
+	if len(v) < minEntropyLength || isPlaceholder(v) || !tokenValue.MatchString(v) {
+		return false
+	}
+	return strings.ContainsAny(v, "0123456789") && entropy(v) >= minEntropy
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len([]rune(s)))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
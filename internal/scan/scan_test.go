@@ -0,0 +1,169 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fake builds credentials at runtime so this file does not trip scanners.
+func fake(parts ...string) string {
+	return strings.Join(parts, "")
+}
+
+func rules(findings []Finding) []string {
+	var ids []string
+	for _, f := range findings {
+		ids = append(ids, f.Rule)
+	}
+	return ids
+}
+
+func TestScanContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"private key", "id_rsa", fake("-----BEGIN OPENSSH ", "PRIVATE KEY-----\nb3BlbnNzaA==\n"), "private-key"},
+		{"aws key", "env.sh", fake("export AWS_ACCESS_KEY_ID=AKIA", "ABCDEFGHIJKLMNOP\n"), "aws-access-key"},
+		{"github token", "values.yaml", fake("token: ghp_", strings.Repeat("a1B2", 9), "\n"), "github-token"},
+		{"gitlab token", "ci.yml", fake("GITLAB=glpat-", "abcdefghij0123456789\n"), "gitlab-token"},
+		{"kubeconfig", "kubeconfig", fake("users:\n- name: admin\n  user:\n    client-key-data: LS0tLS1", "CRUdJTiBSU0EgUFJJVkFURSBLRVk=\n"), "kubeconfig-credentials"},
+		{"high entropy", "app.yaml", "config:\n  dbPassword: \"q8Zr2Lm9Xw4Tn7Vb1Kc\"\n", RuleHighEntropy},
+		{"secret data", "secret.yaml", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  url: postgres://app:pw@db/app\n", RuleSecretData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := scanContent(tt.file, []byte(tt.content))
+			if len(findings) != 1 || findings[0].Rule != tt.want {
+				t.Fatalf("scanContent() = %v, want one %s finding", rules(findings), tt.want)
+			}
+			if strings.Contains(tt.content, findings[0].Match) && len(findings[0].Match) > 8 {
+				t.Errorf("match not redacted: %s", findings[0].Match)
+			}
+		})
+	}
+}
+
+func TestScanContentIgnoresSafeValues(t *testing.T) {
+	content := `apiVersion: v1
+kind: Secret
+metadata:
+  name: argocd-sso
+  annotations:
+    passwordPolicy: strong
+stringData:
+  clientSecret: ""
+  token: ${GITHUB_TOKEN}
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: encrypted
+stringData:
+  key: ENC[AES256_GCM,data:abc,iv:def,tag:ghi,type:str]
+sops:
+  version: 3.8.1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  secretName: argocd-sso
+  password: changeme
+  apiKey: "{{ .Values.apiKey }}"
+  token: AbCdEfGhIjKlMnOpQrStUvWx # gitopsi:allow
+`
+	if findings := scanContent("manifests.yaml", []byte(content)); len(findings) != 0 {
+		t.Errorf("scanContent() = %+v, want no findings", findings)
+	}
+}
+
+func TestScanAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	leak := fake("key: AKIA", "ABCDEFGHIJKLMNOP\n")
+	writeFile(t, filepath.Join(dir, "apps", "leak.yaml"), leak)
+	writeFile(t, filepath.Join(dir, "docs", "examples", "leak.yaml"), leak)
+	writeFile(t, filepath.Join(dir, "tests", "fixture.yaml"), leak)
+	writeFile(t, filepath.Join(dir, ".git", "config"), leak)
+	writeFile(t, filepath.Join(dir, AllowlistFile), "paths:\n  - docs/**\nfingerprints:\n  - tests/fixture.yaml:aws-access-key:1\n")
+
+	report, err := Scan(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].File != "apps/leak.yaml" {
+		t.Fatalf("Findings = %+v, want apps/leak.yaml only", report.Findings)
+	}
+	if report.Allowed != 2 {
+		t.Errorf("Allowed = %d, want 2", report.Allowed)
+	}
+	if got := report.Findings[0].Fingerprint(); got != "apps/leak.yaml:aws-access-key:1" {
+		t.Errorf("Fingerprint() = %s", got)
+	}
+
+	// Scanning a subdirectory or a file uses the repository allowlist and paths.
+	report, err = Scan(&Options{Path: filepath.Join(dir, "docs", "examples", "leak.yaml")})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(report.Findings) != 0 || report.Allowed != 1 {
+		t.Errorf("Scan(file) = %+v, want the finding allowed", report)
+	}
+
+	report, err = Scan(&Options{Path: dir, Files: []string{"apps/leak.yaml"}, Allowlist: &Allowlist{Rules: []string{"aws-access-key"}}})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if report.Scanned != 1 || len(report.Findings) != 0 {
+		t.Errorf("Scan(Files) = %+v, want one file scanned and its finding allowed", report)
+	}
+}
+
+func TestScanSkipsBinaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "image.bin"), fake("\x00\x01AKIA", "ABCDEFGHIJKLMNOP"))
+
+	report, err := Scan(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if report.Scanned != 0 || len(report.Findings) != 0 {
+		t.Errorf("Scan() = %+v, want binary files skipped", report)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"docs/**", "docs/a/b.md", true},
+		{"**/testdata/*", "internal/x/testdata/key.pem", true},
+		{"*.pem", "certs/ca.pem", true},
+		{"apps/*.yaml", "apps/nested/x.yaml", false},
+		{"docs/**", "apps/docs.yaml", false},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
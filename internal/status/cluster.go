@@ -0,0 +1,238 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/doctor"
+)
+
+// EnvironmentStatus is the state of the cluster of an environment.
+type EnvironmentStatus struct {
+	Name         string              `json:"name" yaml:"name"`
+	Context      string              `json:"context,omitempty" yaml:"context,omitempty"`
+	Status       Status              `json:"status" yaml:"status"`
+	Message      string              `json:"message" yaml:"message"`
+	Bootstrap    []BootstrapStatus   `json:"bootstrap,omitempty" yaml:"bootstrap,omitempty"`
+	Applications []ApplicationStatus `json:"applications,omitempty" yaml:"applications,omitempty"`
+}
+
+// BootstrapStatus is the health of the controllers of a GitOps tool.
+type BootstrapStatus struct {
+	Tool    string `json:"tool" yaml:"tool"`
+	Status  Status `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+	Details string `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// ApplicationStatus is the sync and health state of an ArgoCD Application or
+// Flux Kustomization.
+type ApplicationStatus struct {
+	Name   string `json:"name" yaml:"name"`
+	Kind   string `json:"kind" yaml:"kind"`
+	Health string `json:"health" yaml:"health"`
+	Sync   string `json:"sync" yaml:"sync"`
+	Status Status `json:"status" yaml:"status"`
+}
+
+func (a *Aggregator) tools() []string {
+	if a.opts.GitOpsTool == "both" {
+		return []string{"argocd", "flux"}
+	}
+	return []string{a.opts.GitOpsTool}
+}
+
+func (a *Aggregator) environment(ctx context.Context, env Environment) EnvironmentStatus {
+	status := EnvironmentStatus{Name: env.Name, Context: env.Context}
+	if a.opts.SkipCluster {
+		status.Status = StatusSkip
+		status.Message = "Cluster not queried"
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.opts.Timeout)
+	defer cancel()
+	kube := &doctor.Env{Kubeconfig: a.opts.Kubeconfig, Context: env.Context, Run: a.run}
+
+	if out, err := kube.Kubectl(ctx, "version", "-o", "json"); err != nil {
+		status.Status = StatusFail
+		status.Message = "Cluster unreachable: " + firstLine(string(out))
+		return status
+	}
+
+	statuses := []Status{}
+	for _, tool := range a.tools() {
+		check := &doctor.GitOpsHealthCheck{Tool: tool}
+		result := check.Run(ctx, kube)
+		status.Bootstrap = append(status.Bootstrap, BootstrapStatus{
+			Tool:    tool,
+			Status:  result.Status,
+			Message: result.Message,
+			Details: result.Details,
+		})
+		statuses = append(statuses, result.Status)
+		if result.Status == StatusWarn && result.Message == "Not installed" {
+			continue
+		}
+
+		apps, err := a.applications(ctx, kube, tool)
+		if err != nil {
+			status.Status = StatusFail
+			status.Message = err.Error()
+			return status
+		}
+		for _, app := range apps {
+			if app.Name == env.Name || strings.HasSuffix(app.Name, "-"+env.Name) {
+				status.Applications = append(status.Applications, app)
+				statuses = append(statuses, app.Status)
+			}
+		}
+	}
+
+	status.Status = worst(statuses...)
+	status.Message = environmentMessage(status)
+	return status
+}
+
+func environmentMessage(status EnvironmentStatus) string {
+	for _, b := range status.Bootstrap {
+		if b.Status != StatusOK {
+			return fmt.Sprintf("%s: %s", b.Tool, b.Message)
+		}
+	}
+	if len(status.Applications) == 0 {
+		return "No applications found"
+	}
+
+	var unhealthy []string
+	for _, app := range status.Applications {
+		if app.Status != StatusOK {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is %s/%s", app.Name, app.Health, app.Sync))
+		}
+	}
+	if len(unhealthy) > 0 {
+		return strings.Join(unhealthy, ", ")
+	}
+	return fmt.Sprintf("%d applications Synced and Healthy", len(status.Applications))
+}
+
+// applications lists the Applications or Kustomizations of a GitOps tool.
+func (a *Aggregator) applications(ctx context.Context, kube *doctor.Env, tool string) ([]ApplicationStatus, error) {
+	var apps []ApplicationStatus
+	switch tool {
+	case "flux":
+		out, err := kube.Kubectl(ctx, "get", "kustomizations.kustomize.toolkit.fluxcd.io", "-n", "flux-system", "-o", "json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Flux Kustomizations: %s", firstLine(string(out)))
+		}
+		apps, err = parseKustomizations(out)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		out, err := kube.Kubectl(ctx, "get", "applications.argoproj.io", "-n", a.opts.ArgoCDNamespace, "-o", "json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ArgoCD Applications: %s", firstLine(string(out)))
+		}
+		apps, err = parseApplications(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps, nil
+}
+
+type resourceList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Health struct {
+				Status string `json:"status"`
+			} `json:"health"`
+			Sync struct {
+				Status string `json:"status"`
+			} `json:"sync"`
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+				Reason string `json:"reason"`
+			} `json:"conditions"`
+			LastAppliedRevision   string `json:"lastAppliedRevision"`
+			LastAttemptedRevision string `json:"lastAttemptedRevision"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+func parseApplications(data []byte) ([]ApplicationStatus, error) {
+	var list resourceList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse ArgoCD Applications: %w", err)
+	}
+
+	apps := make([]ApplicationStatus, 0, len(list.Items))
+	for _, item := range list.Items {
+		app := ApplicationStatus{
+			Name:   item.Metadata.Name,
+			Kind:   "Application",
+			Health: valueOr(item.Status.Health.Status, "Unknown"),
+			Sync:   valueOr(item.Status.Sync.Status, "Unknown"),
+		}
+		switch {
+		case app.Health == "Healthy" && app.Sync == "Synced":
+			app.Status = StatusOK
+		case app.Health == "Degraded" || app.Health == "Missing":
+			app.Status = StatusFail
+		default:
+			app.Status = StatusWarn
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func parseKustomizations(data []byte) ([]ApplicationStatus, error) {
+	var list resourceList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse Flux Kustomizations: %w", err)
+	}
+
+	apps := make([]ApplicationStatus, 0, len(list.Items))
+	for _, item := range list.Items {
+		app := ApplicationStatus{Name: item.Metadata.Name, Kind: "Kustomization", Health: "Unknown", Status: StatusWarn}
+		for _, cond := range item.Status.Conditions {
+			if cond.Type != "Ready" {
+				continue
+			}
+			switch cond.Status {
+			case "True":
+				app.Health, app.Status = "Healthy", StatusOK
+			case "False":
+				app.Health, app.Status = valueOr(cond.Reason, "NotReady"), StatusFail
+			default:
+				app.Health = "Progressing"
+			}
+		}
+
+		app.Sync = "OutOfSync"
+		if item.Status.LastAppliedRevision != "" && item.Status.LastAppliedRevision == item.Status.LastAttemptedRevision {
+			app.Sync = "Synced"
+		} else if app.Status == StatusOK {
+			app.Status = StatusWarn
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,114 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+// PatternStatus is the state of a pattern installed from the marketplace.
+type PatternStatus struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	Health  string `json:"health" yaml:"health"`
+	Status  Status `json:"status" yaml:"status"`
+}
+
+// CredentialStatus is the expiry state of a stored credential.
+type CredentialStatus struct {
+	Name      string     `json:"name" yaml:"name"`
+	Type      string     `json:"type" yaml:"type"`
+	Provider  string     `json:"provider,omitempty" yaml:"provider,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Status    Status     `json:"status" yaml:"status"`
+	Message   string     `json:"message" yaml:"message"`
+}
+
+func (a *Aggregator) patterns(ctx context.Context) []PatternStatus {
+	installer := marketplace.NewInstaller(nil, a.opts.ProjectPath, a.opts.GitOpsTool, a.opts.Platform)
+	installed, err := installer.ListInstalled()
+	if err != nil {
+		return []PatternStatus{{Name: "patterns", Health: err.Error(), Status: StatusFail}}
+	}
+	health, err := installer.GetStatus(ctx)
+	if err != nil {
+		return []PatternStatus{{Name: "patterns", Health: err.Error(), Status: StatusFail}}
+	}
+
+	patterns := make([]PatternStatus, 0, len(installed))
+	for _, p := range installed {
+		status := PatternStatus{Name: p.Pattern.Metadata.Name, Version: p.Pattern.Metadata.Version, Health: health[p.Pattern.Metadata.Name], Status: StatusWarn}
+		if status.Health == "healthy" {
+			status.Status = StatusOK
+		}
+		patterns = append(patterns, status)
+	}
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].Name < patterns[j].Name })
+	return patterns
+}
+
+// credentials returns the stored credentials and warnings about the store.
+func (a *Aggregator) credentials(ctx context.Context) ([]CredentialStatus, []string) {
+	path := "keyring"
+	if a.opts.StoreBackend != auth.StoreKeyring {
+		path = a.opts.StorePath
+		if path == "" {
+			path = auth.GetDefaultStorePath()
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+
+	store, err := auth.OpenStore(a.opts.StoreBackend, path)
+	if errors.Is(err, auth.ErrStoreEncrypted) {
+		return nil, []string{"Credential store is encrypted: set " + auth.PassphraseEnvVar + " to check expiry"}
+	}
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Credential store %s: %v", path, err)}
+	}
+	creds, err := store.List(ctx, "")
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Credential store %s: %v", path, err)}
+	}
+
+	now := a.now()
+	statuses := make([]CredentialStatus, 0, len(creds))
+	for _, cred := range creds {
+		status := CredentialStatus{
+			Name:      cred.Name,
+			Type:      string(cred.Type),
+			Provider:  cred.Provider,
+			ExpiresAt: cred.Metadata.ExpiresAt,
+			Status:    StatusOK,
+			Message:   "No expiry",
+		}
+		switch state, left := cred.Expiry(now, a.opts.ExpiryWarning); state {
+		case auth.ExpiryExpired:
+			status.Status = StatusFail
+			status.Message = fmt.Sprintf("Expired %s ago", FormatDays(-left))
+		case auth.ExpirySoon:
+			status.Status = StatusWarn
+			status.Message = fmt.Sprintf("Expires in %s", FormatDays(left))
+		case auth.ExpiryValid:
+			status.Message = fmt.Sprintf("Expires in %s", FormatDays(left))
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// FormatDays renders a duration in days, or hours below a day.
+func FormatDays(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
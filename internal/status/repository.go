@@ -0,0 +1,201 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RepositoryStatus compares the project with the Git HEAD and the branch it
+// tracks. Ahead and Behind are as of the last fetch.
+type RepositoryStatus struct {
+	Status    Status   `json:"status" yaml:"status"`
+	Message   string   `json:"message" yaml:"message"`
+	Branch    string   `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Head      string   `json:"head,omitempty" yaml:"head,omitempty"`
+	Upstream  string   `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Ahead     int      `json:"ahead" yaml:"ahead"`
+	Behind    int      `json:"behind" yaml:"behind"`
+	Modified  []string `json:"modified,omitempty" yaml:"modified,omitempty"`
+	Untracked []string `json:"untracked,omitempty" yaml:"untracked,omitempty"`
+}
+
+func (a *Aggregator) repository() RepositoryStatus {
+	status, err := repositoryStatus(a.opts.ProjectPath)
+	if err != nil {
+		return RepositoryStatus{Status: StatusFail, Message: err.Error()}
+	}
+	return status
+}
+
+func repositoryStatus(projectPath string) (RepositoryStatus, error) {
+	repo, err := git.PlainOpenWithOptions(projectPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return RepositoryStatus{Status: StatusWarn, Message: "Not a Git repository"}, nil
+	}
+	if err != nil {
+		return RepositoryStatus{}, fmt.Errorf("failed to open Git repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return RepositoryStatus{Status: StatusWarn, Message: "No commits yet"}, nil
+	}
+	if err != nil {
+		return RepositoryStatus{}, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	status := RepositoryStatus{Head: head.Hash().String()[:7]}
+	if head.Name().IsBranch() {
+		status.Branch = head.Name().Short()
+	}
+
+	if err := worktreeChanges(repo, projectPath, &status); err != nil {
+		return RepositoryStatus{}, err
+	}
+	if status.Branch != "" {
+		if err := upstreamDistance(repo, head, &status); err != nil {
+			return RepositoryStatus{}, err
+		}
+	}
+
+	var problems []string
+	if n := len(status.Modified); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d files differ from HEAD", n))
+	}
+	if n := len(status.Untracked); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d untracked files", n))
+	}
+	if status.Ahead > 0 {
+		problems = append(problems, fmt.Sprintf("%d commits not pushed to %s", status.Ahead, status.Upstream))
+	}
+	if status.Behind > 0 {
+		problems = append(problems, fmt.Sprintf("%d commits behind %s", status.Behind, status.Upstream))
+	}
+
+	switch {
+	case len(problems) > 0:
+		status.Status = StatusWarn
+		status.Message = strings.Join(problems, ", ")
+	case status.Upstream == "":
+		status.Status = StatusOK
+		status.Message = "Clean, no upstream branch"
+	default:
+		status.Status = StatusOK
+		status.Message = "Clean, up to date with " + status.Upstream
+	}
+	return status, nil
+}
+
+// worktreeChanges records the files of the project that differ from HEAD.
+func worktreeChanges(repo *git.Repository, projectPath string, status *RepositoryStatus) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	changes, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read worktree status: %w", err)
+	}
+
+	prefix, err := projectPrefix(wt.Filesystem.Root(), projectPath)
+	if err != nil {
+		return err
+	}
+	for file, s := range changes {
+		if prefix != "" && !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		switch {
+		case s.Worktree == git.Untracked:
+			status.Untracked = append(status.Untracked, file)
+		case s.Worktree != git.Unmodified || s.Staging != git.Unmodified:
+			status.Modified = append(status.Modified, file)
+		}
+	}
+	sort.Strings(status.Modified)
+	sort.Strings(status.Untracked)
+	return nil
+}
+
+// projectPrefix returns the slash separated path of the project in the
+// repository, with a trailing slash, or "" at the repository root.
+func projectPrefix(root, projectPath string) (string, error) {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel) + "/", nil
+}
+
+// upstreamDistance counts the commits between HEAD and the branch it tracks,
+// origin/<branch> unless configured otherwise.
+func upstreamDistance(repo *git.Repository, head *plumbing.Reference, status *RepositoryStatus) error {
+	remote, branch := git.DefaultRemoteName, status.Branch
+	if cfg, err := repo.Config(); err == nil {
+		if b, ok := cfg.Branches[status.Branch]; ok && b.Remote != "" {
+			remote, branch = b.Remote, b.Merge.Short()
+		}
+	}
+
+	upstream, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, branch), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s/%s: %w", remote, branch, err)
+	}
+	status.Upstream = remote + "/" + branch
+
+	local, err := ancestors(repo, head.Hash())
+	if err != nil {
+		return err
+	}
+	tracked, err := ancestors(repo, upstream.Hash())
+	if err != nil {
+		return err
+	}
+	for hash := range local {
+		if !tracked[hash] {
+			status.Ahead++
+		}
+	}
+	for hash := range tracked {
+		if !local[hash] {
+			status.Behind++
+		}
+	}
+	return nil
+}
+
+func ancestors(repo *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	iter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", from.String()[:7], err)
+	}
+	seen := map[plumbing.Hash]bool{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", from.String()[:7], err)
+	}
+	return seen, nil
+}
@@ -0,0 +1,225 @@
+// Package status aggregates the state of a gitopsi project and its clusters
+// into a single report: local changes against Git, the bootstrap and
+// application state of every environment, installed patterns and credential
+// expiry.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/doctor"
+	"github.com/ihsanmokhlisse/gitopsi/internal/logging"
+)
+
+// Status is the outcome of a part of the report.
+type Status = doctor.Status
+
+const (
+	StatusOK   = doctor.StatusOK
+	StatusWarn = doctor.StatusWarn
+	StatusFail = doctor.StatusFail
+	StatusSkip = doctor.StatusSkip
+)
+
+// DefaultExpiryWarning is how long before expiry credentials are reported.
+const DefaultExpiryWarning = 14 * 24 * time.Hour
+
+// Environment is an environment whose cluster is queried.
+type Environment struct {
+	Name string
+	// Context is the kubeconfig context of the cluster; empty uses the
+	// current context.
+	Context string
+}
+
+// Options configures an Aggregator.
+type Options struct {
+	// ProjectPath is the generated GitOps repository.
+	ProjectPath string
+	// Project is the project name, used in the report.
+	Project string
+	// GitOpsTool is argocd, flux or both.
+	GitOpsTool string
+	// Platform is passed to the pattern installer.
+	Platform string
+	// ArgoCDNamespace is where ArgoCD Applications are read from.
+	ArgoCDNamespace string
+	// Environments are the environments to report; their clusters are
+	// queried unless SkipCluster is set.
+	Environments []Environment
+	// Kubeconfig is an optional path to a kubeconfig file.
+	Kubeconfig string
+	// StoreBackend is the credential store backend, auth.StoreFile (the
+	// default) or auth.StoreKeyring.
+	StoreBackend string
+	// StorePath is the file store location.
+	StorePath string
+	// ExpiryWarning is how long before expiry credentials are reported.
+	ExpiryWarning time.Duration
+	// Timeout bounds the queries of each cluster.
+	Timeout     time.Duration
+	SkipCluster bool
+}
+
+// Report is the aggregated status of a project.
+type Report struct {
+	Project      string              `json:"project" yaml:"project"`
+	GitOpsTool   string              `json:"gitops_tool" yaml:"gitops_tool"`
+	CheckedAt    time.Time           `json:"checked_at" yaml:"checked_at"`
+	Status       Status              `json:"status" yaml:"status"`
+	Repository   RepositoryStatus    `json:"repository" yaml:"repository"`
+	Environments []EnvironmentStatus `json:"environments" yaml:"environments"`
+	Patterns     []PatternStatus     `json:"patterns" yaml:"patterns"`
+	Credentials  []CredentialStatus  `json:"credentials" yaml:"credentials"`
+	// Warnings lists everything needing attention, one line each.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// ToJSON renders the report as indented JSON.
+func (r *Report) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ToYAML renders the report as YAML.
+func (r *Report) ToYAML() (string, error) {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// HasFailures reports whether any part of the report failed.
+func (r *Report) HasFailures() bool {
+	return r.Status == StatusFail
+}
+
+// Aggregator builds status reports.
+type Aggregator struct {
+	opts *Options
+	run  doctor.CommandRunner
+	now  func() time.Time
+}
+
+// New creates an Aggregator.
+func New(opts *Options) *Aggregator {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.ProjectPath == "" {
+		opts.ProjectPath = "."
+	}
+	if opts.GitOpsTool == "" {
+		opts.GitOpsTool = "argocd"
+	}
+	if opts.ArgoCDNamespace == "" {
+		opts.ArgoCDNamespace = "argocd"
+	}
+	if opts.ExpiryWarning == 0 {
+		opts.ExpiryWarning = DefaultExpiryWarning
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &Aggregator{opts: opts, run: runCommand, now: time.Now}
+}
+
+// SetCommandRunner replaces the command runner (used for testing).
+func (a *Aggregator) SetCommandRunner(run doctor.CommandRunner) {
+	a.run = run
+}
+
+// SetClock replaces the clock used for credential expiry (used for testing).
+func (a *Aggregator) SetClock(now func() time.Time) {
+	a.now = now
+}
+
+// Run collects the report. Problems are recorded in the report rather than
+// returned, so one unreachable cluster does not hide the rest.
+func (a *Aggregator) Run(ctx context.Context) *Report {
+	report := &Report{
+		Project:    a.opts.Project,
+		GitOpsTool: a.opts.GitOpsTool,
+		CheckedAt:  a.now().UTC(),
+	}
+
+	report.Repository = a.repository()
+	if report.Repository.Status != StatusOK {
+		report.Warnings = append(report.Warnings, "Repository: "+report.Repository.Message)
+	}
+
+	for _, env := range a.opts.Environments {
+		status := a.environment(ctx, env)
+		report.Environments = append(report.Environments, status)
+		if status.Status == StatusWarn || status.Status == StatusFail {
+			report.Warnings = append(report.Warnings, "Environment "+env.Name+": "+status.Message)
+		}
+	}
+
+	report.Patterns = a.patterns(ctx)
+	for _, p := range report.Patterns {
+		if p.Status != StatusOK {
+			report.Warnings = append(report.Warnings, "Pattern "+p.Name+": "+p.Health)
+		}
+	}
+
+	var credentialWarnings []string
+	report.Credentials, credentialWarnings = a.credentials(ctx)
+	for _, c := range report.Credentials {
+		if c.Status != StatusOK {
+			report.Warnings = append(report.Warnings, "Credential "+c.Name+": "+c.Message)
+		}
+	}
+	report.Warnings = append(report.Warnings, credentialWarnings...)
+
+	report.Status = report.overall()
+	return report
+}
+
+// overall returns the worst status of the report.
+func (r *Report) overall() Status {
+	statuses := []Status{r.Repository.Status}
+	for _, e := range r.Environments {
+		statuses = append(statuses, e.Status)
+	}
+	for _, p := range r.Patterns {
+		statuses = append(statuses, p.Status)
+	}
+	for _, c := range r.Credentials {
+		statuses = append(statuses, c.Status)
+	}
+	return worst(statuses...)
+}
+
+// worst returns the most severe status, ignoring skipped parts.
+func worst(statuses ...Status) Status {
+	result := StatusOK
+	for _, s := range statuses {
+		switch {
+		case s == StatusFail:
+			return StatusFail
+		case s == StatusWarn:
+			result = StatusWarn
+		}
+	}
+	return result
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return logging.CombinedOutput(exec.CommandContext(ctx, name, args...))
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,233 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/auth"
+)
+
+// fakeCluster answers kubectl invocations from a table of argument strings.
+type fakeCluster struct {
+	responses map[string]string
+}
+
+func (f *fakeCluster) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if out, ok := f.responses[name+" "+strings.Join(args, " ")]; ok {
+		return []byte(out), nil
+	}
+	return []byte("error: not found"), errors.New("exit status 1")
+}
+
+func argoCDCluster(kubeContext, applications string) *fakeCluster {
+	prefix := "kubectl --context " + kubeContext + " "
+	responses := map[string]string{
+		prefix + "version -o json":                                "{}",
+		prefix + "get namespace argocd":                           "namespace/argocd",
+		prefix + "get applications.argoproj.io -n argocd -o json": applications,
+	}
+	for _, deploy := range []string{"argocd-server", "argocd-repo-server", "argocd-applicationset-controller"} {
+		responses[prefix+"get deployment "+deploy+" -n argocd -o jsonpath={.status.availableReplicas}"] = "1"
+	}
+	return &fakeCluster{responses: responses}
+}
+
+const testApplications = `{"items": [
+  {"metadata": {"name": "shop-apps-prod"}, "status": {"health": {"status": "Healthy"}, "sync": {"status": "Synced"}}},
+  {"metadata": {"name": "shop-infra-prod"}, "status": {"health": {"status": "Degraded"}, "sync": {"status": "Synced"}}},
+  {"metadata": {"name": "shop-apps-dev"}, "status": {"health": {"status": "Progressing"}, "sync": {"status": "OutOfSync"}}}
+]}`
+
+func commit(t *testing.T, repo *git.Repository, message string) plumbing.Hash {
+	t.Helper()
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.AddGlob("."))
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	hash, err := wt.Commit(message, &git.CommitOptions{Author: sig, Committer: sig})
+	require.NoError(t, err)
+	return hash
+}
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestRepositoryStatus(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "shop")
+	writeFile(t, project, "applications/overlays/prod/kustomization.yaml", "resources: []\n")
+
+	status, err := repositoryStatus(project)
+	require.NoError(t, err)
+	assert.Equal(t, StatusWarn, status.Status)
+	assert.Equal(t, "Not a Git repository", status.Message)
+
+	repo, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+	first := commit(t, repo, "Initial commit")
+
+	status, err = repositoryStatus(project)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOK, status.Status)
+	assert.Equal(t, "master", status.Branch)
+	assert.Equal(t, first.String()[:7], status.Head)
+	assert.Equal(t, "Clean, no upstream branch", status.Message)
+
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "master"), first)))
+	status, err = repositoryStatus(project)
+	require.NoError(t, err)
+	assert.Equal(t, "Clean, up to date with origin/master", status.Message)
+
+	writeFile(t, project, "applications/overlays/prod/kustomization.yaml", "resources:\n  - ../../base\n")
+	commit(t, repo, "Change prod")
+	writeFile(t, project, "applications/overlays/prod/kustomization.yaml", "resources: []\n")
+	writeFile(t, project, "applications/overlays/dev/kustomization.yaml", "resources: []\n")
+	writeFile(t, root, "README.md", "outside the project\n")
+
+	status, err = repositoryStatus(project)
+	require.NoError(t, err)
+	assert.Equal(t, StatusWarn, status.Status)
+	assert.Equal(t, 1, status.Ahead)
+	assert.Equal(t, 0, status.Behind)
+	assert.Equal(t, []string{"shop/applications/overlays/prod/kustomization.yaml"}, status.Modified)
+	assert.Equal(t, []string{"shop/applications/overlays/dev/kustomization.yaml"}, status.Untracked, "files outside the project are ignored")
+	assert.Equal(t, "1 files differ from HEAD, 1 untracked files, 1 commits not pushed to origin/master", status.Message)
+}
+
+func TestAggregator_Environment(t *testing.T) {
+	a := New(&Options{Project: "shop", ArgoCDNamespace: "argocd"})
+	a.SetCommandRunner(argoCDCluster("prod-cluster", testApplications).run)
+
+	status := a.environment(context.Background(), Environment{Name: "prod", Context: "prod-cluster"})
+	assert.Equal(t, StatusFail, status.Status)
+	require.Len(t, status.Bootstrap, 1)
+	assert.Equal(t, StatusOK, status.Bootstrap[0].Status)
+	assert.Equal(t, "Healthy (3/3 components) in argocd", status.Bootstrap[0].Message)
+	assert.Equal(t, []ApplicationStatus{
+		{Name: "shop-apps-prod", Kind: "Application", Health: "Healthy", Sync: "Synced", Status: StatusOK},
+		{Name: "shop-infra-prod", Kind: "Application", Health: "Degraded", Sync: "Synced", Status: StatusFail},
+	}, status.Applications, "applications of other environments are left out")
+	assert.Equal(t, "shop-infra-prod is Degraded/Synced", status.Message)
+
+	status = a.environment(context.Background(), Environment{Name: "staging", Context: "staging-cluster"})
+	assert.Equal(t, StatusFail, status.Status)
+	assert.Equal(t, "Cluster unreachable: error: not found", status.Message)
+
+	a.opts.SkipCluster = true
+	status = a.environment(context.Background(), Environment{Name: "prod", Context: "prod-cluster"})
+	assert.Equal(t, StatusSkip, status.Status)
+}
+
+func TestParseKustomizations(t *testing.T) {
+	apps, err := parseKustomizations([]byte(`{"items": [
+	  {"metadata": {"name": "shop-apps-prod"}, "status": {"conditions": [{"type": "Ready", "status": "True"}], "lastAppliedRevision": "main@sha1:abc", "lastAttemptedRevision": "main@sha1:abc"}},
+	  {"metadata": {"name": "shop-infra-prod"}, "status": {"conditions": [{"type": "Ready", "status": "False", "reason": "BuildFailed"}], "lastAttemptedRevision": "main@sha1:def"}}
+	]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []ApplicationStatus{
+		{Name: "shop-apps-prod", Kind: "Kustomization", Health: "Healthy", Sync: "Synced", Status: StatusOK},
+		{Name: "shop-infra-prod", Kind: "Kustomization", Health: "BuildFailed", Sync: "OutOfSync", Status: StatusFail},
+	}, apps)
+
+	_, err = parseKustomizations([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestAggregator_Run(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, project, ".gitopsi/patterns.yaml", `patterns:
+  monitoring:
+    pattern:
+      metadata:
+        name: monitoring
+        version: 1.2.0
+    status: installed
+    paths:
+      - `+filepath.Join(project, "infrastructure/base/monitoring")+`
+`)
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	storePath := filepath.Join(t.TempDir(), "credentials.yaml")
+	store, err := auth.NewFileStore(storePath)
+	require.NoError(t, err)
+	soon, expired := now.Add(3*24*time.Hour), now.Add(-2*time.Hour)
+	for name, expires := range map[string]*time.Time{"github": &soon, "registry": &expired, "cluster": nil} {
+		require.NoError(t, store.Save(context.Background(), &auth.Credential{
+			Name: name, Type: auth.CredentialTypeGit, Method: auth.MethodToken,
+			Metadata: auth.CredentialMetadata{ExpiresAt: expires},
+		}))
+	}
+
+	a := New(&Options{
+		ProjectPath:  project,
+		Project:      "shop",
+		Environments: []Environment{{Name: "prod"}},
+		StorePath:    storePath,
+		SkipCluster:  true,
+	})
+	a.SetClock(func() time.Time { return now })
+	report := a.Run(context.Background())
+
+	assert.Equal(t, StatusFail, report.Status)
+	assert.True(t, report.HasFailures())
+	assert.Equal(t, now, report.CheckedAt)
+	assert.Equal(t, []PatternStatus{{Name: "monitoring", Version: "1.2.0", Health: "degraded", Status: StatusWarn}}, report.Patterns)
+	require.Len(t, report.Credentials, 3)
+	assert.Equal(t, "No expiry", report.Credentials[0].Message)
+	assert.Equal(t, StatusWarn, report.Credentials[1].Status)
+	assert.Equal(t, "Expires in 3d", report.Credentials[1].Message)
+	assert.Equal(t, StatusFail, report.Credentials[2].Status)
+	assert.Equal(t, "Expired 2h ago", report.Credentials[2].Message)
+	assert.Equal(t, []string{
+		"Repository: Not a Git repository",
+		"Pattern monitoring: degraded",
+		"Credential github: Expires in 3d",
+		"Credential registry: Expired 2h ago",
+	}, report.Warnings)
+
+	out, err := report.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, out, `"status": "fail"`)
+	out, err = report.ToYAML()
+	require.NoError(t, err)
+	assert.Contains(t, out, "project: shop")
+}
+
+func TestAggregator_KeyringCredentials(t *testing.T) {
+	keyring.MockInit()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	require.NoError(t, auth.NewKeyringStore("").Save(context.Background(), &auth.Credential{
+		Name: "github", Type: auth.CredentialTypeGit, Method: auth.MethodToken,
+		Metadata: auth.CredentialMetadata{ExpiresAt: &expired},
+	}))
+
+	a := New(&Options{
+		StoreBackend: auth.StoreKeyring,
+		StorePath:    filepath.Join(t.TempDir(), "missing.yaml"),
+	})
+	a.SetClock(func() time.Time { return now })
+	creds, warnings := a.credentials(context.Background())
+
+	assert.Empty(t, warnings)
+	require.Len(t, creds, 1)
+	assert.Equal(t, "github", creds[0].Name)
+	assert.Equal(t, StatusFail, creds[0].Status)
+	assert.Equal(t, "Expired 1h ago", creds[0].Message)
+}
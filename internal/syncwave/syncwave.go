@@ -0,0 +1,72 @@
+// Package syncwave orders ArgoCD resources by their dependencies with
+// sync-wave annotations.
+package syncwave
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Annotation is the annotation ArgoCD orders the resources of a sync by:
+// lower waves are applied, and become healthy, before higher ones.
+const Annotation = "argocd.argoproj.io/sync-wave"
+
+// Compute returns the wave of every node of the dependency graph, mapping a
+// node to the nodes it depends on. Nodes without dependencies are in wave 0
+// and every other node is one wave after its latest dependency. It fails on
+// dependencies missing from the graph and on cycles.
+func Compute(dependsOn map[string][]string) (map[string]int, error) {
+	names := make([]string, 0, len(dependsOn))
+	for name := range dependsOn {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, dep := range dependsOn[name] {
+			if _, ok := dependsOn[dep]; !ok {
+				return nil, fmt.Errorf("%s depends on unknown %s", name, dep)
+			}
+		}
+	}
+
+	waves := make(map[string]int, len(dependsOn))
+	visiting := map[string]bool{}
+	var visit func(name string, path []string) (int, error)
+	visit = func(name string, path []string) (int, error) {
+		if wave, ok := waves[name]; ok {
+			return wave, nil
+		}
+		path = append(path, name)
+		if visiting[name] {
+			return 0, fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		visiting[name] = true
+
+		wave := 0
+		for _, dep := range dependsOn[name] {
+			depWave, err := visit(dep, path)
+			if err != nil {
+				return 0, err
+			}
+			if depWave+1 > wave {
+				wave = depWave + 1
+			}
+		}
+		waves[name] = wave
+		return wave, nil
+	}
+
+	for _, name := range names {
+		if _, err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return waves, nil
+}
+
+// Value formats a wave as the annotation value.
+func Value(wave int) string {
+	return strconv.Itoa(wave)
+}
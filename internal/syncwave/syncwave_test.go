@@ -0,0 +1,43 @@
+package syncwave
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	waves, err := Compute(map[string][]string{
+		"cert-manager": nil,
+		"issuers":      {"cert-manager"},
+		"ingress":      nil,
+		"api":          {"issuers", "ingress"},
+		"frontend":     {"api"},
+	})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	want := map[string]int{"cert-manager": 0, "ingress": 0, "issuers": 1, "api": 2, "frontend": 3}
+	if !reflect.DeepEqual(waves, want) {
+		t.Errorf("Compute() = %v, want %v", waves, want)
+	}
+}
+
+func TestCompute_Errors(t *testing.T) {
+	tests := map[string]struct {
+		graph map[string][]string
+		want  string
+	}{
+		"unknown": {map[string][]string{"api": {"db"}}, "api depends on unknown db"},
+		"cycle":   {map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, "dependency cycle: a -> b -> c -> a"},
+		"self":    {map[string][]string{"a": {"a"}}, "dependency cycle: a -> a"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compute(tt.graph)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compute() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
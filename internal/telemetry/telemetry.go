@@ -0,0 +1,136 @@
+// Package telemetry instruments long gitopsi operations with OpenTelemetry
+// spans and metrics, and logs their results. Spans and metrics are dropped
+// until Setup installs exporters, which only happens when the user opts in.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer and meter of gitopsi.
+const instrumentationName = "github.com/ihsanmokhlisse/gitopsi"
+
+// Options configures the export of telemetry.
+type Options struct {
+	// Endpoint is the OTLP/HTTP endpoint URL; empty uses the OTEL_EXPORTER_OTLP_*
+	// environment variables or https://localhost:4318.
+	Endpoint string
+	// Version is the gitopsi version reported as service.version.
+	Version string
+}
+
+// Setup installs OTLP exporters for traces and metrics as the global
+// OpenTelemetry providers. The returned function flushes and stops them.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("gitopsi"),
+		semconv.ServiceVersion(opts.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry resource: %w", err)
+	}
+
+	var traceOpts []otlptracehttp.Option
+	var metricOpts []otlpmetrichttp.Option
+	if opts.Endpoint != "" {
+		traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(opts.Endpoint))
+	}
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter), sdktrace.WithResource(res))
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// operationMetrics are the metrics recorded for every operation.
+type operationMetrics struct {
+	operations metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// instruments returns the operation metrics, created once from the global
+// meter, which forwards to the provider installed by Setup.
+var instruments = sync.OnceValue(func() operationMetrics {
+	meter := otel.Meter(instrumentationName)
+	var m operationMetrics
+	m.operations, _ = meter.Int64Counter("gitopsi.operations",
+		metric.WithDescription("Number of gitopsi operations by result"))
+	m.duration, _ = meter.Float64Histogram("gitopsi.operation.duration",
+		metric.WithDescription("Duration of gitopsi operations"), metric.WithUnit("s"))
+	return m
+})
+
+// Start starts a span for an operation, such as "bootstrap" or
+// "generate.applications". The returned function ends it with the result of
+// the operation, records it as the span status, counts the operation and
+// its duration by result and logs them at info level.
+func Start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, operation, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		result := "success"
+		if err != nil {
+			result = "failure"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		duration := time.Since(start)
+		logAttrs := []any{slog.String("operation", operation), slog.String("result", result), slog.Duration("duration", duration)}
+		if err != nil {
+			logAttrs = append(logAttrs, slog.String("error", err.Error()))
+		}
+		slog.InfoContext(ctx, "operation finished", logAttrs...)
+
+		m := instruments()
+		set := metric.WithAttributes(attribute.String("operation", operation), attribute.String("result", result))
+		if m.operations != nil {
+			m.operations.Add(ctx, 1, set)
+		}
+		if m.duration != nil {
+			m.duration.Record(ctx, duration.Seconds(), set)
+		}
+	}
+}
+
+// Run runs fn as an operation started with Start.
+func Run(ctx context.Context, operation string, fn func(context.Context) error, attrs ...attribute.KeyValue) error {
+	ctx, end := Start(ctx, operation, attrs...)
+	err := fn(ctx)
+	end(err)
+	return err
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRun(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	t.Cleanup(func() {
+		_ = tracerProvider.Shutdown(context.Background())
+		_ = meterProvider.Shutdown(context.Background())
+	})
+
+	ctx := context.Background()
+	err := Run(ctx, "bootstrap", func(ctx context.Context) error {
+		return Run(ctx, "bootstrap.install", func(context.Context) error {
+			return errors.New("helm install failed")
+		})
+	}, attribute.String("tool", "argocd"))
+	if err == nil {
+		t.Fatal("Run() should return the error of the operation")
+	}
+	if err := Run(ctx, "generate", func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	ended := spans.GetSpans()
+	if len(ended) != 3 {
+		t.Fatalf("got %d spans, want 3", len(ended))
+	}
+	install, bootstrap, generate := ended[0], ended[1], ended[2]
+	if install.Name != "bootstrap.install" || install.Parent.SpanID() != bootstrap.SpanContext.SpanID() {
+		t.Errorf("bootstrap.install should be a child of bootstrap: %+v", install.Parent)
+	}
+	if install.Status.Code != codes.Error || install.Status.Description != "helm install failed" {
+		t.Errorf("bootstrap.install status = %+v", install.Status)
+	}
+	if len(bootstrap.Attributes) != 1 || bootstrap.Attributes[0] != attribute.String("tool", "argocd") {
+		t.Errorf("bootstrap attributes = %v", bootstrap.Attributes)
+	}
+	if generate.Status.Code == codes.Error {
+		t.Errorf("generate status = %+v", generate.Status)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	histograms := 0
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					op, _ := dp.Attributes.Value("operation")
+					result, _ := dp.Attributes.Value("result")
+					counts[op.AsString()+"/"+result.AsString()] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				histograms += len(data.DataPoints)
+			}
+		}
+	}
+	want := map[string]int64{"bootstrap/failure": 1, "bootstrap.install/failure": 1, "generate/success": 1}
+	for key, n := range want {
+		if counts[key] != n {
+			t.Errorf("gitopsi.operations{%s} = %d, want %d (all: %v)", key, counts[key], n, counts)
+		}
+	}
+	if histograms != 3 {
+		t.Errorf("gitopsi.operation.duration has %d series, want 3", histograms)
+	}
+}
@@ -0,0 +1,213 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// ProjectOverrideDir is the per-project directory searched for template overrides.
+const ProjectOverrideDir = ".gitopsi/templates"
+
+// SourceEmbedded marks a template served from the built-in defaults.
+const SourceEmbedded = "embedded"
+
+var (
+	overridesMu  sync.RWMutex
+	overrideDirs []string
+)
+
+// Info describes a template and where it is loaded from.
+type Info struct {
+	Name string `json:"name" yaml:"name"`
+	// Source is SourceEmbedded or the path of the overriding file.
+	Source string `json:"source" yaml:"source"`
+}
+
+// Overridden reports whether the template is served from a user directory.
+func (i Info) Overridden() bool {
+	return i.Source != SourceEmbedded
+}
+
+// SetOverrideDirs sets the directories searched for template overrides, in
+// priority order. Files use the same relative names as the embedded
+// templates, e.g. kubernetes/deployment.yaml.tmpl. Missing directories are
+// ignored.
+func SetOverrideDirs(dirs ...string) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+
+	overrideDirs = overrideDirs[:0]
+	for _, d := range dirs {
+		if d != "" {
+			overrideDirs = append(overrideDirs, d)
+		}
+	}
+}
+
+// OverrideDirs returns the configured override directories.
+func OverrideDirs() []string {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	return append([]string(nil), overrideDirs...)
+}
+
+// load returns the content of a template, preferring overrides.
+func load(name string) ([]byte, error) {
+	if p := overridePath(name); p != "" {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template override %s: %w", p, err)
+		}
+		return content, nil
+	}
+	return FS.ReadFile("files/" + name)
+}
+
+// overridePath returns the first override file for name, or "".
+func overridePath(name string) string {
+	for _, dir := range OverrideDirs() {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// All returns every embedded template with its effective source, sorted by name.
+func All() ([]Info, error) {
+	var infos []Info
+	err := fs.WalkDir(FS, "files", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := strings.TrimPrefix(p, "files/")
+		source := SourceEmbedded
+		if o := overridePath(name); o != "" {
+			source = o
+		}
+		infos = append(infos, Info{Name: name, Source: source})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// Export copies embedded templates into dir so they can be customized. With
+// no names every template is exported; a name ending in "/" exports a whole
+// directory. Existing files are only replaced when force is set.
+func Export(dir string, force bool, names ...string) ([]string, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	for _, info := range all {
+		if matchesAny(info.Name, names) {
+			selected = append(selected, info.Name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no templates match: %s", strings.Join(names, ", "))
+	}
+
+	written := make([]string, 0, len(selected))
+	for _, name := range selected {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(dest); err == nil && !force {
+			return written, fmt.Errorf("template already exists: %s (use --force to overwrite)", dest)
+		}
+
+		content, err := FS.ReadFile("files/" + name)
+		if err != nil {
+			return written, fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return written, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(dest, content, 0644); err != nil {
+			return written, fmt.Errorf("failed to write template %s: %w", dest, err)
+		}
+		written = append(written, dest)
+	}
+	return written, nil
+}
+
+// ValidateOverrides checks every file in the override directories: it must
+// correspond to an embedded template and, for .tmpl files, parse cleanly.
+// All problems are returned joined.
+func ValidateOverrides() error {
+	known := make(map[string]bool)
+	if err := fs.WalkDir(FS, "files", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			known[strings.TrimPrefix(p, "files/")] = true
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	var errs []error
+	for _, dir := range OverrideDirs() {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+
+			if !known[name] {
+				errs = append(errs, fmt.Errorf("%s: unknown template %s", p, name))
+				return nil
+			}
+			if path.Ext(name) != ".tmpl" {
+				return nil
+			}
+
+			content, err := os.ReadFile(p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p, err))
+				return nil
+			}
+			if _, err := template.New(name).Funcs(funcMap).Parse(string(content)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p, err))
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read override directory %s: %w", dir, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if name == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(name, p)) {
+			return true
+		}
+	}
+	return false
+}
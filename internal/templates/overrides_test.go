@@ -0,0 +1,137 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeOverride(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func useOverrides(t *testing.T, dirs ...string) {
+	t.Helper()
+	SetOverrideDirs(dirs...)
+	t.Cleanup(func() { SetOverrideDirs() })
+}
+
+func TestRenderWithOverride(t *testing.T) {
+	primary := t.TempDir()
+	secondary := t.TempDir()
+	writeOverride(t, primary, "kubernetes/service.yaml.tmpl", "custom-service: {{.Name}}\n")
+	writeOverride(t, secondary, "kubernetes/service.yaml.tmpl", "secondary: {{.Name}}\n")
+	writeOverride(t, secondary, "argocd/project.yaml.tmpl", "custom-project: {{.Name}}\n")
+	useOverrides(t, primary, secondary, filepath.Join(t.TempDir(), "missing"))
+
+	out, err := Render("kubernetes/service.yaml.tmpl", map[string]string{"Name": "web"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if string(out) != "custom-service: web\n" {
+		t.Errorf("Render() = %q, want override from first directory", out)
+	}
+
+	out, err = Render("argocd/project.yaml.tmpl", map[string]string{"Name": "apps"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if string(out) != "custom-project: apps\n" {
+		t.Errorf("Render() = %q, want override from second directory", out)
+	}
+
+	out, err = Render("infrastructure/namespace.yaml.tmpl", map[string]string{"Name": "ns"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(string(out), "kind: Namespace") {
+		t.Errorf("Render() should fall back to embedded template, got %q", out)
+	}
+}
+
+func TestAll(t *testing.T) {
+	dir := t.TempDir()
+	override := writeOverride(t, dir, "kubernetes/deployment.yaml.tmpl", "x")
+	useOverrides(t, dir)
+
+	infos, err := All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+
+	found := false
+	for _, info := range infos {
+		switch info.Name {
+		case "kubernetes/deployment.yaml.tmpl":
+			found = true
+			if info.Source != override || !info.Overridden() {
+				t.Errorf("deployment source = %s, want %s", info.Source, override)
+			}
+		case "kubernetes/service.yaml.tmpl":
+			if info.Overridden() {
+				t.Errorf("service should not be overridden")
+			}
+		}
+	}
+	if !found {
+		t.Error("All() missing kubernetes/deployment.yaml.tmpl")
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+
+	written, err := Export(dir, false, "argocd/", "kubernetes/service.yaml.tmpl")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(written) < 2 {
+		t.Fatalf("Export() wrote %d files, want several", len(written))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "argocd", "project.yaml.tmpl")); err != nil {
+		t.Errorf("expected argocd/project.yaml.tmpl to be exported: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "kubernetes", "deployment.yaml.tmpl")); err == nil {
+		t.Error("kubernetes/deployment.yaml.tmpl should not be exported")
+	}
+
+	if _, err := Export(dir, false, "kubernetes/service.yaml.tmpl"); err == nil {
+		t.Error("Export() should refuse to overwrite without force")
+	}
+	if _, err := Export(dir, true, "kubernetes/service.yaml.tmpl"); err != nil {
+		t.Errorf("Export() with force error = %v", err)
+	}
+	if _, err := Export(dir, false, "nope.tmpl"); err == nil {
+		t.Error("Export() should fail for unknown templates")
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	dir := t.TempDir()
+	useOverrides(t, dir)
+
+	writeOverride(t, dir, "kubernetes/service.yaml.tmpl", "name: {{.Name | indent 2}}\n")
+	if err := ValidateOverrides(); err != nil {
+		t.Errorf("ValidateOverrides() error = %v", err)
+	}
+
+	writeOverride(t, dir, "kubernetes/deployment.yaml.tmpl", "name: {{.Name\n")
+	writeOverride(t, dir, "kubernetes/typo.yaml.tmpl", "x")
+	err := ValidateOverrides()
+	if err == nil {
+		t.Fatal("ValidateOverrides() expected error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "deployment.yaml.tmpl") || !strings.Contains(msg, "unknown template kubernetes/typo.yaml.tmpl") {
+		t.Errorf("ValidateOverrides() error = %v", err)
+	}
+}
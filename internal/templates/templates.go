@@ -4,19 +4,39 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
 //go:embed all:files
 var FS embed.FS
 
+var funcMap = template.FuncMap{
+	"indent": indent,
+	"quote":  strconv.Quote,
+}
+
+// indent prefixes every non-empty line of s with the given number of spaces.
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render executes the named template, preferring a user override when one exists.
 func Render(name string, data any) ([]byte, error) {
-	content, err := FS.ReadFile("files/" + name)
+	content, err := load(name)
 	if err != nil {
 		return nil, fmt.Errorf("template not found: %s: %w", name, err)
 	}
 
-	tmpl, err := template.New(name).Parse(string(content))
+	tmpl, err := template.New(name).Funcs(funcMap).Parse(string(content))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
 	}
@@ -30,7 +50,7 @@ func Render(name string, data any) ([]byte, error) {
 }
 
 func RenderString(tmplContent string, data any) ([]byte, error) {
-	tmpl, err := template.New("inline").Parse(tmplContent)
+	tmpl, err := template.New("inline").Funcs(funcMap).Parse(tmplContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
@@ -43,6 +63,15 @@ func RenderString(tmplContent string, data any) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Raw returns a template file without rendering, preferring a user override.
+func Raw(name string) ([]byte, error) {
+	content, err := load(name)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s: %w", name, err)
+	}
+	return content, nil
+}
+
 func List() ([]string, error) {
 	entries, err := FS.ReadDir("files")
 	if err != nil {
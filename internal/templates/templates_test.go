@@ -679,3 +679,31 @@ func TestListIncludesNewTemplates(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderStringIndent(t *testing.T) {
+	result, err := RenderString("values:\n{{ .Values | indent 2 }}\n", map[string]string{
+		"Values": "a: 1\n\nb:\n  c: 2\n",
+	})
+	if err != nil {
+		t.Fatalf("RenderString() error = %v", err)
+	}
+
+	want := "values:\n  a: 1\n\n  b:\n    c: 2\n"
+	if string(result) != want {
+		t.Errorf("RenderString() = %q, want %q", string(result), want)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	content, err := Raw("charts/app/templates/deployment.yaml")
+	if err != nil {
+		t.Fatalf("Raw() error = %v", err)
+	}
+	if !strings.Contains(string(content), "{{ .Values.replicaCount }}") {
+		t.Error("Raw() should return the chart template unrendered")
+	}
+
+	if _, err := Raw("charts/missing.yaml"); err == nil {
+		t.Error("Raw() expected error for missing file")
+	}
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// PreviewFiles returns the files init would generate for cfg, relative to the
+// output directory and sorted, without writing anything.
+func PreviewFiles(cfg *config.Config) ([]string, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	writer := &output.Writer{
+		DryRun: true,
+		Log:    io.Discard,
+		BeforeWrite: func(relativePath string, _ []byte) error {
+			files = append(files, filepath.ToSlash(relativePath))
+			return nil
+		},
+	}
+	c := *cfg
+	// The preview lists files: it does not query registries.
+	c.Images.PinDigests = false
+	gen := generator.New(&c, writer, false)
+	gen.Log = io.Discard
+	if err := gen.Generate(); err != nil {
+		return nil, fmt.Errorf("failed to generate preview: %w", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+type treeNode struct {
+	children map[string]*treeNode
+}
+
+// renderTree draws paths as a directory tree, truncated to maxLines lines
+// (no limit when maxLines is zero).
+func renderTree(paths []string, maxLines int) string {
+	root := &treeNode{children: map[string]*treeNode{}}
+	for _, p := range paths {
+		node := root
+		for _, part := range strings.Split(path.Clean(p), "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+	}
+
+	var lines []string
+	var walk func(node *treeNode, prefix string)
+	walk = func(node *treeNode, prefix string) {
+		names := make([]string, 0, len(node.children))
+		for name := range node.children {
+			names = append(names, name)
+		}
+		// Directories first, then files, each alphabetically.
+		sort.Slice(names, func(i, j int) bool {
+			di, dj := len(node.children[names[i]].children) > 0, len(node.children[names[j]].children) > 0
+			if di != dj {
+				return di
+			}
+			return names[i] < names[j]
+		})
+		for i, name := range names {
+			child := node.children[name]
+			connector, indent := "├── ", "│   "
+			if i == len(names)-1 {
+				connector, indent = "└── ", "    "
+			}
+			if len(child.children) > 0 {
+				name += "/"
+			}
+			lines = append(lines, prefix+connector+name)
+			walk(child, prefix+indent)
+		}
+	}
+	walk(root, "")
+
+	if maxLines > 0 && len(lines) > maxLines {
+		hidden := len(lines) - maxLines + 1
+		lines = append(lines[:maxLines-1], fmt.Sprintf("… %d more", hidden))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,632 @@
+// Package tui implements the full-screen init wizard. It walks through the
+// project settings, an environment editor and an application editor while
+// previewing the directory tree that will be generated, and ends on a summary
+// screen before anything is written.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+// ErrAborted is returned by Run when the wizard is quit before confirming.
+var ErrAborted = errors.New("wizard aborted")
+
+type step int
+
+const (
+	stepProject step = iota
+	stepEnvironments
+	stepApplications
+	stepSummary
+)
+
+var stepTitles = []string{"Project", "Environments", "Applications", "Summary"}
+
+// Fields of the project form.
+const (
+	fieldName = iota
+	fieldPlatform
+	fieldScope
+	fieldTool
+	fieldOutput
+	fieldGitURL
+	fieldDocs
+)
+
+// Fields of the application form.
+const (
+	appFieldName = iota
+	appFieldImage
+	appFieldPort
+	appFieldReplicas
+)
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	activeStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("10"))
+	mutedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	previewStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
+
+// field is a form field: a choice cycled with left/right when options is
+// set, a text input otherwise.
+type field struct {
+	label   string
+	options []string
+	index   int
+	input   textinput.Model
+}
+
+func newChoice(label string, options []string, value string) field {
+	f := field{label: label, options: options}
+	if i := slices.Index(options, value); i >= 0 {
+		f.index = i
+	}
+	return f
+}
+
+func newText(label, value, placeholder string) field {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = placeholder
+	input.SetValue(value)
+	return field{label: label, input: input}
+}
+
+func (f *field) value() string {
+	if f.options != nil {
+		return f.options[f.index]
+	}
+	return strings.TrimSpace(f.input.Value())
+}
+
+func (f *field) cycle(delta int) {
+	f.index = (f.index + delta + len(f.options)) % len(f.options)
+}
+
+// Model is the bubbletea model of the wizard. It edits a copy of the config
+// it was created with; Config returns the result.
+type Model struct {
+	cfg    *config.Config
+	step   step
+	fields []field
+	focus  int
+
+	envs   []config.Environment
+	apps   []config.Application
+	cursor int
+	// form is the open add form of the environment or application editor.
+	form      []field
+	formFocus int
+
+	message    string
+	preview    []string
+	previewErr error
+	height     int
+
+	done    bool
+	aborted bool
+}
+
+// New creates a wizard prefilled from cfg.
+func New(cfg *config.Config) *Model {
+	c := *cfg
+	docs := "yes"
+	if !cfg.Docs.Readme && !cfg.Docs.Architecture && !cfg.Docs.Onboarding {
+		docs = "no"
+	}
+	outputType := cfg.Output.Type
+	if outputType == "" {
+		outputType = "local"
+	}
+
+	m := &Model{
+		cfg: &c,
+		fields: []field{
+			fieldName:     newText("Project name", cfg.Project.Name, "my-platform"),
+			fieldPlatform: newChoice("Platform", config.ValidPlatforms(), cfg.Platform),
+			fieldScope:    newChoice("Scope", config.ValidScopes(), cfg.Scope),
+			fieldTool:     newChoice("GitOps tool", config.ValidGitOpsTools(), cfg.GitOpsTool),
+			fieldOutput:   newChoice("Output", []string{"local", "git"}, outputType),
+			fieldGitURL:   newText("Git URL", cfg.Git.URL, "git@github.com:org/repo.git"),
+			fieldDocs:     newChoice("Documentation", []string{"yes", "no"}, docs),
+		},
+		envs: slices.Clone(cfg.Environments),
+		apps: slices.Clone(cfg.Apps),
+	}
+	if m.fields[fieldName].value() == "" {
+		m.fields[fieldName].input.SetValue("my-platform")
+	}
+	m.fields[fieldName].input.Focus()
+	m.refresh()
+	return m
+}
+
+// Config returns the config built by the wizard.
+func (m *Model) Config() *config.Config {
+	c := *m.cfg
+	c.Project.Name = m.fields[fieldName].value()
+	c.Platform = m.fields[fieldPlatform].value()
+	c.Cluster.Platform = c.Platform
+	c.Scope = m.fields[fieldScope].value()
+	c.GitOpsTool = m.fields[fieldTool].value()
+	c.Output.Type = m.fields[fieldOutput].value()
+	if url := m.fields[fieldGitURL].value(); url != "" {
+		c.Git.URL = url
+		c.Output.URL = url
+	}
+	docs := m.fields[fieldDocs].value() == "yes"
+	c.Docs.Readme = docs
+	c.Docs.Architecture = docs
+	c.Docs.Onboarding = docs
+	c.Environments = slices.Clone(m.envs)
+	c.Apps = slices.Clone(m.apps)
+	return &c
+}
+
+// Done reports whether the summary was confirmed.
+func (m *Model) Done() bool {
+	return m.done
+}
+
+// refresh regenerates the preview of the directory tree.
+func (m *Model) refresh() {
+	m.preview, m.previewErr = PreviewFiles(m.Config())
+}
+
+func (m *Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			m.aborted = true
+			return m, tea.Quit
+		}
+		m.message = ""
+		switch m.step {
+		case stepProject:
+			return m.updateProject(msg)
+		case stepEnvironments:
+			return m.updateEnvironments(msg)
+		case stepApplications:
+			return m.updateApplications(msg)
+		case stepSummary:
+			return m.updateSummary(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m *Model) updateProject(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := &m.fields[m.focus]
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.aborted = true
+		return m, tea.Quit
+	case tea.KeyUp, tea.KeyShiftTab:
+		m.setFocus(m.focus - 1)
+		return m, nil
+	case tea.KeyDown, tea.KeyTab:
+		m.setFocus(m.focus + 1)
+		return m, nil
+	case tea.KeyEnter:
+		if err := m.validateProject(); err != nil {
+			m.message = err.Error()
+			return m, nil
+		}
+		m.next()
+		return m, nil
+	case tea.KeyLeft, tea.KeyRight:
+		if f.options != nil {
+			if msg.Type == tea.KeyLeft {
+				f.cycle(-1)
+			} else {
+				f.cycle(1)
+			}
+			m.refresh()
+			return m, nil
+		}
+	}
+	if f.options != nil {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	m.refresh()
+	return m, cmd
+}
+
+func (m *Model) setFocus(i int) {
+	m.fields[m.focus].input.Blur()
+	m.focus = (i + len(m.fields)) % len(m.fields)
+	if m.fields[m.focus].options == nil {
+		m.fields[m.focus].input.Focus()
+	}
+}
+
+func (m *Model) validateProject() error {
+	if m.fields[fieldName].value() == "" {
+		return errors.New("project name is required")
+	}
+	if m.fields[fieldOutput].value() == "git" && m.fields[fieldGitURL].value() == "" {
+		return errors.New("git URL is required when output is git")
+	}
+	return nil
+}
+
+func (m *Model) updateEnvironments(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.form != nil {
+		return m.updateForm(msg, m.addEnvironment)
+	}
+	switch msg.String() {
+	case "up", "k":
+		m.moveCursor(-1, len(m.envs))
+	case "down", "j":
+		m.moveCursor(1, len(m.envs))
+	case "a":
+		m.openForm(newText("Name", "", "qa"))
+	case "d", "delete":
+		if len(m.envs) > 0 {
+			m.envs = slices.Delete(m.envs, m.cursor, m.cursor+1)
+			m.moveCursor(0, len(m.envs))
+			m.refresh()
+		}
+	case "p":
+		if len(m.envs) > 0 {
+			m.envs[m.cursor].Protected = !m.envs[m.cursor].Protected
+		}
+	case "enter":
+		if len(m.envs) == 0 {
+			m.message = "at least one environment is required"
+			return m, nil
+		}
+		m.next()
+	case "esc", "b":
+		m.back()
+	}
+	return m, nil
+}
+
+func (m *Model) updateApplications(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.form != nil {
+		return m.updateForm(msg, m.addApplication)
+	}
+	switch msg.String() {
+	case "up", "k":
+		m.moveCursor(-1, len(m.apps))
+	case "down", "j":
+		m.moveCursor(1, len(m.apps))
+	case "a":
+		m.openForm(
+			newText("Name", "", "frontend"),
+			newText("Image", "", "nginx:1.27"),
+			newText("Port", "8080", "8080"),
+			newText("Replicas", "1", "1"),
+		)
+	case "d", "delete":
+		if len(m.apps) > 0 {
+			m.apps = slices.Delete(m.apps, m.cursor, m.cursor+1)
+			m.moveCursor(0, len(m.apps))
+			m.refresh()
+		}
+	case "enter":
+		m.next()
+	case "esc", "b":
+		m.back()
+	}
+	return m, nil
+}
+
+func (m *Model) updateSummary(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "y":
+		// The preview validates the config and runs the generator.
+		if m.previewErr != nil {
+			m.message = m.previewErr.Error()
+			return m, nil
+		}
+		m.done = true
+		return m, tea.Quit
+	case "esc", "b":
+		m.back()
+	case "q":
+		m.aborted = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *Model) openForm(fields ...field) {
+	m.form = fields
+	m.formFocus = 0
+	m.form[0].input.Focus()
+}
+
+// updateForm handles the keys of an open add form; enter submits it to add.
+func (m *Model) updateForm(msg tea.KeyMsg, add func() error) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.form = nil
+		return m, nil
+	case tea.KeyEnter:
+		if err := add(); err != nil {
+			m.message = err.Error()
+			return m, nil
+		}
+		m.form = nil
+		m.refresh()
+		return m, nil
+	case tea.KeyUp, tea.KeyDown, tea.KeyTab, tea.KeyShiftTab:
+		delta := 1
+		if msg.Type == tea.KeyUp || msg.Type == tea.KeyShiftTab {
+			delta = -1
+		}
+		m.form[m.formFocus].input.Blur()
+		m.formFocus = (m.formFocus + delta + len(m.form)) % len(m.form)
+		m.form[m.formFocus].input.Focus()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.form[m.formFocus].input, cmd = m.form[m.formFocus].input.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) addEnvironment() error {
+	name := m.form[0].value()
+	if name == "" {
+		return errors.New("environment name is required")
+	}
+	if slices.ContainsFunc(m.envs, func(e config.Environment) bool { return e.Name == name }) {
+		return fmt.Errorf("environment %s already exists", name)
+	}
+	m.envs = append(m.envs, config.Environment{Name: name})
+	m.cursor = len(m.envs) - 1
+	return nil
+}
+
+func (m *Model) addApplication() error {
+	app := config.Application{
+		Name:  m.form[appFieldName].value(),
+		Image: m.form[appFieldImage].value(),
+	}
+	if app.Name == "" || app.Image == "" {
+		return errors.New("application name and image are required")
+	}
+	if slices.ContainsFunc(m.apps, func(a config.Application) bool { return a.Name == app.Name }) {
+		return fmt.Errorf("application %s already exists", app.Name)
+	}
+	var err error
+	if app.Port, err = strconv.Atoi(m.form[appFieldPort].value()); err != nil || app.Port <= 0 {
+		return fmt.Errorf("invalid port: %s", m.form[appFieldPort].value())
+	}
+	if app.Replicas, err = strconv.Atoi(m.form[appFieldReplicas].value()); err != nil || app.Replicas < 0 {
+		return fmt.Errorf("invalid replicas: %s", m.form[appFieldReplicas].value())
+	}
+	m.apps = append(m.apps, app)
+	m.cursor = len(m.apps) - 1
+	return nil
+}
+
+func (m *Model) moveCursor(delta, n int) {
+	m.cursor += delta
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+func (m *Model) next() {
+	m.step++
+	m.cursor = 0
+}
+
+func (m *Model) back() {
+	if m.step > stepProject {
+		m.step--
+		m.cursor = 0
+	}
+}
+
+func (m *Model) View() string {
+	if m.done || m.aborted {
+		return ""
+	}
+
+	var steps []string
+	for i, title := range stepTitles {
+		if step(i) == m.step {
+			steps = append(steps, activeStyle.Render(title))
+		} else {
+			steps = append(steps, mutedStyle.Render(title))
+		}
+	}
+	header := titleStyle.Render("🎯 gitopsi init") + "  " + strings.Join(steps, mutedStyle.Render(" › "))
+
+	var body, help string
+	switch m.step {
+	case stepProject:
+		body = m.viewProject()
+		help = "↑/↓ move • ←/→ change • enter next • esc quit"
+	case stepEnvironments:
+		body = m.viewEnvironments()
+		help = "a add • d delete • p toggle protected • enter next • esc back"
+	case stepApplications:
+		body = m.viewApplications()
+		help = "a add • d delete • enter next • esc back"
+	case stepSummary:
+		body = m.viewSummary()
+		help = "enter/y generate • esc/b back • q quit"
+	}
+	if m.form != nil {
+		help = "tab next field • enter add • esc cancel"
+	}
+	if m.message != "" {
+		body += "\n\n" + errorStyle.Render(m.message)
+	}
+
+	content := lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().Width(56).Render(body), m.viewPreview())
+	return header + "\n\n" + content + "\n\n" + mutedStyle.Render(help) + "\n"
+}
+
+func (m *Model) viewProject() string {
+	var b strings.Builder
+	for i := range m.fields {
+		f := &m.fields[i]
+		if i == fieldGitURL && m.fields[fieldOutput].value() != "git" && f.value() == "" && m.focus != i {
+			continue
+		}
+		label := fmt.Sprintf("%-14s", f.label)
+		marker := "  "
+		if i == m.focus {
+			marker = activeStyle.Render("› ")
+			label = activeStyle.Render(label)
+		}
+		value := f.input.View()
+		if f.options != nil {
+			value = "‹ " + f.value() + " ›"
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", marker, label, value)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (m *Model) viewEnvironments() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Environments") + "\n\n")
+	if len(m.envs) == 0 {
+		b.WriteString(mutedStyle.Render("  No environments") + "\n")
+	}
+	for i, env := range m.envs {
+		line := env.Name
+		if env.Protected {
+			line += mutedStyle.Render(" (protected)")
+		}
+		b.WriteString(m.listLine(i, line) + "\n")
+	}
+	b.WriteString(m.viewForm())
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (m *Model) viewApplications() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Applications") + "\n\n")
+	if len(m.apps) == 0 {
+		b.WriteString(mutedStyle.Render("  No applications") + "\n")
+	}
+	for i, app := range m.apps {
+		line := fmt.Sprintf("%s %s", app.Name, mutedStyle.Render(fmt.Sprintf("%s :%d ×%d", app.Image, app.Port, app.Replicas)))
+		b.WriteString(m.listLine(i, line) + "\n")
+	}
+	b.WriteString(m.viewForm())
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (m *Model) listLine(i int, line string) string {
+	if i == m.cursor && m.form == nil {
+		return activeStyle.Render("› ") + line
+	}
+	return "  " + line
+}
+
+func (m *Model) viewForm() string {
+	if m.form == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for i := range m.form {
+		label := fmt.Sprintf("%-10s", m.form[i].label)
+		if i == m.formFocus {
+			label = activeStyle.Render(label)
+		}
+		fmt.Fprintf(&b, "  %s %s\n", label, m.form[i].input.View())
+	}
+	return b.String()
+}
+
+func (m *Model) viewSummary() string {
+	cfg := m.Config()
+	var envs, apps []string
+	for _, env := range cfg.Environments {
+		name := env.Name
+		if env.Protected {
+			name += " (protected)"
+		}
+		envs = append(envs, name)
+	}
+	for _, app := range cfg.Apps {
+		apps = append(apps, app.Name)
+	}
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Summary") + "\n\n")
+	writeRow(&b, "Project", cfg.Project.Name)
+	writeRow(&b, "Platform", cfg.Platform)
+	writeRow(&b, "Scope", cfg.Scope)
+	writeRow(&b, "GitOps tool", cfg.GitOpsTool)
+	writeRow(&b, "Output", cfg.Output.Type)
+	if cfg.Git.URL != "" {
+		writeRow(&b, "Git URL", cfg.Git.URL)
+	}
+	writeRow(&b, "Environments", strings.Join(envs, ", "))
+	writeRow(&b, "Applications", valueOrNone(strings.Join(apps, ", ")))
+	writeRow(&b, "Files", strconv.Itoa(len(m.preview)))
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeRow(w io.Writer, label, value string) {
+	fmt.Fprintf(w, "  %-14s %s\n", label+":", value)
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+func (m *Model) viewPreview() string {
+	if m.previewErr != nil {
+		return previewStyle.Render(errorStyle.Render(m.previewErr.Error()))
+	}
+	maxLines := 0
+	if m.height > 0 {
+		// Leave room for the header, the help line and the border.
+		maxLines = max(m.height-8, 5)
+	}
+	return previewStyle.Render(renderTree(m.preview, maxLines))
+}
+
+// Run shows the wizard prefilled from cfg and returns the confirmed config,
+// or ErrAborted when the user quits.
+func Run(cfg *config.Config, in io.Reader, out io.Writer) (*config.Config, error) {
+	m := New(cfg)
+	final, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithInput(in), tea.WithOutput(out)).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run wizard: %w", err)
+	}
+	if result, ok := final.(*Model); !ok || !result.Done() {
+		return nil, ErrAborted
+	}
+	return m.Config(), nil
+}
@@ -0,0 +1,184 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+)
+
+func newTestConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "demo"
+	cfg.Output.Type = "git"
+	cfg.Git.URL = "https://github.com/org/demo.git"
+	cfg.Output.URL = cfg.Git.URL
+	return cfg
+}
+
+func key(m *Model, keys ...string) {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "right":
+			msg = tea.KeyMsg{Type: tea.KeyRight}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m.Update(msg)
+	}
+}
+
+func TestWizardPrefillsFromConfig(t *testing.T) {
+	m := New(newTestConfig())
+
+	cfg := m.Config()
+	assert.Equal(t, "demo", cfg.Project.Name)
+	assert.Equal(t, "kubernetes", cfg.Platform)
+	assert.Equal(t, "argocd", cfg.GitOpsTool)
+	assert.Len(t, cfg.Environments, 3)
+	assert.NoError(t, m.previewErr)
+	assert.NotEmpty(t, m.preview)
+}
+
+func TestWizardProjectForm(t *testing.T) {
+	m := New(newTestConfig())
+
+	key(m, "backspace", "backspace", "backspace", "backspace", "shop")
+	key(m, "down", "right")
+	cfg := m.Config()
+	assert.Equal(t, "shop", cfg.Project.Name)
+	assert.Equal(t, "openshift", cfg.Platform)
+	assert.Equal(t, "openshift", cfg.Cluster.Platform)
+}
+
+func TestWizardRequiresProjectName(t *testing.T) {
+	m := New(newTestConfig())
+
+	key(m, "backspace", "backspace", "backspace", "backspace", "enter")
+	assert.Equal(t, stepProject, m.step)
+	assert.Contains(t, m.message, "project name is required")
+}
+
+func TestWizardEnvironmentEditor(t *testing.T) {
+	m := New(newTestConfig())
+	key(m, "enter")
+	require.Equal(t, stepEnvironments, m.step)
+
+	key(m, "a", "qa", "enter")
+	assert.Equal(t, []string{"dev", "staging", "prod", "qa"}, envNames(m.envs))
+
+	key(m, "a", "qa", "enter")
+	assert.Contains(t, m.message, "already exists")
+	key(m, "esc")
+	assert.Nil(t, m.form)
+
+	key(m, "p")
+	assert.True(t, m.envs[3].Protected)
+
+	m.cursor = 0
+	key(m, "d")
+	assert.Equal(t, []string{"staging", "prod", "qa"}, envNames(m.envs))
+
+	key(m, "d", "d", "d", "enter")
+	assert.Equal(t, stepEnvironments, m.step)
+	assert.Contains(t, m.message, "at least one environment")
+}
+
+func TestWizardApplicationEditor(t *testing.T) {
+	m := New(newTestConfig())
+	key(m, "enter", "enter")
+	require.Equal(t, stepApplications, m.step)
+
+	key(m, "a", "api", "tab", "ghcr.io/org/api:1.0", "tab", "backspace", "backspace", "backspace", "backspace", "9090", "enter")
+	require.Len(t, m.apps, 1)
+	assert.Equal(t, config.Application{Name: "api", Image: "ghcr.io/org/api:1.0", Port: 9090, Replicas: 1}, m.apps[0])
+	assert.Contains(t, m.preview, "demo/applications/base/api/deployment.yaml")
+
+	key(m, "a", "web", "enter")
+	assert.Contains(t, m.message, "name and image are required")
+	key(m, "esc", "d")
+	assert.Empty(t, m.apps)
+}
+
+func TestWizardSummary(t *testing.T) {
+	m := New(newTestConfig())
+	key(m, "enter", "enter", "enter")
+	require.Equal(t, stepSummary, m.step)
+	assert.Contains(t, m.View(), "Summary")
+
+	key(m, "b")
+	assert.Equal(t, stepApplications, m.step)
+
+	key(m, "enter", "y")
+	assert.True(t, m.Done())
+}
+
+func TestWizardSummaryRequiresValidPreview(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Output.Type = "local"
+	cfg.Git.URL = ""
+	cfg.Output.URL = ""
+	m := New(cfg)
+	require.Error(t, m.previewErr)
+	assert.Contains(t, m.View(), "git.url is required")
+
+	key(m, "enter", "enter", "enter", "y")
+	assert.False(t, m.Done())
+	assert.Contains(t, m.message, "git.url is required")
+}
+
+func TestWizardAbort(t *testing.T) {
+	m := New(newTestConfig())
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	assert.True(t, m.aborted)
+	assert.False(t, m.Done())
+	require.NotNil(t, cmd)
+}
+
+func TestPreviewFiles(t *testing.T) {
+	files, err := PreviewFiles(newTestConfig())
+	require.NoError(t, err)
+	assert.Contains(t, files, "demo/README.md")
+
+	cfg := newTestConfig()
+	cfg.Environments = nil
+	_, err = PreviewFiles(cfg)
+	assert.Error(t, err)
+}
+
+func TestRenderTree(t *testing.T) {
+	tree := renderTree([]string{"demo/README.md", "demo/apps/base/kustomization.yaml", "demo/apps/overlays/dev/kustomization.yaml"}, 0)
+	assert.Equal(t, `└── demo/
+    ├── apps/
+    │   ├── base/
+    │   │   └── kustomization.yaml
+    │   └── overlays/
+    │       └── dev/
+    │           └── kustomization.yaml
+    └── README.md`, tree)
+
+	truncated := renderTree([]string{"a/1", "a/2", "a/3", "a/4"}, 3)
+	assert.Equal(t, "└── a/\n    ├── 1\n… 3 more", truncated)
+}
+
+func envNames(envs []config.Environment) []string {
+	names := make([]string, 0, len(envs))
+	for _, env := range envs {
+		names = append(names, env.Name)
+	}
+	return names
+}
@@ -0,0 +1,197 @@
+package updates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+)
+
+// helmRepository is a Flux HelmRepository source.
+type helmRepository struct {
+	url string
+	oci bool
+}
+
+// chartVersion is the pinned version of a Helm chart found in a manifest.
+type chartVersion struct {
+	name    string
+	repo    string
+	version string
+	line    int
+}
+
+// findHelmRepositories returns the HelmRepository sources of content by name.
+func findHelmRepositories(content []byte) map[string]helmRepository {
+	repos := map[string]helmRepository{}
+	for _, root := range documents(content) {
+		if scalar(root, "kind") != "HelmRepository" {
+			continue
+		}
+		name := scalar(lookup(root, "metadata"), "name")
+		spec := lookup(root, "spec")
+		url := scalar(spec, "url")
+		if name == "" || url == "" {
+			continue
+		}
+		repos[name] = helmRepository{url: url, oci: scalar(spec, "type") == "oci" || strings.HasPrefix(url, "oci://")}
+	}
+	return repos
+}
+
+// findCharts returns the chart versions of content: Flux HelmReleases, Argo
+// CD Applications and the helmCharts of kustomizations.
+func findCharts(content []byte, repos map[string]helmRepository) []chartVersion {
+	var charts []chartVersion
+	for _, root := range documents(content) {
+		switch scalar(root, "kind") {
+		case "HelmRelease":
+			spec := lookup(root, "spec", "chart", "spec")
+			source := lookup(spec, "sourceRef")
+			repo, ok := repos[scalar(source, "name")]
+			if !ok || scalar(source, "kind") != "HelmRepository" {
+				continue
+			}
+			url := repo.url
+			if repo.oci && !strings.HasPrefix(url, "oci://") {
+				url = "oci://" + url
+			}
+			charts = appendChart(charts, spec, "chart", "version", url)
+		case "Application":
+			spec := lookup(root, "spec")
+			sources := []*yaml.Node{lookup(spec, "source")}
+			if list := lookup(spec, "sources"); list != nil && list.Kind == yaml.SequenceNode {
+				sources = append(sources, list.Content...)
+			}
+			for _, source := range sources {
+				url := scalar(source, "repoURL")
+				if url != "" && !strings.Contains(url, "://") {
+					// Argo CD writes OCI chart repositories without a scheme.
+					url = "oci://" + url
+				}
+				charts = appendChart(charts, source, "chart", "targetRevision", url)
+			}
+		case "Kustomization", "":
+			list := lookup(root, "helmCharts")
+			if list == nil || list.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, chart := range list.Content {
+				charts = appendChart(charts, chart, "name", "version", scalar(chart, "repo"))
+			}
+		}
+	}
+	return charts
+}
+
+func appendChart(charts []chartVersion, m *yaml.Node, nameKey, versionKey, repo string) []chartVersion {
+	name := scalar(m, nameKey)
+	version := lookup(m, versionKey)
+	if name == "" || repo == "" || version == nil || version.Kind != yaml.ScalarNode || version.Value == "" {
+		return charts
+	}
+	return append(charts, chartVersion{name: name, repo: repo, version: version.Value, line: version.Line})
+}
+
+// chartVersions returns the versions of chart published by the repository at
+// url: the index.yaml of HTTP repositories or the tags of OCI repositories.
+func chartVersions(ctx context.Context, opts *Options, url, chart string) ([]string, error) {
+	if strings.HasPrefix(url, "oci://") {
+		ref, err := images.Parse(strings.TrimSuffix(strings.TrimPrefix(url, "oci://"), "/") + "/" + chart)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := opts.resolver().Tags(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of chart %s: %w", chart, err)
+		}
+		// OCI tags cannot hold "+": Helm pushes build metadata with "_".
+		for i, tag := range tags {
+			tags[i] = strings.ReplaceAll(tag, "_", "+")
+		}
+		return tags, nil
+	}
+
+	index := strings.TrimSuffix(url, "/") + "/index.yaml"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, index, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := opts.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chart repository index %s: %w", index, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chart repository index %s returned %s", index, resp.Status)
+	}
+	var body struct {
+		Entries map[string][]struct {
+			Version    string `yaml:"version"`
+			Deprecated bool   `yaml:"deprecated"`
+		} `yaml:"entries"`
+	}
+	if err := yaml.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse chart repository index %s: %w", index, err)
+	}
+	entries, ok := body.Entries[chart]
+	if !ok {
+		return nil, fmt.Errorf("chart %s not found in %s", chart, url)
+	}
+	var versions []string
+	for _, e := range entries {
+		if !e.Deprecated {
+			versions = append(versions, e.Version)
+		}
+	}
+	return versions, nil
+}
+
+// documents returns the root mappings of the YAML documents of content, none
+// for content that is not YAML.
+func documents(content []byte) []*yaml.Node {
+	var roots []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil
+			}
+			return roots
+		}
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			roots = append(roots, doc.Content[0])
+		}
+	}
+}
+
+func lookup(m *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if m == nil || m.Kind != yaml.MappingNode {
+			return nil
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			if m.Content[i].Value == key {
+				value = m.Content[i+1]
+			}
+		}
+		m = value
+	}
+	return m
+}
+
+func scalar(m *yaml.Node, key string) string {
+	if v := lookup(m, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
@@ -0,0 +1,364 @@
+// Package updates finds outdated dependencies of a GitOps repository: Helm
+// chart versions, container image tags and marketplace patterns. It queries
+// their upstream sources for newer releases and rewrites the manifests to the
+// versions of an update plan.
+package updates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+// Kind is the kind of a dependency.
+type Kind string
+
+const (
+	KindChart   Kind = "chart"
+	KindImage   Kind = "image"
+	KindPattern Kind = "pattern"
+)
+
+// Kinds returns the dependency kinds in the order plans list them.
+func Kinds() []Kind {
+	return []Kind{KindChart, KindImage, KindPattern}
+}
+
+// Update is a dependency with a newer release.
+type Update struct {
+	Kind Kind `json:"kind" yaml:"kind"`
+	// Name is the chart, the image name or the pattern.
+	Name string `json:"name" yaml:"name"`
+	// File and Line locate the version in the repository; patterns have none.
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`
+	Line    int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Current string `json:"current" yaml:"current"`
+	Latest  string `json:"latest" yaml:"latest"`
+	// Major is set when Latest is a new major version.
+	Major bool `json:"major,omitempty" yaml:"major,omitempty"`
+	// Pinned is set for images pinned by digest, which are re-pinned to the
+	// digest of the new tag.
+	Pinned bool `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	// Source is the chart repository or the image registry.
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// Key identifies the dependency bump of u, shared by the updates of every
+// file using the same dependency.
+func (u Update) Key() string {
+	return string(u.Kind) + "/" + u.Name + "@" + u.Latest
+}
+
+// Plan is the result of Check.
+type Plan struct {
+	Path    string   `json:"path" yaml:"path"`
+	Scanned int      `json:"scanned" yaml:"scanned"`
+	Updates []Update `json:"updates" yaml:"updates"`
+	// Errors lists the sources that could not be queried.
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Patterns gives access to the installed marketplace patterns.
+// *marketplace.Marketplace implements it.
+type Patterns interface {
+	ListInstalled() ([]marketplace.InstalledPattern, error)
+	CheckUpdates(ctx context.Context) (map[string]string, error)
+	Update(ctx context.Context, name string, opts marketplace.UpdateOptions) (*marketplace.InstallResult, error)
+}
+
+// Options configures Check and Apply.
+type Options struct {
+	// Path is the repository root, a directory or a single file.
+	Path string
+	// Kinds restricts the dependencies checked; empty checks all of them.
+	Kinds []Kind
+	// SkipMajor ignores releases of a new major version.
+	SkipMajor bool
+	// Resolver queries container registries for tags and digests.
+	Resolver *images.Resolver
+	// Client fetches Helm repository indexes.
+	Client *http.Client
+	// Patterns checks marketplace patterns; nil skips them.
+	Patterns Patterns
+}
+
+func (o *Options) checks(kind Kind) bool {
+	if len(o.Kinds) == 0 {
+		return true
+	}
+	for _, k := range o.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) resolver() *images.Resolver {
+	if o.Resolver == nil {
+		o.Resolver = images.NewResolver(nil)
+	}
+	return o.Resolver
+}
+
+func (o *Options) client() *http.Client {
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return o.Client
+}
+
+// Check scans the YAML files under opts.Path and returns the dependencies
+// with a newer release. Sources that cannot be queried are reported in
+// Plan.Errors instead of failing the check.
+func Check(ctx context.Context, opts *Options) (*Plan, error) {
+	files, err := yamlFiles(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Path: opts.Path, Updates: []Update{}}
+	c := &checker{opts: opts, plan: plan, versions: map[string][]string{}, failed: map[string]bool{}}
+
+	contents := map[string][]byte{}
+	repositories := map[string]helmRepository{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		contents[file] = content
+		for name, repo := range findHelmRepositories(content) {
+			repositories[name] = repo
+		}
+	}
+
+	for _, file := range files {
+		plan.Scanned++
+		rel := relPath(opts.Path, file)
+		if opts.checks(KindChart) {
+			for _, chart := range findCharts(contents[file], repositories) {
+				c.checkChart(ctx, rel, chart)
+			}
+		}
+		if opts.checks(KindImage) {
+			for _, found := range images.Find(contents[file]) {
+				c.checkImage(ctx, rel, found)
+			}
+		}
+	}
+	if opts.checks(KindPattern) && opts.Patterns != nil {
+		if err := c.checkPatterns(ctx); err != nil {
+			plan.Errors = append(plan.Errors, err.Error())
+		}
+	}
+	return plan, nil
+}
+
+// checker queries each source once per Check.
+type checker struct {
+	opts     *Options
+	plan     *Plan
+	versions map[string][]string
+	failed   map[string]bool
+}
+
+// fetch returns the versions published by a source, caching them by key.
+func (c *checker) fetch(key string, list func() ([]string, error)) ([]string, bool) {
+	if versions, ok := c.versions[key]; ok {
+		return versions, true
+	}
+	if c.failed[key] {
+		return nil, false
+	}
+	versions, err := list()
+	if err != nil {
+		c.failed[key] = true
+		c.plan.Errors = append(c.plan.Errors, err.Error())
+		return nil, false
+	}
+	c.versions[key] = versions
+	return versions, true
+}
+
+func (c *checker) add(u Update, candidates []string) {
+	latest, major, ok := newest(u.Current, candidates, c.opts.SkipMajor)
+	if !ok {
+		return
+	}
+	u.Latest, u.Major = latest, major
+	c.plan.Updates = append(c.plan.Updates, u)
+}
+
+func (c *checker) checkChart(ctx context.Context, file string, chart chartVersion) {
+	versions, ok := c.fetch("chart:"+chart.repo+"/"+chart.name, func() ([]string, error) {
+		return chartVersions(ctx, c.opts, chart.repo, chart.name)
+	})
+	if !ok {
+		return
+	}
+	c.add(Update{Kind: KindChart, Name: chart.name, File: file, Line: chart.line, Current: chart.version, Source: chart.repo}, versions)
+}
+
+func (c *checker) checkImage(ctx context.Context, file string, found images.Occurrence) {
+	ref, err := images.Parse(found.Image)
+	if err != nil || ref.Tag == "" || ref.Tag == images.DefaultTag {
+		return
+	}
+	tags, ok := c.fetch("image:"+ref.Registry+"/"+ref.Repository, func() ([]string, error) {
+		return c.opts.resolver().Tags(ctx, ref)
+	})
+	if !ok {
+		return
+	}
+	c.add(Update{Kind: KindImage, Name: ref.Name, File: file, Line: found.Line, Current: ref.Tag, Pinned: ref.Pinned(), Source: ref.Registry}, tags)
+}
+
+func (c *checker) checkPatterns(ctx context.Context) error {
+	installed, err := c.opts.Patterns.ListInstalled()
+	if err != nil {
+		return fmt.Errorf("failed to list installed patterns: %w", err)
+	}
+	if len(installed) == 0 {
+		return nil
+	}
+	latest, err := c.opts.Patterns.CheckUpdates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check pattern updates: %w", err)
+	}
+	sort.Slice(installed, func(i, j int) bool { return installed[i].Pattern.Metadata.Name < installed[j].Pattern.Metadata.Name })
+	for _, p := range installed {
+		name := p.Pattern.Metadata.Name
+		if version, ok := latest[name]; ok {
+			c.add(Update{Kind: KindPattern, Name: name, Current: p.Pattern.Metadata.Version, Source: "marketplace"}, []string{version})
+		}
+	}
+	return nil
+}
+
+// Apply rewrites the repository under opts.Path to the versions of updates.
+// Images pinned by digest are pinned to the digest of their new tag.
+func Apply(ctx context.Context, opts *Options, updates []Update) error {
+	byFile := map[string][]Update{}
+	var files []string
+	for _, u := range updates {
+		if u.Kind == KindPattern {
+			if opts.Patterns == nil {
+				return fmt.Errorf("cannot update pattern %s: marketplace not configured", u.Name)
+			}
+			if _, err := opts.Patterns.Update(ctx, u.Name, marketplace.UpdateOptions{Version: u.Latest}); err != nil {
+				return fmt.Errorf("failed to update pattern %s: %w", u.Name, err)
+			}
+			continue
+		}
+		if _, ok := byFile[u.File]; !ok {
+			files = append(files, u.File)
+		}
+		byFile[u.File] = append(byFile[u.File], u)
+	}
+
+	for _, file := range files {
+		path := filepath.Join(opts.Path, filepath.FromSlash(file))
+		if info, err := os.Stat(opts.Path); err == nil && !info.IsDir() {
+			path = opts.Path
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		fileUpdates := byFile[file]
+		// Edit from the bottom so inserted digest lines do not shift the next updates.
+		sort.SliceStable(fileUpdates, func(i, j int) bool { return fileUpdates[i].Line > fileUpdates[j].Line })
+		for _, u := range fileUpdates {
+			if content, err = apply(ctx, opts, content, u); err != nil {
+				return fmt.Errorf("%s:%d: %w", file, u.Line, err)
+			}
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func apply(ctx context.Context, opts *Options, content []byte, u Update) ([]byte, error) {
+	if u.Kind == KindChart {
+		return setVersion(content, u.Line, u.Current, u.Latest)
+	}
+	digest := ""
+	if u.Pinned {
+		ref, err := images.Parse(u.Name + ":" + u.Latest)
+		if err != nil {
+			return nil, err
+		}
+		if digest, err = opts.resolver().Resolve(ctx, ref); err != nil {
+			return nil, err
+		}
+	}
+	return images.Retag(content, u.Line, u.Latest, digest)
+}
+
+// setVersion replaces version current with latest in the value on line.
+func setVersion(content []byte, line int, current, latest string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return nil, fmt.Errorf("line %d out of range", line)
+	}
+	text := lines[line-1]
+	colon := strings.Index(text, ":")
+	i := strings.Index(text[colon+1:], current)
+	if colon < 0 || i < 0 {
+		return nil, fmt.Errorf("version %s not found", current)
+	}
+	i += colon + 1
+	lines[line-1] = text[:i] + latest + text[i+len(current):]
+	return []byte(strings.Join(lines, "")), nil
+}
+
+func relPath(root, file string) string {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == "." {
+		rel = filepath.Base(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// yamlFiles returns the YAML files under path, skipping the Git and gitopsi
+// metadata directories.
+func yamlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == ".gitopsi" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return files, nil
+}
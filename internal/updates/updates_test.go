@@ -0,0 +1,254 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+	"github.com/ihsanmokhlisse/gitopsi/internal/marketplace"
+)
+
+const digestV2 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+
+// newUpstream serves a Helm repository index and the tags of org/app.
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/charts/index.yaml":
+			fmt.Fprint(w, `apiVersion: v1
+entries:
+  ingress-nginx:
+    - version: 5.0.0
+    - version: 4.11.2
+    - version: 4.11.0
+    - version: 4.12.0-beta.0
+  cert-manager:
+    - version: v1.15.3
+    - version: v1.16.0
+      deprecated: true
+`)
+		case "/v2/org/app/tags/list":
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0","1.1.0-debug","2.0.0","latest"]}`)
+		case "/v2/org/app/manifests/1.1.0":
+			w.Header().Set("Docker-Content-Digest", digestV2)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type fakePatterns struct {
+	updated map[string]string
+}
+
+func (f *fakePatterns) ListInstalled() ([]marketplace.InstalledPattern, error) {
+	p := marketplace.NewPattern("monitoring", "1.0.0", "Monitoring stack")
+	return []marketplace.InstalledPattern{{Pattern: *p}}, nil
+}
+
+func (f *fakePatterns) CheckUpdates(context.Context) (map[string]string, error) {
+	return map[string]string{"monitoring": "1.2.0"}, nil
+}
+
+func (f *fakePatterns) Update(_ context.Context, name string, opts marketplace.UpdateOptions) (*marketplace.InstallResult, error) {
+	f.updated[name] = opts.Version
+	return &marketplace.InstallResult{Pattern: name, Version: opts.Version, Success: true}, nil
+}
+
+func writeRepo(t *testing.T, host string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"infrastructure/ingress.yaml": `apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: charts
+spec:
+  url: https://` + host + `/charts
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: ingress-nginx
+spec:
+  chart:
+    spec:
+      chart: ingress-nginx
+      version: "4.11.0" # pinned
+      sourceRef:
+        kind: HelmRepository
+        name: charts
+`,
+		"argocd/cert-manager.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: cert-manager
+spec:
+  source:
+    repoURL: https://` + host + `/charts
+    chart: cert-manager
+    targetRevision: v1.15.0
+`,
+		"applications/app/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: ` + host + `/org/app:1.0.0@sha256:1111111111111111111111111111111111111111111111111111111111111111
+        - name: sidecar
+          image: ` + host + `/org/app:latest
+`,
+		"broken.yaml": "image: {{ .Values.image }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCheckAndApply(t *testing.T) {
+	server := newUpstream(t)
+	host := strings.TrimPrefix(server.URL, "https://")
+	dir := writeRepo(t, host)
+	resolver := images.NewResolver(nil)
+	resolver.Client = server.Client()
+	patterns := &fakePatterns{updated: map[string]string{}}
+	opts := &Options{Path: dir, Resolver: resolver, Client: server.Client(), Patterns: patterns}
+
+	plan, err := Check(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if plan.Scanned != 4 || len(plan.Errors) != 0 {
+		t.Errorf("Check() scanned %d files, errors %v", plan.Scanned, plan.Errors)
+	}
+	got := map[string]Update{}
+	for _, u := range plan.Updates {
+		got[string(u.Kind)+"/"+u.Name] = u
+	}
+	if len(got) != 4 {
+		t.Fatalf("Check() updates = %+v", plan.Updates)
+	}
+	if u := got["chart/ingress-nginx"]; u.Latest != "5.0.0" || !u.Major || u.Line != 16 || u.File != "infrastructure/ingress.yaml" {
+		t.Errorf("ingress-nginx update = %+v", u)
+	}
+	if u := got["chart/cert-manager"]; u.Latest != "v1.15.3" || u.Major {
+		t.Errorf("cert-manager update = %+v, deprecated versions should be skipped", u)
+	}
+	if u := got["image/"+host+"/org/app"]; u.Latest != "2.0.0" || !u.Pinned || u.Line != 8 {
+		t.Errorf("image update = %+v", u)
+	}
+	if u := got["pattern/monitoring"]; u.Current != "1.0.0" || u.Latest != "1.2.0" {
+		t.Errorf("pattern update = %+v", u)
+	}
+
+	opts.SkipMajor = true
+	opts.Kinds = []Kind{KindChart, KindImage}
+	plan, err = Check(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(plan.Updates) != 3 {
+		t.Fatalf("Check() with SkipMajor updates = %+v", plan.Updates)
+	}
+	if err := Apply(context.Background(), opts, plan.Updates); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	ingress, _ := os.ReadFile(filepath.Join(dir, "infrastructure/ingress.yaml"))
+	if !strings.Contains(string(ingress), `version: "4.11.2" # pinned`) {
+		t.Errorf("Apply() did not update the HelmRelease:\n%s", ingress)
+	}
+	certManager, _ := os.ReadFile(filepath.Join(dir, "argocd/cert-manager.yaml"))
+	if !strings.Contains(string(certManager), "targetRevision: v1.15.3") {
+		t.Errorf("Apply() did not update the Application:\n%s", certManager)
+	}
+	deployment, _ := os.ReadFile(filepath.Join(dir, "applications/app/deployment.yaml"))
+	if !strings.Contains(string(deployment), "/org/app:1.1.0@"+digestV2) || !strings.Contains(string(deployment), "/org/app:latest") {
+		t.Errorf("Apply() did not re-pin the image:\n%s", deployment)
+	}
+
+	if err := Apply(context.Background(), opts, []Update{{Kind: KindPattern, Name: "monitoring", Latest: "1.2.0"}}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if patterns.updated["monitoring"] != "1.2.0" {
+		t.Errorf("Apply() did not update the pattern: %v", patterns.updated)
+	}
+}
+
+func TestCheckReportsUnreachableSources(t *testing.T) {
+	server := newUpstream(t)
+	dir := writeRepo(t, strings.TrimPrefix(server.URL, "https://"))
+	server.Close()
+
+	plan, err := Check(context.Background(), &Options{Path: dir, Kinds: []Kind{KindChart}, Client: server.Client()})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	// Each chart reports its unreachable index once.
+	if len(plan.Updates) != 0 || len(plan.Errors) != 2 {
+		t.Errorf("Check() = %+v", plan)
+	}
+}
+
+func TestApplyPatternWithoutMarketplace(t *testing.T) {
+	err := Apply(context.Background(), &Options{Path: t.TempDir()}, []Update{{Kind: KindPattern, Name: "monitoring"}})
+	if err == nil {
+		t.Error("Apply() should fail to update patterns without a marketplace")
+	}
+}
+
+func TestNewest(t *testing.T) {
+	tests := []struct {
+		current    string
+		candidates []string
+		skipMajor  bool
+		latest     string
+		major      bool
+	}{
+		{"1.25", []string{"1.26", "1.27.1", "2", "1.27"}, false, "1.27", false},
+		{"1.25-alpine", []string{"1.27", "1.27-alpine", "1.28-bookworm"}, false, "1.27-alpine", false},
+		{"1.26.0-alpine3.19", []string{"1.27.0-alpine3.20", "1.27.0"}, false, "1.27.0-alpine3.20", false},
+		{"v1.2.3", []string{"1.9.0", "v2.0.0", "v1.3.0-rc.1"}, false, "v2.0.0", true},
+		{"v1.2.3", []string{"v2.0.0", "v1.4.0"}, true, "v1.4.0", false},
+		{"1.0.0-rc.1", []string{"1.0.0-rc.2", "1.0.0"}, false, "1.0.0", false},
+		{"1.2.3", []string{"1.2.3", "1.2.2"}, false, "", false},
+		{"stable", []string{"1.0.0"}, false, "", false},
+	}
+	for _, tt := range tests {
+		latest, major, ok := newest(tt.current, tt.candidates, tt.skipMajor)
+		if latest != tt.latest || major != tt.major || ok != (tt.latest != "") {
+			t.Errorf("newest(%s, %v) = %s, %v, %v; want %s, %v", tt.current, tt.candidates, latest, major, ok, tt.latest, tt.major)
+		}
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	out, err := setVersion([]byte("a: 1\nversion: '1.0.0'\n"), 2, "1.0.0", "1.1.0")
+	if err != nil || string(out) != "a: 1\nversion: '1.1.0'\n" {
+		t.Errorf("setVersion() = %q, %v", out, err)
+	}
+	if _, err := setVersion([]byte("version: 2.0.0\n"), 1, "1.0.0", "1.1.0"); err == nil {
+		t.Error("setVersion() should fail when the version is not on the line")
+	}
+	if _, err := setVersion([]byte("version: 1.0.0\n"), 5, "1.0.0", "1.1.0"); err == nil {
+		t.Error("setVersion() should fail on a line out of range")
+	}
+}
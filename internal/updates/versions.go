@@ -0,0 +1,89 @@
+package updates
+
+import (
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// prereleases are the suffixes of releases that stable versions replace.
+var prereleases = []string{"alpha", "beta", "rc", "pre", "preview", "dev"}
+
+// newest returns the highest candidate newer than current that has the same
+// shape: the same "v" prefix, number of components and variant suffix, so
+// 1.27-alpine only moves to 1.28-alpine and stable versions never move to a
+// release candidate. major reports a new major version; skipMajor ignores
+// those releases.
+func newest(current string, candidates []string, skipMajor bool) (latest string, major, ok bool) {
+	cur, err := semver.NewVersion(current)
+	if err != nil {
+		return "", false, false
+	}
+	shape := shapeOf(current)
+	best := cur
+	for _, candidate := range candidates {
+		if candidate == current {
+			continue
+		}
+		s := shapeOf(candidate)
+		if s.prefix != shape.prefix || s.components != shape.components || !compatibleVariant(shape.variant, s.variant) {
+			continue
+		}
+		v, err := semver.NewVersion(candidate)
+		if err != nil || !v.GreaterThan(best) || (skipMajor && v.Major() != cur.Major()) {
+			continue
+		}
+		best, latest = v, candidate
+	}
+	if latest == "" {
+		return "", false, false
+	}
+	return latest, best.Major() > cur.Major(), true
+}
+
+// shape describes how a version is written.
+type shape struct {
+	prefix     string
+	components int
+	// variant is the suffix without its digits, e.g. "-alpine." for
+	// 1.27.0-alpine3.20 or "-rc." for 1.2.0-rc.1.
+	variant string
+}
+
+func shapeOf(version string) shape {
+	var s shape
+	if strings.HasPrefix(version, "v") {
+		s.prefix, version = "v", version[1:]
+	}
+	core, suffix := version, ""
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		core, suffix = version[:i], version[i:]
+	}
+	s.components = strings.Count(core, ".") + 1
+	s.variant = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return -1
+		}
+		return r
+	}, suffix)
+	return s
+}
+
+// compatibleVariant reports whether a version with variant candidate may
+// replace one with variant current: the same variant, or a stable release
+// replacing a prerelease.
+func compatibleVariant(current, candidate string) bool {
+	if current == candidate {
+		return true
+	}
+	if candidate != "" {
+		return false
+	}
+	label := strings.ToLower(strings.Trim(current, "-+."))
+	for _, p := range prereleases {
+		if strings.HasPrefix(label, p) {
+			return true
+		}
+	}
+	return false
+}
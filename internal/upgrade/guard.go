@@ -0,0 +1,212 @@
+package upgrade
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
+)
+
+// Strategy selects how regeneration handles files modified since gitopsi
+// generated them.
+type Strategy string
+
+const (
+	// StrategyKeep leaves modified files untouched and reports them as
+	// conflicts.
+	StrategyKeep Strategy = "keep"
+	// StrategyMerge merges modified files three-way with their new version.
+	StrategyMerge Strategy = "merge"
+	// StrategyForce overwrites modified files.
+	StrategyForce Strategy = "force"
+)
+
+// Guard reconciles the files written by a regeneration with the files of an
+// existing repository: files gitopsi owns and the user did not modify are
+// replaced, modified ones are handled according to the strategy. A Guard
+// is safe for concurrent use; Changes are sorted by path.
+type Guard struct {
+	mu       sync.Mutex
+	dir      string
+	previous *layout.Metadata
+	repo     *git.Repository
+	strategy Strategy
+	dryRun   bool
+	// kept are the modified files left untouched.
+	kept map[string]bool
+
+	Changes   []FileChange
+	Unchanged int
+}
+
+// NewGuard returns a Guard for the repository in dir. Repositories without
+// metadata are guarded too: every existing file that differs counts as
+// modified.
+func NewGuard(dir string, strategy Strategy, dryRun bool) (*Guard, error) {
+	previous, err := layout.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		previous = &layout.Metadata{Files: map[string]string{}}
+	}
+	g := &Guard{dir: dir, previous: previous, strategy: strategy, dryRun: dryRun, kept: map[string]bool{}}
+	g.repo, _ = git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	return g, nil
+}
+
+// Reconcile returns the content to write for the generated file at path,
+// relative to the repository root, or false to leave the file untouched.
+// The layout metadata keeps the previous hash of files left untouched, so
+// they are still merged against what the user last received.
+func (g *Guard) Reconcile(path string, generated []byte) ([]byte, bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if path == layout.MetadataFile {
+		content, err := g.metadata(generated)
+		return content, err == nil, err
+	}
+
+	current, err := os.ReadFile(filepath.Join(g.dir, filepath.FromSlash(path)))
+	switch {
+	case os.IsNotExist(err):
+		g.record(path, ActionCreated)
+		return generated, true, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if string(current) == string(generated) {
+		g.Unchanged++
+		return nil, false, nil
+	}
+	if !g.previous.Modified(path, current) {
+		g.record(path, ActionUpdated)
+		return generated, true, nil
+	}
+
+	switch g.strategy {
+	case StrategyForce:
+		g.record(path, ActionOverwritten)
+		return generated, true, nil
+	case StrategyMerge:
+		merged, conflict := Merge(g.base(path), current, generated)
+		// Keep the generated version as the base of the next merge.
+		g.storeBlob(generated)
+		if conflict {
+			g.record(path, ActionConflict)
+		} else {
+			g.record(path, ActionMerged)
+		}
+		return merged, true, nil
+	default:
+		g.kept[path] = true
+		g.record(path, ActionConflict)
+		return nil, false, nil
+	}
+}
+
+// Hook adapts Reconcile to output.Writer paths, which are prefixed with the
+// project directory.
+func (g *Guard) Hook(project string) func(string, []byte) ([]byte, bool, error) {
+	prefix := project + "/"
+	return func(file string, content []byte) ([]byte, bool, error) {
+		rel, ok := strings.CutPrefix(filepath.ToSlash(file), prefix)
+		if !ok {
+			return content, true, nil
+		}
+		return g.Reconcile(rel, content)
+	}
+}
+
+// Count returns the number of files with action.
+func (g *Guard) Count(action Action) int {
+	n := 0
+	for _, c := range g.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// metadata restores the previous hash of the files left untouched in the
+// generated metadata.
+func (g *Guard) metadata(generated []byte) ([]byte, error) {
+	if len(g.kept) == 0 {
+		return generated, nil
+	}
+	m := layout.New()
+	if err := yaml.Unmarshal(generated, m); err != nil {
+		return nil, fmt.Errorf("failed to parse generated %s: %w", layout.MetadataFile, err)
+	}
+	for path := range g.kept {
+		if hash, ok := g.previous.Files[path]; ok {
+			m.Files[path] = hash
+		} else {
+			delete(m.Files, path)
+		}
+	}
+	return m.Marshal()
+}
+
+// base returns the content previously generated for path from the Git
+// objects of the repository, or nil when it is not available.
+func (g *Guard) base(path string) []byte {
+	hash, ok := g.previous.Files[path]
+	if !ok || g.repo == nil {
+		return nil
+	}
+	blob, err := g.repo.BlobObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// storeBlob adds content to the Git objects of the repository so it can be
+// found as a merge base by the next merge from this clone.
+func (g *Guard) storeBlob(content []byte) {
+	if g.repo == nil || g.dryRun {
+		return
+	}
+	obj := g.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return
+	}
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return
+	}
+	if err := w.Close(); err != nil {
+		return
+	}
+	_, _ = g.repo.Storer.SetEncodedObject(obj)
+}
+
+func (g *Guard) record(path string, action Action) {
+	i := sort.Search(len(g.Changes), func(i int) bool { return g.Changes[i].Path > path })
+	g.Changes = append(g.Changes, FileChange{})
+	copy(g.Changes[i+1:], g.Changes[i:])
+	g.Changes[i] = FileChange{Path: path, Action: action}
+}
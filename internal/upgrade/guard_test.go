@@ -0,0 +1,102 @@
+package upgrade
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// regenerate runs the generator into the parent of root through a Guard.
+func regenerate(t *testing.T, root string, strategy Strategy, edit func(*generator.Generator)) *Guard {
+	t.Helper()
+	guard, err := NewGuard(root, strategy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	writer := output.New(filepath.Dir(root), false, false)
+	writer.Reconcile = guard.Hook(cfg.Project.Name)
+	gen := generator.New(cfg, writer, false)
+	gen.Log = io.Discard
+	if edit != nil {
+		edit(gen)
+	}
+	if err := gen.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	return guard
+}
+
+func TestGuard(t *testing.T) {
+	const deployment = "applications/base/api/deployment.yaml"
+	const service = "applications/base/api/service.yaml"
+	bumpPort := func(gen *generator.Generator) { gen.Config.Apps[0].Port = 8081 }
+
+	t.Run("idempotent", func(t *testing.T) {
+		root := generateRepo(t, testConfig())
+		guard := regenerate(t, root, StrategyKeep, nil)
+		if len(guard.Changes) != 0 || guard.Unchanged == 0 {
+			t.Errorf("expected no changes, got %+v", guard.Changes)
+		}
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		root := generateRepo(t, testConfig())
+		editFile(t, root, deployment, func(s string) string { return s + "# mine\n" })
+
+		guard := regenerate(t, root, StrategyKeep, bumpPort)
+		if got := actions(&Report{Changes: guard.Changes}); got[deployment] != ActionConflict || got[service] != ActionUpdated {
+			t.Errorf("unexpected actions: %v", got)
+		}
+		if content := readFile(t, root, deployment); !strings.HasSuffix(content, "# mine\n") || strings.Contains(content, "8081") {
+			t.Errorf("expected the modified file to be left untouched:\n%s", content)
+		}
+		if !strings.Contains(readFile(t, root, service), "8081") {
+			t.Error("expected the unmodified service to be updated")
+		}
+
+		// The metadata keeps the hash of what was generated before, so the
+		// file is still merged against it later.
+		meta, err := layout.Load(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guard.previous.Files[deployment] != meta.Files[deployment] {
+			t.Error("expected the previous hash of the kept file to be recorded")
+		}
+		if meta.Modified(service, []byte(readFile(t, root, service))) {
+			t.Error("expected the updated service to be recorded")
+		}
+	})
+
+	t.Run("force", func(t *testing.T) {
+		root := generateRepo(t, testConfig())
+		editFile(t, root, deployment, func(s string) string { return s + "# mine\n" })
+
+		guard := regenerate(t, root, StrategyForce, bumpPort)
+		if got := actions(&Report{Changes: guard.Changes}); got[deployment] != ActionOverwritten {
+			t.Errorf("unexpected actions: %v", got)
+		}
+		if content := readFile(t, root, deployment); strings.Contains(content, "# mine") {
+			t.Errorf("expected the modified file to be overwritten:\n%s", content)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		root := generateRepo(t, testConfig())
+		editFile(t, root, deployment, func(s string) string { return s + "# mine\n" })
+
+		guard := regenerate(t, root, StrategyMerge, bumpPort)
+		if got := actions(&Report{Changes: guard.Changes}); got[deployment] != ActionMerged {
+			t.Errorf("unexpected actions: %v", got)
+		}
+		if content := readFile(t, root, deployment); !strings.Contains(content, "# mine") || !strings.Contains(content, "8081") {
+			t.Errorf("expected a merged file:\n%s", content)
+		}
+	})
+}
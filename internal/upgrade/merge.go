@@ -0,0 +1,207 @@
+package upgrade
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+// Conflict markers written around the conflicting lines of a merge.
+const (
+	markerCurrent   = "<<<<<<< current\n"
+	markerSeparator = "=======\n"
+	markerGenerated = ">>>>>>> gitopsi\n"
+)
+
+// Merge performs a line-based three-way merge of the edits from base to
+// current (the user's version) and from base to generated (the new gitopsi
+// version). Where both changed the same lines differently, both versions are
+// kept between conflict markers and conflict is true.
+func Merge(base, current, generated []byte) (merged []byte, conflict bool) {
+	b, c, g := splitLines(base), splitLines(current), splitLines(generated)
+	toCurrent, toGenerated := matchLines(b, c), matchLines(b, g)
+
+	var out bytes.Buffer
+	write := func(lines []string) {
+		for _, line := range lines {
+			out.WriteString(line)
+		}
+	}
+
+	bi, ci, gi := 0, 0, 0
+	for {
+		// Find the next base line kept by both sides.
+		next := bi
+		for next < len(b) && (toCurrent[next] < 0 || toGenerated[next] < 0) {
+			next++
+		}
+		cEnd, gEnd := len(c), len(g)
+		if next < len(b) {
+			cEnd, gEnd = toCurrent[next], toGenerated[next]
+		}
+
+		baseChunk, currentChunk, generatedChunk := b[bi:next], c[ci:cEnd], g[gi:gEnd]
+		switch {
+		case slices.Equal(currentChunk, generatedChunk), slices.Equal(generatedChunk, baseChunk):
+			write(currentChunk)
+		case slices.Equal(currentChunk, baseChunk):
+			write(generatedChunk)
+		default:
+			conflict = true
+			out.WriteString(markerCurrent)
+			write(withNewline(currentChunk))
+			out.WriteString(markerSeparator)
+			write(withNewline(generatedChunk))
+			out.WriteString(markerGenerated)
+		}
+
+		if next == len(b) {
+			break
+		}
+		out.WriteString(b[next])
+		bi, ci, gi = next+1, cEnd+1, gEnd+1
+	}
+	return out.Bytes(), conflict
+}
+
+// splitLines splits content into lines, keeping line endings.
+func splitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n')
+		if i < 0 {
+			lines = append(lines, string(content))
+			break
+		}
+		lines = append(lines, string(content[:i+1]))
+		content = content[i+1:]
+	}
+	return lines
+}
+
+// withNewline terminates the last line of a conflict side so the following
+// marker starts on its own line.
+func withNewline(lines []string) []string {
+	if n := len(lines); n > 0 && lines[n-1][len(lines[n-1])-1] != '\n' {
+		lines = append(lines[:n-1:n-1], lines[n-1]+"\n")
+	}
+	return lines
+}
+
+// matchLines maps every line of a to its index in b along a longest common
+// subsequence, or -1 when the line is not kept.
+func matchLines(a, b []string) []int {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	match := make([]int, len(a))
+	i, j := 0, 0
+	for i < len(a) {
+		switch {
+		case j < len(b) && a[i] == b[j]:
+			match[i] = j
+			i++
+			j++
+		case j < len(b) && lcs[i][j+1] >= lcs[i+1][j]:
+			j++
+		default:
+			match[i] = -1
+			i++
+		}
+	}
+	return match
+}
+
+// diffContext is the number of unchanged lines around the changes of a hunk.
+const diffContext = 3
+
+// Diff returns the unified diff from a to b, labelled with name, or the
+// empty string when they are equal.
+func Diff(name string, a, b []byte) string {
+	al, bl := splitLines(a), splitLines(b)
+	match := matchLines(al, bl)
+
+	// Walk both sides into a sequence of kept (' '), removed ('-') and
+	// added ('+') lines.
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	j := 0
+	for i, text := range al {
+		if match[i] < 0 {
+			lines = append(lines, line{'-', text})
+			continue
+		}
+		for ; j < match[i]; j++ {
+			lines = append(lines, line{'+', bl[j]})
+		}
+		lines = append(lines, line{' ', text})
+		j++
+	}
+	for ; j < len(bl); j++ {
+		lines = append(lines, line{'+', bl[j]})
+	}
+
+	var out bytes.Buffer
+	aLine, bLine := 1, 1
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			aLine++
+			bLine++
+			start++
+			continue
+		}
+
+		// Extend the hunk over changes separated by less than twice the
+		// context.
+		from := max(start-diffContext, 0)
+		end := start
+		for k := start; k < len(lines) && k-end <= 2*diffContext; k++ {
+			if lines[k].op != ' ' {
+				end = k + 1
+			}
+		}
+		to := min(end+diffContext, len(lines))
+
+		aStart, bStart := aLine-(start-from), bLine-(start-from)
+		aCount, bCount := 0, 0
+		var hunk bytes.Buffer
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				aCount++
+			}
+			if l.op != '-' {
+				bCount++
+			}
+			hunk.WriteByte(l.op)
+			hunk.WriteString(l.text)
+			if l.text[len(l.text)-1] != '\n' {
+				hunk.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		if out.Len() == 0 {
+			out.WriteString("--- " + name + "\n+++ " + name + "\n")
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		out.Write(hunk.Bytes())
+
+		aLine, bLine = aStart+aCount, bStart+bCount
+		start = to
+	}
+	return out.String()
+}
@@ -0,0 +1,63 @@
+package upgrade
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name         string
+		base         string
+		current      string
+		generated    string
+		want         string
+		wantConflict bool
+	}{
+		{"no changes", base, base, base, base, false},
+		{"current only", base, "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", false},
+		{"generated only", base, base, "a\nb\nc\nD\ne\n", "a\nb\nc\nD\ne\n", false},
+		{"disjoint edits", base, "a\nB\nc\nd\ne\n", "a\nb\nc\nD\ne\nf\n", "a\nB\nc\nD\ne\nf\n", false},
+		{"same edit", base, "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", false},
+		{"deletion and edit elsewhere", base, "a\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "a\nc\nd\nE\n", false},
+		{"overlapping edits", base, "a\nB\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\n<<<<<<< current\nB\n=======\nX\n>>>>>>> gitopsi\nc\nd\ne\n", true},
+		{"missing base", "", "x\n", "y", "<<<<<<< current\nx\n=======\ny\n>>>>>>> gitopsi\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflict := Merge([]byte(tt.base), []byte(tt.current), []byte(tt.generated))
+			if string(got) != tt.want {
+				t.Errorf("Merge() = %q, want %q", got, tt.want)
+			}
+			if conflict != tt.wantConflict {
+				t.Errorf("Merge() conflict = %v, want %v", conflict, tt.wantConflict)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	long := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"insert at start", "a\nb\n", "x\na\nb\n", "--- f\n+++ f\n@@ -1,2 +1,3 @@\n+x\n a\n b\n"},
+		{"replace", "a\nb\nc\n", "a\nB\nc\n", "--- f\n+++ f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{
+			"separate hunks",
+			long,
+			"1\nX\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n",
+			"--- f\n+++ f\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n 4\n 5\n@@ -11,5 +11,4 @@\n 11\n 12\n 13\n-14\n 15\n",
+		},
+		{"missing newline", "a\n", "a\nb", "--- f\n+++ f\n@@ -1,1 +1,2 @@\n a\n+b\n\\ No newline at end of file\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff("f", []byte(tt.a), []byte(tt.b)); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+// Package upgrade brings repositories generated by older gitopsi versions to
+// the current layout. It applies structural migrations, regenerates the
+// repository from its config and merges the result with the files on disk,
+// preserving user edits.
+package upgrade
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
+	"github.com/ihsanmokhlisse/gitopsi/internal/images"
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// Action is what an upgrade or a regeneration did to a file.
+type Action string
+
+const (
+	// ActionCreated is a file new in the current layout.
+	ActionCreated Action = "created"
+	// ActionUpdated is an unmodified file replaced with its new version.
+	ActionUpdated Action = "updated"
+	// ActionMerged is a modified file merged cleanly with its new version.
+	ActionMerged Action = "merged"
+	// ActionConflict is a modified file left with conflict markers, or left
+	// untouched by StrategyKeep.
+	ActionConflict Action = "conflict"
+	// ActionOverwritten is a modified file replaced by StrategyForce.
+	ActionOverwritten Action = "overwritten"
+	// ActionMoved is a file moved by a structural migration.
+	ActionMoved Action = "moved"
+	// ActionRemoved is an unmodified file no longer generated.
+	ActionRemoved Action = "removed"
+	// ActionKept is a modified file no longer generated, left in place.
+	ActionKept Action = "kept"
+)
+
+// Migration restructures a repository from layout version From to From+1.
+type Migration struct {
+	From        int
+	Description string
+	// Moves maps old paths to new paths, relative to the repository root.
+	Moves map[string]string
+}
+
+// migrations are applied in order to repositories of older layouts.
+var migrations = []Migration{
+	{From: 0, Description: "Record generated files in " + layout.MetadataFile},
+}
+
+// Options configures Run.
+type Options struct {
+	// Dir is the root of the generated repository.
+	Dir    string
+	Config *config.Config
+	// DryRun reports the changes without writing them.
+	DryRun bool
+	// Images resolves image digests for images.pin_digests; nil pulls
+	// anonymously.
+	Images *images.Resolver
+}
+
+// FileChange is a file touched by an upgrade.
+type FileChange struct {
+	Path   string `json:"path" yaml:"path"`
+	Action Action `json:"action" yaml:"action"`
+	// From is the previous path of a moved file.
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+}
+
+// Report describes an upgrade.
+type Report struct {
+	FromVersion int          `json:"from_version" yaml:"from_version"`
+	ToVersion   int          `json:"to_version" yaml:"to_version"`
+	Migrations  []string     `json:"migrations,omitempty" yaml:"migrations,omitempty"`
+	Changes     []FileChange `json:"changes" yaml:"changes"`
+	Unchanged   int          `json:"unchanged" yaml:"unchanged"`
+	DryRun      bool         `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// Count returns the number of files with action.
+func (r *Report) Count(action Action) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Run upgrades the repository in opts.Dir.
+func Run(opts *Options) (*Report, error) {
+	guard, err := NewGuard(opts.Dir, StrategyMerge, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	if guard.previous.LayoutVersion > layout.CurrentVersion {
+		return nil, fmt.Errorf("repository layout version %d is newer than this gitopsi supports (%d): upgrade gitopsi first", guard.previous.LayoutVersion, layout.CurrentVersion)
+	}
+
+	u := &upgrader{opts: opts, guard: guard, previous: guard.previous, report: &Report{
+		FromVersion: guard.previous.LayoutVersion,
+		ToVersion:   layout.CurrentVersion,
+		DryRun:      opts.DryRun,
+	}}
+
+	if err := u.migrate(); err != nil {
+		return nil, err
+	}
+	generated, metadata, err := u.generate()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.apply(generated); err != nil {
+		return nil, err
+	}
+	u.report.Changes = append(u.report.Changes, guard.Changes...)
+	u.report.Unchanged = guard.Unchanged
+	if err := u.prune(generated); err != nil {
+		return nil, err
+	}
+
+	content, err := metadata.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if content, _, err = guard.Reconcile(layout.MetadataFile, content); err != nil {
+		return nil, err
+	}
+	if err := u.write(layout.MetadataFile, content); err != nil {
+		return nil, err
+	}
+	return u.report, nil
+}
+
+type upgrader struct {
+	opts     *Options
+	guard    *Guard
+	previous *layout.Metadata
+	report   *Report
+}
+
+// migrate applies the structural migrations from the previous layout.
+func (u *upgrader) migrate() error {
+	for _, m := range migrations {
+		if m.From < u.previous.LayoutVersion || m.From >= layout.CurrentVersion {
+			continue
+		}
+		u.report.Migrations = append(u.report.Migrations, fmt.Sprintf("v%d → v%d: %s", m.From, m.From+1, m.Description))
+
+		sources := make([]string, 0, len(m.Moves))
+		for from := range m.Moves {
+			sources = append(sources, from)
+		}
+		sort.Strings(sources)
+		for _, from := range sources {
+			to := m.Moves[from]
+			if !u.exists(from) || u.exists(to) {
+				continue
+			}
+			if !u.opts.DryRun {
+				target := u.path(to)
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
+				}
+				if err := os.Rename(u.path(from), target); err != nil {
+					return fmt.Errorf("failed to move %s to %s: %w", from, to, err)
+				}
+			}
+			if hash, ok := u.previous.Files[from]; ok {
+				delete(u.previous.Files, from)
+				u.previous.Files[to] = hash
+			}
+			u.report.Changes = append(u.report.Changes, FileChange{Path: to, From: from, Action: ActionMoved})
+		}
+	}
+	return nil
+}
+
+// generate renders the repository from the config without writing it and
+// returns the files relative to the repository root with their metadata.
+func (u *upgrader) generate() (map[string][]byte, *layout.Metadata, error) {
+	prefix := u.opts.Config.Project.Name + "/"
+	files := map[string][]byte{}
+	writer := &output.Writer{
+		DryRun: true,
+		Log:    io.Discard,
+		BeforeWrite: func(file string, content []byte) error {
+			if rel, ok := strings.CutPrefix(filepath.ToSlash(file), prefix); ok && rel != layout.MetadataFile {
+				files[rel] = content
+			}
+			return nil
+		},
+	}
+	cfg := *u.opts.Config
+	gen := generator.New(&cfg, writer, false)
+	gen.Log = io.Discard
+	gen.Images = u.opts.Images
+	if err := gen.Generate(); err != nil {
+		return nil, nil, fmt.Errorf("failed to regenerate repository: %w", err)
+	}
+	return files, gen.Metadata(), nil
+}
+
+// apply writes the generated files, merging those the user modified.
+func (u *upgrader) apply(generated map[string][]byte) error {
+	paths := make([]string, 0, len(generated))
+	for path := range generated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		content, write, err := u.guard.Reconcile(path, generated[path])
+		if err != nil {
+			return err
+		}
+		if write {
+			if err := u.write(path, content); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prune removes previously generated files that are no longer generated,
+// keeping those the user modified.
+func (u *upgrader) prune(generated map[string][]byte) error {
+	paths := make([]string, 0, len(u.previous.Files))
+	for path := range u.previous.Files {
+		if _, ok := generated[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		current, err := os.ReadFile(u.path(path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if u.previous.Modified(path, current) {
+			u.record(path, ActionKept)
+			continue
+		}
+		if !u.opts.DryRun {
+			if err := os.Remove(u.path(path)); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+		u.record(path, ActionRemoved)
+	}
+	return nil
+}
+
+func (u *upgrader) record(path string, action Action) {
+	u.report.Changes = append(u.report.Changes, FileChange{Path: path, Action: action})
+}
+
+func (u *upgrader) path(rel string) string {
+	return filepath.Join(u.opts.Dir, filepath.FromSlash(rel))
+}
+
+func (u *upgrader) exists(rel string) bool {
+	_, err := os.Stat(u.path(rel))
+	return err == nil
+}
+
+func (u *upgrader) write(rel string, content []byte) error {
+	if u.opts.DryRun {
+		return nil
+	}
+	path := u.path(rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package upgrade
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
+	"github.com/ihsanmokhlisse/gitopsi/internal/gitops"
+	"github.com/ihsanmokhlisse/gitopsi/internal/layout"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+func testConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Project.Name = "plat"
+	cfg.Scope = "application"
+	cfg.GitOpsTool = "argocd"
+	cfg.Git.URL = "https://github.com/org/plat.git"
+	cfg.Environments = []config.Environment{{Name: "dev"}}
+	cfg.Apps = []config.Application{
+		{Name: "api", Image: "api:1.0", Port: 8080, Replicas: 1},
+		{Name: "web", Image: "web:1.0", Port: 3000, Replicas: 1},
+		{Name: "old", Image: "old:1.0", Port: 9000, Replicas: 1},
+		{Name: "legacy", Image: "legacy:1.0", Port: 9000, Replicas: 1},
+	}
+	return cfg
+}
+
+// generateRepo generates cfg and commits it to a new Git repository,
+// returning the repository root.
+func generateRepo(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+	dir := t.TempDir()
+	gen := generator.New(cfg, output.New(dir, false, false), false)
+	gen.Log = io.Discard
+	if err := gen.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, cfg.Project.Name)
+	if _, err := git.PlainInit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gitops.Commit(root, "initial"); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func readFile(t *testing.T, root, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func editFile(t *testing.T, root, path string, edit func(string) string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, path), []byte(edit(readFile(t, root, path))), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func actions(report *Report) map[string]Action {
+	result := map[string]Action{}
+	for _, c := range report.Changes {
+		result[c.Path] = c.Action
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	cfg := testConfig()
+	root := generateRepo(t, cfg)
+
+	editFile(t, root, "applications/base/api/deployment.yaml", func(s string) string { return s + "# owned by team-a\n" })
+	editFile(t, root, "applications/base/web/deployment.yaml", func(s string) string {
+		return strings.Replace(s, "replicas: 1", "replicas: 2", 1)
+	})
+	editFile(t, root, "applications/base/legacy/service.yaml", func(s string) string { return s + "# keep\n" })
+
+	cfg.Apps[0].Port = 8081
+	cfg.Apps[1].Replicas = 4
+	cfg.Apps = append(cfg.Apps[:2], config.Application{Name: "new", Image: "new:1.0", Port: 80, Replicas: 1})
+
+	report, err := Run(&Options{Dir: root, Config: cfg})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.FromVersion != layout.CurrentVersion || len(report.Migrations) != 0 {
+		t.Errorf("unexpected versions: %+v", report)
+	}
+
+	got := actions(report)
+	for path, want := range map[string]Action{
+		"applications/base/api/deployment.yaml":    ActionMerged,
+		"applications/base/api/service.yaml":       ActionUpdated,
+		"applications/base/web/deployment.yaml":    ActionConflict,
+		"applications/base/new/deployment.yaml":    ActionCreated,
+		"applications/base/old/deployment.yaml":    ActionRemoved,
+		"applications/base/legacy/service.yaml":    ActionKept,
+		"applications/base/legacy/deployment.yaml": ActionRemoved,
+	} {
+		if got[path] != want {
+			t.Errorf("%s: action = %q, want %q", path, got[path], want)
+		}
+	}
+
+	api := readFile(t, root, "applications/base/api/deployment.yaml")
+	if !strings.Contains(api, "containerPort: 8081") || !strings.Contains(api, "# owned by team-a") {
+		t.Errorf("expected the merged deployment to keep the edit and the new port:\n%s", api)
+	}
+	web := readFile(t, root, "applications/base/web/deployment.yaml")
+	if !strings.Contains(web, "<<<<<<< current\n  replicas: 2\n=======\n  replicas: 4\n>>>>>>> gitopsi\n") {
+		t.Errorf("expected conflict markers:\n%s", web)
+	}
+	if _, err := os.Stat(filepath.Join(root, "applications/base/old/deployment.yaml")); err == nil {
+		t.Error("expected the unmodified file of a removed application to be deleted")
+	}
+
+	meta, err := layout.Load(root)
+	if err != nil || meta == nil {
+		t.Fatalf("Load() = %v, %v", meta, err)
+	}
+	if _, ok := meta.Files["applications/base/new/deployment.yaml"]; !ok {
+		t.Error("expected the metadata to record new files")
+	}
+	if _, ok := meta.Files["applications/base/old/deployment.yaml"]; ok {
+		t.Error("expected the metadata to drop files no longer generated")
+	}
+
+	// The merge base of the merged file is kept in the repository, so a
+	// second upgrade merges again instead of conflicting.
+	if _, err := gitops.Commit(root, "upgrade"); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Apps[0].Replicas = 2
+	report, err = Run(&Options{Dir: root, Config: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(report)["applications/base/api/deployment.yaml"]; got != ActionMerged {
+		t.Errorf("second upgrade: action = %q, want merged", got)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	cfg := testConfig()
+	root := generateRepo(t, cfg)
+	before := readFile(t, root, "applications/base/api/service.yaml")
+
+	cfg.Apps[0].Port = 8081
+	report, err := Run(&Options{Dir: root, Config: cfg, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions(report)["applications/base/api/service.yaml"] != ActionUpdated {
+		t.Errorf("expected the service to be reported as updated: %+v", report.Changes)
+	}
+	if readFile(t, root, "applications/base/api/service.yaml") != before {
+		t.Error("dry run modified a file")
+	}
+}
+
+func TestRunLegacyLayout(t *testing.T) {
+	cfg := testConfig()
+	root := generateRepo(t, cfg)
+	if err := os.Remove(filepath.Join(root, layout.MetadataFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Apps[0].Port = 8081
+	report, err := Run(&Options{Dir: root, Config: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FromVersion != 0 || report.ToVersion != layout.CurrentVersion || len(report.Migrations) != 1 {
+		t.Errorf("unexpected migrations: %+v", report)
+	}
+	// Without metadata user edits cannot be told apart from older templates.
+	if got := actions(report)["applications/base/api/service.yaml"]; got != ActionConflict {
+		t.Errorf("action = %q, want conflict", got)
+	}
+	if meta, _ := layout.Load(root); meta == nil || meta.LayoutVersion != layout.CurrentVersion {
+		t.Errorf("expected the layout to be stamped, got %+v", meta)
+	}
+}
+
+func TestRunNewerLayout(t *testing.T) {
+	cfg := testConfig()
+	root := generateRepo(t, cfg)
+	if err := os.WriteFile(filepath.Join(root, layout.MetadataFile), []byte("layout_version: 99\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(&Options{Dir: root, Config: cfg}); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected an error for a newer layout, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/conventions"
+)
+
+// validateConventions flags the resources missing the labels the
+// organization conventions mandate.
+func (v *Validator) validateConventions(manifests []string, result *ValidationResult) {
+	catResult := &CategoryResult{Issues: []Issue{}}
+	result.Categories[CategoryConventions] = catResult
+
+	results := v.checkFiles("conventions", fingerprint(v.opts.RequiredLabels...), manifests, func(manifest string, data []byte) ([]Issue, bool) {
+		var issues []Issue
+		for _, violation := range conventions.Check(data, v.opts.RequiredLabels) {
+			issues = append(issues, Issue{
+				File:       manifest,
+				Line:       violation.Line,
+				Category:   CategoryConventions,
+				Severity:   SeverityHigh,
+				Rule:       "CONV001",
+				Message:    fmt.Sprintf("%s %s is missing required labels: %s", violation.Kind, violation.Name, strings.Join(violation.Missing, ", ")),
+				Suggestion: "Set the labels in conventions.labels and regenerate, or add them to the resource",
+			})
+		}
+		return issues, true
+	})
+	for _, r := range results {
+		catResult.Issues = append(catResult.Issues, r.Issues...)
+		if len(r.Issues) > 0 {
+			catResult.Failed++
+		}
+	}
+	catResult.Passed = len(manifests) - catResult.Failed
+
+	result.Issues = append(result.Issues, catResult.Issues...)
+}
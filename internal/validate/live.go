@@ -0,0 +1,328 @@
+package validate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Runner runs kubectl with args and returns its standard output.
+type Runner func(ctx context.Context, args ...string) ([]byte, error)
+
+// Prerequisite is an installation a live validation found missing: the
+// cluster serves the APIs of its kinds once it is installed.
+type Prerequisite struct {
+	Name    string   `json:"name" yaml:"name"`
+	Install string   `json:"install" yaml:"install"`
+	Kinds   []string `json:"kinds" yaml:"kinds"`
+}
+
+// apiProviders name what installs the API groups of well-known CRDs, by
+// group/Kind or group: the marketplace patterns and the GitOps tools
+// gitopsi bootstrap installs.
+var apiProviders = map[string]Prerequisite{
+	"argoproj.io/Rollout":            {Name: "Argo Rollouts", Install: "install the Argo Rollouts controller"},
+	"argoproj.io/AnalysisTemplate":   {Name: "Argo Rollouts", Install: "install the Argo Rollouts controller"},
+	"argoproj.io":                    {Name: "ArgoCD", Install: "gitopsi bootstrap"},
+	"source.toolkit.fluxcd.io":       {Name: "Flux", Install: "gitopsi bootstrap"},
+	"kustomize.toolkit.fluxcd.io":    {Name: "Flux", Install: "gitopsi bootstrap"},
+	"helm.toolkit.fluxcd.io":         {Name: "Flux", Install: "gitopsi bootstrap"},
+	"notification.toolkit.fluxcd.io": {Name: "Flux", Install: "gitopsi bootstrap"},
+	"image.toolkit.fluxcd.io":        {Name: "Flux image automation", Install: "gitopsi bootstrap"},
+	"cert-manager.io":                patternProvider("cert-manager"),
+	"monitoring.coreos.com":          patternProvider("prometheus-stack"),
+	"velero.io":                      patternProvider("velero"),
+	"bitnami.com":                    patternProvider("sealed-secrets"),
+	"external-secrets.io":            patternProvider("vault-integration"),
+	"networking.istio.io":            patternProvider("istio-mesh"),
+	"security.istio.io":              patternProvider("istio-mesh"),
+	"postgresql.cnpg.io":             patternProvider("postgresql-operator"),
+	"redis.redis.opstreelabs.in":     patternProvider("redis-operator"),
+	"kyverno.io":                     patternProvider("kyverno-policies"),
+	"tekton.dev":                     patternProvider("tekton-pipelines"),
+	"externaldns.k8s.io":             patternProvider("external-dns"),
+}
+
+func patternProvider(name string) Prerequisite {
+	return Prerequisite{Name: "pattern " + name, Install: "gitopsi marketplace install " + name}
+}
+
+// liveResource is a resource of a manifest checked against the cluster.
+type liveResource struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Line       int
+}
+
+// discovery holds the API group versions of a cluster and, once looked up,
+// the kinds each serves.
+type discovery struct {
+	run           Runner
+	prefix        []string
+	groupVersions map[string]bool
+	kinds         map[string]map[string]bool
+}
+
+// SetRunner replaces the kubectl runner of the live validation, for tests.
+func (v *Validator) SetRunner(run Runner) {
+	v.run = run
+}
+
+// validateLive checks that the cluster serves the apiVersion and kind of
+// every resource. Kinds of the CustomResourceDefinitions of the manifests
+// are served once these are applied and are not reported.
+func (v *Validator) validateLive(ctx context.Context, manifests []string, result *ValidationResult) error {
+	catResult := &CategoryResult{Issues: []Issue{}}
+	result.Categories[CategoryLive] = catResult
+
+	d := &discovery{run: v.run, kinds: map[string]map[string]bool{}}
+	if d.run == nil {
+		d.run = runKubectl
+	}
+	if v.opts.Kubeconfig != "" {
+		d.prefix = append(d.prefix, "--kubeconfig", v.opts.Kubeconfig)
+	}
+	if v.opts.Context != "" {
+		d.prefix = append(d.prefix, "--context", v.opts.Context)
+	}
+	if err := d.load(ctx); err != nil {
+		return err
+	}
+
+	resources := map[string][]liveResource{}
+	defined := map[string]bool{}
+	for _, manifest := range manifests {
+		data, err := os.ReadFile(manifest)
+		if err != nil {
+			continue
+		}
+		resources[manifest] = liveResources(data, defined)
+	}
+
+	missing := map[string]*Prerequisite{}
+	for _, manifest := range manifests {
+		failed := false
+		for _, r := range resources[manifest] {
+			if defined[r.APIVersion+" "+r.Kind] {
+				continue
+			}
+			served, err := d.serves(ctx, r.APIVersion, r.Kind)
+			if err != nil {
+				return err
+			}
+			if served {
+				continue
+			}
+			failed = true
+			issue := Issue{
+				File:     manifest,
+				Line:     r.Line,
+				Category: CategoryLive,
+				Severity: SeverityHigh,
+				Rule:     "LIVE001",
+				Message:  fmt.Sprintf("%s %s: the cluster does not serve %s %s", r.Kind, r.Name, r.APIVersion, r.Kind),
+			}
+			if provider, ok := lookupProvider(r.APIVersion, r.Kind); ok {
+				issue.Suggestion = fmt.Sprintf("Install %s first: %s", provider.Name, provider.Install)
+				p, ok := missing[provider.Name]
+				if !ok {
+					p = &Prerequisite{Name: provider.Name, Install: provider.Install}
+					missing[provider.Name] = p
+				}
+				if kind := r.APIVersion + " " + r.Kind; !slices.Contains(p.Kinds, kind) {
+					p.Kinds = append(p.Kinds, kind)
+				}
+			} else {
+				issue.Suggestion = "Install the CRD or operator serving this API, or target a Kubernetes version serving it"
+			}
+			catResult.Issues = append(catResult.Issues, issue)
+		}
+		if failed {
+			catResult.Failed++
+		} else {
+			catResult.Passed++
+		}
+	}
+
+	for _, p := range missing {
+		sort.Strings(p.Kinds)
+		result.Prerequisites = append(result.Prerequisites, *p)
+	}
+	sort.Slice(result.Prerequisites, func(i, j int) bool { return result.Prerequisites[i].Name < result.Prerequisites[j].Name })
+	result.Issues = append(result.Issues, catResult.Issues...)
+	return nil
+}
+
+// liveResources returns the Kubernetes resources of a YAML stream, and adds
+// the "apiVersion kind" of the CustomResourceDefinitions to defined.
+// Kustomize configurations are not resources of the cluster.
+func liveResources(content []byte, defined map[string]bool) []liveResource {
+	var resources []liveResource
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			// The end of the stream, or content that is not YAML.
+			return resources
+		}
+		if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		root := doc.Content[0]
+		var obj struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Metadata   struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if err := root.Decode(&obj); err != nil || obj.APIVersion == "" || obj.Kind == "" {
+			continue
+		}
+		if strings.HasPrefix(obj.APIVersion, "kustomize.config.k8s.io/") {
+			continue
+		}
+		if obj.Kind == "CustomResourceDefinition" {
+			var crd struct {
+				Spec struct {
+					Group string `yaml:"group"`
+					Names struct {
+						Kind string `yaml:"kind"`
+					} `yaml:"names"`
+					Versions []struct {
+						Name string `yaml:"name"`
+					} `yaml:"versions"`
+				} `yaml:"spec"`
+			}
+			if root.Decode(&crd) == nil {
+				for _, version := range crd.Spec.Versions {
+					defined[crd.Spec.Group+"/"+version.Name+" "+crd.Spec.Names.Kind] = true
+				}
+			}
+		}
+		resources = append(resources, liveResource{
+			APIVersion: obj.APIVersion,
+			Kind:       obj.Kind,
+			Name:       obj.Metadata.Name,
+			Line:       root.Line,
+		})
+	}
+}
+
+// lookupProvider returns what installs the API of a kind.
+func lookupProvider(apiVersion, kind string) (Prerequisite, bool) {
+	group, _, found := strings.Cut(apiVersion, "/")
+	if !found {
+		return Prerequisite{}, false
+	}
+	if p, ok := apiProviders[group+"/"+kind]; ok {
+		return p, true
+	}
+	if p, ok := apiProviders[group]; ok {
+		return p, true
+	}
+	// API groups of a provider share its domain, such as
+	// acme.cert-manager.io.
+	for g, p := range apiProviders {
+		if !strings.Contains(g, "/") && strings.HasSuffix(group, "."+g) {
+			return p, true
+		}
+	}
+	return Prerequisite{}, false
+}
+
+// load reads the group versions the cluster serves.
+func (d *discovery) load(ctx context.Context) error {
+	var core struct {
+		Versions []string `json:"versions"`
+	}
+	if err := d.get(ctx, "/api", &core); err != nil {
+		return fmt.Errorf("failed to read the API versions of the cluster: %w", err)
+	}
+	var groups struct {
+		Groups []struct {
+			Versions []struct {
+				GroupVersion string `json:"groupVersion"`
+			} `json:"versions"`
+		} `json:"groups"`
+	}
+	if err := d.get(ctx, "/apis", &groups); err != nil {
+		return fmt.Errorf("failed to read the API groups of the cluster: %w", err)
+	}
+
+	d.groupVersions = map[string]bool{}
+	for _, version := range core.Versions {
+		d.groupVersions[version] = true
+	}
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			d.groupVersions[version.GroupVersion] = true
+		}
+	}
+	return nil
+}
+
+// serves reports whether the cluster serves kind in apiVersion. The kinds of
+// a group version are read the first time it is looked up.
+func (d *discovery) serves(ctx context.Context, apiVersion, kind string) (bool, error) {
+	if !d.groupVersions[apiVersion] {
+		return false, nil
+	}
+	kinds, ok := d.kinds[apiVersion]
+	if !ok {
+		path := "/apis/" + apiVersion
+		if !strings.Contains(apiVersion, "/") {
+			path = "/api/" + apiVersion
+		}
+		var list struct {
+			Resources []struct {
+				Name string `json:"name"`
+				Kind string `json:"kind"`
+			} `json:"resources"`
+		}
+		if err := d.get(ctx, path, &list); err != nil {
+			return false, fmt.Errorf("failed to read the resources of %s: %w", apiVersion, err)
+		}
+		kinds = map[string]bool{}
+		for _, r := range list.Resources {
+			// Subresources, such as deployments/scale, report the kind they return.
+			if !strings.Contains(r.Name, "/") {
+				kinds[r.Kind] = true
+			}
+		}
+		d.kinds[apiVersion] = kinds
+	}
+	return kinds[kind], nil
+}
+
+func (d *discovery) get(ctx context.Context, path string, out any) error {
+	args := append(append([]string{}, d.prefix...), "get", "--raw", path)
+	data, err := d.run(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func runKubectl(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("kubectl %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to run kubectl: %w", err)
+	}
+	return out, nil
+}
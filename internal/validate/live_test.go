@@ -0,0 +1,131 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDiscovery serves the discovery API of a cluster with apps/v1,
+// argoproj.io/v1alpha1 without Rollouts, and no cert-manager.
+func fakeDiscovery(calls *[]string) Runner {
+	responses := map[string]string{
+		"/api":                       `{"versions": ["v1"]}`,
+		"/apis":                      `{"groups": [{"versions": [{"groupVersion": "apps/v1"}]}, {"versions": [{"groupVersion": "argoproj.io/v1alpha1"}]}]}`,
+		"/api/v1":                    `{"resources": [{"name": "namespaces", "kind": "Namespace"}, {"name": "configmaps", "kind": "ConfigMap"}]}`,
+		"/apis/apps/v1":              `{"resources": [{"name": "deployments", "kind": "Deployment"}, {"name": "deployments/scale", "kind": "Scale"}]}`,
+		"/apis/argoproj.io/v1alpha1": `{"resources": [{"name": "applications", "kind": "Application"}]}`,
+	}
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		*calls = append(*calls, strings.Join(args, " "))
+		path := args[len(args)-1]
+		if out, ok := responses[path]; ok {
+			return []byte(out), nil
+		}
+		return nil, fmt.Errorf("not found: %s", path)
+	}
+}
+
+func TestValidateLive(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"apps.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: web
+`,
+		"argocd.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: web
+`,
+		"tls.yaml": `apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: letsencrypt
+---
+apiVersion: acme.cert-manager.io/v1
+kind: Challenge
+metadata:
+  name: challenge
+`,
+		"crd.yaml": `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  versions:
+    - name: v1
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+`,
+		"values.yaml": "replicas: 2\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	var calls []string
+	v := New(&Options{Path: dir, Live: true, Context: "prod", FailOn: SeverityHigh})
+	v.SetRunner(fakeDiscovery(&calls))
+	result, err := v.Validate(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "--context prod get --raw /api", calls[0])
+	live := result.Categories[CategoryLive]
+	require.NotNil(t, live)
+	messages := map[string]Issue{}
+	for _, issue := range live.Issues {
+		messages[issue.Message] = issue
+	}
+	assert.Len(t, live.Issues, 4, "%v", live.Issues)
+
+	rollout := messages["Rollout web: the cluster does not serve argoproj.io/v1alpha1 Rollout"]
+	assert.Equal(t, "Install Argo Rollouts first: install the Argo Rollouts controller", rollout.Suggestion)
+	assert.Equal(t, 6, rollout.Line)
+	issuer := messages["ClusterIssuer letsencrypt: the cluster does not serve cert-manager.io/v1 ClusterIssuer"]
+	assert.Equal(t, "Install pattern cert-manager first: gitopsi marketplace install cert-manager", issuer.Suggestion)
+	assert.Contains(t, messages, "Challenge challenge: the cluster does not serve acme.cert-manager.io/v1 Challenge")
+	assert.Contains(t, messages, "CustomResourceDefinition widgets.example.com: the cluster does not serve apiextensions.k8s.io/v1 CustomResourceDefinition")
+	assert.Equal(t, 2, live.Passed, "argocd.yaml and values.yaml")
+	assert.Equal(t, 3, live.Failed)
+
+	require.Len(t, result.Prerequisites, 2)
+	assert.Equal(t, Prerequisite{Name: "Argo Rollouts", Install: "install the Argo Rollouts controller", Kinds: []string{"argoproj.io/v1alpha1 Rollout"}}, result.Prerequisites[0])
+	assert.Equal(t, []string{"acme.cert-manager.io/v1 Challenge", "cert-manager.io/v1 ClusterIssuer"}, result.Prerequisites[1].Kinds)
+	assert.True(t, v.ShouldFail(result))
+
+	for _, call := range calls {
+		assert.NotContains(t, call, "cert-manager", "group versions the cluster does not serve are not looked up")
+	}
+}
+
+func TestValidateLiveUnreachable(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ns.yaml"), []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shop\n"), 0644))
+
+	v := New(&Options{Path: dir, Live: true})
+	v.SetRunner(func(ctx context.Context, args ...string) ([]byte, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+	_, err := v.Validate(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read the API versions of the cluster: connection refused")
+}
@@ -0,0 +1,181 @@
+package validate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DefaultCacheDir is the directory of the validation cache, relative to the
+// validated repository.
+const DefaultCacheDir = ".gitopsi/validate-cache"
+
+// cacheVersion invalidates the cached results of earlier rule sets.
+const cacheVersion = "1"
+
+const cacheFile = "results.json"
+
+// fileResult is the result of a per-file check: the issues of the file, or
+// the error reading it.
+type fileResult struct {
+	Issues []Issue
+	Err    error
+}
+
+// fileCheck checks a file. Results that depend on more than the content and
+// the options, such as a schema that could not be downloaded, are not
+// cacheable.
+type fileCheck func(file string, data []byte) (issues []Issue, cacheable bool)
+
+// resultCache holds the issues of per-file checks by content hash. Entries
+// not looked up during a run are dropped when it is saved.
+type resultCache struct {
+	dir     string
+	mu      sync.Mutex
+	entries map[string][]Issue
+	used    map[string][]Issue
+	hits    int
+}
+
+// loadCache reads the cache of dir. A missing or unreadable cache is empty.
+func loadCache(dir string) *resultCache {
+	c := &resultCache{dir: dir, entries: map[string][]Issue{}, used: map[string][]Issue{}}
+	data, err := os.ReadFile(filepath.Join(dir, cacheFile))
+	if err != nil {
+		return c
+	}
+	var stored struct {
+		Version string             `json:"version"`
+		Entries map[string][]Issue `json:"entries"`
+	}
+	if json.Unmarshal(data, &stored) == nil && stored.Version == cacheVersion && stored.Entries != nil {
+		c.entries = stored.Entries
+	}
+	return c
+}
+
+func (c *resultCache) get(key, file string) ([]Issue, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.used[key] = stored
+	c.hits++
+	issues := make([]Issue, len(stored))
+	for i, issue := range stored {
+		issue.File = file
+		issues[i] = issue
+	}
+	return issues, true
+}
+
+func (c *resultCache) put(key string, issues []Issue) {
+	stored := make([]Issue, len(issues))
+	for i, issue := range issues {
+		// Files with the same content share the entry.
+		issue.File = ""
+		stored[i] = issue
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[key] = stored
+}
+
+// save writes the entries of the run. The directory ignores itself, so the
+// cache is never committed.
+func (c *resultCache) save() error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, ".gitignore"), []byte("*\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write cache .gitignore: %w", err)
+	}
+	data, err := json.Marshal(map[string]any{"version": cacheVersion, "entries": c.used})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, cacheFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+func cacheKey(check, fingerprint string, data []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", cacheVersion, check, fingerprint)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// jobs returns the number of workers of the run.
+func (v *Validator) jobs() int {
+	if v.opts.Jobs > 0 {
+		return v.opts.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// parallel calls fn for every index below n on the workers of the run.
+func (v *Validator) parallel(n int, fn func(i int)) {
+	workers := v.jobs()
+	if workers > n {
+		workers = n
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// checkFiles runs a per-file check on the workers of the run and returns the
+// results in the order of files. Files whose content, check and fingerprint
+// match a cached result are not checked again.
+func (v *Validator) checkFiles(check, fingerprint string, files []string, fn fileCheck) []fileResult {
+	results := make([]fileResult, len(files))
+	v.parallel(len(files), func(i int) {
+		data, err := os.ReadFile(files[i])
+		if err != nil {
+			results[i].Err = err
+			return
+		}
+		var key string
+		if v.cache != nil {
+			key = cacheKey(check, fingerprint, data)
+			if issues, ok := v.cache.get(key, files[i]); ok {
+				results[i].Issues = issues
+				return
+			}
+		}
+		issues, cacheable := fn(files[i], data)
+		if v.cache != nil && cacheable {
+			v.cache.put(key, issues)
+		}
+		results[i].Issues = issues
+	})
+	return results
+}
+
+// fingerprint joins the options a check depends on.
+func fingerprint(values ...string) string {
+	return strings.Join(values, "\x00")
+}
@@ -0,0 +1,87 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifests(t *testing.T, dir string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app-%d\n", i)
+		if i%2 == 0 {
+			content = fmt.Sprintf("apiVersion: extensions/v1beta1\nkind: Ingress\nmetadata:\n  name: app-%d\n  labels:\n    team: web\n", i)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("app-%02d.yaml", i)), []byte(content), 0644))
+	}
+}
+
+func TestValidateParallel(t *testing.T) {
+	dir := t.TempDir()
+	writeManifests(t, dir, 20)
+
+	run := func(jobs int) *ValidationResult {
+		result, err := New(&Options{Path: dir, Security: true, Deprecation: true, RequiredLabels: []string{"team"}, Jobs: jobs}).Validate(context.Background())
+		require.NoError(t, err)
+		return result
+	}
+	serial, concurrent := run(1), run(8)
+	assert.Equal(t, serial.Issues, concurrent.Issues, "issues are reported in file order whatever the workers")
+	assert.Equal(t, serial.Categories, concurrent.Categories)
+	assert.Len(t, concurrent.Categories[CategoryDeprecation].Issues, 10)
+	assert.Equal(t, filepath.Join(dir, "app-00.yaml"), concurrent.Categories[CategoryDeprecation].Issues[0].File)
+	assert.Equal(t, 10, concurrent.Categories[CategoryConventions].Failed)
+}
+
+func TestValidateCache(t *testing.T) {
+	dir := t.TempDir()
+	writeManifests(t, dir, 4)
+	cacheDir := filepath.Join(dir, DefaultCacheDir)
+
+	run := func() *ValidationResult {
+		result, err := New(&Options{Path: dir, Security: true, Deprecation: true, RequiredLabels: []string{"team"}, CacheDir: cacheDir}).Validate(context.Background())
+		require.NoError(t, err)
+		return result
+	}
+	first := run()
+	assert.Zero(t, first.CachedFiles)
+	assert.FileExists(t, filepath.Join(cacheDir, "results.json"))
+	ignore, err := os.ReadFile(filepath.Join(cacheDir, ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(ignore))
+
+	second := run()
+	assert.Equal(t, 12, second.CachedFiles, "4 files of 3 checks")
+	assert.Equal(t, first.Issues, second.Issues)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app-01.yaml"), []byte("apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: app-1\n"), 0644))
+	third := run()
+	assert.Equal(t, 9, third.CachedFiles, "the changed file is checked again")
+	assert.Contains(t, third.Categories[CategoryDeprecation].Issues[1].Message, "apps/v1beta1")
+	assert.Equal(t, filepath.Join(dir, "app-01.yaml"), third.Categories[CategoryDeprecation].Issues[1].File)
+
+	labels, err := New(&Options{Path: dir, RequiredLabels: []string{"owner"}, CacheDir: cacheDir}).Validate(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, labels.CachedFiles, "results of other options are not reused")
+	assert.Equal(t, 4, labels.Categories[CategoryConventions].Failed)
+}
+
+func TestResultCacheCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "results.json"), []byte("{not json"), 0644))
+	c := loadCache(dir)
+	_, ok := c.get("key", "file.yaml")
+	assert.False(t, ok)
+
+	c.put("key", []Issue{{File: "file.yaml", Rule: "DEP001"}})
+	require.NoError(t, c.save())
+	issues, ok := loadCache(dir).get("key", "moved.yaml")
+	require.True(t, ok)
+	assert.Equal(t, []Issue{{File: "moved.yaml", Rule: "DEP001"}}, issues)
+}
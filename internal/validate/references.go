@@ -0,0 +1,388 @@
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFiles are the file names kustomize reads in a directory.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// workloadKinds are the kinds whose pod template labels Services select.
+var workloadKinds = map[string]bool{
+	"Deployment": true, "StatefulSet": true, "DaemonSet": true, "ReplicaSet": true, "Rollout": true, "DeploymentConfig": true,
+}
+
+// manifestResource is a resource of a manifest of the repository.
+type manifestResource struct {
+	File string
+	Line int
+	obj  map[string]any
+}
+
+func (r manifestResource) str(keys ...string) string {
+	s, _ := lookupValue(r.obj, keys...).(string)
+	return s
+}
+
+// validateReferences checks the references between the files and resources
+// of the repository: the files kustomizations list, the paths Applications
+// and Flux Kustomizations sync, the workloads Services select and the
+// namespaces of the manifests Applications deploy.
+func (v *Validator) validateReferences(manifests []string, result *ValidationResult) error {
+	catResult := &CategoryResult{Issues: []Issue{}}
+	result.Categories[CategoryReferences] = catResult
+
+	var resources []manifestResource
+	for _, manifest := range manifests {
+		data, err := os.ReadFile(manifest)
+		if err != nil {
+			continue
+		}
+		resources = append(resources, manifestResources(manifest, data)...)
+	}
+
+	checked := len(manifests)
+	err := filepath.Walk(v.opts.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isKustomizationFile(path) {
+			return err
+		}
+		checked++
+		catResult.Issues = append(catResult.Issues, kustomizationIssues(path)...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sources := gitRepositories(resources)
+	for _, r := range resources {
+		var issues []Issue
+		switch {
+		case r.str("kind") == "Application" && strings.HasPrefix(r.str("apiVersion"), "argoproj.io/"):
+			issues = v.applicationIssues(r)
+		case r.str("kind") == "Kustomization" && strings.HasPrefix(r.str("apiVersion"), "kustomize.toolkit.fluxcd.io/"):
+			issues = v.fluxKustomizationIssues(r, sources)
+		case r.str("kind") == "Service":
+			issues = serviceIssues(r, resources)
+		}
+		catResult.Issues = append(catResult.Issues, issues...)
+	}
+
+	failed := map[string]bool{}
+	for _, issue := range catResult.Issues {
+		failed[issue.File] = true
+	}
+	catResult.Failed = len(failed)
+	catResult.Passed = checked - catResult.Failed
+	result.Issues = append(result.Issues, catResult.Issues...)
+	return nil
+}
+
+func isKustomizationFile(path string) bool {
+	base := filepath.Base(path)
+	for _, name := range kustomizationFiles {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// kustomizationIssues reports the resources, components and patches of a
+// kustomization that do not exist. Remote resources are not checked.
+func kustomizationIssues(path string) []Issue {
+	k, err := readKustomization(path)
+	if err != nil {
+		return nil
+	}
+	refs := append(append(append([]string{}, k.Resources...), k.Bases...), k.Components...)
+	refs = append(refs, k.patchFiles()...)
+
+	var issues []Issue
+	dir := filepath.Dir(path)
+	for _, ref := range refs {
+		// Inline strategic merge patches are YAML, not paths.
+		if isRemote(ref) || strings.Contains(ref, "\n") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, ref)); err == nil {
+			continue
+		}
+		issues = append(issues, Issue{
+			File:       path,
+			Category:   CategoryReferences,
+			Severity:   SeverityHigh,
+			Rule:       "REF001",
+			Message:    fmt.Sprintf("Kustomization references %s, which does not exist", ref),
+			Suggestion: "Create the file or directory, or remove it from the kustomization",
+		})
+	}
+	return issues
+}
+
+// kustomization holds the files a kustomization references.
+type kustomization struct {
+	Resources             []string `yaml:"resources"`
+	Bases                 []string `yaml:"bases"`
+	Components            []string `yaml:"components"`
+	PatchesStrategicMerge []string `yaml:"patchesStrategicMerge"`
+	Patches               []struct {
+		Path string `yaml:"path"`
+	} `yaml:"patches"`
+}
+
+func readKustomization(path string) (*kustomization, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var k kustomization
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// patchFiles returns the patch files of the kustomization, relative to its
+// directory.
+func (k *kustomization) patchFiles() []string {
+	files := append([]string{}, k.PatchesStrategicMerge...)
+	for _, p := range k.Patches {
+		if p.Path != "" {
+			files = append(files, p.Path)
+		}
+	}
+	return files
+}
+
+// isRemote reports whether a kustomization reference is a remote URL.
+func isRemote(ref string) bool {
+	return strings.Contains(ref, "://") || strings.HasPrefix(ref, "github.com/") ||
+		strings.HasPrefix(ref, "git@") || strings.Contains(ref, "?ref=")
+}
+
+// applicationIssues checks the sources of an ArgoCD Application from the
+// repository: the path must exist, and the manifests at the path must not
+// set another namespace than the destination.
+func (v *Validator) applicationIssues(app manifestResource) []Issue {
+	var sources []any
+	if src := lookupValue(app.obj, "spec", "source"); src != nil {
+		sources = append(sources, src)
+	}
+	if list, ok := lookupValue(app.obj, "spec", "sources").([]any); ok {
+		sources = append(sources, list...)
+	}
+	destination, _ := lookupValue(app.obj, "spec", "destination", "namespace").(string)
+
+	var issues []Issue
+	for _, src := range sources {
+		repo, _ := lookupValue(src, "repoURL").(string)
+		path, _ := lookupValue(src, "path").(string)
+		if path == "" || !v.inRepository(repo) || strings.Contains(path, "{{") {
+			continue
+		}
+		issues = append(issues, v.pathIssues(app, "Application", path, destination)...)
+	}
+	return issues
+}
+
+// gitRepositories returns the URLs of the Flux GitRepositories by
+// namespace/name.
+func gitRepositories(resources []manifestResource) map[string]string {
+	urls := map[string]string{}
+	for _, r := range resources {
+		if r.str("kind") == "GitRepository" && strings.HasPrefix(r.str("apiVersion"), "source.toolkit.fluxcd.io/") {
+			urls[r.str("metadata", "namespace")+"/"+r.str("metadata", "name")] = r.str("spec", "url")
+		}
+	}
+	return urls
+}
+
+// fluxKustomizationIssues checks the path of a Flux Kustomization syncing a
+// GitRepository of the repository.
+func (v *Validator) fluxKustomizationIssues(k manifestResource, sources map[string]string) []Issue {
+	if kind := k.str("spec", "sourceRef", "kind"); kind != "" && kind != "GitRepository" {
+		return nil
+	}
+	namespace := k.str("spec", "sourceRef", "namespace")
+	if namespace == "" {
+		namespace = k.str("metadata", "namespace")
+	}
+	url, ok := sources[namespace+"/"+k.str("spec", "sourceRef", "name")]
+	path := k.str("spec", "path")
+	if !ok || path == "" || !v.inRepository(url) {
+		return nil
+	}
+	return v.pathIssues(k, "Flux Kustomization", path, k.str("spec", "targetNamespace"))
+}
+
+// inRepository reports whether a repository URL is the validated
+// repository. Without RepoURL, every repository is.
+func (v *Validator) inRepository(url string) bool {
+	if v.opts.RepoURL == "" {
+		return true
+	}
+	normalize := func(u string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(u), "/"), ".git")
+	}
+	return normalize(url) == normalize(v.opts.RepoURL)
+}
+
+// pathIssues reports a synced path missing from the repository, and the
+// namespaces set at the path that differ from the destination namespace.
+func (v *Validator) pathIssues(r manifestResource, kind, path, destination string) []Issue {
+	name := r.str("metadata", "name")
+	dir := filepath.Join(v.opts.Path, filepath.FromSlash(strings.TrimPrefix(path, "./")))
+	if _, err := os.Stat(dir); err != nil {
+		return []Issue{{
+			File:       r.File,
+			Line:       r.Line,
+			Category:   CategoryReferences,
+			Severity:   SeverityHigh,
+			Rule:       "REF002",
+			Message:    fmt.Sprintf("%s %s syncs path %s, which does not exist in the repository", kind, name, path),
+			Suggestion: "Create the directory or fix the path",
+		}}
+	}
+	if destination == "" {
+		return nil
+	}
+
+	mismatch := func(namespace, where string) Issue {
+		return Issue{
+			File:       r.File,
+			Line:       r.Line,
+			Category:   CategoryReferences,
+			Severity:   SeverityMedium,
+			Rule:       "REF004",
+			Message:    fmt.Sprintf("%s %s deploys to namespace %s, but %s sets namespace %s", kind, name, destination, where, namespace),
+			Suggestion: "Align the destination namespace with the namespace of the manifests",
+		}
+	}
+	for _, file := range kustomizationFiles {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		var k struct {
+			Namespace string `yaml:"namespace"`
+		}
+		if yaml.Unmarshal(data, &k) == nil && k.Namespace != "" {
+			if k.Namespace != destination {
+				return []Issue{mismatch(k.Namespace, filepath.ToSlash(filepath.Join(path, file)))}
+			}
+			// The kustomization overrides the namespaces of its resources.
+			return nil
+		}
+	}
+
+	var issues []Issue
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") || isKustomizationFile(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, res := range manifestResources(entry.Name(), data) {
+			if ns := res.str("metadata", "namespace"); ns != "" && ns != destination {
+				issues = append(issues, mismatch(ns, fmt.Sprintf("%s %s in %s", res.str("kind"), res.str("metadata", "name"), filepath.ToSlash(filepath.Join(path, entry.Name())))))
+			}
+		}
+	}
+	return issues
+}
+
+// serviceIssues reports a Service whose selector matches the pod template of
+// no workload of its namespace. Resources without a namespace, which
+// overlays set, match any namespace.
+func serviceIssues(svc manifestResource, resources []manifestResource) []Issue {
+	selector, ok := lookupValue(svc.obj, "spec", "selector").(map[string]any)
+	if !ok || len(selector) == 0 || svc.str("spec", "type") == "ExternalName" {
+		return nil
+	}
+	namespace := svc.str("metadata", "namespace")
+	for _, r := range resources {
+		if !workloadKinds[r.str("kind")] {
+			continue
+		}
+		if ns := r.str("metadata", "namespace"); ns != "" && namespace != "" && ns != namespace {
+			continue
+		}
+		labels, _ := lookupValue(r.obj, "spec", "template", "metadata", "labels").(map[string]any)
+		if selects(selector, labels) {
+			return nil
+		}
+	}
+
+	keys := make([]string, 0, len(selector))
+	for k, val := range selector {
+		keys = append(keys, fmt.Sprintf("%s=%v", k, val))
+	}
+	sort.Strings(keys)
+	return []Issue{{
+		File:       svc.File,
+		Line:       svc.Line,
+		Category:   CategoryReferences,
+		Severity:   SeverityMedium,
+		Rule:       "REF003",
+		Message:    fmt.Sprintf("Service %s selects %s, which matches no workload", svc.str("metadata", "name"), strings.Join(keys, ",")),
+		Suggestion: "Fix the selector or the pod template labels of the workload",
+	}}
+}
+
+func selects(selector, labels map[string]any) bool {
+	for k, want := range selector {
+		if got, ok := labels[k]; !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// manifestResources returns the Kubernetes resources of a YAML stream.
+func manifestResources(file string, content []byte) []manifestResource {
+	var resources []manifestResource
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			// The end of the stream, or content that is not YAML.
+			return resources
+		}
+		if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		var obj map[string]any
+		if doc.Content[0].Decode(&obj) != nil {
+			continue
+		}
+		r := manifestResource{File: file, Line: doc.Content[0].Line, obj: obj}
+		if r.str("apiVersion") == "" || r.str("kind") == "" {
+			continue
+		}
+		resources = append(resources, r)
+	}
+}
+
+// lookupValue returns the value at a path of nested maps, or nil.
+func lookupValue(v any, keys ...string) any {
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
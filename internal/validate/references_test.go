@@ -0,0 +1,170 @@
+package validate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReferences(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"apps/web/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: web
+resources:
+  - deployment.yaml
+  - service.yaml
+  - missing.yaml
+  - https://github.com/example/remote//base?ref=v1
+patches:
+  - path: patch.yaml
+`,
+		"apps/web/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+        tier: frontend
+`,
+		"apps/web/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  selector:
+    app: api
+`,
+		"apps/jobs/cronjob.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: jobs
+  namespace: batch
+`,
+		"argocd/apps.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: web
+spec:
+  source:
+    repoURL: https://github.com/example/platform.git
+    path: apps/web
+  destination:
+    namespace: shop
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: jobs
+spec:
+  sources:
+    - repoURL: https://github.com/example/platform
+      path: apps/jobs
+    - repoURL: https://github.com/example/platform
+      path: apps/removed
+    - repoURL: https://github.com/example/other
+      path: apps/elsewhere
+    - repoURL: https://charts.example.com
+      chart: jobs
+  destination:
+    namespace: jobs
+`,
+		"flux/sync.yaml": `apiVersion: source.toolkit.fluxcd.io/v1
+kind: GitRepository
+metadata:
+  name: platform
+  namespace: flux-system
+spec:
+  url: https://github.com/example/platform/
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: infra
+  namespace: flux-system
+spec:
+  path: ./infrastructure
+  sourceRef:
+    kind: GitRepository
+    name: platform
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	v := New(&Options{Path: dir, References: true, RepoURL: "https://github.com/example/platform", FailOn: SeverityHigh})
+	result, err := v.Validate(context.Background())
+	require.NoError(t, err)
+
+	refs := result.Categories[CategoryReferences]
+	require.NotNil(t, refs)
+	byMessage := map[string]Issue{}
+	for _, issue := range refs.Issues {
+		byMessage[issue.Message] = issue
+	}
+	assert.Len(t, refs.Issues, 7, "%v", refs.Issues)
+
+	missing := byMessage["Kustomization references missing.yaml, which does not exist"]
+	assert.Equal(t, "REF001", missing.Rule)
+	assert.Equal(t, SeverityHigh, missing.Severity)
+	assert.Contains(t, byMessage, "Kustomization references patch.yaml, which does not exist")
+
+	removed := byMessage["Application jobs syncs path apps/removed, which does not exist in the repository"]
+	assert.Equal(t, "REF002", removed.Rule)
+	assert.Equal(t, 12, removed.Line)
+	assert.Contains(t, byMessage, "Flux Kustomization infra syncs path ./infrastructure, which does not exist in the repository")
+
+	api := byMessage["Service api selects app=api, which matches no workload"]
+	assert.Equal(t, "REF003", api.Rule)
+	assert.Equal(t, SeverityMedium, api.Severity)
+
+	assert.Equal(t, "REF004", byMessage["Application web deploys to namespace shop, but apps/web/kustomization.yaml sets namespace web"].Rule)
+	assert.Contains(t, byMessage, "Application jobs deploys to namespace jobs, but ConfigMap jobs in apps/jobs/cronjob.yaml sets namespace batch")
+
+	assert.Equal(t, 4, refs.Failed, "the kustomization, argocd/apps.yaml, flux/sync.yaml and service.yaml")
+	assert.True(t, v.ShouldFail(result))
+}
+
+func TestValidateReferencesClean(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "base"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "kustomization.yaml"), []byte("resources:\n  - ns.yaml\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "ns.yaml"), []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shop\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: shop
+spec:
+  source:
+    repoURL: https://github.com/example/platform
+    path: base
+  destination:
+    namespace: shop
+`), 0644))
+
+	result, err := New(&Options{Path: dir, References: true}).Validate(context.Background())
+	require.NoError(t, err)
+	refs := result.Categories[CategoryReferences]
+	require.NotNil(t, refs)
+	assert.Empty(t, refs.Issues)
+	assert.Equal(t, 3, refs.Passed)
+}
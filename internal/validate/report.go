@@ -0,0 +1,259 @@
+package validate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report formats supported by Report.
+const (
+	ReportSARIF = "sarif"
+	ReportJUnit = "junit"
+	ReportJSON  = "json"
+)
+
+// ReportFormats are the formats of Report.
+var ReportFormats = []string{ReportSARIF, ReportJUnit, ReportJSON}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Report renders the result in a format CI systems read: SARIF for code
+// scanning, JUnit XML for test reports, or JSON.
+func (result *ValidationResult) Report(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case ReportSARIF:
+		return result.ToSARIF()
+	case ReportJUnit:
+		return result.ToJUnit()
+	case ReportJSON:
+		return result.ToJSON()
+	default:
+		return "", fmt.Errorf("unsupported report format: %s (use sarif, junit or json)", format)
+	}
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// ToSARIF renders the issues as a SARIF 2.1.0 log. Critical and high issues
+// are errors, medium issues warnings, and low and info issues notes.
+func (result *ValidationResult) ToSARIF() (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "gitopsi",
+			InformationURI: "https://github.com/ihsanmokhlisse/gitopsi",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, issue := range result.Issues {
+		if !rules[issue.Rule] {
+			rules[issue.Rule] = true
+			rule := sarifRule{
+				ID:               issue.Rule,
+				Name:             string(issue.Category),
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("gitopsi %s check %s", issue.Category, issue.Rule)},
+			}
+			rule.DefaultConfiguration.Level = sarifLevel(issue.Severity)
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		message := issue.Message
+		if issue.Suggestion != "" {
+			message += ". " + issue.Suggestion
+		}
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = reportPath(issue.File)
+		if issue.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: issue.Line}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    issue.Rule,
+			Level:     sarifLevel(issue.Severity),
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{Version: "2.1.0", Schema: sarifSchema, Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ToJUnit renders the result as JUnit XML, with a test suite per category
+// and a test case per issue. As in the summary, critical and high issues
+// fail; medium and low issues pass with the issue as output. A category
+// without issues has a single passing test case.
+func (result *ValidationResult) ToJUnit() (string, error) {
+	suites := junitTestSuites{Name: "gitopsi validate", Suites: []junitTestSuite{}}
+
+	byCategory := map[Category][]Issue{}
+	for _, issue := range result.Issues {
+		byCategory[issue.Category] = append(byCategory[issue.Category], issue)
+	}
+	categories := make([]string, 0, len(result.Categories))
+	for category := range result.Categories {
+		categories = append(categories, string(category))
+	}
+	for category := range byCategory {
+		if _, ok := result.Categories[category]; !ok {
+			categories = append(categories, string(category))
+		}
+	}
+	sort.Strings(categories)
+
+	for _, name := range categories {
+		suite := junitTestSuite{Name: name}
+		for _, issue := range byCategory[Category(name)] {
+			tc := junitTestCase{
+				Name:      fmt.Sprintf("%s %s", issue.Rule, issueLocation(issue)),
+				Classname: name,
+				File:      reportPath(issue.File),
+			}
+			text := issue.Message
+			if issue.Suggestion != "" {
+				text += "\n" + issue.Suggestion
+			}
+			switch issue.Severity {
+			case SeverityCritical, SeverityHigh:
+				tc.Failure = &junitFailure{Message: issue.Message, Type: string(issue.Severity), Text: text}
+				suite.Failures++
+			default:
+				tc.SystemOut = fmt.Sprintf("[%s] %s", strings.ToUpper(string(issue.Severity)), text)
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: name, Classname: name})
+		}
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+func issueLocation(issue Issue) string {
+	if issue.Line > 0 {
+		return fmt.Sprintf("%s:%d", reportPath(issue.File), issue.Line)
+	}
+	return reportPath(issue.File)
+}
+
+// reportPath returns a file of an issue as a slash-separated path, relative
+// to the working directory when possible, as code scanning resolves paths
+// from the repository root.
+func reportPath(file string) string {
+	if wd, err := os.Getwd(); err == nil && filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(file))
+}
@@ -0,0 +1,99 @@
+package validate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reportResult() *ValidationResult {
+	issues := []Issue{
+		{File: "apps/web/deployment.yaml", Line: 12, Category: CategorySecurity, Severity: SeverityHigh, Rule: "SEC001", Message: "Container runs as root", Suggestion: "Set runAsNonRoot"},
+		{File: "apps/web/service.yaml", Category: CategoryReferences, Severity: SeverityMedium, Rule: "REF003", Message: "Service web selects app=web, which matches no workload"},
+		{File: "apps/web/deployment.yaml", Line: 30, Category: CategorySecurity, Severity: SeverityLow, Rule: "SEC003", Message: "No resource limits"},
+	}
+	return &ValidationResult{
+		Path:   "apps",
+		Issues: issues,
+		Categories: map[Category]*CategoryResult{
+			CategorySecurity:   {Failed: 1, Issues: issues[:1]},
+			CategoryReferences: {Issues: issues[1:2]},
+			CategorySchema:     {Passed: 2, Issues: []Issue{}},
+		},
+	}
+}
+
+func TestToSARIF(t *testing.T) {
+	out, err := reportResult().Report("SARIF")
+	require.NoError(t, err)
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal([]byte(out), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "gitopsi", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 3)
+	assert.Equal(t, "SEC001", run.Tool.Driver.Rules[0].ID)
+	assert.Equal(t, "error", run.Tool.Driver.Rules[0].DefaultConfiguration.Level)
+
+	require.Len(t, run.Results, 3)
+	root := run.Results[0]
+	assert.Equal(t, "SEC001", root.RuleID)
+	assert.Equal(t, "error", root.Level)
+	assert.Equal(t, "Container runs as root. Set runAsNonRoot", root.Message.Text)
+	assert.Equal(t, "apps/web/deployment.yaml", root.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 12, root.Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "warning", run.Results[1].Level)
+	assert.Nil(t, run.Results[1].Locations[0].PhysicalLocation.Region, "issues without a line have no region")
+	assert.Equal(t, "note", run.Results[2].Level)
+	assert.Contains(t, out, `"$schema": "https://json.schemastore.org/sarif-2.1.0.json"`)
+
+	empty, err := (&ValidationResult{}).ToSARIF()
+	require.NoError(t, err)
+	assert.Contains(t, empty, `"results": []`)
+}
+
+func TestToJUnit(t *testing.T) {
+	out, err := reportResult().Report(ReportJUnit)
+	require.NoError(t, err)
+	assert.Contains(t, out, `<?xml version="1.0" encoding="UTF-8"?>`)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal([]byte(out), &suites))
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	require.Len(t, suites.Suites, 3)
+
+	refs, schema, security := suites.Suites[0], suites.Suites[1], suites.Suites[2]
+	assert.Equal(t, "references", refs.Name)
+	assert.Equal(t, 0, refs.Failures, "medium issues do not fail")
+	assert.Equal(t, "[MEDIUM] Service web selects app=web, which matches no workload", refs.Cases[0].SystemOut)
+
+	assert.Equal(t, "schema", schema.Name)
+	require.Len(t, schema.Cases, 1)
+	assert.Nil(t, schema.Cases[0].Failure)
+
+	assert.Equal(t, 2, security.Tests)
+	assert.Equal(t, 1, security.Failures)
+	tc := security.Cases[0]
+	assert.Equal(t, "SEC001 apps/web/deployment.yaml:12", tc.Name)
+	assert.Equal(t, "apps/web/deployment.yaml", tc.File)
+	require.NotNil(t, tc.Failure)
+	assert.Equal(t, "high", tc.Failure.Type)
+	assert.Equal(t, "Container runs as root\nSet runAsNonRoot", tc.Failure.Text)
+	assert.Nil(t, security.Cases[1].Failure)
+}
+
+func TestReportUnsupported(t *testing.T) {
+	_, err := reportResult().Report("html")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported report format: html")
+
+	out, err := reportResult().Report(ReportJSON)
+	require.NoError(t, err)
+	assert.Contains(t, out, `"rule": "REF003"`)
+}
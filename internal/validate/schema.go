@@ -0,0 +1,421 @@
+package validate
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/yannh/kubeconform/pkg/validator"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+)
+
+// DefaultSchemaLocation is the upstream Kubernetes JSON schema registry used
+// by kubeconform.
+const DefaultSchemaLocation = "default"
+
+// CRDCatalogSchemaLocation is the community CRD schema catalog, consulted for
+// custom resources without an embedded schema.
+const CRDCatalogSchemaLocation = "https://raw.githubusercontent.com/datreeio/CRDs-catalog/main/{{ .Group }}/{{ .ResourceKind }}_{{ .ResourceAPIVersion }}.json"
+
+// defaultSchemaTemplate is the schema location kubeconform uses for
+// DefaultSchemaLocation.
+const defaultSchemaTemplate = "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+
+// crdSchemaPath is the layout of the embedded CRD schemas, matching the CRD catalog.
+const crdSchemaPath = "{{ .Group }}/{{ .ResourceKind }}_{{ .ResourceAPIVersion }}.json"
+
+// Embedded JSON schemas for the ArgoCD and Flux resources gitopsi generates,
+// so these validate offline.
+//
+//go:embed schemas
+var crdSchemas embed.FS
+
+// DefaultSchemaCacheDir returns the directory downloaded schemas are cached in.
+func DefaultSchemaCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".gitopsi", "cache", "schemas")
+	}
+	return filepath.Join(home, ".gitopsi", "cache", "schemas")
+}
+
+// schemaStore prepares schema locations for kubeconform. Embedded CRD schemas
+// are extracted to disk because kubeconform reads local schemas from files.
+type schemaStore struct {
+	locations []string
+	cacheDir  string
+	// mirrorDir holds the schemas downloaded by mirror
+	mirrorDir string
+	// unavailable are the errors downloading the schemas of resources, by
+	// apiVersion and kind
+	unavailable map[string]error
+	cleanup     func()
+}
+
+func newSchemaStore(opts *Options) (*schemaStore, error) {
+	store := &schemaStore{cleanup: func() {}}
+
+	var crdDir string
+	if opts.SchemaCacheDir != "" {
+		store.cacheDir = filepath.Join(opts.SchemaCacheDir, "http")
+		if err := os.MkdirAll(store.cacheDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create schema cache: %w", err)
+		}
+		crdDir = filepath.Join(opts.SchemaCacheDir, "crds")
+		store.mirrorDir = filepath.Join(opts.SchemaCacheDir, "mirror")
+	} else {
+		tmp, err := os.MkdirTemp("", "gitopsi-schemas-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create schema directory: %w", err)
+		}
+		store.cleanup = func() { os.RemoveAll(tmp) }
+		crdDir = filepath.Join(tmp, "crds")
+		store.mirrorDir = filepath.Join(tmp, "mirror")
+	}
+
+	if err := extractCRDSchemas(crdDir); err != nil {
+		store.cleanup()
+		return nil, err
+	}
+
+	// Embedded schemas come first: kubeconform stops at the first registry
+	// that fails with anything other than "not found", so offline use must
+	// not depend on the remote registries being reachable.
+	store.locations = append(store.locations, filepath.Join(crdDir, crdSchemaPath))
+	if len(opts.SchemaLocations) > 0 {
+		store.locations = append(store.locations, opts.SchemaLocations...)
+	} else {
+		store.locations = append(store.locations, DefaultSchemaLocation, CRDCatalogSchemaLocation)
+	}
+	return store, nil
+}
+
+// mirror downloads the schemas of the resources of manifests from the HTTP
+// schema locations through http.DefaultTransport, and replaces these
+// locations with the downloaded copies. kubeconform builds its own HTTP
+// transport, which ignores the CA bundle of network.Configure.
+//
+// Like kubeconform, the locations are tried in order until one has the
+// schema; a download failing with anything other than "not found" leaves the
+// resource unavailable.
+func (s *schemaStore) mirror(ctx context.Context, manifests []string, k8sVersion string, strict bool) error {
+	if k8sVersion == "" {
+		k8sVersion = "master"
+	}
+	var remote []int
+	for i, location := range s.locations {
+		location = expandSchemaLocation(location)
+		if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+			s.locations[i] = location
+			remote = append(remote, i)
+		}
+	}
+	if len(remote) == 0 {
+		return nil
+	}
+
+	s.unavailable = map[string]error{}
+	seen := map[string]bool{}
+	for _, file := range manifests {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, sig := range resourceSignatures(data) {
+			if seen[sig.key()] {
+				continue
+			}
+			seen[sig.key()] = true
+			for _, i := range remote {
+				found, err := s.download(ctx, s.locations[i], sig, k8sVersion, strict)
+				if err != nil {
+					s.unavailable[sig.key()] = err
+					break
+				}
+				if found {
+					break
+				}
+			}
+		}
+	}
+
+	for _, i := range remote {
+		s.locations[i] = s.mirrorPath(s.locations[i])
+	}
+	return nil
+}
+
+// download downloads the schema of a resource from a location into the
+// mirror, and reports whether the location has it.
+func (s *schemaStore) download(ctx context.Context, location string, sig resourceSignature, k8sVersion string, strict bool) (bool, error) {
+	url, err := renderSchemaLocation(location, sig, k8sVersion, strict)
+	if err != nil {
+		return false, err
+	}
+	dest := s.mirrorPath(url)
+	if _, err := os.Stat(dest); err == nil {
+		return true, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to download schema %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to download schema %s: HTTP %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to download schema %s: %w", url, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return false, fmt.Errorf("failed to create schema directory: %w", err)
+	}
+	if err := os.WriteFile(dest, body, 0644); err != nil {
+		return false, fmt.Errorf("failed to write schema %s: %w", dest, err)
+	}
+	return true, nil
+}
+
+// mirrorPath maps an HTTP schema location or URL to the mirror.
+func (s *schemaStore) mirrorPath(location string) string {
+	_, rest, _ := strings.Cut(location, "://")
+	return s.mirrorDir + "/" + rest
+}
+
+// expandSchemaLocation expands a schema location the way kubeconform does:
+// DefaultSchemaLocation is its registry, and a location not ending with json
+// holds the layout of that registry.
+func expandSchemaLocation(location string) string {
+	if location == DefaultSchemaLocation {
+		return defaultSchemaTemplate
+	}
+	if !strings.HasSuffix(location, "json") {
+		return location + "/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+	}
+	return location
+}
+
+// renderSchemaLocation renders a schema location template for a resource,
+// with the variables of kubeconform.
+func renderSchemaLocation(location string, sig resourceSignature, k8sVersion string, strict bool) (string, error) {
+	version := k8sVersion
+	if version != "master" {
+		version = "v" + version
+	}
+	strictSuffix := ""
+	if strict {
+		strictSuffix = "-strict"
+	}
+	groupParts := strings.Split(sig.APIVersion, "/")
+	versionParts := strings.Split(groupParts[0], ".")
+	kindSuffix := "-" + strings.ToLower(versionParts[0])
+	if len(groupParts) > 1 {
+		kindSuffix += "-" + strings.ToLower(groupParts[1])
+	}
+
+	tmpl, err := template.New("location").Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid schema location %s: %w", location, err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"NormalizedKubernetesVersion": version,
+		"StrictSuffix":                strictSuffix,
+		"ResourceKind":                strings.ToLower(sig.Kind),
+		"ResourceAPIVersion":          groupParts[len(groupParts)-1],
+		"Group":                       groupParts[0],
+		"KindSuffix":                  kindSuffix,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid schema location %s: %w", location, err)
+	}
+	return buf.String(), nil
+}
+
+// resourceSignature identifies the schema of a resource.
+type resourceSignature struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+func (r resourceSignature) key() string {
+	return r.APIVersion + " " + r.Kind
+}
+
+// resourceSignatures returns the resources of a YAML file.
+func resourceSignatures(data []byte) []resourceSignature {
+	var sigs []resourceSignature
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var sig resourceSignature
+		// The schema check reports invalid YAML.
+		if err := dec.Decode(&sig); err != nil {
+			return sigs
+		}
+		if sig.APIVersion != "" && sig.Kind != "" {
+			sigs = append(sigs, sig)
+		}
+	}
+}
+
+func extractCRDSchemas(dir string) error {
+	return fs.WalkDir(crdSchemas, "schemas", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel("schemas", filepath.FromSlash(p))
+		if err != nil {
+			return err
+		}
+		data, err := crdSchemas.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create schema directory: %w", err)
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			return fmt.Errorf("failed to write schema %s: %w", dest, err)
+		}
+		return nil
+	})
+}
+
+func (v *Validator) validateSchema(ctx context.Context, manifests []string, result *ValidationResult) error {
+	catResult := &CategoryResult{Issues: []Issue{}}
+	result.Categories[CategorySchema] = catResult
+
+	store, err := newSchemaStore(v.opts)
+	if err != nil {
+		return err
+	}
+	defer store.cleanup()
+	if network.CABundle() != "" {
+		if err := store.mirror(ctx, manifests, v.opts.K8sVersion, v.opts.StrictSchema); err != nil {
+			return err
+		}
+	}
+
+	val, err := validator.New(store.locations, validator.Opts{
+		Cache:                store.cacheDir,
+		KubernetesVersion:    v.opts.K8sVersion,
+		Strict:               v.opts.StrictSchema,
+		SkipTLS:              network.InsecureTLS(),
+		IgnoreMissingSchemas: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize schema validator: %w", err)
+	}
+
+	fp := fingerprint(v.opts.K8sVersion, fmt.Sprint(v.opts.StrictSchema), strings.Join(v.opts.SchemaLocations, ","))
+	results := v.checkFiles("schema", fp, manifests, func(file string, data []byte) ([]Issue, bool) {
+		issues := schemaIssues(file, val.ValidateWithContext(ctx, file, io.NopCloser(bytes.NewReader(data))))
+		for _, sig := range resourceSignatures(data) {
+			if err := store.unavailable[sig.key()]; err != nil {
+				issues = append(issues, unavailableSchemaIssue(file, sig.APIVersion, sig.Kind, err))
+			}
+		}
+		for _, issue := range issues {
+			// The schema may be downloaded on the next run.
+			if issue.Rule == "schema-unavailable" {
+				return issues, false
+			}
+		}
+		return issues, true
+	})
+	for i, r := range results {
+		if r.Err != nil {
+			catResult.Issues = append(catResult.Issues, Issue{
+				File:     manifests[i],
+				Category: CategorySchema,
+				Severity: SeverityHigh,
+				Rule:     "yaml-read",
+				Message:  fmt.Sprintf("Cannot read file: %v", r.Err),
+			})
+			catResult.Failed++
+			continue
+		}
+		if len(r.Issues) == 0 {
+			catResult.Passed++
+			continue
+		}
+		catResult.Issues = append(catResult.Issues, r.Issues...)
+		catResult.Failed += len(r.Issues)
+	}
+
+	result.Issues = append(result.Issues, catResult.Issues...)
+	return nil
+}
+
+// schemaIssues converts kubeconform results for a file into issues.
+func schemaIssues(file string, results []validator.Result) []Issue {
+	var issues []Issue
+	for _, r := range results {
+		switch r.Status {
+		case validator.Invalid:
+			sig, _ := r.Resource.Signature()
+			resource := fmt.Sprintf("%s %s", sig.Kind, sig.Name)
+			if len(r.ValidationErrors) == 0 {
+				issues = append(issues, schemaIssue(file, "schema-invalid", SeverityHigh, fmt.Sprintf("%s: %v", resource, r.Err)))
+			}
+			for _, ve := range r.ValidationErrors {
+				issues = append(issues, schemaIssue(file, "schema-invalid", SeverityHigh, fmt.Sprintf("%s: %s: %s", resource, ve.Path, ve.Msg)))
+			}
+		case validator.Error:
+			sig, sigErr := r.Resource.Signature()
+			if sigErr != nil {
+				var doc interface{}
+				if err := yaml.Unmarshal(r.Resource.Bytes, &doc); err != nil {
+					issues = append(issues, schemaIssue(file, "yaml-syntax", SeverityHigh, fmt.Sprintf("Invalid YAML syntax: %v", err)))
+				}
+				// Valid YAML without kind/apiVersion (values files, Chart.yaml) is not a Kubernetes resource.
+				continue
+			}
+			issues = append(issues, unavailableSchemaIssue(file, sig.Version, sig.Kind, r.Err))
+		}
+	}
+	return issues
+}
+
+// unavailableSchemaIssue reports a resource whose schema cannot be fetched.
+func unavailableSchemaIssue(file, apiVersion, kind string, err error) Issue {
+	return Issue{
+		File:       file,
+		Category:   CategorySchema,
+		Severity:   SeverityLow,
+		Rule:       "schema-unavailable",
+		Message:    fmt.Sprintf("No schema available for %s %s: %v", apiVersion, kind, err),
+		Suggestion: "Run once with network access to populate the schema cache, or pass --schema-location",
+	}
+}
+
+func schemaIssue(file, rule string, severity Severity, msg string) Issue {
+	return Issue{
+		File:     file,
+		Category: CategorySchema,
+		Severity: severity,
+		Rule:     rule,
+		Message:  msg,
+	}
+}
@@ -0,0 +1,218 @@
+package validate
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/config"
+	"github.com/ihsanmokhlisse/gitopsi/internal/generator"
+	"github.com/ihsanmokhlisse/gitopsi/internal/network"
+	"github.com/ihsanmokhlisse/gitopsi/internal/output"
+)
+
+// offlineSchemaOptions validates against embedded CRD schemas plus an empty
+// local registry, so core Kubernetes kinds are skipped and no network is used.
+func offlineSchemaOptions(t *testing.T, path string) *Options {
+	t.Helper()
+	return &Options{
+		Path:            path,
+		K8sVersion:      "1.29",
+		Schema:          true,
+		SchemaLocations: []string{filepath.Join(t.TempDir(), "{{ .ResourceKind }}{{ .KindSuffix }}.json")},
+	}
+}
+
+func writeManifest(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+}
+
+func schemaRules(result *ValidationResult) []string {
+	var rules []string
+	for _, issue := range result.Categories[CategorySchema].Issues {
+		rules = append(rules, issue.Rule)
+	}
+	return rules
+}
+
+func TestValidateSchemaEmbeddedCRDs(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, "app.yaml", `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: web
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/org/repo.git
+    path: apps/web
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: web
+`)
+	writeManifest(t, dir, "ks.yaml", `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: apps
+spec:
+  interval: 10m
+  path: ./apps
+  sourceRef:
+    kind: GitRepository
+    name: repo
+`)
+	writeManifest(t, dir, "repo.yaml", `apiVersion: source.toolkit.fluxcd.io/v1
+kind: GitRepository
+metadata:
+  name: repo
+spec:
+  interval: 1m
+  url: git@github.com:org/repo.git
+`)
+	writeManifest(t, dir, "values.yaml", "replicaCount: 2\n")
+
+	result, err := New(offlineSchemaOptions(t, dir)).Validate(context.Background())
+	require.NoError(t, err)
+
+	cat := result.Categories[CategorySchema]
+	assert.Equal(t, 2, cat.Passed, "Application and values.yaml should pass")
+	assert.Equal(t, []string{"schema-invalid", "schema-invalid"}, schemaRules(result))
+
+	var messages []string
+	for _, issue := range cat.Issues {
+		assert.Equal(t, SeverityHigh, issue.Severity)
+		messages = append(messages, issue.Message)
+	}
+	assert.Contains(t, messages[0]+messages[1], "prune")
+	assert.Contains(t, messages[0]+messages[1], "GitRepository repo")
+}
+
+func TestValidateSchemaLocationOverride(t *testing.T) {
+	schemas := t.TempDir()
+	writeManifest(t, schemas, "configmap-v1.json", `{
+  "type": "object",
+  "required": ["data"],
+  "properties": {"data": {"type": "object"}}
+}`)
+
+	dir := t.TempDir()
+	writeManifest(t, dir, "cm.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: empty\n")
+
+	opts := offlineSchemaOptions(t, dir)
+	opts.SchemaLocations = []string{filepath.Join(schemas, "{{ .ResourceKind }}{{ .KindSuffix }}.json")}
+	result, err := New(opts).Validate(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"schema-invalid"}, schemaRules(result))
+}
+
+func TestValidateSchemaCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/configmap-v1.json":
+			_, _ = io.WriteString(w, `{"type": "object", "required": ["data"]}`)
+		case "/secret-v1.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	transport := http.DefaultTransport.(*http.Transport)
+	proxy, tlsConfig := transport.Proxy, transport.TLSClientConfig
+	t.Cleanup(func() {
+		require.NoError(t, network.Configure(network.Options{}))
+		transport.Proxy, transport.TLSClientConfig = proxy, tlsConfig
+		transport.CloseIdleConnections()
+	})
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	require.NoError(t, network.Configure(network.Options{CABundle: bundle}))
+
+	dir := t.TempDir()
+	writeManifest(t, dir, "cm.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: empty\n")
+	writeManifest(t, dir, "sa.yaml", "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: app\n")
+	writeManifest(t, dir, "secret.yaml", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\n")
+
+	cache := t.TempDir()
+	opts := offlineSchemaOptions(t, dir)
+	opts.SchemaLocations = []string{server.URL + "/{{ .ResourceKind }}{{ .KindSuffix }}.json"}
+	opts.SchemaCacheDir = cache
+	result, err := New(opts).Validate(context.Background())
+	require.NoError(t, err)
+
+	cat := result.Categories[CategorySchema]
+	assert.Equal(t, 1, cat.Passed, "the ServiceAccount without schema is skipped")
+	require.Len(t, cat.Issues, 2)
+	assert.Equal(t, "schema-invalid", cat.Issues[0].Rule)
+	assert.Contains(t, cat.Issues[0].Message, "ConfigMap empty")
+	assert.Equal(t, "schema-unavailable", cat.Issues[1].Rule)
+	assert.Contains(t, cat.Issues[1].Message, "HTTP 500")
+	assert.FileExists(t, filepath.Join(cache, "mirror", server.Listener.Addr().String(), "configmap-v1.json"))
+}
+
+func TestValidateSchemaCacheDir(t *testing.T) {
+	cache := t.TempDir()
+	opts := offlineSchemaOptions(t, t.TempDir())
+	opts.SchemaCacheDir = cache
+
+	_, err := New(opts).Validate(context.Background())
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(cache, "crds", "argoproj.io", "application_v1alpha1.json"))
+	assert.DirExists(t, filepath.Join(cache, "http"))
+}
+
+func TestValidateSchemaSkipsHelmTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, "chart/Chart.yaml", "apiVersion: v2\nname: app\nversion: 0.1.0\n")
+	writeManifest(t, dir, "chart/templates/deployment.yaml", "{{- if .Values.enabled }}\nkind: Deployment\n{{- end }}\n")
+
+	manifests, err := New(&Options{Path: dir}).findManifests()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "chart/Chart.yaml")}, manifests)
+}
+
+func TestValidateSchemaGeneratedOutput(t *testing.T) {
+	for _, tool := range []string{"argocd", "flux"} {
+		t.Run(tool, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{
+				Project:    config.Project{Name: "schema-" + tool},
+				Platform:   "kubernetes",
+				Scope:      "both",
+				GitOpsTool: tool,
+				Git:        config.GitConfig{URL: "https://github.com/test/repo.git", Branch: "main"},
+				Environments: []config.Environment{
+					{Name: "dev"},
+					{Name: "prod"},
+				},
+				Apps: []config.Application{
+					{Name: "web", Image: "ghcr.io/org/web:1.2.3", Port: 8080, Replicas: 2},
+				},
+				Infra: config.Infrastructure{Namespaces: true, RBAC: true, NetworkPolicies: true, ResourceQuotas: true},
+				Flux: config.FluxConfig{
+					HelmReleases:    true,
+					ImageAutomation: config.FluxImageAutomation{Enabled: true},
+				},
+			}
+			require.NoError(t, generator.New(cfg, output.New(dir, false, false), false).Generate())
+
+			result, err := New(offlineSchemaOptions(t, dir)).Validate(context.Background())
+			require.NoError(t, err)
+			assert.Empty(t, result.Categories[CategorySchema].Issues)
+		})
+	}
+}
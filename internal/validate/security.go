@@ -0,0 +1,258 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SecurityCheck is a built-in security check, with the CIS Kubernetes
+// Benchmark and NSA/CISA Kubernetes Hardening Guide controls it covers.
+type SecurityCheck struct {
+	ID          string
+	Name        string
+	Severity    Severity
+	Benchmark   string
+	Remediation string
+	// Fixable checks are fixed by gitopsi fix
+	Fixable bool
+}
+
+// SecurityChecks are the built-in checks of the pod specs of workloads. They
+// run whether or not trivy or checkov are installed, and are disabled by ID
+// or name with Options.DisabledChecks.
+var SecurityChecks = []SecurityCheck{
+	{ID: "SEC001", Name: "privileged", Severity: SeverityHigh, Benchmark: "CIS 5.2.2, NSA non-privileged containers",
+		Remediation: "Remove securityContext.privileged or set it to false"},
+	{ID: "SEC002", Name: "run-as-non-root", Severity: SeverityMedium, Benchmark: "CIS 5.2.7, NSA non-root containers",
+		Remediation: "Set securityContext.runAsNonRoot: true and a non-zero runAsUser", Fixable: true},
+	{ID: "SEC003", Name: "resource-limits", Severity: SeverityMedium, Benchmark: "NSA resource policies",
+		Remediation: "Define resources.limits and resources.requests", Fixable: true},
+	{ID: "SEC004", Name: "read-only-root-filesystem", Severity: SeverityLow, Benchmark: "NSA immutable container file systems",
+		Remediation: "Set securityContext.readOnlyRootFilesystem: true and mount emptyDir volumes where the container writes"},
+	{ID: "SEC005", Name: "host-path", Severity: SeverityHigh, Benchmark: "CIS 5.2.12, NSA pod security",
+		Remediation: "Use a PersistentVolumeClaim, ConfigMap or emptyDir volume instead of hostPath"},
+	{ID: "SEC006", Name: "image-latest", Severity: SeverityMedium, Benchmark: "NSA image integrity",
+		Remediation: "Pin the image to a version tag or a digest"},
+	{ID: "SEC007", Name: "seccomp-profile", Severity: SeverityLow, Benchmark: "CIS 5.7.2, NSA kernel hardening",
+		Remediation: "Set securityContext.seccompProfile.type: RuntimeDefault on the pod or container", Fixable: true},
+	{ID: "SEC008", Name: "drop-capabilities", Severity: SeverityMedium, Benchmark: "CIS 5.2.9, NSA non-privileged containers",
+		Remediation: "Set securityContext.capabilities.drop: [ALL] and add back only the capabilities the container needs", Fixable: true},
+}
+
+// securityCheck returns the built-in check of an ID or name.
+func securityCheck(idOrName string) (SecurityCheck, bool) {
+	for _, check := range SecurityChecks {
+		if strings.EqualFold(check.ID, idOrName) || strings.EqualFold(check.Name, idOrName) {
+			return check, true
+		}
+	}
+	return SecurityCheck{}, false
+}
+
+// enabledChecks returns the built-in checks that are not disabled, by ID.
+func (v *Validator) enabledChecks() (map[string]bool, error) {
+	enabled := map[string]bool{}
+	for _, check := range SecurityChecks {
+		enabled[check.ID] = true
+	}
+	for _, disabled := range v.opts.DisabledChecks {
+		check, ok := securityCheck(disabled)
+		if !ok {
+			return nil, fmt.Errorf("unknown security check: %s", disabled)
+		}
+		delete(enabled, check.ID)
+	}
+	return enabled, nil
+}
+
+// runBenchmarkChecks runs the built-in checks on the manifests. Resources of
+// kustomize patches, which only set the fields they change, are not checked.
+func (v *Validator) runBenchmarkChecks(manifests []string, catResult *CategoryResult) error {
+	enabled, err := v.enabledChecks()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(enabled))
+	for id := range enabled {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	patches := v.patchFiles()
+	var checked []string
+	for _, manifest := range manifests {
+		if !patches[filepath.Clean(manifest)] {
+			checked = append(checked, manifest)
+		}
+	}
+
+	results := v.checkFiles("security", fingerprint(ids...), checked, func(file string, data []byte) ([]Issue, bool) {
+		var issues []Issue
+		for _, r := range manifestResources(file, data) {
+			issues = append(issues, benchmarkIssues(r, enabled)...)
+		}
+		return issues, true
+	})
+	for _, r := range results {
+		catResult.Issues = append(catResult.Issues, r.Issues...)
+		if len(r.Issues) > 0 {
+			catResult.Failed++
+		}
+	}
+	catResult.Passed = len(manifests) - catResult.Failed
+	return nil
+}
+
+// patchFiles returns the files the kustomizations of the repository use as
+// patches.
+func (v *Validator) patchFiles() map[string]bool {
+	patches := map[string]bool{}
+	_ = filepath.Walk(v.opts.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isKustomizationFile(path) {
+			return nil
+		}
+		k, err := readKustomization(path)
+		if err != nil {
+			return nil
+		}
+		for _, patch := range k.patchFiles() {
+			if !strings.Contains(patch, "\n") {
+				patches[filepath.Join(filepath.Dir(path), patch)] = true
+			}
+		}
+		return nil
+	})
+	return patches
+}
+
+// podSpec returns the pod spec of a workload, or nil.
+func podSpec(r manifestResource) map[string]any {
+	var spec any
+	switch r.str("kind") {
+	case "Pod":
+		spec = lookupValue(r.obj, "spec")
+	case "CronJob":
+		spec = lookupValue(r.obj, "spec", "jobTemplate", "spec", "template", "spec")
+	case "Job":
+		spec = lookupValue(r.obj, "spec", "template", "spec")
+	default:
+		if workloadKinds[r.str("kind")] {
+			spec = lookupValue(r.obj, "spec", "template", "spec")
+		}
+	}
+	m, _ := spec.(map[string]any)
+	return m
+}
+
+// benchmarkIssues runs the enabled checks on the pod spec of a workload.
+func benchmarkIssues(r manifestResource, enabled map[string]bool) []Issue {
+	spec := podSpec(r)
+	if spec == nil {
+		return nil
+	}
+	resource := fmt.Sprintf("%s %s", r.str("kind"), r.str("metadata", "name"))
+	var issues []Issue
+	report := func(id, message string) {
+		if !enabled[id] {
+			return
+		}
+		check, _ := securityCheck(id)
+		issues = append(issues, Issue{
+			File:       r.File,
+			Line:       r.Line,
+			Category:   CategorySecurity,
+			Severity:   check.Severity,
+			Rule:       check.ID,
+			Message:    fmt.Sprintf("%s: %s (%s)", resource, message, check.Benchmark),
+			Suggestion: check.Remediation,
+			Fixable:    check.Fixable,
+		})
+	}
+
+	if volumes, ok := spec["volumes"].([]any); ok {
+		for _, volume := range volumes {
+			if lookupValue(volume, "hostPath") != nil {
+				name, _ := lookupValue(volume, "name").(string)
+				report("SEC005", fmt.Sprintf("volume %s mounts a hostPath", name))
+			}
+		}
+	}
+
+	pod, _ := spec["securityContext"].(map[string]any)
+	var containers []any
+	for _, key := range []string{"initContainers", "containers"} {
+		if list, ok := spec[key].([]any); ok {
+			containers = append(containers, list...)
+		}
+	}
+	for _, c := range containers {
+		container, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := container["name"].(string)
+		sc, _ := container["securityContext"].(map[string]any)
+		setting := func(key string) any {
+			if v, ok := sc[key]; ok {
+				return v
+			}
+			return pod[key]
+		}
+
+		if sc["privileged"] == true {
+			report("SEC001", fmt.Sprintf("container %s runs privileged", name))
+		}
+		if !runsAsNonRoot(setting("runAsNonRoot"), setting("runAsUser")) {
+			report("SEC002", fmt.Sprintf("container %s may run as root", name))
+		}
+		if lookupValue(container, "resources", "limits") == nil {
+			report("SEC003", fmt.Sprintf("container %s has no resource limits", name))
+		}
+		if sc["readOnlyRootFilesystem"] != true {
+			report("SEC004", fmt.Sprintf("container %s has a writable root filesystem", name))
+		}
+		if image, _ := container["image"].(string); image != "" && usesLatest(image) {
+			report("SEC006", fmt.Sprintf("container %s uses image %s without a pinned tag", name, image))
+		}
+		if profile, _ := lookupValue(setting("seccompProfile"), "type").(string); profile == "" || profile == "Unconfined" {
+			report("SEC007", fmt.Sprintf("container %s has no seccomp profile", name))
+		}
+		if !dropsAll(lookupValue(sc, "capabilities", "drop")) {
+			report("SEC008", fmt.Sprintf("container %s does not drop ALL capabilities", name))
+		}
+	}
+	return issues
+}
+
+// runsAsNonRoot reports whether the effective runAsNonRoot and runAsUser of
+// a container keep it from running as root.
+func runsAsNonRoot(nonRoot, user any) bool {
+	if uid, ok := user.(int); ok {
+		return uid != 0
+	}
+	return nonRoot == true
+}
+
+// usesLatest reports whether an image has the latest tag, or no tag or
+// digest.
+func usesLatest(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(name, ":")
+	return !found || tag == "latest"
+}
+
+func dropsAll(drop any) bool {
+	list, _ := drop.([]any)
+	for _, capability := range list {
+		if s, ok := capability.(string); ok && strings.EqualFold(s, "ALL") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package validate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const insecureWorkload = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: docker
+          hostPath:
+            path: /var/run/docker.sock
+      containers:
+        - name: app
+          image: nginx:latest
+          securityContext:
+            privileged: true
+            runAsUser: 0
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          securityContext:
+            runAsNonRoot: true
+            seccompProfile:
+              type: RuntimeDefault
+          containers:
+            - name: report
+              image: registry.example.com:5000/report
+              resources:
+                limits:
+                  memory: 128Mi
+              securityContext:
+                readOnlyRootFilesystem: true
+                capabilities:
+                  drop: ["ALL"]
+`
+
+const hardenedWorkload = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsUser: 1000
+        seccompProfile:
+          type: RuntimeDefault
+      containers:
+        - name: db
+          image: postgres@sha256:0123456789abcdef
+          resources:
+            limits:
+              cpu: "1"
+          securityContext:
+            readOnlyRootFilesystem: true
+            capabilities:
+              drop: [ALL]
+`
+
+func TestBenchmarkChecks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "insecure.yaml"), []byte(insecureWorkload), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hardened.yaml"), []byte(hardenedWorkload), 0644))
+
+	v := New(&Options{Path: dir, Security: true, FailOn: SeverityHigh})
+	result, err := v.Validate(context.Background())
+	require.NoError(t, err)
+
+	security := result.Categories[CategorySecurity]
+	require.NotNil(t, security)
+	rules := map[string]Issue{}
+	for _, issue := range security.Issues {
+		assert.Equal(t, filepath.Join(dir, "insecure.yaml"), issue.File, "the hardened workload passes every check")
+		if issue.Line == 1 {
+			rules[issue.Rule] = issue
+		}
+	}
+	assert.Len(t, security.Issues, 9, "%v", security.Issues)
+	assert.Equal(t, 1, security.Failed)
+	assert.Equal(t, 1, security.Passed)
+
+	privileged := rules["SEC001"]
+	assert.Equal(t, SeverityHigh, privileged.Severity)
+	assert.Equal(t, "Deployment web: container app runs privileged (CIS 5.2.2, NSA non-privileged containers)", privileged.Message)
+	assert.Equal(t, "Remove securityContext.privileged or set it to false", privileged.Suggestion)
+	assert.Equal(t, 1, privileged.Line)
+	assert.Contains(t, rules["SEC005"].Message, "volume docker mounts a hostPath")
+	assert.Contains(t, rules["SEC006"].Message, "container app uses image nginx:latest")
+	assert.Equal(t, "Deployment web: container app has no resource limits (NSA resource policies)", rules["SEC003"].Message)
+
+	var report []string
+	for _, issue := range security.Issues {
+		if issue.Line > 1 {
+			report = append(report, issue.Rule)
+		}
+	}
+	assert.Equal(t, []string{"SEC006"}, report, "the CronJob only uses an image without a tag")
+	assert.True(t, v.ShouldFail(result))
+}
+
+func TestBenchmarkChecksDisabled(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "insecure.yaml"), []byte(insecureWorkload), 0644))
+
+	result, err := New(&Options{Path: dir, Security: true, DisabledChecks: []string{"SEC001", "host-path", "image-latest", "sec004"}}).Validate(context.Background())
+	require.NoError(t, err)
+	for _, issue := range result.Categories[CategorySecurity].Issues {
+		assert.NotContains(t, []string{"SEC001", "SEC004", "SEC005", "SEC006"}, issue.Rule)
+	}
+	assert.Len(t, result.Categories[CategorySecurity].Issues, 4)
+
+	_, err = New(&Options{Path: dir, Security: true, DisabledChecks: []string{"no-such-check"}}).Validate(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown security check: no-such-check")
+}
+
+func TestBenchmarkChecksSkipPatches(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte("resources:\n  - ../base\npatches:\n  - path: replicas.yaml\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "replicas.yaml"), []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  template:\n    spec:\n      containers:\n        - name: app\n          image: nginx:1.25\n"), 0644))
+
+	result, err := New(&Options{Path: dir, Security: true}).Validate(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result.Categories[CategorySecurity].Issues)
+}
+
+func TestUsesLatest(t *testing.T) {
+	tests := map[string]bool{
+		"nginx":                            true,
+		"nginx:latest":                     true,
+		"nginx:1.25":                       false,
+		"registry.example.com:5000/app":    true,
+		"registry.example.com:5000/app:v1": false,
+		"app@sha256:abc":                   false,
+	}
+	for image, want := range tests {
+		assert.Equal(t, want, usesLatest(image), image)
+	}
+}
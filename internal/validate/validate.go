@@ -7,9 +7,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/ihsanmokhlisse/gitopsi/internal/scan"
 )
 
 type Severity string
@@ -30,6 +33,10 @@ const (
 	CategoryDeprecation  Category = "deprecation"
 	CategoryBestPractice Category = "best-practice"
 	CategoryKustomize    Category = "kustomize"
+	CategorySecrets      Category = "secrets"
+	CategoryConventions  Category = "conventions"
+	CategoryLive         Category = "live"
+	CategoryReferences   Category = "references"
 )
 
 type Issue struct {
@@ -51,6 +58,10 @@ type ValidationResult struct {
 	Failed         int                          `json:"failed" yaml:"failed"`
 	Issues         []Issue                      `json:"issues" yaml:"issues"`
 	Categories     map[Category]*CategoryResult `json:"categories" yaml:"categories"`
+	// Prerequisites are the installations the live validation found missing.
+	Prerequisites []Prerequisite `json:"prerequisites,omitempty" yaml:"prerequisites,omitempty"`
+	// CachedFiles counts the file checks answered by the cache.
+	CachedFiles int `json:"cached_files,omitempty" yaml:"cached_files,omitempty"`
 }
 
 type CategoryResult struct {
@@ -68,27 +79,64 @@ type Options struct {
 	Deprecation   bool
 	BestPractice  bool
 	Kustomize     bool
-	FailOn        Severity
-	OutputFormat  string
-	Fix           bool
+	// References checks the references between the files and resources of
+	// the repository.
+	References bool
+	// RepoURL is the URL of the repository. Application paths are checked
+	// for the sources of this repository only; empty checks every source.
+	RepoURL string
+	// DisabledChecks are the IDs or names of the built-in security checks
+	// not to run.
+	DisabledChecks []string
+	// Secrets scans every file for leaked credentials.
+	Secrets      bool
+	FailOn       Severity
+	OutputFormat string
+	Fix          bool
+	// SchemaLocations overrides the schema registries (kubeconform
+	// -schema-location syntax). Embedded ArgoCD/Flux schemas are always used.
+	SchemaLocations []string
+	// SchemaCacheDir caches downloaded schemas for offline use. Empty disables caching.
+	SchemaCacheDir string
+	// StrictSchema rejects fields not present in the schema.
+	StrictSchema bool
+	// RequiredLabels are the labels every resource must set. Empty disables
+	// the conventions check.
+	RequiredLabels []string
+	// Live checks that the cluster of Kubeconfig and Context serves the API
+	// of every resource.
+	Live       bool
+	Kubeconfig string
+	Context    string
+	// Jobs is the number of files checked concurrently. Zero uses one
+	// worker per CPU.
+	Jobs int
+	// CacheDir caches the results of per-file checks by content hash, so
+	// unchanged files are not checked again. Empty disables caching.
+	CacheDir string
 }
 
 func DefaultOptions() *Options {
 	return &Options{
-		K8sVersion:    "1.29",
-		ArgoCDVersion: "2.10",
-		Schema:        true,
-		Security:      true,
-		Deprecation:   true,
-		BestPractice:  true,
-		Kustomize:     true,
-		FailOn:        SeverityHigh,
-		OutputFormat:  "table",
+		K8sVersion:     "1.29",
+		ArgoCDVersion:  "2.10",
+		Schema:         true,
+		Security:       true,
+		Deprecation:    true,
+		BestPractice:   true,
+		Kustomize:      true,
+		References:     true,
+		Secrets:        true,
+		FailOn:         SeverityHigh,
+		OutputFormat:   "table",
+		SchemaCacheDir: DefaultSchemaCacheDir(),
 	}
 }
 
 type Validator struct {
-	opts *Options
+	opts  *Options
+	run   Runner
+	cache *resultCache
 }
 
 func New(opts *Options) *Validator {
@@ -115,6 +163,10 @@ func (v *Validator) Validate(ctx context.Context) (*ValidationResult, error) {
 	}
 	result.TotalManifests = len(manifests)
 
+	if v.opts.CacheDir != "" {
+		v.cache = loadCache(v.opts.CacheDir)
+	}
+
 	if v.opts.Schema {
 		if schemaErr := v.validateSchema(ctx, manifests, result); schemaErr != nil {
 			return nil, fmt.Errorf("schema validation failed: %w", schemaErr)
@@ -122,7 +174,7 @@ func (v *Validator) Validate(ctx context.Context) (*ValidationResult, error) {
 	}
 
 	if v.opts.Security {
-		if secErr := v.validateSecurity(ctx, result); secErr != nil {
+		if secErr := v.validateSecurity(ctx, manifests, result); secErr != nil {
 			return nil, fmt.Errorf("security validation failed: %w", secErr)
 		}
 	}
@@ -139,11 +191,69 @@ func (v *Validator) Validate(ctx context.Context) (*ValidationResult, error) {
 		}
 	}
 
+	if v.opts.References {
+		if refErr := v.validateReferences(manifests, result); refErr != nil {
+			return nil, fmt.Errorf("reference validation failed: %w", refErr)
+		}
+	}
+
+	if v.opts.Secrets {
+		if scanErr := v.scanSecrets(result); scanErr != nil {
+			return nil, fmt.Errorf("secret scan failed: %w", scanErr)
+		}
+	}
+
+	if len(v.opts.RequiredLabels) > 0 {
+		v.validateConventions(manifests, result)
+	}
+
+	if v.opts.Live {
+		if liveErr := v.validateLive(ctx, manifests, result); liveErr != nil {
+			return nil, fmt.Errorf("live validation failed: %w", liveErr)
+		}
+	}
+
+	if v.cache != nil {
+		result.CachedFiles = v.cache.hits
+		if cacheErr := v.cache.save(); cacheErr != nil {
+			return nil, cacheErr
+		}
+	}
+
 	v.calculateSummary(result)
 
 	return result, nil
 }
 
+func (v *Validator) scanSecrets(result *ValidationResult) error {
+	catResult := &CategoryResult{Issues: []Issue{}}
+	result.Categories[CategorySecrets] = catResult
+
+	report, err := scan.Scan(&scan.Options{Path: v.opts.Path})
+	if err != nil {
+		return err
+	}
+	for _, f := range report.Findings {
+		catResult.Issues = append(catResult.Issues, Issue{
+			File:       f.File,
+			Line:       f.Line,
+			Category:   CategorySecrets,
+			Severity:   Severity(f.Severity),
+			Rule:       f.Rule,
+			Message:    fmt.Sprintf("%s (%s)", f.Description, f.Match),
+			Suggestion: fmt.Sprintf("Remove and rotate the credential, or add %s to the fingerprints of %s", f.Fingerprint(), scan.AllowlistFile),
+		})
+		catResult.Failed++
+	}
+	catResult.Passed = report.Scanned - len(report.Findings)
+	if catResult.Passed < 0 {
+		catResult.Passed = 0
+	}
+
+	result.Issues = append(result.Issues, catResult.Issues...)
+	return nil
+}
+
 func (v *Validator) findManifests() ([]string, error) {
 	var manifests []string
 
@@ -156,6 +266,9 @@ func (v *Validator) findManifests() ([]string, error) {
 		}
 		ext := strings.ToLower(filepath.Ext(path))
 		if ext == ".yaml" || ext == ".yml" {
+			if isHelmTemplate(path) {
+				return nil
+			}
 			if !strings.Contains(path, "kustomization") {
 				manifests = append(manifests, path)
 			}
@@ -166,125 +279,37 @@ func (v *Validator) findManifests() ([]string, error) {
 	return manifests, err
 }
 
-func (v *Validator) validateSchema(ctx context.Context, manifests []string, result *ValidationResult) error {
-	catResult := &CategoryResult{Issues: []Issue{}}
-	result.Categories[CategorySchema] = catResult
-
-	kubeconformPath, err := exec.LookPath("kubeconform")
-	if err != nil {
-		for _, m := range manifests {
-			if validateErr := v.basicYAMLValidation(m, catResult); validateErr != nil {
-				continue
-			}
-		}
-		catResult.Passed = len(manifests) - catResult.Failed
-		result.Issues = append(result.Issues, catResult.Issues...)
-		return nil
-	}
-
-	for _, manifest := range manifests {
-		args := []string{
-			"-kubernetes-version", v.opts.K8sVersion,
-			"-summary",
-			"-output", "json",
-			manifest,
-		}
-
-		cmd := exec.CommandContext(ctx, kubeconformPath, args...)
-		output, cmdErr := cmd.CombinedOutput()
-
-		if cmdErr != nil {
-			var kubeResult struct {
-				Resources []struct {
-					Filename string `json:"filename"`
-					Kind     string `json:"kind"`
-					Name     string `json:"name"`
-					Status   string `json:"status"`
-					Msg      string `json:"msg"`
-				} `json:"resources"`
-			}
-
-			if jsonErr := json.Unmarshal(output, &kubeResult); jsonErr == nil {
-				for _, r := range kubeResult.Resources {
-					if r.Status != "ok" && r.Status != "skipped" {
-						issue := Issue{
-							File:     r.Filename,
-							Category: CategorySchema,
-							Severity: SeverityHigh,
-							Rule:     "kubeconform",
-							Message:  r.Msg,
-						}
-						catResult.Issues = append(catResult.Issues, issue)
-						catResult.Failed++
-					}
-				}
-			} else {
-				catResult.Passed++
-			}
-		} else {
-			catResult.Passed++
-		}
+// isHelmTemplate reports whether path is a template of a Helm chart, which is
+// not valid YAML until rendered.
+func isHelmTemplate(path string) bool {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) != "templates" {
+		return false
 	}
-
-	result.Issues = append(result.Issues, catResult.Issues...)
-	return nil
+	_, err := os.Stat(filepath.Join(filepath.Dir(dir), "Chart.yaml"))
+	return err == nil
 }
 
-func (v *Validator) basicYAMLValidation(path string, catResult *CategoryResult) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		issue := Issue{
-			File:     path,
-			Category: CategorySchema,
-			Severity: SeverityHigh,
-			Rule:     "yaml-read",
-			Message:  fmt.Sprintf("Cannot read file: %v", err),
-		}
-		catResult.Issues = append(catResult.Issues, issue)
-		catResult.Failed++
-		return err
-	}
+func (v *Validator) validateSecurity(ctx context.Context, manifests []string, result *ValidationResult) error {
+	catResult := &CategoryResult{Issues: []Issue{}}
+	result.Categories[CategorySecurity] = catResult
 
-	var doc interface{}
-	if err := yaml.Unmarshal(data, &doc); err != nil {
-		issue := Issue{
-			File:     path,
-			Category: CategorySchema,
-			Severity: SeverityHigh,
-			Rule:     "yaml-syntax",
-			Message:  fmt.Sprintf("Invalid YAML syntax: %v", err),
-		}
-		catResult.Issues = append(catResult.Issues, issue)
-		catResult.Failed++
+	if err := v.runBenchmarkChecks(manifests, catResult); err != nil {
 		return err
 	}
 
-	catResult.Passed++
-	return nil
-}
-
-func (v *Validator) validateSecurity(ctx context.Context, result *ValidationResult) error {
-	catResult := &CategoryResult{Issues: []Issue{}}
-	result.Categories[CategorySecurity] = catResult
-
-	trivyPath, trivyErr := exec.LookPath("trivy")
-	if trivyErr == nil {
+	if trivyPath, err := exec.LookPath("trivy"); err == nil {
 		if err := v.runTrivy(ctx, trivyPath, catResult); err != nil {
 			return err
 		}
 	}
 
-	checkovPath, checkovErr := exec.LookPath("checkov")
-	if checkovErr == nil {
+	if checkovPath, err := exec.LookPath("checkov"); err == nil {
 		if err := v.runCheckov(ctx, checkovPath, catResult); err != nil {
 			return err
 		}
 	}
 
-	if trivyErr != nil && checkovErr != nil {
-		v.runBasicSecurityChecks(result, catResult)
-	}
-
 	result.Issues = append(result.Issues, catResult.Issues...)
 	return nil
 }
@@ -385,59 +410,6 @@ func (v *Validator) runCheckov(ctx context.Context, checkovPath string, catResul
 	return nil
 }
 
-func (v *Validator) runBasicSecurityChecks(result *ValidationResult, catResult *CategoryResult) {
-	manifests, _ := v.findManifests()
-	for _, manifest := range manifests {
-		data, err := os.ReadFile(manifest)
-		if err != nil {
-			continue
-		}
-
-		content := string(data)
-
-		if strings.Contains(content, "privileged: true") {
-			issue := Issue{
-				File:       manifest,
-				Category:   CategorySecurity,
-				Severity:   SeverityHigh,
-				Rule:       "SEC001",
-				Message:    "Container running in privileged mode",
-				Suggestion: "Remove 'privileged: true' from securityContext",
-			}
-			catResult.Issues = append(catResult.Issues, issue)
-			catResult.Failed++
-		}
-
-		if strings.Contains(content, "runAsUser: 0") || strings.Contains(content, "runAsNonRoot: false") {
-			issue := Issue{
-				File:       manifest,
-				Category:   CategorySecurity,
-				Severity:   SeverityMedium,
-				Rule:       "SEC002",
-				Message:    "Container may run as root",
-				Suggestion: "Set runAsNonRoot: true and runAsUser to non-zero value",
-			}
-			catResult.Issues = append(catResult.Issues, issue)
-			catResult.Failed++
-		}
-
-		if !strings.Contains(content, "resources:") && strings.Contains(content, "kind: Deployment") {
-			issue := Issue{
-				File:       manifest,
-				Category:   CategorySecurity,
-				Severity:   SeverityMedium,
-				Rule:       "SEC003",
-				Message:    "No resource limits defined",
-				Suggestion: "Define resources.limits and resources.requests",
-			}
-			catResult.Issues = append(catResult.Issues, issue)
-			catResult.Failed++
-		}
-	}
-
-	catResult.Passed = result.TotalManifests - catResult.Failed
-}
-
 func (v *Validator) validateDeprecation(ctx context.Context, manifests []string, result *ValidationResult) error {
 	catResult := &CategoryResult{Issues: []Issue{}}
 	result.Categories[CategoryDeprecation] = catResult
@@ -508,41 +480,48 @@ func (v *Validator) validateDeprecation(ctx context.Context, manifests []string,
 	return nil
 }
 
+// deprecatedAPIs are the deprecated API versions the basic deprecation check
+// reports, with their replacements.
+var deprecatedAPIs = map[string]string{
+	"extensions/v1beta1":                   "apps/v1",
+	"apps/v1beta1":                         "apps/v1",
+	"apps/v1beta2":                         "apps/v1",
+	"networking.k8s.io/v1beta1":            "networking.k8s.io/v1",
+	"rbac.authorization.k8s.io/v1beta1":    "rbac.authorization.k8s.io/v1",
+	"admissionregistration.k8s.io/v1beta1": "admissionregistration.k8s.io/v1",
+}
+
 func (v *Validator) runBasicDeprecationChecks(manifests []string, catResult *CategoryResult) {
-	deprecatedAPIs := map[string]string{
-		"extensions/v1beta1":                   "apps/v1",
-		"apps/v1beta1":                         "apps/v1",
-		"apps/v1beta2":                         "apps/v1",
-		"networking.k8s.io/v1beta1":            "networking.k8s.io/v1",
-		"rbac.authorization.k8s.io/v1beta1":    "rbac.authorization.k8s.io/v1",
-		"admissionregistration.k8s.io/v1beta1": "admissionregistration.k8s.io/v1",
+	for _, r := range v.checkFiles("deprecation", "", manifests, basicDeprecationIssues) {
+		catResult.Issues = append(catResult.Issues, r.Issues...)
+		catResult.Failed += len(r.Issues)
 	}
 
-	for _, manifest := range manifests {
-		data, err := os.ReadFile(manifest)
-		if err != nil {
-			continue
-		}
+	catResult.Passed = len(manifests) - catResult.Failed
+}
 
-		content := string(data)
-		for deprecated, replacement := range deprecatedAPIs {
-			if strings.Contains(content, "apiVersion: "+deprecated) {
-				issue := Issue{
-					File:       manifest,
-					Category:   CategoryDeprecation,
-					Severity:   SeverityMedium,
-					Rule:       "DEP001",
-					Message:    fmt.Sprintf("Uses deprecated API version: %s", deprecated),
-					Suggestion: fmt.Sprintf("Migrate to %s", replacement),
-					Fixable:    true,
-				}
-				catResult.Issues = append(catResult.Issues, issue)
-				catResult.Failed++
-			}
+func basicDeprecationIssues(manifest string, data []byte) ([]Issue, bool) {
+	var issues []Issue
+	content := string(data)
+	deprecated := make([]string, 0, len(deprecatedAPIs))
+	for api := range deprecatedAPIs {
+		deprecated = append(deprecated, api)
+	}
+	sort.Strings(deprecated)
+	for _, api := range deprecated {
+		if strings.Contains(content, "apiVersion: "+api) {
+			issues = append(issues, Issue{
+				File:       manifest,
+				Category:   CategoryDeprecation,
+				Severity:   SeverityMedium,
+				Rule:       "DEP001",
+				Message:    fmt.Sprintf("Uses deprecated API version: %s", api),
+				Suggestion: fmt.Sprintf("Migrate to %s", deprecatedAPIs[api]),
+				Fixable:    true,
+			})
 		}
 	}
-
-	catResult.Passed = len(manifests) - catResult.Failed
+	return issues, true
 }
 
 func (v *Validator) validateKustomize(ctx context.Context, result *ValidationResult) error {
@@ -554,7 +533,7 @@ func (v *Validator) validateKustomize(ctx context.Context, result *ValidationRes
 		if err != nil {
 			return nil
 		}
-		if !info.IsDir() && strings.Contains(filepath.Base(path), "kustomization") {
+		if !info.IsDir() && strings.Contains(filepath.Base(path), "kustomization") && !isComponent(path) {
 			kustomizeFiles = append(kustomizeFiles, filepath.Dir(path))
 		}
 		return nil
@@ -570,7 +549,9 @@ func (v *Validator) validateKustomize(ctx context.Context, result *ValidationRes
 		buildCmd = kubectlPath
 	}
 
-	for _, kDir := range kustomizeFiles {
+	failures := make([]*Issue, len(kustomizeFiles))
+	v.parallel(len(kustomizeFiles), func(i int) {
+		kDir := kustomizeFiles[i]
 		var cmd *exec.Cmd
 		switch {
 		case buildCmd == kustomizePath:
@@ -578,20 +559,23 @@ func (v *Validator) validateKustomize(ctx context.Context, result *ValidationRes
 		case buildCmd == kubectlPath:
 			cmd = exec.CommandContext(ctx, buildCmd, "kustomize", kDir)
 		default:
-			catResult.Passed++
-			continue
+			return
 		}
 
 		output, buildErr := cmd.CombinedOutput()
 		if buildErr != nil {
-			issue := Issue{
+			failures[i] = &Issue{
 				File:     filepath.Join(kDir, "kustomization.yaml"),
 				Category: CategoryKustomize,
 				Severity: SeverityHigh,
 				Rule:     "KUS001",
 				Message:  fmt.Sprintf("Kustomize build failed: %s", strings.TrimSpace(string(output))),
 			}
-			catResult.Issues = append(catResult.Issues, issue)
+		}
+	})
+	for _, failure := range failures {
+		if failure != nil {
+			catResult.Issues = append(catResult.Issues, *failure)
 			catResult.Failed++
 		} else {
 			catResult.Passed++
@@ -602,6 +586,19 @@ func (v *Validator) validateKustomize(ctx context.Context, result *ValidationRes
 	return nil
 }
 
+// isComponent reports whether a kustomization file declares a kustomize
+// Component, which only builds as part of the overlays including it.
+func isComponent(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var kustomization struct {
+		Kind string `yaml:"kind"`
+	}
+	return yaml.Unmarshal(data, &kustomization) == nil && kustomization.Kind == "Component"
+}
+
 func (v *Validator) calculateSummary(result *ValidationResult) {
 	result.Passed = result.TotalManifests
 	result.Warnings = 0
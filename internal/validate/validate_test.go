@@ -38,6 +38,7 @@ func TestCategory(t *testing.T) {
 		{CategoryDeprecation, "deprecation"},
 		{CategoryBestPractice, "best-practice"},
 		{CategoryKustomize, "kustomize"},
+		{CategorySecrets, "secrets"},
 	}
 
 	for _, tt := range tests {
@@ -55,6 +56,8 @@ func TestDefaultOptions(t *testing.T) {
 	assert.True(t, opts.Deprecation)
 	assert.True(t, opts.BestPractice)
 	assert.True(t, opts.Kustomize)
+	assert.True(t, opts.References)
+	assert.True(t, opts.Secrets)
 	assert.Equal(t, SeverityHigh, opts.FailOn)
 	assert.Equal(t, "table", opts.OutputFormat)
 }
@@ -251,6 +254,29 @@ spec:
 	assert.Greater(t, len(securityIssues.Issues), 0)
 }
 
+func TestValidateSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	secret := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  password: s3cr3t-Pa55w0rd
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "secret.yaml"), []byte(secret), 0644))
+
+	v := New(&Options{Path: tmpDir, Secrets: true, FailOn: SeverityHigh})
+	result, err := v.Validate(context.Background())
+	require.NoError(t, err)
+
+	secrets := result.Categories[CategorySecrets]
+	require.Len(t, secrets.Issues, 1)
+	assert.Equal(t, "kubernetes-secret-data", secrets.Issues[0].Rule)
+	assert.Equal(t, 6, secrets.Issues[0].Line)
+	assert.NotContains(t, secrets.Issues[0].Message, "s3cr3t-Pa55w0rd")
+	assert.True(t, v.ShouldFail(result))
+}
+
 func TestValidateKustomize(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "gitopsi-validate-test-*")
 	require.NoError(t, err)
@@ -457,3 +483,39 @@ func TestCalculateSummary(t *testing.T) {
 	assert.Equal(t, 2, result.Failed)
 	assert.Equal(t, 2, result.Warnings)
 }
+
+func TestValidateRequiredLabels(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifests := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop-dev
+  labels:
+    team: payments
+    cost-center: "1234"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    team: payments
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "shop.yaml"), []byte(manifests), 0644))
+
+	v := New(&Options{Path: tmpDir, RequiredLabels: []string{"team", "cost-center"}, FailOn: SeverityHigh})
+	result, err := v.Validate(context.Background())
+	require.NoError(t, err)
+
+	conventions := result.Categories[CategoryConventions]
+	require.Len(t, conventions.Issues, 1)
+	assert.Equal(t, "CONV001", conventions.Issues[0].Rule)
+	assert.Equal(t, 9, conventions.Issues[0].Line)
+	assert.Equal(t, "Deployment web is missing required labels: cost-center", conventions.Issues[0].Message)
+	assert.True(t, v.ShouldFail(result))
+
+	v = New(&Options{Path: tmpDir, FailOn: SeverityHigh})
+	result, err = v.Validate(context.Background())
+	require.NoError(t, err)
+	assert.NotContains(t, result.Categories, CategoryConventions)
+}
@@ -154,10 +154,6 @@ func TestInitDryRun(t *testing.T) {
 }
 
 func TestInitWithFlux(t *testing.T) {
-	// TODO: Flux support is disabled - focus on ArgoCD first
-	// Uncomment when Flux support is ready for production
-	t.Skip("Flux support is disabled - focusing on ArgoCD first")
-
 	tmpDir := t.TempDir()
 
 	cmd := exec.Command(binaryPath, "init",